@@ -26,6 +26,8 @@ type Options struct {
 	AutoMigrate bool
 	// SeedNonProd whether to write default configuration in non-production environments (default true)
 	SeedNonProd bool
+	// DBOptions overrides pool/replica/statement timeout settings; nil uses global configuration
+	DBOptions *utils.DBOptions
 }
 
 // SetupDatabase unified entry: connect database -> run initialization SQL -> migrate entities -> (non-production) write default configuration
@@ -35,7 +37,11 @@ func SetupDatabase(logWriter io.Writer, opts *Options) (*gorm.DB, error) {
 	}
 
 	// 1) Connect to database
-	db, err := initDBConn(logWriter)
+	dbOpts := opts.DBOptions
+	if dbOpts == nil {
+		dbOpts = dbOptionsFromConfig()
+	}
+	db, err := initDBConn(logWriter, *dbOpts)
 	if err != nil {
 		logger.Error("init database failed", zap.Error(err))
 		return nil, err
@@ -77,10 +83,40 @@ func SetupDatabase(logWriter io.Writer, opts *Options) (*gorm.DB, error) {
 }
 
 // initDBConn creates *gorm.DB based on global configuration
-func initDBConn(logWriter io.Writer) (*gorm.DB, error) {
+func initDBConn(logWriter io.Writer, dbOpts utils.DBOptions) (*gorm.DB, error) {
 	dbDriver := config.GlobalConfig.DBDriver
 	dsn := config.GlobalConfig.DSN
-	return utils.InitDatabase(logWriter, dbDriver, dsn)
+	db, err := utils.InitDatabaseWithOptions(logWriter, dbDriver, dsn, dbOpts)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("database connection pool configured",
+		zap.Int("maxIdleConns", dbOpts.MaxIdleConns),
+		zap.Int("maxOpenConns", dbOpts.MaxOpenConns),
+		zap.Duration("connMaxLifetime", dbOpts.ConnMaxLifetime),
+		zap.Duration("connMaxIdleTime", dbOpts.ConnMaxIdleTime),
+		zap.Duration("statementTimeout", dbOpts.StatementTimeout),
+		zap.Int("replicas", len(dbOpts.ReplicaDSNs)),
+	)
+	return db, nil
+}
+
+// dbOptionsFromConfig builds database options from global configuration
+func dbOptionsFromConfig() *utils.DBOptions {
+	cfg := config.GlobalConfig
+	dbOpts := &utils.DBOptions{
+		MaxIdleConns:     cfg.DBMaxIdleConns,
+		MaxOpenConns:     cfg.DBMaxOpenConns,
+		ConnMaxLifetime:  cfg.DBConnMaxLifetime,
+		ConnMaxIdleTime:  cfg.DBConnMaxIdleTime,
+		StatementTimeout: cfg.DBStatementTimeout,
+	}
+	for _, replica := range strings.Split(cfg.DBReplicaDSNs, ",") {
+		if replica = strings.TrimSpace(replica); replica != "" {
+			dbOpts.ReplicaDSNs = append(dbOpts.ReplicaDSNs, replica)
+		}
+	}
+	return dbOpts
 }
 
 // RunInitSQL executes SQL statements from a local .sql file segment by segment (split by semicolon ;), idempotent scripts should use IF NOT EXISTS in SQL for protection
@@ -139,6 +175,10 @@ func RunMigrations(db *gorm.DB) error {
 		&models.User{},
 		&models.Group{},
 		&models.UserCredential{},
+		&models.APIToken{},
+		&models.CredentialUsageCounter{},
+		&models.MCPInvocation{},
+		&models.MCPTool{},
 		&models.GroupMember{},
 		&models.GroupInvitation{},
 		&models.Assistant{},
@@ -147,7 +187,12 @@ func RunMigrations(db *gorm.DB) error {
 		&models.PromptModel{},
 		&models.PromptArgModel{},
 		&notification.InternalNotification{},
+		&notification.UserNotificationChannel{},
+		&notification.PushDeviceToken{},
+		&notification.WebPushSubscription{},
 		&models.Knowledge{}, // New knowledge base model
+		&models.KnowledgeSyncSource{},
+		&models.KnowledgeSyncDocument{},
 		// Voice training related rtcmedia
 		&models.VoiceTrainingTask{},
 		&models.VoiceClone{},
@@ -158,6 +203,8 @@ func RunMigrations(db *gorm.DB) error {
 		&models.VoiceTrainingTextSegment{},
 		// Operation log model
 		&middleware.OperationLog{},
+		// Audit log of sensitive operations
+		&models.AuditLog{},
 		&models.JSTemplate{},
 		&models.JSTemplateVersion{},
 		// Device model for OTA
@@ -167,24 +214,48 @@ func RunMigrations(db *gorm.DB) error {
 		// Billing models
 		&models.UsageRecord{},
 		&models.Bill{},
+		&models.BillingPlan{},
+		&models.BillingSubscription{},
+		&models.UsageStatement{},
 		// Alert models
 		&models.AlertRule{},
 		&models.Alert{},
 		&models.AlertNotification{},
+		// Call recording and transcript models
+		&models.CallRecording{},
+		&models.RecordingPolicy{},
+		&models.CallTranscript{},
+		// Webhook models
+		&models.Webhook{},
+		&models.WebhookDelivery{},
 		// Quota models
 		&models.UserQuota{},
 		&models.GroupQuota{},
 		&models.WorkflowDefinition{},
 		&models.WorkflowInstance{},
 		&models.WorkflowVersion{},
+		&models.WorkflowApproval{},
+		&models.WorkflowStep{},
+		&models.WorkflowScheduleRun{},
+		&models.Lease{},
+		&models.StorageObject{},
 		&models.OverviewConfig{},
 		// Login security models
-		&models.UserDevice{},   // 用户设备管理表
-		&models.LoginHistory{}, // 登录历史记录表
-		&models.AccountLock{},  // 账号锁定记录表
+		&models.UserDevice{},         // 用户设备管理表
+		&models.WebAuthnCredential{}, // 通行密钥表
+		&models.UserIdentity{},       // 第三方登录身份关联表
+		&models.UserSession{},        // 登录会话表
+		&models.LoginHistory{},       // 登录历史记录表
+		&models.AccountLock{},        // 账号锁定记录表
 		// SIP user model
-		&models.SipUser{}, // SIP用户表
+		&models.SipUser{},         // SIP用户表
+		&models.SipRegistration{}, // SIP注册绑定表
 		// SIP call model
 		&models.SipCall{}, // SIP通话记录表
+		// SIP campaign model
+		&models.SipCampaign{},     // 外呼任务表
+		&models.SipCampaignCall{}, // 外呼号码表
+		// Call detail records
+		&models.CallDetailRecord{}, // 通话详单表
 	})
 }
@@ -55,6 +55,9 @@ func LogConfigInfo() {
 		zap.String("mail_password", config.GlobalConfig.Mail.Password),
 		zap.String("mail_from", config.GlobalConfig.Mail.From),
 		zap.Int64("mail_port", config.GlobalConfig.Mail.Port),
+		zap.Bool("push_fcm_enabled", config.GlobalConfig.Push.FCM.CredentialsFile != ""),
+		zap.Bool("push_apns_enabled", config.GlobalConfig.Push.APNs.KeyFile != ""),
+		zap.Bool("push_apns_production", config.GlobalConfig.Push.APNs.Production),
 	)
 
 	logger.Info("search config",
@@ -66,6 +69,11 @@ func LogConfigInfo() {
 		zap.Bool("backup_enabled", config.GlobalConfig.BackupEnabled),
 		zap.String("backup_path", config.GlobalConfig.BackupPath),
 		zap.String("backup_schedule", config.GlobalConfig.BackupSchedule),
+		zap.Bool("backup_encrypted", config.GlobalConfig.BackupEncryptionKey != ""),
+		zap.Int("backup_retention_days", config.GlobalConfig.BackupRetentionDays),
+		zap.Int("backup_retention_keep", config.GlobalConfig.BackupRetentionKeep),
+		zap.String("backup_s3_endpoint", config.GlobalConfig.BackupS3Endpoint),
+		zap.String("backup_s3_bucket", config.GlobalConfig.BackupS3Bucket),
 	)
 
 	logger.Info("xun fei config",
@@ -102,6 +102,23 @@ func (s *SeedService) seedConfigs() error {
 			return "100"
 		}()},
 		{Key: constants.KEY_SEARCH_INDEX_SCHEDULE, Desc: "Search Index Schedule (Cron)", Autoload: true, Public: false, Format: "text", Value: "0 */6 * * *"}, // Execute every 6 hours
+		{Key: constants.KEY_SEARCH_ENGINE, Desc: "Search Engine (bleve/meilisearch)", Autoload: true, Public: false, Format: "text", Value: func() string {
+			if config.GlobalConfig.SearchEngine != "" {
+				return config.GlobalConfig.SearchEngine
+			}
+			return "bleve"
+		}()},
+		{Key: constants.KEY_SEARCH_ANALYZER, Desc: "Search Analyzer (cjk/standard, rebuild index after change)", Autoload: true, Public: false, Format: "text", Value: func() string {
+			if config.GlobalConfig.SearchAnalyzer != "" {
+				return config.GlobalConfig.SearchAnalyzer
+			}
+			return "cjk"
+		}()},
+		{Key: constants.KEY_SEARCH_FUZZINESS, Desc: "Search Typo Tolerance (edit distance, bleve only)", Autoload: true, Public: false, Format: "int", Value: "1"},
+		// WebRTC ICE configuration
+		{Key: constants.KEY_WEBRTC_STUN_URLS, Desc: "WebRTC STUN Servers (comma separated)", Autoload: true, Public: false, Format: "text", Value: config.GlobalConfig.WebRTCSTUNURLs},
+		{Key: constants.KEY_WEBRTC_TURN_SERVERS, Desc: "WebRTC TURN Servers (JSON array, overrides WEBRTC_TURN_* env)", Autoload: true, Public: false, Format: "json", Value: ""},
+		{Key: constants.KEY_WEBRTC_ICE_TRANSPORT_POLICY, Desc: "WebRTC ICE Transport Policy (all/relay)", Autoload: true, Public: false, Format: "text", Value: config.GlobalConfig.WebRTCICETransportPolicy},
 		{Key: constants.KEY_SERVER_WEBSOCKET, Desc: "SERVER WEBSOCKET", Autoload: true, Public: false, Format: "text", Value: "wss://lingecho.com/api/voice/websocket/voice/lingecho/v1/"},
 	}
 	for _, cfg := range defaults {
@@ -234,24 +251,28 @@ func (s *SeedService) seedPromptModels() error {
 		{
 			Name:        "summarize_article",
 			Description: "Summarize the main content of an article, suitable for extracting summaries from long paragraphs or blog posts.",
+			Template:    "Summarize the main points of the following article in a few sentences:\n\n{{content}}",
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		},
 		{
 			Name:        "translate_text",
 			Description: "Translate input text to a specified language, suitable for scenarios like English-Chinese translation.",
+			Template:    "Translate the following text into {{target_language}}. Only return the translation.\n\n{{text}}",
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		},
 		{
 			Name:        "generate_title",
 			Description: "Generate a concise and attractive title based on article content.",
+			Template:    "Write a concise and attractive title for the following article. Only return the title.\n\n{{article}}",
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		},
 		{
 			Name:        "email_reply_generator",
 			Description: "Automatically generate professional email replies based on email content and intent.",
+			Template:    "Write a professional reply to the following email. Preferred tone, if any: {{tone}}\n\n{{email_body}}",
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		},
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	transportSSE  = "sse"
+	transportHTTP = "http"
+
+	defaultServerURL = "http://localhost:3001/sse"
+)
+
+// Profile is a named MCP server connection
+type Profile struct {
+	URL       string            `json:"url"`
+	Transport string            `json:"transport,omitempty"` // sse (default) or http
+	Token     string            `json:"token,omitempty"`     // sent as Authorization: Bearer <token>
+	Headers   map[string]string `json:"headers,omitempty"`   // e.g. X-API-KEY / X-API-SECRET
+	Output    string            `json:"output,omitempty"`    // default output format
+}
+
+// ClientConfig is the mcp-client config file:
+//
+//	{
+//	  "default": "local",
+//	  "profiles": {
+//	    "local": {"url": "http://localhost:3001/sse"},
+//	    "prod": {"url": "https://mcp.example.com/mcp", "transport": "http", "token": "..."}
+//	  }
+//	}
+type ClientConfig struct {
+	Default  string              `json:"default,omitempty"`
+	Profiles map[string]*Profile `json:"profiles"`
+}
+
+// defaultConfigPath returns $MCP_CLIENT_CONFIG or ~/.lingecho/mcp-client.json
+func defaultConfigPath() string {
+	if path := os.Getenv("MCP_CLIENT_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".lingecho", "mcp-client.json")
+}
+
+// loadConfig reads the config file. A missing file yields an empty config.
+func loadConfig(path string) (*ClientConfig, error) {
+	cfg := &ClientConfig{Profiles: map[string]*Profile{}}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]*Profile{}
+	}
+	return cfg, nil
+}
+
+// Profile returns the named profile, or the default profile when name is empty.
+// With no name and no default, it returns nil and no error.
+func (c *ClientConfig) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" {
+		return nil, nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found (available: %v)", name, c.ProfileNames())
+	}
+	return profile, nil
+}
+
+// ProfileNames returns the configured profile names in order
+func (c *ClientConfig) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -6,161 +6,179 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
-	"strings"
 )
 
 func main() {
+	var configPath string
+	var profileName string
 	var serverURL string
+	var transportType string
+	var token string
+	var output string
 	var toolName string
 	var argsJSON string
-
-	flag.StringVar(&serverURL, "url", "http://localhost:3001/sse", "MCP server URL (should include /sse path)")
-	flag.StringVar(&toolName, "tool", "", "Tool name to call")
+	var interactive bool
+
+	flag.StringVar(&configPath, "config", defaultConfigPath(), "Config file with named server profiles (default $MCP_CLIENT_CONFIG or ~/.lingecho/mcp-client.json)")
+	flag.StringVar(&profileName, "profile", "", "Server profile from the config file (default: the config's default profile)")
+	flag.StringVar(&serverURL, "url", defaultServerURL, "MCP server URL (SSE URLs should include the /sse path)")
+	flag.StringVar(&transportType, "transport", transportSSE, "Transport type (sse or http)")
+	flag.StringVar(&token, "token", "", "Bearer token sent in the Authorization header")
+	flag.StringVar(&output, "output", outputTable, "Output format (table, json or raw)")
+	flag.StringVar(&toolName, "tool", "", "Tool name to call, or \"list\" to list tools")
 	flag.StringVar(&argsJSON, "args", "{}", "Tool arguments as JSON string")
+	flag.BoolVar(&interactive, "i", false, "Start an interactive session (default when -tool is not set)")
 	flag.Parse()
 
-	if toolName == "" {
-		fmt.Println("Usage: go run cmd/mcp-client/main.go -tool <tool_name> [-url <server_url>] [-args <json_args>]")
-		fmt.Println("\nExample:")
-		fmt.Println("  # List all tools")
-		fmt.Println("  go run cmd/mcp-client/main.go -tool list")
-		fmt.Println("\n  # Call echo tool")
-		fmt.Println("  go run cmd/mcp-client/main.go -tool echo -args '{\"text\":\"Hello World\"}'")
-		fmt.Println("\n  # Call calculator tool")
-		fmt.Println("  go run cmd/mcp-client/main.go -tool calculator -args '{\"expression\":\"2+2\"}'")
+	// 1. Resolve the server profile; explicit flags override profile values
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
 	}
-
-	// Ensure URL includes /sse path
-	if !strings.HasSuffix(serverURL, "/sse") {
-		if !strings.HasSuffix(serverURL, "/") {
-			serverURL += "/"
+	profile, err := cfg.Profile(profileName)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	if profile == nil {
+		profile = &Profile{}
+	}
+	headers := make(map[string]string, len(profile.Headers))
+	for key, value := range profile.Headers {
+		headers[key] = value
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "url":
+			profile.URL = serverURL
+		case "transport":
+			profile.Transport = transportType
+		case "token":
+			profile.Token = token
+		case "output":
+			profile.Output = output
 		}
-		serverURL += "sse"
+	})
+	if profile.URL == "" {
+		profile.URL = serverURL
 	}
-
-	// Create client
-	httpTransport, err := transport.NewSSE(serverURL)
-	if err != nil {
-		fmt.Printf("Failed to create transport: %v\n", err)
+	if profile.Transport == "" {
+		profile.Transport = transportType
+	}
+	if profile.Output == "" {
+		profile.Output = output
+	}
+	if profile.Token != "" {
+		headers["Authorization"] = "Bearer " + profile.Token
+	}
+	if !validOutput(profile.Output) {
+		fmt.Printf("Unknown output format: %s (table, json or raw)\n", profile.Output)
 		os.Exit(1)
 	}
 
-	mcpClient := client.NewClient(httpTransport)
-	if err = mcpClient.Start(context.Background()); err != nil {
-		fmt.Printf("Failed to start client: %v\n", err)
+	// 2. Connect
+	ctx := context.Background()
+	mcpClient, err := connect(ctx, profile, headers)
+	if err != nil {
+		fmt.Printf("%v\n", err)
 		os.Exit(1)
 	}
 	defer mcpClient.Close()
 
-	// Initialize
-	initRequest := mcp.InitializeRequest{}
-	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
-	initRequest.Params.Capabilities = mcp.ClientCapabilities{}
+	out := &printer{out: os.Stdout, format: profile.Output}
 
-	serverInfo, err := mcpClient.Initialize(context.Background(), initRequest)
-	if err != nil {
-		fmt.Printf("Failed to initialize: %v\n", err)
-		os.Exit(1)
+	// 3. Interactive session
+	if interactive || toolName == "" {
+		historyPath := ""
+		if configPath != "" {
+			historyPath = filepath.Join(filepath.Dir(configPath), "mcp-client_history")
+			if err := os.MkdirAll(filepath.Dir(historyPath), 0o700); err != nil {
+				historyPath = ""
+			}
+		}
+		(&session{client: mcpClient, printer: out, in: os.Stdin, historyPath: historyPath}).run(ctx)
+		return
 	}
 
-	fmt.Printf("Connected to MCP server: %s v%s\n\n", serverInfo.ServerInfo.Name, serverInfo.ServerInfo.Version)
-
-	// If it's a list command, list all tools
+	// 4. Single command
 	if toolName == "list" {
-		listTools(serverURL)
+		toolsResult, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+		if err != nil {
+			fmt.Printf("Failed to list tools: %v\n", err)
+			os.Exit(1)
+		}
+		out.tools(toolsResult.Tools)
 		return
 	}
 
-	// Parse arguments
 	var arguments map[string]any
 	if err := json.Unmarshal([]byte(argsJSON), &arguments); err != nil {
 		fmt.Printf("Failed to parse arguments JSON: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Call tool
 	request := mcp.CallToolRequest{}
 	request.Params.Name = toolName
 	request.Params.Arguments = arguments
 
-	fmt.Printf("Calling tool: %s\n", toolName)
-	fmt.Printf("Arguments: %s\n\n", argsJSON)
-
-	result, err := mcpClient.CallTool(context.Background(), request)
+	result, err := mcpClient.CallTool(ctx, request)
 	if err != nil {
 		fmt.Printf("Error calling tool: %v\n", err)
 		os.Exit(1)
 	}
-
+	out.result(result)
 	if result.IsError {
-		fmt.Printf("Tool returned error\n")
-	}
-
-	// Print result
-	fmt.Println("Result:")
-	for _, content := range result.Content {
-		if textContent, ok := content.(mcp.TextContent); ok {
-			fmt.Println(textContent.Text)
-		} else {
-			fmt.Printf("%+v\n", content)
-		}
+		os.Exit(1)
 	}
 }
 
-func listTools(serverURL string) {
-	// Ensure URL includes /sse path
-	if !strings.HasSuffix(serverURL, "/sse") {
-		if !strings.HasSuffix(serverURL, "/") {
-			serverURL += "/"
+// connect starts and initializes a client for the profile
+func connect(ctx context.Context, profile *Profile, headers map[string]string) (*client.Client, error) {
+	var mcpTransport transport.Interface
+	var err error
+	switch profile.Transport {
+	case transportSSE:
+		// Ensure URL includes /sse path
+		serverURL := profile.URL
+		if !strings.HasSuffix(serverURL, "/sse") {
+			if !strings.HasSuffix(serverURL, "/") {
+				serverURL += "/"
+			}
+			serverURL += "sse"
 		}
-		serverURL += "sse"
+		mcpTransport, err = transport.NewSSE(serverURL, transport.WithHeaders(headers))
+	case transportHTTP:
+		mcpTransport, err = transport.NewStreamableHTTP(profile.URL, transport.WithHTTPHeaders(headers))
+	default:
+		return nil, fmt.Errorf("unknown transport: %s (sse or http)", profile.Transport)
 	}
-
-	httpTransport, err := transport.NewSSE(serverURL)
 	if err != nil {
-		fmt.Printf("Failed to create transport: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("failed to create transport: %w", err)
 	}
 
-	mcpClient := client.NewClient(httpTransport)
-	if err = mcpClient.Start(context.Background()); err != nil {
-		fmt.Printf("Failed to start client: %v\n", err)
-		os.Exit(1)
+	mcpClient := client.NewClient(mcpTransport)
+	if err := mcpClient.Start(ctx); err != nil {
+		return nil, fmt.Errorf("failed to start client: %w", err)
 	}
-	defer mcpClient.Close()
 
 	// Initialize
 	initRequest := mcp.InitializeRequest{}
 	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
 	initRequest.Params.Capabilities = mcp.ClientCapabilities{}
 
-	_, err = mcpClient.Initialize(context.Background(), initRequest)
-	if err != nil {
-		fmt.Printf("Failed to initialize: %v\n", err)
-		os.Exit(1)
-	}
-
-	// List tools
-	toolsRequest := mcp.ListToolsRequest{}
-	toolsResult, err := mcpClient.ListTools(context.Background(), toolsRequest)
+	serverInfo, err := mcpClient.Initialize(ctx, initRequest)
 	if err != nil {
-		fmt.Printf("Failed to list tools: %v\n", err)
-		os.Exit(1)
+		mcpClient.Close()
+		return nil, fmt.Errorf("failed to initialize: %w", err)
 	}
 
-	fmt.Printf("Available tools (%d):\n\n", len(toolsResult.Tools))
-	for i, tool := range toolsResult.Tools {
-		fmt.Printf("%d. %s\n", i+1, tool.Name)
-		fmt.Printf("   Description: %s\n", tool.Description)
-		if tool.InputSchema.Properties != nil {
-			schemaBytes, _ := json.MarshalIndent(tool.InputSchema, "   ", "  ")
-			fmt.Printf("   Schema: %s\n", string(schemaBytes))
-		}
-		fmt.Println()
-	}
+	fmt.Fprintf(os.Stderr, "Connected to MCP server: %s v%s\n", serverInfo.ServerInfo.Name, serverInfo.ServerInfo.Version)
+	return mcpClient, nil
 }
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputRaw   = "raw"
+
+	maxCellWidth = 60
+)
+
+func validOutput(format string) bool {
+	return format == outputTable || format == outputJSON || format == outputRaw
+}
+
+// printer renders tools and tool results in the selected output format
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+// tools prints the tool list
+func (p *printer) tools(tools []mcp.Tool) {
+	switch p.format {
+	case outputJSON:
+		p.json(tools)
+	case outputRaw:
+		for _, tool := range tools {
+			fmt.Fprintln(p.out, tool.Name)
+		}
+	default:
+		w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tPARAMETERS\tDESCRIPTION")
+		for _, tool := range tools {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", tool.Name, strings.Join(parameterNames(tool), ", "), truncate(tool.Description))
+		}
+		w.Flush()
+		fmt.Fprintf(p.out, "\n%d tools (* = required)\n", len(tools))
+	}
+}
+
+// tool prints a single tool definition
+func (p *printer) tool(tool mcp.Tool) {
+	switch p.format {
+	case outputJSON:
+		p.json(tool)
+	case outputRaw:
+		schema, _ := json.MarshalIndent(tool.InputSchema, "", "  ")
+		fmt.Fprintf(p.out, "%s\n%s\n%s\n", tool.Name, tool.Description, schema)
+	default:
+		fmt.Fprintf(p.out, "%s\n  %s\n\n", tool.Name, tool.Description)
+		if len(tool.InputSchema.Properties) == 0 {
+			fmt.Fprintln(p.out, "No parameters")
+			return
+		}
+		required := requiredSet(tool)
+		w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PARAMETER\tTYPE\tREQUIRED\tDESCRIPTION")
+		for _, name := range sortedKeys(tool.InputSchema.Properties) {
+			property, _ := tool.InputSchema.Properties[name].(map[string]any)
+			fmt.Fprintf(w, "%s\t%v\t%t\t%v\n", name, property["type"], required[name], property["description"])
+		}
+		w.Flush()
+	}
+}
+
+// result prints a tool call result
+func (p *printer) result(result *mcp.CallToolResult) {
+	if p.format == outputJSON {
+		p.json(result)
+		return
+	}
+	if result.IsError {
+		fmt.Fprintln(p.out, "Tool returned error")
+	}
+	for _, content := range result.Content {
+		text, ok := content.(mcp.TextContent)
+		if !ok {
+			fmt.Fprintf(p.out, "%+v\n", content)
+			continue
+		}
+		if p.format == outputRaw {
+			fmt.Fprintln(p.out, text.Text)
+			continue
+		}
+		p.table(text.Text)
+	}
+}
+
+// table renders JSON text as a table: objects as key/value rows, arrays of
+// objects as one row per element. The {"code","msg","data"} envelope of
+// successful LingEcho tool responses is unwrapped. Other text is printed as is.
+func (p *printer) table(text string) {
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		fmt.Fprintln(p.out, text)
+		return
+	}
+	if envelope, ok := value.(map[string]any); ok {
+		if code, _ := envelope["code"].(float64); code == 200 {
+			if data, ok := envelope["data"]; ok {
+				value = data
+			}
+		}
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	switch v := value.(type) {
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			fmt.Fprintf(w, "%s\t%s\n", key, cell(v[key]))
+		}
+	case []any:
+		rows := make([]map[string]any, 0, len(v))
+		columns := map[string]bool{}
+		for _, item := range v {
+			row, ok := item.(map[string]any)
+			if !ok {
+				fmt.Fprintln(w, cell(item))
+				continue
+			}
+			rows = append(rows, row)
+			for key := range row {
+				columns[key] = true
+			}
+		}
+		if len(rows) == 0 {
+			return
+		}
+		names := sortedKeys(columns)
+		fmt.Fprintln(w, strings.ToUpper(strings.Join(names, "\t")))
+		for _, row := range rows {
+			cells := make([]string, len(names))
+			for i, name := range names {
+				cells[i] = cell(row[name])
+			}
+			fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+	default:
+		fmt.Fprintln(w, cell(v))
+	}
+}
+
+func (p *printer) json(value any) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		fmt.Fprintf(p.out, "Failed to encode JSON: %v\n", err)
+		return
+	}
+	fmt.Fprintln(p.out, string(data))
+}
+
+// cell formats a value for a table cell: scalars as is, the rest as compact JSON
+func cell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return truncate(v)
+	case float64, bool:
+		return fmt.Sprint(v)
+	}
+	data, _ := json.Marshal(value)
+	return truncate(string(data))
+}
+
+func truncate(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxCellWidth {
+		return string(runes[:maxCellWidth-3]) + "..."
+	}
+	return text
+}
+
+// parameterNames lists tool parameters, marking required ones with *
+func parameterNames(tool mcp.Tool) []string {
+	required := requiredSet(tool)
+	names := sortedKeys(tool.InputSchema.Properties)
+	for i, name := range names {
+		if required[name] {
+			names[i] = name + "*"
+		}
+	}
+	return names
+}
+
+func requiredSet(tool mcp.Tool) map[string]bool {
+	required := make(map[string]bool, len(tool.InputSchema.Required))
+	for _, name := range tool.InputSchema.Required {
+		required[name] = true
+	}
+	return required
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
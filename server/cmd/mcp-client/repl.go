@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxHistory is the number of commands kept in the history file
+const maxHistory = 500
+
+// session is an interactive client session
+type session struct {
+	client      *client.Client
+	printer     *printer
+	in          io.Reader
+	tools       []mcp.Tool
+	history     []string
+	historyPath string
+}
+
+const replHelp = `Commands:
+  list [filter]                 List tools, optionally filtered by name
+  describe <tool>               Show a tool's parameters
+  call <tool> [json|k=v ...]    Call a tool with a JSON object or key=value arguments
+  <tool> [json|k=v ...]         Shorthand for call
+  output [table|json|raw]       Show or change the output format
+  history                       Show command history
+  !!, !<n>                      Re-run the last or n-th command
+  help                          Show this help
+  exit, quit                    Leave`
+
+// run reads commands until EOF or exit
+func (s *session) run(ctx context.Context) {
+	s.loadHistory()
+	fmt.Fprintln(s.printer.out, `Type "help" for commands.`)
+
+	scanner := bufio.NewScanner(s.in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for {
+		fmt.Fprint(s.printer.out, "mcp> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.printer.out)
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			recalled, err := s.recall(line)
+			if err != nil {
+				fmt.Fprintln(s.printer.out, err)
+				continue
+			}
+			line = recalled
+			fmt.Fprintln(s.printer.out, line)
+		}
+		if line == "exit" || line == "quit" {
+			return
+		}
+		s.addHistory(line)
+
+		if err := s.execute(ctx, line); err != nil {
+			fmt.Fprintf(s.printer.out, "Error: %v\n", err)
+		}
+	}
+}
+
+// execute runs a single command line
+func (s *session) execute(ctx context.Context, line string) error {
+	command, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch command {
+	case "help":
+		fmt.Fprintln(s.printer.out, replHelp)
+	case "list":
+		tools, err := s.listTools(ctx, true)
+		if err != nil {
+			return err
+		}
+		if rest != "" {
+			filtered := tools[:0:0]
+			for _, tool := range tools {
+				if strings.Contains(tool.Name, rest) {
+					filtered = append(filtered, tool)
+				}
+			}
+			tools = filtered
+		}
+		s.printer.tools(tools)
+	case "describe":
+		tool, err := s.findTool(ctx, rest)
+		if err != nil {
+			return err
+		}
+		s.printer.tool(tool)
+	case "call":
+		name, args, _ := strings.Cut(rest, " ")
+		return s.call(ctx, name, strings.TrimSpace(args))
+	case "output":
+		if rest == "" {
+			fmt.Fprintln(s.printer.out, s.printer.format)
+			return nil
+		}
+		if !validOutput(rest) {
+			return fmt.Errorf("unknown output format %q (table, json or raw)", rest)
+		}
+		s.printer.format = rest
+	case "history":
+		for i, entry := range s.history {
+			fmt.Fprintf(s.printer.out, "%4d  %s\n", i+1, entry)
+		}
+	default:
+		if _, err := s.findTool(ctx, command); err != nil {
+			return fmt.Errorf("unknown command %q, type \"help\" for commands", command)
+		}
+		return s.call(ctx, command, rest)
+	}
+	return nil
+}
+
+// call parses the arguments against the tool schema and calls the tool
+func (s *session) call(ctx context.Context, name, args string) error {
+	tool, err := s.findTool(ctx, name)
+	if err != nil {
+		return err
+	}
+	arguments, err := parseArguments(tool, args)
+	if err != nil {
+		return err
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool.Name
+	request.Params.Arguments = arguments
+	result, err := s.client.CallTool(ctx, request)
+	if err != nil {
+		return err
+	}
+	s.printer.result(result)
+	return nil
+}
+
+// listTools returns the server's tools, cached unless refresh is set
+func (s *session) listTools(ctx context.Context, refresh bool) ([]mcp.Tool, error) {
+	if s.tools != nil && !refresh {
+		return s.tools, nil
+	}
+	result, err := s.client.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	s.tools = result.Tools
+	return s.tools, nil
+}
+
+func (s *session) findTool(ctx context.Context, name string) (mcp.Tool, error) {
+	if name == "" {
+		return mcp.Tool{}, fmt.Errorf("tool name is required")
+	}
+	tools, err := s.listTools(ctx, false)
+	if err != nil {
+		return mcp.Tool{}, err
+	}
+	for _, tool := range tools {
+		if tool.Name == name {
+			return tool, nil
+		}
+	}
+	return mcp.Tool{}, fmt.Errorf("tool %q not found", name)
+}
+
+// parseArguments accepts a JSON object or key=value pairs. Values of
+// non-string parameters are decoded as JSON when possible, so count=3 is a
+// number and enabled=true a boolean.
+func parseArguments(tool mcp.Tool, args string) (map[string]any, error) {
+	arguments := map[string]any{}
+	if args == "" {
+		return arguments, nil
+	}
+	if strings.HasPrefix(args, "{") {
+		if err := json.Unmarshal([]byte(args), &arguments); err != nil {
+			return nil, fmt.Errorf("invalid JSON arguments: %w", err)
+		}
+		return arguments, nil
+	}
+
+	pairs, err := splitArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid argument %q, expected key=value", pair)
+		}
+		property, _ := tool.InputSchema.Properties[key].(map[string]any)
+		if property["type"] == "string" {
+			arguments[key] = value
+			continue
+		}
+		var decoded any
+		if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+			arguments[key] = decoded
+		} else {
+			arguments[key] = value
+		}
+	}
+	return arguments, nil
+}
+
+// splitArgs splits on whitespace, keeping single- or double-quoted sections together
+func splitArgs(args string) ([]string, error) {
+	var (
+		fields  []string
+		current strings.Builder
+		quote   rune
+		inField bool
+	)
+	for _, r := range args {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in arguments")
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
+
+// recall resolves !! and !<n> against the history
+func (s *session) recall(line string) (string, error) {
+	if len(s.history) == 0 {
+		return "", fmt.Errorf("history is empty")
+	}
+	if line == "!!" {
+		return s.history[len(s.history)-1], nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(s.history) {
+		return "", fmt.Errorf("no such history entry: %s", line)
+	}
+	return s.history[n-1], nil
+}
+
+func (s *session) addHistory(line string) {
+	if len(s.history) > 0 && s.history[len(s.history)-1] == line {
+		return
+	}
+	s.history = append(s.history, line)
+	if len(s.history) > maxHistory {
+		s.history = s.history[len(s.history)-maxHistory:]
+	}
+	if s.historyPath == "" {
+		return
+	}
+	file, err := os.OpenFile(s.historyPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
+
+// loadHistory reads the last maxHistory commands of previous sessions
+func (s *session) loadHistory() {
+	if s.historyPath == "" {
+		return
+	}
+	data, err := os.ReadFile(s.historyPath)
+	if err != nil {
+		return
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+		// Compact the file so it does not grow without bound
+		_ = os.WriteFile(s.historyPath, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+	}
+	for _, line := range lines {
+		if line != "" {
+			s.history = append(s.history, line)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"os"
+	"time"
 
 	"github.com/code-100-precent/LingEcho/cmd/bootstrap"
 	"github.com/code-100-precent/LingEcho/pkg/agent"
@@ -13,6 +14,7 @@ import (
 	"github.com/code-100-precent/LingEcho/pkg/llm"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
 	lingechoMCP "github.com/code-100-precent/LingEcho/pkg/mcp"
+	"github.com/code-100-precent/LingEcho/pkg/prompt"
 	"github.com/mark3labs/mcp-go/server"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -23,9 +25,17 @@ func main() {
 	var transport string
 	var port string
 	var mode string
-
-	flag.StringVar(&transport, "transport", "sse", "Transport type (stdio or sse)")
-	flag.StringVar(&port, "port", "3001", "Port to run the MCP server on (only for SSE transport)")
+	var endpointPath string
+	var stateless bool
+	var sessionTTL time.Duration
+	var authToken string
+
+	flag.StringVar(&transport, "transport", "sse", "Transport type (stdio, sse or http)")
+	flag.StringVar(&port, "port", "3001", "Port to run the MCP server on (only for SSE and HTTP transports)")
+	flag.StringVar(&endpointPath, "path", "/mcp", "Endpoint path of the streamable HTTP transport")
+	flag.BoolVar(&stateless, "stateless", false, "Run the streamable HTTP transport without sessions")
+	flag.DurationVar(&sessionTTL, "session-ttl", lingechoMCP.DefaultSessionIdleTTL, "Idle timeout of streamable HTTP sessions")
+	flag.StringVar(&authToken, "auth-token", os.Getenv("MCP_AUTH_TOKEN"), "Bearer token required by the SSE and HTTP transports (default $MCP_AUTH_TOKEN)")
 	flag.StringVar(&mode, "mode", "", "Running environment (development, test, production)")
 	flag.Parse()
 
@@ -80,17 +90,19 @@ func main() {
 
 	// 7. Initialize graph store (if enabled)
 	var graphStore graph.Store
-	if config.GlobalConfig.Neo4jEnabled {
-		graphStore, err = graph.NewNeo4jStore(
-			config.GlobalConfig.Neo4jURI,
-			config.GlobalConfig.Neo4jUsername,
-			config.GlobalConfig.Neo4jPassword,
-			config.GlobalConfig.Neo4jDatabase,
-		)
+	if graphStoreType := graph.ResolveStoreType(config.GlobalConfig.GraphStore, config.GlobalConfig.Neo4jEnabled); graphStoreType != "" {
+		graphStore, err = graph.NewStore(graph.StoreConfig{
+			Type:          graphStoreType,
+			Neo4jURI:      config.GlobalConfig.Neo4jURI,
+			Neo4jUsername: config.GlobalConfig.Neo4jUsername,
+			Neo4jPassword: config.GlobalConfig.Neo4jPassword,
+			Neo4jDatabase: config.GlobalConfig.Neo4jDatabase,
+			SQLitePath:    config.GlobalConfig.GraphSQLitePath,
+		})
 		if err != nil {
-			logger.Warn("Neo4j initialization failed, graph memory agent will not work", zap.Error(err))
+			logger.Warn("Graph store initialization failed, graph memory agent will not work", zap.String("type", graphStoreType), zap.Error(err))
 		} else {
-			logger.Info("Neo4j graph store initialized")
+			logger.Info("Graph store initialized", zap.String("type", graphStoreType))
 		}
 	}
 
@@ -174,15 +186,57 @@ func main() {
 		agent.RegisterAgentTools(mcpServer, agentManager, log)
 	}
 
-	// 13. Start server
-	if transport == "sse" {
-		sseServer := server.NewSSEServer(mcpServer.GetServer())
+	// 13. Register resources, prompt templates and dynamic HTTP tools (requires database)
+	if db != nil {
+		lingechoMCP.RegisterResourceProviders(mcpServer, db)
+		if err := prompt.InitPromptSystem(db); err != nil {
+			logger.Warn("Prompt system initialization failed, prompts will not be available", zap.Error(err))
+		} else {
+			logger.Info("Prompt templates registered", zap.Int("count", lingechoMCP.RegisterPromptTemplates(mcpServer)))
+		}
+
+		dynamicTools := lingechoMCP.NewDynamicTools(mcpServer, db, log)
+		if count, err := dynamicTools.Sync(ctx); err != nil {
+			logger.Warn("Dynamic tools could not be loaded", zap.Error(err))
+		} else {
+			logger.Info("Dynamic tools registered", zap.Int("count", count))
+		}
+		go dynamicTools.Run(ctx, lingechoMCP.DefaultDynamicToolSyncInterval)
+	}
+
+	// 14. Authentication and invocation audit: user API credentials (scoped by
+	// their MCP scopes) when the database is available, plus the static token
+	var authenticators []lingechoMCP.Authenticator
+	if db != nil {
+		authenticators = append(authenticators, lingechoMCP.CredentialAuthenticator(db))
+		mcpServer.SetAuditor(lingechoMCP.DBAuditor(db, log))
+	}
+	if authToken != "" {
+		authenticators = append(authenticators, lingechoMCP.StaticTokenAuthenticator(authToken))
+	}
+	var authenticator lingechoMCP.Authenticator
+	if len(authenticators) > 0 {
+		authenticator = lingechoMCP.FirstOf(authenticators...)
+	}
+
+	// 15. Start server
+	switch transport {
+	case lingechoMCP.TransportSSE:
 		log.Info("Enhanced SSE server listening", zap.String("port", port))
 
-		if err := sseServer.Start(":" + port); err != nil {
+		if err := mcpServer.ServeSSE(":"+port, authenticator); err != nil {
 			logger.Fatal("Server error", zap.Error(err))
 		}
-	} else {
+	case lingechoMCP.TransportHTTP:
+		if err := mcpServer.ServeStreamableHTTP(":"+port, lingechoMCP.StreamableHTTPConfig{
+			EndpointPath:   endpointPath,
+			Stateless:      stateless,
+			SessionIdleTTL: sessionTTL,
+			Authenticator:  authenticator,
+		}); err != nil {
+			logger.Fatal("Server error", zap.Error(err))
+		}
+	default:
 		logger.Info("Starting enhanced stdio server")
 		if err := server.ServeStdio(mcpServer.GetServer()); err != nil {
 			logger.Fatal("Server error", zap.Error(err))
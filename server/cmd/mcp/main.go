@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/config"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
@@ -16,9 +17,17 @@ func main() {
 	var transport string
 	var port string
 	var mode string
+	var endpointPath string
+	var stateless bool
+	var sessionTTL time.Duration
+	var authToken string
 
-	flag.StringVar(&transport, "transport", "sse", "Transport type (stdio or sse)")
-	flag.StringVar(&port, "port", "3001", "Port to run the MCP server on (only for SSE transport)")
+	flag.StringVar(&transport, "transport", "sse", "Transport type (stdio, sse or http)")
+	flag.StringVar(&port, "port", "3001", "Port to run the MCP server on (only for SSE and HTTP transports)")
+	flag.StringVar(&endpointPath, "path", "/mcp", "Endpoint path of the streamable HTTP transport")
+	flag.BoolVar(&stateless, "stateless", false, "Run the streamable HTTP transport without sessions")
+	flag.DurationVar(&sessionTTL, "session-ttl", lingechoMCP.DefaultSessionIdleTTL, "Idle timeout of streamable HTTP sessions")
+	flag.StringVar(&authToken, "auth-token", os.Getenv("MCP_AUTH_TOKEN"), "Bearer token required by the SSE and HTTP transports (default $MCP_AUTH_TOKEN)")
 	flag.StringVar(&mode, "mode", "", "Running environment (development, test, production)")
 	flag.Parse()
 
@@ -59,14 +68,27 @@ func main() {
 	lingechoMCP.RegisterDefaultTools(mcpServer)
 
 	// 7. Start server
-	if transport == "sse" {
-		sseServer := server.NewSSEServer(mcpServer.GetServer())
+	var authenticator lingechoMCP.Authenticator
+	if authToken != "" {
+		authenticator = lingechoMCP.StaticTokenAuthenticator(authToken)
+	}
+	switch transport {
+	case lingechoMCP.TransportSSE:
 		log.Info("SSE server listening", zap.String("port", port))
 
-		if err := sseServer.Start(":" + port); err != nil {
+		if err := mcpServer.ServeSSE(":"+port, authenticator); err != nil {
+			logger.Fatal("Server error", zap.Error(err))
+		}
+	case lingechoMCP.TransportHTTP:
+		if err := mcpServer.ServeStreamableHTTP(":"+port, lingechoMCP.StreamableHTTPConfig{
+			EndpointPath:   endpointPath,
+			Stateless:      stateless,
+			SessionIdleTTL: sessionTTL,
+			Authenticator:  authenticator,
+		}); err != nil {
 			logger.Fatal("Server error", zap.Error(err))
 		}
-	} else {
+	default:
 		logger.Info("Starting stdio server")
 		if err := server.ServeStdio(mcpServer.GetServer()); err != nil {
 			logger.Fatal("Server error", zap.Error(err))
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/code-100-precent/LingEcho"
@@ -19,16 +26,23 @@ import (
 	"github.com/code-100-precent/LingEcho/pkg/config"
 	"github.com/code-100-precent/LingEcho/pkg/constants"
 	"github.com/code-100-precent/LingEcho/pkg/graph"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/connector"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/ingest"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"github.com/code-100-precent/LingEcho/pkg/metrics"
 	"github.com/code-100-precent/LingEcho/pkg/middleware"
 	"github.com/code-100-precent/LingEcho/pkg/prompt"
 	"github.com/code-100-precent/LingEcho/pkg/sip"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
+	"github.com/code-100-precent/LingEcho/pkg/synthesizer"
 	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/code-100-precent/LingEcho/pkg/utils/backup"
 	"github.com/code-100-precent/LingEcho/pkg/utils/search"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"gorm.io/gorm"
 )
 
@@ -53,6 +67,11 @@ func (app *LingEchoApp) RegisterRoutes(r *gin.Engine) {
 }
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		os.Exit(runRestoreCommand(os.Args[2:]))
+	}
+
 	// 1. Print Banner
 	if err := bootstrap.PrintBannerFromFile("banner.txt"); err != nil {
 		log.Fatalf("unload banner: %v", err)
@@ -78,6 +97,7 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	defer logger.Sync()
 
 	// 6. Print Configuration
 	bootstrap.LogConfigInfo()
@@ -92,6 +112,13 @@ func main() {
 		logger.Error("database setup failed", zap.Error(err))
 		return
 	}
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				logger.Warn("Failed to close database", zap.Error(err))
+			}
+		}
+	}()
 
 	// 8. Load Base Configs
 	var addr = config.GlobalConfig.Addr
@@ -121,7 +148,6 @@ func main() {
 		logger.Error("failed to initialize cache", zap.Error(err))
 		logger.Info("falling back to default local cache")
 	}
-	utils.InitGlobalCache(1024, 5*time.Minute)
 
 	// Initialize global registration guard
 	utils.InitGlobalRegistrationGuard(logger.Lg)
@@ -135,6 +161,11 @@ func main() {
 	// Initialize global login security manager
 	utils.InitGlobalLoginSecurityManager(logger.Lg)
 
+	// 合成结果缓存：开场白等短句的音频保存在默认存储中，助手可单独关闭
+	if !utils.GetBoolEnv("TTS_RESULT_CACHE_DISABLED") {
+		synthesizer.SetResultCacheStore(stores.Default())
+	}
+
 	// 10. Load Prompt System
 	err = prompt.InitPromptSystem(db)
 	if err != nil {
@@ -146,6 +177,7 @@ func main() {
 
 	// 11.5. Initialize SIP Server (if enabled)
 	// Check if SIP server should be enabled via environment variable
+	var sipServer *sip.SipServer
 	sipEnabled := utils.GetBoolEnv("SIP_ENABLED")
 	if sipEnabled {
 		sipPortInt64 := utils.GetIntEnv("SIP_PORT")
@@ -160,11 +192,20 @@ func main() {
 		}
 		rtpPort := int(rtpPortInt64)
 
-		sipServer := sip.NewSipServer(rtpPort)
+		sipOptions, err := sipServerOptions()
+		if err != nil {
+			panic("invalid SIP TLS/SRTP configuration: " + err.Error())
+		}
+		sipServer = sip.NewSipServerWithOptions(rtpPort, sipOptions)
 		sipServer.SetDBConfig(db)
+		if realm := utils.GetEnv("SIP_REALM"); realm != "" {
+			sipServer.Registrar.Realm = realm
+		}
 
 		// Set SIP server to handlers (wrap to match interface)
 		app.handlers.SetSipServer(sipServer)
+		// Inbound calls to SIP users with an assistant are answered by the voice pipeline
+		sipServer.BridgeHandler = app.handlers.AnswerSIPCall
 
 		// Start SIP server in background (pass empty targetURI to avoid auto-call)
 		go func() {
@@ -172,7 +213,11 @@ func main() {
 			sipServer.Start(sipPort, "") // Empty targetURI means no auto-call
 		}()
 
-		logger.Info("SIP server initialized", zap.Int("sip_port", sipPort), zap.Int("rtp_port", rtpPort))
+		logger.Info("SIP server initialized",
+			zap.Int("sip_port", sipPort),
+			zap.Int("rtp_port", rtpPort),
+			zap.Int("tls_port", sipServer.TLSPort),
+			zap.String("media_encryption", string(sipServer.MediaEncryption)))
 	} else {
 		logger.Info("SIP server is disabled (set SIP_ENABLED=true to enable)")
 	}
@@ -201,6 +246,15 @@ func main() {
 
 	// Tracing feature consumes the most memory, disabled by default
 	enableTracing := utils.GetBoolEnv("METRICS_ENABLE_TRACING")
+	// OpenTelemetry export: setting the OTLP endpoint turns tracing on and ships sampled spans to the collector
+	otlpEndpoint := utils.GetEnv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if otlpEndpoint != "" {
+		enableTracing = true
+	}
+	otelServiceName := utils.GetEnv("OTEL_SERVICE_NAME")
+	if otelServiceName == "" {
+		otelServiceName = "lingecho"
+	}
 	enableSQLAnalysis := utils.GetBoolEnv("METRICS_ENABLE_SQL_ANALYSIS")
 	if !enableSQLAnalysis && utils.GetEnv("METRICS_ENABLE_SQL_ANALYSIS") == "" {
 		enableSQLAnalysis = true // Enable SQL analysis by default
@@ -214,56 +268,90 @@ func main() {
 		EnableMetrics:       true,
 		EnableTracing:       enableTracing,
 		MaxSpans:            maxSpans,
+		TraceSampleRatio:    utils.GetFloatEnv("OTEL_TRACES_SAMPLER_ARG"), // 0 or unset samples everything
 		EnableSQLAnalysis:   enableSQLAnalysis,
 		MaxQueries:          maxQueries,
 		SlowThreshold:       100 * time.Millisecond,
 		EnableSystemMonitor: enableSystemMonitor,
 		MaxStats:            maxStats,
 		MonitorInterval:     30 * time.Second,
+		OTLP: metrics.OTLPConfig{
+			Endpoint:    otlpEndpoint,
+			Headers:     metrics.ParseOTLPHeaders(utils.GetEnv("OTEL_EXPORTER_OTLP_HEADERS")),
+			ServiceName: otelServiceName,
+		},
 	})
 
 	// 13. Set Global Monitor
 	metrics.SetGlobalMonitor(monitor)
 
+	// Record every GORM statement (latency, slow queries, errors) through the monitor
+	if err := db.Use(metrics.NewGormPlugin(monitor)); err != nil {
+		logger.Warn("Failed to register database metrics plugin", zap.Error(err))
+	}
+
 	monitor.Start()
 	defer monitor.Stop()
 
-	// 14. Initialize Neo4j Graph Database (if enabled)
-	if config.GlobalConfig.Neo4jEnabled {
-		graphStore, err := graph.NewNeo4jStore(
-			config.GlobalConfig.Neo4jURI,
-			config.GlobalConfig.Neo4jUsername,
-			config.GlobalConfig.Neo4jPassword,
-			config.GlobalConfig.Neo4jDatabase,
-		)
+	// 14. Initialize Graph Store (Neo4j, SQLite or in-memory, if enabled)
+	if graphStoreType := graph.ResolveStoreType(config.GlobalConfig.GraphStore, config.GlobalConfig.Neo4jEnabled); graphStoreType != "" {
+		graphStore, err := graph.NewStore(graph.StoreConfig{
+			Type:          graphStoreType,
+			Neo4jURI:      config.GlobalConfig.Neo4jURI,
+			Neo4jUsername: config.GlobalConfig.Neo4jUsername,
+			Neo4jPassword: config.GlobalConfig.Neo4jPassword,
+			Neo4jDatabase: config.GlobalConfig.Neo4jDatabase,
+			SQLitePath:    config.GlobalConfig.GraphSQLitePath,
+		})
 		if err != nil {
-			logger.Error("Failed to initialize Neo4j", zap.Error(err))
+			logger.Error("Failed to initialize graph store", zap.String("type", graphStoreType), zap.Error(err))
 			logger.Warn("Graph processing will be disabled")
 			task.InitGraphProcessor(nil, false)
 			graph.SetDefaultStore(nil)
 		} else {
-			logger.Info("Neo4j graph database initialized successfully")
+			logger.Info("Graph store initialized successfully", zap.String("type", graphStoreType))
 			task.InitGraphProcessor(graphStore, true)
 			// 设置全局默认的图存储实例，供实时助手等组件读取用户画像
 			graph.SetDefaultStore(graphStore)
 			defer func() {
 				if err := graphStore.Close(); err != nil {
-					logger.Error("Failed to close Neo4j connection", zap.Error(err))
+					logger.Error("Failed to close graph store", zap.Error(err))
 				}
 			}()
 		}
 	} else {
-		logger.Info("Neo4j is disabled, graph processing will be skipped")
+		logger.Info("Graph store is disabled, graph processing will be skipped")
 		task.InitGraphProcessor(nil, false)
 		graph.SetDefaultStore(nil)
 	}
 
 	// 15. Start Timed task
-	go task.StartOfflineChecker(db)
+	task.StartOfflineChecker(db)
 	// Start Email Cleaner Task
 	task.StartEmailCleaner(db)
+	// Start Recording Cleaner
+	task.StartRecordingCleaner(db)
+	// Start Storage Cleaner for expired files
+	task.StartStorageCleaner(db)
 	// Start Quota Alert Checker
 	task.StartQuotaAlertChecker(db)
+	// Start Monthly Statement Generator
+	task.StartStatementGenerator(db)
+	// Start Graph Memory Decay
+	task.StartGraphMemoryDecay(db)
+	// Start Webhook Dispatcher
+	task.StartWebhookDispatcher(db)
+	// Start outbound SIP campaign dispatcher
+	if sipServer != nil {
+		task.StartSipCampaignDispatcher(db, sipServer)
+	}
+	// Start dynamic MCP tool sync
+	go task.StartMCPToolSync(app.handlers.GetMCPTools())
+	// Start knowledge base sync connectors
+	if config.GlobalConfig.KnowledgeBaseEnabled {
+		connector.SetLocalRoot(config.GlobalConfig.KnowledgeSyncDir)
+		task.StartKnowledgeSync(db)
+	}
 	// Start Backup Data
 	if config.GlobalConfig.BackupEnabled {
 		backup.StartBackupScheduler()
@@ -305,9 +393,33 @@ func main() {
 	r.Use(middleware.LoggerMiddleware(zap.L()))
 
 	// RateLimit Middleware - Loosen rate limiting configuration
+	// RATE_LIMIT_RATE can be changed at runtime through the config watcher
+	rateLimit := utils.GetEnv("RATE_LIMIT_RATE")
+	if rateLimit == "" {
+		rateLimit = "1000-M" // 1000 requests per minute, much more relaxed than the default 10 per second
+	}
+	// Count logged-in users and API keys separately so users behind a shared NAT don't exhaust each other's limit
+	rateLimitIdentifier := utils.GetEnv("RATE_LIMIT_IDENTIFIER")
+	if rateLimitIdentifier == "" {
+		rateLimitIdentifier = "identity"
+	}
+	// Share counters between replicas when the cache is backed by Redis
+	if config.GlobalConfig.Cache.Type == "redis" {
+		store, err := middleware.NewRedisStore(cache.NewRedisClient(config.GlobalConfig.Cache.Redis), "ratelimit")
+		if err != nil {
+			logger.Warn("failed to create redis rate limit store, using in-memory counters", zap.Error(err))
+		} else {
+			middleware.SetRateLimiterStore(store)
+		}
+	}
+	middleware.SetRateLimiterIdentityFunc(func(c *gin.Context) middleware.RateLimitIdentity {
+		key, plan := models.ResolveRateLimitIdentity(c, db)
+		return middleware.RateLimitIdentity{Key: key, Plan: plan}
+	})
 	middleware.SetRateLimiterConfig(middleware.RateLimiterConfig{
-		Rate:        "1000-M", // 1000 requests per minute, much more relaxed than the default 10 per second
-		Identifier:  "ip",
+		Rate:        rateLimit,
+		PlanRates:   middleware.ParsePlanRates(utils.GetEnv("RATE_LIMIT_PLAN_RATES")),
+		Identifier:  rateLimitIdentifier,
 		AddHeaders:  true,
 		DenyStatus:  429,
 		DenyMessage: "Requests too frequent, please try again later",
@@ -334,15 +446,19 @@ func main() {
 	if uploadDir == "" {
 		uploadDir = "./uploads"
 	}
-	// 注册 /uploads（主路径）并保留 /media 兼容历史
-	r.Static("/uploads", uploadDir)
-	r.Static("/media", uploadDir)
+	// 注册 /uploads（主路径）并保留 /media 兼容历史，访问前均校验签名地址
 	apiPrefix := config.GlobalConfig.APIPrefix
 	if apiPrefix == "" {
 		apiPrefix = "/api"
 	}
-	r.Static(apiPrefix+"/uploads", uploadDir)
-	r.Static(apiPrefix+"/media", uploadDir)
+	registerMediaRoutes(r.Group("/uploads", middleware.SignedMediaMiddleware()), uploadDir)
+	registerMediaRoutes(r.Group(apiPrefix+"/uploads", middleware.SignedMediaMiddleware()), uploadDir)
+	registerMediaRoutes(r.Group("/media", middleware.SignedMediaMiddleware()), uploadDir)
+	registerMediaRoutes(r.Group(apiPrefix+"/media", middleware.SignedMediaMiddleware()), uploadDir)
+	// Signed URLs point at MEDIA_PREFIX, so serve it as well when it is a custom prefix
+	if prefix := stores.MediaRoutePrefix(); prefix != "/media" && prefix != "/uploads" {
+		registerMediaRoutes(r.Group(prefix, middleware.SignedMediaMiddleware()), uploadDir)
+	}
 
 	// Add /api/static route to serve static files under API prefix
 	// This is needed for SDK files accessed via /api/static/js/lingecho-sdk.js
@@ -373,15 +489,24 @@ func main() {
 	}
 	// Combine API prefix with monitor prefix: /api/metrics
 	fullMonitorPrefix := apiPrefix + monitorPrefix
-	monitorGroup := r.Group(fullMonitorPrefix)
+	// Monitoring data is limited to users with the metrics.read permission
+	monitorGroup := r.Group(fullMonitorPrefix, middleware.InjectDB(db), models.AuthRequired, models.PermissionRequired(models.PermissionMetricsRead))
 	monitorAPI := metrics.NewMonitorAPI(monitor)
 	monitorAPI.RegisterRoutes(monitorGroup)
+	// Last-run status of the background tasks in internal/task
+	monitorGroup.GET("/tasks", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": task.Statuses()})
+	})
 	logger.Info("Metrics monitor routes registered", zap.String("prefix", fullMonitorPrefix))
 
+	// Prometheus scrape endpoint at the conventional root path (skipped by the rate limiter)
+	r.GET("/metrics", metrics.PrometheusHandler(utils.GetEnv("METRICS_SCRAPE_TOKEN")))
+
 	// 19. Initialize System Listener
 	// Initialize system listener (pass in database connection)
 	listeners.InitLLMListenerWithDB(db)
 	listeners.InitBillingListenerWithDB(db)
+	listeners.InitCallDetailRecordListener(db)
 	listeners.InitSystemListeners()
 
 	// 20. Start Search Indexer (if enabled)
@@ -397,6 +522,9 @@ func main() {
 			searchEngine = app.handlers.GetSearchHandler().GetEngine()
 		}
 		if searchEngine != nil {
+			// Keyword side of hybrid knowledge base retrieval
+			knowledge.SetDefaultKeywordSearcher(task.NewKnowledgeKeywordSearcher(searchEngine))
+			ingest.SetDefaultKeywordIndexer(task.NewKnowledgeChunkIndexer(searchEngine))
 			// Start scheduled task
 			task.StartSearchIndexer(db, searchEngine)
 			// Asynchronously execute initial indexing (delayed execution to avoid memory spikes at startup)
@@ -418,6 +546,20 @@ func main() {
 	// 21. Emit system initialization signal
 	utils.Sig().Emit(models.SigInitSystemConfig, nil)
 
+	// Watch the .env file and the config table; listeners reconfigure on config.SigConfigChanged
+	configWatcher := config.NewWatcher(db, config.GlobalConfig.ConfigWatchInterval)
+	configWatcher.Start()
+	defer configWatcher.Stop()
+	// SIGHUP reloads immediately instead of waiting for the next check
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	defer signal.Stop(reloadSignals)
+	go func() {
+		for range reloadSignals {
+			configWatcher.Check()
+		}
+	}()
+
 	// 21.5. Start Workflow Event Listener and Scheduler
 	// Start workflow event listener
 	eventListener := workflowdef.NewWorkflowEventListener(db)
@@ -436,6 +578,10 @@ func main() {
 	}
 
 	// 22. Start HTTP/HTTPS Server
+	// Every request context derives from baseCtx; cancelling it ends WebSocket calls
+	// that are still running when the drain deadline passes
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
 	httpServer := &http.Server{
 		Addr:           addr,
 		Handler:        r,
@@ -443,32 +589,171 @@ func main() {
 		WriteTimeout:   30 * time.Second,
 		IdleTimeout:    120 * time.Second,
 		MaxHeaderBytes: 1 << 20, // 1MB
+		BaseContext:    func(net.Listener) context.Context { return baseCtx },
 	}
 
-	// Check if SSL is enabled
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- listenAndServe(httpServer)
+	}()
+
+	// Embedded and native clients can use gRPC signaling instead of the WebSocket endpoint
+	grpcServer, err := startVoiceGRPC(app.handlers)
+	if err != nil {
+		logger.Error("Failed to start gRPC signaling server", zap.Error(err))
+	}
+
+	// 23. Wait for SIGINT/SIGTERM, then drain
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	select {
+	case err := <-serverErr:
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("HTTP server run failed", zap.Error(err))
+		}
+	case <-signalCtx.Done():
+		logger.Info("Shutdown signal received, draining connections")
+	}
+	// A second signal terminates immediately
+	stopSignals()
+
+	drainTimeout := time.Duration(utils.GetIntEnv("SHUTDOWN_TIMEOUT_SECONDS")) * time.Second
+	if drainTimeout <= 0 {
+		drainTimeout = 30 * time.Second
+	}
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancelDrain()
+
+	// New calls are refused while existing ones finish; schedulers stop taking new runs at the same time
+	steps := map[string]func(context.Context) error{
+		"http server":        httpServer.Shutdown,
+		"realtime sessions":  app.handlers.Shutdown,
+		"workflow scheduler": scheduler.Shutdown,
+		"scheduled tasks":    task.StopSchedulers,
+		"backup scheduler":   backup.StopBackupScheduler,
+	}
+	if sipServer != nil {
+		steps["sip server"] = sipServer.Shutdown
+	}
+	if grpcServer != nil {
+		steps["grpc signaling"] = func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				grpcServer.Stop()
+				return ctx.Err()
+			}
+		}
+	}
+	var wg sync.WaitGroup
+	for name, step := range steps {
+		wg.Add(1)
+		go func(name string, step func(context.Context) error) {
+			defer wg.Done()
+			if err := step(drainCtx); err != nil {
+				logger.Warn("Shutdown step did not finish cleanly", zap.String("step", name), zap.Error(err))
+			}
+		}(name, step)
+	}
+	wg.Wait()
+	cancelBase()
+	logger.Info("Server stopped")
+	// Deferred calls stop the monitor, close the graph store and the database, then flush the logs
+}
+
+// registerMediaRoutes serves signed media URLs from the upload dir, or from
+// object storage when STORAGE_KIND is not local
+func registerMediaRoutes(group *gin.RouterGroup, uploadDir string) {
+	if stores.DefaultStoreKind == stores.KindLocal {
+		group.Static("/", uploadDir)
+		return
+	}
+	serve := func(c *gin.Context) {
+		stores.ServeObject(c.Writer, c.Request, stores.Default(), c.Param("filepath"))
+	}
+	group.GET("/*filepath", serve)
+	group.HEAD("/*filepath", serve)
+}
+
+// listenAndServe serves HTTPS when SSL is configured, plain HTTP otherwise,
+// until the server is shut down
+func listenAndServe(httpServer *http.Server) error {
 	if config.GlobalConfig.SSLEnabled && listeners.IsSSLEnabled() {
 		tlsConfig, err := listeners.GetTLSConfig()
 		if err != nil {
-			logger.Error("failed to get TLS config", zap.Error(err))
-			return
+			return fmt.Errorf("failed to get TLS config: %w", err)
 		}
-
 		if tlsConfig != nil {
 			httpServer.TLSConfig = tlsConfig
-			logger.Info("Starting HTTPS server", zap.String("addr", addr))
-			if err := httpServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
-				logger.Error("HTTPS server run failed", zap.Error(err))
-			}
-		} else {
-			logger.Warn("SSL enabled but TLS config is nil, falling back to HTTP")
-			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				logger.Error("HTTP server run failed", zap.Error(err))
-			}
+			logger.Info("Starting HTTPS server", zap.String("addr", httpServer.Addr))
+			return httpServer.ListenAndServeTLS("", "")
 		}
+		logger.Warn("SSL enabled but TLS config is nil, falling back to HTTP")
 	} else {
-		logger.Info("Starting HTTP server", zap.String("addr", addr))
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("HTTP server run failed", zap.Error(err))
+		logger.Info("Starting HTTP server", zap.String("addr", httpServer.Addr))
+	}
+	return httpServer.ListenAndServe()
+}
+
+// sipServerOptions reads the SIPS listener certificate, the TLS settings for
+// dialing sips: targets and the default media encryption of bridged calls
+func sipServerOptions() (sip.SipServerOptions, error) {
+	var opts sip.SipServerOptions
+	certFile, keyFile := utils.GetEnv("SIP_TLS_CERT_FILE"), utils.GetEnv("SIP_TLS_KEY_FILE")
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return opts, fmt.Errorf("load SIP TLS certificate: %w", err)
 		}
+		opts.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		opts.TLSPort = int(utils.GetIntEnv("SIP_TLS_PORT"))
+	}
+	if utils.GetBoolEnv("SIP_TLS_SKIP_VERIFY") {
+		// Carriers are often dialed by IP, which their certificates do not cover
+		opts.TLSClientConfig = &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12}
 	}
+	encryption, err := sip.ParseMediaEncryption(utils.GetEnv("SIP_MEDIA_ENCRYPTION"))
+	if err != nil {
+		return opts, err
+	}
+	opts.MediaEncryption = encryption
+	return opts, nil
+}
+
+// startVoiceGRPC serves gRPC voice signaling on VOICE_GRPC_ADDR, with the
+// same TLS settings as the HTTP server. It returns nil when no address is set
+func startVoiceGRPC(h *handlers.Handlers) (*grpc.Server, error) {
+	addr := config.GlobalConfig.VoiceGRPCAddr
+	if addr == "" {
+		return nil, nil
+	}
+	var opts []grpc.ServerOption
+	if config.GlobalConfig.SSLEnabled && listeners.IsSSLEnabled() {
+		tlsConfig, err := listeners.GetTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get TLS config: %w", err)
+		}
+		if tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	grpcServer := grpc.NewServer(opts...)
+	h.RegisterVoiceGRPC(grpcServer)
+	go func() {
+		logger.Info("Starting gRPC signaling server", zap.String("addr", addr))
+		if err := grpcServer.Serve(lis); err != nil {
+			logger.Error("gRPC signaling server stopped", zap.Error(err))
+		}
+	}()
+	return grpcServer, nil
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/utils/backup"
+)
+
+// runRestoreCommand implements `server restore`, which validates and restores a database backup.
+//
+//	server restore -list [-from s3]
+//	server restore -name latest -dry-run
+//	server restore -name sys_backup_20250101_020000.db.enc -from s3 -yes
+func runRestoreCommand(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	mode := fs.String("mode", "", "running environment (development, test, production)")
+	from := fs.String("from", "local", "backup source: local or s3")
+	name := fs.String("name", "", "backup name to restore, or \"latest\"")
+	list := fs.Bool("list", false, "list available backups and exit")
+	dryRun := fs.Bool("dry-run", false, "download and validate the backup without restoring it")
+	yes := fs.Bool("yes", false, "confirm overwriting the current database")
+	driver := fs.String("db-driver", "", "target database driver (defaults to DB_DRIVER)")
+	dsn := fs.String("dsn", "", "target database source name (defaults to DSN)")
+	timeout := fs.Duration("timeout", 30*time.Minute, "maximum duration of the restore")
+	fs.Parse(args)
+
+	if *mode != "" {
+		os.Setenv("APP_ENV", *mode)
+	}
+	if err := config.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "config load failed: %v\n", err)
+		return 1
+	}
+	cfg := config.GlobalConfig
+
+	var source backup.Destination
+	switch *from {
+	case "local":
+		source = backup.LocalDestinationFromConfig(cfg)
+	case "s3":
+		if source = backup.RemoteDestinationFromConfig(cfg); source == nil {
+			fmt.Fprintln(os.Stderr, "S3 backup destination is not configured (BACKUP_S3_ENDPOINT, BACKUP_S3_BUCKET)")
+			return 1
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown backup source: %s\n", *from)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if *list {
+		objects, err := source.List(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list backups: %v\n", err)
+			return 1
+		}
+		for _, obj := range objects {
+			fmt.Printf("%-48s %12d  %s\n", obj.Name, obj.Size, obj.CreatedAt.Format(time.RFC3339))
+		}
+		return 0
+	}
+
+	if *name == "" {
+		fmt.Fprintln(os.Stderr, "-name is required (use -list to see available backups)")
+		return 2
+	}
+	if !*dryRun && !*yes {
+		fmt.Fprintln(os.Stderr, "restoring overwrites the current database; stop the server and re-run with -yes, or use -dry-run to only validate")
+		return 2
+	}
+
+	opts := backup.RestoreOptions{
+		Source:        source,
+		Name:          *name,
+		Driver:        cfg.DBDriver,
+		DSN:           cfg.DSN,
+		EncryptionKey: cfg.BackupEncryptionKey,
+		DryRun:        *dryRun,
+	}
+	if *driver != "" {
+		opts.Driver = *driver
+	}
+	if *dsn != "" {
+		opts.DSN = *dsn
+	}
+
+	result, err := backup.Restore(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore failed: %v\n", err)
+		return 1
+	}
+	if *dryRun {
+		fmt.Printf("backup %s (%d bytes) is valid\n", result.Name, result.Size)
+		return 0
+	}
+	fmt.Printf("restored backup %s (%d bytes)\n", result.Name, result.Size)
+	if result.SafetyCopy != "" {
+		fmt.Printf("previous database kept at %s\n", result.SafetyCopy)
+	}
+	return 0
+}
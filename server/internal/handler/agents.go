@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/agent"
+	"github.com/code-100-precent/LingEcho/pkg/graph"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
+	"github.com/code-100-precent/LingEcho/pkg/llm"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// RunAgentsRequest 多agent编排请求
+type RunAgentsRequest struct {
+	Content         string                 `json:"content" binding:"required"`
+	CredentialID    uint                   `json:"credentialId" binding:"required"` // 规划、合并与LLM子任务使用的凭证
+	AssistantID     uint                   `json:"assistantId"`                     // 可选，使用助手的知识库、图记忆、提示词与模型
+	KnowledgeBaseID string                 `json:"knowledgeBaseId"`                 // 可选，覆盖助手的知识库
+	GraphMemory     *bool                  `json:"graphMemory"`                     // 可选，覆盖助手的图记忆开关
+	SessionID       string                 `json:"sessionId"`
+	Model           string                 `json:"model"`
+	Parameters      map[string]interface{} `json:"parameters"`
+}
+
+// RunAgents 多agent编排
+// @Summary 多agent编排
+// @Description 由规划agent分解请求，并行分派子任务给RAG、图记忆、LLM等agents并合并结果
+// @Tags Agents
+// @Accept json
+// @Produce json
+// @Param request body RunAgentsRequest true "编排请求"
+// @Success 200 {object} response.Response
+// @Router /api/agents/run [post]
+func (h *Handlers) RunAgents(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "Unauthorized", "User not logged in")
+		return
+	}
+
+	var req RunAgentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request: "+err.Error(), nil)
+		return
+	}
+
+	cred, err := models.GetUserCredentialByID(h.db, user.ID, req.CredentialID)
+	if err != nil || cred == nil {
+		response.Fail(c, "Credential not found", nil)
+		return
+	}
+
+	// 助手、知识库必须属于当前用户
+	taskContext := &agent.TaskContext{
+		UserID:    user.ID,
+		SessionID: req.SessionID,
+	}
+	systemPrompt := "请用中文回复用户的问题。"
+	model := req.Model
+	if req.AssistantID > 0 {
+		var assistant models.Assistant
+		if err := h.db.First(&assistant, req.AssistantID).Error; err != nil {
+			response.Fail(c, "Assistant not found", nil)
+			return
+		}
+		if assistant.UserID != user.ID && assistant.GroupID == nil {
+			response.Fail(c, "Permission denied: assistant does not belong to you", nil)
+			return
+		}
+		taskContext.AssistantID = assistant.ID
+		taskContext.GraphMemoryEnabled = assistant.EnableGraphMemory
+		if assistant.KnowledgeBaseID != nil {
+			taskContext.KnowledgeBaseID = *assistant.KnowledgeBaseID
+		}
+		if assistant.SystemPrompt != "" {
+			systemPrompt = assistant.SystemPrompt
+		}
+		if model == "" {
+			model = assistant.LLMModel
+		}
+	}
+	if req.KnowledgeBaseID != "" {
+		k, err := models.GetKnowledge(h.db, req.KnowledgeBaseID)
+		if err != nil || k.UserID != int(user.ID) {
+			response.Fail(c, "Knowledge base not found", nil)
+			return
+		}
+		taskContext.KnowledgeBaseID = req.KnowledgeBaseID
+	}
+	if req.GraphMemory != nil {
+		taskContext.GraphMemoryEnabled = *req.GraphMemory
+	}
+	if taskContext.GraphMemoryEnabled && taskContext.AssistantID == 0 {
+		response.Fail(c, "Graph memory requires an assistant", nil)
+		return
+	}
+
+	provider, err := llm.NewLLMProvider(c.Request.Context(), cred, systemPrompt)
+	if err != nil {
+		response.Fail(c, fmt.Sprintf("初始化LLM失败: %v", err), nil)
+		return
+	}
+	defer provider.Hangup()
+
+	manager, err := agent.NewManager(&agent.Config{
+		DB:          h.db,
+		GraphStore:  graph.GetDefaultStore(),
+		KBManager:   knowledge.GetManager(),
+		LLMProvider: provider,
+		Logger:      logger.Lg,
+	})
+	if err != nil {
+		response.Fail(c, "Failed to initialize agents: "+err.Error(), nil)
+		return
+	}
+
+	parameters := req.Parameters
+	if parameters == nil {
+		parameters = make(map[string]interface{})
+	}
+	if model != "" {
+		parameters["model"] = model
+	}
+
+	result, err := manager.Run(c.Request.Context(), &agent.TaskRequest{
+		ID:         fmt.Sprintf("api_run_%d", time.Now().UnixNano()),
+		Content:    req.Content,
+		Context:    taskContext,
+		Parameters: parameters,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		response.Fail(c, "Task processing failed: "+err.Error(), nil)
+		return
+	}
+	if !result.Success {
+		response.Fail(c, "Task processing failed: "+result.Error, result.Data)
+		return
+	}
+
+	response.Success(c, "success", gin.H{
+		"taskId":         result.ID,
+		"content":        result.Content,
+		"data":           result.Data,
+		"agentId":        result.AgentID,
+		"processingTime": result.ProcessingTime.Milliseconds(),
+	})
+}
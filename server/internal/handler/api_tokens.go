@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CreateAPITokenRequest 签发API令牌请求
+type CreateAPITokenRequest struct {
+	Name      string `json:"name"`
+	Scopes    string `json:"scopes" binding:"required"` // 如 "voice:oneshot,tts:synthesize"
+	ExpiresIn int64  `json:"expiresIn"`                 // 有效期（秒），0 表示永不过期
+}
+
+// handleCreateAPIToken 为凭证签发带调用范围的API令牌，令牌明文只在此处返回一次
+func (h *Handlers) handleCreateAPIToken(c *gin.Context) {
+	user := models.CurrentUser(c)
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid credential ID", err)
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ExpiresIn < 0 {
+		response.Fail(c, "Invalid request", nil)
+		return
+	}
+	scopes, err := models.ParseAPIScopes(req.Scopes)
+	if err != nil {
+		response.Fail(c, "Invalid scopes", err.Error())
+		return
+	}
+
+	credential, err := models.GetUserCredentialByID(h.db, user.ID, uint(credentialID))
+	if err != nil {
+		response.Fail(c, "Failed to load credential", err)
+		return
+	}
+	if credential == nil {
+		response.Fail(c, "Credential not found", nil)
+		return
+	}
+
+	raw, token, err := models.IssueAPIToken(h.db, credential, req.Name, scopes, time.Duration(req.ExpiresIn)*time.Second)
+	if err != nil {
+		response.Fail(c, "Failed to create API token", err)
+		return
+	}
+	h.recordAudit(c, models.AuditActionAPITokenCreate, "api_token", token.ID, nil, token)
+	response.Success(c, "API token created successfully", gin.H{
+		"token":     raw,
+		"id":        token.ID,
+		"name":      token.Name,
+		"scopes":    token.Scopes,
+		"expiresAt": token.ExpiresAt,
+	})
+}
+
+// handleListAPITokens 获取凭证签发的API令牌，包括已吊销的
+func (h *Handlers) handleListAPITokens(c *gin.Context) {
+	user := models.CurrentUser(c)
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid credential ID", err)
+		return
+	}
+	tokens, err := models.GetAPITokens(h.db, user.ID, uint(credentialID))
+	if err != nil {
+		response.Fail(c, "Failed to list API tokens", err)
+		return
+	}
+	response.Success(c, "success", tokens)
+}
+
+// handleRevokeAPIToken 吊销API令牌，吊销后立即失效
+func (h *Handlers) handleRevokeAPIToken(c *gin.Context) {
+	user := models.CurrentUser(c)
+	tokenID, err := strconv.ParseUint(c.Param("tokenId"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid token ID", err)
+		return
+	}
+	var before models.APIToken
+	h.db.Where("id = ? AND user_id = ?", tokenID, user.ID).First(&before)
+	if err := models.RevokeAPIToken(h.db, user.ID, uint(tokenID)); err != nil {
+		response.Fail(c, "API token not found or already revoked", nil)
+		return
+	}
+	after := before
+	h.db.First(&after, before.ID)
+	h.recordAudit(c, models.AuditActionAPITokenRevoke, "api_token", tokenID, &before, &after)
+	response.Success(c, "API token revoked successfully", nil)
+}
+
+// oauthTokenError 按 RFC 6749 第 5.2 节返回令牌接口错误
+func oauthTokenError(c *gin.Context, status int, code, description string) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{"error": code, "error_description": description})
+}
+
+// handleOAuthToken OAuth2 client_credentials 令牌接口：以 apiKey/apiSecret 作为客户端ID与密钥换取短期的 Bearer 令牌，
+// 服务端换取令牌后下发给浏览器等不可信环境，凭证密钥不再出现在请求体中
+func (h *Handlers) handleOAuthToken(c *gin.Context) {
+	if grantType := c.PostForm("grant_type"); grantType != "client_credentials" {
+		oauthTokenError(c, http.StatusBadRequest, "unsupported_grant_type", "only client_credentials is supported")
+		return
+	}
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID, clientSecret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+	if clientID == "" || clientSecret == "" {
+		oauthTokenError(c, http.StatusUnauthorized, "invalid_client", "client credentials are required")
+		return
+	}
+
+	credential, err := models.GetUserCredentialByApiSecretAndApiKey(h.db, clientID, clientSecret)
+	if err != nil {
+		logger.Error("Failed to load credential for token request", zap.Error(err))
+		oauthTokenError(c, http.StatusInternalServerError, "server_error", "failed to load credential")
+		return
+	}
+	if credential == nil {
+		oauthTokenError(c, http.StatusUnauthorized, "invalid_client", "invalid client credentials")
+		return
+	}
+
+	scopes, err := models.ParseAPIScopes(c.PostForm("scope"))
+	if err != nil {
+		oauthTokenError(c, http.StatusBadRequest, "invalid_scope", err.Error())
+		return
+	}
+
+	ttl := config.GlobalConfig.APITokenTTL
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	raw, _, err := models.IssueAPIToken(h.db, credential, "client_credentials", scopes, ttl)
+	if err != nil {
+		logger.Error("Failed to issue API token", zap.Uint("credentialID", credential.ID), zap.Error(err))
+		oauthTokenError(c, http.StatusInternalServerError, "server_error", "failed to issue token")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": raw,
+		"token_type":   "Bearer",
+		"expires_in":   int64(ttl / time.Second),
+		"scope":        strings.Join(scopes, " "),
+	})
+}
+
+// requestCredential 返回本次请求使用的凭证：使用API令牌时为签发令牌的凭证，否则按 apiKey/apiSecret 查找。
+// 找不到凭证时返回 nil
+func (h *Handlers) requestCredential(c *gin.Context, apiKey, apiSecret string) (*models.UserCredential, error) {
+	if token := models.CurrentAPIToken(c); token != nil {
+		return models.GetUserCredentialByID(h.db, token.UserID, token.CredentialID)
+	}
+	if apiKey == "" || apiSecret == "" {
+		return nil, nil
+	}
+	return models.GetUserCredentialByApiSecretAndApiKey(h.db, apiKey, apiSecret)
+}
@@ -15,12 +15,13 @@ import (
 	"github.com/code-100-precent/LingEcho/pkg/llm"
 	"github.com/code-100-precent/LingEcho/pkg/llm/apis"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
+	lingechoMCP "github.com/code-100-precent/LingEcho/pkg/mcp"
 	"go.uber.org/zap"
 )
 
-// LoadAssistantToolsToHandler loads assistant tools from database and registers them to LLMHandler
+// LoadAssistantToolsToHandler loads assistant tools from database and registers them to the LLM provider
 // Each assistant has an independent tool set, and tool names are unique within an assistant
-func (h *Handlers) LoadAssistantToolsToHandler(handler *llm.LLMHandler, assistantID int64) error {
+func (h *Handlers) LoadAssistantToolsToHandler(handler llm.LLMProvider, assistantID int64) error {
 	// Get all enabled tools for the assistant
 	tools, err := models.GetAssistantTools(h.db, assistantID)
 	if err != nil {
@@ -65,8 +66,32 @@ func (h *Handlers) LoadAssistantToolsToHandler(handler *llm.LLMHandler, assistan
 	return nil
 }
 
+// loadConversationTools registers the tools the model may call while answering
+// the assistant's caller: the assistant's own tools and workflows, plus the
+// built-in MCP tools when the assistant enables them. Failures only lose the
+// tools, the conversation goes on without them.
+func (h *Handlers) loadConversationTools(provider llm.LLMProvider, assistantID int64, enableMCP bool) {
+	if err := h.LoadAssistantToolsToHandler(provider, assistantID); err != nil {
+		logger.Warn("Failed to load assistant tools",
+			zap.Int64("assistantID", assistantID),
+			zap.Error(err))
+	}
+	if !enableMCP {
+		return
+	}
+	count, err := llm.RegisterMCPTools(provider, lingechoMCP.Default(), nil)
+	if err != nil {
+		logger.Warn("Failed to register MCP tools",
+			zap.Int64("assistantID", assistantID),
+			zap.Error(err))
+	}
+	logger.Info("Registered MCP tools",
+		zap.Int64("assistantID", assistantID),
+		zap.Int("toolCount", count))
+}
+
 // LoadWorkflowToolsToHandler loads workflows that can be called by the assistant as tools
-func (h *Handlers) LoadWorkflowToolsToHandler(handler *llm.LLMHandler, assistantID int64) error {
+func (h *Handlers) LoadWorkflowToolsToHandler(handler llm.LLMProvider, assistantID int64) error {
 	// Get all active workflows
 	var workflows []models.WorkflowDefinition
 	if err := h.db.Where("status = ?", "active").Find(&workflows).Error; err != nil {
@@ -74,9 +99,15 @@ func (h *Handlers) LoadWorkflowToolsToHandler(handler *llm.LLMHandler, assistant
 	}
 
 	workflowCount := 0
-	for _, wf := range workflows {
+	for i := range workflows {
+		// Tools are built from the published version, drafts stay private to the editor
+		wf, err := workflowdef.ResolvePublishedDefinition(h.db, &workflows[i])
+		if err != nil {
+			continue
+		}
+
 		// Parse trigger config
-		config, err := workflowdef.ParseTriggerConfig(&wf)
+		config, err := workflowdef.ParseTriggerConfig(wf)
 		if err != nil {
 			continue
 		}
@@ -582,7 +613,7 @@ func findRightOperand(expr string, opIndex int) int {
 
 // ReloadAssistantTools reloads assistant tools
 // Note: Since LLMHandler is created for each request, this method is mainly used for testing or management scenarios
-func (h *Handlers) ReloadAssistantTools(handler *llm.LLMHandler, assistantID int64) error {
+func (h *Handlers) ReloadAssistantTools(handler llm.LLMProvider, assistantID int64) error {
 	// Directly load new tools, if name conflicts occur, they will be overwritten
 	return h.LoadAssistantToolsToHandler(handler, assistantID)
 }
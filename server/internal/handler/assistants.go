@@ -162,6 +162,7 @@ func (h *Handlers) UpdateAssistant(c *gin.Context) {
 		MaxTokens            int      `json:"maxTokens"`
 		Language             string   `json:"language"`
 		Speaker              string   `json:"speaker"`
+		Greeting             *string  `json:"greeting"` // 开场白，空字符串恢复默认问候语
 		VoiceCloneId         *int     `json:"voiceCloneId"`
 		KnowledgeBaseId      *string  `json:"knowledgeBaseId"`
 		TtsProvider          string   `json:"ttsProvider"`
@@ -169,9 +170,14 @@ func (h *Handlers) UpdateAssistant(c *gin.Context) {
 		ApiSecret            string   `json:"apiSecret"`
 		LLMModel             string   `json:"llmModel"` // LLM model name
 		EnableGraphMemory    *bool    `json:"enableGraphMemory"`
+		EnableMCPTools       *bool    `json:"enableMCPTools"`       // 对话中允许调用内置 MCP 工具
 		EnableVAD            *bool    `json:"enableVAD"`            // 是否启用VAD
 		VADThreshold         *float64 `json:"vadThreshold"`         // VAD阈值
 		VADConsecutiveFrames *int     `json:"vadConsecutiveFrames"` // VAD连续帧数
+		TurnSilenceMs        *int     `json:"turnSilenceMs"`        // 静音判定说完的阈值（毫秒）
+		TurnMaxUtteranceMs   *int     `json:"turnMaxUtteranceMs"`   // 单轮发言最长时长（毫秒）
+		TurnEndOnSentence    *bool    `json:"turnEndOnSentence"`    // 完整句子即回答
+		TTSCacheEnabled      *bool    `json:"ttsCacheEnabled"`      // 复用已合成的短句音频
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		response.Fail(c, "invalid request", "parameter error")
@@ -189,6 +195,8 @@ func (h *Handlers) UpdateAssistant(c *gin.Context) {
 		return
 	}
 
+	before := auditSnapshot(assistant)
+
 	// Update fields
 	updateData := map[string]interface{}{
 		"updated_at": time.Now(),
@@ -222,6 +230,9 @@ func (h *Handlers) UpdateAssistant(c *gin.Context) {
 	if input.Speaker != "" {
 		updateData["speaker"] = input.Speaker
 	}
+	if input.Greeting != nil {
+		updateData["greeting"] = strings.TrimSpace(*input.Greeting)
+	}
 	if input.VoiceCloneId != nil {
 		updateData["voice_clone_id"] = input.VoiceCloneId
 	}
@@ -252,6 +263,29 @@ func (h *Handlers) UpdateAssistant(c *gin.Context) {
 	if input.VADConsecutiveFrames != nil {
 		updateData["vad_consecutive_frames"] = *input.VADConsecutiveFrames
 	}
+	if input.TurnSilenceMs != nil {
+		if *input.TurnSilenceMs < 0 {
+			response.Fail(c, "invalid request", "turnSilenceMs must not be negative")
+			return
+		}
+		updateData["turn_silence_ms"] = *input.TurnSilenceMs
+	}
+	if input.TurnMaxUtteranceMs != nil {
+		if *input.TurnMaxUtteranceMs < 0 {
+			response.Fail(c, "invalid request", "turnMaxUtteranceMs must not be negative")
+			return
+		}
+		updateData["turn_max_utterance_ms"] = *input.TurnMaxUtteranceMs
+	}
+	if input.TurnEndOnSentence != nil {
+		updateData["turn_end_on_sentence"] = *input.TurnEndOnSentence
+	}
+	if input.EnableMCPTools != nil {
+		updateData["enable_mcp_tools"] = *input.EnableMCPTools
+	}
+	if input.TTSCacheEnabled != nil {
+		updateData["tts_cache_enabled"] = *input.TTSCacheEnabled
+	}
 
 	if err := h.db.Model(&assistant).Where("id = ?", id).Updates(updateData).Error; err != nil {
 		response.Fail(c, "update failed", "Update failed")
@@ -263,6 +297,7 @@ func (h *Handlers) UpdateAssistant(c *gin.Context) {
 		response.Fail(c, "update failed", "Failed to query updated data")
 		return
 	}
+	h.recordAudit(c, models.AuditActionAssistantUpdate, "assistant", assistant.ID, before, &assistant)
 
 	response.Success(c, "Update successful", assistant)
 }
@@ -334,12 +369,6 @@ func (h *Handlers) GetAssistantGraphData(c *gin.Context) {
 		return
 	}
 
-	// 检查是否启用了 Neo4j
-	if !config.GlobalConfig.Neo4jEnabled {
-		response.Fail(c, "Neo4j not enabled", "Neo4j is not enabled in the system")
-		return
-	}
-
 	// 检查助手是否启用了图记忆
 	if !assistant.EnableGraphMemory {
 		response.Fail(c, "Graph memory not enabled", "Graph memory is not enabled for this assistant")
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// recordAudit 记录当前用户的敏感操作，before/after 为变更前后的对象，创建时 before 为 nil，删除时 after 为 nil。
+// 写入失败只记录日志，不影响已完成的操作
+func (h *Handlers) recordAudit(c *gin.Context, action, resourceType string, resourceID interface{}, before, after interface{}) {
+	entry := &models.AuditLog{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   fmt.Sprint(resourceID),
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+	}
+	if user := models.CurrentUser(c); user != nil {
+		entry.ActorID = user.ID
+		entry.ActorEmail = user.Email
+	}
+	if err := models.RecordAuditLog(h.db, entry, before, after); err != nil {
+		logger.Error("Failed to record audit log",
+			zap.String("action", action),
+			zap.String("resourceType", resourceType),
+			zap.String("resourceId", entry.ResourceID),
+			zap.Error(err))
+	}
+}
+
+// auditSnapshot 立即序列化变更前的对象，之后的更新可能改写其中共享的指针字段
+func auditSnapshot(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// handleListAuditLogs 分页查询审计日志
+func (h *Handlers) handleListAuditLogs(c *gin.Context) {
+	params := parseAuditLogParams(c)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if size > 100 {
+		size = 100
+	}
+
+	logs, total, err := models.ListAuditLogs(h.db, params, page, size)
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, "success", gin.H{
+		"list":  logs,
+		"total": total,
+		"page":  page,
+		"size":  size,
+	})
+}
+
+// handleListAuditActions 获取可筛选的审计操作
+func (h *Handlers) handleListAuditActions(c *gin.Context) {
+	response.Success(c, "success", models.AuditActions())
+}
+
+// handleExportAuditLogs 以 CSV 导出筛选后的审计日志，导出本身也会被审计
+func (h *Handlers) handleExportAuditLogs(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	params := parseAuditLogParams(c)
+	h.recordAudit(c, models.AuditActionAuditLogExport, "audit_log", "", nil, c.Request.URL.Query())
+
+	fileName := fmt.Sprintf("audit_logs_%s.csv", time.Now().Format("20060102_150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", fileName))
+	c.Status(http.StatusOK)
+
+	// 分批写出，中途失败只能记录日志
+	writer := csv.NewWriter(c.Writer)
+	err := writer.Write([]string{
+		"ID", "时间", "操作人ID", "操作人", "操作", "资源类型", "资源ID",
+		"变更前", "变更后", "变更字段", "IP地址", "User-Agent",
+	})
+	if err == nil {
+		err = models.EachAuditLog(h.db, params, func(l *models.AuditLog) error {
+			return writer.Write([]string{
+				strconv.FormatUint(uint64(l.ID), 10),
+				l.CreatedAt.Format(cdrTimeLayout),
+				strconv.FormatUint(uint64(l.ActorID), 10),
+				l.ActorEmail,
+				l.Action,
+				l.ResourceType,
+				l.ResourceID,
+				l.Before,
+				l.After,
+				l.Changes,
+				l.IPAddress,
+				l.UserAgent,
+			})
+		})
+	}
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		logger.Warn("Failed to export audit logs", zap.Uint("userId", user.ID), zap.Error(err))
+	}
+}
+
+// parseAuditLogParams 解析审计日志列表与导出共用的筛选参数
+func parseAuditLogParams(c *gin.Context) map[string]interface{} {
+	params := make(map[string]interface{})
+	if actorIDStr := c.Query("actorId"); actorIDStr != "" {
+		if id, err := strconv.ParseUint(actorIDStr, 10, 32); err == nil {
+			params["actorId"] = uint(id)
+		}
+	}
+	for _, key := range []string{"action", "resourceType", "resourceId"} {
+		if value := c.Query(key); value != "" {
+			params[key] = value
+		}
+	}
+	if startTimeStr := c.Query("startTime"); startTimeStr != "" {
+		if t, err := time.Parse("2006-01-02", startTimeStr); err == nil {
+			params["startTime"] = t
+		}
+	}
+	if endTimeStr := c.Query("endTime"); endTimeStr != "" {
+		if t, err := time.Parse("2006-01-02", endTimeStr); err == nil {
+			params["endTime"] = time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, t.Location())
+		}
+	}
+	return params
+}
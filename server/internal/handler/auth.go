@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -77,7 +78,7 @@ func (h *Handlers) handleUserInfo(c *gin.Context) {
 			if expired >= 24*time.Hour {
 				expired = 24 * time.Hour
 			}
-			user.AuthToken = models.BuildAuthToken(user, expired, false)
+			user.AuthToken = issueAuthToken(c, h.db, user, expired)
 		}
 	}
 	response.Success(c, "success", user)
@@ -171,9 +172,8 @@ func (h *Handlers) handleUserSigninByEmail(c *gin.Context) {
 		return
 	}
 
-	// 从缓存中获取验证码
-	cachedCode, ok := utils.GlobalCache.Get(form.Email)
-	if !ok || cachedCode != form.Code {
+	// 校验并消费验证码
+	if !consumeEmailCode(c, form.Email, form.Code) {
 		if utils.GlobalLoginSecurityManager != nil {
 			recordFunc := func(db *gorm.DB, email string, userID uint, ipAddress string, failedCount int) error {
 				_, err := models.CreateOrUpdateAccountLock(db, email, userID, ipAddress, failedCount)
@@ -185,9 +185,6 @@ func (h *Handlers) handleUserSigninByEmail(c *gin.Context) {
 		return
 	}
 
-	// 清除已用验证码
-	utils.GlobalCache.Remove(form.Email)
-
 	// 6. 检查用户是否允许登录（激活、启用等）
 	err = models.CheckUserAllowLogin(db, user)
 	if err != nil {
@@ -232,6 +229,7 @@ func (h *Handlers) handleUserSigninByEmail(c *gin.Context) {
 				zap.String("email", user.Email),
 				zap.String("ip", clientIP),
 				zap.String("location", location))
+			notifySuspiciousLogin(db, user, clientIP, location, userAgent)
 		}
 	}
 
@@ -283,7 +281,7 @@ func (h *Handlers) handleUserSigninByEmail(c *gin.Context) {
 		if expired < 24*time.Hour {
 			expired = 24 * time.Hour
 		}
-		user.AuthToken = models.BuildAuthToken(user, expired, false)
+		user.AuthToken = issueAuthToken(c, db, user, expired)
 	}
 
 	// 返回登录结果（包含可疑登录警告）
@@ -483,7 +481,7 @@ func (h *Handlers) handleUserSigninByPassword(c *gin.Context) {
 			return
 		}
 	} else {
-		user, err = models.DecodeHashToken(db, form.AuthToken, false)
+		user, _, err = models.AuthenticateToken(db, form.AuthToken)
 		if err != nil {
 			logger.Warn("Login failed: invalid auth token", zap.String("ip", clientIP), zap.Error(err))
 			response.Fail(c, "login failed", err)
@@ -528,6 +526,7 @@ func (h *Handlers) handleUserSigninByPassword(c *gin.Context) {
 				zap.String("email", user.Email),
 				zap.String("ip", clientIP),
 				zap.String("location", location))
+			notifySuspiciousLogin(db, user, clientIP, location, userAgent)
 		}
 	}
 
@@ -599,7 +598,7 @@ func (h *Handlers) handleUserSigninByPassword(c *gin.Context) {
 		// 7 days
 		expired = 7 * 24 * time.Hour
 	}
-	user.AuthToken = models.BuildAuthToken(user, expired, false)
+	user.AuthToken = issueAuthToken(c, db, user, expired)
 
 	// 15. 返回登录结果（包含可疑登录警告）
 	responseData := gin.H{
@@ -647,7 +646,7 @@ func (h *Handlers) handleUserSignin(c *gin.Context) {
 			return
 		}
 	} else {
-		user, err = models.DecodeHashToken(db, form.AuthToken, false)
+		user, _, err = models.AuthenticateToken(db, form.AuthToken)
 		if err != nil {
 			LingEcho.AbortWithJSONError(c, http.StatusUnauthorized, err)
 			return
@@ -696,7 +695,7 @@ func (h *Handlers) handleUserSignin(c *gin.Context) {
 			// 7 days
 			expired = 7 * 24 * time.Hour
 		}
-		user.AuthToken = models.BuildAuthToken(user, expired, false)
+		user.AuthToken = issueAuthToken(c, db, user, expired)
 	}
 	c.JSON(http.StatusOK, user)
 }
@@ -936,9 +935,8 @@ func (h *Handlers) handleUserSignupByEmail(c *gin.Context) {
 		LingEcho.AbortWithJSONError(c, http.StatusBadRequest, errors.New("email has exists"))
 		return
 	}
-	// 从缓存中获取验证码（假设你使用的是 util.GlobalCache）
-	cachedCode, ok := utils.GlobalCache.Get(form.Email)
-	if !ok || cachedCode != form.Code {
+	// 校验并消费验证码
+	if !consumeEmailCode(c, form.Email, form.Code) {
 		if utils.GlobalRegistrationGuard != nil {
 			utils.GlobalRegistrationGuard.RecordRegistrationAttempt(clientIP, form.Email, false, "invalid verification code")
 		}
@@ -946,9 +944,6 @@ func (h *Handlers) handleUserSignupByEmail(c *gin.Context) {
 		return
 	}
 
-	// 清除已用验证码
-	utils.GlobalCache.Remove(form.Email)
-
 	// 处理加密密码：如果是加密格式，提取原始密码哈希
 	passwordToStore := form.Password
 	if strings.Contains(form.Password, ":") && len(strings.Split(form.Password, ":")) == 4 {
@@ -1217,16 +1212,12 @@ func (h *Handlers) handleChangePasswordByEmail(c *gin.Context) {
 		return
 	}
 
-	// 从缓存中获取验证码
-	cachedCode, ok := utils.GlobalCache.Get(user.Email)
-	if !ok || cachedCode != form.EmailCode {
+	// 校验并消费验证码
+	if !consumeEmailCode(c, user.Email, form.EmailCode) {
 		response.Fail(c, "邮箱验证码无效或已过期", errors.New("invalid or expired email code"))
 		return
 	}
 
-	// 清除已用验证码
-	utils.GlobalCache.Remove(user.Email)
-
 	// 设置新密码（不验证旧密码）
 	err := models.SetPassword(h.db, user, form.NewPassword)
 	if err != nil {
@@ -1453,8 +1444,8 @@ func (h *Handlers) handleGetSalt(c *gin.Context) {
 
 	// 将盐和时间戳存储到缓存中，用于验证
 	key := fmt.Sprintf("password_salt:%s", salt)
-	if utils.GlobalCache != nil {
-		utils.GlobalCache.Add(key, timestamp)
+	if err := cache.Set(c, key, timestamp, time.Duration(expiresIn)*time.Second); err != nil {
+		logger.Warn("Failed to store password salt", zap.Error(err))
 	}
 
 	response.Success(c, "success", gin.H{
@@ -1482,19 +1473,51 @@ func (h *Handlers) handleSendPhoneVerification(c *gin.Context) {
 		return
 	}
 
+	if h.sms.Configured() && !h.sms.Allowed(user.Phone) {
+		response.AbortWithStatusJSON(c, http.StatusTooManyRequests, notification.ErrSMSRateLimited)
+		return
+	}
+
 	token, err := models.GeneratePhoneVerifyToken(h.db, user)
 	if err != nil {
 		response.Fail(c, "Failed to generate verification code", err)
 		return
 	}
 
-	// 这里可以集成短信服务发送验证码
-	// 目前只是记录日志
-	logger.Info("Phone verification code", zap.String("phone", user.Phone), zap.String("code", token))
+	if !h.sms.Configured() {
+		// 未配置短信服务商时只记录日志，便于开发调试
+		logger.Info("Phone verification code", zap.String("phone", user.Phone), zap.String("code", token))
+		response.Success(c, "Verification code sent", nil)
+		return
+	}
+
+	err = h.sms.Send(c.Request.Context(), user.Phone, notification.SMSMessage{
+		Template: notification.SMSTemplateVerifyCode,
+		Params:   map[string]string{"code": token},
+	})
+	if errors.Is(err, notification.ErrSMSRateLimited) {
+		response.AbortWithStatusJSON(c, http.StatusTooManyRequests, err)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to send phone verification code", zap.String("phone", user.Phone), zap.Error(err))
+		response.Fail(c, "Failed to send verification code", err.Error())
+		return
+	}
 
 	response.Success(c, "Verification code sent", nil)
 }
 
+// newSMSService 根据配置创建短信服务，配置有误时记录错误并视为未配置
+func newSMSService() *notification.SMSService {
+	cfg := config.GlobalConfig.SMS
+	sender, err := notification.NewSMSSender(cfg)
+	if err != nil {
+		logger.Error("Failed to initialize sms provider", zap.Error(err))
+	}
+	return notification.NewSMSService(sender, cfg.Interval, cfg.HourlyLimit)
+}
+
 // handleUpdateNotificationSettings 更新通知设置
 func (h *Handlers) handleUpdateNotificationSettings(c *gin.Context) {
 	var settings map[string]bool
@@ -1638,12 +1661,28 @@ func (h *Handlers) handleUploadAvatar(c *gin.Context) {
 	// 获取存储实例 - 优先使用本地存储，避免七牛云配置问题
 	store := stores.Default()
 
+	// 头像计入存储配额
+	var credentialID uint
+	if credentials, err := models.GetUserCredentials(h.db, user.ID); err == nil && len(credentials) > 0 {
+		credentialID = credentials[0].ID
+	}
+	if err := models.CheckStorageQuota(h.db, user.ID, credentialID, header.Size); err != nil {
+		if errors.Is(err, models.ErrStorageQuotaExceeded) {
+			response.AbortWithStatusJSON(c, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		logger.Warn("Failed to check storage quota", zap.Uint("userId", user.ID), zap.Error(err))
+	}
+
 	// 如果用户已有头像且不是默认头像，删除旧头像
 	if user.Avatar != "" && !isDefaultAvatar(user.Avatar) {
 		// 从URL中提取文件路径
 		oldKey := extractKeyFromURL(user.Avatar)
 		if oldKey != "" {
 			store.Delete(oldKey)
+			if err := models.ReleaseStorageObjectByKey(h.db, oldKey); err != nil {
+				logger.Warn("Failed to release old avatar storage", zap.String("key", oldKey), zap.Error(err))
+			}
 		}
 	}
 
@@ -1664,25 +1703,20 @@ func (h *Handlers) handleUploadAvatar(c *gin.Context) {
 	}
 
 	// 记录存储使用量
-	go func() {
-		var credentialID uint
-		credentials, err := models.GetUserCredentials(h.db, user.ID)
-		if err == nil && len(credentials) > 0 {
-			credentialID = credentials[0].ID
-		}
-		if fileSize > 0 {
-			models.RecordStorageUsage(
-				h.db,
-				user.ID,
-				credentialID,
-				nil, // assistantID
-				nil, // groupID
-				fmt.Sprintf("avatar_%d_%d", user.ID, time.Now().Unix()),
-				fileSize,
-				fmt.Sprintf("上传头像: %s", fileName),
-			)
+	if fileSize > 0 {
+		object := &models.StorageObject{
+			StorageKey:   fileName,
+			UserID:       user.ID,
+			CredentialID: credentialID,
+			Category:     models.StorageCategoryAvatar,
+			Size:         fileSize,
 		}
-	}()
+		go func() {
+			if err := models.TrackStorageObject(h.db, object, fmt.Sprintf("上传头像: %s", fileName)); err != nil {
+				logger.Warn("Failed to record avatar storage usage", zap.Uint("userId", user.ID), zap.Error(err))
+			}
+		}()
+	}
 
 	// 更新用户头像URL
 	avatarURL := store.PublicURL(fileName)
@@ -1768,6 +1802,29 @@ func sendHashMail(db *gorm.DB, user *models.User, signame, expireKey, defaultExp
 	utils.Sig().Emit(signame, user, hash, clientIp, useragent)
 }
 
+// emailCodeExpiration 邮箱验证码有效期
+const emailCodeExpiration = 5 * time.Minute
+
+// emailCodeKey 邮箱验证码的缓存键
+func emailCodeKey(email string) string {
+	return "verify:email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// consumeEmailCode 校验邮箱验证码，通过后立即删除。验证码保存在全局缓存中，
+// 使用 Redis 时多实例共享；输错不会作废验证码，GetDel 保证同一验证码只能被使用一次
+func consumeEmailCode(ctx context.Context, email, code string) bool {
+	if code == "" {
+		return false
+	}
+	key := emailCodeKey(email)
+	cached, ok := cache.Get(ctx, key)
+	if !ok || fmt.Sprint(cached) != code {
+		return false
+	}
+	cached, ok = cache.GetDel(ctx, key)
+	return ok && fmt.Sprint(cached) == code
+}
+
 // handleSendEmailCode Send Email Code
 func (h *Handlers) handleSendEmailCode(context *gin.Context) {
 	var req models.SendEmailVerifyEmail
@@ -1778,7 +1835,10 @@ func (h *Handlers) handleSendEmailCode(context *gin.Context) {
 	req.UserAgent = context.Request.UserAgent()
 	req.ClientIp = context.ClientIP()
 	text := utils.RandNumberText(6)
-	utils.GlobalCache.Add(req.Email, text)
+	if err := cache.Set(context, emailCodeKey(req.Email), text, emailCodeExpiration); err != nil {
+		LingEcho.AbortWithJSONError(context, http.StatusInternalServerError, err)
+		return
+	}
 	go func() {
 		err := notification.NewMailNotification(config.GlobalConfig.Mail).SendVerificationCode(req.Email, text)
 		if err != nil {
@@ -2062,3 +2122,102 @@ func (h *Handlers) handleGetUserActivity(c *gin.Context) {
 		},
 	})
 }
+
+// completeLogin 在身份已验证后完成登录：检测异地登录、记录设备与登录历史、清除失败计数、
+// 设置登录会话并生成认证Token，返回与密码登录一致的响应数据；设置会话失败时请求已中止，返回 nil
+func (h *Handlers) completeLogin(c *gin.Context, db *gorm.DB, user *models.User, loginType string) gin.H {
+	clientIP := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+
+	// 获取IP地理位置
+	country, city, location := "Unknown", "Unknown", "Unknown"
+	if h.ipLocationService != nil {
+		country, city, location, _ = h.ipLocationService.GetLocation(clientIP)
+	}
+
+	// 检测异地登录
+	isSuspicious := false
+	if utils.GlobalLoginSecurityManager != nil {
+		getLocationsFunc := func(db *gorm.DB, userID uint, limit int) ([]utils.LoginLocation, error) {
+			histories, err := models.GetRecentLoginLocations(db, userID, limit)
+			if err != nil {
+				return nil, err
+			}
+			locations := make([]utils.LoginLocation, len(histories))
+			for i, h := range histories {
+				locations[i] = utils.LoginLocation{
+					Country: h.Country,
+					City:    h.City,
+				}
+			}
+			return locations, nil
+		}
+		isSuspicious, _ = utils.GlobalLoginSecurityManager.DetectSuspiciousLogin(db, user.ID, clientIP, location, country, getLocationsFunc)
+		if isSuspicious {
+			logger.Warn("Suspicious login detected",
+				zap.Uint("userID", user.ID),
+				zap.String("email", user.Email),
+				zap.String("ip", clientIP),
+				zap.String("location", location))
+			notifySuspiciousLogin(db, user, clientIP, location, userAgent)
+		}
+	}
+
+	// 记录设备与登录历史
+	deviceType, os, browser := utils.ParseUserAgent(userAgent)
+	deviceID := utils.GetDeviceID(userAgent, clientIP)
+	if _, err := models.CreateOrUpdateUserDevice(db, user.ID, deviceID, fmt.Sprintf("%s on %s", browser, os), deviceType, os, browser, userAgent, clientIP, location); err != nil {
+		logger.Warn("Failed to create/update user device", zap.Error(err))
+	}
+	if err := models.RecordLoginHistory(db, user.ID, user.Email, clientIP, location, country, city, userAgent, deviceID, loginType, true, "", isSuspicious); err != nil {
+		logger.Warn("Failed to record login history", zap.Error(err))
+	}
+
+	// 清除失败登录计数
+	if utils.GlobalLoginSecurityManager != nil {
+		utils.GlobalLoginSecurityManager.ClearFailedLoginCount(user.Email)
+	}
+
+	// 登录用户，设置 Session
+	models.Login(c, user)
+	if c.IsAborted() {
+		return nil
+	}
+	if updatedUser, err := models.GetUserByUID(db, user.ID); err == nil {
+		user = updatedUser
+	}
+
+	// 生成认证Token
+	expired, err := time.ParseDuration(utils.GetValue(db, constants.KEY_AUTH_TOKEN_EXPIRED))
+	if err != nil {
+		expired = 7 * 24 * time.Hour
+	}
+	user.AuthToken = issueAuthToken(c, db, user, expired)
+
+	responseData := gin.H{
+		"user":  user,
+		"token": user.AuthToken, // 为了兼容前端，同时返回token字段
+	}
+	if isSuspicious {
+		responseData["suspiciousLogin"] = true
+		responseData["message"] = "Login from new location detected. Please verify your identity."
+	}
+	return responseData
+}
+
+// notifySuspiciousLogin 通知用户账号在异常地点登录
+func notifySuspiciousLogin(db *gorm.DB, user *models.User, clientIP, location, userAgent string) {
+	utils.Sig().Emit(models.SigUserNotify, user, db, notification.Message{
+		Event: notification.EventSuspiciousLogin,
+		Level: notification.LevelWarning,
+		Title: "Suspicious login detected",
+		Content: fmt.Sprintf("Your account %s was signed in from %s (IP %s) at %s. If this was not you, change your password immediately.",
+			user.Email, location, clientIP, time.Now().Format("2006-01-02 15:04:05")),
+		Data: map[string]interface{}{
+			"ip":        clientIP,
+			"location":  location,
+			"userAgent": userAgent,
+			"time":      time.Now().Format("2006-01-02 15:04:05"),
+		},
+	})
+}
@@ -812,6 +812,7 @@ func (h *Handlers) exportBillToCSV(bill *models.Bill, records []models.UsageReco
 		{"ASR调用次数", fmt.Sprintf("%d", bill.TotalASRCount)},
 		{"TTS总时长(秒)", fmt.Sprintf("%d", bill.TotalTTSDuration)},
 		{"TTS调用次数", fmt.Sprintf("%d", bill.TotalTTSCount)},
+		{"TTS合成字符数", fmt.Sprintf("%d", bill.TotalTTSCharacters)},
 		{"总存储大小(字节)", fmt.Sprintf("%d", bill.TotalStorageSize)},
 		{"总API调用次数", fmt.Sprintf("%d", bill.TotalAPICalls)},
 		{""},
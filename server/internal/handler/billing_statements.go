@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// usageStatementDetail 月度账单及其明细
+type usageStatementDetail struct {
+	*models.UsageStatement
+	LineItems   []models.StatementLineItem `json:"lineItems"`
+	Credentials []models.MeteredUsage      `json:"credentials"`
+}
+
+func newUsageStatementDetail(statement *models.UsageStatement) usageStatementDetail {
+	return usageStatementDetail{
+		UsageStatement: statement,
+		LineItems:      statement.GetLineItems(),
+		Credentials:    statement.GetCredentialUsage(),
+	}
+}
+
+// GetBillingPlans lists the plans a user can subscribe to
+func (h *Handlers) GetBillingPlans(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	plans, err := models.GetBillingPlans(h.db)
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, "success", plans)
+}
+
+// GetCurrentBillingPlan gets the user's plan together with the current period statement
+func (h *Handlers) GetCurrentBillingPlan(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	plan, err := models.GetUserBillingPlan(h.db, user.ID)
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	statement, err := models.GenerateMonthlyStatement(h.db, user.ID, time.Now().Format(models.StatementPeriodLayout))
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.Success(c, "success", gin.H{
+		"plan":      plan,
+		"statement": newUsageStatementDetail(statement),
+	})
+}
+
+// SubscribeBillingPlan switches the user's plan, effective from the current period
+func (h *Handlers) SubscribeBillingPlan(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	var req struct {
+		PlanID uint `json:"planId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request parameters: "+err.Error(), nil)
+		return
+	}
+
+	sub, err := models.SubscribeBillingPlan(h.db, user.ID, req.PlanID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Fail(c, "Plan not found", nil)
+		return
+	}
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// 重新计算当前账期，使新套餐立即体现在账单中
+	if _, err := models.GenerateMonthlyStatement(h.db, user.ID, time.Now().Format(models.StatementPeriodLayout)); err != nil {
+		logger.Warn("Failed to refresh current statement", zap.Uint("userId", user.ID), zap.Error(err))
+	}
+
+	response.Success(c, "Plan subscribed", sub)
+}
+
+// GetUsageStatements lists the user's monthly statements
+func (h *Handlers) GetUsageStatements(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	// 确保当前账期的账单存在且是最新的
+	if _, err := models.GenerateMonthlyStatement(h.db, user.ID, time.Now().Format(models.StatementPeriodLayout)); err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	statements, err := models.GetUsageStatements(h.db, user.ID)
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, "success", statements)
+}
+
+// GenerateUsageStatement generates or refreshes the statement of a period
+func (h *Handlers) GenerateUsageStatement(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	var req struct {
+		Period string `json:"period" binding:"required"` // YYYY-MM
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request parameters: "+err.Error(), nil)
+		return
+	}
+	start, _, err := models.StatementPeriodRange(req.Period)
+	if err != nil {
+		response.Fail(c, err.Error(), nil)
+		return
+	}
+	if start.After(time.Now()) {
+		response.Fail(c, "Statement period has not started yet", nil)
+		return
+	}
+
+	statement, err := models.GenerateMonthlyStatement(h.db, user.ID, req.Period)
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, "success", newUsageStatementDetail(statement))
+}
+
+// GetUsageStatement gets a monthly statement with line items and per-credential usage
+func (h *Handlers) GetUsageStatement(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	statement, ok := h.loadUsageStatement(c, user.ID)
+	if !ok {
+		return
+	}
+	response.Success(c, "success", newUsageStatementDetail(statement))
+}
+
+// ExportUsageStatement downloads a monthly statement as a CSV or JSON invoice
+func (h *Handlers) ExportUsageStatement(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	statement, ok := h.loadUsageStatement(c, user.ID)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	fileName := fmt.Sprintf("%s.%s", statement.InvoiceNo, format)
+	switch format {
+	case "json":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+		c.JSON(http.StatusOK, newUsageStatementDetail(statement))
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+		c.Status(http.StatusOK)
+		if err := writeUsageStatementCSV(c.Writer, statement); err != nil {
+			logger.Warn("Failed to write statement csv", zap.Uint("statementId", statement.ID), zap.Error(err))
+		}
+	default:
+		response.Fail(c, "Unsupported export format, use csv or json", nil)
+	}
+}
+
+// loadUsageStatement 按路径参数加载当前用户的账单
+func (h *Handlers) loadUsageStatement(c *gin.Context, userID uint) (*models.UsageStatement, bool) {
+	statementID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Fail(c, "Invalid statement ID", nil)
+		return nil, false
+	}
+	statement, err := models.GetUsageStatement(h.db, userID, uint(statementID))
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusNotFound, err)
+		return nil, false
+	}
+	return statement, true
+}
+
+// writeUsageStatementCSV 输出账单发票，金额单位为分
+func writeUsageStatementCSV(w http.ResponseWriter, statement *models.UsageStatement) error {
+	writer := csv.NewWriter(w)
+
+	rows := [][]string{
+		{"发票编号", statement.InvoiceNo},
+		{"账期", statement.Period},
+		{"状态", string(statement.Status)},
+		{"套餐", statement.PlanName},
+		{"币种", statement.Currency},
+		{"开始时间", statement.StartTime.Format("2006-01-02 15:04:05")},
+		{"结束时间", statement.EndTime.Format("2006-01-02 15:04:05")},
+		{""},
+		{"计量项", "用量", "包含额度", "超额用量", "超额单价(分)", "计价单位", "金额(分)"},
+	}
+	for _, item := range statement.GetLineItems() {
+		rows = append(rows, []string{
+			string(item.Meter),
+			strconv.FormatInt(item.Quantity, 10),
+			strconv.FormatInt(item.Included, 10),
+			strconv.FormatInt(item.Overage, 10),
+			strconv.FormatInt(item.UnitPrice, 10),
+			strconv.FormatInt(item.PriceUnit, 10),
+			strconv.FormatInt(item.Amount, 10),
+		})
+	}
+	rows = append(rows,
+		[]string{""},
+		[]string{"月费(分)", strconv.FormatInt(statement.MonthlyFee, 10)},
+		[]string{"超额费用(分)", strconv.FormatInt(statement.OverageAmount, 10)},
+		[]string{"应付总额(分)", strconv.FormatInt(statement.TotalAmount, 10)},
+		[]string{""},
+		[]string{"凭证ID", "ASR时长(秒)", "TTS字符数", "LLM Tokens", "通话时长(秒)"},
+	)
+	for _, usage := range statement.GetCredentialUsage() {
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(usage.CredentialID), 10),
+			strconv.FormatInt(usage.ASRSeconds, 10),
+			strconv.FormatInt(usage.TTSCharacters, 10),
+			strconv.FormatInt(usage.LLMTokens, 10),
+			strconv.FormatInt(usage.CallSeconds, 10),
+		})
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// rejectOverPlanQuota 硬限制套餐额度用完时拒绝新的语音会话，返回 true 表示已拒绝
+func (h *Handlers) rejectOverPlanQuota(c *gin.Context, userID uint) bool {
+	if err := h.planQuotaError(userID); err != nil {
+		response.AbortWithStatusJSON(c, http.StatusPaymentRequired, err)
+		return true
+	}
+	return false
+}
+
+// planQuotaError 用户已超出套餐配额时返回 models.ErrPlanQuotaExceeded
+func (h *Handlers) planQuotaError(userID uint) error {
+	err := models.CheckPlanQuota(h.db, userID)
+	if err == nil || errors.Is(err, models.ErrPlanQuotaExceeded) {
+		return err
+	}
+	// 计量查询失败不应阻断通话
+	logger.Warn("Failed to check plan quota", zap.Uint("userId", userID), zap.Error(err))
+	return nil
+}
+
+// voiceCallMeters 实时语音通话消耗的计量项，任一项达到凭证上限都不再建立新的通话
+var voiceCallMeters = []models.MeterType{models.MeterCallMinutes, models.MeterASRMinutes, models.MeterLLMTokens, models.MeterTTSCharacters}
+
+// rejectOverCredentialQuota 凭证本月用量达到上限时拒绝请求，返回 true 表示已拒绝
+func (h *Handlers) rejectOverCredentialQuota(c *gin.Context, credential *models.UserCredential, meters ...models.MeterType) bool {
+	if err := h.credentialQuotaError(credential, meters...); err != nil {
+		response.AbortWithStatusJSON(c, http.StatusPaymentRequired, err)
+		return true
+	}
+	return false
+}
+
+// credentialQuotaError 凭证某项用量达到上限时返回 *models.CredentialQuotaError
+func (h *Handlers) credentialQuotaError(credential *models.UserCredential, meters ...models.MeterType) error {
+	if credential == nil {
+		return nil
+	}
+	err := models.CheckCredentialQuota(h.db, credential, time.Now(), meters...)
+	if err == nil || errors.Is(err, models.ErrCredentialQuotaExceeded) {
+		return err
+	}
+	// 计量查询失败不应阻断请求
+	logger.Warn("Failed to check credential quota", zap.Uint("credentialId", credential.ID), zap.Error(err))
+	return nil
+}
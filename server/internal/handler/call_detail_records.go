@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// cdrTimeLayout time format used in CDR exports
+const cdrTimeLayout = "2006-01-02 15:04:05"
+
+// GetCallDetailRecords lists call detail records of the current user
+func (h *Handlers) GetCallDetailRecords(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	params := parseCallDetailRecordParams(c)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	params["page"] = page
+	params["size"] = size
+
+	records, total, err := models.ListCallDetailRecords(h.db, user.ID, params)
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.Success(c, "success", gin.H{
+		"list":  records,
+		"total": total,
+		"page":  page,
+		"size":  size,
+	})
+}
+
+// GetCallDetailRecord gets a single call detail record
+func (h *Handlers) GetCallDetailRecord(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Fail(c, "Invalid record ID", nil)
+		return
+	}
+	record, err := models.GetCallDetailRecord(h.db, user.ID, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, "Record not found", nil)
+		} else {
+			response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	response.Success(c, "success", record)
+}
+
+// ExportCallDetailRecords downloads the filtered call detail records as CSV
+func (h *Handlers) ExportCallDetailRecords(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	params := parseCallDetailRecordParams(c)
+	fileName := fmt.Sprintf("cdr_%s.csv", time.Now().Format("20060102_150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", fileName))
+	c.Status(http.StatusOK)
+
+	// Records are streamed in batches, so a failure midway can only be logged
+	writer := csv.NewWriter(c.Writer)
+	err := writer.Write([]string{
+		"通话ID", "渠道", "方向", "主叫", "被叫", "编解码",
+		"凭证ID", "助手ID", "开始时间", "接通时间", "结束时间", "通话时长(秒)",
+		"ASR时长(秒)", "ASR音频(字节)", "TTS时长(秒)", "TTS字符数",
+		"LLM轮次", "LLM输入Tokens", "LLM输出Tokens", "LLM总Tokens",
+		"结果", "挂断原因",
+	})
+	if err == nil {
+		err = models.EachCallDetailRecord(h.db, user.ID, params, func(r *models.CallDetailRecord) error {
+			return writer.Write(callDetailRecordRow(r))
+		})
+	}
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		logger.Warn("Failed to export call detail records", zap.Uint("userId", user.ID), zap.Error(err))
+	}
+}
+
+// parseCallDetailRecordParams 解析话单列表与导出共用的筛选参数
+func parseCallDetailRecordParams(c *gin.Context) map[string]interface{} {
+	params := make(map[string]interface{})
+	if channel := c.Query("channel"); channel != "" {
+		params["channel"] = models.CallChannel(channel)
+	}
+	if direction := c.Query("direction"); direction != "" {
+		params["direction"] = models.SipCallDirection(direction)
+	}
+	if disposition := c.Query("disposition"); disposition != "" {
+		params["disposition"] = models.CallDisposition(disposition)
+	}
+	if credentialIDStr := c.Query("credentialId"); credentialIDStr != "" {
+		if id, err := strconv.ParseUint(credentialIDStr, 10, 32); err == nil {
+			params["credentialId"] = uint(id)
+		}
+	}
+	if assistantIDStr := c.Query("assistantId"); assistantIDStr != "" {
+		if id, err := strconv.ParseUint(assistantIDStr, 10, 32); err == nil {
+			params["assistantId"] = uint(id)
+		}
+	}
+	if startTimeStr := c.Query("startTime"); startTimeStr != "" {
+		if t, err := time.Parse("2006-01-02", startTimeStr); err == nil {
+			params["startTime"] = t
+		}
+	}
+	if endTimeStr := c.Query("endTime"); endTimeStr != "" {
+		if t, err := time.Parse("2006-01-02", endTimeStr); err == nil {
+			// Set to 23:59:59 of the day
+			params["endTime"] = time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, t.Location())
+		}
+	}
+	return params
+}
+
+// callDetailRecordRow 话单导出的一行，列顺序与表头一致
+func callDetailRecordRow(r *models.CallDetailRecord) []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil || t.IsZero() {
+			return ""
+		}
+		return t.Format(cdrTimeLayout)
+	}
+	assistantID := ""
+	if r.AssistantID != nil {
+		assistantID = strconv.FormatUint(uint64(*r.AssistantID), 10)
+	}
+	return []string{
+		r.CallID,
+		string(r.Channel),
+		string(r.Direction),
+		r.Caller,
+		r.Callee,
+		r.Codec,
+		strconv.FormatUint(uint64(r.CredentialID), 10),
+		assistantID,
+		formatTime(&r.StartTime),
+		formatTime(r.AnswerTime),
+		formatTime(r.EndTime),
+		strconv.Itoa(r.Duration),
+		strconv.Itoa(r.ASRSeconds),
+		strconv.FormatInt(r.ASRAudioBytes, 10),
+		strconv.Itoa(r.TTSSeconds),
+		strconv.Itoa(r.TTSCharacters),
+		strconv.Itoa(r.LLMTurns),
+		strconv.Itoa(r.LLMPromptTokens),
+		strconv.Itoa(r.LLMCompletionTokens),
+		strconv.Itoa(r.LLMTotalTokens),
+		string(r.Disposition),
+		r.HangupCause,
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetCallTranscript Get the timestamped transcript of a call
+// ?format=txt downloads it as plain text
+func (h *Handlers) GetCallTranscript(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "Unauthorized", "User not logged in")
+		return
+	}
+
+	sessionID := c.Param("id")
+	segments, err := models.GetCallTranscript(h.db, sessionID, user.ID)
+	if err != nil {
+		response.Fail(c, "Query failed", err.Error())
+		return
+	}
+	if len(segments) == 0 {
+		response.Fail(c, "Transcript not found", nil)
+		return
+	}
+
+	if c.Query("format") == "txt" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.txt", sessionID))
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(formatTranscript(segments)))
+		return
+	}
+
+	response.Success(c, "Query successful", gin.H{
+		"sessionId": sessionID,
+		"segments":  segments,
+	})
+}
+
+// DeleteCallTranscript Delete the transcript of a call
+func (h *Handlers) DeleteCallTranscript(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "Unauthorized", "User not logged in")
+		return
+	}
+
+	deleted, err := models.DeleteCallTranscript(h.db, c.Param("id"), user.ID)
+	if err != nil {
+		response.Fail(c, "Delete failed", err.Error())
+		return
+	}
+	if deleted == 0 {
+		response.Fail(c, "Transcript not found", nil)
+		return
+	}
+
+	response.Success(c, "Delete successful", gin.H{"deleted": deleted})
+}
+
+// formatTranscript renders segments as "[mm:ss.mmm] speaker: text" lines
+func formatTranscript(segments []models.CallTranscript) string {
+	var b strings.Builder
+	for _, s := range segments {
+		ms := s.StartMs
+		fmt.Fprintf(&b, "[%02d:%02d.%03d] %s: %s", ms/60000, ms/1000%60, ms%1000, s.Speaker, s.Text)
+		if s.Interrupted {
+			b.WriteString(" (interrupted)")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -1,67 +1,46 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/cache"
 	"github.com/code-100-precent/LingEcho/pkg/config"
+	constants2 "github.com/code-100-precent/LingEcho/pkg/constants"
 	"github.com/code-100-precent/LingEcho/pkg/graph"
+	"github.com/code-100-precent/LingEcho/pkg/media/endpointing"
+	"github.com/code-100-precent/LingEcho/pkg/recording"
 	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/code-100-precent/LingEcho/pkg/voiceclone"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/signaling"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/signaling/signalingpb"
 	transports "github.com/code-100-precent/LingEcho/pkg/webrtc/transport"
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
 	"github.com/pion/webrtc/v3"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
 )
 
 // Constants
 const (
 	// Connection configuration
-	maxConnectionRetries       = 50
-	connectionRetryDelay       = 100 * time.Millisecond
-	connectionStateLogInterval = 10
-	connectionReadyDelay       = 200 * time.Millisecond
+	connectionTimeout    = 5 * time.Second
+	connectionReadyDelay = 200 * time.Millisecond
 )
 
-// ClientManager manages WebRTC client connections
-type ClientManager struct {
-	clients map[string]*transports.AIClient
-	mutex   sync.RWMutex
-}
-
-func NewClientManager() *ClientManager {
-	return &ClientManager{
-		clients: make(map[string]*transports.AIClient),
-	}
-}
-
-func (m *ClientManager) AddClient(sessionID string, client *transports.AIClient) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	m.clients[sessionID] = client
-}
-
-func (m *ClientManager) RemoveClient(sessionID string) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	delete(m.clients, sessionID)
-}
-
-func (m *ClientManager) GetClient(sessionID string) (*transports.AIClient, bool) {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-	client, exists := m.clients[sessionID]
-	return client, exists
-}
-
 type ChatRequest struct {
 	AssistantID  int64   `json:"assistantId" binding:"required"`
 	SystemPrompt string  `json:"systemPrompt"`
@@ -351,172 +330,347 @@ func (h *Handlers) getChatSessionLogByAssistant(c *gin.Context) {
 	})
 }
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
+// callClientKey 会话中保存 AIClient 的键
+const callClientKey = "aiClient"
 
-var manager = NewClientManager()
+// callGreetingKey 会话中保存助手开场白的键
+const callGreetingKey = "greeting"
 
-// SignalMessage represents a WebSocket signaling message
-type SignalMessage struct {
-	Type      string      `json:"type"`
-	SessionID string      `json:"session_id,omitempty"`
-	Data      interface{} `json:"data,omitempty"`
+// newVoiceSignaling 创建语音通话的信令服务，以登录令牌（Authorization 头或 token 参数，
+// 配合 credentialId 参数）或 URL 参数中的 apiKey/apiSecret 认证，并按用户限制并发会话数
+func newVoiceSignaling(db *gorm.DB) *signaling.Server {
+	srv := signaling.NewServer()
+	srv.Upgrader.CheckOrigin = checkWSOrigin
+	srv.Authenticator = func(r *http.Request) (interface{}, error) {
+		if token := signaling.BearerToken(r); token != "" {
+			return authenticateVoiceToken(db, r, token)
+		}
+		apiKey := r.URL.Query().Get("apiKey")
+		apiSecret := r.URL.Query().Get("apiSecret")
+		if apiKey == "" || apiSecret == "" {
+			return nil, &signaling.AuthError{Status: http.StatusBadRequest, Message: "Missing required parameters: apiKey and apiSecret are required"}
+		}
+		cred, err := models.GetUserCredentialByApiSecretAndApiKey(db, apiKey, apiSecret)
+		if err != nil {
+			return nil, &signaling.AuthError{Status: http.StatusInternalServerError, Message: "Database error: " + err.Error()}
+		}
+		if cred == nil {
+			return nil, &signaling.AuthError{Status: http.StatusUnauthorized, Message: "Invalid credentials"}
+		}
+		return cred, nil
+	}
+	srv.IdentityKey = func(identity interface{}) string {
+		if cred, ok := identity.(*models.UserCredential); ok {
+			return strconv.FormatUint(uint64(cred.UserID), 10)
+		}
+		return ""
+	}
+	srv.Handle(signaling.TypeConnected, handleCallConnected)
+
+	// 多副本部署时通过 Redis 共享会话登记，任一节点都能向通话发送信令
+	if cfg := config.GlobalConfig; cfg != nil {
+		srv.MaxSessionsPerIdentity = cfg.VoiceMaxSessionsPerUser
+		if cfg.NodeID != "" {
+			srv.NodeID = cfg.NodeID
+		}
+		if cfg.SignalingStore == "redis" {
+			store, err := newRedisSignalingStore(cfg.Cache.Redis)
+			if err != nil {
+				log.Printf("[Server] Redis signaling store unavailable, sessions stay node-local: %v", err)
+			} else {
+				srv.Store = store
+			}
+		}
+	}
+	return srv
 }
 
-func (h *Handlers) handleConnection(c *gin.Context) {
-	// 从 URL 参数中获取认证信息
-	apiKey := c.Query("apiKey")
-	apiSecret := c.Query("apiSecret")
-	assistantIDStr := c.Query("assistantId")
-
-	// 验证必需参数 - 在 WebSocket 升级之前验证，失败时直接返回 HTTP 错误
-	if apiKey == "" || apiSecret == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required parameters: apiKey and apiSecret are required"})
-		c.Abort()
-		return
+// authenticateVoiceToken 以登录令牌认证，通话使用 credentialId 指定的该用户凭证
+func authenticateVoiceToken(db *gorm.DB, r *http.Request, token string) (*models.UserCredential, error) {
+	user, _, err := models.AuthenticateToken(db, token)
+	if err != nil {
+		return nil, &signaling.AuthError{Status: http.StatusUnauthorized, Message: "Invalid token: " + err.Error()}
 	}
-	if assistantIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required parameter: assistantId is required"})
-		c.Abort()
-		return
+	if err := models.CheckUserAllowLogin(db, user); err != nil {
+		return nil, &signaling.AuthError{Status: http.StatusForbidden, Message: err.Error()}
 	}
-
-	// 立即验证认证信息
-	cred, err := models.GetUserCredentialByApiSecretAndApiKey(h.db, apiKey, apiSecret)
+	credentialID, err := strconv.ParseUint(r.URL.Query().Get("credentialId"), 10, 64)
+	if err != nil || credentialID == 0 {
+		return nil, &signaling.AuthError{Status: http.StatusBadRequest, Message: "Missing or invalid parameter: credentialId is required with token authentication"}
+	}
+	cred, err := models.GetUserCredentialByID(db, user.ID, uint(credentialID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
-		c.Abort()
-		return
+		return nil, &signaling.AuthError{Status: http.StatusInternalServerError, Message: "Database error: " + err.Error()}
 	}
 	if cred == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
-		c.Abort()
-		return
+		return nil, &signaling.AuthError{Status: http.StatusForbidden, Message: "Credential not found"}
+	}
+	return cred, nil
+}
+
+// checkWSOrigin 按 WS_ALLOWED_ORIGINS 校验 WebSocket 连接的来源，每次读取配置以便热更新生效
+func checkWSOrigin(r *http.Request) bool {
+	var origins []string
+	if config.GlobalConfig != nil {
+		origins = splitURLs(config.GlobalConfig.WSAllowedOrigins)
+	}
+	return signaling.AllowOrigins(origins)(r)
+}
+
+// newRedisSignalingStore 按缓存的 Redis 配置连接并创建信令会话存储
+func newRedisSignalingStore(cfg cache.RedisConfig) (*signaling.RedisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		PoolSize:     cfg.PoolSize,
+		MinIdleConns: cfg.MinIdleConns,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return signaling.NewRedisStore(client, ""), nil
+}
+
+// voiceCall 一通 WebRTC 语音通话的配置，由凭证、助手与请求参数得出，WebSocket 与 gRPC 信令共用
+type voiceCall struct {
+	cred         *models.UserCredential
+	assistantID  uint
+	knowledgeKey string
+	systemPrompt string
+	maxTokens    int
+	temperature  float32
+	language     string
+	speaker      string
+	llmModel     string
+	greeting     string             // 助手开场白，为空时使用默认问候语
+	voiceClone   *models.VoiceClone // 助手选用的训练音色，nil 时使用 speaker 发音人
+	turnPolicy   endpointing.Policy
+	ttsCache     bool // 复用已合成的短句音频
+	mcpTools     bool // 允许模型调用内置 MCP 工具
+
+	codec            string
+	redundancy       bool
+	noiseSuppression bool
+}
+
+// prepareVoiceCall 校验并发上限、套餐配额与助手归属并读取通话配置，失败时返回给客户端的 HTTP 状态码
+func (h *Handlers) prepareVoiceCall(ctx context.Context, cred *models.UserCredential, query url.Values) (*voiceCall, int, error) {
+	// 建立连接前先检查并发上限，避免为注定被拒绝的通话创建 ASR/TTS 连接；Serve 中会再次原子地检查
+	if limit := h.voiceSignaling.MaxSessionsPerIdentity; limit > 0 && h.voiceSignaling.ActiveSessions(cred) >= limit {
+		return nil, http.StatusTooManyRequests, errors.New("Too many concurrent calls")
+	}
+
+	assistantIDStr := query.Get("assistantId")
+	if assistantIDStr == "" {
+		return nil, http.StatusBadRequest, errors.New("Missing required parameter: assistantId is required")
+	}
+	if err := h.planQuotaError(cred.UserID); err != nil {
+		return nil, http.StatusPaymentRequired, err
+	}
+	if err := h.credentialQuotaError(cred, voiceCallMeters...); err != nil {
+		return nil, http.StatusPaymentRequired, err
 	}
 
 	// 解析 assistantId
 	assistantID, err := strconv.ParseInt(assistantIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assistantId format"})
-		c.Abort()
-		return
+		return nil, http.StatusBadRequest, errors.New("Invalid assistantId format")
 	}
 
 	// 查询 assistant 配置
 	var assistant models.Assistant
 	if err := h.db.First(&assistant, assistantID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Assistant not found"})
-		c.Abort()
-		return
+		return nil, http.StatusNotFound, errors.New("Assistant not found")
 	}
 
 	// 验证 assistant 是否属于该用户（通过 credential 的 UserID）
 	if assistant.UserID != cred.UserID {
 		// 检查是否是组织共享的助手
 		if assistant.GroupID == nil {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied: assistant does not belong to you"})
-			c.Abort()
-			return
+			return nil, http.StatusForbidden, errors.New("Permission denied: assistant does not belong to you")
 		}
 		// TODO: 可以在这里添加组织成员权限检查
 	}
 
+	call := &voiceCall{
+		cred:         cred,
+		assistantID:  uint(assistantID),
+		systemPrompt: assistant.SystemPrompt,
+		maxTokens:    assistant.MaxTokens, // 0 表示不限制
+		temperature:  assistant.Temperature,
+		language:     assistant.Language,
+		speaker:      assistant.Speaker,
+		llmModel:     assistant.LLMModel,
+		greeting:     assistant.Greeting,
+		voiceClone:   h.assistantVoiceClone(&assistant),
+		mcpTools:     assistant.EnableMCPTools,
+		turnPolicy: endpointing.Policy{
+			SilenceThreshold: time.Duration(assistant.TurnSilenceMs) * time.Millisecond,
+			MaxUtterance:     time.Duration(assistant.TurnMaxUtteranceMs) * time.Millisecond,
+			EndOnSentence:    assistant.TurnEndOnSentence,
+		},
+		ttsCache: assistant.TTSCacheEnabled,
+	}
+
 	// 从 assistant 中读取配置
-	knowledgeKey := ""
 	if assistant.KnowledgeBaseID != nil && *assistant.KnowledgeBaseID != "" {
-		knowledgeKey = *assistant.KnowledgeBaseID
+		call.knowledgeKey = *assistant.KnowledgeBaseID
 	}
 
-	systemPrompt := assistant.SystemPrompt
-	if systemPrompt == "" {
-		systemPrompt = "你是一个友好的AI助手，请用简洁明了的语言回答问题。"
+	if call.systemPrompt == "" {
+		call.systemPrompt = "你是一个友好的AI助手，请用简洁明了的语言回答问题。"
 	}
 
-	// 如果开启了图记忆功能，则尝试从 Neo4j 中获取该用户的长期偏好主题，并拼接到系统提示词中
-	if config.GlobalConfig.Neo4jEnabled && assistant.EnableGraphMemory {
+	// 如果开启了图记忆功能，则尝试从图存储中获取该用户的长期偏好主题，并拼接到系统提示词中
+	if assistant.EnableGraphMemory {
 		if store := graph.GetDefaultStore(); store != nil {
-			ctx := c.Request.Context()
 			if userCtx, err := store.GetUserContext(ctx, cred.UserID, assistantID); err == nil {
 				if len(userCtx.Topics) > 0 {
 					// 构建一段自然语言描述用户长期偏好
 					preferenceText := fmt.Sprintf("该用户在历史对话中经常讨论这些主题：%s。请在回答时优先从这些兴趣和习惯的角度来组织内容，让风格尽量贴近他的偏好。",
 						strings.Join(userCtx.Topics, "、"))
-					if systemPrompt == "" {
-						systemPrompt = preferenceText
-					} else {
-						systemPrompt = systemPrompt + "\n\n" + preferenceText
-					}
+					call.systemPrompt = call.systemPrompt + "\n\n" + preferenceText
 				}
 			}
 		}
 	}
 
-	maxTokens := assistant.MaxTokens
-	if maxTokens == 0 {
-		maxTokens = 0 // 0 表示不限制
+	if call.temperature == 0 {
+		call.temperature = 0.7 // 默认值
+	}
+	if call.language == "" {
+		call.language = "zh" // 默认中文
 	}
 
-	temperature := assistant.Temperature
-	if temperature == 0 {
-		temperature = 0.7 // 默认值
+	// 客户端可通过 ?codec=opus 协商 48kHz 宽带音频，?codec=g722 协商 16kHz HD 语音，默认 PCMA
+	call.codec = strings.ToLower(query.Get("codec"))
+	switch call.codec {
+	case constants.CodecPCMA, constants.CodecPCMU, constants.CodecOPUS, constants.CodecG722:
+	default:
+		call.codec = constants.CodecPCMA
 	}
+	// 客户端可通过 ?redundancy=true 协商 NACK 与 RED 冗余，适合丢包较多的移动网络
+	call.redundancy, _ = strconv.ParseBool(query.Get("redundancy"))
+	// 客户端可通过 ?ns=true 开启服务端降噪（谱减法），适合嘈杂环境
+	call.noiseSuppression, _ = strconv.ParseBool(query.Get("ns"))
+	return call, 0, nil
+}
+
+// newVoiceSession 为通话创建 WebRTC 传输与 AIClient，返回交给 voiceSignaling.Serve 的会话；
+// release 在会话结束后调用
+func (h *Handlers) newVoiceSession(conn voiceConn, call *voiceCall) (*signaling.Session, func(), error) {
+	cred := call.cred
+	sessionID := signaling.NewSessionID()
 
-	language := assistant.Language
-	if language == "" {
-		language = "zh" // 默认中文
+	// Create WebRTC transport
+	opt := h.webrtcICEOption(strconv.FormatUint(uint64(cred.UserID), 10))
+	opt.Codec = call.codec
+	opt.StreamID = "lingecho_ai_server"
+	opt.ICETimeout = constants.DefaultICETimeout
+	opt.EnableRedundancy = call.redundancy
+	aiClient, err := h.newVoiceClient(conn, call, opt, sessionID)
+	if err != nil {
+		return nil, nil, err
 	}
+	transport := aiClient.Transport
 
-	speaker := assistant.Speaker
-	llmModel := assistant.LLMModel
+	// The client restarts ICE after network changes; the session survives as long as the signaling connection does
+	transport.OnReconnected(func() {
+		log.Printf("[Server] WebRTC connection recovered for client %s", sessionID)
+	})
 
-	// 转换 assistantID 为 *uint
-	aid := uint(assistantID)
+	// 所有写入经由 AIClient 的锁，与其发送的其它消息互不交错
+	session := signaling.NewSession(sessionID, conn, transport)
+	session.Identity = cred
+	session.SetWriter(aiClient.WriteJSON)
+	session.Set(callClientKey, aiClient)
+	session.Set(callGreetingKey, call.greeting)
+	return session, func() { aiClient.Close() }, nil
+}
 
-	// 升级 HTTP 请求为 WebSocket 连接
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+// assistantVoiceClone 返回助手选用且已训练完成的音色；音色须属于助手的创建者或所在组织
+func (h *Handlers) assistantVoiceClone(assistant *models.Assistant) *models.VoiceClone {
+	if assistant.VoiceCloneID == nil || *assistant.VoiceCloneID <= 0 {
+		return nil
+	}
+	var clone models.VoiceClone
+	if err := h.db.Where("id = ? AND is_active = ?", *assistant.VoiceCloneID, true).First(&clone).Error; err != nil {
+		log.Printf("[Server] Voice clone %d of assistant %d not available: %v", *assistant.VoiceCloneID, assistant.ID, err)
+		return nil
+	}
+	sameGroup := clone.GroupID != nil && assistant.GroupID != nil && *clone.GroupID == *assistant.GroupID
+	if clone.UserID != assistant.UserID && !sameGroup {
+		log.Printf("[Server] Voice clone %d is not accessible to assistant %d", clone.ID, assistant.ID)
+		return nil
+	}
+	if clone.AssetID == "" {
+		// 音色尚未训练完成
+		return nil
+	}
+	return &clone
+}
+
+// applyVoiceClone 让 AIClient 使用训练音色合成，失败时保留发音人合成
+func applyVoiceClone(aiClient *transports.AIClient, clone *models.VoiceClone, language string) {
+	svc, err := voiceclone.NewFactory().CreateServiceFromEnv(voiceclone.Provider(strings.ToLower(clone.Provider)))
 	if err != nil {
-		log.Println("Error upgrading connection:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to upgrade connection"})
+		log.Printf("[Server] Failed to create voice clone service for clone %d, using speaker: %v", clone.ID, err)
 		return
 	}
-	defer conn.Close()
-	sessionID := fmt.Sprintf("session_%d", time.Now().UnixNano())
+	aiClient.SetTTSService(voiceclone.NewSynthesisService(svc, clone.AssetID, language))
+}
 
-	// Create WebRTC transport
-	transport := rtcmedia.NewWebRTCTransport(rtcmedia.WebRTCOption{
-		Codec: constants.CodecPCMA,
-		ICEServers: []webrtc.ICEServer{
-			{URLs: []string{"stun:stun.l.google.com:19302"}},
-		},
-		StreamID:   "lingecho_ai_server",
-		ICETimeout: constants.DefaultICETimeout,
-	})
+// newVoiceClient 按 opt 创建 WebRTC 传输与 AIClient，并在收到对端音轨时开始识别
+func (h *Handlers) newVoiceClient(conn transports.SignalConn, call *voiceCall, opt rtcmedia.WebRTCOption, sessionID string) (*transports.AIClient, error) {
+	cred := call.cred
+	transport := rtcmedia.NewWebRTCTransport(opt)
 	transport.NewPeerConnection()
 
 	// Use credential and assistant configuration to initialize services
+	aid := call.assistantID
 	aiClient, err := transports.NewAIClientWithCredential(
 		conn,
 		transport,
 		sessionID,
-		knowledgeKey,
+		call.knowledgeKey,
 		h.db,
 		cred.UserID,
 		cred.ID,
 		&aid,
 		cred,
-		systemPrompt,
-		maxTokens,
-		temperature,
-		language,
-		speaker,
-		llmModel,
+		call.systemPrompt,
+		call.maxTokens,
+		call.temperature,
+		call.language,
+		call.speaker,
+		call.llmModel,
 	)
 	if err != nil {
-		log.Printf("[Server] Failed to create AI client: %v", err)
-		return
+		transport.Close()
+		return nil, fmt.Errorf("failed to create AI client: %w", err)
+	}
+	aiClient.SetNoiseSuppression(call.noiseSuppression)
+	aiClient.SetTurnPolicy(call.turnPolicy)
+	h.loadConversationTools(aiClient.LLMProvider(), int64(call.assistantID), call.mcpTools)
+	if call.voiceClone != nil {
+		applyVoiceClone(aiClient, call.voiceClone, call.language)
+	}
+	if call.ttsCache {
+		aiClient.EnableTTSCache()
+	}
+
+	// 按用户的录音策略录制通话，通话结束时上传
+	if rec, err := recording.ForUser(h.db, cred.UserID); err != nil {
+		log.Printf("[Server] Failed to start recording: %v", err)
+	} else if rec != nil {
+		aiClient.SetRecorder(rec)
 	}
 
 	// Set up OnTrack callback BEFORE handling any signaling messages
@@ -546,193 +700,236 @@ func (h *Handlers) handleConnection(c *gin.Context) {
 		}
 	})
 	fmt.Printf("[Server] OnTrack callback registered for client %s\n", sessionID)
+	return aiClient, nil
+}
+
+// voiceConn 语音通话的信令连接：WebSocket 连接或 gRPC 信令流
+type voiceConn interface {
+	signaling.Conn
+	Close() error
+}
 
-	manager.AddClient(sessionID, aiClient)
-	defer manager.RemoveClient(sessionID)
-	defer aiClient.Close()
+func (h *Handlers) handleConnection(c *gin.Context) {
+	if !h.beginRealtimeSession(c) {
+		return
+	}
+	defer h.realtime.end()
 
-	// Send session ID to client
-	initMsg := SignalMessage{
-		Type:      "init",
-		SessionID: sessionID,
+	// 在 WebSocket 升级之前认证，失败时直接返回 HTTP 错误
+	identity, err := h.voiceSignaling.Authenticate(c.Request)
+	if err != nil {
+		c.JSON(signaling.AuthStatus(err), gin.H{"error": err.Error()})
+		c.Abort()
+		return
 	}
-	if err := conn.WriteJSON(initMsg); err != nil {
-		log.Printf("[Server] Failed to send init message: %v", err)
+	call, status, err := h.prepareVoiceCall(c.Request.Context(), identity.(*models.UserCredential), c.Request.URL.Query())
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		c.Abort()
 		return
 	}
 
-	// Handle incoming messages
-	for {
-		var msg SignalMessage
-		if err := conn.ReadJSON(&msg); err != nil {
-			// WebSocket 连接关闭或出错
-			log.Printf("[Server] WebSocket connection closed or error: %v", err)
-			// 确保清理资源
-			if aiClient != nil {
-				aiClient.Close()
-			}
-			break
-		}
+	// 升级 HTTP 请求为 WebSocket 连接
+	conn, err := h.voiceSignaling.Upgrade(c.Writer, c.Request)
+	if err != nil {
+		log.Println("Error upgrading connection:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to upgrade connection"})
+		return
+	}
+	defer conn.Close()
 
-		// 处理关闭消息
-		if msg.Type == "close" || msg.Type == "disconnect" {
-			log.Printf("[Server] Received close/disconnect message from client")
-			if aiClient != nil {
-				aiClient.Close()
-			}
-			break
-		}
+	session, release, err := h.newVoiceSession(conn, call)
+	if err != nil {
+		log.Printf("[Server] %v", err)
+		return
+	}
+	defer release()
 
-		handleSignalMessage(aiClient, msg)
+	if err := h.voiceSignaling.Serve(session); err != nil {
+		log.Printf("[Server] WebSocket connection closed or error: %v", err)
 	}
 }
 
-// handleSignalMessage routes signaling messages
-func handleSignalMessage(client *transports.AIClient, msg SignalMessage) {
-	switch msg.Type {
-	case "offer":
-		handleOffer(client, msg)
-	case "connected":
-		handleConnection(client, msg)
-	default:
-		log.Printf("[Server] Unknown message type: %s", msg.Type)
+// RegisterVoiceGRPC 在 gRPC 服务上注册语音信令
+func (h *Handlers) RegisterVoiceGRPC(s grpc.ServiceRegistrar) {
+	signalingpb.RegisterSignalingServer(s, h.newVoiceGRPC())
+}
+
+// newVoiceGRPC 创建 gRPC 信令服务，与 WebSocket 接口共用 voiceSignaling 的认证、会话管理与并发限制
+func (h *Handlers) newVoiceGRPC() *signaling.GRPCHandler {
+	handler := signaling.NewGRPCHandler(h.voiceSignaling)
+	handler.OnStream = func(r *http.Request, conn *signaling.StreamConn) (*signaling.Session, func(), error) {
+		if !h.realtime.begin() {
+			return nil, nil, signaling.ErrServerClosed
+		}
+		identity, err := h.voiceSignaling.Authenticate(r)
+		if err != nil {
+			h.realtime.end()
+			return nil, nil, err
+		}
+		call, status, err := h.prepareVoiceCall(r.Context(), identity.(*models.UserCredential), r.URL.Query())
+		if err != nil {
+			h.realtime.end()
+			return nil, nil, &signaling.AuthError{Status: status, Message: err.Error()}
+		}
+		session, release, err := h.newVoiceSession(conn, call)
+		if err != nil {
+			h.realtime.end()
+			return nil, nil, err
+		}
+		return session, func() {
+			release()
+			h.realtime.end()
+		}, nil
 	}
+	return handler
 }
 
-// handleOffer handles the WebRTC offer
-func handleOffer(client *transports.AIClient, msg SignalMessage) {
-	offerData, ok := msg.Data.(map[string]interface{})
+// handleCallConnected handles connection established message (client confirmation)
+func handleCallConnected(s *signaling.Session, msg signaling.SignalMessage) error {
+	value, _ := s.Get(callClientKey)
+	client, ok := value.(*transports.AIClient)
 	if !ok {
-		log.Println("[Server] Invalid offer data")
-		return
+		return signaling.ErrNoTransport
 	}
+	fmt.Printf("[Server] Client confirmed connection for session %s\n", client.SessionID)
 
-	offerStr, ok := offerData["sdp"].(string)
-	if !ok {
-		log.Println("[Server] Invalid offer SDP")
+	// Wait for connection to be established, then send the assistant's greeting
+	greeting, _ := s.Get(callGreetingKey)
+	text, _ := greeting.(string)
+	go sendGreeting(client, text)
+	return nil
+}
+
+// defaultGreeting is spoken when the call does not carry its own opening line
+const defaultGreeting = "你好，我是AI助手，很高兴和你对话。"
+
+// sendGreeting waits for the WebRTC connection and the send track, then greets the caller
+func sendGreeting(client *transports.AIClient, greeting string) {
+	if err := waitForConnection(client.Transport); err != nil {
+		log.Printf("[Server] Connection not established: %v", err)
 		return
 	}
 
-	// Debug: Check if offer SDP contains audio media
-	if strings.Contains(offerStr, "m=audio") {
-		fmt.Printf("[Server] Offer SDP contains audio media description\n")
-	} else {
-		fmt.Printf("[Server] WARNING: Offer SDP does NOT contain audio media description!\n")
-		previewLen := 200
-		if len(offerStr) < previewLen {
-			previewLen = len(offerStr)
+	// Wait for txTrack to be ready
+	maxWait := 50
+	for i := 0; i < maxWait; i++ {
+		txTrack := client.Transport.GetTxTrack()
+		if txTrack != nil {
+			fmt.Printf("[Server] txTrack is ready after %d attempts\n", i+1)
+			break
 		}
-		fmt.Printf("[Server] Offer SDP preview: %s...\n", offerStr[:previewLen])
+		if i == 0 {
+			fmt.Printf("[Server] Waiting for txTrack to be ready...\n")
+		}
+		time.Sleep(50 * time.Millisecond)
 	}
 
-	if err := client.Transport.SetRemoteDescription(offerStr); err != nil {
-		log.Printf("[Server] Error setting remote description: %v", err)
-		return
+	// Wait a bit more for client's audio receiver to be ready
+	time.Sleep(connectionReadyDelay * 2)
+
+	// Send greeting to start the conversation
+	if greeting == "" {
+		greeting = defaultGreeting
 	}
-	fmt.Printf("[Server] Remote description set successfully\n")
+	fmt.Printf("[Server] Sending greeting: %s\n", greeting)
+	client.GenerateTTS(greeting)
+}
 
-	candidates, ok := offerData["candidates"].([]interface{})
-	if !ok {
-		log.Println("[Server] Invalid candidates data")
-		return
+// waitForConnection waits for WebRTC connection
+func waitForConnection(transport *rtcmedia.WebRTCTransport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	fmt.Printf("[Server] Waiting for connection... (state: %s)\n", transport.GetConnectionState().String())
+	if err := transport.WaitUntilConnected(ctx); err != nil {
+		return fmt.Errorf("connection not established (state: %s): %w", transport.GetConnectionState().String(), err)
 	}
+	return nil
+}
 
-	candidateStrs := extractCandidates(candidates)
-	answer, serverCandidates, err := client.Transport.CreateAnswer(candidateStrs)
-	if err != nil {
-		log.Printf("[Server] Error creating answer: %v", err)
+// getICEServers 返回浏览器端 RTCPeerConnection 使用的 ICE 服务器（含 TURN 临时凭证）
+func (h *Handlers) getICEServers(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
 		return
 	}
-
-	// Debug: Check if answer SDP contains audio media
-	if strings.Contains(answer, "m=audio") {
-		fmt.Printf("[Server] Answer SDP contains audio media description\n")
-	} else {
-		fmt.Printf("[Server] WARNING: Answer SDP does NOT contain audio media description!\n")
+	opt := h.webrtcICEOption(strconv.FormatUint(uint64(user.ID), 10))
+	data := gin.H{
+		"iceServers":         opt.GetICEServers(),
+		"iceTransportPolicy": opt.ICETransportPolicy.String(),
 	}
-
-	answerMsg := SignalMessage{
-		Type:      "answer",
-		SessionID: client.SessionID,
-		Data: map[string]interface{}{
-			"sdp":        answer,
-			"candidates": serverCandidates,
-		},
+	// 配置了固定 DTLS 证书时返回其指纹，客户端可与 answer SDP 中的 a=fingerprint 比对
+	if opt.CertFile != "" {
+		cert, err := rtcmedia.LoadOrCreateCertificate(opt.CertFile, opt.KeyFile)
+		if err != nil {
+			log.Printf("[Server] Failed to load WebRTC certificate: %v", err)
+		} else if fingerprints, err := rtcmedia.CertificateFingerprints(cert); err == nil {
+			data["fingerprints"] = fingerprints
+		}
 	}
+	response.Success(c, "success", data)
+}
 
-	if err := client.Conn.WriteJSON(answerMsg); err != nil {
-		log.Printf("[Server] Error sending answer: %v", err)
-		return
+// webrtcICEOption 加载 STUN/TURN 配置：数据库配置优先，未配置时使用环境变量
+// turnUser 写入 TURN 临时凭证的用户名，便于在 TURN 服务端按用户排查
+func (h *Handlers) webrtcICEOption(turnUser string) rtcmedia.WebRTCOption {
+	opt := rtcmedia.WebRTCOption{
+		TURNUser:           turnUser,
+		ICETransportPolicy: webrtc.ICETransportPolicyAll,
+		CertFile:           config.GlobalConfig.WebRTCCertFile,
+		KeyFile:            config.GlobalConfig.WebRTCKeyFile,
 	}
 
-	fmt.Printf("[Server] Sent answer to client %s\n", client.SessionID)
-
-	// Note: Audio receiving is now handled by the OnTrack callback
-	// which is set up in websocketHandler before any signaling messages are processed
-	// No need to wait here - OnTrack will fire automatically when the track arrives
-	fmt.Println("[Server] Answer sent, waiting for OnTrack callback to fire when client sends audio...")
-}
+	stunURLs := utils.GetValue(h.db, constants2.KEY_WEBRTC_STUN_URLS)
+	if stunURLs == "" {
+		stunURLs = config.GlobalConfig.WebRTCSTUNURLs
+	}
+	if urls := splitURLs(stunURLs); len(urls) > 0 {
+		opt.ICEServers = []webrtc.ICEServer{{URLs: urls}}
+	}
 
-// extractCandidates extracts candidate strings
-func extractCandidates(candidates []interface{}) []string {
-	var candidateStrs []string
-	for _, c := range candidates {
-		if candidateStr, ok := c.(string); ok {
-			candidateStrs = append(candidateStrs, candidateStr)
+	if raw := utils.GetValue(h.db, constants2.KEY_WEBRTC_TURN_SERVERS); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opt.TURNServers); err != nil {
+			log.Printf("[Server] Invalid %s config: %v", constants2.KEY_WEBRTC_TURN_SERVERS, err)
+			opt.TURNServers = nil
 		}
 	}
-	return candidateStrs
-}
-
-// handleConnection handles connection established message (client confirmation)
-func handleConnection(client *transports.AIClient, msg SignalMessage) {
-	fmt.Printf("[Server] Client confirmed connection for session %s\n", client.SessionID)
-
-	// Wait for connection to be established, then send greeting
-	go func() {
-		if err := waitForConnection(client.Transport); err != nil {
-			log.Printf("[Server] Connection not established: %v", err)
-			return
+	if len(opt.TURNServers) == 0 {
+		if urls := splitURLs(config.GlobalConfig.WebRTCTURNURLs); len(urls) > 0 {
+			opt.TURNServers = []rtcmedia.TURNServer{{
+				URLs:       urls,
+				Username:   config.GlobalConfig.WebRTCTURNUsername,
+				Credential: config.GlobalConfig.WebRTCTURNCredential,
+				Secret:     config.GlobalConfig.WebRTCTURNSecret,
+			}}
 		}
+	}
+	for i := range opt.TURNServers {
+		opt.TURNServers[i].TTL = config.GlobalConfig.WebRTCTURNTTL
+	}
 
-		// Wait for txTrack to be ready
-		maxWait := 50
-		for i := 0; i < maxWait; i++ {
-			txTrack := client.Transport.GetTxTrack()
-			if txTrack != nil {
-				fmt.Printf("[Server] txTrack is ready after %d attempts\n", i+1)
-				break
-			}
-			if i == 0 {
-				fmt.Printf("[Server] Waiting for txTrack to be ready...\n")
-			}
-			time.Sleep(50 * time.Millisecond)
+	policy := utils.GetValue(h.db, constants2.KEY_WEBRTC_ICE_TRANSPORT_POLICY)
+	if policy == "" {
+		policy = config.GlobalConfig.WebRTCICETransportPolicy
+	}
+	if policy == "relay" {
+		if len(opt.TURNServers) == 0 {
+			log.Printf("[Server] ICE transport policy is relay but no TURN server is configured, falling back to all")
+		} else {
+			opt.ICETransportPolicy = webrtc.ICETransportPolicyRelay
 		}
-
-		// Wait a bit more for client's audio receiver to be ready
-		time.Sleep(connectionReadyDelay * 2)
-
-		// Send greeting to start the conversation
-		greeting := "你好，我是AI助手，很高兴和你对话。"
-		fmt.Printf("[Server] Sending greeting: %s\n", greeting)
-		client.GenerateTTS(greeting)
-	}()
+	}
+	return opt
 }
 
-// waitForConnection waits for WebRTC connection
-func waitForConnection(transport *rtcmedia.WebRTCTransport) error {
-	for i := 0; i < maxConnectionRetries; i++ {
-		state := transport.GetConnectionState()
-		if state == webrtc.PeerConnectionStateConnected {
-			return nil
-		}
-
-		if i%connectionStateLogInterval == 0 {
-			fmt.Printf("[Server] Waiting for connection... (state: %s)\n", state.String())
+// splitURLs 拆分逗号分隔的 ICE 服务器地址或来源列表
+func splitURLs(s string) []string {
+	var urls []string
+	for _, u := range strings.Split(s, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
 		}
-
-		time.Sleep(connectionRetryDelay)
 	}
-
-	return fmt.Errorf("connection timeout")
+	return urls
 }
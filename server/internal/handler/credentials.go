@@ -2,9 +2,13 @@ package handlers
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
+	lingechoMCP "github.com/code-100-precent/LingEcho/pkg/mcp"
 	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/code-100-precent/LingEcho/pkg/synthesizer"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,11 +25,19 @@ func (h *Handlers) handleCreateCredential(c *gin.Context) {
 		response.Fail(c, "User is not logged in.", nil)
 	}
 
+	scopes, err := lingechoMCP.ParseScopes(credential.MCPScopes)
+	if err != nil {
+		response.Fail(c, "Invalid MCP scopes", err.Error())
+		return
+	}
+	credential.MCPScopes = scopes.String()
+
 	userCredential, err := models.CreateUserCredential(h.db, user.ID, &credential)
 	if err != nil {
 		response.Fail(c, "create user credential failed", err)
 		return
 	}
+	h.recordAudit(c, models.AuditActionCredentialCreate, "credential", userCredential.ID, nil, userCredential)
 
 	response.Success(c, "create user credential success", gin.H{
 		"apiKey":    userCredential.APIKey,
@@ -61,12 +73,262 @@ func (h *Handlers) handleDeleteCredential(c *gin.Context) {
 		return
 	}
 
+	before, _ := models.GetUserCredentialByID(h.db, user.ID, credentialID)
+
 	// Delete credential
 	err = models.DeleteUserCredential(h.db, user.ID, credentialID)
 	if err != nil {
 		response.Fail(c, "Failed to delete credential", err)
 		return
 	}
+	// Tokens issued from the credential stop working with it
+	if err := models.RevokeCredentialAPITokens(h.db, credentialID); err != nil {
+		response.Fail(c, "Failed to revoke API tokens", err)
+		return
+	}
+	h.recordAudit(c, models.AuditActionCredentialDelete, "credential", credentialID, before, nil)
 
 	response.Success(c, "Credential deleted successfully", nil)
 }
+
+// UpdateCredentialMCPScopesRequest 更新凭证MCP调用范围请求
+type UpdateCredentialMCPScopesRequest struct {
+	MCPScopes string `json:"mcpScopes"` // 如 "tool:search_*,agent:rag_agent"，为空时不限制
+}
+
+// handleUpdateCredentialMCPScopes 更新凭证可调用的MCP工具及agents
+func (h *Handlers) handleUpdateCredentialMCPScopes(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid credential ID", err)
+		return
+	}
+
+	var req UpdateCredentialMCPScopesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request", nil)
+		return
+	}
+	scopes, err := lingechoMCP.ParseScopes(req.MCPScopes)
+	if err != nil {
+		response.Fail(c, "Invalid MCP scopes", err.Error())
+		return
+	}
+
+	before, _ := models.GetUserCredentialByID(h.db, user.ID, uint(credentialID))
+	if err := models.UpdateUserCredentialMCPScopes(h.db, user.ID, uint(credentialID), scopes.String()); err != nil {
+		response.Fail(c, "Failed to update MCP scopes", err.Error())
+		return
+	}
+	h.auditCredentialUpdate(c, user.ID, before)
+
+	response.Success(c, "MCP scopes updated successfully", gin.H{
+		"mcpScopes": scopes.String(),
+	})
+}
+
+// UpdateCredentialStorageQuotaRequest 更新凭证存储配额请求
+type UpdateCredentialStorageQuotaRequest struct {
+	StorageQuota int64 `json:"storageQuota"` // 字节，0 表示不限制
+}
+
+// handleUpdateCredentialStorageQuota 更新凭证可占用的存储空间，超出后该凭证的上传将被拒绝
+func (h *Handlers) handleUpdateCredentialStorageQuota(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid credential ID", err)
+		return
+	}
+
+	var req UpdateCredentialStorageQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.StorageQuota < 0 {
+		response.Fail(c, "Invalid request", nil)
+		return
+	}
+
+	before, _ := models.GetUserCredentialByID(h.db, user.ID, uint(credentialID))
+	if err := models.UpdateUserCredentialStorageQuota(h.db, user.ID, uint(credentialID), req.StorageQuota); err != nil {
+		response.Fail(c, "Failed to update storage quota", err.Error())
+		return
+	}
+	h.auditCredentialUpdate(c, user.ID, before)
+
+	response.Success(c, "Storage quota updated successfully", gin.H{
+		"storageQuota": req.StorageQuota,
+	})
+}
+
+// handleUpdateCredentialUsageLimits 更新凭证每月的 LLM Token、语音合成字符、语音识别时长与实时通话时长上限
+func (h *Handlers) handleUpdateCredentialUsageLimits(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid credential ID", err)
+		return
+	}
+
+	var req models.CredentialUsageLimits
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request", nil)
+		return
+	}
+
+	before, _ := models.GetUserCredentialByID(h.db, user.ID, uint(credentialID))
+	if err := models.UpdateUserCredentialUsageLimits(h.db, user.ID, uint(credentialID), req); err != nil {
+		response.Fail(c, "Failed to update usage limits", err.Error())
+		return
+	}
+	h.auditCredentialUpdate(c, user.ID, before)
+	if req.QuotaAction == "" {
+		req.QuotaAction = models.CredentialQuotaReject
+	}
+	response.Success(c, "Usage limits updated successfully", req)
+}
+
+// handleGetCredentialUsage 获取凭证本月（或 period 指定账期）的用量与上限
+func (h *Handlers) handleGetCredentialUsage(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid credential ID", err)
+		return
+	}
+
+	credential, err := models.GetUserCredentialByID(h.db, user.ID, uint(credentialID))
+	if err != nil {
+		response.Fail(c, "Failed to load credential", err)
+		return
+	}
+	if credential == nil {
+		response.Fail(c, "Credential not found", nil)
+		return
+	}
+
+	period := c.DefaultQuery("period", models.CredentialUsagePeriod(time.Now()))
+	if _, err := time.Parse(models.StatementPeriodLayout, period); err != nil {
+		response.Fail(c, "Invalid period", "period must be formatted as YYYY-MM")
+		return
+	}
+	usage, err := models.GetCredentialUsage(h.db, credential.ID, period)
+	if err != nil {
+		response.Fail(c, "Failed to get credential usage", err)
+		return
+	}
+
+	quotaAction := credential.QuotaAction
+	if quotaAction == "" {
+		quotaAction = models.CredentialQuotaReject
+	}
+	response.Success(c, "get credential usage success", gin.H{
+		"period": period,
+		"usage":  usage,
+		"limits": models.CredentialUsageLimits{
+			LLMTokenLimit:     credential.LLMTokenLimit,
+			TTSCharacterLimit: credential.TTSCharacterLimit,
+			ASRSecondLimit:    credential.ASRSecondLimit,
+			WebRTCMinuteLimit: credential.WebRTCMinuteLimit,
+			QuotaAction:       quotaAction,
+		},
+	})
+}
+
+// handleCheckCredentialTTSHealth 探测凭证的TTS主提供商及备用提供商是否可用
+func (h *Handlers) handleCheckCredentialTTSHealth(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid credential ID", err)
+		return
+	}
+
+	credential, err := models.GetUserCredentialByID(h.db, user.ID, uint(credentialID))
+	if err != nil {
+		response.Fail(c, "Failed to load credential", err)
+		return
+	}
+	if credential == nil {
+		response.Fail(c, "Credential not found", nil)
+		return
+	}
+	if len(credential.TtsConfig) == 0 {
+		response.Fail(c, "Credential has no TTS config", nil)
+		return
+	}
+
+	svc, err := synthesizer.NewSynthesisServiceFromCredential(synthesizer.TTSCredentialConfig(credential.TtsConfig))
+	if err != nil {
+		response.Fail(c, "Failed to create TTS service", err.Error())
+		return
+	}
+	defer svc.Close()
+
+	response.Success(c, "check tts health success", gin.H{
+		"providers": synthesizer.CheckHealth(c.Request.Context(), svc),
+	})
+}
+
+// handleListCredentialMCPInvocations 获取凭证最近的MCP工具调用记录
+func (h *Handlers) handleListCredentialMCPInvocations(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid credential ID", err)
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	invocations, err := models.GetMCPInvocations(h.db, user.ID, uint(credentialID), limit)
+	if err != nil {
+		response.Fail(c, "Failed to get MCP invocations", err)
+		return
+	}
+	response.Success(c, "get MCP invocations success", invocations)
+}
+
+// auditCredentialUpdate 记录凭证配置变更，before 为更新前读取的凭证
+func (h *Handlers) auditCredentialUpdate(c *gin.Context, userID uint, before *models.UserCredential) {
+	if before == nil {
+		return
+	}
+	after, err := models.GetUserCredentialByID(h.db, userID, before.ID)
+	if err != nil || after == nil {
+		return
+	}
+	h.recordAudit(c, models.AuditActionCredentialUpdate, "credential", before.ID, before, after)
+}
@@ -499,6 +499,7 @@ func (h *Handlers) GetDeviceConfig(c *gin.Context) {
 		"apiSecret":            assistant.ApiSecret,
 		"language":             assistant.Language,
 		"speaker":              assistant.Speaker,
+		"greeting":             assistant.Greeting,
 		"llmModel":             assistant.LLMModel,
 		"temperature":          assistant.Temperature,
 		"systemPrompt":         assistant.SystemPrompt,
@@ -506,6 +507,10 @@ func (h *Handlers) GetDeviceConfig(c *gin.Context) {
 		"enableVAD":            assistant.EnableVAD,
 		"vadThreshold":         assistant.VADThreshold,
 		"vadConsecutiveFrames": assistant.VADConsecutiveFrames,
+		"turnSilenceMs":        assistant.TurnSilenceMs,
+		"turnMaxUtteranceMs":   assistant.TurnMaxUtteranceMs,
+		"turnEndOnSentence":    assistant.TurnEndOnSentence,
+		"enableMCPTools":       assistant.EnableMCPTools,
 	}
 
 	// 知识库ID（可选）
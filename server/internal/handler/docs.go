@@ -263,6 +263,40 @@ func (h *Handlers) RegisterAdmin(router *gin.RouterGroup) {
 			Searchables: []string{"BillNo", "Title"},
 			Icon:        &models.AdminIcon{SVG: string(iconChatSessionLog)},
 		},
+		{
+			Model:       &models.BillingPlan{},
+			Group:       "Billing",
+			Name:        "Billing Plans",
+			Desc:        "Plans with monthly included quotas and overage prices (in cents).",
+			Shows:       []string{"ID", "Code", "Name", "MonthlyFee", "Currency", "HardLimit", "IsDefault", "Enabled"},
+			Editables:   []string{"Code", "Name", "Description", "Currency", "MonthlyFee", "IsDefault", "Enabled", "IncludedASRMinutes", "IncludedTTSCharacters", "IncludedLLMTokens", "IncludedCallMinutes", "ASRPricePerMinute", "TTSPricePer1KChars", "LLMPricePer1KTokens", "CallPricePerMinute", "HardLimit"},
+			Orderables:  []string{"MonthlyFee", "CreatedAt"},
+			Searchables: []string{"Code", "Name"},
+			Requireds:   []string{"Code", "Name"},
+			Icon:        &models.AdminIcon{SVG: string(iconChatSessionLog)},
+		},
+		{
+			Model:       &models.BillingSubscription{},
+			Group:       "Billing",
+			Name:        "Billing Subscriptions",
+			Desc:        "The plan each user is subscribed to.",
+			Shows:       []string{"ID", "UserID", "PlanID", "UpdatedAt"},
+			Editables:   []string{"UserID", "PlanID"},
+			Orderables:  []string{"UpdatedAt"},
+			Searchables: []string{"UserID"},
+			Icon:        &models.AdminIcon{SVG: string(iconChatSessionLog)},
+		},
+		{
+			Model:       &models.UsageStatement{},
+			Group:       "Billing",
+			Name:        "Usage Statements",
+			Desc:        "Monthly usage statements generated from metered usage.",
+			Shows:       []string{"ID", "InvoiceNo", "UserID", "Period", "Status", "PlanName", "TotalAmount", "CreatedAt"},
+			Editables:   []string{"Status"},
+			Orderables:  []string{"Period", "CreatedAt"},
+			Searchables: []string{"InvoiceNo", "Period"},
+			Icon:        &models.AdminIcon{SVG: string(iconChatSessionLog)},
+		},
 		// Quota management
 		{
 			Model:       &models.UserQuota{},
@@ -374,6 +408,41 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			AuthRequired: true,
 			Desc:         "User logout, if `?next={NEXT_URL}`is not empty, redirect to {NEXT_URL}",
 		},
+		{
+			Group:        "User Authorization",
+			Path:         config.GlobalConfig.APIPrefix + "/auth/sessions",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List the user's active cookie sessions and auth tokens; current marks the session making the request",
+			Response: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "sessions", Type: apidocs.TYPE_OBJECT},
+				},
+			},
+		},
+		{
+			Group:        "User Authorization",
+			Path:         config.GlobalConfig.APIPrefix + "/auth/sessions/:id",
+			Method:       http.MethodDelete,
+			AuthRequired: true,
+			Desc:         "Revoke one session; its cookie or auth token is rejected from the next request on",
+		},
+		{
+			Group:        "User Authorization",
+			Path:         config.GlobalConfig.APIPrefix + "/auth/sessions/logout-all",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Log out everywhere: revoke all sessions, including ones signed in before sessions were tracked. Set keepCurrent to stay signed in on this device",
+			Request:      apidocs.GetDocDefine(LogoutAllRequest{}),
+			Response: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "revoked", Type: apidocs.TYPE_INT},
+					{Name: "keepCurrent", Type: apidocs.TYPE_BOOLEAN},
+				},
+			},
+		},
 		{
 			Group:        "User Authorization",
 			Path:         config.GlobalConfig.APIPrefix + "/auth/register",
@@ -683,6 +752,27 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			AuthRequired: true,
 			Desc:         "Disable search feature (admin only)",
 		},
+		{
+			Group:        "System Module",
+			Path:         config.GlobalConfig.APIPrefix + "/system/audit-logs",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List audit records of sensitive operations, newest first (requires audit.read). Filters: actorId, action (a trailing dot matches a prefix, e.g. credential.), resourceType, resourceId, startTime, endTime (YYYY-MM-DD), page, size. Secrets in before/after are masked",
+		},
+		{
+			Group:        "System Module",
+			Path:         config.GlobalConfig.APIPrefix + "/system/audit-logs/actions",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List the audited actions (requires audit.read)",
+		},
+		{
+			Group:        "System Module",
+			Path:         config.GlobalConfig.APIPrefix + "/system/audit-logs/export",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Download the filtered audit records as CSV (requires audit.read); the export itself is audited",
+		},
 
 		// ==================== Notifications ====================
 		{
@@ -742,6 +832,266 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			AuthRequired: true,
 			Desc:         "Batch delete notifications",
 		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/channels",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List the user's external notification channels",
+		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/channels",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Add an external notification channel (webhook, slack, dingtalk, feishu)",
+			Request: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "type", Type: apidocs.TYPE_STRING, Required: true},
+					{Name: "name", Type: apidocs.TYPE_STRING},
+					{Name: "webhookUrl", Type: apidocs.TYPE_STRING, Required: true},
+					{Name: "secret", Type: apidocs.TYPE_STRING},
+					{Name: "events", Type: "array", Desc: "Subscribed events, empty for all: quota_alert, system_alert, suspicious_login, training_completed"},
+					{Name: "enabled", Type: apidocs.TYPE_BOOLEAN},
+				},
+			},
+		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/channels/:id",
+			Method:       http.MethodPut,
+			AuthRequired: true,
+			Desc:         "Update an external notification channel",
+		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/channels/:id",
+			Method:       http.MethodDelete,
+			AuthRequired: true,
+			Desc:         "Delete an external notification channel",
+		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/channels/:id/test",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Send a test message to a notification channel",
+		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/push/devices",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List the devices registered for mobile push",
+		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/push/devices",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Register a device token for mobile push (FCM for android, APNs for ios)",
+			Request: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "platform", Type: apidocs.TYPE_STRING, Required: true, Desc: "android or ios"},
+					{Name: "token", Type: apidocs.TYPE_STRING, Required: true},
+					{Name: "deviceId", Type: apidocs.TYPE_STRING},
+					{Name: "appVersion", Type: apidocs.TYPE_STRING},
+				},
+			},
+		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/push/devices",
+			Method:       http.MethodDelete,
+			AuthRequired: true,
+			Desc:         "Unregister the device token given in the token query parameter, e.g. on logout",
+		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/webpush/key",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Get the VAPID public key to pass as applicationServerKey when subscribing to browser push",
+		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/webpush/subscriptions",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List the browser push subscriptions of the current user",
+		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/webpush/subscriptions",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Save a browser push subscription; the body is the result of PushSubscription.toJSON()",
+			Request: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "endpoint", Type: apidocs.TYPE_STRING, Required: true, Desc: "Push service URL, must be https"},
+					{Name: "keys", Type: apidocs.TYPE_OBJECT, Required: true, Fields: []apidocs.DocField{
+						{Name: "p256dh", Type: apidocs.TYPE_STRING, Required: true},
+						{Name: "auth", Type: apidocs.TYPE_STRING, Required: true},
+					}},
+				},
+			},
+		},
+		{
+			Group:        "Notifications",
+			Path:         config.GlobalConfig.APIPrefix + "/notification/webpush/subscriptions",
+			Method:       http.MethodDelete,
+			AuthRequired: true,
+			Desc:         "Remove the browser push subscription given in the endpoint query parameter",
+		},
+
+		// ==================== Webhooks ====================
+		{
+			Group:        "Webhooks",
+			Path:         config.GlobalConfig.APIPrefix + "/webhooks/events",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List the platform events a webhook can subscribe to",
+		},
+		{
+			Group:        "Webhooks",
+			Path:         config.GlobalConfig.APIPrefix + "/webhooks",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List the webhooks of the current user",
+		},
+		{
+			Group:        "Webhooks",
+			Path:         config.GlobalConfig.APIPrefix + "/webhooks",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Create a webhook. The signing secret is only returned here; requests carry X-LingEcho-Signature: sha256=HMAC-SHA256(secret, timestamp + \".\" + body)",
+			Request: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "name", Type: apidocs.TYPE_STRING},
+					{Name: "url", Type: apidocs.TYPE_STRING, Required: true, Desc: "http(s) endpoint receiving the POST callbacks"},
+					{Name: "events", Type: "array", Desc: "call.ended, transcript.ready, training.completed; empty subscribes to all"},
+					{Name: "enabled", Type: apidocs.TYPE_BOOLEAN},
+				},
+			},
+		},
+		{
+			Group:        "Webhooks",
+			Path:         config.GlobalConfig.APIPrefix + "/webhooks/:id",
+			Method:       http.MethodPut,
+			AuthRequired: true,
+			Desc:         "Update a webhook's name, url, events or enabled flag",
+			Request: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "name", Type: apidocs.TYPE_STRING},
+					{Name: "url", Type: apidocs.TYPE_STRING, Required: false, Desc: "http(s) endpoint receiving the POST callbacks"},
+					{Name: "events", Type: "array", Desc: "call.ended, transcript.ready, training.completed; empty subscribes to all"},
+					{Name: "enabled", Type: apidocs.TYPE_BOOLEAN},
+				},
+			},
+		},
+		{
+			Group:        "Webhooks",
+			Path:         config.GlobalConfig.APIPrefix + "/webhooks/:id",
+			Method:       http.MethodDelete,
+			AuthRequired: true,
+			Desc:         "Delete a webhook and its delivery log",
+		},
+		{
+			Group:        "Webhooks",
+			Path:         config.GlobalConfig.APIPrefix + "/webhooks/:id/secret",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Generate a new signing secret; the old one stops working immediately",
+		},
+		{
+			Group:        "Webhooks",
+			Path:         config.GlobalConfig.APIPrefix + "/webhooks/:id/test",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Send a webhook.ping event and return the delivery result",
+		},
+		{
+			Group:        "Webhooks",
+			Path:         config.GlobalConfig.APIPrefix + "/webhooks/:id/deliveries",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Delivery log of a webhook, newest first; supports page, pageSize and status (pending, succeeded, failed). Failed deliveries are retried with exponential backoff up to 8 attempts",
+		},
+		{
+			Group:        "Webhooks",
+			Path:         config.GlobalConfig.APIPrefix + "/webhooks/:id/deliveries/:deliveryId/redeliver",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Send a finished delivery again once and return the result",
+		},
+
+		// ==================== Billing ====================
+		{
+			Group:        "Billing",
+			Path:         config.GlobalConfig.APIPrefix + "/billing/plans",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List the billing plans available for subscription",
+		},
+		{
+			Group:        "Billing",
+			Path:         config.GlobalConfig.APIPrefix + "/billing/plan",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Get the current plan and this month's statement with usage against the plan quotas",
+		},
+		{
+			Group:        "Billing",
+			Path:         config.GlobalConfig.APIPrefix + "/billing/plan",
+			Method:       http.MethodPut,
+			AuthRequired: true,
+			Desc:         "Subscribe to a billing plan, effective from the current period",
+			Request: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "planId", Type: apidocs.TYPE_INT, Required: true},
+				},
+			},
+		},
+		{
+			Group:        "Billing",
+			Path:         config.GlobalConfig.APIPrefix + "/billing/statements",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List monthly usage statements, newest period first",
+		},
+		{
+			Group:        "Billing",
+			Path:         config.GlobalConfig.APIPrefix + "/billing/statements",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Generate or refresh the statement of a period; finished periods are finalized",
+			Request: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "period", Type: apidocs.TYPE_STRING, Required: true, Desc: "YYYY-MM"},
+				},
+			},
+		},
+		{
+			Group:        "Billing",
+			Path:         config.GlobalConfig.APIPrefix + "/billing/statements/:id",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Get a statement with line items and per-credential usage",
+		},
+		{
+			Group:        "Billing",
+			Path:         config.GlobalConfig.APIPrefix + "/billing/statements/:id/export",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Download a statement as an invoice, format=csv (default) or json",
+		},
 
 		// ==================== Groups ====================
 		{
@@ -1070,7 +1420,14 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			Path:         config.GlobalConfig.APIPrefix + "/chat/call",
 			Method:       http.MethodGet,
 			AuthRequired: true,
-			Desc:         "Handle WebRTC connection for real-time voice chat",
+			Desc:         "Handle WebRTC connection for real-time voice chat (query codec: pcma, pcmu, g722 or opus; default pcma; ns=true enables noise suppression; redundancy=true negotiates NACK and RED)",
+		},
+		{
+			Group:        "Chat",
+			Path:         config.GlobalConfig.APIPrefix + "/chat/ice-servers",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Get STUN/TURN servers (with short-lived TURN credentials), ICE transport policy and, when WEBRTC_CERT_FILE is set, the server DTLS fingerprints for the browser peer connection",
 		},
 
 		// ==================== Credentials ====================
@@ -1108,6 +1465,168 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			AuthRequired: true,
 			Desc:         "Delete a credential",
 		},
+		{
+			Group:        "Credentials",
+			Path:         config.GlobalConfig.APIPrefix + "/credentials/:id/mcp-scopes",
+			Method:       http.MethodPut,
+			AuthRequired: true,
+			Desc:         "Restrict which MCP tools (tool:<pattern>) and agents (agent:<pattern>) the credential may invoke; empty means unrestricted",
+			Request:      apidocs.GetDocDefine(UpdateCredentialMCPScopesRequest{}),
+			Response: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "mcpScopes", Type: apidocs.TYPE_STRING},
+				},
+			},
+		},
+		{
+			Group:        "Credentials",
+			Path:         config.GlobalConfig.APIPrefix + "/credentials/:id/storage-quota",
+			Method:       http.MethodPut,
+			AuthRequired: true,
+			Desc:         "Limit the bytes of storage files uploaded with the credential may occupy; 0 means unlimited. Uploads over the limit are rejected with 413",
+			Request:      apidocs.GetDocDefine(UpdateCredentialStorageQuotaRequest{}),
+			Response: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "storageQuota", Type: apidocs.TYPE_INT},
+				},
+			},
+		},
+		{
+			Group:        "Credentials",
+			Path:         config.GlobalConfig.APIPrefix + "/credentials/:id/usage-limits",
+			Method:       http.MethodPut,
+			AuthRequired: true,
+			Desc:         "Set the credential's monthly LLM token, TTS character, ASR second and WebRTC minute limits; 0 means unlimited. quotaAction reject returns 402 once a limit is reached, degrade keeps one-shot text working without speech synthesis",
+			Request:      apidocs.GetDocDefine(models.CredentialUsageLimits{}),
+			Response:     apidocs.GetDocDefine(models.CredentialUsageLimits{}),
+		},
+		{
+			Group:        "Credentials",
+			Path:         config.GlobalConfig.APIPrefix + "/credentials/:id/usage",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Get the credential's usage counters and limits for the current month, or for period=YYYY-MM",
+			Response: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "period", Type: apidocs.TYPE_STRING},
+					{Name: "usage", Type: apidocs.TYPE_OBJECT},
+					{Name: "limits", Type: apidocs.TYPE_OBJECT},
+				},
+			},
+		},
+		{
+			Group:        "Credentials",
+			Path:         config.GlobalConfig.APIPrefix + "/credentials/:id/tts-health",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Probe the credential's TTS provider and each provider in ttsConfig.fallbacks. Providers that fail repeatedly are skipped for a short cooldown and synthesis falls back to the next one in order",
+			Response: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "providers", Type: apidocs.TYPE_OBJECT},
+				},
+			},
+		},
+		{
+			Group:        "Credentials",
+			Path:         config.GlobalConfig.APIPrefix + "/credentials/:id/tokens",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Issue a bearer API token from the credential with scopes voice:oneshot, tts:synthesize and/or asr:stream; expiresIn is in seconds, 0 means no expiry. The token is only returned once",
+			Request:      apidocs.GetDocDefine(CreateAPITokenRequest{}),
+			Response: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "token", Type: apidocs.TYPE_STRING},
+					{Name: "id", Type: apidocs.TYPE_INT},
+					{Name: "scopes", Type: apidocs.TYPE_STRING},
+					{Name: "expiresAt", Type: apidocs.TYPE_DATE, CanNull: true},
+				},
+			},
+		},
+		{
+			Group:        "Credentials",
+			Path:         config.GlobalConfig.APIPrefix + "/credentials/:id/tokens",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List API tokens issued from the credential, including revoked ones",
+		},
+		{
+			Group:        "Credentials",
+			Path:         config.GlobalConfig.APIPrefix + "/credentials/:id/tokens/:tokenId",
+			Method:       http.MethodDelete,
+			AuthRequired: true,
+			Desc:         "Revoke an API token; it stops working immediately",
+		},
+		{
+			Group:  "Credentials",
+			Path:   config.GlobalConfig.APIPrefix + "/oauth/token",
+			Method: http.MethodPost,
+			Desc:   "OAuth2 client_credentials grant: exchange apiKey/apiSecret (as client_id/client_secret, form fields or HTTP Basic) and a space separated scope for a short-lived bearer API token",
+			Response: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "access_token", Type: apidocs.TYPE_STRING},
+					{Name: "token_type", Type: apidocs.TYPE_STRING},
+					{Name: "expires_in", Type: apidocs.TYPE_INT},
+					{Name: "scope", Type: apidocs.TYPE_STRING},
+				},
+			},
+		},
+		{
+			Group:        "Credentials",
+			Path:         config.GlobalConfig.APIPrefix + "/credentials/:id/mcp-invocations",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List recent MCP tool invocations made with the credential (query: limit, default 50, max 200)",
+			Response: &apidocs.DocField{
+				Type:   "array",
+				Fields: apidocs.GetDocDefine(models.MCPInvocation{}).Fields,
+			},
+		},
+
+		// ==================== MCP Tools ====================
+		{
+			Group:        "MCP Tools",
+			Path:         config.GlobalConfig.APIPrefix + "/mcp/tools",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List runtime-defined HTTP tools exposed by the MCP servers (admin only)",
+			Response: &apidocs.DocField{
+				Type:   "array",
+				Fields: apidocs.GetDocDefine(models.MCPTool{}).Fields,
+			},
+		},
+		{
+			Group:        "MCP Tools",
+			Path:         config.GlobalConfig.APIPrefix + "/mcp/tools",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Define an HTTP-backed MCP tool (name, JSON schema, endpoint, auth); it is exposed immediately without redeploy (admin only)",
+			Request:      apidocs.GetDocDefine(MCPToolRequest{}),
+			Response: &apidocs.DocField{
+				Type:   "object",
+				Fields: apidocs.GetDocDefine(models.MCPTool{}).Fields,
+			},
+		},
+		{
+			Group:        "MCP Tools",
+			Path:         config.GlobalConfig.APIPrefix + "/mcp/tools/:id",
+			Method:       http.MethodPut,
+			AuthRequired: true,
+			Desc:         "Update an HTTP-backed MCP tool; an empty authSecret keeps the stored one (admin only)",
+			Request:      apidocs.GetDocDefine(MCPToolRequest{}),
+		},
+		{
+			Group:        "MCP Tools",
+			Path:         config.GlobalConfig.APIPrefix + "/mcp/tools/:id",
+			Method:       http.MethodDelete,
+			AuthRequired: true,
+			Desc:         "Delete an HTTP-backed MCP tool (admin only)",
+		},
 
 		// ==================== Knowledge Base ====================
 		{
@@ -1155,6 +1674,96 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			AuthRequired: true,
 			Desc:         "Upload file to knowledge base",
 		},
+		{
+			Group:        "Knowledge Base",
+			Path:         config.GlobalConfig.APIPrefix + "/knowledge/ingest",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Queue a document (pdf, docx, md, html, txt) for chunking, embedding and upsert",
+			Request: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "file", Type: apidocs.TYPE_STRING, Required: true},
+					{Name: "knowledgeKey", Type: apidocs.TYPE_STRING, Required: true},
+					{Name: "documentId", Type: apidocs.TYPE_STRING},
+					{Name: "chunkStrategy", Type: apidocs.TYPE_STRING, Desc: "fixed, sentence (default) or semantic"},
+					{Name: "chunkSize", Type: apidocs.TYPE_INT},
+					{Name: "chunkOverlap", Type: apidocs.TYPE_INT},
+					{Name: "semanticThreshold", Type: apidocs.TYPE_FLOAT},
+				},
+			},
+		},
+		{
+			Group:        "Knowledge Base",
+			Path:         config.GlobalConfig.APIPrefix + "/knowledge/ingest",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List the ingestion jobs of a knowledge base (query: knowledgeKey)",
+		},
+		{
+			Group:        "Knowledge Base",
+			Path:         config.GlobalConfig.APIPrefix + "/knowledge/ingest/:id",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Get the progress of an ingestion job",
+		},
+		{
+			Group:        "Knowledge Base",
+			Path:         config.GlobalConfig.APIPrefix + "/knowledge/sync-sources",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Add an S3, web or Notion export source synced into a knowledge base",
+		},
+		{
+			Group:        "Knowledge Base",
+			Path:         config.GlobalConfig.APIPrefix + "/knowledge/sync-sources",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List knowledge base sync sources (query: knowledgeKey)",
+		},
+		{
+			Group:        "Knowledge Base",
+			Path:         config.GlobalConfig.APIPrefix + "/knowledge/sync-sources/:id",
+			Method:       http.MethodPut,
+			AuthRequired: true,
+			Desc:         "Update a knowledge base sync source",
+		},
+		{
+			Group:        "Knowledge Base",
+			Path:         config.GlobalConfig.APIPrefix + "/knowledge/sync-sources/:id",
+			Method:       http.MethodDelete,
+			AuthRequired: true,
+			Desc:         "Delete a knowledge base sync source",
+		},
+		{
+			Group:        "Knowledge Base",
+			Path:         config.GlobalConfig.APIPrefix + "/knowledge/sync-sources/:id/run",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Sync a knowledge base source now",
+		},
+
+		// ==================== Agents ====================
+		{
+			Group:        "Agents",
+			Path:         config.GlobalConfig.APIPrefix + "/agents/run",
+			Method:       http.MethodPost,
+			AuthRequired: true,
+			Desc:         "Decompose a request with the planner agent, run sub-tasks on RAG/graph/LLM agents in parallel and merge the results",
+			Request: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "content", Type: apidocs.TYPE_STRING, Required: true},
+					{Name: "credentialId", Type: apidocs.TYPE_INT, Required: true},
+					{Name: "assistantId", Type: apidocs.TYPE_INT, Desc: "Use the assistant's knowledge base, graph memory, prompt and model"},
+					{Name: "knowledgeBaseId", Type: apidocs.TYPE_STRING},
+					{Name: "graphMemory", Type: apidocs.TYPE_BOOLEAN},
+					{Name: "sessionId", Type: apidocs.TYPE_STRING},
+					{Name: "model", Type: apidocs.TYPE_STRING},
+					{Name: "parameters", Type: apidocs.TYPE_MAP},
+				},
+			},
+		},
 
 		// ==================== Xunfei TTS ====================
 		{
@@ -1162,7 +1771,7 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			Path:         config.GlobalConfig.APIPrefix + "/xunfei/synthesize",
 			Method:       http.MethodPost,
 			AuthRequired: true,
-			Desc:         "Xunfei text-to-speech synthesis",
+			Desc:         "Xunfei text-to-speech synthesis; send an Idempotency-Key header to retry safely, retries within 24 hours return the first successful response",
 			Request: &apidocs.DocField{
 				Type: "object",
 				Fields: []apidocs.DocField{
@@ -1184,7 +1793,7 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			Path:         config.GlobalConfig.APIPrefix + "/xunfei/task/create",
 			Method:       http.MethodPost,
 			AuthRequired: true,
-			Desc:         "Create Xunfei voice training task",
+			Desc:         "Create Xunfei voice training task; send an Idempotency-Key header to retry safely, retries within 24 hours return the first successful response",
 			Request: &apidocs.DocField{
 				Type: "object",
 				Fields: []apidocs.DocField{
@@ -1235,7 +1844,7 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			Path:         config.GlobalConfig.APIPrefix + "/voice/training/create",
 			Method:       http.MethodPost,
 			AuthRequired: true,
-			Desc:         "Create voice training task",
+			Desc:         "Create voice training task; send an Idempotency-Key header to retry safely, retries within 24 hours return the first successful response",
 			Request: &apidocs.DocField{
 				Type: "object",
 				Fields: []apidocs.DocField{
@@ -1251,7 +1860,7 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			Path:         config.GlobalConfig.APIPrefix + "/voice/training/submit-audio",
 			Method:       http.MethodPost,
 			AuthRequired: true,
-			Desc:         "Submit audio file for voice training",
+			Desc:         "Submit audio file for voice training. The recording is decoded, checked for duration, clipping and speech, trimmed of leading/trailing silence, loudness-normalized and resampled to the provider spec before upload; the response reports the analysis",
 		},
 		{
 			Group:        "Voice Training",
@@ -1310,7 +1919,7 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			Path:         config.GlobalConfig.APIPrefix + "/voice/synthesize",
 			Method:       http.MethodPost,
 			AuthRequired: true,
-			Desc:         "Synthesize speech with trained voice",
+			Desc:         "Synthesize speech with trained voice; send an Idempotency-Key header to retry safely, retries within 24 hours return the first successful response",
 			Request: &apidocs.DocField{
 				Type: "object",
 				Fields: []apidocs.DocField{
@@ -1347,7 +1956,7 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			Path:         config.GlobalConfig.APIPrefix + "/voice/oneshot_text",
 			Method:       http.MethodPost,
 			AuthRequired: true,
-			Desc:         "One-shot text synthesis",
+			Desc:         "One-shot text synthesis. Authenticate with Authorization: Bearer <API token> (scope voice:oneshot) instead of apiKey/apiSecret in the body",
 		},
 		{
 			Group:        "Voice Training",
@@ -1363,6 +1972,20 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			AuthRequired: true,
 			Desc:         "Get voice options list based on TTS provider",
 		},
+		{
+			Group:        "Voice Training",
+			Path:         config.GlobalConfig.APIPrefix + "/voice/clone-providers",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List voice clone providers with supported languages, sample rates, audio limits, training flow and whether each is configured",
+		},
+		{
+			Group:        "Voice Training",
+			Path:         config.GlobalConfig.APIPrefix + "/voice/clone-providers/:provider/quota",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Query remaining training times for a provider that supports it. Query parameter taskId is the training task or speaker ID",
+		},
 
 		// ==================== WebSocket ====================
 		{
@@ -1393,6 +2016,64 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 			AuthRequired: true,
 			Desc:         "WebSocket health check",
 		},
+		{
+			Group:        "Recordings",
+			Path:         config.GlobalConfig.APIPrefix + "/recordings",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "List call recordings, filterable by source and sessionId, paginated with page/pageSize",
+		},
+		{
+			Group:        "Recordings",
+			Path:         config.GlobalConfig.APIPrefix + "/recordings/:id/download",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Download a call recording (audio/wav or audio/ogg)",
+		},
+		{
+			Group:        "Recordings",
+			Path:         config.GlobalConfig.APIPrefix + "/recordings/:id",
+			Method:       http.MethodDelete,
+			AuthRequired: true,
+			Desc:         "Delete a call recording and its file",
+		},
+		{
+			Group:        "Recordings",
+			Path:         config.GlobalConfig.APIPrefix + "/recordings/policy",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Get the user's recording policy; recording is disabled until a policy is saved",
+		},
+		{
+			Group:        "Recordings",
+			Path:         config.GlobalConfig.APIPrefix + "/recordings/policy",
+			Method:       http.MethodPut,
+			AuthRequired: true,
+			Desc:         "Update the user's recording policy",
+			Request: &apidocs.DocField{
+				Type: "object",
+				Fields: []apidocs.DocField{
+					{Name: "enabled", Type: apidocs.TYPE_BOOLEAN},
+					{Name: "retentionDays", Type: apidocs.TYPE_INT, Desc: "Days to keep recordings, 0 keeps them forever"},
+					{Name: "mode", Type: apidocs.TYPE_STRING, Desc: "stereo (caller left, AI right) or mixed"},
+					{Name: "format", Type: apidocs.TYPE_STRING, Desc: "wav or opus"},
+				},
+			},
+		},
+		{
+			Group:        "Calls",
+			Path:         config.GlobalConfig.APIPrefix + "/calls/:id/transcript",
+			Method:       http.MethodGet,
+			AuthRequired: true,
+			Desc:         "Get the transcript of a call by session ID: speaker, start/end offsets in ms, text and confidence. ?format=txt downloads plain text",
+		},
+		{
+			Group:        "Calls",
+			Path:         config.GlobalConfig.APIPrefix + "/calls/:id/transcript",
+			Method:       http.MethodDelete,
+			AuthRequired: true,
+			Desc:         "Delete the transcript of a call",
+		},
 		{
 			Group:        "WebSocket",
 			Path:         config.GlobalConfig.APIPrefix + "/ws/user/:user_id",
@@ -1535,6 +2216,17 @@ func (h *Handlers) GetDocs() []apidocs.UriDoc {
 					},
 				},
 			},
+			{
+				Group:        "Search",
+				Path:         config.GlobalConfig.APIPrefix + "/search/rebuild",
+				Method:       http.MethodPost,
+				AuthRequired: true,
+				Desc:         "Clear the search index and re-index all data in the background (admin only)",
+				Response: &apidocs.DocField{
+					Type: apidocs.TYPE_BOOLEAN,
+					Desc: "true if the rebuild has started",
+				},
+			},
 		}...)
 	}
 	return uriDocs
@@ -18,6 +18,7 @@ import (
 	"github.com/code-100-precent/LingEcho/pkg/constants"
 	"github.com/code-100-precent/LingEcho/pkg/knowledge"
 	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/go-resty/resty/v2"
 )
@@ -41,24 +42,24 @@ func getAliyunConfig() map[string]interface{} {
 // getMilvusConfig gets Milvus knowledge base config from config file
 func getMilvusConfig() map[string]interface{} {
 	cfg := config.GlobalConfig
-	return map[string]interface{}{
+	return withEmbeddingConfig(map[string]interface{}{
 		knowledge.ConfigKeyMilvusAddress:        cfg.MilvusAddress,
 		knowledge.ConfigKeyMilvusUsername:       cfg.MilvusUsername,
 		knowledge.ConfigKeyMilvusPassword:       cfg.MilvusPassword,
 		knowledge.ConfigKeyMilvusCollectionName: cfg.MilvusCollection,
 		knowledge.ConfigKeyMilvusDimension:      cfg.MilvusDimension,
-	}
+	})
 }
 
 // getQdrantConfig gets Qdrant knowledge base config from config file
 func getQdrantConfig() map[string]interface{} {
 	cfg := config.GlobalConfig
-	return map[string]interface{}{
+	return withEmbeddingConfig(map[string]interface{}{
 		knowledge.ConfigKeyQdrantBaseURL:        cfg.QdrantBaseURL,
 		knowledge.ConfigKeyQdrantApiKey:         cfg.QdrantApiKey,
 		knowledge.ConfigKeyQdrantCollectionName: cfg.QdrantCollection,
 		knowledge.ConfigKeyQdrantDimension:      cfg.QdrantDimension,
-	}
+	})
 }
 
 // getElasticsearchConfig gets Elasticsearch knowledge base config from config file
@@ -75,12 +76,53 @@ func getElasticsearchConfig() map[string]interface{} {
 // getPineconeConfig gets Pinecone knowledge base config from config file
 func getPineconeConfig() map[string]interface{} {
 	cfg := config.GlobalConfig
-	return map[string]interface{}{
+	return withEmbeddingConfig(map[string]interface{}{
 		knowledge.ConfigKeyPineconeApiKey:    cfg.PineconeApiKey,
 		knowledge.ConfigKeyPineconeBaseURL:   cfg.PineconeBaseURL,
 		knowledge.ConfigKeyPineconeIndexName: cfg.PineconeIndexName,
 		knowledge.ConfigKeyPineconeDimension: cfg.PineconeDimension,
+	})
+}
+
+// withEmbeddingConfig adds the embedding config shared by the vector store providers
+func withEmbeddingConfig(kbConfig map[string]interface{}) map[string]interface{} {
+	cfg := config.GlobalConfig
+	if cfg.EmbeddingProvider == "" {
+		return kbConfig
+	}
+	kbConfig[knowledge.ConfigKeyEmbeddingProvider] = cfg.EmbeddingProvider
+	kbConfig[knowledge.ConfigKeyEmbeddingModel] = cfg.EmbeddingModel
+	kbConfig[knowledge.ConfigKeyEmbeddingApiKey] = cfg.EmbeddingApiKey
+	kbConfig[knowledge.ConfigKeyEmbeddingBaseURL] = cfg.EmbeddingBaseURL
+	kbConfig[knowledge.ConfigKeyEmbeddingDimension] = cfg.EmbeddingDimension
+	kbConfig[knowledge.ConfigKeyEmbeddingBatchSize] = cfg.EmbeddingBatchSize
+	return kbConfig
+}
+
+// retrievalFormFields are the optional form fields selecting how a knowledge base is searched
+var retrievalFormFields = []string{
+	knowledge.ConfigKeyRetrievalMode,
+	knowledge.ConfigKeyRRFK,
+	knowledge.ConfigKeyRerankProvider,
+	knowledge.ConfigKeyRerankBaseURL,
+	knowledge.ConfigKeyRerankApiKey,
+	knowledge.ConfigKeyRerankModel,
+}
+
+// withRetrievalConfig adds the retrieval settings (hybrid search, rerank) given in the request form.
+// A custom rerank base URL must point to a public host.
+func withRetrievalConfig(c *gin.Context, kbConfig map[string]interface{}) (map[string]interface{}, error) {
+	for _, key := range retrievalFormFields {
+		if value := c.PostForm(key); value != "" {
+			kbConfig[key] = value
+		}
+	}
+	if baseURL, _ := kbConfig[knowledge.ConfigKeyRerankBaseURL].(string); baseURL != "" {
+		if err := utils.ValidatePublicURL(c.Request.Context(), baseURL); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", knowledge.ConfigKeyRerankBaseURL, err)
+		}
 	}
+	return kbConfig, nil
 }
 
 // getKnowledgeBaseConfig gets config for the specified provider
@@ -194,8 +236,12 @@ func (h *Handlers) CreateKnowledgeBase(c *gin.Context) {
 		return
 	}
 
-	// 5. Get config for the specified provider
-	config := getKnowledgeBaseConfig(provider)
+	// 5. Get config for the specified provider, plus the retrieval settings of this knowledge base
+	config, err := withRetrievalConfig(c, getKnowledgeBaseConfig(provider))
+	if err != nil {
+		response.Fail(c, err.Error(), nil)
+		return
+	}
 
 	// 6. Generate knowledge base key (userID + knowledge name)
 	knowledgeKey := models.GenerateKnowledgeKey(userId, knowledgeName)
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/constants"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/ingest"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxIngestFileSize bounds the documents accepted for ingestion
+const maxIngestFileSize = 50 << 20
+
+// IngestKnowledgeDocument queues a document for extraction, chunking,
+// embedding and upsert into a knowledge base, returning the job to poll
+func (h *Handlers) IngestKnowledgeDocument(c *gin.Context) {
+	// 1. Receive file
+	file, header, err := c.Request.FormFile(constants.FormFieldFile)
+	if err != nil {
+		response.Fail(c, knowledge.ErrFileReceiveFailed, err)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxIngestFileSize+1))
+	if err != nil {
+		response.Fail(c, knowledge.ErrFileReceiveFailed, err)
+		return
+	}
+	if len(data) == 0 {
+		response.Fail(c, knowledge.ErrFileEmpty, nil)
+		return
+	}
+	if len(data) > maxIngestFileSize {
+		response.Fail(c, knowledge.ErrFileTooLarge, fmt.Sprintf("max %d MB", maxIngestFileSize>>20))
+		return
+	}
+
+	// 2. Receive knowledge base key and chunking options
+	knowledgeKey := c.PostForm(constants.FormFieldKnowledgeKey)
+	if knowledgeKey == "" {
+		response.Fail(c, knowledge.ErrKnowledgeKeyRequired, nil)
+		return
+	}
+	chunking, err := chunkOptionsFromForm(c)
+	if err != nil {
+		response.Fail(c, knowledge.ErrInvalidChunkOptions, err.Error())
+		return
+	}
+
+	// 3. Get knowledge base instance
+	k, err := models.GetKnowledge(h.db, knowledgeKey)
+	if err != nil {
+		response.Fail(c, knowledge.ErrKnowledgeNotFound, err)
+		return
+	}
+	config, err := models.GetKnowledgeConfigOrDefault(k.Provider, k.Config, getKnowledgeBaseConfig)
+	if err != nil {
+		response.Fail(c, knowledge.ErrConfigParseFailed, err)
+		return
+	}
+	kb, err := knowledge.GetKnowledgeBaseByProvider(k.Provider, config)
+	if err != nil {
+		response.Fail(c, knowledge.ErrKnowledgeBaseInitFailed, err)
+		return
+	}
+
+	// 4. Queue the job
+	job, err := ingest.Default().Submit(ingest.NewPipeline(kb, chunking), ingest.Source{
+		KnowledgeKey: knowledgeKey,
+		FileName:     header.Filename,
+		Data:         data,
+		DocumentID:   c.PostForm(constants.FormFieldDocumentID),
+		Metadata: map[string]interface{}{
+			knowledge.MetadataKeyUserID: k.UserID,
+			knowledge.MetadataKeyName:   k.KnowledgeName,
+			knowledge.MetadataKeySource: knowledge.MetadataSourceAPIUpload,
+		},
+	})
+	if err != nil {
+		response.Fail(c, knowledge.ErrIngestSubmitFailed, err.Error())
+		return
+	}
+	response.Success(c, "ingestion queued", job)
+}
+
+// GetKnowledgeIngestJob returns the progress of an ingestion job
+func (h *Handlers) GetKnowledgeIngestJob(c *gin.Context) {
+	job, ok := ingest.Default().Get(c.Param("id"))
+	if !ok {
+		response.Fail(c, knowledge.ErrIngestJobNotFound, nil)
+		return
+	}
+	response.Success(c, "retrieved successfully", job)
+}
+
+// ListKnowledgeIngestJobs lists the ingestion jobs of a knowledge base
+func (h *Handlers) ListKnowledgeIngestJobs(c *gin.Context) {
+	knowledgeKey := c.Query(constants.QueryParamKnowledgeKey)
+	if knowledgeKey == "" {
+		response.Fail(c, knowledge.ErrKnowledgeKeyRequired, nil)
+		return
+	}
+	response.Success(c, "retrieved successfully", ingest.Default().List(knowledgeKey))
+}
+
+// chunkOptionsFromForm reads the chunking options of an ingestion request,
+// empty fields keep the defaults
+func chunkOptionsFromForm(c *gin.Context) (ingest.ChunkOptions, error) {
+	opts := ingest.ChunkOptions{Strategy: c.PostForm(constants.FormFieldChunkStrategy)}
+	switch opts.Strategy {
+	case "", ingest.StrategyFixed, ingest.StrategySentence, ingest.StrategySemantic:
+	default:
+		return opts, fmt.Errorf("unsupported chunking strategy: %s", opts.Strategy)
+	}
+	if value := c.PostForm(constants.FormFieldChunkSize); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return opts, errors.New("chunkSize must be a positive integer")
+		}
+		opts.Size = size
+	}
+	if value := c.PostForm(constants.FormFieldChunkOverlap); value != "" {
+		overlap, err := strconv.Atoi(value)
+		if err != nil {
+			return opts, errors.New("chunkOverlap must be an integer")
+		}
+		if overlap == 0 {
+			// An explicit 0 disables the overlap
+			overlap = -1
+		}
+		opts.Overlap = overlap
+	}
+	if value := c.PostForm(constants.FormFieldSemanticThreshold); value != "" {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			return opts, errors.New("semanticThreshold must be in (0, 1]")
+		}
+		opts.SemanticThreshold = threshold
+	}
+	return opts, nil
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/internal/task"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/connector"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/ingest"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// minKnowledgeSyncInterval is the shortest sync interval in minutes
+const minKnowledgeSyncInterval = 15
+
+// KnowledgeSyncSourceRequest creates or updates a knowledge base sync source
+type KnowledgeSyncSourceRequest struct {
+	KnowledgeKey string `json:"knowledgeKey"`
+	Name         string `json:"name"`
+	// Type is s3, web or notion
+	Type string `json:"type"`
+	// Config is the connector config:
+	//   s3: endpoint, region, bucket, prefix, access_key_id, secret_access_key, insecure
+	//   web: urls, sitemap, max_pages
+	//   notion: path (export zip or directory on the server) or url (export zip)
+	Config        map[string]interface{} `json:"config"`
+	Interval      int                    `json:"interval"` // minutes
+	Enabled       *bool                  `json:"enabled"`
+	ChunkStrategy string                 `json:"chunkStrategy"`
+	ChunkSize     int                    `json:"chunkSize"`
+	ChunkOverlap  int                    `json:"chunkOverlap"`
+}
+
+// CreateKnowledgeSyncSource adds a source the knowledge base is synced from
+func (h *Handlers) CreateKnowledgeSyncSource(c *gin.Context) {
+	user := models.CurrentUser(c)
+	var req KnowledgeSyncSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "invalid request", err.Error())
+		return
+	}
+	if req.KnowledgeKey == "" {
+		response.Fail(c, knowledge.ErrKnowledgeKeyRequired, nil)
+		return
+	}
+	k, err := models.GetKnowledge(h.db, req.KnowledgeKey)
+	if err != nil || k.UserID != int(user.ID) {
+		response.Fail(c, knowledge.ErrKnowledgeNotFound, nil)
+		return
+	}
+
+	source := &models.KnowledgeSyncSource{
+		UserID:       user.ID,
+		KnowledgeKey: req.KnowledgeKey,
+		Name:         req.Name,
+		Type:         req.Type,
+		Enabled:      true,
+	}
+	if source.Name == "" {
+		source.Name = req.Type
+	}
+	if err := applyKnowledgeSyncRequest(c.Request.Context(), source, &req); err != nil {
+		response.Fail(c, "invalid sync source", err.Error())
+		return
+	}
+	if err := h.db.Create(source).Error; err != nil {
+		response.Fail(c, "failed to create sync source", err.Error())
+		return
+	}
+	response.Success(c, "created successfully", source)
+}
+
+// ListKnowledgeSyncSources lists the sync sources of a knowledge base
+func (h *Handlers) ListKnowledgeSyncSources(c *gin.Context) {
+	user := models.CurrentUser(c)
+	query := h.db.Where("user_id = ?", user.ID)
+	if knowledgeKey := c.Query("knowledgeKey"); knowledgeKey != "" {
+		query = query.Where("knowledge_key = ?", knowledgeKey)
+	}
+	var sources []models.KnowledgeSyncSource
+	if err := query.Order("id DESC").Find(&sources).Error; err != nil {
+		response.Fail(c, "failed to query sync sources", err.Error())
+		return
+	}
+	response.Success(c, "retrieved successfully", sources)
+}
+
+// UpdateKnowledgeSyncSource changes the config, interval or chunking of a
+// sync source, or pauses it. Changed chunking applies to changed documents.
+func (h *Handlers) UpdateKnowledgeSyncSource(c *gin.Context) {
+	source, ok := h.ownedKnowledgeSyncSource(c)
+	if !ok {
+		return
+	}
+	var req KnowledgeSyncSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "invalid request", err.Error())
+		return
+	}
+	if req.Name != "" {
+		source.Name = req.Name
+	}
+	req.Type = source.Type
+	if req.Config == nil {
+		// Keep the stored config (and its secrets)
+		req.Config, _ = source.ConfigMap()
+	}
+	if err := applyKnowledgeSyncRequest(c.Request.Context(), source, &req); err != nil {
+		response.Fail(c, "invalid sync source", err.Error())
+		return
+	}
+	if err := h.db.Save(source).Error; err != nil {
+		response.Fail(c, "failed to update sync source", err.Error())
+		return
+	}
+	response.Success(c, "updated successfully", source)
+}
+
+// DeleteKnowledgeSyncSource removes a sync source. Documents already synced
+// stay in the knowledge base.
+func (h *Handlers) DeleteKnowledgeSyncSource(c *gin.Context) {
+	source, ok := h.ownedKnowledgeSyncSource(c)
+	if !ok {
+		return
+	}
+	if err := models.DeleteKnowledgeSyncSource(h.db, source.ID); err != nil {
+		response.Fail(c, "failed to delete sync source", err.Error())
+		return
+	}
+	response.Success(c, "deleted successfully", nil)
+}
+
+// RunKnowledgeSyncSource starts syncing a source now, the result is recorded
+// on the source (lastStatus, lastReport)
+func (h *Handlers) RunKnowledgeSyncSource(c *gin.Context) {
+	source, ok := h.ownedKnowledgeSyncSource(c)
+	if !ok {
+		return
+	}
+	if source.LastStatus == models.KnowledgeSyncStatusRunning {
+		response.Fail(c, task.ErrKnowledgeSyncRunning.Error(), nil)
+		return
+	}
+	go func() {
+		if _, err := task.SyncKnowledgeSource(h.db, source); err != nil && !errors.Is(err, task.ErrKnowledgeSyncRunning) {
+			logger.Warn("Knowledge sync failed", zap.Uint("sourceId", source.ID), zap.Error(err))
+		}
+	}()
+	response.Success(c, "sync started", nil)
+}
+
+func (h *Handlers) ownedKnowledgeSyncSource(c *gin.Context) (*models.KnowledgeSyncSource, bool) {
+	user := models.CurrentUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "invalid sync source id", nil)
+		return nil, false
+	}
+	var source models.KnowledgeSyncSource
+	if err := h.db.Where("id = ? AND user_id = ?", id, user.ID).First(&source).Error; err != nil {
+		response.Fail(c, "sync source not found", nil)
+		return nil, false
+	}
+	return &source, true
+}
+
+// applyKnowledgeSyncRequest validates req and copies it to source
+func applyKnowledgeSyncRequest(ctx context.Context, source *models.KnowledgeSyncSource, req *KnowledgeSyncSourceRequest) error {
+	if err := connector.Validate(ctx, req.Type, req.Config); err != nil {
+		return err
+	}
+	switch req.ChunkStrategy {
+	case "", ingest.StrategyFixed, ingest.StrategySentence, ingest.StrategySemantic:
+	default:
+		return errors.New("unsupported chunking strategy: " + req.ChunkStrategy)
+	}
+	config, err := json.Marshal(req.Config)
+	if err != nil {
+		return err
+	}
+	source.Config = string(config)
+	if req.Interval > 0 {
+		source.Interval = max(req.Interval, minKnowledgeSyncInterval)
+	}
+	if req.Enabled != nil {
+		source.Enabled = *req.Enabled
+	}
+	source.ChunkStrategy = req.ChunkStrategy
+	source.ChunkSize = req.ChunkSize
+	source.ChunkOverlap = req.ChunkOverlap
+	source.ApplyDefaults()
+	return nil
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	lingechoMCP "github.com/code-100-precent/LingEcho/pkg/mcp"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MCPToolRequest 创建/更新动态MCP工具请求
+type MCPToolRequest struct {
+	Name           string            `json:"name" binding:"required"`
+	Description    string            `json:"description"`
+	InputSchema    json.RawMessage   `json:"inputSchema"` // JSON Schema 对象，为空时无参数
+	Method         string            `json:"method"`      // 默认 POST
+	Endpoint       string            `json:"endpoint" binding:"required"`
+	Headers        map[string]string `json:"headers"`
+	TimeoutSeconds int               `json:"timeoutSeconds"` // 默认 30 秒
+	AuthType       string            `json:"authType"`       // none/bearer/basic/header
+	AuthHeader     string            `json:"authHeader"`
+	AuthSecret     string            `json:"authSecret"` // 更新时为空则保留原值
+	Enabled        *bool             `json:"enabled"`    // 默认启用
+}
+
+// apply 将请求写入工具定义
+func (req *MCPToolRequest) apply(tool *models.MCPTool) {
+	tool.Name = req.Name
+	tool.Description = req.Description
+	tool.InputSchema = string(req.InputSchema)
+	tool.Method = req.Method
+	tool.Endpoint = req.Endpoint
+	tool.Headers = ""
+	if len(req.Headers) > 0 {
+		headers, _ := json.Marshal(req.Headers)
+		tool.Headers = string(headers)
+	}
+	tool.TimeoutSeconds = req.TimeoutSeconds
+	if req.AuthType != tool.AuthType {
+		tool.AuthSecret = ""
+	}
+	tool.AuthType = req.AuthType
+	tool.AuthHeader = req.AuthHeader
+	if req.AuthSecret != "" {
+		tool.AuthSecret = req.AuthSecret
+	}
+	if req.Enabled != nil {
+		tool.Enabled = *req.Enabled
+	}
+}
+
+// ListMCPTools 获取动态MCP工具列表
+func (h *Handlers) ListMCPTools(c *gin.Context) {
+	tools, err := models.ListMCPTools(h.db)
+	if err != nil {
+		response.Fail(c, "获取工具列表失败", err.Error())
+		return
+	}
+	response.Success(c, "获取成功", tools)
+}
+
+// CreateMCPTool 创建动态MCP工具，保存后立即注册到MCP服务器
+func (h *Handlers) CreateMCPTool(c *gin.Context) {
+	var req MCPToolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "参数错误", err.Error())
+		return
+	}
+
+	tool := &models.MCPTool{Enabled: true, CreatedBy: models.CurrentUser(c).ID}
+	req.apply(tool)
+	if err := lingechoMCP.ValidateHTTPTool(tool); err != nil {
+		response.Fail(c, "工具定义无效", err.Error())
+		return
+	}
+	if lingechoMCP.Default().HasTool(tool.Name) {
+		response.Fail(c, "工具名与内置工具重名", tool.Name)
+		return
+	}
+	if err := models.SaveMCPTool(h.db, tool); err != nil {
+		response.Fail(c, "创建工具失败", err.Error())
+		return
+	}
+
+	h.syncMCPTools(c)
+	response.Success(c, "创建成功", tool)
+}
+
+// UpdateMCPTool 更新动态MCP工具
+func (h *Handlers) UpdateMCPTool(c *gin.Context) {
+	tool, ok := h.getMCPTool(c)
+	if !ok {
+		return
+	}
+	var req MCPToolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "参数错误", err.Error())
+		return
+	}
+
+	if req.Name != tool.Name && lingechoMCP.Default().HasTool(req.Name) {
+		response.Fail(c, "工具名与已有工具重名", req.Name)
+		return
+	}
+	req.apply(tool)
+	if err := lingechoMCP.ValidateHTTPTool(tool); err != nil {
+		response.Fail(c, "工具定义无效", err.Error())
+		return
+	}
+	if err := models.SaveMCPTool(h.db, tool); err != nil {
+		response.Fail(c, "更新工具失败", err.Error())
+		return
+	}
+
+	h.syncMCPTools(c)
+	response.Success(c, "更新成功", tool)
+}
+
+// DeleteMCPTool 删除动态MCP工具，MCP服务器立即移除该工具
+func (h *Handlers) DeleteMCPTool(c *gin.Context) {
+	tool, ok := h.getMCPTool(c)
+	if !ok {
+		return
+	}
+	if err := models.DeleteMCPTool(h.db, tool.ID); err != nil {
+		response.Fail(c, "删除工具失败", err.Error())
+		return
+	}
+
+	h.syncMCPTools(c)
+	response.Success(c, "删除成功", nil)
+}
+
+func (h *Handlers) getMCPTool(c *gin.Context) (*models.MCPTool, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "无效的工具ID", nil)
+		return nil, false
+	}
+	tool, err := models.GetMCPTool(h.db, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, "工具不存在", nil)
+		} else {
+			response.Fail(c, "获取工具失败", err.Error())
+		}
+		return nil, false
+	}
+	return tool, true
+}
+
+// syncMCPTools 同步本进程的MCP服务器，其他进程（如 mcp-enhanced）在下一个同步周期生效
+func (h *Handlers) syncMCPTools(c *gin.Context) {
+	if h.mcpTools == nil {
+		return
+	}
+	if _, err := h.mcpTools.Sync(c.Request.Context()); err != nil {
+		logger.Lg.Warn("同步动态MCP工具失败", zap.Error(err))
+	}
+}
@@ -1,14 +1,22 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"github.com/code-100-precent/LingEcho/pkg/notification"
 	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/code-100-precent/LingEcho/pkg/websocket"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // GetUnReadNotificationCount get user unread notification count
@@ -96,6 +104,7 @@ func (h *Handlers) handleAllNotifications(c *gin.Context) {
 		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
 		return
 	}
+	h.pushUnreadCount(user.ID)
 	response.Success(c, "already mark all notifications", nil)
 }
 
@@ -129,6 +138,7 @@ func (h *Handlers) handleMarkNotificationAsRead(c *gin.Context) {
 		return
 	}
 
+	h.pushUnreadCount(user.ID)
 	response.Success(c, "Notification marked as read", nil)
 }
 
@@ -149,6 +159,7 @@ func (h *Handlers) handleDeleteNotification(c *gin.Context) {
 		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
 		return
 	}
+	h.pushUnreadCount(user.ID)
 	response.Success(c, "Notification deleted", nil)
 }
 
@@ -181,8 +192,374 @@ func (h *Handlers) handleBatchDeleteNotifications(c *gin.Context) {
 		return
 	}
 
+	h.pushUnreadCount(user.ID)
 	response.Success(c, "Notifications deleted successfully", gin.H{
 		"deletedCount":   deletedCount,
 		"totalRequested": len(request.IDs),
 	})
 }
+
+// subscribeInboxNotifications 站内通知创建后通过 WebSocket 实时推送给用户的在线连接
+func (h *Handlers) subscribeInboxNotifications() {
+	utils.Sig().Connect(models.SigUserInbox, func(sender any, params ...any) {
+		if len(params) < 1 {
+			return
+		}
+		user, ok := sender.(*models.User)
+		if !ok {
+			return
+		}
+		item, ok := params[0].(*notification.InternalNotification)
+		if !ok {
+			return
+		}
+		h.pushInboxNotification(user.ID, item)
+	})
+}
+
+// pushInboxNotification 推送新通知及最新未读数
+func (h *Handlers) pushInboxNotification(userID uint, item *notification.InternalNotification) {
+	if h.wsHub == nil {
+		return
+	}
+	count, err := notification.NewInternalNotificationService(h.db).GetUnreadNotificationsCount(userID)
+	if err != nil {
+		logger.Warn("Failed to count unread notifications", zap.Uint("userId", userID), zap.Error(err))
+		return
+	}
+	h.wsHub.SendToUser(strconv.FormatUint(uint64(userID), 10), websocket.MessageTypeNotification, gin.H{
+		"notification": item,
+		"unreadCount":  count,
+	})
+}
+
+// pushUnreadCount 通知被标记已读或删除后同步未读数，使用户的其他页面/设备及时更新角标
+func (h *Handlers) pushUnreadCount(userID uint) {
+	if h.wsHub == nil {
+		return
+	}
+	count, err := notification.NewInternalNotificationService(h.db).GetUnreadNotificationsCount(userID)
+	if err != nil {
+		logger.Warn("Failed to count unread notifications", zap.Uint("userId", userID), zap.Error(err))
+		return
+	}
+	h.wsHub.SendToUser(strconv.FormatUint(uint64(userID), 10), websocket.MessageTypeUnreadCount, gin.H{
+		"unreadCount": count,
+	})
+}
+
+// notificationChannelRequest 通知渠道创建/更新请求
+type notificationChannelRequest struct {
+	Type       string   `json:"type"`
+	Name       string   `json:"name"`
+	WebhookURL string   `json:"webhookUrl"`
+	Secret     *string  `json:"secret"` // 为空表示更新时保留原密钥
+	Events     []string `json:"events"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// handleListNotificationChannels 获取用户的外部通知渠道
+func (h *Handlers) handleListNotificationChannels(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	channels, err := notification.NewChannelService(h.db).List(user.ID)
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, "success", channels)
+}
+
+// handleCreateNotificationChannel 新增外部通知渠道
+func (h *Handlers) handleCreateNotificationChannel(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	var req notificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request format", err)
+		return
+	}
+
+	channel := notification.UserNotificationChannel{UserID: user.ID, Enabled: true}
+	if err := applyNotificationChannelRequest(c.Request.Context(), &channel, &req); err != nil {
+		response.Fail(c, err.Error(), nil)
+		return
+	}
+	service := notification.NewChannelService(h.db)
+	if err := service.Save(&channel); err != nil {
+		response.Fail(c, "Failed to create notification channel", err.Error())
+		return
+	}
+	// gorm 在创建时会用默认值 true 覆盖零值，需单独更新
+	if !channel.Enabled {
+		h.db.Model(&channel).Update("enabled", false)
+	}
+	response.Success(c, "Notification channel created", channel)
+}
+
+// handleUpdateNotificationChannel 更新外部通知渠道
+func (h *Handlers) handleUpdateNotificationChannel(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid channel ID", nil)
+		return
+	}
+	var req notificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request format", err)
+		return
+	}
+
+	service := notification.NewChannelService(h.db)
+	channel, err := service.Get(user.ID, uint(channelID))
+	if err != nil {
+		response.Fail(c, "Notification channel not found", nil)
+		return
+	}
+	if err := applyNotificationChannelRequest(c.Request.Context(), channel, &req); err != nil {
+		response.Fail(c, err.Error(), nil)
+		return
+	}
+	if err := service.Save(channel); err != nil {
+		response.Fail(c, "Failed to update notification channel", err.Error())
+		return
+	}
+	response.Success(c, "Notification channel updated", channel)
+}
+
+// handleDeleteNotificationChannel 删除外部通知渠道
+func (h *Handlers) handleDeleteNotificationChannel(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid channel ID", nil)
+		return
+	}
+	if err := notification.NewChannelService(h.db).Delete(user.ID, uint(channelID)); err != nil {
+		response.Fail(c, "Failed to delete notification channel", err.Error())
+		return
+	}
+	response.Success(c, "Notification channel deleted", nil)
+}
+
+// handleTestNotificationChannel 向渠道发送一条测试消息
+func (h *Handlers) handleTestNotificationChannel(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid channel ID", nil)
+		return
+	}
+	service := notification.NewChannelService(h.db)
+	channel, err := service.Get(user.ID, uint(channelID))
+	if err != nil {
+		response.Fail(c, "Notification channel not found", nil)
+		return
+	}
+
+	delivery := service.Deliver(c.Request.Context(), channel, notification.Message{
+		Event:   notification.EventTest,
+		Level:   notification.LevelInfo,
+		Title:   "LingEcho test notification",
+		Content: fmt.Sprintf("This is a test message for channel \"%s\".", channel.Name),
+	})
+	if delivery.Error != "" {
+		response.Fail(c, "Failed to send test notification", delivery.Error)
+		return
+	}
+	response.Success(c, "Test notification sent", delivery)
+}
+
+// applyNotificationChannelRequest 将请求字段应用到渠道配置
+func applyNotificationChannelRequest(ctx context.Context, channel *notification.UserNotificationChannel, req *notificationChannelRequest) error {
+	if req.Type != "" {
+		channel.Type = req.Type
+	}
+	if !notification.IsChannelTypeSupported(channel.Type) {
+		return fmt.Errorf("unsupported channel type: %s", channel.Type)
+	}
+	if req.Name != "" {
+		channel.Name = req.Name
+	}
+	if channel.Name == "" {
+		channel.Name = channel.Type
+	}
+	if req.WebhookURL != "" {
+		channel.WebhookURL = req.WebhookURL
+	}
+	if err := utils.ValidatePublicURL(ctx, channel.WebhookURL); err != nil {
+		if errors.Is(err, utils.ErrBlockedAddress) {
+			return fmt.Errorf("webhook url must point to a public address")
+		}
+		return fmt.Errorf("invalid webhook url")
+	}
+	if req.Secret != nil {
+		channel.Secret = *req.Secret
+	}
+	if req.Events != nil {
+		if err := channel.SetEvents(req.Events); err != nil {
+			return err
+		}
+	}
+	if req.Enabled != nil {
+		channel.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// handleListPushDevices 获取用户已注册推送的设备
+func (h *Handlers) handleListPushDevices(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	devices, err := notification.NewPushService(h.db, nil).ListTokens(user.ID)
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, "success", devices)
+}
+
+// handleRegisterPushDevice 注册设备推送 token，App 启动或 token 刷新时调用
+func (h *Handlers) handleRegisterPushDevice(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	var req struct {
+		Platform   string `json:"platform" binding:"required"` // android, ios
+		Token      string `json:"token" binding:"required"`
+		DeviceID   string `json:"deviceId"`
+		AppVersion string `json:"appVersion"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request format", err.Error())
+		return
+	}
+	device, err := notification.NewPushService(h.db, nil).RegisterToken(user.ID, req.Platform, req.Token, req.DeviceID, req.AppVersion)
+	if err != nil {
+		response.Fail(c, "Failed to register push device", err.Error())
+		return
+	}
+	response.Success(c, "Push device registered", device)
+}
+
+// handleUnregisterPushDevice 注销设备推送 token，App 退出登录时调用
+func (h *Handlers) handleUnregisterPushDevice(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	token := c.Query("token")
+	if token == "" {
+		response.Fail(c, "token is required", nil)
+		return
+	}
+	if err := notification.NewPushService(h.db, nil).UnregisterToken(user.ID, token); err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, "Push device unregistered", nil)
+}
+
+// newWebPushSender 根据配置创建浏览器推送发送器，未配置或配置有误时返回 nil
+func newWebPushSender() *notification.WebPushSender {
+	sender, err := notification.NewWebPushSender(config.GlobalConfig.WebPush)
+	if err != nil {
+		logger.Error("Failed to initialize web push sender", zap.Error(err))
+		return nil
+	}
+	return sender
+}
+
+// handleGetWebPushKey 获取 VAPID 公钥，浏览器订阅时作为 applicationServerKey
+func (h *Handlers) handleGetWebPushKey(c *gin.Context) {
+	if h.webPush == nil {
+		response.Fail(c, "Web push is not configured", nil)
+		return
+	}
+	response.Success(c, "success", gin.H{"publicKey": h.webPush.PublicKey()})
+}
+
+// handleListWebPushSubscriptions 获取用户的浏览器推送订阅
+func (h *Handlers) handleListWebPushSubscriptions(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	subs, err := notification.NewWebPushService(h.db, nil).ListSubscriptions(user.ID)
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, "success", subs)
+}
+
+// handleSubscribeWebPush 保存浏览器推送订阅，请求体为 PushSubscription.toJSON() 的结果
+func (h *Handlers) handleSubscribeWebPush(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	var req struct {
+		Endpoint string `json:"endpoint" binding:"required"`
+		Keys     struct {
+			P256dh string `json:"p256dh" binding:"required"`
+			Auth   string `json:"auth" binding:"required"`
+		} `json:"keys"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request format", err.Error())
+		return
+	}
+	sub, err := notification.NewWebPushService(h.db, nil).Subscribe(c.Request.Context(), user.ID, req.Endpoint, req.Keys.P256dh, req.Keys.Auth, c.Request.UserAgent())
+	if err != nil {
+		response.Fail(c, "Failed to save web push subscription", err.Error())
+		return
+	}
+	response.Success(c, "Web push subscribed", sub)
+}
+
+// handleUnsubscribeWebPush 删除浏览器推送订阅，浏览器取消订阅或退出登录时调用
+func (h *Handlers) handleUnsubscribeWebPush(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	endpoint := c.Query("endpoint")
+	if endpoint == "" {
+		response.Fail(c, "endpoint is required", nil)
+		return
+	}
+	if err := notification.NewWebPushService(h.db, nil).Unsubscribe(user.ID, endpoint); err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, "Web push unsubscribed", nil)
+}
@@ -0,0 +1,441 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/constants"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/code-100-precent/LingEcho/pkg/sso"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	oauthStateSessionKey    = "oauth_state"
+	oauthStateTimeout       = 10 * time.Minute
+	oauthPendingSessionKey  = "oauth_pending_login"
+	oauthPendingMaxAttempts = 5
+)
+
+// oauthState 发起第三方登录时保存在会话中的状态，回调时校验
+type oauthState struct {
+	Provider  string    `json:"provider"`
+	State     string    `json:"state"`
+	Nonce     string    `json:"nonce"`
+	Redirect  string    `json:"redirect"`
+	LinkUser  uint      `json:"linkUser,omitempty"` // 已登录用户发起的关联操作
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// oauthPendingLogin 第三方身份校验通过但账号启用了两步验证，等待提交验证码的登录
+type oauthPendingLogin struct {
+	UserID    uint      `json:"userId"`
+	Provider  string    `json:"provider"`
+	Redirect  string    `json:"redirect"`
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// oauthCallbackURL 第三方登录的回调地址
+func oauthCallbackURL(provider string) string {
+	base := config.GlobalConfig.OAuthCallbackBase
+	if base == "" {
+		base = config.GlobalConfig.ServerUrl
+	}
+	return strings.TrimSuffix(base, "/") + config.GlobalConfig.APIPrefix + config.GlobalConfig.AuthPrefix + "/oauth/" + provider + "/callback"
+}
+
+// safeRedirect 只允许跳转到本站的相对路径，避免开放重定向
+func safeRedirect(redirect string) string {
+	if redirect == "" || !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") {
+		return "/"
+	}
+	return redirect
+}
+
+// oauthFailRedirect 登录失败时带上错误信息跳回前端
+func oauthFailRedirect(c *gin.Context, redirect, reason string) {
+	target, err := url.Parse(safeRedirect(redirect))
+	if err != nil {
+		target = &url.URL{Path: "/"}
+	}
+	query := target.Query()
+	query.Set("oauthError", reason)
+	target.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, target.String())
+}
+
+// handleOAuthProviders 列出已配置的第三方登录提供方
+func (h *Handlers) handleOAuthProviders(c *gin.Context) {
+	response.Success(c, "success", gin.H{"providers": h.ssoProviders.Names()})
+}
+
+// handleOAuthLogin 跳转到第三方授权页。已登录用户发起时，回调后将第三方账号关联到当前用户
+func (h *Handlers) handleOAuthLogin(c *gin.Context) {
+	providerName := c.Param("provider")
+	provider, err := h.ssoProviders.Get(c.Request.Context(), providerName)
+	if err != nil {
+		logger.Warn("OAuth provider unavailable", zap.String("provider", providerName), zap.Error(err))
+		if errors.Is(err, sso.ErrProviderNotConfigured) {
+			response.AbortWithStatusJSON(c, http.StatusNotFound, err)
+			return
+		}
+		response.AbortWithStatusJSON(c, http.StatusBadGateway, err)
+		return
+	}
+
+	state := oauthState{
+		Provider:  providerName,
+		State:     utils.RandText(32),
+		Nonce:     utils.RandText(32),
+		Redirect:  safeRedirect(c.Query("redirect")),
+		CreatedAt: time.Now(),
+	}
+	if user := models.CurrentUser(c); user != nil && c.Query("link") == "true" {
+		state.LinkUser = user.ID
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		response.Fail(c, "failed to start sso login", err)
+		return
+	}
+	session := sessions.Default(c)
+	session.Set(oauthStateSessionKey, string(raw))
+	if err := session.Save(); err != nil {
+		response.Fail(c, "failed to start sso login", err)
+		return
+	}
+	c.Redirect(http.StatusFound, provider.AuthCodeURL(state.State, state.Nonce))
+}
+
+// takeOAuthState 取出并删除会话中的登录状态，校验 state 与提供方
+func takeOAuthState(c *gin.Context, providerName string) (*oauthState, error) {
+	session := sessions.Default(c)
+	raw, ok := session.Get(oauthStateSessionKey).(string)
+	if !ok || raw == "" {
+		return nil, errors.New("no sso login in progress")
+	}
+	session.Delete(oauthStateSessionKey)
+	if err := session.Save(); err != nil {
+		return nil, err
+	}
+	var state oauthState
+	if err := json.Unmarshal([]byte(raw), &state); err != nil {
+		return nil, err
+	}
+	if state.Provider != providerName || state.State == "" || c.Query("state") != state.State {
+		return &state, errors.New("sso state mismatch")
+	}
+	if time.Since(state.CreatedAt) > oauthStateTimeout {
+		return &state, errors.New("sso login expired")
+	}
+	return &state, nil
+}
+
+// handleOAuthCallback 第三方授权回调：换取身份、查找或关联用户，然后按密码登录相同的流程记录设备与登录历史
+func (h *Handlers) handleOAuthCallback(c *gin.Context) {
+	providerName := c.Param("provider")
+	db := c.MustGet(constants.DbField).(*gorm.DB)
+	clientIP := c.ClientIP()
+
+	state, err := takeOAuthState(c, providerName)
+	if err != nil {
+		logger.Warn("OAuth callback rejected", zap.String("provider", providerName), zap.String("ip", clientIP), zap.Error(err))
+		redirect := ""
+		if state != nil {
+			redirect = state.Redirect
+		}
+		oauthFailRedirect(c, redirect, "invalid_state")
+		return
+	}
+	if errCode := c.Query("error"); errCode != "" {
+		oauthFailRedirect(c, state.Redirect, errCode)
+		return
+	}
+
+	// 1. IP限流检查
+	if utils.GlobalLoginSecurityManager != nil {
+		if err := utils.GlobalLoginSecurityManager.CheckIPRateLimit(clientIP); err != nil {
+			oauthFailRedirect(c, state.Redirect, "too_many_attempts")
+			return
+		}
+	}
+
+	// 2. 换取第三方身份
+	provider, err := h.ssoProviders.Get(c.Request.Context(), providerName)
+	if err != nil {
+		oauthFailRedirect(c, state.Redirect, "provider_unavailable")
+		return
+	}
+	identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), state.Nonce)
+	if err != nil {
+		logger.Warn("OAuth code exchange failed", zap.String("provider", providerName), zap.String("ip", clientIP), zap.Error(err))
+		oauthFailRedirect(c, state.Redirect, "exchange_failed")
+		return
+	}
+
+	// 3. 关联操作：将第三方账号绑定到发起关联的已登录用户
+	if state.LinkUser != 0 {
+		current := models.CurrentUser(c)
+		if current == nil || current.ID != state.LinkUser {
+			oauthFailRedirect(c, state.Redirect, "not_logged_in")
+			return
+		}
+		if existing, err := models.GetUserIdentity(db, identity.Provider, identity.Subject); err == nil && existing.UserID != current.ID {
+			oauthFailRedirect(c, state.Redirect, "identity_in_use")
+			return
+		}
+		if _, err := models.LinkUserIdentity(db, current.ID, identity.Provider, identity.Subject, identity.Email); err != nil {
+			logger.Error("Failed to link sso identity", zap.Uint("userID", current.ID), zap.String("provider", providerName), zap.Error(err))
+			oauthFailRedirect(c, state.Redirect, "link_failed")
+			return
+		}
+		logger.Info("SSO identity linked", zap.Uint("userID", current.ID), zap.String("provider", providerName))
+		c.Redirect(http.StatusFound, state.Redirect)
+		return
+	}
+
+	// 4. 查找登录用户
+	user, reason := h.resolveOAuthUser(db, identity)
+	if user == nil {
+		logger.Warn("SSO login rejected", zap.String("provider", providerName), zap.String("email", identity.Email), zap.String("reason", reason))
+		oauthFailRedirect(c, state.Redirect, reason)
+		return
+	}
+
+	// 5. 账号锁定检查
+	if utils.GlobalLoginSecurityManager != nil {
+		checkLockFunc := func(db *gorm.DB, email string, userID uint) (*utils.AccountLockInfo, error) {
+			lock, err := models.GetAccountLock(db, email, userID)
+			if err != nil || lock == nil {
+				return nil, err
+			}
+			return &utils.AccountLockInfo{
+				IsLocked: lock.IsLocked(),
+				UnlockAt: lock.UnlockAt,
+			}, nil
+		}
+		if err := utils.GlobalLoginSecurityManager.CheckAccountLock(db, user.Email, user.ID, checkLockFunc); err != nil {
+			oauthFailRedirect(c, state.Redirect, "account_locked")
+			return
+		}
+	}
+
+	// 6. 检查用户是否允许登录（激活、启用等）
+	if err := models.CheckUserAllowLogin(db, user); err != nil {
+		oauthFailRedirect(c, state.Redirect, "login_not_allowed")
+		return
+	}
+
+	// 7. 启用了两步验证时与密码登录一样先要求验证码，验证通过后才设置登录会话
+	if user.TwoFactorEnabled {
+		if err := savePendingOAuthLogin(c, &oauthPendingLogin{
+			UserID:    user.ID,
+			Provider:  providerName,
+			Redirect:  state.Redirect,
+			CreatedAt: time.Now(),
+		}); err != nil {
+			logger.Error("Failed to save pending sso login", zap.Uint("userID", user.ID), zap.Error(err))
+			oauthFailRedirect(c, state.Redirect, "login_failed")
+			return
+		}
+		target, err := url.Parse(state.Redirect)
+		if err != nil {
+			target = &url.URL{Path: "/"}
+		}
+		query := target.Query()
+		query.Set("requiresTwoFactor", "true")
+		target.RawQuery = query.Encode()
+		c.Redirect(http.StatusFound, target.String())
+		return
+	}
+
+	// 8. 记录设备与登录历史并设置登录会话，前端凭会话 Cookie 获取用户信息
+	if h.completeLogin(c, db, user, "oauth:"+providerName) == nil {
+		return
+	}
+	logger.Info("SSO login successful", zap.Uint("userID", user.ID), zap.String("provider", providerName), zap.String("ip", clientIP))
+	c.Redirect(http.StatusFound, state.Redirect)
+}
+
+// savePendingOAuthLogin 保存等待两步验证的第三方登录
+func savePendingOAuthLogin(c *gin.Context, pending *oauthPendingLogin) error {
+	raw, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	session := sessions.Default(c)
+	session.Set(oauthPendingSessionKey, string(raw))
+	return session.Save()
+}
+
+// clearPendingOAuthLogin 删除会话中等待两步验证的第三方登录
+func clearPendingOAuthLogin(c *gin.Context) {
+	session := sessions.Default(c)
+	session.Delete(oauthPendingSessionKey)
+	if err := session.Save(); err != nil {
+		logger.Warn("Failed to clear pending sso login", zap.Error(err))
+	}
+}
+
+// handleOAuthTwoFactor 提交两步验证码，完成启用了两步验证的账号的第三方登录
+func (h *Handlers) handleOAuthTwoFactor(c *gin.Context) {
+	var form struct {
+		TwoFactorCode string `json:"twoFactorCode"`
+	}
+	if err := c.ShouldBindJSON(&form); err != nil {
+		response.Fail(c, "Invalid request", err)
+		return
+	}
+	db := c.MustGet(constants.DbField).(*gorm.DB)
+	clientIP := c.ClientIP()
+
+	if utils.GlobalLoginSecurityManager != nil {
+		if err := utils.GlobalLoginSecurityManager.CheckIPRateLimit(clientIP); err != nil {
+			response.Fail(c, err.Error(), nil)
+			return
+		}
+	}
+
+	raw, _ := sessions.Default(c).Get(oauthPendingSessionKey).(string)
+	var pending oauthPendingLogin
+	if raw == "" || json.Unmarshal([]byte(raw), &pending) != nil || time.Since(pending.CreatedAt) > oauthStateTimeout {
+		clearPendingOAuthLogin(c)
+		response.Fail(c, "No sso login awaiting two-factor authentication", errors.New("no pending sso login"))
+		return
+	}
+	user, err := models.GetUserByUID(db, pending.UserID)
+	if err != nil {
+		clearPendingOAuthLogin(c)
+		response.Fail(c, "No sso login awaiting two-factor authentication", err)
+		return
+	}
+
+	if form.TwoFactorCode == "" {
+		response.Success(c, "Two-factor authentication required", gin.H{
+			"requiresTwoFactor": true,
+			"message":           "Please enter your two-factor authentication code",
+		})
+		return
+	}
+	if !totp.Validate(form.TwoFactorCode, user.TwoFactorSecret) {
+		logger.Warn("SSO login failed: invalid 2fa code", zap.Uint("userID", user.ID), zap.String("provider", pending.Provider), zap.String("ip", clientIP))
+		if utils.GlobalLoginSecurityManager != nil {
+			recordFunc := func(db *gorm.DB, email string, userID uint, ipAddress string, failedCount int) error {
+				_, err := models.CreateOrUpdateAccountLock(db, email, userID, ipAddress, failedCount)
+				return err
+			}
+			utils.GlobalLoginSecurityManager.RecordFailedLogin(db, user.Email, user.ID, clientIP, recordFunc)
+		}
+		// 多次输错后需要重新走第三方授权
+		if pending.Attempts++; pending.Attempts >= oauthPendingMaxAttempts {
+			clearPendingOAuthLogin(c)
+		} else if err := savePendingOAuthLogin(c, &pending); err != nil {
+			logger.Warn("Failed to save pending sso login", zap.Error(err))
+		}
+		response.Fail(c, "Invalid two-factor authentication code", errors.New("invalid 2fa code"))
+		return
+	}
+
+	clearPendingOAuthLogin(c)
+	if err := models.CheckUserAllowLogin(db, user); err != nil {
+		response.Fail(c, "login not allowed", err)
+		return
+	}
+	responseData := h.completeLogin(c, db, user, "oauth:"+pending.Provider)
+	if responseData == nil {
+		return
+	}
+	responseData["redirect"] = pending.Redirect
+	logger.Info("SSO login successful", zap.Uint("userID", user.ID), zap.String("provider", pending.Provider), zap.String("ip", clientIP))
+	response.Success(c, "login successful", responseData)
+}
+
+// resolveOAuthUser 查找第三方身份对应的用户：已关联的直接返回；邮箱已验证且与已有用户相同时自动关联；
+// 允许注册时创建新用户。找不到用户时返回失败原因
+func (h *Handlers) resolveOAuthUser(db *gorm.DB, identity *sso.Identity) (*models.User, string) {
+	if linked, err := models.GetUserIdentity(db, identity.Provider, identity.Subject); err == nil {
+		user, err := models.GetUserByUID(db, linked.UserID)
+		if err != nil {
+			return nil, "user_not_found"
+		}
+		if err := models.TouchUserIdentity(db, linked, identity.Email); err != nil {
+			logger.Warn("Failed to update sso identity", zap.Uint("userID", user.ID), zap.Error(err))
+		}
+		return user, ""
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, "email_not_verified"
+	}
+
+	user, err := models.GetUserByEmail(db, identity.Email)
+	if err != nil {
+		if !config.GlobalConfig.OAuthAllowSignup {
+			return nil, "signup_disabled"
+		}
+		user, err = models.CreateUser(db, identity.Email, utils.RandText(32))
+		if err != nil {
+			logger.Error("Failed to create sso user", zap.String("email", identity.Email), zap.Error(err))
+			return nil, "signup_failed"
+		}
+		// 第三方已验证邮箱，新用户直接激活
+		if err := models.UpdateUserFields(db, user, map[string]any{
+			"Activated":     true,
+			"EmailVerified": true,
+			"DisplayName":   identity.Name,
+			"Avatar":        identity.Avatar,
+			"Source":        "oauth:" + identity.Provider,
+		}); err != nil {
+			logger.Warn("Failed to update sso user profile", zap.Uint("userID", user.ID), zap.Error(err))
+		}
+		logger.Info("SSO user signed up", zap.Uint("userID", user.ID), zap.String("provider", identity.Provider))
+	}
+
+	linked, err := models.LinkUserIdentity(db, user.ID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		logger.Error("Failed to link sso identity", zap.Uint("userID", user.ID), zap.Error(err))
+		return nil, "link_failed"
+	}
+	if err := models.TouchUserIdentity(db, linked, identity.Email); err != nil {
+		logger.Warn("Failed to update sso identity", zap.Uint("userID", user.ID), zap.Error(err))
+	}
+	return user, ""
+}
+
+// handleListOAuthIdentities 获取当前用户关联的第三方账号
+func (h *Handlers) handleListOAuthIdentities(c *gin.Context) {
+	user := models.CurrentUser(c)
+	identities, err := models.GetUserIdentities(h.db, user.ID)
+	if err != nil {
+		response.Fail(c, "failed to load linked accounts", err)
+		return
+	}
+	response.Success(c, "success", identities)
+}
+
+// handleUnlinkOAuthIdentity 解除第三方账号关联
+func (h *Handlers) handleUnlinkOAuthIdentity(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if err := models.UnlinkUserIdentity(h.db, user.ID, c.Param("provider")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, "linked account not found", nil)
+			return
+		}
+		response.Fail(c, "failed to unlink account", err)
+		return
+	}
+	response.Success(c, "account unlinked", nil)
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/recording"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UpdateRecordingPolicyRequest Update recording policy request
+type UpdateRecordingPolicyRequest struct {
+	Enabled       *bool   `json:"enabled"`
+	RetentionDays *int    `json:"retentionDays"`
+	Mode          *string `json:"mode"`
+	Format        *string `json:"format"`
+}
+
+// ListRecordings List the current user's call recordings
+func (h *Handlers) ListRecordings(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "Unauthorized", "User not logged in")
+		return
+	}
+
+	query := h.db.Model(&models.CallRecording{}).Where("user_id = ?", user.ID)
+
+	// Optional: Filter by source or session
+	if source := c.Query("source"); source != "" {
+		query = query.Where("source = ?", source)
+	}
+	if sessionID := c.Query("sessionId"); sessionID != "" {
+		query = query.Where("session_id = ?", sessionID)
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var recordings []models.CallRecording
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order("created_at DESC").Find(&recordings).Error; err != nil {
+		response.Fail(c, "Query failed", err.Error())
+		return
+	}
+
+	response.Success(c, "Query successful", gin.H{
+		"list":     recordings,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// findRecording loads a recording owned by the current user, writing the failure response itself
+func (h *Handlers) findRecording(c *gin.Context) (*models.CallRecording, bool) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "Unauthorized", "User not logged in")
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Fail(c, "Parameter error", "Invalid recording ID")
+		return nil, false
+	}
+
+	var rec models.CallRecording
+	if err := h.db.Where("id = ? AND user_id = ?", id, user.ID).First(&rec).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, "Recording not found", nil)
+		} else {
+			response.Fail(c, "Query failed", err.Error())
+		}
+		return nil, false
+	}
+	return &rec, true
+}
+
+// DownloadRecording Download a call recording
+func (h *Handlers) DownloadRecording(c *gin.Context) {
+	rec, ok := h.findRecording(c)
+	if !ok {
+		return
+	}
+
+	reader, size, err := stores.Default().Read(rec.StorageKey)
+	if err != nil {
+		response.Fail(c, "Recording file not available", err.Error())
+		return
+	}
+	defer reader.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", path.Base(rec.StorageKey)))
+	c.DataFromReader(200, size, recording.ContentType(rec.Format), io.Reader(reader), nil)
+}
+
+// GetRecordingURL Get a signed, expiring /media URL for playing a recording in the browser
+func (h *Handlers) GetRecordingURL(c *gin.Context) {
+	rec, ok := h.findRecording(c)
+	if !ok {
+		return
+	}
+
+	response.Success(c, "success", gin.H{
+		"url":       stores.SignedURL(rec.StorageKey, rec.UserID, 0),
+		"expiresAt": time.Now().Add(stores.MediaURLTTL()),
+	})
+}
+
+// DeleteRecording Delete a call recording and its file
+func (h *Handlers) DeleteRecording(c *gin.Context) {
+	rec, ok := h.findRecording(c)
+	if !ok {
+		return
+	}
+
+	if err := recording.Delete(h.db, stores.Default(), rec); err != nil {
+		response.Fail(c, "Delete failed", err.Error())
+		return
+	}
+
+	response.Success(c, "Delete successful", nil)
+}
+
+// GetRecordingPolicy Get the current user's recording policy
+func (h *Handlers) GetRecordingPolicy(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "Unauthorized", "User not logged in")
+		return
+	}
+
+	policy, err := models.GetRecordingPolicy(h.db, user.ID)
+	if err != nil {
+		response.Fail(c, "Query failed", err.Error())
+		return
+	}
+
+	response.Success(c, "Query successful", policy)
+}
+
+// UpdateRecordingPolicy Create or update the current user's recording policy
+func (h *Handlers) UpdateRecordingPolicy(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "Unauthorized", "User not logged in")
+		return
+	}
+
+	var req UpdateRecordingPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Parameter error", err.Error())
+		return
+	}
+
+	policy, err := models.GetRecordingPolicy(h.db, user.ID)
+	if err != nil {
+		response.Fail(c, "Query failed", err.Error())
+		return
+	}
+
+	if req.Enabled != nil {
+		policy.Enabled = *req.Enabled
+	}
+	if req.RetentionDays != nil {
+		if *req.RetentionDays < 0 {
+			response.Fail(c, "Parameter error", "Retention days must not be negative")
+			return
+		}
+		policy.RetentionDays = *req.RetentionDays
+	}
+	if req.Mode != nil {
+		switch recording.Mode(*req.Mode) {
+		case recording.ModeMixed, recording.ModeStereo:
+			policy.Mode = *req.Mode
+		default:
+			response.Fail(c, "Parameter error", "Invalid recording mode")
+			return
+		}
+	}
+	if req.Format != nil {
+		switch recording.Format(*req.Format) {
+		case recording.FormatWAV, recording.FormatOpus:
+			policy.Format = *req.Format
+		default:
+			response.Fail(c, "Parameter error", "Invalid recording format")
+			return
+		}
+	}
+
+	if err := h.db.Save(policy).Error; err != nil {
+		response.Fail(c, "Update failed", err.Error())
+		return
+	}
+
+	response.Success(c, "Update successful", policy)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// handleCurrentPermissions 获取当前用户的角色与实际权限，前端据此显示管理入口
+func (h *Handlers) handleCurrentPermissions(c *gin.Context) {
+	user := models.CurrentUser(c)
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	response.Success(c, "success", gin.H{
+		"role":        role,
+		"permissions": user.EffectivePermissions(),
+	})
+}
+
+// handleListRoles 列出内置角色及其默认权限
+func (h *Handlers) handleListRoles(c *gin.Context) {
+	roles := make([]gin.H, 0, len(models.Roles()))
+	for _, role := range models.Roles() {
+		roles = append(roles, gin.H{
+			"role":        role,
+			"permissions": models.RolePermissions(role),
+		})
+	}
+	response.Success(c, "success", roles)
+}
+
+// handleUpdateUserRole 修改用户的角色与附加权限（需要 user.manage 权限）
+func (h *Handlers) handleUpdateUserRole(c *gin.Context) {
+	operator := models.CurrentUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Fail(c, "invalid user id", nil)
+		return
+	}
+	var input struct {
+		Role        string    `json:"role" binding:"required"`
+		Permissions *[]string `json:"permissions"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.Fail(c, "invalid request", err)
+		return
+	}
+	if uint(id) == operator.ID {
+		response.Fail(c, "cannot change your own role", nil)
+		return
+	}
+
+	user, err := models.GetUserByUID(h.db, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, "user not found", nil)
+			return
+		}
+		response.Fail(c, "failed to load user", err)
+		return
+	}
+	target := *user
+	target.Role = input.Role
+	if input.Permissions != nil {
+		target.Permissions = ""
+		if len(*input.Permissions) > 0 {
+			raw, err := json.Marshal(*input.Permissions)
+			if err != nil {
+				response.Fail(c, "invalid permissions", err)
+				return
+			}
+			target.Permissions = string(raw)
+		}
+	}
+	if err := models.CheckRoleChange(operator, user, &target); err != nil {
+		response.Fail(c, err.Error(), nil)
+		return
+	}
+
+	before := gin.H{"role": user.Role, "permissions": user.EffectivePermissions()}
+	if err := models.UpdateUserFields(h.db, user, map[string]any{
+		"Role":        target.Role,
+		"Permissions": target.Permissions,
+	}); err != nil {
+		response.Fail(c, "failed to update role", err)
+		return
+	}
+	h.recordAudit(c, models.AuditActionUserRoleUpdate, "user", user.ID, before,
+		gin.H{"role": target.Role, "permissions": target.EffectivePermissions()})
+	logger.Info("User role updated",
+		zap.Uint("operatorID", operator.ID),
+		zap.Uint("userID", user.ID),
+		zap.String("role", target.Role))
+	response.Success(c, "role updated", gin.H{
+		"id":          user.ID,
+		"role":        target.Role,
+		"permissions": target.EffectivePermissions(),
+	})
+}
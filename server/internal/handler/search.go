@@ -51,6 +51,7 @@ func (h *Handlers) UpdateSearchConfig(c *gin.Context) {
 		return
 	}
 
+	before := h.searchConfig()
 	if input.Enabled != nil {
 		utils.SetValue(h.db, constants.KEY_SEARCH_ENABLED, strconv.FormatBool(*input.Enabled), "bool", true, true)
 	}
@@ -69,10 +70,21 @@ func (h *Handlers) UpdateSearchConfig(c *gin.Context) {
 
 	// Reload configuration
 	utils.LoadAutoloads(h.db)
+	h.recordAudit(c, models.AuditActionSearchConfigUpdate, "search_config", "", before, h.searchConfig())
 
 	response.Success(c, "Update successful", nil)
 }
 
+// searchConfig returns the current search settings, used as the audit snapshot
+func (h *Handlers) searchConfig() gin.H {
+	return gin.H{
+		"enabled":    utils.GetBoolValue(h.db, constants.KEY_SEARCH_ENABLED),
+		"searchPath": utils.GetValue(h.db, constants.KEY_SEARCH_PATH),
+		"batchSize":  utils.GetIntValue(h.db, constants.KEY_SEARCH_BATCH_SIZE, 100),
+		"schedule":   utils.GetValue(h.db, constants.KEY_SEARCH_INDEX_SCHEDULE),
+	}
+}
+
 // EnableSearch enables search function
 func (h *Handlers) EnableSearch(c *gin.Context) {
 	user := models.CurrentUser(c)
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// userSessionView 会话列表项，标记发起请求的会话
+type userSessionView struct {
+	models.UserSession
+	Current bool `json:"current"`
+}
+
+// LogoutAllRequest 退出所有设备请求
+type LogoutAllRequest struct {
+	KeepCurrent bool `json:"keepCurrent"` // 保留当前会话，只退出其他设备
+}
+
+// issueAuthToken 签发登录令牌并登记会话，登记失败时不返回令牌（Cookie 会话不受影响）
+func issueAuthToken(c *gin.Context, db *gorm.DB, user *models.User, expired time.Duration) string {
+	token, _, err := models.IssueSessionToken(db, user, expired, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		logger.Warn("Failed to issue auth token", zap.Uint("userId", user.ID), zap.Error(err))
+		return ""
+	}
+	return token
+}
+
+// handleListSessions 获取当前用户所有有效的登录会话
+func (h *Handlers) handleListSessions(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.AbortWithStatus(c, http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := models.GetActiveUserSessions(h.db, user.ID)
+	if err != nil {
+		response.Fail(c, "获取会话列表失败", err)
+		return
+	}
+
+	current := models.CurrentSession(c)
+	views := make([]userSessionView, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, userSessionView{
+			UserSession: session,
+			Current:     current != nil && current.ID == session.ID,
+		})
+	}
+	response.Success(c, "获取会话列表成功", gin.H{
+		"sessions": views,
+	})
+}
+
+// handleRevokeSession 吊销某个会话，对应的 Cookie 或令牌在下一次请求时即失效
+func (h *Handlers) handleRevokeSession(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.AbortWithStatus(c, http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "无效的会话ID", err)
+		return
+	}
+
+	if err := models.RevokeUserSession(h.db, user.ID, uint(sessionID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, "会话不存在或已失效", nil)
+			return
+		}
+		response.Fail(c, "吊销会话失败", err)
+		return
+	}
+
+	// 吊销的是当前会话时同时清除 Cookie
+	if current := models.CurrentSession(c); current != nil && current.ID == uint(sessionID) {
+		models.Logout(c, user)
+	}
+	response.Success(c, "会话已吊销", nil)
+}
+
+// handleLogoutAll 退出所有设备，可选择保留当前会话
+func (h *Handlers) handleLogoutAll(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.AbortWithStatus(c, http.StatusUnauthorized)
+		return
+	}
+
+	var req LogoutAllRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Fail(c, "参数错误", err.Error())
+			return
+		}
+	}
+
+	var keepID uint
+	current := models.CurrentSession(c)
+	if req.KeepCurrent && current != nil {
+		keepID = current.ID
+	}
+
+	revoked, err := models.RevokeAllUserSessions(h.db, user, keepID)
+	if err != nil {
+		response.Fail(c, "退出所有设备失败", err)
+		return
+	}
+	if keepID == 0 {
+		models.Logout(c, user)
+	}
+	response.Success(c, "已退出所有设备", gin.H{
+		"revoked":     revoked,
+		"keepCurrent": keepID != 0,
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// errShuttingDown 服务关闭期间拒绝新通话时返回给客户端
+var errShuttingDown = errors.New("server is shutting down")
+
+// realtimeSessions 统计进行中的实时通话（WebRTC 与 WebSocket 语音），
+// 服务关闭时拒绝新通话并等待已有通话结束
+type realtimeSessions struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	draining bool
+}
+
+// begin 登记一通通话，关闭中时返回 false
+func (r *realtimeSessions) begin() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.draining {
+		return false
+	}
+	r.wg.Add(1)
+	return true
+}
+
+// end 通话结束
+func (r *realtimeSessions) end() {
+	r.wg.Done()
+}
+
+// drain 停止登记新通话，等待已有通话结束或 ctx 到期
+func (r *realtimeSessions) drain(ctx context.Context) error {
+	r.mu.Lock()
+	r.draining = true
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// beginRealtimeSession 在升级连接前登记通话；服务关闭中时返回 503 并返回 false，
+// 返回 true 时调用方须在通话结束后调用 h.realtime.end()
+func (h *Handlers) beginRealtimeSession(c *gin.Context) bool {
+	if h.realtime.begin() {
+		return true
+	}
+	c.Header("Retry-After", "5")
+	response.AbortWithStatusJSON(c, http.StatusServiceUnavailable, errShuttingDown)
+	return false
+}
+
+// Shutdown 拒绝新的实时通话并等待进行中的通话结束；ctx 到期后以 server_shutdown 关闭
+// 剩余的 WebRTC 会话并返回 ctx 的错误。WebSocket 语音通话随请求 context 结束，
+// 由调用方取消 http.Server 的 BaseContext
+func (h *Handlers) Shutdown(ctx context.Context) error {
+	err := h.realtime.drain(ctx)
+	if sigErr := h.voiceSignaling.Shutdown(ctx); err == nil {
+		err = sigErr
+	}
+	return err
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	workflowdef "github.com/code-100-precent/LingEcho/internal/workflow"
+	"github.com/code-100-precent/LingEcho/pkg/sip"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/signaling"
+	transports "github.com/code-100-precent/LingEcho/pkg/webrtc/transport"
+)
+
+// sipBridgeConn SIP 桥接的通话没有信令连接，AIClient 推送的识别结果等消息直接丢弃
+type sipBridgeConn struct{}
+
+func (sipBridgeConn) WriteJSON(v interface{}) error { return nil }
+
+func (sipBridgeConn) Close() error { return nil }
+
+// sipCallTarget 接听通话的助手与计费凭证
+type sipCallTarget struct {
+	userID       uint
+	credentialID uint
+	assistantID  uint
+	workflowID   *uint  // 助手接听前执行的IVR工作流
+	greeting     string // 开场白，为空时使用助手的开场白
+}
+
+// resolveSIPCallTarget 呼入通话按被叫SIP用户的配置，呼出通话按发起时的参数
+func (h *Handlers) resolveSIPCallTarget(call *sip.BridgeCall) (*sipCallTarget, error) {
+	if call.Outbound {
+		target := &sipCallTarget{greeting: call.Metadata[sip.MetadataGreeting]}
+		for key, dst := range map[string]*uint{
+			sip.MetadataUserID:       &target.userID,
+			sip.MetadataCredentialID: &target.credentialID,
+			sip.MetadataAssistantID:  &target.assistantID,
+		} {
+			id, err := strconv.ParseUint(call.Metadata[key], 10, 64)
+			if err != nil || id == 0 {
+				return nil, fmt.Errorf("outgoing call %s: invalid %s", call.CallID, key)
+			}
+			*dst = uint(id)
+		}
+		return target, nil
+	}
+
+	sipUser, err := models.GetSipUserByUsername(h.db, call.To)
+	if err != nil || sipUser.AssistantID == nil {
+		return nil, sip.ErrNotBridged
+	}
+	if sipUser.UserID == nil || sipUser.CredentialID == nil {
+		return nil, fmt.Errorf("sip user %s has an assistant but no credential", sipUser.Username)
+	}
+	return &sipCallTarget{
+		userID:       *sipUser.UserID,
+		credentialID: *sipUser.CredentialID,
+		assistantID:  *sipUser.AssistantID,
+		workflowID:   sipUser.WorkflowID,
+	}, nil
+}
+
+// AnswerSIPCall 作为 SipServer 的 BridgeHandler：被叫 SIP 用户配置了助手时由 AI 语音会话接听呼入通话，
+// 呼出通话按 Metadata 指定的助手通话
+func (h *Handlers) AnswerSIPCall(ctx context.Context, call *sip.BridgeCall) (string, func(), error) {
+	target, err := h.resolveSIPCallTarget(call)
+	if err != nil {
+		return "", nil, err
+	}
+	cred, err := models.GetUserCredentialByID(h.db, target.userID, target.credentialID)
+	if err != nil {
+		return "", nil, err
+	}
+	if cred == nil {
+		return "", nil, errors.New("credential not found")
+	}
+
+	if !h.realtime.begin() {
+		return "", nil, signaling.ErrServerClosed
+	}
+	query := url.Values{
+		"assistantId": {strconv.FormatUint(uint64(target.assistantID), 10)},
+		"codec":       {call.Codec},
+	}
+	voice, _, err := h.prepareVoiceCall(ctx, cred, query)
+	if err != nil {
+		h.realtime.end()
+		return "", nil, err
+	}
+
+	// 桥接端与 AI 会话在同一进程内，不需要 STUN/TURN
+	opt := rtcmedia.WebRTCOption{
+		Codec:      voice.codec,
+		StreamID:   "lingecho_sip_bridge",
+		ICETimeout: constants.DefaultICETimeout,
+	}
+	aiClient, err := h.newVoiceClient(sipBridgeConn{}, voice, opt, "sip-"+call.CallID)
+	if err != nil {
+		h.realtime.end()
+		return "", nil, err
+	}
+	// 主被叫、时间与结果由 SIP 信令侧写入话单，这里只标明话单归属的通话
+	direction := models.SipCallDirectionInbound
+	if call.Outbound {
+		direction = models.SipCallDirectionOutbound
+	}
+	aiClient.SetCallInfo(transports.CallInfo{CallID: call.CallID, Channel: models.CallChannelSIP, Direction: direction})
+	release := func() {
+		aiClient.Close()
+		h.realtime.end()
+	}
+	if err := aiClient.Transport.SetRemoteDescription(call.Offer); err != nil {
+		release()
+		return "", nil, err
+	}
+	answer, _, err := aiClient.Transport.CreateAnswer(nil)
+	if err != nil {
+		release()
+		return "", nil, err
+	}
+	log.Printf("[SIP] Call %s from %s answered by assistant %d (codec %s)", call.CallID, call.From, voice.assistantID, voice.codec)
+
+	greeting := target.greeting
+	if greeting == "" {
+		greeting = voice.greeting
+	}
+	if token := call.Metadata[sip.MetadataWorkflowCall]; call.Outbound && token != "" {
+		go h.attachWorkflowCall(token, call, aiClient)
+	} else if target.workflowID != nil {
+		go h.runSIPMenu(*target.workflowID, call, aiClient, greeting)
+	} else {
+		go sendGreeting(aiClient, greeting)
+	}
+	return answer, release, nil
+}
+
+// runSIPMenu 先执行被叫配置的 IVR 工作流（按键菜单），结束后再由助手接管通话
+func (h *Handlers) runSIPMenu(workflowID uint, call *sip.BridgeCall, aiClient *transports.AIClient, greeting string) {
+	if err := waitForConnection(aiClient.Transport); err != nil {
+		log.Printf("[SIP] Call %s connection not established: %v", call.CallID, err)
+		return
+	}
+	// 菜单期间主叫的声音不送 ASR，避免助手抢答
+	aiClient.SetConversationPaused(true)
+	params := map[string]interface{}{
+		"call_id": call.CallID,
+		"caller":  call.From,
+		"callee":  call.To,
+	}
+	session := &sipCallSession{client: aiClient, digits: call.DTMF, done: call.Done}
+	manager := workflowdef.NewWorkflowTriggerManager(h.db)
+	if _, err := manager.TriggerCallWorkflow(workflowID, params, "sip", session); err != nil {
+		log.Printf("[SIP] Call %s IVR workflow %d failed: %v", call.CallID, workflowID, err)
+	}
+	aiClient.SetConversationPaused(false)
+
+	select {
+	case <-call.Done:
+		return
+	default:
+	}
+	sendGreeting(aiClient, greeting)
+}
+
+var errSIPCallEnded = errors.New("sip call ended")
+
+// sipCallSession 将桥接的 SIP 通话适配为工作流 IVR 与语音节点使用的 CallSession
+type sipCallSession struct {
+	client *transports.AIClient
+	digits <-chan string
+	done   <-chan struct{}
+}
+
+func (s *sipCallSession) Say(text string) error {
+	select {
+	case <-s.done:
+		return errSIPCallEnded
+	default:
+	}
+	return s.client.Speak(text)
+}
+
+func (s *sipCallSession) ReadDigits(max int, timeout time.Duration) (string, error) {
+	// 提示音播放期间的按键保留在通道中，允许主叫提前输入
+	var digits strings.Builder
+	for digits.Len() < max {
+		timer := time.NewTimer(timeout)
+		select {
+		case <-s.done:
+			timer.Stop()
+			return digits.String(), errSIPCallEnded
+		case <-timer.C:
+			return digits.String(), nil
+		case digit := <-s.digits:
+			timer.Stop()
+			if digit == "#" {
+				return digits.String(), nil
+			}
+			digits.WriteString(digit)
+		}
+	}
+	return digits.String(), nil
+}
+
+// Listen 识别主叫说的话：开口后静音超过 silence 或总时长超过 timeout 时返回，期间助手不作答
+func (s *sipCallSession) Listen(timeout, silence time.Duration) (string, error) {
+	results, stop := s.client.StartListening()
+	defer stop()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	var pause *time.Timer
+	var pauseC <-chan time.Time
+	defer func() {
+		if pause != nil {
+			pause.Stop()
+		}
+	}()
+
+	// 识别中间结果是当前句子的全文，句子结束后由最终结果替换
+	var finals []string
+	var partial string
+	text := func() string {
+		parts := finals
+		if partial != "" {
+			parts = append(parts[:len(parts):len(parts)], partial)
+		}
+		return strings.Join(parts, " ")
+	}
+	for {
+		select {
+		case <-s.done:
+			return text(), errSIPCallEnded
+		case <-deadline.C:
+			return text(), nil
+		case <-pauseC:
+			return text(), nil
+		case result := <-results:
+			if result.Final {
+				finals = append(finals, result.Text)
+				partial = ""
+			} else {
+				partial = result.Text
+			}
+			if pause == nil {
+				pause = time.NewTimer(silence)
+				pauseC = pause.C
+			} else {
+				if !pause.Stop() {
+					select {
+					case <-pause.C:
+					default:
+					}
+				}
+				pause.Reset(silence)
+			}
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxCampaignTargets 单个外呼任务的号码数上限
+const maxCampaignTargets = 10000
+
+// CreateSipCampaignRequest 创建外呼任务请求
+type CreateSipCampaignRequest struct {
+	Name           string     `json:"name" binding:"required"`
+	Targets        []string   `json:"targets" binding:"required"`      // 被叫URI列表，如 sip:1001@192.168.1.100
+	AssistantID    uint       `json:"assistantId" binding:"required"`  // 接通后通话的助手
+	CredentialID   uint       `json:"credentialId" binding:"required"` // 计费使用的API凭证
+	Script         string     `json:"script"`                          // 接通后播报的开场白
+	StartAt        *time.Time `json:"startAt"`
+	EndAt          *time.Time `json:"endAt"`
+	WindowStart    string     `json:"windowStart"` // 每天允许拨打的开始时间 HH:MM
+	WindowEnd      string     `json:"windowEnd"`   // 每天允许拨打的结束时间 HH:MM
+	Timezone       string     `json:"timezone"`
+	MaxConcurrency int        `json:"maxConcurrency"`
+	MaxAttempts    int        `json:"maxAttempts"`
+	RetryInterval  int        `json:"retryInterval"` // 秒
+	MaxCallSeconds int        `json:"maxCallSeconds"`
+	Start          bool       `json:"start"` // 创建后立即开始
+}
+
+// SipCampaignDetail 外呼任务及号码统计
+type SipCampaignDetail struct {
+	models.SipCampaign
+	Counts map[models.SipCampaignCallStatus]int64 `json:"counts"`
+}
+
+// CreateSipCampaign 创建外呼任务
+// @Summary 创建外呼任务
+// @Description 批量拨打号码，接通后由助手通话；支持拨打时段、并发上限与未接通重试
+// @Tags SIP
+// @Accept json
+// @Produce json
+// @Param request body CreateSipCampaignRequest true "外呼任务"
+// @Success 200 {object} response.Response{data=models.SipCampaign}
+// @Router /api/sip/campaigns [post]
+func (h *SipHandler) CreateSipCampaign(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "Unauthorized", "User not logged in")
+		return
+	}
+
+	var req CreateSipCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request: "+err.Error(), nil)
+		return
+	}
+
+	targets := make([]string, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		if !strings.HasPrefix(target, "sip:") && !strings.HasPrefix(target, "sips:") {
+			response.Fail(c, "Invalid target URI: "+target, nil)
+			return
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		response.Fail(c, "At least one target is required", nil)
+		return
+	}
+	if len(targets) > maxCampaignTargets {
+		response.Fail(c, "Too many targets", nil)
+		return
+	}
+
+	// 凭证与助手必须属于当前用户
+	cred, err := models.GetUserCredentialByID(h.db, user.ID, req.CredentialID)
+	if err != nil || cred == nil {
+		response.Fail(c, "Credential not found", nil)
+		return
+	}
+	var assistant models.Assistant
+	if err := h.db.First(&assistant, req.AssistantID).Error; err != nil {
+		response.Fail(c, "Assistant not found", nil)
+		return
+	}
+	if assistant.UserID != user.ID && assistant.GroupID == nil {
+		response.Fail(c, "Permission denied: assistant does not belong to you", nil)
+		return
+	}
+
+	campaign := &models.SipCampaign{
+		UserID:         user.ID,
+		Name:           req.Name,
+		Status:         models.SipCampaignStatusDraft,
+		AssistantID:    req.AssistantID,
+		CredentialID:   req.CredentialID,
+		Script:         req.Script,
+		StartAt:        req.StartAt,
+		EndAt:          req.EndAt,
+		WindowStart:    req.WindowStart,
+		WindowEnd:      req.WindowEnd,
+		Timezone:       req.Timezone,
+		MaxConcurrency: req.MaxConcurrency,
+		MaxAttempts:    req.MaxAttempts,
+		RetryInterval:  req.RetryInterval,
+		MaxCallSeconds: req.MaxCallSeconds,
+	}
+	if req.Start {
+		campaign.Status = models.SipCampaignStatusRunning
+	}
+	campaign.ApplyDefaults()
+	if err := campaign.Validate(); err != nil {
+		response.Fail(c, "Invalid request: "+err.Error(), nil)
+		return
+	}
+
+	if err := models.CreateSipCampaign(h.db, campaign, targets); err != nil {
+		response.Fail(c, "Failed to create campaign: "+err.Error(), nil)
+		return
+	}
+	response.Success(c, "Campaign created successfully", campaign)
+}
+
+// ListSipCampaigns 获取外呼任务列表
+// @Summary 获取外呼任务列表
+// @Tags SIP
+// @Produce json
+// @Success 200 {object} response.Response{data=[]models.SipCampaign}
+// @Router /api/sip/campaigns [get]
+func (h *SipHandler) ListSipCampaigns(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "Unauthorized", "User not logged in")
+		return
+	}
+	campaigns, err := models.ListSipCampaigns(h.db, user.ID)
+	if err != nil {
+		response.Fail(c, "Failed to list campaigns: "+err.Error(), nil)
+		return
+	}
+	response.Success(c, "Success", campaigns)
+}
+
+// GetSipCampaign 获取外呼任务详情（含各状态号码数）
+// @Summary 获取外呼任务详情
+// @Tags SIP
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} response.Response{data=SipCampaignDetail}
+// @Router /api/sip/campaigns/{id} [get]
+func (h *SipHandler) GetSipCampaign(c *gin.Context) {
+	campaign, ok := h.loadSipCampaign(c)
+	if !ok {
+		return
+	}
+	counts, err := models.CountSipCampaignCalls(h.db, campaign.ID)
+	if err != nil {
+		response.Fail(c, "Failed to count calls: "+err.Error(), nil)
+		return
+	}
+	response.Success(c, "Success", SipCampaignDetail{SipCampaign: *campaign, Counts: counts})
+}
+
+// GetSipCampaignCalls 获取外呼任务的号码及拨打结果
+// @Summary 获取外呼任务的拨打结果
+// @Tags SIP
+// @Produce json
+// @Param id path int true "任务ID"
+// @Param status query string false "状态筛选"
+// @Success 200 {object} response.Response{data=[]models.SipCampaignCall}
+// @Router /api/sip/campaigns/{id}/calls [get]
+func (h *SipHandler) GetSipCampaignCalls(c *gin.Context) {
+	campaign, ok := h.loadSipCampaign(c)
+	if !ok {
+		return
+	}
+	calls, err := models.GetSipCampaignCalls(h.db, campaign.ID, models.SipCampaignCallStatus(c.Query("status")))
+	if err != nil {
+		response.Fail(c, "Failed to get campaign calls: "+err.Error(), nil)
+		return
+	}
+	response.Success(c, "Success", calls)
+}
+
+// StartSipCampaign 开始或继续外呼任务
+// @Summary 开始外呼任务
+// @Tags SIP
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} response.Response
+// @Router /api/sip/campaigns/{id}/start [post]
+func (h *SipHandler) StartSipCampaign(c *gin.Context) {
+	h.setSipCampaignStatus(c, models.SipCampaignStatusRunning,
+		models.SipCampaignStatusDraft, models.SipCampaignStatusPaused)
+}
+
+// PauseSipCampaign 暂停外呼任务，进行中的通话不受影响
+// @Summary 暂停外呼任务
+// @Tags SIP
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} response.Response
+// @Router /api/sip/campaigns/{id}/pause [post]
+func (h *SipHandler) PauseSipCampaign(c *gin.Context) {
+	h.setSipCampaignStatus(c, models.SipCampaignStatusPaused, models.SipCampaignStatusRunning)
+}
+
+// CancelSipCampaign 取消外呼任务，未拨打的号码不再拨打
+// @Summary 取消外呼任务
+// @Tags SIP
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} response.Response
+// @Router /api/sip/campaigns/{id}/cancel [post]
+func (h *SipHandler) CancelSipCampaign(c *gin.Context) {
+	campaign, ok := h.setSipCampaignStatus(c, models.SipCampaignStatusCancelled,
+		models.SipCampaignStatusDraft, models.SipCampaignStatusRunning, models.SipCampaignStatusPaused)
+	if !ok {
+		return
+	}
+	if err := models.CancelPendingSipCampaignCalls(h.db, campaign.ID); err != nil {
+		response.Fail(c, "Failed to cancel pending calls: "+err.Error(), nil)
+		return
+	}
+	response.Success(c, "Campaign cancelled", campaign)
+}
+
+// setSipCampaignStatus 在允许的状态下切换任务状态；取消时由调用方返回响应
+func (h *SipHandler) setSipCampaignStatus(c *gin.Context, status models.SipCampaignStatus, from ...models.SipCampaignStatus) (*models.SipCampaign, bool) {
+	campaign, ok := h.loadSipCampaign(c)
+	if !ok {
+		return nil, false
+	}
+	allowed := false
+	for _, s := range from {
+		if campaign.Status == s {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		response.Fail(c, "Campaign cannot change from "+string(campaign.Status)+" to "+string(status), nil)
+		return nil, false
+	}
+	if err := models.UpdateSipCampaignStatus(h.db, campaign.ID, status); err != nil {
+		response.Fail(c, "Failed to update campaign: "+err.Error(), nil)
+		return nil, false
+	}
+	campaign.Status = status
+	if status != models.SipCampaignStatusCancelled {
+		response.Success(c, "Campaign "+string(status), campaign)
+	}
+	return campaign, true
+}
+
+// loadSipCampaign 读取路径中的任务，只能访问自己的任务
+func (h *SipHandler) loadSipCampaign(c *gin.Context) (*models.SipCampaign, bool) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "Unauthorized", "User not logged in")
+		return nil, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Fail(c, "Invalid campaign ID", nil)
+		return nil, false
+	}
+	campaign, err := models.GetSipCampaign(h.db, user.ID, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, "Campaign not found", nil)
+		} else {
+			response.Fail(c, "Failed to get campaign: "+err.Error(), nil)
+		}
+		return nil, false
+	}
+	return campaign, true
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/sip"
+	transports "github.com/code-100-precent/LingEcho/pkg/webrtc/transport"
+	runtimewf "github.com/code-100-precent/LingEcho/pkg/workflow"
+)
+
+// workflowCallPollInterval 等待接通期间检查呼出会话状态的间隔
+const workflowCallPollInterval = 500 * time.Millisecond
+
+// pendingWorkflowCalls 等待接通的工作流呼出：令牌 -> 接通后交回的通话会话
+var (
+	pendingWorkflowCallsMu sync.Mutex
+	pendingWorkflowCalls   = map[string]chan *sipCallSession{}
+	workflowCallSeq        atomic.Uint64
+)
+
+// bridgedCallDialer 支持将呼出通话桥接给 BridgeHandler 的SIP服务器（*sip.SipServer）
+type bridgedCallDialer interface {
+	SipServerInterface
+	MakeOutgoingCallWithOptions(targetURI string, opts sip.OutgoingCallOptions) (string, error)
+}
+
+// workflowCall 工作流呼叫节点发起并已接通的通话
+type workflowCall struct {
+	*sipCallSession
+	callID string
+	server SipServerInterface
+}
+
+func (c *workflowCall) CallID() string {
+	return c.callID
+}
+
+func (c *workflowCall) Hangup() error {
+	select {
+	case <-c.done:
+		return nil
+	default:
+	}
+	return c.server.HangupOutgoingCall(c.callID)
+}
+
+// dialWorkflowCall 作为工作流的 CallDialer：以工作流所有者的凭证和指定助手的语音配置呼出，
+// 接通后返回通话会话，通话期间助手不作答，由工作流的语音节点驱动
+func (h *Handlers) dialWorkflowCall(req runtimewf.DialRequest) (runtimewf.PlacedCall, error) {
+	if h.sipHandler == nil || h.sipHandler.sipServer == nil {
+		return nil, errors.New("sip server is not enabled")
+	}
+	server, ok := h.sipHandler.sipServer.(bridgedCallDialer)
+	if !ok {
+		return nil, errors.New("sip server does not support bridged outgoing calls")
+	}
+	cred, err := models.GetUserCredentialByID(h.db, req.UserID, req.CredentialID)
+	if err != nil || cred == nil {
+		return nil, fmt.Errorf("credential %d not found for user %d", req.CredentialID, req.UserID)
+	}
+
+	token := strconv.FormatUint(workflowCallSeq.Add(1), 10)
+	answered := make(chan *sipCallSession, 1)
+	pendingWorkflowCallsMu.Lock()
+	pendingWorkflowCalls[token] = answered
+	pendingWorkflowCallsMu.Unlock()
+	defer func() {
+		pendingWorkflowCallsMu.Lock()
+		delete(pendingWorkflowCalls, token)
+		pendingWorkflowCallsMu.Unlock()
+	}()
+
+	callID, err := server.MakeOutgoingCallWithOptions(req.Target, sip.OutgoingCallOptions{
+		Bridge: true,
+		Metadata: map[string]string{
+			sip.MetadataUserID:       strconv.FormatUint(uint64(req.UserID), 10),
+			sip.MetadataCredentialID: strconv.FormatUint(uint64(req.CredentialID), 10),
+			sip.MetadataAssistantID:  strconv.FormatUint(uint64(req.AssistantID), 10),
+			sip.MetadataWorkflowCall: token,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	userID := req.UserID
+	if err := models.CreateSipCall(h.db, &models.SipCall{
+		CallID:    callID,
+		Direction: models.SipCallDirectionOutbound,
+		Status:    models.SipCallStatusCalling,
+		ToURI:     req.Target,
+		StartTime: time.Now(),
+		UserID:    &userID,
+		Notes:     "workflow call",
+	}); err != nil {
+		log.Printf("[SIP] Failed to create call record for workflow call %s: %v", callID, err)
+	}
+
+	ringTimeout := time.NewTimer(req.RingTimeout)
+	defer ringTimeout.Stop()
+	poll := time.NewTicker(workflowCallPollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case session := <-answered:
+			return &workflowCall{sipCallSession: session, callID: callID, server: server}, nil
+		case <-ringTimeout.C:
+			_ = server.CancelOutgoingCall(callID)
+			return nil, fmt.Errorf("no answer within %v", req.RingTimeout)
+		case <-poll.C:
+			raw, exists := server.GetOutgoingSession(callID)
+			session, ok := raw.(*sip.OutgoingSession)
+			if !exists || !ok {
+				return nil, errors.New("call session lost")
+			}
+			switch session.Status {
+			case "failed", "cancelled", "ended":
+				reason := session.Error
+				if reason == "" {
+					reason = session.Status
+				}
+				return nil, fmt.Errorf("call %s", reason)
+			}
+		}
+	}
+}
+
+// attachWorkflowCall 呼出接通且媒体连通后，将通话会话交给等待中的呼叫节点
+func (h *Handlers) attachWorkflowCall(token string, call *sip.BridgeCall, aiClient *transports.AIClient) {
+	if err := waitForConnection(aiClient.Transport); err != nil {
+		log.Printf("[SIP] Workflow call %s connection not established: %v", call.CallID, err)
+		return
+	}
+	// 工作流驱动通话期间助手不作答，listen 节点单独打开识别
+	aiClient.SetConversationPaused(true)
+
+	pendingWorkflowCallsMu.Lock()
+	answered, ok := pendingWorkflowCalls[token]
+	pendingWorkflowCallsMu.Unlock()
+	if !ok {
+		// 呼叫节点已超时放弃，挂断由其取消呼叫完成
+		log.Printf("[SIP] Workflow call %s answered after the call node gave up", call.CallID)
+		return
+	}
+	select {
+	case answered <- &sipCallSession{client: aiClient, digits: call.DTMF, done: call.Done}:
+	default:
+	}
+}
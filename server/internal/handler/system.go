@@ -224,8 +224,11 @@ func (h *Handlers) SaveVoiceCloneConfig(c *gin.Context) {
 		return
 	}
 
+	before := h.getVoiceCloneConfig(req.Provider)
+
 	// 保存到数据库
 	utils.SetValue(h.db, configKey, string(configJSON), "json", true, true)
+	h.recordAudit(c, models.AuditActionVoiceCloneConfigSave, "voice_clone_config", req.Provider, before, req.Config)
 
 	response.Success(c, "配置保存成功", nil)
 }
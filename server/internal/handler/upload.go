@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
 	"time"
 
@@ -44,6 +46,45 @@ func (h *UploadHandler) UploadAudio(c *gin.Context) {
 		return
 	}
 
+	// Resolve who the file is charged to and reject it when it would exceed their storage quota
+	user := models.CurrentUser(c)
+	var gormDB *gorm.DB
+	if db, exists := c.Get("db"); exists {
+		gormDB, _ = db.(*gorm.DB)
+	}
+	var credentialID uint
+	if user != nil && gormDB != nil {
+		// Try to get credential ID (from request parameters or user's default credential)
+		if credIDStr := c.Query("credentialId"); credIDStr != "" {
+			id, err := strconv.ParseUint(credIDStr, 10, 32)
+			if err != nil || id == 0 {
+				response.AbortWithStatusJSON(c, http.StatusBadRequest, errors.New("invalid credentialId"))
+				return
+			}
+			credentialID = uint(id)
+		}
+		// 如果没有提供凭证ID，尝试获取用户的第一个凭证
+		if credentialID == 0 {
+			credentials, err := models.GetUserCredentials(gormDB, user.ID)
+			if err == nil && len(credentials) > 0 {
+				credentialID = credentials[0].ID
+			}
+		}
+		if err := models.CheckStorageQuota(gormDB, user.ID, credentialID, header.Size); err != nil {
+			if errors.Is(err, models.ErrStorageQuotaExceeded) {
+				response.AbortWithStatusJSON(c, http.StatusRequestEntityTooLarge, err)
+				return
+			}
+			// Unknown ids and other users' credentials cannot be charged
+			if errors.Is(err, models.ErrStorageCredentialNotFound) {
+				response.AbortWithStatusJSON(c, http.StatusForbidden, err)
+				return
+			}
+			// Quota lookups failing should not block uploads
+			fmt.Printf("Failed to check storage quota: %v\n", err)
+		}
+	}
+
 	// Generate storage key (relative to storage root)
 	timestamp := time.Now().Unix()
 	randomStr := utils.RandString(8)
@@ -65,47 +106,27 @@ func (h *UploadHandler) UploadAudio(c *gin.Context) {
 	}
 
 	// Record storage usage
-	user := models.CurrentUser(c)
-	if user != nil {
-		// 从middleware获取数据库连接
-		db, exists := c.Get("db")
-		if exists {
-			if gormDB, ok := db.(*gorm.DB); ok {
-				// Try to get credential ID (from request parameters or user's default credential)
-				var credentialID uint
-				if credIDStr := c.Query("credentialId"); credIDStr != "" {
-					if id, err := strconv.ParseUint(credIDStr, 10, 32); err == nil {
-						credentialID = uint(id)
-					}
-				}
-				// 如果没有提供凭证ID，尝试获取用户的第一个凭证
-				if credentialID == 0 {
-					credentials, err := models.GetUserCredentials(gormDB, user.ID)
-					if err == nil && len(credentials) > 0 {
-						credentialID = credentials[0].ID
-					}
-				}
-
-				go func() {
-					if err := models.RecordStorageUsage(
-						gormDB,
-						user.ID,
-						credentialID,
-						nil, // assistantID
-						nil, // groupID
-						fmt.Sprintf("upload_%d_%d", user.ID, time.Now().Unix()),
-						fileSize,
-						fmt.Sprintf("上传音频文件: %s", fileName),
-					); err != nil {
-						// Recording failure does not affect the upload process, only logs
-						fmt.Printf("Failed to record storage usage: %v\n", err)
-					}
-				}()
-			}
+	if user != nil && gormDB != nil {
+		object := &models.StorageObject{
+			StorageKey:   storageKey,
+			UserID:       user.ID,
+			CredentialID: credentialID,
+			Category:     models.StorageCategoryUpload,
+			Size:         fileSize,
 		}
+		go func() {
+			if err := models.TrackStorageObject(gormDB, object, fmt.Sprintf("上传音频文件: %s", fileName)); err != nil {
+				// Recording failure does not affect the upload process, only logs
+				fmt.Printf("Failed to record storage usage: %v\n", err)
+			}
+		}()
 	}
 
 	fileURL := store.PublicURL(storageKey)
+	var userID uint
+	if user != nil {
+		userID = user.ID
+	}
 
 	// Return success response
 	response.Success(c, "音频文件上传成功", map[string]interface{}{
@@ -114,5 +135,6 @@ func (h *UploadHandler) UploadAudio(c *gin.Context) {
 		"fileSize":   fileSize,
 		"uploadTime": time.Now().Format(time.RFC3339),
 		"url":        fileURL,
+		"signedUrl":  stores.SignedURL(storageKey, userID, 0), // still works once MEDIA_REQUIRE_SIGNATURE is on
 	})
 }
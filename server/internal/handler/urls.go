@@ -1,19 +1,26 @@
 package handlers
 
 import (
+	"context"
 	"log"
 	"time"
 
 	"github.com/code-100-precent/LingEcho"
 	"github.com/code-100-precent/LingEcho/internal/apidocs"
 	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/internal/task"
 	"github.com/code-100-precent/LingEcho/pkg/config"
 	"github.com/code-100-precent/LingEcho/pkg/constants"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
+	lingechoMCP "github.com/code-100-precent/LingEcho/pkg/mcp"
 	"github.com/code-100-precent/LingEcho/pkg/middleware"
+	"github.com/code-100-precent/LingEcho/pkg/notification"
+	"github.com/code-100-precent/LingEcho/pkg/sso"
 	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/code-100-precent/LingEcho/pkg/utils/search"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/signaling"
 	"github.com/code-100-precent/LingEcho/pkg/websocket"
+	runtimewf "github.com/code-100-precent/LingEcho/pkg/workflow"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -25,6 +32,17 @@ type Handlers struct {
 	searchHandler     *search.SearchHandlers
 	ipLocationService *utils.IPLocationService
 	sipHandler        *SipHandler
+	voiceSignaling    *signaling.Server
+	realtime          realtimeSessions
+	mcpTools          *lingechoMCP.DynamicTools
+	ssoProviders      *sso.Registry
+	sms               *notification.SMSService
+	webPush           *notification.WebPushSender
+}
+
+// GetMCPTools gets the dynamic MCP tool registry of the in-process MCP server (for scheduled tasks)
+func (h *Handlers) GetMCPTools() *lingechoMCP.DynamicTools {
+	return h.mcpTools
 }
 
 // GetSearchHandler gets the search handler (for scheduled tasks)
@@ -32,6 +50,54 @@ func (h *Handlers) GetSearchHandler() *search.SearchHandlers {
 	return h.searchHandler
 }
 
+// newSearchEngine creates the search engine from the config table, falling back to environment variables
+func newSearchEngine(db *gorm.DB) (search.Engine, error) {
+	searchPath := utils.GetValue(db, constants.KEY_SEARCH_PATH)
+	if searchPath == "" && config.GlobalConfig != nil {
+		searchPath = config.GlobalConfig.SearchPath
+	}
+	if searchPath == "" {
+		searchPath = "./search"
+	}
+
+	batchSize := utils.GetIntValue(db, constants.KEY_SEARCH_BATCH_SIZE, 100)
+	if batchSize == 0 && config.GlobalConfig != nil {
+		batchSize = config.GlobalConfig.SearchBatchSize
+	}
+	if batchSize == 0 {
+		batchSize = 100
+	}
+
+	cfg := search.Config{
+		Backend:         utils.GetValue(db, constants.KEY_SEARCH_ENGINE),
+		IndexPath:       searchPath,
+		DefaultAnalyzer: utils.GetValue(db, constants.KEY_SEARCH_ANALYZER),
+		OpenTimeout:     5 * time.Second,
+		QueryTimeout:    5 * time.Second,
+		BatchSize:       batchSize,
+		Fuzziness:       utils.GetIntValue(db, constants.KEY_SEARCH_FUZZINESS, 1),
+	}
+	if config.GlobalConfig != nil {
+		if cfg.Backend == "" {
+			cfg.Backend = config.GlobalConfig.SearchEngine
+		}
+		if cfg.DefaultAnalyzer == "" {
+			cfg.DefaultAnalyzer = config.GlobalConfig.SearchAnalyzer
+		}
+		cfg.MeiliHost = config.GlobalConfig.MeilisearchHost
+		cfg.MeiliAPIKey = config.GlobalConfig.MeilisearchAPIKey
+		cfg.MeiliIndex = config.GlobalConfig.MeilisearchIndex
+	}
+	return search.Open(cfg)
+}
+
+// searchReindexer re-indexes all user data after the index has been rebuilt
+func searchReindexer(db *gorm.DB) search.ReindexFunc {
+	return func(ctx context.Context, engine search.Engine) error {
+		return task.IndexUserData(db, engine)
+	}
+}
+
 func NewHandlers(db *gorm.DB) *Handlers {
 	wsConfig := websocket.LoadConfigFromEnv()
 	wsHub := websocket.NewHub(wsConfig)
@@ -45,36 +111,14 @@ func NewHandlers(db *gorm.DB) *Handlers {
 	}
 
 	if searchEnabled {
-		searchPath := utils.GetValue(db, constants.KEY_SEARCH_PATH)
-		if searchPath == "" && config.GlobalConfig != nil {
-			searchPath = config.GlobalConfig.SearchPath
-		}
-		if searchPath == "" {
-			searchPath = "./search"
-		}
-
-		batchSize := utils.GetIntValue(db, constants.KEY_SEARCH_BATCH_SIZE, 100)
-		if batchSize == 0 && config.GlobalConfig != nil {
-			batchSize = config.GlobalConfig.SearchBatchSize
-		}
-		if batchSize == 0 {
-			batchSize = 100
-		}
-
-		engine, err := search.New(
-			search.Config{
-				IndexPath:    searchPath,
-				QueryTimeout: 5 * time.Second,
-				BatchSize:    batchSize,
-			},
-			search.BuildIndexMapping(""),
-		)
+		engine, err := newSearchEngine(db)
 		if err != nil {
 			log.Printf("Failed to initialize search engine: %v", err)
 			// Even if initialization fails, create an empty handler for route registration
 			searchHandler = search.NewSearchHandlers(nil)
 		} else {
 			searchHandler = search.NewSearchHandlers(engine)
+			searchHandler.SetReindexer(searchReindexer(db))
 		}
 		// Set database connection for configuration checking
 		if searchHandler != nil {
@@ -94,13 +138,20 @@ func NewHandlers(db *gorm.DB) *Handlers {
 	// 初始化SIP handler（SipServer可以通过SetSipServer方法设置）
 	sipHandler := NewSipHandler(db, nil)
 
-	return &Handlers{
+	h := &Handlers{
 		db:                db,
 		wsHub:             wsHub,
 		searchHandler:     searchHandler,
 		ipLocationService: ipLocationService,
 		sipHandler:        sipHandler,
+		voiceSignaling:    newVoiceSignaling(db),
+		mcpTools:          lingechoMCP.NewDynamicTools(lingechoMCP.Default(), db, logger.Lg),
+		ssoProviders:      sso.NewRegistry(oauthCallbackURL),
+		sms:               newSMSService(),
+		webPush:           newWebPushSender(),
 	}
+	h.subscribeInboxNotifications()
+	return h
 }
 
 // SetSipServer 设置SIP服务器（用于依赖注入）
@@ -108,8 +159,13 @@ func (h *Handlers) SetSipServer(sipServer SipServerInterface) {
 	if h.sipHandler != nil {
 		h.sipHandler.sipServer = sipServer
 	}
+	// 工作流呼叫节点通过该SIP服务器呼出
+	runtimewf.SetCallDialer(h.dialWorkflowCall)
 }
 
+// idempotent 创建类接口的幂等处理，客户端携带 Idempotency-Key 重试时返回首次响应，需放在认证之后
+var idempotent = middleware.Idempotency(middleware.DefaultIdempotencyTTL)
+
 func (h *Handlers) Register(engine *gin.Engine) {
 
 	r := engine.Group(config.GlobalConfig.APIPrefix)
@@ -123,36 +179,14 @@ func (h *Handlers) Register(engine *gin.Engine) {
 	// Register routes regardless of whether search is enabled, check in handler methods
 	// If handler is nil, try to initialize
 	if h.searchHandler == nil {
-		searchPath := utils.GetValue(h.db, constants.KEY_SEARCH_PATH)
-		if searchPath == "" && config.GlobalConfig != nil {
-			searchPath = config.GlobalConfig.SearchPath
-		}
-		if searchPath == "" {
-			searchPath = "./search"
-		}
-
-		batchSize := utils.GetIntValue(h.db, constants.KEY_SEARCH_BATCH_SIZE, 100)
-		if batchSize == 0 && config.GlobalConfig != nil {
-			batchSize = config.GlobalConfig.SearchBatchSize
-		}
-		if batchSize == 0 {
-			batchSize = 100
-		}
-
-		engine, err := search.New(
-			search.Config{
-				IndexPath:    searchPath,
-				QueryTimeout: 5 * time.Second,
-				BatchSize:    batchSize,
-			},
-			search.BuildIndexMapping(""),
-		)
+		engine, err := newSearchEngine(h.db)
 		if err != nil {
 			logger.Warn("Failed to initialize search engine in Register", zap.Error(err))
 			// Even if initialization fails, create an empty handler for route registration
 			h.searchHandler = search.NewSearchHandlers(nil)
 		} else {
 			h.searchHandler = search.NewSearchHandlers(engine)
+			h.searchHandler.SetReindexer(searchReindexer(h.db))
 		}
 	}
 
@@ -185,14 +219,19 @@ func (h *Handlers) Register(engine *gin.Engine) {
 	// Register Business Module Routes
 	h.registerAuthRoutes(r)
 	h.registerNotificationRoutes(r)
+	h.registerWebhookRoutes(r)
 	h.registerGroupRoutes(r)
 	h.registerQuotaRoutes(r)
 	h.registerAlertRoutes(r)
+	h.registerRecordingRoutes(r)
+	h.registerCallRoutes(r)
 	h.registerWebSocketRoutes(r)
 	h.registerAssistantRoutes(r)
 	h.registerChatRoutes(r)
 	h.registerCredentialsRoutes(r)
 	h.registerKnowledgeRoutes(r)
+	h.registerAgentRoutes(r)
+	h.registerMCPToolRoutes(r)
 	h.registerXunfeiTTSRoutes(r)
 	h.registerVolcengineTTSRoutes(r)
 	h.registerVoiceTrainingRoutes(r)
@@ -255,6 +294,11 @@ func (h *Handlers) registerAuthRoutes(r *gin.RouterGroup) {
 		auth.DELETE("/devices", models.AuthRequired, h.handleDeleteUserDevice)
 		auth.POST("/devices/trust", models.AuthRequired, h.handleTrustUserDevice)
 
+		// 登录会话管理
+		auth.GET("/sessions", models.AuthRequired, h.handleListSessions)
+		auth.DELETE("/sessions/:id", models.AuthRequired, h.handleRevokeSession)
+		auth.POST("/sessions/logout-all", models.AuthRequired, h.handleLogoutAll)
+
 		// email verification
 		auth.GET("/verify-email", h.handleVerifyEmail)
 		auth.POST("/send-email-verification", models.AuthRequired, h.handleSendEmailVerification)
@@ -286,6 +330,25 @@ func (h *Handlers) registerAuthRoutes(r *gin.RouterGroup) {
 		auth.POST("/two-factor/disable", models.AuthRequired, h.handleTwoFactorDisable)
 		auth.GET("/two-factor/status", models.AuthRequired, h.handleTwoFactorStatus)
 
+		// passkey (WebAuthn) registration and login
+		auth.POST("/webauthn/register/begin", models.AuthRequired, h.handleWebAuthnRegisterBegin)
+		auth.POST("/webauthn/register/finish", models.AuthRequired, h.handleWebAuthnRegisterFinish)
+		auth.POST("/webauthn/login/begin", h.handleWebAuthnLoginBegin)
+		auth.POST("/webauthn/login/finish", h.handleWebAuthnLoginFinish)
+		auth.GET("/webauthn/credentials", models.AuthRequired, h.handleListWebAuthnCredentials)
+		auth.DELETE("/webauthn/credentials/:id", models.AuthRequired, h.handleDeleteWebAuthnCredential)
+
+		// 第三方登录 (OAuth2/OIDC) 与账号关联
+		auth.GET("/oauth/providers", h.handleOAuthProviders)
+		auth.GET("/oauth/identities", models.AuthRequired, h.handleListOAuthIdentities)
+		auth.GET("/oauth/:provider/login", h.handleOAuthLogin)
+		auth.GET("/oauth/:provider/callback", h.handleOAuthCallback)
+		auth.POST("/oauth/2fa", h.handleOAuthTwoFactor)
+		auth.DELETE("/oauth/:provider", models.AuthRequired, h.handleUnlinkOAuthIdentity)
+
+		// current user's role and permissions
+		auth.GET("/permissions", models.AuthRequired, h.handleCurrentPermissions)
+
 		// user activity logs
 		auth.GET("/activity", models.AuthRequired, h.handleGetUserActivity)
 	}
@@ -307,6 +370,43 @@ func (h *Handlers) registerNotificationRoutes(r *gin.RouterGroup) {
 
 		// Batch delete notifications
 		notificationGroup.POST("/batch-delete", models.AuthRequired, h.handleBatchDeleteNotifications)
+
+		// External notification channels (webhook, Slack, DingTalk, Feishu)
+		notificationGroup.GET("/channels", models.AuthRequired, h.handleListNotificationChannels)
+		notificationGroup.POST("/channels", models.AuthRequired, h.handleCreateNotificationChannel)
+		notificationGroup.PUT("/channels/:id", models.AuthRequired, h.handleUpdateNotificationChannel)
+		notificationGroup.DELETE("/channels/:id", models.AuthRequired, h.handleDeleteNotificationChannel)
+		notificationGroup.POST("/channels/:id/test", models.AuthRequired, h.handleTestNotificationChannel)
+
+		// Mobile push device tokens (FCM / APNs)
+		notificationGroup.GET("/push/devices", models.AuthRequired, h.handleListPushDevices)
+		notificationGroup.POST("/push/devices", models.AuthRequired, h.handleRegisterPushDevice)
+		notificationGroup.DELETE("/push/devices", models.AuthRequired, h.handleUnregisterPushDevice)
+
+		// Browser push subscriptions (Web Push / VAPID)
+		notificationGroup.GET("/webpush/key", models.AuthRequired, h.handleGetWebPushKey)
+		notificationGroup.GET("/webpush/subscriptions", models.AuthRequired, h.handleListWebPushSubscriptions)
+		notificationGroup.POST("/webpush/subscriptions", models.AuthRequired, h.handleSubscribeWebPush)
+		notificationGroup.DELETE("/webpush/subscriptions", models.AuthRequired, h.handleUnsubscribeWebPush)
+	}
+}
+
+// registerWebhookRoutes Webhook Module (平台事件回调)
+func (h *Handlers) registerWebhookRoutes(r *gin.RouterGroup) {
+	webhooks := r.Group("webhooks")
+	webhooks.Use(models.AuthRequired)
+	{
+		webhooks.GET("/events", h.handleListWebhookEvents)
+		webhooks.GET("", h.handleListWebhooks)
+		webhooks.POST("", h.handleCreateWebhook)
+		webhooks.PUT("/:id", h.handleUpdateWebhook)
+		webhooks.DELETE("/:id", h.handleDeleteWebhook)
+		webhooks.POST("/:id/secret", h.handleRotateWebhookSecret)
+		webhooks.POST("/:id/test", h.handleTestWebhook)
+
+		// 投递日志
+		webhooks.GET("/:id/deliveries", h.handleListWebhookDeliveries)
+		webhooks.POST("/:id/deliveries/:deliveryId/redeliver", h.handleRedeliverWebhook)
 	}
 }
 
@@ -314,7 +414,7 @@ func (h *Handlers) registerNotificationRoutes(r *gin.RouterGroup) {
 func (h *Handlers) registerSystemRoutes(r *gin.RouterGroup) {
 	system := r.Group("system")
 	{
-		system.POST("/rate-limiter/config", h.UpdateRateLimiterConfig)
+		system.POST("/rate-limiter/config", models.AuthRequired, models.PermissionRequired(models.PermissionSystemConfig), h.UpdateRateLimiterConfig)
 
 		system.GET("/health", h.HealthCheck)
 		system.GET("/status", h.SystemStatus)
@@ -331,6 +431,15 @@ func (h *Handlers) registerSystemRoutes(r *gin.RouterGroup) {
 		system.PUT("/search/config", models.AuthRequired, h.UpdateSearchConfig)
 		system.POST("/search/enable", models.AuthRequired, h.EnableSearch)
 		system.POST("/search/disable", models.AuthRequired, h.DisableSearch)
+
+		// 角色与权限管理
+		system.GET("/roles", models.AuthRequired, models.PermissionRequired(models.PermissionUserManage), h.handleListRoles)
+		system.PUT("/users/:id/role", models.AuthRequired, models.PermissionRequired(models.PermissionUserManage), h.handleUpdateUserRole)
+
+		// 审计日志
+		system.GET("/audit-logs", models.AuthRequired, models.PermissionRequired(models.PermissionAuditRead), h.handleListAuditLogs)
+		system.GET("/audit-logs/actions", models.AuthRequired, models.PermissionRequired(models.PermissionAuditRead), h.handleListAuditActions)
+		system.GET("/audit-logs/export", models.AuthRequired, models.PermissionRequired(models.PermissionAuditRead), h.handleExportAuditLogs)
 	}
 }
 
@@ -463,6 +572,34 @@ func (h *Handlers) registerAlertRoutes(r *gin.RouterGroup) {
 	}
 }
 
+// registerRecordingRoutes 注册通话录音路由
+func (h *Handlers) registerRecordingRoutes(r *gin.RouterGroup) {
+	recordings := r.Group("recordings")
+	recordings.Use(models.AuthRequired)
+	{
+		// 录音策略
+		recordings.GET("/policy", h.GetRecordingPolicy)
+		recordings.PUT("/policy", h.UpdateRecordingPolicy)
+
+		// 录音管理
+		recordings.GET("", h.ListRecordings)
+		recordings.GET("/:id/download", h.DownloadRecording)
+		recordings.GET("/:id/url", h.GetRecordingURL)
+		recordings.DELETE("/:id", h.DeleteRecording)
+	}
+}
+
+// registerCallRoutes 注册通话记录路由
+func (h *Handlers) registerCallRoutes(r *gin.RouterGroup) {
+	calls := r.Group("calls")
+	calls.Use(models.AuthRequired)
+	{
+		// 通话转写，:id 为 WebRTC 会话 ID
+		calls.GET("/:id/transcript", h.GetCallTranscript)
+		calls.DELETE("/:id/transcript", h.DeleteCallTranscript)
+	}
+}
+
 // registerAssistantRoutes Assistant Module
 func (h *Handlers) registerAssistantRoutes(r *gin.RouterGroup) {
 	assistant := r.Group("assistant")
@@ -535,6 +672,8 @@ func (h *Handlers) registerChatRoutes(r *gin.RouterGroup) {
 	// 其他路由需要认证
 	chat.Use(models.AuthApiRequired)
 	{
+		chat.GET("ice-servers", h.getICEServers)
+
 		chat.GET("chat-session-log", h.getChatSessionLog)
 
 		chat.GET("chat-session-log/:id", h.getChatSessionLogDetail)
@@ -554,7 +693,30 @@ func (h *Handlers) registerCredentialsRoutes(r *gin.RouterGroup) {
 		credential.GET("/", models.AuthRequired, h.handleGetCredential)
 
 		credential.DELETE("/:id", models.AuthRequired, h.handleDeleteCredential)
+
+		// MCP调用范围及调用审计
+		credential.PUT("/:id/mcp-scopes", models.AuthRequired, h.handleUpdateCredentialMCPScopes)
+
+		credential.GET("/:id/mcp-invocations", models.AuthRequired, h.handleListCredentialMCPInvocations)
+
+		// 凭证存储配额
+		credential.PUT("/:id/storage-quota", models.AuthRequired, h.handleUpdateCredentialStorageQuota)
+
+		// 凭证每月用量上限及本月用量
+		credential.PUT("/:id/usage-limits", models.AuthRequired, h.handleUpdateCredentialUsageLimits)
+		credential.GET("/:id/usage", models.AuthRequired, h.handleGetCredentialUsage)
+
+		// TTS提供商健康检查（含备用提供商）
+		credential.GET("/:id/tts-health", models.AuthRequired, h.handleCheckCredentialTTSHealth)
+
+		// 凭证签发的API令牌
+		credential.POST("/:id/tokens", models.AuthRequired, h.handleCreateAPIToken)
+		credential.GET("/:id/tokens", models.AuthRequired, h.handleListAPITokens)
+		credential.DELETE("/:id/tokens/:tokenId", models.AuthRequired, h.handleRevokeAPIToken)
 	}
+
+	// OAuth2 client_credentials：以 apiKey/apiSecret 换取带调用范围的API令牌
+	r.POST("/oauth/token", h.handleOAuthToken)
 }
 
 // registerWebSocketRoutes registers WebSocket routes
@@ -564,8 +726,8 @@ func (h *Handlers) registerWebSocketRoutes(r *gin.RouterGroup) {
 	// WebSocket连接端点
 	r.GET("/ws", models.AuthRequired, wsHandler.HandleWebSocket)
 
-	// 通用WebSocket语音端点（支持多服务商）
-	r.GET("/voice/websocket", h.HandleWebSocketVoice)
+	// 通用WebSocket语音端点（支持多服务商），凭证可用 apiKey/apiSecret 或带 asr:stream 范围的API令牌
+	r.GET("/voice/websocket", models.WithAPIToken(models.ScopeASRStream), h.HandleWebSocketVoice)
 
 	// WebSocket管理API端点
 	wsGroup := r.Group("/ws")
@@ -595,19 +757,50 @@ func (h *Handlers) registerKnowledgeRoutes(r *gin.RouterGroup) {
 		knowledge.GET("/get", models.AuthApiRequired, h.GetKnowledgeBase)
 		//上传文件到知识库（支持多 provider）
 		knowledge.POST("/upload", models.AuthRequired, h.UploadFileToKnowledgeBase)
+		//异步解析、分块、向量化入库，并查询任务进度
+		knowledge.POST("/ingest", h.IngestKnowledgeDocument)
+		knowledge.GET("/ingest", h.ListKnowledgeIngestJobs)
+		knowledge.GET("/ingest/:id", h.GetKnowledgeIngestJob)
+		//同步源：定时从 S3、网页、Notion 导出增量同步文档
+		knowledge.POST("/sync-sources", h.CreateKnowledgeSyncSource)
+		knowledge.GET("/sync-sources", h.ListKnowledgeSyncSources)
+		knowledge.PUT("/sync-sources/:id", h.UpdateKnowledgeSyncSource)
+		knowledge.DELETE("/sync-sources/:id", h.DeleteKnowledgeSyncSource)
+		knowledge.POST("/sync-sources/:id/run", h.RunKnowledgeSyncSource)
+	}
+}
+
+// registerAgentRoutes Agent Module
+func (h *Handlers) registerAgentRoutes(r *gin.RouterGroup) {
+	agents := r.Group("/agents")
+	{
+		//规划agent分解请求，并行分派子任务给RAG、图记忆、LLM agents并合并结果
+		agents.POST("/run", models.AuthRequired, h.RunAgents)
+	}
+}
+
+// registerMCPToolRoutes MCP Dynamic Tool Module
+func (h *Handlers) registerMCPToolRoutes(r *gin.RouterGroup) {
+	tools := r.Group("/mcp/tools", models.AuthRequired, models.PermissionRequired(models.PermissionMCPManage))
+	{
+		// 运行时定义的HTTP工具，保存后MCP服务器立即生效
+		tools.GET("", h.ListMCPTools)
+		tools.POST("", h.CreateMCPTool)
+		tools.PUT("/:id", h.UpdateMCPTool)
+		tools.DELETE("/:id", h.DeleteMCPTool)
 	}
 }
 
 // registerXunfeiTTSRoutes 注册讯飞TTS路由
 func (h *Handlers) registerXunfeiTTSRoutes(r *gin.RouterGroup) {
 	xunfei := r.Group("/xunfei")
+	// 语音合成，同时接受带 tts:synthesize 范围的API令牌
+	xunfei.POST("/synthesize", models.WithAPIToken(models.ScopeTTSSynthesize), models.AuthRequired, idempotent, h.XunfeiSynthesize)
 	xunfei.Use(models.AuthRequired) // 需要认证
 	{
-		// 语音合成
-		xunfei.POST("/synthesize", h.XunfeiSynthesize)
 
 		// 训练任务管理
-		xunfei.POST("/task/create", h.XunfeiCreateTask)
+		xunfei.POST("/task/create", idempotent, h.XunfeiCreateTask)
 		xunfei.POST("/task/submit-audio", h.XunfeiSubmitAudio)
 		xunfei.POST("/task/query", h.XunfeiQueryTask)
 
@@ -619,11 +812,10 @@ func (h *Handlers) registerXunfeiTTSRoutes(r *gin.RouterGroup) {
 // registerVolcengineTTSRoutes 注册火山引擎TTS路由
 func (h *Handlers) registerVolcengineTTSRoutes(r *gin.RouterGroup) {
 	volcengine := r.Group("/volcengine")
+	// 语音合成，同时接受带 tts:synthesize 范围的API令牌
+	volcengine.POST("/synthesize", models.WithAPIToken(models.ScopeTTSSynthesize), models.AuthRequired, idempotent, h.VolcengineSynthesize)
 	volcengine.Use(models.AuthRequired) // 需要认证
 	{
-		// 语音合成
-		volcengine.POST("/synthesize", h.VolcengineSynthesize)
-
 		// 训练任务管理
 		// 注意：火山引擎不需要 create task，speaker_id 从控制台获取
 		volcengine.POST("/task/submit-audio", h.VolcengineSubmitAudio)
@@ -636,10 +828,21 @@ func (h *Handlers) registerVolcengineTTSRoutes(r *gin.RouterGroup) {
 func (h *Handlers) registerVoiceTrainingRoutes(r *gin.RouterGroup) {
 	voice := r.Group("/voice")
 	voice.GET("/lingecho/v1/", h.HandleHardwareWebSocketVoice)
+
+	// 同时接受API令牌（Authorization: Bearer lat_...）的接口，令牌须包含对应的调用范围
+	oneshot := models.WithAPIToken(models.ScopeVoiceOneshot)
+	voice.POST("/synthesize", models.WithAPIToken(models.ScopeTTSSynthesize), models.AuthRequired, idempotent, h.SynthesizeWithVoice)
+	voice.POST("/oneshot_text", oneshot, models.AuthRequired, h.OneShotText)
+	voice.POST("/oneshot_text/stream", oneshot, models.AuthRequired, h.OneShotTextStream)
+	voice.POST("/plain_text", oneshot, models.AuthRequired, h.PlainText)
+	voice.POST("/plain_text/stream", oneshot, models.AuthRequired, h.PlainTextStream)
+	voice.GET("/plain_text/ws", oneshot, models.AuthRequired, h.PlainTextWS)
+	voice.GET("/audio_status", oneshot, models.AuthRequired, h.GetAudioStatus)
+
 	voice.Use(models.AuthRequired) // 需要认证
 	{
 		// 训练任务管理
-		voice.POST("/training/create", h.CreateTrainingTask)
+		voice.POST("/training/create", idempotent, h.CreateTrainingTask)
 		voice.POST("/training/submit-audio", h.SubmitAudio)
 		voice.POST("/training/query", h.QueryTaskStatus)
 
@@ -649,9 +852,6 @@ func (h *Handlers) registerVoiceTrainingRoutes(r *gin.RouterGroup) {
 		voice.POST("/clones/update", h.UpdateVoiceClone)
 		voice.POST("/clones/delete", h.DeleteVoiceClone)
 
-		// 语音合成
-		voice.POST("/synthesize", h.SynthesizeWithVoice)
-
 		// 合成历史
 		voice.GET("/synthesis/history", h.GetSynthesisHistory)
 		voice.POST("/synthesis/delete", h.DeleteSynthesisRecord)
@@ -659,17 +859,13 @@ func (h *Handlers) registerVoiceTrainingRoutes(r *gin.RouterGroup) {
 		// 训练文本
 		voice.GET("/training-texts", h.GetTrainingTexts)
 
-		// 一句话模式
-		voice.POST("/oneshot_text", h.OneShotText)
-
-		voice.POST("/plain_text", h.PlainText)
-
-		// 音频处理
-		voice.GET("/audio_status", h.GetAudioStatus)
-
 		// 获取音色选项列表（根据TTS Provider）
 		voice.GET("/options", h.GetVoiceOptions)
 		voice.GET("/language-options", h.GetLanguageOptions)
+
+		// 音色克隆提供商能力与训练次数
+		voice.GET("/clone-providers", h.GetVoiceCloneProviders)
+		voice.GET("/clone-providers/:provider/quota", h.GetVoiceCloneQuota)
 	}
 }
 
@@ -695,6 +891,20 @@ func (h *Handlers) registerBillingRoutes(r *gin.RouterGroup) {
 		billing.POST("/bills/:id/archive", h.ArchiveBill)
 		billing.PUT("/bills/:id/notes", h.UpdateBillNotes)
 		billing.GET("/bills/:id/export", h.ExportBill)
+
+		// 套餐与月度账单
+		billing.GET("/plans", h.GetBillingPlans)
+		billing.GET("/plan", h.GetCurrentBillingPlan)
+		billing.PUT("/plan", h.SubscribeBillingPlan)
+		billing.GET("/statements", h.GetUsageStatements)
+		billing.POST("/statements", h.GenerateUsageStatement)
+		billing.GET("/statements/:id", h.GetUsageStatement)
+		billing.GET("/statements/:id/export", h.ExportUsageStatement)
+
+		// 通话详单
+		billing.GET("/cdrs", h.GetCallDetailRecords)
+		billing.GET("/cdrs/export", h.ExportCallDetailRecords)
+		billing.GET("/cdrs/:id", h.GetCallDetailRecord)
 	}
 }
 
@@ -706,12 +916,21 @@ func (h *Handlers) registerSipRoutes(r *gin.RouterGroup) {
 		sip.GET("/users", models.AuthRequired, h.sipHandler.GetSipUsers)
 
 		// 呼出相关
-		sip.POST("/calls/outgoing", models.AuthRequired, h.sipHandler.MakeOutgoingCall)
+		sip.POST("/calls/outgoing", models.AuthRequired, idempotent, h.sipHandler.MakeOutgoingCall)
 		sip.GET("/calls/outgoing/:callId", models.AuthRequired, h.sipHandler.GetOutgoingCallStatus)
 		sip.POST("/calls/outgoing/:callId/cancel", models.AuthRequired, h.sipHandler.CancelOutgoingCall)
 		sip.POST("/calls/outgoing/:callId/hangup", models.AuthRequired, h.sipHandler.HangupOutgoingCall)
 
 		// 通话历史
 		sip.GET("/calls", models.AuthRequired, h.sipHandler.GetCallHistory)
+
+		// 外呼任务
+		sip.POST("/campaigns", models.AuthRequired, idempotent, h.sipHandler.CreateSipCampaign)
+		sip.GET("/campaigns", models.AuthRequired, h.sipHandler.ListSipCampaigns)
+		sip.GET("/campaigns/:id", models.AuthRequired, h.sipHandler.GetSipCampaign)
+		sip.GET("/campaigns/:id/calls", models.AuthRequired, h.sipHandler.GetSipCampaignCalls)
+		sip.POST("/campaigns/:id/start", models.AuthRequired, h.sipHandler.StartSipCampaign)
+		sip.POST("/campaigns/:id/pause", models.AuthRequired, h.sipHandler.PauseSipCampaign)
+		sip.POST("/campaigns/:id/cancel", models.AuthRequired, h.sipHandler.CancelSipCampaign)
 	}
 }
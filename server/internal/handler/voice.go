@@ -5,22 +5,25 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/cache"
 	"github.com/code-100-precent/LingEcho/pkg/config"
 	"github.com/code-100-precent/LingEcho/pkg/constants"
 	"github.com/code-100-precent/LingEcho/pkg/graph"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
 	v2 "github.com/code-100-precent/LingEcho/pkg/llm"
+	"github.com/code-100-precent/LingEcho/pkg/notification"
 	"github.com/code-100-precent/LingEcho/pkg/response"
 	stores "github.com/code-100-precent/LingEcho/pkg/storage"
 	"github.com/code-100-precent/LingEcho/pkg/synthesizer"
@@ -28,19 +31,57 @@ import (
 	"github.com/code-100-precent/LingEcho/pkg/voiceclone"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // Audio processing status cache
 type AudioProcessResult struct {
-	Status   string // processing | text_ready | completed | failed
-	Text     string // Recognized and generated text
-	AudioURL string // Audio URL after synthesis is completed
+	Status   string `json:"status"`   // processing | text_ready | completed | failed
+	Text     string `json:"text"`     // Recognized and generated text
+	AudioURL string `json:"audioUrl"` // Audio URL after synthesis is completed
 }
 
-var (
-	audioProcessingCache = make(map[string]AudioProcessResult) // requestID -> result
-	audioCacheMutex      sync.RWMutex
-)
+// audioStatusTTL 音频处理状态在缓存中的保留时间，超时未被轮询的结果自动过期
+const audioStatusTTL = 30 * time.Minute
+
+// audioStatusKey 音频处理状态缓存键（requestID -> result）
+func audioStatusKey(requestID string) string {
+	return "voice:audio_status:" + requestID
+}
+
+// setAudioProcessResult 写入音频处理状态到全局缓存（Redis 时多实例共享）
+func setAudioProcessResult(requestID string, result AudioProcessResult) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		logrus.WithError(err).Warn("marshal audio process result failed")
+		return
+	}
+	if err := cache.Set(context.Background(), audioStatusKey(requestID), string(data), audioStatusTTL); err != nil {
+		logrus.WithError(err).WithField("requestId", requestID).Warn("save audio process result failed")
+	}
+}
+
+// getAudioProcessResult 从全局缓存读取音频处理状态
+func getAudioProcessResult(requestID string) (AudioProcessResult, bool) {
+	var result AudioProcessResult
+	value, ok := cache.Get(context.Background(), audioStatusKey(requestID))
+	if !ok {
+		return result, false
+	}
+	raw, ok := value.(string)
+	if !ok {
+		return result, false
+	}
+	if err := json.Unmarshal([]byte(raw), &result); err != nil {
+		return result, false
+	}
+	return result, true
+}
+
+// deleteAudioProcessResult 删除音频处理状态
+func deleteAudioProcessResult(requestID string) {
+	_ = cache.Delete(context.Background(), audioStatusKey(requestID))
+}
 
 // getLanguageConfigKey Get language configuration field name based on provider
 // Load from language configuration file, use default value if no configuration file exists
@@ -66,45 +107,6 @@ func getLanguageConfigKey(provider string) string {
 	}
 }
 
-// cleanTextForTTS Clean text, remove Markdown format symbols to make it suitable for TTS playback
-func cleanTextForTTS(text string) string {
-	// 移除Markdown粗体标记 **text**
-	text = regexp.MustCompile(`\*\*(.*?)\*\*`).ReplaceAllString(text, "$1")
-
-	// 移除Markdown斜体标记 *text*
-	text = regexp.MustCompile(`\*(.*?)\*`).ReplaceAllString(text, "$1")
-
-	// 移除Markdown代码标记 `text`
-	text = regexp.MustCompile("`(.*?)`").ReplaceAllString(text, "$1")
-
-	// 移除Markdown链接 [text](url)
-	text = regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)`).ReplaceAllString(text, "$1")
-
-	// 移除Markdown标题标记 # ## ###
-	text = regexp.MustCompile(`^#{1,6}\s*`).ReplaceAllString(text, "")
-
-	// 移除Markdown列表标记 - * +
-	text = regexp.MustCompile(`^[\s]*[-*+]\s*`).ReplaceAllString(text, "")
-
-	// 移除Markdown引用标记 >
-	text = regexp.MustCompile(`^>\s*`).ReplaceAllString(text, "")
-
-	// 移除多余的空行（连续的空行）
-	text = regexp.MustCompile(`\n\s*\n`).ReplaceAllString(text, "\n")
-
-	// 移除行首行尾的空白字符
-	lines := strings.Split(text, "\n")
-	for i, line := range lines {
-		lines[i] = strings.TrimSpace(line)
-	}
-	text = strings.Join(lines, "\n")
-
-	// 移除开头和结尾的空白字符
-	text = strings.TrimSpace(text)
-
-	return text
-}
-
 // CreateTrainingTaskRequest Create training task request
 type CreateTrainingTaskRequest struct {
 	TaskName string `json:"taskName" binding:"required"`
@@ -163,6 +165,10 @@ func (h *Handlers) CreateTrainingTask(c *gin.Context) {
 	if req.Language == "" {
 		req.Language = models.LanguageChinese
 	}
+	if caps, ok := voiceclone.GetCapabilities(voiceclone.ProviderXunfei); ok && !caps.SupportsLanguage(req.Language) {
+		response.Fail(c, "不支持的语言", fmt.Sprintf("%s 支持的语言: %s", caps.Name, strings.Join(caps.Languages, ", ")))
+		return
+	}
 
 	// 1) 调用讯飞创建任务（使用 voiceclone）
 	factory := voiceclone.NewFactory()
@@ -224,6 +230,52 @@ func (h *Handlers) CreateTrainingTask(c *gin.Context) {
 	response.Success(c, "创建训练任务成功", task)
 }
 
+// VoiceCloneProviderInfo 音色克隆提供商能力及当前是否已配置
+type VoiceCloneProviderInfo struct {
+	voiceclone.Capabilities
+	Configured bool `json:"configured"`
+}
+
+// GetVoiceCloneProviders 列出音色克隆提供商的能力，供前端按提供商展示语言、录音要求和训练流程
+func (h *Handlers) GetVoiceCloneProviders(c *gin.Context) {
+	factory := voiceclone.NewFactory()
+	providers := make([]VoiceCloneProviderInfo, 0)
+	for _, caps := range voiceclone.ListCapabilities() {
+		_, err := factory.CreateServiceFromEnv(caps.Provider)
+		providers = append(providers, VoiceCloneProviderInfo{
+			Capabilities: caps,
+			Configured:   err == nil,
+		})
+	}
+	response.Success(c, "获取音色克隆提供商成功", providers)
+}
+
+// GetVoiceCloneQuota 查询提供商剩余训练次数，taskId 为训练任务ID或音色ID
+func (h *Handlers) GetVoiceCloneQuota(c *gin.Context) {
+	provider := voiceclone.Provider(strings.ToLower(c.Param("provider")))
+	caps, ok := voiceclone.GetCapabilities(provider)
+	if !ok {
+		response.Fail(c, "不支持的提供商", string(provider))
+		return
+	}
+	if !caps.TrainingQuota {
+		response.Fail(c, "该提供商不支持查询训练次数", string(provider))
+		return
+	}
+
+	service, err := voiceclone.NewFactory().CreateServiceFromEnv(provider)
+	if err != nil {
+		response.Fail(c, "初始化音色克隆服务失败", err.Error())
+		return
+	}
+	quota, err := voiceclone.QueryTrainingQuota(c.Request.Context(), service, c.Query("taskId"))
+	if err != nil {
+		response.Fail(c, "查询训练次数失败", err.Error())
+		return
+	}
+	response.Success(c, "查询训练次数成功", quota)
+}
+
 // saveVoiceCloneConfig 保存音色克隆配置到数据库
 func (h *Handlers) saveVoiceCloneConfig(provider string) {
 	var configKey string
@@ -344,6 +396,14 @@ func (h *Handlers) SubmitAudio(c *gin.Context) {
 		return
 	}
 
+	// 校验并预处理录音（时长、削波、静音、响度、采样率），不合格的录音不提交给提供商
+	caps, _ := voiceclone.GetCapabilities(voiceclone.ProviderXunfei)
+	prepared, err := voiceclone.PrepareTrainingAudio(audioData, file.Filename, caps.PreprocessOptions())
+	if err != nil {
+		response.Fail(c, "音频不符合训练要求", err.Error())
+		return
+	}
+
 	// 2) 调用讯飞提交音频（使用 voiceclone）
 	factory := voiceclone.NewFactory()
 	service, err := factory.CreateServiceFromEnv(voiceclone.ProviderXunfei)
@@ -356,7 +416,7 @@ func (h *Handlers) SubmitAudio(c *gin.Context) {
 		TaskID:    task.TaskID,
 		TextID:    task.TextID,
 		TextSegID: req.TextSegID,
-		AudioFile: bytes.NewReader(audioData),
+		AudioFile: bytes.NewReader(prepared.WAV),
 		Language:  task.Language,
 	}
 	if err := service.SubmitAudio(c.Request.Context(), submitReq); err != nil {
@@ -372,7 +432,7 @@ func (h *Handlers) SubmitAudio(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, "提交音频成功", nil)
+	response.Success(c, "提交音频成功", prepared)
 }
 
 // QueryTaskStatus 查询任务状态
@@ -426,6 +486,7 @@ func (h *Handlers) QueryTaskStatus(c *gin.Context) {
 		trainStatus = models.TrainingStatusInProgress
 	}
 
+	previousStatus := task.Status
 	task.Status = trainStatus
 	task.TrainVID = status.TrainVID
 	task.AssetID = status.AssetID // xunfei 返回的音色ID
@@ -443,9 +504,46 @@ func (h *Handlers) QueryTaskStatus(c *gin.Context) {
 		}
 	}
 
+	if previousStatus != trainStatus && task.IsCompleted() {
+		notifyTrainingCompleted(h.db, user, &task)
+	}
+
 	response.Success(c, "查询任务状态成功", task)
 }
 
+// notifyTrainingCompleted 通知用户音色训练已结束
+func notifyTrainingCompleted(db *gorm.DB, user *models.User, task *models.VoiceTrainingTask) {
+	msg := notification.Message{
+		Event:   notification.EventTrainingCompleted,
+		Level:   notification.LevelInfo,
+		Title:   "音色训练完成",
+		Content: fmt.Sprintf("训练任务「%s」已完成，音色可以在音色列表中使用。", task.TaskName),
+		Data: map[string]interface{}{
+			"taskId":  task.TaskID,
+			"assetId": task.AssetID,
+			"status":  task.Status,
+		},
+	}
+	if !task.IsSuccess() {
+		msg.Level = notification.LevelError
+		msg.Title = "音色训练失败"
+		msg.Content = fmt.Sprintf("训练任务「%s」训练失败：%s", task.TaskName, task.FailedReason)
+	}
+	utils.Sig().Emit(models.SigUserNotify, user, db, msg)
+
+	data := map[string]interface{}{
+		"taskId":       task.TaskID,
+		"taskName":     task.TaskName,
+		"assetId":      task.AssetID,
+		"status":       task.Status,
+		"success":      task.IsSuccess(),
+		"failedReason": task.FailedReason,
+	}
+	if _, err := models.EnqueueWebhookEvent(db, user.ID, models.WebhookEventTrainingCompleted, "training.completed:"+task.TaskID, data, 0); err != nil {
+		logrus.WithError(err).WithField("taskId", task.TaskID).Warn("queue training webhook failed")
+	}
+}
+
 // GetUserVoiceClones 获取用户的音色列表
 func (h *Handlers) GetUserVoiceClones(c *gin.Context) {
 	user := models.CurrentUser(c)
@@ -609,6 +707,7 @@ func (h *Handlers) SynthesizeWithVoice(c *gin.Context) {
 		fmt.Printf("更新音色使用统计失败: %v\n", err)
 	}
 
+	synthesis.AudioURL = stores.SignPublicURL(synthesis.AudioURL, user.ID)
 	response.Success(c, "语音合成成功", synthesis)
 }
 
@@ -689,7 +788,7 @@ func (h *Handlers) GetSynthesisHistory(c *gin.Context) {
 			VoiceCloneID: item.VoiceCloneID,
 			Text:         item.Text,
 			Language:     item.Language,
-			AudioURL:     item.AudioURL,
+			AudioURL:     stores.SignPublicURL(item.AudioURL, user.ID),
 			Status:       item.Status,
 			CreatedAt:    item.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 			Provider:     providerStr,
@@ -1158,9 +1257,7 @@ func (h *Handlers) GetAudioStatus(c *gin.Context) {
 		return
 	}
 
-	audioCacheMutex.RLock()
-	result, exists := audioProcessingCache[requestID]
-	audioCacheMutex.RUnlock()
+	result, exists := getAudioProcessResult(requestID)
 
 	if !exists {
 		response.Success(c, "处理中", gin.H{
@@ -1173,9 +1270,7 @@ func (h *Handlers) GetAudioStatus(c *gin.Context) {
 
 	if result.Status == "completed" {
 		// 返回后删除
-		audioCacheMutex.Lock()
-		delete(audioProcessingCache, requestID)
-		audioCacheMutex.Unlock()
+		deleteAudioProcessResult(requestID)
 	}
 
 	response.Success(c, "状态", gin.H{
@@ -1187,8 +1282,8 @@ func (h *Handlers) GetAudioStatus(c *gin.Context) {
 
 // OneShotTextRequest 请求结构
 type OneShotTextRequest struct {
-	APIKey          string  `json:"apiKey" binding:"required"`
-	APISecret       string  `json:"apiSecret" binding:"required"`
+	APIKey          string  `json:"apiKey"`    // 使用 Authorization: Bearer <API令牌> 时可省略
+	APISecret       string  `json:"apiSecret"` // 使用 Authorization: Bearer <API令牌> 时可省略
 	Text            string  `json:"text" binding:"required"`
 	AssistantID     int     `json:"assistantId"`
 	Language        string  `json:"language"`
@@ -1209,22 +1304,45 @@ func (h *Handlers) OneShotText(c *gin.Context) {
 		return
 	}
 
+	chat, ok := h.prepareOneShotText(c, &req)
+	if !ok {
+		return
+	}
+	llmResponse, ok := chat.reply(c)
+	if !ok {
+		return
+	}
+
+	// 3. 立即返回文本，异步处理音频
+	// 聊天记录已通过 LLMListener 自动保存（如果提供了 UserID 和 AssistantID）
+	requestId := h.startOneShotAudio(chat, &req, llmResponse)
+	response.Success(c, "处理完成", gin.H{
+		"text":          llmResponse,
+		"audioUrl":      "",        // 先返回空，后续通过轮询获取
+		"requestId":     requestId, // 用于轮询，为空表示未合成音频
+		"citations":     chat.citations,
+		"audioDegraded": chat.skipAudio, // 凭证语音合成用量已达上限，只返回文本
+	})
+}
+
+// prepareOneShotText 校验凭证并根据请求与助手配置准备一句话模式的 LLM 调用，失败时已写出错误响应
+func (h *Handlers) prepareOneShotText(c *gin.Context, req *OneShotTextRequest) (*textChat, bool) {
 	// 1. 查询用户凭证配置
-	credential, err := models.GetUserCredentialByApiSecretAndApiKey(h.db, req.APIKey, req.APISecret)
+	credential, err := h.requestCredential(c, req.APIKey, req.APISecret)
 	if err != nil {
 		response.Fail(c, "查询凭证失败", err.Error())
-		return
+		return nil, false
 	}
 	if credential == nil {
-		response.Fail(c, "凭证不存在", "无效的 apiKey 或 apiSecret")
-		return
+		response.Fail(c, "凭证不存在", "无效的 API 令牌或 apiKey/apiSecret")
+		return nil, false
 	}
 
 	// 获取用户信息
 	var user models.User
 	if err := h.db.First(&user, credential.UserID).Error; err != nil {
 		response.Fail(c, "用户不存在", err.Error())
-		return
+		return nil, false
 	}
 
 	// 设置默认值
@@ -1235,9 +1353,22 @@ func (h *Handlers) OneShotText(c *gin.Context) {
 		}
 	}
 
+	chat := &textChat{credential: credential, user: user, text: req.Text}
+
+	// 凭证本月用量上限：LLM 用完时拒绝；语音合成用完时按凭证设置拒绝或降级为只返回文本
+	if h.rejectOverCredentialQuota(c, credential, models.MeterLLMTokens) {
+		return nil, false
+	}
+	var quotaErr *models.CredentialQuotaError
+	if err := h.credentialQuotaError(credential, models.MeterTTSCharacters); errors.As(err, &quotaErr) {
+		if !quotaErr.Degradable() {
+			response.AbortWithStatusJSON(c, http.StatusPaymentRequired, err)
+			return nil, false
+		}
+		chat.skipAudio = true
+	}
+
 	// 2. 调用LLM处理文本
-	var llmResponse string
-	var errLLM error
 	if credential.LLMProvider != "" && credential.LLMApiKey != "" {
 		llmBaseURL := credential.LLMApiURL
 		if llmBaseURL == "" {
@@ -1301,8 +1432,8 @@ func (h *Handlers) OneShotText(c *gin.Context) {
 			}
 		}
 
-		// 如果开启了图记忆功能，则尝试从 Neo4j 中获取该用户的长期偏好主题，并拼接到系统提示词中
-		if config.GlobalConfig.Neo4jEnabled && assistant.EnableGraphMemory {
+		// 如果开启了图记忆功能，则尝试从图存储中获取该用户的长期偏好主题，并拼接到系统提示词中
+		if assistant.EnableGraphMemory {
 			if store := graph.GetDefaultStore(); store != nil {
 				ctx := c.Request.Context()
 				if userCtx, err := store.GetUserContext(ctx, user.ID, int64(req.AssistantID)); err == nil {
@@ -1330,11 +1461,13 @@ func (h *Handlers) OneShotText(c *gin.Context) {
 				"code": 500,
 				"msg":  fmt.Sprintf("初始化LLM失败: %v", err),
 			})
-			return
+			return nil, false
+		}
+		if req.AssistantID > 0 {
+			h.loadConversationTools(llmHandler, int64(req.AssistantID), assistant.EnableMCPTools)
 		}
 
 		// 构建查询文本（如果提供了知识库，先检索知识库）
-		queryText := req.Text
 		var knowledgeKey = req.KnowledgeBaseID
 
 		// 如果前端没传，但 assistantId 有值，查询 assistant 表
@@ -1346,33 +1479,7 @@ func (h *Handlers) OneShotText(c *gin.Context) {
 			}
 		}
 
-		// 如果找到了 knowledgeKey，检索知识库
-		if knowledgeKey != "" {
-			// 检索知识库
-			knowledgeResults, err := models.SearchKnowledgeBase(h.db, knowledgeKey, req.Text, 5)
-			if err != nil {
-				logrus.Warnf("Failed to search knowledge base: %v", err)
-				// 搜索失败时使用原始查询
-				queryText = req.Text
-			} else if len(knowledgeResults) > 0 {
-				// 构建上下文：使用自然的 prompt 模板格式，避免AI提到"文档"
-				var contextBuilder strings.Builder
-				contextBuilder.WriteString(fmt.Sprintf("用户问题: %s\n\n", req.Text))
-				// 直接提供信息内容，不强调"文档"或"参考信息"
-				for i, result := range knowledgeResults {
-					if i > 0 {
-						contextBuilder.WriteString("\n\n")
-					}
-					contextBuilder.WriteString(result.Content)
-				}
-				contextBuilder.WriteString("\n\n请基于以上信息回答用户问题，回答要自然流畅，不要提及信息来源。")
-				queryText = contextBuilder.String()
-				logrus.Infof("Retrieved %d relevant documents from knowledge base (key: %s)", len(knowledgeResults), knowledgeKey)
-			} else {
-				// 没有找到相关内容，使用原始查询
-				queryText = req.Text
-			}
-		}
+		queryText, citations := h.knowledgeQuery(knowledgeKey, req.Text)
 
 		userID := user.ID
 		assistantID := int64(req.AssistantID)
@@ -1381,7 +1488,11 @@ func (h *Handlers) OneShotText(c *gin.Context) {
 			sessionID = fmt.Sprintf("text_v2_%d_%d", user.ID, time.Now().Unix())
 		}
 		credentialID := credential.ID
-		llmResponse, errLLM = llmHandler.QueryWithOptions(queryText, v2.QueryOptions{
+		chat.provider = llmHandler
+		chat.queryText = queryText
+		chat.citations = citations
+		chat.knowledgeKey = knowledgeKey
+		chat.options = v2.QueryOptions{
 			Model:        llmModel,
 			Temperature:  temp,
 			MaxTokens:    maxTokens,
@@ -1390,37 +1501,20 @@ func (h *Handlers) OneShotText(c *gin.Context) {
 			CredentialID: &credentialID,
 			SessionID:    sessionID,
 			ChatType:     models.ChatTypeText,
-		})
-		if errLLM != nil {
-			// 提取更友好的错误信息
-			errMsg := errLLM.Error()
-
-			// 检查是否是模型不可用的错误
-			if strings.Contains(errMsg, "no available channels") || strings.Contains(errMsg, "model") {
-				response.Fail(c, "模型不可用", fmt.Sprintf("模型 %s 当前不可用，请检查模型配置或尝试其他模型。错误详情：%s", llmModel, errMsg))
-			} else {
-				response.Fail(c, "LLM处理失败", errMsg)
-			}
-			return
 		}
-	} else {
-		// 如果没有配置LLM，直接返回原文本
-		llmResponse = req.Text
 	}
+	return chat, true
+}
 
-	// 3. 立即返回文本，异步处理音频
-	requestId := fmt.Sprintf("%d_%d", user.ID, time.Now().Unix())
-	response.Success(c, "处理完成", gin.H{
-		"text":      llmResponse,
-		"audioUrl":  "",        // 先返回空，后续通过轮询获取
-		"requestId": requestId, // 用于轮询
-	})
-
-	// 4. 聊天记录已通过 LLMListener 自动保存（如果提供了 UserID 和 AssistantID）
-	// 这里不再需要手动保存，避免重复记录
-
-	// 5. 异步处理音频合成（使用pkg/synthesis）
-	go h.processAudioAsyncV2(context.Background(), credential, user.ID, llmResponse, req.Language, req.Speaker, req.VoiceCloneID, requestId)
+// startOneShotAudio 异步合成回复音频，返回用于轮询 audio_status 的 requestId；凭证语音合成用量已降级时不合成，返回空
+func (h *Handlers) startOneShotAudio(chat *textChat, req *OneShotTextRequest, text string) string {
+	if chat.skipAudio {
+		return ""
+	}
+	requestId := fmt.Sprintf("%d_%d", chat.user.ID, time.Now().Unix())
+	setAudioProcessResult(requestId, AudioProcessResult{Status: "processing", Text: text})
+	go h.processAudioAsyncV2(context.Background(), chat.credential, chat.user.ID, text, req.Language, req.Speaker, req.VoiceCloneID, requestId)
+	return requestId
 }
 
 // PlainText 处理纯文本对话（不进行TTS合成，用于调试）
@@ -1431,40 +1525,62 @@ func (h *Handlers) PlainText(c *gin.Context) {
 		return
 	}
 
+	chat, ok := h.preparePlainText(c, &req)
+	if !ok {
+		return
+	}
+	llmResponse, ok := chat.reply(c)
+	if !ok {
+		return
+	}
+
+	// 返回成功响应
+	response.Success(c, "处理成功", gin.H{
+		"text":      llmResponse,
+		"citations": chat.citations,
+	})
+}
+
+// preparePlainText 校验助手归属并准备纯文本对话的 LLM 调用，失败时已写出错误响应
+func (h *Handlers) preparePlainText(c *gin.Context, req *OneShotTextRequest) (*textChat, bool) {
 	// 1. 检查助手是否存在
 	var assistant models.Assistant
 	if err := h.db.First(&assistant, req.AssistantID).Error; err != nil {
 		response.Fail(c, "助手不存在", "请检查助手ID是否正确")
-		return
+		return nil, false
 	}
 
 	// 2. 查询用户凭证配置
-	credential, err := models.GetUserCredentialByApiSecretAndApiKey(h.db, req.APIKey, req.APISecret)
+	credential, err := h.requestCredential(c, req.APIKey, req.APISecret)
 	if err != nil {
 		response.Fail(c, "查询凭证失败", err.Error())
-		return
+		return nil, false
 	}
 	if credential == nil {
-		response.Fail(c, "凭证不存在", "无效的 apiKey 或 apiSecret")
-		return
+		response.Fail(c, "凭证不存在", "无效的 API 令牌或 apiKey/apiSecret")
+		return nil, false
 	}
 
 	// 3. 获取用户信息
 	var user models.User
 	if err := h.db.First(&user, credential.UserID).Error; err != nil {
 		response.Fail(c, "用户不存在", err.Error())
-		return
+		return nil, false
 	}
 
 	// 4. 判断是否是该用户的助手
 	if assistant.UserID != user.ID {
 		response.Fail(c, "无权限", "请检查助手ID是否正确")
-		return
+		return nil, false
+	}
+
+	if h.rejectOverCredentialQuota(c, credential, models.MeterLLMTokens) {
+		return nil, false
 	}
 
+	chat := &textChat{credential: credential, user: user, text: req.Text}
+
 	// 5. 调用LLM处理文本
-	var llmResponse string
-	var errLLM error
 	if credential.LLMProvider != "" && credential.LLMApiKey != "" {
 		llmBaseURL := credential.LLMApiURL
 		if llmBaseURL == "" {
@@ -1478,8 +1594,8 @@ func (h *Handlers) PlainText(c *gin.Context) {
 			}
 		}
 
-		// 如果开启了图记忆功能，则尝试从 Neo4j 中获取该用户的长期偏好主题，并拼接到系统提示词中
-		if config.GlobalConfig.Neo4jEnabled && assistant.EnableGraphMemory {
+		// 如果开启了图记忆功能，则尝试从图存储中获取该用户的长期偏好主题，并拼接到系统提示词中
+		if assistant.EnableGraphMemory {
 			if store := graph.GetDefaultStore(); store != nil {
 				ctx := c.Request.Context()
 				if userCtx, err := store.GetUserContext(ctx, user.ID, int64(req.AssistantID)); err == nil {
@@ -1498,7 +1614,10 @@ func (h *Handlers) PlainText(c *gin.Context) {
 				"code": 500,
 				"msg":  fmt.Sprintf("初始化LLM失败: %v", err),
 			})
-			return
+			return nil, false
+		}
+		if req.AssistantID > 0 {
+			h.loadConversationTools(llmHandler, int64(req.AssistantID), assistant.EnableMCPTools)
 		}
 
 		// 获取模型，优先级：Assistant配置 > 环境变量 > 默认值
@@ -1511,7 +1630,6 @@ func (h *Handlers) PlainText(c *gin.Context) {
 		}
 
 		// 构建查询文本（如果提供了知识库，先检索知识库）
-		queryText := req.Text
 		var knowledgeKey = req.KnowledgeBaseID
 
 		// 如果前端没传，但 assistantId 有值，查询 assistant 表
@@ -1521,33 +1639,7 @@ func (h *Handlers) PlainText(c *gin.Context) {
 			}
 		}
 
-		// 如果找到了 knowledgeKey，检索知识库
-		if knowledgeKey != "" {
-			// 检索知识库
-			knowledgeResults, err := models.SearchKnowledgeBase(h.db, knowledgeKey, req.Text, 5)
-			if err != nil {
-				logrus.Warnf("Failed to search knowledge base: %v", err)
-				// 搜索失败时使用原始查询
-				queryText = req.Text
-			} else if len(knowledgeResults) > 0 {
-				// 构建上下文：使用自然的 prompt 模板格式，避免AI提到"文档"
-				var contextBuilder strings.Builder
-				contextBuilder.WriteString(fmt.Sprintf("用户问题: %s\n\n", req.Text))
-				// 直接提供信息内容，不强调"文档"或"参考信息"
-				for i, result := range knowledgeResults {
-					if i > 0 {
-						contextBuilder.WriteString("\n\n")
-					}
-					contextBuilder.WriteString(result.Content)
-				}
-				contextBuilder.WriteString("\n\n请基于以上信息回答用户问题，回答要自然流畅，不要提及信息来源。")
-				queryText = contextBuilder.String()
-				logrus.Infof("Retrieved %d relevant documents from knowledge base (key: %s)", len(knowledgeResults), knowledgeKey)
-			} else {
-				// 没有找到相关内容，使用原始查询
-				queryText = req.Text
-			}
-		}
+		queryText, citations := h.knowledgeQuery(knowledgeKey, req.Text)
 
 		// 优先使用请求中的 temperature 和 maxTokens，如果没有则从 assistant 中读取
 		var temp *float32
@@ -1592,7 +1684,11 @@ func (h *Handlers) PlainText(c *gin.Context) {
 			sessionID = fmt.Sprintf("plain_text_%d_%d", user.ID, time.Now().Unix())
 		}
 		credentialID := credential.ID
-		llmResponse, errLLM = llmHandler.QueryWithOptions(queryText, v2.QueryOptions{
+		chat.provider = llmHandler
+		chat.queryText = queryText
+		chat.citations = citations
+		chat.knowledgeKey = knowledgeKey
+		chat.options = v2.QueryOptions{
 			Model:        llmModel,
 			Temperature:  temp,
 			MaxTokens:    maxTokens,
@@ -1601,34 +1697,102 @@ func (h *Handlers) PlainText(c *gin.Context) {
 			CredentialID: &credentialID,
 			SessionID:    sessionID,
 			ChatType:     models.ChatTypeText,
-		})
-		if errLLM != nil {
-			// 提取更友好的错误信息
-			errMsg := errLLM.Error()
-
-			// 检查是否是模型不可用的错误
-			if strings.Contains(errMsg, "no available channels") || strings.Contains(errMsg, "model") {
-				response.Fail(c, "模型不可用", fmt.Sprintf("模型 %s 当前不可用，请检查模型配置或尝试其他模型。错误详情：%s", llmModel, errMsg))
-			} else {
-				response.Fail(c, "LLM处理失败", errMsg)
-			}
-			return
 		}
-	} else {
+	}
+	return chat, true
+}
+
+// textChat 一次文本对话的 LLM 调用，由 prepareOneShotText / preparePlainText 生成
+type textChat struct {
+	credential   *models.UserCredential
+	user         models.User
+	text         string               // 用户输入
+	provider     v2.LLMProvider       // 为 nil 时凭证未配置 LLM，直接返回用户输入
+	queryText    string               // 拼接知识库内容后的查询文本
+	citations    []knowledge.Citation // queryText 中知识库内容的来源，随回复返回
+	knowledgeKey string               // 检索的知识库，为空时不检索
+	options      v2.QueryOptions      // 含日志上下文，LLMListener 据此保存聊天记录
+	skipAudio    bool                 // 凭证语音合成用量已达上限且设置为降级，不合成音频
+}
+
+// reply 非流式获取回复，失败时已写出错误响应
+func (t *textChat) reply(c *gin.Context) (string, bool) {
+	if t.provider == nil {
 		// 如果没有配置LLM，直接返回原文本
-		llmResponse = req.Text
+		return t.text, true
+	}
+	llmResponse, err := t.provider.QueryWithOptions(t.queryText, t.options)
+	if err != nil {
+		title, detail := llmFailure(t.options.Model, err)
+		response.Fail(c, title, detail)
+		return "", false
 	}
+	return llmResponse, true
+}
 
-	// 返回成功响应
-	response.Success(c, "处理成功", map[string]string{
-		"text": llmResponse,
-	})
+// knowledgeQuery 检索知识库并把相关内容拼接到查询文本中，同时返回这些内容的来源（文档、页码、
+// 位置与相关度）。未检索到时返回原文本，来源为空
+func (h *Handlers) knowledgeQuery(knowledgeKey, text string) (string, []knowledge.Citation) {
+	if knowledgeKey == "" || text == "" {
+		return text, nil
+	}
+	knowledgeResults, err := models.SearchKnowledgeBase(h.db, knowledgeKey, text, 5)
+	if err != nil {
+		logrus.Warnf("Failed to search knowledge base: %v", err)
+		// 搜索失败时使用原始查询
+		return text, nil
+	}
+	if len(knowledgeResults) == 0 {
+		// 没有找到相关内容，使用原始查询
+		return text, nil
+	}
+	// 构建上下文：使用自然的 prompt 模板格式，避免AI提到"文档"
+	var contextBuilder strings.Builder
+	contextBuilder.WriteString(fmt.Sprintf("用户问题: %s\n\n", text))
+	// 直接提供信息内容，不强调"文档"或"参考信息"
+	for i, result := range knowledgeResults {
+		if i > 0 {
+			contextBuilder.WriteString("\n\n")
+		}
+		contextBuilder.WriteString(result.Content)
+	}
+	contextBuilder.WriteString("\n\n请基于以上信息回答用户问题，回答要自然流畅，不要提及信息来源。")
+	logrus.Infof("Retrieved %d relevant documents from knowledge base (key: %s)", len(knowledgeResults), knowledgeKey)
+	return contextBuilder.String(), knowledge.CitationsFromResults(knowledgeResults)
+}
+
+// llmFailure 把 LLM 调用错误转换为更友好的错误信息
+func llmFailure(model string, err error) (string, string) {
+	errMsg := err.Error()
+	// 检查是否是模型不可用的错误
+	if strings.Contains(errMsg, "no available channels") || strings.Contains(errMsg, "model") {
+		return "模型不可用", fmt.Sprintf("模型 %s 当前不可用，请检查模型配置或尝试其他模型。错误详情：%s", model, errMsg)
+	}
+	return "LLM处理失败", errMsg
+}
+
+// trackOneshotAudio 登记一句话语音合成的音频，按 ONESHOT_AUDIO_TTL 设置过期时间，由存储清理任务删除
+func (h *Handlers) trackOneshotAudio(credential *models.UserCredential, userID uint, key string, size int64) {
+	object := &models.StorageObject{
+		StorageKey:   key,
+		UserID:       userID,
+		CredentialID: credential.ID,
+		Category:     models.StorageCategoryOneshot,
+		Size:         size,
+	}
+	if ttl := config.GlobalConfig.OneshotAudioTTL; ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		object.ExpiresAt = &expiresAt
+	}
+	if err := models.TrackStorageObject(h.db, object, "一句话语音合成音频"); err != nil {
+		logrus.WithError(err).WithField("key", key).Warn("Failed to track oneshot audio")
+	}
 }
 
 // processAudioAsyncV2 异步处理音频合成（V2版本，使用用户凭证配置）
 func (h *Handlers) processAudioAsyncV2(ctx context.Context, credential *models.UserCredential, userID uint, text, language, speaker string, voiceCloneID int, requestID string) {
-	// 清理文本，移除Markdown格式符号
-	text = cleanTextForTTS(text)
+	// 归一化文本：去掉Markdown与代码块，按语言展开数字、日期、货币等读法
+	text = synthesizer.NormalizeForSpeech(text, language)
 
 	// 确定使用的音色
 	voiceType := speaker
@@ -1720,13 +1884,11 @@ func (h *Handlers) processAudioAsyncV2(ctx context.Context, credential *models.U
 
 					if len(collectedAudio) == 0 {
 						fmt.Printf("[V2] 音色克隆音频数据为空\n")
-						audioCacheMutex.Lock()
-						audioProcessingCache[requestID] = AudioProcessResult{
+						setAudioProcessResult(requestID, AudioProcessResult{
 							Status:   "failed",
 							Text:     text,
 							AudioURL: "",
-						}
-						audioCacheMutex.Unlock()
+						})
 						return
 					}
 
@@ -1739,13 +1901,11 @@ func (h *Handlers) processAudioAsyncV2(ctx context.Context, credential *models.U
 					wavData, err := h.createWAVFile(collectedAudio, sampleRate, channels, bitDepth)
 					if err != nil {
 						fmt.Printf("[V2] 创建WAV文件失败: %v\n", err)
-						audioCacheMutex.Lock()
-						audioProcessingCache[requestID] = AudioProcessResult{
+						setAudioProcessResult(requestID, AudioProcessResult{
 							Status:   "failed",
 							Text:     text,
 							AudioURL: "",
-						}
-						audioCacheMutex.Unlock()
+						})
 						return
 					}
 
@@ -1755,18 +1915,18 @@ func (h *Handlers) processAudioAsyncV2(ctx context.Context, credential *models.U
 					err = store.Write(ttsKey, bytes.NewReader(wavData))
 					if err != nil {
 						fmt.Printf("[V2] 保存音频失败: %v\n", err)
-						audioCacheMutex.Lock()
-						audioProcessingCache[requestID] = AudioProcessResult{
+						setAudioProcessResult(requestID, AudioProcessResult{
 							Status:   "failed",
 							Text:     text,
 							AudioURL: "",
-						}
-						audioCacheMutex.Unlock()
+						})
 						return
 					}
 
-					// 获取音频URL
-					ttsAudioURL := store.PublicURL(ttsKey)
+					h.trackOneshotAudio(credential, userID, ttsKey, int64(len(wavData)))
+
+					// 获取音频URL：调用方凭 API 密钥访问、没有登录会话，签名地址不绑定用户
+					ttsAudioURL := stores.SignedURL(ttsKey, 0, 0)
 
 					// 更新音色使用统计
 					clone.UsageCount++
@@ -1775,13 +1935,11 @@ func (h *Handlers) processAudioAsyncV2(ctx context.Context, credential *models.U
 					h.db.Save(&clone)
 
 					// 将音频URL存储到缓存中
-					audioCacheMutex.Lock()
-					audioProcessingCache[requestID] = AudioProcessResult{
+					setAudioProcessResult(requestID, AudioProcessResult{
 						Status:   "completed",
 						Text:     text,
 						AudioURL: ttsAudioURL,
-					}
-					audioCacheMutex.Unlock()
+					})
 
 					fmt.Printf("[V2] 音色克隆流式音频合成完成: %s (SampleRate=%d, AudioSize=%d)\n", ttsAudioURL, sampleRate, len(collectedAudio))
 					return
@@ -1794,13 +1952,11 @@ func (h *Handlers) processAudioAsyncV2(ctx context.Context, credential *models.U
 	ttsProvider := credential.GetTTSProvider()
 	if ttsProvider == "" {
 		fmt.Printf("[V2] TTS provider 未配置\n")
-		audioCacheMutex.Lock()
-		audioProcessingCache[requestID] = AudioProcessResult{
+		setAudioProcessResult(requestID, AudioProcessResult{
 			Status:   "failed",
 			Text:     text,
 			AudioURL: "",
-		}
-		audioCacheMutex.Unlock()
+		})
 		return
 	}
 
@@ -1871,25 +2027,21 @@ func (h *Handlers) processAudioAsyncV2(ctx context.Context, credential *models.U
 	ttsService, err := synthesizer.NewSynthesisServiceFromCredential(ttsConfig)
 	if err != nil {
 		fmt.Printf("[V2] 无法创建TTS服务: %v\n", err)
-		audioCacheMutex.Lock()
-		audioProcessingCache[requestID] = AudioProcessResult{
+		setAudioProcessResult(requestID, AudioProcessResult{
 			Status:   "failed",
 			Text:     text,
 			AudioURL: "",
-		}
-		audioCacheMutex.Unlock()
+		})
 		return
 	}
 
 	if ttsService == nil {
 		fmt.Printf("[V2] 无法创建TTS服务，配置不完整\n")
-		audioCacheMutex.Lock()
-		audioProcessingCache[requestID] = AudioProcessResult{
+		setAudioProcessResult(requestID, AudioProcessResult{
 			Status:   "failed",
 			Text:     text,
 			AudioURL: "",
-		}
-		audioCacheMutex.Unlock()
+		})
 		return
 	}
 
@@ -1909,26 +2061,22 @@ func (h *Handlers) processAudioAsyncV2(ctx context.Context, credential *models.U
 	err = ttsService.Synthesize(ctx, handler, text)
 	if err != nil {
 		fmt.Printf("[V2] TTS合成失败: %v\n", err)
-		audioCacheMutex.Lock()
-		audioProcessingCache[requestID] = AudioProcessResult{
+		setAudioProcessResult(requestID, AudioProcessResult{
 			Status:   "failed",
 			Text:     text,
 			AudioURL: "",
-		}
-		audioCacheMutex.Unlock()
+		})
 		return
 	}
 
 	// 保存音频到存储
 	if len(audioData) == 0 {
 		fmt.Printf("[V2] 音频数据为空\n")
-		audioCacheMutex.Lock()
-		audioProcessingCache[requestID] = AudioProcessResult{
+		setAudioProcessResult(requestID, AudioProcessResult{
 			Status:   "failed",
 			Text:     text,
 			AudioURL: "",
-		}
-		audioCacheMutex.Unlock()
+		})
 		return
 	}
 
@@ -1939,13 +2087,11 @@ func (h *Handlers) processAudioAsyncV2(ctx context.Context, credential *models.U
 	wavData, err := h.createWAVFile(audioData, format.SampleRate, format.Channels, format.BitDepth)
 	if err != nil {
 		fmt.Printf("[V2] 创建WAV文件失败: %v\n", err)
-		audioCacheMutex.Lock()
-		audioProcessingCache[requestID] = AudioProcessResult{
+		setAudioProcessResult(requestID, AudioProcessResult{
 			Status:   "failed",
 			Text:     text,
 			AudioURL: "",
-		}
-		audioCacheMutex.Unlock()
+		})
 		return
 	}
 
@@ -1955,27 +2101,25 @@ func (h *Handlers) processAudioAsyncV2(ctx context.Context, credential *models.U
 	err = store.Write(ttsKey, bytes.NewReader(wavData))
 	if err != nil {
 		fmt.Printf("[V2] 保存音频失败: %v\n", err)
-		audioCacheMutex.Lock()
-		audioProcessingCache[requestID] = AudioProcessResult{
+		setAudioProcessResult(requestID, AudioProcessResult{
 			Status:   "failed",
 			Text:     text,
 			AudioURL: "",
-		}
-		audioCacheMutex.Unlock()
+		})
 		return
 	}
 
-	// 获取音频URL
-	ttsAudioURL := store.PublicURL(ttsKey)
+	h.trackOneshotAudio(credential, userID, ttsKey, int64(len(wavData)))
+
+	// 获取音频URL：调用方凭 API 密钥访问、没有登录会话，签名地址不绑定用户
+	ttsAudioURL := stores.SignedURL(ttsKey, 0, 0)
 
 	// 将音频URL存储到缓存中
-	audioCacheMutex.Lock()
-	audioProcessingCache[requestID] = AudioProcessResult{
+	setAudioProcessResult(requestID, AudioProcessResult{
 		Status:   "completed",
 		Text:     text,
 		AudioURL: ttsAudioURL,
-	}
-	audioCacheMutex.Unlock()
+	})
 
 	fmt.Printf("[V2] 音频合成完成: %s\n", ttsAudioURL)
 	ttsService.Close()
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
+	v2 "github.com/code-100-precent/LingEcho/pkg/llm"
+	"github.com/code-100-precent/LingEcho/pkg/llm/memory"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// 流式文本对话事件类型
+const (
+	textEventDelta = "delta" // 回复的增量文本
+	textEventDone  = "done"  // 回复结束，携带完整回复
+	textEventError = "error" // 回复失败
+)
+
+// textStreamEvent 流式文本对话推送给客户端的事件，SSE 以 Type 为事件名，WebSocket 整体作为一条 JSON 消息
+type textStreamEvent struct {
+	Type      string               `json:"type"`
+	Text      string               `json:"text,omitempty"`      // delta 为增量文本，done 为完整回复
+	RequestID string               `json:"requestId,omitempty"` // 一句话模式下轮询音频的 ID，仅 done 事件携带
+	Citations []knowledge.Citation `json:"citations,omitempty"` // 回复引用的知识库来源，仅 done 事件携带
+	Message   string               `json:"msg,omitempty"`       // error 事件的错误信息
+
+	AudioDegraded bool `json:"audioDegraded,omitempty"` // 凭证语音合成用量已达上限，未合成音频，仅 done 事件携带
+}
+
+// textChatUpgrader 文本对话只传输少量 JSON，使用默认缓冲区
+var textChatUpgrader = websocket.Upgrader{
+	CheckOrigin: checkWSOrigin,
+}
+
+// stream 流式获取回复，每段增量交给 emit。未配置 LLM 时整段输入作为一个增量。
+// 完整回复仍由 LLMListener 根据 options 保存到聊天记录
+func (t *textChat) stream(emit func(delta string) error) (string, error) {
+	if t.provider == nil {
+		return t.text, emit(t.text)
+	}
+	options := t.options
+	options.StreamDeltas = true
+	return t.provider.QueryStream(t.queryText, options, func(segment string, isComplete bool) error {
+		if isComplete || segment == "" {
+			return nil
+		}
+		return emit(segment)
+	})
+}
+
+// startSSE 写出 SSE 响应头，之后只能通过 writeSSE 输出
+func startSSE(c *gin.Context) {
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Accel-Buffering", "no") // 避免反向代理缓冲事件
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+}
+
+// writeSSE 写出一个事件并立即刷新，客户端断开后返回错误
+func writeSSE(c *gin.Context, event textStreamEvent) error {
+	c.SSEvent(event.Type, event)
+	c.Writer.Flush()
+	return c.Request.Context().Err()
+}
+
+// streamSSE 以 SSE 推送 chat 的回复增量，失败时推送 error 事件并返回 false
+func streamSSE(c *gin.Context, chat *textChat) (string, bool) {
+	startSSE(c)
+	reply, err := chat.stream(func(delta string) error {
+		return writeSSE(c, textStreamEvent{Type: textEventDelta, Text: delta})
+	})
+	if err != nil {
+		_, detail := llmFailure(chat.options.Model, err)
+		writeSSE(c, textStreamEvent{Type: textEventError, Message: detail})
+		return "", false
+	}
+	return reply, true
+}
+
+// OneShotTextStream 一句话模式的流式版本：以 SSE 逐段推送 delta 事件，
+// 回复结束后推送带完整回复与音频轮询 requestId 的 done 事件
+func (h *Handlers) OneShotTextStream(c *gin.Context) {
+	var req OneShotTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "参数错误", err.Error())
+		return
+	}
+
+	chat, ok := h.prepareOneShotText(c, &req)
+	if !ok {
+		return
+	}
+	reply, ok := streamSSE(c, chat)
+	if !ok {
+		return
+	}
+	requestId := h.startOneShotAudio(chat, &req, reply)
+	writeSSE(c, textStreamEvent{Type: textEventDone, Text: reply, RequestID: requestId, Citations: chat.citations, AudioDegraded: chat.skipAudio})
+}
+
+// PlainTextStream 纯文本对话的流式版本，以 SSE 逐段推送 delta 事件，最后推送 done 事件
+func (h *Handlers) PlainTextStream(c *gin.Context) {
+	var req OneShotTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "参数错误", err.Error())
+		return
+	}
+
+	chat, ok := h.preparePlainText(c, &req)
+	if !ok {
+		return
+	}
+	reply, ok := streamSSE(c, chat)
+	if !ok {
+		return
+	}
+	writeSSE(c, textStreamEvent{Type: textEventDone, Text: reply, Citations: chat.citations})
+}
+
+// plainTextMessage PlainTextWS 中客户端发送的一轮输入
+type plainTextMessage struct {
+	Text string `json:"text"`
+}
+
+// PlainTextWS 纯文本对话的 WebSocket 版本。助手与凭证通过查询参数 apiKey、apiSecret、
+// assistantId、sessionId 指定；连接建立后客户端每发送一条 {"text": "..."} 即开始一轮对话，
+// 服务端逐段推送 delta 事件并以 done 或 error 事件结束该轮。同一连接内保留对话上下文
+func (h *Handlers) PlainTextWS(c *gin.Context) {
+	assistantID, err := strconv.Atoi(c.Query("assistantId"))
+	if err != nil || assistantID <= 0 {
+		response.Fail(c, "参数错误", "无效的助手ID")
+		return
+	}
+	req := OneShotTextRequest{
+		APIKey:      c.Query("apiKey"),
+		APISecret:   c.Query("apiSecret"),
+		AssistantID: assistantID,
+		SessionID:   c.Query("sessionId"),
+	}
+	chat, ok := h.preparePlainText(c, &req)
+	if !ok {
+		return
+	}
+	if chat.provider != nil {
+		// 同一连接内对话可以很长，较早的轮次压缩为摘要
+		v2.EnableMemory(chat.provider, memory.Default())
+	}
+
+	conn, err := textChatUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("[PlainTextWS] Failed to upgrade connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// 服务关闭时请求 context 结束，关闭连接以结束读取
+	ctx := c.Request.Context()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for {
+		var msg plainTextMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) && ctx.Err() == nil {
+				log.Printf("[PlainTextWS] Read error: %v", err)
+			}
+			return
+		}
+		text := strings.TrimSpace(msg.Text)
+		if text == "" {
+			if err := conn.WriteJSON(textStreamEvent{Type: textEventError, Message: "text is required"}); err != nil {
+				return
+			}
+			continue
+		}
+
+		chat.text = text
+		chat.queryText, chat.citations = h.knowledgeQuery(chat.knowledgeKey, text)
+		reply, err := chat.stream(func(delta string) error {
+			return conn.WriteJSON(textStreamEvent{Type: textEventDelta, Text: delta})
+		})
+		event := textStreamEvent{Type: textEventDone, Text: reply, Citations: chat.citations}
+		if err != nil {
+			_, detail := llmFailure(chat.options.Model, err)
+			event = textStreamEvent{Type: textEventError, Message: detail}
+		}
+		if err := conn.WriteJSON(event); err != nil {
+			return
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"strconv"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
 	"github.com/code-100-precent/LingEcho/pkg/response"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
 	"github.com/code-100-precent/LingEcho/pkg/voiceclone"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -116,7 +119,7 @@ func (h *Handlers) VolcengineSynthesize(c *gin.Context) {
 		}
 	}
 
-	response.Success(c, "语音合成成功", VolcengineTTSResponse{URL: url})
+	response.Success(c, "语音合成成功", VolcengineTTSResponse{URL: stores.SignPublicURL(url, user.ID)})
 }
 
 // VolcengineSubmitAudio 提交音频文件进行训练
@@ -143,6 +146,20 @@ func (h *Handlers) VolcengineSubmitAudio(c *gin.Context) {
 	}
 	defer src.Close()
 
+	audioData, err := io.ReadAll(src)
+	if err != nil {
+		response.Fail(c, "读取音频文件失败", err.Error())
+		return
+	}
+
+	// 校验并预处理录音，统一转为 WAV 提交
+	caps, _ := voiceclone.GetCapabilities(voiceclone.ProviderVolcengine)
+	prepared, err := voiceclone.PrepareTrainingAudio(audioData, file.Filename, caps.PreprocessOptions())
+	if err != nil {
+		response.Fail(c, "音频不符合训练要求", err.Error())
+		return
+	}
+
 	// 提交音频（使用 voiceclone）
 	factory := voiceclone.NewFactory()
 	service, err := factory.CreateServiceFromEnv(voiceclone.ProviderVolcengine)
@@ -155,7 +172,7 @@ func (h *Handlers) VolcengineSubmitAudio(c *gin.Context) {
 		TaskID:    req.SpeakerID, // 使用 speaker_id 作为 TaskID
 		TextID:    0,             // 火山引擎不需要
 		TextSegID: 0,             // 火山引擎不需要
-		AudioFile: src,
+		AudioFile: bytes.NewReader(prepared.WAV),
 		Language:  req.Language,
 	}
 	err = service.SubmitAudio(c.Request.Context(), submitReq)
@@ -170,6 +187,7 @@ func (h *Handlers) VolcengineSubmitAudio(c *gin.Context) {
 	response.Success(c, "提交音频成功", map[string]interface{}{
 		"speakerId": req.SpeakerID,
 		"message":   "音频已提交，请使用 speaker_id 查询训练状态",
+		"audio":     prepared,
 	})
 }
 
@@ -221,6 +239,7 @@ func (h *Handlers) VolcengineQueryTask(c *gin.Context) {
 		// 火山引擎没有 VoiceTrainingTask，需要先创建或查找一个虚拟任务
 		// 查找或创建虚拟训练任务（使用 speaker_id 作为 task_id）
 		var task models.VoiceTrainingTask
+		completed := true // 本次查询是否首次发现训练成功
 		if err := h.db.Where("user_id = ? AND task_id = ?", user.ID, req.SpeakerID).First(&task).Error; err != nil {
 			// 不存在，创建虚拟任务
 			task = models.VoiceTrainingTask{
@@ -237,6 +256,7 @@ func (h *Handlers) VolcengineQueryTask(c *gin.Context) {
 			}
 		} else {
 			// 已存在，更新状态
+			completed = task.Status != models.TrainingStatusSuccess
 			task.Status = models.TrainingStatusSuccess
 			task.AssetID = status.AssetID
 			task.TrainVID = status.TrainVID
@@ -251,6 +271,9 @@ func (h *Handlers) VolcengineQueryTask(c *gin.Context) {
 			response.Fail(c, "创建音色记录失败", err.Error())
 			return
 		}
+		if completed {
+			notifyTrainingCompleted(h.db, user, &task)
+		}
 	}
 
 	response.Success(c, "查询任务状态成功", VolcengineQueryTaskResponse{
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/constants"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 通行密钥注册/登录过程中保存在会话中的挑战数据
+const (
+	webauthnRegistrationSessionKey = "webauthn_registration"
+	webauthnLoginSessionKey        = "webauthn_login"
+	webauthnCeremonyTimeout        = 5 * time.Minute
+)
+
+// WebAuthnLoginBeginRequest 开始通行密钥登录，提供邮箱时只允许该用户的密钥，否则使用可发现凭证登录
+type WebAuthnLoginBeginRequest struct {
+	Email string `json:"email"`
+}
+
+// newWebAuthn 按配置创建 WebAuthn 依赖方，未配置时从 SERVER_URL 推导域名与来源
+func newWebAuthn() (*webauthn.WebAuthn, error) {
+	rpID := config.GlobalConfig.WebAuthnRPID
+	var origins []string
+	for _, origin := range strings.Split(config.GlobalConfig.WebAuthnRPOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if serverURL, err := url.Parse(config.GlobalConfig.ServerUrl); err == nil && serverURL.Host != "" {
+		if rpID == "" {
+			rpID = serverURL.Hostname()
+		}
+		if len(origins) == 0 {
+			origins = []string{serverURL.Scheme + "://" + serverURL.Host}
+		}
+	}
+	if rpID == "" || len(origins) == 0 {
+		return nil, errors.New("webauthn is not configured, set WEBAUTHN_RP_ID and WEBAUTHN_RP_ORIGINS or SERVER_URL")
+	}
+
+	displayName := config.GlobalConfig.ServerName
+	if displayName == "" {
+		displayName = "LingEcho"
+	}
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: webauthnCeremonyTimeout, TimeoutUVD: webauthnCeremonyTimeout}
+	return webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: displayName,
+		RPOrigins:     origins,
+		Timeouts:      webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+}
+
+// saveWebAuthnSession 将挑战数据保存到会话，完成时取回校验
+func saveWebAuthnSession(c *gin.Context, key string, data *webauthn.SessionData) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	session := sessions.Default(c)
+	session.Set(key, string(raw))
+	return session.Save()
+}
+
+// takeWebAuthnSession 取出并删除会话中的挑战数据，每个挑战只能使用一次
+func takeWebAuthnSession(c *gin.Context, key string) (*webauthn.SessionData, error) {
+	session := sessions.Default(c)
+	raw, ok := session.Get(key).(string)
+	if !ok || raw == "" {
+		return nil, errors.New("no webauthn ceremony in progress")
+	}
+	session.Delete(key)
+	if err := session.Save(); err != nil {
+		return nil, err
+	}
+	var data webauthn.SessionData
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// handleWebAuthnRegisterBegin 开始注册通行密钥
+func (h *Handlers) handleWebAuthnRegisterBegin(c *gin.Context) {
+	user := models.CurrentUser(c)
+	wa, err := newWebAuthn()
+	if err != nil {
+		response.Fail(c, "passkey login is not available", err)
+		return
+	}
+	webauthnUser, err := models.LoadWebAuthnUser(h.db, user)
+	if err != nil {
+		response.Fail(c, "failed to load passkeys", err)
+		return
+	}
+
+	creation, sessionData, err := wa.BeginRegistration(webauthnUser,
+		webauthn.WithExclusions(webauthn.Credentials(webauthnUser.Credentials).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		response.Fail(c, "failed to begin passkey registration", err)
+		return
+	}
+	if err := saveWebAuthnSession(c, webauthnRegistrationSessionKey, sessionData); err != nil {
+		response.Fail(c, "failed to begin passkey registration", err)
+		return
+	}
+	response.Success(c, "success", creation)
+}
+
+// handleWebAuthnRegisterFinish 完成注册，请求体为浏览器 navigator.credentials.create 的结果，密钥名称通过 name 参数指定
+func (h *Handlers) handleWebAuthnRegisterFinish(c *gin.Context) {
+	user := models.CurrentUser(c)
+	wa, err := newWebAuthn()
+	if err != nil {
+		response.Fail(c, "passkey login is not available", err)
+		return
+	}
+	sessionData, err := takeWebAuthnSession(c, webauthnRegistrationSessionKey)
+	if err != nil {
+		response.Fail(c, "passkey registration failed", err)
+		return
+	}
+	webauthnUser, err := models.LoadWebAuthnUser(h.db, user)
+	if err != nil {
+		response.Fail(c, "failed to load passkeys", err)
+		return
+	}
+
+	credential, err := wa.FinishRegistration(webauthnUser, *sessionData, c.Request)
+	if err != nil {
+		logger.Warn("Passkey registration failed", zap.Uint("userID", user.ID), zap.Error(err))
+		response.Fail(c, "passkey registration failed", err)
+		return
+	}
+
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" {
+		deviceType, os, browser := utils.ParseUserAgent(c.Request.UserAgent())
+		name = fmt.Sprintf("%s on %s (%s)", browser, os, deviceType)
+	}
+	record, err := models.CreateWebAuthnCredential(h.db, user.ID, name, credential)
+	if err != nil {
+		response.Fail(c, "failed to save passkey", err)
+		return
+	}
+	logger.Info("Passkey registered", zap.Uint("userID", user.ID), zap.Uint("credentialID", record.ID))
+	response.Success(c, "passkey registered", record)
+}
+
+// handleWebAuthnLoginBegin 开始通行密钥登录
+func (h *Handlers) handleWebAuthnLoginBegin(c *gin.Context) {
+	var req WebAuthnLoginBeginRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Fail(c, "invalid request", err)
+			return
+		}
+	}
+
+	clientIP := c.ClientIP()
+	if utils.GlobalLoginSecurityManager != nil {
+		if err := utils.GlobalLoginSecurityManager.CheckIPRateLimit(clientIP); err != nil {
+			response.AbortWithStatusJSON(c, http.StatusTooManyRequests, err)
+			return
+		}
+	}
+
+	wa, err := newWebAuthn()
+	if err != nil {
+		response.Fail(c, "passkey login is not available", err)
+		return
+	}
+
+	var (
+		assertion   *protocol.CredentialAssertion
+		sessionData *webauthn.SessionData
+	)
+	if req.Email != "" {
+		// 用户不存在或未注册通行密钥时使用替身用户，响应与正常用户一致，避免通过该接口探测账号
+		var webauthnUser *models.WebAuthnUser
+		if user, err := models.GetUserByEmail(h.db, req.Email); err == nil {
+			webauthnUser, err = models.LoadWebAuthnUser(h.db, user)
+			if err != nil {
+				response.Fail(c, "failed to load passkeys", err)
+				return
+			}
+		}
+		if webauthnUser == nil || len(webauthnUser.Credentials) == 0 {
+			webauthnUser = models.NewDecoyWebAuthnUser(req.Email, config.GlobalConfig.SessionSecret)
+		}
+		assertion, sessionData, err = wa.BeginLogin(webauthnUser)
+		if err != nil {
+			response.Fail(c, "failed to begin passkey login", err)
+			return
+		}
+	} else {
+		assertion, sessionData, err = wa.BeginDiscoverableLogin()
+		if err != nil {
+			response.Fail(c, "failed to begin passkey login", err)
+			return
+		}
+	}
+
+	if err := saveWebAuthnSession(c, webauthnLoginSessionKey, sessionData); err != nil {
+		response.Fail(c, "failed to begin passkey login", err)
+		return
+	}
+	response.Success(c, "success", assertion)
+}
+
+// handleWebAuthnLoginFinish 完成通行密钥登录，请求体为浏览器 navigator.credentials.get 的结果。
+// 通行密钥本身即为多因素凭证，登录时不再要求两步验证码
+func (h *Handlers) handleWebAuthnLoginFinish(c *gin.Context) {
+	clientIP := c.ClientIP()
+	db := c.MustGet(constants.DbField).(*gorm.DB)
+
+	// 1. IP限流检查
+	if utils.GlobalLoginSecurityManager != nil {
+		if err := utils.GlobalLoginSecurityManager.CheckIPRateLimit(clientIP); err != nil {
+			response.AbortWithStatusJSON(c, http.StatusTooManyRequests, err)
+			return
+		}
+	}
+
+	wa, err := newWebAuthn()
+	if err != nil {
+		response.Fail(c, "passkey login is not available", err)
+		return
+	}
+	sessionData, err := takeWebAuthnSession(c, webauthnLoginSessionKey)
+	if err != nil {
+		response.Fail(c, "passkey login failed", err)
+		return
+	}
+
+	// 2. 校验断言：指定了用户时校验该用户的密钥，否则按用户句柄找回用户
+	var user *models.User
+	loadUser := func(userHandle []byte) (*models.WebAuthnUser, error) {
+		userID, err := models.ParseWebAuthnUserHandle(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		found, err := models.GetUserByUID(db, userID)
+		if err != nil {
+			return nil, err
+		}
+		user = found
+		return models.LoadWebAuthnUser(db, found)
+	}
+	var credential *webauthn.Credential
+	if len(sessionData.UserID) > 0 {
+		webauthnUser, loadErr := loadUser(sessionData.UserID)
+		if loadErr != nil {
+			response.Fail(c, "passkey login failed", loadErr)
+			return
+		}
+		credential, err = wa.FinishLogin(webauthnUser, *sessionData, c.Request)
+	} else {
+		credential, err = wa.FinishDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			return loadUser(userHandle)
+		}, *sessionData, c.Request)
+	}
+	if err == nil && credential.Authenticator.CloneWarning {
+		err = errors.New("authenticator sign count went backwards, the passkey may be cloned")
+	}
+	if err != nil {
+		logger.Warn("Passkey login failed", zap.String("ip", clientIP), zap.Error(err))
+		if user != nil && utils.GlobalLoginSecurityManager != nil {
+			recordFunc := func(db *gorm.DB, email string, userID uint, ipAddress string, failedCount int) error {
+				_, err := models.CreateOrUpdateAccountLock(db, email, userID, ipAddress, failedCount)
+				return err
+			}
+			utils.GlobalLoginSecurityManager.RecordFailedLogin(db, user.Email, user.ID, clientIP, recordFunc)
+		}
+		response.Fail(c, "passkey login failed", err)
+		return
+	}
+
+	// 3. 账号锁定检查
+	if utils.GlobalLoginSecurityManager != nil {
+		checkLockFunc := func(db *gorm.DB, email string, userID uint) (*utils.AccountLockInfo, error) {
+			lock, err := models.GetAccountLock(db, email, userID)
+			if err != nil || lock == nil {
+				return nil, err
+			}
+			return &utils.AccountLockInfo{
+				IsLocked: lock.IsLocked(),
+				UnlockAt: lock.UnlockAt,
+			}, nil
+		}
+		if err := utils.GlobalLoginSecurityManager.CheckAccountLock(db, user.Email, user.ID, checkLockFunc); err != nil {
+			response.AbortWithStatusJSON(c, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	// 4. 检查用户是否允许登录（激活、启用等）
+	if err := models.CheckUserAllowLogin(db, user); err != nil {
+		response.Fail(c, "login failed", err)
+		return
+	}
+
+	// 5. 保存新的签名计数
+	if err := models.UpdateWebAuthnCredentialUsage(db, user.ID, credential); err != nil {
+		logger.Warn("Failed to update passkey usage", zap.Uint("userID", user.ID), zap.Error(err))
+	}
+
+	if timezone := c.Query("timezone"); timezone != "" {
+		models.InTimezone(c, timezone)
+	}
+
+	// 6. 记录设备与登录历史并设置登录会话
+	responseData := h.completeLogin(c, db, user, "webauthn")
+	if responseData == nil {
+		return
+	}
+
+	logger.Info("Passkey login successful", zap.Uint("userID", user.ID), zap.String("ip", clientIP))
+	response.Success(c, "login successful", responseData)
+}
+
+// handleListWebAuthnCredentials 获取当前用户的通行密钥
+func (h *Handlers) handleListWebAuthnCredentials(c *gin.Context) {
+	user := models.CurrentUser(c)
+	records, err := models.GetWebAuthnCredentials(h.db, user.ID)
+	if err != nil {
+		response.Fail(c, "failed to load passkeys", err)
+		return
+	}
+	response.Success(c, "success", records)
+}
+
+// handleDeleteWebAuthnCredential 删除通行密钥
+func (h *Handlers) handleDeleteWebAuthnCredential(c *gin.Context) {
+	user := models.CurrentUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Fail(c, "invalid passkey id", err)
+		return
+	}
+	if err := models.DeleteWebAuthnCredential(h.db, user.ID, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, "passkey not found", nil)
+			return
+		}
+		response.Fail(c, "failed to delete passkey", err)
+		return
+	}
+	response.Success(c, "passkey deleted", nil)
+}
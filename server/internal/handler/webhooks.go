@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/internal/task"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// webhookRequest Webhook 创建/更新请求
+type webhookRequest struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Events  []string `json:"events"` // 为空表示订阅全部事件
+	Enabled *bool    `json:"enabled"`
+}
+
+// handleListWebhookEvents 获取可订阅的平台事件
+func (h *Handlers) handleListWebhookEvents(c *gin.Context) {
+	response.Success(c, "success", models.WebhookEvents())
+}
+
+// handleListWebhooks 获取用户的 Webhook
+func (h *Handlers) handleListWebhooks(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	var webhooks []models.Webhook
+	if err := h.db.Where("user_id = ?", user.ID).Order("id DESC").Find(&webhooks).Error; err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, "success", webhooks)
+}
+
+// handleCreateWebhook 新增 Webhook，签名密钥只在此处返回一次
+func (h *Handlers) handleCreateWebhook(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return
+	}
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request format", err)
+		return
+	}
+
+	webhook := models.Webhook{UserID: user.ID, Secret: models.NewWebhookSecret(), Enabled: true}
+	if err := applyWebhookRequest(c.Request.Context(), &webhook, &req); err != nil {
+		response.Fail(c, err.Error(), nil)
+		return
+	}
+	if err := h.db.Create(&webhook).Error; err != nil {
+		response.Fail(c, "Failed to create webhook", err.Error())
+		return
+	}
+	h.recordAudit(c, models.AuditActionWebhookCreate, "webhook", webhook.ID, nil, &webhook)
+	response.Success(c, "Webhook created", gin.H{
+		"webhook": webhook,
+		"secret":  webhook.Secret,
+	})
+}
+
+// handleUpdateWebhook 更新 Webhook，不修改签名密钥
+func (h *Handlers) handleUpdateWebhook(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, "Invalid request format", err)
+		return
+	}
+	before := auditSnapshot(webhook)
+	if err := applyWebhookRequest(c.Request.Context(), webhook, &req); err != nil {
+		response.Fail(c, err.Error(), nil)
+		return
+	}
+	if err := h.db.Save(webhook).Error; err != nil {
+		response.Fail(c, "Failed to update webhook", err.Error())
+		return
+	}
+	h.recordAudit(c, models.AuditActionWebhookUpdate, "webhook", webhook.ID, before, webhook)
+	response.Success(c, "Webhook updated", webhook)
+}
+
+// handleRotateWebhookSecret 重新生成签名密钥，旧密钥立即失效
+func (h *Handlers) handleRotateWebhookSecret(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	secret := models.NewWebhookSecret()
+	if err := h.db.Model(webhook).Update("secret", secret).Error; err != nil {
+		response.Fail(c, "Failed to rotate webhook secret", err.Error())
+		return
+	}
+	h.recordAudit(c, models.AuditActionWebhookSecretRotate, "webhook", webhook.ID, nil, nil)
+	response.Success(c, "Webhook secret rotated", gin.H{"secret": secret})
+}
+
+// handleDeleteWebhook 删除 Webhook 及其投递记录
+func (h *Handlers) handleDeleteWebhook(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	if err := models.DeleteWebhook(h.db, webhook); err != nil {
+		response.Fail(c, "Failed to delete webhook", err.Error())
+		return
+	}
+	h.recordAudit(c, models.AuditActionWebhookDelete, "webhook", webhook.ID, webhook, nil)
+	response.Success(c, "Webhook deleted", nil)
+}
+
+// handleTestWebhook 向 Webhook 发送一条测试事件并返回投递结果
+func (h *Handlers) handleTestWebhook(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	delivery, err := models.CreateWebhookPing(h.db, webhook)
+	if err != nil {
+		response.Fail(c, "Failed to create test delivery", err.Error())
+		return
+	}
+	if err := task.DeliverWebhook(c.Request.Context(), h.db, delivery, false); err != nil {
+		response.Fail(c, "Failed to send test event", delivery)
+		return
+	}
+	response.Success(c, "Test event sent", delivery)
+}
+
+// handleListWebhookDeliveries 分页获取 Webhook 的投递日志，可按状态过滤
+func (h *Handlers) handleListWebhookDeliveries(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	deliveries, total, err := models.GetWebhookDeliveries(h.db, webhook.ID, c.Query("status"), page, pageSize)
+	if err != nil {
+		response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, "success", gin.H{
+		"list":     deliveries,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// handleRedeliverWebhook 立即重新投递一条已结束的投递记录，只尝试一次
+func (h *Handlers) handleRedeliverWebhook(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseUint(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid delivery ID", nil)
+		return
+	}
+	var delivery models.WebhookDelivery
+	if err := h.db.Where("id = ? AND webhook_id = ?", deliveryID, webhook.ID).First(&delivery).Error; err != nil {
+		response.Fail(c, "Delivery not found", nil)
+		return
+	}
+	// 等待重试的投递由后台任务发送，避免重复投递
+	if delivery.Status == models.WebhookDeliveryPending && delivery.NextAttemptAt != nil {
+		response.Fail(c, "Delivery is still queued for retry", nil)
+		return
+	}
+	if err := task.DeliverWebhook(c.Request.Context(), h.db, &delivery, false); err != nil {
+		response.Fail(c, "Failed to redeliver webhook", delivery)
+		return
+	}
+	response.Success(c, "Webhook redelivered", delivery)
+}
+
+// findWebhook 获取当前用户的 Webhook，失败时直接写入响应
+func (h *Handlers) findWebhook(c *gin.Context) (*models.Webhook, bool) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "User is not logged in.", nil)
+		return nil, false
+	}
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, "Invalid webhook ID", nil)
+		return nil, false
+	}
+	webhook, err := models.GetUserWebhook(h.db, user.ID, uint(webhookID))
+	if err != nil {
+		if errors.Is(err, models.ErrWebhookNotFound) {
+			response.Fail(c, "Webhook not found", nil)
+		} else {
+			response.AbortWithStatusJSON(c, http.StatusInternalServerError, err)
+		}
+		return nil, false
+	}
+	return webhook, true
+}
+
+// applyWebhookRequest 将请求字段应用到 Webhook 配置，URL 不能指向回环、内网或云元数据等地址
+func applyWebhookRequest(ctx context.Context, webhook *models.Webhook, req *webhookRequest) error {
+	if req.Name != "" {
+		webhook.Name = req.Name
+	}
+	if req.URL != "" {
+		webhook.URL = req.URL
+	}
+	if err := task.ValidateWebhookURL(ctx, webhook.URL); err != nil {
+		return fmt.Errorf("invalid webhook url: %w", err)
+	}
+	if req.Events != nil {
+		events, err := models.ParseWebhookEvents(req.Events)
+		if err != nil {
+			return err
+		}
+		webhook.Events = events
+	}
+	if req.Enabled != nil {
+		webhook.Enabled = *req.Enabled
+	}
+	return nil
+}
@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
-	"github.com/code-100-precent/LingEcho/pkg/config"
 	"github.com/code-100-precent/LingEcho/pkg/graph"
 	"github.com/code-100-precent/LingEcho/pkg/hardware"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
@@ -22,13 +21,16 @@ import (
 var voiceUpgrader = websocket.Upgrader{
 	ReadBufferSize:  1024 * 1024, // 1MB读缓冲区，支持大音频数据
 	WriteBufferSize: 1024 * 1024, // 1MB写缓冲区，支持大音频数据
-	CheckOrigin: func(r *http.Request) bool {
-		return true // 允许所有来源
-	},
+	CheckOrigin:     checkWSOrigin,
 }
 
 // HandleWebSocketVoice 处理通用WebSocket语音连接
 func (h *Handlers) HandleWebSocketVoice(c *gin.Context) {
+	if !h.beginRealtimeSession(c) {
+		return
+	}
+	defer h.realtime.end()
+
 	// 获取参数
 	apiKey := c.Query("apiKey")
 	apiSecret := c.Query("apiSecret")
@@ -42,8 +44,8 @@ func (h *Handlers) HandleWebSocketVoice(c *gin.Context) {
 		speaker = "101016"
 	}
 
-	// 验证参数
-	if apiKey == "" || apiSecret == "" {
+	// 验证参数，使用API令牌时无需apiKey/apiSecret
+	if models.CurrentAPIToken(c) == nil && (apiKey == "" || apiSecret == "") {
 		response.Fail(c, "缺少apiKey或apiSecret参数", nil)
 		return
 	}
@@ -55,7 +57,7 @@ func (h *Handlers) HandleWebSocketVoice(c *gin.Context) {
 	}
 
 	// 验证凭证
-	cred, err := models.GetUserCredentialByApiSecretAndApiKey(h.db, apiKey, apiSecret)
+	cred, err := h.requestCredential(c, apiKey, apiSecret)
 	if err != nil {
 		response.Fail(c, "Database error: "+err.Error(), nil)
 		return
@@ -64,6 +66,12 @@ func (h *Handlers) HandleWebSocketVoice(c *gin.Context) {
 		response.Fail(c, "Invalid credentials", nil)
 		return
 	}
+	if h.rejectOverPlanQuota(c, cred.UserID) {
+		return
+	}
+	if h.rejectOverCredentialQuota(c, cred, voiceCallMeters...) {
+		return
+	}
 
 	// 获取助手配置
 	var assistant models.Assistant
@@ -94,8 +102,8 @@ func (h *Handlers) HandleWebSocketVoice(c *gin.Context) {
 		speaker = assistant.Speaker
 	}
 
-	// 如果开启了图记忆功能，则尝试从 Neo4j 中获取该用户的长期偏好主题，并拼接到系统提示词中
-	if config.GlobalConfig.Neo4jEnabled && assistant.EnableGraphMemory {
+	// 如果开启了图记忆功能，则尝试从图存储中获取该用户的长期偏好主题，并拼接到系统提示词中
+	if assistant.EnableGraphMemory {
 		if store := graph.GetDefaultStore(); store != nil {
 			// 通过凭证反查用户
 			var user models.User
@@ -145,6 +153,11 @@ func (h *Handlers) HandleWebSocketVoice(c *gin.Context) {
 // HandleHardwareWebSocketVoice 处理硬件WebSocket语音连接（与xiaozhi-esp32兼容）
 // 从Header中获取Device-Id（MAC地址），查询设备绑定的助手，动态获取配置
 func (h *Handlers) HandleHardwareWebSocketVoice(c *gin.Context) {
+	if !h.beginRealtimeSession(c) {
+		return
+	}
+	defer h.realtime.end()
+
 	// 从Header获取Device-Id（MAC地址），与xiaozhi-esp32兼容
 	deviceID := c.GetHeader("Device-Id")
 	if deviceID == "" {
@@ -249,6 +262,12 @@ func (h *Handlers) HandleHardwareWebSocketVoice(c *gin.Context) {
 		c.Abort()
 		return
 	}
+	if h.rejectOverPlanQuota(c, cred.UserID) {
+		return
+	}
+	if h.rejectOverCredentialQuota(c, cred, voiceCallMeters...) {
+		return
+	}
 
 	// 升级为WebSocket连接
 	logger.Info("准备升级WebSocket连接",
@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	workflowdef "github.com/code-100-precent/LingEcho/internal/workflow"
+	"github.com/code-100-precent/LingEcho/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// userWorkflowInstances scopes instance queries to definitions owned by the user
+func (h *Handlers) userWorkflowInstances(userID uint) *gorm.DB {
+	return h.db.Model(&models.WorkflowInstance{}).
+		Where("definition_id IN (?)", h.db.Model(&models.WorkflowDefinition{}).Select("id").Where("user_id = ?", userID))
+}
+
+// ListWorkflowInstances lists workflow executions, e.g. ?status=dead_letter to inspect the dead-letter queue
+func (h *Handlers) ListWorkflowInstances(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "unauthorized", "User not logged in")
+		return
+	}
+
+	query := h.userWorkflowInstances(user.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if definitionID := c.Query("definitionId"); definitionID != "" {
+		query = query.Where("definition_id = ?", definitionID)
+	}
+	if triggerSource := c.Query("triggerSource"); triggerSource != "" {
+		query = query.Where("trigger_source = ?", triggerSource)
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var list []models.WorkflowInstance
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order("updated_at DESC").Find(&list).Error; err != nil {
+		response.Fail(c, "failed to list workflow instances", err.Error())
+		return
+	}
+
+	response.Success(c, "ok", gin.H{
+		"list":     list,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// GetWorkflowInstance returns a single execution with its error details
+func (h *Handlers) GetWorkflowInstance(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "unauthorized", "User not logged in")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Fail(c, "invalid id", nil)
+		return
+	}
+
+	var instance models.WorkflowInstance
+	if err := h.userWorkflowInstances(user.ID).First(&instance, id).Error; err != nil {
+		response.Fail(c, "workflow instance not found", err.Error())
+		return
+	}
+
+	response.Success(c, "ok", instance)
+}
+
+// RetryWorkflowInstance re-runs a failed or dead-letter execution with its original parameters
+func (h *Handlers) RetryWorkflowInstance(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "unauthorized", "User not logged in")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Fail(c, "invalid id", nil)
+		return
+	}
+
+	var instance models.WorkflowInstance
+	if err := h.userWorkflowInstances(user.ID).First(&instance, id).Error; err != nil {
+		response.Fail(c, "workflow instance not found", err.Error())
+		return
+	}
+
+	manager := workflowdef.NewWorkflowTriggerManager(h.db)
+	retried, err := manager.RetryWorkflowInstance(instance.ID)
+	if retried == nil {
+		response.Fail(c, "failed to retry workflow instance", err.Error())
+		return
+	}
+	if err != nil {
+		response.Fail(c, "workflow retry failed", gin.H{
+			"instance": retried,
+			"error":    err.Error(),
+		})
+		return
+	}
+
+	response.Success(c, "workflow retried successfully", retried)
+}
+
+// ResumeWorkflowInstance re-runs a failed or dead-letter execution from the failing node
+// (or the node given as nodeId) with the context it had when it failed
+func (h *Handlers) ResumeWorkflowInstance(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "unauthorized", "User not logged in")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Fail(c, "invalid id", nil)
+		return
+	}
+
+	var input struct {
+		NodeID string `json:"nodeId"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && err.Error() != "EOF" {
+		response.Fail(c, "invalid payload", err.Error())
+		return
+	}
+
+	var instance models.WorkflowInstance
+	if err := h.userWorkflowInstances(user.ID).First(&instance, id).Error; err != nil {
+		response.Fail(c, "workflow instance not found", err.Error())
+		return
+	}
+
+	manager := workflowdef.NewWorkflowTriggerManager(h.db)
+	resumed, err := manager.ResumeWorkflowInstanceFrom(instance.ID, input.NodeID)
+	if resumed == nil {
+		response.Fail(c, "failed to resume workflow instance", err.Error())
+		return
+	}
+	if err != nil {
+		response.Fail(c, "workflow retry failed", gin.H{
+			"instance": resumed,
+			"error":    err.Error(),
+		})
+		return
+	}
+
+	response.Success(c, "workflow resumed successfully", resumed)
+}
+
+// ListWorkflowInstanceSteps lists the node executions of an instance in run order without their payloads,
+// e.g. ?attempt=2 to only show the second run
+func (h *Handlers) ListWorkflowInstanceSteps(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "unauthorized", "User not logged in")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Fail(c, "invalid id", nil)
+		return
+	}
+
+	var instance models.WorkflowInstance
+	if err := h.userWorkflowInstances(user.ID).First(&instance, id).Error; err != nil {
+		response.Fail(c, "workflow instance not found", err.Error())
+		return
+	}
+
+	query := h.db.Model(&models.WorkflowStep{}).
+		Omit("inputs", "outputs").
+		Where("instance_id = ?", instance.ID)
+	if attempt := c.Query("attempt"); attempt != "" {
+		query = query.Where("attempt = ?", attempt)
+	}
+
+	var steps []models.WorkflowStep
+	if err := query.Order("id ASC").Find(&steps).Error; err != nil {
+		response.Fail(c, "failed to list workflow steps", err.Error())
+		return
+	}
+
+	response.Success(c, "ok", steps)
+}
+
+// GetWorkflowInstanceStep returns a single node execution with its inputs and outputs
+func (h *Handlers) GetWorkflowInstanceStep(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "unauthorized", "User not logged in")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Fail(c, "invalid id", nil)
+		return
+	}
+	stepID, err := strconv.Atoi(c.Param("stepId"))
+	if err != nil {
+		response.Fail(c, "invalid step id", nil)
+		return
+	}
+
+	var instance models.WorkflowInstance
+	if err := h.userWorkflowInstances(user.ID).First(&instance, id).Error; err != nil {
+		response.Fail(c, "workflow instance not found", err.Error())
+		return
+	}
+
+	var step models.WorkflowStep
+	if err := h.db.Where("instance_id = ?", instance.ID).First(&step, stepID).Error; err != nil {
+		response.Fail(c, "workflow step not found", err.Error())
+		return
+	}
+
+	response.Success(c, "ok", step)
+}
+
+// ListWorkflowApprovals lists approvals the current user can decide on
+func (h *Handlers) ListWorkflowApprovals(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "unauthorized", "User not logged in")
+		return
+	}
+
+	status := c.DefaultQuery("status", models.WorkflowApprovalPending)
+	query := h.db.Model(&models.WorkflowApproval{})
+	if status != "all" {
+		query = query.Where("status = ?", status)
+	}
+	// Approvers are stored as a JSON array, narrow down with LIKE then check precisely
+	idPattern := "%\"" + strconv.FormatUint(uint64(user.ID), 10) + "\"%"
+	if user.Email != "" {
+		query = query.Where("user_id = ? OR approvers LIKE ? OR approvers LIKE ?", user.ID, idPattern, "%\""+user.Email+"\"%")
+	} else {
+		query = query.Where("user_id = ? OR approvers LIKE ?", user.ID, idPattern)
+	}
+
+	var candidates []models.WorkflowApproval
+	if err := query.Order("created_at DESC").Limit(200).Find(&candidates).Error; err != nil {
+		response.Fail(c, "failed to list approvals", err.Error())
+		return
+	}
+	list := make([]models.WorkflowApproval, 0, len(candidates))
+	for _, approval := range candidates {
+		if approval.CanDecide(user) {
+			list = append(list, approval)
+		}
+	}
+
+	response.Success(c, "ok", list)
+}
+
+// GetWorkflowApproval returns a single approval request
+func (h *Handlers) GetWorkflowApproval(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "unauthorized", "User not logged in")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Fail(c, "invalid id", nil)
+		return
+	}
+
+	var approval models.WorkflowApproval
+	if err := h.db.First(&approval, id).Error; err != nil || !approval.CanDecide(user) {
+		response.Fail(c, "approval not found", nil)
+		return
+	}
+
+	response.Success(c, "ok", approval)
+}
+
+// workflowApprovalInput is the body of an approve/reject request
+type workflowApprovalInput struct {
+	Action  string `json:"action"` // approve or reject, only used by ActOnWorkflowApproval
+	Comment string `json:"comment"`
+}
+
+// ApproveWorkflowApproval approves a pending request and resumes the workflow
+func (h *Handlers) ApproveWorkflowApproval(c *gin.Context) {
+	var input workflowApprovalInput
+	if err := c.ShouldBindJSON(&input); err != nil && err.Error() != "EOF" {
+		response.Fail(c, "invalid payload", err.Error())
+		return
+	}
+	h.decideWorkflowApproval(c, true, input.Comment)
+}
+
+// RejectWorkflowApproval rejects a pending request and resumes the workflow on its reject branch
+func (h *Handlers) RejectWorkflowApproval(c *gin.Context) {
+	var input workflowApprovalInput
+	if err := c.ShouldBindJSON(&input); err != nil && err.Error() != "EOF" {
+		response.Fail(c, "invalid payload", err.Error())
+		return
+	}
+	h.decideWorkflowApproval(c, false, input.Comment)
+}
+
+// ActOnWorkflowApproval approves or rejects a pending request according to the action field
+func (h *Handlers) ActOnWorkflowApproval(c *gin.Context) {
+	var input workflowApprovalInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.Fail(c, "invalid payload", err.Error())
+		return
+	}
+	switch input.Action {
+	case "approve":
+		h.decideWorkflowApproval(c, true, input.Comment)
+	case "reject":
+		h.decideWorkflowApproval(c, false, input.Comment)
+	default:
+		response.Fail(c, "invalid action", "action must be approve or reject")
+	}
+}
+
+func (h *Handlers) decideWorkflowApproval(c *gin.Context, approved bool, comment string) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "unauthorized", "User not logged in")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Fail(c, "invalid id", nil)
+		return
+	}
+
+	instance, err := workflowdef.ResolveApproval(h.db, uint(id), user, approved, comment)
+	if instance == nil {
+		response.Fail(c, "failed to resolve approval", err.Error())
+		return
+	}
+	if err != nil {
+		response.Fail(c, "workflow execution failed", gin.H{
+			"instance": instance,
+			"error":    err.Error(),
+		})
+		return
+	}
+
+	response.Success(c, "ok", instance)
+}
@@ -43,8 +43,13 @@ func (h *Handlers) ExecutePublicWorkflow(c *gin.Context) {
 		return
 	}
 
-	// 解析触发器配置
-	config, err := workflowdef.ParseTriggerConfig(&def)
+	// 解析已发布版本的触发器配置
+	published, err := workflowdef.ResolvePublishedDefinition(h.db, &def)
+	if err != nil {
+		response.Fail(c, "failed to load published workflow", err.Error())
+		return
+	}
+	config, err := workflowdef.ParseTriggerConfig(published)
 	if err != nil {
 		response.Fail(c, "invalid trigger config", err.Error())
 		return
@@ -108,8 +113,13 @@ func (h *Handlers) WebhookTriggerWorkflow(c *gin.Context) {
 		return
 	}
 
-	// 解析触发器配置
-	config, err := workflowdef.ParseTriggerConfig(&def)
+	// 解析已发布版本的触发器配置
+	published, err := workflowdef.ResolvePublishedDefinition(h.db, &def)
+	if err != nil {
+		response.Fail(c, "failed to load published workflow", err.Error())
+		return
+	}
+	config, err := workflowdef.ParseTriggerConfig(published)
 	if err != nil {
 		response.Fail(c, "invalid trigger config", err.Error())
 		return
@@ -67,6 +67,32 @@ func (h *Handlers) registerWorkflowRoutes(r *gin.RouterGroup) {
 		defs.GET("/:id/versions/:versionId", h.GetWorkflowVersion)
 		defs.POST("/:id/versions/:versionId/rollback", h.RollbackWorkflowVersion)
 		defs.GET("/:id/versions/compare", h.CompareWorkflowVersions)
+		defs.POST("/:id/publish", models.PermissionRequired(models.PermissionWorkflowPublish), h.PublishWorkflowDefinition)
+
+		// Execution history and dead-letter handling
+		instances := workflows.Group("/instances")
+		instances.GET("", h.ListWorkflowInstances)
+		instances.GET("/:id", h.GetWorkflowInstance)
+		instances.POST("/:id/retry", h.RetryWorkflowInstance)
+		instances.POST("/:id/resume", h.ResumeWorkflowInstance)
+		instances.GET("/:id/steps", h.ListWorkflowInstanceSteps)
+		instances.GET("/:id/steps/:stepId", h.GetWorkflowInstanceStep)
+
+		// Human approval routes
+		approvals := workflows.Group("/approvals")
+		approvals.GET("", h.ListWorkflowApprovals)
+		approvals.GET("/:id", h.GetWorkflowApproval)
+		approvals.POST("/:id/approve", h.ApproveWorkflowApproval)
+		approvals.POST("/:id/reject", h.RejectWorkflowApproval)
+	}
+
+	// Approval inbox: list pending approvals and approve/reject with an optional comment in one call
+	inbox := r.Group("/workflow/approvals")
+	inbox.Use(models.AuthRequired)
+	{
+		inbox.GET("", h.ListWorkflowApprovals)
+		inbox.GET("/:id", h.GetWorkflowApproval)
+		inbox.POST("/:id", h.ActOnWorkflowApproval)
 	}
 }
 
@@ -346,22 +372,7 @@ func (h *Handlers) UpdateWorkflowDefinition(c *gin.Context) {
 	}
 
 	// Save current version to history before updating
-	versionHistory := models.WorkflowVersion{
-		DefinitionID: def.ID,
-		Version:      def.Version,
-		Name:         def.Name,
-		Slug:         def.Slug,
-		Description:  def.Description,
-		Status:       def.Status,
-		Definition:   def.Definition,
-		Settings:     def.Settings,
-		Triggers:     def.Triggers,
-		Tags:         def.Tags,
-		CreatedBy:    def.CreatedBy,
-		UpdatedBy:    def.UpdatedBy,
-		ChangeNote:   input.ChangeNote,
-	}
-	if err := h.db.Create(&versionHistory).Error; err != nil {
+	if _, err := workflowdef.SaveVersionSnapshot(h.db, &def, input.ChangeNote); err != nil {
 		// Log error but don't fail the update (version history is non-critical)
 		logger.Error("failed to save version history", zap.Error(err), zap.Uint("definition_id", def.ID), zap.Uint("version", def.Version))
 		// Continue with update even if version history save fails
@@ -483,6 +494,18 @@ var allowedNodeTypes = map[string]struct{}{
 	"wait":      {},
 	"timer":     {},
 	"script":    {},
+	"http":      {},
+	"llm":       {},
+	"tts":       {},
+	"delay":     {},
+	"approval":  {},
+	"ivr":       {},
+	// Voice nodes
+	"call":         {},
+	"say":          {},
+	"collect_dtmf": {},
+	"listen":       {},
+	"intent":       {},
 }
 
 func validateWorkflowGraph(graph models.WorkflowGraph) error {
@@ -535,13 +558,14 @@ func validateWorkflowGraph(graph models.WorkflowGraph) error {
 
 		switch edge.Type {
 		case models.WorkflowEdgeTypeTrue, models.WorkflowEdgeTypeFalse:
-			if sourceType != "gateway" && sourceType != "condition" {
-				return fmt.Errorf("edge type %s allowed only for gateway/condition nodes", edge.Type)
+			if sourceType != "gateway" && sourceType != "condition" && sourceType != "approval" && sourceType != "call" {
+				return fmt.Errorf("edge type %s allowed only for gateway/condition/approval/call nodes", edge.Type)
 			}
 			// Note: condition type is deprecated but kept for backward compatibility
 		case models.WorkflowEdgeTypeBranch:
-			if sourceType != "parallel" {
-				return fmt.Errorf("branch edge allowed only for parallel nodes")
+			// IVR and intent branches carry the digits or intent name in the edge condition
+			if sourceType != "parallel" && sourceType != "ivr" && sourceType != "intent" {
+				return fmt.Errorf("branch edge allowed only for parallel/ivr/intent nodes")
 			}
 		}
 	}
@@ -618,17 +642,8 @@ func (h *Handlers) RunWorkflowDefinition(c *gin.Context) {
 	}
 
 	// Create workflow instance
-	now := time.Now()
-	instance := models.WorkflowInstance{
-		DefinitionID:   def.ID,
-		DefinitionName: def.Name,
-		Status:         "running",
-		StartedAt:      &now,
-		ContextData:    make(models.JSONMap),
-		ResultData:     make(models.JSONMap),
-	}
-
-	if err := h.db.Create(&instance).Error; err != nil {
+	instance, err := workflowdef.NewWorkflowInstance(h.db, &def, input.Parameters, "manual")
+	if err != nil {
 		response.Fail(c, "failed to create workflow instance", err.Error())
 		return
 	}
@@ -637,35 +652,14 @@ func (h *Handlers) RunWorkflowDefinition(c *gin.Context) {
 	execErr := runtimeWf.Execute()
 
 	// Update instance with execution result
-	completedAt := time.Now()
-	instance.CompletedAt = &completedAt
-
-	if execErr != nil {
-		instance.Status = "failed"
-		instance.ResultData = models.JSONMap{
-			"error": execErr.Error(),
-		}
-	} else {
-		instance.Status = "completed"
-		// Store workflow context data as result
-		if runtimeWf.Context != nil {
-			instance.ContextData = runtimeWf.Context.NodeData
-			instance.ResultData = models.JSONMap{
-				"success": true,
-				"context": runtimeWf.Context.NodeData,
-			}
-		}
-	}
-
-	// Update current node if available
-	if runtimeWf.Context != nil && runtimeWf.Context.CurrentNode != "" {
-		instance.CurrentNodeID = runtimeWf.Context.CurrentNode
-	}
-
-	if err := h.db.Save(&instance).Error; err != nil {
+	if err := workflowdef.FinishWorkflowInstance(h.db, instance, runtimeWf, execErr); err != nil {
 		response.Fail(c, "failed to update workflow instance", err.Error())
 		return
 	}
+	if errors.Is(execErr, runtimewf.ErrWorkflowSuspended) {
+		// Paused on an approval node, resumed via the approvals API
+		execErr = nil
+	}
 
 	// Prepare response with logs
 	responseData := map[string]interface{}{
@@ -825,6 +819,84 @@ func (h *Handlers) TestWorkflowNode(c *gin.Context) {
 	response.Success(c, "node test completed", responseData)
 }
 
+// PublishWorkflowDefinition publishes the current draft. Triggers, the scheduler and the
+// event listener only execute the published version, so later draft edits stay offline.
+func (h *Handlers) PublishWorkflowDefinition(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil {
+		response.Fail(c, "unauthorized", nil)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Fail(c, "invalid id", nil)
+		return
+	}
+
+	var def models.WorkflowDefinition
+	if err := h.db.First(&def, id).Error; err != nil {
+		response.Fail(c, "workflow definition not found", err.Error())
+		return
+	}
+	if !h.canManageWorkflow(user, &def) {
+		response.Fail(c, "forbidden", "only the creator or group admin can publish this workflow")
+		return
+	}
+
+	var input struct {
+		Version    uint   `json:"version"`    // expected draft version, guards against publishing unseen edits
+		ChangeNote string `json:"changeNote"` // 发布说明
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && err.Error() != "EOF" {
+		response.Fail(c, "invalid payload", err.Error())
+		return
+	}
+	if input.Version != 0 && input.Version != def.Version {
+		response.Fail(c, "version conflict", fmt.Sprintf("expected version %d", def.Version))
+		return
+	}
+
+	before := gin.H{"version": def.Version, "status": def.Status, "publishedVersion": def.PublishedVersion}
+	version, err := workflowdef.PublishWorkflowDefinition(h.db, &def, user.Email, input.ChangeNote)
+	if err != nil {
+		response.Fail(c, "failed to publish workflow definition", err.Error())
+		return
+	}
+	h.recordAudit(c, models.AuditActionWorkflowPublish, "workflow", def.ID, before, gin.H{
+		"version":          def.Version,
+		"status":           def.Status,
+		"publishedVersion": def.PublishedVersion,
+		"changeNote":       input.ChangeNote,
+	})
+
+	workflowdef.GetWorkflowScheduler(h.db).RefreshWorkflow(def.ID)
+
+	response.Success(c, "workflow definition published", gin.H{
+		"definition": def,
+		"version":    version,
+	})
+}
+
+// canManageWorkflow 创建者、组织创建者或组织管理员可以管理工作流
+func (h *Handlers) canManageWorkflow(user *models.User, def *models.WorkflowDefinition) bool {
+	if def.UserID == user.ID {
+		return true
+	}
+	if def.GroupID == nil {
+		return false
+	}
+	var group models.Group
+	if err := h.db.Where("id = ?", *def.GroupID).First(&group).Error; err != nil {
+		return false
+	}
+	if group.CreatorID == user.ID {
+		return true
+	}
+	var member models.GroupMember
+	return h.db.Where("group_id = ? AND user_id = ? AND role = ?", *def.GroupID, user.ID, models.GroupRoleAdmin).First(&member).Error == nil
+}
+
 // ListWorkflowVersions returns all historical versions of a workflow definition.
 func (h *Handlers) ListWorkflowVersions(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -918,22 +990,7 @@ func (h *Handlers) RollbackWorkflowVersion(c *gin.Context) {
 	}
 
 	// Save current version to history before rollback
-	currentVersionHistory := models.WorkflowVersion{
-		DefinitionID: def.ID,
-		Version:      def.Version,
-		Name:         def.Name,
-		Slug:         def.Slug,
-		Description:  def.Description,
-		Status:       def.Status,
-		Definition:   def.Definition,
-		Settings:     def.Settings,
-		Triggers:     def.Triggers,
-		Tags:         def.Tags,
-		CreatedBy:    def.CreatedBy,
-		UpdatedBy:    def.UpdatedBy,
-		ChangeNote:   fmt.Sprintf("Rollback to version %d", version.Version),
-	}
-	if err := h.db.Create(&currentVersionHistory).Error; err != nil {
+	if _, err := workflowdef.SaveVersionSnapshot(h.db, &def, fmt.Sprintf("Rollback to version %d", version.Version)); err != nil {
 		response.Fail(c, "failed to save version history", err.Error())
 		return
 	}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"bytes"
+	"io"
 	"strconv"
 
+	"github.com/code-100-precent/LingEcho/internal/models"
 	"github.com/code-100-precent/LingEcho/pkg/response"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
 	"github.com/code-100-precent/LingEcho/pkg/voiceclone"
 	"github.com/gin-gonic/gin"
 )
@@ -95,7 +99,11 @@ func (h *Handlers) XunfeiSynthesize(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, "语音合成成功", XunfeiTTSResponse{URL: url})
+	var userID uint
+	if user := models.CurrentUser(c); user != nil {
+		userID = user.ID
+	}
+	response.Success(c, "语音合成成功", XunfeiTTSResponse{URL: stores.SignPublicURL(url, userID)})
 }
 
 // XunfeiCreateTask 创建训练任务
@@ -165,6 +173,20 @@ func (h *Handlers) XunfeiSubmitAudio(c *gin.Context) {
 	}
 	defer src.Close()
 
+	audioData, err := io.ReadAll(src)
+	if err != nil {
+		response.Fail(c, "读取音频文件失败", err.Error())
+		return
+	}
+
+	// 校验并预处理录音，统一转为 WAV 提交
+	caps, _ := voiceclone.GetCapabilities(voiceclone.ProviderXunfei)
+	prepared, err := voiceclone.PrepareTrainingAudio(audioData, file.Filename, caps.PreprocessOptions())
+	if err != nil {
+		response.Fail(c, "音频不符合训练要求", err.Error())
+		return
+	}
+
 	// 提交音频（使用 voiceclone）
 	factory := voiceclone.NewFactory()
 	service, err := factory.CreateServiceFromEnv(voiceclone.ProviderXunfei)
@@ -177,7 +199,7 @@ func (h *Handlers) XunfeiSubmitAudio(c *gin.Context) {
 		TaskID:    req.TaskID,
 		TextID:    req.TextID,
 		TextSegID: req.TextSegID,
-		AudioFile: src,
+		AudioFile: bytes.NewReader(prepared.WAV),
 		Language:  req.Language,
 	}
 	err = service.SubmitAudio(c.Request.Context(), submitReq)
@@ -186,7 +208,7 @@ func (h *Handlers) XunfeiSubmitAudio(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, "提交音频成功", nil)
+	response.Success(c, "提交音频成功", prepared)
 }
 
 // XunfeiQueryTask 查询任务状态
@@ -1,16 +1,122 @@
 package listeners
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/notification"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+var billingListenerDB *gorm.DB
+
 // InitBillingListenerWithDB Initialize billing listener (with database connection)
-// Note: LLM usage recording is handled in llm_listener.go
-// Call usage recording is directly called in models.RecordCallUsage when call ends
+// Note: LLM token usage is recorded in llm_listener.go; ASR seconds, TTS characters
+// and call minutes are metered here from models.SigUsageMetered events
 func InitBillingListenerWithDB(db *gorm.DB) {
-	// Currently billing records are integrated into other listeners and business logic
-	// Retain initialization function here for future expansion of other listening events
+	billingListenerDB = db
+	utils.Sig().Connect(models.SigUsageMetered, func(sender any, params ...any) {
+		event, ok := sender.(*models.UsageEvent)
+		if !ok || billingListenerDB == nil {
+			return
+		}
+		if event.UserID == 0 || event.CredentialID == 0 {
+			return
+		}
+		go recordUsageEvent(billingListenerDB, event)
+	})
+	utils.Sig().Connect(models.SigCredentialQuotaExceeded, func(sender any, params ...any) {
+		event, ok := sender.(*models.CredentialQuotaEvent)
+		if !ok || billingListenerDB == nil {
+			return
+		}
+		go notifyCredentialQuotaExceeded(billingListenerDB, event)
+	})
 	logger.Info("Billing listener initialized", zap.Bool("db_available", db != nil))
 }
+
+// recordUsageEvent records a metered event, attributing it to the assistant's group when applicable
+func recordUsageEvent(db *gorm.DB, event *models.UsageEvent) {
+	if event.GroupID == nil && event.AssistantID != nil {
+		var assistant models.Assistant
+		if err := db.Select("id", "group_id").Where("id = ?", *event.AssistantID).First(&assistant).Error; err == nil {
+			event.GroupID = assistant.GroupID
+		}
+	}
+
+	if err := models.RecordUsageEvent(db, event); err != nil {
+		logger.Warn("Failed to record metered usage",
+			zap.Error(err),
+			zap.String("usageType", string(event.UsageType)),
+			zap.Uint("userId", event.UserID),
+			zap.Uint("credentialId", event.CredentialID))
+	}
+
+	if meter, amount, ok := models.UsageEventAmount(event); ok {
+		meterCredentialUsage(db, event.UserID, event.CredentialID, meter, amount)
+	}
+}
+
+// meterCredentialUsage adds usage to the credential's monthly counter and emits
+// models.SigCredentialQuotaExceeded when this usage is the one that reaches the limit
+func meterCredentialUsage(db *gorm.DB, userID, credentialID uint, meter models.MeterType, amount int64) {
+	counter, err := models.AddCredentialUsage(db, userID, credentialID, meter, amount, time.Now())
+	if err != nil {
+		logger.Warn("Failed to meter credential usage",
+			zap.Error(err),
+			zap.String("meter", string(meter)),
+			zap.Uint("credentialId", credentialID))
+		return
+	}
+
+	var credential models.UserCredential
+	if err := db.Where("id = ?", credentialID).First(&credential).Error; err != nil {
+		return
+	}
+	limit := credential.UsageLimit(meter)
+	used := counter.Used(meter)
+	if limit <= 0 || used < limit || used-amount >= limit {
+		return
+	}
+	utils.Sig().Emit(models.SigCredentialQuotaExceeded, &models.CredentialQuotaEvent{
+		UserID:       userID,
+		CredentialID: credentialID,
+		Name:         credential.Name,
+		Meter:        meter,
+		Used:         used,
+		Limit:        limit,
+		Period:       counter.Period,
+		Action:       credential.QuotaAction,
+	})
+}
+
+// notifyCredentialQuotaExceeded tells the credential owner which limit was reached
+func notifyCredentialQuotaExceeded(db *gorm.DB, event *models.CredentialQuotaEvent) {
+	var user models.User
+	if err := db.First(&user, event.UserID).Error; err != nil {
+		return
+	}
+	behavior := "new requests using it are rejected"
+	if event.Action == models.CredentialQuotaDegrade {
+		behavior = "it now runs in degraded mode"
+	}
+	utils.Sig().Emit(models.SigUserNotify, &user, db, notification.Message{
+		Event: notification.EventQuotaAlert,
+		Level: notification.LevelWarning,
+		Title: "Credential usage limit reached",
+		Content: fmt.Sprintf("Credential %q reached its %s limit for %s (%d/%d); %s until the limit is raised or the next month starts.",
+			event.Name, event.Meter, event.Period, event.Used, event.Limit, behavior),
+		Data: map[string]interface{}{
+			"credentialId": event.CredentialID,
+			"meter":        event.Meter,
+			"used":         event.Used,
+			"limit":        event.Limit,
+			"period":       event.Period,
+			"action":       event.Action,
+		},
+	})
+}
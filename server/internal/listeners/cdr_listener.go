@@ -0,0 +1,52 @@
+package listeners
+
+import (
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// InitCallDetailRecordListener persists call detail records emitted on session teardown.
+// A bridged SIP call is reported twice (signaling and AI session); both are merged into one record
+func InitCallDetailRecordListener(db *gorm.DB) {
+	utils.Sig().Connect(models.SigCallDetailRecord, func(sender any, params ...any) {
+		cdr, ok := sender.(*models.CallDetailRecord)
+		if !ok || db == nil || cdr.CallID == "" {
+			return
+		}
+		go func() {
+			if err := models.MergeCallDetailRecord(db, cdr); err != nil {
+				logger.Warn("Failed to save call detail record",
+					zap.Error(err),
+					zap.String("callId", cdr.CallID),
+					zap.String("channel", string(cdr.Channel)))
+				return
+			}
+			enqueueCallEndedWebhook(db, cdr)
+		}()
+	})
+	logger.Info("Call detail record listener initialized", zap.Bool("db_available", db != nil))
+}
+
+// callEndedWebhookDelay leaves time for the second half of a bridged call to be
+// merged before the call.ended webhook goes out
+const callEndedWebhookDelay = 15 * time.Second
+
+// enqueueCallEndedWebhook queues the call.ended webhook with the merged record.
+// Both halves of a bridged call share the event id, so the later one only refreshes the payload
+func enqueueCallEndedWebhook(db *gorm.DB, cdr *models.CallDetailRecord) {
+	if cdr.UserID == 0 {
+		return
+	}
+	var record models.CallDetailRecord
+	if err := db.Where("call_id = ?", cdr.CallID).First(&record).Error; err != nil {
+		return
+	}
+	if _, err := models.EnqueueWebhookEvent(db, record.UserID, models.WebhookEventCallEnded, "call.ended:"+record.CallID, &record, callEndedWebhookDelay); err != nil {
+		logger.Warn("Failed to queue call ended webhook", zap.Error(err), zap.String("callId", record.CallID))
+	}
+}
@@ -0,0 +1,46 @@
+package listeners
+
+import (
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/middleware"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// InitConfigListeners applies hot-reloaded settings that components cache at startup.
+// Providers (TTS, ASR, LLM) read the environment and the config table per session,
+// so they pick up changes without a listener.
+func InitConfigListeners() {
+	utils.Sig().Connect(config.SigConfigChanged, func(sender any, params ...any) {
+		change, ok := sender.(*config.Change)
+		if !ok {
+			return
+		}
+
+		if level, ok := change.Values["LOG_LEVEL"]; ok {
+			if level == "" {
+				level = "info"
+			}
+			if err := logger.SetLevel(level); err != nil {
+				logger.Warn("Ignoring invalid LOG_LEVEL", zap.String("level", level), zap.Error(err))
+			} else {
+				logger.Info("Log level changed", zap.String("level", level))
+			}
+		}
+
+		// Removing RATE_LIMIT_RATE keeps the current rate until restart
+		if rate := change.Values["RATE_LIMIT_RATE"]; rate != "" {
+			cfg := middleware.GetRateLimiterConfig()
+			cfg.Rate = rate
+			middleware.SetRateLimiterConfig(cfg)
+			logger.Info("Rate limit changed", zap.String("rate", rate))
+		}
+		if planRates, ok := change.Values["RATE_LIMIT_PLAN_RATES"]; ok {
+			cfg := middleware.GetRateLimiterConfig()
+			cfg.PlanRates = middleware.ParsePlanRates(planRates)
+			middleware.SetRateLimiterConfig(cfg)
+			logger.Info("Plan rate limits changed", zap.Any("planRates", cfg.PlanRates))
+		}
+	})
+}
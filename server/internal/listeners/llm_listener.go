@@ -125,7 +125,7 @@ func InitLLMListenerWithDB(db *gorm.DB) {
 					logger.Info("Chat log saved", zap.String("sessionID", sessionID))
 
 					// Trigger async graph processing for conversation
-					// This will summarize the conversation and store knowledge in Neo4j
+					// This will summarize the conversation and store knowledge in the graph store
 					task.ProcessConversationAsync(
 						llmListenerDB,
 						*usageInfo.AssistantID,
@@ -175,6 +175,9 @@ func InitLLMListenerWithDB(db *gorm.DB) {
 				); err != nil {
 					logger.Warn("Failed to record LLM usage", zap.Error(err))
 				}
+				if credentialID != 0 && usageInfo.TotalTokens > 0 {
+					meterCredentialUsage(llmListenerDB, *usageInfo.UserID, credentialID, models.MeterLLMTokens, int64(usageInfo.TotalTokens))
+				}
 			}()
 		}
 	})
@@ -0,0 +1,181 @@
+package listeners
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/notification"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// selfMailedEvents are not emailed here because their sender already emails the recipients
+// it chose (approval nodes have their own email channel)
+var selfMailedEvents = map[string]bool{
+	notification.EventWorkflowApproval: true,
+}
+
+// smsEvents are security alerts also sent by SMS to a verified phone number, mapped to their SMS template
+var smsEvents = map[string]string{
+	notification.EventSuspiciousLogin: notification.SMSTemplateSuspiciousLogin,
+}
+
+var (
+	pushSendersOnce sync.Once
+	pushSendersMap  map[string]notification.PushSender
+
+	webPushSenderOnce sync.Once
+	webPushSenderInst *notification.WebPushSender
+
+	smsServiceOnce sync.Once
+	smsServiceInst *notification.SMSService
+)
+
+// pushSenders builds the FCM / APNs senders from config on first use
+func pushSenders() map[string]notification.PushSender {
+	pushSendersOnce.Do(func() {
+		senders, err := notification.NewPushSenders(config.GlobalConfig.Push)
+		if err != nil {
+			logger.Error("Failed to initialize push senders", zap.Error(err))
+			return
+		}
+		pushSendersMap = senders
+	})
+	return pushSendersMap
+}
+
+// webPushSender builds the browser push sender from config on first use, nil when VAPID keys are not configured
+func webPushSender() *notification.WebPushSender {
+	webPushSenderOnce.Do(func() {
+		sender, err := notification.NewWebPushSender(config.GlobalConfig.WebPush)
+		if err != nil {
+			logger.Error("Failed to initialize web push sender", zap.Error(err))
+			return
+		}
+		webPushSenderInst = sender
+	})
+	return webPushSenderInst
+}
+
+// smsService builds the SMS service from config on first use
+func smsService() *notification.SMSService {
+	smsServiceOnce.Do(func() {
+		cfg := config.GlobalConfig.SMS
+		sender, err := notification.NewSMSSender(cfg)
+		if err != nil {
+			logger.Error("Failed to initialize sms provider", zap.Error(err))
+		}
+		smsServiceInst = notification.NewSMSService(sender, cfg.Interval, cfg.HourlyLimit)
+	})
+	return smsServiceInst
+}
+
+// InitNotificationListeners initializes user notification listeners
+func InitNotificationListeners() {
+	// Deliver a notification to every channel the user has enabled
+	utils.Sig().Connect(models.SigUserNotify, func(sender any, params ...any) {
+		if len(params) < 2 {
+			return
+		}
+		user, ok := sender.(*models.User)
+		if !ok {
+			return
+		}
+		db, ok := params[0].(*gorm.DB)
+		if !ok {
+			return
+		}
+		msg, ok := params[1].(notification.Message)
+		if !ok {
+			return
+		}
+
+		go NotifyUser(db, user, msg)
+	})
+
+	logger.Info("notification module listener is already")
+}
+
+// NotifyUser sends msg through the site inbox, email, mobile and browser push and the user's external channels,
+// honoring the user's notification settings and channel subscriptions
+func NotifyUser(db *gorm.DB, user *models.User, msg notification.Message) {
+	if user.SystemNotifications {
+		item, err := notification.NewInternalNotificationService(db).SendMessage(user.ID, msg)
+		if err != nil {
+			logger.Error("Failed to send internal notification", zap.Error(err), zap.Uint("userId", user.ID))
+		} else {
+			// Lets online clients update the inbox without polling
+			utils.Sig().Emit(models.SigUserInbox, user, item)
+		}
+	}
+
+	if user.EmailNotifications && !selfMailedEvents[msg.Event] && user.Email != "" && config.GlobalConfig.Mail.Host != "" {
+		mailer := notification.NewMailNotification(config.GlobalConfig.Mail)
+		if err := mailer.SendHTML(user.Email, msg.Title, notificationEmailBody(msg)); err != nil {
+			logger.Error("Failed to send notification email", zap.Error(err), zap.String("email", user.Email))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if user.PushNotifications {
+		if senders := pushSenders(); len(senders) > 0 {
+			if _, err := notification.NewPushService(db, senders).SendToUser(ctx, user.ID, msg); err != nil {
+				logger.Warn("Failed to send push notification", zap.Error(err), zap.Uint("userId", user.ID), zap.String("event", msg.Event))
+			}
+		}
+		if sender := webPushSender(); sender != nil {
+			if _, err := notification.NewWebPushService(db, sender).SendToUser(ctx, user.ID, msg); err != nil {
+				logger.Warn("Failed to send web push notification", zap.Error(err), zap.Uint("userId", user.ID), zap.String("event", msg.Event))
+			}
+		}
+	}
+
+	if template, ok := smsEvents[msg.Event]; ok && user.PhoneVerified && user.Phone != "" {
+		if sms := smsService(); sms.Configured() {
+			if err := sms.Send(ctx, user.Phone, notification.SMSMessage{Template: template, Params: smsParams(msg.Data)}); err != nil {
+				logger.Warn("Failed to send notification sms", zap.Error(err), zap.Uint("userId", user.ID), zap.String("event", msg.Event))
+			}
+		}
+	}
+
+	for _, delivery := range notification.NewChannelService(db).Notify(ctx, user.ID, msg) {
+		if delivery.Error != "" {
+			logger.Warn("Failed to deliver notification",
+				zap.Uint("userId", user.ID),
+				zap.Uint("channelId", delivery.ChannelID),
+				zap.String("channel", delivery.Type),
+				zap.String("event", msg.Event),
+				zap.String("error", delivery.Error))
+		}
+	}
+}
+
+// smsParams converts the notification data into SMS template parameters
+func smsParams(data map[string]interface{}) map[string]string {
+	params := make(map[string]string, len(data))
+	for key, value := range data {
+		params[key] = fmt.Sprint(value)
+	}
+	return params
+}
+
+// notificationEmailBody renders msg as a simple HTML email
+func notificationEmailBody(msg notification.Message) string {
+	body := fmt.Sprintf(`<div style="font-family: Arial, sans-serif; padding: 20px;">
+	<h2>%s</h2>
+	<p style="white-space: pre-line;">%s</p>`, html.EscapeString(msg.Title), html.EscapeString(msg.Content))
+	if msg.Link != "" {
+		body += fmt.Sprintf(`
+	<p><a href="%s">%s</a></p>`, html.EscapeString(msg.Link), html.EscapeString(msg.Link))
+	}
+	return body + "\n</div>"
+}
@@ -27,6 +27,8 @@ func InitSystemListeners() {
 	})
 	InitAssistantListener()
 	InitUserListeners()
+	InitNotificationListeners()
+	InitConfigListeners()
 	// InitLLMListener is initialized in main.go (requires database connection)
 	logger.Info("system module listener is already")
 }
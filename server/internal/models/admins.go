@@ -157,6 +157,14 @@ func GetLingEchoAdminObjects() []AdminObject {
 		}
 		return nil
 	}
+	// 用户管理需要 user.manage 权限
+	userManageAccessCheck := func(c *gin.Context, obj *AdminObject) error {
+		user := CurrentUser(c)
+		if !user.HasPermission(PermissionUserManage) {
+			return errors.New("user.manage permission required")
+		}
+		return nil
+	}
 
 	iconUser, _ := LingEcho.EmbedStaticAssets.ReadFile("static/img/icon_user.svg")
 	iconGroup, _ := LingEcho.EmbedStaticAssets.ReadFile("static/img/icon_group.svg")
@@ -176,9 +184,12 @@ func GetLingEchoAdminObjects() []AdminObject {
 			Searchables: []string{"Username", "Email", "FirstName", "ListName"},
 			Orders:      []LingEcho.Order{{"UpdatedAt", LingEcho.OrderOpDesc}},
 			Icon:        &AdminIcon{SVG: string(iconUser)},
-			AccessCheck: superAccessCheck,
+			AccessCheck: userManageAccessCheck,
 			BeforeCreate: func(db *gorm.DB, c *gin.Context, obj any) error {
 				user := obj.(*User)
+				if err := CheckRoleChange(CurrentUser(c), nil, user); err != nil {
+					return err
+				}
 				if user.Password != "" {
 					user.Password = HashPassword(user.Password)
 				}
@@ -186,7 +197,18 @@ func GetLingEchoAdminObjects() []AdminObject {
 				return nil
 			},
 			BeforeUpdate: func(db *gorm.DB, c *gin.Context, obj any, vals map[string]any) error {
+				// obj 为修改前的记录，按提交的角色与权限检查修改后的结果
 				user := obj.(*User)
+				target := *user
+				if role, ok := vals["role"].(string); ok {
+					target.Role = role
+				}
+				if perms, ok := vals["permissions"].(string); ok {
+					target.Permissions = perms
+				}
+				if err := CheckRoleChange(CurrentUser(c), user, &target); err != nil {
+					return err
+				}
 				if dbUser, err := GetUserByEmail(db, user.Email); err == nil {
 					if dbUser.Password != user.Password {
 						user.Password = HashPassword(user.Password)
@@ -295,6 +317,23 @@ func WithAdminAuth() gin.HandlerFunc {
 	}
 }
 
+// PermissionRequired 要求当前用户拥有全部指定权限，未登录返回401，权限不足返回403。
+// 需要支持 API 令牌访问时放在 AuthRequired 之后
+func PermissionRequired(permissions ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := CurrentUser(ctx)
+		if user == nil {
+			LingEcho.AbortWithJSONError(ctx, http.StatusUnauthorized, errors.New("login required"))
+			return
+		}
+		if !user.HasAllPermissions(permissions...) {
+			LingEcho.AbortWithJSONError(ctx, http.StatusForbidden, errors.New("permission denied"))
+			return
+		}
+		ctx.Next()
+	}
+}
+
 func BuildAdminObjects(r *gin.RouterGroup, db *gorm.DB, objs []AdminObject) []*AdminObject {
 	handledObjects := make([]*AdminObject, 0)
 	exists := make(map[string]bool)
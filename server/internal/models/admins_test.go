@@ -174,6 +174,40 @@ func TestWithAdminAuth_IsStaff(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestPermissionRequired(t *testing.T) {
+	db := setupAdminsTestDB(t)
+
+	tests := []struct {
+		name string
+		user *User
+		want int
+	}{
+		{name: "not logged in", user: nil, want: http.StatusUnauthorized},
+		{name: "member", user: &User{ID: 1, Role: RoleUser}, want: http.StatusForbidden},
+		{name: "operator", user: &User{ID: 2, Role: RoleOperator}, want: http.StatusOK},
+		{name: "member with permission", user: &User{ID: 3, Role: RoleUser, Permissions: `["metrics.read"]`}, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupAdminsTestRouter(t, db)
+			router.Use(func(c *gin.Context) {
+				if tt.user != nil {
+					c.Set(constants.UserField, tt.user)
+				}
+				c.Next()
+			})
+			router.GET("/metrics", PermissionRequired(PermissionMetricsRead), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"status": "ok"})
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/metrics", nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
 func TestAdminObject_Build(t *testing.T) {
 	db := setupAdminsTestDB(t)
 
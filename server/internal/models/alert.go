@@ -42,6 +42,9 @@ const (
 	NotificationChannelInternal NotificationChannel = "internal" // 站内通知
 	NotificationChannelWebhook  NotificationChannel = "webhook"  // Webhook
 	NotificationChannelSMS      NotificationChannel = "sms"      // 短信（预留）
+	NotificationChannelSlack    NotificationChannel = "slack"    // Slack（用户渠道）
+	NotificationChannelDingTalk NotificationChannel = "dingtalk" // 钉钉（用户渠道）
+	NotificationChannelFeishu   NotificationChannel = "feishu"   // 飞书（用户渠道）
 )
 
 // AlertRule 告警规则
@@ -0,0 +1,236 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho"
+	"github.com/code-100-precent/LingEcho/pkg/constants"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APITokenPrefix API 令牌前缀，用于与登录令牌区分
+const APITokenPrefix = "lat_"
+
+// API 令牌的调用范围
+const (
+	ScopeVoiceOneshot  = "voice:oneshot"  // 一句话模式与纯文本对话
+	ScopeTTSSynthesize = "tts:synthesize" // 语音合成
+	ScopeASRStream     = "asr:stream"     // 实时语音识别（WebSocket）
+)
+
+var (
+	ErrAPITokenInvalid = errors.New("invalid api token")
+	ErrAPITokenExpired = errors.New("api token expired")
+	ErrAPITokenRevoked = errors.New("api token revoked")
+)
+
+// APIScopes 返回所有可授予的调用范围
+func APIScopes() []string {
+	return []string{ScopeVoiceOneshot, ScopeTTSSynthesize, ScopeASRStream}
+}
+
+// APIToken 由凭证签发的 Bearer 令牌，带调用范围与有效期，调用方不再需要在请求中携带 apiKey/apiSecret
+type APIToken struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"index;not null" json:"userId"`
+	CredentialID uint       `gorm:"index;not null" json:"credentialId"`
+	Name         string     `gorm:"size:128" json:"name"`                  // 用途备注
+	TokenHash    string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // 令牌的 SHA-256，令牌明文只在签发时返回一次
+	Hint         string     `gorm:"size:16" json:"hint"`                   // 令牌末尾几位，便于用户辨认
+	Scopes       string     `gorm:"size:255" json:"scopes"`                // 逗号分隔的调用范围
+	ExpiresAt    *time.Time `gorm:"index" json:"expiresAt,omitempty"`      // 为空表示永不过期
+	RevokedAt    *time.Time `gorm:"index" json:"revokedAt,omitempty"`      // 吊销时间，吊销后立即失效
+	LastUsedAt   *time.Time `json:"lastUsedAt,omitempty"`                  // 最后使用时间
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// HasScope 检查令牌是否包含指定调用范围
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range strings.Split(t.Scopes, ",") {
+		if strings.TrimSpace(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAPIScopes 解析并校验调用范围，支持逗号或空格分隔，返回去重后的列表
+func ParseAPIScopes(raw string) ([]string, error) {
+	valid := make(map[string]bool)
+	for _, scope := range APIScopes() {
+		valid[scope] = true
+	}
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, scope := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !valid[scope] {
+			return nil, fmt.Errorf("unknown scope: %s", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+func hashAPIToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueAPIToken 为凭证签发 API 令牌，ttl 为 0 表示永不过期。返回的明文令牌只此一次可见
+func IssueAPIToken(db *gorm.DB, credential *UserCredential, name string, scopes []string, ttl time.Duration) (string, *APIToken, error) {
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	raw := APITokenPrefix + strings.TrimRight(secret, "=")
+	token := &APIToken{
+		UserID:       credential.UserID,
+		CredentialID: credential.ID,
+		Name:         name,
+		TokenHash:    hashAPIToken(raw),
+		Hint:         raw[len(raw)-4:],
+		Scopes:       strings.Join(scopes, ","),
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := db.Create(token).Error; err != nil {
+		return "", nil, err
+	}
+	return raw, token, nil
+}
+
+// LookupAPIToken 校验明文令牌，返回未过期且未吊销的令牌记录
+func LookupAPIToken(db *gorm.DB, raw string) (*APIToken, error) {
+	if !strings.HasPrefix(raw, APITokenPrefix) {
+		return nil, ErrAPITokenInvalid
+	}
+	var token APIToken
+	if err := db.Where("token_hash = ?", hashAPIToken(raw)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPITokenInvalid
+		}
+		return nil, err
+	}
+	if token.RevokedAt != nil {
+		return nil, ErrAPITokenRevoked
+	}
+	if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+		return nil, ErrAPITokenExpired
+	}
+	return &token, nil
+}
+
+// TouchAPIToken 记录令牌最后使用时间，一分钟内重复使用不再更新
+func TouchAPIToken(db *gorm.DB, token *APIToken) error {
+	now := time.Now()
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < time.Minute {
+		return nil
+	}
+	token.LastUsedAt = &now
+	return db.Model(token).Update("last_used_at", now).Error
+}
+
+// GetAPITokens 获取凭证签发的令牌（包括已吊销的），按签发时间倒序
+func GetAPITokens(db *gorm.DB, userID, credentialID uint) ([]APIToken, error) {
+	var tokens []APIToken
+	err := db.Where("user_id = ? AND credential_id = ?", userID, credentialID).
+		Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeAPIToken 吊销用户的令牌
+func RevokeAPIToken(db *gorm.DB, userID, tokenID uint) error {
+	result := db.Model(&APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeCredentialAPITokens 吊销凭证签发的所有令牌，删除凭证时调用
+func RevokeCredentialAPITokens(db *gorm.DB, credentialID uint) error {
+	return db.Model(&APIToken{}).
+		Where("credential_id = ? AND revoked_at IS NULL", credentialID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// CurrentAPIToken 返回本次请求使用的 API 令牌，未使用令牌时返回 nil
+func CurrentAPIToken(c *gin.Context) *APIToken {
+	if obj, exists := c.Get(constants.APITokenField); exists && obj != nil {
+		return obj.(*APIToken)
+	}
+	return nil
+}
+
+// bearerAPIToken 从 Authorization 头读取 API 令牌，浏览器 WebSocket 无法设置请求头时也可用 access_token 查询参数
+func bearerAPIToken(c *gin.Context) string {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		token = c.Query("access_token")
+	}
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		return ""
+	}
+	return token
+}
+
+// WithAPIToken 接受带有 scope 的 API 令牌（Authorization: Bearer lat_...）。
+// 令牌有效时设置当前用户与令牌，未携带 API 令牌时直接放行，由后续的认证中间件或处理函数校验
+func WithAPIToken(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := bearerAPIToken(c)
+		if raw == "" {
+			c.Next()
+			return
+		}
+		db := c.MustGet(constants.DbField).(*gorm.DB)
+		token, err := LookupAPIToken(db, raw)
+		if err != nil {
+			LingEcho.AbortWithJSONError(c, http.StatusUnauthorized, err)
+			return
+		}
+		if !token.HasScope(scope) {
+			LingEcho.AbortWithJSONError(c, http.StatusForbidden, fmt.Errorf("api token lacks scope %s", scope))
+			return
+		}
+		user, err := GetUserByUID(db, token.UserID)
+		if err != nil {
+			LingEcho.AbortWithJSONError(c, http.StatusUnauthorized, ErrAPITokenInvalid)
+			return
+		}
+		if err := CheckUserAllowLogin(db, user); err != nil {
+			LingEcho.AbortWithJSONError(c, http.StatusForbidden, err)
+			return
+		}
+		_ = TouchAPIToken(db, token)
+		c.Set(constants.UserField, user)
+		c.Set(constants.APITokenField, token)
+		c.Next()
+	}
+}
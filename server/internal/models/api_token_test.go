@@ -0,0 +1,114 @@
+package models
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/constants"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestParseAPIScopes(t *testing.T) {
+	scopes, err := ParseAPIScopes("voice:oneshot, tts:synthesize voice:oneshot")
+	require.NoError(t, err)
+	assert.Equal(t, []string{ScopeVoiceOneshot, ScopeTTSSynthesize}, scopes)
+
+	_, err = ParseAPIScopes("")
+	assert.Error(t, err)
+	_, err = ParseAPIScopes("voice:oneshot,admin:all")
+	assert.Error(t, err)
+}
+
+func TestAPIToken_Lifecycle(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &APIToken{})
+	credential := &UserCredential{ID: 3, UserID: 9}
+
+	raw, token, err := IssueAPIToken(db, credential, "web", []string{ScopeVoiceOneshot}, time.Hour)
+	require.NoError(t, err)
+	assert.Contains(t, raw, APITokenPrefix)
+	assert.NotContains(t, token.TokenHash, raw)
+	require.NotNil(t, token.ExpiresAt)
+
+	found, err := LookupAPIToken(db, raw)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), found.CredentialID)
+	assert.True(t, found.HasScope(ScopeVoiceOneshot))
+	assert.False(t, found.HasScope(ScopeASRStream))
+
+	_, err = LookupAPIToken(db, raw+"x")
+	assert.ErrorIs(t, err, ErrAPITokenInvalid)
+
+	// 吊销后立即失效
+	require.NoError(t, RevokeAPIToken(db, 9, token.ID))
+	_, err = LookupAPIToken(db, raw)
+	assert.ErrorIs(t, err, ErrAPITokenRevoked)
+	assert.ErrorIs(t, RevokeAPIToken(db, 9, token.ID), gorm.ErrRecordNotFound)
+
+	// 过期令牌
+	raw, token, err = IssueAPIToken(db, credential, "old", []string{ScopeASRStream}, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(token).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, err = LookupAPIToken(db, raw)
+	assert.ErrorIs(t, err, ErrAPITokenExpired)
+
+	// 删除凭证时吊销其签发的令牌
+	raw, _, err = IssueAPIToken(db, credential, "forever", []string{ScopeTTSSynthesize}, 0)
+	require.NoError(t, err)
+	require.NoError(t, RevokeCredentialAPITokens(db, credential.ID))
+	_, err = LookupAPIToken(db, raw)
+	assert.ErrorIs(t, err, ErrAPITokenRevoked)
+
+	tokens, err := GetAPITokens(db, 9, 3)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 3)
+}
+
+func TestWithAPIToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDBWithSilentLogger(t, &User{}, &APIToken{})
+	user := &User{Email: "token@example.com", Enabled: true, Activated: true}
+	require.NoError(t, db.Create(user).Error)
+	raw, _, err := IssueAPIToken(db, &UserCredential{ID: 1, UserID: user.ID}, "", []string{ScopeVoiceOneshot}, 0)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(constants.DbField, db)
+		c.Next()
+	})
+	router.GET("/oneshot", WithAPIToken(ScopeVoiceOneshot), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user": CurrentUser(c).ID, "credential": CurrentAPIToken(c).CredentialID})
+	})
+	router.GET("/asr", WithAPIToken(ScopeASRStream), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{name: "valid token", path: "/oneshot", header: "Bearer " + raw, want: http.StatusOK},
+		{name: "query token", path: "/oneshot?access_token=" + raw, want: http.StatusOK},
+		{name: "missing scope", path: "/asr", header: "Bearer " + raw, want: http.StatusForbidden},
+		{name: "unknown token", path: "/oneshot", header: "Bearer " + APITokenPrefix + "nope", want: http.StatusUnauthorized},
+		{name: "no api token passes through", path: "/asr", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
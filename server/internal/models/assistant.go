@@ -25,6 +25,7 @@ type Assistant struct {
 	MaxTokens            int       `json:"maxTokens"`
 	Language             string    `json:"language" gorm:"column:language"`                                     // 语言设置
 	Speaker              string    `json:"speaker" gorm:"column:speaker"`                                       // 发音人ID
+	Greeting             string    `json:"greeting" gorm:"column:greeting;type:text"`                           // 通话接通后的开场白，为空时使用默认问候语
 	VoiceCloneID         *int      `json:"voiceCloneId" gorm:"column:voice_clone_id"`                           // 训练音色ID（可选）
 	KnowledgeBaseID      *string   `json:"knowledgeBaseId" gorm:"column:knowledge_base_id"`                     // 知识库ID（可选）
 	TtsProvider          string    `json:"ttsProvider" gorm:"column:tts_provider"`                              // TTS提供商
@@ -32,9 +33,14 @@ type Assistant struct {
 	ApiSecret            string    `json:"apiSecret" gorm:"column:api_secret"`                                  // API密钥
 	LLMModel             string    `json:"llmModel" gorm:"column:llm_model"`                                    // LLM模型名称
 	EnableGraphMemory    bool      `json:"enableGraphMemory" gorm:"column:enable_graph_memory;default:false"`   // 是否启用基于图数据库的长期记忆
+	EnableMCPTools       bool      `json:"enableMCPTools" gorm:"column:enable_mcp_tools;default:false"`         // 对话中是否允许模型调用内置 MCP 工具
 	EnableVAD            bool      `json:"enableVAD" gorm:"column:enable_vad;default:true"`                     // 是否启用VAD（语音活动检测）用于打断TTS
 	VADThreshold         float64   `json:"vadThreshold" gorm:"column:vad_threshold;default:500"`                // VAD阈值（RMS值，范围0-32768，默认500）
 	VADConsecutiveFrames int       `json:"vadConsecutiveFrames" gorm:"column:vad_consecutive_frames;default:2"` // 需要连续超过阈值的帧数（默认2帧，约40ms）
+	TurnSilenceMs        int       `json:"turnSilenceMs" gorm:"column:turn_silence_ms;default:0"`               // 识别结果停止更新多久后判定用户说完（毫秒，0表示只依据识别最终结果）
+	TurnMaxUtteranceMs   int       `json:"turnMaxUtteranceMs" gorm:"column:turn_max_utterance_ms;default:0"`    // 单轮发言最长时长，超过后直接回答（毫秒，0表示不限制）
+	TurnEndOnSentence    bool      `json:"turnEndOnSentence" gorm:"column:turn_end_on_sentence;default:true"`   // 识别到完整句子（句末标点）即回答，不等最终结果
+	TTSCacheEnabled      bool      `json:"ttsCacheEnabled" gorm:"column:tts_cache_enabled;default:true"`        // 是否复用已合成的短句音频（开场白、固定话术），关闭后每次都重新合成
 	CreatedAt            time.Time `json:"createdAt" gorm:"autoCreateTime"`
 	UpdatedAt            time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
 }
@@ -0,0 +1,268 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// 审计操作
+const (
+	AuditActionCredentialCreate     = "credential.create"
+	AuditActionCredentialUpdate     = "credential.update"
+	AuditActionCredentialDelete     = "credential.delete"
+	AuditActionAPITokenCreate       = "api_token.create"
+	AuditActionAPITokenRevoke       = "api_token.revoke"
+	AuditActionAssistantUpdate      = "assistant.update"
+	AuditActionVoiceCloneConfigSave = "voice_clone_config.update"
+	AuditActionSearchConfigUpdate   = "search_config.update"
+	AuditActionWorkflowPublish      = "workflow.publish"
+	AuditActionUserRoleUpdate       = "user.role.update"
+	AuditActionWebhookCreate        = "webhook.create"
+	AuditActionWebhookUpdate        = "webhook.update"
+	AuditActionWebhookDelete        = "webhook.delete"
+	AuditActionWebhookSecretRotate  = "webhook.secret.rotate"
+	AuditActionAuditLogExport       = "audit_log.export"
+)
+
+// auditRedacted 替换敏感字段的值，变更仍会记录但不保存明文
+const auditRedacted = "******"
+
+// auditSensitiveSuffixes 字段名（小写、去掉分隔符）以这些片段结尾时视为敏感字段，如 apiSecret、llmApiKey、accessToken
+var auditSensitiveSuffixes = []string{"secret", "password", "key", "token"}
+
+var ErrAuditLogImmutable = errors.New("audit log is immutable")
+
+// AuditLog 敏感操作审计记录，写入后不可修改或删除
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"createdAt"`
+
+	ActorID      uint   `gorm:"index" json:"actorId"`
+	ActorEmail   string `gorm:"size:128" json:"actorEmail"`
+	Action       string `gorm:"size:64;index" json:"action"`
+	ResourceType string `gorm:"size:64;index:idx_audit_resource" json:"resourceType"`
+	ResourceID   string `gorm:"size:64;index:idx_audit_resource" json:"resourceId"`
+
+	Before  string `gorm:"type:text" json:"before,omitempty"`  // 变更前的 JSON，敏感字段已脱敏
+	After   string `gorm:"type:text" json:"after,omitempty"`   // 变更后的 JSON，敏感字段已脱敏
+	Changes string `gorm:"type:text" json:"changes,omitempty"` // 字段级差异 {"field": {"from": ..., "to": ...}}
+
+	IPAddress string `gorm:"size:64" json:"ipAddress"`
+	UserAgent string `gorm:"size:255" json:"userAgent"`
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// BeforeUpdate 禁止修改审计记录
+func (*AuditLog) BeforeUpdate(*gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// BeforeDelete 禁止删除审计记录
+func (*AuditLog) BeforeDelete(*gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// AuditChange 单个字段的变更
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// RecordAuditLog 写入一条审计记录，before/after 为变更前后的对象（可为 nil），
+// 序列化后脱敏并计算字段级差异
+func RecordAuditLog(db *gorm.DB, log *AuditLog, before, after interface{}) error {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	var err error
+	if log.Before, err = auditJSON(beforeFields); err != nil {
+		return err
+	}
+	if log.After, err = auditJSON(afterFields); err != nil {
+		return err
+	}
+	if changes := AuditDiff(beforeFields, afterFields); len(changes) > 0 {
+		if log.Changes, err = auditJSON(changes); err != nil {
+			return err
+		}
+	}
+	if len(log.UserAgent) > 255 {
+		log.UserAgent = log.UserAgent[:255]
+	}
+	log.ID = 0
+	return db.Create(log).Error
+}
+
+// AuditDiff 比较两组字段，返回发生变化的字段
+func AuditDiff(before, after map[string]interface{}) map[string]AuditChange {
+	changes := make(map[string]AuditChange)
+	for key, from := range before {
+		to, ok := after[key]
+		if !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = AuditChange{From: from, To: to}
+		}
+	}
+	for key, to := range after {
+		if _, ok := before[key]; !ok {
+			changes[key] = AuditChange{To: to}
+		}
+	}
+	return changes
+}
+
+// auditFields 将对象序列化为字段表并脱敏；敏感字段发生变化时两侧的脱敏值不同，差异仍可见
+func auditFields(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		// 非对象类型记录在 value 字段下
+		var value interface{}
+		json.Unmarshal(data, &value)
+		return map[string]interface{}{"value": value}
+	}
+	redactAuditFields(fields)
+	return fields
+}
+
+// redactAuditFields 递归脱敏敏感字段，嵌套的供应商配置中的密钥同样处理
+func redactAuditFields(fields map[string]interface{}) {
+	for key, value := range fields {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			redactAuditFields(v)
+		case []interface{}:
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					redactAuditFields(m)
+				}
+			}
+		case nil:
+		default:
+			if isAuditSensitiveKey(key) && v != "" {
+				fields[key] = auditRedacted + auditFingerprint(v)
+			}
+		}
+	}
+}
+
+// auditFingerprint 敏感值的短指纹，只用于区分是否变更
+func auditFingerprint(value interface{}) string {
+	data, _ := json.Marshal(value)
+	sum := sha256.Sum256(data)
+	return ":" + hex.EncodeToString(sum[:4])
+}
+
+// isAuditSensitiveKey 判断字段是否需要脱敏
+func isAuditSensitiveKey(key string) bool {
+	normalized := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	for _, suffix := range auditSensitiveSuffixes {
+		if strings.HasSuffix(normalized, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditJSON 序列化字段，空值返回空字符串
+func auditJSON(v interface{}) (string, error) {
+	if reflect.ValueOf(v).Len() == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// auditLogQuery 按筛选参数构建审计记录查询
+func auditLogQuery(db *gorm.DB, params map[string]interface{}) *gorm.DB {
+	query := db.Model(&AuditLog{})
+	if actorID, ok := params["actorId"].(uint); ok && actorID > 0 {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if action, ok := params["action"].(string); ok && action != "" {
+		// 以 . 结尾时按前缀匹配，如 credential. 匹配所有凭证操作
+		if strings.HasSuffix(action, ".") {
+			query = query.Where("action LIKE ?", action+"%")
+		} else {
+			query = query.Where("action = ?", action)
+		}
+	}
+	if resourceType, ok := params["resourceType"].(string); ok && resourceType != "" {
+		query = query.Where("resource_type = ?", resourceType)
+	}
+	if resourceID, ok := params["resourceId"].(string); ok && resourceID != "" {
+		query = query.Where("resource_id = ?", resourceID)
+	}
+	if startTime, ok := params["startTime"].(time.Time); ok {
+		query = query.Where("created_at >= ?", startTime)
+	}
+	if endTime, ok := params["endTime"].(time.Time); ok {
+		query = query.Where("created_at <= ?", endTime)
+	}
+	return query
+}
+
+// ListAuditLogs 分页查询审计记录，按时间倒序
+func ListAuditLogs(db *gorm.DB, params map[string]interface{}, page, size int) ([]AuditLog, int64, error) {
+	query := auditLogQuery(utils.ReadDB(db), params)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 20
+	}
+	var logs []AuditLog
+	err := query.Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&logs).Error
+	return logs, total, err
+}
+
+// EachAuditLog 分批遍历符合条件的审计记录，用于导出
+func EachAuditLog(db *gorm.DB, params map[string]interface{}, fn func(*AuditLog) error) error {
+	var batch []AuditLog
+	return auditLogQuery(utils.ReadDB(db), params).
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				if err := fn(&batch[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// AuditActions 返回所有审计操作
+func AuditActions() []string {
+	return []string{
+		AuditActionCredentialCreate, AuditActionCredentialUpdate, AuditActionCredentialDelete,
+		AuditActionAPITokenCreate, AuditActionAPITokenRevoke,
+		AuditActionAssistantUpdate, AuditActionVoiceCloneConfigSave, AuditActionSearchConfigUpdate,
+		AuditActionWorkflowPublish, AuditActionUserRoleUpdate,
+		AuditActionWebhookCreate, AuditActionWebhookUpdate, AuditActionWebhookDelete, AuditActionWebhookSecretRotate,
+		AuditActionAuditLogExport,
+	}
+}
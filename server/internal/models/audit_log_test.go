@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAuditSensitiveKey(t *testing.T) {
+	for _, key := range []string{"apiSecret", "llmApiKey", "secret_key", "accessToken", "password"} {
+		assert.True(t, isAuditSensitiveKey(key), key)
+	}
+	for _, key := range []string{"llmTokenLimit", "name", "mcpScopes", "userId"} {
+		assert.False(t, isAuditSensitiveKey(key), key)
+	}
+}
+
+func TestRecordAuditLog(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &AuditLog{})
+
+	before := map[string]interface{}{
+		"name":      "prod",
+		"llmApiKey": "sk-old",
+		"ttsConfig": map[string]interface{}{"provider": "xunfei", "apiSecret": "s1"},
+		"removed":   true,
+	}
+	after := map[string]interface{}{
+		"name":      "prod",
+		"llmApiKey": "sk-new",
+		"ttsConfig": map[string]interface{}{"provider": "xunfei", "apiSecret": "s1"},
+		"added":     1,
+	}
+	log := &AuditLog{ActorID: 1, ActorEmail: "admin@example.com", Action: AuditActionCredentialUpdate, ResourceType: "credential", ResourceID: "7"}
+	require.NoError(t, RecordAuditLog(db, log, before, after))
+	require.NotZero(t, log.ID)
+
+	// 密钥不以明文保存
+	assert.NotContains(t, log.Before, "sk-old")
+	assert.NotContains(t, log.After, "sk-new")
+	assert.NotContains(t, log.After, `"s1"`)
+
+	var changes map[string]AuditChange
+	require.NoError(t, json.Unmarshal([]byte(log.Changes), &changes))
+	assert.Len(t, changes, 3)
+	assert.Contains(t, changes, "llmApiKey", "a changed secret is still reported")
+	assert.True(t, strings.HasPrefix(changes["llmApiKey"].To.(string), auditRedacted))
+	assert.NotContains(t, changes, "ttsConfig")
+	assert.Nil(t, changes["added"].From)
+	assert.Nil(t, changes["removed"].To)
+
+	// 创建操作没有变更前的数据
+	created := &AuditLog{ActorID: 1, Action: AuditActionWebhookCreate, ResourceType: "webhook", ResourceID: "3"}
+	require.NoError(t, RecordAuditLog(db, created, nil, map[string]interface{}{"url": "https://a.example.com"}))
+	assert.Empty(t, created.Before)
+	assert.NotEmpty(t, created.Changes)
+}
+
+func TestAuditLog_Immutable(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &AuditLog{})
+	log := &AuditLog{ActorID: 1, Action: AuditActionWorkflowPublish, ResourceType: "workflow", ResourceID: "1"}
+	require.NoError(t, RecordAuditLog(db, log, nil, nil))
+
+	assert.ErrorIs(t, db.Model(log).Update("action", "tampered").Error, ErrAuditLogImmutable)
+	assert.ErrorIs(t, db.Model(&AuditLog{}).Where("id = ?", log.ID).Update("actor_id", 2).Error, ErrAuditLogImmutable)
+	assert.ErrorIs(t, db.Delete(log).Error, ErrAuditLogImmutable)
+
+	var stored AuditLog
+	require.NoError(t, db.First(&stored, log.ID).Error)
+	assert.Equal(t, AuditActionWorkflowPublish, stored.Action)
+}
+
+func TestListAuditLogs(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &AuditLog{})
+	for _, action := range []string{AuditActionCredentialCreate, AuditActionCredentialDelete, AuditActionWorkflowPublish} {
+		require.NoError(t, RecordAuditLog(db, &AuditLog{ActorID: 1, Action: action, ResourceType: strings.Split(action, ".")[0]}, nil, nil))
+	}
+	require.NoError(t, RecordAuditLog(db, &AuditLog{ActorID: 2, Action: AuditActionUserRoleUpdate, ResourceType: "user"}, nil, nil))
+
+	logs, total, err := ListAuditLogs(db, map[string]interface{}{"actorId": uint(1)}, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, logs, 2)
+	assert.Equal(t, AuditActionWorkflowPublish, logs[0].Action, "newest first")
+
+	_, total, err = ListAuditLogs(db, map[string]interface{}{"action": "credential."}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+
+	_, total, err = ListAuditLogs(db, map[string]interface{}{"startTime": time.Now().Add(time.Hour)}, 1, 10)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+
+	var exported []string
+	require.NoError(t, EachAuditLog(db, map[string]interface{}{"resourceType": "user"}, func(l *AuditLog) error {
+		exported = append(exported, l.Action)
+		return nil
+	}))
+	assert.Equal(t, []string{AuditActionUserRoleUpdate}, exported)
+}
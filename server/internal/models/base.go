@@ -73,6 +73,7 @@ type User struct {
 	// 新增用户统计信息
 	LoginCount         int        `json:"loginCount" gorm:"default:0"`      // 登录次数
 	LastPasswordChange *time.Time `json:"lastPasswordChange,omitempty"`     // 最后密码修改时间
+	SessionsRevokedAt  *time.Time `json:"-"`                                // 退出所有设备的时间，此后未登记的旧会话不再有效
 	ProfileComplete    int        `json:"profileComplete" gorm:"default:0"` // 资料完整度百分比
 
 	// 新增用户角色和权限
@@ -131,6 +132,19 @@ type UserCredential struct {
 	// TTS配置 - 使用JSON字段存储灵活的配置
 	TtsConfig ProviderConfig `json:"ttsConfig" gorm:"type:json"`
 
+	// MCP调用范围，逗号分隔，如 "tool:search_*,agent:rag_agent"，为空时不限制
+	MCPScopes string `json:"mcpScopes" gorm:"type:text"`
+
+	// 该凭证可占用的存储空间（字节），0 表示不限制
+	StorageQuota int64 `json:"storageQuota" gorm:"default:0"`
+
+	// 每月用量上限，0 表示不限制，用量见 CredentialUsageCounter
+	LLMTokenLimit     int64  `json:"llmTokenLimit" gorm:"default:0"`              // LLM Token
+	TTSCharacterLimit int64  `json:"ttsCharacterLimit" gorm:"default:0"`          // 语音合成字符数
+	ASRSecondLimit    int64  `json:"asrSecondLimit" gorm:"default:0"`             // 语音识别秒数
+	WebRTCMinuteLimit int64  `json:"webrtcMinuteLimit" gorm:"default:0"`          // 实时语音通话分钟数
+	QuotaAction       string `json:"quotaAction" gorm:"size:16;default:'reject'"` // 达到上限后的处理：reject 拒绝请求，degrade 降级（一句话模式只返回文本）
+
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 }
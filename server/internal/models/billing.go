@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"gorm.io/gorm"
 )
 
@@ -60,6 +61,23 @@ const (
 	UsageTypeAPI     UsageType = "api"     // API调用
 )
 
+// SigUsageMetered 计量事件，由计费监听器按凭证记录使用量
+// sender: *UsageEvent
+const SigUsageMetered = "billing.usage"
+
+// UsageEvent 语音链路上报的一次计量事件（ASR/TTS/通话）
+type UsageEvent struct {
+	UsageType    UsageType
+	UserID       uint
+	CredentialID uint
+	AssistantID  *uint
+	GroupID      *uint
+	SessionID    string
+	Duration     int   // 音频或通话时长（秒）
+	AudioSize    int64 // 音频大小（字节）
+	Characters   int   // TTS 合成字符数
+}
+
 // UsageRecord 使用量记录
 type UsageRecord struct {
 	ID           uint    `json:"id" gorm:"primaryKey"`
@@ -86,6 +104,7 @@ type UsageRecord struct {
 	// ASR/TTS相关
 	AudioDuration int   `json:"audioDuration" gorm:"default:0"` // 音频时长（秒）
 	AudioSize     int64 `json:"audioSize" gorm:"default:0"`     // 音频大小（字节）
+	TTSCharacters int   `json:"ttsCharacters" gorm:"default:0"` // 合成文本字符数（仅TTS）
 
 	// 存储相关
 	StorageSize int64 `json:"storageSize" gorm:"default:0"` // 存储大小（字节）
@@ -145,8 +164,9 @@ type Bill struct {
 	TotalASRDuration int64 `json:"totalASRDuration" gorm:"default:0"` // ASR总时长（秒）
 	TotalASRCount    int64 `json:"totalASRCount" gorm:"default:0"`    // ASR调用次数
 
-	TotalTTSDuration int64 `json:"totalTTSDuration" gorm:"default:0"` // TTS总时长（秒）
-	TotalTTSCount    int64 `json:"totalTTSCount" gorm:"default:0"`    // TTS调用次数
+	TotalTTSDuration   int64 `json:"totalTTSDuration" gorm:"default:0"`   // TTS总时长（秒）
+	TotalTTSCount      int64 `json:"totalTTSCount" gorm:"default:0"`      // TTS调用次数
+	TotalTTSCharacters int64 `json:"totalTTSCharacters" gorm:"default:0"` // TTS合成字符数
 
 	TotalStorageSize int64 `json:"totalStorageSize" gorm:"default:0"` // 总存储大小（字节）
 	TotalAPICalls    int64 `json:"totalAPICalls" gorm:"default:0"`    // 总API调用次数
@@ -189,8 +209,9 @@ type UsageStatistics struct {
 	ASRCount    int64 `json:"asrCount"`    // ASR调用次数
 
 	// TTS统计
-	TTSDuration int64 `json:"ttsDuration"` // TTS总时长（秒）
-	TTSCount    int64 `json:"ttsCount"`    // TTS调用次数
+	TTSDuration   int64 `json:"ttsDuration"`   // TTS总时长（秒）
+	TTSCount      int64 `json:"ttsCount"`      // TTS调用次数
+	TTSCharacters int64 `json:"ttsCharacters"` // TTS合成字符数
 
 	// 存储统计
 	StorageSize int64 `json:"storageSize"` // 存储大小（字节）
@@ -206,6 +227,9 @@ func CreateUsageRecord(db *gorm.DB, record *UsageRecord) error {
 
 // GetUsageRecords 获取使用量记录列表
 func GetUsageRecords(db *gorm.DB, userID uint, params map[string]interface{}) ([]UsageRecord, int64, error) {
+	// 列表/统计查询走只读副本（未配置副本时即主库）
+	db = utils.ReadDB(db)
+
 	var records []UsageRecord
 	var total int64
 
@@ -269,6 +293,8 @@ func GetUsageRecords(db *gorm.DB, userID uint, params map[string]interface{}) ([
 
 // GetUsageStatistics 获取使用量统计
 func GetUsageStatistics(db *gorm.DB, userID uint, startTime, endTime time.Time, credentialID *uint, groupID *uint) (*UsageStatistics, error) {
+	db = utils.ReadDB(db)
+
 	stats := &UsageStatistics{
 		StartTime: startTime,
 		EndTime:   endTime,
@@ -336,14 +362,16 @@ func GetUsageStatistics(db *gorm.DB, userID uint, startTime, endTime time.Time,
 
 	// TTS统计
 	var ttsStats struct {
-		Count    int64
-		Duration int64
+		Count      int64
+		Duration   int64
+		Characters int64
 	}
 	createBaseQuery().Where("usage_type = ?", UsageTypeTTS).
-		Select("COUNT(*) as count, SUM(audio_duration) as duration").
+		Select("COUNT(*) as count, SUM(audio_duration) as duration, SUM(tts_characters) as characters").
 		Scan(&ttsStats)
 	stats.TTSCount = ttsStats.Count
 	stats.TTSDuration = ttsStats.Duration
+	stats.TTSCharacters = ttsStats.Characters
 
 	// 存储统计
 	var storageStats struct {
@@ -383,6 +411,8 @@ type DailyUsageData struct {
 
 // GetDailyUsageData 获取按日期分组的使用量数据
 func GetDailyUsageData(db *gorm.DB, userID uint, startTime, endTime time.Time, credentialID *uint, groupID *uint) ([]DailyUsageData, error) {
+	db = utils.ReadDB(db)
+
 	var results []DailyUsageData
 
 	// 创建基础查询
@@ -468,6 +498,8 @@ func CreateBill(db *gorm.DB, bill *Bill) error {
 
 // GetBills 获取账单列表
 func GetBills(db *gorm.DB, userID uint, params map[string]interface{}) ([]Bill, int64, error) {
+	db = utils.ReadDB(db)
+
 	var bills []Bill
 	var total int64
 
@@ -599,7 +631,7 @@ func RecordASRUsage(db *gorm.DB, userID, credentialID uint, assistantID *uint, g
 }
 
 // RecordTTSUsage 记录TTS使用量
-func RecordTTSUsage(db *gorm.DB, userID, credentialID uint, assistantID *uint, groupID *uint, sessionID string, duration int, audioSize int64) error {
+func RecordTTSUsage(db *gorm.DB, userID, credentialID uint, assistantID *uint, groupID *uint, sessionID string, duration int, audioSize int64, characters int) error {
 	record := &UsageRecord{
 		UserID:        userID,
 		GroupID:       groupID,
@@ -609,11 +641,26 @@ func RecordTTSUsage(db *gorm.DB, userID, credentialID uint, assistantID *uint, g
 		UsageType:     UsageTypeTTS,
 		AudioDuration: duration,
 		AudioSize:     audioSize,
+		TTSCharacters: characters,
 		UsageTime:     time.Now(),
 	}
 	return CreateUsageRecord(db, record)
 }
 
+// RecordUsageEvent 将计量事件写入使用量记录
+func RecordUsageEvent(db *gorm.DB, event *UsageEvent) error {
+	switch event.UsageType {
+	case UsageTypeASR:
+		return RecordASRUsage(db, event.UserID, event.CredentialID, event.AssistantID, event.GroupID, event.SessionID, event.Duration, event.AudioSize)
+	case UsageTypeTTS:
+		return RecordTTSUsage(db, event.UserID, event.CredentialID, event.AssistantID, event.GroupID, event.SessionID, event.Duration, event.AudioSize, event.Characters)
+	case UsageTypeCall:
+		return RecordCallUsage(db, event.UserID, event.CredentialID, event.AssistantID, event.GroupID, event.SessionID, nil, event.Duration)
+	default:
+		return fmt.Errorf("unsupported metered usage type: %s", event.UsageType)
+	}
+}
+
 // RecordStorageUsage 记录存储使用量
 func RecordStorageUsage(db *gorm.DB, userID, credentialID uint, assistantID *uint, groupID *uint, sessionID string, storageSize int64, description string) error {
 	record := &UsageRecord{
@@ -674,6 +721,7 @@ func GenerateBill(db *gorm.DB, userID uint, credentialID *uint, groupID *uint, s
 		TotalASRCount:         stats.ASRCount,
 		TotalTTSDuration:      stats.TTSDuration,
 		TotalTTSCount:         stats.TTSCount,
+		TotalTTSCharacters:    stats.TTSCharacters,
 		TotalStorageSize:      stats.StorageSize,
 		TotalAPICalls:         stats.APICalls,
 	}
@@ -0,0 +1,421 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// MeterType 计费计量项
+type MeterType string
+
+const (
+	MeterASRMinutes    MeterType = "asr_minutes"    // 语音识别（分钟）
+	MeterTTSCharacters MeterType = "tts_characters" // 语音合成（字符）
+	MeterLLMTokens     MeterType = "llm_tokens"     // LLM Token
+	MeterCallMinutes   MeterType = "call_minutes"   // 通话（分钟）
+)
+
+// Meters 账单中计量项的固定顺序
+var Meters = []MeterType{MeterASRMinutes, MeterTTSCharacters, MeterLLMTokens, MeterCallMinutes}
+
+// ErrPlanQuotaExceeded 套餐额度已用完且套餐不允许超额使用
+var ErrPlanQuotaExceeded = errors.New("plan quota exceeded")
+
+// StatementPeriodLayout 账期格式
+const StatementPeriodLayout = "2006-01"
+
+// BillingPlan 计费套餐，金额单位均为分
+type BillingPlan struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	Code        string `json:"code" gorm:"size:50;uniqueIndex"`
+	Name        string `json:"name" gorm:"size:100"`
+	Description string `json:"description,omitempty" gorm:"size:500"`
+	Currency    string `json:"currency" gorm:"size:10;default:'CNY'"`
+	MonthlyFee  int64  `json:"monthlyFee" gorm:"default:0"` // 月费（分）
+	IsDefault   bool   `json:"isDefault" gorm:"default:false"`
+	Enabled     bool   `json:"enabled" gorm:"default:true"`
+
+	// 每月包含额度，0 表示不包含，全部按超额计费
+	IncludedASRMinutes    int64 `json:"includedAsrMinutes" gorm:"default:0"`
+	IncludedTTSCharacters int64 `json:"includedTtsCharacters" gorm:"default:0"`
+	IncludedLLMTokens     int64 `json:"includedLlmTokens" gorm:"default:0"`
+	IncludedCallMinutes   int64 `json:"includedCallMinutes" gorm:"default:0"`
+
+	// 超额单价（分）
+	ASRPricePerMinute   int64 `json:"asrPricePerMinute" gorm:"default:0"`
+	TTSPricePer1KChars  int64 `json:"ttsPricePer1kChars" gorm:"default:0"`
+	LLMPricePer1KTokens int64 `json:"llmPricePer1kTokens" gorm:"default:0"`
+	CallPricePerMinute  int64 `json:"callPricePerMinute" gorm:"default:0"`
+
+	// 硬限制：包含额度用完后拒绝新的语音会话，而不是按超额计费
+	HardLimit bool `json:"hardLimit" gorm:"default:false"`
+}
+
+func (BillingPlan) TableName() string {
+	return "billing_plans"
+}
+
+// Included 返回计量项的每月包含额度
+func (p *BillingPlan) Included(meter MeterType) int64 {
+	switch meter {
+	case MeterASRMinutes:
+		return p.IncludedASRMinutes
+	case MeterTTSCharacters:
+		return p.IncludedTTSCharacters
+	case MeterLLMTokens:
+		return p.IncludedLLMTokens
+	case MeterCallMinutes:
+		return p.IncludedCallMinutes
+	}
+	return 0
+}
+
+// UnitPrice 返回计量项的超额单价（分）及计价单位数量
+func (p *BillingPlan) UnitPrice(meter MeterType) (price int64, per int64) {
+	switch meter {
+	case MeterASRMinutes:
+		return p.ASRPricePerMinute, 1
+	case MeterTTSCharacters:
+		return p.TTSPricePer1KChars, 1000
+	case MeterLLMTokens:
+		return p.LLMPricePer1KTokens, 1000
+	case MeterCallMinutes:
+		return p.CallPricePerMinute, 1
+	}
+	return 0, 1
+}
+
+// BillingSubscription 用户订阅的套餐
+type BillingSubscription struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	UserID uint `json:"userId" gorm:"uniqueIndex"`
+	PlanID uint `json:"planId" gorm:"index"`
+}
+
+func (BillingSubscription) TableName() string {
+	return "billing_subscriptions"
+}
+
+// MeteredUsage 按凭证汇总的计量用量（原始单位）
+type MeteredUsage struct {
+	CredentialID  uint  `json:"credentialId"`
+	ASRSeconds    int64 `json:"asrSeconds"`
+	TTSCharacters int64 `json:"ttsCharacters"`
+	LLMTokens     int64 `json:"llmTokens"`
+	CallSeconds   int64 `json:"callSeconds"`
+}
+
+// Quantity 将用量换算为计量项的计费单位，时长按分钟向上取整
+func (u MeteredUsage) Quantity(meter MeterType) int64 {
+	switch meter {
+	case MeterASRMinutes:
+		return (u.ASRSeconds + 59) / 60
+	case MeterTTSCharacters:
+		return u.TTSCharacters
+	case MeterLLMTokens:
+		return u.LLMTokens
+	case MeterCallMinutes:
+		return (u.CallSeconds + 59) / 60
+	}
+	return 0
+}
+
+// StatementLineItem 账单明细行
+type StatementLineItem struct {
+	Meter     MeterType `json:"meter"`
+	Quantity  int64     `json:"quantity"`  // 本期用量
+	Included  int64     `json:"included"`  // 套餐包含额度
+	Overage   int64     `json:"overage"`   // 超额用量
+	UnitPrice int64     `json:"unitPrice"` // 超额单价（分）
+	PriceUnit int64     `json:"priceUnit"` // 单价对应的用量单位数，如 1000 个字符
+	Amount    int64     `json:"amount"`    // 金额（分）
+}
+
+// StatementStatus 月度账单状态
+type StatementStatus string
+
+const (
+	StatementStatusOpen      StatementStatus = "open"      // 当前账期，用量仍在累计
+	StatementStatusFinalized StatementStatus = "finalized" // 账期已结束，金额不再变化
+)
+
+// UsageStatement 月度用量账单（发票）
+type UsageStatement struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	UserID    uint            `json:"userId" gorm:"uniqueIndex:idx_statement_user_period"`
+	Period    string          `json:"period" gorm:"size:7;uniqueIndex:idx_statement_user_period"` // 账期 YYYY-MM
+	InvoiceNo string          `json:"invoiceNo" gorm:"size:50;uniqueIndex"`
+	Status    StatementStatus `json:"status" gorm:"size:20;index"`
+	StartTime time.Time       `json:"startTime"`
+	EndTime   time.Time       `json:"endTime"`
+
+	PlanID   *uint  `json:"planId,omitempty"`
+	PlanName string `json:"planName,omitempty" gorm:"size:100"`
+	Currency string `json:"currency" gorm:"size:10"`
+
+	MonthlyFee    int64 `json:"monthlyFee"`    // 月费（分）
+	OverageAmount int64 `json:"overageAmount"` // 超额费用（分）
+	TotalAmount   int64 `json:"totalAmount"`   // 应付总额（分）
+
+	LineItems   string `json:"-" gorm:"type:text"` // JSON 格式的 []StatementLineItem
+	Credentials string `json:"-" gorm:"type:text"` // JSON 格式的 []MeteredUsage，按凭证拆分
+
+	FinalizedAt *time.Time `json:"finalizedAt,omitempty"`
+}
+
+func (UsageStatement) TableName() string {
+	return "usage_statements"
+}
+
+// GetLineItems 获取账单明细
+func (s *UsageStatement) GetLineItems() []StatementLineItem {
+	var items []StatementLineItem
+	if s.LineItems != "" {
+		json.Unmarshal([]byte(s.LineItems), &items)
+	}
+	return items
+}
+
+// GetCredentialUsage 获取按凭证拆分的用量
+func (s *UsageStatement) GetCredentialUsage() []MeteredUsage {
+	var usage []MeteredUsage
+	if s.Credentials != "" {
+		json.Unmarshal([]byte(s.Credentials), &usage)
+	}
+	return usage
+}
+
+// StatementPeriodRange 解析账期，返回账期的起止时间
+func StatementPeriodRange(period string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation(StatementPeriodLayout, period, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid statement period %q, expected YYYY-MM", period)
+	}
+	return start, start.AddDate(0, 1, 0).Add(-time.Nanosecond), nil
+}
+
+// GetBillingPlans 获取可订阅的套餐
+func GetBillingPlans(db *gorm.DB) ([]BillingPlan, error) {
+	var plans []BillingPlan
+	err := db.Where("enabled = ?", true).Order("monthly_fee ASC, id ASC").Find(&plans).Error
+	return plans, err
+}
+
+// GetUserBillingPlan 获取用户当前套餐，未订阅时使用默认套餐，都没有时返回 nil
+func GetUserBillingPlan(db *gorm.DB, userID uint) (*BillingPlan, error) {
+	var plan BillingPlan
+	var sub BillingSubscription
+	err := db.Where("user_id = ?", userID).First(&sub).Error
+	if err == nil {
+		if err := db.First(&plan, sub.PlanID).Error; err == nil {
+			return &plan, nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	err = db.Where("is_default = ? AND enabled = ?", true, true).Order("id ASC").First(&plan).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// SubscribeBillingPlan 为用户订阅套餐，从当前账期开始生效
+func SubscribeBillingPlan(db *gorm.DB, userID, planID uint) (*BillingSubscription, error) {
+	var plan BillingPlan
+	if err := db.Where("id = ? AND enabled = ?", planID, true).First(&plan).Error; err != nil {
+		return nil, err
+	}
+
+	var sub BillingSubscription
+	err := db.Where("user_id = ?", userID).First(&sub).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	sub.UserID = userID
+	sub.PlanID = plan.ID
+	if err := db.Save(&sub).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// GetMeteredUsage 按凭证汇总时间范围内的计量用量
+func GetMeteredUsage(db *gorm.DB, userID uint, startTime, endTime time.Time) ([]MeteredUsage, error) {
+	var usage []MeteredUsage
+	err := utils.ReadDB(db).Model(&UsageRecord{}).
+		Select(`credential_id,
+			COALESCE(SUM(CASE WHEN usage_type = ? THEN audio_duration ELSE 0 END), 0) AS asr_seconds,
+			COALESCE(SUM(CASE WHEN usage_type = ? THEN tts_characters ELSE 0 END), 0) AS tts_characters,
+			COALESCE(SUM(CASE WHEN usage_type = ? THEN total_tokens ELSE 0 END), 0) AS llm_tokens,
+			COALESCE(SUM(CASE WHEN usage_type = ? THEN call_duration ELSE 0 END), 0) AS call_seconds`,
+			UsageTypeASR, UsageTypeTTS, UsageTypeLLM, UsageTypeCall).
+		Where("user_id = ? AND usage_time >= ? AND usage_time <= ?", userID, startTime, endTime).
+		Where("usage_type IN ?", []UsageType{UsageTypeASR, UsageTypeTTS, UsageTypeLLM, UsageTypeCall}).
+		Group("credential_id").
+		Order("credential_id ASC").
+		Scan(&usage).Error
+	return usage, err
+}
+
+// sumMeteredUsage 合计所有凭证的用量
+func sumMeteredUsage(usage []MeteredUsage) MeteredUsage {
+	var total MeteredUsage
+	for _, u := range usage {
+		total.ASRSeconds += u.ASRSeconds
+		total.TTSCharacters += u.TTSCharacters
+		total.LLMTokens += u.LLMTokens
+		total.CallSeconds += u.CallSeconds
+	}
+	return total
+}
+
+// BuildStatementLineItems 按套餐计算各计量项的超额用量和金额，plan 为 nil 时不计费
+func BuildStatementLineItems(plan *BillingPlan, total MeteredUsage) []StatementLineItem {
+	items := make([]StatementLineItem, 0, len(Meters))
+	for _, meter := range Meters {
+		item := StatementLineItem{Meter: meter, Quantity: total.Quantity(meter), PriceUnit: 1}
+		if plan != nil {
+			item.Included = plan.Included(meter)
+			item.UnitPrice, item.PriceUnit = plan.UnitPrice(meter)
+		}
+		if item.Quantity > item.Included {
+			item.Overage = item.Quantity - item.Included
+		}
+		// 不足一个计价单位的部分向上取整
+		item.Amount = (item.Overage*item.UnitPrice + item.PriceUnit - 1) / item.PriceUnit
+		items = append(items, item)
+	}
+	return items
+}
+
+// CheckPlanQuota 检查用户本月用量是否已超出硬限制套餐的包含额度
+func CheckPlanQuota(db *gorm.DB, userID uint) error {
+	plan, err := GetUserBillingPlan(db, userID)
+	if err != nil || plan == nil || !plan.HardLimit {
+		return err
+	}
+
+	start, end, _ := StatementPeriodRange(time.Now().Format(StatementPeriodLayout))
+	usage, err := GetMeteredUsage(db, userID, start, end)
+	if err != nil {
+		return err
+	}
+	total := sumMeteredUsage(usage)
+	for _, meter := range Meters {
+		if included := plan.Included(meter); included > 0 && total.Quantity(meter) >= included {
+			return fmt.Errorf("%w: %s", ErrPlanQuotaExceeded, meter)
+		}
+	}
+	return nil
+}
+
+// GenerateMonthlyStatement 汇总账期用量生成月度账单；已结束的账期会被定稿，定稿后不再重新计算
+func GenerateMonthlyStatement(db *gorm.DB, userID uint, period string) (*UsageStatement, error) {
+	start, end, err := StatementPeriodRange(period)
+	if err != nil {
+		return nil, err
+	}
+
+	var statement UsageStatement
+	err = db.Where("user_id = ? AND period = ?", userID, period).First(&statement).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if statement.Status == StatementStatusFinalized {
+		return &statement, nil
+	}
+
+	plan, err := GetUserBillingPlan(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := GetMeteredUsage(db, userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate usage: %w", err)
+	}
+	items := BuildStatementLineItems(plan, sumMeteredUsage(usage))
+
+	statement.UserID = userID
+	statement.Period = period
+	statement.InvoiceNo = fmt.Sprintf("INV-%s-%06d", strings.ReplaceAll(period, "-", ""), userID)
+	statement.StartTime = start
+	statement.EndTime = end
+	statement.Status = StatementStatusOpen
+	statement.PlanID = nil
+	statement.PlanName = ""
+	statement.Currency = "CNY"
+	statement.MonthlyFee = 0
+	if plan != nil {
+		statement.PlanID = &plan.ID
+		statement.PlanName = plan.Name
+		statement.Currency = plan.Currency
+		statement.MonthlyFee = plan.MonthlyFee
+	}
+	statement.OverageAmount = 0
+	for _, item := range items {
+		statement.OverageAmount += item.Amount
+	}
+	statement.TotalAmount = statement.MonthlyFee + statement.OverageAmount
+
+	itemsJSON, _ := json.Marshal(items)
+	statement.LineItems = string(itemsJSON)
+	usageJSON, _ := json.Marshal(usage)
+	statement.Credentials = string(usageJSON)
+
+	if now := time.Now(); now.After(end) {
+		statement.Status = StatementStatusFinalized
+		statement.FinalizedAt = &now
+	}
+
+	if err := db.Save(&statement).Error; err != nil {
+		return nil, fmt.Errorf("failed to save statement: %w", err)
+	}
+	return &statement, nil
+}
+
+// GetUsageStatements 获取用户的月度账单，按账期倒序
+func GetUsageStatements(db *gorm.DB, userID uint) ([]UsageStatement, error) {
+	var statements []UsageStatement
+	err := db.Where("user_id = ?", userID).Order("period DESC").Find(&statements).Error
+	return statements, err
+}
+
+// GetUsageStatement 获取单个月度账单
+func GetUsageStatement(db *gorm.DB, userID, statementID uint) (*UsageStatement, error) {
+	var statement UsageStatement
+	if err := db.Where("user_id = ? AND id = ?", userID, statementID).First(&statement).Error; err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// GetUsersWithUsage 获取时间范围内有计量用量的用户
+func GetUsersWithUsage(db *gorm.DB, startTime, endTime time.Time) ([]uint, error) {
+	var userIDs []uint
+	err := db.Model(&UsageRecord{}).
+		Where("usage_time >= ? AND usage_time <= ?", startTime, endTime).
+		Distinct("user_id").Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
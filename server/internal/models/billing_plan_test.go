@@ -0,0 +1,130 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupBillingPlanTestDB(t *testing.T) *gorm.DB {
+	return setupTestDBWithSilentLogger(t,
+		&UsageRecord{},
+		&BillingPlan{},
+		&BillingSubscription{},
+		&UsageStatement{},
+	)
+}
+
+func TestBuildStatementLineItems(t *testing.T) {
+	plan := &BillingPlan{
+		IncludedASRMinutes:  10,
+		IncludedLLMTokens:   1000,
+		ASRPricePerMinute:   5,
+		TTSPricePer1KChars:  30,
+		LLMPricePer1KTokens: 2,
+	}
+	items := BuildStatementLineItems(plan, MeteredUsage{
+		ASRSeconds:    12*60 + 1, // 13 分钟
+		TTSCharacters: 1500,
+		LLMTokens:     800,
+	})
+	require.Len(t, items, len(Meters))
+
+	assert.Equal(t, int64(13), items[0].Quantity)
+	assert.Equal(t, int64(3), items[0].Overage)
+	assert.Equal(t, int64(15), items[0].Amount)
+
+	// 1500 字符 * 30 分 / 1000 = 45 分
+	assert.Equal(t, int64(1500), items[1].Overage)
+	assert.Equal(t, int64(45), items[1].Amount)
+
+	assert.Equal(t, int64(0), items[2].Overage)
+	assert.Equal(t, int64(0), items[2].Amount)
+
+	for _, item := range BuildStatementLineItems(nil, MeteredUsage{CallSeconds: 120}) {
+		assert.Equal(t, int64(0), item.Amount)
+	}
+}
+
+func TestGenerateMonthlyStatement(t *testing.T) {
+	db := setupBillingPlanTestDB(t)
+
+	plan := &BillingPlan{Code: "pro", Name: "Pro", Currency: "CNY", MonthlyFee: 9900, Enabled: true,
+		IncludedCallMinutes: 1, CallPricePerMinute: 10, TTSPricePer1KChars: 20}
+	require.NoError(t, db.Create(plan).Error)
+	_, err := SubscribeBillingPlan(db, 1, plan.ID)
+	require.NoError(t, err)
+
+	usageTime := time.Date(2026, 9, 10, 12, 0, 0, 0, time.Local)
+	records := []UsageRecord{
+		{UserID: 1, CredentialID: 1, UsageType: UsageTypeCall, CallDuration: 150, UsageTime: usageTime},
+		{UserID: 1, CredentialID: 2, UsageType: UsageTypeTTS, TTSCharacters: 2000, UsageTime: usageTime},
+		{UserID: 1, CredentialID: 2, UsageType: UsageTypeASR, AudioDuration: 30, UsageTime: usageTime},
+		// 其他账期与其他用户的用量不计入
+		{UserID: 1, CredentialID: 1, UsageType: UsageTypeCall, CallDuration: 600, UsageTime: usageTime.AddDate(0, 1, 0)},
+		{UserID: 2, CredentialID: 3, UsageType: UsageTypeCall, CallDuration: 600, UsageTime: usageTime},
+	}
+	require.NoError(t, db.Create(&records).Error)
+
+	statement, err := GenerateMonthlyStatement(db, 1, "2026-09")
+	require.NoError(t, err)
+	assert.Equal(t, "INV-202609-000001", statement.InvoiceNo)
+	assert.Equal(t, StatementStatusFinalized, statement.Status)
+	assert.Equal(t, "Pro", statement.PlanName)
+
+	// 通话 3 分钟，超额 2 分钟 * 10 分；TTS 2000 字符 * 20 分 / 1000
+	assert.Equal(t, int64(60), statement.OverageAmount)
+	assert.Equal(t, int64(9960), statement.TotalAmount)
+
+	credentials := statement.GetCredentialUsage()
+	require.Len(t, credentials, 2)
+	assert.Equal(t, int64(150), credentials[0].CallSeconds)
+	assert.Equal(t, int64(2000), credentials[1].TTSCharacters)
+	assert.Equal(t, int64(30), credentials[1].ASRSeconds)
+
+	// 定稿后的账单不再重新计算
+	require.NoError(t, db.Create(&UsageRecord{UserID: 1, CredentialID: 1, UsageType: UsageTypeCall, CallDuration: 600, UsageTime: usageTime}).Error)
+	again, err := GenerateMonthlyStatement(db, 1, "2026-09")
+	require.NoError(t, err)
+	assert.Equal(t, statement.ID, again.ID)
+	assert.Equal(t, int64(9960), again.TotalAmount)
+
+	_, err = GenerateMonthlyStatement(db, 1, "2026/09")
+	assert.Error(t, err)
+}
+
+func TestCheckPlanQuota(t *testing.T) {
+	db := setupBillingPlanTestDB(t)
+
+	// 没有套餐时不限制
+	assert.NoError(t, CheckPlanQuota(db, 1))
+
+	plan := &BillingPlan{Code: "free", Name: "Free", Enabled: true, IsDefault: true, IncludedTTSCharacters: 100, HardLimit: true}
+	require.NoError(t, db.Create(plan).Error)
+	assert.NoError(t, CheckPlanQuota(db, 1))
+
+	require.NoError(t, db.Create(&UsageRecord{UserID: 1, CredentialID: 1, UsageType: UsageTypeTTS, TTSCharacters: 100, UsageTime: time.Now()}).Error)
+	assert.ErrorIs(t, CheckPlanQuota(db, 1), ErrPlanQuotaExceeded)
+
+	// 超额计费的套餐不拒绝
+	require.NoError(t, db.Model(plan).Update("hard_limit", false).Error)
+	assert.NoError(t, CheckPlanQuota(db, 1))
+}
+
+func TestRecordUsageEvent(t *testing.T) {
+	db := setupBillingPlanTestDB(t)
+
+	require.NoError(t, RecordUsageEvent(db, &UsageEvent{UsageType: UsageTypeTTS, UserID: 1, CredentialID: 2, Duration: 3, Characters: 42}))
+	require.NoError(t, RecordUsageEvent(db, &UsageEvent{UsageType: UsageTypeCall, UserID: 1, CredentialID: 2, Duration: 90}))
+	assert.Error(t, RecordUsageEvent(db, &UsageEvent{UsageType: UsageTypeStorage, UserID: 1}))
+
+	usage, err := GetMeteredUsage(db, 1, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, uint(2), usage[0].CredentialID)
+	assert.Equal(t, int64(42), usage[0].TTSCharacters)
+	assert.Equal(t, int64(90), usage[0].CallSeconds)
+}
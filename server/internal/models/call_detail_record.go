@@ -0,0 +1,211 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SigCallDetailRecord 通话结束事件，由话单监听器写入 CallDetailRecord
+// sender: *CallDetailRecord
+const SigCallDetailRecord = "call.detail_record"
+
+// CallChannel 通话接入渠道
+type CallChannel string
+
+const (
+	CallChannelWebRTC CallChannel = "webrtc" // 浏览器/客户端 WebRTC 通话
+	CallChannelSIP    CallChannel = "sip"    // SIP 电话
+)
+
+// CallDisposition 通话结果
+type CallDisposition string
+
+const (
+	CallDispositionAnswered  CallDisposition = "answered"  // 已接通
+	CallDispositionNoAnswer  CallDisposition = "no_answer" // 无人接听
+	CallDispositionBusy      CallDisposition = "busy"      // 被叫忙
+	CallDispositionFailed    CallDisposition = "failed"    // 呼叫失败
+	CallDispositionCancelled CallDisposition = "cancelled" // 接通前取消
+)
+
+// CallDetailRecord 通话详单（CDR），每通电话一条，按 CallID 唯一。
+// SIP 桥接到助手的通话由信令侧（时间、结果）与 AI 会话侧（用量）分别写入同一条记录
+type CallDetailRecord struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	CallID    string           `json:"callId" gorm:"size:128;uniqueIndex;not null"` // WebRTC 会话ID或 SIP Call-ID
+	Channel   CallChannel      `json:"channel" gorm:"size:20;index"`
+	Direction SipCallDirection `json:"direction" gorm:"size:20;index"`
+	Caller    string           `json:"caller" gorm:"size:256"`
+	Callee    string           `json:"callee" gorm:"size:256"`
+	Codec     string           `json:"codec,omitempty" gorm:"size:50"`
+
+	// 归属
+	UserID       uint  `json:"userId" gorm:"index"`
+	CredentialID uint  `json:"credentialId,omitempty" gorm:"index"`
+	AssistantID  *uint `json:"assistantId,omitempty" gorm:"index"`
+
+	// 时间信息
+	StartTime  time.Time  `json:"startTime" gorm:"index"`
+	AnswerTime *time.Time `json:"answerTime,omitempty"`
+	EndTime    *time.Time `json:"endTime,omitempty"`
+	Duration   int        `json:"duration" gorm:"default:0"` // 接通时长（秒）
+
+	// 用量
+	ASRSeconds          int   `json:"asrSeconds" gorm:"default:0"`
+	ASRAudioBytes       int64 `json:"asrAudioBytes" gorm:"default:0"`
+	TTSSeconds          int   `json:"ttsSeconds" gorm:"default:0"`
+	TTSCharacters       int   `json:"ttsCharacters" gorm:"default:0"`
+	LLMTurns            int   `json:"llmTurns" gorm:"default:0"`
+	LLMPromptTokens     int   `json:"llmPromptTokens" gorm:"default:0"`
+	LLMCompletionTokens int   `json:"llmCompletionTokens" gorm:"default:0"`
+	LLMTotalTokens      int   `json:"llmTotalTokens" gorm:"default:0"`
+
+	Disposition CallDisposition `json:"disposition" gorm:"size:20;index"`
+	HangupCause string          `json:"hangupCause,omitempty" gorm:"size:255"`
+}
+
+// TableName 指定表名
+func (CallDetailRecord) TableName() string {
+	return "call_detail_records"
+}
+
+// NewSipCallDetailRecord 根据结束的 SIP 通话记录生成信令侧话单
+func NewSipCallDetailRecord(db *gorm.DB, call *SipCall) *CallDetailRecord {
+	cdr := &CallDetailRecord{
+		CallID:      call.CallID,
+		Channel:     CallChannelSIP,
+		Direction:   call.Direction,
+		Caller:      call.FromURI,
+		Callee:      call.ToURI,
+		StartTime:   call.StartTime,
+		AnswerTime:  call.AnswerTime,
+		EndTime:     call.EndTime,
+		Duration:    call.Duration,
+		Disposition: SipCallDisposition(call),
+		HangupCause: call.ErrorMessage,
+	}
+	if userID, ok := call.CalleeUserID(db); ok {
+		cdr.UserID = userID
+	}
+	if cdr.HangupCause == "" {
+		cdr.HangupCause = string(call.Status)
+	}
+	return cdr
+}
+
+// SipCallDisposition 由 SIP 通话的最终状态推断通话结果
+func SipCallDisposition(call *SipCall) CallDisposition {
+	if call.AnswerTime != nil {
+		return CallDispositionAnswered
+	}
+	switch call.ErrorCode {
+	case 486, 600:
+		return CallDispositionBusy
+	case 408, 480, 487:
+		return CallDispositionNoAnswer
+	}
+	switch call.Status {
+	case SipCallStatusCancelled:
+		return CallDispositionCancelled
+	case SipCallStatusEnded:
+		// 呼入振铃后主叫挂断
+		return CallDispositionNoAnswer
+	}
+	if strings.Contains(strings.ToLower(call.ErrorMessage), "busy") {
+		return CallDispositionBusy
+	}
+	return CallDispositionFailed
+}
+
+// MergeCallDetailRecord 写入话单；同一 CallID 已存在时只覆盖本次非零字段，
+// 信令侧与 AI 会话侧各自写入所掌握的字段
+func MergeCallDetailRecord(db *gorm.DB, cdr *CallDetailRecord) error {
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(cdr)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	return db.Model(&CallDetailRecord{}).Where("call_id = ?", cdr.CallID).
+		Omit("id", "created_at").Updates(cdr).Error
+}
+
+// GetCallDetailRecord 获取用户的单条话单
+func GetCallDetailRecord(db *gorm.DB, userID uint, id uint) (*CallDetailRecord, error) {
+	var cdr CallDetailRecord
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&cdr).Error; err != nil {
+		return nil, err
+	}
+	return &cdr, nil
+}
+
+// callDetailRecordQuery 按筛选参数构建话单查询
+func callDetailRecordQuery(db *gorm.DB, userID uint, params map[string]interface{}) *gorm.DB {
+	query := db.Model(&CallDetailRecord{}).Where("user_id = ?", userID)
+
+	if channel, ok := params["channel"].(CallChannel); ok && channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+	if direction, ok := params["direction"].(SipCallDirection); ok && direction != "" {
+		query = query.Where("direction = ?", direction)
+	}
+	if disposition, ok := params["disposition"].(CallDisposition); ok && disposition != "" {
+		query = query.Where("disposition = ?", disposition)
+	}
+	if credentialID, ok := params["credentialId"].(uint); ok && credentialID > 0 {
+		query = query.Where("credential_id = ?", credentialID)
+	}
+	if assistantID, ok := params["assistantId"].(uint); ok && assistantID > 0 {
+		query = query.Where("assistant_id = ?", assistantID)
+	}
+	if startTime, ok := params["startTime"].(time.Time); ok {
+		query = query.Where("start_time >= ?", startTime)
+	}
+	if endTime, ok := params["endTime"].(time.Time); ok {
+		query = query.Where("start_time <= ?", endTime)
+	}
+	return query
+}
+
+// ListCallDetailRecords 分页获取话单列表
+func ListCallDetailRecords(db *gorm.DB, userID uint, params map[string]interface{}) ([]CallDetailRecord, int64, error) {
+	// 列表查询走只读副本（未配置副本时即主库）
+	query := callDetailRecordQuery(utils.ReadDB(db), userID, params)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := 1
+	size := 20
+	if p, ok := params["page"].(int); ok && p > 0 {
+		page = p
+	}
+	if s, ok := params["size"].(int); ok && s > 0 {
+		size = s
+	}
+
+	var records []CallDetailRecord
+	err := query.Order("start_time DESC").Offset((page - 1) * size).Limit(size).Find(&records).Error
+	return records, total, err
+}
+
+// EachCallDetailRecord 分批遍历符合条件的话单，用于导出
+func EachCallDetailRecord(db *gorm.DB, userID uint, params map[string]interface{}, fn func(*CallDetailRecord) error) error {
+	var batch []CallDetailRecord
+	return callDetailRecordQuery(utils.ReadDB(db), userID, params).
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				if err := fn(&batch[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
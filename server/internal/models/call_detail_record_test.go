@@ -0,0 +1,80 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSipCallDisposition(t *testing.T) {
+	answered := time.Now()
+	assert.Equal(t, CallDispositionAnswered, SipCallDisposition(&SipCall{Status: SipCallStatusEnded, AnswerTime: &answered}))
+	assert.Equal(t, CallDispositionBusy, SipCallDisposition(&SipCall{Status: SipCallStatusFailed, ErrorCode: 486}))
+	assert.Equal(t, CallDispositionBusy, SipCallDisposition(&SipCall{Status: SipCallStatusFailed, ErrorMessage: "486 Busy Here"}))
+	assert.Equal(t, CallDispositionNoAnswer, SipCallDisposition(&SipCall{Status: SipCallStatusFailed, ErrorCode: 408}))
+	assert.Equal(t, CallDispositionNoAnswer, SipCallDisposition(&SipCall{Status: SipCallStatusEnded}))
+	assert.Equal(t, CallDispositionCancelled, SipCallDisposition(&SipCall{Status: SipCallStatusCancelled}))
+	assert.Equal(t, CallDispositionFailed, SipCallDisposition(&SipCall{Status: SipCallStatusFailed}))
+}
+
+func TestMergeCallDetailRecord(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &CallDetailRecord{})
+
+	start := time.Now().Add(-time.Minute)
+	answer := start.Add(5 * time.Second)
+	end := time.Now()
+	assistantID := uint(7)
+
+	// AI 会话侧先写入用量
+	require.NoError(t, MergeCallDetailRecord(db, &CallDetailRecord{
+		CallID:          "call-1",
+		Channel:         CallChannelSIP,
+		UserID:          1,
+		CredentialID:    2,
+		AssistantID:     &assistantID,
+		Codec:           "pcmu",
+		ASRSeconds:      12,
+		TTSCharacters:   80,
+		LLMTurns:        3,
+		LLMTotalTokens:  450,
+		LLMPromptTokens: 300,
+	}))
+	// 信令侧补充时间与结果
+	require.NoError(t, MergeCallDetailRecord(db, &CallDetailRecord{
+		CallID:      "call-1",
+		Channel:     CallChannelSIP,
+		Direction:   SipCallDirectionInbound,
+		Caller:      "sip:1001@a",
+		Callee:      "sip:2001@a",
+		UserID:      1,
+		StartTime:   start,
+		AnswerTime:  &answer,
+		EndTime:     &end,
+		Duration:    55,
+		Disposition: CallDispositionAnswered,
+	}))
+
+	records, total, err := ListCallDetailRecords(db, 1, map[string]interface{}{"channel": CallChannelSIP})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	cdr := records[0]
+	assert.Equal(t, "pcmu", cdr.Codec)
+	assert.Equal(t, 12, cdr.ASRSeconds)
+	assert.Equal(t, 450, cdr.LLMTotalTokens)
+	assert.Equal(t, 55, cdr.Duration)
+	assert.Equal(t, CallDispositionAnswered, cdr.Disposition)
+	assert.Equal(t, "sip:1001@a", cdr.Caller)
+
+	_, total, err = ListCallDetailRecords(db, 2, nil)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+
+	var exported []string
+	require.NoError(t, EachCallDetailRecord(db, 1, map[string]interface{}{"disposition": CallDispositionAnswered}, func(r *CallDetailRecord) error {
+		exported = append(exported, r.CallID)
+		return nil
+	}))
+	assert.Equal(t, []string{"call-1"}, exported)
+}
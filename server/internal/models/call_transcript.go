@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TranscriptSpeaker 转写片段的说话方
+type TranscriptSpeaker string
+
+const (
+	TranscriptSpeakerUser      TranscriptSpeaker = "user"      // 用户（ASR 识别结果）
+	TranscriptSpeakerAssistant TranscriptSpeaker = "assistant" // AI 助手（TTS 播报的文本）
+)
+
+// CallTranscript 通话转写片段，一句话一条，按开始时间排序即为完整对话
+type CallTranscript struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+
+	SessionID   string            `json:"sessionId" gorm:"size:128;index"`
+	UserID      uint              `json:"userId" gorm:"index"`
+	AssistantID *uint             `json:"assistantId,omitempty" gorm:"index"`
+	Speaker     TranscriptSpeaker `json:"speaker" gorm:"size:20"`
+
+	StartMs     int64    `json:"startMs"` // 相对通话开始的偏移（毫秒）
+	EndMs       int64    `json:"endMs"`   // 相对通话开始的偏移（毫秒）
+	Text        string   `json:"text" gorm:"type:text"`
+	Confidence  *float64 `json:"confidence,omitempty"` // 识别置信度（0-1），ASR 未提供时为空
+	Interrupted bool     `json:"interrupted"`          // 助手播报被用户打断
+}
+
+// TableName 指定表名
+func (CallTranscript) TableName() string {
+	return "call_transcripts"
+}
+
+// GetCallTranscript 获取会话的完整转写，按时间顺序
+func GetCallTranscript(db *gorm.DB, sessionID string, userID uint) ([]CallTranscript, error) {
+	var segments []CallTranscript
+	err := db.Where("session_id = ? AND user_id = ?", sessionID, userID).
+		Order("start_ms ASC, id ASC").
+		Find(&segments).Error
+	return segments, err
+}
+
+// DeleteCallTranscript 删除会话的转写，返回删除的片段数
+func DeleteCallTranscript(db *gorm.DB, sessionID string, userID uint) (int64, error) {
+	result := db.Where("session_id = ? AND user_id = ?", sessionID, userID).Delete(&CallTranscript{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallTranscript_TableName(t *testing.T) {
+	assert.Equal(t, "call_transcripts", CallTranscript{}.TableName())
+}
+
+func TestGetCallTranscript(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &CallTranscript{})
+
+	confidence := 0.92
+	segments := []CallTranscript{
+		{SessionID: "s1", UserID: 1, Speaker: TranscriptSpeakerAssistant, StartMs: 2500, EndMs: 4000, Text: "您好，请问有什么可以帮您？", Interrupted: true},
+		{SessionID: "s1", UserID: 1, Speaker: TranscriptSpeakerUser, StartMs: 300, EndMs: 1800, Text: "你好", Confidence: &confidence},
+		{SessionID: "s1", UserID: 2, Speaker: TranscriptSpeakerUser, StartMs: 0, EndMs: 1000, Text: "other user"},
+		{SessionID: "s2", UserID: 1, Speaker: TranscriptSpeakerUser, StartMs: 0, EndMs: 1000, Text: "other session"},
+	}
+	require.NoError(t, db.Create(&segments).Error)
+
+	got, err := GetCallTranscript(db, "s1", 1)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, TranscriptSpeakerUser, got[0].Speaker)
+	require.NotNil(t, got[0].Confidence)
+	assert.InDelta(t, 0.92, *got[0].Confidence, 1e-9)
+	assert.Equal(t, TranscriptSpeakerAssistant, got[1].Speaker)
+	assert.Nil(t, got[1].Confidence)
+	assert.True(t, got[1].Interrupted)
+
+	deleted, err := DeleteCallTranscript(db, "s1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	got, err = GetCallTranscript(db, "s1", 2)
+	require.NoError(t, err)
+	assert.Len(t, got, 1)
+}
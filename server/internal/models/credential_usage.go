@@ -0,0 +1,233 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 凭证用量达到上限后的处理方式
+const (
+	CredentialQuotaReject  = "reject"  // 拒绝请求
+	CredentialQuotaDegrade = "degrade" // 降级：能去掉的环节（如一句话模式的语音合成）不再执行，其余仍拒绝
+)
+
+// ErrCredentialQuotaExceeded 凭证本月用量已达到上限
+var ErrCredentialQuotaExceeded = errors.New("credential quota exceeded")
+
+// SigCredentialQuotaExceeded 凭证某项用量首次达到当月上限，由计费监听器通知用户
+// sender: *CredentialQuotaEvent
+const SigCredentialQuotaExceeded = "billing.credential_quota_exceeded"
+
+// CredentialQuotaEvent 凭证用量达到上限事件
+type CredentialQuotaEvent struct {
+	UserID       uint
+	CredentialID uint
+	Name         string // 凭证名称
+	Meter        MeterType
+	Used         int64 // 用量（原始单位：Token、字符、秒）
+	Limit        int64 // 上限（原始单位）
+	Period       string
+	Action       string
+}
+
+// CredentialQuotaError 凭证某项用量已达到上限
+type CredentialQuotaError struct {
+	Meter  MeterType
+	Used   int64
+	Limit  int64
+	Action string
+}
+
+func (e *CredentialQuotaError) Error() string {
+	return fmt.Sprintf("%s: %s %d/%d", ErrCredentialQuotaExceeded, e.Meter, e.Used, e.Limit)
+}
+
+func (e *CredentialQuotaError) Is(target error) bool {
+	return target == ErrCredentialQuotaExceeded
+}
+
+// Degradable 达到上限后是否按降级处理
+func (e *CredentialQuotaError) Degradable() bool {
+	return e.Action == CredentialQuotaDegrade
+}
+
+// CredentialUsageCounter 凭证每月用量计数，由计费监听器在每次计量时累加
+type CredentialUsageCounter struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	CredentialID  uint      `json:"credentialId" gorm:"uniqueIndex:idx_credential_usage_period"`
+	Period        string    `json:"period" gorm:"size:7;uniqueIndex:idx_credential_usage_period"` // 账期，如 2024-05
+	UserID        uint      `json:"userId" gorm:"index"`
+	LLMTokens     int64     `json:"llmTokens" gorm:"default:0"`
+	TTSCharacters int64     `json:"ttsCharacters" gorm:"default:0"`
+	ASRSeconds    int64     `json:"asrSeconds" gorm:"default:0"`
+	CallSeconds   int64     `json:"callSeconds" gorm:"default:0"` // 实时语音通话（WebRTC、WebSocket 语音）时长
+	UpdatedAt     time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (CredentialUsageCounter) TableName() string {
+	return "credential_usage_counters"
+}
+
+// credentialUsageColumns 计量项对应的计数列
+var credentialUsageColumns = map[MeterType]string{
+	MeterLLMTokens:     "llm_tokens",
+	MeterTTSCharacters: "tts_characters",
+	MeterASRMinutes:    "asr_seconds",
+	MeterCallMinutes:   "call_seconds",
+}
+
+// Used 返回计量项的用量（原始单位：Token、字符、秒）
+func (u *CredentialUsageCounter) Used(meter MeterType) int64 {
+	switch meter {
+	case MeterLLMTokens:
+		return u.LLMTokens
+	case MeterTTSCharacters:
+		return u.TTSCharacters
+	case MeterASRMinutes:
+		return u.ASRSeconds
+	case MeterCallMinutes:
+		return u.CallSeconds
+	}
+	return 0
+}
+
+// UsageLimit 返回计量项的每月上限（与 CredentialUsageCounter.Used 单位相同），0 表示不限制
+func (c *UserCredential) UsageLimit(meter MeterType) int64 {
+	switch meter {
+	case MeterLLMTokens:
+		return c.LLMTokenLimit
+	case MeterTTSCharacters:
+		return c.TTSCharacterLimit
+	case MeterASRMinutes:
+		return c.ASRSecondLimit
+	case MeterCallMinutes:
+		return c.WebRTCMinuteLimit * 60
+	}
+	return 0
+}
+
+// CredentialUsagePeriod 返回 t 所在的账期
+func CredentialUsagePeriod(t time.Time) string {
+	return t.Format(StatementPeriodLayout)
+}
+
+// UsageEventAmount 返回计量事件计入的计量项与用量，不计入凭证用量的事件返回 false
+func UsageEventAmount(event *UsageEvent) (MeterType, int64, bool) {
+	switch event.UsageType {
+	case UsageTypeASR:
+		return MeterASRMinutes, int64(event.Duration), event.Duration > 0
+	case UsageTypeTTS:
+		return MeterTTSCharacters, int64(event.Characters), event.Characters > 0
+	case UsageTypeCall:
+		return MeterCallMinutes, int64(event.Duration), event.Duration > 0
+	}
+	return "", 0, false
+}
+
+// GetCredentialUsage 获取凭证在账期内的用量，没有记录时返回零值
+func GetCredentialUsage(db *gorm.DB, credentialID uint, period string) (*CredentialUsageCounter, error) {
+	var counter CredentialUsageCounter
+	err := db.Where("credential_id = ? AND period = ?", credentialID, period).First(&counter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &CredentialUsageCounter{CredentialID: credentialID, Period: period}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &counter, nil
+}
+
+// AddCredentialUsage 原子地累加凭证当月的用量，返回累加后的计数
+func AddCredentialUsage(db *gorm.DB, userID, credentialID uint, meter MeterType, amount int64, now time.Time) (*CredentialUsageCounter, error) {
+	column, ok := credentialUsageColumns[meter]
+	if !ok {
+		return nil, fmt.Errorf("unsupported meter: %s", meter)
+	}
+	period := CredentialUsagePeriod(now)
+	counter := &CredentialUsageCounter{CredentialID: credentialID, Period: period, UserID: userID}
+	switch meter {
+	case MeterLLMTokens:
+		counter.LLMTokens = amount
+	case MeterTTSCharacters:
+		counter.TTSCharacters = amount
+	case MeterASRMinutes:
+		counter.ASRSeconds = amount
+	case MeterCallMinutes:
+		counter.CallSeconds = amount
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "credential_id"}, {Name: "period"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			column:       gorm.Expr(column+" + ?", amount),
+			"updated_at": now,
+		}),
+	}).Create(counter).Error
+	if err != nil {
+		return nil, err
+	}
+	return GetCredentialUsage(db, credentialID, period)
+}
+
+// CheckCredentialQuota 检查凭证当月的指定计量项是否已达到上限，达到时返回 *CredentialQuotaError
+func CheckCredentialQuota(db *gorm.DB, credential *UserCredential, now time.Time, meters ...MeterType) error {
+	var usage *CredentialUsageCounter
+	for _, meter := range meters {
+		limit := credential.UsageLimit(meter)
+		if limit <= 0 {
+			continue
+		}
+		if usage == nil {
+			var err error
+			if usage, err = GetCredentialUsage(db, credential.ID, CredentialUsagePeriod(now)); err != nil {
+				return err
+			}
+		}
+		if used := usage.Used(meter); used >= limit {
+			return &CredentialQuotaError{Meter: meter, Used: used, Limit: limit, Action: credential.QuotaAction}
+		}
+	}
+	return nil
+}
+
+// CredentialUsageLimits 凭证每月用量上限，0 表示不限制
+type CredentialUsageLimits struct {
+	LLMTokenLimit     int64  `json:"llmTokenLimit"`
+	TTSCharacterLimit int64  `json:"ttsCharacterLimit"`
+	ASRSecondLimit    int64  `json:"asrSecondLimit"`
+	WebRTCMinuteLimit int64  `json:"webrtcMinuteLimit"`
+	QuotaAction       string `json:"quotaAction"` // reject 或 degrade，为空时为 reject
+}
+
+// UpdateUserCredentialUsageLimits 更新凭证的每月用量上限
+func UpdateUserCredentialUsageLimits(db *gorm.DB, userID, credentialID uint, limits CredentialUsageLimits) error {
+	if limits.LLMTokenLimit < 0 || limits.TTSCharacterLimit < 0 || limits.ASRSecondLimit < 0 || limits.WebRTCMinuteLimit < 0 {
+		return errors.New("usage limits must not be negative")
+	}
+	switch limits.QuotaAction {
+	case "":
+		limits.QuotaAction = CredentialQuotaReject
+	case CredentialQuotaReject, CredentialQuotaDegrade:
+	default:
+		return fmt.Errorf("unknown quota action: %s", limits.QuotaAction)
+	}
+	result := db.Model(&UserCredential{}).
+		Where("id = ? AND user_id = ?", credentialID, userID).
+		Updates(map[string]any{
+			"llm_token_limit":      limits.LLMTokenLimit,
+			"tts_character_limit":  limits.TTSCharacterLimit,
+			"asr_second_limit":     limits.ASRSecondLimit,
+			"web_rtc_minute_limit": limits.WebRTCMinuteLimit,
+			"quota_action":         limits.QuotaAction,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("credential not found or access denied")
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddCredentialUsage_Accumulates(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &CredentialUsageCounter{})
+	may := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+
+	_, err := AddCredentialUsage(db, 1, 7, MeterLLMTokens, 100, may)
+	require.NoError(t, err)
+	counter, err := AddCredentialUsage(db, 1, 7, MeterLLMTokens, 50, may)
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), counter.LLMTokens)
+	assert.Equal(t, "2024-05", counter.Period)
+
+	counter, err = AddCredentialUsage(db, 1, 7, MeterTTSCharacters, 30, may)
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), counter.LLMTokens)
+	assert.Equal(t, int64(30), counter.TTSCharacters)
+
+	// 新账期重新计数
+	counter, err = AddCredentialUsage(db, 1, 7, MeterLLMTokens, 5, may.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), counter.LLMTokens)
+
+	_, err = AddCredentialUsage(db, 1, 7, MeterType("storage"), 1, may)
+	assert.Error(t, err)
+}
+
+func TestCheckCredentialQuota(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &CredentialUsageCounter{})
+	now := time.Now()
+	credential := &UserCredential{ID: 7, UserID: 1, LLMTokenLimit: 100, WebRTCMinuteLimit: 1, QuotaAction: CredentialQuotaDegrade}
+
+	assert.NoError(t, CheckCredentialQuota(db, credential, now, MeterLLMTokens, MeterCallMinutes, MeterTTSCharacters))
+
+	_, err := AddCredentialUsage(db, 1, 7, MeterCallMinutes, 59, now)
+	require.NoError(t, err)
+	assert.NoError(t, CheckCredentialQuota(db, credential, now, MeterCallMinutes))
+	_, err = AddCredentialUsage(db, 1, 7, MeterCallMinutes, 1, now)
+	require.NoError(t, err)
+
+	err = CheckCredentialQuota(db, credential, now, MeterLLMTokens, MeterCallMinutes)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrCredentialQuotaExceeded))
+	var quotaErr *CredentialQuotaError
+	require.True(t, errors.As(err, &quotaErr))
+	assert.Equal(t, MeterCallMinutes, quotaErr.Meter)
+	assert.Equal(t, int64(60), quotaErr.Limit)
+	assert.True(t, quotaErr.Degradable())
+
+	// 未设置上限的计量项不受限制
+	_, err = AddCredentialUsage(db, 1, 7, MeterTTSCharacters, 1_000_000, now)
+	require.NoError(t, err)
+	assert.NoError(t, CheckCredentialQuota(db, credential, now, MeterTTSCharacters))
+}
+
+func TestUsageEventAmount(t *testing.T) {
+	meter, amount, ok := UsageEventAmount(&UsageEvent{UsageType: UsageTypeTTS, Characters: 12})
+	assert.True(t, ok)
+	assert.Equal(t, MeterTTSCharacters, meter)
+	assert.Equal(t, int64(12), amount)
+
+	meter, amount, ok = UsageEventAmount(&UsageEvent{UsageType: UsageTypeCall, Duration: 90})
+	assert.True(t, ok)
+	assert.Equal(t, MeterCallMinutes, meter)
+	assert.Equal(t, int64(90), amount)
+
+	_, _, ok = UsageEventAmount(&UsageEvent{UsageType: UsageTypeASR})
+	assert.False(t, ok)
+	_, _, ok = UsageEventAmount(&UsageEvent{UsageType: UsageTypeLLM, Duration: 3})
+	assert.False(t, ok)
+}
+
+func TestUpdateUserCredentialUsageLimits(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &UserCredential{})
+	credential := &UserCredential{UserID: 1, Name: "app", APIKey: "key", APISecret: "secret"}
+	require.NoError(t, db.Create(credential).Error)
+
+	limits := CredentialUsageLimits{LLMTokenLimit: 1000, WebRTCMinuteLimit: 30, QuotaAction: CredentialQuotaDegrade}
+	require.NoError(t, UpdateUserCredentialUsageLimits(db, 1, credential.ID, limits))
+
+	var saved UserCredential
+	require.NoError(t, db.First(&saved, credential.ID).Error)
+	assert.Equal(t, int64(1000), saved.LLMTokenLimit)
+	assert.Equal(t, int64(30), saved.WebRTCMinuteLimit)
+	assert.Equal(t, CredentialQuotaDegrade, saved.QuotaAction)
+
+	require.NoError(t, UpdateUserCredentialUsageLimits(db, 1, credential.ID, CredentialUsageLimits{}))
+	require.NoError(t, db.First(&saved, credential.ID).Error)
+	assert.Equal(t, CredentialQuotaReject, saved.QuotaAction)
+
+	assert.Error(t, UpdateUserCredentialUsageLimits(db, 1, credential.ID, CredentialUsageLimits{TTSCharacterLimit: -1}))
+	assert.Error(t, UpdateUserCredentialUsageLimits(db, 1, credential.ID, CredentialUsageLimits{QuotaAction: "ignore"}))
+	assert.Error(t, UpdateUserCredentialUsageLimits(db, 2, credential.ID, limits))
+}
@@ -18,6 +18,10 @@ type UserCredentialRequest struct {
 	// JSON格式配置
 	AsrConfig ProviderConfig `json:"asrConfig"` // ASR配置,格式: {"provider": "qiniu", "apiKey": "...", "baseUrl": "..."} 或 {"provider": "qcloud", "appId": "...", "secretId": "...", "secretKey": "..."}
 	TtsConfig ProviderConfig `json:"ttsConfig"` // TTS配置
+
+	MCPScopes string `json:"mcpScopes"` // MCP调用范围，如 "tool:search_*,agent:rag_agent"，为空时不限制
+
+	StorageQuota int64 `json:"storageQuota"` // 存储空间上限（字节），0 表示不限制
 }
 
 // BuildASRConfig 从请求中构建ASR配置
@@ -63,15 +67,17 @@ func CreateUserCredential(db *gorm.DB, userID uint, credential *UserCredentialRe
 	ttsConfig := credential.BuildTTSConfig()
 
 	userCred := &UserCredential{
-		UserID:      userID,
-		APIKey:      apiKey,
-		APISecret:   apiSecret,
-		Name:        credential.Name,
-		LLMProvider: credential.LLMProvider,
-		LLMApiKey:   credential.LLMApiKey,
-		LLMApiURL:   credential.LLMApiURL,
-		AsrConfig:   asrConfig,
-		TtsConfig:   ttsConfig,
+		UserID:       userID,
+		APIKey:       apiKey,
+		APISecret:    apiSecret,
+		Name:         credential.Name,
+		LLMProvider:  credential.LLMProvider,
+		LLMApiKey:    credential.LLMApiKey,
+		LLMApiURL:    credential.LLMApiURL,
+		AsrConfig:    asrConfig,
+		TtsConfig:    ttsConfig,
+		MCPScopes:    credential.MCPScopes,
+		StorageQuota: credential.StorageQuota,
 	}
 
 	err = db.Create(userCred).Error
@@ -106,6 +112,49 @@ func GetUserCredentialByApiSecretAndApiKey(db *gorm.DB, apiKey, apiSecret string
 	return &credential, nil
 }
 
+// GetUserCredentialByID 获取属于该用户的凭证，不存在时返回 nil
+func GetUserCredentialByID(db *gorm.DB, userID, credentialID uint) (*UserCredential, error) {
+	var credential UserCredential
+	result := db.Where("id = ? AND user_id = ?", credentialID, userID).First(&credential)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &credential, nil
+}
+
+// UpdateUserCredentialMCPScopes 更新凭证的MCP调用范围
+func UpdateUserCredentialMCPScopes(db *gorm.DB, userID, credentialID uint, scopes string) error {
+	result := db.Model(&UserCredential{}).
+		Where("id = ? AND user_id = ?", credentialID, userID).
+		Update("mcp_scopes", scopes)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("credential not found or access denied")
+	}
+	return nil
+}
+
+// UpdateUserCredentialStorageQuota 更新凭证的存储空间上限（字节），0 表示不限制
+func UpdateUserCredentialStorageQuota(db *gorm.DB, userID, credentialID uint, quota int64) error {
+	result := db.Model(&UserCredential{}).
+		Where("id = ? AND user_id = ?", credentialID, userID).
+		Update("storage_quota", quota)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("credential not found or access denied")
+	}
+	return nil
+}
+
 // CheckAndReserveCredits 原子性校验并预占额度（可选）。need 为需要的额度。
 func CheckAndReserveCredits(db *gorm.DB, credentialID uint, need int64) (*UserCredential, error) {
 	var cred UserCredential
@@ -209,6 +209,28 @@ func TestGetUserCredentialByApiSecretAndApiKey(t *testing.T) {
 	assert.Nil(t, retrieved)
 }
 
+func TestGetUserCredentialByID(t *testing.T) {
+	db := setupCredentialsTestDB(t)
+
+	user, err := CreateUser(db, "test@example.com", "password123")
+	require.NoError(t, err)
+	other, err := CreateUser(db, "other@example.com", "password123")
+	require.NoError(t, err)
+
+	cred, err := CreateUserCredential(db, user.ID, &UserCredentialRequest{Name: "Test App"})
+	require.NoError(t, err)
+
+	retrieved, err := GetUserCredentialByID(db, user.ID, cred.ID)
+	require.NoError(t, err)
+	require.NotNil(t, retrieved)
+	assert.Equal(t, cred.APIKey, retrieved.APIKey)
+
+	// Credentials of other users are not visible
+	retrieved, err = GetUserCredentialByID(db, other.ID, cred.ID)
+	require.NoError(t, err)
+	assert.Nil(t, retrieved)
+}
+
 func TestCheckAndReserveCredits(t *testing.T) {
 	db := setupCredentialsTestDB(t)
 
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -238,34 +239,45 @@ func GetKnowledgeBaseInfoWithQuery(db *gorm.DB, knowledgeKey string, query strin
 	return messages, nil
 }
 
-// SearchKnowledgeBase 搜索知识库并返回结构化结果
-func SearchKnowledgeBase(db *gorm.DB, knowledgeKey string, query string, topK int) ([]knowledge.SearchResult, error) {
-	// 1. 从数据库获取知识库信息
+// OpenKnowledgeBase 按数据库中保存的 provider 与配置创建知识库实例，同时返回知识库记录与配置
+func OpenKnowledgeBase(db *gorm.DB, knowledgeKey string) (*Knowledge, knowledge.KnowledgeBase, map[string]interface{}, error) {
 	k, err := GetKnowledge(db, knowledgeKey)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
-	// 2. 解析配置信息
 	var config map[string]interface{}
 	if k.Config != "" {
 		if err := json.Unmarshal([]byte(k.Config), &config); err != nil {
-			return nil, fmt.Errorf("解析配置失败: %w", err)
+			return nil, nil, nil, fmt.Errorf("解析配置失败: %w", err)
 		}
 	}
 
-	// 3. 获取知识库实例
 	kb, err := knowledge.GetKnowledgeBaseByProvider(k.Provider, config)
 	if err != nil {
-		return nil, fmt.Errorf("创建知识库实例失败: %w", err)
+		return nil, nil, nil, fmt.Errorf("创建知识库实例失败: %w", err)
 	}
+	return k, kb, config, nil
+}
 
-	// 4. 执行检索
+// SearchKnowledgeBase 搜索知识库并返回结构化结果
+func SearchKnowledgeBase(db *gorm.DB, knowledgeKey string, query string, topK int) ([]knowledge.SearchResult, error) {
+	// 1. 获取知识库实例
+	_, kb, config, err := OpenKnowledgeBase(db, knowledgeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 按知识库配置的检索方式（向量/混合检索、重排序）执行检索
+	retriever, err := knowledge.NewRetriever(kb, config)
+	if err != nil {
+		return nil, fmt.Errorf("创建检索器失败: %w", err)
+	}
 	options := knowledge.SearchOptions{
 		Query: query,
 		TopK:  topK,
 	}
-	return kb.Search(nil, knowledgeKey, options)
+	return retriever.Search(context.Background(), knowledgeKey, options)
 }
 
 // GetStringOrDefault returns default value if string is empty
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/connector"
+	"gorm.io/gorm"
+)
+
+// 同步状态
+const (
+	KnowledgeSyncStatusIdle    = "idle"    // 尚未同步
+	KnowledgeSyncStatusRunning = "running" // 同步中
+	KnowledgeSyncStatusSuccess = "success" // 上次同步完成（个别文档失败时见 LastReport）
+	KnowledgeSyncStatusFailed  = "failed"  // 上次同步失败，原因见 LastError
+)
+
+// DefaultKnowledgeSyncInterval 同步源默认的同步间隔（分钟）
+const DefaultKnowledgeSyncInterval = 360
+
+// KnowledgeSyncSource 知识库同步源：定时从 S3 前缀、网页列表/站点地图或 Notion 导出拉取文档，
+// 按校验和检测变更并增量更新知识库
+type KnowledgeSyncSource struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	UserID       uint   `json:"userId" gorm:"index;not null"`
+	KnowledgeKey string `json:"knowledgeKey" gorm:"size:128;index;not null"`
+	Name         string `json:"name" gorm:"size:128;not null"`
+	Type         string `json:"type" gorm:"size:20;not null"` // s3、web、notion
+	Config       string `json:"-" gorm:"type:text"`           // 连接配置（JSON），含密钥，不返回给前端
+
+	Interval int  `json:"interval"` // 同步间隔（分钟）
+	Enabled  bool `json:"enabled" gorm:"default:true"`
+
+	// 分块方式，为空时使用默认值
+	ChunkStrategy string `json:"chunkStrategy,omitempty" gorm:"size:20"`
+	ChunkSize     int    `json:"chunkSize,omitempty"`
+	ChunkOverlap  int    `json:"chunkOverlap,omitempty"`
+
+	LastSyncAt *time.Time `json:"lastSyncAt,omitempty"`
+	LastStatus string     `json:"lastStatus" gorm:"size:20;default:idle"`
+	LastError  string     `json:"lastError,omitempty" gorm:"type:text"`
+	LastReport string     `json:"lastReport,omitempty" gorm:"type:text"` // 上次同步的 connector.Report（JSON）
+}
+
+// TableName 指定表名
+func (KnowledgeSyncSource) TableName() string {
+	return "knowledge_sync_sources"
+}
+
+// KnowledgeSyncDocument 同步源中一个文档上次同步的版本
+type KnowledgeSyncDocument struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	SourceID   uint      `json:"sourceId" gorm:"uniqueIndex:idx_knowledge_sync_doc;not null"`
+	URI        string    `json:"uri" gorm:"size:512;uniqueIndex:idx_knowledge_sync_doc;not null"` // 对象键、网址或导出内路径
+	DocumentID string    `json:"documentId" gorm:"size:64"`
+	Checksum   string    `json:"checksum" gorm:"size:64"`
+	Chunks     int       `json:"chunks"`
+	SyncedAt   time.Time `json:"syncedAt"`
+}
+
+// TableName 指定表名
+func (KnowledgeSyncDocument) TableName() string {
+	return "knowledge_sync_documents"
+}
+
+// ApplyDefaults 补全未设置的同步间隔
+func (s *KnowledgeSyncSource) ApplyDefaults() {
+	if s.Interval <= 0 {
+		s.Interval = DefaultKnowledgeSyncInterval
+	}
+	if s.LastStatus == "" {
+		s.LastStatus = KnowledgeSyncStatusIdle
+	}
+}
+
+// ConfigMap 解析连接配置
+func (s *KnowledgeSyncSource) ConfigMap() (map[string]interface{}, error) {
+	return ParseKnowledgeConfig(s.Config)
+}
+
+// Due 是否到了同步时间
+func (s *KnowledgeSyncSource) Due(now time.Time) bool {
+	if !s.Enabled || s.LastStatus == KnowledgeSyncStatusRunning {
+		return false
+	}
+	if s.LastSyncAt == nil {
+		return true
+	}
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultKnowledgeSyncInterval
+	}
+	return !now.Before(s.LastSyncAt.Add(time.Duration(interval) * time.Minute))
+}
+
+// GetDueKnowledgeSyncSources 查询到了同步时间的同步源
+func GetDueKnowledgeSyncSources(db *gorm.DB, now time.Time) ([]KnowledgeSyncSource, error) {
+	var sources []KnowledgeSyncSource
+	if err := db.Where("enabled = ? AND last_status <> ?", true, KnowledgeSyncStatusRunning).Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	due := sources[:0]
+	for _, source := range sources {
+		if source.Due(now) {
+			due = append(due, source)
+		}
+	}
+	return due, nil
+}
+
+// ResetRunningKnowledgeSyncSources 把服务重启前未完成的同步标记为失败，使其按间隔重新同步
+func ResetRunningKnowledgeSyncSources(db *gorm.DB) error {
+	return db.Model(&KnowledgeSyncSource{}).
+		Where("last_status = ?", KnowledgeSyncStatusRunning).
+		Updates(map[string]interface{}{"last_status": KnowledgeSyncStatusFailed, "last_error": "interrupted by restart"}).Error
+}
+
+// DeleteKnowledgeSyncSource 删除同步源及其文档同步记录（已入库的分片保留）
+func DeleteKnowledgeSyncSource(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_id = ?", id).Delete(&KnowledgeSyncDocument{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&KnowledgeSyncSource{}, id).Error
+	})
+}
+
+// knowledgeSyncStateStore 以 KnowledgeSyncDocument 表保存一个同步源的文档版本
+type knowledgeSyncStateStore struct {
+	db       *gorm.DB
+	sourceID uint
+}
+
+// NewKnowledgeSyncStateStore 返回同步源的文档版本存储
+func NewKnowledgeSyncStateStore(db *gorm.DB, sourceID uint) connector.StateStore {
+	return &knowledgeSyncStateStore{db: db, sourceID: sourceID}
+}
+
+func (s *knowledgeSyncStateStore) Load(ctx context.Context) (map[string]connector.State, error) {
+	var docs []KnowledgeSyncDocument
+	if err := s.db.WithContext(ctx).Where("source_id = ?", s.sourceID).Find(&docs).Error; err != nil {
+		return nil, err
+	}
+	states := make(map[string]connector.State, len(docs))
+	for _, doc := range docs {
+		states[doc.URI] = connector.State{URI: doc.URI, DocumentID: doc.DocumentID, Checksum: doc.Checksum, Chunks: doc.Chunks}
+	}
+	return states, nil
+}
+
+func (s *knowledgeSyncStateStore) Save(ctx context.Context, state connector.State) error {
+	db := s.db.WithContext(ctx)
+	var doc KnowledgeSyncDocument
+	err := db.Where("source_id = ? AND uri = ?", s.sourceID, state.URI).First(&doc).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	doc.SourceID = s.sourceID
+	doc.URI = state.URI
+	doc.DocumentID = state.DocumentID
+	doc.Checksum = state.Checksum
+	doc.Chunks = state.Chunks
+	doc.SyncedAt = time.Now()
+	return db.Save(&doc).Error
+}
+
+func (s *knowledgeSyncStateStore) Delete(ctx context.Context, uri string) error {
+	return s.db.WithContext(ctx).Where("source_id = ? AND uri = ?", s.sourceID, uri).Delete(&KnowledgeSyncDocument{}).Error
+}
+
+// FinishKnowledgeSync 记录一次同步的结果
+func FinishKnowledgeSync(db *gorm.DB, source *KnowledgeSyncSource, report connector.Report, syncErr error) error {
+	now := time.Now()
+	reportJSON, _ := json.Marshal(report)
+	updates := map[string]interface{}{
+		"last_sync_at": now,
+		"last_status":  KnowledgeSyncStatusSuccess,
+		"last_error":   "",
+		"last_report":  string(reportJSON),
+	}
+	if syncErr != nil {
+		updates["last_status"] = KnowledgeSyncStatusFailed
+		updates["last_error"] = syncErr.Error()
+	}
+	source.LastSyncAt = &now
+	source.LastStatus = updates["last_status"].(string)
+	return db.Model(source).Updates(updates).Error
+}
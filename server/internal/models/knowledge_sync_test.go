@@ -0,0 +1,26 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKnowledgeSyncSource_Due(t *testing.T) {
+	now := time.Now()
+	s := &KnowledgeSyncSource{Enabled: true, Interval: 60}
+	assert.True(t, s.Due(now), "never synced")
+
+	last := now.Add(-30 * time.Minute)
+	s.LastSyncAt = &last
+	assert.False(t, s.Due(now))
+	assert.True(t, s.Due(now.Add(30*time.Minute)))
+
+	s.LastStatus = KnowledgeSyncStatusRunning
+	assert.False(t, s.Due(now.Add(time.Hour)))
+
+	s.LastStatus = KnowledgeSyncStatusFailed
+	s.Enabled = false
+	assert.False(t, s.Due(now.Add(time.Hour)))
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Lease 多副本部署下的分布式租约，用于选主和只需单实例执行的任务
+type Lease struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:128"`
+	Holder    string    `json:"holder" gorm:"size:128"` // 持有者标识（主机名-进程号）
+	ExpiresAt time.Time `json:"expiresAt" gorm:"index"` // 到期未续约则可被其他实例抢占
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// TryAcquireLease 获取或续约租约：租约不存在、已过期或本就由 holder 持有时成功
+func TryAcquireLease(db *gorm.DB, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	result := db.Model(&Lease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": now.Add(ttl)})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+	// 首次获取：并发插入时只有一个实例成功
+	result = db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseLease 主动释放 holder 持有的租约，其他实例无需等待过期即可接管
+func ReleaseLease(db *gorm.DB, name, holder string) error {
+	return db.Model(&Lease{}).
+		Where("name = ? AND holder = ?", name, holder).
+		Update("expires_at", time.Time{}).Error
+}
+
+// LeaseHolderID 本节点的租约持有者标识：配置的 NODE_ID，未配置时为主机名-进程号
+func LeaseHolderID() string {
+	if config.GlobalConfig != nil && config.GlobalConfig.NodeID != "" {
+		return config.GlobalConfig.NodeID
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "node"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryAcquireLease(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &Lease{})
+
+	ok, err := TryAcquireLease(db, "scheduler", "node-a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 未过期时其他实例无法抢占，持有者可以续约
+	ok, err = TryAcquireLease(db, "scheduler", "node-b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = TryAcquireLease(db, "scheduler", "node-a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 释放后立即可被接管
+	require.NoError(t, ReleaseLease(db, "scheduler", "node-a"))
+	ok, err = TryAcquireLease(db, "scheduler", "node-b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	var lease Lease
+	require.NoError(t, db.First(&lease, "name = ?", "scheduler").Error)
+	assert.Equal(t, "node-b", lease.Holder)
+}
+
+func TestTryAcquireLease_Expired(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &Lease{})
+	require.NoError(t, db.Create(&Lease{Name: "scheduler", Holder: "node-a", ExpiresAt: time.Now().Add(-time.Second)}).Error)
+
+	ok, err := TryAcquireLease(db, "scheduler", "node-b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
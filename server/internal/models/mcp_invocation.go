@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MCPInvocation MCP工具调用审计记录
+type MCPInvocation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime;index"`
+
+	UserID       uint   `json:"userId" gorm:"index"`
+	CredentialID uint   `json:"credentialId" gorm:"index"`
+	Tool         string `json:"tool" gorm:"size:128;index"`
+	Arguments    string `json:"arguments" gorm:"type:text"` // JSON，超长时截断
+
+	Success    bool   `json:"success"`
+	Denied     bool   `json:"denied"` // 超出凭证的调用范围被拒绝
+	Error      string `json:"error,omitempty" gorm:"type:text"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// TableName 指定表名
+func (MCPInvocation) TableName() string {
+	return "mcp_invocations"
+}
+
+// CreateMCPInvocation 记录一次MCP工具调用
+func CreateMCPInvocation(db *gorm.DB, invocation *MCPInvocation) error {
+	return db.Create(invocation).Error
+}
+
+// GetMCPInvocations 获取凭证最近的MCP调用记录，按时间倒序
+func GetMCPInvocations(db *gorm.DB, userID, credentialID uint, limit int) ([]MCPInvocation, error) {
+	var invocations []MCPInvocation
+	err := db.Where("user_id = ? AND credential_id = ?", userID, credentialID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&invocations).Error
+	return invocations, err
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPInvocation_TableName(t *testing.T) {
+	assert.Equal(t, "mcp_invocations", MCPInvocation{}.TableName())
+}
+
+func TestGetMCPInvocations(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &MCPInvocation{})
+
+	require.NoError(t, CreateMCPInvocation(db, &MCPInvocation{UserID: 1, CredentialID: 10, Tool: "search_knowledge", Success: true, DurationMs: 12}))
+	require.NoError(t, CreateMCPInvocation(db, &MCPInvocation{UserID: 1, CredentialID: 10, Tool: "run_agents", Denied: true, Error: "scope denied"}))
+	require.NoError(t, CreateMCPInvocation(db, &MCPInvocation{UserID: 1, CredentialID: 11, Tool: "list_agents", Success: true}))
+	require.NoError(t, CreateMCPInvocation(db, &MCPInvocation{UserID: 2, CredentialID: 10, Tool: "list_agents", Success: true}))
+
+	got, err := GetMCPInvocations(db, 1, 10, 10)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "run_agents", got[0].Tool)
+	assert.True(t, got[0].Denied)
+	assert.Equal(t, "search_knowledge", got[1].Tool)
+	assert.Equal(t, int64(12), got[1].DurationMs)
+
+	got, err = GetMCPInvocations(db, 1, 10, 1)
+	require.NoError(t, err)
+	assert.Len(t, got, 1)
+}
+
+func TestUpdateUserCredentialMCPScopes(t *testing.T) {
+	db := setupCredentialsTestDB(t)
+
+	credential, err := CreateUserCredential(db, 1, &UserCredentialRequest{Name: "mcp", MCPScopes: "tool:list_agents"})
+	require.NoError(t, err)
+	assert.Equal(t, "tool:list_agents", credential.MCPScopes)
+
+	require.NoError(t, UpdateUserCredentialMCPScopes(db, 1, credential.ID, "tool:search_*,agent:rag_agent"))
+	updated, err := GetUserCredentialByID(db, 1, credential.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "tool:search_*,agent:rag_agent", updated.MCPScopes)
+
+	assert.Error(t, UpdateUserCredentialMCPScopes(db, 2, credential.ID, ""))
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MCP 动态工具的认证方式
+const (
+	MCPToolAuthNone   = "none"   // 不认证
+	MCPToolAuthBearer = "bearer" // Authorization: Bearer <AuthSecret>
+	MCPToolAuthBasic  = "basic"  // AuthSecret 为 user:password
+	MCPToolAuthHeader = "header" // AuthHeader: <AuthSecret>
+)
+
+// MCPTool 管理员在运行时定义的 HTTP 工具，MCP 服务器无需重新部署即可对外提供
+type MCPTool struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"size:64;uniqueIndex"`
+	Description string `json:"description" gorm:"type:text"`
+	InputSchema string `json:"inputSchema" gorm:"type:text"` // 参数定义（JSON Schema）
+
+	Method         string `json:"method" gorm:"size:10"` // GET/POST/PUT/PATCH/DELETE
+	Endpoint       string `json:"endpoint" gorm:"type:text"`
+	Headers        string `json:"headers,omitempty" gorm:"type:text"` // 附加请求头（JSON 对象）
+	TimeoutSeconds int    `json:"timeoutSeconds"`
+
+	AuthType   string `json:"authType" gorm:"size:20"`
+	AuthHeader string `json:"authHeader,omitempty" gorm:"size:100"` // AuthType 为 header 时的请求头名
+	AuthSecret string `json:"-" gorm:"type:text"`                   // 不在接口中返回
+
+	Enabled   bool      `json:"enabled"`
+	CreatedBy uint      `json:"createdBy" gorm:"index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (MCPTool) TableName() string {
+	return "mcp_tools"
+}
+
+// ListMCPTools 获取所有动态工具，按名称排序
+func ListMCPTools(db *gorm.DB) ([]MCPTool, error) {
+	var tools []MCPTool
+	err := db.Order("name ASC").Find(&tools).Error
+	return tools, err
+}
+
+// ListEnabledMCPTools 获取启用的动态工具
+func ListEnabledMCPTools(db *gorm.DB) ([]MCPTool, error) {
+	var tools []MCPTool
+	err := db.Where("enabled = ?", true).Order("name ASC").Find(&tools).Error
+	return tools, err
+}
+
+// GetMCPTool 根据ID获取动态工具
+func GetMCPTool(db *gorm.DB, id uint) (*MCPTool, error) {
+	var tool MCPTool
+	if err := db.First(&tool, id).Error; err != nil {
+		return nil, err
+	}
+	return &tool, nil
+}
+
+// SaveMCPTool 创建或更新动态工具
+func SaveMCPTool(db *gorm.DB, tool *MCPTool) error {
+	if tool.ID == 0 {
+		return db.Create(tool).Error
+	}
+	return db.Save(tool).Error
+}
+
+// DeleteMCPTool 删除动态工具
+func DeleteMCPTool(db *gorm.DB, id uint) error {
+	result := db.Delete(&MCPTool{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
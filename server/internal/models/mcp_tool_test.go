@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMCPTool_TableName(t *testing.T) {
+	assert.Equal(t, "mcp_tools", MCPTool{}.TableName())
+}
+
+func TestMCPToolCRUD(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &MCPTool{})
+
+	weather := &MCPTool{Name: "weather", Endpoint: "https://example.com/weather", Method: "GET", Enabled: true}
+	crm := &MCPTool{Name: "crm_lookup", Endpoint: "https://example.com/crm", Method: "POST", Enabled: false}
+	require.NoError(t, SaveMCPTool(db, weather))
+	require.NoError(t, SaveMCPTool(db, crm))
+
+	all, err := ListMCPTools(db)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "crm_lookup", all[0].Name)
+
+	enabled, err := ListEnabledMCPTools(db)
+	require.NoError(t, err)
+	require.Len(t, enabled, 1)
+	assert.Equal(t, "weather", enabled[0].Name)
+
+	crm.Enabled = true
+	require.NoError(t, SaveMCPTool(db, crm))
+	got, err := GetMCPTool(db, crm.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Enabled)
+
+	require.NoError(t, DeleteMCPTool(db, weather.ID))
+	assert.ErrorIs(t, DeleteMCPTool(db, weather.ID), gorm.ErrRecordNotFound)
+	_, err = GetMCPTool(db, weather.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
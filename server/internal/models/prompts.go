@@ -6,6 +6,7 @@ type PromptModel struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	Name        string    `gorm:"uniqueIndex;size:100;not null" json:"name"` // 模板唯一名称
 	Description string    `gorm:"type:text" json:"description"`              // 描述
+	Template    string    `gorm:"type:text" json:"template"`                 // 提示模板，{{参数名}} 替换为参数值，为空时按参数生成示例
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -20,6 +20,7 @@ const (
 	QuotaTypeASRCount     QuotaType = "asr_count"     // 语音识别次数
 	QuotaTypeTTSDuration  QuotaType = "tts_duration"  // 语音合成时长（秒）
 	QuotaTypeTTSCount     QuotaType = "tts_count"     // 语音合成次数
+	QuotaTypeTTSChars     QuotaType = "tts_chars"     // 语音合成字符数
 )
 
 // QuotaPeriod 配额周期
@@ -209,6 +210,14 @@ func calculateUserQuotaUsage(db *gorm.DB, userID uint, quotaType QuotaType) int6
 		db.Model(&UsageRecord{}).
 			Where("user_id = ? AND usage_type = ?", userID, UsageTypeTTS).
 			Count(&used)
+
+	case QuotaTypeTTSChars:
+		var result struct{ Total int64 }
+		db.Model(&UsageRecord{}).
+			Where("user_id = ? AND usage_type = ?", userID, UsageTypeTTS).
+			Select("COALESCE(SUM(tts_characters), 0) as total").
+			Scan(&result)
+		used = result.Total
 	}
 	return used
 }
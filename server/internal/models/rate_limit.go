@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/cache"
+	"github.com/code-100-precent/LingEcho/pkg/constants"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// rateLimitCacheTTL 凭证对应的用户与用户套餐的缓存时间，套餐变更最多延迟该时长生效
+const rateLimitCacheTTL = 5 * time.Minute
+
+// ResolveRateLimitIdentity 解析限流身份，返回计数键与套餐编码，无法识别时 key 为空（按 IP 限流）。
+// 限流在认证之前执行：会话与登录令牌按用户计数，API 密钥与 API 令牌按凭证计数；
+// 无法解析到用户的凭证按 IP 计数，避免每次请求携带随机凭证绕过 IP 限流。有效凭证对应的用户缓存在全局缓存中，避免每个请求查询数据库
+func ResolveRateLimitIdentity(c *gin.Context, db *gorm.DB) (key, plan string) {
+	if _, ok := c.Get(sessions.DefaultKey); ok {
+		if userID, ok := sessions.Default(c).Get(constants.UserField).(uint); ok && userID > 0 {
+			return fmt.Sprintf("user:%d", userID), rateLimitPlan(c, db, userID)
+		}
+	}
+
+	apiKey, apiSecret := c.GetHeader("X-API-KEY"), c.GetHeader("X-API-SECRET")
+	if apiKey == "" || apiSecret == "" {
+		apiKey, apiSecret = c.Query("apiKey"), c.Query("apiSecret")
+	}
+	if apiKey != "" && apiSecret != "" {
+		hash := hashAPIToken(apiKey + ":" + apiSecret)
+		userID := rateLimitUserID(c, hash, func() (uint, error) {
+			var credential UserCredential
+			err := db.Select("user_id").Where("api_key = ? AND api_secret = ?", apiKey, apiSecret).Take(&credential).Error
+			return credential.UserID, err
+		})
+		if userID == 0 {
+			return "", ""
+		}
+		return "key:" + hash[:16], rateLimitPlan(c, db, userID)
+	}
+
+	if raw := bearerAPIToken(c); raw != "" {
+		hash := hashAPIToken(raw)
+		userID := rateLimitUserID(c, hash, func() (uint, error) {
+			var token APIToken
+			err := db.Select("user_id").Where("token_hash = ?", hash).Take(&token).Error
+			return token.UserID, err
+		})
+		if userID == 0 {
+			return "", ""
+		}
+		return "key:" + hash[:16], rateLimitPlan(c, db, userID)
+	}
+
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		token = c.Query("token")
+	}
+	if token = strings.TrimPrefix(token, "Bearer "); token != "" {
+		hash := hashAPIToken(token)
+		userID := rateLimitUserID(c, hash, func() (uint, error) {
+			user, err := DecodeHashToken(db, token, false)
+			if err != nil {
+				return 0, err
+			}
+			return user.ID, nil
+		})
+		if userID == 0 {
+			return "", ""
+		}
+		return fmt.Sprintf("user:%d", userID), rateLimitPlan(c, db, userID)
+	}
+	return "", ""
+}
+
+// rateLimitUserID 获取凭证哈希对应的用户 ID，无效凭证返回 0。
+// 只缓存有效凭证：无效凭证的哈希由请求方任意构造，缓存它们会被用来填满缓存
+func rateLimitUserID(c *gin.Context, hash string, lookup func() (uint, error)) uint {
+	cacheKey := "ratelimit:cred:" + hash
+	if cached, ok := cache.Get(c, cacheKey); ok {
+		if id, err := strconv.ParseUint(fmt.Sprint(cached), 10, 64); err == nil {
+			return uint(id)
+		}
+	}
+	userID, err := lookup()
+	if err != nil || userID == 0 {
+		return 0
+	}
+	cache.Set(c, cacheKey, userID, rateLimitCacheTTL)
+	return userID
+}
+
+// rateLimitPlan 获取用户当前套餐编码，未订阅套餐时返回空
+func rateLimitPlan(c *gin.Context, db *gorm.DB, userID uint) string {
+	if userID == 0 {
+		return ""
+	}
+	cacheKey := fmt.Sprintf("ratelimit:plan:%d", userID)
+	if cached, ok := cache.Get(c, cacheKey); ok {
+		if code, ok := cached.(string); ok {
+			return code
+		}
+	}
+	plan, err := GetUserBillingPlan(db, userID)
+	if err != nil {
+		return ""
+	}
+	code := ""
+	if plan != nil {
+		code = plan.Code
+	}
+	cache.Set(c, cacheKey, code, rateLimitCacheTTL)
+	return code
+}
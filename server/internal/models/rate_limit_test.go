@@ -0,0 +1,65 @@
+package models
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveRateLimitIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cache.SetGlobalCache(cache.NewLocalCache(cache.LocalConfig{MaxSize: 100, DefaultExpiration: time.Minute, CleanupInterval: time.Hour}))
+	defer cache.SetGlobalCache(nil)
+
+	db := setupTestDBWithSilentLogger(t, &UserCredential{}, &APIToken{}, &BillingPlan{}, &BillingSubscription{})
+	plan := &BillingPlan{Code: "pro", Name: "Pro", Enabled: true}
+	require.NoError(t, db.Create(plan).Error)
+	require.NoError(t, db.Create(&BillingSubscription{UserID: 7, PlanID: plan.ID}).Error)
+	credential := &UserCredential{UserID: 7, APIKey: "ak", APISecret: "as"}
+	require.NoError(t, db.Create(credential).Error)
+	raw, _, err := IssueAPIToken(db, credential, "ci", []string{ScopeVoiceOneshot}, 0)
+	require.NoError(t, err)
+
+	resolve := func(header, value string) (string, string) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/test", nil)
+		if header != "" {
+			c.Request.Header.Set(header, value)
+		}
+		if header == "X-API-KEY" {
+			c.Request.Header.Set("X-API-SECRET", "as")
+		}
+		return ResolveRateLimitIdentity(c, db)
+	}
+
+	key, planCode := resolve("", "")
+	assert.Empty(t, key)
+	assert.Empty(t, planCode)
+
+	key, planCode = resolve("X-API-KEY", "ak")
+	assert.True(t, strings.HasPrefix(key, "key:"))
+	assert.Equal(t, "pro", planCode)
+
+	tokenKey, planCode := resolve("Authorization", "Bearer "+raw)
+	assert.True(t, strings.HasPrefix(tokenKey, "key:"))
+	assert.NotEqual(t, key, tokenKey, "each credential is counted separately")
+	assert.Equal(t, "pro", planCode)
+
+	// 无效凭证按 IP 计数，且不缓存查询结果
+	for _, value := range []string{"Bearer " + raw + "x", "Bearer lat_random", "Bearer random-session-token"} {
+		key, planCode = resolve("Authorization", value)
+		assert.Empty(t, key, value)
+		assert.Empty(t, planCode)
+		_, cached := cache.Get(context.Background(), "ratelimit:cred:"+hashAPIToken(strings.TrimPrefix(value, "Bearer ")))
+		assert.False(t, cached, value)
+	}
+	key, _ = resolve("X-API-KEY", "wrong")
+	assert.Empty(t, key)
+}
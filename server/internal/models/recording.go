@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecordingSource 录音来源
+type RecordingSource string
+
+const (
+	RecordingSourceWebRTC RecordingSource = "webrtc" // 网页/App 实时语音
+	RecordingSourceSIP    RecordingSource = "sip"    // SIP 电话
+)
+
+// CallRecording 通话录音
+type CallRecording struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	UserID      uint            `json:"userId" gorm:"index"`
+	AssistantID *uint           `json:"assistantId,omitempty" gorm:"index"`
+	SessionID   string          `json:"sessionId" gorm:"size:128;index"` // WebRTC 会话 ID 或 SIP Call-ID
+	Source      RecordingSource `json:"source" gorm:"size:20;index"`
+
+	Format     string `json:"format" gorm:"size:20"` // wav / opus
+	Mode       string `json:"mode" gorm:"size:20"`   // mixed / stereo
+	SampleRate int    `json:"sampleRate"`
+	Duration   int    `json:"duration"` // 时长（秒）
+	Size       int64  `json:"size"`     // 文件大小（字节）
+
+	StorageKey string `json:"-" gorm:"size:500"`
+}
+
+// TableName 指定表名
+func (CallRecording) TableName() string {
+	return "call_recordings"
+}
+
+// RecordingPolicy 用户的录音策略，未配置时不录音
+type RecordingPolicy struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	UserID        uint   `json:"userId" gorm:"uniqueIndex"`
+	Enabled       bool   `json:"enabled"`                            // 是否录制该用户的通话
+	RetentionDays int    `json:"retentionDays"`                      // 保留天数，0 表示永久保留，修改后对已有录音同样生效
+	Mode          string `json:"mode" gorm:"size:20;default:stereo"` // mixed / stereo
+	Format        string `json:"format" gorm:"size:20;default:wav"`  // wav / opus
+}
+
+// TableName 指定表名
+func (RecordingPolicy) TableName() string {
+	return "recording_policies"
+}
+
+// GetRecordingPolicy 获取用户的录音策略，未配置时返回不录音的默认策略
+func GetRecordingPolicy(db *gorm.DB, userID uint) (*RecordingPolicy, error) {
+	var policy RecordingPolicy
+	err := db.Where("user_id = ?", userID).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &RecordingPolicy{UserID: userID, Mode: "stereo", Format: "wav"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
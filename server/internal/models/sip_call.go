@@ -81,6 +81,29 @@ func (SipCall) TableName() string {
 	return "sip_calls"
 }
 
+// IsMissed 是否为未接来电：呼入通话在接通前结束（主叫取消、挂断或失败）
+func (c *SipCall) IsMissed() bool {
+	if c.Direction != SipCallDirectionInbound || c.AnswerTime != nil {
+		return false
+	}
+	return c.Status == SipCallStatusCancelled || c.Status == SipCallStatusEnded || c.Status == SipCallStatusFailed
+}
+
+// CalleeUserID 获取被叫对应的系统用户：优先使用通话记录关联的用户，其次是被叫SIP账号绑定的用户
+func (c *SipCall) CalleeUserID(db *gorm.DB) (uint, bool) {
+	if c.UserID != nil {
+		return *c.UserID, true
+	}
+	if c.ToUsername == "" {
+		return 0, false
+	}
+	sipUser, err := GetSipUserByUsername(db, c.ToUsername)
+	if err != nil || sipUser.UserID == nil {
+		return 0, false
+	}
+	return *sipUser.UserID, true
+}
+
 // CreateSipCall 创建SIP通话记录
 func CreateSipCall(db *gorm.DB, sipCall *SipCall) error {
 	return db.Create(sipCall).Error
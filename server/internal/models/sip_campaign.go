@@ -0,0 +1,295 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SipCampaignStatus 外呼任务状态
+type SipCampaignStatus string
+
+const (
+	SipCampaignStatusDraft     SipCampaignStatus = "draft"     // 未启动
+	SipCampaignStatusRunning   SipCampaignStatus = "running"   // 外呼中
+	SipCampaignStatusPaused    SipCampaignStatus = "paused"    // 已暂停，进行中的通话不受影响
+	SipCampaignStatusCompleted SipCampaignStatus = "completed" // 所有号码已处理完
+	SipCampaignStatusCancelled SipCampaignStatus = "cancelled" // 已取消，未拨打的号码不再拨打
+)
+
+// SipCampaignCallStatus 外呼号码状态
+type SipCampaignCallStatus string
+
+const (
+	SipCampaignCallPending   SipCampaignCallStatus = "pending"   // 等待拨打（含等待重试）
+	SipCampaignCallDialing   SipCampaignCallStatus = "dialing"   // 通话进行中
+	SipCampaignCallCompleted SipCampaignCallStatus = "completed" // 已接通并结束
+	SipCampaignCallFailed    SipCampaignCallStatus = "failed"    // 重试次数用尽仍未接通
+	SipCampaignCallCancelled SipCampaignCallStatus = "cancelled" // 任务取消或过期，未拨打
+)
+
+// 外呼任务默认值
+const (
+	DefaultSipCampaignConcurrency   = 1
+	DefaultSipCampaignMaxAttempts   = 3
+	DefaultSipCampaignRetryInterval = 600 // 秒
+)
+
+// SipCampaign 外呼任务：按时间窗口与并发上限拨打一批号码，接通后由助手通话
+type SipCampaign struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	UserID uint              `json:"userId" gorm:"index;not null"` // 创建者
+	Name   string            `json:"name" gorm:"size:128;not null"`
+	Status SipCampaignStatus `json:"status" gorm:"size:20;index"`
+
+	// 通话内容
+	AssistantID  uint   `json:"assistantId" gorm:"not null"`       // 接通后通话的助手
+	CredentialID uint   `json:"credentialId" gorm:"not null"`      // 计费使用的API凭证（属于UserID）
+	Script       string `json:"script,omitempty" gorm:"type:text"` // 接通后播报的开场白（TTS），为空时使用默认问候语
+
+	// 拨打时间：StartAt/EndAt 限定任务有效期，WindowStart/WindowEnd 为每天允许拨打的时段（HH:MM，按 Timezone）
+	StartAt     *time.Time `json:"startAt,omitempty"`
+	EndAt       *time.Time `json:"endAt,omitempty"`
+	WindowStart string     `json:"windowStart,omitempty" gorm:"size:5"`
+	WindowEnd   string     `json:"windowEnd,omitempty" gorm:"size:5"`
+	Timezone    string     `json:"timezone,omitempty" gorm:"size:64"`
+
+	// 并发与重试
+	MaxConcurrency int `json:"maxConcurrency"` // 同时进行的通话数上限
+	MaxAttempts    int `json:"maxAttempts"`    // 每个号码最多拨打次数（含首次）
+	RetryInterval  int `json:"retryInterval"`  // 未接通后重试的间隔（秒）
+	MaxCallSeconds int `json:"maxCallSeconds"` // 单次通话时长上限（秒），0 表示不限制
+}
+
+// TableName 指定表名
+func (SipCampaign) TableName() string {
+	return "sip_campaigns"
+}
+
+// SipCampaignCall 外呼任务中的一个号码及其拨打结果
+type SipCampaignCall struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	CampaignID uint                  `json:"campaignId" gorm:"index;not null"`
+	TargetURI  string                `json:"targetUri" gorm:"size:256;not null"` // 被叫URI，如 sip:1001@192.168.1.100
+	Status     SipCampaignCallStatus `json:"status" gorm:"size:20;index"`
+
+	Attempts      int       `json:"attempts"`                               // 已拨打次数
+	NextAttemptAt time.Time `json:"nextAttemptAt" gorm:"index"`             // 下次可拨打的时间
+	CallID        string    `json:"callId,omitempty" gorm:"size:128;index"` // 最近一次拨打的 SIP Call-ID
+	LastError     string    `json:"lastError,omitempty" gorm:"size:500"`    // 最近一次未接通的原因
+
+	AnsweredAt *time.Time `json:"answeredAt,omitempty"`
+	EndedAt    *time.Time `json:"endedAt,omitempty"`
+	Duration   int        `json:"duration"` // 通话时长（秒）
+}
+
+// TableName 指定表名
+func (SipCampaignCall) TableName() string {
+	return "sip_campaign_calls"
+}
+
+// ApplyDefaults 补全未设置的并发与重试参数
+func (c *SipCampaign) ApplyDefaults() {
+	if c.MaxConcurrency <= 0 {
+		c.MaxConcurrency = DefaultSipCampaignConcurrency
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultSipCampaignMaxAttempts
+	}
+	if c.RetryInterval <= 0 {
+		c.RetryInterval = DefaultSipCampaignRetryInterval
+	}
+}
+
+// Validate 检查时间窗口与时区配置
+func (c *SipCampaign) Validate() error {
+	if _, err := c.location(); err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+	if (c.WindowStart == "") != (c.WindowEnd == "") {
+		return fmt.Errorf("windowStart and windowEnd must be set together")
+	}
+	for _, v := range []string{c.WindowStart, c.WindowEnd} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", v); err != nil {
+			return fmt.Errorf("invalid window time %q, expected HH:MM", v)
+		}
+	}
+	if c.StartAt != nil && c.EndAt != nil && !c.EndAt.After(*c.StartAt) {
+		return fmt.Errorf("endAt must be after startAt")
+	}
+	return nil
+}
+
+func (c *SipCampaign) location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(c.Timezone)
+}
+
+// Expired 任务有效期已过
+func (c *SipCampaign) Expired(now time.Time) bool {
+	return c.EndAt != nil && !now.Before(*c.EndAt)
+}
+
+// CanDial 当前时间是否允许拨打：在有效期内且在每天的拨打时段内，时段可以跨越零点（如 22:00-02:00）
+func (c *SipCampaign) CanDial(now time.Time) bool {
+	if c.StartAt != nil && now.Before(*c.StartAt) {
+		return false
+	}
+	if c.Expired(now) {
+		return false
+	}
+	if c.WindowStart == "" || c.WindowEnd == "" {
+		return true
+	}
+	loc, err := c.location()
+	if err != nil {
+		return false
+	}
+	start, err1 := time.Parse("15:04", c.WindowStart)
+	end, err2 := time.Parse("15:04", c.WindowEnd)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+	if from <= to {
+		return minute >= from && minute < to
+	}
+	return minute >= from || minute < to
+}
+
+// RecordFailure 记录一次未接通：未达到最大次数时安排重试，否则标记为失败；任务已取消时不再重试
+func (c *SipCampaign) RecordFailure(call *SipCampaignCall, reason string, now time.Time) {
+	call.LastError = reason
+	call.EndedAt = &now
+	if c.Status == SipCampaignStatusCancelled {
+		call.Status = SipCampaignCallCancelled
+		return
+	}
+	if call.Attempts < c.MaxAttempts {
+		call.Status = SipCampaignCallPending
+		call.NextAttemptAt = now.Add(time.Duration(c.RetryInterval) * time.Second)
+		return
+	}
+	call.Status = SipCampaignCallFailed
+}
+
+// CreateSipCampaign 创建外呼任务及其号码
+func CreateSipCampaign(db *gorm.DB, campaign *SipCampaign, targets []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(campaign).Error; err != nil {
+			return err
+		}
+		calls := make([]SipCampaignCall, 0, len(targets))
+		for _, target := range targets {
+			calls = append(calls, SipCampaignCall{
+				CampaignID:    campaign.ID,
+				TargetURI:     target,
+				Status:        SipCampaignCallPending,
+				NextAttemptAt: campaign.CreatedAt,
+			})
+		}
+		if len(calls) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(calls, 500).Error
+	})
+}
+
+// GetSipCampaign 获取用户的外呼任务
+func GetSipCampaign(db *gorm.DB, userID, id uint) (*SipCampaign, error) {
+	var campaign SipCampaign
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&campaign).Error; err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// ListSipCampaigns 获取用户的外呼任务，最近创建的在前
+func ListSipCampaigns(db *gorm.DB, userID uint) ([]SipCampaign, error) {
+	var campaigns []SipCampaign
+	err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&campaigns).Error
+	return campaigns, err
+}
+
+// GetSipCampaignsByStatus 获取指定状态的外呼任务
+func GetSipCampaignsByStatus(db *gorm.DB, status SipCampaignStatus) ([]SipCampaign, error) {
+	var campaigns []SipCampaign
+	err := db.Where("status = ?", status).Order("id").Find(&campaigns).Error
+	return campaigns, err
+}
+
+// UpdateSipCampaignStatus 更新外呼任务状态
+func UpdateSipCampaignStatus(db *gorm.DB, id uint, status SipCampaignStatus) error {
+	return db.Model(&SipCampaign{}).Where("id = ?", id).Update("status", status).Error
+}
+
+// GetSipCampaignCalls 获取外呼任务的号码，可按状态过滤
+func GetSipCampaignCalls(db *gorm.DB, campaignID uint, status SipCampaignCallStatus) ([]SipCampaignCall, error) {
+	var calls []SipCampaignCall
+	query := db.Where("campaign_id = ?", campaignID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("id").Find(&calls).Error
+	return calls, err
+}
+
+// GetDueSipCampaignCalls 获取到了拨打时间的号码，最多 limit 个
+func GetDueSipCampaignCalls(db *gorm.DB, campaignID uint, now time.Time, limit int) ([]SipCampaignCall, error) {
+	var calls []SipCampaignCall
+	err := db.Where("campaign_id = ? AND status = ? AND next_attempt_at <= ?", campaignID, SipCampaignCallPending, now).
+		Order("next_attempt_at, id").Limit(limit).Find(&calls).Error
+	return calls, err
+}
+
+// GetDialingSipCampaignCalls 获取所有通话进行中的号码
+func GetDialingSipCampaignCalls(db *gorm.DB) ([]SipCampaignCall, error) {
+	var calls []SipCampaignCall
+	err := db.Where("status = ?", SipCampaignCallDialing).Order("id").Find(&calls).Error
+	return calls, err
+}
+
+// CountSipCampaignCalls 按状态统计外呼任务的号码数
+func CountSipCampaignCalls(db *gorm.DB, campaignID uint) (map[SipCampaignCallStatus]int64, error) {
+	var rows []struct {
+		Status SipCampaignCallStatus
+		Count  int64
+	}
+	err := db.Model(&SipCampaignCall{}).Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", campaignID).Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[SipCampaignCallStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// SaveSipCampaignCall 保存号码的拨打结果
+func SaveSipCampaignCall(db *gorm.DB, call *SipCampaignCall) error {
+	return db.Save(call).Error
+}
+
+// CancelPendingSipCampaignCalls 取消任务中尚未拨打的号码
+func CancelPendingSipCampaignCalls(db *gorm.DB, campaignID uint) error {
+	return db.Model(&SipCampaignCall{}).
+		Where("campaign_id = ? AND status = ?", campaignID, SipCampaignCallPending).
+		Update("status", SipCampaignCallCancelled).Error
+}
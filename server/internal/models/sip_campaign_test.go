@@ -0,0 +1,87 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSipCampaign_CanDial(t *testing.T) {
+	at := func(hhmm string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", "2026-03-02 "+hhmm, time.UTC)
+		require.NoError(t, err)
+		return ts
+	}
+
+	c := &SipCampaign{WindowStart: "09:00", WindowEnd: "18:00", Timezone: "UTC"}
+	require.NoError(t, c.Validate())
+	assert.True(t, c.CanDial(at("09:00")))
+	assert.False(t, c.CanDial(at("18:00")))
+	assert.False(t, c.CanDial(at("08:59")))
+
+	// 跨越零点的时段
+	c = &SipCampaign{WindowStart: "22:00", WindowEnd: "02:00", Timezone: "UTC"}
+	assert.True(t, c.CanDial(at("23:30")))
+	assert.True(t, c.CanDial(at("01:00")))
+	assert.False(t, c.CanDial(at("12:00")))
+
+	end := at("12:00")
+	c = &SipCampaign{EndAt: &end}
+	assert.True(t, c.CanDial(at("11:59")))
+	assert.False(t, c.CanDial(end))
+	assert.True(t, c.Expired(end))
+
+	assert.Error(t, (&SipCampaign{WindowStart: "09:00"}).Validate())
+	assert.Error(t, (&SipCampaign{WindowStart: "9am", WindowEnd: "18:00"}).Validate())
+	assert.Error(t, (&SipCampaign{Timezone: "Mars/Olympus"}).Validate())
+}
+
+func TestSipCampaign_RecordFailure(t *testing.T) {
+	c := &SipCampaign{}
+	c.ApplyDefaults()
+	c.MaxAttempts = 2
+	now := time.Now()
+
+	call := &SipCampaignCall{Status: SipCampaignCallDialing, Attempts: 1}
+	c.RecordFailure(call, "486 Busy Here", now)
+	assert.Equal(t, SipCampaignCallPending, call.Status)
+	assert.Equal(t, now.Add(time.Duration(DefaultSipCampaignRetryInterval)*time.Second), call.NextAttemptAt)
+
+	call.Attempts = 2
+	c.RecordFailure(call, "timeout", now)
+	assert.Equal(t, SipCampaignCallFailed, call.Status)
+	assert.Equal(t, "timeout", call.LastError)
+}
+
+func TestSipCampaignCalls(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &SipCampaign{}, &SipCampaignCall{})
+	campaign := &SipCampaign{UserID: 1, Name: "notify", AssistantID: 2, CredentialID: 3, Status: SipCampaignStatusRunning}
+	campaign.ApplyDefaults()
+	require.NoError(t, CreateSipCampaign(db, campaign, []string{"sip:1001@a", "sip:1002@a", "sip:1003@a"}))
+
+	now := time.Now().Add(time.Second)
+	due, err := GetDueSipCampaignCalls(db, campaign.ID, now, 2)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, "sip:1001@a", due[0].TargetURI)
+
+	due[0].Status = SipCampaignCallDialing
+	require.NoError(t, SaveSipCampaignCall(db, &due[0]))
+	dialing, err := GetDialingSipCampaignCalls(db)
+	require.NoError(t, err)
+	require.Len(t, dialing, 1)
+
+	require.NoError(t, CancelPendingSipCampaignCalls(db, campaign.ID))
+	counts, err := CountSipCampaignCalls(db, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), counts[SipCampaignCallDialing])
+	assert.Equal(t, int64(2), counts[SipCampaignCallCancelled])
+
+	running, err := GetSipCampaignsByStatus(db, SipCampaignStatusRunning)
+	require.NoError(t, err)
+	require.Len(t, running, 1)
+	_, err = GetSipCampaign(db, 2, campaign.ID)
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SipRegistration SIP联系地址绑定表，一个SIP用户可以同时在多个终端上注册
+type SipRegistration struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	SipUserID uint   `json:"sipUserId" gorm:"uniqueIndex:idx_sip_registration_contact;not null"` // 关联的SIP用户
+	Username  string `json:"username" gorm:"size:128;index;not null"`                            // SIP用户名
+
+	// 绑定信息
+	Contact     string    `json:"contact" gorm:"size:256;uniqueIndex:idx_sip_registration_contact;not null"` // Contact地址（完整URI）
+	ContactIP   string    `json:"contactIp" gorm:"size:64"`                                                  // 实际发送请求的IP（NAT后为公网地址）
+	ContactPort int       `json:"contactPort"`                                                               // 实际发送请求的端口
+	Transport   string    `json:"transport" gorm:"size:16"`                                                  // 传输协议（udp/tcp/tls）
+	Expires     int       `json:"expires"`                                                                   // 有效期（秒）
+	ExpiresAt   time.Time `json:"expiresAt" gorm:"index"`                                                    // 过期时间点
+
+	// 注册请求信息，用于识别同一终端的刷新与乱序请求
+	CallID string `json:"callId" gorm:"size:128"`
+	CSeq   uint32 `json:"cseq"`
+
+	UserAgent string `json:"userAgent,omitempty" gorm:"size:256"` // 用户代理（User-Agent）
+	RemoteIP  string `json:"remoteIp,omitempty" gorm:"size:64"`   // 远程IP地址
+}
+
+// TableName 指定表名
+func (SipRegistration) TableName() string {
+	return "sip_registrations"
+}
+
+// IsExpired 检查绑定是否已过期
+func (r *SipRegistration) IsExpired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// SaveSipRegistration 创建或刷新绑定（按SIP用户与Contact唯一）
+func SaveSipRegistration(db *gorm.DB, reg *SipRegistration) error {
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "sip_user_id"}, {Name: "contact"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"updated_at", "contact_ip", "contact_port", "transport", "expires", "expires_at",
+			"call_id", "c_seq", "user_agent", "remote_ip",
+		}),
+	}).Create(reg).Error
+}
+
+// DeleteSipRegistration 删除SIP用户的指定绑定
+func DeleteSipRegistration(db *gorm.DB, sipUserID uint, contact string) error {
+	return db.Where("sip_user_id = ? AND contact = ?", sipUserID, contact).Delete(&SipRegistration{}).Error
+}
+
+// DeleteSipRegistrations 删除SIP用户的所有绑定
+func DeleteSipRegistrations(db *gorm.DB, sipUserID uint) error {
+	return db.Where("sip_user_id = ?", sipUserID).Delete(&SipRegistration{}).Error
+}
+
+// GetSipRegistrations 获取SIP用户未过期的绑定，最近注册的在前
+func GetSipRegistrations(db *gorm.DB, sipUserID uint, now time.Time) ([]SipRegistration, error) {
+	var regs []SipRegistration
+	err := db.Where("sip_user_id = ? AND expires_at > ?", sipUserID, now).
+		Order("updated_at DESC").Find(&regs).Error
+	return regs, err
+}
+
+// GetActiveSipRegistrations 获取所有未过期的绑定
+func GetActiveSipRegistrations(db *gorm.DB, now time.Time) ([]SipRegistration, error) {
+	var regs []SipRegistration
+	err := db.Where("expires_at > ?", now).Order("updated_at DESC").Find(&regs).Error
+	return regs, err
+}
+
+// DeleteExpiredSipRegistrations 删除已过期的绑定，返回受影响的SIP用户ID
+func DeleteExpiredSipRegistrations(db *gorm.DB, now time.Time) ([]uint, error) {
+	var userIDs []uint
+	if err := db.Model(&SipRegistration{}).Where("expires_at <= ?", now).
+		Distinct().Pluck("sip_user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	err := db.Where("expires_at <= ?", now).Delete(&SipRegistration{}).Error
+	return userIDs, err
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveSipRegistration_Upsert(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &SipRegistration{})
+	now := time.Now()
+
+	reg := &SipRegistration{SipUserID: 1, Username: "1001", Contact: "sip:1001@10.0.0.5", Expires: 60, ExpiresAt: now.Add(time.Minute), CSeq: 1}
+	require.NoError(t, SaveSipRegistration(db, reg))
+	refresh := &SipRegistration{SipUserID: 1, Username: "1001", Contact: "sip:1001@10.0.0.5", Expires: 600, ExpiresAt: now.Add(10 * time.Minute), CSeq: 2}
+	require.NoError(t, SaveSipRegistration(db, refresh))
+	require.NoError(t, SaveSipRegistration(db, &SipRegistration{SipUserID: 1, Username: "1001", Contact: "sip:1001@10.0.0.6", ExpiresAt: now.Add(-time.Second)}))
+
+	regs, err := GetSipRegistrations(db, 1, now)
+	require.NoError(t, err)
+	require.Len(t, regs, 1)
+	assert.Equal(t, 600, regs[0].Expires)
+	assert.Equal(t, uint32(2), regs[0].CSeq)
+	assert.False(t, regs[0].IsExpired(now))
+}
+
+func TestDeleteExpiredSipRegistrations(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &SipRegistration{})
+	now := time.Now()
+	require.NoError(t, SaveSipRegistration(db, &SipRegistration{SipUserID: 1, Username: "1001", Contact: "sip:a", ExpiresAt: now.Add(-time.Minute)}))
+	require.NoError(t, SaveSipRegistration(db, &SipRegistration{SipUserID: 2, Username: "1002", Contact: "sip:b", ExpiresAt: now.Add(time.Minute)}))
+
+	userIDs, err := DeleteExpiredSipRegistrations(db, now)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1}, userIDs)
+
+	active, err := GetActiveSipRegistrations(db, now)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "1002", active[0].Username)
+
+	require.NoError(t, DeleteSipRegistrations(db, 2))
+	active, err = GetActiveSipRegistrations(db, now)
+	require.NoError(t, err)
+	assert.Empty(t, active)
+}
@@ -48,6 +48,11 @@ type SipUser struct {
 	GroupID *uint `json:"groupId,omitempty" gorm:"index"` // 关联到组织（可选）
 	Group   Group `json:"group,omitempty" gorm:"foreignKey:GroupID"`
 
+	// AI应答：设置后打给该用户的呼入通话由助手接听
+	AssistantID  *uint `json:"assistantId,omitempty" gorm:"index"` // 接听的助手
+	CredentialID *uint `json:"credentialId,omitempty"`             // 计费使用的API凭证（属于UserID）
+	WorkflowID   *uint `json:"workflowId,omitempty"`               // 助手接听前执行的IVR工作流（按键菜单）
+
 	// 显示信息
 	DisplayName string `json:"displayName,omitempty" gorm:"size:128"` // 显示名称
 	Alias       string `json:"alias,omitempty" gorm:"size:128"`       // 别名
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrStorageQuotaExceeded 写入后会超出用户或凭证的存储配额
+var ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+
+// ErrStorageCredentialNotFound 计费凭证不存在或不属于该用户
+var ErrStorageCredentialNotFound = errors.New("credential not found")
+
+// 存储对象类别
+const (
+	StorageCategoryUpload  = "upload"  // 用户上传的音频
+	StorageCategoryAvatar  = "avatar"  // 头像
+	StorageCategoryOneshot = "oneshot" // 一次性对话合成的音频，按 TTL 清理
+)
+
+// StorageObject 记录写入存储的文件及其归属，带过期时间的文件由存储清理任务删除
+type StorageObject struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	StorageKey   string     `json:"storageKey" gorm:"size:500;uniqueIndex:idx_storage_object_key,length:191"`
+	UserID       uint       `json:"userId" gorm:"index"`
+	CredentialID uint       `json:"credentialId" gorm:"index"`
+	Category     string     `json:"category" gorm:"size:32;index"`
+	Size         int64      `json:"size"`
+	ExpiresAt    *time.Time `json:"expiresAt" gorm:"index"` // 为空表示永久保留
+	CreatedAt    time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// CheckStorageQuota 检查再写入 size 字节后是否超出用户（含组织）或凭证的存储配额，
+// 凭证必须属于该用户，否则返回 ErrStorageCredentialNotFound
+func CheckStorageQuota(db *gorm.DB, userID, credentialID uint, size int64) error {
+	total, used, err := GetEffectiveQuota(db, userID, QuotaTypeStorage)
+	if err != nil {
+		return err
+	}
+	if total > 0 && used+size > total {
+		return fmt.Errorf("%w: used %d of %d bytes", ErrStorageQuotaExceeded, used, total)
+	}
+
+	if credentialID == 0 {
+		return nil
+	}
+	var credential UserCredential
+	if err := db.Select("id", "storage_quota").Where("user_id = ?", userID).First(&credential, credentialID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrStorageCredentialNotFound
+		}
+		return err
+	}
+	if credential.StorageQuota <= 0 {
+		return nil
+	}
+	var credentialUsed struct{ Total int64 }
+	if err := db.Model(&UsageRecord{}).
+		Where("credential_id = ? AND usage_type = ?", credentialID, UsageTypeStorage).
+		Select("COALESCE(SUM(storage_size), 0) as total").
+		Scan(&credentialUsed).Error; err != nil {
+		return err
+	}
+	if credentialUsed.Total+size > credential.StorageQuota {
+		return fmt.Errorf("%w: credential used %d of %d bytes", ErrStorageQuotaExceeded, credentialUsed.Total, credential.StorageQuota)
+	}
+	return nil
+}
+
+// TrackStorageObject 登记新写入的文件并计入存储用量
+func TrackStorageObject(db *gorm.DB, object *StorageObject, description string) error {
+	if err := db.Create(object).Error; err != nil {
+		return err
+	}
+	return RecordStorageUsage(db, object.UserID, object.CredentialID, nil, nil,
+		fmt.Sprintf("%s_%d", object.Category, object.ID), object.Size, description)
+}
+
+// GetExpiredStorageObjects 获取已过期的文件，每次最多 limit 个
+func GetExpiredStorageObjects(db *gorm.DB, now time.Time, limit int) ([]StorageObject, error) {
+	var objects []StorageObject
+	err := db.Where("expires_at IS NOT NULL AND expires_at < ?", now).
+		Order("expires_at ASC").Limit(limit).Find(&objects).Error
+	return objects, err
+}
+
+// ReleaseStorageObject 文件删除后移除登记，并记一笔负的存储用量使已用空间回落
+func ReleaseStorageObject(db *gorm.DB, object *StorageObject) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(object).Error; err != nil {
+			return err
+		}
+		return RecordStorageUsage(tx, object.UserID, object.CredentialID, nil, nil,
+			fmt.Sprintf("%s_%d", object.Category, object.ID), -object.Size, "删除文件: "+object.StorageKey)
+	})
+}
+
+// ReleaseStorageObjectByKey 按存储键释放文件登记，未登记的文件（如功能上线前写入的）直接忽略
+func ReleaseStorageObjectByKey(db *gorm.DB, key string) error {
+	var object StorageObject
+	if err := db.Where("storage_key = ?", key).First(&object).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return ReleaseStorageObject(db, &object)
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupStorageTestDB(t *testing.T) *gorm.DB {
+	return setupTestDBWithSilentLogger(t,
+		&UserQuota{},
+		&GroupQuota{},
+		&GroupMember{},
+		&UsageRecord{},
+		&UserCredential{},
+		&StorageObject{},
+	)
+}
+
+func TestCheckStorageQuota_User(t *testing.T) {
+	db := setupStorageTestDB(t)
+
+	// 未设置配额时不限制
+	require.NoError(t, CheckStorageQuota(db, 1, 0, 1<<30))
+
+	require.NoError(t, db.Create(&UserQuota{UserID: 1, QuotaType: QuotaTypeStorage, TotalQuota: 1000, Period: QuotaPeriodLifetime}).Error)
+	require.NoError(t, TrackStorageObject(db, &StorageObject{StorageKey: "uploads/a.wav", UserID: 1, Category: StorageCategoryUpload, Size: 800}, "上传文件"))
+
+	require.NoError(t, CheckStorageQuota(db, 1, 0, 200))
+	assert.ErrorIs(t, CheckStorageQuota(db, 1, 0, 201), ErrStorageQuotaExceeded)
+
+	// 删除文件后用量回落
+	require.NoError(t, ReleaseStorageObjectByKey(db, "uploads/a.wav"))
+	require.NoError(t, CheckStorageQuota(db, 1, 0, 1000))
+	var count int64
+	db.Model(&StorageObject{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// 未登记的文件直接忽略
+	require.NoError(t, ReleaseStorageObjectByKey(db, "uploads/missing.wav"))
+}
+
+func TestCheckStorageQuota_Credential(t *testing.T) {
+	db := setupStorageTestDB(t)
+	credential := &UserCredential{UserID: 1, APIKey: "key", APISecret: "secret", StorageQuota: 500}
+	require.NoError(t, db.Create(credential).Error)
+
+	require.NoError(t, TrackStorageObject(db, &StorageObject{StorageKey: "uploads/b.wav", UserID: 1, CredentialID: credential.ID, Category: StorageCategoryUpload, Size: 400}, "上传文件"))
+	assert.ErrorIs(t, CheckStorageQuota(db, 1, credential.ID, 101), ErrStorageQuotaExceeded)
+	require.NoError(t, CheckStorageQuota(db, 1, credential.ID, 100))
+
+	// 其他凭证的用量不计入
+	require.NoError(t, TrackStorageObject(db, &StorageObject{StorageKey: "uploads/c.wav", UserID: 1, CredentialID: credential.ID + 1, Category: StorageCategoryUpload, Size: 400}, "上传文件"))
+	require.NoError(t, CheckStorageQuota(db, 1, credential.ID, 100))
+
+	// 不存在或其他用户的凭证不能用于计费
+	assert.ErrorIs(t, CheckStorageQuota(db, 1, credential.ID+100, 1), ErrStorageCredentialNotFound)
+	assert.ErrorIs(t, CheckStorageQuota(db, 2, credential.ID, 1), ErrStorageCredentialNotFound)
+}
+
+func TestGetExpiredStorageObjects(t *testing.T) {
+	db := setupStorageTestDB(t)
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	require.NoError(t, TrackStorageObject(db, &StorageObject{StorageKey: "oneshot/expired.wav", UserID: 1, Category: StorageCategoryOneshot, Size: 10, ExpiresAt: &past}, "一句话语音合成音频"))
+	require.NoError(t, TrackStorageObject(db, &StorageObject{StorageKey: "oneshot/fresh.wav", UserID: 1, Category: StorageCategoryOneshot, Size: 10, ExpiresAt: &future}, "一句话语音合成音频"))
+	require.NoError(t, TrackStorageObject(db, &StorageObject{StorageKey: "uploads/keep.wav", UserID: 1, Category: StorageCategoryUpload, Size: 10}, "上传文件"))
+
+	expired, err := GetExpiredStorageObjects(db, now, 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "oneshot/expired.wav", expired[0].StorageKey)
+
+	require.NoError(t, ReleaseStorageObject(db, &expired[0]))
+	_, used, err := GetEffectiveQuota(db, 1, QuotaTypeStorage)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), used)
+}
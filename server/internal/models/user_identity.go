@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentity 用户关联的第三方登录账号（Google、GitHub、企业 OIDC 等）
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index;not null" json:"userId"`
+	Provider    string     `gorm:"size:64;not null;uniqueIndex:idx_user_identity_subject" json:"provider"` // 提供方名称
+	Subject     string     `gorm:"size:255;not null;uniqueIndex:idx_user_identity_subject" json:"-"`       // 提供方内的用户标识
+	Email       string     `gorm:"size:128" json:"email"`                                                  // 第三方账号的邮箱
+	LastLoginAt *time.Time `json:"lastLoginAt,omitempty"`                                                  // 最后一次通过该账号登录的时间
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// TableName 指定表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// GetUserIdentity 按提供方与用户标识查找关联记录
+func GetUserIdentity(db *gorm.DB, provider, subject string) (*UserIdentity, error) {
+	var identity UserIdentity
+	if err := db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetUserIdentities 获取用户关联的第三方账号
+func GetUserIdentities(db *gorm.DB, userID uint) ([]UserIdentity, error) {
+	var identities []UserIdentity
+	err := db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+// LinkUserIdentity 将第三方账号关联到用户，同一用户在同一提供方只能关联一个账号
+func LinkUserIdentity(db *gorm.DB, userID uint, provider, subject, email string) (*UserIdentity, error) {
+	identity := &UserIdentity{UserID: userID, Provider: provider, Subject: subject, Email: email}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND provider = ?", userID, provider).Delete(&UserIdentity{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(identity).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// TouchUserIdentity 更新第三方账号的最后登录时间与邮箱
+func TouchUserIdentity(db *gorm.DB, identity *UserIdentity, email string) error {
+	now := time.Now()
+	identity.LastLoginAt = &now
+	identity.Email = email
+	return db.Model(identity).Updates(map[string]any{"last_login_at": now, "email": email}).Error
+}
+
+// UnlinkUserIdentity 解除用户与某提供方账号的关联
+func UnlinkUserIdentity(db *gorm.DB, userID uint, provider string) error {
+	result := db.Where("user_id = ? AND provider = ?", userID, provider).Delete(&UserIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestUserIdentity_Lifecycle(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &UserIdentity{})
+
+	identity, err := LinkUserIdentity(db, 7, "github", "1001", "old@example.com")
+	require.NoError(t, err)
+	require.NotZero(t, identity.ID)
+
+	found, err := GetUserIdentity(db, "github", "1001")
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), found.UserID)
+
+	require.NoError(t, TouchUserIdentity(db, found, "new@example.com"))
+	found, err = GetUserIdentity(db, "github", "1001")
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", found.Email)
+	assert.NotNil(t, found.LastLoginAt)
+
+	// 同一提供方重新关联时替换旧账号
+	_, err = LinkUserIdentity(db, 7, "github", "2002", "other@example.com")
+	require.NoError(t, err)
+	_, err = GetUserIdentity(db, "github", "1001")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	_, err = LinkUserIdentity(db, 7, "google", "g-1", "user@example.com")
+	require.NoError(t, err)
+	identities, err := GetUserIdentities(db, 7)
+	require.NoError(t, err)
+	assert.Len(t, identities, 2)
+
+	// 同一第三方账号不能关联到两个用户
+	_, err = LinkUserIdentity(db, 8, "google", "g-1", "user@example.com")
+	assert.Error(t, err)
+
+	require.NoError(t, UnlinkUserIdentity(db, 7, "google"))
+	assert.ErrorIs(t, UnlinkUserIdentity(db, 7, "google"), gorm.ErrRecordNotFound)
+}
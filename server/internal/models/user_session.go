@@ -0,0 +1,198 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/constants"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 会话类型
+const (
+	SessionKindCookie = "cookie" // 浏览器 Cookie 会话
+	SessionKindToken  = "token"  // 登录令牌（Authorization: Bearer）
+)
+
+// sessionTouchInterval 最后活跃时间的更新间隔，避免每个请求都写库
+const sessionTouchInterval = time.Minute
+
+var (
+	ErrSessionRevoked = errors.New("session revoked")
+	ErrSessionExpired = errors.New("session expired")
+)
+
+// UserSession 服务端登记的登录会话，认证中间件在每个请求上检查其是否已被吊销
+type UserSession struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"userId"`
+	Kind       string     `gorm:"size:16;not null" json:"kind"`          // cookie 或 token
+	SessionKey string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // Cookie 会话为随机标识，登录令牌为令牌的 SHA-256
+	DeviceID   string     `gorm:"size:128;index" json:"deviceId"`        // 与 user_devices.device_id 一致
+	IPAddress  string     `gorm:"size:128" json:"ipAddress"`
+	UserAgent  string     `gorm:"type:text" json:"userAgent"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expiresAt,omitempty"` // 为空表示随 Cookie 失效
+	RevokedAt  *time.Time `gorm:"index" json:"revokedAt,omitempty"` // 吊销时间，吊销后立即失效
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// TableName 指定表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// registerUserSession 登记会话，相同标识的会话已存在时直接返回
+func registerUserSession(db *gorm.DB, userID uint, kind, key, ipAddress, userAgent string, expiresAt *time.Time) (*UserSession, error) {
+	var existing UserSession
+	err := db.Where("session_key = ?", key).First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	session := &UserSession{
+		UserID:     userID,
+		Kind:       kind,
+		SessionKey: key,
+		DeviceID:   utils.GetDeviceID(userAgent, ipAddress),
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		LastSeenAt: time.Now(),
+		ExpiresAt:  expiresAt,
+	}
+	if err := db.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// StartCookieSession 为 Cookie 登录登记会话，会话的 SessionKey 写入 Cookie
+func StartCookieSession(db *gorm.DB, user *User, ipAddress, userAgent string) (*UserSession, error) {
+	key, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+	return registerUserSession(db, user.ID, SessionKindCookie, key, ipAddress, userAgent, nil)
+}
+
+// IssueSessionToken 签发登录令牌并登记会话，吊销会话后该令牌立即失效
+func IssueSessionToken(db *gorm.DB, user *User, expired time.Duration, ipAddress, userAgent string) (string, *UserSession, error) {
+	nonce, err := utils.GenerateSecureToken(9)
+	if err != nil {
+		return "", nil, err
+	}
+	expiresAt := time.Now().Add(expired)
+	token := encodeHashToken(user, expiresAt.Unix(), false, nonce)
+	session, err := registerUserSession(db, user.ID, SessionKindToken, hashAPIToken(token), ipAddress, userAgent, &expiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// ValidateUserSession 校验会话是否仍然有效，有效时返回登记的会话。
+// key 为空或登录令牌未登记时视为启用会话登记之前签发的旧会话，在用户退出所有设备之前仍然有效，此时返回 nil
+func ValidateUserSession(db *gorm.DB, user *User, kind, key string) (*UserSession, error) {
+	legacy := func() (*UserSession, error) {
+		if user.SessionsRevokedAt != nil {
+			return nil, ErrSessionRevoked
+		}
+		return nil, nil
+	}
+	if key == "" {
+		return legacy()
+	}
+	var session UserSession
+	err := db.Where("session_key = ?", key).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if kind == SessionKindToken {
+			return legacy()
+		}
+		return nil, ErrSessionRevoked
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != user.ID || session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+	now := time.Now()
+	if session.ExpiresAt != nil && now.After(*session.ExpiresAt) {
+		return nil, ErrSessionExpired
+	}
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		session.LastSeenAt = now
+		db.Model(&session).UpdateColumn("last_seen_at", now)
+	}
+	return &session, nil
+}
+
+// AuthenticateToken 校验登录令牌及其会话，返回令牌所属用户
+func AuthenticateToken(db *gorm.DB, token string) (*User, *UserSession, error) {
+	user, err := DecodeHashToken(db, token, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := ValidateUserSession(db, user, SessionKindToken, hashAPIToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, session, nil
+}
+
+// CurrentSession 获取当前请求所属的登记会话，旧会话或非登录认证时返回 nil
+func CurrentSession(c *gin.Context) *UserSession {
+	if obj, exists := c.Get(constants.SessionField); exists && obj != nil {
+		return obj.(*UserSession)
+	}
+	return nil
+}
+
+// GetActiveUserSessions 获取用户未吊销且未过期的会话，按最后活跃时间倒序
+func GetActiveUserSessions(db *gorm.DB, userID uint) ([]UserSession, error) {
+	var sessions []UserSession
+	err := db.Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeUserSession 吊销用户的某个会话
+func RevokeUserSession(db *gorm.DB, userID, sessionID uint) error {
+	result := db.Model(&UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeAllUserSessions 退出所有设备：吊销用户除 exceptID 外的全部会话，同时使未登记的旧会话失效。
+// exceptID 为 0 时不保留任何会话，返回吊销的会话数
+func RevokeAllUserSessions(db *gorm.DB, user *User, exceptID uint) (int64, error) {
+	now := time.Now()
+	var revoked int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&UserSession{}).
+			Where("user_id = ? AND id <> ? AND revoked_at IS NULL", user.ID, exceptID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		revoked = result.RowsAffected
+		return tx.Model(user).UpdateColumn("sessions_revoked_at", now).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	user.SessionsRevokedAt = &now
+	return revoked, nil
+}
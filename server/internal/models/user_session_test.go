@@ -0,0 +1,116 @@
+package models
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueSessionToken_Revoke(t *testing.T) {
+	db := setupHandlerTestDB(t)
+	user, err := CreateUser(db, "test@example.com", "password123")
+	require.NoError(t, err)
+
+	token, session, err := IssueSessionToken(db, user, time.Hour, "10.0.0.1", "test-agent")
+	require.NoError(t, err)
+	assert.Equal(t, SessionKindToken, session.Kind)
+	assert.NotEqual(t, token, session.SessionKey)
+
+	found, current, err := AuthenticateToken(db, token)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+	assert.Equal(t, session.ID, current.ID)
+
+	sessions, err := GetActiveUserSessions(db, user.ID)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	require.NoError(t, RevokeUserSession(db, user.ID, session.ID))
+	_, _, err = AuthenticateToken(db, token)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	assert.Error(t, RevokeUserSession(db, user.ID, session.ID))
+
+	sessions, err = GetActiveUserSessions(db, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestRevokeAllUserSessions(t *testing.T) {
+	db := setupHandlerTestDB(t)
+	user, err := CreateUser(db, "test@example.com", "password123")
+	require.NoError(t, err)
+
+	cookieSession, err := StartCookieSession(db, user, "10.0.0.1", "browser")
+	require.NoError(t, err)
+	token, _, err := IssueSessionToken(db, user, time.Hour, "10.0.0.2", "cli")
+	require.NoError(t, err)
+	legacyToken := EncodeHashToken(user, time.Now().Add(time.Hour).Unix(), false)
+
+	// 未登记的旧令牌在退出所有设备之前仍然有效
+	_, current, err := AuthenticateToken(db, legacyToken)
+	require.NoError(t, err)
+	assert.Nil(t, current)
+
+	revoked, err := RevokeAllUserSessions(db, user, cookieSession.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), revoked)
+
+	_, _, err = AuthenticateToken(db, token)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, _, err = AuthenticateToken(db, legacyToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+
+	reloaded, err := GetUserByUID(db, user.ID)
+	require.NoError(t, err)
+	kept, err := ValidateUserSession(db, reloaded, SessionKindCookie, cookieSession.SessionKey)
+	require.NoError(t, err)
+	assert.Equal(t, cookieSession.ID, kept.ID)
+	_, err = ValidateUserSession(db, reloaded, SessionKindCookie, "")
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, err = ValidateUserSession(db, reloaded, SessionKindCookie, "unknown")
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+}
+
+func TestCurrentUser_RevokedCookieSession(t *testing.T) {
+	db := setupHandlerTestDB(t)
+	router := setupHandlerTestRouter(t, db)
+	user, err := CreateUser(db, "test@example.com", "password123")
+	require.NoError(t, err)
+
+	router.POST("/login", func(c *gin.Context) {
+		Login(c, user)
+		c.JSON(http.StatusOK, gin.H{"session": CurrentSession(c).ID})
+	})
+	router.GET("/me", AuthRequired, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/login", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	require.NotEmpty(t, cookies)
+
+	get := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, get())
+
+	sessions, err := GetActiveUserSessions(db, user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.NoError(t, RevokeUserSession(db, user.ID, sessions[0].ID))
+	assert.Equal(t, http.StatusUnauthorized, get())
+}
@@ -1,7 +1,6 @@
 package models
 
 import (
-	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -16,7 +15,6 @@ import (
 	"github.com/code-100-precent/LingEcho"
 	"github.com/code-100-precent/LingEcho/pkg/constants"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
-	"github.com/code-100-precent/LingEcho/pkg/metrics"
 	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -35,6 +33,10 @@ const (
 	SigUserVerifyEmail = "user.verifyemail"
 	// SigUserResetPassword : user *User, hash, clientIp, userAgent string
 	SigUserResetPassword = "user.resetpassword"
+	// SigUserNotify : user *User, db *gorm.DB, msg notification.Message
+	SigUserNotify = "user.notify"
+	// SigUserInbox : user *User, item *notification.InternalNotification
+	SigUserInbox = "user.inbox"
 )
 
 type SendEmailVerifyEmail struct {
@@ -132,16 +134,34 @@ func Login(c *gin.Context, user *User) {
 		return
 	}
 
+	userSession, err := StartCookieSession(db, user, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		logger.Error("user.login", zap.Error(err))
+		LingEcho.AbortWithJSONError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Set(constants.SessionField, userSession)
+
 	session := sessions.Default(c)
 	session.Set(constants.UserField, user.ID)
+	session.Set(constants.SessionKeyField, userSession.SessionKey)
 	session.Save()
 	utils.Sig().Emit(SigUserLogin, user, db)
 }
 
 func Logout(c *gin.Context, user *User) {
+	// 吊销当前会话，使同一 Cookie 或令牌不能再使用
+	if current := CurrentSession(c); current != nil {
+		db := c.MustGet(constants.DbField).(*gorm.DB)
+		if err := RevokeUserSession(db, user.ID, current.ID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warn("user.logout", zap.Error(err))
+		}
+	}
 	c.Set(constants.UserField, nil)
+	c.Set(constants.SessionField, nil)
 	session := sessions.Default(c)
 	session.Delete(constants.UserField)
+	session.Delete(constants.SessionKeyField)
 	session.Save()
 	utils.Sig().Emit(SigUserLogout, user, c)
 }
@@ -182,12 +202,13 @@ func AuthRequired(c *gin.Context) {
 	db := c.MustGet(constants.DbField).(*gorm.DB)
 	// split bearer
 	token = strings.TrimPrefix(token, "Bearer ")
-	user, err := DecodeHashToken(db, token, false)
+	user, userSession, err := AuthenticateToken(db, token)
 	if err != nil {
 		LingEcho.AbortWithJSONError(c, http.StatusUnauthorized, err)
 		return
 	}
 	c.Set(constants.UserField, user)
+	c.Set(constants.SessionField, userSession)
 	c.Next()
 }
 
@@ -205,7 +226,17 @@ func CurrentUser(c *gin.Context) *User {
 	if err != nil {
 		return nil
 	}
+	key, _ := session.Get(constants.SessionKeyField).(string)
+	userSession, err := ValidateUserSession(db, user, SessionKindCookie, key)
+	if err != nil {
+		// 会话已在其他设备上被吊销，清除 Cookie
+		session.Delete(constants.UserField)
+		session.Delete(constants.SessionKeyField)
+		session.Save()
+		return nil
+	}
 	c.Set(constants.UserField, user)
+	c.Set(constants.SessionField, userSession)
 	return user
 }
 
@@ -289,15 +320,7 @@ func VerifyEncryptedPassword(encryptedPassword, storedPasswordHash string) bool
 
 func GetUserByUID(db *gorm.DB, userID uint) (*User, error) {
 	var val User
-	start := time.Now()
 	result := db.Where("id", userID).Where("enabled", true).Take(&val)
-	duration := time.Since(start)
-
-	// Record database query metrics (if monitoring system is available)
-	if monitor := getMonitorFromContext(db); monitor != nil {
-		monitor.RecordSQLQuery(context.Background(), "SELECT * FROM users WHERE id = ? AND enabled = ?",
-			[]interface{}{userID, true}, "users", "SELECT", duration, 1, result.Error)
-	}
 
 	if result.Error != nil {
 		return nil, result.Error
@@ -307,15 +330,7 @@ func GetUserByUID(db *gorm.DB, userID uint) (*User, error) {
 
 func GetUserByEmail(db *gorm.DB, email string) (user *User, err error) {
 	var val User
-	start := time.Now()
 	result := db.Table("users").Where("email", strings.ToLower(email)).Take(&val)
-	duration := time.Since(start)
-
-	// Record database query metrics (if monitoring system is available)
-	if monitor := getMonitorFromContext(db); monitor != nil {
-		monitor.RecordSQLQuery(context.Background(), "SELECT * FROM users WHERE email = ?",
-			[]interface{}{email}, "users", "SELECT", duration, 1, result.Error)
-	}
 
 	if result.Error != nil {
 		return nil, result.Error
@@ -323,12 +338,6 @@ func GetUserByEmail(db *gorm.DB, email string) (user *User, err error) {
 	return &val, nil
 }
 
-// getMonitorFromContext Get monitor from context (if available)
-func getMonitorFromContext(db *gorm.DB) *metrics.Monitor {
-	// Get monitor instance from global variable
-	return metrics.GetGlobalMonitor()
-}
-
 func IsExistsByEmail(db *gorm.DB, email string) bool {
 	_, err := GetUserByEmail(db, email)
 	return err == nil
@@ -379,12 +388,13 @@ func AuthApiRequired(c *gin.Context) {
 	db := c.MustGet(constants.DbField).(*gorm.DB)
 	// split bearer
 	token = strings.TrimPrefix(token, "Bearer ")
-	user, err := DecodeHashToken(db, token, false)
+	user, userSession, err := AuthenticateToken(db, token)
 	if err != nil {
 		LingEcho.AbortWithJSONError(c, http.StatusUnauthorized, err)
 		return
 	}
 	c.Set(constants.UserField, user)
+	c.Set(constants.SessionField, userSession)
 	c.Next()
 }
 
@@ -438,28 +448,12 @@ func CreateUser(db *gorm.DB, email, password string) (*User, error) {
 		Activated: false,
 	}
 
-	start := time.Now()
 	result := db.Create(&user)
-	duration := time.Since(start)
-
-	// 记录数据库查询指标（如果监控系统可用）
-	if monitor := getMonitorFromContext(db); monitor != nil {
-		monitor.RecordSQLQuery(context.Background(), "INSERT INTO users (email, password, enabled, activated) VALUES (?, ?, ?, ?)",
-			[]interface{}{email, user.Password, true, false}, "users", "INSERT", duration, 1, result.Error)
-	}
 
 	return &user, result.Error
 }
 func UpdateUserFields(db *gorm.DB, user *User, vals map[string]any) error {
-	start := time.Now()
 	result := db.Model(user).Updates(vals)
-	duration := time.Since(start)
-
-	// Record database query metrics (if monitoring system is available)
-	if monitor := getMonitorFromContext(db); monitor != nil {
-		monitor.RecordSQLQuery(context.Background(), "UPDATE users SET ... WHERE id = ?",
-			[]interface{}{user.ID}, "users", "UPDATE", duration, 1, result.Error)
-	}
 
 	return result.Error
 }
@@ -473,20 +467,17 @@ func SetLastLogin(db *gorm.DB, user *User, lastIp string) error {
 	user.LastLogin = &now
 	user.LastLoginIP = lastIp
 
-	start := time.Now()
 	result := db.Model(user).Updates(vals)
-	duration := time.Since(start)
-
-	// Record database query metrics (if monitoring system is available)
-	if monitor := getMonitorFromContext(db); monitor != nil {
-		monitor.RecordSQLQuery(context.Background(), "UPDATE users SET LastLoginIP = ?, LastLogin = ? WHERE id = ?",
-			[]interface{}{lastIp, &now, user.ID}, "users", "UPDATE", duration, 1, result.Error)
-	}
 
 	return result.Error
 }
 
 func EncodeHashToken(user *User, timestamp int64, useLastlogin bool) (hash string) {
+	return encodeHashToken(user, timestamp, useLastlogin, "")
+}
+
+// encodeHashToken nonce 不为空时附加在令牌中，使同一秒签发的登录令牌互不相同
+func encodeHashToken(user *User, timestamp int64, useLastlogin bool, nonce string) (hash string) {
 	//
 	// ts-uid-token
 	logintimestamp := "0"
@@ -494,6 +485,9 @@ func EncodeHashToken(user *User, timestamp int64, useLastlogin bool) (hash strin
 		logintimestamp = fmt.Sprintf("%d", user.LastLogin.Unix())
 	}
 	t := fmt.Sprintf("%s$%d", user.Email, timestamp)
+	if nonce != "" {
+		t += "$" + nonce
+	}
 	hashVal := sha256.Sum256([]byte(logintimestamp + user.Password + t))
 	hash = base64.RawStdEncoding.EncodeToString([]byte(t)) + "-" + fmt.Sprintf("%x", hashVal)
 	return hash
@@ -510,9 +504,13 @@ func DecodeHashToken(db *gorm.DB, hash string, useLastLogin bool) (user *User, e
 	}
 
 	vals = strings.Split(string(data), "$")
-	if len(vals) != 2 {
+	if len(vals) != 2 && len(vals) != 3 {
 		return nil, errors.New("bad token")
 	}
+	nonce := ""
+	if len(vals) == 3 {
+		nonce = vals[2]
+	}
 
 	ts, err := strconv.ParseInt(vals[1], 10, 64)
 	if err != nil {
@@ -527,7 +525,7 @@ func DecodeHashToken(db *gorm.DB, hash string, useLastLogin bool) (user *User, e
 	if err != nil {
 		return nil, errors.New("bad token")
 	}
-	token := EncodeHashToken(user, ts, useLastLogin)
+	token := encodeHashToken(user, ts, useLastLogin, nonce)
 	if token != hash {
 		return nil, errors.New("bad token")
 	}
@@ -882,7 +880,9 @@ func IncrementLoginCount(db *gorm.DB, user *User) error {
 const (
 	RoleSuperAdmin = "superadmin" // 超级管理员
 	RoleAdmin      = "admin"      // 管理员
+	RoleOperator   = "operator"   // 运维人员：查看监控指标、发布工作流
 	RoleUser       = "user"       // 普通用户
+	RoleMember     = RoleUser     // 普通成员，与 user 角色相同
 )
 
 // 权限常量
@@ -894,8 +894,61 @@ const (
 	PermissionUserWrite    = "user.write"    // 用户写入
 	PermissionSearchConfig = "search.config" // 搜索配置
 	PermissionSystemConfig = "system.config" // 系统配置
+
+	PermissionMetricsRead     = "metrics.read"     // 查看监控指标与后台任务状态
+	PermissionUserManage      = "user.manage"      // 管理用户账号与角色
+	PermissionWorkflowPublish = "workflow.publish" // 发布工作流
+	PermissionMCPManage       = "mcp.manage"       // 管理 MCP 工具注册表
+	PermissionAuditRead       = "audit.read"       // 查看与导出审计日志
 )
 
+// rolePermissions 各角色的默认权限，超级管理员拥有所有权限不在此列出
+var rolePermissions = map[string][]string{
+	RoleAdmin: {
+		PermissionAdminRead, PermissionAdminWrite,
+		PermissionUserRead, PermissionUserWrite,
+		PermissionSearchConfig, PermissionSystemConfig,
+		PermissionMetricsRead, PermissionUserManage,
+		PermissionWorkflowPublish, PermissionMCPManage,
+		PermissionAuditRead,
+	},
+	RoleOperator: {
+		PermissionUserRead, PermissionUserWrite,
+		PermissionMetricsRead, PermissionWorkflowPublish,
+	},
+	// 普通用户可以发布自己创建的工作流，发布接口另外校验所有权
+	RoleUser: {
+		PermissionUserRead, PermissionUserWrite,
+		PermissionWorkflowPublish,
+	},
+}
+
+// Roles 返回所有内置角色，按权限从高到低排列
+func Roles() []string {
+	return []string{RoleSuperAdmin, RoleAdmin, RoleOperator, RoleUser}
+}
+
+// IsValidRole 检查是否为内置角色
+func IsValidRole(role string) bool {
+	for _, r := range Roles() {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissions 返回角色的默认权限，超级管理员返回 ["*"]
+func RolePermissions(role string) []string {
+	if role == RoleSuperAdmin {
+		return []string{PermissionAll}
+	}
+	if role == "" {
+		role = RoleUser
+	}
+	return append([]string(nil), rolePermissions[role]...)
+}
+
 // getPermissions 解析用户权限 JSON 字符串，返回权限列表
 func (u *User) getPermissions() []string {
 	if u.Permissions == "" {
@@ -942,19 +995,8 @@ func (u *User) HasPermission(permission string) bool {
 	}
 
 	// 基于角色的默认权限
-	switch u.Role {
-	case RoleAdmin:
-		// 管理员默认拥有 admin 和 user 相关权限
-		switch permission {
-		case PermissionAdminRead, PermissionAdminWrite,
-			PermissionUserRead, PermissionUserWrite,
-			PermissionSearchConfig, PermissionSystemConfig:
-			return true
-		}
-	case RoleUser:
-		// 普通用户默认拥有 user 相关权限
-		switch permission {
-		case PermissionUserRead, PermissionUserWrite:
+	for _, p := range RolePermissions(u.Role) {
+		if p == permission {
 			return true
 		}
 	}
@@ -962,6 +1004,65 @@ func (u *User) HasPermission(permission string) bool {
 	return false
 }
 
+// CheckRoleChange 检查 operator 能否把 current（新建用户时为 nil）的角色与权限改为 target：
+// 只有超级管理员能修改其他管理员或超级管理员，也只有超级管理员能将用户提升为管理员；其余规则见 CheckGrantable
+func CheckRoleChange(operator, current, target *User) error {
+	if operator == nil {
+		return errors.New("login required")
+	}
+	if !operator.IsSuperAdmin() {
+		if current != nil && current.ID != operator.ID && current.IsAdmin() {
+			return errors.New("only superadmin can change an admin")
+		}
+		if target.Role == RoleAdmin && (current == nil || current.Role != RoleAdmin) {
+			return errors.New("only superadmin can grant the admin role")
+		}
+	}
+	return CheckGrantable(operator, target)
+}
+
+// CheckGrantable 检查 operator 能否将 target 设置为当前的角色与权限列表：
+// 角色必须是内置角色，只有超级管理员能授予超级管理员角色，权限列表中的权限 operator 自己必须拥有
+func CheckGrantable(operator, target *User) error {
+	if operator == nil {
+		return errors.New("login required")
+	}
+	if target.Role != "" && !IsValidRole(target.Role) {
+		return fmt.Errorf("unknown role: %s", target.Role)
+	}
+	if target.Role == RoleSuperAdmin && !operator.IsSuperAdmin() {
+		return errors.New("only superadmin can grant the superadmin role")
+	}
+	if target.Permissions != "" {
+		var perms []string
+		if err := json.Unmarshal([]byte(target.Permissions), &perms); err != nil {
+			return fmt.Errorf("invalid permissions: %w", err)
+		}
+		for _, p := range perms {
+			if (p == PermissionAll && !operator.IsSuperAdmin()) || !operator.HasPermission(p) {
+				return fmt.Errorf("cannot grant permission: %s", p)
+			}
+		}
+	}
+	return nil
+}
+
+// EffectivePermissions 返回用户实际拥有的权限：角色默认权限与权限列表的并集
+func (u *User) EffectivePermissions() []string {
+	perms := RolePermissions(u.Role)
+	seen := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		seen[p] = true
+	}
+	for _, p := range u.getPermissions() {
+		if !seen[p] {
+			seen[p] = true
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
 // HasAnyPermission 检查是否有任意一个权限
 func (u *User) HasAnyPermission(permissions ...string) bool {
 	for _, perm := range permissions {
@@ -24,7 +24,7 @@ func init() {
 }
 
 func setupHandlerTestDB(t *testing.T) *gorm.DB {
-	return setupTestDBWithSilentLogger(t, &User{}, &UserCredential{})
+	return setupTestDBWithSilentLogger(t, &User{}, &UserCredential{}, &UserSession{})
 }
 
 func setupHandlerTestRouter(t *testing.T, db *gorm.DB) *gin.Engine {
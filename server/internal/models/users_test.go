@@ -900,6 +900,69 @@ func TestCalculateProfileComplete_Minimal(t *testing.T) {
 	assert.LessOrEqual(t, complete, 30)
 }
 
+func TestUser_RolePermissions(t *testing.T) {
+	operator := &User{Role: RoleOperator}
+	assert.True(t, operator.HasPermission(PermissionMetricsRead))
+	assert.True(t, operator.HasPermission(PermissionWorkflowPublish))
+	assert.False(t, operator.HasPermission(PermissionUserManage))
+	assert.False(t, operator.HasPermission(PermissionMCPManage))
+
+	member := &User{Role: RoleMember}
+	// 普通成员可以发布自己的工作流，所有权由发布接口校验
+	assert.True(t, member.HasPermission(PermissionWorkflowPublish))
+	assert.False(t, member.HasPermission(PermissionMetricsRead))
+
+	// 未设置角色视为普通成员
+	assert.True(t, (&User{}).HasPermission(PermissionUserRead))
+
+	admin := &User{Role: RoleAdmin}
+	assert.True(t, admin.HasAllPermissions(PermissionUserManage, PermissionMCPManage, PermissionMetricsRead))
+
+	member.Permissions = `["metrics.read","user.read"]`
+	assert.ElementsMatch(t, []string{PermissionUserRead, PermissionUserWrite, PermissionWorkflowPublish, PermissionMetricsRead}, member.EffectivePermissions())
+	assert.Equal(t, []string{PermissionAll}, (&User{Role: RoleSuperAdmin}).EffectivePermissions())
+}
+
+func TestCheckGrantable(t *testing.T) {
+	superAdmin := &User{Role: RoleSuperAdmin}
+	admin := &User{Role: RoleAdmin}
+
+	assert.NoError(t, CheckGrantable(admin, &User{Role: RoleOperator}))
+	assert.NoError(t, CheckGrantable(superAdmin, &User{Role: RoleSuperAdmin}))
+	assert.Error(t, CheckGrantable(admin, &User{Role: RoleSuperAdmin}))
+	assert.Error(t, CheckGrantable(admin, &User{Role: "root"}))
+	assert.Error(t, CheckGrantable(nil, &User{Role: RoleUser}))
+
+	// 不能授予自己没有的权限
+	assert.NoError(t, CheckGrantable(admin, &User{Role: RoleUser, Permissions: `["metrics.read"]`}))
+	assert.Error(t, CheckGrantable(admin, &User{Role: RoleUser, Permissions: `["*"]`}))
+	assert.Error(t, CheckGrantable(&User{Role: RoleOperator}, &User{Role: RoleUser, Permissions: `["user.manage"]`}))
+	assert.Error(t, CheckGrantable(admin, &User{Role: RoleUser, Permissions: `not-json`}))
+}
+
+func TestCheckRoleChange(t *testing.T) {
+	superAdmin := &User{Role: RoleSuperAdmin}
+	superAdmin.ID = 1
+	admin := &User{Role: RoleAdmin}
+	admin.ID = 2
+	otherAdmin := &User{Role: RoleAdmin}
+	otherAdmin.ID = 3
+	member := &User{Role: RoleUser}
+	member.ID = 4
+
+	// 管理员之间不能互相修改，也不能提升普通用户为管理员
+	assert.Error(t, CheckRoleChange(admin, otherAdmin, &User{Role: RoleUser}))
+	assert.Error(t, CheckRoleChange(admin, member, &User{Role: RoleAdmin}))
+	assert.Error(t, CheckRoleChange(admin, nil, &User{Role: RoleAdmin}))
+	assert.NoError(t, CheckRoleChange(admin, member, &User{Role: RoleOperator}))
+	// 修改自己的资料时保持原有的管理员角色
+	assert.NoError(t, CheckRoleChange(admin, admin, &User{Role: RoleAdmin}))
+
+	assert.NoError(t, CheckRoleChange(superAdmin, otherAdmin, &User{Role: RoleUser}))
+	assert.NoError(t, CheckRoleChange(superAdmin, member, &User{Role: RoleAdmin}))
+	assert.Error(t, CheckRoleChange(nil, member, &User{Role: RoleUser}))
+}
+
 func TestUser_HasPermission_EdgeCases(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"gorm.io/gorm"
+)
+
+// WebAuthnCredential 用户注册的 WebAuthn 通行密钥
+type WebAuthnCredential struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"index;not null" json:"userId"`
+	CredentialID string     `gorm:"size:512;uniqueIndex:idx_webauthn_credential_id,length:191;not null" json:"-"` // 凭证ID（base64url）
+	Name         string     `gorm:"size:128" json:"name"`                                                         // 用户为密钥设置的名称
+	Data         string     `gorm:"type:text;not null" json:"-"`                                                  // webauthn.Credential 的 JSON（公钥、签名计数等）
+	LastUsedAt   *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+// TableName 指定表名
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+// Credential 解析保存的 webauthn.Credential
+func (w *WebAuthnCredential) Credential() (webauthn.Credential, error) {
+	var credential webauthn.Credential
+	err := json.Unmarshal([]byte(w.Data), &credential)
+	return credential, err
+}
+
+// WebAuthnUser 将 User 与其通行密钥适配为 webauthn.User
+type WebAuthnUser struct {
+	*User
+	Credentials []webauthn.Credential
+}
+
+// WebAuthnID 用户句柄，使用用户ID，登录时据此找回用户
+func (u *WebAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatUint(uint64(u.ID), 10))
+}
+
+func (u *WebAuthnUser) WebAuthnName() string {
+	return u.Email
+}
+
+func (u *WebAuthnUser) WebAuthnDisplayName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Email
+}
+
+func (u *WebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.Credentials
+}
+
+// ParseWebAuthnUserHandle 从用户句柄解析用户ID
+func ParseWebAuthnUserHandle(userHandle []byte) (uint, error) {
+	id, err := strconv.ParseUint(string(userHandle), 10, 64)
+	return uint(id), err
+}
+
+// NewDecoyWebAuthnUser 为不存在或未注册通行密钥的邮箱生成替身用户，登录挑战与真实用户形式相同，无法据此判断账号是否存在。
+// 凭证ID由 secret 与邮箱派生，同一邮箱每次相同；用户ID为 0，完成登录时一定失败
+func NewDecoyWebAuthnUser(email, secret string) *WebAuthnUser {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("webauthn-decoy:" + strings.ToLower(strings.TrimSpace(email))))
+	return &WebAuthnUser{
+		User:        &User{Email: email},
+		Credentials: []webauthn.Credential{{ID: mac.Sum(nil)}},
+	}
+}
+
+// LoadWebAuthnUser 加载用户的全部通行密钥
+func LoadWebAuthnUser(db *gorm.DB, user *User) (*WebAuthnUser, error) {
+	records, err := GetWebAuthnCredentials(db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	webauthnUser := &WebAuthnUser{User: user, Credentials: make([]webauthn.Credential, 0, len(records))}
+	for i := range records {
+		credential, err := records[i].Credential()
+		if err != nil {
+			return nil, err
+		}
+		webauthnUser.Credentials = append(webauthnUser.Credentials, credential)
+	}
+	return webauthnUser, nil
+}
+
+// GetWebAuthnCredentials 获取用户的通行密钥列表
+func GetWebAuthnCredentials(db *gorm.DB, userID uint) ([]WebAuthnCredential, error) {
+	var records []WebAuthnCredential
+	err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&records).Error
+	return records, err
+}
+
+// CreateWebAuthnCredential 保存新注册的通行密钥
+func CreateWebAuthnCredential(db *gorm.DB, userID uint, name string, credential *webauthn.Credential) (*WebAuthnCredential, error) {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return nil, err
+	}
+	record := &WebAuthnCredential{
+		UserID:       userID,
+		CredentialID: base64.RawURLEncoding.EncodeToString(credential.ID),
+		Name:         name,
+		Data:         string(data),
+	}
+	if err := db.Create(record).Error; err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// UpdateWebAuthnCredentialUsage 登录成功后保存新的签名计数并更新最后使用时间
+func UpdateWebAuthnCredentialUsage(db *gorm.DB, userID uint, credential *webauthn.Credential) error {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	return db.Model(&WebAuthnCredential{}).
+		Where("user_id = ? AND credential_id = ?", userID, base64.RawURLEncoding.EncodeToString(credential.ID)).
+		Updates(map[string]any{"data": string(data), "last_used_at": time.Now()}).Error
+}
+
+// DeleteWebAuthnCredential 删除用户的通行密钥
+func DeleteWebAuthnCredential(db *gorm.DB, userID, id uint) error {
+	result := db.Where("id = ? AND user_id = ?", id, userID).Delete(&WebAuthnCredential{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
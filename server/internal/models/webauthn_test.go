@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestWebAuthnCredential_Lifecycle(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &WebAuthnCredential{})
+	user := &User{ID: 42, Email: "passkey@example.com"}
+
+	credential := &webauthn.Credential{
+		ID:        []byte{1, 2, 3, 4},
+		PublicKey: []byte("public-key"),
+		Authenticator: webauthn.Authenticator{
+			SignCount: 1,
+		},
+	}
+	record, err := CreateWebAuthnCredential(db, user.ID, "Laptop", credential)
+	require.NoError(t, err)
+	assert.Equal(t, "AQIDBA", record.CredentialID)
+
+	webauthnUser, err := LoadWebAuthnUser(db, user)
+	require.NoError(t, err)
+	require.Len(t, webauthnUser.WebAuthnCredentials(), 1)
+	assert.Equal(t, credential.PublicKey, webauthnUser.WebAuthnCredentials()[0].PublicKey)
+	assert.Equal(t, "passkey@example.com", webauthnUser.WebAuthnDisplayName())
+
+	// 用户句柄可以还原出用户ID
+	userID, err := ParseWebAuthnUserHandle(webauthnUser.WebAuthnID())
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, userID)
+
+	// 登录后保存新的签名计数
+	credential.Authenticator.SignCount = 5
+	require.NoError(t, UpdateWebAuthnCredentialUsage(db, user.ID, credential))
+	records, err := GetWebAuthnCredentials(db, user.ID)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.NotNil(t, records[0].LastUsedAt)
+	saved, err := records[0].Credential()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(5), saved.Authenticator.SignCount)
+
+	// 只能删除自己的密钥
+	assert.ErrorIs(t, DeleteWebAuthnCredential(db, 7, record.ID), gorm.ErrRecordNotFound)
+	require.NoError(t, DeleteWebAuthnCredential(db, user.ID, record.ID))
+	records, err = GetWebAuthnCredentials(db, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestNewDecoyWebAuthnUser(t *testing.T) {
+	decoy := NewDecoyWebAuthnUser("ghost@example.com", "secret")
+	require.Len(t, decoy.WebAuthnCredentials(), 1)
+	assert.Len(t, decoy.WebAuthnCredentials()[0].ID, 32)
+
+	// 同一邮箱的凭证ID保持不变，不同邮箱或密钥得到不同的ID
+	assert.Equal(t, decoy.Credentials[0].ID, NewDecoyWebAuthnUser(" Ghost@example.com", "secret").Credentials[0].ID)
+	assert.NotEqual(t, decoy.Credentials[0].ID, NewDecoyWebAuthnUser("other@example.com", "secret").Credentials[0].ID)
+	assert.NotEqual(t, decoy.Credentials[0].ID, NewDecoyWebAuthnUser("ghost@example.com", "other").Credentials[0].ID)
+
+	// 用户句柄不对应任何用户
+	userID, err := ParseWebAuthnUserHandle(decoy.WebAuthnID())
+	require.NoError(t, err)
+	assert.Zero(t, userID)
+}
@@ -0,0 +1,355 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Webhook 平台事件
+const (
+	WebhookEventCallEnded         = "call.ended"         // 通话结束，数据为话单
+	WebhookEventTranscriptReady   = "transcript.ready"   // 通话转写已生成
+	WebhookEventTrainingCompleted = "training.completed" // 音色训练结束（成功或失败）
+	WebhookEventPing              = "webhook.ping"       // 测试事件，总是投递
+)
+
+// Webhook 投递状态
+const (
+	WebhookDeliveryPending   = "pending"   // 等待投递或重试
+	WebhookDeliverySucceeded = "succeeded" // 对方返回 2xx
+	WebhookDeliveryFailed    = "failed"    // 重试次数用尽
+)
+
+const (
+	// WebhookMaxAttempts 单次投递最多尝试的次数（含首次）
+	WebhookMaxAttempts = 8
+	// 重试间隔从 webhookRetryBase 开始指数增长，最长 webhookRetryMax
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = time.Hour
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// WebhookEvents 返回所有可订阅的事件
+func WebhookEvents() []string {
+	return []string{WebhookEventCallEnded, WebhookEventTranscriptReady, WebhookEventTrainingCompleted}
+}
+
+// Webhook 用户配置的平台事件回调地址
+type Webhook struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+
+	UserID  uint   `gorm:"index;not null" json:"userId"`
+	Name    string `gorm:"size:100" json:"name"`
+	URL     string `gorm:"size:500;not null" json:"url"`
+	Secret  string `gorm:"size:100" json:"-"`      // 签名密钥，只在创建时返回一次
+	Events  string `gorm:"size:255" json:"events"` // 逗号分隔的订阅事件，为空表示全部事件
+	Enabled bool   `json:"enabled"`
+
+	LastDeliveryAt     *time.Time `json:"lastDeliveryAt,omitempty"`                    // 最近一次投递时间
+	LastDeliveryStatus string     `gorm:"size:20" json:"lastDeliveryStatus,omitempty"` // 最近一次投递结果
+}
+
+// TableName 指定表名
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Subscribes 判断是否订阅了事件
+func (w *Webhook) Subscribes(event string) bool {
+	if event == WebhookEventPing || strings.TrimSpace(w.Events) == "" {
+		return true
+	}
+	for _, e := range strings.Split(w.Events, ",") {
+		if strings.TrimSpace(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseWebhookEvents 校验订阅事件并返回去重后的逗号分隔形式
+func ParseWebhookEvents(events []string) (string, error) {
+	valid := make(map[string]bool)
+	for _, event := range WebhookEvents() {
+		valid[event] = true
+	}
+	seen := make(map[string]bool)
+	var list []string
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !valid[event] {
+			return "", fmt.Errorf("unknown webhook event: %s", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			list = append(list, event)
+		}
+	}
+	return strings.Join(list, ","), nil
+}
+
+// NewWebhookSecret 生成签名密钥
+func NewWebhookSecret() string {
+	buf := make([]byte, 24)
+	rand.Read(buf)
+	return "whsec_" + hex.EncodeToString(buf)
+}
+
+// WebhookDelivery Webhook 投递记录，失败后按指数退避重试，同时作为投递日志
+type WebhookDelivery struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+
+	WebhookID uint   `gorm:"uniqueIndex:idx_webhook_delivery_event;not null" json:"webhookId"`
+	UserID    uint   `gorm:"index;not null" json:"userId"`
+	EventID   string `gorm:"size:128;uniqueIndex:idx_webhook_delivery_event;not null" json:"eventId"` // 事件 ID，同一事件对同一 Webhook 只投递一次
+	Event     string `gorm:"size:64;index" json:"event"`
+	Payload   string `gorm:"type:text" json:"payload"` // 请求体 JSON
+
+	Status         string     `gorm:"size:20;index" json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `gorm:"index" json:"nextAttemptAt,omitempty"` // 下次尝试时间，投递结束后为空
+	LastAttemptAt  *time.Time `json:"lastAttemptAt,omitempty"`
+	ResponseStatus int        `json:"responseStatus,omitempty"`        // 最近一次响应状态码，响应内容不保存，避免借 Webhook 读取其他服务的响应
+	Error          string     `gorm:"size:500" json:"error,omitempty"` // 最近一次错误
+	DurationMs     int64      `json:"durationMs"`                      // 最近一次请求耗时
+}
+
+// TableName 指定表名
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookPayload 投递给对方的请求体
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookRetryDelay 第 attempt 次尝试失败后到下次重试的间隔
+func WebhookRetryDelay(attempt int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempt && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMax {
+		delay = webhookRetryMax
+	}
+	return delay
+}
+
+// EnqueueWebhookEvent 为用户订阅了该事件的 Webhook 创建投递记录，返回创建的记录数。
+// eventID 为空时自动生成；相同 eventID 的事件尚未开始投递时只更新数据，便于分多次写入的事件合并为一次投递。
+// delay 为首次投递前的等待时间
+func EnqueueWebhookEvent(db *gorm.DB, userID uint, event, eventID string, data interface{}, delay time.Duration) (int, error) {
+	var webhooks []Webhook
+	if err := db.Where("user_id = ? AND enabled = ?", userID, true).Find(&webhooks).Error; err != nil {
+		return 0, err
+	}
+	if eventID == "" {
+		eventID = "evt_" + uuid.NewString()
+	}
+	now := time.Now()
+	payload, err := json.Marshal(WebhookPayload{ID: eventID, Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		return 0, err
+	}
+	nextAttempt := now.Add(delay)
+
+	created := 0
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		var existing WebhookDelivery
+		err := db.Where("webhook_id = ? AND event_id = ?", webhook.ID, eventID).First(&existing).Error
+		switch {
+		case err == nil:
+			if existing.Attempts == 0 && existing.Status == WebhookDeliveryPending {
+				if err := db.Model(&existing).Update("payload", string(payload)).Error; err != nil {
+					return created, err
+				}
+			}
+			continue
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return created, err
+		}
+		delivery := WebhookDelivery{
+			WebhookID:     webhook.ID,
+			UserID:        userID,
+			EventID:       eventID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        WebhookDeliveryPending,
+			NextAttemptAt: &nextAttempt,
+		}
+		if err := db.Create(&delivery).Error; err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
+
+// CreateWebhookPing 为 Webhook 创建一条测试投递，由调用方立即发送，不进入重试队列
+func CreateWebhookPing(db *gorm.DB, webhook *Webhook) (*WebhookDelivery, error) {
+	eventID := "evt_" + uuid.NewString()
+	payload, err := json.Marshal(WebhookPayload{
+		ID:        eventID,
+		Event:     WebhookEventPing,
+		CreatedAt: time.Now(),
+		Data:      map[string]interface{}{"webhookId": webhook.ID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	delivery := &WebhookDelivery{
+		WebhookID: webhook.ID,
+		UserID:    webhook.UserID,
+		EventID:   eventID,
+		Event:     WebhookEventPing,
+		Payload:   string(payload),
+		Status:    WebhookDeliveryPending,
+	}
+	if err := db.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// GetDueWebhookDeliveries 获取到达重试时间的投递
+func GetDueWebhookDeliveries(db *gorm.DB, now time.Time, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := db.Where("status = ? AND next_attempt_at <= ?", WebhookDeliveryPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimWebhookDelivery 将投递的下次尝试时间推迟 lease，防止重叠的投递任务重复发送；返回是否抢到
+func ClaimWebhookDelivery(db *gorm.DB, delivery *WebhookDelivery, lease time.Duration) (bool, error) {
+	now := time.Now()
+	next := now.Add(lease)
+	result := db.Model(&WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, WebhookDeliveryPending, now).
+		Update("next_attempt_at", next)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	delivery.NextAttemptAt = &next
+	return true, nil
+}
+
+// RecordWebhookAttempt 记录一次投递结果；retry 为 true 且失败次数未达上限时安排下次重试，
+// 手动重新投递和测试事件只尝试一次
+func RecordWebhookAttempt(db *gorm.DB, delivery *WebhookDelivery, statusCode int, attemptErr error, duration time.Duration, retry bool) error {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = statusCode
+	delivery.DurationMs = duration.Milliseconds()
+	delivery.Error = ""
+	delivery.NextAttemptAt = nil
+
+	switch {
+	case attemptErr == nil:
+		delivery.Status = WebhookDeliverySucceeded
+	case !retry || delivery.Attempts >= WebhookMaxAttempts:
+		delivery.Status = WebhookDeliveryFailed
+		delivery.Error = webhookErrorText(attemptErr)
+	default:
+		delivery.Status = WebhookDeliveryPending
+		delivery.Error = webhookErrorText(attemptErr)
+		next := now.Add(WebhookRetryDelay(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"last_attempt_at": delivery.LastAttemptAt,
+			"response_status": delivery.ResponseStatus,
+			"error":           delivery.Error,
+			"duration_ms":     delivery.DurationMs,
+		}).Error; err != nil {
+			return err
+		}
+		status := WebhookDeliverySucceeded
+		if attemptErr != nil {
+			status = WebhookDeliveryFailed
+		}
+		return tx.Model(&Webhook{}).Where("id = ?", delivery.WebhookID).Updates(map[string]interface{}{
+			"last_delivery_at":     now,
+			"last_delivery_status": status,
+		}).Error
+	})
+}
+
+// GetUserWebhook 获取用户的 Webhook
+func GetUserWebhook(db *gorm.DB, userID, id uint) (*Webhook, error) {
+	var webhook Webhook
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&webhook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook 删除 Webhook 及其投递记录
+func DeleteWebhook(db *gorm.DB, webhook *Webhook) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(webhook).Error
+	})
+}
+
+// GetWebhookDeliveries 分页获取 Webhook 的投递记录，status 为空表示全部
+func GetWebhookDeliveries(db *gorm.DB, webhookID uint, status string, page, size int) ([]WebhookDelivery, int64, error) {
+	query := db.Model(&WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var deliveries []WebhookDelivery
+	err := query.Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+// CleanupWebhookDeliveries 删除 before 之前已结束的投递记录
+func CleanupWebhookDeliveries(db *gorm.DB, before time.Time) (int64, error) {
+	result := db.Where("status <> ? AND created_at < ?", WebhookDeliveryPending, before).Delete(&WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
+
+// webhookErrorText 截断错误信息以适应 error 列长度
+func webhookErrorText(err error) string {
+	text := err.Error()
+	if len(text) > 490 {
+		text = text[:490] + "..."
+	}
+	return text
+}
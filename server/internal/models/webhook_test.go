@@ -0,0 +1,163 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWebhookEvents(t *testing.T) {
+	events, err := ParseWebhookEvents([]string{"call.ended", " transcript.ready", "call.ended"})
+	require.NoError(t, err)
+	assert.Equal(t, "call.ended,transcript.ready", events)
+
+	events, err = ParseWebhookEvents(nil)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	_, err = ParseWebhookEvents([]string{"user.deleted"})
+	assert.Error(t, err)
+}
+
+func TestWebhook_Subscribes(t *testing.T) {
+	all := &Webhook{}
+	assert.True(t, all.Subscribes(WebhookEventCallEnded))
+
+	calls := &Webhook{Events: "call.ended,transcript.ready"}
+	assert.True(t, calls.Subscribes(WebhookEventTranscriptReady))
+	assert.False(t, calls.Subscribes(WebhookEventTrainingCompleted))
+	assert.True(t, calls.Subscribes(WebhookEventPing))
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, WebhookRetryDelay(1))
+	assert.Equal(t, time.Minute, WebhookRetryDelay(2))
+	assert.Equal(t, 8*time.Minute, WebhookRetryDelay(5))
+	assert.Equal(t, time.Hour, WebhookRetryDelay(20))
+}
+
+func TestEnqueueWebhookEvent(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &Webhook{}, &WebhookDelivery{})
+	require.NoError(t, db.Create(&Webhook{UserID: 1, URL: "https://a.example.com", Enabled: true}).Error)
+	require.NoError(t, db.Create(&Webhook{UserID: 1, URL: "https://b.example.com", Events: WebhookEventTrainingCompleted, Enabled: true}).Error)
+	require.NoError(t, db.Create(&Webhook{UserID: 1, URL: "https://c.example.com", Enabled: false}).Error)
+	require.NoError(t, db.Create(&Webhook{UserID: 2, URL: "https://d.example.com", Enabled: true}).Error)
+
+	n, err := EnqueueWebhookEvent(db, 1, WebhookEventCallEnded, "call.ended:abc", map[string]interface{}{"duration": 0}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// 同一事件再次写入时只更新尚未投递的数据
+	n, err = EnqueueWebhookEvent(db, 1, WebhookEventCallEnded, "call.ended:abc", map[string]interface{}{"duration": 42}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	var deliveries []WebhookDelivery
+	require.NoError(t, db.Find(&deliveries).Error)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, WebhookDeliveryPending, deliveries[0].Status)
+	var payload WebhookPayload
+	require.NoError(t, json.Unmarshal([]byte(deliveries[0].Payload), &payload))
+	assert.Equal(t, "call.ended:abc", payload.ID)
+	assert.Equal(t, WebhookEventCallEnded, payload.Event)
+	assert.Equal(t, float64(42), payload.Data.(map[string]interface{})["duration"])
+
+	n, err = EnqueueWebhookEvent(db, 1, WebhookEventTrainingCompleted, "", nil, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	due, err := GetDueWebhookDeliveries(db, time.Now(), 10)
+	require.NoError(t, err)
+	assert.Len(t, due, 1, "delayed deliveries are not due yet")
+}
+
+func TestRecordWebhookAttempt(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &Webhook{}, &WebhookDelivery{})
+	webhook := &Webhook{UserID: 1, URL: "https://a.example.com", Enabled: true}
+	require.NoError(t, db.Create(webhook).Error)
+	_, err := EnqueueWebhookEvent(db, 1, WebhookEventTranscriptReady, "", nil, 0)
+	require.NoError(t, err)
+
+	due, err := GetDueWebhookDeliveries(db, time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	delivery := &due[0]
+
+	claimed, err := ClaimWebhookDelivery(db, delivery, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = ClaimWebhookDelivery(db, &due[0], time.Minute)
+	require.NoError(t, err)
+	assert.False(t, claimed, "a claimed delivery cannot be claimed again")
+
+	// 失败后按退避时间重试
+	require.NoError(t, RecordWebhookAttempt(db, delivery, 500, errors.New("status 500"), 20*time.Millisecond, true))
+	var stored WebhookDelivery
+	require.NoError(t, db.First(&stored, delivery.ID).Error)
+	assert.Equal(t, WebhookDeliveryPending, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Equal(t, 500, stored.ResponseStatus)
+	require.NotNil(t, stored.NextAttemptAt)
+	assert.WithinDuration(t, time.Now().Add(WebhookRetryDelay(1)), *stored.NextAttemptAt, 5*time.Second)
+
+	// 达到最大次数后不再重试
+	stored.Attempts = WebhookMaxAttempts - 1
+	require.NoError(t, RecordWebhookAttempt(db, &stored, 0, errors.New("timeout"), time.Second, true))
+	require.NoError(t, db.First(&stored, delivery.ID).Error)
+	assert.Equal(t, WebhookDeliveryFailed, stored.Status)
+	assert.Nil(t, stored.NextAttemptAt)
+	assert.Equal(t, "timeout", stored.Error)
+
+	require.NoError(t, RecordWebhookAttempt(db, &stored, 200, nil, time.Second, false))
+	require.NoError(t, db.First(&stored, delivery.ID).Error)
+	assert.Equal(t, WebhookDeliverySucceeded, stored.Status)
+	assert.Empty(t, stored.Error)
+	require.NoError(t, db.First(webhook, webhook.ID).Error)
+	assert.Equal(t, WebhookDeliverySucceeded, webhook.LastDeliveryStatus)
+
+	list, total, err := GetWebhookDeliveries(db, webhook.ID, WebhookDeliverySucceeded, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, list, 1)
+
+	deleted, err := CleanupWebhookDeliveries(db, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestCreateWebhookPing(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &Webhook{}, &WebhookDelivery{})
+	webhook := &Webhook{UserID: 1, URL: "https://a.example.com", Events: WebhookEventCallEnded, Enabled: true}
+	require.NoError(t, db.Create(webhook).Error)
+
+	delivery, err := CreateWebhookPing(db, webhook)
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEventPing, delivery.Event)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	due, err := GetDueWebhookDeliveries(db, time.Now(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, due, "pings are sent by the caller, not the dispatcher")
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	db := setupTestDBWithSilentLogger(t, &Webhook{}, &WebhookDelivery{})
+	webhook := &Webhook{UserID: 1, URL: "https://a.example.com", Enabled: true}
+	require.NoError(t, db.Create(webhook).Error)
+	_, err := EnqueueWebhookEvent(db, 1, WebhookEventCallEnded, "", nil, 0)
+	require.NoError(t, err)
+
+	_, err = GetUserWebhook(db, 2, webhook.ID)
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+
+	found, err := GetUserWebhook(db, 1, webhook.ID)
+	require.NoError(t, err)
+	require.NoError(t, DeleteWebhook(db, found))
+
+	var count int64
+	db.Model(&WebhookDelivery{}).Count(&count)
+	assert.Zero(t, count)
+}
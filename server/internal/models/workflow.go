@@ -7,46 +7,65 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // WorkflowDefinition describes a reusable workflow template whose structure is stored as JSON graph data.
 type WorkflowDefinition struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	UserID      uint           `json:"userId" gorm:"index"`            // 用户ID
-	GroupID     *uint          `json:"groupId,omitempty" gorm:"index"` // 组织ID，如果设置则表示这是组织共享的工作流
-	Name        string         `json:"name" gorm:"size:128;not null"`
-	Slug        string         `json:"slug" gorm:"size:128;uniqueIndex"`
-	Description string         `json:"description" gorm:"type:text"`
-	Version     uint           `json:"version" gorm:"default:1"`
-	Status      string         `json:"status" gorm:"size:32;default:'draft'"` // draft, active, archived
-	Definition  WorkflowGraph  `json:"definition" gorm:"type:json"`           // 节点及连线的 JSON 编排
-	Settings    JSONMap        `json:"settings" gorm:"type:json"`             // 全局配置，比如默认超时、重试策略
-	Triggers    JSONMap        `json:"triggers,omitempty" gorm:"type:json"`   // 触发器配置
-	Tags        StringArray    `json:"tags" gorm:"type:json"`
-	CreatedBy   string         `json:"createdBy" gorm:"size:64"`
-	UpdatedBy   string         `json:"updatedBy" gorm:"size:64"`
-	CreatedAt   time.Time      `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	UserID           uint           `json:"userId" gorm:"index"`            // 用户ID
+	GroupID          *uint          `json:"groupId,omitempty" gorm:"index"` // 组织ID，如果设置则表示这是组织共享的工作流
+	Name             string         `json:"name" gorm:"size:128;not null"`
+	Slug             string         `json:"slug" gorm:"size:128;uniqueIndex"`
+	Description      string         `json:"description" gorm:"type:text"`
+	Version          uint           `json:"version" gorm:"default:1"`
+	Status           string         `json:"status" gorm:"size:32;default:'draft'"` // draft, active, archived
+	PublishedVersion uint           `json:"publishedVersion" gorm:"default:0"`     // 线上执行的版本，0 表示从未发布（触发器执行草稿）
+	PublishedAt      *time.Time     `json:"publishedAt,omitempty"`
+	Definition       WorkflowGraph  `json:"definition" gorm:"type:json"`         // 节点及连线的 JSON 编排
+	Settings         JSONMap        `json:"settings" gorm:"type:json"`           // 全局配置，比如默认超时、重试策略
+	Triggers         JSONMap        `json:"triggers,omitempty" gorm:"type:json"` // 触发器配置
+	Tags             StringArray    `json:"tags" gorm:"type:json"`
+	CreatedBy        string         `json:"createdBy" gorm:"size:64"`
+	UpdatedBy        string         `json:"updatedBy" gorm:"size:64"`
+	CreatedAt        time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // WorkflowInstance represents a runtime execution that references a workflow definition.
 type WorkflowInstance struct {
-	ID             uint                `json:"id" gorm:"primaryKey"`
-	DefinitionID   uint                `json:"definitionId" gorm:"index"`
-	DefinitionName string              `json:"definitionName" gorm:"size:128"`
-	Status         string              `json:"status" gorm:"size:32;default:'pending'"` // pending,running,completed,failed
-	CurrentNodeID  string              `json:"currentNodeId" gorm:"size:128"`
-	ContextData    JSONMap             `json:"contextData" gorm:"type:json"` // 运行时上下文镜像
-	ResultData     JSONMap             `json:"resultData" gorm:"type:json"`
-	StartedAt      *time.Time          `json:"startedAt"`
-	CompletedAt    *time.Time          `json:"completedAt"`
-	DeletedAt      gorm.DeletedAt      `json:"-" gorm:"index"`
-	CreatedAt      time.Time           `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt      time.Time           `json:"updatedAt" gorm:"autoUpdateTime"`
-	Definition     *WorkflowDefinition `json:"definition" gorm:"foreignKey:DefinitionID"`
+	ID                uint                `json:"id" gorm:"primaryKey"`
+	DefinitionID      uint                `json:"definitionId" gorm:"index"`
+	DefinitionName    string              `json:"definitionName" gorm:"size:128"`
+	DefinitionVersion uint                `json:"definitionVersion"`                             // 启动时的定义版本，恢复和重试固定使用该版本
+	Status            string              `json:"status" gorm:"size:32;default:'pending';index"` // pending,running,completed,failed,dead_letter
+	CurrentNodeID     string              `json:"currentNodeId" gorm:"size:128"`
+	ContextData       JSONMap             `json:"contextData" gorm:"type:json"` // 运行时上下文镜像
+	ResultData        JSONMap             `json:"resultData" gorm:"type:json"`
+	Parameters        JSONMap             `json:"parameters" gorm:"type:json"`  // 触发参数，用于手动重试
+	TriggerSource     string              `json:"triggerSource" gorm:"size:32"` // manual, api, event, schedule, webhook ...
+	Attempts          int                 `json:"attempts" gorm:"default:0"`    // 执行次数（含手动重试）
+	FailedNodeID      string              `json:"failedNodeId" gorm:"size:128"` // 最后一次失败的节点
+	LastError         string              `json:"lastError" gorm:"type:text"`   // 最后一次失败原因
+	StartedAt         *time.Time          `json:"startedAt"`
+	CompletedAt       *time.Time          `json:"completedAt"`
+	DeletedAt         gorm.DeletedAt      `json:"-" gorm:"index"`
+	CreatedAt         time.Time           `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time           `json:"updatedAt" gorm:"autoUpdateTime"`
+	Definition        *WorkflowDefinition `json:"definition" gorm:"foreignKey:DefinitionID"`
 }
 
+// Workflow instance statuses
+const (
+	WorkflowInstanceStatusPending    = "pending"
+	WorkflowInstanceStatusRunning    = "running"
+	WorkflowInstanceStatusCompleted  = "completed"
+	WorkflowInstanceStatusFailed     = "failed"
+	WorkflowInstanceStatusDeadLetter = "dead_letter" // 重试耗尽或超时，等待人工处理
+	WorkflowInstanceStatusWaiting    = "waiting"     // 等待人工审批
+)
+
 // WorkflowGraph captures nodes and edges for a workflow definition serialized as JSON.
 type WorkflowGraph struct {
 	Nodes    []WorkflowNodeSchema `json:"nodes"`
@@ -220,11 +239,97 @@ type WorkflowVersion struct {
 	CreatedBy     string              `json:"createdBy" gorm:"size:64"`
 	UpdatedBy     string              `json:"updatedBy" gorm:"size:64"`
 	ChangeNote    string              `json:"changeNote" gorm:"type:text"` // 版本变更说明
+	PublishedAt   *time.Time          `json:"publishedAt,omitempty"`       // 发布时间，为空表示仅为编辑历史
+	PublishedBy   string              `json:"publishedBy,omitempty" gorm:"size:64"`
 	CreatedAt     time.Time           `json:"createdAt" gorm:"autoCreateTime"`
 	DefinitionRef *WorkflowDefinition `json:"-" gorm:"foreignKey:DefinitionID"`
 }
 
+// Workflow approval statuses
+const (
+	WorkflowApprovalPending  = "pending"
+	WorkflowApprovalApproved = "approved"
+	WorkflowApprovalRejected = "rejected"
+	WorkflowApprovalExpired  = "expired"
+)
+
+// WorkflowApproval is a pending human decision raised by an approval node.
+type WorkflowApproval struct {
+	ID           uint        `json:"id" gorm:"primaryKey"`
+	InstanceID   uint        `json:"instanceId" gorm:"index;not null"`
+	DefinitionID uint        `json:"definitionId" gorm:"index"`
+	UserID       uint        `json:"userId" gorm:"index"` // 工作流所有者
+	NodeID       string      `json:"nodeId" gorm:"size:128"`
+	NodeName     string      `json:"nodeName" gorm:"size:128"`
+	Title        string      `json:"title" gorm:"size:255"`
+	Message      string      `json:"message" gorm:"type:text"`
+	Approvers    StringArray `json:"approvers" gorm:"type:json"` // 用户ID或邮箱
+	Status       string      `json:"status" gorm:"size:32;default:'pending';index"`
+	OnExpire     string      `json:"onExpire" gorm:"size:16"` // reject, approve, fail
+	ExpiresAt    *time.Time  `json:"expiresAt" gorm:"index"`
+	DecidedBy    uint        `json:"decidedBy"`
+	DecidedAt    *time.Time  `json:"decidedAt"`
+	Comment      string      `json:"comment" gorm:"type:text"`
+	CreatedAt    time.Time   `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time   `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// CanDecide reports whether the user is allowed to approve or reject.
+// The workflow owner can always decide; otherwise the user id or email must be listed.
+func (a *WorkflowApproval) CanDecide(user *User) bool {
+	if user == nil {
+		return false
+	}
+	if user.ID == a.UserID {
+		return true
+	}
+	id := fmt.Sprintf("%d", user.ID)
+	for _, approver := range a.Approvers {
+		if approver == id || (user.Email != "" && approver == user.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkflowStep records a single node execution of a workflow instance for step-level debugging.
+type WorkflowStep struct {
+	ID         uint        `json:"id" gorm:"primaryKey"`
+	InstanceID uint        `json:"instanceId" gorm:"index;not null"`
+	Attempt    int         `json:"attempt" gorm:"index"` // 所属的实例执行次数，对应 WorkflowInstance.Attempts
+	NodeID     string      `json:"nodeId" gorm:"size:128"`
+	NodeName   string      `json:"nodeName" gorm:"size:128"`
+	NodeType   string      `json:"nodeType" gorm:"size:32"`
+	Status     string      `json:"status" gorm:"size:32"`              // completed, failed, waiting
+	Inputs     JSONMap     `json:"inputs,omitempty" gorm:"type:json"`  // 节点解析后的输入参数
+	Outputs    JSONMap     `json:"outputs,omitempty" gorm:"type:json"` // 节点新增或修改的上下文数据
+	NextNodes  StringArray `json:"nextNodes" gorm:"type:json"`
+	Error      string      `json:"error,omitempty" gorm:"type:text"`
+	StartedAt  time.Time   `json:"startedAt"`
+	DurationMs int64       `json:"durationMs"`
+	CreatedAt  time.Time   `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// WorkflowScheduleRun claims one scheduled firing of a workflow so it runs once across replicas.
+type WorkflowScheduleRun struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	DefinitionID uint      `json:"definitionId" gorm:"index"`
+	RunKey       string    `json:"runKey" gorm:"size:128;uniqueIndex"` // 工作流ID + 计划触发时间
+	Holder       string    `json:"holder" gorm:"size:128"`             // 执行该次触发的实例
+	CreatedAt    time.Time `json:"createdAt" gorm:"autoCreateTime;index"`
+}
+
+// ClaimWorkflowScheduleRun records a scheduled firing, returning false when another replica already claimed it.
+func ClaimWorkflowScheduleRun(db *gorm.DB, definitionID uint, runKey, holder string) (bool, error) {
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&WorkflowScheduleRun{DefinitionID: definitionID, RunKey: runKey, Holder: holder})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
 // MigrateWorkflowTables runs auto-migrations for workflow models.
 func MigrateWorkflowTables(db *gorm.DB) error {
-	return db.AutoMigrate(&WorkflowDefinition{}, &WorkflowInstance{}, &WorkflowVersion{})
+	return db.AutoMigrate(&WorkflowDefinition{}, &WorkflowInstance{}, &WorkflowVersion{}, &WorkflowApproval{}, &WorkflowStep{}, &WorkflowScheduleRun{})
 }
@@ -13,6 +13,8 @@ func setupWorkflowTestDB(t *testing.T) *gorm.DB {
 		&WorkflowDefinition{},
 		&WorkflowInstance{},
 		&WorkflowVersion{},
+		&WorkflowStep{},
+		&WorkflowScheduleRun{},
 	)
 }
 
@@ -647,3 +649,51 @@ func TestWorkflowEdgeSchema_WithCondition(t *testing.T) {
 	assert.NotNil(t, edge.Metadata["priority"])
 	assert.True(t, edge.Metadata["priority"] == 1 || edge.Metadata["priority"] == float64(1))
 }
+
+func TestWorkflowApproval_CanDecide(t *testing.T) {
+	approval := &WorkflowApproval{UserID: 1, Approvers: StringArray{"2", "boss@example.com"}}
+
+	assert.True(t, approval.CanDecide(&User{ID: 1}))
+	assert.True(t, approval.CanDecide(&User{ID: 2}))
+	assert.True(t, approval.CanDecide(&User{ID: 3, Email: "boss@example.com"}))
+	assert.False(t, approval.CanDecide(&User{ID: 4, Email: "other@example.com"}))
+	assert.False(t, approval.CanDecide(nil))
+}
+
+func TestWorkflowStep_Persistence(t *testing.T) {
+	db := setupWorkflowTestDB(t)
+	step := &WorkflowStep{
+		InstanceID: 1,
+		Attempt:    1,
+		NodeID:     "task",
+		NodeType:   "task",
+		Status:     "completed",
+		Inputs:     JSONMap{"message": "ping"},
+		Outputs:    JSONMap{"task.echo": "ping"},
+		NextNodes:  StringArray{"end"},
+		DurationMs: 12,
+	}
+	require.NoError(t, db.Create(step).Error)
+
+	var loaded WorkflowStep
+	require.NoError(t, db.First(&loaded, step.ID).Error)
+	assert.Equal(t, "ping", loaded.Inputs["message"])
+	assert.Equal(t, "ping", loaded.Outputs["task.echo"])
+	assert.Equal(t, StringArray{"end"}, loaded.NextNodes)
+}
+
+func TestClaimWorkflowScheduleRun(t *testing.T) {
+	db := setupWorkflowTestDB(t)
+
+	claimed, err := ClaimWorkflowScheduleRun(db, 1, "1:1700000000", "node-a")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = ClaimWorkflowScheduleRun(db, 1, "1:1700000000", "node-b")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	claimed, err = ClaimWorkflowScheduleRun(db, 1, "1:1700000060", "node-b")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
@@ -1,40 +1,29 @@
 package task
 
 import (
+	"context"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"github.com/code-100-precent/LingEcho/pkg/notification"
-	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 // StartEmailCleaner starts the email cleanup scheduled task
 func StartEmailCleaner(db *gorm.DB) {
-	c := cron.New()
-
-	// Execute cleanup task at 2 AM every day
-	schedule := "0 2 * * *"
-
-	// Add scheduled task
-	_, err := c.AddFunc(schedule, func() {
-		if err := CleanUnreadEmails(db); err != nil {
-			logger.Error("Email cleaner task failed", zap.Error(err))
-		} else {
-			logger.Info("Email cleaner task completed successfully")
-		}
+	err := Register(db, Task{
+		Name:     "email-cleaner",
+		Schedule: "0 2 * * *", // Execute cleanup task at 2 AM every day
+		Jitter:   5 * time.Minute,
+		RunOnce:  true,
+		Run: func(ctx context.Context) error {
+			return CleanUnreadEmails(db)
+		},
 	})
-
 	if err != nil {
-		logger.Error("Failed to add email cleaner cron job", zap.Error(err))
-		return
+		logger.Error("Failed to register email cleaner task", zap.Error(err))
 	}
-
-	// Start the scheduled task
-	c.Start()
-
-	logger.Info("Email cleaner started", zap.String("schedule", schedule))
 }
 
 // CleanUnreadEmails cleans up emails unread for more than seven days
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
@@ -18,6 +19,20 @@ import (
 var graphStore graph.Store
 var graphProcessorEnabled bool
 
+const (
+	// graphProcessDelay 会话空闲多久后再提取，避免每轮对话都重复调用 LLM
+	graphProcessDelay = 2 * time.Minute
+	// graphInterestHalfLife 用户偏好权重的半衰期
+	graphInterestHalfLife = 30 * 24 * time.Hour
+	// graphInterestMinWeight 衰减后低于该权重的偏好被删除
+	graphInterestMinWeight = 0.1
+)
+
+var (
+	pendingGraphMu       sync.Mutex
+	pendingGraphSessions = make(map[string]*time.Timer)
+)
+
 // InitGraphProcessor 初始化图处理器
 func InitGraphProcessor(store graph.Store, enabled bool) {
 	graphStore = store
@@ -29,13 +44,24 @@ func InitGraphProcessor(store graph.Store, enabled bool) {
 	}
 }
 
-// ProcessConversationAsync 异步处理对话记录，提取知识并存储到图数据库
+// ProcessConversationAsync 异步处理对话记录，提取知识并存储到图数据库。
+// 每保存一轮对话都会调用，同一会话在空闲 graphProcessDelay 后只处理一次
 func ProcessConversationAsync(db *gorm.DB, assistantID int64, sessionID string, userID uint) {
 	if !graphProcessorEnabled || graphStore == nil {
 		return
 	}
 
-	go func() {
+	pendingGraphMu.Lock()
+	defer pendingGraphMu.Unlock()
+	if timer, ok := pendingGraphSessions[sessionID]; ok {
+		timer.Reset(graphProcessDelay)
+		return
+	}
+	pendingGraphSessions[sessionID] = time.AfterFunc(graphProcessDelay, func() {
+		pendingGraphMu.Lock()
+		delete(pendingGraphSessions, sessionID)
+		pendingGraphMu.Unlock()
+
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
 
@@ -45,7 +71,34 @@ func ProcessConversationAsync(db *gorm.DB, assistantID int64, sessionID string,
 				zap.String("sessionID", sessionID),
 				zap.Error(err))
 		}
-	}()
+	})
+}
+
+// StartGraphMemoryDecay 启动用户偏好衰减任务，长期未再提及的主题和实体权重逐渐降低直至删除
+func StartGraphMemoryDecay(db *gorm.DB) {
+	if !graphProcessorEnabled || graphStore == nil {
+		return
+	}
+	// 图存储由各副本共享，每次只能由一个副本执行衰减
+	err := Register(db, Task{
+		Name:     "graph-memory-decay",
+		Schedule: "0 4 * * *", // 每天凌晨 4 点执行
+		Jitter:   5 * time.Minute,
+		RunOnce:  true,
+		Run: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+			defer cancel()
+			removed, err := graphStore.DecayInterests(ctx, graphInterestHalfLife, graphInterestMinWeight)
+			if err != nil {
+				return err
+			}
+			logger.Info("Graph memory decay task completed", zap.Int("removed", removed))
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to register graph memory decay task", zap.Error(err))
+	}
 }
 
 // processConversation 处理对话记录的核心逻辑
@@ -67,7 +120,7 @@ func processConversation(ctx context.Context, db *gorm.DB, assistantID int64, se
 		return fmt.Errorf("failed to get assistant: %w", err)
 	}
 
-	// 如果该助手未开启图记忆功能，则直接跳过（不写入 Neo4j）
+	// 如果该助手未开启图记忆功能，则直接跳过（不写入图存储）
 	if !assistant.EnableGraphMemory {
 		logger.Info("Graph memory is disabled for assistant, skip graph processing",
 			zap.Int64("assistantID", assistantID),
@@ -196,7 +249,7 @@ func buildSummaryPrompt(conversationText string, assistant models.Assistant) str
 
 重要提示：
 1. 请首先判断对话内容是否与助手的系统提示词、描述和角色定位相关
-2. 如果对话内容与助手定位完全无关（例如：用户只是闲聊、测试、或者讨论与助手职责无关的话题），请在 JSON 中设置 "relevant": false，并返回空的 topics、intents 和 knowledge 数组
+2. 如果对话内容与助手定位完全无关（例如：用户只是闲聊、测试、或者讨论与助手职责无关的话题），请在 JSON 中设置 "relevant": false，并返回空的 topics、intents、entities、relations 和 knowledge 数组
 3. 只有当对话内容与助手定位相关时，才提取和存储知识信息
 
 请按照以下 JSON 格式返回分析结果：
@@ -205,6 +258,12 @@ func buildSummaryPrompt(conversationText string, assistant models.Assistant) str
   "summary": "对话的简要总结（100-200字，如果不相关可以简要说明原因）",
   "topics": ["主题1", "主题2", ...],  // 讨论的主要主题，最多10个（如果不相关则为空数组）
   "intents": ["意图1", "意图2", ...],  // 用户的主要意图，最多5个（如果不相关则为空数组）
+  "entities": [
+    {"name": "实体名称", "type": "实体类型（如：person、organization、product、place、concept）"}
+  ],  // 对话中提到的具体实体，最多15个（如果不相关则为空数组）
+  "relations": [
+    {"source": "实体名称", "relation": "关系（如：works_at、uses、located_in）", "target": "实体名称"}
+  ],  // 实体之间的关系，source 和 target 必须出现在 entities 中，最多15个（如果不相关则为空数组）
   "knowledge": [
     {
       "content": "知识点内容",
@@ -219,8 +278,9 @@ func buildSummaryPrompt(conversationText string, assistant models.Assistant) str
 1. 严格判断对话相关性：如果对话与助手的系统提示词、描述、角色定位完全无关，必须设置 "relevant": false
 2. topics 应该是具体的主题名称，如"机器学习"、"Python编程"等
 3. intents 应该是用户的意图，如"学习新知识"、"解决问题"、"获取建议"等
-4. knowledge 应该是有价值的知识点，避免过于琐碎的信息
-5. 只返回 JSON，不要包含其他文字说明`, assistantContext, conversationText)
+4. entities 使用对话中出现的规范名称，同一实体只列出一次
+5. knowledge 应该是有价值的知识点，避免过于琐碎的信息
+6. 只返回 JSON，不要包含其他文字说明`, assistantContext, conversationText)
 }
 
 // parseSummaryResponse 解析 LLM 返回的总结
@@ -243,6 +303,8 @@ func parseSummaryResponse(response string, assistantID int64, assistantName stri
 		Summary   string            `json:"summary"`
 		Topics    []string          `json:"topics"`
 		Intents   []string          `json:"intents"`
+		Entities  []graph.Entity    `json:"entities"`
+		Relations []graph.Relation  `json:"relations"`
 		Knowledge []graph.Knowledge `json:"knowledge"`
 	}
 
@@ -284,9 +346,12 @@ func parseSummaryResponse(response string, assistantID int64, assistantName stri
 		Summary:       result.Summary,
 		Topics:        result.Topics,
 		Intents:       result.Intents,
+		Entities:      result.Entities,
+		Relations:     result.Relations,
 		Turns:         turns,
 		Knowledge:     result.Knowledge,
 	}
+	summary.Normalize()
 
 	return summary, relevant, nil
 }
@@ -317,6 +382,7 @@ func storeBasicConversationInfo(ctx context.Context, assistantID int64, sessionI
 		Turns:         turns,
 		Knowledge:     []graph.Knowledge{},
 	}
+	summary.Normalize()
 
 	return graphStore.ProcessConversation(ctx, assistantID, sessionID, summary)
 }
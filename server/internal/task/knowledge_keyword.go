@@ -0,0 +1,99 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/ingest"
+	search2 "github.com/code-100-precent/LingEcho/pkg/utils/search"
+)
+
+// KnowledgeChunkDocType is the search document type of knowledge base chunks,
+// the keyword side of hybrid retrieval searches only these documents
+const KnowledgeChunkDocType = "knowledge_chunk"
+
+// KnowledgeChunkDoc builds the search document of one knowledge base chunk
+func KnowledgeChunkDoc(knowledgeKey, chunkID, source, content string) search2.Doc {
+	return search2.Doc{
+		ID:   fmt.Sprintf("%s_%s_%s", KnowledgeChunkDocType, knowledgeKey, chunkID),
+		Type: KnowledgeChunkDocType,
+		Fields: map[string]interface{}{
+			"knowledgeKey": knowledgeKey,
+			"chunkId":      chunkID,
+			"title":        source,
+			"content":      content,
+			"category":     KnowledgeChunkDocType,
+		},
+	}
+}
+
+// knowledgeKeywordSearcher runs the keyword (BM25) side of hybrid retrieval on the search engine
+type knowledgeKeywordSearcher struct {
+	engine search2.Engine
+}
+
+// NewKnowledgeKeywordSearcher returns a keyword searcher over the chunk documents in engine
+func NewKnowledgeKeywordSearcher(engine search2.Engine) knowledge.KeywordSearcher {
+	return &knowledgeKeywordSearcher{engine: engine}
+}
+
+// NewKnowledgeChunkIndexer returns an indexer writing ingested chunks to engine
+// as the documents NewKnowledgeKeywordSearcher searches
+func NewKnowledgeChunkIndexer(engine search2.Engine) ingest.KeywordIndexer {
+	return &knowledgeKeywordSearcher{engine: engine}
+}
+
+func (s *knowledgeKeywordSearcher) KeywordSearch(ctx context.Context, knowledgeKey string, query string, topK int) ([]knowledge.SearchResult, error) {
+	result, err := s.engine.Search(ctx, search2.SearchRequest{
+		Keyword:      query,
+		SearchFields: []string{"content", "title"},
+		MustTerms: map[string][]string{
+			"type":         {KnowledgeChunkDocType},
+			"knowledgeKey": {knowledgeKey},
+		},
+		Size: topK,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]knowledge.SearchResult, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		content, _ := hit.Fields["content"].(string)
+		source, _ := hit.Fields["title"].(string)
+		r := knowledge.SearchResult{
+			Content: content,
+			Score:   hit.Score,
+			Source:  source,
+		}
+		if chunkID, ok := hit.Fields["chunkId"].(string); ok && chunkID != "" {
+			r.Metadata = map[string]interface{}{knowledge.MetadataKeyChunkID: chunkID}
+			// Provenance of chunks written by the ingestion pipeline
+			if documentID, ok := hit.Fields["documentId"].(string); ok && documentID != "" {
+				r.Metadata[knowledge.MetadataKeyDocumentID] = documentID
+				r.Metadata[knowledge.MetadataKeyTitle] = source
+			}
+			for field, key := range map[string]string{"page": knowledge.MetadataKeyPage, "offset": knowledge.MetadataKeyOffset} {
+				if value, ok := hit.Fields[field]; ok {
+					r.Metadata[key] = value
+				}
+			}
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func (s *knowledgeKeywordSearcher) IndexChunks(ctx context.Context, knowledgeKey string, chunks []knowledge.VectorChunk) error {
+	docs := make([]search2.Doc, 0, len(chunks))
+	for _, chunk := range chunks {
+		title, _ := chunk.Metadata[knowledge.MetadataKeyTitle].(string)
+		doc := KnowledgeChunkDoc(knowledgeKey, chunk.ID, title, chunk.Content)
+		doc.Fields["documentId"] = chunk.DocumentID
+		doc.Fields["page"] = chunk.Metadata[knowledge.MetadataKeyPage]
+		doc.Fields["offset"] = chunk.Metadata[knowledge.MetadataKeyOffset]
+		docs = append(docs, doc)
+	}
+	return s.engine.IndexBatch(ctx, docs)
+}
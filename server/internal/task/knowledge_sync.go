@@ -0,0 +1,133 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/connector"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/ingest"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// knowledgeSyncInterval how often sync sources are checked for due syncs
+const knowledgeSyncInterval = time.Minute
+
+// knowledgeSyncTimeout bounds one sync run
+const knowledgeSyncTimeout = 2 * time.Hour
+
+// ErrKnowledgeSyncRunning is returned when a source is already syncing
+var ErrKnowledgeSyncRunning = errors.New("knowledge sync is already running")
+
+var (
+	knowledgeSyncMu      sync.Mutex
+	knowledgeSyncRunning = make(map[uint]bool)
+)
+
+// StartKnowledgeSync starts syncing knowledge base sources on their interval
+func StartKnowledgeSync(db *gorm.DB) {
+	if err := models.ResetRunningKnowledgeSyncSources(db); err != nil {
+		logger.Warn("Failed to reset interrupted knowledge syncs", zap.Error(err))
+	}
+
+	err := Register(db, Task{
+		Name:     "knowledge-sync",
+		Schedule: "@every " + knowledgeSyncInterval.String(),
+		RunOnce:  true,
+		Run: func(ctx context.Context) error {
+			sources, err := models.GetDueKnowledgeSyncSources(db, time.Now())
+			if err != nil {
+				return fmt.Errorf("load due knowledge sync sources: %w", err)
+			}
+			// Sources sync one after another to bound the embedding load
+			for i := range sources {
+				if ctx.Err() != nil {
+					return nil
+				}
+				if _, err := SyncKnowledgeSource(db, &sources[i]); err != nil && !errors.Is(err, ErrKnowledgeSyncRunning) {
+					logger.Warn("Knowledge sync failed", zap.Uint("sourceId", sources[i].ID), zap.Error(err))
+				}
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to register knowledge sync task", zap.Error(err))
+	}
+}
+
+// SyncKnowledgeSource syncs a source now and records the result on it
+func SyncKnowledgeSource(db *gorm.DB, source *models.KnowledgeSyncSource) (connector.Report, error) {
+	knowledgeSyncMu.Lock()
+	if knowledgeSyncRunning[source.ID] {
+		knowledgeSyncMu.Unlock()
+		return connector.Report{}, ErrKnowledgeSyncRunning
+	}
+	knowledgeSyncRunning[source.ID] = true
+	knowledgeSyncMu.Unlock()
+	defer func() {
+		knowledgeSyncMu.Lock()
+		delete(knowledgeSyncRunning, source.ID)
+		knowledgeSyncMu.Unlock()
+	}()
+
+	db.Model(source).Update("last_status", models.KnowledgeSyncStatusRunning)
+	ctx, cancel := context.WithTimeout(context.Background(), knowledgeSyncTimeout)
+	defer cancel()
+	go func() {
+		// End the run on shutdown
+		select {
+		case <-stopped:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	report, err := runKnowledgeSync(ctx, db, source)
+	if finishErr := models.FinishKnowledgeSync(db, source, report, err); finishErr != nil {
+		logger.Error("Failed to save knowledge sync result", zap.Uint("sourceId", source.ID), zap.Error(finishErr))
+	}
+	logger.Info("Knowledge sync finished",
+		zap.Uint("sourceId", source.ID),
+		zap.String("knowledgeKey", source.KnowledgeKey),
+		zap.Int("added", report.Added),
+		zap.Int("updated", report.Updated),
+		zap.Int("deleted", report.Deleted),
+		zap.Int("failed", report.Failed),
+		zap.Error(err))
+	return report, err
+}
+
+func runKnowledgeSync(ctx context.Context, db *gorm.DB, source *models.KnowledgeSyncSource) (connector.Report, error) {
+	config, err := source.ConfigMap()
+	if err != nil {
+		return connector.Report{}, err
+	}
+	conn, err := connector.New(source.Type, config)
+	if err != nil {
+		return connector.Report{}, err
+	}
+	_, kb, _, err := models.OpenKnowledgeBase(db, source.KnowledgeKey)
+	if err != nil {
+		return connector.Report{}, err
+	}
+
+	syncer := &connector.Syncer{
+		SourceID:     strconv.FormatUint(uint64(source.ID), 10),
+		KnowledgeKey: source.KnowledgeKey,
+		Connector:    conn,
+		Pipeline: ingest.NewPipeline(kb, ingest.ChunkOptions{
+			Strategy: source.ChunkStrategy,
+			Size:     source.ChunkSize,
+			Overlap:  source.ChunkOverlap,
+		}),
+		State: models.NewKnowledgeSyncStateStore(db, source.ID),
+	}
+	return syncer.Run(ctx)
+}
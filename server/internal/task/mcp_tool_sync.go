@@ -0,0 +1,32 @@
+package task
+
+import (
+	"context"
+
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	lingechoMCP "github.com/code-100-precent/LingEcho/pkg/mcp"
+	"go.uber.org/zap"
+)
+
+// StartMCPToolSync registers the dynamic MCP tools defined in the database and keeps them
+// in sync, so tools edited on another instance show up within one sync interval
+func StartMCPToolSync(tools *lingechoMCP.DynamicTools) {
+	if tools == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopped
+		cancel()
+	}()
+
+	count, err := tools.Sync(ctx)
+	if err != nil {
+		logger.Warn("Failed to load dynamic MCP tools", zap.Error(err))
+	} else {
+		logger.Info("Dynamic MCP tools loaded", zap.Int("count", count))
+	}
+	tools.Run(ctx, lingechoMCP.DefaultDynamicToolSyncInterval)
+}
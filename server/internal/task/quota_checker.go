@@ -1,9 +1,11 @@
 package task
 
 import (
+	"context"
+	"time"
+
 	"github.com/code-100-precent/LingEcho/pkg/alert"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
-	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -16,30 +18,18 @@ func StartQuotaAlertChecker(db *gorm.DB) {
 	// Create quota checker
 	checker := alert.NewQuotaChecker(db, triggerService)
 
-	// Execute a check immediately at startup
-	logger.Info("Executing quota alert check at startup")
-	checker.CheckAllQuotaAlerts()
-
-	// Use cron for scheduled execution
-	c := cron.New()
-
-	// Execute quota check every 5 minutes
-	schedule := "*/5 * * * *"
-
-	// Add scheduled task
-	_, err := c.AddFunc(schedule, func() {
-		logger.Info("Starting quota alert check execution")
-		checker.CheckAllQuotaAlerts()
-		logger.Info("Quota alert check completed")
+	err := Register(db, Task{
+		Name:         "quota-alert-checker",
+		Schedule:     "*/5 * * * *", // Execute quota check every 5 minutes
+		Jitter:       30 * time.Second,
+		RunOnce:      true,
+		RunAtStartup: true,
+		Run: func(ctx context.Context) error {
+			checker.CheckAllQuotaAlerts()
+			return nil
+		},
 	})
-
 	if err != nil {
-		logger.Error("Failed to add quota alert checker cron job", zap.Error(err))
-		return
+		logger.Error("Failed to register quota alert checker task", zap.Error(err))
 	}
-
-	// Start the scheduled task
-	c.Start()
-
-	logger.Info("Quota alert checker started", zap.String("schedule", schedule))
 }
@@ -0,0 +1,43 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/recording"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// StartRecordingCleaner starts the task that deletes recordings past each user's
+// retention period. Users without a recording policy fall back to RECORDING_RETENTION_DAYS.
+func StartRecordingCleaner(db *gorm.DB) {
+	err := Register(db, Task{
+		Name:     "recording-cleaner",
+		Schedule: "0 3 * * *", // Execute cleanup task at 3 AM every day
+		Jitter:   5 * time.Minute,
+		RunOnce:  true,
+		Run: func(ctx context.Context) error {
+			now := time.Now()
+			deleted, err := recording.PurgeExpired(db, stores.Default(), now)
+			if err != nil {
+				return err
+			}
+			if days := config.GlobalConfig.RecordingRetentionDays; days > 0 {
+				purged, err := recording.PurgeWithoutPolicy(db, stores.Default(), now.AddDate(0, 0, -days))
+				deleted += purged
+				if err != nil {
+					return err
+				}
+			}
+			logger.Info("Recording cleaner task completed", zap.Int("deleted", deleted))
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to register recording cleaner task", zap.Error(err))
+	}
+}
@@ -0,0 +1,247 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Task is a background job run on a cron schedule by the task registry
+type Task struct {
+	Name     string        // unique name, used in DISABLED_TASKS and the status API
+	Schedule string        // standard 5-field cron expression or descriptor such as "@every 5s"
+	Jitter   time.Duration // random delay before each run so replicas do not hit the database together
+	// RunOnce holds a database lease until the next firing so that only one
+	// replica runs each firing in a multi-replica deployment
+	RunOnce      bool
+	RunAtStartup bool // also run once right after registration
+	Run          func(ctx context.Context) error
+}
+
+// Task run results reported in TaskStatus.LastResult
+const (
+	TaskResultSuccess = "success"
+	TaskResultFailed  = "failed"
+	TaskResultSkipped = "skipped" // another replica holds the run-once lock
+)
+
+// TaskStatus is the last-run status of a registered task
+type TaskStatus struct {
+	Name         string     `json:"name"`
+	Schedule     string     `json:"schedule"`
+	Enabled      bool       `json:"enabled"`
+	Running      bool       `json:"running"`
+	RunOnce      bool       `json:"runOnce"`
+	LastResult   string     `json:"lastResult,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+	LastStartAt  *time.Time `json:"lastStartAt,omitempty"`
+	LastDuration string     `json:"lastDuration,omitempty"`
+	NextRunAt    *time.Time `json:"nextRunAt,omitempty"`
+	Runs         int64      `json:"runs"`
+	Failures     int64      `json:"failures"`
+}
+
+type registeredTask struct {
+	Task
+	db       *gorm.DB
+	schedule cron.Schedule
+	entryID  cron.EntryID
+
+	mu     sync.Mutex
+	status TaskStatus
+}
+
+var (
+	registryMu    sync.Mutex
+	registry      = make(map[string]*registeredTask)
+	registryCron  *cron.Cron
+	registryCtx   context.Context
+	registryStop  context.CancelFunc
+	registryStart sync.Once
+)
+
+// Register schedules t on the shared task cron. db is used for the run-once
+// lock and may be nil when t.RunOnce is false. Tasks listed in DISABLED_TASKS
+// are registered but never run, so they still show up in the status API.
+func Register(db *gorm.DB, t Task) error {
+	if t.Name == "" || t.Run == nil {
+		return errors.New("task name and run function are required")
+	}
+	if t.RunOnce && db == nil {
+		return fmt.Errorf("task %s: run-once lock requires a database", t.Name)
+	}
+	schedule, err := cron.ParseStandard(t.Schedule)
+	if err != nil {
+		return fmt.Errorf("task %s: invalid schedule %q: %w", t.Name, t.Schedule, err)
+	}
+
+	registryStart.Do(func() {
+		registryCron = cron.New()
+		registryCtx, registryStop = context.WithCancel(context.Background())
+		go func() {
+			<-stopped
+			registryStop()
+		}()
+		startCron(registryCron)
+	})
+
+	rt := &registeredTask{
+		Task:     t,
+		db:       db,
+		schedule: schedule,
+		status: TaskStatus{
+			Name:     t.Name,
+			Schedule: t.Schedule,
+			Enabled:  taskEnabled(t.Name),
+			RunOnce:  t.RunOnce,
+		},
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[t.Name]; exists {
+		return fmt.Errorf("task %s is already registered", t.Name)
+	}
+	registry[t.Name] = rt
+	if !rt.status.Enabled {
+		logger.Info("Background task disabled by configuration", zap.String("task", t.Name))
+		return nil
+	}
+	rt.entryID = registryCron.Schedule(schedule, cron.FuncJob(func() { rt.fire(registryCtx, true) }))
+	if t.RunAtStartup {
+		go rt.fire(registryCtx, false)
+	}
+	logger.Info("Background task scheduled", zap.String("task", t.Name), zap.String("schedule", t.Schedule))
+	return nil
+}
+
+// Statuses returns the last-run status of every registered task sorted by name
+func Statuses() []TaskStatus {
+	registryMu.Lock()
+	tasks := make([]*registeredTask, 0, len(registry))
+	for _, rt := range registry {
+		tasks = append(tasks, rt)
+	}
+	registryMu.Unlock()
+
+	list := make([]TaskStatus, 0, len(tasks))
+	for _, rt := range tasks {
+		rt.mu.Lock()
+		status := rt.status
+		rt.mu.Unlock()
+		if next := registryCron.Entry(rt.entryID).Next; status.Enabled && !next.IsZero() {
+			status.NextRunAt = &next
+		}
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// taskEnabled reports whether name is absent from DISABLED_TASKS
+func taskEnabled(name string) bool {
+	if config.GlobalConfig == nil {
+		return true
+	}
+	for _, disabled := range strings.Split(config.GlobalConfig.DisabledTasks, ",") {
+		if strings.TrimSpace(disabled) == name {
+			return false
+		}
+	}
+	return true
+}
+
+// fire runs the task unless its previous run is still going. scheduled is
+// false for the startup run, which is not subject to jitter.
+func (rt *registeredTask) fire(ctx context.Context, scheduled bool) {
+	rt.mu.Lock()
+	if rt.status.Running {
+		rt.mu.Unlock()
+		logger.Warn("Background task still running, skipping this firing", zap.String("task", rt.Name))
+		return
+	}
+	rt.status.Running = true
+	rt.mu.Unlock()
+	defer func() {
+		rt.mu.Lock()
+		rt.status.Running = false
+		rt.mu.Unlock()
+	}()
+
+	if scheduled && rt.Jitter > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(rand.Int63n(int64(rt.Jitter)))):
+		}
+	}
+
+	if rt.RunOnce {
+		// The lease expires just before the next firing, so a replica firing
+		// late (clock skew, jitter) cannot run the same firing again
+		now := time.Now()
+		ttl := rt.schedule.Next(now).Sub(now) - time.Second
+		if ttl < time.Second {
+			ttl = time.Second
+		}
+		acquired, err := models.TryAcquireLease(rt.db, "task:"+rt.Name, models.LeaseHolderID(), ttl)
+		if err != nil || !acquired {
+			if err != nil {
+				logger.Warn("Failed to acquire task lock", zap.String("task", rt.Name), zap.Error(err))
+			}
+			rt.finish(time.Now(), 0, TaskResultSkipped, err)
+			return
+		}
+	}
+
+	startedAt := time.Now()
+	err := rt.run(ctx)
+	result := TaskResultSuccess
+	if err != nil {
+		result = TaskResultFailed
+		logger.Error("Background task failed", zap.String("task", rt.Name), zap.Error(err))
+	}
+	rt.finish(startedAt, time.Since(startedAt), result, err)
+}
+
+// run calls the task function, turning a panic into an error so one bad run
+// does not take the process down
+func (rt *registeredTask) run(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return rt.Run(ctx)
+}
+
+func (rt *registeredTask) finish(startedAt time.Time, duration time.Duration, result string, err error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.status.LastResult = result
+	rt.status.LastError = ""
+	if err != nil {
+		rt.status.LastError = err.Error()
+	}
+	if result == TaskResultSkipped {
+		return
+	}
+	rt.status.LastStartAt = &startedAt
+	rt.status.LastDuration = duration.String()
+	rt.status.Runs++
+	if result == TaskResultFailed {
+		rt.status.Failures++
+	}
+}
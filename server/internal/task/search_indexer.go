@@ -11,7 +11,6 @@ import (
 	"github.com/code-100-precent/LingEcho/pkg/notification"
 	"github.com/code-100-precent/LingEcho/pkg/utils"
 	search2 "github.com/code-100-precent/LingEcho/pkg/utils/search"
-	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -24,37 +23,27 @@ func StartSearchIndexer(db *gorm.DB, engine search2.Engine) {
 	searchEngine = engine
 	searchIndexerRunning = true
 
-	c := cron.New()
-
 	// Get scheduled task expression from configuration
 	schedule := utils.GetValue(db, constants.KEY_SEARCH_INDEX_SCHEDULE)
 	if schedule == "" {
 		schedule = "0 */6 * * *" // Default to execute every 6 hours
 	}
 
-	// Add scheduled task
-	_, err := c.AddFunc(schedule, func() {
-		if !utils.GetBoolValue(db, constants.KEY_SEARCH_ENABLED) {
-			logger.Info("Search is disabled, skipping index task")
-			return
-		}
-
-		if err := IndexUserData(db, engine); err != nil {
-			logger.Error("Search index task failed", zap.Error(err))
-		} else {
-			logger.Info("Search index task completed successfully")
-		}
+	// The index is local to each replica, so every replica builds its own
+	err := Register(db, Task{
+		Name:     "search-indexer",
+		Schedule: schedule,
+		Run: func(ctx context.Context) error {
+			if !utils.GetBoolValue(db, constants.KEY_SEARCH_ENABLED) {
+				logger.Info("Search is disabled, skipping index task")
+				return nil
+			}
+			return IndexUserData(db, engine)
+		},
 	})
-
 	if err != nil {
-		logger.Error("Failed to add search index cron job", zap.Error(err))
-		return
+		logger.Error("Failed to register search index task", zap.Error(err))
 	}
-
-	// Start the scheduled task
-	c.Start()
-
-	logger.Info("Search indexer started", zap.String("schedule", schedule))
 }
 
 // IndexUserDataAsync asynchronously indexes user data (used at project startup)
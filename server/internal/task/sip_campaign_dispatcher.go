@@ -0,0 +1,215 @@
+package task
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/sip"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// campaignDispatchInterval how often campaigns are checked for due calls
+const campaignDispatchInterval = 5 * time.Second
+
+// CampaignDialer places and tracks the outgoing calls of a campaign
+type CampaignDialer interface {
+	MakeOutgoingCallWithOptions(targetURI string, opts sip.OutgoingCallOptions) (string, error)
+	GetOutgoingSession(callID string) (interface{}, bool)
+	HangupOutgoingCall(callID string) error
+}
+
+// StartSipCampaignDispatcher starts dialing running outbound campaigns
+func StartSipCampaignDispatcher(db *gorm.DB, dialer CampaignDialer) {
+	// Calls are reconciled through the local SIP server's sessions, so the
+	// dispatcher is not a run-once task
+	err := Register(db, Task{
+		Name:     "sip-campaign-dispatcher",
+		Schedule: "@every " + campaignDispatchInterval.String(),
+		Run: func(ctx context.Context) error {
+			dispatchSipCampaigns(db, dialer, time.Now())
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to register SIP campaign dispatcher task", zap.Error(err))
+	}
+}
+
+// dispatchSipCampaigns collects results of finished calls, then dials due numbers of running campaigns
+func dispatchSipCampaigns(db *gorm.DB, dialer CampaignDialer, now time.Time) {
+	campaigns := make(map[uint]*models.SipCampaign)
+	campaignByID := func(id uint) *models.SipCampaign {
+		if c, ok := campaigns[id]; ok {
+			return c
+		}
+		var c models.SipCampaign
+		if err := db.First(&c, id).Error; err != nil {
+			return nil
+		}
+		c.ApplyDefaults()
+		campaigns[id] = &c
+		return &c
+	}
+
+	dialing, err := models.GetDialingSipCampaignCalls(db)
+	if err != nil {
+		logger.Error("Failed to load dialing campaign calls", zap.Error(err))
+		return
+	}
+	inFlight := make(map[uint]int)
+	for i := range dialing {
+		call := &dialing[i]
+		campaign := campaignByID(call.CampaignID)
+		if campaign == nil {
+			continue
+		}
+		if !reconcileCampaignCall(db, dialer, campaign, call, now) {
+			inFlight[call.CampaignID]++
+		}
+	}
+
+	running, err := models.GetSipCampaignsByStatus(db, models.SipCampaignStatusRunning)
+	if err != nil {
+		logger.Error("Failed to load running campaigns", zap.Error(err))
+		return
+	}
+	for i := range running {
+		campaign := &running[i]
+		campaign.ApplyDefaults()
+		if campaign.Expired(now) {
+			// Numbers not dialed before the campaign ends are dropped
+			if err := models.CancelPendingSipCampaignCalls(db, campaign.ID); err != nil {
+				logger.Error("Failed to cancel pending campaign calls", zap.Uint("campaignId", campaign.ID), zap.Error(err))
+			}
+		} else if campaign.CanDial(now) {
+			dialCampaignCalls(db, dialer, campaign, campaign.MaxConcurrency-inFlight[campaign.ID], now)
+		}
+		finishCampaignIfDone(db, campaign)
+	}
+}
+
+// reconcileCampaignCall records the outcome of a call whose SIP session has ended; returns true when it is no longer in flight
+func reconcileCampaignCall(db *gorm.DB, dialer CampaignDialer, campaign *models.SipCampaign, call *models.SipCampaignCall, now time.Time) bool {
+	raw, exists := dialer.GetOutgoingSession(call.CallID)
+	session, ok := raw.(*sip.OutgoingSession)
+	if !exists || !ok {
+		// Session is gone, e.g. the server restarted mid-call
+		campaign.RecordFailure(call, "call session lost", now)
+		saveCampaignCall(db, call)
+		return true
+	}
+
+	switch session.Status {
+	case "answered":
+		if campaign.MaxCallSeconds > 0 && session.AnswerTime != nil &&
+			now.Sub(*session.AnswerTime) > time.Duration(campaign.MaxCallSeconds)*time.Second {
+			if err := dialer.HangupOutgoingCall(call.CallID); err != nil {
+				logger.Warn("Failed to hang up campaign call over time limit", zap.String("callId", call.CallID), zap.Error(err))
+			}
+		}
+		return false
+	case "ended", "failed", "cancelled":
+	default:
+		return false
+	}
+
+	endedAt := now
+	if session.EndTime != nil {
+		endedAt = *session.EndTime
+	}
+	if session.AnswerTime != nil {
+		call.Status = models.SipCampaignCallCompleted
+		call.AnsweredAt = session.AnswerTime
+		call.EndedAt = &endedAt
+		call.Duration = int(endedAt.Sub(*session.AnswerTime).Seconds())
+		call.LastError = ""
+	} else {
+		reason := session.Error
+		if reason == "" {
+			reason = session.Status
+		}
+		campaign.RecordFailure(call, reason, endedAt)
+	}
+	saveCampaignCall(db, call)
+	return true
+}
+
+// dialCampaignCalls places up to slots calls for numbers that are due
+func dialCampaignCalls(db *gorm.DB, dialer CampaignDialer, campaign *models.SipCampaign, slots int, now time.Time) {
+	if slots <= 0 {
+		return
+	}
+	due, err := models.GetDueSipCampaignCalls(db, campaign.ID, now, slots)
+	if err != nil {
+		logger.Error("Failed to load due campaign calls", zap.Uint("campaignId", campaign.ID), zap.Error(err))
+		return
+	}
+	opts := sip.OutgoingCallOptions{
+		Bridge: true,
+		Metadata: map[string]string{
+			sip.MetadataUserID:       strconv.FormatUint(uint64(campaign.UserID), 10),
+			sip.MetadataCredentialID: strconv.FormatUint(uint64(campaign.CredentialID), 10),
+			sip.MetadataAssistantID:  strconv.FormatUint(uint64(campaign.AssistantID), 10),
+			sip.MetadataGreeting:     campaign.Script,
+		},
+	}
+	for i := range due {
+		call := &due[i]
+		call.Attempts++
+		callID, err := dialer.MakeOutgoingCallWithOptions(call.TargetURI, opts)
+		if err != nil {
+			campaign.RecordFailure(call, err.Error(), now)
+			saveCampaignCall(db, call)
+			continue
+		}
+		call.Status = models.SipCampaignCallDialing
+		call.CallID = callID
+		call.AnsweredAt, call.EndedAt = nil, nil
+		saveCampaignCall(db, call)
+
+		userID := campaign.UserID
+		sipCall := &models.SipCall{
+			CallID:    callID,
+			Direction: models.SipCallDirectionOutbound,
+			Status:    models.SipCallStatusCalling,
+			ToURI:     call.TargetURI,
+			StartTime: now,
+			UserID:    &userID,
+			Notes:     "campaign: " + campaign.Name,
+		}
+		if err := models.CreateSipCall(db, sipCall); err != nil {
+			logger.Warn("Failed to create call record for campaign call", zap.String("callId", callID), zap.Error(err))
+		}
+		logger.Info("Campaign call placed",
+			zap.Uint("campaignId", campaign.ID),
+			zap.String("target", call.TargetURI),
+			zap.Int("attempt", call.Attempts),
+			zap.String("callId", callID))
+	}
+}
+
+// finishCampaignIfDone marks the campaign completed once no number is pending or dialing
+func finishCampaignIfDone(db *gorm.DB, campaign *models.SipCampaign) {
+	counts, err := models.CountSipCampaignCalls(db, campaign.ID)
+	if err != nil {
+		return
+	}
+	if counts[models.SipCampaignCallPending] > 0 || counts[models.SipCampaignCallDialing] > 0 {
+		return
+	}
+	if err := models.UpdateSipCampaignStatus(db, campaign.ID, models.SipCampaignStatusCompleted); err != nil {
+		logger.Error("Failed to complete campaign", zap.Uint("campaignId", campaign.ID), zap.Error(err))
+		return
+	}
+	logger.Info("Campaign completed", zap.Uint("campaignId", campaign.ID))
+}
+
+func saveCampaignCall(db *gorm.DB, call *models.SipCampaignCall) {
+	if err := models.SaveSipCampaignCall(db, call); err != nil {
+		logger.Error("Failed to save campaign call", zap.Uint("id", call.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,57 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// StartStatementGenerator starts the monthly usage statement scheduled task
+func StartStatementGenerator(db *gorm.DB) {
+	err := Register(db, Task{
+		Name:     "statement-generator",
+		Schedule: "30 0 1 * *", // Finalize last month's statements at 00:30 on the first day of every month
+		Jitter:   5 * time.Minute,
+		RunOnce:  true,
+		Run: func(ctx context.Context) error {
+			period := time.Now().AddDate(0, -1, 0).Format(models.StatementPeriodLayout)
+			if err := GenerateMonthlyStatements(db, period); err != nil {
+				return fmt.Errorf("generate statements for %s: %w", period, err)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to register statement generator task", zap.Error(err))
+	}
+}
+
+// GenerateMonthlyStatements generates statements for every user with metered usage in the period
+func GenerateMonthlyStatements(db *gorm.DB, period string) error {
+	start, end, err := models.StatementPeriodRange(period)
+	if err != nil {
+		return err
+	}
+
+	userIDs, err := models.GetUsersWithUsage(db, start, end)
+	if err != nil {
+		return err
+	}
+
+	generated := 0
+	for _, userID := range userIDs {
+		if _, err := models.GenerateMonthlyStatement(db, userID, period); err != nil {
+			logger.Warn("Failed to generate monthly statement", zap.Uint("userId", userID), zap.String("period", period), zap.Error(err))
+			continue
+		}
+		generated++
+	}
+
+	logger.Info("Monthly statements generated", zap.String("period", period), zap.Int("count", generated))
+	return nil
+}
@@ -0,0 +1,71 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// storageCleanerBatchSize is the number of expired objects deleted per query
+const storageCleanerBatchSize = 200
+
+// StartStorageCleaner starts the task that deletes stored files past their
+// expiry time, such as oneshot TTS audio, and releases their storage usage
+func StartStorageCleaner(db *gorm.DB) {
+	err := Register(db, Task{
+		Name:     "storage-cleaner",
+		Schedule: "*/10 * * * *",
+		Jitter:   time.Minute,
+		RunOnce:  true,
+		Run: func(ctx context.Context) error {
+			deleted, err := purgeExpiredStorageObjects(ctx, db, stores.Default(), time.Now())
+			if err != nil {
+				return err
+			}
+			if deleted > 0 {
+				logger.Info("Storage cleaner task completed", zap.Int("deleted", deleted))
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to register storage cleaner task", zap.Error(err))
+	}
+}
+
+// purgeExpiredStorageObjects deletes expired files batch by batch. A file that
+// fails to delete stays registered and is retried on the next run.
+func purgeExpiredStorageObjects(ctx context.Context, db *gorm.DB, store stores.Store, now time.Time) (int, error) {
+	deleted := 0
+	for ctx.Err() == nil {
+		objects, err := models.GetExpiredStorageObjects(db, now, storageCleanerBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+		progress := 0
+		for i := range objects {
+			object := &objects[i]
+			if exists, err := store.Exists(object.StorageKey); err == nil && exists {
+				if err := store.Delete(object.StorageKey); err != nil {
+					logger.Warn("Failed to delete expired file", zap.String("key", object.StorageKey), zap.Error(err))
+					continue
+				}
+			}
+			if err := models.ReleaseStorageObject(db, object); err != nil {
+				return deleted, err
+			}
+			progress++
+		}
+		deleted += progress
+		// Stop when the backlog is drained or the remaining files keep failing
+		if len(objects) < storageCleanerBatchSize || progress == 0 {
+			break
+		}
+	}
+	return deleted, nil
+}
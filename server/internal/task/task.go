@@ -1,18 +1,67 @@
 package task
 
 import (
-	"time"
+	"context"
+	"sync"
 
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+var (
+	schedulersMu sync.Mutex
+	schedulers   []*cron.Cron
+	// stopped is closed by StopSchedulers to cancel running tasks
+	stopped  = make(chan struct{})
+	stopOnce sync.Once
+)
+
+// startCron starts c and keeps it so StopSchedulers can stop it on shutdown
+func startCron(c *cron.Cron) {
+	schedulersMu.Lock()
+	schedulers = append(schedulers, c)
+	schedulersMu.Unlock()
+	c.Start()
+}
+
+// StopSchedulers stops every scheduled task started by this package and waits
+// for running jobs to finish, or returns ctx's error if they outlive it
+func StopSchedulers(ctx context.Context) error {
+	stopOnce.Do(func() { close(stopped) })
+
+	schedulersMu.Lock()
+	running := make([]context.Context, 0, len(schedulers))
+	for _, c := range schedulers {
+		running = append(running, c.Stop())
+	}
+	schedulers = nil
+	schedulersMu.Unlock()
+
+	for _, done := range running {
+		select {
+		case <-done.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
 // StartOfflineChecker starts the user offline checking task
 func StartOfflineChecker(db *gorm.DB) {
-	ticker := time.NewTicker(2 * time.Minute) // Check every 2 minutes
-	defer ticker.Stop()
-
-	for range ticker.C {
-		checkOfflineUsers(db)
+	err := Register(db, Task{
+		Name:     "offline-checker",
+		Schedule: "@every 2m", // Check every 2 minutes
+		RunOnce:  true,
+		Run: func(ctx context.Context) error {
+			checkOfflineUsers(db)
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to register offline checker task", zap.Error(err))
 	}
 }
 
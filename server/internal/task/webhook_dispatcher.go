@@ -0,0 +1,191 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/notification"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// webhookDispatchBatch is the number of due deliveries sent per run
+	webhookDispatchBatch = 100
+	// webhookDispatchWorkers bounds concurrent requests so one slow endpoint does not hold up the rest
+	webhookDispatchWorkers = 8
+	// webhookDeliveryLease keeps a claimed delivery from being picked up again while it is in flight
+	webhookDeliveryLease = 2 * time.Minute
+	// webhookDeliveryRetention is how long finished deliveries are kept in the delivery log
+	webhookDeliveryRetention = 30 * 24 * time.Hour
+	// webhookResponseDrain is how much of a response body is read so the connection can be reused;
+	// the body itself is never stored or shown to the user
+	webhookResponseDrain = 4096
+)
+
+var (
+	// webhookClient refuses loopback, private, link-local and metadata addresses at dial time,
+	// so a user-supplied URL cannot be used to reach the internal network
+	webhookClient = utils.NewPublicHTTPClient(10 * time.Second)
+	// webhookPrivateClient is used when WEBHOOK_ALLOW_PRIVATE_NETWORKS is set for self-hosted receivers
+	webhookPrivateClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// webhookAllowPrivateNetworks reports whether webhooks may target internal addresses
+func webhookAllowPrivateNetworks() bool {
+	return utils.GetBoolEnv("WEBHOOK_ALLOW_PRIVATE_NETWORKS")
+}
+
+// ValidateWebhookURL checks that a webhook URL is http(s) and, unless private networks are allowed,
+// that its host resolves only to public addresses
+func ValidateWebhookURL(ctx context.Context, raw string) error {
+	if webhookAllowPrivateNetworks() {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid url: %s", raw)
+		}
+		return nil
+	}
+	return utils.ValidatePublicURL(ctx, raw)
+}
+
+// StartWebhookDispatcher starts delivering queued webhook events and pruning the delivery log
+func StartWebhookDispatcher(db *gorm.DB) {
+	err := Register(db, Task{
+		Name:     "webhook-dispatcher",
+		Schedule: "@every 5s",
+		RunOnce:  true,
+		Run: func(ctx context.Context) error {
+			return DispatchWebhooks(ctx, db)
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to register webhook dispatcher task", zap.Error(err))
+	}
+
+	err = Register(db, Task{
+		Name:     "webhook-delivery-cleaner",
+		Schedule: "30 3 * * *", // 3:30 AM every day
+		Jitter:   5 * time.Minute,
+		RunOnce:  true,
+		Run: func(ctx context.Context) error {
+			deleted, err := models.CleanupWebhookDeliveries(db, time.Now().Add(-webhookDeliveryRetention))
+			if deleted > 0 {
+				logger.Info("Cleaned up webhook deliveries", zap.Int64("deleted", deleted))
+			}
+			return err
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to register webhook delivery cleaner task", zap.Error(err))
+	}
+}
+
+// DispatchWebhooks sends every delivery whose next attempt is due
+func DispatchWebhooks(ctx context.Context, db *gorm.DB) error {
+	due, err := models.GetDueWebhookDeliveries(db, time.Now(), webhookDispatchBatch)
+	if err != nil {
+		return err
+	}
+
+	jobs := make(chan *models.WebhookDelivery)
+	var wg sync.WaitGroup
+	for i := 0; i < webhookDispatchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range jobs {
+				claimed, err := models.ClaimWebhookDelivery(db, delivery, webhookDeliveryLease)
+				if err != nil || !claimed {
+					continue
+				}
+				if err := DeliverWebhook(ctx, db, delivery, true); err != nil {
+					logger.Warn("Webhook delivery failed",
+						zap.Uint("deliveryId", delivery.ID),
+						zap.Uint("webhookId", delivery.WebhookID),
+						zap.String("event", delivery.Event),
+						zap.Int("attempts", delivery.Attempts),
+						zap.Error(err))
+				}
+			}
+		}()
+	}
+	for i := range due {
+		jobs <- &due[i]
+	}
+	close(jobs)
+	wg.Wait()
+	return nil
+}
+
+// DeliverWebhook sends one attempt of delivery and records the result in the delivery log.
+// With retry a failed attempt is rescheduled with exponential backoff until the attempts run out
+func DeliverWebhook(ctx context.Context, db *gorm.DB, delivery *models.WebhookDelivery, retry bool) error {
+	var webhook models.Webhook
+	if err := db.First(&webhook, delivery.WebhookID).Error; err != nil {
+		return models.RecordWebhookAttempt(db, delivery, 0, fmt.Errorf("webhook not found: %w", err), 0, false)
+	}
+	if !webhook.Enabled && delivery.Event != models.WebhookEventPing {
+		return models.RecordWebhookAttempt(db, delivery, 0, errors.New("webhook is disabled"), 0, false)
+	}
+
+	start := time.Now()
+	status, sendErr := sendWebhook(ctx, &webhook, delivery)
+	if err := models.RecordWebhookAttempt(db, delivery, status, sendErr, time.Since(start), retry); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+// sendWebhook posts the delivery payload signed with the webhook secret and returns the response status
+func sendWebhook(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "LingEcho-Webhook/1.0")
+	req.Header.Set(notification.WebhookEventHeader, delivery.Event)
+	req.Header.Set(notification.WebhookTimestampHeader, timestamp)
+	req.Header.Set(notification.WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	if webhook.Secret != "" {
+		req.Header.Set(notification.WebhookSignatureHeader, "sha256="+notification.SignWebhookPayload(webhook.Secret, timestamp, payload))
+	}
+
+	client := webhookClient
+	if webhookAllowPrivateNetworks() {
+		client = webhookPrivateClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, webhookSendError(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseDrain))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// webhookSendError keeps the delivery log from echoing internal network details back to the user
+func webhookSendError(err error) error {
+	if errors.Is(err, utils.ErrBlockedAddress) {
+		return utils.ErrBlockedAddress
+	}
+	return err
+}
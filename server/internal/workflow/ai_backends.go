@@ -0,0 +1,144 @@
+package workflowdef
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/llm"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
+	"github.com/code-100-precent/LingEcho/pkg/synthesizer"
+	runtimewf "github.com/code-100-precent/LingEcho/pkg/workflow"
+)
+
+const (
+	// llmNodeTimeout 单次 LLM 节点调用超时
+	llmNodeTimeout = 2 * time.Minute
+	// ttsNodeTimeout 单次 TTS 节点合成超时
+	ttsNodeTimeout = 2 * time.Minute
+)
+
+func init() {
+	runtimewf.SetLLMCompleter(completeWithLLM)
+	runtimewf.SetTTSSynthesizer(synthesizeToStorage)
+}
+
+// completeWithLLM 使用 pkg/llm 完成 llm 节点请求。API Key 必须由节点配置提供，
+// 不使用服务器的全局 LLM Key，避免用户工作流消耗服务器额度；地址与模型未配置时回退到全局配置
+func completeWithLLM(req runtimewf.LLMRequest) (string, error) {
+	providerType := req.Provider
+	if providerType == "" {
+		providerType = string(llm.ProviderTypeOpenAI)
+	}
+	apiKey, baseURL, model := req.APIKey, req.BaseURL, req.Model
+	if apiKey == "" && providerType != string(llm.ProviderTypeOllama) {
+		return "", errors.New("LLM node requires an apiKey")
+	}
+	if cfg := config.GlobalConfig; cfg != nil {
+		if baseURL == "" {
+			baseURL = cfg.LLMBaseURL
+		}
+		if model == "" {
+			model = cfg.LLMModel
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), llmNodeTimeout)
+	defer cancel()
+
+	provider, err := llm.NewLLMProviderFromConfig(ctx, providerType, apiKey, baseURL, req.SystemPrompt, req.Extra)
+	if err != nil {
+		return "", err
+	}
+	defer provider.Hangup()
+
+	return provider.QueryWithOptions(req.Prompt, llm.QueryOptions{
+		Model:       model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	})
+}
+
+// synthesizeToStorage 调用 TTS 服务合成音频，并以 WAV 格式保存到存储
+func synthesizeToStorage(req runtimewf.TTSRequest) (*runtimewf.TTSResult, error) {
+	svc, err := synthesizer.NewSynthesisServiceFromCredential(synthesizer.TTSCredentialConfig(req.Config))
+	if err != nil {
+		return nil, err
+	}
+	defer svc.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), ttsNodeTimeout)
+	defer cancel()
+
+	collector := &pcmCollector{}
+	if err := svc.Synthesize(ctx, collector, req.Text); err != nil {
+		return nil, err
+	}
+	pcm := collector.Bytes()
+	if len(pcm) == 0 {
+		return nil, fmt.Errorf("TTS returned empty audio")
+	}
+
+	format := svc.Format()
+	wavData := pcmToWAV(pcm, format.SampleRate, format.Channels, format.BitDepth)
+
+	store := stores.Default()
+	key := fmt.Sprintf("workflow/tts_%d.wav", time.Now().UnixNano())
+	if err := store.Write(key, bytes.NewReader(wavData)); err != nil {
+		return nil, fmt.Errorf("save audio failed: %w", err)
+	}
+
+	return &runtimewf.TTSResult{
+		AudioURL:   stores.SignPublicURL(store.PublicURL(key), 0),
+		Format:     "wav",
+		Size:       len(wavData),
+		SampleRate: format.SampleRate,
+	}, nil
+}
+
+// pcmCollector 收集 TTS 输出的 PCM 数据
+type pcmCollector struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (c *pcmCollector) OnMessage(data []byte) {
+	c.mu.Lock()
+	c.data = append(c.data, data...)
+	c.mu.Unlock()
+}
+
+func (c *pcmCollector) OnTimestamp(timestamp synthesizer.SentenceTimestamp) {}
+
+func (c *pcmCollector) Bytes() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.data
+}
+
+// pcmToWAV 为 PCM 数据添加 44 字节 WAV 头
+func pcmToWAV(pcmData []byte, sampleRate, channels, bitDepth int) []byte {
+	header := make([]byte, 44)
+	dataSize := len(pcmData)
+
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(36+dataSize))
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], 1)
+	binary.LittleEndian.PutUint16(header[22:24], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(sampleRate*channels*bitDepth/8))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(channels*bitDepth/8))
+	binary.LittleEndian.PutUint16(header[34:36], uint16(bitDepth))
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(dataSize))
+
+	return append(header, pcmData...)
+}
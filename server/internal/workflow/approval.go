@@ -0,0 +1,305 @@
+package workflowdef
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/notification"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	runtimewf "github.com/code-100-precent/LingEcho/pkg/workflow"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 审批节点属性
+const (
+	propApprovalExpiresIn  = "expires_in"  // 审批有效期，如 "24h"
+	propApprovalOnExpire   = "on_expire"   // 过期处理：reject(默认)、approve、fail
+	propApprovalChannels   = "channels"    // 通知渠道：inapp,email,webhook（默认 inapp,email）
+	propApprovalWebhookURL = "webhook_url" // webhook 通知地址
+)
+
+// Approval expiry behaviours
+const (
+	ApprovalOnExpireReject  = "reject"
+	ApprovalOnExpireApprove = "approve"
+	ApprovalOnExpireFail    = "fail"
+)
+
+var (
+	ErrApprovalNotPending = errors.New("approval is not pending")
+	ErrApprovalForbidden  = errors.New("user is not an approver")
+	ErrInstanceNotWaiting = errors.New("workflow instance is not waiting for approval")
+)
+
+// ApprovalNotifier 审批通知发送函数，便于测试替换
+var ApprovalNotifier = notifyApprovers
+
+// suspendForApproval 工作流在审批节点暂停时创建审批记录并通知审批人
+func suspendForApproval(db *gorm.DB, instance *models.WorkflowInstance, wf *runtimewf.Workflow, nodeID string) error {
+	node, ok := wf.Nodes[nodeID].(*runtimewf.ApprovalNode)
+	if !ok {
+		return fmt.Errorf("node %s is not an approval node", nodeID)
+	}
+
+	var def models.WorkflowDefinition
+	if err := db.Select("id", "user_id", "name").First(&def, instance.DefinitionID).Error; err != nil {
+		return fmt.Errorf("workflow definition not found: %w", err)
+	}
+
+	approval := models.WorkflowApproval{
+		InstanceID:   instance.ID,
+		DefinitionID: instance.DefinitionID,
+		UserID:       def.UserID,
+		NodeID:       node.ID,
+		NodeName:     node.Name,
+		Title:        node.Title,
+		Message:      node.RenderMessage(wf.Context),
+		Approvers:    models.StringArray(node.Approvers),
+		Status:       models.WorkflowApprovalPending,
+		OnExpire:     node.Properties[propApprovalOnExpire],
+	}
+	if approval.OnExpire == "" {
+		approval.OnExpire = ApprovalOnExpireReject
+	}
+	expiresIn, err := parseOptionalDuration(node.Properties[propApprovalExpiresIn])
+	if err != nil {
+		return fmt.Errorf("%s: %w", propApprovalExpiresIn, err)
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		approval.ExpiresAt = &expiresAt
+	}
+	if err := db.Create(&approval).Error; err != nil {
+		return fmt.Errorf("failed to create approval: %w", err)
+	}
+
+	channels := parseStringList(node.Properties[propApprovalChannels])
+	if len(channels) == 0 {
+		channels = []string{"inapp", "email"}
+	}
+	go ApprovalNotifier(db, &approval, def.Name, channels, node.Properties[propApprovalWebhookURL])
+	return nil
+}
+
+// notifyApprovers 通过站内信、邮件、webhook 通知审批人
+func notifyApprovers(db *gorm.DB, approval *models.WorkflowApproval, workflowName string, channels []string, webhookURL string) {
+	subject := fmt.Sprintf("[%s] 待审批: %s", workflowName, approval.Title)
+	body := fmt.Sprintf("%s\n\n审批ID: %d", approval.Message, approval.ID)
+
+	enabled := make(map[string]bool, len(channels))
+	for _, ch := range channels {
+		enabled[strings.ToLower(ch)] = true
+	}
+
+	// 解析审批人：数字为用户ID，包含 @ 的为邮箱
+	var userIDs []uint
+	var emails []string
+	approvers := approval.Approvers
+	if len(approvers) == 0 {
+		approvers = models.StringArray{strconv.FormatUint(uint64(approval.UserID), 10)}
+	}
+	for _, approver := range approvers {
+		if strings.Contains(approver, "@") {
+			emails = append(emails, approver)
+		} else if id, err := strconv.ParseUint(approver, 10, 64); err == nil {
+			userIDs = append(userIDs, uint(id))
+		}
+	}
+
+	if len(userIDs) > 0 {
+		var users []models.User
+		db.Where("id IN ?", userIDs).Find(&users)
+		for i := range users {
+			u := &users[i]
+			if enabled["inapp"] {
+				// 站内信会实时推送到在线页面，并按用户设置推送到浏览器和移动设备
+				utils.Sig().Emit(models.SigUserNotify, u, db, notification.Message{
+					Event:   notification.EventWorkflowApproval,
+					Level:   notification.LevelWarning,
+					Title:   subject,
+					Content: body,
+					Data: map[string]interface{}{
+						"approvalId": approval.ID,
+						"instanceId": approval.InstanceID,
+						"workflowId": approval.DefinitionID,
+					},
+				})
+			}
+			if u.Email != "" {
+				emails = append(emails, u.Email)
+			}
+		}
+	}
+
+	if enabled["email"] && config.GlobalConfig != nil && config.GlobalConfig.Mail.Host != "" {
+		mailer := notification.NewMailNotification(config.GlobalConfig.Mail)
+		for _, to := range emails {
+			if err := mailer.Send(to, subject, body); err != nil {
+				logger.Warn("Failed to send approval email", zap.String("to", to), zap.Error(err))
+			}
+		}
+	}
+
+	if enabled["webhook"] && webhookURL != "" {
+		payload, _ := json.Marshal(map[string]interface{}{
+			"event":    "workflow.approval.requested",
+			"approval": approval,
+			"workflow": workflowName,
+		})
+		// webhook_url 由工作流作者填写，只允许访问公网地址
+		client := utils.NewPublicHTTPClient(10 * time.Second)
+		resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			logger.Warn("Failed to call approval webhook", zap.String("url", webhookURL), zap.Error(err))
+			return
+		}
+		resp.Body.Close()
+	}
+}
+
+// ResolveApproval 审批通过或拒绝，并从审批节点继续执行工作流
+func ResolveApproval(db *gorm.DB, approvalID uint, user *models.User, approved bool, comment string) (*models.WorkflowInstance, error) {
+	var approval models.WorkflowApproval
+	if err := db.First(&approval, approvalID).Error; err != nil {
+		return nil, fmt.Errorf("approval not found: %w", err)
+	}
+	if approval.Status != models.WorkflowApprovalPending {
+		return nil, ErrApprovalNotPending
+	}
+	if !approval.CanDecide(user) {
+		return nil, ErrApprovalForbidden
+	}
+
+	status := models.WorkflowApprovalRejected
+	if approved {
+		status = models.WorkflowApprovalApproved
+	}
+	if err := closeApproval(db, &approval, status, user.ID, comment); err != nil {
+		return nil, err
+	}
+	return resumeAfterApproval(db, &approval, approved)
+}
+
+// closeApproval 原子地将待审批记录更新为最终状态，避免重复处理
+func closeApproval(db *gorm.DB, approval *models.WorkflowApproval, status string, userID uint, comment string) error {
+	now := time.Now()
+	result := db.Model(&models.WorkflowApproval{}).
+		Where("id = ? AND status = ?", approval.ID, models.WorkflowApprovalPending).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"decided_by": userID,
+			"decided_at": now,
+			"comment":    comment,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrApprovalNotPending
+	}
+	approval.Status = status
+	approval.DecidedBy = userID
+	approval.DecidedAt = &now
+	approval.Comment = comment
+	return nil
+}
+
+// resumeAfterApproval 恢复工作流上下文并从审批节点继续执行
+func resumeAfterApproval(db *gorm.DB, approval *models.WorkflowApproval, approved bool) (*models.WorkflowInstance, error) {
+	var instance models.WorkflowInstance
+	if err := db.First(&instance, approval.InstanceID).Error; err != nil {
+		return nil, fmt.Errorf("workflow instance not found: %w", err)
+	}
+	if instance.Status != models.WorkflowInstanceStatusWaiting {
+		return nil, ErrInstanceNotWaiting
+	}
+	def, err := loadInstanceDefinition(db, &instance)
+	if err != nil {
+		return nil, err
+	}
+
+	runtimeWf, err := BuildRuntimeWorkflow(def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build workflow: %w", err)
+	}
+	for k, v := range instance.Parameters {
+		runtimeWf.Context.Parameters[k] = v
+	}
+	for k, v := range instance.ContextData {
+		runtimeWf.Context.NodeData[k] = v
+	}
+	decision := runtimewf.ApprovalRejected
+	if approved {
+		decision = runtimewf.ApprovalApproved
+	}
+	runtimeWf.Context.NodeData[runtimewf.ApprovalDecisionKey(approval.NodeID)] = decision
+	runtimeWf.Context.NodeData[approval.NodeID+"_comment"] = approval.Comment
+
+	// 仅恢复仍在等待审批的实例，已取消或失败的实例不会被过期处理或迟到的审批重新执行
+	result := db.Model(&models.WorkflowInstance{}).
+		Where("id = ? AND status = ?", instance.ID, models.WorkflowInstanceStatusWaiting).
+		Update("status", models.WorkflowInstanceStatusRunning)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update workflow instance: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInstanceNotWaiting
+	}
+	instance.Status = models.WorkflowInstanceStatusRunning
+
+	execErr := runtimeWf.ResumeFrom(approval.NodeID)
+	if err := FinishWorkflowInstance(db, &instance, runtimeWf, execErr); err != nil {
+		return nil, err
+	}
+	if execErr != nil && !errors.Is(execErr, runtimewf.ErrWorkflowSuspended) {
+		return &instance, execErr
+	}
+	return &instance, nil
+}
+
+// ExpireApprovals 处理已过期的待审批记录，按 OnExpire 配置自动通过、拒绝或终止工作流
+func ExpireApprovals(db *gorm.DB) {
+	var expired []models.WorkflowApproval
+	if err := db.Where("status = ? AND expires_at IS NOT NULL AND expires_at < ?", models.WorkflowApprovalPending, time.Now()).
+		Find(&expired).Error; err != nil {
+		logger.Error("Failed to load expired approvals", zap.Error(err))
+		return
+	}
+
+	for i := range expired {
+		approval := &expired[i]
+		if err := closeApproval(db, approval, models.WorkflowApprovalExpired, 0, "expired"); err != nil {
+			continue
+		}
+		switch approval.OnExpire {
+		case ApprovalOnExpireApprove, ApprovalOnExpireReject:
+			if _, err := resumeAfterApproval(db, approval, approval.OnExpire == ApprovalOnExpireApprove); err != nil {
+				logger.Warn("Workflow failed after approval expiry",
+					zap.Uint("approvalId", approval.ID),
+					zap.Error(err))
+			}
+		default:
+			now := time.Now()
+			db.Model(&models.WorkflowInstance{}).
+				Where("id = ? AND status = ?", approval.InstanceID, models.WorkflowInstanceStatusWaiting).
+				Updates(map[string]interface{}{
+					"status":         models.WorkflowInstanceStatusFailed,
+					"failed_node_id": approval.NodeID,
+					"last_error":     "approval expired",
+					"completed_at":   now,
+				})
+		}
+		logger.Info("Workflow approval expired",
+			zap.Uint("approvalId", approval.ID),
+			zap.String("onExpire", approval.OnExpire))
+	}
+}
@@ -3,6 +3,8 @@ package workflowdef
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
 	runtimewf "github.com/code-100-precent/LingEcho/pkg/workflow"
@@ -19,6 +21,10 @@ func BuildRuntimeWorkflow(def *models.WorkflowDefinition) (*runtimewf.Workflow,
 
 	wf := runtimewf.NewWorkflow(fmt.Sprintf("definition-%d", def.ID))
 	wf.Context = runtimewf.NewWorkflowContext(fmt.Sprintf("definition-%d", def.ID))
+	wf.Context.UserID = def.UserID
+	if err := applyExecutionSettings(wf, def.Settings); err != nil {
+		return nil, err
+	}
 
 	nodeRegistry := make(map[string]runtimewf.ExecutableNode, len(def.Definition.Nodes))
 	startCount := 0
@@ -43,6 +49,9 @@ func BuildRuntimeWorkflow(def *models.WorkflowDefinition) (*runtimewf.Workflow,
 		if err != nil {
 			return nil, fmt.Errorf("node %s: %w", nodeSchema.ID, err)
 		}
+		if err := applyNodePolicy(execNode.Base()); err != nil {
+			return nil, fmt.Errorf("node %s: %w", nodeSchema.ID, err)
+		}
 		if baseNode.Type == runtimewf.NodeTypeStart {
 			startCount++
 		}
@@ -135,6 +144,9 @@ func BuildRuntimeNode(nodeSchema *models.WorkflowNodeSchema, graph *models.Workf
 	if err != nil {
 		return nil, fmt.Errorf("node %s: %w", nodeSchema.ID, err)
 	}
+	if err := applyNodePolicy(execNode.Base()); err != nil {
+		return nil, fmt.Errorf("node %s: %w", nodeSchema.ID, err)
+	}
 
 	return execNode, nil
 }
@@ -162,21 +174,16 @@ func instantiateNode(base runtimewf.Node) (runtimewf.ExecutableNode, error) {
 			}
 
 			// Parse task config (all properties except type/action)
-			taskNode.Config = make(map[string]interface{})
-			for k, v := range base.Properties {
-				if k != "task_type" && k != "type" && k != "action" {
-					// Try to parse as JSON if it looks like JSON
-					var jsonValue interface{}
-					if err := json.Unmarshal([]byte(v), &jsonValue); err == nil {
-						taskNode.Config[k] = jsonValue
-					} else {
-						// If not valid JSON, use as string
-						taskNode.Config[k] = v
-					}
-				}
-			}
+			taskNode.Config = parseTaskConfig(base.Properties)
 		}
 		return taskNode, nil
+	case runtimewf.NodeTypeHTTP, runtimewf.NodeTypeLLM, runtimewf.NodeTypeTTS, runtimewf.NodeTypeDelay:
+		// Built-in nodes are task nodes bound to the executor of the same name
+		return &runtimewf.TaskNode{
+			Node:     base,
+			TaskType: string(base.Type),
+			Config:   parseTaskConfig(base.Properties),
+		}, nil
 	case runtimewf.NodeTypeGateway:
 		gatewayNode := &runtimewf.GatewayNode{Node: base}
 		// Extract condition/expression from properties if present
@@ -187,6 +194,7 @@ func instantiateNode(base runtimewf.Node) (runtimewf.ExecutableNode, error) {
 				// Expression mode: use expression field
 				if expression, ok := base.Properties["expression"]; ok {
 					gatewayNode.Expression = expression
+					gatewayNode.Evaluator = runtimewf.EvaluateExpression
 				}
 			} else {
 				// Value mode (default): use condition field as context key
@@ -252,10 +260,41 @@ func instantiateNode(base runtimewf.Node) (runtimewf.ExecutableNode, error) {
 			} else if condition, ok := base.Properties["condition"]; ok {
 				gatewayNode.Expression = condition
 			}
+			gatewayNode.Evaluator = runtimewf.EvaluateExpression
 			// Condition nodes always store result
 			gatewayNode.StoreResult = true
 		}
 		return gatewayNode, nil
+	case runtimewf.NodeTypeApproval:
+		approvalNode := &runtimewf.ApprovalNode{Node: base}
+		if base.Properties != nil {
+			approvalNode.Title = base.Properties["title"]
+			approvalNode.Message = base.Properties["message"]
+			approvalNode.Approvers = parseStringList(base.Properties["approvers"])
+		}
+		if approvalNode.Title == "" {
+			approvalNode.Title = base.Name
+		}
+		return approvalNode, nil
+	case runtimewf.NodeTypeIVR:
+		return newIVRNode(base)
+	case runtimewf.NodeTypeCall:
+		return newCallNode(base)
+	case runtimewf.NodeTypeSay:
+		sayNode := &runtimewf.SayNode{Node: base}
+		if base.Properties != nil {
+			sayNode.Text = base.Properties["text"]
+		}
+		if sayNode.Text == "" {
+			return nil, fmt.Errorf("say node requires text")
+		}
+		return sayNode, nil
+	case runtimewf.NodeTypeCollectDTMF:
+		return newCollectDTMFNode(base)
+	case runtimewf.NodeTypeListen:
+		return newListenNode(base)
+	case runtimewf.NodeTypeIntent:
+		return newIntentNode(base)
 	case runtimewf.NodeTypeParallel:
 		return &runtimewf.ParallelNode{Node: base}, nil
 	case runtimewf.NodeTypeWait:
@@ -297,6 +336,46 @@ func AssignEdgeMetadata(node runtimewf.ExecutableNode, edge models.WorkflowEdgeS
 		if edge.Condition != "" && n.Condition == "" {
 			n.Condition = edge.Condition
 		}
+	case *runtimewf.ApprovalNode:
+		switch edge.Type {
+		case models.WorkflowEdgeTypeTrue:
+			n.ApprovedNextNodeID = edge.Target
+		case models.WorkflowEdgeTypeFalse:
+			n.RejectedNextNodeID = edge.Target
+		}
+	case *runtimewf.IVRNode:
+		// Branch edges carry the digits in their condition, the error edge handles no input
+		switch edge.Type {
+		case models.WorkflowEdgeTypeBranch:
+			if edge.Condition != "" {
+				if n.Options == nil {
+					n.Options = map[string]string{}
+				}
+				n.Options[strings.TrimSpace(edge.Condition)] = edge.Target
+			}
+		case models.WorkflowEdgeTypeError:
+			n.DefaultNextNodeID = edge.Target
+		}
+	case *runtimewf.CallNode:
+		switch edge.Type {
+		case models.WorkflowEdgeTypeTrue:
+			n.AnsweredNextNodeID = edge.Target
+		case models.WorkflowEdgeTypeFalse, models.WorkflowEdgeTypeError:
+			n.NoAnswerNextNodeID = edge.Target
+		}
+	case *runtimewf.IntentNode:
+		// Branch edges carry the intent name in their condition, the error edge handles no match
+		switch edge.Type {
+		case models.WorkflowEdgeTypeBranch:
+			if edge.Condition != "" {
+				if n.Routes == nil {
+					n.Routes = map[string]string{}
+				}
+				n.Routes[strings.TrimSpace(edge.Condition)] = edge.Target
+			}
+		case models.WorkflowEdgeTypeError:
+			n.DefaultNextNodeID = edge.Target
+		}
 	case *runtimewf.ConditionNode:
 		// ConditionNode is deprecated, but handle for backward compatibility
 		switch edge.Type {
@@ -317,6 +396,181 @@ func AssignEdgeMetadata(node runtimewf.ExecutableNode, edge models.WorkflowEdgeS
 	}
 }
 
+// parseTaskConfig converts node properties into executor config, decoding JSON values where possible
+func parseTaskConfig(props map[string]string) map[string]interface{} {
+	config := make(map[string]interface{})
+	for k, v := range props {
+		if k == "task_type" || k == "type" || k == "action" {
+			continue
+		}
+		// Try to parse as JSON if it looks like JSON
+		var jsonValue interface{}
+		if err := json.Unmarshal([]byte(v), &jsonValue); err == nil {
+			config[k] = jsonValue
+		} else {
+			// If not valid JSON, use as string
+			config[k] = v
+		}
+	}
+	return config
+}
+
+// newIVRNode reads menu settings from node properties; options may also come from branch edges
+func newIVRNode(base runtimewf.Node) (*runtimewf.IVRNode, error) {
+	ivrNode := &runtimewf.IVRNode{Node: base, Retries: runtimewf.DefaultIVRRetries}
+	props := base.Properties
+	if props == nil {
+		return ivrNode, nil
+	}
+	ivrNode.Prompt = props["prompt"]
+	ivrNode.InvalidPrompt = props["invalid_prompt"]
+	if raw := strings.TrimSpace(props["options"]); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &ivrNode.Options); err != nil {
+			return nil, fmt.Errorf("options: %w", err)
+		}
+	}
+	var err error
+	if v := props["max_digits"]; v != "" {
+		if ivrNode.MaxDigits, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("max_digits: %w", err)
+		}
+	}
+	if v := props["retries"]; v != "" {
+		if ivrNode.Retries, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("retries: %w", err)
+		}
+	}
+	if ivrNode.Timeout, err = parseOptionalDuration(props["input_timeout"]); err != nil {
+		return nil, fmt.Errorf("input_timeout: %w", err)
+	}
+	return ivrNode, nil
+}
+
+// newCallNode reads the callee and the assistant/credential the call uses from node properties
+func newCallNode(base runtimewf.Node) (*runtimewf.CallNode, error) {
+	callNode := &runtimewf.CallNode{Node: base}
+	props := base.Properties
+	if props == nil {
+		return nil, fmt.Errorf("call node requires target, assistant_id and credential_id")
+	}
+	callNode.Target = props["target"]
+	if callNode.Target == "" {
+		callNode.Target = props["to"]
+	}
+	if callNode.Target == "" {
+		return nil, fmt.Errorf("call node requires target")
+	}
+	for key, dst := range map[string]*uint{"assistant_id": &callNode.AssistantID, "credential_id": &callNode.CredentialID} {
+		id, err := strconv.ParseUint(props[key], 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("call node requires a valid %s", key)
+		}
+		*dst = uint(id)
+	}
+	var err error
+	if callNode.RingTimeout, err = parseOptionalDuration(props["ring_timeout"]); err != nil {
+		return nil, fmt.Errorf("ring_timeout: %w", err)
+	}
+	return callNode, nil
+}
+
+// newCollectDTMFNode reads prompt and digit limits from node properties
+func newCollectDTMFNode(base runtimewf.Node) (*runtimewf.CollectDTMFNode, error) {
+	node := &runtimewf.CollectDTMFNode{Node: base}
+	props := base.Properties
+	if props == nil {
+		return node, nil
+	}
+	node.Prompt = props["prompt"]
+	node.InvalidPrompt = props["invalid_prompt"]
+	for key, dst := range map[string]*int{"max_digits": &node.MaxDigits, "min_digits": &node.MinDigits, "retries": &node.Retries} {
+		if v := props[key]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			*dst = n
+		}
+	}
+	var err error
+	if node.Timeout, err = parseOptionalDuration(props["input_timeout"]); err != nil {
+		return nil, fmt.Errorf("input_timeout: %w", err)
+	}
+	return node, nil
+}
+
+// newListenNode reads prompt and listening limits from node properties
+func newListenNode(base runtimewf.Node) (*runtimewf.ListenNode, error) {
+	node := &runtimewf.ListenNode{Node: base}
+	props := base.Properties
+	if props == nil {
+		return node, nil
+	}
+	node.Prompt = props["prompt"]
+	var err error
+	if node.Timeout, err = parseOptionalDuration(props["timeout"]); err != nil {
+		return nil, fmt.Errorf("timeout: %w", err)
+	}
+	if node.Silence, err = parseOptionalDuration(props["silence"]); err != nil {
+		return nil, fmt.Errorf("silence: %w", err)
+	}
+	return node, nil
+}
+
+// newIntentNode reads intents as a JSON object of intent -> keywords (an array or a comma separated string);
+// routes come from branch edges
+func newIntentNode(base runtimewf.Node) (*runtimewf.IntentNode, error) {
+	node := &runtimewf.IntentNode{Node: base}
+	props := base.Properties
+	if props == nil {
+		return node, nil
+	}
+	node.Input = props["input"]
+	node.UseLLM = props["use_llm"] == "true" || props["use_llm"] == "1"
+	node.Provider = props["provider"]
+	node.APIKey = props["api_key"]
+	node.BaseURL = props["base_url"]
+	node.Model = props["model"]
+	if raw := strings.TrimSpace(props["intents"]); raw != "" {
+		var intents map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(raw), &intents); err != nil {
+			return nil, fmt.Errorf("intents: %w", err)
+		}
+		node.Intents = make(map[string][]string, len(intents))
+		for name, keywords := range intents {
+			var list string
+			if err := json.Unmarshal(keywords, &list); err == nil {
+				node.Intents[name] = parseStringList(list)
+				continue
+			}
+			var keywordList []string
+			if err := json.Unmarshal(keywords, &keywordList); err != nil {
+				return nil, fmt.Errorf("intents.%s: %w", name, err)
+			}
+			node.Intents[name] = keywordList
+		}
+	}
+	return node, nil
+}
+
+// parseStringList accepts a JSON array or a comma separated list
+func parseStringList(v string) []string {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal([]byte(v), &list); err == nil {
+		return list
+	}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func toNativeMap(sm models.StringMap) map[string]string {
 	if len(sm) == 0 {
 		return nil
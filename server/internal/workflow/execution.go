@@ -0,0 +1,431 @@
+package workflowdef
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/notification"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	runtimewf "github.com/code-100-precent/LingEcho/pkg/workflow"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ExecutionSettings 工作流级别的执行策略，存放在 WorkflowDefinition.Settings 中
+//
+//	{
+//	  "timeout": "5m",          // 整个工作流超时
+//	  "nodeTimeout": "30s",     // 节点默认单次执行超时
+//	  "retry": {"maxAttempts": 3, "initialBackoff": "1s", "maxBackoff": "30s", "multiplier": 2},
+//	  "notify": "failure"       // 执行结果通知：failure（默认）、always、never
+//	}
+type ExecutionSettings struct {
+	Timeout     string         `json:"timeout,omitempty"`
+	NodeTimeout string         `json:"nodeTimeout,omitempty"`
+	Retry       *RetrySettings `json:"retry,omitempty"`
+	Notify      string         `json:"notify,omitempty"`
+}
+
+// 执行结果通知策略
+const (
+	NotifyOnFailure = "failure"
+	NotifyAlways    = "always"
+	NotifyNever     = "never"
+)
+
+// RetrySettings 重试策略配置
+type RetrySettings struct {
+	MaxAttempts    int     `json:"maxAttempts"`
+	InitialBackoff string  `json:"initialBackoff,omitempty"`
+	MaxBackoff     string  `json:"maxBackoff,omitempty"`
+	Multiplier     float64 `json:"multiplier,omitempty"`
+}
+
+// Node properties recognised for per-node policies
+const (
+	propRetryMaxAttempts = "retry_max_attempts"
+	propRetryBackoff     = "retry_backoff"
+	propRetryMaxBackoff  = "retry_max_backoff"
+	propRetryMultiplier  = "retry_multiplier"
+	propNodeTimeout      = "node_timeout"
+)
+
+// ParseExecutionSettings 解析定义中的执行策略
+func ParseExecutionSettings(settings models.JSONMap) (*ExecutionSettings, error) {
+	if len(settings) == 0 {
+		return &ExecutionSettings{}, nil
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	var es ExecutionSettings
+	if err := json.Unmarshal(data, &es); err != nil {
+		return nil, fmt.Errorf("invalid execution settings: %w", err)
+	}
+	return &es, nil
+}
+
+func (r *RetrySettings) toPolicy() (*runtimewf.RetryPolicy, error) {
+	if r == nil {
+		return nil, nil
+	}
+	policy := &runtimewf.RetryPolicy{MaxAttempts: r.MaxAttempts, Multiplier: r.Multiplier}
+	var err error
+	if policy.InitialBackoff, err = parseOptionalDuration(r.InitialBackoff); err != nil {
+		return nil, fmt.Errorf("retry.initialBackoff: %w", err)
+	}
+	if policy.MaxBackoff, err = parseOptionalDuration(r.MaxBackoff); err != nil {
+		return nil, fmt.Errorf("retry.maxBackoff: %w", err)
+	}
+	return policy, nil
+}
+
+// applyExecutionSettings 将定义级策略写入运行时工作流
+func applyExecutionSettings(wf *runtimewf.Workflow, settings models.JSONMap) error {
+	es, err := ParseExecutionSettings(settings)
+	if err != nil {
+		return err
+	}
+	if wf.Timeout, err = parseOptionalDuration(es.Timeout); err != nil {
+		return fmt.Errorf("settings.timeout: %w", err)
+	}
+	if wf.DefaultNodeTimeout, err = parseOptionalDuration(es.NodeTimeout); err != nil {
+		return fmt.Errorf("settings.nodeTimeout: %w", err)
+	}
+	if wf.DefaultRetry, err = es.Retry.toPolicy(); err != nil {
+		return fmt.Errorf("settings.%w", err)
+	}
+	switch es.Notify {
+	case "", NotifyOnFailure, NotifyAlways, NotifyNever:
+	default:
+		return fmt.Errorf("settings.notify: unknown value %q", es.Notify)
+	}
+	return nil
+}
+
+// applyNodePolicy 从节点属性读取重试与超时配置
+func applyNodePolicy(node *runtimewf.Node) error {
+	if node == nil || len(node.Properties) == 0 {
+		return nil
+	}
+	props := node.Properties
+	var err error
+	if node.Timeout, err = parseOptionalDuration(props[propNodeTimeout]); err != nil {
+		return fmt.Errorf("%s: %w", propNodeTimeout, err)
+	}
+	attempts := props[propRetryMaxAttempts]
+	if attempts == "" {
+		return nil
+	}
+	policy := &runtimewf.RetryPolicy{}
+	if policy.MaxAttempts, err = strconv.Atoi(attempts); err != nil {
+		return fmt.Errorf("%s: %w", propRetryMaxAttempts, err)
+	}
+	if policy.InitialBackoff, err = parseOptionalDuration(props[propRetryBackoff]); err != nil {
+		return fmt.Errorf("%s: %w", propRetryBackoff, err)
+	}
+	if policy.MaxBackoff, err = parseOptionalDuration(props[propRetryMaxBackoff]); err != nil {
+		return fmt.Errorf("%s: %w", propRetryMaxBackoff, err)
+	}
+	if m := props[propRetryMultiplier]; m != "" {
+		if policy.Multiplier, err = strconv.ParseFloat(m, 64); err != nil {
+			return fmt.Errorf("%s: %w", propRetryMultiplier, err)
+		}
+	}
+	node.Retry = policy
+	return nil
+}
+
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	// 纯数字按秒处理
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// NewWorkflowInstance 创建运行中的实例记录
+func NewWorkflowInstance(db *gorm.DB, def *models.WorkflowDefinition, parameters map[string]interface{}, triggerSource string) (*models.WorkflowInstance, error) {
+	now := time.Now()
+	instance := models.WorkflowInstance{
+		DefinitionID:      def.ID,
+		DefinitionName:    def.Name,
+		DefinitionVersion: def.Version,
+		Status:            models.WorkflowInstanceStatusRunning,
+		StartedAt:         &now,
+		ContextData:       make(models.JSONMap),
+		ResultData:        make(models.JSONMap),
+		Parameters:        models.JSONMap(parameters),
+		TriggerSource:     triggerSource,
+		Attempts:          1,
+	}
+	if err := db.Create(&instance).Error; err != nil {
+		return nil, fmt.Errorf("failed to create workflow instance: %w", err)
+	}
+	return &instance, nil
+}
+
+// FinishWorkflowInstance 根据执行结果更新实例状态。
+// 节点执行失败（重试耗尽或超时）的实例进入 dead_letter 状态，可通过 API 查看并手动重试。
+func FinishWorkflowInstance(db *gorm.DB, instance *models.WorkflowInstance, wf *runtimewf.Workflow, execErr error) error {
+	if wf != nil && wf.Context != nil && wf.Context.CurrentNode != "" {
+		instance.CurrentNodeID = wf.Context.CurrentNode
+	}
+	if wf != nil {
+		saveWorkflowSteps(db, instance, wf.Context)
+	}
+
+	// 审批等节点暂停执行：保存上下文，等待恢复
+	var suspended *runtimewf.SuspendedError
+	if errors.As(execErr, &suspended) {
+		instance.Status = models.WorkflowInstanceStatusWaiting
+		if wf != nil && wf.Context != nil {
+			instance.ContextData = wf.Context.NodeData
+			instance.Parameters = wf.Context.Parameters
+		}
+		if err := db.Save(instance).Error; err != nil {
+			return fmt.Errorf("failed to update workflow instance: %w", err)
+		}
+		if wf != nil {
+			return suspendForApproval(db, instance, wf, suspended.NodeID)
+		}
+		return nil
+	}
+
+	completedAt := time.Now()
+	instance.CompletedAt = &completedAt
+
+	if execErr != nil {
+		instance.Status = models.WorkflowInstanceStatusFailed
+		var nodeErr *runtimewf.NodeExecutionError
+		if errors.As(execErr, &nodeErr) {
+			// 只有重试耗尽或超时进入死信，等待人工处理
+			if nodeErr.DeadLetter() {
+				instance.Status = models.WorkflowInstanceStatusDeadLetter
+			}
+			instance.FailedNodeID = nodeErr.NodeID
+		} else if wf != nil {
+			instance.FailedNodeID = wf.FailedNodeID
+		}
+		instance.LastError = execErr.Error()
+		instance.ResultData = models.JSONMap{
+			"error": execErr.Error(),
+		}
+		// 保存失败时的上下文，用于从失败节点恢复执行
+		if wf != nil && wf.Context != nil {
+			instance.ContextData = wf.Context.NodeData
+		}
+	} else {
+		instance.Status = models.WorkflowInstanceStatusCompleted
+		instance.FailedNodeID = ""
+		instance.LastError = ""
+		if wf != nil && wf.Context != nil {
+			instance.ContextData = wf.Context.NodeData
+			instance.ResultData = models.JSONMap{
+				"success": true,
+				"context": wf.Context.NodeData,
+			}
+		}
+	}
+
+	if err := db.Save(instance).Error; err != nil {
+		return fmt.Errorf("failed to update workflow instance: %w", err)
+	}
+	notifyWorkflowResult(db, instance)
+	return nil
+}
+
+// notifyWorkflowResult 按定义的通知策略把执行结果通知给工作流所有者
+func notifyWorkflowResult(db *gorm.DB, instance *models.WorkflowInstance) {
+	def, err := loadInstanceDefinition(db, instance)
+	if err != nil {
+		return
+	}
+	policy := NotifyOnFailure
+	if settings, err := ParseExecutionSettings(def.Settings); err == nil && settings.Notify != "" {
+		policy = settings.Notify
+	}
+	succeeded := instance.Status == models.WorkflowInstanceStatusCompleted
+	if policy == NotifyNever || (policy == NotifyOnFailure && succeeded) {
+		return
+	}
+
+	var owner models.User
+	if err := db.First(&owner, def.UserID).Error; err != nil {
+		return
+	}
+	msg := notification.Message{
+		Event:   notification.EventWorkflowResult,
+		Level:   notification.LevelInfo,
+		Title:   fmt.Sprintf("Workflow \"%s\" completed", def.Name),
+		Content: fmt.Sprintf("Workflow instance #%d finished successfully.", instance.ID),
+		Data: map[string]interface{}{
+			"workflowId": def.ID,
+			"instanceId": instance.ID,
+			"status":     instance.Status,
+		},
+	}
+	if !succeeded {
+		msg.Level = notification.LevelError
+		msg.Title = fmt.Sprintf("Workflow \"%s\" failed", def.Name)
+		msg.Content = fmt.Sprintf("Workflow instance #%d failed at node %s: %s", instance.ID, instance.FailedNodeID, instance.LastError)
+	}
+	utils.Sig().Emit(models.SigUserNotify, &owner, db, msg)
+}
+
+// maxStepValueBytes 步骤记录中单个输入/输出值序列化后的最大长度，超出部分截断
+const maxStepValueBytes = 16 * 1024
+
+// saveWorkflowSteps 保存本次执行的节点步骤记录，失败只记录日志，不影响实例状态
+func saveWorkflowSteps(db *gorm.DB, instance *models.WorkflowInstance, ctx *runtimewf.WorkflowContext) {
+	if ctx == nil || len(ctx.Steps) == 0 {
+		return
+	}
+	steps := make([]models.WorkflowStep, 0, len(ctx.Steps))
+	for _, step := range ctx.Steps {
+		steps = append(steps, models.WorkflowStep{
+			InstanceID: instance.ID,
+			Attempt:    instance.Attempts,
+			NodeID:     step.NodeID,
+			NodeName:   step.NodeName,
+			NodeType:   string(step.NodeType),
+			Status:     string(step.Status),
+			Inputs:     stepPayload(step.Inputs),
+			Outputs:    stepPayload(step.Outputs),
+			NextNodes:  models.StringArray(step.NextNodes),
+			Error:      step.Error,
+			StartedAt:  step.StartedAt,
+			DurationMs: step.Duration.Milliseconds(),
+		})
+	}
+	if err := db.Create(&steps).Error; err != nil {
+		logger.Warn("Failed to save workflow steps",
+			zap.Uint("instanceId", instance.ID),
+			zap.Error(err))
+	}
+	// 已保存的步骤不再重复写入（同一上下文可能再次结束，如审批恢复）
+	ctx.Steps = nil
+}
+
+// stepPayload 将步骤数据转为可存储的 JSON，无法序列化的值记录为字符串，过大的值截断
+func stepPayload(values map[string]interface{}) models.JSONMap {
+	if len(values) == 0 {
+		return nil
+	}
+	payload := make(models.JSONMap, len(values))
+	for key, value := range values {
+		data, err := json.Marshal(value)
+		switch {
+		case err != nil:
+			payload[key] = fmt.Sprintf("%v", value)
+		case len(data) > maxStepValueBytes:
+			payload[key] = string(data[:maxStepValueBytes]) + "...(truncated)"
+		default:
+			payload[key] = json.RawMessage(data)
+		}
+	}
+	return payload
+}
+
+// RetryWorkflowInstance 使用原始参数重新执行失败或死信实例
+func (m *WorkflowTriggerManager) RetryWorkflowInstance(instanceID uint) (*models.WorkflowInstance, error) {
+	return m.rerunWorkflowInstance(instanceID, false, "")
+}
+
+// ResumeWorkflowInstanceFrom 从指定节点（为空时为失败节点）继续执行失败或死信实例。
+// 上下文恢复为失败时保存的数据，之前已完成的节点不再执行。
+func (m *WorkflowTriggerManager) ResumeWorkflowInstanceFrom(instanceID uint, nodeID string) (*models.WorkflowInstance, error) {
+	return m.rerunWorkflowInstance(instanceID, true, nodeID)
+}
+
+func (m *WorkflowTriggerManager) rerunWorkflowInstance(instanceID uint, resume bool, fromNodeID string) (*models.WorkflowInstance, error) {
+	var instance models.WorkflowInstance
+	if err := m.db.First(&instance, instanceID).Error; err != nil {
+		return nil, fmt.Errorf("workflow instance not found: %w", err)
+	}
+	if instance.Status != models.WorkflowInstanceStatusDeadLetter && instance.Status != models.WorkflowInstanceStatusFailed {
+		return nil, fmt.Errorf("only failed or dead-letter instances can be retried (current status: %s)", instance.Status)
+	}
+
+	// 重试使用实例启动时的版本，而不是当前草稿或最新发布版本
+	def, err := loadInstanceDefinition(m.db, &instance)
+	if err != nil {
+		return nil, err
+	}
+
+	runtimeWf, err := BuildRuntimeWorkflow(def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build workflow: %w", err)
+	}
+	for k, v := range instance.Parameters {
+		runtimeWf.Context.Parameters[k] = v
+	}
+	runtimeWf.Context.Parameters["_retry_of"] = instance.ID
+
+	if resume {
+		if fromNodeID == "" {
+			fromNodeID = instance.FailedNodeID
+		}
+		if fromNodeID == "" {
+			return nil, errors.New("instance has no failed node to resume from")
+		}
+		if _, ok := runtimeWf.Nodes[fromNodeID]; !ok {
+			return nil, fmt.Errorf("node %s not found in workflow", fromNodeID)
+		}
+		for k, v := range instance.ContextData {
+			runtimeWf.Context.NodeData[k] = v
+		}
+		runtimeWf.Context.Parameters["_resumed_from"] = fromNodeID
+	}
+
+	// 以状态为条件更新，并发的重试请求只有一个能把实例改为运行中
+	now := time.Now()
+	result := m.db.Model(&models.WorkflowInstance{}).
+		Where("id = ? AND status IN ?", instance.ID, []string{models.WorkflowInstanceStatusDeadLetter, models.WorkflowInstanceStatusFailed}).
+		Updates(map[string]interface{}{
+			"status":       models.WorkflowInstanceStatusRunning,
+			"started_at":   now,
+			"completed_at": nil,
+			"attempts":     gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update workflow instance: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("workflow instance is already being retried")
+	}
+	instance.Status = models.WorkflowInstanceStatusRunning
+	instance.StartedAt = &now
+	instance.CompletedAt = nil
+	instance.Attempts++
+
+	var execErr error
+	if resume {
+		execErr = runtimeWf.ResumeFrom(fromNodeID)
+	} else {
+		execErr = runtimeWf.Execute()
+	}
+	if err := FinishWorkflowInstance(m.db, &instance, runtimeWf, execErr); err != nil {
+		return nil, err
+	}
+	if errors.Is(execErr, runtimewf.ErrWorkflowSuspended) {
+		return &instance, nil
+	}
+	if execErr != nil {
+		logger.Warn("Workflow retry failed",
+			zap.Uint("instanceId", instance.ID),
+			zap.String("fromNode", fromNodeID),
+			zap.Int("attempts", instance.Attempts),
+			zap.Error(execErr))
+	}
+	return &instance, execErr
+}
@@ -1,8 +1,11 @@
 package workflowdef
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
@@ -11,13 +14,31 @@ import (
 	"gorm.io/gorm"
 )
 
+// 多副本部署时通过数据库租约选主，只有主节点触发定时工作流和处理过期审批
+const (
+	schedulerLeaseName     = "workflow-scheduler"
+	schedulerLeaseTTL      = 30 * time.Second
+	schedulerRenewInterval = 10 * time.Second
+	scheduleRunRetention   = 7 * 24 * time.Hour // 触发记录保留时长
+)
+
 // WorkflowScheduler 工作流定时任务调度器
 type WorkflowScheduler struct {
 	db             *gorm.DB
 	triggerManager *WorkflowTriggerManager
 	cron           *cron.Cron
-	jobIDs         map[uint]cron.EntryID // 工作流ID -> Cron任务ID
+	jobIDs         map[uint]scheduledJob // 工作流ID -> 已注册的定时任务
 	mu             sync.RWMutex
+
+	holder   string        // 本实例的租约持有者标识
+	leader   atomic.Bool   // 当前是否持有调度租约
+	stopElec chan struct{} // 关闭以停止选主循环
+}
+
+// scheduledJob 已注册的定时任务，cronExpr 用于同步时判断配置是否变化
+type scheduledJob struct {
+	entryID  cron.EntryID
+	cronExpr string
 }
 
 var (
@@ -32,7 +53,8 @@ func GetWorkflowScheduler(db *gorm.DB) *WorkflowScheduler {
 			db:             db,
 			triggerManager: NewWorkflowTriggerManager(db),
 			cron:           cron.New(cron.WithSeconds()),
-			jobIDs:         make(map[uint]cron.EntryID),
+			jobIDs:         make(map[uint]scheduledJob),
+			holder:         models.LeaseHolderID(),
 		}
 	})
 	return schedulerInstance
@@ -59,9 +81,28 @@ func (s *WorkflowScheduler) Start() error {
 		}
 	}
 
+	// 定期处理过期审批
+	if _, err := s.cron.AddFunc("@every 1m", func() {
+		if s.IsLeader() {
+			ExpireApprovals(s.db)
+		}
+	}); err != nil {
+		logger.Error("Failed to register approval expiry job", zap.Error(err))
+	}
+
+	// 其他副本上修改的定时配置只更新了各自的 Cron，主节点定期与数据库同步
+	if _, err := s.cron.AddFunc("@every 1m", func() {
+		if s.IsLeader() {
+			s.syncSchedules()
+		}
+	}); err != nil {
+		logger.Error("Failed to register schedule sync job", zap.Error(err))
+	}
+
 	// 启动 Cron
 	s.cron.Start()
-	logger.Info("Workflow scheduler started")
+	s.startElection()
+	logger.Info("Workflow scheduler started", zap.String("holder", s.holder))
 
 	return nil
 }
@@ -69,9 +110,22 @@ func (s *WorkflowScheduler) Start() error {
 // Stop 停止调度器
 func (s *WorkflowScheduler) Stop() {
 	s.cron.Stop()
+	s.stopElection()
 	logger.Info("Workflow scheduler stopped")
 }
 
+// Shutdown 停止调度器并等待正在执行的定时任务结束，ctx 到期时返回 ctx 的错误
+func (s *WorkflowScheduler) Shutdown(ctx context.Context) error {
+	select {
+	case <-s.cron.Stop().Done():
+		s.stopElection()
+		logger.Info("Workflow scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // ScheduleWorkflow 为工作流注册定时任务
 func (s *WorkflowScheduler) ScheduleWorkflow(workflowID uint) error {
 	// 获取工作流定义
@@ -85,8 +139,12 @@ func (s *WorkflowScheduler) ScheduleWorkflow(workflowID uint) error {
 		return fmt.Errorf("workflow is not active")
 	}
 
-	// 解析触发器配置
-	config, err := ParseTriggerConfig(&def)
+	// 解析已发布版本的触发器配置
+	published, err := ResolvePublishedDefinition(s.db, &def)
+	if err != nil {
+		return fmt.Errorf("failed to load published workflow: %w", err)
+	}
+	config, err := ParseTriggerConfig(published)
 	if err != nil {
 		return fmt.Errorf("failed to parse trigger config: %w", err)
 	}
@@ -101,7 +159,8 @@ func (s *WorkflowScheduler) ScheduleWorkflow(workflowID uint) error {
 
 	// 创建定时任务
 	entryID, err := s.cron.AddFunc(config.Schedule.CronExpr, func() {
-		s.executeScheduledWorkflow(workflowID)
+		// 按秒对齐的计划触发时间作为运行键，各副本时钟存在毫秒级偏差也能得到相同的键
+		s.executeScheduledWorkflow(workflowID, time.Now().Truncate(time.Second))
 	})
 
 	if err != nil {
@@ -110,7 +169,7 @@ func (s *WorkflowScheduler) ScheduleWorkflow(workflowID uint) error {
 
 	// 保存任务ID
 	s.mu.Lock()
-	s.jobIDs[workflowID] = entryID
+	s.jobIDs[workflowID] = scheduledJob{entryID: entryID, cronExpr: config.Schedule.CronExpr}
 	s.mu.Unlock()
 
 	logger.Info("Workflow scheduled",
@@ -125,21 +184,49 @@ func (s *WorkflowScheduler) UnscheduleWorkflow(workflowID uint) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if entryID, exists := s.jobIDs[workflowID]; exists {
-		s.cron.Remove(entryID)
+	if job, exists := s.jobIDs[workflowID]; exists {
+		s.cron.Remove(job.entryID)
 		delete(s.jobIDs, workflowID)
 		logger.Info("Workflow unscheduled",
 			zap.Uint("workflowId", workflowID))
 	}
 }
 
-// executeScheduledWorkflow 执行定时工作流
-func (s *WorkflowScheduler) executeScheduledWorkflow(workflowID uint) {
+// RefreshWorkflow 按最新发布版本重新注册定时任务，未启用定时触发时移除已有任务
+func (s *WorkflowScheduler) RefreshWorkflow(workflowID uint) {
+	if err := s.ScheduleWorkflow(workflowID); err != nil {
+		s.UnscheduleWorkflow(workflowID)
+	}
+}
+
+// executeScheduledWorkflow 执行定时工作流。只有主节点执行，并先以运行键登记本次触发，
+// 主节点切换期间新旧主节点同时触发时只有一个能登记成功
+func (s *WorkflowScheduler) executeScheduledWorkflow(workflowID uint, scheduledAt time.Time) {
+	if !s.IsLeader() {
+		return
+	}
+	runKey := fmt.Sprintf("%d:%d", workflowID, scheduledAt.Unix())
+	claimed, err := models.ClaimWorkflowScheduleRun(s.db, workflowID, runKey, s.holder)
+	if err != nil {
+		logger.Error("Failed to claim scheduled workflow run",
+			zap.Uint("workflowId", workflowID),
+			zap.String("runKey", runKey),
+			zap.Error(err))
+		return
+	}
+	if !claimed {
+		logger.Info("Scheduled workflow run already claimed by another replica",
+			zap.Uint("workflowId", workflowID),
+			zap.String("runKey", runKey))
+		return
+	}
+
 	logger.Info("Executing scheduled workflow",
-		zap.Uint("workflowId", workflowID))
+		zap.Uint("workflowId", workflowID),
+		zap.String("runKey", runKey))
 
 	// 使用触发器管理器执行工作流
-	_, err := s.triggerManager.TriggerWorkflow(
+	_, err = s.triggerManager.TriggerWorkflow(
 		workflowID,
 		make(map[string]interface{}), // 定时任务通常没有参数
 		fmt.Sprintf("schedule:%d", workflowID),
@@ -162,7 +249,7 @@ func (s *WorkflowScheduler) ReloadSchedules() error {
 
 	// 清空所有任务
 	s.mu.Lock()
-	s.jobIDs = make(map[uint]cron.EntryID)
+	s.jobIDs = make(map[uint]scheduledJob)
 	s.mu.Unlock()
 
 	// 重新创建 Cron 实例
@@ -171,3 +258,112 @@ func (s *WorkflowScheduler) ReloadSchedules() error {
 	// 重新加载并启动
 	return s.Start()
 }
+
+// IsLeader 当前实例是否持有调度租约
+func (s *WorkflowScheduler) IsLeader() bool {
+	return s.leader.Load()
+}
+
+// startElection 立即尝试获取租约，之后定期续约或抢占
+func (s *WorkflowScheduler) startElection() {
+	s.mu.Lock()
+	if s.stopElec != nil {
+		s.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	s.stopElec = stop
+	s.mu.Unlock()
+
+	s.campaign()
+	go func() {
+		ticker := time.NewTicker(schedulerRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.campaign()
+			}
+		}
+	}()
+}
+
+// stopElection 停止选主并释放租约，其他副本无需等待过期即可接管
+func (s *WorkflowScheduler) stopElection() {
+	s.mu.Lock()
+	stop := s.stopElec
+	s.stopElec = nil
+	s.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	if s.leader.Swap(false) {
+		if err := models.ReleaseLease(s.db, schedulerLeaseName, s.holder); err != nil {
+			logger.Warn("Failed to release workflow scheduler lease", zap.Error(err))
+		}
+	}
+}
+
+// campaign 获取或续约调度租约；数据库出错时放弃主节点身份，宁可漏触发也不重复触发
+func (s *WorkflowScheduler) campaign() {
+	acquired, err := models.TryAcquireLease(s.db, schedulerLeaseName, s.holder, schedulerLeaseTTL)
+	if err != nil {
+		logger.Warn("Failed to renew workflow scheduler lease", zap.Error(err))
+		acquired = false
+	}
+	wasLeader := s.leader.Swap(acquired)
+	switch {
+	case acquired && !wasLeader:
+		logger.Info("Became workflow scheduler leader", zap.String("holder", s.holder))
+		// 接管前其他副本可能修改过定时配置
+		s.syncSchedules()
+	case !acquired && wasLeader:
+		logger.Warn("Lost workflow scheduler leadership", zap.String("holder", s.holder))
+	}
+}
+
+// syncSchedules 使已注册的定时任务与数据库中已发布的配置一致，并清理过期的触发记录
+func (s *WorkflowScheduler) syncSchedules() {
+	workflows, err := s.triggerManager.GetScheduledWorkflows()
+	if err != nil {
+		logger.Error("Failed to load scheduled workflows", zap.Error(err))
+		return
+	}
+
+	wanted := make(map[uint]string, len(workflows))
+	for _, wf := range workflows {
+		if config, err := ParseTriggerConfig(&wf); err == nil && config.Schedule != nil {
+			wanted[wf.ID] = config.Schedule.CronExpr
+		}
+	}
+
+	s.mu.RLock()
+	var stale, changed []uint
+	for id := range s.jobIDs {
+		if _, ok := wanted[id]; !ok {
+			stale = append(stale, id)
+		}
+	}
+	for id, expr := range wanted {
+		if job, ok := s.jobIDs[id]; !ok || job.cronExpr != expr {
+			changed = append(changed, id)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, id := range stale {
+		s.UnscheduleWorkflow(id)
+	}
+	for _, id := range changed {
+		if err := s.ScheduleWorkflow(id); err != nil {
+			logger.Error("Failed to schedule workflow",
+				zap.Uint("workflowId", id),
+				zap.Error(err))
+		}
+	}
+
+	s.db.Where("created_at < ?", time.Now().Add(-scheduleRunRetention)).Delete(&models.WorkflowScheduleRun{})
+}
@@ -2,6 +2,7 @@ package workflowdef
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -156,6 +157,15 @@ func NewWorkflowTriggerManager(db *gorm.DB) *WorkflowTriggerManager {
 
 // TriggerWorkflow 触发工作流执行
 func (m *WorkflowTriggerManager) TriggerWorkflow(definitionID uint, parameters map[string]interface{}, triggerSource string) (*models.WorkflowInstance, error) {
+	return m.trigger(definitionID, parameters, triggerSource, nil)
+}
+
+// TriggerCallWorkflow 在通话中触发工作流，IVR 节点通过 call 播放提示音并读取按键
+func (m *WorkflowTriggerManager) TriggerCallWorkflow(definitionID uint, parameters map[string]interface{}, triggerSource string, call runtimewf.CallSession) (*models.WorkflowInstance, error) {
+	return m.trigger(definitionID, parameters, triggerSource, call)
+}
+
+func (m *WorkflowTriggerManager) trigger(definitionID uint, parameters map[string]interface{}, triggerSource string, call runtimewf.CallSession) (*models.WorkflowInstance, error) {
 	var def models.WorkflowDefinition
 	if err := m.db.First(&def, definitionID).Error; err != nil {
 		return nil, fmt.Errorf("workflow definition not found: %w", err)
@@ -166,8 +176,14 @@ func (m *WorkflowTriggerManager) TriggerWorkflow(definitionID uint, parameters m
 		return nil, fmt.Errorf("workflow is not active (current status: %s)", def.Status)
 	}
 
+	// 触发执行的是已发布版本，草稿的编辑不影响线上
+	published, err := ResolvePublishedDefinition(m.db, &def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load published workflow: %w", err)
+	}
+
 	// 构建并执行工作流
-	runtimeWf, err := BuildRuntimeWorkflow(&def)
+	runtimeWf, err := BuildRuntimeWorkflow(published)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build workflow: %w", err)
 	}
@@ -180,6 +196,8 @@ func (m *WorkflowTriggerManager) TriggerWorkflow(definitionID uint, parameters m
 		runtimeWf.Context.Parameters = make(map[string]interface{})
 	}
 
+	runtimeWf.Context.Call = call
+
 	// 添加触发源信息
 	runtimeWf.Context.Parameters["_trigger_source"] = triggerSource
 	runtimeWf.Context.Parameters["_trigger_time"] = time.Now().Format(time.RFC3339)
@@ -190,55 +208,42 @@ func (m *WorkflowTriggerManager) TriggerWorkflow(definitionID uint, parameters m
 	}
 
 	// 创建实例记录
-	now := time.Now()
-	instance := models.WorkflowInstance{
-		DefinitionID:   def.ID,
-		DefinitionName: def.Name,
-		Status:         "running",
-		StartedAt:      &now,
-		ContextData:    make(models.JSONMap),
-		ResultData:     make(models.JSONMap),
-	}
-
-	if err := m.db.Create(&instance).Error; err != nil {
-		return nil, fmt.Errorf("failed to create workflow instance: %w", err)
+	instance, err := NewWorkflowInstance(m.db, published, parameters, triggerSource)
+	if err != nil {
+		return nil, err
 	}
 
 	// 执行工作流
 	execErr := runtimeWf.Execute()
 
-	// 更新实例状态
-	completedAt := time.Now()
-	instance.CompletedAt = &completedAt
+	if errors.Is(execErr, runtimewf.ErrWorkflowSuspended) {
+		// 暂停等待审批不视为失败
+		logger.Info("Workflow suspended",
+			zap.Uint("definitionId", definitionID),
+			zap.Uint("instanceId", instance.ID))
+		if err := FinishWorkflowInstance(m.db, instance, runtimeWf, execErr); err != nil {
+			return nil, err
+		}
+		return instance, nil
+	}
 
 	if execErr != nil {
-		instance.Status = "failed"
-		instance.ResultData = models.JSONMap{
-			"error": execErr.Error(),
-		}
 		logger.Error("Workflow execution failed",
 			zap.Uint("definitionId", definitionID),
 			zap.String("triggerSource", triggerSource),
 			zap.Error(execErr))
 	} else {
-		instance.Status = "completed"
-		if runtimeWf.Context != nil {
-			instance.ContextData = runtimeWf.Context.NodeData
-			instance.ResultData = models.JSONMap{
-				"success": true,
-				"context": runtimeWf.Context.NodeData,
-			}
-		}
 		logger.Info("Workflow executed successfully",
 			zap.Uint("definitionId", definitionID),
 			zap.String("triggerSource", triggerSource))
 	}
 
-	if err := m.db.Save(&instance).Error; err != nil {
-		return nil, fmt.Errorf("failed to update workflow instance: %w", err)
+	// 更新实例状态
+	if err := FinishWorkflowInstance(m.db, instance, runtimeWf, execErr); err != nil {
+		return nil, err
 	}
 
-	return &instance, execErr
+	return instance, execErr
 }
 
 // GetActiveWorkflowsByEvent 根据事件类型获取需要触发的工作流
@@ -251,7 +256,7 @@ func (m *WorkflowTriggerManager) GetActiveWorkflowsByEvent(eventType string) ([]
 	}
 
 	var result []models.WorkflowDefinition
-	for _, wf := range workflows {
+	for _, wf := range m.publishedDefinitions(workflows) {
 		config, err := ParseTriggerConfig(&wf)
 		if err != nil {
 			logger.Warn("Failed to parse trigger config",
@@ -277,7 +282,7 @@ func (m *WorkflowTriggerManager) GetScheduledWorkflows() ([]models.WorkflowDefin
 	}
 
 	var result []models.WorkflowDefinition
-	for _, wf := range workflows {
+	for _, wf := range m.publishedDefinitions(workflows) {
 		config, err := ParseTriggerConfig(&wf)
 		if err != nil {
 			continue
@@ -290,3 +295,20 @@ func (m *WorkflowTriggerManager) GetScheduledWorkflows() ([]models.WorkflowDefin
 
 	return result, nil
 }
+
+// publishedDefinitions 将定义替换为其已发布版本，触发器配置以发布时为准
+func (m *WorkflowTriggerManager) publishedDefinitions(workflows []models.WorkflowDefinition) []models.WorkflowDefinition {
+	result := make([]models.WorkflowDefinition, 0, len(workflows))
+	for i := range workflows {
+		published, err := ResolvePublishedDefinition(m.db, &workflows[i])
+		if err != nil {
+			logger.Warn("Failed to load published workflow version",
+				zap.Uint("workflowId", workflows[i].ID),
+				zap.Uint("publishedVersion", workflows[i].PublishedVersion),
+				zap.Error(err))
+			continue
+		}
+		result = append(result, *published)
+	}
+	return result
+}
@@ -0,0 +1,145 @@
+package workflowdef
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrVersionNotFound 指定版本的快照不存在
+var ErrVersionNotFound = errors.New("workflow version not found")
+
+// SaveVersionSnapshot 将定义当前内容保存为版本快照。
+// 同一版本号只保留一份快照，已存在时直接返回（发布与编辑都会调用）。
+func SaveVersionSnapshot(db *gorm.DB, def *models.WorkflowDefinition, changeNote string) (*models.WorkflowVersion, error) {
+	var existing models.WorkflowVersion
+	err := db.Where("definition_id = ? AND version = ?", def.ID, def.Version).Order("id DESC").First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	snapshot := models.WorkflowVersion{
+		DefinitionID: def.ID,
+		Version:      def.Version,
+		Name:         def.Name,
+		Slug:         def.Slug,
+		Description:  def.Description,
+		Status:       def.Status,
+		Definition:   def.Definition,
+		Settings:     def.Settings,
+		Triggers:     def.Triggers,
+		Tags:         def.Tags,
+		CreatedBy:    def.CreatedBy,
+		UpdatedBy:    def.UpdatedBy,
+		ChangeNote:   changeNote,
+	}
+	if err := db.Create(&snapshot).Error; err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// PublishWorkflowDefinition 将当前草稿发布为线上版本。
+// 定时任务、事件监听和触发接口只执行已发布版本，之后对草稿的编辑不会影响线上。
+func PublishWorkflowDefinition(db *gorm.DB, def *models.WorkflowDefinition, publishedBy, changeNote string) (*models.WorkflowVersion, error) {
+	if err := ValidateRuntimeDefinition(def); err != nil {
+		return nil, err
+	}
+
+	var snapshot *models.WorkflowVersion
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if snapshot, err = SaveVersionSnapshot(tx, def, changeNote); err != nil {
+			return fmt.Errorf("failed to save version snapshot: %w", err)
+		}
+
+		now := time.Now()
+		snapshot.PublishedAt = &now
+		snapshot.PublishedBy = publishedBy
+		if changeNote != "" {
+			snapshot.ChangeNote = changeNote
+		}
+		if err := tx.Save(snapshot).Error; err != nil {
+			return err
+		}
+
+		status := def.Status
+		if status == "" || status == "draft" {
+			status = "active"
+		}
+		result := tx.Model(&models.WorkflowDefinition{}).
+			Where("id = ? AND version = ?", def.ID, def.Version).
+			Updates(map[string]interface{}{
+				"published_version": def.Version,
+				"published_at":      now,
+				"status":            status,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("version conflict: workflow definition was updated by others")
+		}
+		def.PublishedVersion = def.Version
+		def.PublishedAt = &now
+		def.Status = status
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// ValidateRuntimeDefinition 检查定义能否构建为运行时工作流
+func ValidateRuntimeDefinition(def *models.WorkflowDefinition) error {
+	if _, err := BuildRuntimeWorkflow(def); err != nil {
+		return fmt.Errorf("workflow cannot be built: %w", err)
+	}
+	return nil
+}
+
+// LoadDefinitionVersion 返回指定版本的定义内容。
+// version 为 0 或等于当前版本时返回草稿本身，否则使用历史快照覆盖图、配置和触发器。
+func LoadDefinitionVersion(db *gorm.DB, def *models.WorkflowDefinition, version uint) (*models.WorkflowDefinition, error) {
+	if version == 0 || version == def.Version {
+		return def, nil
+	}
+
+	var snapshot models.WorkflowVersion
+	if err := db.Where("definition_id = ? AND version = ?", def.ID, version).Order("id DESC").First(&snapshot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: definition %d version %d", ErrVersionNotFound, def.ID, version)
+		}
+		return nil, err
+	}
+
+	resolved := *def
+	resolved.Version = snapshot.Version
+	resolved.Name = snapshot.Name
+	resolved.Definition = snapshot.Definition
+	resolved.Settings = snapshot.Settings
+	resolved.Triggers = snapshot.Triggers
+	return &resolved, nil
+}
+
+// ResolvePublishedDefinition 返回线上应执行的定义。
+// 从未发布过的工作流沿用草稿内容，保持与版本化之前的行为一致。
+func ResolvePublishedDefinition(db *gorm.DB, def *models.WorkflowDefinition) (*models.WorkflowDefinition, error) {
+	return LoadDefinitionVersion(db, def, def.PublishedVersion)
+}
+
+// loadInstanceDefinition 加载实例启动时所用的定义版本，保证恢复和重试不受之后编辑的影响
+func loadInstanceDefinition(db *gorm.DB, instance *models.WorkflowInstance) (*models.WorkflowDefinition, error) {
+	var def models.WorkflowDefinition
+	if err := db.First(&def, instance.DefinitionID).Error; err != nil {
+		return nil, fmt.Errorf("workflow definition not found: %w", err)
+	}
+	return LoadDefinitionVersion(db, &def, instance.DefinitionVersion)
+}
@@ -131,7 +131,7 @@ func (e *DecisionEngine) registerDefaultStrategies() {
 // Decide 决策：选择要使用的Agents
 func (e *DecisionEngine) Decide(ctx context.Context, request *TaskRequest) ([]Agent, error) {
 	// 1. 获取候选Agents
-	candidates := e.getCandidates(request)
+	candidates := e.getCandidates(ctx, request)
 	if len(candidates) == 0 {
 		return nil, fmt.Errorf("no agents available for task type: %s", request.Type)
 	}
@@ -170,7 +170,7 @@ func (e *DecisionEngine) Decide(ctx context.Context, request *TaskRequest) ([]Ag
 }
 
 // getCandidates 获取候选Agents
-func (e *DecisionEngine) getCandidates(request *TaskRequest) []Agent {
+func (e *DecisionEngine) getCandidates(ctx context.Context, request *TaskRequest) []Agent {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
@@ -191,9 +191,12 @@ func (e *DecisionEngine) getCandidates(request *TaskRequest) []Agent {
 		}
 	}
 
-	// 过滤掉不健康的Agent
+	// 过滤掉不健康及调用方无权使用的Agent
 	healthyCandidates := make([]Agent, 0)
 	for _, agent := range candidates {
+		if !agentAllowed(ctx, agent.ID()) {
+			continue
+		}
 		status, err := e.registry.GetStatus(agent.ID())
 		if err == nil && status.Health == HealthHealthy {
 			healthyCandidates = append(healthyCandidates, agent)
@@ -382,3 +385,16 @@ func (e *DecisionEngine) AddStrategy(strategy DecisionStrategy) {
 	defer e.mu.Unlock()
 	e.strategies = append(e.strategies, strategy)
 }
+
+type agentFilterContextKey struct{}
+
+// WithAgentFilter 限制该上下文中的任务只能分派给 allow 返回 true 的agents（如按MCP凭证的调用范围）
+func WithAgentFilter(ctx context.Context, allow func(agentID string) bool) context.Context {
+	return context.WithValue(ctx, agentFilterContextKey{}, allow)
+}
+
+// agentAllowed 上下文未设置过滤时允许所有agents
+func agentAllowed(ctx context.Context, agentID string) bool {
+	allow, ok := ctx.Value(agentFilterContextKey{}).(func(string) bool)
+	return !ok || allow == nil || allow(agentID)
+}
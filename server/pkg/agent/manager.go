@@ -97,6 +97,13 @@ func (m *Manager) registerDefaultAgents(cfg *Config) error {
 		m.logger.Info("LLM agent registered")
 	}
 
+	// 注册Planner Agent，分解请求并分派给以上agents
+	plannerAgent := NewPlannerAgent(m.registry, cfg.LLMProvider, m.logger)
+	if err := m.registry.Register(plannerAgent); err != nil {
+		return err
+	}
+	m.logger.Info("Planner agent registered")
+
 	return nil
 }
 
@@ -114,6 +121,16 @@ func (m *Manager) Process(ctx context.Context, request *TaskRequest) (*TaskRespo
 	return m.orchestrator.Process(ctx, request)
 }
 
+// Run 由Planner Agent分解请求，并行分派子任务给其他agents并合并结果
+func (m *Manager) Run(ctx context.Context, request *TaskRequest) (*TaskResponse, error) {
+	if !m.initialized {
+		return nil, ErrNotInitialized
+	}
+
+	request.Type = TaskTypePlan
+	return m.orchestrator.Process(ctx, request)
+}
+
 // ProcessWorkflow 处理工作流
 func (m *Manager) ProcessWorkflow(ctx context.Context, workflow *Workflow, request *TaskRequest) (*TaskResponse, error) {
 	if !m.initialized {
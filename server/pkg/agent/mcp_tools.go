@@ -78,10 +78,10 @@ func RegisterAgentTools(mcpServer *lingechoMCP.MCPServer, manager *Manager, logg
 	)
 
 	// 3. 处理任务
-	mcpServer.RegisterTool(
+	mcpServer.RegisterContextTool(
 		"process_task",
 		"通过agent系统处理任务",
-		func(arguments map[string]any) (*mcp.CallToolResult, error) {
+		func(ctx context.Context, arguments map[string]any) (*mcp.CallToolResult, error) {
 			taskType, err := lingechoMCP.SafeGetString(arguments, "type", true)
 			if err != nil {
 				return lingechoMCP.ErrorResponse(400, err.Error()), nil
@@ -122,7 +122,7 @@ func RegisterAgentTools(mcpServer *lingechoMCP.MCPServer, manager *Manager, logg
 			}
 
 			// 处理任务
-			response, err := manager.Process(context.Background(), request)
+			response, err := manager.Process(callerContext(ctx), request)
 			if err != nil {
 				return lingechoMCP.ErrorResponse(500, fmt.Sprintf("Task processing failed: %v", err)), nil
 			}
@@ -157,7 +157,72 @@ func RegisterAgentTools(mcpServer *lingechoMCP.MCPServer, manager *Manager, logg
 		),
 	)
 
-	// 4. 健康检查
+	// 4. 规划并执行任务
+	mcpServer.RegisterContextTool(
+		"run_agents",
+		"由规划agent分解请求，并行分派子任务给RAG、图记忆、LLM等agents并合并结果",
+		func(ctx context.Context, arguments map[string]any) (*mcp.CallToolResult, error) {
+			content, err := lingechoMCP.SafeGetString(arguments, "content", true)
+			if err != nil {
+				return lingechoMCP.ErrorResponse(400, err.Error()), nil
+			}
+
+			// 解析上下文（可选）
+			var taskContext *TaskContext
+			if contextStr, ok := arguments["context"].(string); ok && contextStr != "" {
+				if err := json.Unmarshal([]byte(contextStr), &taskContext); err != nil {
+					logger.Warn("Failed to parse context, using nil", zap.Error(err))
+				}
+			}
+
+			// 解析参数（可选）
+			parameters := make(map[string]interface{})
+			if paramsStr, ok := arguments["parameters"].(string); ok && paramsStr != "" {
+				if err := json.Unmarshal([]byte(paramsStr), &parameters); err != nil {
+					logger.Warn("Failed to parse parameters, using empty map", zap.Error(err))
+					parameters = make(map[string]interface{})
+				}
+			}
+
+			request := &TaskRequest{
+				ID:         fmt.Sprintf("mcp_run_%d", time.Now().UnixNano()),
+				Content:    content,
+				Context:    taskContext,
+				Parameters: parameters,
+				CreatedAt:  time.Now(),
+			}
+
+			response, err := manager.Run(callerContext(ctx), request)
+			if err != nil {
+				return lingechoMCP.ErrorResponse(500, fmt.Sprintf("Task processing failed: %v", err)), nil
+			}
+
+			return lingechoMCP.SuccessResponse(map[string]interface{}{
+				"taskId":         response.ID,
+				"success":        response.Success,
+				"content":        response.Content,
+				"data":           response.Data,
+				"agentId":        response.AgentID,
+				"processingTime": response.ProcessingTime.Milliseconds(),
+				"error":          response.Error,
+			}), nil
+		},
+		mcp.WithString(
+			"content",
+			mcp.Description("请求内容"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"context",
+			mcp.Description("任务上下文（JSON字符串），如 knowledgeBaseId、graphMemoryEnabled、userId、assistantId"),
+		),
+		mcp.WithString(
+			"parameters",
+			mcp.Description("任务参数（JSON字符串），如 model"),
+		),
+	)
+
+	// 5. 健康检查
 	mcpServer.RegisterTool(
 		"agent_health_check",
 		"检查所有agents的健康状态",
@@ -188,3 +253,11 @@ func RegisterAgentTools(mcpServer *lingechoMCP.MCPServer, manager *Manager, logg
 
 	logger.Info("Agent tools registered in MCP server")
 }
+
+// callerContext 按MCP调用方凭证的调用范围限制可分派任务的agents
+func callerContext(ctx context.Context) context.Context {
+	if caller := lingechoMCP.CallerFromContext(ctx); caller != nil && caller.Scopes != nil {
+		return WithAgentFilter(ctx, caller.Scopes.AllowAgent)
+	}
+	return ctx
+}
@@ -362,6 +362,9 @@ func (o *Orchestrator) processStep(ctx context.Context, step WorkflowStep, reque
 	}
 
 	// 获取agent
+	if !agentAllowed(ctx, step.AgentID) {
+		return nil, fmt.Errorf("agent not allowed: %s", step.AgentID)
+	}
 	agent, err := o.registry.Get(step.AgentID)
 	if err != nil {
 		return nil, fmt.Errorf("agent not found: %s", step.AgentID)
@@ -0,0 +1,517 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/llm"
+	"go.uber.org/zap"
+)
+
+const (
+	// PlannerAgentID 规划Agent的ID
+	PlannerAgentID = "planner_agent"
+
+	// defaultMaxPlanSteps 一次规划的最大子任务数
+	defaultMaxPlanSteps = 5
+)
+
+// Plan 规划结果：请求被分解成的子任务
+type Plan struct {
+	// Steps 子任务，依赖只能指向排在前面的子任务
+	Steps []PlanStep `json:"steps"`
+
+	// Planner 规划方式（llm 或 rule）
+	Planner string `json:"planner"`
+}
+
+// PlanStep 子任务
+type PlanStep struct {
+	// ID 子任务ID
+	ID string `json:"id"`
+
+	// AgentID 执行该子任务的agent ID
+	AgentID string `json:"agentId"`
+
+	// Content 子任务内容
+	Content string `json:"content"`
+
+	// DependsOn 依赖的子任务ID，依赖的结果会作为参考信息附加到子任务内容中
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// StepResult 子任务执行结果
+type StepResult struct {
+	Step     PlanStep      `json:"step"`
+	Response *TaskResponse `json:"response"`
+}
+
+// PlannerAgent 规划Agent：将请求分解为子任务，按依赖分批并行分派给RAG、图记忆、LLM等agent执行，
+// 再合并各子任务的结果
+type PlannerAgent struct {
+	id          string
+	name        string
+	description string
+	registry    *Registry
+	llmProvider llm.LLMProvider // 用于规划与合并，为 nil 时按规则规划并拼接结果
+	logger      *zap.Logger
+	maxSteps    int
+}
+
+// NewPlannerAgent 创建新的规划Agent
+func NewPlannerAgent(registry *Registry, llmProvider llm.LLMProvider, logger *zap.Logger) *PlannerAgent {
+	return &PlannerAgent{
+		id:          PlannerAgentID,
+		name:        "Planner Agent",
+		description: "规划Agent，负责分解请求、分派子任务给其他agents并合并结果",
+		registry:    registry,
+		llmProvider: llmProvider,
+		logger:      logger,
+		maxSteps:    defaultMaxPlanSteps,
+	}
+}
+
+// ID 返回agent ID
+func (a *PlannerAgent) ID() string {
+	return a.id
+}
+
+// Name 返回agent名称
+func (a *PlannerAgent) Name() string {
+	return a.name
+}
+
+// Description 返回agent描述
+func (a *PlannerAgent) Description() string {
+	return a.description
+}
+
+// Capabilities 返回agent能力
+func (a *PlannerAgent) Capabilities() []Capability {
+	return []Capability{
+		{
+			Name:        "task_planning",
+			Description: "将复杂请求分解为子任务并分派给其他agents",
+			Type:        TaskTypePlan,
+			Parameters: map[string]interface{}{
+				"maxSteps": a.maxSteps,
+			},
+		},
+	}
+}
+
+// CanHandle 判断是否能处理任务
+func (a *PlannerAgent) CanHandle(request *TaskRequest) bool {
+	return request.Type == TaskTypePlan
+}
+
+// Process 处理任务：规划、执行、合并
+func (a *PlannerAgent) Process(ctx context.Context, request *TaskRequest) (*TaskResponse, error) {
+	startTime := time.Now()
+
+	workers := a.workers(ctx)
+	plan, err := a.Plan(ctx, request, workers)
+	if err != nil {
+		return &TaskResponse{
+			ID:        request.ID,
+			Success:   false,
+			Error:     fmt.Sprintf("Planning failed: %v", err),
+			CreatedAt: time.Now(),
+		}, nil
+	}
+
+	results := a.Execute(ctx, request, plan)
+	content, success := a.Merge(ctx, request, plan, results)
+
+	a.logger.Info("Plan completed",
+		zap.String("taskID", request.ID),
+		zap.String("planner", plan.Planner),
+		zap.Int("steps", len(plan.Steps)),
+		zap.Bool("success", success),
+		zap.Duration("processingTime", time.Since(startTime)),
+	)
+
+	response := &TaskResponse{
+		ID:      request.ID,
+		Success: success,
+		Content: content,
+		Data: map[string]interface{}{
+			"plan":    plan,
+			"results": results,
+		},
+		AgentID:        a.id,
+		ProcessingTime: time.Since(startTime),
+		CreatedAt:      time.Now(),
+	}
+	if !success {
+		response.Error = "All plan steps failed"
+	}
+	return response, nil
+}
+
+// Health 健康检查
+func (a *PlannerAgent) Health(ctx context.Context) error {
+	if a.registry == nil {
+		return fmt.Errorf("agent registry is nil")
+	}
+	return nil
+}
+
+// workers 可被分派子任务的健康agents（不含规划Agent自身及调用方无权使用的agents）
+func (a *PlannerAgent) workers(ctx context.Context) map[string]Agent {
+	workers := make(map[string]Agent)
+	for _, agent := range a.registry.List() {
+		if agent.ID() == a.id || !agentAllowed(ctx, agent.ID()) {
+			continue
+		}
+		if status, err := a.registry.GetStatus(agent.ID()); err == nil && status.Health != HealthHealthy {
+			continue
+		}
+		workers[agent.ID()] = agent
+	}
+	return workers
+}
+
+// Plan 分解请求：有LLM时由LLM规划，失败或无LLM时按规则规划
+func (a *PlannerAgent) Plan(ctx context.Context, request *TaskRequest, workers map[string]Agent) (*Plan, error) {
+	if len(workers) == 0 {
+		return nil, fmt.Errorf("no agents available")
+	}
+	if a.llmProvider != nil {
+		plan, err := a.llmPlan(request, workers)
+		if err == nil {
+			return plan, nil
+		}
+		a.logger.Warn("LLM planning failed, falling back to rule planning",
+			zap.String("taskID", request.ID),
+			zap.Error(err),
+		)
+	}
+	return a.rulePlan(request, workers)
+}
+
+// rulePlan 按规则规划：图记忆与知识库检索并行执行，LLM 依赖两者的结果生成回答
+func (a *PlannerAgent) rulePlan(request *TaskRequest, workers map[string]Agent) (*Plan, error) {
+	plan := &Plan{Planner: "rule"}
+	var retrievals []string
+	if request.Context != nil && request.Context.GraphMemoryEnabled {
+		if _, ok := workers["graph_memory_agent"]; ok {
+			plan.Steps = append(plan.Steps, PlanStep{ID: "graph", AgentID: "graph_memory_agent", Content: request.Content})
+			retrievals = append(retrievals, "graph")
+		}
+	}
+	if request.Context != nil && request.Context.KnowledgeBaseID != "" {
+		if _, ok := workers["rag_agent"]; ok {
+			plan.Steps = append(plan.Steps, PlanStep{ID: "rag", AgentID: "rag_agent", Content: request.Content})
+			retrievals = append(retrievals, "rag")
+		}
+	}
+	if _, ok := workers["llm_agent"]; ok {
+		plan.Steps = append(plan.Steps, PlanStep{ID: "answer", AgentID: "llm_agent", Content: request.Content, DependsOn: retrievals})
+	}
+	if len(plan.Steps) == 0 {
+		return nil, fmt.Errorf("no agents can handle the request")
+	}
+	return plan, nil
+}
+
+// llmPlan 由LLM根据可用agents分解请求
+func (a *PlannerAgent) llmPlan(request *TaskRequest, workers map[string]Agent) (*Plan, error) {
+	response, err := a.query(request, buildPlanPrompt(request, workers, a.maxSteps))
+	if err != nil {
+		return nil, err
+	}
+
+	var plan Plan
+	if err := json.Unmarshal([]byte(extractJSON(response)), &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	plan.Planner = "llm"
+	if err := validatePlan(&plan, workers, a.maxSteps); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// buildPlanPrompt 构建规划 prompt
+func buildPlanPrompt(request *TaskRequest, workers map[string]Agent, maxSteps int) string {
+	var agents strings.Builder
+	for id, agent := range workers {
+		capabilities := make([]string, 0, len(agent.Capabilities()))
+		for _, capability := range agent.Capabilities() {
+			capabilities = append(capabilities, capability.Description)
+		}
+		agents.WriteString(fmt.Sprintf("- %s: %s（能力: %s）\n", id, agent.Description(), strings.Join(capabilities, "、")))
+	}
+
+	var hints []string
+	if request.Context != nil {
+		if request.Context.KnowledgeBaseID != "" {
+			hints = append(hints, "已配置知识库，rag_agent 可检索")
+		}
+		if request.Context.GraphMemoryEnabled {
+			hints = append(hints, "已启用图记忆，graph_memory_agent 可获取用户偏好")
+		}
+	}
+	if len(hints) == 0 {
+		hints = append(hints, "无")
+	}
+
+	return fmt.Sprintf(`你是一个任务规划助手。请将用户请求分解为若干子任务，并为每个子任务选择最合适的 agent。
+
+可用的 agents：
+%s
+上下文：%s
+
+用户请求：
+%s
+
+请按照以下 JSON 格式返回规划结果：
+{
+  "steps": [
+    {"id": "s1", "agentId": "agent ID", "content": "子任务内容", "dependsOn": []}
+  ]
+}
+
+要求：
+1. 最多 %d 个子任务，简单请求只需要 1 个子任务
+2. agentId 必须是上面列出的 agent
+3. 互不依赖的子任务会并行执行；需要其他子任务结果的子任务在 dependsOn 中列出它们的 id，且只能依赖排在前面的子任务
+4. 只返回 JSON，不要包含其他文字说明`, agents.String(), strings.Join(hints, "；"), request.Content, maxSteps)
+}
+
+// validatePlan 校验并规范化LLM返回的规划
+func validatePlan(plan *Plan, workers map[string]Agent, maxSteps int) error {
+	if len(plan.Steps) == 0 {
+		return fmt.Errorf("plan has no steps")
+	}
+	if len(plan.Steps) > maxSteps {
+		plan.Steps = plan.Steps[:maxSteps]
+	}
+	seen := make(map[string]bool, len(plan.Steps))
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		if _, ok := workers[step.AgentID]; !ok {
+			return fmt.Errorf("step %d uses unknown agent %q", i+1, step.AgentID)
+		}
+		if strings.TrimSpace(step.Content) == "" {
+			return fmt.Errorf("step %d has no content", i+1)
+		}
+		if step.ID == "" || seen[step.ID] {
+			step.ID = fmt.Sprintf("s%d", i+1)
+		}
+		// 只保留对前面子任务的依赖，避免循环
+		deps := step.DependsOn[:0]
+		for _, dep := range step.DependsOn {
+			if seen[dep] {
+				deps = append(deps, dep)
+			}
+		}
+		step.DependsOn = deps
+		seen[step.ID] = true
+	}
+	return nil
+}
+
+// Execute 按依赖分批执行子任务，同一批内并行执行
+func (a *PlannerAgent) Execute(ctx context.Context, request *TaskRequest, plan *Plan) []StepResult {
+	results := make([]StepResult, len(plan.Steps))
+	done := make(map[string]*TaskResponse, len(plan.Steps))
+
+	for len(done) < len(plan.Steps) {
+		var batch []int
+		for i, step := range plan.Steps {
+			if _, ok := done[step.ID]; ok {
+				continue
+			}
+			ready := true
+			for _, dep := range step.DependsOn {
+				if _, ok := done[dep]; !ok {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				batch = append(batch, i)
+			}
+		}
+		if len(batch) == 0 {
+			// 依赖无法满足（规则规划与校验后的规划不会出现）
+			for i, step := range plan.Steps {
+				if _, ok := done[step.ID]; !ok {
+					results[i] = StepResult{Step: step, Response: a.stepError(request, step, "unresolved dependencies")}
+					done[step.ID] = results[i].Response
+				}
+			}
+			break
+		}
+
+		var wg sync.WaitGroup
+		for _, i := range batch {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				step := plan.Steps[i]
+				results[i] = StepResult{Step: step, Response: a.runStep(ctx, request, step, done)}
+			}(i)
+		}
+		wg.Wait()
+		for _, i := range batch {
+			done[plan.Steps[i].ID] = results[i].Response
+		}
+	}
+	return results
+}
+
+// runStep 执行单个子任务，依赖的成功结果作为参考信息附加到子任务内容中
+func (a *PlannerAgent) runStep(ctx context.Context, request *TaskRequest, step PlanStep, done map[string]*TaskResponse) *TaskResponse {
+	agent, err := a.registry.Get(step.AgentID)
+	if err != nil {
+		return a.stepError(request, step, err.Error())
+	}
+
+	content := step.Content
+	var references []string
+	for _, dep := range step.DependsOn {
+		if response := done[dep]; response != nil && response.Success && response.Content != "" {
+			references = append(references, response.Content)
+		}
+	}
+	if len(references) > 0 {
+		content = fmt.Sprintf("%s\n\n参考信息：\n%s", content, strings.Join(references, "\n\n"))
+	}
+
+	taskType := TaskTypeGeneral
+	if capabilities := agent.Capabilities(); len(capabilities) > 0 {
+		taskType = capabilities[0].Type
+	}
+	parameters := mergeMaps(request.Parameters, nil)
+	delete(parameters, "strategy")
+
+	stepRequest := &TaskRequest{
+		ID:         fmt.Sprintf("%s_%s", request.ID, step.ID),
+		Type:       taskType,
+		Content:    content,
+		Context:    request.Context,
+		Parameters: parameters,
+		Metadata: mergeMaps(request.Metadata, map[string]interface{}{
+			"planTaskID": request.ID,
+			"stepID":     step.ID,
+		}),
+		CreatedAt: time.Now(),
+	}
+
+	startTime := time.Now()
+	response, err := agent.Process(ctx, stepRequest)
+	if err != nil {
+		return a.stepError(request, step, err.Error())
+	}
+	response.AgentID = agent.ID()
+	response.ProcessingTime = time.Since(startTime)
+
+	a.logger.Debug("Plan step completed",
+		zap.String("taskID", request.ID),
+		zap.String("stepID", step.ID),
+		zap.String("agentID", agent.ID()),
+		zap.Bool("success", response.Success),
+	)
+	return response
+}
+
+func (a *PlannerAgent) stepError(request *TaskRequest, step PlanStep, errorMsg string) *TaskResponse {
+	return &TaskResponse{
+		ID:        fmt.Sprintf("%s_%s", request.ID, step.ID),
+		Success:   false,
+		Error:     errorMsg,
+		AgentID:   step.AgentID,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Merge 合并子任务结果。只有一个最终子任务（没有其他子任务依赖它）且成功时直接使用其结果；
+// 否则有LLM时由LLM综合各最终子任务的结果，无LLM时拼接。返回合并内容与是否有子任务成功
+func (a *PlannerAgent) Merge(ctx context.Context, request *TaskRequest, plan *Plan, results []StepResult) (string, bool) {
+	dependedOn := make(map[string]bool)
+	for _, step := range plan.Steps {
+		for _, dep := range step.DependsOn {
+			dependedOn[dep] = true
+		}
+	}
+
+	success := false
+	var finals []StepResult
+	for _, result := range results {
+		if !result.Response.Success {
+			continue
+		}
+		success = true
+		if !dependedOn[result.Step.ID] {
+			finals = append(finals, result)
+		}
+	}
+	if len(finals) == 0 {
+		return "", success
+	}
+	if len(finals) == 1 {
+		return finals[0].Response.Content, true
+	}
+
+	var sections strings.Builder
+	for i, result := range finals {
+		if i > 0 {
+			sections.WriteString("\n\n")
+		}
+		sections.WriteString(fmt.Sprintf("[%s（%s）]\n%s", result.Step.Content, result.Step.AgentID, result.Response.Content))
+	}
+	if a.llmProvider == nil {
+		return sections.String(), true
+	}
+
+	prompt := fmt.Sprintf(`请综合以下子任务的结果，直接回答用户的原始请求，回答要自然流畅，不要提及子任务。
+
+用户请求：
+%s
+
+子任务结果：
+%s`, request.Content, sections.String())
+	merged, err := a.query(request, prompt)
+	if err != nil {
+		a.logger.Warn("LLM merge failed, concatenating results",
+			zap.String("taskID", request.ID),
+			zap.Error(err),
+		)
+		return sections.String(), true
+	}
+	return merged, true
+}
+
+// query 调用LLM，规划与合并的提示不保留在对话历史中
+func (a *PlannerAgent) query(request *TaskRequest, prompt string) (string, error) {
+	a.llmProvider.ResetMessages()
+	defer a.llmProvider.ResetMessages()
+
+	model := "gpt-4o"
+	if modelVal, ok := request.Parameters["model"].(string); ok && modelVal != "" {
+		model = modelVal
+	}
+	temperature := float32(0.2)
+	return a.llmProvider.QueryWithOptions(prompt, llm.QueryOptions{
+		Model:       model,
+		Temperature: &temperature,
+	})
+}
+
+// extractJSON 去除LLM返回内容中的 markdown 代码块，提取 JSON 对象
+func extractJSON(response string) string {
+	response = strings.TrimSpace(response)
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start >= 0 && end > start {
+		return response[start : end+1]
+	}
+	return response
+}
@@ -269,6 +269,7 @@ const (
 	TaskTypeLLM         = "llm"
 	TaskTypeWorkflow    = "workflow"
 	TaskTypeGeneral     = "general"
+	TaskTypePlan        = "plan"
 
 	// 工作流步骤类型
 	StepTypeSequential  = "sequential"
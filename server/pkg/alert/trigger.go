@@ -2,6 +2,7 @@ package alert
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -254,6 +255,9 @@ func (s *TriggerService) sendNotifications(alert *models.Alert, rule *models.Ale
 		}
 	}
 
+	// 用户配置的外部渠道（Slack、钉钉、飞书、Webhook）
+	s.sendUserChannelNotifications(alert, user)
+
 	// 更新告警的已通知状态
 	now := time.Now()
 	alert.Notified = true
@@ -357,6 +361,51 @@ func (s *TriggerService) sendWebhookNotification(alert *models.Alert, rule *mode
 	return nil
 }
 
+// sendUserChannelNotifications 发送到用户订阅了该告警事件的外部渠道，并记录发送结果
+func (s *TriggerService) sendUserChannelNotifications(alert *models.Alert, user *models.User) {
+	event := notification.EventSystemAlert
+	if alert.AlertType == models.AlertTypeQuotaExceeded {
+		event = notification.EventQuotaAlert
+	}
+	level := notification.LevelWarning
+	if alert.Severity == models.AlertSeverityCritical {
+		level = notification.LevelError
+	}
+	msg := notification.Message{
+		Event:   event,
+		Level:   level,
+		Title:   fmt.Sprintf("[告警] %s", alert.Title),
+		Content: alert.Message,
+		Data: map[string]interface{}{
+			"alertId":   alert.ID,
+			"alertType": alert.AlertType,
+			"severity":  alert.Severity,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, delivery := range notification.NewChannelService(s.db).Notify(ctx, user.ID, msg) {
+		now := time.Now()
+		record := models.AlertNotification{
+			AlertID: alert.ID,
+			Channel: models.NotificationChannel(delivery.Type),
+			Status:  "success",
+			SentAt:  &now,
+		}
+		if delivery.Error != "" {
+			record.Status = "failed"
+			record.Message = delivery.Error
+			logger.Error("发送告警通知失败",
+				zap.String("channel", delivery.Type),
+				zap.Uint("channelId", delivery.ChannelID),
+				zap.String("error", delivery.Error),
+				zap.Uint("alertId", alert.ID))
+		}
+		s.db.Create(&record)
+	}
+}
+
 // getUser 获取用户信息
 func (s *TriggerService) getUser(userID uint) (*models.User, error) {
 	var user models.User
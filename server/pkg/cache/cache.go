@@ -5,7 +5,8 @@ import (
 	"time"
 )
 
-// Cache 缓存接口
+// Cache 缓存接口，本地与 Redis 实现语义一致：expiration 为 0 表示不过期。
+// Redis 中的值以 JSON 保存，读取时数字为 float64、结构体为 map
 type Cache interface {
 	// Get 获取缓存值
 	Get(ctx context.Context, key string) (interface{}, bool)
@@ -40,6 +41,12 @@ type Cache interface {
 	// GetWithTTL 获取值并返回剩余TTL
 	GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, bool)
 
+	// SetNX 键不存在时设置，返回是否设置成功，可用作跨实例的锁
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)
+
+	// GetDel 获取并删除，并发调用时只有一个能取到值，用于一次性的验证码等
+	GetDel(ctx context.Context, key string) (interface{}, bool)
+
 	// Close 关闭缓存连接
 	Close() error
 }
@@ -230,6 +230,21 @@ func (lc *layeredCache) GetWithTTL(ctx context.Context, key string) (interface{}
 	return nil, 0, false
 }
 
+// SetNX 以分布式缓存为准，成功后回填本地缓存
+func (lc *layeredCache) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	ok, err := lc.distributed.SetNX(ctx, key, value, expiration)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return true, lc.local.Set(ctx, key, value, lc.options.LocalExpiration)
+}
+
+// GetDel 从分布式缓存取走，本地缓存中的副本一并删除
+func (lc *layeredCache) GetDel(ctx context.Context, key string) (interface{}, bool) {
+	lc.local.Delete(ctx, key)
+	return lc.distributed.GetDel(ctx, key)
+}
+
 // Close 关闭缓存连接
 func (lc *layeredCache) Close() error {
 	// 关闭本地缓存
@@ -127,3 +127,13 @@ func SetMulti(ctx context.Context, data map[string]interface{}, expiration time.
 func DeleteMulti(ctx context.Context, keys ...string) error {
 	return GetGlobalCache().DeleteMulti(ctx, keys...)
 }
+
+// SetNX 键不存在时设置全局缓存值
+func SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return GetGlobalCache().SetNX(ctx, key, value, expiration)
+}
+
+// GetDel 从全局缓存获取并删除
+func GetDel(ctx context.Context, key string) (interface{}, bool) {
+	return GetGlobalCache().GetDel(ctx, key)
+}
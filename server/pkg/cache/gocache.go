@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	gocache "github.com/patrickmn/go-cache"
@@ -10,6 +11,7 @@ import (
 // goCacheWrapper go-cache包装器
 type goCacheWrapper struct {
 	cache *gocache.Cache
+	mu    sync.Mutex // 保证 GetDel 的读取与删除不被其他 GetDel 打断
 }
 
 // NewGoCache 创建基于go-cache的本地缓存
@@ -127,6 +129,23 @@ func (gc *goCacheWrapper) GetWithTTL(ctx context.Context, key string) (interface
 	return nil, 0, false
 }
 
+// SetNX 键不存在时设置，go-cache 的 Add 本身是原子的
+func (gc *goCacheWrapper) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return gc.cache.Add(key, value, expiration) == nil, nil
+}
+
+// GetDel 获取并删除
+func (gc *goCacheWrapper) GetDel(ctx context.Context, key string) (interface{}, bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	value, found := gc.cache.Get(key)
+	if found {
+		gc.cache.Delete(key)
+	}
+	return value, found
+}
+
 // Close 关闭缓存连接
 func (gc *goCacheWrapper) Close() error {
 	// go-cache不需要关闭连接
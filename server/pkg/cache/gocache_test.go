@@ -241,3 +241,21 @@ func TestFlush(t *testing.T) {
 	_, exists = c.Get(ctx, "key2")
 	assert.False(t, exists)
 }
+
+func TestGoCacheSetNXGetDel(t *testing.T) {
+	c := newTestGoCache()
+	ctx := context.Background()
+
+	ok, err := c.SetNX(ctx, "lock", "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = c.SetNX(ctx, "lock", "b", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	val, exists := c.GetDel(ctx, "lock")
+	assert.True(t, exists)
+	assert.Equal(t, "a", val)
+	_, exists = c.GetDel(ctx, "lock")
+	assert.False(t, exists)
+}
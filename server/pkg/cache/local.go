@@ -47,8 +47,8 @@ func NewLocalCache(config LocalConfig) Cache {
 
 // Get 获取缓存值
 func (lc *localCache) Get(ctx context.Context, key string) (interface{}, bool) {
-	lc.mu.RLock()
-	defer lc.mu.RUnlock()
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
 
 	item, exists := lc.cache.get(key)
 	if !exists {
@@ -97,8 +97,8 @@ func (lc *localCache) Delete(ctx context.Context, key string) error {
 
 // Exists 检查键是否存在
 func (lc *localCache) Exists(ctx context.Context, key string) bool {
-	lc.mu.RLock()
-	defer lc.mu.RUnlock()
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
 
 	item, exists := lc.cache.get(key)
 	if !exists {
@@ -215,8 +215,8 @@ func (lc *localCache) Decrement(ctx context.Context, key string, value int64) (i
 
 // GetWithTTL 获取值并返回剩余TTL
 func (lc *localCache) GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, bool) {
-	lc.mu.RLock()
-	defer lc.mu.RUnlock()
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
 
 	item, exists := lc.cache.get(key)
 	if !exists {
@@ -242,6 +242,38 @@ func (lc *localCache) GetWithTTL(ctx context.Context, key string) (interface{},
 	return item.value, ttl, true
 }
 
+// SetNX 键不存在或已过期时设置
+func (lc *localCache) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if item, exists := lc.cache.get(key); exists && (item.expiration.IsZero() || time.Now().Before(item.expiration)) {
+		return false, nil
+	}
+	var exp time.Time
+	if expiration > 0 {
+		exp = time.Now().Add(expiration)
+	}
+	lc.cache.set(key, &cacheItem{value: value, expiration: exp, lastAccess: time.Now()})
+	return true, nil
+}
+
+// GetDel 获取并删除
+func (lc *localCache) GetDel(ctx context.Context, key string) (interface{}, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	item, exists := lc.cache.get(key)
+	if !exists {
+		return nil, false
+	}
+	lc.cache.delete(key)
+	if !item.expiration.IsZero() && time.Now().After(item.expiration) {
+		return nil, false
+	}
+	return item.value, true
+}
+
 // Close 关闭缓存连接
 func (lc *localCache) Close() error {
 	// 本地缓存不需要关闭连接
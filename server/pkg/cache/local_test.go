@@ -2,6 +2,8 @@ package cache
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -232,3 +234,58 @@ func TestDeleteAfterExpiration(t *testing.T) {
 	_, ok := c.Get(ctx, "exp_key")
 	assert.False(t, ok)
 }
+
+func TestSetNX(t *testing.T) {
+	c := newTestCache(10, time.Minute, time.Hour)
+	ctx := context.Background()
+
+	ok, err := c.SetNX(ctx, "lock", 1, 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = c.SetNX(ctx, "lock", 2, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	v, _ := c.Get(ctx, "lock")
+	assert.Equal(t, 1, v)
+
+	// 过期后可以重新设置
+	time.Sleep(80 * time.Millisecond)
+	ok, err = c.SetNX(ctx, "lock", 3, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestGetDel(t *testing.T) {
+	c := newTestCache(10, time.Minute, time.Hour)
+	ctx := context.Background()
+
+	assert.NoError(t, c.Set(ctx, "code", "123456", time.Minute))
+	v, ok := c.GetDel(ctx, "code")
+	assert.True(t, ok)
+	assert.Equal(t, "123456", v)
+
+	_, ok = c.GetDel(ctx, "code")
+	assert.False(t, ok)
+	assert.False(t, c.Exists(ctx, "code"))
+}
+
+func TestGetDel_Concurrent(t *testing.T) {
+	c := newTestCache(10, time.Minute, time.Hour)
+	ctx := context.Background()
+	assert.NoError(t, c.Set(ctx, "code", "123456", time.Minute))
+
+	var hits int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := c.GetDel(ctx, "code"); ok {
+				atomic.AddInt32(&hits, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), hits)
+}
@@ -15,9 +15,9 @@ type redisCache struct {
 	config RedisConfig
 }
 
-// NewRedisCache 创建Redis缓存
-func NewRedisCache(config RedisConfig) (Cache, error) {
-	client := redis.NewClient(&redis.Options{
+// NewRedisClient 按缓存配置创建 Redis 客户端，供限流等需要直接访问 Redis 的组件复用同一配置
+func NewRedisClient(config RedisConfig) *redis.Client {
+	return redis.NewClient(&redis.Options{
 		Addr:         config.Addr,
 		Password:     config.Password,
 		DB:           config.DB,
@@ -28,6 +28,11 @@ func NewRedisCache(config RedisConfig) (Cache, error) {
 		WriteTimeout: config.WriteTimeout,
 		PoolTimeout:  config.IdleTimeout,
 	})
+}
+
+// NewRedisCache 创建Redis缓存
+func NewRedisCache(config RedisConfig) (Cache, error) {
+	client := NewRedisClient(config)
 
 	// 测试连接
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -180,6 +185,37 @@ func (rc *redisCache) GetWithTTL(ctx context.Context, key string) (interface{},
 	return value, ttl.Val(), true
 }
 
+// SetNX 键不存在时设置
+func (rc *redisCache) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	return rc.client.SetNX(ctx, key, data, expiration).Result()
+}
+
+// getDelScript 原子地读取并删除，兼容不支持 GETDEL 的 Redis 6.2 以下版本
+var getDelScript = redis.NewScript(`
+local value = redis.call("GET", KEYS[1])
+if value then
+	redis.call("DEL", KEYS[1])
+end
+return value`)
+
+// GetDel 获取并删除
+func (rc *redisCache) GetDel(ctx context.Context, key string) (interface{}, bool) {
+	result, err := getDelScript.Run(ctx, rc.client, []string{key}).Text()
+	if err != nil {
+		return nil, false
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(result), &value); err != nil {
+		return result, true
+	}
+	return value, true
+}
+
 // Close 关闭缓存连接
 func (rc *redisCache) Close() error {
 	return rc.client.Close()
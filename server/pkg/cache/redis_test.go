@@ -156,6 +156,26 @@ func TestRedisIncrementDecrement(t *testing.T) {
 	assert.Equal(t, int64(12), nv)
 }
 
+func TestRedisSetNXGetDel(t *testing.T) {
+	c := newTestRedisCache()
+	skipIfRedisNotAvailable(t, c)
+	ctx := context.Background()
+	c.Delete(ctx, "nx_key")
+
+	ok, err := c.SetNX(ctx, "nx_key", "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = c.SetNX(ctx, "nx_key", "b", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	val, exists := c.GetDel(ctx, "nx_key")
+	assert.True(t, exists)
+	assert.Equal(t, "a", val)
+	_, exists = c.GetDel(ctx, "nx_key")
+	assert.False(t, exists)
+}
+
 func TestRedisBatchOps(t *testing.T) {
 	c := newTestRedisCache()
 	skipIfRedisNotAvailable(t, c)
@@ -23,6 +23,9 @@ type Config struct {
 	DSN              string `env:"DSN"`
 	Log              logger.LogConfig
 	Mail             notification.MailConfig
+	Push             notification.PushConfig
+	WebPush          notification.WebPushConfig
+	SMS              notification.SMSConfig
 	Addr             string `env:"ADDR"`
 	Mode             string `env:"MODE"`
 	DocsPrefix       string `env:"DOCS_PREFIX"`
@@ -37,6 +40,8 @@ type Config struct {
 	SearchEnabled    bool   `env:"SEARCH_ENABLED"`
 	SearchPath       string `env:"SEARCH_PATH"`
 	SearchBatchSize  int    `env:"SEARCH_BATCH_SIZE"`
+	SearchEngine     string `env:"SEARCH_ENGINE"`   // bleve（内嵌，默认）或 meilisearch
+	SearchAnalyzer   string `env:"SEARCH_ANALYZER"` // Bleve 分析器：cjk（默认）或 standard
 	MonitorPrefix    string `env:"MONITOR_PREFIX"`
 	LanguageEnabled  bool   `env:"LANGUAGE_ENABLED"`
 	APISecretKey     string `env:"API_SECRET_KEY"`
@@ -87,12 +92,26 @@ type Config struct {
 	ElasticsearchPassword string `env:"ELASTICSEARCH_PASSWORD"` // 密码（可选）
 	ElasticsearchIndex    string `env:"ELASTICSEARCH_INDEX"`    // 索引名称
 
+	// Meilisearch 配置（SEARCH_ENGINE=meilisearch 时使用）
+	MeilisearchHost   string `env:"MEILISEARCH_HOST"`    // 服务地址（默认: http://localhost:7700）
+	MeilisearchAPIKey string `env:"MEILISEARCH_API_KEY"` // API Key（可选）
+	MeilisearchIndex  string `env:"MEILISEARCH_INDEX"`   // 索引 UID（默认: lingecho）
+
 	// Pinecone 配置
 	PineconeApiKey    string `env:"PINECONE_API_KEY"`    // API Key（必需）
 	PineconeBaseURL   string `env:"PINECONE_BASE_URL"`   // Base URL（默认: https://api.pinecone.io）
 	PineconeIndexName string `env:"PINECONE_INDEX_NAME"` // 索引名称（必需）
 	PineconeDimension int    `env:"PINECONE_DIMENSION"`  // 向量维度（默认: 1536）
 
+	// 向量知识库（Milvus/Qdrant/Pinecone）的 Embedding 配置，与向量库任意组合
+	EmbeddingProvider  string `env:"EMBEDDING_PROVIDER"`   // openai/azure/qwen/ollama，为空时检索需调用方提供向量
+	EmbeddingModel     string `env:"EMBEDDING_MODEL"`      // 模型名称，Azure 为部署名称
+	EmbeddingApiKey    string `env:"EMBEDDING_API_KEY"`    // API Key（ollama 不需要）
+	EmbeddingBaseURL   string `env:"EMBEDDING_BASE_URL"`   // Base URL（可选，Azure 必需）
+	EmbeddingDimension int    `env:"EMBEDDING_DIMENSION"`  // 输出维度（可选）
+	EmbeddingBatchSize int    `env:"EMBEDDING_BATCH_SIZE"` // 单次请求文本数（可选）
+	KnowledgeSyncDir   string `env:"KNOWLEDGE_SYNC_DIR"`   // 同步源可读取的本地目录（Notion 导出），为空时不允许本地路径
+
 	// 缓存配置
 	Cache cache.Config
 
@@ -107,13 +126,104 @@ type Config struct {
 	Neo4jUsername string `env:"NEO4J_USERNAME"` // Neo4j 用户名（默认: neo4j）
 	Neo4jPassword string `env:"NEO4J_PASSWORD"` // Neo4j 密码
 	Neo4jDatabase string `env:"NEO4J_DATABASE"` // Neo4j 数据库名称（默认: neo4j）
+
+	// 图记忆存储配置
+	GraphStore      string `env:"GRAPH_STORE"`       // 图存储后端：neo4j、sqlite、memory（为空时 NEO4J_ENABLED=true 使用 neo4j）
+	GraphSQLitePath string `env:"GRAPH_SQLITE_PATH"` // SQLite 图存储文件路径（默认: ./graph.db）
+
+	// 数据库连接池与只读副本配置
+	DBReplicaDSNs      string        `env:"DB_REPLICA_DSNS"`       // 只读副本 DSN，多个以逗号分隔（列表/统计类重查询走副本）
+	DBMaxIdleConns     int           `env:"DB_MAX_IDLE_CONNS"`     // 最大空闲连接数（默认: 10）
+	DBMaxOpenConns     int           `env:"DB_MAX_OPEN_CONNS"`     // 最大打开连接数（默认: 100）
+	DBConnMaxLifetime  time.Duration `env:"DB_CONN_MAX_LIFETIME"`  // 连接最大存活时间（默认: 1h）
+	DBConnMaxIdleTime  time.Duration `env:"DB_CONN_MAX_IDLE_TIME"` // 连接最大空闲时间（默认: 30m）
+	DBStatementTimeout time.Duration `env:"DB_STATEMENT_TIMEOUT"`  // 单条语句超时（默认: 0 不限制）
+
+	// 备份加密、保留策略与远程存储配置
+	BackupEncryptionKey string `env:"BACKUP_ENCRYPTION_KEY"` // 备份加密口令，设置后备份文件使用 AES-256-GCM 加密
+	BackupRetentionDays int    `env:"BACKUP_RETENTION_DAYS"` // 备份保留天数（默认: 0 不按时间清理）
+	BackupRetentionKeep int    `env:"BACKUP_RETENTION_KEEP"` // 最多保留的备份数量（默认: 0 不按数量清理）
+	BackupS3Endpoint    string `env:"BACKUP_S3_ENDPOINT"`    // S3 兼容存储地址，为空则只保存在本地
+	BackupS3AccessKey   string `env:"BACKUP_S3_ACCESS_KEY"`
+	BackupS3SecretKey   string `env:"BACKUP_S3_SECRET_KEY"`
+	BackupS3Bucket      string `env:"BACKUP_S3_BUCKET"`
+	BackupS3Region      string `env:"BACKUP_S3_REGION"`
+	BackupS3Prefix      string `env:"BACKUP_S3_PREFIX"` // 对象前缀（默认: backups/）
+	BackupS3UseSSL      bool   `env:"BACKUP_S3_USE_SSL"`
+
+	// WebRTC ICE 配置（STUN/TURN），数据库中的 WEBRTC_* 配置优先
+	WebRTCSTUNURLs           string        `env:"WEBRTC_STUN_URLS"`            // STUN 地址，多个以逗号分隔（默认: stun:stun.l.google.com:19302）
+	WebRTCTURNURLs           string        `env:"WEBRTC_TURN_URLS"`            // TURN 地址，多个以逗号分隔，为空则不使用中继
+	WebRTCTURNUsername       string        `env:"WEBRTC_TURN_USERNAME"`        // TURN 长期凭证用户名
+	WebRTCTURNCredential     string        `env:"WEBRTC_TURN_CREDENTIAL"`      // TURN 长期凭证密码
+	WebRTCTURNSecret         string        `env:"WEBRTC_TURN_SECRET"`          // TURN REST API 共享密钥，设置后生成临时凭证
+	WebRTCTURNTTL            time.Duration `env:"WEBRTC_TURN_TTL"`             // 临时凭证有效期（默认: 24h）
+	WebRTCICETransportPolicy string        `env:"WEBRTC_ICE_TRANSPORT_POLICY"` // all（默认）或 relay（仅使用 TURN 中继）
+	// WebRTC DTLS 证书（PEM），设置后所有会话使用同一证书，文件不存在时自动生成；为空则每个连接使用临时证书
+	WebRTCCertFile string `env:"WEBRTC_CERT_FILE"`
+	WebRTCKeyFile  string `env:"WEBRTC_KEY_FILE"`
+	// 信令会话存储：memory（默认，仅本节点）或 redis（多副本部署，复用 REDIS_* 连接配置）
+	SignalingStore string `env:"SIGNALING_STORE"`
+	// 集群中本节点的标识（默认: 主机名-进程号）
+	NodeID string `env:"NODE_ID"`
+	// WebSocket 信令与语音连接允许的 Origin，多个以逗号分隔，支持以 * 结尾的前缀匹配；为空时只允许同源，设为 * 允许所有来源
+	WSAllowedOrigins string `env:"WS_ALLOWED_ORIGINS"`
+	// 每个用户在本节点的并发 WebRTC 语音会话上限（默认: 5，负数不限制）
+	VoiceMaxSessionsPerUser int `env:"VOICE_MAX_SESSIONS_PER_USER"`
+	// gRPC 信令监听地址（如 :9090），供嵌入式与原生客户端使用；为空时不启动
+	VoiceGRPCAddr string `env:"VOICE_GRPC_ADDR"`
+
+	// 配置热更新：检查 .env 文件与数据库配置表变化的间隔（默认: 10s，0 关闭）
+	ConfigWatchInterval time.Duration `env:"CONFIG_WATCH_INTERVAL"`
+
+	// 禁用的后台任务名称，多个以逗号分隔（如 email-cleaner,search-indexer），任务状态见 /api/metrics/tasks
+	DisabledTasks string `env:"DISABLED_TASKS"`
+
+	// 一句话语音接口生成的音频保留时长，过期后由 storage-cleaner 删除，0 表示永久保留
+	OneshotAudioTTL time.Duration `env:"ONESHOT_AUDIO_TTL"`
+	// 未设置录音保留策略的用户的录音保留天数，0 表示永久保留
+	RecordingRetentionDays int `env:"RECORDING_RETENTION_DAYS"`
+
+	// 媒体文件签名地址配置
+	MediaSigningSecret    string        `env:"MEDIA_SIGNING_SECRET"`    // 签名密钥，为空时使用 SESSION_SECRET
+	MediaURLTTL           time.Duration `env:"MEDIA_URL_TTL"`           // 签名地址有效期
+	MediaRequireSignature bool          `env:"MEDIA_REQUIRE_SIGNATURE"` // 访问 /media、/uploads 下的文件必须带有效签名
+	MediaPublicPrefixes   string        `env:"MEDIA_PUBLIC_PREFIXES"`   // 无需签名即可访问的存储键前缀，多个以逗号分隔
+
+	// WebAuthn 通行密钥登录配置，为空时从 SERVER_URL 推导
+	WebAuthnRPID      string `env:"WEBAUTHN_RP_ID"`      // 依赖方ID，即站点域名
+	WebAuthnRPOrigins string `env:"WEBAUTHN_RP_ORIGINS"` // 允许的前端来源，多个以逗号分隔
+
+	// 第三方登录（OAuth2/OIDC）配置，未设置客户端ID的提供方不启用
+	OAuthGoogleClientID     string `env:"OAUTH_GOOGLE_CLIENT_ID"`
+	OAuthGoogleClientSecret string `env:"OAUTH_GOOGLE_CLIENT_SECRET"`
+	OAuthGitHubClientID     string `env:"OAUTH_GITHUB_CLIENT_ID"`
+	OAuthGitHubClientSecret string `env:"OAUTH_GITHUB_CLIENT_SECRET"`
+	OIDCProviderName        string `env:"OIDC_PROVIDER_NAME"` // 企业 IdP 在登录地址中的名称，默认 oidc
+	OIDCIssuer              string `env:"OIDC_ISSUER"`        // 企业 IdP 的 issuer，需支持 /.well-known/openid-configuration
+	OIDCClientID            string `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret        string `env:"OIDC_CLIENT_SECRET"`
+	OIDCScopes              string `env:"OIDC_SCOPES"`         // 额外的 scope，默认 email profile
+	OIDCTrustEmail          bool   `env:"OIDC_TRUST_EMAIL"`    // IdP 未声明 email_verified 时也信任其邮箱
+	OAuthAllowSignup        bool   `env:"OAUTH_ALLOW_SIGNUP"`  // 第三方账号没有对应用户时自动注册
+	OAuthCallbackBase       string `env:"OAUTH_CALLBACK_BASE"` // 回调地址的站点前缀，默认 SERVER_URL
+
+	// 通过 /oauth/token（client_credentials）换取的 API 令牌有效期
+	APITokenTTL time.Duration `env:"API_TOKEN_TTL"`
 }
 
 var GlobalConfig *Config
 
+// envFile 启动时加载的 .env 文件，Watcher 监听其变化
+var envFile = ".env"
+
 func Load() error {
 	// 1. 根据环境加载 .env 文件（如果不存在也不报错，使用默认值）
 	env := os.Getenv("APP_ENV")
+	envFile = ".env"
+	if env != "" {
+		envFile = ".env." + env
+	}
 	err := utils.LoadEnv(env)
 	if err != nil {
 		// .env文件不存在时只记录日志，不影响启动
@@ -121,7 +231,13 @@ func Load() error {
 	}
 
 	// 2. 加载全局配置（所有配置都有默认值，确保无.env文件也能启动）
-	GlobalConfig = &Config{
+	GlobalConfig = fromEnv()
+	return nil
+}
+
+// fromEnv 根据当前环境变量构建配置
+func fromEnv() *Config {
+	return &Config{
 		MachineID:        utils.GetIntEnv("MACHINE_ID"),
 		ServerName:       getStringOrDefault("SERVER_NAME", ""),
 		ServerDesc:       getStringOrDefault("SERVER_DESC", ""),
@@ -153,12 +269,58 @@ func Load() error {
 			Port:     int64(getIntOrDefault("MAIL_PORT", 587)),
 			From:     getStringOrDefault("MAIL_FROM", ""),
 		},
+		Push: notification.PushConfig{
+			FCM: notification.FCMConfig{
+				CredentialsFile: getStringOrDefault("PUSH_FCM_CREDENTIALS_FILE", ""),
+				ProjectID:       getStringOrDefault("PUSH_FCM_PROJECT_ID", ""),
+			},
+			APNs: notification.APNsConfig{
+				KeyFile:    getStringOrDefault("PUSH_APNS_KEY_FILE", ""),
+				KeyID:      getStringOrDefault("PUSH_APNS_KEY_ID", ""),
+				TeamID:     getStringOrDefault("PUSH_APNS_TEAM_ID", ""),
+				Topic:      getStringOrDefault("PUSH_APNS_TOPIC", ""),
+				Production: getBoolOrDefault("PUSH_APNS_PRODUCTION", false),
+			},
+		},
+		WebPush: notification.WebPushConfig{
+			PublicKey:  getStringOrDefault("WEBPUSH_VAPID_PUBLIC_KEY", ""),
+			PrivateKey: getStringOrDefault("WEBPUSH_VAPID_PRIVATE_KEY", ""),
+			Subject:    getStringOrDefault("WEBPUSH_VAPID_SUBJECT", ""),
+		},
+		SMS: notification.SMSConfig{
+			Provider: getStringOrDefault("SMS_PROVIDER", ""),
+			Aliyun: notification.AliyunSMSConfig{
+				AccessKeyId:     getStringOrDefault("SMS_ALIYUN_ACCESS_KEY_ID", ""),
+				AccessKeySecret: getStringOrDefault("SMS_ALIYUN_ACCESS_KEY_SECRET", ""),
+				SignName:        getStringOrDefault("SMS_SIGN_NAME", ""),
+				TemplateCode:    getStringOrDefault("SMS_TEMPLATE_VERIFY_CODE", ""),
+				Templates:       smsTemplates(),
+				Endpoint:        getStringOrDefault("SMS_ALIYUN_REGION", "cn-hangzhou"),
+			},
+			Tencent: notification.TencentSMSConfig{
+				SecretID:  getStringOrDefault("SMS_TENCENT_SECRET_ID", ""),
+				SecretKey: getStringOrDefault("SMS_TENCENT_SECRET_KEY", ""),
+				SdkAppID:  getStringOrDefault("SMS_TENCENT_SDK_APP_ID", ""),
+				SignName:  getStringOrDefault("SMS_SIGN_NAME", ""),
+				Region:    getStringOrDefault("SMS_TENCENT_REGION", "ap-guangzhou"),
+				Templates: smsTemplates(),
+			},
+			Twilio: notification.TwilioSMSConfig{
+				AccountSID: getStringOrDefault("SMS_TWILIO_ACCOUNT_SID", ""),
+				AuthToken:  getStringOrDefault("SMS_TWILIO_AUTH_TOKEN", ""),
+				From:       getStringOrDefault("SMS_TWILIO_FROM", ""),
+			},
+			Interval:    getDurationOrDefault("SMS_SEND_INTERVAL", time.Minute),
+			HourlyLimit: getIntOrDefault("SMS_HOURLY_LIMIT", 5),
+		},
 		LLMApiKey:       getStringOrDefault("LLM_API_KEY", ""),
 		LLMBaseURL:      getStringOrDefault("LLM_BASE_URL", "https://api.openai.com/v1"),
 		LLMModel:        getStringOrDefault("LLM_MODEL", "gpt-3.5-turbo"),
 		SearchEnabled:   getBoolOrDefault("SEARCH_ENABLED", false),
 		SearchPath:      getStringOrDefault("SEARCH_PATH", "./search"),
 		SearchBatchSize: getIntOrDefault("SEARCH_BATCH_SIZE", 100),
+		SearchEngine:    getStringOrDefault("SEARCH_ENGINE", "bleve"),
+		SearchAnalyzer:  getStringOrDefault("SEARCH_ANALYZER", "cjk"),
 		MonitorPrefix:   getStringOrDefault("MONITOR_PREFIX", "/metrics"),
 		LanguageEnabled: getBoolOrDefault("LANGUAGE_ENABLED", true),
 		APISecretKey:    getStringOrDefault("API_SECRET_KEY", generateDefaultSessionSecret()),
@@ -202,11 +364,22 @@ func Load() error {
 		ElasticsearchUsername: getStringOrDefault("ELASTICSEARCH_USERNAME", ""),
 		ElasticsearchPassword: getStringOrDefault("ELASTICSEARCH_PASSWORD", ""),
 		ElasticsearchIndex:    getStringOrDefault("ELASTICSEARCH_INDEX", ""),
+		MeilisearchHost:       getStringOrDefault("MEILISEARCH_HOST", "http://localhost:7700"),
+		MeilisearchAPIKey:     getStringOrDefault("MEILISEARCH_API_KEY", ""),
+		MeilisearchIndex:      getStringOrDefault("MEILISEARCH_INDEX", "lingecho"),
 		// Pinecone 配置
 		PineconeApiKey:    getStringOrDefault("PINECONE_API_KEY", ""),
 		PineconeBaseURL:   getStringOrDefault("PINECONE_BASE_URL", "https://api.pinecone.io"),
 		PineconeIndexName: getStringOrDefault("PINECONE_INDEX_NAME", ""),
 		PineconeDimension: getIntOrDefault("PINECONE_DIMENSION", 1536),
+		// Embedding 配置
+		EmbeddingProvider:  getStringOrDefault("EMBEDDING_PROVIDER", ""),
+		EmbeddingModel:     getStringOrDefault("EMBEDDING_MODEL", ""),
+		EmbeddingApiKey:    getStringOrDefault("EMBEDDING_API_KEY", ""),
+		EmbeddingBaseURL:   getStringOrDefault("EMBEDDING_BASE_URL", ""),
+		EmbeddingDimension: getIntOrDefault("EMBEDDING_DIMENSION", 0),
+		EmbeddingBatchSize: getIntOrDefault("EMBEDDING_BATCH_SIZE", 0),
+		KnowledgeSyncDir:   getStringOrDefault("KNOWLEDGE_SYNC_DIR", ""),
 		// 缓存配置
 		Cache: loadCacheConfig(),
 		// SSL/TLS配置（默认禁用）
@@ -219,11 +392,77 @@ func Load() error {
 		Neo4jUsername: getStringOrDefault("NEO4J_USERNAME", "neo4j"),
 		Neo4jPassword: getStringOrDefault("NEO4J_PASSWORD", ""),
 		Neo4jDatabase: getStringOrDefault("NEO4J_DATABASE", "neo4j"),
+		// 图记忆存储配置
+		GraphStore:      getStringOrDefault("GRAPH_STORE", ""),
+		GraphSQLitePath: getStringOrDefault("GRAPH_SQLITE_PATH", "./graph.db"),
+		// 数据库连接池与只读副本配置
+		DBReplicaDSNs:      getStringOrDefault("DB_REPLICA_DSNS", ""),
+		DBMaxIdleConns:     getIntOrDefault("DB_MAX_IDLE_CONNS", 10),
+		DBMaxOpenConns:     getIntOrDefault("DB_MAX_OPEN_CONNS", 100),
+		DBConnMaxLifetime:  getDurationOrDefault("DB_CONN_MAX_LIFETIME", time.Hour),
+		DBConnMaxIdleTime:  getDurationOrDefault("DB_CONN_MAX_IDLE_TIME", 30*time.Minute),
+		DBStatementTimeout: getDurationOrDefault("DB_STATEMENT_TIMEOUT", 0),
+		// 备份加密、保留策略与远程存储配置
+		BackupEncryptionKey: getStringOrDefault("BACKUP_ENCRYPTION_KEY", ""),
+		BackupRetentionDays: getIntOrDefault("BACKUP_RETENTION_DAYS", 0),
+		BackupRetentionKeep: getIntOrDefault("BACKUP_RETENTION_KEEP", 0),
+		BackupS3Endpoint:    getStringOrDefault("BACKUP_S3_ENDPOINT", ""),
+		BackupS3AccessKey:   getStringOrDefault("BACKUP_S3_ACCESS_KEY", ""),
+		BackupS3SecretKey:   getStringOrDefault("BACKUP_S3_SECRET_KEY", ""),
+		BackupS3Bucket:      getStringOrDefault("BACKUP_S3_BUCKET", ""),
+		BackupS3Region:      getStringOrDefault("BACKUP_S3_REGION", ""),
+		BackupS3Prefix:      getStringOrDefault("BACKUP_S3_PREFIX", "backups/"),
+		BackupS3UseSSL:      getBoolOrDefault("BACKUP_S3_USE_SSL", true),
+		// WebRTC ICE 配置
+		WebRTCSTUNURLs:           getStringOrDefault("WEBRTC_STUN_URLS", "stun:stun.l.google.com:19302"),
+		WebRTCTURNURLs:           getStringOrDefault("WEBRTC_TURN_URLS", ""),
+		WebRTCTURNUsername:       getStringOrDefault("WEBRTC_TURN_USERNAME", ""),
+		WebRTCTURNCredential:     getStringOrDefault("WEBRTC_TURN_CREDENTIAL", ""),
+		WebRTCTURNSecret:         getStringOrDefault("WEBRTC_TURN_SECRET", ""),
+		WebRTCTURNTTL:            getDurationOrDefault("WEBRTC_TURN_TTL", 24*time.Hour),
+		WebRTCICETransportPolicy: getStringOrDefault("WEBRTC_ICE_TRANSPORT_POLICY", "all"),
+		WebRTCCertFile:           getStringOrDefault("WEBRTC_CERT_FILE", ""),
+		WebRTCKeyFile:            getStringOrDefault("WEBRTC_KEY_FILE", ""),
+		SignalingStore:           getStringOrDefault("SIGNALING_STORE", "memory"),
+		NodeID:                   getStringOrDefault("NODE_ID", ""),
+		WSAllowedOrigins:         getStringOrDefault("WS_ALLOWED_ORIGINS", ""),
+		VoiceMaxSessionsPerUser:  getIntOrDefault("VOICE_MAX_SESSIONS_PER_USER", 5),
+		VoiceGRPCAddr:            getStringOrDefault("VOICE_GRPC_ADDR", ""),
+		ConfigWatchInterval:      getDurationOrDefault("CONFIG_WATCH_INTERVAL", 10*time.Second),
+		DisabledTasks:            getStringOrDefault("DISABLED_TASKS", ""),
+		OneshotAudioTTL:          getDurationOrDefault("ONESHOT_AUDIO_TTL", 24*time.Hour),
+		RecordingRetentionDays:   getIntOrDefault("RECORDING_RETENTION_DAYS", 0),
+		MediaSigningSecret:       getStringOrDefault("MEDIA_SIGNING_SECRET", ""),
+		MediaURLTTL:              getDurationOrDefault("MEDIA_URL_TTL", time.Hour),
+		MediaRequireSignature:    getBoolOrDefault("MEDIA_REQUIRE_SIGNATURE", false),
+		MediaPublicPrefixes:      getStringOrDefault("MEDIA_PUBLIC_PREFIXES", "avatars/,group_avatars/"),
+		WebAuthnRPID:             getStringOrDefault("WEBAUTHN_RP_ID", ""),
+		WebAuthnRPOrigins:        getStringOrDefault("WEBAUTHN_RP_ORIGINS", ""),
+		OAuthGoogleClientID:      getStringOrDefault("OAUTH_GOOGLE_CLIENT_ID", ""),
+		OAuthGoogleClientSecret:  getStringOrDefault("OAUTH_GOOGLE_CLIENT_SECRET", ""),
+		OAuthGitHubClientID:      getStringOrDefault("OAUTH_GITHUB_CLIENT_ID", ""),
+		OAuthGitHubClientSecret:  getStringOrDefault("OAUTH_GITHUB_CLIENT_SECRET", ""),
+		OIDCProviderName:         getStringOrDefault("OIDC_PROVIDER_NAME", "oidc"),
+		OIDCIssuer:               getStringOrDefault("OIDC_ISSUER", ""),
+		OIDCClientID:             getStringOrDefault("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:         getStringOrDefault("OIDC_CLIENT_SECRET", ""),
+		OIDCScopes:               getStringOrDefault("OIDC_SCOPES", ""),
+		OIDCTrustEmail:           getBoolOrDefault("OIDC_TRUST_EMAIL", false),
+		OAuthAllowSignup:         getBoolOrDefault("OAUTH_ALLOW_SIGNUP", true),
+		OAuthCallbackBase:        getStringOrDefault("OAUTH_CALLBACK_BASE", ""),
+		APITokenTTL:              getDurationOrDefault("API_TOKEN_TTL", time.Hour),
 	}
-	return nil
 }
 
 // getStringOrDefault 获取环境变量值，如果为空则返回默认值
+// smsTemplates 阿里云、腾讯云短信的模板名 → 服务商模板 ID
+func smsTemplates() map[string]string {
+	return map[string]string{
+		notification.SMSTemplateVerifyCode:      getStringOrDefault("SMS_TEMPLATE_VERIFY_CODE", ""),
+		notification.SMSTemplateSuspiciousLogin: getStringOrDefault("SMS_TEMPLATE_SUSPICIOUS_LOGIN", ""),
+	}
+}
+
 func getStringOrDefault(key, defaultValue string) string {
 	value := utils.GetEnv(key)
 	if value == "" {
@@ -250,6 +489,19 @@ func getIntOrDefault(key string, defaultValue int) int {
 	return int(value)
 }
 
+// getDurationOrDefault 获取时间间隔环境变量值（如 30s、5m），为空或格式错误时返回默认值
+func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := utils.GetEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return d
+}
+
 // generateDefaultSessionSecret 生成默认的会话密钥（仅用于开发环境）
 func generateDefaultSessionSecret() string {
 	// 如果环境变量中已有值，使用环境变量
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// SigConfigChanged 配置变化时通过 utils.Sig() 发出，sender 为 *Change
+const SigConfigChanged = "config.changed"
+
+const (
+	ChangeSourceFile = "file" // .env 文件
+	ChangeSourceDB   = "db"   // 数据库配置表（utils.Config）
+)
+
+// Change 一次配置变化
+type Change struct {
+	Source string
+	// Values 变化的键（大写）及新值，从 .env 文件删除的键值为空
+	Values map[string]string
+}
+
+// Has 报告任一键是否变化
+func (c *Change) Has(keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := c.Values[strings.ToUpper(key)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys 返回排序后的变化键
+func (c *Change) Keys() []string {
+	keys := make([]string, 0, len(c.Values))
+	for key := range c.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Watcher 定期检查 .env 文件与数据库配置表：文件变化时更新进程环境变量并重建 GlobalConfig，
+// 数据库配置变化时清除 utils.GetValue 的缓存，随后发出 SigConfigChanged，
+// 由中间件、日志、服务提供方等按需重新配置
+type Watcher struct {
+	db       *gorm.DB
+	path     string
+	interval time.Duration
+
+	mu        sync.Mutex
+	fileMod   time.Time
+	fileVals  map[string]string
+	dbUpdated time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewWatcher 创建监听器并记录当前状态，db 为 nil 时只监听文件
+func NewWatcher(db *gorm.DB, interval time.Duration) *Watcher {
+	w := &Watcher{
+		db:       db,
+		path:     envFile,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if info, err := os.Stat(w.path); err == nil {
+		w.fileMod = info.ModTime()
+		w.fileVals, _ = readEnvFile(w.path)
+	}
+	if db != nil {
+		var latest utils.Config
+		if err := db.Order("updated_at desc").Take(&latest).Error; err == nil {
+			w.dbUpdated = latest.UpdatedAt
+		}
+	}
+	return w
+}
+
+// Start 在后台按间隔检查，interval <= 0 时不启动
+func (w *Watcher) Start() {
+	if w.interval <= 0 {
+		close(w.done)
+		return
+	}
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.Check()
+			}
+		}
+	}()
+	logger.Info("Config watcher started", zap.String("file", w.path), zap.Duration("interval", w.interval))
+}
+
+// Stop 停止后台检查
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+// Check 立即检查一次，如收到 SIGHUP 时调用
+func (w *Watcher) Check() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if change := w.checkFile(); change != nil {
+		emit(change)
+	}
+	if change := w.checkDB(); change != nil {
+		emit(change)
+	}
+}
+
+// checkFile 文件修改时间变化后重新读取，把变化写入进程环境变量并重建 GlobalConfig
+func (w *Watcher) checkFile() *Change {
+	info, err := os.Stat(w.path)
+	if err != nil || info.ModTime().Equal(w.fileMod) {
+		return nil
+	}
+	vals, err := readEnvFile(w.path)
+	if err != nil {
+		logger.Warn("Failed to reload env file", zap.String("file", w.path), zap.Error(err))
+		return nil
+	}
+	w.fileMod = info.ModTime()
+
+	changed := make(map[string]string)
+	for key, value := range vals {
+		if old, ok := w.fileVals[key]; !ok || old != value {
+			os.Setenv(key, value)
+			changed[strings.ToUpper(key)] = value
+		}
+	}
+	for key := range w.fileVals {
+		if _, ok := vals[key]; !ok {
+			os.Unsetenv(key)
+			changed[strings.ToUpper(key)] = ""
+		}
+	}
+	w.fileVals = vals
+	if len(changed) == 0 {
+		return nil
+	}
+	for key := range changed {
+		utils.InvalidateEnv(key)
+	}
+	reload()
+	return &Change{Source: ChangeSourceFile, Values: changed}
+}
+
+// checkDB 查询上次检查后更新过的配置项并清除其缓存
+func (w *Watcher) checkDB() *Change {
+	if w.db == nil {
+		return nil
+	}
+	var rows []utils.Config
+	if err := w.db.Where("updated_at > ?", w.dbUpdated).Order("updated_at").Find(&rows).Error; err != nil {
+		logger.Debug("Failed to check config table", zap.Error(err))
+		return nil
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	changed := make(map[string]string, len(rows))
+	for _, row := range rows {
+		utils.InvalidateValue(row.Key)
+		changed[strings.ToUpper(row.Key)] = row.Value
+		w.dbUpdated = row.UpdatedAt
+	}
+	return &Change{Source: ChangeSourceDB, Values: changed}
+}
+
+// reload 根据当前环境变量重建 GlobalConfig
+func reload() {
+	prev := GlobalConfig
+	next := fromEnv()
+	if prev != nil {
+		// 未配置时 fromEnv 会生成新的随机密钥，沿用原值以免已签发的会话与签名失效
+		if utils.GetEnv("SESSION_SECRET") == "" {
+			next.SessionSecret = prev.SessionSecret
+		}
+		if utils.GetEnv("API_SECRET_KEY") == "" {
+			next.APISecretKey = prev.APISecretKey
+		}
+	}
+	GlobalConfig = next
+}
+
+func emit(change *Change) {
+	// 只记录键，值可能包含密钥
+	logger.Info("Config changed", zap.String("source", change.Source), zap.Strings("keys", change.Keys()))
+	utils.Sig().Emit(SigConfigChanged, change)
+}
+
+// readEnvFile 按 utils.LoadEnv 的规则解析 .env 文件
+func readEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vals := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		vals[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return vals, nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// collectChanges 订阅 SigConfigChanged，测试结束时取消订阅
+func collectChanges(t *testing.T) *[]*Change {
+	var changes []*Change
+	id := utils.Sig().Connect(SigConfigChanged, func(sender any, params ...any) {
+		changes = append(changes, sender.(*Change))
+	})
+	t.Cleanup(func() { utils.Sig().Disconnect(SigConfigChanged, id) })
+	return &changes
+}
+
+func writeEnvFile(t *testing.T, path, content string, mod time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherReloadsEnvFile(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "info")
+	t.Setenv("WATCHER_REMOVED", "x")
+	path := filepath.Join(t.TempDir(), ".env")
+	prevFile := envFile
+	envFile = path
+	t.Cleanup(func() { envFile = prevFile })
+	prevConfig := GlobalConfig
+	t.Cleanup(func() { GlobalConfig = prevConfig })
+	GlobalConfig = fromEnv()
+	secret := GlobalConfig.SessionSecret
+
+	start := time.Now().Add(-time.Hour)
+	writeEnvFile(t, path, "LOG_LEVEL=info\nWATCHER_REMOVED=x\n", start)
+	w := NewWatcher(nil, 0)
+	changes := collectChanges(t)
+
+	// 修改时间未变时不重新读取
+	w.Check()
+	if len(*changes) != 0 {
+		t.Fatalf("expected no change, got %v", (*changes)[0].Values)
+	}
+
+	writeEnvFile(t, path, "# comment\nLOG_LEVEL=debug\n", start.Add(time.Minute))
+	w.Check()
+	if len(*changes) != 1 {
+		t.Fatalf("expected one change, got %d", len(*changes))
+	}
+	change := (*changes)[0]
+	if change.Source != ChangeSourceFile || !change.Has("log_level") || change.Has("MODE") {
+		t.Fatalf("unexpected change %+v", change)
+	}
+	if got := change.Keys(); len(got) != 2 || got[0] != "LOG_LEVEL" || got[1] != "WATCHER_REMOVED" {
+		t.Fatalf("unexpected keys %v", got)
+	}
+	if os.Getenv("LOG_LEVEL") != "debug" {
+		t.Fatalf("expected LOG_LEVEL=debug in environment")
+	}
+	if _, ok := os.LookupEnv("WATCHER_REMOVED"); ok {
+		t.Fatalf("expected removed key to be unset")
+	}
+	if GlobalConfig.Log.Level != "debug" {
+		t.Fatalf("expected GlobalConfig to be rebuilt, got level %q", GlobalConfig.Log.Level)
+	}
+	if GlobalConfig.SessionSecret != secret {
+		t.Fatalf("expected session secret to survive reload")
+	}
+}
+
+func TestWatcherDetectsDBChanges(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&utils.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	utils.SetValue(db, "WATCHER_DB_KEY", "old", "text", false, false)
+	if got := utils.GetValue(db, "WATCHER_DB_KEY"); got != "old" {
+		t.Fatalf("expected old, got %q", got)
+	}
+
+	prevFile := envFile
+	envFile = filepath.Join(t.TempDir(), "missing.env")
+	t.Cleanup(func() { envFile = prevFile })
+	w := NewWatcher(db, 0)
+	changes := collectChanges(t)
+
+	w.Check()
+	if len(*changes) != 0 {
+		t.Fatalf("expected existing rows to be ignored")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	utils.SetValue(db, "watcher_db_key", "new", "text", false, false)
+	w.Check()
+	if len(*changes) != 1 || (*changes)[0].Source != ChangeSourceDB || (*changes)[0].Values["WATCHER_DB_KEY"] != "new" {
+		t.Fatalf("unexpected changes %+v", *changes)
+	}
+	// 缓存已失效，读取到新值
+	if got := utils.GetValue(db, "WATCHER_DB_KEY"); got != "new" {
+		t.Fatalf("expected new, got %q", got)
+	}
+
+	w.Check()
+	if len(*changes) != 1 {
+		t.Fatalf("expected no further change")
+	}
+}
@@ -22,6 +22,9 @@ const GroupField = "_lingecho_gid"
 const TzField = "_lingecho_tz"
 const AssetsField = "_lingecho_assets"
 const TemplatesField = "_lingecho_templates"
+const APITokenField = "_lingecho_api_token"
+const SessionField = "_lingecho_session"
+const SessionKeyField = "_lingecho_sid"
 
 const KEY_VERIFY_EMAIL_EXPIRED = "VERIFY_EMAIL_EXPIRED"
 const KEY_AUTH_TOKEN_EXPIRED = "AUTH_TOKEN_EXPIRED"
@@ -52,11 +55,19 @@ const KEY_SEARCH_ENABLED = "SEARCH_ENABLED"
 const KEY_SEARCH_PATH = "SEARCH_PATH"
 const KEY_SEARCH_BATCH_SIZE = "SEARCH_BATCH_SIZE"
 const KEY_SEARCH_INDEX_SCHEDULE = "SEARCH_INDEX_SCHEDULE"
+const KEY_SEARCH_ENGINE = "SEARCH_ENGINE"
+const KEY_SEARCH_ANALYZER = "SEARCH_ANALYZER"
+const KEY_SEARCH_FUZZINESS = "SEARCH_FUZZINESS"
 
 // Voice clone configuration keys
 const KEY_VOICE_CLONE_XUNFEI_CONFIG = "VOICE_CLONE_XUNFEI_CONFIG"
 const KEY_VOICE_CLONE_VOLCENGINE_CONFIG = "VOICE_CLONE_VOLCENGINE_CONFIG"
 
+// WebRTC ICE configuration keys
+const KEY_WEBRTC_STUN_URLS = "WEBRTC_STUN_URLS"
+const KEY_WEBRTC_TURN_SERVERS = "WEBRTC_TURN_SERVERS"
+const KEY_WEBRTC_ICE_TRANSPORT_POLICY = "WEBRTC_ICE_TRANSPORT_POLICY"
+
 // OTA and device configuration keys
 const KEY_SERVER_WEBSOCKET = "server.websocket"
 const KEY_SERVER_MQTT_GATEWAY = "server.mqtt_gateway"
@@ -8,6 +8,13 @@ const (
 	FormFieldProvider      = "provider"
 	FormFieldKnowledgeKey  = "knowledgeKey"
 
+	// Ingestion form field names
+	FormFieldDocumentID        = "documentId"
+	FormFieldChunkStrategy     = "chunkStrategy"
+	FormFieldChunkSize         = "chunkSize"
+	FormFieldChunkOverlap      = "chunkOverlap"
+	FormFieldSemanticThreshold = "semanticThreshold"
+
 	// Query parameters
 	QueryParamKnowledgeKey = "knowledgeKey"
 )
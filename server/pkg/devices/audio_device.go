@@ -30,6 +30,10 @@ type DeviceConfig struct {
 	Format malgo.FormatType
 	// ALSA NoMMap 设置，默认 1
 	AlsaNoMMap uint32
+	// 捕获设备，nil 表示使用系统默认设备，可通过 FindCaptureDevice 获取
+	CaptureDeviceID *malgo.DeviceID
+	// 播放设备，nil 表示使用系统默认设备，可通过 FindPlaybackDevice 获取
+	PlaybackDeviceID *malgo.DeviceID
 	// 日志回调函数，可选
 	LogCallback func(message string)
 }
@@ -96,6 +100,7 @@ func (ad *AudioDevice) StartRecording() error {
 	deviceConfig.Capture.Channels = ad.config.Channels
 	deviceConfig.SampleRate = ad.config.SampleRate
 	deviceConfig.Alsa.NoMMap = ad.config.AlsaNoMMap
+	ad.applyDeviceIDs(&deviceConfig)
 
 	// 清空之前的录制数据
 	ad.capturedSamples = make([]byte, 0)
@@ -164,6 +169,7 @@ func (ad *AudioDevice) StartPlaybackFromData(audioData []byte) error {
 	deviceConfig.Playback.Channels = ad.config.Channels
 	deviceConfig.SampleRate = ad.config.SampleRate
 	deviceConfig.Alsa.NoMMap = ad.config.AlsaNoMMap
+	ad.applyDeviceIDs(&deviceConfig)
 
 	// 重置播放位置
 	ad.playbackPosition = 0
@@ -254,6 +260,7 @@ func (ad *AudioDevice) StartDuplex(playbackData []byte) error {
 	deviceConfig.Playback.Channels = ad.config.Channels
 	deviceConfig.SampleRate = ad.config.SampleRate
 	deviceConfig.Alsa.NoMMap = ad.config.AlsaNoMMap
+	ad.applyDeviceIDs(&deviceConfig)
 
 	// 清空之前的录制数据
 	ad.capturedSamples = make([]byte, 0)
@@ -421,3 +428,13 @@ func (ad *AudioDevice) Close() error {
 
 	return err
 }
+
+// applyDeviceIDs 将配置中选定的设备写入 malgo 设备配置，未选定时使用系统默认设备
+func (ad *AudioDevice) applyDeviceIDs(deviceConfig *malgo.DeviceConfig) {
+	if ad.config.CaptureDeviceID != nil {
+		deviceConfig.Capture.DeviceID = ad.config.CaptureDeviceID.Pointer()
+	}
+	if ad.config.PlaybackDeviceID != nil {
+		deviceConfig.Playback.DeviceID = ad.config.PlaybackDeviceID.Pointer()
+	}
+}
@@ -1,38 +1,56 @@
 package devices
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/gen2brain/malgo"
 )
 
+// ErrDeviceNotFound 未找到匹配的音频设备
+var ErrDeviceNotFound = errors.New("audio device not found")
+
 // DeviceInfo 设备信息
 type DeviceInfo struct {
-	ID      malgo.DeviceID
-	Name    string
-	Formats []malgo.DataFormat
-	Error   string
+	ID        malgo.DeviceID
+	Name      string
+	IsDefault bool
+	Formats   []malgo.DataFormat
+	Error     string
 }
 
 // ListPlaybackDevices 列出所有播放设备
 func ListPlaybackDevices(ctx *malgo.AllocatedContext) ([]DeviceInfo, error) {
+	return listDevices(ctx, malgo.Playback)
+}
+
+// ListCaptureDevices 列出所有捕获设备
+func ListCaptureDevices(ctx *malgo.AllocatedContext) ([]DeviceInfo, error) {
+	return listDevices(ctx, malgo.Capture)
+}
+
+// listDevices 枚举指定类型的设备，并查询每个设备支持的格式
+func listDevices(ctx *malgo.AllocatedContext, deviceType malgo.DeviceType) ([]DeviceInfo, error) {
 	if ctx == nil {
 		return nil, fmt.Errorf("context is nil")
 	}
 
-	infos, err := ctx.Devices(malgo.Playback)
+	infos, err := ctx.Devices(deviceType)
 	if err != nil {
-		return nil, fmt.Errorf("获取播放设备列表失败: %w", err)
+		return nil, fmt.Errorf("获取%s设备列表失败: %w", deviceTypeName(deviceType), err)
 	}
 
 	result := make([]DeviceInfo, 0, len(infos))
 	for _, info := range infos {
 		deviceInfo := DeviceInfo{
-			ID:   info.ID,
-			Name: info.Name(),
+			ID:        info.ID,
+			Name:      info.Name(),
+			IsDefault: info.IsDefault != 0,
 		}
 
-		full, err := ctx.DeviceInfo(malgo.Playback, info.ID, malgo.Shared)
+		full, err := ctx.DeviceInfo(deviceType, info.ID, malgo.Shared)
 		if err != nil {
 			deviceInfo.Error = err.Error()
 		} else {
@@ -45,35 +63,71 @@ func ListPlaybackDevices(ctx *malgo.AllocatedContext) ([]DeviceInfo, error) {
 	return result, nil
 }
 
-// ListCaptureDevices 列出所有捕获设备
-func ListCaptureDevices(ctx *malgo.AllocatedContext) ([]DeviceInfo, error) {
-	if ctx == nil {
-		return nil, fmt.Errorf("context is nil")
+// FindPlaybackDevice 按选择器查找播放设备，选择器规则见 MatchDevice
+func FindPlaybackDevice(ctx *malgo.AllocatedContext, selector string) (*DeviceInfo, error) {
+	devices, err := ListPlaybackDevices(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return MatchDevice(devices, selector)
+}
 
-	infos, err := ctx.Devices(malgo.Capture)
+// FindCaptureDevice 按选择器查找捕获设备，选择器规则见 MatchDevice
+func FindCaptureDevice(ctx *malgo.AllocatedContext, selector string) (*DeviceInfo, error) {
+	devices, err := ListCaptureDevices(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("获取捕获设备列表失败: %w", err)
+		return nil, err
 	}
+	return MatchDevice(devices, selector)
+}
 
-	result := make([]DeviceInfo, 0, len(infos))
-	for _, info := range infos {
-		deviceInfo := DeviceInfo{
-			ID:   info.ID,
-			Name: info.Name(),
+// MatchDevice 从设备列表中选出设备，依次按以下规则匹配：
+// 设备 ID（十六进制字符串）、列表序号（如 "#1"）、完整名称、名称子串（不区分大小写）。
+// 选择器为空或 "default" 时返回系统默认设备
+func MatchDevice(devices []DeviceInfo, selector string) (*DeviceInfo, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" || strings.EqualFold(selector, "default") {
+		for i := range devices {
+			if devices[i].IsDefault {
+				return &devices[i], nil
+			}
 		}
+		return nil, fmt.Errorf("%w: no default device", ErrDeviceNotFound)
+	}
 
-		full, err := ctx.DeviceInfo(malgo.Capture, info.ID, malgo.Shared)
-		if err != nil {
-			deviceInfo.Error = err.Error()
-		} else {
-			deviceInfo.Formats = full.Formats
+	for i := range devices {
+		if devices[i].ID.String() == selector {
+			return &devices[i], nil
+		}
+	}
+	if strings.HasPrefix(selector, "#") {
+		if index, err := strconv.Atoi(selector[1:]); err == nil && index >= 0 && index < len(devices) {
+			return &devices[index], nil
 		}
-
-		result = append(result, deviceInfo)
 	}
+	for i := range devices {
+		if devices[i].Name == selector {
+			return &devices[i], nil
+		}
+	}
+	lower := strings.ToLower(selector)
+	for i := range devices {
+		if strings.Contains(strings.ToLower(devices[i].Name), lower) {
+			return &devices[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrDeviceNotFound, selector)
+}
 
-	return result, nil
+// deviceTypeName 设备类型的中文名称，用于错误信息
+func deviceTypeName(deviceType malgo.DeviceType) string {
+	switch deviceType {
+	case malgo.Playback:
+		return "播放"
+	case malgo.Capture:
+		return "捕获"
+	}
+	return "音频"
 }
 
 // PrintPlaybackDevices 打印所有播放设备信息
@@ -89,6 +143,9 @@ func PrintPlaybackDevices(ctx *malgo.AllocatedContext) error {
 		if device.Error != "" {
 			status = device.Error
 		}
+		if device.IsDefault {
+			status += ", default"
+		}
 		fmt.Printf("    %d: %v, %s, [%s], formats: %+v\n",
 			i, device.ID, device.Name, status, device.Formats)
 	}
@@ -109,6 +166,9 @@ func PrintCaptureDevices(ctx *malgo.AllocatedContext) error {
 		if device.Error != "" {
 			status = device.Error
 		}
+		if device.IsDefault {
+			status += ", default"
+		}
 		fmt.Printf("    %d: %v, %s, [%s], formats: %+v\n",
 			i, device.ID, device.Name, status, device.Formats)
 	}
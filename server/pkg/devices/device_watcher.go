@@ -0,0 +1,119 @@
+package devices
+
+import (
+	"context"
+	"time"
+
+	"github.com/gen2brain/malgo"
+)
+
+// DefaultWatchInterval 设备热插拔检测的默认轮询间隔
+const DefaultWatchInterval = 2 * time.Second
+
+// DeviceChangeType 设备变化类型
+type DeviceChangeType string
+
+const (
+	DeviceAdded          DeviceChangeType = "added"           // 新设备接入
+	DeviceRemoved        DeviceChangeType = "removed"         // 设备被移除（如拔出耳机）
+	DeviceDefaultChanged DeviceChangeType = "default_changed" // 系统默认设备切换
+)
+
+// DeviceChange 设备变化通知
+type DeviceChange struct {
+	Type       DeviceChangeType
+	DeviceType malgo.DeviceType // malgo.Capture 或 malgo.Playback
+	Device     DeviceInfo
+}
+
+// WatchDevices 轮询设备列表，在设备接入、移除或默认设备切换时回调 onChange，直到 ctx 结束
+// miniaudio 的设备通知回调无法在 Go 中注册，因此通过周期性枚举比对实现热插拔检测
+func WatchDevices(ctx context.Context, malgoCtx *malgo.AllocatedContext, interval time.Duration, onChange func(DeviceChange)) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	capture, err := ListCaptureDevices(malgoCtx)
+	if err != nil {
+		return err
+	}
+	playback, err := ListPlaybackDevices(malgoCtx)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		// 枚举失败（如音频服务重启）时保留上一次的列表，下个周期重试
+		if current, err := ListCaptureDevices(malgoCtx); err == nil {
+			for _, change := range diffDevices(malgo.Capture, capture, current) {
+				onChange(change)
+			}
+			capture = current
+		}
+		if current, err := ListPlaybackDevices(malgoCtx); err == nil {
+			for _, change := range diffDevices(malgo.Playback, playback, current) {
+				onChange(change)
+			}
+			playback = current
+		}
+	}
+}
+
+// diffDevices 比较两次枚举结果，返回移除、接入及默认设备切换事件
+func diffDevices(deviceType malgo.DeviceType, previous, current []DeviceInfo) []DeviceChange {
+	var changes []DeviceChange
+
+	currentIDs := make(map[malgo.DeviceID]bool, len(current))
+	for _, device := range current {
+		currentIDs[device.ID] = true
+	}
+	previousIDs := make(map[malgo.DeviceID]bool, len(previous))
+	var previousDefault *DeviceInfo
+	for i, device := range previous {
+		previousIDs[device.ID] = true
+		if device.IsDefault {
+			previousDefault = &previous[i]
+		}
+	}
+
+	for _, device := range previous {
+		if !currentIDs[device.ID] {
+			changes = append(changes, DeviceChange{Type: DeviceRemoved, DeviceType: deviceType, Device: device})
+		}
+	}
+	for _, device := range current {
+		if !previousIDs[device.ID] {
+			changes = append(changes, DeviceChange{Type: DeviceAdded, DeviceType: deviceType, Device: device})
+		}
+		if device.IsDefault && (previousDefault == nil || previousDefault.ID != device.ID) {
+			changes = append(changes, DeviceChange{Type: DeviceDefaultChanged, DeviceType: deviceType, Device: device})
+		}
+	}
+
+	return changes
+}
+
+// switchTarget 根据设备变化判断正在使用 current 的设备是否需要重建，以及重建后使用的设备
+// current 为 nil 表示跟随系统默认设备：默认设备切换（含原默认设备被移除）时在新的默认设备上重建；
+// 指定设备被移除（如拔出耳机）时回退到系统默认设备（返回 nil）
+func switchTarget(deviceType malgo.DeviceType, current *malgo.DeviceID, change DeviceChange) (*malgo.DeviceID, bool) {
+	if change.DeviceType != deviceType {
+		return nil, false
+	}
+	if current == nil {
+		return nil, change.Type == DeviceDefaultChanged
+	}
+	if change.Type == DeviceRemoved && change.Device.ID == *current {
+		return nil, true
+	}
+	return current, false
+}
@@ -0,0 +1,139 @@
+package devices
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gen2brain/malgo"
+)
+
+func testDevice(id byte, name string, isDefault bool) DeviceInfo {
+	var deviceID malgo.DeviceID
+	deviceID[0] = id
+	return DeviceInfo{ID: deviceID, Name: name, IsDefault: isDefault}
+}
+
+func TestMatchDevice(t *testing.T) {
+	list := []DeviceInfo{
+		testDevice(1, "Built-in Speakers", true),
+		testDevice(2, "USB Headset", false),
+		testDevice(3, "HDMI Output", false),
+	}
+
+	cases := []struct {
+		selector string
+		want     string
+	}{
+		{"", "Built-in Speakers"},
+		{"default", "Built-in Speakers"},
+		{list[2].ID.String(), "HDMI Output"},
+		{"#1", "USB Headset"},
+		{"USB Headset", "USB Headset"},
+		{"headset", "USB Headset"},
+	}
+	for _, c := range cases {
+		device, err := MatchDevice(list, c.selector)
+		if err != nil {
+			t.Fatalf("selector %q: %v", c.selector, err)
+		}
+		if device.Name != c.want {
+			t.Errorf("selector %q: got %s, want %s", c.selector, device.Name, c.want)
+		}
+	}
+
+	if _, err := MatchDevice(list, "bluetooth"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound, got %v", err)
+	}
+	if _, err := MatchDevice(list, "#9"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound for out of range index, got %v", err)
+	}
+}
+
+func TestDiffDevices(t *testing.T) {
+	speakers := testDevice(1, "Built-in Speakers", true)
+	headset := testDevice(2, "USB Headset", false)
+
+	if changes := diffDevices(malgo.Playback, []DeviceInfo{speakers}, []DeviceInfo{speakers}); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+
+	// 插入耳机并成为默认设备
+	headsetDefault := headset
+	headsetDefault.IsDefault = true
+	speakersNotDefault := speakers
+	speakersNotDefault.IsDefault = false
+	changes := diffDevices(malgo.Playback, []DeviceInfo{speakers}, []DeviceInfo{speakersNotDefault, headsetDefault})
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+	if changes[0].Type != DeviceAdded || changes[0].Device.Name != "USB Headset" {
+		t.Errorf("unexpected change: %+v", changes[0])
+	}
+	if changes[1].Type != DeviceDefaultChanged || changes[1].DeviceType != malgo.Playback {
+		t.Errorf("unexpected change: %+v", changes[1])
+	}
+
+	// 拔出耳机，默认设备回到扬声器
+	changes = diffDevices(malgo.Playback, []DeviceInfo{speakersNotDefault, headsetDefault}, []DeviceInfo{speakers})
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+	if changes[0].Type != DeviceRemoved || changes[0].Device.Name != "USB Headset" {
+		t.Errorf("unexpected change: %+v", changes[0])
+	}
+	if changes[1].Type != DeviceDefaultChanged || changes[1].Device.Name != "Built-in Speakers" {
+		t.Errorf("unexpected change: %+v", changes[1])
+	}
+}
+
+func TestSwitchTarget(t *testing.T) {
+	headset := testDevice(2, "USB Headset", false)
+	speakers := testDevice(1, "Built-in Speakers", true)
+	selected := headset.ID
+
+	cases := []struct {
+		name       string
+		current    *malgo.DeviceID
+		change     DeviceChange
+		want       *malgo.DeviceID
+		wantSwitch bool
+	}{
+		{"selected removed", &selected, DeviceChange{Type: DeviceRemoved, DeviceType: malgo.Playback, Device: headset}, nil, true},
+		{"other removed", &selected, DeviceChange{Type: DeviceRemoved, DeviceType: malgo.Playback, Device: speakers}, &selected, false},
+		{"other device type", &selected, DeviceChange{Type: DeviceRemoved, DeviceType: malgo.Capture, Device: headset}, nil, false},
+		{"selected ignores default change", &selected, DeviceChange{Type: DeviceDefaultChanged, DeviceType: malgo.Playback, Device: speakers}, &selected, false},
+		{"default follows default change", nil, DeviceChange{Type: DeviceDefaultChanged, DeviceType: malgo.Playback, Device: speakers}, nil, true},
+		{"default ignores added", nil, DeviceChange{Type: DeviceAdded, DeviceType: malgo.Playback, Device: headset}, nil, false},
+	}
+	for _, c := range cases {
+		target, ok := switchTarget(malgo.Playback, c.current, c.change)
+		if ok != c.wantSwitch {
+			t.Errorf("%s: switch = %v, want %v", c.name, ok, c.wantSwitch)
+		}
+		if ok && target != c.want {
+			t.Errorf("%s: target = %v, want %v", c.name, target, c.want)
+		}
+	}
+}
+
+func TestStreamDeviceID(t *testing.T) {
+	config := StreamConfig{}
+	deviceConfig := config.asDeviceConfig(malgo.Capture)
+	if streamDeviceID(deviceConfig) != nil {
+		t.Fatal("expected default device")
+	}
+
+	headset := testDevice(2, "USB Headset", false)
+	setStreamDeviceID(&deviceConfig, &headset.ID)
+	if id := streamDeviceID(deviceConfig); id == nil || *id != headset.ID {
+		t.Fatalf("got %v, want %s", id, headset.ID.String())
+	}
+	if deviceConfig.Playback.DeviceID != nil {
+		t.Error("playback device should stay unset for a capture stream")
+	}
+
+	setStreamDeviceID(&deviceConfig, nil)
+	if streamDeviceID(deviceConfig) != nil {
+		t.Error("expected default device after reset")
+	}
+}
@@ -1,23 +1,50 @@
 package devices
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"github.com/gen2brain/malgo"
+	"go.uber.org/zap"
+)
+
+// ErrBufferFull 播放缓冲区已满，本次写入的数据被丢弃
+var ErrBufferFull = errors.New("audio buffer full")
+
+// OverflowPolicy 缓冲区已满时 Write 的处理方式
+type OverflowPolicy int
+
+const (
+	// OverflowReject 拒绝新数据并返回 ErrBufferFull（默认）
+	OverflowReject OverflowPolicy = iota
+	// OverflowDropOldest 丢弃最早排队的数据为新数据腾出空间，适合实时语音：宁可跳过旧音频也不累积延迟
+	OverflowDropOldest
 )
 
 // StreamAudioPlayer 用于流式播放音频数据的播放器
 type StreamAudioPlayer struct {
-	ctx         *malgo.AllocatedContext
-	device      *malgo.Device
+	ctx       *malgo.AllocatedContext
+	device    *malgo.Device
+	callbacks malgo.DeviceCallbacks
+	// lifecycle 保护 device 的创建与销毁；与回调使用的 mu 分开，避免 Uninit 等待回调退出时死锁
+	lifecycle   sync.Mutex
 	channels    uint32
 	sampleRate  uint32
 	audioBuffer chan []byte
 	format      malgo.FormatType
+	// 播放设备，nil 表示使用系统默认设备
+	deviceID *malgo.DeviceID
 	// 内部缓冲区，用于平滑数据流
 	internalBuffer []byte
 	mu             sync.RWMutex
+
+	overflow    atomic.Int32 // OverflowPolicy
+	queuedBytes atomic.Int64 // audioBuffer 中排队的字节数
+	dropped     atomic.Uint64
 }
 
 // NewStreamAudioPlayer 创建流式音频播放器
@@ -25,8 +52,14 @@ type StreamAudioPlayer struct {
 // sampleRate: 采样率（如 8000, 16000, 48000）
 // format: 音频格式（malgo.FormatS16 表示 16-bit signed integer）
 func NewStreamAudioPlayer(channels uint32, sampleRate uint32, format malgo.FormatType) (*StreamAudioPlayer, error) {
+	return NewStreamAudioPlayerWithDevice(channels, sampleRate, format, nil)
+}
+
+// NewStreamAudioPlayerWithDevice 创建使用指定播放设备的流式音频播放器
+// deviceID 为 nil 时使用系统默认设备，可通过 ListPlaybackDevices / FindPlaybackDevice 获取
+func NewStreamAudioPlayerWithDevice(channels uint32, sampleRate uint32, format malgo.FormatType, deviceID *malgo.DeviceID) (*StreamAudioPlayer, error) {
 	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, func(message string) {
-		fmt.Printf("<%v>\n", message)
+		logger.Debug("malgo", zap.String("message", message))
 	})
 	if err != nil {
 		return nil, err
@@ -40,6 +73,7 @@ func NewStreamAudioPlayer(channels uint32, sampleRate uint32, format malgo.Forma
 		channels:       channels,
 		sampleRate:     sampleRate,
 		format:         format,
+		deviceID:       deviceID,
 		audioBuffer:    make(chan []byte, bufferSize),
 		internalBuffer: make([]byte, 0, 8192), // 预分配内部缓冲区
 	}
@@ -47,14 +81,66 @@ func NewStreamAudioPlayer(channels uint32, sampleRate uint32, format malgo.Forma
 	return player, nil
 }
 
+// SelectDevice 选择播放设备，选择器规则见 MatchDevice；播放过程中调用会切换到新设备
+func (p *StreamAudioPlayer) SelectDevice(selector string) (*DeviceInfo, error) {
+	device, err := FindPlaybackDevice(p.ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	id := device.ID
+	if err := p.SwitchDevice(&id); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// SwitchDevice 切换播放设备，nil 表示系统默认设备
+// 未开始播放时仅记录设备；播放中则在新设备上重建输出，已排队的音频保留并继续播放
+func (p *StreamAudioPlayer) SwitchDevice(deviceID *malgo.DeviceID) error {
+	p.lifecycle.Lock()
+	defer p.lifecycle.Unlock()
+
+	p.mu.Lock()
+	p.deviceID = deviceID
+	p.mu.Unlock()
+
+	if p.device == nil {
+		return nil
+	}
+	p.device.Uninit()
+	p.device = nil
+
+	device, err := p.startDevice()
+	if err != nil {
+		return err
+	}
+	p.device = device
+	return nil
+}
+
+// FollowDevices 监听设备热插拔并自动切换播放设备，直到 ctx 结束
+// 指定设备被移除时回退到系统默认设备；使用默认设备时跟随系统默认设备的切换
+func (p *StreamAudioPlayer) FollowDevices(ctx context.Context, interval time.Duration) error {
+	return WatchDevices(ctx, p.ctx, interval, func(change DeviceChange) {
+		p.mu.RLock()
+		current := p.deviceID
+		p.mu.RUnlock()
+
+		target, ok := switchTarget(malgo.Playback, current, change)
+		if !ok {
+			return
+		}
+		logger.Info("devices: switching playback device",
+			zap.String("change", string(change.Type)),
+			zap.String("device", change.Device.Name))
+		if err := p.SwitchDevice(target); err != nil {
+			logger.Warn("devices: failed to switch playback device", zap.Error(err))
+		}
+	})
+}
+
 // Play 开始播放音频流
 func (p *StreamAudioPlayer) Play() error {
-	deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
-	deviceConfig.Playback.Format = p.format
-	deviceConfig.Playback.Channels = p.channels
-	deviceConfig.SampleRate = p.sampleRate
-	deviceConfig.Alsa.NoMMap = 1
-
 	// 计算每帧的字节数
 	bytesPerSample := 2 // FormatS16 = 2 bytes per sample
 	bytesPerFrame := bytesPerSample * int(p.channels)
@@ -71,6 +157,7 @@ func (p *StreamAudioPlayer) Play() error {
 		for len(p.internalBuffer) < bytesNeeded {
 			select {
 			case data := <-p.audioBuffer:
+				p.queuedBytes.Add(-int64(len(data)))
 				if len(data) > 0 {
 					p.internalBuffer = append(p.internalBuffer, data...)
 				}
@@ -115,32 +202,118 @@ func (p *StreamAudioPlayer) Play() error {
 		}
 	}
 
-	deviceCallbacks := malgo.DeviceCallbacks{
+	p.lifecycle.Lock()
+	defer p.lifecycle.Unlock()
+
+	p.callbacks = malgo.DeviceCallbacks{
 		Data: onSamples,
 	}
-
-	var err error
-	p.device, err = malgo.InitDevice(p.ctx.Context, deviceConfig, deviceCallbacks)
+	device, err := p.startDevice()
 	if err != nil {
 		return err
 	}
+	p.device = device
+	return nil
+}
+
+// startDevice 在当前选定的设备上创建并启动播放设备，调用方需持有 lifecycle
+func (p *StreamAudioPlayer) startDevice() (*malgo.Device, error) {
+	deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
+	deviceConfig.Playback.Format = p.format
+	deviceConfig.Playback.Channels = p.channels
+	deviceConfig.SampleRate = p.sampleRate
+	deviceConfig.Alsa.NoMMap = 1
+	p.mu.RLock()
+	if p.deviceID != nil {
+		deviceConfig.Playback.DeviceID = p.deviceID.Pointer()
+	}
+	p.mu.RUnlock()
 
-	err = p.device.Start()
+	device, err := malgo.InitDevice(p.ctx.Context, deviceConfig, p.callbacks)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := device.Start(); err != nil {
+		device.Uninit()
+		return nil, err
 	}
+	return device, nil
+}
 
-	return nil
+// SetOverflowPolicy 设置缓冲区已满时 Write 的处理方式
+func (p *StreamAudioPlayer) SetOverflowPolicy(policy OverflowPolicy) {
+	p.overflow.Store(int32(policy))
 }
 
-// Write 写入音频数据到播放缓冲区
+// Write 写入音频数据到播放缓冲区，缓冲区已满时按 OverflowPolicy 处理，
+// OverflowReject 下返回 ErrBufferFull
 func (p *StreamAudioPlayer) Write(data []byte) error {
+	for {
+		select {
+		case p.audioBuffer <- data:
+			p.queuedBytes.Add(int64(len(data)))
+			return nil
+		default:
+		}
+		if OverflowPolicy(p.overflow.Load()) != OverflowDropOldest {
+			return ErrBufferFull
+		}
+		// 播放回调可能同时取走数据，丢弃一块后重试
+		select {
+		case old := <-p.audioBuffer:
+			p.queuedBytes.Add(-int64(len(old)))
+			p.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// WriteWithTimeout 写入音频数据，缓冲区已满时最多等待 timeout 让播放腾出空间，
+// 超时返回 ErrBufferFull。用于需要背压而不是丢弃数据的生产者
+func (p *StreamAudioPlayer) WriteWithTimeout(data []byte, timeout time.Duration) error {
 	select {
 	case p.audioBuffer <- data:
+		p.queuedBytes.Add(int64(len(data)))
 		return nil
 	default:
-		return fmt.Errorf("音频缓冲区已满")
 	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case p.audioBuffer <- data:
+		p.queuedBytes.Add(int64(len(data)))
+		return nil
+	case <-timer.C:
+		return ErrBufferFull
+	}
+}
+
+// FreeSpace 返回缓冲区还能无阻塞接收的写入次数（每次 Write 占一个槽位）
+func (p *StreamAudioPlayer) FreeSpace() int {
+	return cap(p.audioBuffer) - len(p.audioBuffer)
+}
+
+// BufferedBytes 返回已写入但尚未播放的字节数
+func (p *StreamAudioPlayer) BufferedBytes() int {
+	p.mu.RLock()
+	internal := len(p.internalBuffer)
+	p.mu.RUnlock()
+	return int(p.queuedBytes.Load()) + internal
+}
+
+// BufferedDuration 返回已写入但尚未播放的音频时长
+func (p *StreamAudioPlayer) BufferedDuration() time.Duration {
+	bytesPerSecond := int64(p.sampleRate) * int64(p.channels) * int64(malgo.SampleSizeInBytes(p.format))
+	if bytesPerSecond == 0 {
+		return 0
+	}
+	return time.Duration(int64(p.BufferedBytes()) * int64(time.Second) / bytesPerSecond)
+}
+
+// Dropped 返回 OverflowDropOldest 策略下丢弃的数据块数
+func (p *StreamAudioPlayer) Dropped() uint64 {
+	return p.dropped.Load()
 }
 
 // ClearBuffer 清空播放缓冲区，用于防止音频重复/回声
@@ -154,8 +327,9 @@ func (p *StreamAudioPlayer) ClearBuffer() {
 	// 清空channel中的数据
 	for {
 		select {
-		case <-p.audioBuffer:
+		case data := <-p.audioBuffer:
 			// 丢弃数据
+			p.queuedBytes.Add(-int64(len(data)))
 		default:
 			return
 		}
@@ -164,9 +338,12 @@ func (p *StreamAudioPlayer) ClearBuffer() {
 
 // Close 关闭流式播放器
 func (p *StreamAudioPlayer) Close() {
+	p.lifecycle.Lock()
 	if p.device != nil {
 		p.device.Uninit()
+		p.device = nil
 	}
+	p.lifecycle.Unlock()
 	if p.ctx != nil {
 		p.ctx.Uninit()
 		p.ctx.Free()
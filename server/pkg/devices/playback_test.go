@@ -0,0 +1,93 @@
+package devices
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gen2brain/malgo"
+)
+
+func newTestPlayer(slots int) *StreamAudioPlayer {
+	return &StreamAudioPlayer{
+		channels:    1,
+		sampleRate:  8000,
+		format:      malgo.FormatS16,
+		audioBuffer: make(chan []byte, slots),
+	}
+}
+
+func TestStreamAudioPlayerWriteBufferFull(t *testing.T) {
+	p := newTestPlayer(1)
+	if err := p.Write([]byte{1, 2}); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	if err := p.Write([]byte{3, 4}); !errors.Is(err, ErrBufferFull) {
+		t.Errorf("expected ErrBufferFull, got %v", err)
+	}
+}
+
+func TestStreamAudioPlayerOccupancy(t *testing.T) {
+	p := newTestPlayer(4)
+	if p.FreeSpace() != 4 || p.BufferedDuration() != 0 {
+		t.Fatalf("unexpected empty state: free=%d duration=%v", p.FreeSpace(), p.BufferedDuration())
+	}
+
+	// 8kHz 16-bit 单声道：320 字节 = 20ms
+	for i := 0; i < 3; i++ {
+		if err := p.Write(make([]byte, 320)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p.FreeSpace() != 1 {
+		t.Errorf("expected 1 free slot, got %d", p.FreeSpace())
+	}
+	if got := p.BufferedDuration(); got != 60*time.Millisecond {
+		t.Errorf("expected 60ms buffered, got %v", got)
+	}
+
+	p.ClearBuffer()
+	if p.BufferedBytes() != 0 || p.FreeSpace() != 4 {
+		t.Errorf("expected empty buffer after clear, got %d bytes", p.BufferedBytes())
+	}
+}
+
+func TestStreamAudioPlayerWriteWithTimeout(t *testing.T) {
+	p := newTestPlayer(1)
+	if err := p.WriteWithTimeout([]byte{1, 2}, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := p.WriteWithTimeout([]byte{3, 4}, 20*time.Millisecond); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull, got %v", err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("WriteWithTimeout returned before the timeout")
+	}
+
+	// 消费者腾出空间后写入成功
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-p.audioBuffer
+	}()
+	if err := p.WriteWithTimeout([]byte{5, 6}, time.Second); err != nil {
+		t.Fatalf("expected write to succeed once space is freed, got %v", err)
+	}
+}
+
+func TestStreamAudioPlayerDropOldest(t *testing.T) {
+	p := newTestPlayer(2)
+	p.SetOverflowPolicy(OverflowDropOldest)
+	for i := byte(1); i <= 3; i++ {
+		if err := p.Write([]byte{i, i}); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	if p.Dropped() != 1 {
+		t.Errorf("expected 1 dropped chunk, got %d", p.Dropped())
+	}
+	if first := <-p.audioBuffer; first[0] != 2 {
+		t.Errorf("expected oldest chunk to be dropped, got %v first", first)
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"io"
 	"sync"
+	"time"
+	"unsafe"
 
+	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"github.com/gen2brain/malgo"
+	"go.uber.org/zap"
 )
 
 // StreamContext 流式音频操作的上下文
@@ -55,6 +59,51 @@ func (sc *StreamContext) GetContext() *malgo.AllocatedContext {
 	return sc.ctx
 }
 
+// ListCaptureDevices 列出当前上下文中的捕获设备
+func (sc *StreamContext) ListCaptureDevices() ([]DeviceInfo, error) {
+	return ListCaptureDevices(sc.GetContext())
+}
+
+// ListPlaybackDevices 列出当前上下文中的播放设备
+func (sc *StreamContext) ListPlaybackDevices() ([]DeviceInfo, error) {
+	return ListPlaybackDevices(sc.GetContext())
+}
+
+// SelectCaptureDevice 选择后续 Capture 使用的设备，选择器规则见 MatchDevice
+func (sc *StreamContext) SelectCaptureDevice(selector string) (*DeviceInfo, error) {
+	device, err := FindCaptureDevice(sc.GetContext(), selector)
+	if err != nil {
+		return nil, err
+	}
+	id := device.ID
+	sc.mu.Lock()
+	sc.config.CaptureDeviceID = &id
+	sc.mu.Unlock()
+	return device, nil
+}
+
+// SelectPlaybackDevice 选择后续 Playback 使用的设备，选择器规则见 MatchDevice
+func (sc *StreamContext) SelectPlaybackDevice(selector string) (*DeviceInfo, error) {
+	device, err := FindPlaybackDevice(sc.GetContext(), selector)
+	if err != nil {
+		return nil, err
+	}
+	id := device.ID
+	sc.mu.Lock()
+	sc.config.PlaybackDeviceID = &id
+	sc.mu.Unlock()
+	return device, nil
+}
+
+// WatchDevices 监听设备热插拔，直到 ctx 结束，详见包级函数 WatchDevices
+func (sc *StreamContext) WatchDevices(ctx context.Context, interval time.Duration, onChange func(DeviceChange)) error {
+	malgoCtx := sc.GetContext()
+	if malgoCtx == nil {
+		return io.ErrClosedPipe
+	}
+	return WatchDevices(ctx, malgoCtx, interval, onChange)
+}
+
 // Capture 将传入的样本录制到提供的 writer 中
 // 该函数在默认上下文中初始化一个捕获设备，使用提供的流配置
 // 录制将持续将样本写入 writer，直到 writer 返回错误或 context 信号完成
@@ -153,9 +202,12 @@ func (sc *StreamContext) Playback(ctx context.Context, r io.Reader) error {
 }
 
 // stream 内部流处理函数
+// 开启 FollowDevices 时，所用设备被移除或默认设备切换后会在新的设备上重建流
 func (sc *StreamContext) stream(ctx context.Context, abortChan chan error, deviceConfig malgo.DeviceConfig, deviceCallbacks malgo.DeviceCallbacks) error {
 	sc.mu.RLock()
 	malgoCtx := sc.ctx
+	follow := sc.config.FollowDevices
+	interval := sc.config.WatchInterval
 	sc.mu.RUnlock()
 
 	if malgoCtx == nil {
@@ -166,25 +218,82 @@ func (sc *StreamContext) stream(ctx context.Context, abortChan chan error, devic
 	if err != nil {
 		return err
 	}
-	defer device.Uninit()
+	defer func() {
+		if device != nil {
+			device.Uninit()
+		}
+	}()
 
 	err = device.Start()
 	if err != nil {
 		return err
 	}
 
-	ctxChan := ctx.Done()
-	if ctxChan != nil {
+	var switchChan chan *malgo.DeviceID
+	if follow {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		switchChan = make(chan *malgo.DeviceID, 1)
+		current := streamDeviceID(deviceConfig)
+		go WatchDevices(watchCtx, malgoCtx, interval, func(change DeviceChange) {
+			target, ok := switchTarget(deviceConfig.DeviceType, current, change)
+			if !ok {
+				return
+			}
+			current = target
+			select {
+			case switchChan <- target:
+			case <-watchCtx.Done():
+			}
+		})
+	}
+
+	for {
 		select {
-		case <-ctxChan:
-			err = ctx.Err()
+		case <-ctx.Done():
+			return ctx.Err()
 		case err = <-abortChan:
+			return err
+		case target := <-switchChan:
+			device.Uninit()
+			setStreamDeviceID(&deviceConfig, target)
+			device, err = malgo.InitDevice(malgoCtx.Context, deviceConfig, deviceCallbacks)
+			if err != nil {
+				device = nil
+				return err
+			}
+			if err = device.Start(); err != nil {
+				return err
+			}
+			logger.Info("devices: stream switched device", zap.Bool("default", target == nil))
 		}
-	} else {
-		err = <-abortChan
 	}
+}
 
-	return err
+// streamDeviceID 返回设备配置中选定的设备，nil 表示系统默认设备
+func streamDeviceID(deviceConfig malgo.DeviceConfig) *malgo.DeviceID {
+	pointer := deviceConfig.Playback.DeviceID
+	if deviceConfig.DeviceType == malgo.Capture {
+		pointer = deviceConfig.Capture.DeviceID
+	}
+	if pointer == nil {
+		return nil
+	}
+	id := *(*malgo.DeviceID)(pointer)
+	return &id
+}
+
+// setStreamDeviceID 替换设备配置中选定的设备，nil 表示系统默认设备
+func setStreamDeviceID(deviceConfig *malgo.DeviceConfig, id *malgo.DeviceID) {
+	var pointer unsafe.Pointer
+	if id != nil {
+		pointer = id.Pointer()
+	}
+	if deviceConfig.DeviceType == malgo.Capture {
+		deviceConfig.Capture.DeviceID = pointer
+	} else {
+		deviceConfig.Playback.DeviceID = pointer
+	}
 }
 
 // CaptureToWriter 便捷函数：使用默认上下文进行录制
@@ -1,6 +1,10 @@
 package devices
 
-import "github.com/gen2brain/malgo"
+import (
+	"time"
+
+	"github.com/gen2brain/malgo"
+)
 
 // StreamConfig 描述音频流的参数
 // 默认值将使用默认设备的默认值
@@ -13,6 +17,14 @@ type StreamConfig struct {
 	SampleRate int
 	// AlsaNoMMap ALSA NoMMap 设置，默认 1
 	AlsaNoMMap uint32
+	// CaptureDeviceID 捕获设备，nil 表示使用系统默认设备
+	CaptureDeviceID *malgo.DeviceID
+	// PlaybackDeviceID 播放设备，nil 表示使用系统默认设备
+	PlaybackDeviceID *malgo.DeviceID
+	// FollowDevices 为 true 时监听设备热插拔：所选设备被移除时切换到系统默认设备继续录制/播放
+	FollowDevices bool
+	// WatchInterval 设备热插拔检测的轮询间隔，0 表示使用 DefaultWatchInterval
+	WatchInterval time.Duration
 }
 
 // DefaultStreamConfig 返回默认的流配置
@@ -47,5 +59,12 @@ func (config StreamConfig) asDeviceConfig(deviceType malgo.DeviceType) malgo.Dev
 		deviceConfig.Alsa.NoMMap = config.AlsaNoMMap
 	}
 
+	if config.CaptureDeviceID != nil {
+		deviceConfig.Capture.DeviceID = config.CaptureDeviceID.Pointer()
+	}
+	if config.PlaybackDeviceID != nil {
+		deviceConfig.Playback.DeviceID = config.PlaybackDeviceID.Pointer()
+	}
+
 	return deviceConfig
 }
@@ -0,0 +1,504 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+var errReadOnlyTx = errors.New("graph: write in read-only transaction")
+
+// 关系类型
+const (
+	relHasConversation = "HAS_CONVERSATION"
+	relWithAssistant   = "WITH_ASSISTANT"
+	relHasTurn         = "HAS_TURN"
+	relDiscusses       = "DISCUSSES"
+	relLikes           = "LIKES"
+	relHasIntent       = "HAS_INTENT"
+	relHasKnowledge    = "HAS_KNOWLEDGE"
+	relRelatedTo       = "RELATED_TO"
+	relMentions        = "MENTIONS"
+	relMentioned       = "MENTIONED"
+	relRelates         = "RELATES"
+)
+
+// edgeNode 边表模型中的节点，ID 形如 Label_key，与 GraphNode.ID 一致
+type edgeNode struct {
+	ID    string
+	Label string
+	Props map[string]interface{}
+}
+
+// edgeKey 唯一确定一条边，Name 区分同一对节点间的同类型边（如 RELATES 的关系名）
+type edgeKey struct {
+	From string
+	Type string
+	Name string
+	To   string
+}
+
+// edgeRecord 边表模型中的边，偏好类的边带有权重
+type edgeRecord struct {
+	edgeKey
+	Weight      float64
+	LastSession string // 最近一次累加权重的会话，同一会话重复处理时不再累加
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	DecayedAt   time.Time
+}
+
+// edgeFilter 边查询条件，空字段不参与过滤
+type edgeFilter struct {
+	From  string
+	To    string
+	Types []string
+}
+
+// edgeTx 边表模型上的一次事务
+type edgeTx interface {
+	// getNode 获取节点，不存在时返回 nil
+	getNode(id string) (*edgeNode, error)
+	putNode(node *edgeNode) error
+	// getEdge 获取边，不存在时返回 nil
+	getEdge(key edgeKey) (*edgeRecord, error)
+	putEdge(edge *edgeRecord) error
+	deleteEdge(key edgeKey) error
+	edges(filter edgeFilter) ([]*edgeRecord, error)
+}
+
+// edgeBackend 边表模型的存储后端
+type edgeBackend interface {
+	update(ctx context.Context, fn func(tx edgeTx) error) error
+	view(ctx context.Context, fn func(tx edgeTx) error) error
+	close() error
+}
+
+// EdgeStore 基于节点表与边表实现的图存储，适合不部署 Neo4j 的小型服务器，
+// 数据可保存在内存（NewMemoryStore）或 SQLite（NewSQLiteStore）中
+type EdgeStore struct {
+	backend edgeBackend
+	now     func() time.Time
+}
+
+func newEdgeStore(backend edgeBackend) *EdgeStore {
+	return &EdgeStore{backend: backend, now: time.Now}
+}
+
+func nodeID(label string, key interface{}) string {
+	return fmt.Sprintf("%s_%v", label, key)
+}
+
+// ProcessConversation 处理对话记录，写入与 Neo4jStore 相同结构的节点和关系
+func (s *EdgeStore) ProcessConversation(ctx context.Context, assistantID int64, sessionID string, summary *ConversationSummary) error {
+	now := s.now()
+	w := &edgeWriter{now: now, ts: now.Format(time.RFC3339), sessionID: sessionID}
+
+	err := s.backend.update(ctx, func(tx edgeTx) error {
+		w.tx = tx
+		assistant := nodeID("Assistant", assistantID)
+		user := nodeID("User", summary.UserID)
+		conversation := nodeID("Conversation", sessionID)
+
+		// 1. Assistant、User 与 Conversation 节点
+		w.mergeNode(assistant, "Assistant", map[string]interface{}{
+			"id":   assistantID,
+			"name": summary.AssistantName,
+		})
+		w.mergeNode(user, "User", map[string]interface{}{
+			"id": summary.UserID,
+		})
+		w.mergeNode(conversation, "Conversation", map[string]interface{}{
+			"sessionID":   sessionID,
+			"assistantID": assistantID,
+			"userID":      summary.UserID,
+			"summary":     summary.Summary,
+			"topics":      summary.Topics,
+			"intents":     summary.Intents,
+		})
+		w.link(user, relHasConversation, "", conversation)
+		w.link(conversation, relWithAssistant, "", assistant)
+
+		// 2. Turn 节点（对话轮次）
+		for i, turn := range summary.Turns {
+			turnNode := nodeID("Turn", fmt.Sprintf("%s_turn_%d", sessionID, i))
+			w.mergeNode(turnNode, "Turn", map[string]interface{}{
+				"userMessage":  turn.UserMessage,
+				"agentMessage": turn.AgentMessage,
+				"sequence":     i,
+			})
+			w.link(conversation, relHasTurn, "", turnNode)
+		}
+
+		// 3. Topic 节点与用户偏好
+		for _, topic := range summary.Topics {
+			topicNode := nodeID("Topic", topic)
+			w.mergeNode(topicNode, "Topic", map[string]interface{}{"name": topic})
+			w.link(conversation, relDiscusses, "", topicNode)
+			w.bump(user, relLikes, "", topicNode)
+		}
+
+		// 4. Intent 节点
+		for _, intent := range summary.Intents {
+			intentNode := nodeID("Intent", intent)
+			w.mergeNode(intentNode, "Intent", map[string]interface{}{"name": intent})
+			w.link(conversation, relHasIntent, "", intentNode)
+		}
+
+		// 5. Knowledge 节点，只关联已存在的主题
+		for _, knowledge := range summary.Knowledge {
+			id := knowledgeNodeID(assistantID, knowledge.Content)
+			knowledgeNode := nodeID("Knowledge", id)
+			w.mergeNode(knowledgeNode, "Knowledge", map[string]interface{}{
+				"id":       id,
+				"content":  knowledge.Content,
+				"category": knowledge.Category,
+				"source":   knowledge.Source,
+			})
+			w.link(assistant, relHasKnowledge, "", knowledgeNode)
+			for _, topic := range knowledge.RelatedTopics {
+				topicNode := nodeID("Topic", topic)
+				if node, err := tx.getNode(topicNode); err == nil && node != nil {
+					w.link(knowledgeNode, relRelatedTo, "", topicNode)
+				}
+			}
+		}
+
+		// 6. Entity 节点与实体关系
+		for _, entity := range summary.Entities {
+			entityNode := nodeID("Entity", entity.Name)
+			props := map[string]interface{}{"name": entity.Name}
+			if entity.Type != "" {
+				props["type"] = entity.Type
+			}
+			w.mergeNode(entityNode, "Entity", props)
+			w.link(conversation, relMentions, "", entityNode)
+			w.bump(user, relMentioned, "", entityNode)
+		}
+		for _, relation := range summary.Relations {
+			w.bump(nodeID("Entity", relation.Source), relRelates, relation.Relation, nodeID("Entity", relation.Target))
+		}
+		return w.err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to process conversation: %w", err)
+	}
+	return nil
+}
+
+// edgeWriter 在一次事务中合并节点和边，记录第一个错误
+type edgeWriter struct {
+	tx        edgeTx
+	now       time.Time
+	ts        string
+	sessionID string
+	err       error
+}
+
+// mergeNode 创建或更新节点属性，首次创建时记录 createdAt
+func (w *edgeWriter) mergeNode(id, label string, props map[string]interface{}) {
+	if w.err != nil {
+		return
+	}
+	node, err := w.tx.getNode(id)
+	if err != nil {
+		w.err = err
+		return
+	}
+	if node == nil {
+		node = &edgeNode{ID: id, Label: label, Props: map[string]interface{}{"createdAt": w.ts}}
+	}
+	for k, v := range props {
+		node.Props[k] = v
+	}
+	node.Props["updatedAt"] = w.ts
+	w.err = w.tx.putNode(node)
+}
+
+// link 创建不带权重的边，已存在时不变
+func (w *edgeWriter) link(from, typ, name, to string) {
+	if w.err != nil {
+		return
+	}
+	key := edgeKey{From: from, Type: typ, Name: name, To: to}
+	edge, err := w.tx.getEdge(key)
+	if err != nil || edge != nil {
+		w.err = err
+		return
+	}
+	w.err = w.tx.putEdge(&edgeRecord{edgeKey: key, FirstSeen: w.now, LastSeen: w.now})
+}
+
+// bump 创建带权重的边或累加权重，同一会话只累加一次
+func (w *edgeWriter) bump(from, typ, name, to string) {
+	if w.err != nil {
+		return
+	}
+	key := edgeKey{From: from, Type: typ, Name: name, To: to}
+	edge, err := w.tx.getEdge(key)
+	if err != nil {
+		w.err = err
+		return
+	}
+	if edge == nil {
+		edge = &edgeRecord{edgeKey: key, Weight: 1, FirstSeen: w.now}
+	} else if edge.LastSession != w.sessionID {
+		edge.Weight++
+	}
+	edge.LastSeen = w.now
+	edge.LastSession = w.sessionID
+	w.err = w.tx.putEdge(edge)
+}
+
+// GetUserContext 获取用户权重最高的 20 个偏好主题
+func (s *EdgeStore) GetUserContext(ctx context.Context, userID uint, assistantID int64) (*UserContext, error) {
+	userContext := &UserContext{UserID: userID, AssistantID: assistantID, Topics: []string{}}
+	err := s.backend.view(ctx, func(tx edgeTx) error {
+		likes, err := tx.edges(edgeFilter{From: nodeID("User", userID), Types: []string{relLikes}})
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(likes, func(i, j int) bool { return likes[i].Weight > likes[j].Weight })
+		for _, like := range likes {
+			if like.Weight <= 0 {
+				continue
+			}
+			topic, err := tx.getNode(like.To)
+			if err != nil {
+				return err
+			}
+			if topic == nil {
+				continue
+			}
+			if name, ok := topic.Props["name"].(string); ok {
+				userContext.Topics = append(userContext.Topics, name)
+			}
+			if len(userContext.Topics) == 20 {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user context: %w", err)
+	}
+	return userContext, nil
+}
+
+// GetAssistantGraphData 获取助手的对话、用户、主题、意图与知识节点及其关系
+func (s *EdgeStore) GetAssistantGraphData(ctx context.Context, assistantID int64) (*AssistantGraphData, error) {
+	nodesMap := make(map[string]GraphNode)
+	edges := []GraphEdge{}
+	addNode := func(node *edgeNode, label string) {
+		nodesMap[node.ID] = GraphNode{ID: node.ID, Label: label, Type: node.Label, Props: node.Props}
+	}
+	addEdge := func(from, typ, to string) {
+		edges = append(edges, GraphEdge{
+			ID:     fmt.Sprintf("edge_%s_%d", typ, len(edges)),
+			Source: from,
+			Target: to,
+			Type:   typ,
+			Props:  map[string]interface{}{},
+		})
+	}
+
+	err := s.backend.view(ctx, func(tx edgeTx) error {
+		assistant := nodeID("Assistant", assistantID)
+		node, err := tx.getNode(assistant)
+		if err != nil {
+			return err
+		}
+		if node != nil {
+			name, _ := node.Props["name"].(string)
+			addNode(node, name)
+		}
+
+		// 对话及其用户、主题、意图
+		conversations, err := tx.edges(edgeFilter{To: assistant, Types: []string{relWithAssistant}})
+		if err != nil {
+			return err
+		}
+		for _, c := range conversations {
+			conversation, err := tx.getNode(c.From)
+			if err != nil {
+				return err
+			}
+			if conversation == nil {
+				continue
+			}
+			sessionID, _ := conversation.Props["sessionID"].(string)
+			label := sessionID
+			if prefix := prefixRunes(sessionID, 20); prefix != sessionID {
+				label = prefix + "..."
+			}
+			addNode(conversation, label)
+			addEdge(c.From, relWithAssistant, assistant)
+
+			users, err := tx.edges(edgeFilter{To: c.From, Types: []string{relHasConversation}})
+			if err != nil {
+				return err
+			}
+			for _, u := range users {
+				user, err := tx.getNode(u.From)
+				if err != nil {
+					return err
+				}
+				if user == nil {
+					continue
+				}
+				addNode(user, fmt.Sprintf("User %v", user.Props["id"]))
+				addEdge(u.From, relHasConversation, c.From)
+			}
+
+			related, err := tx.edges(edgeFilter{From: c.From, Types: []string{relDiscusses, relHasIntent}})
+			if err != nil {
+				return err
+			}
+			for _, r := range related {
+				target, err := tx.getNode(r.To)
+				if err != nil {
+					return err
+				}
+				if target == nil {
+					continue
+				}
+				name, _ := target.Props["name"].(string)
+				addNode(target, name)
+				addEdge(c.From, r.Type, r.To)
+			}
+		}
+
+		// 知识节点
+		knowledge, err := tx.edges(edgeFilter{From: assistant, Types: []string{relHasKnowledge}})
+		if err != nil {
+			return err
+		}
+		for _, k := range knowledge {
+			node, err := tx.getNode(k.To)
+			if err != nil {
+				return err
+			}
+			if node == nil {
+				continue
+			}
+			content, _ := node.Props["content"].(string)
+			addNode(node, prefixRunes(content, 30)+"...")
+			addEdge(assistant, relHasKnowledge, k.To)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assistant graph data: %w", err)
+	}
+
+	nodes := make([]GraphNode, 0, len(nodesMap))
+	for _, node := range nodesMap {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	stats := calculateGraphStats(nodes)
+	stats.TotalEdges = len(edges)
+	return &AssistantGraphData{
+		AssistantID: assistantID,
+		Nodes:       nodes,
+		Edges:       edges,
+		Stats:       stats,
+	}, nil
+}
+
+// DecayInterests 按半衰期衰减用户对主题与实体的偏好权重，删除低于 minWeight 的偏好
+func (s *EdgeStore) DecayInterests(ctx context.Context, halfLife time.Duration, minWeight float64) (int, error) {
+	if halfLife <= 0 {
+		return 0, fmt.Errorf("half life must be positive")
+	}
+	now := s.now()
+	removed := 0
+	err := s.backend.update(ctx, func(tx edgeTx) error {
+		interests, err := tx.edges(edgeFilter{Types: []string{relLikes, relMentioned}})
+		if err != nil {
+			return err
+		}
+		for _, edge := range interests {
+			since := edge.DecayedAt
+			if since.IsZero() {
+				since = edge.FirstSeen
+			}
+			if elapsed := now.Sub(since); !since.IsZero() && elapsed > 0 {
+				edge.Weight *= math.Pow(0.5, elapsed.Seconds()/halfLife.Seconds())
+			}
+			edge.DecayedAt = now
+			if edge.Weight < minWeight {
+				if err := tx.deleteEdge(edge.edgeKey); err != nil {
+					return err
+				}
+				removed++
+				continue
+			}
+			if err := tx.putEdge(edge); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to decay interests: %w", err)
+	}
+	return removed, nil
+}
+
+// Close 关闭存储
+func (s *EdgeStore) Close() error {
+	return s.backend.close()
+}
+
+// prefixRunes 返回 s 的前 n 个字符
+func prefixRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// match 判断边是否满足查询条件
+func (f edgeFilter) match(edge *edgeRecord) bool {
+	if f.From != "" && edge.From != f.From {
+		return false
+	}
+	if f.To != "" && edge.To != f.To {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, typ := range f.Types {
+		if edge.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// sortEdges 按创建时间排序，使查询结果的顺序与后端无关
+func sortEdges(edges []*edgeRecord) {
+	sort.SliceStable(edges, func(i, j int) bool {
+		if !edges[i].FirstSeen.Equal(edges[j].FirstSeen) {
+			return edges[i].FirstSeen.Before(edges[j].FirstSeen)
+		}
+		a, b := edges[i].edgeKey, edges[j].edgeKey
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.To < b.To
+	})
+}
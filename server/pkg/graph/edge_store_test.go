@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func edgeStores(t *testing.T) map[string]*EdgeStore {
+	sqliteStore, err := NewSQLiteStore(filepath.Join(t.TempDir(), "graph.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqliteStore.Close() })
+	return map[string]*EdgeStore{
+		StoreTypeMemory: NewMemoryStore(),
+		StoreTypeSQLite: sqliteStore,
+	}
+}
+
+func testSummary(sessionID string, topics ...string) *ConversationSummary {
+	return &ConversationSummary{
+		AssistantID:   1,
+		AssistantName: "客服助手",
+		UserID:        7,
+		SessionID:     sessionID,
+		Summary:       "用户咨询了订单问题",
+		Topics:        topics,
+		Intents:       []string{"解决问题"},
+		Turns:         []Turn{{UserMessage: "我的订单呢", AgentMessage: "正在为您查询"}},
+		Knowledge:     []Knowledge{{Content: "订单 48 小时内发货", Category: "事实", RelatedTopics: []string{"订单"}}},
+		Entities:      []Entity{{Name: "LingEcho", Type: "product"}, {Name: "顺丰", Type: "organization"}},
+		Relations:     []Relation{{Source: "LingEcho", Relation: "ships_with", Target: "顺丰"}},
+	}
+}
+
+func TestEdgeStoreProcessConversation(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range edgeStores(t) {
+		t.Run(name, func(t *testing.T) {
+			// 同一会话重复处理不重复累加权重
+			require.NoError(t, store.ProcessConversation(ctx, 1, "s1", testSummary("s1", "订单", "物流")))
+			require.NoError(t, store.ProcessConversation(ctx, 1, "s1", testSummary("s1", "订单", "物流")))
+			require.NoError(t, store.ProcessConversation(ctx, 1, "s2", testSummary("s2", "物流")))
+
+			userContext, err := store.GetUserContext(ctx, 7, 1)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"物流", "订单"}, userContext.Topics)
+
+			data, err := store.GetAssistantGraphData(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, 1, data.Stats.UsersCount)
+			assert.Equal(t, 2, data.Stats.ConversationsCount)
+			assert.Equal(t, 2, data.Stats.TopicsCount)
+			assert.Equal(t, 1, data.Stats.IntentsCount)
+			assert.Equal(t, 1, data.Stats.KnowledgeCount)
+			// 2 WITH_ASSISTANT + 2 HAS_CONVERSATION + 3 DISCUSSES + 2 HAS_INTENT + 1 HAS_KNOWLEDGE
+			assert.Equal(t, 10, data.Stats.TotalEdges)
+
+			empty, err := store.GetAssistantGraphData(ctx, 2)
+			require.NoError(t, err)
+			assert.Empty(t, empty.Nodes)
+		})
+	}
+}
+
+func TestEdgeStoreDecayInterests(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, store := range edgeStores(t) {
+		t.Run(name, func(t *testing.T) {
+			store.now = func() time.Time { return start }
+			require.NoError(t, store.ProcessConversation(ctx, 1, "s1", testSummary("s1", "订单")))
+			store.now = func() time.Time { return start.Add(24 * time.Hour) }
+			require.NoError(t, store.ProcessConversation(ctx, 1, "s2", testSummary("s2", "订单", "物流")))
+
+			// 两个半衰期后：订单 2 -> 0.5 保留，物流 1 -> 0.25 删除；实体 MENTIONED 2 -> 0.5 保留
+			store.now = func() time.Time { return start.Add(24*time.Hour + 60*24*time.Hour) }
+			removed, err := store.DecayInterests(ctx, 30*24*time.Hour, 0.3)
+			require.NoError(t, err)
+			assert.Equal(t, 1, removed)
+
+			userContext, err := store.GetUserContext(ctx, 7, 1)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"订单"}, userContext.Topics)
+
+			// 再次衰减只计算距上次衰减的时间
+			removed, err = store.DecayInterests(ctx, 30*24*time.Hour, 0.3)
+			require.NoError(t, err)
+			assert.Equal(t, 0, removed)
+
+			_, err = store.DecayInterests(ctx, 0, 0.3)
+			assert.Error(t, err)
+		})
+	}
+}
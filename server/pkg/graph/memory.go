@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// NewMemoryStore 创建内存图存储，数据不持久化，进程重启后丢失，适合开发与测试
+func NewMemoryStore() *EdgeStore {
+	return newEdgeStore(&memoryBackend{
+		nodes: make(map[string]*edgeNode),
+		edges: make(map[edgeKey]*edgeRecord),
+	})
+}
+
+// memoryBackend 内存存储后端，写事务先暂存修改，成功后一次性提交
+type memoryBackend struct {
+	mu    sync.RWMutex
+	nodes map[string]*edgeNode
+	edges map[edgeKey]*edgeRecord
+}
+
+func (b *memoryBackend) update(ctx context.Context, fn func(tx edgeTx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tx := &memoryTx{
+		backend:     b,
+		stagedNodes: make(map[string]*edgeNode),
+		stagedEdges: make(map[edgeKey]*edgeRecord),
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	for id, node := range tx.stagedNodes {
+		b.nodes[id] = node
+	}
+	for key, edge := range tx.stagedEdges {
+		if edge == nil {
+			delete(b.edges, key)
+		} else {
+			b.edges[key] = edge
+		}
+	}
+	return nil
+}
+
+func (b *memoryBackend) view(ctx context.Context, fn func(tx edgeTx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return fn(&memoryTx{backend: b, readOnly: true})
+}
+
+func (b *memoryBackend) close() error {
+	return nil
+}
+
+// memoryTx 内存事务，stagedNodes/stagedEdges 保存未提交的修改（nil 边表示删除）
+type memoryTx struct {
+	backend     *memoryBackend
+	readOnly    bool
+	stagedNodes map[string]*edgeNode
+	stagedEdges map[edgeKey]*edgeRecord
+}
+
+func (tx *memoryTx) getNode(id string) (*edgeNode, error) {
+	node, ok := tx.stagedNodes[id]
+	if !ok {
+		node = tx.backend.nodes[id]
+	}
+	if node == nil {
+		return nil, nil
+	}
+	return cloneNode(node), nil
+}
+
+func (tx *memoryTx) putNode(node *edgeNode) error {
+	if tx.readOnly {
+		return errReadOnlyTx
+	}
+	tx.stagedNodes[node.ID] = cloneNode(node)
+	return nil
+}
+
+func (tx *memoryTx) getEdge(key edgeKey) (*edgeRecord, error) {
+	edge, ok := tx.stagedEdges[key]
+	if !ok {
+		edge = tx.backend.edges[key]
+	}
+	if edge == nil {
+		return nil, nil
+	}
+	clone := *edge
+	return &clone, nil
+}
+
+func (tx *memoryTx) putEdge(edge *edgeRecord) error {
+	if tx.readOnly {
+		return errReadOnlyTx
+	}
+	clone := *edge
+	tx.stagedEdges[edge.edgeKey] = &clone
+	return nil
+}
+
+func (tx *memoryTx) deleteEdge(key edgeKey) error {
+	if tx.readOnly {
+		return errReadOnlyTx
+	}
+	tx.stagedEdges[key] = nil
+	return nil
+}
+
+func (tx *memoryTx) edges(filter edgeFilter) ([]*edgeRecord, error) {
+	var result []*edgeRecord
+	for key, edge := range tx.backend.edges {
+		if _, staged := tx.stagedEdges[key]; !staged && filter.match(edge) {
+			clone := *edge
+			result = append(result, &clone)
+		}
+	}
+	for _, edge := range tx.stagedEdges {
+		if edge != nil && filter.match(edge) {
+			clone := *edge
+			result = append(result, &clone)
+		}
+	}
+	sortEdges(result)
+	return result, nil
+}
+
+func cloneNode(node *edgeNode) *edgeNode {
+	props := make(map[string]interface{}, len(node.Props))
+	for k, v := range node.Props {
+		props[k] = v
+	}
+	return &edgeNode{ID: node.ID, Label: node.Label, Props: props}
+}
@@ -2,7 +2,10 @@ package graph
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/logger"
@@ -33,6 +36,10 @@ type Store interface {
 	// GetAssistantGraphData 获取助手在图数据库中的完整图数据
 	GetAssistantGraphData(ctx context.Context, assistantID int64) (*AssistantGraphData, error)
 
+	// DecayInterests 按半衰期衰减用户对主题与实体的偏好权重，删除权重低于 minWeight 的偏好，
+	// 返回删除的偏好数
+	DecayInterests(ctx context.Context, halfLife time.Duration, minWeight float64) (int, error)
+
 	// Close 关闭连接
 	Close() error
 }
@@ -108,15 +115,15 @@ func (s *Neo4jStore) ProcessConversation(ctx context.Context, assistantID int64,
 			MATCH (a:Assistant {id: $assistantID})
 			MATCH (u:User {id: $userID})
 			MERGE (c:Conversation {sessionID: $sessionID})
+			ON CREATE SET c.createdAt = datetime()
 			SET c.assistantID = $assistantID,
 			    c.userID = $userID,
 			    c.summary = $summary,
 			    c.topics = $topics,
 			    c.intents = $intents,
-			    c.createdAt = datetime(),
 			    c.updatedAt = datetime()
-			CREATE (u)-[:HAS_CONVERSATION]->(c)
-			CREATE (c)-[:WITH_ASSISTANT]->(a)
+			MERGE (u)-[:HAS_CONVERSATION]->(c)
+			MERGE (c)-[:WITH_ASSISTANT]->(a)
 			RETURN c`
 		_, err = tx.Run(ctx, conversationQuery, map[string]any{
 			"sessionID":   sessionID,
@@ -135,11 +142,11 @@ func (s *Neo4jStore) ProcessConversation(ctx context.Context, assistantID int64,
 			turnQuery := `
 				MATCH (c:Conversation {sessionID: $sessionID})
 				MERGE (t:Turn {id: $turnID})
+				ON CREATE SET t.createdAt = datetime()
 				SET t.userMessage = $userMessage,
 				    t.agentMessage = $agentMessage,
-				    t.sequence = $sequence,
-				    t.createdAt = datetime()
-				CREATE (c)-[:HAS_TURN]->(t)
+				    t.sequence = $sequence
+				MERGE (c)-[:HAS_TURN]->(t)
 				RETURN t`
 			turnID := fmt.Sprintf("%s_turn_%d", sessionID, i)
 			_, err = tx.Run(ctx, turnQuery, map[string]any{
@@ -172,17 +179,19 @@ func (s *Neo4jStore) ProcessConversation(ctx context.Context, assistantID int64,
 				continue
 			}
 
-			// 建立用户与主题的关系（偏好）
+			// 建立用户与主题的关系（偏好），同一会话重复处理时只计一次
 			userTopicQuery := `
 				MATCH (u:User {id: $userID})
 				MATCH (t:Topic {name: $topicName})
 				MERGE (u)-[r:LIKES]->(t)
-				ON CREATE SET r.weight = 1, r.firstSeen = datetime()
-				ON MATCH SET r.weight = r.weight + 1, r.lastSeen = datetime()
+				ON CREATE SET r.weight = 1.0, r.firstSeen = datetime()
+				ON MATCH SET r.weight = CASE WHEN r.lastSession = $sessionID THEN r.weight ELSE r.weight + 1 END
+				SET r.lastSeen = datetime(), r.lastSession = $sessionID
 				RETURN r`
 			_, err = tx.Run(ctx, userTopicQuery, map[string]any{
 				"userID":    summary.UserID,
 				"topicName": topic,
+				"sessionID": sessionID,
 			})
 			if err != nil {
 				logger.Warn("failed to create user-topic relationship", zap.Error(err))
@@ -212,14 +221,14 @@ func (s *Neo4jStore) ProcessConversation(ctx context.Context, assistantID int64,
 			knowledgeQuery := `
 				MATCH (a:Assistant {id: $assistantID})
 				MERGE (k:Knowledge {id: $knowledgeID})
+				ON CREATE SET k.createdAt = datetime()
 				SET k.content = $content,
 				    k.category = $category,
 				    k.source = $source,
-				    k.createdAt = datetime(),
 				    k.updatedAt = datetime()
 				MERGE (a)-[:HAS_KNOWLEDGE]->(k)
 				RETURN k`
-			knowledgeID := fmt.Sprintf("kg_%d_%d", assistantID, time.Now().UnixNano())
+			knowledgeID := knowledgeNodeID(assistantID, knowledge.Content)
 			_, err = tx.Run(ctx, knowledgeQuery, map[string]any{
 				"assistantID": assistantID,
 				"knowledgeID": knowledgeID,
@@ -251,6 +260,54 @@ func (s *Neo4jStore) ProcessConversation(ctx context.Context, assistantID int64,
 			}
 		}
 
+		// 8. 创建 Entity 节点，记录对话提及与用户关注的实体
+		for _, entity := range summary.Entities {
+			entityQuery := `
+				MATCH (c:Conversation {sessionID: $sessionID})
+				MATCH (u:User {id: $userID})
+				MERGE (e:Entity {name: $name})
+				ON CREATE SET e.createdAt = datetime()
+				SET e.type = CASE WHEN $type = '' THEN e.type ELSE $type END,
+				    e.updatedAt = datetime()
+				MERGE (c)-[:MENTIONS]->(e)
+				MERGE (u)-[r:MENTIONED]->(e)
+				ON CREATE SET r.weight = 1.0, r.firstSeen = datetime()
+				ON MATCH SET r.weight = CASE WHEN r.lastSession = $sessionID THEN r.weight ELSE r.weight + 1 END
+				SET r.lastSeen = datetime(), r.lastSession = $sessionID
+				RETURN e`
+			_, err = tx.Run(ctx, entityQuery, map[string]any{
+				"sessionID": sessionID,
+				"userID":    summary.UserID,
+				"name":      entity.Name,
+				"type":      entity.Type,
+			})
+			if err != nil {
+				logger.Warn("failed to create entity", zap.Error(err), zap.String("entity", entity.Name))
+			}
+		}
+
+		// 9. 建立实体之间的关系，关系类型作为属性保存
+		for _, relation := range summary.Relations {
+			relationQuery := `
+				MATCH (s:Entity {name: $source})
+				MATCH (t:Entity {name: $target})
+				MERGE (s)-[r:RELATES {type: $relation}]->(t)
+				ON CREATE SET r.weight = 1.0, r.firstSeen = datetime()
+				ON MATCH SET r.weight = CASE WHEN r.lastSession = $sessionID THEN r.weight ELSE r.weight + 1 END
+				SET r.lastSeen = datetime(), r.lastSession = $sessionID
+				RETURN r`
+			_, err = tx.Run(ctx, relationQuery, map[string]any{
+				"source":    relation.Source,
+				"target":    relation.Target,
+				"relation":  relation.Relation,
+				"sessionID": sessionID,
+			})
+			if err != nil {
+				logger.Warn("failed to create entity relation", zap.Error(err),
+					zap.String("source", relation.Source), zap.String("target", relation.Target))
+			}
+		}
+
 		return nil, nil
 	})
 
@@ -264,6 +321,57 @@ func (s *Neo4jStore) ProcessConversation(ctx context.Context, assistantID int64,
 	return nil
 }
 
+// DecayInterests 按半衰期衰减用户对主题（LIKES）与实体（MENTIONED）的偏好权重。
+// 权重按距上次衰减（首次为 firstSeen）经过的时间指数衰减，低于 minWeight 的偏好被删除
+func (s *Neo4jStore) DecayInterests(ctx context.Context, halfLife time.Duration, minWeight float64) (int, error) {
+	if halfLife <= 0 {
+		return 0, fmt.Errorf("half life must be positive")
+	}
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{
+		DatabaseName: s.db,
+		AccessMode:   neo4j.AccessModeWrite,
+	})
+	defer session.Close(ctx)
+
+	removed, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		decayQuery := `
+			MATCH (:User)-[r:LIKES|MENTIONED]->()
+			WITH r, duration.inSeconds(coalesce(r.decayedAt, r.firstSeen, datetime()), datetime()).seconds AS elapsed
+			SET r.weight = toFloat(r.weight) * 0.5 ^ (toFloat(elapsed) / $halfLife),
+			    r.decayedAt = datetime()`
+		if _, err := tx.Run(ctx, decayQuery, map[string]any{"halfLife": halfLife.Seconds()}); err != nil {
+			return nil, err
+		}
+
+		deleteQuery := `
+			MATCH (:User)-[r:LIKES|MENTIONED]->()
+			WHERE r.weight < $minWeight
+			DELETE r
+			RETURN count(r) AS removed`
+		result, err := tx.Run(ctx, deleteQuery, map[string]any{"minWeight": minWeight})
+		if err != nil {
+			return nil, err
+		}
+		record, err := result.Single(ctx)
+		if err != nil {
+			return nil, err
+		}
+		count, _ := record.Get("removed")
+		n, _ := count.(int64)
+		return int(n), nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to decay interests: %w", err)
+	}
+	return removed.(int), nil
+}
+
+// knowledgeNodeID 同一助手的相同知识内容对应同一个节点，重复提取时不产生重复节点
+func knowledgeNodeID(assistantID int64, content string) string {
+	sum := sha1.Sum([]byte(strings.TrimSpace(content)))
+	return fmt.Sprintf("kg_%d_%s", assistantID, hex.EncodeToString(sum[:8]))
+}
+
 // GetUserContext 获取用户上下文（偏好、历史主题等）
 func (s *Neo4jStore) GetUserContext(ctx context.Context, userID uint, assistantID int64) (*UserContext, error) {
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{
@@ -602,7 +710,7 @@ func (s *Neo4jStore) getDetailedGraphData(tx neo4j.ManagedTransaction, ctx conte
 	}
 
 	// 计算统计信息
-	stats := s.calculateStats(nodes)
+	stats := calculateGraphStats(nodes)
 	stats.TotalEdges = len(edges)
 
 	return &AssistantGraphData{
@@ -613,8 +721,8 @@ func (s *Neo4jStore) getDetailedGraphData(tx neo4j.ManagedTransaction, ctx conte
 	}, nil
 }
 
-// calculateStats 计算图统计信息
-func (s *Neo4jStore) calculateStats(nodes []GraphNode) GraphStats {
+// calculateGraphStats 计算图统计信息
+func calculateGraphStats(nodes []GraphNode) GraphStats {
 	stats := GraphStats{
 		TotalNodes: len(nodes),
 	}
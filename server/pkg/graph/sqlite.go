@@ -0,0 +1,193 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// graphNodeRow SQLite 节点表
+type graphNodeRow struct {
+	ID    string `gorm:"primaryKey;size:255"`
+	Label string `gorm:"size:32;index"`
+	Props string `gorm:"type:text"` // JSON 编码的节点属性
+}
+
+func (graphNodeRow) TableName() string {
+	return "graph_nodes"
+}
+
+// graphEdgeRow SQLite 边表，(from_id, type, name, to_id) 为主键
+type graphEdgeRow struct {
+	FromID      string `gorm:"primaryKey;size:255"`
+	Type        string `gorm:"primaryKey;size:32;index"`
+	Name        string `gorm:"primaryKey;size:128"`
+	ToID        string `gorm:"primaryKey;size:255;index"`
+	Weight      float64
+	LastSession string `gorm:"size:128"`
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	DecayedAt   time.Time
+}
+
+func (graphEdgeRow) TableName() string {
+	return "graph_edges"
+}
+
+// NewSQLiteStore 创建基于 SQLite 边表的图存储，path 为数据库文件路径
+func NewSQLiteStore(path string) (*EdgeStore, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create graph database directory: %w", err)
+		}
+	}
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open graph database: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	// SQLite 同一时间只允许一个写事务，单连接避免 database is locked
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&graphNodeRow{}, &graphEdgeRow{}); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to migrate graph tables: %w", err)
+	}
+
+	logger.Info("SQLite graph store opened", zap.String("path", path))
+	return newEdgeStore(&sqliteBackend{db: db}), nil
+}
+
+// sqliteBackend SQLite 存储后端
+type sqliteBackend struct {
+	db *gorm.DB
+}
+
+func (b *sqliteBackend) update(ctx context.Context, fn func(tx edgeTx) error) error {
+	return b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&sqliteTx{db: tx})
+	})
+}
+
+func (b *sqliteBackend) view(ctx context.Context, fn func(tx edgeTx) error) error {
+	return fn(&sqliteTx{db: b.db.WithContext(ctx)})
+}
+
+func (b *sqliteBackend) close() error {
+	sqlDB, err := b.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+type sqliteTx struct {
+	db *gorm.DB
+}
+
+func (tx *sqliteTx) getNode(id string) (*edgeNode, error) {
+	var row graphNodeRow
+	if err := tx.db.Where("id = ?", id).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	node := &edgeNode{ID: row.ID, Label: row.Label, Props: map[string]interface{}{}}
+	if row.Props != "" {
+		if err := json.Unmarshal([]byte(row.Props), &node.Props); err != nil {
+			return nil, fmt.Errorf("invalid props of node %s: %w", id, err)
+		}
+	}
+	return node, nil
+}
+
+func (tx *sqliteTx) putNode(node *edgeNode) error {
+	props, err := json.Marshal(node.Props)
+	if err != nil {
+		return err
+	}
+	return tx.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&graphNodeRow{ID: node.ID, Label: node.Label, Props: string(props)}).Error
+}
+
+func (tx *sqliteTx) getEdge(key edgeKey) (*edgeRecord, error) {
+	var row graphEdgeRow
+	err := tx.db.Where("from_id = ? AND type = ? AND name = ? AND to_id = ?", key.From, key.Type, key.Name, key.To).
+		First(&row).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return row.record(), nil
+}
+
+func (tx *sqliteTx) putEdge(edge *edgeRecord) error {
+	return tx.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&graphEdgeRow{
+		FromID:      edge.From,
+		Type:        edge.Type,
+		Name:        edge.Name,
+		ToID:        edge.To,
+		Weight:      edge.Weight,
+		LastSession: edge.LastSession,
+		FirstSeen:   edge.FirstSeen,
+		LastSeen:    edge.LastSeen,
+		DecayedAt:   edge.DecayedAt,
+	}).Error
+}
+
+func (tx *sqliteTx) deleteEdge(key edgeKey) error {
+	return tx.db.Where("from_id = ? AND type = ? AND name = ? AND to_id = ?", key.From, key.Type, key.Name, key.To).
+		Delete(&graphEdgeRow{}).Error
+}
+
+func (tx *sqliteTx) edges(filter edgeFilter) ([]*edgeRecord, error) {
+	query := tx.db.Model(&graphEdgeRow{})
+	if filter.From != "" {
+		query = query.Where("from_id = ?", filter.From)
+	}
+	if filter.To != "" {
+		query = query.Where("to_id = ?", filter.To)
+	}
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	var rows []graphEdgeRow
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*edgeRecord, 0, len(rows))
+	for i := range rows {
+		result = append(result, rows[i].record())
+	}
+	sortEdges(result)
+	return result, nil
+}
+
+func (row *graphEdgeRow) record() *edgeRecord {
+	return &edgeRecord{
+		edgeKey:     edgeKey{From: row.FromID, Type: row.Type, Name: row.Name, To: row.ToID},
+		Weight:      row.Weight,
+		LastSession: row.LastSession,
+		FirstSeen:   row.FirstSeen,
+		LastSeen:    row.LastSeen,
+		DecayedAt:   row.DecayedAt,
+	}
+}
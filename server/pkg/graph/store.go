@@ -0,0 +1,47 @@
+package graph
+
+import "fmt"
+
+// 图存储后端类型
+const (
+	StoreTypeNeo4j  = "neo4j"
+	StoreTypeSQLite = "sqlite"
+	StoreTypeMemory = "memory"
+)
+
+// StoreConfig 图存储配置
+type StoreConfig struct {
+	Type string // 后端类型：neo4j、sqlite、memory
+
+	Neo4jURI      string
+	Neo4jUsername string
+	Neo4jPassword string
+	Neo4jDatabase string
+
+	SQLitePath string // SQLite 数据库文件路径
+}
+
+// ResolveStoreType 解析图存储类型：未指定时兼容旧配置，NEO4J_ENABLED 开启则使用 Neo4j，
+// 返回空字符串表示未启用图存储
+func ResolveStoreType(storeType string, neo4jEnabled bool) string {
+	if storeType == "" && neo4jEnabled {
+		return StoreTypeNeo4j
+	}
+	return storeType
+}
+
+// NewStore 按配置创建图存储
+func NewStore(cfg StoreConfig) (Store, error) {
+	switch cfg.Type {
+	case StoreTypeNeo4j:
+		return NewNeo4jStore(cfg.Neo4jURI, cfg.Neo4jUsername, cfg.Neo4jPassword, cfg.Neo4jDatabase)
+	case StoreTypeSQLite:
+		if cfg.SQLitePath == "" {
+			return nil, fmt.Errorf("sqlite graph store requires a database path")
+		}
+		return NewSQLiteStore(cfg.SQLitePath)
+	case StoreTypeMemory:
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unsupported graph store type: %s", cfg.Type)
+}
@@ -1,5 +1,10 @@
 package graph
 
+import (
+	"strings"
+	"unicode"
+)
+
 // ConversationSummary 对话总结
 type ConversationSummary struct {
 	AssistantID   int64       `json:"assistantId"`
@@ -11,6 +16,21 @@ type ConversationSummary struct {
 	Intents       []string    `json:"intents"`   // 用户意图列表
 	Turns         []Turn      `json:"turns"`     // 对话轮次
 	Knowledge     []Knowledge `json:"knowledge"` // 提取的知识点
+	Entities      []Entity    `json:"entities"`  // 提到的实体（人物、产品、地点等）
+	Relations     []Relation  `json:"relations"` // 实体之间的关系
+}
+
+// Entity 对话中提到的实体
+type Entity struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // 实体类型，如 person、product、organization、place
+}
+
+// Relation 两个实体之间的关系，Source/Target 为实体名称
+type Relation struct {
+	Source   string `json:"source"`
+	Relation string `json:"relation"` // 关系描述，如 works_at、owns、prefers
+	Target   string `json:"target"`
 }
 
 // Turn 对话轮次
@@ -70,3 +90,83 @@ type GraphStats struct {
 	IntentsCount       int `json:"intentsCount"`
 	KnowledgeCount     int `json:"knowledgeCount"`
 }
+
+// NormalizeName 规范化主题、意图与实体名称：去除首尾空白与包裹的引号，合并连续空白，
+// 使同一名称的不同写法合并为同一个节点
+func NormalizeName(name string) string {
+	name = strings.TrimFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"'“”‘’「」《》`, r)
+	})
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// DedupNames 规范化并去重名称列表（不区分大小写，保留首次出现的写法），丢弃空名称
+func DedupNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = NormalizeName(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// Normalize 规范化并去重总结中的主题、意图、实体与关系；关系的两端须是已提取的实体，
+// 其名称统一为实体的写法
+func (s *ConversationSummary) Normalize() {
+	s.Topics = DedupNames(s.Topics)
+	s.Intents = DedupNames(s.Intents)
+
+	entities := make([]Entity, 0, len(s.Entities))
+	entityNames := make(map[string]string, len(s.Entities))
+	for _, entity := range s.Entities {
+		entity.Name = NormalizeName(entity.Name)
+		key := strings.ToLower(entity.Name)
+		if entity.Name == "" {
+			continue
+		}
+		if _, ok := entityNames[key]; ok {
+			continue
+		}
+		entityNames[key] = entity.Name
+		entity.Type = strings.ToLower(NormalizeName(entity.Type))
+		entities = append(entities, entity)
+	}
+	s.Entities = entities
+
+	relations := make([]Relation, 0, len(s.Relations))
+	seen := make(map[Relation]bool, len(s.Relations))
+	for _, relation := range s.Relations {
+		source, okSource := entityNames[strings.ToLower(NormalizeName(relation.Source))]
+		target, okTarget := entityNames[strings.ToLower(NormalizeName(relation.Target))]
+		relation.Relation = strings.ToLower(strings.ReplaceAll(NormalizeName(relation.Relation), " ", "_"))
+		if !okSource || !okTarget || relation.Relation == "" || source == target {
+			continue
+		}
+		relation.Source, relation.Target = source, target
+		if seen[relation] {
+			continue
+		}
+		seen[relation] = true
+		relations = append(relations, relation)
+	}
+	s.Relations = relations
+
+	knowledge := make([]Knowledge, 0, len(s.Knowledge))
+	seenKnowledge := make(map[string]bool, len(s.Knowledge))
+	for _, k := range s.Knowledge {
+		k.Content = strings.TrimSpace(k.Content)
+		if k.Content == "" || seenKnowledge[k.Content] {
+			continue
+		}
+		seenKnowledge[k.Content] = true
+		k.RelatedTopics = DedupNames(k.RelatedTopics)
+		knowledge = append(knowledge, k)
+	}
+	s.Knowledge = knowledge
+}
@@ -21,6 +21,8 @@ const (
 type ServiceFactory struct {
 	transcriberFactory *recognizer.DefaultTranscriberFactory
 	logger             *zap.Logger
+	ttsCache           bool
+	ttsLanguage        string
 }
 
 // NewServiceFactory 创建服务工厂
@@ -126,10 +128,24 @@ func (f *ServiceFactory) CreateTTS(credential *models.UserCredential, speaker st
 	if err != nil {
 		return nil, errhandler.NewRecoverableError("Factory", "创建TTS服务失败", err)
 	}
+	ttsService = synthesizer.WithTextNormalizer(ttsService, f.ttsLanguage)
+	if f.ttsCache {
+		ttsService = synthesizer.WithResultCache(ttsService)
+	}
 
 	return ttsService, nil
 }
 
+// SetTTSCache 设置创建的TTS服务是否复用已合成的短句音频
+func (f *ServiceFactory) SetTTSCache(enabled bool) {
+	f.ttsCache = enabled
+}
+
+// SetTTSLanguage 设置合成前文本归一化（数字、日期、单位等读法）使用的语言
+func (f *ServiceFactory) SetTTSLanguage(language string) {
+	f.ttsLanguage = language
+}
+
 // CreateLLM 创建LLM服务
 func (f *ServiceFactory) CreateLLM(ctx context.Context, credential *models.UserCredential, systemPrompt string) (llm.LLMProvider, error) {
 	provider, err := llm.NewLLMProvider(ctx, credential, systemPrompt)
@@ -45,6 +45,7 @@ func (h *Handler) HandleWebSocket(
 	enableVAD := true
 	vadThreshold := 500.0
 	vadConsecutiveFrames := 2
+	ttsCache := true
 	if assistantID > 0 && db != nil {
 		var assistant models.Assistant
 		if err := db.First(&assistant, assistantID).Error; err == nil {
@@ -65,6 +66,7 @@ func (h *Handler) HandleWebSocket(
 			if assistant.VADConsecutiveFrames > 0 {
 				vadConsecutiveFrames = assistant.VADConsecutiveFrames
 			}
+			ttsCache = assistant.TTSCacheEnabled
 		}
 	}
 
@@ -92,6 +94,7 @@ func (h *Handler) HandleWebSocket(
 		EnableVAD:            enableVAD,
 		VADThreshold:         vadThreshold,
 		VADConsecutiveFrames: vadConsecutiveFrames,
+		TTSCache:             ttsCache,
 	}
 
 	// 创建会话
@@ -79,6 +79,8 @@ func NewSession(config *SessionConfig) (*Session, error) {
 	// 创建服务工厂
 	transcriberFactory := recognizer.GetGlobalFactory()
 	serviceFactory := factory.NewServiceFactory(transcriberFactory, config.Logger)
+	serviceFactory.SetTTSCache(config.TTSCache)
+	serviceFactory.SetTTSLanguage(config.Language)
 
 	// 创建消息写入器
 	messageWriter := message.NewWriter(config.Conn, config.Logger)
@@ -503,49 +505,34 @@ func (s *Session) handleHelloMessage(msg map[string]interface{}) {
 
 // initializeOpusCodecs 初始化OPUS编解码器
 func (s *Session) initializeOpusCodecs(sampleRate, channels int, frameDuration string) error {
+	opusConfig := media.CodecConfig{
+		Codec:         "opus",
+		SampleRate:    sampleRate,
+		Channels:      channels,
+		BitDepth:      16,
+		FrameDuration: frameDuration,
+	}
+	pcmConfig := media.CodecConfig{
+		Codec:         "pcm",
+		SampleRate:    sampleRate,
+		Channels:      channels,
+		BitDepth:      16,
+		FrameDuration: frameDuration,
+	}
+
 	// 创建OPUS解码器（OPUS -> PCM，用于ASR）
-	opusDecoder, err := encoder.CreateDecode(
-		media.CodecConfig{
-			Codec:         "opus",
-			SampleRate:    sampleRate,
-			Channels:      channels,
-			BitDepth:      16,
-			FrameDuration: frameDuration,
-		},
-		media.CodecConfig{
-			Codec:         "pcm",
-			SampleRate:    sampleRate,
-			Channels:      channels,
-			BitDepth:      16,
-			FrameDuration: frameDuration,
-		},
-	)
+	opusDecoder, err := encoder.NewTranscoder(opusConfig, pcmConfig)
 	if err != nil {
 		return fmt.Errorf("创建OPUS解码器失败: %w", err)
 	}
-	s.opusDecoder = opusDecoder
+	s.opusDecoder = opusDecoder.Transcode
 
 	// 创建OPUS编码器（PCM -> OPUS，用于TTS）
-	opusEncoder, err := encoder.CreateEncode(
-		media.CodecConfig{
-			Codec:         "opus",
-			SampleRate:    sampleRate,
-			Channels:      channels,
-			BitDepth:      16,
-			FrameDuration: frameDuration,
-		},
-		media.CodecConfig{
-			Codec:         "pcm",
-			SampleRate:    sampleRate,
-			Channels:      channels,
-			BitDepth:      16,
-			FrameDuration: frameDuration,
-		},
-	)
+	opusEncoder, err := encoder.NewTranscoder(pcmConfig, opusConfig)
 	if err != nil {
 		return fmt.Errorf("创建OPUS编码器失败: %w", err)
 	}
-	s.opusEncoder = opusEncoder
+	s.opusEncoder = opusEncoder.Transcode
 
 	return nil
 }
@@ -554,6 +541,8 @@ func (s *Session) initializeOpusCodecs(sampleRate, channels int, frameDuration s
 func (s *Session) reinitializeServices(sampleRate, channels int) error {
 	transcriberFactory := recognizer.GetGlobalFactory()
 	serviceFactory := factory.NewServiceFactory(transcriberFactory, s.config.Logger)
+	serviceFactory.SetTTSCache(s.config.TTSCache)
+	serviceFactory.SetTTSLanguage(s.config.Language)
 
 	// 停止旧的ASR服务
 	s.config.Logger.Info("停止旧的ASR服务")
@@ -0,0 +1,95 @@
+package knowledge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultCitationSnippetLength is the number of characters of a chunk quoted in its citation
+const DefaultCitationSnippetLength = 200
+
+// Citation is the provenance of a chunk used to answer a question, returned
+// with the answer so clients can show its sources
+type Citation struct {
+	// Index is the 1-based position of the chunk in the prompt context
+	Index      int     `json:"index"`
+	DocumentID string  `json:"documentId,omitempty"`
+	Title      string  `json:"title,omitempty"`
+	Page       int     `json:"page,omitempty"`
+	Offset     int     `json:"offset,omitempty"`
+	Score      float64 `json:"score"`
+	Snippet    string  `json:"snippet,omitempty"`
+}
+
+// Metadata keys some hosted providers (Aliyun Bailian) use for document provenance
+var (
+	documentIDMetadataKeys = []string{MetadataKeyDocumentID, "doc_id", "documentId"}
+	titleMetadataKeys      = []string{MetadataKeyTitle, "doc_name", "file_name"}
+	pageMetadataKeys       = []string{MetadataKeyPage, "page_number"}
+)
+
+// CitationsFromResults builds the citations of search results, in order
+func CitationsFromResults(results []SearchResult) []Citation {
+	citations := make([]Citation, 0, len(results))
+	for i, result := range results {
+		citation := Citation{
+			Index:      i + 1,
+			DocumentID: metadataString(result.Metadata, documentIDMetadataKeys...),
+			Title:      metadataString(result.Metadata, titleMetadataKeys...),
+			Page:       metadataInt(result.Metadata, pageMetadataKeys...),
+			Offset:     metadataInt(result.Metadata, MetadataKeyOffset),
+			Score:      result.Score,
+			Snippet:    snippet(result.Content, DefaultCitationSnippetLength),
+		}
+		if citation.Title == "" {
+			citation.Title = result.Source
+		}
+		citations = append(citations, citation)
+	}
+	return citations
+}
+
+func metadataString(metadata map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := metadata[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case nil:
+		default:
+			return fmt.Sprintf("%v", v)
+		}
+	}
+	return ""
+}
+
+// metadataInt reads an integer stored as a number (JSON payloads decode to
+// float64) or a numeric string
+func metadataInt(metadata map[string]interface{}, keys ...string) int {
+	for _, key := range keys {
+		switch v := metadata[key].(type) {
+		case int:
+			return v
+		case int64:
+			return int(v)
+		case float64:
+			return int(v)
+		case string:
+			if n, err := strconv.Atoi(v); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+func snippet(content string, length int) string {
+	content = strings.Join(strings.Fields(content), " ")
+	runes := []rune(content)
+	if len(runes) <= length {
+		return content
+	}
+	return string(runes[:length]) + "…"
+}
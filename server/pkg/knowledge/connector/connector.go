@@ -0,0 +1,200 @@
+// Package connector keeps knowledge bases in sync with external document
+// sources (an S3 bucket prefix, web pages listed by URL or sitemap, a Notion
+// export). A Syncer lists the documents of a source, detects changes by
+// checksum and re-ingests only the documents that changed.
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/ingest"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+)
+
+// Connector types
+const (
+	TypeS3     = "s3"
+	TypeWeb    = "web"
+	TypeNotion = "notion"
+)
+
+// MaxDocumentSize bounds the size of a fetched document
+const MaxDocumentSize = 50 << 20
+
+var (
+	localRootMu sync.RWMutex
+	localRoot   string
+)
+
+// SetLocalRoot sets the server directory local sources (Notion export paths)
+// may read from. Local sources are rejected while it is empty.
+func SetLocalRoot(dir string) {
+	localRootMu.Lock()
+	defer localRootMu.Unlock()
+	localRoot = dir
+}
+
+// localPath resolves p inside the local root
+func localPath(p string) (string, error) {
+	localRootMu.RLock()
+	root := localRoot
+	localRootMu.RUnlock()
+	if root == "" {
+		return "", errors.New("local paths are disabled, set KNOWLEDGE_SYNC_DIR to allow them")
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(root, p)
+	}
+	p = filepath.Clean(p)
+	if rel, err := filepath.Rel(root, p); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside %s", p, root)
+	}
+	return p, nil
+}
+
+// Document is a document listed by a connector
+type Document struct {
+	// URI identifies the document in its source (object key, URL, export path)
+	URI string
+	// FileName selects the text extractor and is the title of its chunks
+	FileName string
+}
+
+// Connector lists and fetches the documents of an external source
+type Connector interface {
+	Type() string
+	// List returns the documents currently in the source
+	List(ctx context.Context) ([]Document, error)
+	// Fetch returns the content of a listed document
+	Fetch(ctx context.Context, doc Document) ([]byte, error)
+}
+
+// New creates the connector of a type from its config
+func New(typ string, config map[string]interface{}) (Connector, error) {
+	switch strings.ToLower(typ) {
+	case TypeS3:
+		return newS3Connector(config)
+	case TypeWeb:
+		return newWebConnector(config)
+	case TypeNotion:
+		return newNotionConnector(config)
+	default:
+		return nil, fmt.Errorf("unsupported connector type: %s", typ)
+	}
+}
+
+// Validate checks the config of a source before it is saved: the connector must
+// build and every URL it fetches must point to a public host. Fetches also go
+// through a client that refuses internal addresses, which covers redirects,
+// sitemap entries and DNS changes after the check.
+func Validate(ctx context.Context, typ string, config map[string]interface{}) error {
+	if _, err := New(typ, config); err != nil {
+		return err
+	}
+	var urls []string
+	switch strings.ToLower(typ) {
+	case TypeWeb:
+		urls = append(urls, configStrings(config, ConfigKeyWebURLs)...)
+		urls = append(urls, configString(config, ConfigKeyWebSitemap))
+	case TypeNotion:
+		urls = append(urls, configString(config, ConfigKeyNotionURL))
+	case TypeS3:
+		if endpoint := configString(config, ConfigKeyS3Endpoint); endpoint != "" {
+			urls = append(urls, "https://"+endpoint)
+		}
+	}
+	for _, u := range urls {
+		if u == "" {
+			continue
+		}
+		if err := utils.ValidatePublicURL(ctx, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// supported reports whether the ingestion pipeline can extract fileName
+func supported(fileName string) bool {
+	ext := strings.ToLower(path.Ext(fileName))
+	for _, supportedExt := range ingest.SupportedExtensions() {
+		if ext == supportedExt {
+			return true
+		}
+	}
+	return false
+}
+
+// readLimited reads r, failing for documents over MaxDocumentSize
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxDocumentSize {
+		return nil, errors.New("document is too large")
+	}
+	return data, nil
+}
+
+func configString(config map[string]interface{}, key string) string {
+	if v, ok := config[key].(string); ok {
+		return strings.TrimSpace(v)
+	}
+	return ""
+}
+
+func configBool(config map[string]interface{}, key string) bool {
+	switch v := config[key].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "1" || strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+func configInt(config map[string]interface{}, key string) int {
+	switch v := config[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// configStrings reads a list given as a JSON array or a newline/comma separated string
+func configStrings(config map[string]interface{}, key string) []string {
+	var values []string
+	switch v := config[key].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	case []string:
+		values = v
+	case string:
+		values = strings.FieldsFunc(v, func(r rune) bool { return r == '\n' || r == ',' })
+	}
+	result := values[:0]
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,170 @@
+package connector
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+)
+
+// Notion connector config keys, one of path and url is required
+const (
+	ConfigKeyNotionPath = "path" // export zip file or unzipped export directory on the server
+	ConfigKeyNotionURL  = "url"  // export zip to download on every sync
+)
+
+// notionPageID is the page ID Notion appends to exported file names
+var notionPageID = regexp.MustCompile(`\s+[0-9a-f]{32}(\.[A-Za-z]+)$`)
+
+// notionConnector syncs the pages of a Notion workspace export ("Markdown &
+// CSV" or "HTML"). The export is read once per sync: List loads it and Fetch
+// serves the pages from memory.
+type notionConnector struct {
+	path       string
+	url        string
+	httpClient *http.Client
+	pages      map[string][]byte
+}
+
+func newNotionConnector(config map[string]interface{}) (Connector, error) {
+	n := &notionConnector{
+		path:       configString(config, ConfigKeyNotionPath),
+		url:        configString(config, ConfigKeyNotionURL),
+		httpClient: utils.NewPublicHTTPClient(5 * time.Minute),
+	}
+	if n.path == "" && n.url == "" {
+		return nil, errors.New("path or url is required")
+	}
+	if n.path != "" {
+		resolved, err := localPath(n.path)
+		if err != nil {
+			return nil, err
+		}
+		n.path = resolved
+	}
+	return n, nil
+}
+
+func (n *notionConnector) Type() string {
+	return TypeNotion
+}
+
+func (n *notionConnector) List(ctx context.Context) ([]Document, error) {
+	pages, err := n.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n.pages = pages
+	docs := make([]Document, 0, len(pages))
+	for uri := range pages {
+		docs = append(docs, Document{URI: uri, FileName: notionPageID.ReplaceAllString(path.Base(uri), "$1")})
+	}
+	return docs, nil
+}
+
+func (n *notionConnector) Fetch(ctx context.Context, doc Document) ([]byte, error) {
+	data, ok := n.pages[doc.URI]
+	if !ok {
+		return nil, fmt.Errorf("page not found in export: %s", doc.URI)
+	}
+	return data, nil
+}
+
+// load reads the supported pages of the export keyed by their path in it
+func (n *notionConnector) load(ctx context.Context) (map[string][]byte, error) {
+	if n.url != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := n.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("download export: %s", resp.Status)
+		}
+		data, err := readLimited(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return readExportZip(data)
+	}
+
+	info, err := os.Stat(n.path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(n.path)
+		if err != nil {
+			return nil, err
+		}
+		return readExportZip(data)
+	}
+	pages := make(map[string][]byte)
+	err = filepath.WalkDir(n.path, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !supported(p) {
+			return err
+		}
+		rel, err := filepath.Rel(n.path, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		pages[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	return pages, err
+}
+
+// readExportZip reads an export zip. Large workspaces are exported as a zip
+// of zips ("Export-<id>-Part-1.zip", ...), which are read too.
+func readExportZip(data []byte) (map[string][]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid export zip: %w", err)
+	}
+	pages := make(map[string][]byte)
+	for _, f := range archive.File {
+		isZip := strings.EqualFold(path.Ext(f.Name), ".zip")
+		if f.FileInfo().IsDir() || (!isZip && !supported(f.Name)) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := readLimited(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if !isZip {
+			pages[f.Name] = content
+			continue
+		}
+		nested, err := readExportZip(content)
+		if err != nil {
+			return nil, err
+		}
+		for name, page := range nested {
+			pages[name] = page
+		}
+	}
+	return pages, nil
+}
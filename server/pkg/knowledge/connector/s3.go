@@ -0,0 +1,97 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 connector config keys
+const (
+	ConfigKeyS3Endpoint  = "endpoint" // host[:port], defaults to AWS S3 of the region
+	ConfigKeyS3Region    = "region"
+	ConfigKeyS3Bucket    = "bucket"
+	ConfigKeyS3Prefix    = "prefix"
+	ConfigKeyS3AccessKey = "access_key_id"
+	ConfigKeyS3SecretKey = "secret_access_key"
+	ConfigKeyS3Insecure  = "insecure" // plain HTTP, for local MinIO
+)
+
+// s3Connector syncs the objects under a prefix of an S3 compatible bucket
+type s3Connector struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func newS3Connector(config map[string]interface{}) (Connector, error) {
+	bucket := configString(config, ConfigKeyS3Bucket)
+	if bucket == "" {
+		return nil, errors.New("bucket is required")
+	}
+	region := configString(config, ConfigKeyS3Region)
+	endpoint := configString(config, ConfigKeyS3Endpoint)
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+		if region != "" {
+			endpoint = "s3." + region + ".amazonaws.com"
+		}
+	}
+	var creds *credentials.Credentials
+	if accessKey := configString(config, ConfigKeyS3AccessKey); accessKey != "" {
+		creds = credentials.NewStaticV4(accessKey, configString(config, ConfigKeyS3SecretKey), "")
+	} else {
+		// Environment variables, shared credentials file or instance role
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{},
+		})
+	}
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:     creds,
+		Secure:    !configBool(config, ConfigKeyS3Insecure),
+		Region:    region,
+		Transport: utils.NewPublicHTTPClient(0).Transport, // the endpoint is user supplied
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s3Connector{
+		client: client,
+		bucket: bucket,
+		prefix: strings.TrimPrefix(configString(config, ConfigKeyS3Prefix), "/"),
+	}, nil
+}
+
+func (s *s3Connector) Type() string {
+	return TypeS3
+}
+
+func (s *s3Connector) List(ctx context.Context) ([]Document, error) {
+	var docs []Document
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		if strings.HasSuffix(object.Key, "/") || !supported(object.Key) {
+			continue
+		}
+		docs = append(docs, Document{URI: object.Key, FileName: path.Base(object.Key)})
+	}
+	return docs, nil
+}
+
+func (s *s3Connector) Fetch(ctx context.Context, doc Document) ([]byte, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, doc.URI, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+	return readLimited(object)
+}
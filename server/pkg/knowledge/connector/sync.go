@@ -0,0 +1,148 @@
+package connector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/ingest"
+)
+
+// maxReportErrors bounds the per document errors kept in a Report
+const maxReportErrors = 20
+
+// State is the last synced version of a document
+type State struct {
+	URI        string
+	DocumentID string
+	Checksum   string // hex SHA-256 of the content
+	Chunks     int
+}
+
+// StateStore persists the synced versions of the documents of one source
+type StateStore interface {
+	Load(ctx context.Context) (map[string]State, error)
+	Save(ctx context.Context, state State) error
+	Delete(ctx context.Context, uri string) error
+}
+
+// Report summarizes a sync run
+type Report struct {
+	Listed    int      `json:"listed"`
+	Added     int      `json:"added"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Deleted   int      `json:"deleted"`
+	Failed    int      `json:"failed"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+func (r *Report) fail(uri string, err error) {
+	r.Failed++
+	if len(r.Errors) < maxReportErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf("%s: %v", uri, err))
+	}
+}
+
+// Syncer syncs one source into a knowledge base
+type Syncer struct {
+	// SourceID distinguishes the documents of this source from other sources
+	// of the same knowledge base
+	SourceID     string
+	KnowledgeKey string
+	Connector    Connector
+	Pipeline     *ingest.Pipeline
+	State        StateStore
+}
+
+// Run ingests the new and changed documents of the source and removes the
+// chunks of documents no longer listed. A document failing to fetch or
+// ingest is reported and retried on the next run; only a failure to list
+// the source or to access the state fails the run.
+func (s *Syncer) Run(ctx context.Context) (Report, error) {
+	var report Report
+	states, err := s.State.Load(ctx)
+	if err != nil {
+		return report, fmt.Errorf("load sync state: %w", err)
+	}
+	docs, err := s.Connector.List(ctx)
+	if err != nil {
+		return report, fmt.Errorf("list %s source: %w", s.Connector.Type(), err)
+	}
+	report.Listed = len(docs)
+
+	listed := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		listed[doc.URI] = true
+		data, err := s.Connector.Fetch(ctx, doc)
+		if err != nil {
+			report.fail(doc.URI, err)
+			continue
+		}
+		sum := sha256.Sum256(data)
+		checksum := hex.EncodeToString(sum[:])
+		previous, known := states[doc.URI]
+		if known && previous.Checksum == checksum {
+			report.Unchanged++
+			continue
+		}
+
+		// The pipeline replaces the previous chunks of the document
+		result, err := s.Pipeline.Run(ctx, ingest.Source{
+			KnowledgeKey: s.KnowledgeKey,
+			FileName:     doc.FileName,
+			Data:         data,
+			DocumentID:   s.documentID(doc.URI),
+			Metadata: map[string]interface{}{
+				knowledge.MetadataKeySource: s.Connector.Type() + ":" + doc.URI,
+			},
+		}, nil)
+		if err != nil {
+			report.fail(doc.URI, err)
+			continue
+		}
+		state := State{URI: doc.URI, DocumentID: result.DocumentID, Checksum: checksum, Chunks: result.Chunks}
+		if err := s.State.Save(ctx, state); err != nil {
+			return report, fmt.Errorf("save sync state: %w", err)
+		}
+		if known {
+			report.Updated++
+		} else {
+			report.Added++
+		}
+	}
+
+	for uri, state := range states {
+		if listed[uri] {
+			continue
+		}
+		if err := s.deleteDocument(ctx, state); err != nil {
+			report.fail(uri, err)
+			continue
+		}
+		if err := s.State.Delete(ctx, uri); err != nil {
+			return report, fmt.Errorf("delete sync state: %w", err)
+		}
+		report.Deleted++
+	}
+	return report, nil
+}
+
+// documentID is stable for a document of the source across syncs
+func (s *Syncer) documentID(uri string) string {
+	return ingest.DocumentID(s.KnowledgeKey, s.SourceID+"\x00"+uri)
+}
+
+func (s *Syncer) deleteDocument(ctx context.Context, state State) error {
+	if store, ok := s.Pipeline.KB.(knowledge.VectorStore); ok {
+		return store.DeleteDocumentChunks(ctx, s.KnowledgeKey, state.DocumentID)
+	}
+	// Knowledge bases parsing files themselves assign their own document IDs,
+	// the uploaded copy has to be removed in the provider console
+	return nil
+}
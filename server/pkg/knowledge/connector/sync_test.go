@@ -0,0 +1,188 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge/ingest"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+)
+
+type fakeConnector map[string]string
+
+func (f fakeConnector) Type() string { return "fake" }
+
+func (f fakeConnector) List(ctx context.Context) ([]Document, error) {
+	var docs []Document
+	for uri := range f {
+		docs = append(docs, Document{URI: uri, FileName: uri})
+	}
+	return docs, nil
+}
+
+func (f fakeConnector) Fetch(ctx context.Context, doc Document) ([]byte, error) {
+	return []byte(f[doc.URI]), nil
+}
+
+type memoryState map[string]State
+
+func (m memoryState) Load(ctx context.Context) (map[string]State, error) {
+	states := make(map[string]State, len(m))
+	for k, v := range m {
+		states[k] = v
+	}
+	return states, nil
+}
+
+func (m memoryState) Save(ctx context.Context, state State) error {
+	m[state.URI] = state
+	return nil
+}
+
+func (m memoryState) Delete(ctx context.Context, uri string) error {
+	delete(m, uri)
+	return nil
+}
+
+// fakeStore is a vector knowledge base keeping chunks per document
+type fakeStore struct {
+	knowledge.KnowledgeBase
+	chunks map[string][]knowledge.VectorChunk
+}
+
+func (s *fakeStore) UpsertChunks(ctx context.Context, key string, chunks []knowledge.VectorChunk) error {
+	for _, chunk := range chunks {
+		s.chunks[chunk.DocumentID] = append(s.chunks[chunk.DocumentID], chunk)
+	}
+	return nil
+}
+
+func (s *fakeStore) DeleteDocumentChunks(ctx context.Context, key, documentID string) error {
+	delete(s.chunks, documentID)
+	return nil
+}
+
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) Provider() string { return "fake" }
+func (fakeEmbedder) Model() string    { return "fake" }
+func (fakeEmbedder) Dimension() int   { return 1 }
+func (fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return []float32{1}, nil
+}
+func (e fakeEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{1}
+	}
+	return vectors, nil
+}
+
+func TestSyncerRun(t *testing.T) {
+	source := fakeConnector{"a.txt": "Alpha.", "b.txt": "Beta."}
+	store := &fakeStore{chunks: make(map[string][]knowledge.VectorChunk)}
+	state := memoryState{}
+	syncer := &Syncer{
+		SourceID:     "1",
+		KnowledgeKey: "kb",
+		Connector:    source,
+		Pipeline:     &ingest.Pipeline{KB: store, Embedder: fakeEmbedder{}},
+		State:        state,
+	}
+
+	report, err := syncer.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Added != 2 || len(store.chunks) != 2 {
+		t.Fatalf("first run: %+v, %d documents", report, len(store.chunks))
+	}
+
+	source["a.txt"] = "Alpha changed."
+	delete(source, "b.txt")
+	report, err = syncer.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Updated != 1 || report.Deleted != 1 || report.Unchanged != 0 {
+		t.Fatalf("second run: %+v", report)
+	}
+	chunks := store.chunks[state["a.txt"].DocumentID]
+	if len(store.chunks) != 1 || len(chunks) != 1 || chunks[0].Content != "Alpha changed." {
+		t.Fatalf("store after second run: %+v", store.chunks)
+	}
+
+	report, err = syncer.Run(context.Background())
+	if err != nil || report.Unchanged != 1 || report.Updated != 0 {
+		t.Fatalf("third run: %+v, %v", report, err)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	SetLocalRoot("")
+	if _, err := localPath("/tmp/export.zip"); err == nil {
+		t.Fatal("local paths must be rejected without a root")
+	}
+	SetLocalRoot("/data/sync")
+	defer SetLocalRoot("")
+	if p, err := localPath("notion/export.zip"); err != nil || p != "/data/sync/notion/export.zip" {
+		t.Fatalf("got %q, %v", p, err)
+	}
+	for _, p := range []string{"../etc/passwd", "/etc/passwd", "/data/sync-other/x.md"} {
+		if _, err := localPath(p); err == nil {
+			t.Errorf("%s must be rejected", p)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+	valid := []struct {
+		typ    string
+		config map[string]interface{}
+	}{
+		{TypeWeb, map[string]interface{}{ConfigKeyWebURLs: "https://8.8.8.8/docs"}},
+		{TypeNotion, map[string]interface{}{ConfigKeyNotionURL: "https://8.8.8.8/export.zip"}},
+		{TypeS3, map[string]interface{}{ConfigKeyS3Bucket: "docs", ConfigKeyS3Endpoint: "8.8.8.8:9000"}},
+	}
+	for _, tc := range valid {
+		if err := Validate(ctx, tc.typ, tc.config); err != nil {
+			t.Errorf("%s: %v", tc.typ, err)
+		}
+	}
+
+	// Internal services and cloud metadata are refused
+	invalid := []struct {
+		typ    string
+		config map[string]interface{}
+	}{
+		{TypeWeb, map[string]interface{}{ConfigKeyWebURLs: []interface{}{"https://8.8.8.8/docs", "http://127.0.0.1:8080/admin"}}},
+		{TypeWeb, map[string]interface{}{ConfigKeyWebSitemap: "http://169.254.169.254/latest/meta-data"}},
+		{TypeNotion, map[string]interface{}{ConfigKeyNotionURL: "http://10.0.0.1/export.zip"}},
+		{TypeS3, map[string]interface{}{ConfigKeyS3Bucket: "docs", ConfigKeyS3Endpoint: "192.168.1.10:9000"}},
+	}
+	for _, tc := range invalid {
+		if err := Validate(ctx, tc.typ, tc.config); err == nil {
+			t.Errorf("%s %v must be rejected", tc.typ, tc.config)
+		}
+	}
+}
+
+func TestWebConnectorRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	c, err := New(TypeWeb, map[string]interface{}{ConfigKeyWebURLs: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Fetch(context.Background(), Document{URI: server.URL}); !errors.Is(err, utils.ErrBlockedAddress) {
+		t.Fatalf("loopback fetch must be refused, got %v", err)
+	}
+}
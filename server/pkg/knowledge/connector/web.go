@@ -0,0 +1,155 @@
+package connector
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+)
+
+// Web connector config keys
+const (
+	ConfigKeyWebURLs     = "urls"      // pages to sync
+	ConfigKeyWebSitemap  = "sitemap"   // sitemap.xml or sitemap index listing pages to sync
+	ConfigKeyWebMaxPages = "max_pages" // DefaultWebMaxPages when unset
+)
+
+// DefaultWebMaxPages bounds the pages synced from one web source
+const DefaultWebMaxPages = 500
+
+// maxSitemapDepth bounds nested sitemap indexes
+const maxSitemapDepth = 3
+
+// webConnector syncs web pages listed explicitly or by a sitemap
+type webConnector struct {
+	urls       []string
+	sitemap    string
+	maxPages   int
+	httpClient *http.Client
+}
+
+func newWebConnector(config map[string]interface{}) (Connector, error) {
+	w := &webConnector{
+		urls:       configStrings(config, ConfigKeyWebURLs),
+		sitemap:    configString(config, ConfigKeyWebSitemap),
+		maxPages:   configInt(config, ConfigKeyWebMaxPages),
+		httpClient: utils.NewPublicHTTPClient(30 * time.Second),
+	}
+	if len(w.urls) == 0 && w.sitemap == "" {
+		return nil, errors.New("urls or sitemap is required")
+	}
+	if w.maxPages <= 0 {
+		w.maxPages = DefaultWebMaxPages
+	}
+	return w, nil
+}
+
+func (w *webConnector) Type() string {
+	return TypeWeb
+}
+
+func (w *webConnector) List(ctx context.Context) ([]Document, error) {
+	pages := append([]string(nil), w.urls...)
+	if w.sitemap != "" {
+		listed, err := w.readSitemap(ctx, w.sitemap, 0)
+		if err != nil {
+			return nil, fmt.Errorf("read sitemap: %w", err)
+		}
+		pages = append(pages, listed...)
+	}
+
+	seen := make(map[string]bool, len(pages))
+	docs := make([]Document, 0, len(pages))
+	for _, page := range pages {
+		u, err := url.Parse(page)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		u.Fragment = ""
+		uri := u.String()
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+		docs = append(docs, Document{URI: uri, FileName: webFileName(u)})
+		if len(docs) == w.maxPages {
+			break
+		}
+	}
+	return docs, nil
+}
+
+func (w *webConnector) Fetch(ctx context.Context, doc Document) ([]byte, error) {
+	return w.get(ctx, doc.URI)
+}
+
+func (w *webConnector) get(ctx context.Context, pageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "LingEcho-KnowledgeSync/1.0")
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", pageURL, resp.Status)
+	}
+	return readLimited(resp.Body)
+}
+
+// readSitemap returns the page URLs of a sitemap, following sitemap indexes
+func (w *webConnector) readSitemap(ctx context.Context, sitemapURL string, depth int) ([]string, error) {
+	data, err := w.get(ctx, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	var sitemap struct {
+		XMLName  xml.Name
+		URLs     []string `xml:"url>loc"`
+		Sitemaps []string `xml:"sitemap>loc"`
+	}
+	if err := xml.Unmarshal(data, &sitemap); err != nil {
+		return nil, err
+	}
+	pages := sitemap.URLs
+	if depth < maxSitemapDepth {
+		for _, nested := range sitemap.Sitemaps {
+			if len(pages) >= w.maxPages {
+				break
+			}
+			listed, err := w.readSitemap(ctx, strings.TrimSpace(nested), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			pages = append(pages, listed...)
+		}
+	}
+	for i := range pages {
+		pages[i] = strings.TrimSpace(pages[i])
+	}
+	return pages, nil
+}
+
+// webFileName names a page after its URL. Linked documents keep their
+// extension, other pages are extracted as HTML.
+func webFileName(u *url.URL) string {
+	base := path.Base(u.Path)
+	if base != "." && base != "/" && supported(base) {
+		return base
+	}
+	name := strings.Trim(u.Host+u.Path, "/")
+	if u.RawQuery != "" {
+		name += "?" + u.RawQuery
+	}
+	return name + ".html"
+}
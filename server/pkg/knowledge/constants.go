@@ -62,6 +62,14 @@ const (
 	MetadataKeyUserID = "user_id"
 	MetadataKeyName   = "name"
 	MetadataKeySource = "source"
+
+	// Chunk provenance, stored with every chunk written by the ingestion pipeline
+	MetadataKeyDocumentID = "document_id"
+	MetadataKeyChunkID    = "chunk_id"
+	MetadataKeyTitle      = "title"
+	MetadataKeyPage       = "page"
+	MetadataKeyOffset     = "offset"
+	MetadataKeyChunkIndex = "chunk_index"
 )
 
 // Metadata value constants
@@ -77,6 +85,7 @@ const KnowledgeNameSeparator = "-"
 const (
 	ErrKnowledgeBaseDisabled    = "knowledge base feature is disabled, please set KNOWLEDGE_BASE_ENABLED=true in config"
 	ErrUnsupportedProvider      = "unsupported knowledge base provider: %s"
+	ErrUnsupportedEmbedder      = "unsupported embedding provider: %s"
 	ErrAccessKeyRequired        = "access_key_id and access_key_secret are required"
 	ErrApiKeyRequired           = "api_key is required"
 	ErrCollectionNameRequired   = "collection_name is required"
@@ -92,4 +101,8 @@ const (
 	ErrIndexDeleteFailed        = "failed to delete knowledge base"
 	ErrDatabaseDeleteFailed     = "failed to delete database record"
 	ErrQueryKnowledgeListFailed = "failed to query knowledge base list"
+	ErrFileTooLarge             = "file is too large"
+	ErrInvalidChunkOptions      = "invalid chunking options"
+	ErrIngestSubmitFailed       = "failed to submit ingestion job"
+	ErrIngestJobNotFound        = "ingestion job not found"
 )
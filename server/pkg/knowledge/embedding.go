@@ -0,0 +1,152 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// EmbeddingProviderOpenAI OpenAI Embeddings API
+	EmbeddingProviderOpenAI = "openai"
+	// EmbeddingProviderAzure Azure OpenAI embedding deployment
+	EmbeddingProviderAzure = "azure"
+	// EmbeddingProviderQwen Aliyun DashScope (Qwen) text embedding, OpenAI compatible mode
+	EmbeddingProviderQwen = "qwen"
+	// EmbeddingProviderOllama local embedding model served by Ollama
+	EmbeddingProviderOllama = "ollama"
+)
+
+// Embedding config keys, shared by the vector store providers so any backend
+// can be combined with any embedding model
+const (
+	ConfigKeyEmbeddingProvider   = "embedding_provider"
+	ConfigKeyEmbeddingModel      = "embedding_model"
+	ConfigKeyEmbeddingApiKey     = "embedding_api_key"
+	ConfigKeyEmbeddingBaseURL    = "embedding_base_url"
+	ConfigKeyEmbeddingDimension  = "embedding_dimension"
+	ConfigKeyEmbeddingBatchSize  = "embedding_batch_size"
+	ConfigKeyEmbeddingApiVersion = "embedding_api_version" // Azure only
+	ConfigKeyEmbeddingCacheSize  = "embedding_cache_size"  // 0 uses the default, negative disables the cache
+)
+
+// Embedder turns text into vectors, independent of where the vectors are stored
+type Embedder interface {
+	// Provider returns embedding provider name
+	Provider() string
+
+	// Model returns embedding model name
+	Model() string
+
+	// Dimension returns vector dimension, 0 until known when not configured
+	Dimension() int
+
+	// Embed embeds a single text
+	Embed(ctx context.Context, text string) ([]float32, error)
+
+	// EmbedBatch embeds texts, the result has the same order as texts.
+	// Large inputs are split into several requests by the provider's batch size
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedderFactory creates an embedder from config
+type EmbedderFactory func(config map[string]interface{}) (Embedder, error)
+
+var (
+	embedderMu        sync.RWMutex
+	embedderFactories = make(map[string]EmbedderFactory)
+)
+
+// RegisterEmbedder registers an embedding provider
+func RegisterEmbedder(name string, factory EmbedderFactory) {
+	embedderMu.Lock()
+	defer embedderMu.Unlock()
+	embedderFactories[name] = factory
+}
+
+// ListEmbedders lists all registered embedding providers
+func ListEmbedders() []string {
+	embedderMu.RLock()
+	defer embedderMu.RUnlock()
+	names := make([]string, 0, len(embedderFactories))
+	for name := range embedderFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewEmbedder creates an embedder of the given provider. Config uses the
+// embedding_* keys; unless the cache is disabled the embedder is wrapped with
+// an in-memory cache of recent texts.
+func NewEmbedder(provider string, config map[string]interface{}) (Embedder, error) {
+	embedderMu.RLock()
+	factory, ok := embedderFactories[strings.ToLower(provider)]
+	embedderMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf(ErrUnsupportedEmbedder, provider)
+	}
+
+	embedder, err := factory(config)
+	if err != nil {
+		return nil, err
+	}
+	cacheSize := getIntFromConfig(config, ConfigKeyEmbeddingCacheSize)
+	if cacheSize < 0 {
+		return embedder, nil
+	}
+	return NewCachedEmbedder(embedder, cacheSize), nil
+}
+
+// embedderFromConfig creates the embedder configured for a vector store,
+// nil when embedding_provider is not set
+func embedderFromConfig(config map[string]interface{}) (Embedder, error) {
+	provider := getStringFromConfig(config, ConfigKeyEmbeddingProvider)
+	if provider == "" {
+		return nil, nil
+	}
+	return NewEmbedder(provider, config)
+}
+
+// resolveQueryEmbedding returns the vector to search with: the "embedding"
+// filter when the caller already computed it, otherwise the query embedded by
+// the store's embedder
+func resolveQueryEmbedding(ctx context.Context, embedder Embedder, options SearchOptions, store string) ([]float32, error) {
+	if vector := getFloatVectorFromConfig(options.Filter, "embedding"); len(vector) > 0 {
+		return vector, nil
+	}
+	if embedder == nil || strings.TrimSpace(options.Query) == "" {
+		return nil, fmt.Errorf("embedding vector is required for %s search", store)
+	}
+	vector, err := embedder.Embed(ctx, options.Query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	return vector, nil
+}
+
+// embedInBatches splits texts into batches of at most size and concatenates
+// the vectors returned by embed
+func embedInBatches(ctx context.Context, texts []string, size int, embed func(ctx context.Context, batch []string) ([][]float32, error)) ([][]float32, error) {
+	if size <= 0 {
+		size = len(texts)
+	}
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += size {
+		end := start + size
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("embedding returned %d vectors for %d texts", len(batch), end-start)
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
@@ -0,0 +1,100 @@
+package knowledge
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// DefaultEmbeddingCacheSize 默认缓存的文本数
+const DefaultEmbeddingCacheSize = 1024
+
+// cachedEmbedder 为 Embedder 增加 LRU 缓存，相同文本（如重复的查询）不再请求模型
+type cachedEmbedder struct {
+	Embedder
+	size int
+
+	mu      sync.Mutex
+	order   *list.List // 最近使用的在前
+	entries map[string]*list.Element
+}
+
+type embeddingCacheEntry struct {
+	text   string
+	vector []float32
+}
+
+// NewCachedEmbedder 用容量为 size 的 LRU 缓存包装 embedder，size <= 0 使用默认容量
+func NewCachedEmbedder(embedder Embedder, size int) Embedder {
+	if size <= 0 {
+		size = DefaultEmbeddingCacheSize
+	}
+	return &cachedEmbedder{
+		Embedder: embedder,
+		size:     size,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *cachedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := c.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch 只把未命中缓存的文本交给底层 embedder，同一批内的重复文本只请求一次
+func (c *cachedEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	missing := make([]string, 0, len(texts))
+	positions := make(map[string][]int)
+
+	c.mu.Lock()
+	for i, text := range texts {
+		if elem, ok := c.entries[text]; ok {
+			c.order.MoveToFront(elem)
+			vectors[i] = elem.Value.(*embeddingCacheEntry).vector
+			continue
+		}
+		if _, ok := positions[text]; !ok {
+			missing = append(missing, text)
+		}
+		positions[text] = append(positions[text], i)
+	}
+	c.mu.Unlock()
+
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+	embedded, err := c.Embedder.EmbedBatch(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, text := range missing {
+		for _, pos := range positions[text] {
+			vectors[pos] = embedded[i]
+		}
+		c.put(text, embedded[i])
+	}
+	return vectors, nil
+}
+
+// put 写入缓存并淘汰最久未使用的条目，调用方持有锁
+func (c *cachedEmbedder) put(text string, vector []float32) {
+	if elem, ok := c.entries[text]; ok {
+		elem.Value.(*embeddingCacheEntry).vector = vector
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[text] = c.order.PushFront(&embeddingCacheEntry{text: text, vector: vector})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*embeddingCacheEntry).text)
+	}
+}
@@ -0,0 +1,104 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Ollama embedding 默认值
+const (
+	DefaultOllamaEmbeddingBaseURL = "http://localhost:11434"
+	DefaultOllamaEmbeddingModel   = "nomic-embed-text"
+)
+
+// ollamaEmbedder 本地 Ollama 服务的 embedding 实现，使用 /api/embed 批量接口
+type ollamaEmbedder struct {
+	model      string
+	endpoint   string
+	batchSize  int
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	dimension int
+}
+
+// newOllamaEmbedder 创建Ollama embedding实例
+// 配置选项：
+//   - embedding_base_url: Ollama 服务地址（默认: http://localhost:11434）
+//   - embedding_model: 模型（默认: nomic-embed-text）
+//   - embedding_dimension: 向量维度（可选，未配置时取首次响应的向量长度）
+//   - embedding_batch_size: 单次请求文本数（默认: 32）
+func newOllamaEmbedder(config map[string]interface{}) (Embedder, error) {
+	baseURL := stringOrDefault(getStringFromConfig(config, ConfigKeyEmbeddingBaseURL), DefaultOllamaEmbeddingBaseURL)
+	batchSize := getIntFromConfig(config, ConfigKeyEmbeddingBatchSize)
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+	return &ollamaEmbedder{
+		model:      stringOrDefault(getStringFromConfig(config, ConfigKeyEmbeddingModel), DefaultOllamaEmbeddingModel),
+		endpoint:   strings.TrimRight(baseURL, "/") + "/api/embed",
+		batchSize:  batchSize,
+		httpClient: &http.Client{Timeout: defaultEmbeddingTimeout},
+		dimension:  getIntFromConfig(config, ConfigKeyEmbeddingDimension),
+	}, nil
+}
+
+func (e *ollamaEmbedder) Provider() string {
+	return EmbeddingProviderOllama
+}
+
+func (e *ollamaEmbedder) Model() string {
+	return e.model
+}
+
+func (e *ollamaEmbedder) Dimension() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.dimension
+}
+
+func (e *ollamaEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (e *ollamaEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return embedInBatches(ctx, texts, e.batchSize, e.embed)
+}
+
+// embed 发送一批文本
+func (e *ollamaEmbedder) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	reqBody := map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	}
+	var embedResp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := postJSON(ctx, e.httpClient, e.endpoint, nil, reqBody, &embedResp); err != nil {
+		return nil, fmt.Errorf("ollama embedding: %w", err)
+	}
+
+	if len(embedResp.Embeddings) > 0 && len(embedResp.Embeddings[0]) > 0 {
+		e.mu.Lock()
+		if e.dimension == 0 {
+			e.dimension = len(embedResp.Embeddings[0])
+		}
+		e.mu.Unlock()
+	}
+	return embedResp.Embeddings, nil
+}
+
+// 注册Ollama embedding提供者
+func init() {
+	RegisterEmbedder(EmbeddingProviderOllama, newOllamaEmbedder)
+}
@@ -0,0 +1,236 @@
+package knowledge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenAI 兼容 embedding 的默认值
+const (
+	DefaultOpenAIEmbeddingBaseURL = "https://api.openai.com/v1"
+	DefaultOpenAIEmbeddingModel   = "text-embedding-3-small"
+	DefaultQwenEmbeddingBaseURL   = "https://dashscope.aliyuncs.com/compatible-mode/v1"
+	DefaultQwenEmbeddingModel     = "text-embedding-v3"
+	DefaultAzureEmbeddingVersion  = "2024-02-01"
+
+	defaultEmbeddingTimeout = 30 * time.Second
+)
+
+// openAIEmbedder OpenAI 风格 /embeddings 接口的实现，
+// OpenAI、Azure OpenAI 与 DashScope 兼容模式只有地址、认证头和批量上限不同
+type openAIEmbedder struct {
+	provider   string
+	model      string
+	endpoint   string
+	authHeader string
+	authValue  string
+	batchSize  int
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	dimension int // 配置的维度，未配置时取首次响应的向量长度
+	sendDims  bool
+}
+
+// newOpenAIEmbedder 创建OpenAI embedding实例
+// 配置选项：
+//   - embedding_api_key: API Key（必需）
+//   - embedding_base_url: Base URL（默认: https://api.openai.com/v1）
+//   - embedding_model: 模型（默认: text-embedding-3-small）
+//   - embedding_dimension: 输出维度（可选，text-embedding-3 支持截断）
+//   - embedding_batch_size: 单次请求文本数（默认: 256）
+func newOpenAIEmbedder(config map[string]interface{}) (Embedder, error) {
+	apiKey := getStringFromConfig(config, ConfigKeyEmbeddingApiKey)
+	if apiKey == "" {
+		return nil, fmt.Errorf("embedding_api_key is required for openai embedding")
+	}
+	baseURL := stringOrDefault(getStringFromConfig(config, ConfigKeyEmbeddingBaseURL), DefaultOpenAIEmbeddingBaseURL)
+	return newOpenAICompatibleEmbedder(EmbeddingProviderOpenAI, config,
+		strings.TrimRight(baseURL, "/")+"/embeddings", "Authorization", "Bearer "+apiKey,
+		DefaultOpenAIEmbeddingModel, 256), nil
+}
+
+// newQwenEmbedder 创建通义千问（DashScope 兼容模式）embedding实例
+// 配置选项：
+//   - embedding_api_key: DashScope API Key（必需）
+//   - embedding_base_url: Base URL（默认: https://dashscope.aliyuncs.com/compatible-mode/v1）
+//   - embedding_model: 模型（默认: text-embedding-v3）
+//   - embedding_batch_size: 单次请求文本数（默认: 10，DashScope 的上限）
+func newQwenEmbedder(config map[string]interface{}) (Embedder, error) {
+	apiKey := getStringFromConfig(config, ConfigKeyEmbeddingApiKey)
+	if apiKey == "" {
+		return nil, fmt.Errorf("embedding_api_key is required for qwen embedding")
+	}
+	baseURL := stringOrDefault(getStringFromConfig(config, ConfigKeyEmbeddingBaseURL), DefaultQwenEmbeddingBaseURL)
+	return newOpenAICompatibleEmbedder(EmbeddingProviderQwen, config,
+		strings.TrimRight(baseURL, "/")+"/embeddings", "Authorization", "Bearer "+apiKey,
+		DefaultQwenEmbeddingModel, 10), nil
+}
+
+// newAzureEmbedder 创建Azure OpenAI embedding实例，模型由部署决定
+// 配置选项：
+//   - embedding_api_key: API Key（必需）
+//   - embedding_base_url: 资源地址，如 https://{resource}.openai.azure.com（必需）
+//   - embedding_model: 部署名称（必需）
+//   - embedding_api_version: API 版本（默认: 2024-02-01）
+//   - embedding_batch_size: 单次请求文本数（默认: 16）
+func newAzureEmbedder(config map[string]interface{}) (Embedder, error) {
+	apiKey := getStringFromConfig(config, ConfigKeyEmbeddingApiKey)
+	baseURL := getStringFromConfig(config, ConfigKeyEmbeddingBaseURL)
+	deployment := getStringFromConfig(config, ConfigKeyEmbeddingModel)
+	if apiKey == "" || baseURL == "" || deployment == "" {
+		return nil, fmt.Errorf("embedding_api_key, embedding_base_url and embedding_model are required for azure embedding")
+	}
+	version := stringOrDefault(getStringFromConfig(config, ConfigKeyEmbeddingApiVersion), DefaultAzureEmbeddingVersion)
+	endpoint := fmt.Sprintf("%s/openai/deployments/%s/embeddings?api-version=%s",
+		strings.TrimRight(baseURL, "/"), url.PathEscape(deployment), url.QueryEscape(version))
+	return newOpenAICompatibleEmbedder(EmbeddingProviderAzure, config, endpoint, "api-key", apiKey, deployment, 16), nil
+}
+
+func newOpenAICompatibleEmbedder(provider string, config map[string]interface{}, endpoint, authHeader, authValue, defaultModel string, defaultBatch int) *openAIEmbedder {
+	batchSize := getIntFromConfig(config, ConfigKeyEmbeddingBatchSize)
+	if batchSize <= 0 {
+		batchSize = defaultBatch
+	}
+	dimension := getIntFromConfig(config, ConfigKeyEmbeddingDimension)
+	return &openAIEmbedder{
+		provider:   provider,
+		model:      stringOrDefault(getStringFromConfig(config, ConfigKeyEmbeddingModel), defaultModel),
+		endpoint:   endpoint,
+		authHeader: authHeader,
+		authValue:  authValue,
+		batchSize:  batchSize,
+		httpClient: &http.Client{Timeout: defaultEmbeddingTimeout},
+		dimension:  dimension,
+		sendDims:   dimension > 0,
+	}
+}
+
+func (e *openAIEmbedder) Provider() string {
+	return e.provider
+}
+
+func (e *openAIEmbedder) Model() string {
+	return e.model
+}
+
+func (e *openAIEmbedder) Dimension() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.dimension
+}
+
+func (e *openAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (e *openAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return embedInBatches(ctx, texts, e.batchSize, e.embed)
+}
+
+// embed 发送一批文本
+func (e *openAIEmbedder) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	reqBody := map[string]interface{}{
+		"input":           texts,
+		"encoding_format": "float",
+	}
+	// Azure 的模型由部署决定，不需要 model 字段
+	if e.provider != EmbeddingProviderAzure {
+		reqBody["model"] = e.model
+	}
+	if e.sendDims {
+		reqBody["dimensions"] = e.dimension
+	}
+
+	var embedResp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := postJSON(ctx, e.httpClient, e.endpoint, map[string]string{e.authHeader: e.authValue}, reqBody, &embedResp); err != nil {
+		return nil, fmt.Errorf("%s embedding: %w", e.provider, err)
+	}
+
+	// 按 index 还原输入顺序
+	sort.Slice(embedResp.Data, func(i, j int) bool { return embedResp.Data[i].Index < embedResp.Data[j].Index })
+	vectors := make([][]float32, 0, len(embedResp.Data))
+	for _, item := range embedResp.Data {
+		vectors = append(vectors, item.Embedding)
+	}
+	e.learnDimension(vectors)
+	return vectors, nil
+}
+
+func (e *openAIEmbedder) learnDimension(vectors [][]float32) {
+	if len(vectors) == 0 || len(vectors[0]) == 0 {
+		return
+	}
+	e.mu.Lock()
+	if e.dimension == 0 {
+		e.dimension = len(vectors[0])
+	}
+	e.mu.Unlock()
+}
+
+// postJSON 发送 JSON 请求并解析 JSON 响应，非 2xx 状态连同响应体作为错误返回
+func postJSON(ctx context.Context, httpClient *http.Client, endpoint string, headers map[string]string, reqBody interface{}, out interface{}) error {
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// stringOrDefault value 为空时返回 def
+func stringOrDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// 注册 OpenAI 兼容的 embedding 提供者
+func init() {
+	RegisterEmbedder(EmbeddingProviderOpenAI, newOpenAIEmbedder)
+	RegisterEmbedder(EmbeddingProviderAzure, newAzureEmbedder)
+	RegisterEmbedder(EmbeddingProviderQwen, newQwenEmbedder)
+}
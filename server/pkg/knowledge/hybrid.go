@@ -0,0 +1,277 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// RetrievalModeVector searches the knowledge base provider only (default)
+	RetrievalModeVector = "vector"
+	// RetrievalModeHybrid fuses keyword (BM25) and vector results
+	RetrievalModeHybrid = "hybrid"
+)
+
+// Retrieval config keys, stored in the knowledge base config
+const (
+	ConfigKeyRetrievalMode   = "retrieval_mode"
+	ConfigKeyRRFK            = "rrf_k"
+	ConfigKeyCandidateFactor = "candidate_factor"
+	ConfigKeyRerankProvider  = "rerank_provider" // set to enable the rerank step
+	ConfigKeyRerankBaseURL   = "rerank_base_url"
+	ConfigKeyRerankApiKey    = "rerank_api_key"
+	ConfigKeyRerankModel     = "rerank_model"
+)
+
+// Retrieval default values
+const (
+	DefaultRRFK                = 60
+	DefaultRetrievalCandidates = 4 // each retriever returns TopK * factor candidates for fusion
+)
+
+// KeywordSearcher full-text (BM25) retrieval over the chunks of a knowledge base
+type KeywordSearcher interface {
+	// KeywordSearch returns the chunks of knowledgeKey matching query, best first
+	KeywordSearch(ctx context.Context, knowledgeKey string, query string, topK int) ([]SearchResult, error)
+}
+
+var (
+	keywordSearcherMu      sync.RWMutex
+	defaultKeywordSearcher KeywordSearcher
+)
+
+// SetDefaultKeywordSearcher sets the keyword searcher used by hybrid retrieval,
+// nil disables the keyword side
+func SetDefaultKeywordSearcher(searcher KeywordSearcher) {
+	keywordSearcherMu.Lock()
+	defer keywordSearcherMu.Unlock()
+	defaultKeywordSearcher = searcher
+}
+
+// DefaultKeywordSearcher returns the keyword searcher used by hybrid retrieval, may be nil
+func DefaultKeywordSearcher() KeywordSearcher {
+	keywordSearcherMu.RLock()
+	defer keywordSearcherMu.RUnlock()
+	return defaultKeywordSearcher
+}
+
+// RetrievalConfig per knowledge base retrieval settings
+type RetrievalConfig struct {
+	// Mode RetrievalModeVector or RetrievalModeHybrid
+	Mode string
+	// RRFK the k constant of reciprocal rank fusion, larger values flatten rank differences
+	RRFK int
+	// CandidateFactor each retriever fetches TopK * CandidateFactor candidates
+	CandidateFactor int
+	// Rerank cross-encoder rerank step, disabled when Provider is empty
+	Rerank RerankConfig
+}
+
+// RetrievalConfigFromMap reads the retrieval settings from a knowledge base config
+func RetrievalConfigFromMap(config map[string]interface{}) RetrievalConfig {
+	cfg := RetrievalConfig{
+		Mode:            strings.ToLower(getStringFromConfig(config, ConfigKeyRetrievalMode)),
+		RRFK:            getIntFromConfig(config, ConfigKeyRRFK),
+		CandidateFactor: getIntFromConfig(config, ConfigKeyCandidateFactor),
+		Rerank: RerankConfig{
+			Provider: getStringFromConfig(config, ConfigKeyRerankProvider),
+			BaseURL:  getStringFromConfig(config, ConfigKeyRerankBaseURL),
+			APIKey:   getStringFromConfig(config, ConfigKeyRerankApiKey),
+			Model:    getStringFromConfig(config, ConfigKeyRerankModel),
+		},
+	}
+	if cfg.Mode != RetrievalModeHybrid {
+		cfg.Mode = RetrievalModeVector
+	}
+	if cfg.RRFK <= 0 {
+		cfg.RRFK = DefaultRRFK
+	}
+	if cfg.CandidateFactor <= 0 {
+		cfg.CandidateFactor = DefaultRetrievalCandidates
+	}
+	return cfg
+}
+
+// Retriever runs the retrieval configured for a knowledge base: the provider
+// search, optionally fused with keyword search, optionally reranked
+type Retriever struct {
+	KB       KnowledgeBase
+	Keyword  KeywordSearcher // nil falls back to vector only
+	Reranker Reranker        // nil skips the rerank step
+	Config   RetrievalConfig
+}
+
+// NewRetriever creates the retriever for kb from its config, using the
+// default keyword searcher
+func NewRetriever(kb KnowledgeBase, config map[string]interface{}) (*Retriever, error) {
+	cfg := RetrievalConfigFromMap(config)
+	reranker, err := NewReranker(cfg.Rerank)
+	if err != nil {
+		return nil, err
+	}
+	return &Retriever{
+		KB:       kb,
+		Keyword:  DefaultKeywordSearcher(),
+		Reranker: reranker,
+		Config:   cfg,
+	}, nil
+}
+
+// Search returns the TopK results for options.Query
+func (r *Retriever) Search(ctx context.Context, knowledgeKey string, options SearchOptions) ([]SearchResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	topK := options.TopK
+	if topK <= 0 {
+		topK = 10
+	}
+
+	hybrid := r.Config.Mode == RetrievalModeHybrid && r.Keyword != nil
+	if !hybrid && r.Reranker == nil {
+		return r.KB.Search(ctx, knowledgeKey, options)
+	}
+
+	candidates := options
+	candidates.TopK = topK * r.Config.CandidateFactor
+	// The threshold applies to the final scores, not to the candidates
+	candidates.Threshold = 0
+
+	var results []SearchResult
+	if hybrid {
+		fused, err := r.hybridSearch(ctx, knowledgeKey, candidates)
+		if err != nil {
+			return nil, err
+		}
+		results = fused
+	} else {
+		vector, err := r.KB.Search(ctx, knowledgeKey, candidates)
+		if err != nil {
+			return nil, err
+		}
+		results = vector
+	}
+
+	if r.Reranker != nil && len(results) > 0 {
+		reranked, err := r.Reranker.Rerank(ctx, options.Query, results, topK)
+		if err != nil {
+			logger.Warn("rerank failed, using fused results", zap.String("knowledgeKey", knowledgeKey), zap.Error(err))
+		} else {
+			results = reranked
+		}
+	}
+	return filterResults(results, options.Threshold, topK), nil
+}
+
+// hybridSearch runs vector and keyword search concurrently and fuses them.
+// A failing keyword search degrades to vector results, a failing vector
+// search to keyword results; only both failing is an error.
+func (r *Retriever) hybridSearch(ctx context.Context, knowledgeKey string, options SearchOptions) ([]SearchResult, error) {
+	var (
+		wg                    sync.WaitGroup
+		vector, keyword       []SearchResult
+		vectorErr, keywordErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		vector, vectorErr = r.KB.Search(ctx, knowledgeKey, options)
+	}()
+	go func() {
+		defer wg.Done()
+		keyword, keywordErr = r.Keyword.KeywordSearch(ctx, knowledgeKey, options.Query, options.TopK)
+	}()
+	wg.Wait()
+
+	switch {
+	case vectorErr != nil && keywordErr != nil:
+		return nil, fmt.Errorf("hybrid search failed: %w", vectorErr)
+	case vectorErr != nil:
+		logger.Warn("vector search failed, using keyword results only", zap.String("knowledgeKey", knowledgeKey), zap.Error(vectorErr))
+		return FuseRRF(r.Config.RRFK, keyword), nil
+	case keywordErr != nil:
+		logger.Warn("keyword search failed, using vector results only", zap.String("knowledgeKey", knowledgeKey), zap.Error(keywordErr))
+		return FuseRRF(r.Config.RRFK, vector), nil
+	}
+	return FuseRRF(r.Config.RRFK, vector, keyword), nil
+}
+
+// FuseRRF merges ranked result lists with reciprocal rank fusion: a result
+// scores the sum of 1/(k+rank) over the lists it appears in. Scores are
+// normalized to 0-1 by the best possible score, results sorted best first.
+// The same chunk in several lists is recognized by resultKey.
+func FuseRRF(k int, lists ...[]SearchResult) []SearchResult {
+	if k <= 0 {
+		k = DefaultRRFK
+	}
+	type fused struct {
+		result SearchResult
+		score  float64
+		first  int // order of first appearance, keeps ties stable
+	}
+	byKey := make(map[string]*fused)
+	order := 0
+	for _, list := range lists {
+		for rank, result := range list {
+			key := resultKey(result)
+			item, ok := byKey[key]
+			if !ok {
+				item = &fused{result: result, first: order}
+				byKey[key] = item
+				order++
+			} else if item.result.Metadata == nil && result.Metadata != nil {
+				item.result.Metadata = result.Metadata
+			}
+			item.score += 1 / float64(k+rank+1)
+		}
+	}
+
+	maxScore := float64(len(lists)) / float64(k+1)
+	items := make([]*fused, 0, len(byKey))
+	for _, item := range byKey {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].score != items[j].score {
+			return items[i].score > items[j].score
+		}
+		return items[i].first < items[j].first
+	})
+
+	results := make([]SearchResult, 0, len(items))
+	for _, item := range items {
+		item.result.Score = item.score / maxScore
+		results = append(results, item.result)
+	}
+	return results
+}
+
+// resultKey identifies a chunk across retrievers: the chunk id in metadata
+// when present, otherwise its source and content
+func resultKey(result SearchResult) string {
+	if id, ok := result.Metadata[MetadataKeyChunkID]; ok {
+		return fmt.Sprintf("id:%v", id)
+	}
+	return result.Source + "\x00" + strings.TrimSpace(result.Content)
+}
+
+// filterResults drops results below threshold and keeps at most topK
+func filterResults(results []SearchResult, threshold float64, topK int) []SearchResult {
+	filtered := results[:0]
+	for _, result := range results {
+		if threshold > 0 && result.Score < threshold {
+			continue
+		}
+		filtered = append(filtered, result)
+		if len(filtered) == topK {
+			break
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,274 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
+)
+
+// Chunking strategies
+const (
+	// StrategyFixed cuts windows of Size characters overlapping by Overlap
+	StrategyFixed = "fixed"
+	// StrategySentence packs whole sentences up to Size characters and
+	// repeats the last sentences of a chunk (up to Overlap characters) in the next one
+	StrategySentence = "sentence"
+	// StrategySemantic packs sentences while adjacent sentences stay similar
+	// in meaning, starting a new chunk at a topic shift or at Size characters
+	StrategySemantic = "semantic"
+)
+
+// Chunking defaults, sizes are in characters (runes)
+const (
+	DefaultChunkSize         = 500
+	DefaultChunkOverlap      = 50
+	DefaultSemanticThreshold = 0.75
+)
+
+// ChunkOptions selects and tunes the chunking strategy. Zero fields use the defaults.
+type ChunkOptions struct {
+	Strategy string
+	Size     int
+	// Overlap is repeated between consecutive chunks, negative disables it
+	Overlap int
+	// SemanticThreshold is the cosine similarity between adjacent sentences
+	// below which the semantic strategy starts a new chunk
+	SemanticThreshold float64
+}
+
+func (o ChunkOptions) withDefaults() ChunkOptions {
+	if o.Strategy == "" {
+		o.Strategy = StrategySentence
+	}
+	if o.Size <= 0 {
+		o.Size = DefaultChunkSize
+	}
+	if o.Overlap < 0 {
+		o.Overlap = 0
+	} else if o.Overlap == 0 {
+		o.Overlap = DefaultChunkOverlap
+	}
+	if o.Overlap >= o.Size {
+		o.Overlap = o.Size / 4
+	}
+	if o.SemanticThreshold <= 0 {
+		o.SemanticThreshold = DefaultSemanticThreshold
+	}
+	return o
+}
+
+// Chunk is a piece of a document small enough to embed and retrieve
+type Chunk struct {
+	Index  int // position in the document
+	Text   string
+	Page   int // page number, 0 when the format has no pages
+	Offset int // character offset of the chunk in its page
+}
+
+// ChunkPages splits the pages of a document into chunks. Chunks never span
+// pages, so every chunk has an exact page and offset. The semantic strategy
+// needs an embedder to compare sentences.
+func ChunkPages(ctx context.Context, pages []Page, opts ChunkOptions, embedder knowledge.Embedder) ([]Chunk, error) {
+	opts = opts.withDefaults()
+	var chunks []Chunk
+	for _, page := range pages {
+		var (
+			spans []span
+			err   error
+		)
+		runes := []rune(page.Text)
+		switch opts.Strategy {
+		case StrategyFixed:
+			spans = fixedSpans(0, len(runes), opts.Size, opts.Overlap)
+		case StrategySentence:
+			spans = sentenceSpans(runes, opts)
+		case StrategySemantic:
+			if embedder == nil {
+				return nil, errors.New("semantic chunking requires an embedding provider")
+			}
+			spans, err = semanticSpans(ctx, runes, opts, embedder)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported chunking strategy: %s", opts.Strategy)
+		}
+
+		for _, s := range spans {
+			start, end := trimSpan(runes, s.start, s.end)
+			if start >= end {
+				continue
+			}
+			chunks = append(chunks, Chunk{
+				Index:  len(chunks),
+				Text:   string(runes[start:end]),
+				Page:   page.Number,
+				Offset: start,
+			})
+		}
+	}
+	return chunks, nil
+}
+
+// span is a [start, end) range of runes
+type span struct {
+	start, end int
+}
+
+func fixedSpans(start, end, size, overlap int) []span {
+	var spans []span
+	step := size - overlap
+	if step <= 0 {
+		step = size
+	}
+	for pos := start; pos < end; pos += step {
+		stop := pos + size
+		if stop >= end {
+			spans = append(spans, span{pos, end})
+			break
+		}
+		spans = append(spans, span{pos, stop})
+	}
+	return spans
+}
+
+// splitSentences splits text after sentence terminators (Western and CJK)
+// and at line breaks. Sentences keep their trailing punctuation and spaces.
+func splitSentences(runes []rune) []span {
+	var sentences []span
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		end := -1
+		switch {
+		case r == '\n':
+			end = i + 1
+		case strings.ContainsRune("。！？；!?;…", r):
+			end = i + 1
+		case r == '.' && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])):
+			end = i + 1
+		}
+		if end < 0 {
+			continue
+		}
+		// Closing quotes and brackets belong to the sentence
+		for end < len(runes) && strings.ContainsRune(`"'”’」』)）`, runes[end]) {
+			end++
+		}
+		for end < len(runes) && unicode.IsSpace(runes[end]) {
+			end++
+		}
+		sentences = append(sentences, span{start, end})
+		start = end
+		i = end - 1
+	}
+	if start < len(runes) {
+		sentences = append(sentences, span{start, len(runes)})
+	}
+
+	result := sentences[:0]
+	for _, s := range sentences {
+		if a, b := trimSpan(runes, s.start, s.end); a < b {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// limitSentences cuts sentences longer than size into fixed windows
+func limitSentences(sentences []span, size int) []span {
+	limited := make([]span, 0, len(sentences))
+	for _, s := range sentences {
+		if s.end-s.start <= size {
+			limited = append(limited, s)
+			continue
+		}
+		limited = append(limited, fixedSpans(s.start, s.end, size, 0)...)
+	}
+	return limited
+}
+
+func sentenceSpans(runes []rune, opts ChunkOptions) []span {
+	sentences := limitSentences(splitSentences(runes), opts.Size)
+	var spans []span
+	for first := 0; first < len(sentences); {
+		last := first
+		for last+1 < len(sentences) && sentences[last+1].end-sentences[first].start <= opts.Size {
+			last++
+		}
+		spans = append(spans, span{sentences[first].start, sentences[last].end})
+		if last+1 >= len(sentences) {
+			break
+		}
+
+		// Start the next chunk with the trailing sentences fitting in the overlap
+		next := last + 1
+		for next-1 > first && sentences[last].end-sentences[next-1].start <= opts.Overlap {
+			next--
+		}
+		first = next
+	}
+	return spans
+}
+
+func semanticSpans(ctx context.Context, runes []rune, opts ChunkOptions, embedder knowledge.Embedder) ([]span, error) {
+	sentences := limitSentences(splitSentences(runes), opts.Size)
+	if len(sentences) <= 1 {
+		return sentences, nil
+	}
+	texts := make([]string, len(sentences))
+	for i, s := range sentences {
+		texts[i] = string(runes[s.start:s.end])
+	}
+	vectors, err := embedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed sentences: %w", err)
+	}
+	if len(vectors) != len(sentences) {
+		return nil, fmt.Errorf("embedding returned %d vectors for %d sentences", len(vectors), len(sentences))
+	}
+
+	var spans []span
+	first := 0
+	for i := 1; i < len(sentences); i++ {
+		tooLong := sentences[i].end-sentences[first].start > opts.Size
+		if tooLong || cosine(vectors[i-1], vectors[i]) < opts.SemanticThreshold {
+			spans = append(spans, span{sentences[first].start, sentences[i-1].end})
+			first = i
+		}
+	}
+	spans = append(spans, span{sentences[first].start, sentences[len(sentences)-1].end})
+	return spans, nil
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// trimSpan narrows [start, end) to exclude surrounding whitespace
+func trimSpan(runes []rune, start, end int) (int, int) {
+	for start < end && unicode.IsSpace(runes[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(runes[end-1]) {
+		end--
+	}
+	return start, end
+}
@@ -0,0 +1,240 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedFormat is returned for files no extractor is registered for
+var ErrUnsupportedFormat = errors.New("unsupported document format")
+
+// Page is the text of one page of a document. Formats without pages yield a
+// single page with Number 0.
+type Page struct {
+	Number int
+	Text   string
+}
+
+// ExtractFunc extracts the text of a document
+type ExtractFunc func(data []byte) ([]Page, error)
+
+var (
+	extractorMu sync.RWMutex
+	extractors  = map[string]ExtractFunc{
+		".txt":      extractText,
+		".text":     extractText,
+		".md":       extractMarkdown,
+		".markdown": extractMarkdown,
+		".html":     extractHTML,
+		".htm":      extractHTML,
+		".docx":     extractDOCX,
+		".pdf":      extractPDF,
+	}
+)
+
+// RegisterExtractor registers the extractor of a file extension (".pdf"),
+// replacing the built-in one
+func RegisterExtractor(ext string, fn ExtractFunc) {
+	extractorMu.Lock()
+	defer extractorMu.Unlock()
+	extractors[strings.ToLower(ext)] = fn
+}
+
+// SupportedExtensions lists the file extensions that can be extracted
+func SupportedExtensions() []string {
+	extractorMu.RLock()
+	defer extractorMu.RUnlock()
+	exts := make([]string, 0, len(extractors))
+	for ext := range extractors {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// Extract extracts the text of a document, the format is chosen by the file
+// extension. Pages without text are dropped.
+func Extract(filename string, data []byte) ([]Page, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	extractorMu.RLock()
+	fn, ok := extractors[ext]
+	extractorMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, ext)
+	}
+
+	pages, err := fn(data)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", filepath.Base(filename), err)
+	}
+	result := pages[:0]
+	for _, page := range pages {
+		page.Text = strings.TrimSpace(page.Text)
+		if page.Text != "" {
+			result = append(result, page)
+		}
+	}
+	return result, nil
+}
+
+func extractText(data []byte) ([]Page, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, errors.New("text is not valid UTF-8")
+	}
+	return []Page{{Text: normalizeNewlines(string(data))}}, nil
+}
+
+var (
+	markdownFrontMatter = regexp.MustCompile(`(?s)\A---\n.*?\n---\n`)
+	markdownImage       = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink        = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownHeading     = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+	markdownFence       = regexp.MustCompile("(?m)^\\s*(```|~~~).*$")
+	markdownEmphasis    = regexp.MustCompile(`(\*\*|__|~~|\*|` + "`" + `)`)
+	markdownListMarker  = regexp.MustCompile(`(?m)^\s*(?:[-*+]|\d+\.)\s+`)
+	markdownQuote       = regexp.MustCompile(`(?m)^\s*>\s?`)
+)
+
+// extractMarkdown keeps the prose of a Markdown document: link and image
+// texts stay, URLs and formatting marks are removed
+func extractMarkdown(data []byte) ([]Page, error) {
+	pages, err := extractText(data)
+	if err != nil {
+		return nil, err
+	}
+	text := pages[0].Text
+	text = markdownFrontMatter.ReplaceAllString(text, "")
+	text = markdownImage.ReplaceAllString(text, "$1")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = markdownFence.ReplaceAllString(text, "")
+	text = markdownHeading.ReplaceAllString(text, "")
+	text = markdownListMarker.ReplaceAllString(text, "")
+	text = markdownQuote.ReplaceAllString(text, "")
+	text = markdownEmphasis.ReplaceAllString(text, "")
+	return []Page{{Text: text}}, nil
+}
+
+var (
+	htmlInvisible  = regexp.MustCompile(`(?is)<(script|style|noscript|head)[^>]*>.*?</(script|style|noscript|head)>`)
+	htmlComment    = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlBlockBreak = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/h[1-6]|/tr|/section|/article)[^>]*>`)
+	htmlTag        = regexp.MustCompile(`<[^>]+>`)
+	blankLines     = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+	inlineSpaces   = regexp.MustCompile(`[ \t]+`)
+)
+
+// extractHTML strips scripts, styles and tags from an HTML page
+func extractHTML(data []byte) ([]Page, error) {
+	pages, err := extractText(data)
+	if err != nil {
+		return nil, err
+	}
+	text := pages[0].Text
+	text = htmlInvisible.ReplaceAllString(text, "")
+	text = htmlComment.ReplaceAllString(text, "")
+	text = htmlBlockBreak.ReplaceAllString(text, "\n")
+	text = htmlTag.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = inlineSpaces.ReplaceAllString(text, " ")
+	text = blankLines.ReplaceAllString(text, "\n\n")
+	return []Page{{Text: text}}, nil
+}
+
+// extractDOCX reads word/document.xml of a Word document. Explicit and
+// rendered page breaks start a new page.
+func extractDOCX(data []byte) ([]Page, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid docx: %w", err)
+	}
+	var document *zip.File
+	for _, f := range archive.File {
+		if f.Name == "word/document.xml" {
+			document = f
+			break
+		}
+	}
+	if document == nil {
+		return nil, errors.New("invalid docx: word/document.xml not found")
+	}
+	rc, err := document.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		pages  []Page
+		text   strings.Builder
+		inText bool
+	)
+	newPage := func() {
+		pages = append(pages, Page{Number: len(pages) + 1, Text: text.String()})
+		text.Reset()
+	}
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid docx: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteByte('\t')
+			case "br", "cr":
+				if xmlAttr(t, "type") == "page" {
+					newPage()
+				} else {
+					text.WriteByte('\n')
+				}
+			case "lastRenderedPageBreak":
+				newPage()
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+	newPage()
+	return pages, nil
+}
+
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func normalizeNewlines(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+}
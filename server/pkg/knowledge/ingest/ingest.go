@@ -0,0 +1,249 @@
+// Package ingest turns uploaded documents into searchable knowledge base
+// chunks: text extraction (PDF, DOCX, Markdown, HTML, plain text), chunking
+// with a configurable strategy, embedding and upsert into the vector store.
+// Jobs run asynchronously on a Queue that reports their progress.
+//
+// Knowledge bases that parse files themselves (for example Aliyun Bailian)
+// receive the original file through KnowledgeBase.UploadDocument instead.
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"path/filepath"
+	"sync"
+
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
+	"github.com/google/uuid"
+)
+
+// Stages reported while a document is ingested
+const (
+	StageExtracting = "extracting"
+	StageChunking   = "chunking"
+	StageEmbedding  = "embedding"
+	StageIndexing   = "indexing"
+	StageUploading  = "uploading" // knowledge bases parsing files themselves
+)
+
+// DefaultEmbedBatchSize is the number of chunks embedded and upserted together
+const DefaultEmbedBatchSize = 32
+
+// KeywordIndexer indexes chunks for the keyword side of hybrid retrieval
+type KeywordIndexer interface {
+	IndexChunks(ctx context.Context, knowledgeKey string, chunks []knowledge.VectorChunk) error
+}
+
+var (
+	keywordIndexerMu      sync.RWMutex
+	defaultKeywordIndexer KeywordIndexer
+)
+
+// SetDefaultKeywordIndexer sets the indexer new pipelines write chunks to,
+// nil disables keyword indexing
+func SetDefaultKeywordIndexer(indexer KeywordIndexer) {
+	keywordIndexerMu.Lock()
+	defer keywordIndexerMu.Unlock()
+	defaultKeywordIndexer = indexer
+}
+
+// DefaultKeywordIndexer returns the indexer new pipelines write chunks to, may be nil
+func DefaultKeywordIndexer() KeywordIndexer {
+	keywordIndexerMu.RLock()
+	defer keywordIndexerMu.RUnlock()
+	return defaultKeywordIndexer
+}
+
+// Source is a document to ingest
+type Source struct {
+	KnowledgeKey string
+	FileName     string
+	Data         []byte
+	// DocumentID identifies the document across re-ingestion, derived from
+	// the knowledge base and file name when empty
+	DocumentID string
+	// Metadata is stored with every chunk
+	Metadata map[string]interface{}
+}
+
+// Result summarizes an ingested document
+type Result struct {
+	DocumentID string `json:"documentId"`
+	Pages      int    `json:"pages"`
+	Chunks     int    `json:"chunks"`
+}
+
+// ProgressFunc receives the current stage and, while embedding, how many of
+// the chunks are done
+type ProgressFunc func(stage string, done, total int)
+
+// Pipeline ingests documents into one knowledge base
+type Pipeline struct {
+	KB knowledge.KnowledgeBase
+	// Embedder embeds the chunks, defaults to the embedder configured for KB
+	Embedder knowledge.Embedder
+	// Keyword optionally indexes the chunks for hybrid retrieval
+	Keyword   KeywordIndexer
+	Chunking  ChunkOptions
+	BatchSize int
+}
+
+// NewPipeline creates a pipeline for kb using its configured embedder and
+// the default keyword indexer
+func NewPipeline(kb knowledge.KnowledgeBase, chunking ChunkOptions) *Pipeline {
+	p := &Pipeline{
+		KB:        kb,
+		Keyword:   DefaultKeywordIndexer(),
+		Chunking:  chunking,
+		BatchSize: DefaultEmbedBatchSize,
+	}
+	if ekb, ok := kb.(knowledge.EmbeddingKnowledgeBase); ok {
+		p.Embedder = ekb.Embedder()
+	}
+	return p
+}
+
+// DocumentID returns the default document identifier of a file in a knowledge base
+func DocumentID(knowledgeKey, fileName string) string {
+	sum := sha256.Sum256([]byte(knowledgeKey + "\x00" + fileName))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ChunkID returns the identifier of the index-th chunk of a document. It is a
+// UUID, the only string form every vector store accepts as a point ID.
+func ChunkID(documentID string, index int) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s#%d", documentID, index))).String()
+}
+
+// Run ingests src. Re-ingesting a document replaces all its previous chunks.
+func (p *Pipeline) Run(ctx context.Context, src Source, progress ProgressFunc) (Result, error) {
+	if progress == nil {
+		progress = func(string, int, int) {}
+	}
+	if src.DocumentID == "" {
+		src.DocumentID = DocumentID(src.KnowledgeKey, src.FileName)
+	}
+	result := Result{DocumentID: src.DocumentID}
+
+	store, ok := p.KB.(knowledge.VectorStore)
+	if !ok {
+		progress(StageUploading, 0, 1)
+		if err := p.upload(ctx, src); err != nil {
+			return result, err
+		}
+		progress(StageUploading, 1, 1)
+		return result, nil
+	}
+	if p.Embedder == nil {
+		return result, errors.New("knowledge base has no embedding provider configured")
+	}
+
+	progress(StageExtracting, 0, 0)
+	pages, err := Extract(src.FileName, src.Data)
+	if err != nil {
+		return result, err
+	}
+	result.Pages = len(pages)
+
+	progress(StageChunking, 0, 0)
+	chunks, err := ChunkPages(ctx, pages, p.Chunking, p.Embedder)
+	if err != nil {
+		return result, err
+	}
+	if len(chunks) == 0 {
+		return result, errors.New("document has no text")
+	}
+	result.Chunks = len(chunks)
+
+	if err := store.DeleteDocumentChunks(ctx, src.KnowledgeKey, src.DocumentID); err != nil {
+		return result, fmt.Errorf("delete previous chunks: %w", err)
+	}
+
+	batchSize := p.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultEmbedBatchSize
+	}
+	vectorChunks := make([]knowledge.VectorChunk, 0, len(chunks))
+	progress(StageEmbedding, 0, len(chunks))
+	for start := 0; start < len(chunks); start += batchSize {
+		end := start + batchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		batch, err := p.embedChunks(ctx, src, chunks[start:end])
+		if err != nil {
+			return result, err
+		}
+		if err := store.UpsertChunks(ctx, src.KnowledgeKey, batch); err != nil {
+			return result, fmt.Errorf("upsert chunks: %w", err)
+		}
+		vectorChunks = append(vectorChunks, batch...)
+		progress(StageEmbedding, end, len(chunks))
+	}
+
+	if p.Keyword != nil {
+		progress(StageIndexing, 0, 0)
+		if err := p.Keyword.IndexChunks(ctx, src.KnowledgeKey, vectorChunks); err != nil {
+			return result, fmt.Errorf("index chunks for keyword search: %w", err)
+		}
+	}
+	return result, nil
+}
+
+func (p *Pipeline) embedChunks(ctx context.Context, src Source, chunks []Chunk) ([]knowledge.VectorChunk, error) {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	vectors, err := p.Embedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed chunks: %w", err)
+	}
+
+	batch := make([]knowledge.VectorChunk, len(chunks))
+	for i, chunk := range chunks {
+		metadata := make(map[string]interface{}, len(src.Metadata)+4)
+		for k, v := range src.Metadata {
+			metadata[k] = v
+		}
+		metadata[knowledge.MetadataKeyTitle] = src.FileName
+		metadata[knowledge.MetadataKeyPage] = chunk.Page
+		metadata[knowledge.MetadataKeyOffset] = chunk.Offset
+		metadata[knowledge.MetadataKeyChunkIndex] = chunk.Index
+		batch[i] = knowledge.VectorChunk{
+			ID:         ChunkID(src.DocumentID, chunk.Index),
+			DocumentID: src.DocumentID,
+			Content:    chunk.Text,
+			Vector:     vectors[i],
+			Metadata:   metadata,
+		}
+	}
+	return batch, nil
+}
+
+// upload hands the original file to a knowledge base that parses it itself
+func (p *Pipeline) upload(ctx context.Context, src Source) error {
+	header := &multipart.FileHeader{
+		Filename: filepath.Base(src.FileName),
+		Size:     int64(len(src.Data)),
+	}
+	file := memoryFile{bytes.NewReader(src.Data)}
+	if err := p.KB.UploadDocument(ctx, src.KnowledgeKey, file, header, src.Metadata); err != nil {
+		return fmt.Errorf("upload document: %w", err)
+	}
+	return nil
+}
+
+// memoryFile adapts an in-memory document to multipart.File
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error {
+	return nil
+}
@@ -0,0 +1,111 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestChunkPagesFixed(t *testing.T) {
+	pages := []Page{{Number: 1, Text: strings.Repeat("a", 25)}}
+	chunks, err := ChunkPages(context.Background(), pages, ChunkOptions{Strategy: StrategyFixed, Size: 10, Overlap: 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOffsets := []int{0, 8, 16}
+	if len(chunks) != len(wantOffsets) {
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(wantOffsets))
+	}
+	for i, chunk := range chunks {
+		if chunk.Offset != wantOffsets[i] || chunk.Page != 1 || chunk.Index != i {
+			t.Errorf("chunk %d = %+v", i, chunk)
+		}
+	}
+}
+
+func TestChunkPagesSentence(t *testing.T) {
+	text := "First sentence here. Second one follows! 第三句话。Fourth?"
+	chunks, err := ChunkPages(context.Background(), []Page{{Text: text}}, ChunkOptions{Size: 45, Overlap: -1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks: %+v", len(chunks), chunks)
+	}
+	if chunks[0].Text != "First sentence here. Second one follows!" {
+		t.Errorf("chunk 0 = %q", chunks[0].Text)
+	}
+	if chunks[1].Text != "第三句话。Fourth?" {
+		t.Errorf("chunk 1 = %q", chunks[1].Text)
+	}
+	if got := []rune(text)[chunks[1].Offset:]; string(got) != chunks[1].Text {
+		t.Errorf("offset %d does not point at chunk 1", chunks[1].Offset)
+	}
+}
+
+func TestChunkPagesSemanticRequiresEmbedder(t *testing.T) {
+	_, err := ChunkPages(context.Background(), []Page{{Text: "a. b."}}, ChunkOptions{Strategy: StrategySemantic}, nil)
+	if err == nil {
+		t.Fatal("expected an error without embedder")
+	}
+}
+
+func TestExtractMarkdown(t *testing.T) {
+	pages, err := Extract("readme.md", []byte("# Title\n\nSee [the docs](https://x.y) and **bold**.\n- item"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Title\n\nSee the docs and bold.\nitem"
+	if len(pages) != 1 || pages[0].Text != want {
+		t.Fatalf("got %+v, want %q", pages, want)
+	}
+}
+
+func TestExtractUnsupported(t *testing.T) {
+	if _, err := Extract("image.png", []byte{1}); err == nil {
+		t.Fatal("expected ErrUnsupportedFormat")
+	}
+}
+
+func TestExtractDOCX(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("word/document.xml")
+	w.Write([]byte(`<w:document xmlns:w="w"><w:body>` +
+		`<w:p><w:r><w:t>Page one</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:br w:type="page"/><w:t>Page two</w:t></w:r></w:p>` +
+		`</w:body></w:document>`))
+	zw.Close()
+
+	pages, err := Extract("doc.docx", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 || pages[0].Text != "Page one" || pages[1].Text != "Page two" || pages[1].Number != 2 {
+		t.Fatalf("got %+v", pages)
+	}
+}
+
+func TestExtractPDF(t *testing.T) {
+	var content bytes.Buffer
+	zw := zlib.NewWriter(&content)
+	zw.Write([]byte("BT /F1 12 Tf 72 712 Td (Hello) Tj [(Wor) -20 (ld)] TJ 0 -14 Td (Second \\(line\\)) Tj ET"))
+	zw.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Page >>\nendobj\n")
+	pdf.WriteString("4 0 obj\n<< /Length 0 /Filter /FlateDecode >>\nstream\n")
+	pdf.Write(content.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF")
+
+	pages, err := Extract("a.pdf", pdf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || pages[0].Text != "HelloWorld\nSecond (line)" {
+		t.Fatalf("got %+v", pages)
+	}
+}
@@ -0,0 +1,333 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// maxPDFStream bounds the inflated size of one PDF stream
+const maxPDFStream = 64 << 20
+
+var (
+	pdfStreamStart = regexp.MustCompile(`stream\r?\n`)
+	pdfSkipDict    = regexp.MustCompile(`/Subtype\s*/|/Type\s*/(XRef|ObjStm|Metadata|EmbeddedFile)|/Length1|/Length2`)
+)
+
+// extractPDF is a dependency free text extractor for text based PDFs: every
+// content stream is inflated and the strings shown between BT and ET are
+// collected, each content stream becoming a page in file order. It does not
+// handle encrypted files, scanned images or fonts with custom encodings (most
+// CJK PDFs); register a full parser with RegisterExtractor(".pdf", ...) for
+// those.
+func extractPDF(data []byte) ([]Page, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF")) {
+		return nil, errors.New("invalid pdf: missing header")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return nil, errors.New("encrypted pdf is not supported")
+	}
+
+	var pages []Page
+	for _, loc := range pdfStreamStart.FindAllIndex(data, -1) {
+		if bytes.HasSuffix(data[:loc[0]], []byte("end")) {
+			continue
+		}
+		// The stream dictionary sits between "N 0 obj" and the stream keyword
+		objStart := bytes.LastIndex(data[:loc[0]], []byte("obj"))
+		if objStart < 0 {
+			continue
+		}
+		dict := data[objStart:loc[0]]
+		if pdfSkipDict.Match(dict) {
+			continue
+		}
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		content := data[start : start+end]
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			inflated, err := inflate(content)
+			if err != nil {
+				continue
+			}
+			content = inflated
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			// Other filters (DCT, LZW, ...) are not text content
+			continue
+		}
+		if text := pdfContentText(content); strings.TrimSpace(text) != "" {
+			pages = append(pages, Page{Number: len(pages) + 1, Text: text})
+		}
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("no extractable text in pdf")
+	}
+	return pages, nil
+}
+
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var out bytes.Buffer
+	// A truncated stream still yields the text inflated so far
+	_, err = io.Copy(&out, io.LimitReader(r, maxPDFStream))
+	if err != nil && out.Len() == 0 {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// pdfContentText collects the text shown by a content stream. Line moves
+// (Td, TD, T*, ', ") become newlines and wide TJ kerning becomes a space.
+func pdfContentText(content []byte) string {
+	var (
+		text     strings.Builder
+		operands []pdfToken
+		inText   bool
+	)
+	lex := &pdfLexer{data: content}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+		if tok.kind != pdfOperator {
+			operands = append(operands, tok)
+			continue
+		}
+		switch tok.value {
+		case "BT":
+			inText = true
+		case "ET":
+			inText = false
+			text.WriteByte('\n')
+		case "Td", "TD", "T*":
+			if inText && text.Len() > 0 {
+				text.WriteByte('\n')
+			}
+		case "Tj":
+			if inText {
+				writePDFStrings(&text, operands)
+			}
+		case "'", "\"":
+			if inText {
+				text.WriteByte('\n')
+				writePDFStrings(&text, operands)
+			}
+		case "TJ":
+			if inText {
+				writePDFStrings(&text, operands)
+			}
+		}
+		operands = operands[:0]
+	}
+	return blankLines.ReplaceAllString(text.String(), "\n")
+}
+
+func writePDFStrings(text *strings.Builder, operands []pdfToken) {
+	for _, op := range operands {
+		switch op.kind {
+		case pdfString:
+			text.WriteString(decodePDFString(op.value))
+		case pdfNumber:
+			// In a TJ array a large negative adjustment separates words
+			if n, err := strconv.ParseFloat(op.value, 64); err == nil && n < -200 {
+				text.WriteByte(' ')
+			}
+		}
+	}
+}
+
+// decodePDFString decodes UTF-16BE strings (with BOM) and treats others as
+// PDFDocEncoding/Latin-1, dropping control characters
+func decodePDFString(raw string) string {
+	b := []byte(raw)
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	var s strings.Builder
+	for _, c := range b {
+		r := rune(c)
+		if unicode.IsPrint(r) || r == ' ' {
+			s.WriteRune(r)
+		}
+	}
+	return s.String()
+}
+
+type pdfTokenKind int
+
+const (
+	pdfOperator pdfTokenKind = iota
+	pdfString
+	pdfNumber
+	pdfOther
+)
+
+type pdfToken struct {
+	kind  pdfTokenKind
+	value string
+}
+
+// pdfLexer tokenizes a content stream. Arrays are flattened: their elements
+// become operands of the following operator, which is what TJ needs.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func (l *pdfLexer) next() (pdfToken, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c) || c == '[' || c == ']':
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			return pdfToken{kind: pdfString, value: l.literalString()}, true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.pos += 2
+			return pdfToken{kind: pdfOther, value: "<<"}, true
+		case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+			l.pos += 2
+			return pdfToken{kind: pdfOther, value: ">>"}, true
+		case c == '<':
+			return pdfToken{kind: pdfString, value: l.hexString()}, true
+		case c == '/':
+			start := l.pos
+			l.pos++
+			l.skipRegular()
+			return pdfToken{kind: pdfOther, value: string(l.data[start:l.pos])}, true
+		case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			start := l.pos
+			l.pos++
+			l.skipRegular()
+			return pdfToken{kind: pdfNumber, value: string(l.data[start:l.pos])}, true
+		default:
+			start := l.pos
+			l.pos++
+			l.skipRegular()
+			value := string(l.data[start:l.pos])
+			if value == "BI" {
+				// Inline image data is binary, skip to its end
+				if end := bytes.Index(l.data[l.pos:], []byte("EI")); end >= 0 {
+					l.pos += end + 2
+				} else {
+					l.pos = len(l.data)
+				}
+				continue
+			}
+			return pdfToken{kind: pdfOperator, value: value}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+func (l *pdfLexer) skipRegular() {
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+}
+
+func (l *pdfLexer) literalString() string {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos >= len(l.data) {
+				return string(out)
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return string(out)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return string(out)
+}
+
+func (l *pdfLexer) hexString() string {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i+1 < len(digits); i += 2 {
+		n, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			continue
+		}
+		out = append(out, byte(n))
+	}
+	return string(out)
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
@@ -0,0 +1,257 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Job statuses
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// Queue defaults
+const (
+	DefaultWorkers       = 2
+	DefaultQueueCapacity = 100
+	// DefaultJobRetention is how long finished jobs stay queryable
+	DefaultJobRetention = 24 * time.Hour
+)
+
+// ErrQueueFull is returned by Submit when the queue cannot take more jobs
+var ErrQueueFull = errors.New("ingestion queue is full")
+
+// ErrQueueClosed is returned by Submit after Close
+var ErrQueueClosed = errors.New("ingestion queue is closed")
+
+// Job is an ingestion job and its progress
+type Job struct {
+	ID           string     `json:"id"`
+	KnowledgeKey string     `json:"knowledgeKey"`
+	DocumentID   string     `json:"documentId"`
+	FileName     string     `json:"fileName"`
+	Status       string     `json:"status"`
+	Stage        string     `json:"stage,omitempty"`
+	Done         int        `json:"done"`
+	Total        int        `json:"total"`
+	Progress     float64    `json:"progress"` // 0-1
+	Pages        int        `json:"pages"`
+	Chunks       int        `json:"chunks"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+}
+
+type queuedJob struct {
+	job      *Job
+	pipeline *Pipeline
+	source   Source
+}
+
+// Queue runs ingestion jobs on a fixed pool of workers and keeps their
+// progress in memory
+type Queue struct {
+	mu        sync.RWMutex
+	jobs      map[string]*Job
+	pending   chan queuedJob
+	retention time.Duration
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closed    bool
+}
+
+// NewQueue starts a queue with the given number of workers holding at most
+// capacity pending jobs
+func NewQueue(workers, capacity int) *Queue {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if capacity <= 0 {
+		capacity = DefaultQueueCapacity
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		jobs:      make(map[string]*Job),
+		pending:   make(chan queuedJob, capacity),
+		retention: DefaultJobRetention,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	return q
+}
+
+var (
+	defaultQueue     *Queue
+	defaultQueueOnce sync.Once
+)
+
+// Default returns the process wide queue, started on first use
+func Default() *Queue {
+	defaultQueueOnce.Do(func() {
+		defaultQueue = NewQueue(DefaultWorkers, DefaultQueueCapacity)
+	})
+	return defaultQueue
+}
+
+// Submit queues src for ingestion by pipeline and returns a snapshot of the job
+func (q *Queue) Submit(pipeline *Pipeline, src Source) (Job, error) {
+	if src.DocumentID == "" {
+		src.DocumentID = DocumentID(src.KnowledgeKey, src.FileName)
+	}
+	now := time.Now()
+	job := &Job{
+		ID:           uuid.NewString(),
+		KnowledgeKey: src.KnowledgeKey,
+		DocumentID:   src.DocumentID,
+		FileName:     src.FileName,
+		Status:       JobPending,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return Job{}, ErrQueueClosed
+	}
+	q.pruneLocked(now)
+	select {
+	case q.pending <- queuedJob{job: job, pipeline: pipeline, source: src}:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	q.jobs[job.ID] = job
+	return *job, nil
+}
+
+// Get returns a snapshot of a job
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns the jobs of a knowledge base (all jobs when empty), newest first
+func (q *Queue) List(knowledgeKey string) []Job {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		if knowledgeKey == "" || job.KnowledgeKey == knowledgeKey {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Close stops accepting jobs, cancels running ones and waits for the workers
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.pending)
+	q.mu.Unlock()
+	q.cancel()
+	q.wg.Wait()
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for item := range q.pending {
+		q.run(item)
+	}
+}
+
+func (q *Queue) run(item queuedJob) {
+	if q.ctx.Err() != nil {
+		q.finish(item.job, Result{}, q.ctx.Err())
+		return
+	}
+	q.update(item.job, func(job *Job) {
+		job.Status = JobRunning
+	})
+
+	result, err := func() (result Result, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("ingestion panic: %v", r)
+			}
+		}()
+		return item.pipeline.Run(q.ctx, item.source, func(stage string, done, total int) {
+			q.update(item.job, func(job *Job) {
+				job.Stage = stage
+				job.Done = done
+				job.Total = total
+				if total > 0 {
+					job.Progress = float64(done) / float64(total)
+				}
+			})
+		})
+	}()
+	q.finish(item.job, result, err)
+}
+
+func (q *Queue) finish(job *Job, result Result, err error) {
+	q.update(job, func(job *Job) {
+		now := time.Now()
+		job.FinishedAt = &now
+		job.Pages = result.Pages
+		job.Chunks = result.Chunks
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = JobCompleted
+		job.Stage = ""
+		job.Progress = 1
+	})
+	if err != nil {
+		logger.Warn("document ingestion failed",
+			zap.String("jobId", job.ID),
+			zap.String("knowledgeKey", job.KnowledgeKey),
+			zap.String("fileName", job.FileName),
+			zap.Error(err))
+	}
+}
+
+func (q *Queue) update(job *Job, fn func(job *Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	fn(job)
+	job.UpdatedAt = time.Now()
+}
+
+// pruneLocked forgets jobs finished longer than the retention ago
+func (q *Queue) pruneLocked(now time.Time) {
+	for id, job := range q.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > q.retention {
+			delete(q.jobs, id)
+		}
+	}
+}
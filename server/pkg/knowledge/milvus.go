@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"strconv"
 	"strings"
 
 	"github.com/milvus-io/milvus-sdk-go/v2/client"
@@ -17,6 +18,7 @@ type milvusKnowledgeBase struct {
 	client         client.Client
 	collectionName string
 	dimension      int
+	embedder       Embedder // 可选，配置后 Search 可直接使用查询文本
 }
 
 // GetClient 获取 Milvus 客户端（用于直接操作 Milvus）
@@ -31,6 +33,7 @@ func (m *milvusKnowledgeBase) GetClient() client.Client {
 //   - password: 密码（可选）
 //   - collection_name: 集合名称（必需）
 //   - dimension: 向量维度（默认: 768）
+//   - embedding_provider 等: 查询文本的 embedding 配置（可选，见 NewEmbedder）
 func NewMilvusKnowledgeBase(config map[string]interface{}) (KnowledgeBase, error) {
 	addr := getStringFromConfig(config, "address")
 	if addr == "" {
@@ -50,6 +53,11 @@ func NewMilvusKnowledgeBase(config map[string]interface{}) (KnowledgeBase, error
 		dimension = 768 // 默认维度，通常与embedding模型相关
 	}
 
+	embedder, err := embedderFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建Milvus客户端
 	clientConfig := client.Config{
 		Address:  addr,
@@ -66,6 +74,7 @@ func NewMilvusKnowledgeBase(config map[string]interface{}) (KnowledgeBase, error
 		client:         milvusClient,
 		collectionName: collectionName,
 		dimension:      dimension,
+		embedder:       embedder,
 	}, nil
 }
 
@@ -78,11 +87,10 @@ func (m *milvusKnowledgeBase) Search(ctx context.Context, knowledgeKey string, o
 		ctx = context.Background()
 	}
 
-	// 获取embedding（这里需要外部提供embedding服务）
-	// 简化实现：假设query已经转换为embedding
-	queryEmbedding := getFloatVectorFromConfig(options.Filter, "embedding")
-	if len(queryEmbedding) == 0 {
-		return nil, fmt.Errorf("embedding vector is required for milvus search")
+	// 获取embedding：优先使用调用方提供的向量，否则用配置的embedder转换查询文本
+	queryEmbedding, err := resolveQueryEmbedding(ctx, m.embedder, options, "milvus")
+	if err != nil {
+		return nil, err
 	}
 
 	// 构建搜索参数
@@ -320,6 +328,61 @@ func (m *milvusKnowledgeBase) GetDocument(ctx context.Context, knowledgeKey stri
 	return io.NopCloser(strings.NewReader(content)), nil
 }
 
+func (m *milvusKnowledgeBase) Embedder() Embedder {
+	return m.embedder
+}
+
+// UpsertChunks 写入分片，来源信息保存在 metadata JSON 字段中
+func (m *milvusKnowledgeBase) UpsertChunks(ctx context.Context, knowledgeKey string, chunks []VectorChunk) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(chunks))
+	contents := make([]string, 0, len(chunks))
+	metadata := make([][]byte, 0, len(chunks))
+	vectors := make([][]float32, 0, len(chunks))
+	for _, chunk := range chunks {
+		payload := chunkPayload(chunk)
+		delete(payload, "content") // 内容单独存放在 content 字段
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal chunk metadata: %w", err)
+		}
+		ids = append(ids, chunk.ID)
+		contents = append(contents, chunk.Content)
+		metadata = append(metadata, data)
+		vectors = append(vectors, chunk.Vector)
+	}
+
+	_, err := m.client.Upsert(ctx, knowledgeKey, "",
+		entity.NewColumnVarChar("id", ids),
+		entity.NewColumnVarChar("content", contents),
+		entity.NewColumnJSONBytes("metadata", metadata),
+		entity.NewColumnFloatVector("embedding", len(vectors[0]), vectors),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert chunks: %w", err)
+	}
+	return nil
+}
+
+// DeleteDocumentChunks 按 metadata 中的 document_id 删除文档的全部分片
+func (m *milvusKnowledgeBase) DeleteDocumentChunks(ctx context.Context, knowledgeKey string, documentID string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	expr := fmt.Sprintf(`metadata["%s"] == %s`, MetadataKeyDocumentID, strconv.Quote(documentID))
+	if err := m.client.Delete(ctx, knowledgeKey, "", expr); err != nil {
+		return fmt.Errorf("failed to delete document chunks: %w", err)
+	}
+	return nil
+}
+
 // 工具函数
 
 func getFloatVectorFromConfig(filter map[string]interface{}, key string) []float32 {
@@ -17,6 +17,7 @@ type pineconeKnowledgeBase struct {
 	indexName  string
 	dimension  int
 	httpClient *http.Client
+	embedder   Embedder // 可选，配置后 Search 可直接使用查询文本
 }
 
 // NewPineconeKnowledgeBase 创建Pinecone知识库实例
@@ -41,12 +42,18 @@ func NewPineconeKnowledgeBase(config map[string]interface{}) (KnowledgeBase, err
 		dimension = 1536 // Pinecone默认维度
 	}
 
+	embedder, err := embedderFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &pineconeKnowledgeBase{
 		apiKey:     apiKey,
 		baseURL:    baseURL,
 		indexName:  indexName,
 		dimension:  dimension,
 		httpClient: &http.Client{},
+		embedder:   embedder,
 	}, nil
 }
 
@@ -59,10 +66,10 @@ func (p *pineconeKnowledgeBase) Search(ctx context.Context, knowledgeKey string,
 		ctx = context.Background()
 	}
 
-	// 获取embedding向量
-	queryEmbedding := getFloatVectorFromConfig(options.Filter, "embedding")
-	if len(queryEmbedding) == 0 {
-		return nil, fmt.Errorf("embedding vector is required for pinecone search")
+	// 获取embedding向量，未提供时用配置的embedder转换查询文本
+	queryEmbedding, err := resolveQueryEmbedding(ctx, p.embedder, options, "pinecone")
+	if err != nil {
+		return nil, err
 	}
 
 	topK := options.TopK
@@ -270,6 +277,69 @@ func (p *pineconeKnowledgeBase) GetDocument(ctx context.Context, knowledgeKey st
 	return nil, fmt.Errorf("document not found")
 }
 
+func (p *pineconeKnowledgeBase) Embedder() Embedder {
+	return p.embedder
+}
+
+// UpsertChunks 写入分片，内容与来源信息保存在 metadata 中
+func (p *pineconeKnowledgeBase) UpsertChunks(ctx context.Context, knowledgeKey string, chunks []VectorChunk) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	vectors := make([]map[string]interface{}, 0, len(chunks))
+	for _, chunk := range chunks {
+		vectors = append(vectors, map[string]interface{}{
+			"id":       chunk.ID,
+			"values":   chunk.Vector,
+			"metadata": chunkPayload(chunk),
+		})
+	}
+	url := fmt.Sprintf("%s/indexes/%s/vectors/upsert", p.baseURL, knowledgeKey)
+	return p.doJSON(ctx, url, map[string]interface{}{"vectors": vectors}, "upsert chunks")
+}
+
+// DeleteDocumentChunks 按 metadata 中的 document_id 删除文档的全部分片
+func (p *pineconeKnowledgeBase) DeleteDocumentChunks(ctx context.Context, knowledgeKey string, documentID string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	deleteReq := map[string]interface{}{
+		"filter": map[string]interface{}{
+			MetadataKeyDocumentID: map[string]interface{}{"$eq": documentID},
+		},
+	}
+	url := fmt.Sprintf("%s/indexes/%s/vectors/delete", p.baseURL, knowledgeKey)
+	return p.doJSON(ctx, url, deleteReq, "delete document chunks")
+}
+
+// doJSON 发送 POST JSON 请求，非 200 状态返回错误
+func (p *pineconeKnowledgeBase) doJSON(ctx context.Context, url string, body interface{}, action string) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(string(reqBody)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Api-Key", p.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to %s with status: %d", action, resp.StatusCode)
+	}
+	return nil
+}
+
 // 注册Pinecone提供者
 func init() {
 	RegisterKnowledgeBaseProvider(ProviderPinecone, func(config map[string]interface{}) (KnowledgeBase, error) {
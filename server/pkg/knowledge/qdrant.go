@@ -18,6 +18,7 @@ type qdrantKnowledgeBase struct {
 	collectionName string
 	dimension      int
 	httpClient     *http.Client
+	embedder       Embedder // 可选，配置后 Search 可直接使用查询文本
 }
 
 // NewQdrantKnowledgeBase 创建Qdrant知识库实例
@@ -38,6 +39,11 @@ func NewQdrantKnowledgeBase(config map[string]interface{}) (KnowledgeBase, error
 		dimension = 384 // Qdrant默认维度
 	}
 
+	embedder, err := embedderFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
 	httpClient := &http.Client{}
 	if apiKey != "" {
 		// 可以使用自定义transport添加认证
@@ -49,6 +55,7 @@ func NewQdrantKnowledgeBase(config map[string]interface{}) (KnowledgeBase, error
 		collectionName: collectionName,
 		dimension:      dimension,
 		httpClient:     httpClient,
+		embedder:       embedder,
 	}, nil
 }
 
@@ -61,10 +68,10 @@ func (q *qdrantKnowledgeBase) Search(ctx context.Context, knowledgeKey string, o
 		ctx = context.Background()
 	}
 
-	// 获取embedding向量
-	queryEmbedding := getFloatVectorFromConfig(options.Filter, "embedding")
-	if len(queryEmbedding) == 0 {
-		return nil, fmt.Errorf("embedding vector is required for qdrant search")
+	// 获取embedding向量，未提供时用配置的embedder转换查询文本
+	queryEmbedding, err := resolveQueryEmbedding(ctx, q.embedder, options, "qdrant")
+	if err != nil {
+		return nil, err
 	}
 
 	topK := options.TopK
@@ -384,6 +391,74 @@ func (q *qdrantKnowledgeBase) GetDocument(ctx context.Context, knowledgeKey stri
 	return io.NopCloser(strings.NewReader(content)), nil
 }
 
+func (q *qdrantKnowledgeBase) Embedder() Embedder {
+	return q.embedder
+}
+
+// UpsertChunks 写入分片，Qdrant 的点 ID 需为 UUID 或整数，分片 ID 由调用方保证
+func (q *qdrantKnowledgeBase) UpsertChunks(ctx context.Context, knowledgeKey string, chunks []VectorChunk) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	points := make([]map[string]interface{}, 0, len(chunks))
+	for _, chunk := range chunks {
+		points = append(points, map[string]interface{}{
+			"id":      chunk.ID,
+			"vector":  chunk.Vector,
+			"payload": chunkPayload(chunk),
+		})
+	}
+	url := fmt.Sprintf("%s/collections/%s/points?wait=true", q.baseURL, knowledgeKey)
+	return q.doJSON(ctx, http.MethodPut, url, map[string]interface{}{"points": points}, "upsert chunks")
+}
+
+// DeleteDocumentChunks 按 payload 中的 document_id 删除文档的全部分片
+func (q *qdrantKnowledgeBase) DeleteDocumentChunks(ctx context.Context, knowledgeKey string, documentID string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	deleteReq := map[string]interface{}{
+		"filter": map[string]interface{}{
+			"must": []map[string]interface{}{
+				{"key": MetadataKeyDocumentID, "match": map[string]interface{}{"value": documentID}},
+			},
+		},
+	}
+	url := fmt.Sprintf("%s/collections/%s/points/delete?wait=true", q.baseURL, knowledgeKey)
+	return q.doJSON(ctx, http.MethodPost, url, deleteReq, "delete document chunks")
+}
+
+// doJSON 发送 JSON 请求，非 200 状态返回错误
+func (q *qdrantKnowledgeBase) doJSON(ctx context.Context, method, url string, body interface{}, action string) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if q.apiKey != "" {
+		req.Header.Set("api-key", q.apiKey)
+	}
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("failed to %s with status: %d, %s", action, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
 // 注册Qdrant提供者
 func init() {
 	RegisterKnowledgeBaseProvider(ProviderQdrant, func(config map[string]interface{}) (KnowledgeBase, error) {
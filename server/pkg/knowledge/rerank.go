@@ -0,0 +1,172 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+)
+
+const (
+	// RerankProviderCohere Cohere Rerank API
+	RerankProviderCohere = "cohere"
+	// RerankProviderJina Jina Reranker API
+	RerankProviderJina = "jina"
+	// RerankProviderDashScope Aliyun DashScope gte-rerank
+	RerankProviderDashScope = "dashscope"
+	// RerankProviderCustom self-hosted cross-encoder with a Cohere compatible
+	// /rerank endpoint (Xinference, vLLM, LocalAI, ...), rerank_base_url required
+	RerankProviderCustom = "custom"
+)
+
+// Default rerank endpoints and models
+const (
+	DefaultCohereRerankBaseURL  = "https://api.cohere.com/v2"
+	DefaultCohereRerankModel    = "rerank-v3.5"
+	DefaultJinaRerankBaseURL    = "https://api.jina.ai/v1"
+	DefaultJinaRerankModel      = "jina-reranker-v2-base-multilingual"
+	DefaultDashScopeRerankURL   = "https://dashscope.aliyuncs.com/api/v1/services/rerank/text-rerank/text-rerank"
+	DefaultDashScopeRerankModel = "gte-rerank"
+)
+
+// Reranker reorders retrieved chunks by scoring each against the query with a cross-encoder
+type Reranker interface {
+	// Rerank returns at most topN of results, best first, Score replaced by the relevance score
+	Rerank(ctx context.Context, query string, results []SearchResult, topN int) ([]SearchResult, error)
+}
+
+// RerankConfig rerank step settings of a knowledge base
+type RerankConfig struct {
+	Provider string
+	BaseURL  string
+	APIKey   string
+	Model    string
+}
+
+// NewReranker creates the reranker of cfg, nil when no provider is configured
+func NewReranker(cfg RerankConfig) (Reranker, error) {
+	provider := strings.ToLower(cfg.Provider)
+	switch provider {
+	case "":
+		return nil, nil
+	case RerankProviderCohere:
+		return newHTTPReranker(cfg, DefaultCohereRerankBaseURL+"/rerank", DefaultCohereRerankModel, false), nil
+	case RerankProviderJina:
+		return newHTTPReranker(cfg, DefaultJinaRerankBaseURL+"/rerank", DefaultJinaRerankModel, false), nil
+	case RerankProviderDashScope:
+		return newHTTPReranker(cfg, DefaultDashScopeRerankURL, DefaultDashScopeRerankModel, true), nil
+	case RerankProviderCustom:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("rerank_base_url is required for custom rerank provider")
+		}
+		return newHTTPReranker(cfg, "", "", false), nil
+	default:
+		return nil, fmt.Errorf("unsupported rerank provider: %s", cfg.Provider)
+	}
+}
+
+// httpReranker calls a hosted rerank API. Cohere, Jina and most self-hosted
+// servers share one request format, DashScope wraps it in input/parameters.
+type httpReranker struct {
+	endpoint   string
+	apiKey     string
+	model      string
+	dashScope  bool
+	httpClient *http.Client
+}
+
+func newHTTPReranker(cfg RerankConfig, defaultEndpoint, defaultModel string, dashScope bool) *httpReranker {
+	endpoint := defaultEndpoint
+	if cfg.BaseURL != "" {
+		endpoint = strings.TrimRight(cfg.BaseURL, "/")
+		if !dashScope && !strings.HasSuffix(endpoint, "/rerank") {
+			endpoint += "/rerank"
+		}
+	}
+	return &httpReranker{
+		endpoint:   endpoint,
+		apiKey:     cfg.APIKey,
+		model:      stringOrDefault(cfg.Model, defaultModel),
+		dashScope:  dashScope,
+		httpClient: utils.NewPublicHTTPClient(defaultEmbeddingTimeout), // the base URL is set by knowledge base owners
+	}
+}
+
+type rerankScore struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+}
+
+func (r *httpReranker) Rerank(ctx context.Context, query string, results []SearchResult, topN int) ([]SearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+	if topN <= 0 || topN > len(results) {
+		topN = len(results)
+	}
+	documents := make([]string, len(results))
+	for i, result := range results {
+		documents[i] = result.Content
+	}
+
+	var reqBody map[string]interface{}
+	if r.dashScope {
+		reqBody = map[string]interface{}{
+			"model":      r.model,
+			"input":      map[string]interface{}{"query": query, "documents": documents},
+			"parameters": map[string]interface{}{"top_n": topN, "return_documents": false},
+		}
+	} else {
+		reqBody = map[string]interface{}{
+			"query":     query,
+			"documents": documents,
+			"top_n":     topN,
+		}
+		if r.model != "" {
+			reqBody["model"] = r.model
+		}
+	}
+	headers := map[string]string{}
+	if r.apiKey != "" {
+		headers["Authorization"] = "Bearer " + r.apiKey
+	}
+
+	var scores []rerankScore
+	if r.dashScope {
+		var rerankResp struct {
+			Output struct {
+				Results []rerankScore `json:"results"`
+			} `json:"output"`
+		}
+		if err := postJSON(ctx, r.httpClient, r.endpoint, headers, reqBody, &rerankResp); err != nil {
+			return nil, fmt.Errorf("rerank: %w", err)
+		}
+		scores = rerankResp.Output.Results
+	} else {
+		var rerankResp struct {
+			Results []rerankScore `json:"results"`
+		}
+		if err := postJSON(ctx, r.httpClient, r.endpoint, headers, reqBody, &rerankResp); err != nil {
+			return nil, fmt.Errorf("rerank: %w", err)
+		}
+		scores = rerankResp.Results
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].RelevanceScore > scores[j].RelevanceScore })
+	reranked := make([]SearchResult, 0, topN)
+	for _, score := range scores {
+		if score.Index < 0 || score.Index >= len(results) {
+			continue
+		}
+		result := results[score.Index]
+		result.Score = score.RelevanceScore
+		reranked = append(reranked, result)
+		if len(reranked) == topN {
+			break
+		}
+	}
+	return reranked, nil
+}
@@ -0,0 +1,47 @@
+package knowledge
+
+import "context"
+
+// VectorChunk one embedded chunk of a document, written to a vector store
+type VectorChunk struct {
+	// ID chunk identifier, stable for the same document and position so re-ingesting overwrites it
+	ID string
+	// DocumentID identifier of the document the chunk belongs to
+	DocumentID string
+	// Content chunk text
+	Content string
+	// Vector chunk embedding
+	Vector []float32
+	// Metadata provenance of the chunk (title, page, offset, ...), returned with search results
+	Metadata map[string]interface{}
+}
+
+// VectorStore is implemented by knowledge bases that store vectors computed by
+// the caller (Milvus, Qdrant, Pinecone), as opposed to hosted knowledge bases
+// that parse uploaded files themselves
+type VectorStore interface {
+	// UpsertChunks inserts or replaces chunks in the knowledge base
+	UpsertChunks(ctx context.Context, knowledgeKey string, chunks []VectorChunk) error
+
+	// DeleteDocumentChunks deletes all chunks of a document
+	DeleteDocumentChunks(ctx context.Context, knowledgeKey string, documentID string) error
+}
+
+// EmbeddingKnowledgeBase is implemented by knowledge bases configured with an embedder
+type EmbeddingKnowledgeBase interface {
+	// Embedder returns the configured embedder, nil when none is configured
+	Embedder() Embedder
+}
+
+// chunkPayload returns the stored fields of a chunk: its metadata plus content,
+// document and chunk identifiers
+func chunkPayload(chunk VectorChunk) map[string]interface{} {
+	payload := make(map[string]interface{}, len(chunk.Metadata)+3)
+	for k, v := range chunk.Metadata {
+		payload[k] = v
+	}
+	payload["content"] = chunk.Content
+	payload[MetadataKeyDocumentID] = chunk.DocumentID
+	payload[MetadataKeyChunkID] = chunk.ID
+	return payload
+}
@@ -19,7 +19,7 @@ const (
 )
 
 // NewLLMProvider 根据配置创建 LLM 提供者
-// 这是统一的工厂函数，根据 credential 中的 LLMProvider 字段选择提供者
+// 这是统一的工厂函数，根据 credential 中的 LLMProvider 字段选择提供者，查询耗时与错误会上报到全局监控
 func NewLLMProvider(ctx context.Context, credential *models.UserCredential, systemPrompt string) (LLMProvider, error) {
 	providerType := strings.ToLower(strings.TrimSpace(credential.LLMProvider))
 
@@ -28,6 +28,14 @@ func NewLLMProvider(ctx context.Context, credential *models.UserCredential, syst
 		providerType = string(ProviderTypeOpenAI)
 	}
 
+	provider, err := newLLMProvider(ctx, providerType, credential, systemPrompt)
+	if err != nil {
+		return nil, err
+	}
+	return withMetrics(provider, providerType), nil
+}
+
+func newLLMProvider(ctx context.Context, providerType string, credential *models.UserCredential, systemPrompt string) (LLMProvider, error) {
 	switch providerType {
 	case string(ProviderTypeCoze):
 		// Coze API
@@ -97,6 +105,18 @@ func NewLLMProvider(ctx context.Context, credential *models.UserCredential, syst
 func NewLLMProviderFromConfig(ctx context.Context, providerType string, apiKey, baseURL, systemPrompt string, extraConfig map[string]string) (LLMProvider, error) {
 	providerType = strings.ToLower(strings.TrimSpace(providerType))
 
+	provider, err := newLLMProviderFromConfig(ctx, providerType, apiKey, baseURL, systemPrompt, extraConfig)
+	if err != nil {
+		return nil, err
+	}
+	name := providerType
+	if name == "" {
+		name = string(ProviderTypeOpenAI)
+	}
+	return withMetrics(provider, name), nil
+}
+
+func newLLMProviderFromConfig(ctx context.Context, providerType string, apiKey, baseURL, systemPrompt string, extraConfig map[string]string) (LLMProvider, error) {
 	switch providerType {
 	case string(ProviderTypeCoze):
 		botID := ""
@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MCPToolTimeout 模型单次调用 MCP 工具的超时时间
+const MCPToolTimeout = 15 * time.Second
+
+// MCPToolSource 可供模型调用的一组 MCP 工具，进程内的 *mcp.MCPServer 即满足该接口
+type MCPToolSource interface {
+	ListTools() []mcp.Tool
+	CallToolInternal(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error)
+}
+
+// RegisterMCPTools 把 source 中的工具注册为 provider 的函数工具，返回注册数量。
+// filter 不为空时只注册 filter 返回 true 的工具
+func RegisterMCPTools(provider LLMProvider, source MCPToolSource, filter func(name string) bool) (int, error) {
+	count := 0
+	for _, tool := range source.ListTools() {
+		if filter != nil && !filter(tool.Name) {
+			continue
+		}
+		parameters, err := mcpToolParameters(tool)
+		if err != nil {
+			return count, fmt.Errorf("tool %s: %w", tool.Name, err)
+		}
+		provider.RegisterFunctionToolDefinition(&FunctionToolDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  parameters,
+			Callback:    mcpToolCallback(source, tool.Name),
+		})
+		count++
+	}
+	return count, nil
+}
+
+// mcpToolParameters 把 MCP 工具的输入 schema 转为 OpenAI 函数参数
+func mcpToolParameters(tool mcp.Tool) (json.RawMessage, error) {
+	if tool.RawInputSchema != nil {
+		return tool.RawInputSchema, nil
+	}
+	data, err := json.Marshal(tool.InputSchema)
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	if schema["type"] == nil {
+		schema["type"] = "object"
+	}
+	// OpenAI 要求 object 类型带 properties，无参工具也要给空对象
+	if schema["properties"] == nil {
+		schema["properties"] = map[string]any{}
+	}
+	return json.Marshal(schema)
+}
+
+// mcpToolCallback 调用 MCP 工具并把文本结果交给模型，工具返回的错误结果作为错误上报
+func mcpToolCallback(source MCPToolSource, name string) FunctionToolCallback {
+	return func(args map[string]interface{}) (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), MCPToolTimeout)
+		defer cancel()
+
+		result, err := source.CallToolInternal(ctx, name, args)
+		if err != nil {
+			return "", err
+		}
+		if result == nil {
+			return "", nil
+		}
+
+		texts := make([]string, 0, len(result.Content))
+		for _, content := range result.Content {
+			if text, ok := mcp.AsTextContent(content); ok {
+				texts = append(texts, text.Text)
+			}
+		}
+		text := strings.Join(texts, "\n")
+		if result.IsError {
+			if text == "" {
+				text = "tool returned an error"
+			}
+			return "", errors.New(text)
+		}
+		return text, nil
+	}
+}
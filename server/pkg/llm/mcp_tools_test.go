@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	lingechoMCP "github.com/code-100-precent/LingEcho/pkg/mcp"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMCPServer() *lingechoMCP.MCPServer {
+	server := lingechoMCP.NewMCPServer(nil)
+	server.RegisterTool("order_status", "查询订单状态",
+		func(arguments map[string]any) (*mcp.CallToolResult, error) {
+			orderID, _ := arguments["order_id"].(string)
+			if orderID == "" {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "order_id is required"}},
+					IsError: true,
+				}, nil
+			}
+			return lingechoMCP.TextResponse("订单 " + orderID + " 已发货"), nil
+		},
+		mcp.WithString("order_id", mcp.Description("订单号"), mcp.Required()),
+	)
+	server.RegisterTool("ping", "无参数工具",
+		func(arguments map[string]any) (*mcp.CallToolResult, error) {
+			return lingechoMCP.TextResponse("pong"), nil
+		},
+	)
+	return server
+}
+
+func TestRegisterMCPTools(t *testing.T) {
+	provider := NewOpenAIProvider(context.Background(), "test-key", "http://127.0.0.1:0", "")
+	count, err := RegisterMCPTools(provider, newTestMCPServer(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Contains(t, provider.ListFunctionTools(), "order_status")
+
+	def, ok := provider.handler.functionManager.GetTool("order_status")
+	require.True(t, ok)
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(def.Parameters, &schema))
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, []any{"order_id"}, schema["required"])
+
+	result, err := def.Callback(map[string]interface{}{"order_id": "A1"})
+	require.NoError(t, err)
+	assert.Equal(t, "订单 A1 已发货", result)
+
+	// Error results are reported to the model as errors
+	_, err = def.Callback(map[string]interface{}{})
+	assert.EqualError(t, err, "order_id is required")
+
+	// Tools without parameters still get an object schema with properties
+	def, ok = provider.handler.functionManager.GetTool("ping")
+	require.True(t, ok)
+	assert.JSONEq(t, `{"type":"object","properties":{}}`, string(def.Parameters))
+}
+
+func TestRegisterMCPToolsFilter(t *testing.T) {
+	provider := NewOpenAIProvider(context.Background(), "test-key", "http://127.0.0.1:0", "")
+	count, err := RegisterMCPTools(provider, newTestMCPServer(), func(name string) bool { return name == "ping" })
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NotContains(t, provider.ListFunctionTools(), "order_status")
+}
+
+// TestQueryStreamToolRounds checks that tool calls in the follow-up response
+// are executed too, and the answer after the last tool round is delivered
+func TestQueryStreamToolRounds(t *testing.T) {
+	var mu sync.Mutex
+	var requests []openai.ChatCompletionRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req openai.ChatCompletionRequest
+		require.NoError(t, json.Unmarshal(body, &req))
+		mu.Lock()
+		requests = append(requests, req)
+		n := len(requests)
+		mu.Unlock()
+
+		switch n {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, `data: {"id":"r1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"c1","type":"function","function":{"name":"order_status","arguments":"{\"order_id\":\"A1\"}"}}]}}]}`+"\n\n")
+			fmt.Fprint(w, `data: {"id":"r1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`+"\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		case 2:
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"r2","choices":[{"index":0,"message":{"role":"assistant","tool_calls":[{"id":"c2","type":"function","function":{"name":"ping","arguments":"{}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"r3","choices":[{"index":0,"message":{"role":"assistant","content":"您的订单已发货。"},"finish_reason":"stop"}],"usage":{"prompt_tokens":4,"completion_tokens":6,"total_tokens":10}}`)
+		}
+	}))
+	defer srv.Close()
+
+	handler := NewLLMHandler(context.Background(), "test-key", srv.URL, "")
+	_, err := RegisterMCPTools(&OpenAIProvider{handler: handler}, newTestMCPServer(), nil)
+	require.NoError(t, err)
+
+	var segments []string
+	response, err := handler.QueryStream("我的订单到哪了", QueryOptions{Model: "test"}, func(segment string, isComplete bool) error {
+		if !isComplete {
+			segments = append(segments, segment)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "您的订单已发货。", response)
+	assert.Equal(t, []string{"您的订单已发货。"}, segments)
+	require.Len(t, requests, 3)
+
+	var toolResults []string
+	for _, msg := range handler.GetMessages() {
+		if msg.Role == openai.ChatMessageRoleTool {
+			toolResults = append(toolResults, msg.ToolCallID+"="+msg.Content)
+		}
+	}
+	assert.Equal(t, []string{"c1=订单 A1 已发货", "c2=pong"}, toolResults)
+	// The last request carries both tool results
+	last := requests[2]
+	assert.True(t, strings.Contains(last.Messages[len(last.Messages)-1].Content, "pong"))
+
+	usage, ok := handler.GetLastUsage()
+	require.True(t, ok)
+	assert.Equal(t, 15, usage.TotalTokens)
+}
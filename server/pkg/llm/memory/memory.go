@@ -0,0 +1,221 @@
+// Package memory keeps long conversations inside the model's context window.
+//
+// Once a session's history grows past a threshold, the older turns are
+// compressed into a rolling summary in the background: the previous summary
+// and the turns to fold in are sent to the model, and the result replaces
+// both. The summary is stored per session, so a new handler continuing the
+// same session starts from it, and is prepended to the system prompt while
+// only the recent turns are sent verbatim.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Roles of the messages handed to the summarizer
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of the conversation
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Summary is the compressed part of a session's history
+type Summary struct {
+	Text      string
+	Messages  int // messages folded into the summary so far
+	UpdatedAt time.Time
+}
+
+// CompleteFunc asks the model for a completion of a single prompt
+type CompleteFunc func(ctx context.Context, prompt string) (string, error)
+
+// Config tunes when history is compressed. Zero fields use the defaults.
+type Config struct {
+	// Threshold is the number of history messages, not counting the system
+	// prompt, above which older messages are compressed
+	Threshold int
+	// KeepRecent is how many of the newest messages are always sent verbatim
+	KeepRecent int
+	// Timeout bounds one summarization call
+	Timeout time.Duration
+}
+
+const (
+	defaultThreshold  = 24
+	defaultKeepRecent = 8
+	defaultTimeout    = 30 * time.Second
+)
+
+func (c Config) withDefaults() Config {
+	if c.Threshold <= 0 {
+		c.Threshold = defaultThreshold
+	}
+	if c.KeepRecent <= 0 {
+		c.KeepRecent = defaultKeepRecent
+	}
+	if c.KeepRecent >= c.Threshold {
+		c.KeepRecent = c.Threshold / 2
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	return c
+}
+
+// Summarizer compresses session histories into the store. It is safe for
+// concurrent use and runs at most one compression per session at a time.
+type Summarizer struct {
+	cfg   Config
+	store Store
+
+	mu       sync.Mutex
+	inflight map[string]bool
+}
+
+// NewSummarizer creates a summarizer keeping its summaries in store
+func NewSummarizer(cfg Config, store Store) *Summarizer {
+	return &Summarizer{
+		cfg:      cfg.withDefaults(),
+		store:    store,
+		inflight: make(map[string]bool),
+	}
+}
+
+var (
+	defaultSummarizer     *Summarizer
+	defaultSummarizerOnce sync.Once
+)
+
+// Default returns the process-wide summarizer with an in-process store that
+// forgets sessions idle for a day
+func Default() *Summarizer {
+	defaultSummarizerOnce.Do(func() {
+		defaultSummarizer = NewSummarizer(Config{}, NewMemoryStore(24*time.Hour))
+	})
+	return defaultSummarizer
+}
+
+// Summary returns the session's summary, empty when there is none
+func (s *Summarizer) Summary(sessionID string) string {
+	if sessionID == "" {
+		return ""
+	}
+	summary, ok := s.store.Get(sessionID)
+	if !ok {
+		return ""
+	}
+	return summary.Text
+}
+
+// Reset forgets the session's summary
+func (s *Summarizer) Reset(sessionID string) {
+	s.store.Delete(sessionID)
+}
+
+// Cut returns how many of the oldest history messages should be compressed
+// now, 0 while the history is short enough. The cut always falls right
+// before a user message, so a reply or a tool exchange is never split from
+// the question it answers.
+func (s *Summarizer) Cut(history []Message) int {
+	if len(history) <= s.cfg.Threshold {
+		return 0
+	}
+	for cut := len(history) - s.cfg.KeepRecent; cut > 0; cut-- {
+		if history[cut].Role == RoleUser {
+			return cut
+		}
+	}
+	return 0
+}
+
+// Compress folds messages into the session's summary in the background and
+// calls done with the stored result. It returns false without doing anything
+// when a compression of the session is already running; failures keep the
+// old summary and done is not called.
+func (s *Summarizer) Compress(sessionID string, messages []Message, complete CompleteFunc, done func(Summary)) bool {
+	if sessionID == "" || len(messages) == 0 {
+		return false
+	}
+	s.mu.Lock()
+	if s.inflight[sessionID] {
+		s.mu.Unlock()
+		return false
+	}
+	s.inflight[sessionID] = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.inflight, sessionID)
+			s.mu.Unlock()
+		}()
+
+		previous, _ := s.store.Get(sessionID)
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+		defer cancel()
+		text, err := complete(ctx, BuildPrompt(previous.Text, messages))
+		text = strings.TrimSpace(text)
+		if err != nil || text == "" {
+			return
+		}
+
+		summary := Summary{
+			Text:      text,
+			Messages:  previous.Messages + len(messages),
+			UpdatedAt: time.Now(),
+		}
+		s.store.Put(sessionID, summary)
+		if done != nil {
+			done(summary)
+		}
+	}()
+	return true
+}
+
+// BuildPrompt asks the model to merge the previous summary and the new
+// messages into one summary
+func BuildPrompt(previous string, messages []Message) string {
+	var b strings.Builder
+	b.WriteString("请把下面的对话压缩成一段简洁的摘要，供后续对话作为背景使用。")
+	b.WriteString("保留用户的身份、需求、偏好、已确认的事实与结论、尚未解决的问题，省略寒暄和重复内容。")
+	b.WriteString("直接输出摘要正文，不要添加标题或解释。\n\n")
+	if previous != "" {
+		b.WriteString("已有摘要：\n")
+		b.WriteString(previous)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("新的对话：\n")
+	for _, msg := range messages {
+		if msg.Content == "" {
+			continue
+		}
+		speaker := "助手"
+		if msg.Role == RoleUser {
+			speaker = "用户"
+		}
+		fmt.Fprintf(&b, "%s：%s\n", speaker, msg.Content)
+	}
+	return b.String()
+}
+
+// WithSummary appends the summary to the system prompt
+func WithSummary(systemPrompt, summary string) string {
+	if summary == "" {
+		return systemPrompt
+	}
+	section := "以下是此前对话的摘要，请在回答时参考：\n" + summary
+	if systemPrompt == "" {
+		return section
+	}
+	return systemPrompt + "\n\n" + section
+}
@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func turns(n int) []Message {
+	messages := make([]Message, 0, n)
+	for i := 0; i < n; i++ {
+		role := RoleUser
+		if i%2 == 1 {
+			role = RoleAssistant
+		}
+		messages = append(messages, Message{Role: role, Content: strings.Repeat("x", i+1)})
+	}
+	return messages
+}
+
+func TestCut(t *testing.T) {
+	s := NewSummarizer(Config{Threshold: 6, KeepRecent: 3}, NewMemoryStore(0))
+
+	assert.Zero(t, s.Cut(turns(6)), "short history is kept")
+	// 7 messages keep at least 3, the cut moves back to a user message
+	assert.Equal(t, 4, s.Cut(turns(7)))
+	assert.Equal(t, 4, s.Cut(turns(8)))
+
+	// A tool exchange stays with the question it answers
+	history := []Message{
+		{Role: RoleUser, Content: "q1"},
+		{Role: RoleAssistant, Content: "a1"},
+		{Role: RoleUser, Content: "q2"},
+		{Role: RoleAssistant},
+		{Role: "tool", Content: "result"},
+		{Role: RoleAssistant, Content: "a2"},
+		{Role: RoleAssistant, Content: "a2b"},
+	}
+	assert.Equal(t, 2, s.Cut(history))
+}
+
+func TestCompress(t *testing.T) {
+	s := NewSummarizer(Config{}, NewMemoryStore(0))
+	var prompts []string
+	complete := func(ctx context.Context, prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		return " summary " + string(rune('0'+len(prompts))) + "\n", nil
+	}
+	compress := func(messages []Message) Summary {
+		done := make(chan Summary, 1)
+		require.True(t, s.Compress("s1", messages, complete, func(summary Summary) { done <- summary }))
+		select {
+		case summary := <-done:
+			return summary
+		case <-time.After(2 * time.Second):
+			t.Fatal("compression did not finish")
+			return Summary{}
+		}
+	}
+
+	summary := compress([]Message{{Role: RoleUser, Content: "我叫小王"}, {Role: RoleAssistant, Content: "你好小王"}})
+	assert.Equal(t, "summary 1", summary.Text)
+	assert.Equal(t, 2, summary.Messages)
+	assert.Equal(t, "summary 1", s.Summary("s1"))
+	assert.Contains(t, prompts[0], "用户：我叫小王")
+	assert.Contains(t, prompts[0], "助手：你好小王")
+
+	// The next compression merges the previous summary
+	summary = compress([]Message{{Role: RoleUser, Content: "明天提醒我开会"}})
+	assert.Equal(t, 3, summary.Messages)
+	assert.Contains(t, prompts[1], "已有摘要：\nsummary 1")
+
+	s.Reset("s1")
+	assert.Empty(t, s.Summary("s1"))
+	assert.False(t, s.Compress("", turns(2), complete, nil), "sessions without ID are not summarized")
+}
+
+func TestCompressOncePerSession(t *testing.T) {
+	s := NewSummarizer(Config{}, NewMemoryStore(0))
+	release := make(chan struct{})
+	complete := func(ctx context.Context, prompt string) (string, error) {
+		<-release
+		return "", errors.New("model unavailable")
+	}
+	done := func(Summary) { t.Error("failed compression must not report a summary") }
+
+	require.True(t, s.Compress("s1", turns(2), complete, done))
+	assert.False(t, s.Compress("s1", turns(2), complete, done), "one compression per session at a time")
+	assert.True(t, s.Compress("s2", turns(2), complete, done))
+	close(release)
+
+	// The session can be compressed again once the running compression ended
+	assert.Eventually(t, func() bool {
+		return s.Compress("s1", turns(2), func(ctx context.Context, prompt string) (string, error) { return "ok", nil }, nil)
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return s.Summary("s1") == "ok" }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestMemoryStoreTTL(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	store.Put("old", Summary{Text: "old", UpdatedAt: time.Now().Add(-2 * time.Minute)})
+	store.Put("new", Summary{Text: "new", UpdatedAt: time.Now()})
+
+	_, ok := store.Get("old")
+	assert.False(t, ok)
+	summary, ok := store.Get("new")
+	require.True(t, ok)
+	assert.Equal(t, "new", summary.Text)
+}
+
+func TestWithSummary(t *testing.T) {
+	assert.Equal(t, "你是客服", WithSummary("你是客服", ""))
+	prompt := WithSummary("你是客服", "用户叫小王")
+	assert.True(t, strings.HasPrefix(prompt, "你是客服\n\n"))
+	assert.True(t, strings.HasSuffix(prompt, "用户叫小王"))
+}
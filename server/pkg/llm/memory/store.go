@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"sync"
+	"time"
+)
+
+// Store keeps the summary of each session
+type Store interface {
+	Get(sessionID string) (Summary, bool)
+	Put(sessionID string, summary Summary)
+	Delete(sessionID string)
+}
+
+// memoryStore keeps summaries in process. A summary not updated for ttl is
+// dropped the next time the store is touched.
+type memoryStore struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	summaries map[string]Summary
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns an in-process store, ttl 0 keeps summaries forever
+func NewMemoryStore(ttl time.Duration) Store {
+	return &memoryStore{ttl: ttl, summaries: make(map[string]Summary)}
+}
+
+func (m *memoryStore) Get(sessionID string) (Summary, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	summary, ok := m.summaries[sessionID]
+	if ok && m.expired(summary, time.Now()) {
+		delete(m.summaries, sessionID)
+		return Summary{}, false
+	}
+	return summary, ok
+}
+
+func (m *memoryStore) Put(sessionID string, summary Summary) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.summaries[sessionID] = summary
+	m.sweep(time.Now())
+}
+
+func (m *memoryStore) Delete(sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.summaries, sessionID)
+}
+
+func (m *memoryStore) expired(summary Summary, now time.Time) bool {
+	return m.ttl > 0 && now.Sub(summary.UpdatedAt) > m.ttl
+}
+
+// sweep drops expired summaries, at most once per ttl
+func (m *memoryStore) sweep(now time.Time) {
+	if m.ttl <= 0 || now.Sub(m.lastSweep) < m.ttl {
+		return
+	}
+	m.lastSweep = now
+	for id, summary := range m.summaries {
+		if m.expired(summary, now) {
+			delete(m.summaries, id)
+		}
+	}
+}
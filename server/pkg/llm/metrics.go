@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/metrics"
+)
+
+// instrumentedProvider 记录查询耗时与错误，其余方法直接透传
+type instrumentedProvider struct {
+	LLMProvider
+	name string
+}
+
+// withMetrics 包装提供者，使查询上报到全局监控
+func withMetrics(provider LLMProvider, name string) LLMProvider {
+	return &instrumentedProvider{LLMProvider: provider, name: name}
+}
+
+func (p *instrumentedProvider) record(operation string, start time.Time, err error) {
+	if monitor := metrics.GetGlobalMonitor(); monitor != nil {
+		monitor.RecordAIRequest("llm", p.name, operation, time.Since(start), err)
+	}
+}
+
+func (p *instrumentedProvider) Query(text, model string) (string, error) {
+	start := time.Now()
+	resp, err := p.LLMProvider.Query(text, model)
+	p.record("query", start, err)
+	return resp, err
+}
+
+func (p *instrumentedProvider) QueryWithOptions(text string, options QueryOptions) (string, error) {
+	start := time.Now()
+	resp, err := p.LLMProvider.QueryWithOptions(text, options)
+	p.record("query", start, err)
+	return resp, err
+}
+
+// QueryStream 除整体耗时外还记录首个片段到达的时间（first_token），语音场景的响应延迟主要取决于它
+func (p *instrumentedProvider) QueryStream(text string, options QueryOptions, callback func(segment string, isComplete bool) error) (string, error) {
+	start := time.Now()
+	first := true
+	resp, err := p.LLMProvider.QueryStream(text, options, func(segment string, isComplete bool) error {
+		if first && segment != "" {
+			first = false
+			p.record("first_token", start, nil)
+		}
+		if callback == nil {
+			return nil
+		}
+		return callback(segment, isComplete)
+	})
+	p.record("query_stream", start, err)
+	return resp, err
+}
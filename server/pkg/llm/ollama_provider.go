@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"strings"
 
+	"github.com/code-100-precent/LingEcho/pkg/llm/memory"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"go.uber.org/zap"
 )
@@ -143,6 +144,11 @@ func (p *OllamaProvider) GetMessages() []Message {
 	return messages
 }
 
+// SetMemory 开启对话摘要记忆
+func (p *OllamaProvider) SetMemory(summarizer *memory.Summarizer) {
+	p.handler.SetMemory(summarizer)
+}
+
 // Interrupt 中断当前请求
 func (p *OllamaProvider) Interrupt() {
 	select {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/constants"
+	"github.com/code-100-precent/LingEcho/pkg/llm/memory"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/google/uuid"
@@ -17,6 +18,10 @@ import (
 	"go.uber.org/zap"
 )
 
+// maxStreamToolRounds bounds the tool-call rounds of a streamed query, like
+// the iteration limit of QueryWithOptions
+const maxStreamToolRounds = 10
+
 type LLMHandler struct {
 	client          *openai.Client
 	ctx             context.Context
@@ -29,6 +34,8 @@ type LLMHandler struct {
 	functionManager *FunctionToolManager
 	lastUsage       openai.Usage
 	lastUsageValid  bool
+	memory          *memory.Summarizer // nil keeps the raw history
+	historyGen      int                // bumped whenever the history is reset or compacted
 }
 
 type QueryOptions struct {
@@ -44,6 +51,7 @@ type QueryOptions struct {
 	LogitBias           map[string]int                       // force to increase or decrease the frequency of words
 	User                string                               // user flag
 	Stream              bool                                 // is stream
+	StreamDeltas        bool                                 // QueryStream hands every delta to the callback instead of whole sentences
 	ResponseFormat      *openai.ChatCompletionResponseFormat // format of response
 	Seed                *int                                 //  seed
 
@@ -213,7 +221,7 @@ func (h *LLMHandler) QueryWithOptions(text string, options QueryOptions) (string
 		// 确保所有消息的 Content 字段都是有效的字符串类型（避免 DashScope 等兼容模式 API 报错）
 		// DashScope 兼容模式要求 Content 必须是 string 或 array，不能是 null 或 object
 		sanitizedMessages := make([]openai.ChatCompletionMessage, 0, len(h.messages))
-		for _, msg := range h.messages {
+		for _, msg := range h.withSummary(options.SessionID) {
 			// 创建一个新的消息副本，确保 Content 字段是字符串类型
 			sanitizedMsg := openai.ChatCompletionMessage{
 				Role:      msg.Role,
@@ -495,6 +503,7 @@ func (h *LLMHandler) QueryWithOptions(text string, options QueryOptions) (string
 		return "", fmt.Errorf("max iterations reached without final response, possible incomplete tool calls")
 	}
 
+	h.compactHistory(options.SessionID, options.Model)
 	h.mutex.Unlock()
 
 	// Record end time and calculate duration
@@ -575,13 +584,17 @@ func (h *LLMHandler) QueryStream(text string, options QueryOptions, callback fun
 		Content: text,
 	})
 
+	// Usage is only known if the stream reports it, don't carry over the last query's
+	h.lastUsage = openai.Usage{}
+	h.lastUsageValid = false
+
 	// Get all available function tools
 	tools := h.functionManager.GetTools()
 
 	// Construct the OpenAI request with all available options
 	request := openai.ChatCompletionRequest{
 		Model:    options.Model,
-		Messages: h.messages,
+		Messages: h.withSummary(options.SessionID),
 		Stream:   true, // Force stream mode
 		Tools:    tools,
 	}
@@ -758,8 +771,16 @@ func (h *LLMHandler) QueryStream(text string, options QueryOptions, callback fun
 			// Process content if available
 			if response.Choices[0].Delta.Content != "" {
 				content := response.Choices[0].Delta.Content
-				buffer += content
 				fullResponse += content
+				if options.StreamDeltas {
+					if callback != nil {
+						if err := callback(content, false); err != nil {
+							logger.Error("Failed to process stream delta", zap.Error(err))
+						}
+					}
+					continue
+				}
+				buffer += content
 
 				// Check for punctuation in the buffer
 				matches := punctuationRegex.FindAllStringSubmatchIndex(buffer, -1)
@@ -829,66 +850,57 @@ func (h *LLMHandler) QueryStream(text string, options QueryOptions, callback fun
 			ToolCalls: collectedToolCalls,
 		})
 
-		// Process each tool call
-		for _, toolCall := range collectedToolCalls {
-			// Handle all function calls through the function manager
-			result, err := h.functionManager.HandleToolCall(toolCall)
-			if err != nil {
-				logger.Error("Failed to handle tool call",
-					zap.String("tool", toolCall.Function.Name),
-					zap.Error(err))
-				// Add error result to conversation
-				h.messages = append(h.messages, openai.ChatCompletionMessage{
-					Role:       openai.ChatMessageRoleTool,
-					Content:    fmt.Sprintf("Error: %v", err),
-					ToolCallID: toolCall.ID,
-				})
-			} else {
-				logger.Info("Tool call result",
-					zap.String("tool", toolCall.Function.Name),
-					zap.String("result", result))
-				// Add tool result to conversation history
-				h.messages = append(h.messages, openai.ChatCompletionMessage{
-					Role:       openai.ChatMessageRoleTool,
-					Content:    result,
-					ToolCallID: toolCall.ID,
-				})
-			}
-		}
-
-		// Update request and make another call to get final response
-		request.Messages = h.messages
-		request.Stream = false // Use non-streaming for final response
-		h.mutex.Unlock()
-
-		// Make another call to get final response after tool execution
-		finalResp, err := h.client.CreateChatCompletion(h.ctx, request)
-		if err != nil {
-			return fullResponse, fmt.Errorf("error getting final response after tool call: %w", err)
-		}
+		h.executeToolCalls(collectedToolCalls)
 
+		// Keep calling until the model answers without further tool calls, so
+		// multi-step tool use completes before the final answer is delivered
+		request.Stream = false // Use non-streaming for the follow-up calls
 		finalResponse := ""
-		if len(finalResp.Choices) > 0 {
-			finalResponse = finalResp.Choices[0].Message.Content
-			// Add final response to history
-			h.mutex.Lock()
-			h.messages = append(h.messages, finalResp.Choices[0].Message)
+		for round := 1; ; round++ {
+			if round >= maxStreamToolRounds {
+				// Last round: no tools offered, the model has to answer
+				request.Tools = nil
+			}
+			request.Messages = h.withSummary(options.SessionID)
 			h.mutex.Unlock()
-		}
 
-		// Record end time and calculate duration
-		endTime := time.Now()
-		duration := endTime.Sub(startTime).Milliseconds()
+			finalResp, err := h.client.CreateChatCompletion(h.ctx, request)
+			if err != nil {
+				return fullResponse, fmt.Errorf("error getting final response after tool call: %w", err)
+			}
 
-		// Update usage from final response
-		if len(finalResp.Choices) > 0 {
 			h.mutex.Lock()
 			h.lastUsage.PromptTokens += finalResp.Usage.PromptTokens
 			h.lastUsage.CompletionTokens += finalResp.Usage.CompletionTokens
 			h.lastUsage.TotalTokens += finalResp.Usage.TotalTokens
 			h.lastUsageValid = true
-			h.mutex.Unlock()
+			if len(finalResp.Choices) == 0 {
+				break
+			}
+			message := finalResp.Choices[0].Message
+			// Add the response to history
+			h.messages = append(h.messages, message)
+			if len(message.ToolCalls) == 0 {
+				finalResponse = message.Content
+				break
+			}
+
+			logger.Info("Tool calls detected in follow-up response", zap.Int("count", len(message.ToolCalls)), zap.Int("round", round))
+			for _, toolCall := range message.ToolCalls {
+				allToolCalls = append(allToolCalls, ToolCallInfo{
+					ID:        toolCall.ID,
+					Name:      toolCall.Function.Name,
+					Arguments: toolCall.Function.Arguments,
+				})
+			}
+			h.executeToolCalls(message.ToolCalls)
 		}
+		h.compactHistory(options.SessionID, options.Model)
+		h.mutex.Unlock()
+
+		// Record end time and calculate duration
+		endTime := time.Now()
+		duration := endTime.Sub(startTime).Milliseconds()
 
 		// Emit signal for async token usage recording with tool call information
 		usageInfo := &LLMUsageInfo{
@@ -964,71 +976,102 @@ func (h *LLMHandler) QueryStream(text string, options QueryOptions, callback fun
 	endTime := time.Now()
 	duration := endTime.Sub(startTime).Milliseconds()
 
-	// Emit signal for async token usage recording
-	if h.lastUsageValid {
-		usageInfo := &LLMUsageInfo{
-			// Request Information
-			Model:               request.Model,
-			MaxTokens:           options.MaxTokens,
-			MaxCompletionTokens: options.MaxCompletionTokens,
-			Temperature:         options.Temperature,
-			TopP:                options.TopP,
-			FrequencyPenalty:    options.FrequencyPenalty,
-			PresencePenalty:     options.PresencePenalty,
-			Stop:                options.Stop,
-			N:                   options.N,
-			LogitBias:           options.LogitBias,
-			User:                options.User,
-			Stream:              true, // Always true for stream
-			ResponseFormat:      options.ResponseFormat,
-			Seed:                options.Seed,
-
-			// Response Information
-			ResponseID:       responseID,
-			Object:           object,
-			Created:          created,
-			FinishReason:     finishReason,
-			PromptTokens:     h.lastUsage.PromptTokens,
-			CompletionTokens: h.lastUsage.CompletionTokens,
-			TotalTokens:      h.lastUsage.TotalTokens,
+	// Emit signal for async token usage recording; the reply is recorded even
+	// when the stream did not report usage
+	usageInfo := &LLMUsageInfo{
+		// Request Information
+		Model:               request.Model,
+		MaxTokens:           options.MaxTokens,
+		MaxCompletionTokens: options.MaxCompletionTokens,
+		Temperature:         options.Temperature,
+		TopP:                options.TopP,
+		FrequencyPenalty:    options.FrequencyPenalty,
+		PresencePenalty:     options.PresencePenalty,
+		Stop:                options.Stop,
+		N:                   options.N,
+		LogitBias:           options.LogitBias,
+		User:                options.User,
+		Stream:              true, // Always true for stream
+		ResponseFormat:      options.ResponseFormat,
+		Seed:                options.Seed,
 
-			// Context Information
-			SystemPrompt: h.systemMsg,
-			MessageCount: len(h.messages),
+		// Response Information
+		ResponseID:       responseID,
+		Object:           object,
+		Created:          created,
+		FinishReason:     finishReason,
+		PromptTokens:     h.lastUsage.PromptTokens,
+		CompletionTokens: h.lastUsage.CompletionTokens,
+		TotalTokens:      h.lastUsage.TotalTokens,
 
-			// Timing Information
-			StartTime: startTime,
-			EndTime:   endTime,
-			Duration:  duration,
+		// Context Information
+		SystemPrompt: h.systemMsg,
+		MessageCount: len(h.messages),
 
-			// Tool Call Information
-			HasToolCalls:  false,
-			ToolCallCount: 0,
-			ToolCalls:     nil,
+		// Timing Information
+		StartTime: startTime,
+		EndTime:   endTime,
+		Duration:  duration,
 
-			// Optional context for logging
-			UserID:       options.UserID,
-			AssistantID:  options.AssistantID,
-			CredentialID: options.CredentialID,
-			SessionID:    options.SessionID,
-			ChatType:     options.ChatType,
-		}
+		// Tool Call Information
+		HasToolCalls:  false,
+		ToolCallCount: 0,
+		ToolCalls:     nil,
 
-		utils.Sig().Emit(constants.LLMUsage, usageInfo, text, fullResponse)
+		// Optional context for logging
+		UserID:       options.UserID,
+		AssistantID:  options.AssistantID,
+		CredentialID: options.CredentialID,
+		SessionID:    options.SessionID,
+		ChatType:     options.ChatType,
 	}
 
+	utils.Sig().Emit(constants.LLMUsage, usageInfo, text, fullResponse)
+
 	logger.Info("LLM stream completed",
 		zap.String("streamID", streamID),
 		zap.Int("responseLength", len(fullResponse)),
 		zap.Int("totalTokens", h.lastUsage.TotalTokens),
 	)
 
+	h.compactHistory(options.SessionID, options.Model)
 	h.mutex.Unlock()
 
 	return fullResponse, nil
 }
 
 // RegisterFunctionTool 注册新的Function Tool
+// executeToolCalls runs the tool calls of an assistant message and appends
+// their results to the history; failures are reported to the model as the
+// tool result. The caller holds h.mutex.
+func (h *LLMHandler) executeToolCalls(toolCalls []openai.ToolCall) {
+	for _, toolCall := range toolCalls {
+		// Handle all function calls through the function manager
+		result, err := h.functionManager.HandleToolCall(toolCall)
+		if err != nil {
+			logger.Error("Failed to handle tool call",
+				zap.String("tool", toolCall.Function.Name),
+				zap.Error(err))
+			// Add error result to conversation
+			h.messages = append(h.messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    fmt.Sprintf("Error: %v", err),
+				ToolCallID: toolCall.ID,
+			})
+			continue
+		}
+		logger.Info("Tool call result",
+			zap.String("tool", toolCall.Function.Name),
+			zap.String("result", result))
+		// Add tool result to conversation history
+		h.messages = append(h.messages, openai.ChatCompletionMessage{
+			Role:       openai.ChatMessageRoleTool,
+			Content:    result,
+			ToolCallID: toolCall.ID,
+		})
+	}
+}
+
 func (h *LLMHandler) RegisterFunctionTool(name, description string, parameters json.RawMessage, callback FunctionToolCallback) {
 	h.functionManager.RegisterTool(name, description, parameters, callback)
 }
@@ -1110,6 +1153,7 @@ func (h *LLMHandler) ResetMessages() {
 			Content: h.systemMsg,
 		},
 	}
+	h.historyGen++
 }
 
 // SetSystemPrompt 动态设置系统提示词
@@ -1143,6 +1187,88 @@ func (h *LLMHandler) GetMessages() []openai.ChatCompletionMessage {
 	return messages
 }
 
+// SetMemory enables rolling summaries of the conversation. Queries carrying a
+// SessionID then send the session's summary with the system prompt, and once
+// the history grows long its older turns are compressed into the summary and
+// dropped from the history.
+func (h *LLMHandler) SetMemory(summarizer *memory.Summarizer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.memory = summarizer
+}
+
+// withSummary returns the history to send, with the session's summary added
+// to the system prompt. The caller holds h.mutex.
+func (h *LLMHandler) withSummary(sessionID string) []openai.ChatCompletionMessage {
+	if h.memory == nil || len(h.messages) == 0 || h.messages[0].Role != openai.ChatMessageRoleSystem {
+		return h.messages
+	}
+	summary := h.memory.Summary(sessionID)
+	if summary == "" {
+		return h.messages
+	}
+	messages := make([]openai.ChatCompletionMessage, len(h.messages))
+	copy(messages, h.messages)
+	messages[0].Content = memory.WithSummary(messages[0].Content, summary)
+	return messages
+}
+
+// compactHistory starts compressing the older turns once the history is long
+// enough. The turns stay in the history until their summary is stored, so a
+// failed summarization loses nothing. The caller holds h.mutex.
+func (h *LLMHandler) compactHistory(sessionID, model string) {
+	if h.memory == nil || sessionID == "" || len(h.messages) == 0 || h.messages[0].Role != openai.ChatMessageRoleSystem {
+		return
+	}
+	history := make([]memory.Message, 0, len(h.messages)-1)
+	for _, msg := range h.messages[1:] {
+		history = append(history, memory.Message{Role: msg.Role, Content: msg.Content})
+	}
+	cut := h.memory.Cut(history)
+	if cut == 0 {
+		return
+	}
+
+	generation := h.historyGen
+	h.memory.Compress(sessionID, history[:cut], h.summaryCompletion(model), func(memory.Summary) {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		// Leave a history that was reset or compacted meanwhile alone
+		if h.historyGen != generation || len(h.messages) < 1+cut {
+			return
+		}
+		// A new slice, requests built from the old history may still be in flight
+		h.messages = append([]openai.ChatCompletionMessage{h.messages[0]}, h.messages[1+cut:]...)
+		h.historyGen++
+		logger.Info("Compacted conversation history",
+			zap.String("sessionID", sessionID),
+			zap.Int("summarizedMessages", cut),
+			zap.Int("remainingMessages", len(h.messages)))
+	})
+}
+
+// summaryCompletion asks the conversation's model for the summary
+func (h *LLMHandler) summaryCompletion(model string) memory.CompleteFunc {
+	if model == "" {
+		model = openai.GPT4o
+	}
+	return func(ctx context.Context, prompt string) (string, error) {
+		resp, err := h.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+			Model: model,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleUser, Content: prompt},
+			},
+		})
+		if err != nil {
+			return "", fmt.Errorf("error summarizing conversation: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return "", fmt.Errorf("empty summary response")
+		}
+		return resp.Choices[0].Message.Content, nil
+	}
+}
+
 func Float32Ptr(v float32) *float32 {
 	return &v
 }
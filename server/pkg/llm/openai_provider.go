@@ -3,6 +3,8 @@ package llm
 import (
 	"context"
 	"encoding/json"
+
+	"github.com/code-100-precent/LingEcho/pkg/llm/memory"
 )
 
 // OpenAIProvider 包装现有的 LLMHandler，实现 LLMProvider 接口
@@ -117,6 +119,11 @@ func (p *OpenAIProvider) GetMessages() []Message {
 	return messages
 }
 
+// SetMemory 开启对话摘要记忆
+func (p *OpenAIProvider) SetMemory(summarizer *memory.Summarizer) {
+	p.handler.SetMemory(summarizer)
+}
+
 // Interrupt 中断当前请求
 func (p *OpenAIProvider) Interrupt() {
 	select {
@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/code-100-precent/LingEcho/pkg/llm/memory"
 	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"github.com/sashabaranov/go-openai"
 	"github.com/stretchr/testify/assert"
@@ -505,3 +508,85 @@ func BenchmarkConcurrentQueries(b *testing.B) {
 		}
 	})
 }
+
+// TestQueryStream_StreamDeltas checks that deltas are passed through as they
+// arrive instead of being grouped into sentences
+func TestQueryStream_StreamDeltas(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{"你", "好，", "世界"} {
+			fmt.Fprintf(w, `data: {"id":"r1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":%q}}]}`+"\n\n", delta)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	query := func(streamDeltas bool) (string, []string) {
+		handler := NewLLMHandler(context.Background(), "test-key", srv.URL, "")
+		var segments []string
+		response, err := handler.QueryStream("hi", QueryOptions{Model: "test", StreamDeltas: streamDeltas}, func(segment string, isComplete bool) error {
+			if !isComplete {
+				segments = append(segments, segment)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return response, segments
+	}
+
+	response, segments := query(true)
+	assert.Equal(t, "你好，世界", response)
+	assert.Equal(t, []string{"你", "好，", "世界"}, segments)
+
+	response, segments = query(false)
+	assert.Equal(t, "你好，世界", response)
+	assert.Equal(t, []string{"你好，", "世界"}, segments)
+}
+
+// TestQueryWithOptions_Memory checks that older turns are compressed into the
+// session summary, which then goes with the system prompt instead
+func TestQueryWithOptions_Memory(t *testing.T) {
+	var mu sync.Mutex
+	var requests []openai.ChatCompletionRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+
+		content := "好的"
+		if len(req.Messages) == 1 {
+			content = "用户叫小王"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"r","choices":[{"index":0,"message":{"role":"assistant","content":%q},"finish_reason":"stop"}]}`, content)
+	}))
+	defer srv.Close()
+
+	handler := NewLLMHandler(context.Background(), "test-key", srv.URL, "你是客服")
+	handler.SetMemory(memory.NewSummarizer(memory.Config{Threshold: 4, KeepRecent: 2}, memory.NewMemoryStore(0)))
+	options := QueryOptions{Model: "test", SessionID: "session-1"}
+
+	for _, text := range []string{"我叫小王", "帮我订票"} {
+		_, err := handler.QueryWithOptions(text, options)
+		require.NoError(t, err)
+	}
+	// 4 history messages are within the threshold
+	assert.Len(t, handler.GetMessages(), 5)
+
+	_, err := handler.QueryWithOptions("明天的", options)
+	require.NoError(t, err)
+	// The first two turns are folded into the summary and dropped
+	assert.Eventually(t, func() bool { return len(handler.GetMessages()) == 3 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "明天的", handler.GetMessages()[1].Content)
+
+	_, err = handler.QueryWithOptions("谢谢", options)
+	require.NoError(t, err)
+	mu.Lock()
+	last := requests[len(requests)-1]
+	mu.Unlock()
+	assert.Contains(t, last.Messages[0].Content, "你是客服")
+	assert.Contains(t, last.Messages[0].Content, "用户叫小王")
+	assert.Len(t, last.Messages, 4)
+}
@@ -1,5 +1,7 @@
 package llm
 
+import "github.com/code-100-precent/LingEcho/pkg/llm/memory"
+
 // LLMProvider 统一的 LLM 提供者接口
 // 所有 LLM 提供者（OpenAI、Coze 等）都需要实现这个接口
 type LLMProvider interface {
@@ -43,6 +45,21 @@ type LLMProvider interface {
 	Hangup()
 }
 
+// memoryProvider 支持对话摘要记忆的提供者（基于 LLMHandler 的 OpenAI、Ollama）
+type memoryProvider interface {
+	SetMemory(summarizer *memory.Summarizer)
+}
+
+// EnableMemory 为提供者开启对话摘要记忆，长对话的早期轮次会被压缩为按会话保存的摘要；
+// 提供者不支持时返回 false
+func EnableMemory(provider LLMProvider, summarizer *memory.Summarizer) bool {
+	p, ok := provider.(memoryProvider)
+	if ok {
+		p.SetMemory(summarizer)
+	}
+	return ok
+}
+
 // Usage 使用统计信息（统一格式）
 type Usage struct {
 	PromptTokens     int
@@ -21,8 +21,11 @@ type LogConfig struct {
 }
 
 var (
-	Lg           *zap.Logger
+	// Lg 在 Init 之前为 Nop，库代码（如 rtcmedia）可以在未初始化日志时安全调用
+	Lg           = zap.NewNop()
 	alertManager *AlertManager
+	// level 文件日志的级别，可通过 SetLevel 在运行时调整
+	level = zap.NewAtomicLevel()
 )
 
 // Init 初始化lg（兼容旧版本）
@@ -34,11 +37,10 @@ func Init(cfg *LogConfig, mode string) (err error) {
 func InitWithAlert(cfg *LogConfig, mode string, notifiers []NotificationService) (err error) {
 	writeSyncer := getLogWriter(cfg.Filename, cfg.MaxSize, cfg.MaxBackups, cfg.MaxAge, cfg.Daily)
 	encoder := getEncoder()
-	var l = new(zapcore.Level)
-	err = l.UnmarshalText([]byte(cfg.Level))
-	if err != nil {
+	if err = level.UnmarshalText([]byte(cfg.Level)); err != nil {
 		return
 	}
+	l := level
 	var core zapcore.Core
 	if mode == "dev" || mode == "development" {
 		// 进入开发模式，日志输出到终端，启用带色彩的编码器
@@ -181,6 +183,16 @@ func Panic(msg string, fields ...zap.Field) {
 	Lg.Panic(msg, fields...)
 }
 
+// SetLevel 在运行时调整日志级别，如 debug、info、warn
+func SetLevel(text string) error {
+	return level.UnmarshalText([]byte(text))
+}
+
+// Level 返回当前日志级别
+func Level() string {
+	return level.String()
+}
+
 // Sync 刷新缓冲区
 func Sync() {
 	_ = Lg.Sync()
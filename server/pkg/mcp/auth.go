@@ -0,0 +1,219 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// 调用范围前缀
+	ScopeTool  = "tool:"
+	ScopeAgent = "agent:"
+
+	// maxAuditArgumentsSize 审计记录中参数 JSON 的最大长度
+	maxAuditArgumentsSize = 4096
+)
+
+// Scopes 凭证的调用范围：允许调用的工具、可被分派任务的 agent，支持 path.Match 通配符。
+// 某一类未配置时该类不限制；nil 表示不限制
+type Scopes struct {
+	tools  []string
+	agents []string
+}
+
+// ParseScopes 解析逗号分隔的调用范围，如 "tool:search_*,tool:list_agents,agent:rag_agent"，
+// 不带前缀的条目视为工具，空字符串返回 nil（不限制）
+func ParseScopes(value string) (*Scopes, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\t'
+	})
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	scopes := &Scopes{}
+	for _, field := range fields {
+		pattern, isAgent := strings.CutPrefix(field, ScopeAgent)
+		if !isAgent {
+			pattern = strings.TrimPrefix(field, ScopeTool)
+		}
+		if pattern == "" {
+			return nil, fmt.Errorf("empty scope: %s", field)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid scope %s: %w", field, err)
+		}
+		if isAgent {
+			scopes.agents = append(scopes.agents, pattern)
+		} else {
+			scopes.tools = append(scopes.tools, pattern)
+		}
+	}
+	return scopes, nil
+}
+
+// AllowTool 是否允许调用工具
+func (s *Scopes) AllowTool(name string) bool {
+	return s == nil || matchScope(s.tools, name)
+}
+
+// AllowAgent 是否允许向 agent 分派任务
+func (s *Scopes) AllowAgent(id string) bool {
+	return s == nil || matchScope(s.agents, id)
+}
+
+// String 规范化后的调用范围
+func (s *Scopes) String() string {
+	if s == nil {
+		return ""
+	}
+	entries := make([]string, 0, len(s.tools)+len(s.agents))
+	for _, pattern := range s.tools {
+		entries = append(entries, ScopeTool+pattern)
+	}
+	for _, pattern := range s.agents {
+		entries = append(entries, ScopeAgent+pattern)
+	}
+	return strings.Join(entries, ",")
+}
+
+func matchScope(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Caller MCP 调用方，由 Authenticator 写入请求上下文
+type Caller struct {
+	UserID       uint
+	CredentialID uint    // 使用 API 凭证认证时的凭证ID
+	Scopes       *Scopes // 为空时不限制
+}
+
+type callerContextKey struct{}
+
+// ContextWithCaller 在上下文中记录调用方
+func ContextWithCaller(ctx context.Context, caller *Caller) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, caller)
+}
+
+// CallerFromContext 获取调用方，未设置时（如本地 stdio 进程、静态令牌）返回 nil
+func CallerFromContext(ctx context.Context) *Caller {
+	caller, _ := ctx.Value(callerContextKey{}).(*Caller)
+	return caller
+}
+
+// CredentialAuthenticator 使用用户 API 凭证认证：X-API-KEY/X-API-SECRET 请求头（与 HTTP API 一致）
+// 或 Authorization: Bearer <apiKey>:<apiSecret>，认证通过后按凭证的 MCPScopes 限制调用范围
+func CredentialAuthenticator(db *gorm.DB) Authenticator {
+	return func(ctx context.Context, r *http.Request) (context.Context, error) {
+		apiKey, apiSecret := r.Header.Get("X-API-KEY"), r.Header.Get("X-API-SECRET")
+		if apiKey == "" || apiSecret == "" {
+			apiKey, apiSecret, _ = strings.Cut(TokenFromRequest(r), ":")
+		}
+		if apiKey == "" || apiSecret == "" {
+			return nil, ErrUnauthorized
+		}
+
+		credential, err := models.GetUserCredentialByApiSecretAndApiKey(db.WithContext(ctx), apiKey, apiSecret)
+		if err != nil {
+			return nil, err
+		}
+		if credential == nil {
+			return nil, ErrUnauthorized
+		}
+		scopes, err := ParseScopes(credential.MCPScopes)
+		if err != nil {
+			return nil, fmt.Errorf("credential %d: %w", credential.ID, err)
+		}
+		return ContextWithCaller(ctx, &Caller{
+			UserID:       credential.UserID,
+			CredentialID: credential.ID,
+			Scopes:       scopes,
+		}), nil
+	}
+}
+
+// FirstOf 依次尝试多个认证方式，任一通过即可
+func FirstOf(authenticators ...Authenticator) Authenticator {
+	return func(ctx context.Context, r *http.Request) (context.Context, error) {
+		err := ErrUnauthorized
+		for _, authenticate := range authenticators {
+			if authenticate == nil {
+				continue
+			}
+			var authCtx context.Context
+			if authCtx, err = authenticate(ctx, r); err == nil {
+				return authCtx, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// InvocationRecord 一次工具调用的审计信息
+type InvocationRecord struct {
+	Tool      string
+	Arguments map[string]any
+	Success   bool
+	Denied    bool // 超出调用范围被拒绝
+	Error     string
+	Duration  time.Duration
+}
+
+// Auditor 工具调用审计，调用方信息从上下文获取
+type Auditor func(ctx context.Context, record InvocationRecord)
+
+// DBAuditor 将带凭证的工具调用写入 mcp_invocations 表，未认证的调用（stdio、静态令牌）不记录
+func DBAuditor(db *gorm.DB, logger *zap.Logger) Auditor {
+	return func(ctx context.Context, record InvocationRecord) {
+		caller := CallerFromContext(ctx)
+		if caller == nil || caller.CredentialID == 0 {
+			return
+		}
+
+		arguments := ""
+		if len(record.Arguments) > 0 {
+			if data, err := json.Marshal(record.Arguments); err == nil {
+				arguments = string(data)
+				if len(arguments) > maxAuditArgumentsSize {
+					arguments = strings.ToValidUTF8(arguments[:maxAuditArgumentsSize], "")
+				}
+			}
+		}
+
+		invocation := &models.MCPInvocation{
+			UserID:       caller.UserID,
+			CredentialID: caller.CredentialID,
+			Tool:         record.Tool,
+			Arguments:    arguments,
+			Success:      record.Success,
+			Denied:       record.Denied,
+			Error:        record.Error,
+			DurationMs:   record.Duration.Milliseconds(),
+		}
+		// 调用方断开连接不应丢失审计记录
+		if err := models.CreateMCPInvocation(db.WithContext(context.WithoutCancel(ctx)), invocation); err != nil {
+			logger.Warn("MCP 调用审计记录失败",
+				zap.String("tool", record.Tool),
+				zap.Uint("credentialId", caller.CredentialID),
+				zap.Error(err),
+			)
+		}
+	}
+}
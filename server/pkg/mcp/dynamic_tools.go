@@ -0,0 +1,286 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultDynamicToolSyncInterval 动态工具从数据库同步的间隔
+	DefaultDynamicToolSyncInterval = 15 * time.Second
+
+	defaultHTTPToolTimeout = 30 * time.Second
+	maxHTTPToolTimeout     = 120 * time.Second
+
+	// maxHTTPToolResponseSize HTTP 工具响应的最大字节数
+	maxHTTPToolResponseSize = 1 << 20
+)
+
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,63}$`)
+
+// ValidateHTTPTool 校验并规范化动态工具定义：名称、参数 JSON Schema、方法、地址、认证方式
+func ValidateHTTPTool(tool *models.MCPTool) error {
+	if !toolNamePattern.MatchString(tool.Name) {
+		return fmt.Errorf("invalid tool name %q: letters, digits, _ and - only, starting with a letter, at most 64 characters", tool.Name)
+	}
+
+	if strings.TrimSpace(tool.InputSchema) == "" {
+		tool.InputSchema = `{"type":"object","properties":{}}`
+	}
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(tool.InputSchema), &schema); err != nil {
+		return fmt.Errorf("inputSchema must be a JSON object: %w", err)
+	}
+	if schemaType, ok := schema["type"]; ok && schemaType != "object" {
+		return fmt.Errorf("inputSchema type must be object")
+	}
+
+	tool.Method = strings.ToUpper(strings.TrimSpace(tool.Method))
+	if tool.Method == "" {
+		tool.Method = http.MethodPost
+	}
+	switch tool.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return fmt.Errorf("unsupported method: %s", tool.Method)
+	}
+
+	endpoint, err := url.Parse(tool.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("endpoint must be an http(s) URL")
+	}
+
+	if tool.Headers != "" {
+		var headers map[string]string
+		if err := json.Unmarshal([]byte(tool.Headers), &headers); err != nil {
+			return fmt.Errorf("headers must be a JSON object of strings: %w", err)
+		}
+	}
+
+	if tool.TimeoutSeconds < 0 || time.Duration(tool.TimeoutSeconds)*time.Second > maxHTTPToolTimeout {
+		return fmt.Errorf("timeoutSeconds must be between 0 and %d", int(maxHTTPToolTimeout.Seconds()))
+	}
+
+	if tool.AuthType == "" {
+		tool.AuthType = models.MCPToolAuthNone
+	}
+	switch tool.AuthType {
+	case models.MCPToolAuthNone:
+	case models.MCPToolAuthBearer, models.MCPToolAuthBasic:
+		if tool.AuthSecret == "" {
+			return fmt.Errorf("authSecret is required for %s auth", tool.AuthType)
+		}
+	case models.MCPToolAuthHeader:
+		if tool.AuthHeader == "" || tool.AuthSecret == "" {
+			return fmt.Errorf("authHeader and authSecret are required for header auth")
+		}
+	default:
+		return fmt.Errorf("unsupported authType: %s", tool.AuthType)
+	}
+	return nil
+}
+
+// NewHTTPToolHandler 创建调用 HTTP 接口的工具处理函数：GET/DELETE 参数放在查询字符串，
+// 其余方法以 JSON 请求体发送；2xx 的 JSON 响应作为 data 返回，其他响应作为文本返回
+func NewHTTPToolHandler(tool models.MCPTool, client *http.Client) ContextToolHandler {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	timeout := defaultHTTPToolTimeout
+	if tool.TimeoutSeconds > 0 {
+		timeout = time.Duration(tool.TimeoutSeconds) * time.Second
+	}
+	var headers map[string]string
+	if tool.Headers != "" {
+		_ = json.Unmarshal([]byte(tool.Headers), &headers)
+	}
+
+	return func(ctx context.Context, arguments map[string]any) (*mcp.CallToolResult, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		endpoint := tool.Endpoint
+		var body io.Reader
+		if tool.Method == http.MethodGet || tool.Method == http.MethodDelete {
+			u, err := url.Parse(endpoint)
+			if err != nil {
+				return nil, err
+			}
+			query := u.Query()
+			for key, value := range arguments {
+				query.Set(key, queryValue(value))
+			}
+			u.RawQuery = query.Encode()
+			endpoint = u.String()
+		} else {
+			data, err := json.Marshal(arguments)
+			if err != nil {
+				return nil, err
+			}
+			body = bytes.NewReader(data)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, tool.Method, endpoint, body)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("User-Agent", "LingEcho-MCP-Tool/1.0")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		switch tool.AuthType {
+		case models.MCPToolAuthBearer:
+			req.Header.Set("Authorization", "Bearer "+tool.AuthSecret)
+		case models.MCPToolAuthBasic:
+			username, password, _ := strings.Cut(tool.AuthSecret, ":")
+			req.SetBasicAuth(username, password)
+		case models.MCPToolAuthHeader:
+			req.Header.Set(tool.AuthHeader, tool.AuthSecret)
+		}
+		if caller := CallerFromContext(ctx); caller != nil && caller.UserID > 0 {
+			req.Header.Set("X-LingEcho-User-Id", strconv.FormatUint(uint64(caller.UserID), 10))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return ErrorResponse(502, "工具接口请求失败", err.Error()), nil
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPToolResponseSize))
+		if err != nil {
+			return ErrorResponse(502, "读取工具接口响应失败", err.Error()), nil
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return ErrorResponse(502, fmt.Sprintf("工具接口返回状态码 %d", resp.StatusCode), string(data)), nil
+		}
+		if json.Valid(data) {
+			return SuccessResponse(json.RawMessage(data)), nil
+		}
+		return TextResponse(string(data)), nil
+	}
+}
+
+// queryValue 查询参数值：标量原样，其他编码为 JSON
+func queryValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64, bool, int, int64:
+		return fmt.Sprint(v)
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// DynamicTools 将数据库中启用的 HTTP 工具（models.MCPTool）同步到 MCP 服务器：
+// 新增或修改的工具立即注册，删除或禁用的工具移除，已连接的客户端会收到 tools/list_changed 通知
+type DynamicTools struct {
+	server *MCPServer
+	db     *gorm.DB
+	client *http.Client
+	logger *zap.Logger
+
+	mu         sync.Mutex
+	registered map[string]time.Time // 工具名 -> 注册时的 UpdatedAt
+	skipped    map[string]time.Time // 与内置工具重名或定义无效而未注册的工具，同一版本只告警一次
+}
+
+// NewDynamicTools 创建动态工具同步器
+func NewDynamicTools(server *MCPServer, db *gorm.DB, logger *zap.Logger) *DynamicTools {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &DynamicTools{
+		server:     server,
+		db:         db,
+		client:     &http.Client{},
+		logger:     logger,
+		registered: make(map[string]time.Time),
+		skipped:    make(map[string]time.Time),
+	}
+}
+
+// Sync 从数据库加载启用的工具并与已注册的动态工具对比，返回当前动态工具数
+func (d *DynamicTools) Sync(ctx context.Context) (int, error) {
+	tools, err := models.ListEnabledMCPTools(d.db.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current := make(map[string]bool, len(tools))
+	skipped := make(map[string]time.Time)
+	for _, tool := range tools {
+		if updatedAt, ok := d.registered[tool.Name]; ok && updatedAt.Equal(tool.UpdatedAt) {
+			current[tool.Name] = true
+			continue
+		}
+
+		var reason error
+		if _, dynamic := d.registered[tool.Name]; !dynamic && d.server.HasTool(tool.Name) {
+			reason = fmt.Errorf("name conflicts with a built-in tool")
+		} else {
+			reason = ValidateHTTPTool(&tool)
+		}
+		if reason != nil {
+			if updatedAt, ok := d.skipped[tool.Name]; !ok || !updatedAt.Equal(tool.UpdatedAt) {
+				d.logger.Warn("动态工具未注册", zap.String("name", tool.Name), zap.Error(reason))
+			}
+			skipped[tool.Name] = tool.UpdatedAt
+			continue
+		}
+
+		d.server.RegisterRawSchemaTool(tool.Name, tool.Description, json.RawMessage(tool.InputSchema), NewHTTPToolHandler(tool, d.client))
+		d.registered[tool.Name] = tool.UpdatedAt
+		current[tool.Name] = true
+	}
+	d.skipped = skipped
+
+	for name := range d.registered {
+		if !current[name] {
+			d.server.UnregisterTool(name)
+			delete(d.registered, name)
+		}
+	}
+	return len(d.registered), nil
+}
+
+// Run 按 interval 定期同步，直到 ctx 结束；多实例部署时其他实例的修改在一个周期内生效
+func (d *DynamicTools) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultDynamicToolSyncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.Sync(ctx); err != nil {
+				d.logger.Warn("动态工具同步失败", zap.Error(err))
+			}
+		}
+	}
+}
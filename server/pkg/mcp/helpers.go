@@ -153,8 +153,18 @@ func TextResponse(text string) *mcp.CallToolResult {
 // 注意：这里使用 server.CallToolResult 因为 mcp-go 库的类型定义
 type ToolHandler func(arguments map[string]any) (*mcp.CallToolResult, error)
 
+// ContextToolHandler 需要请求上下文（调用方、取消信号）的工具处理函数
+type ContextToolHandler func(ctx context.Context, arguments map[string]any) (*mcp.CallToolResult, error)
+
 // SafeToolHandler 包装工具函数,捕获panic并返回友好错误
 func SafeToolHandler(toolName string, logger *zap.Logger, handler ToolHandler) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return SafeContextToolHandler(toolName, logger, func(_ context.Context, arguments map[string]any) (*mcp.CallToolResult, error) {
+		return handler(arguments)
+	})
+}
+
+// SafeContextToolHandler 同 SafeToolHandler，处理函数可获取请求上下文
+func SafeContextToolHandler(toolName string, logger *zap.Logger, handler ContextToolHandler) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
 		// 捕获panic
 		defer func() {
@@ -178,7 +188,7 @@ func SafeToolHandler(toolName string, logger *zap.Logger, handler ToolHandler) f
 			zap.Any("arguments", arguments),
 		)
 
-		result, err = handler(arguments)
+		result, err = handler(ctx, arguments)
 
 		if err != nil {
 			logger.Error("工具调用失败",
@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/code-100-precent/LingEcho/pkg/prompt"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// RegisterPromptTemplates 将提示系统（pkg/prompt）中已加载的提示模板注册为 MCP 提示，
+// 需在 prompt.InitPromptSystem 之后调用
+func RegisterPromptTemplates(server *MCPServer) int {
+	prompts := prompt.ListPrompts()
+	for _, p := range prompts {
+		options := []mcp.PromptOption{mcp.WithPromptDescription(p.Description)}
+		for _, arg := range p.Arguments {
+			argOptions := []mcp.ArgumentOption{mcp.ArgumentDescription(arg.Description)}
+			if arg.Required {
+				argOptions = append(argOptions, mcp.RequiredArgument())
+			}
+			options = append(options, mcp.WithArgument(arg.Name, argOptions...))
+		}
+
+		name := p.Name
+		server.RegisterPrompt(mcp.NewPrompt(name, options...), func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			result, err := prompt.GetPrompt(ctx, name, request.Params.Arguments)
+			if err != nil {
+				return nil, err
+			}
+			messages := make([]mcp.PromptMessage, 0, len(result.Messages))
+			for _, message := range result.Messages {
+				text, ok := message.Content.(prompt.TextContent)
+				if !ok {
+					return nil, fmt.Errorf("unsupported content type in prompt %s", name)
+				}
+				messages = append(messages, mcp.NewPromptMessage(mcp.Role(message.Role), mcp.NewTextContent(text.Text)))
+			}
+			return mcp.NewGetPromptResult(result.Description, messages), nil
+		})
+	}
+	return len(prompts)
+}
@@ -0,0 +1,290 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/knowledge"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gorm.io/gorm"
+)
+
+const (
+	// ResourceURIPrefix LingEcho 资源 URI 前缀
+	ResourceURIPrefix = "lingecho://"
+
+	// maxResourceDocumentSize 读取知识库文档的最大字节数
+	maxResourceDocumentSize = 1 << 20
+
+	// recentTranscriptSessions 转写列表返回的最近会话数
+	recentTranscriptSessions = 50
+)
+
+// ContextWithUserID 在上下文中记录调用方用户，资源只返回该用户可见的数据
+func ContextWithUserID(ctx context.Context, userID uint) context.Context {
+	return ContextWithCaller(ctx, &Caller{UserID: userID})
+}
+
+// UserIDFromContext 获取调用方用户，未设置时（如本地 stdio 进程）不按用户过滤
+func UserIDFromContext(ctx context.Context) (uint, bool) {
+	caller := CallerFromContext(ctx)
+	if caller == nil || caller.UserID == 0 {
+		return 0, false
+	}
+	return caller.UserID, true
+}
+
+// RegisterResourceProviders 将助手配置、知识库文档、通话转写注册为 MCP 资源，
+// 客户端可通过 resources/list 浏览、resources/read 读取
+func RegisterResourceProviders(server *MCPServer, db *gorm.DB) {
+	// 1. 助手
+	server.RegisterResource(
+		mcp.NewResource(ResourceURIPrefix+"assistants", "assistants",
+			mcp.WithResourceDescription("助手列表"),
+			mcp.WithMIMEType("application/json"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			var assistants []models.Assistant
+			query := db.WithContext(ctx).Select("id", "user_id", "name", "description", "updated_at")
+			if userID, ok := UserIDFromContext(ctx); ok {
+				query = query.Where("user_id = ?", userID)
+			}
+			if err := query.Order("id ASC").Find(&assistants).Error; err != nil {
+				return nil, err
+			}
+			items := make([]map[string]interface{}, 0, len(assistants))
+			for _, assistant := range assistants {
+				items = append(items, map[string]interface{}{
+					"id":          assistant.ID,
+					"name":        assistant.Name,
+					"description": assistant.Description,
+					"uri":         fmt.Sprintf("%sassistants/%d", ResourceURIPrefix, assistant.ID),
+					"updatedAt":   assistant.UpdatedAt,
+				})
+			}
+			return jsonResource(request.Params.URI, items)
+		},
+	)
+	server.RegisterResourceTemplate(
+		mcp.NewResourceTemplate(ResourceURIPrefix+"assistants/{id}", "assistant",
+			mcp.WithTemplateDescription("助手配置（提示词、模型、语音、知识库等），不包含密钥"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			id, err := strconv.ParseInt(resourceArgument(request, "id"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid assistant id")
+			}
+			var assistant models.Assistant
+			query := db.WithContext(ctx).Where("id = ?", id)
+			if userID, ok := UserIDFromContext(ctx); ok {
+				query = query.Where("user_id = ?", userID)
+			}
+			if err := query.First(&assistant).Error; err != nil {
+				return nil, fmt.Errorf("assistant not found: %d", id)
+			}
+			assistant.ApiKey = ""
+			assistant.ApiSecret = ""
+			return jsonResource(request.Params.URI, assistant)
+		},
+	)
+
+	// 2. 知识库
+	server.RegisterResource(
+		mcp.NewResource(ResourceURIPrefix+"knowledge", "knowledge-bases",
+			mcp.WithResourceDescription("知识库列表"),
+			mcp.WithMIMEType("application/json"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			var knowledgeList []models.Knowledge
+			var err error
+			if userID, ok := UserIDFromContext(ctx); ok {
+				knowledgeList, err = models.GetKnowledgeByUserID(db.WithContext(ctx), int(userID))
+			} else {
+				err = db.WithContext(ctx).Order("created_at DESC").Find(&knowledgeList).Error
+			}
+			if err != nil {
+				return nil, err
+			}
+			items := make([]map[string]interface{}, 0, len(knowledgeList))
+			for _, k := range knowledgeList {
+				items = append(items, map[string]interface{}{
+					"key":       k.KnowledgeKey,
+					"name":      k.KnowledgeName,
+					"provider":  k.Provider,
+					"uri":       ResourceURIPrefix + "knowledge/" + k.KnowledgeKey,
+					"createdAt": k.CreatedAt,
+				})
+			}
+			return jsonResource(request.Params.URI, items)
+		},
+	)
+	server.RegisterResourceTemplate(
+		mcp.NewResourceTemplate(ResourceURIPrefix+"knowledge/{key}", "knowledge-base",
+			mcp.WithTemplateDescription("知识库信息及其文档列表"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			key := resourceArgument(request, "key")
+			k, kb, err := openResourceKnowledgeBase(ctx, db, key)
+			if err != nil {
+				return nil, err
+			}
+			documentIDs, err := kb.ListDocuments(ctx, key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list documents: %w", err)
+			}
+			documents := make([]map[string]interface{}, 0, len(documentIDs))
+			for _, documentID := range documentIDs {
+				documents = append(documents, map[string]interface{}{
+					"id":  documentID,
+					"uri": ResourceURIPrefix + "knowledge/" + key + "/documents/" + documentID,
+				})
+			}
+			return jsonResource(request.Params.URI, map[string]interface{}{
+				"key":       k.KnowledgeKey,
+				"name":      k.KnowledgeName,
+				"provider":  k.Provider,
+				"documents": documents,
+			})
+		},
+	)
+	server.RegisterResourceTemplate(
+		mcp.NewResourceTemplate(ResourceURIPrefix+"knowledge/{key}/documents/{documentId}", "knowledge-document",
+			mcp.WithTemplateDescription("知识库文档内容"),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			key := resourceArgument(request, "key")
+			_, kb, err := openResourceKnowledgeBase(ctx, db, key)
+			if err != nil {
+				return nil, err
+			}
+			reader, err := kb.GetDocument(ctx, key, resourceArgument(request, "documentId"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get document: %w", err)
+			}
+			defer reader.Close()
+			data, err := io.ReadAll(io.LimitReader(reader, maxResourceDocumentSize))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read document: %w", err)
+			}
+			return []mcp.ResourceContents{
+				mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "text/plain", Text: string(data)},
+			}, nil
+		},
+	)
+
+	// 3. 通话转写
+	server.RegisterResource(
+		mcp.NewResource(ResourceURIPrefix+"transcripts", "call-transcripts",
+			mcp.WithResourceDescription(fmt.Sprintf("最近 %d 个通话的转写列表", recentTranscriptSessions)),
+			mcp.WithMIMEType("application/json"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			type transcriptSession struct {
+				SessionID string `json:"sessionId"`
+				Segments  int    `json:"segments"`
+				URI       string `json:"uri"`
+			}
+			var sessions []transcriptSession
+			query := db.WithContext(ctx).Model(&models.CallTranscript{}).
+				Select("session_id, COUNT(*) AS segments")
+			if userID, ok := UserIDFromContext(ctx); ok {
+				query = query.Where("user_id = ?", userID)
+			}
+			if err := query.Group("session_id").Order("MAX(id) DESC").Limit(recentTranscriptSessions).
+				Scan(&sessions).Error; err != nil {
+				return nil, err
+			}
+			for i := range sessions {
+				sessions[i].URI = ResourceURIPrefix + "transcripts/" + sessions[i].SessionID
+			}
+			return jsonResource(request.Params.URI, sessions)
+		},
+	)
+	server.RegisterResourceTemplate(
+		mcp.NewResourceTemplate(ResourceURIPrefix+"transcripts/{sessionId}", "call-transcript",
+			mcp.WithTemplateDescription("通话的完整转写，按时间顺序"),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			sessionID := resourceArgument(request, "sessionId")
+			var segments []models.CallTranscript
+			query := db.WithContext(ctx).Where("session_id = ?", sessionID)
+			if userID, ok := UserIDFromContext(ctx); ok {
+				query = query.Where("user_id = ?", userID)
+			}
+			if err := query.Order("start_ms ASC, id ASC").Find(&segments).Error; err != nil {
+				return nil, err
+			}
+			if len(segments) == 0 {
+				return nil, fmt.Errorf("transcript not found: %s", sessionID)
+			}
+			var text strings.Builder
+			for _, segment := range segments {
+				speaker := "用户"
+				if segment.Speaker == models.TranscriptSpeakerAssistant {
+					speaker = "助手"
+				}
+				seconds := segment.StartMs / 1000
+				fmt.Fprintf(&text, "[%02d:%02d] %s: %s\n", seconds/60, seconds%60, speaker, segment.Text)
+			}
+			return []mcp.ResourceContents{
+				mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "text/plain", Text: text.String()},
+			}, nil
+		},
+	)
+}
+
+// openResourceKnowledgeBase 打开知识库，设置了调用方用户时只允许访问其可见的知识库
+func openResourceKnowledgeBase(ctx context.Context, db *gorm.DB, key string) (*models.Knowledge, knowledge.KnowledgeBase, error) {
+	k, kb, _, err := models.OpenKnowledgeBase(db.WithContext(ctx), key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("knowledge base not found: %s", key)
+	}
+	if userID, ok := UserIDFromContext(ctx); ok && k.UserID != int(userID) {
+		visible, err := models.GetKnowledgeByUserID(db.WithContext(ctx), int(userID))
+		if err != nil {
+			return nil, nil, err
+		}
+		found := false
+		for _, v := range visible {
+			if v.KnowledgeKey == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("knowledge base not found: %s", key)
+		}
+	}
+	return k, kb, nil
+}
+
+// resourceArgument 获取 URI 模板变量
+func resourceArgument(request mcp.ReadResourceRequest, name string) string {
+	switch value := request.Params.Arguments[name].(type) {
+	case string:
+		return value
+	case []string:
+		return strings.Join(value, ",")
+	}
+	return ""
+}
+
+// jsonResource 将数据编码为 JSON 资源内容
+func jsonResource(uri string, data interface{}) ([]mcp.ResourceContents, error) {
+	body, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: uri, MIMEType: "application/json", Text: string(body)},
+	}, nil
+}
@@ -2,7 +2,11 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -16,7 +20,10 @@ type ServerOption = server.ServerOption
 type MCPServer struct {
 	server  *server.MCPServer
 	logger  *zap.Logger
-	tools   map[string]ToolHandler
+	mu      sync.RWMutex // 保护 tools、defs，动态工具会在运行时增删
+	tools   map[string]ContextToolHandler
+	defs    map[string]mcp.Tool
+	auditor Auditor
 	version string
 	name    string
 }
@@ -71,7 +78,8 @@ func NewMCPServer(cfg *Config) *MCPServer {
 	return &MCPServer{
 		server:  mcpServer,
 		logger:  cfg.Logger,
-		tools:   make(map[string]ToolHandler),
+		tools:   make(map[string]ContextToolHandler),
+		defs:    make(map[string]mcp.Tool),
 		version: cfg.Version,
 		name:    cfg.Name,
 	}
@@ -93,23 +101,24 @@ func (s *MCPServer) RegisterTool(
 	handler ToolHandler,
 	params ...mcp.ToolOption,
 ) {
-	// 保存处理器
-	s.tools[name] = handler
+	s.RegisterContextTool(name, description, func(_ context.Context, arguments map[string]any) (*mcp.CallToolResult, error) {
+		return handler(arguments)
+	}, params...)
+}
 
+// RegisterContextTool 注册一个需要请求上下文的工具，处理函数可通过 CallerFromContext 获取调用方
+func (s *MCPServer) RegisterContextTool(
+	name string,
+	description string,
+	handler ContextToolHandler,
+	params ...mcp.ToolOption,
+) {
 	// 创建工具定义，合并描述和参数选项
 	options := []mcp.ToolOption{
 		mcp.WithDescription(description),
 	}
 	options = append(options, params...)
-	tool := mcp.NewTool(name, options...)
-
-	// 使用 SafeToolHandler 包装处理器，转换为 server.ToolHandlerFunc
-	wrappedHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return SafeToolHandler(name, s.logger, handler)(ctx, request)
-	}
-
-	// 注册工具
-	s.server.AddTool(tool, wrappedHandler)
+	s.addTool(mcp.NewTool(name, options...), handler)
 
 	s.logger.Info("MCP 工具已注册",
 		zap.String("name", name),
@@ -125,32 +134,107 @@ func (s *MCPServer) RegisterToolWithSchema(
 	handler ToolHandler,
 	params ...mcp.ToolOption,
 ) {
-	// 保存处理器
-	s.tools[name] = handler
-
 	// 创建工具定义
 	options := []mcp.ToolOption{
 		mcp.WithDescription(description),
 	}
 	options = append(options, params...)
-	tool := mcp.NewTool(name, options...)
+	s.addTool(mcp.NewTool(name, options...), func(_ context.Context, arguments map[string]any) (*mcp.CallToolResult, error) {
+		return handler(arguments)
+	})
 
-	// 使用 SafeToolHandler 包装处理器，转换为 server.ToolHandlerFunc
-	wrappedHandler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return SafeToolHandler(name, s.logger, handler)(ctx, request)
-	}
+	s.logger.Info("MCP 工具已注册（自定义 schema）",
+		zap.String("name", name),
+		zap.String("description", description),
+	)
+}
 
-	// 注册工具
-	s.server.AddTool(tool, wrappedHandler)
+// RegisterRawSchemaTool 使用 JSON Schema 原文注册工具（如运行时定义的 HTTP 工具），
+// 同名工具已存在时替换
+func (s *MCPServer) RegisterRawSchemaTool(
+	name string,
+	description string,
+	schema json.RawMessage,
+	handler ContextToolHandler,
+) {
+	s.addTool(mcp.NewToolWithRawSchema(name, description, schema), handler)
 
-	s.logger.Info("MCP 工具已注册（自定义 schema）",
+	s.logger.Info("MCP 工具已注册（JSON Schema）",
 		zap.String("name", name),
 		zap.String("description", description),
 	)
 }
 
+// UnregisterTool 移除工具，已连接的客户端会收到 tools/list_changed 通知
+func (s *MCPServer) UnregisterTool(name string) {
+	s.mu.Lock()
+	_, exists := s.tools[name]
+	delete(s.tools, name)
+	delete(s.defs, name)
+	s.mu.Unlock()
+	if !exists {
+		return
+	}
+
+	s.server.DeleteTools(name)
+	s.logger.Info("MCP 工具已移除", zap.String("name", name))
+}
+
+// HasTool 工具是否已注册
+func (s *MCPServer) HasTool(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, exists := s.tools[name]
+	return exists
+}
+
+// addTool 保存处理器和定义，并以校验调用范围、记录审计的处理函数注册到底层服务器
+func (s *MCPServer) addTool(tool mcp.Tool, handler ContextToolHandler) {
+	s.mu.Lock()
+	s.tools[tool.Name] = handler
+	s.defs[tool.Name] = tool
+	s.mu.Unlock()
+
+	name := tool.Name
+	s.server.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.invoke(ctx, name, handler, request)
+	})
+}
+
+// RegisterResource 注册一个固定 URI 的资源，注册后自动启用资源能力
+func (s *MCPServer) RegisterResource(resource mcp.Resource, handler server.ResourceHandlerFunc) {
+	s.server.AddResource(resource, handler)
+
+	s.logger.Info("MCP 资源已注册",
+		zap.String("uri", resource.URI),
+		zap.String("name", resource.Name),
+	)
+}
+
+// RegisterResourceTemplate 注册资源模板，URI 模板中的变量通过 request.Params.Arguments 传入处理函数
+func (s *MCPServer) RegisterResourceTemplate(template mcp.ResourceTemplate, handler server.ResourceTemplateHandlerFunc) {
+	s.server.AddResourceTemplate(template, handler)
+
+	s.logger.Info("MCP 资源模板已注册",
+		zap.String("uriTemplate", template.URITemplate.Raw()),
+		zap.String("name", template.Name),
+	)
+}
+
+// RegisterPrompt 注册一个提示模板，注册后自动启用提示能力
+func (s *MCPServer) RegisterPrompt(prompt mcp.Prompt, handler server.PromptHandlerFunc) {
+	s.server.AddPrompt(prompt, handler)
+
+	s.logger.Info("MCP 提示已注册",
+		zap.String("name", prompt.Name),
+		zap.String("description", prompt.Description),
+	)
+}
+
 // GetRegisteredTools 获取所有已注册的工具名称
 func (s *MCPServer) GetRegisteredTools() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	tools := make([]string, 0, len(s.tools))
 	for name := range s.tools {
 		tools = append(tools, name)
@@ -158,9 +242,23 @@ func (s *MCPServer) GetRegisteredTools() []string {
 	return tools
 }
 
+// ListTools 获取所有已注册工具的定义，按名称排序
+func (s *MCPServer) ListTools() []mcp.Tool {
+	s.mu.RLock()
+	tools := make([]mcp.Tool, 0, len(s.defs))
+	for _, tool := range s.defs {
+		tools = append(tools, tool)
+	}
+	s.mu.RUnlock()
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
 // CallToolInternal 内部调用工具（用于Agent系统）
 func (s *MCPServer) CallToolInternal(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error) {
+	s.mu.RLock()
 	handler, exists := s.tools[toolName]
+	s.mu.RUnlock()
 	if !exists {
 		return ErrorResponse(404, fmt.Sprintf("Tool %s not found", toolName)), nil
 	}
@@ -174,7 +272,7 @@ func (s *MCPServer) CallToolInternal(ctx context.Context, toolName string, argum
 	}
 
 	// 调用工具处理器
-	result, err := SafeToolHandler(toolName, s.logger, handler)(ctx, request)
+	result, err := s.invoke(ctx, toolName, handler, request)
 	if err != nil {
 		return ErrorResponse(500, fmt.Sprintf("Tool execution failed: %v", err)), nil
 	}
@@ -182,6 +280,96 @@ func (s *MCPServer) CallToolInternal(ctx context.Context, toolName string, argum
 	return result, nil
 }
 
+// SetAuditor 设置工具调用审计，为空时不记录
+func (s *MCPServer) SetAuditor(auditor Auditor) {
+	s.auditor = auditor
+}
+
+// invoke 校验调用方的工具范围后执行工具，并记录审计
+func (s *MCPServer) invoke(ctx context.Context, name string, handler ContextToolHandler, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	startTime := time.Now()
+
+	if caller := CallerFromContext(ctx); caller != nil && !caller.Scopes.AllowTool(name) {
+		s.logger.Warn("MCP 工具调用超出凭证范围",
+			zap.String("tool", name),
+			zap.Uint("userId", caller.UserID),
+			zap.Uint("credentialId", caller.CredentialID),
+		)
+		message := fmt.Sprintf("Tool %s is not allowed for this credential", name)
+		s.audit(ctx, InvocationRecord{
+			Tool:      name,
+			Arguments: request.GetArguments(),
+			Denied:    true,
+			Error:     message,
+			Duration:  time.Since(startTime),
+		})
+		return ErrorResponse(403, message), nil
+	}
+
+	result, err := SafeContextToolHandler(name, s.logger, handler)(ctx, request)
+
+	record := InvocationRecord{
+		Tool:      name,
+		Arguments: request.GetArguments(),
+		Duration:  time.Since(startTime),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		record.Error = resultError(result)
+	}
+	record.Success = err == nil && record.Error == ""
+	s.audit(ctx, record)
+
+	return result, err
+}
+
+func (s *MCPServer) audit(ctx context.Context, record InvocationRecord) {
+	if s.auditor != nil {
+		s.auditor(ctx, record)
+	}
+}
+
+// resultError 从工具结果中提取错误信息：IsError 结果或 ErrorResponse 生成的非 200 响应
+func resultError(result *mcp.CallToolResult) string {
+	if result == nil || len(result.Content) == 0 {
+		return ""
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		if result.IsError {
+			return "tool returned an error"
+		}
+		return ""
+	}
+	if result.IsError {
+		return text.Text
+	}
+	var response struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal([]byte(text.Text), &response); err != nil || response.Code == 0 || response.Code == 200 {
+		return ""
+	}
+	return response.Msg
+}
+
+var (
+	defaultServer     *MCPServer
+	defaultServerOnce sync.Once
+)
+
+// Default 返回进程内共享的 MCP 服务器，首次使用时注册默认工具，
+// 供对话中的 LLM 直接调用
+func Default() *MCPServer {
+	defaultServerOnce.Do(func() {
+		defaultServer = NewMCPServer(&Config{Name: "LingEcho/mcp-internal"})
+		RegisterDefaultTools(defaultServer)
+	})
+	return defaultServer
+}
+
 // getHooks 创建 MCP 服务器钩子
 func getHooks(logger *zap.Logger) *server.Hooks {
 	hooks := &server.Hooks{}
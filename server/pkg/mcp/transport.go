@@ -0,0 +1,228 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+const (
+	// 传输方式
+	TransportStdio = "stdio"
+	TransportSSE   = "sse"
+	TransportHTTP  = "http" // Streamable HTTP
+
+	// DefaultSessionIdleTTL 会话空闲超时，超时后客户端需重新 initialize
+	DefaultSessionIdleTTL = 30 * time.Minute
+
+	// DefaultHeartbeatInterval GET 长连接的心跳间隔，避免代理因空闲断开连接
+	DefaultHeartbeatInterval = 25 * time.Second
+
+	sessionIDPrefix = "mcp-"
+)
+
+// ErrUnauthorized 请求未携带有效的认证信息
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Authenticator 校验 HTTP 请求的认证头，返回用于处理该请求的上下文（可通过 ContextWithCaller 记录调用方及其调用范围）
+type Authenticator func(ctx context.Context, r *http.Request) (context.Context, error)
+
+// TokenFromRequest 从 Authorization: Bearer 或 X-API-Key 请求头中获取令牌
+func TokenFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// StaticTokenAuthenticator 使用固定令牌认证，tokens 为空时不校验
+func StaticTokenAuthenticator(tokens ...string) Authenticator {
+	return func(ctx context.Context, r *http.Request) (context.Context, error) {
+		if len(tokens) == 0 {
+			return ctx, nil
+		}
+		token := TokenFromRequest(r)
+		for _, expected := range tokens {
+			if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+				return ctx, nil
+			}
+		}
+		return nil, ErrUnauthorized
+	}
+}
+
+// RequireAuth 在 MCP HTTP 传输（SSE、Streamable HTTP）前校验认证头，失败时返回 401
+func RequireAuth(next http.Handler, authenticate Authenticator, logger *zap.Logger) http.Handler {
+	if authenticate == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := authenticate(r.Context(), r)
+		if err != nil {
+			logger.Warn("MCP 请求认证失败",
+				zap.String("remote", r.RemoteAddr),
+				zap.String("path", r.URL.Path),
+				zap.Error(err),
+			)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// SessionManager Streamable HTTP 会话管理：生成随机会话ID（Mcp-Session-Id），
+// 空闲超过 idleTTL 的会话视为已终止，客户端收到 404 后重新 initialize
+type SessionManager struct {
+	mu       sync.Mutex
+	sessions map[string]time.Time // 会话ID -> 最后活跃时间
+	idleTTL  time.Duration
+	now      func() time.Time
+}
+
+// NewSessionManager 创建会话管理器，idleTTL <= 0 时使用 DefaultSessionIdleTTL
+func NewSessionManager(idleTTL time.Duration) *SessionManager {
+	if idleTTL <= 0 {
+		idleTTL = DefaultSessionIdleTTL
+	}
+	return &SessionManager{
+		sessions: make(map[string]time.Time),
+		idleTTL:  idleTTL,
+		now:      time.Now,
+	}
+}
+
+// Generate 生成新会话ID
+func (m *SessionManager) Generate() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("failed to generate session id: %v", err))
+	}
+	sessionID := sessionIDPrefix + hex.EncodeToString(buf)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evictExpiredLocked()
+	m.sessions[sessionID] = m.now()
+	return sessionID
+}
+
+// Validate 校验会话ID并刷新活跃时间，过期或已删除的会话返回 isTerminated
+func (m *SessionManager) Validate(sessionID string) (isTerminated bool, err error) {
+	if !strings.HasPrefix(sessionID, sessionIDPrefix) {
+		return false, fmt.Errorf("invalid session id: %s", sessionID)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lastSeen, ok := m.sessions[sessionID]
+	if !ok {
+		return true, nil
+	}
+	now := m.now()
+	if now.Sub(lastSeen) > m.idleTTL {
+		delete(m.sessions, sessionID)
+		return true, nil
+	}
+	m.sessions[sessionID] = now
+	return false, nil
+}
+
+// Terminate 客户端通过 DELETE 主动结束会话
+func (m *SessionManager) Terminate(sessionID string) (isNotAllowed bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, sessionID)
+	return false, nil
+}
+
+// Count 当前活跃会话数
+func (m *SessionManager) Count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evictExpiredLocked()
+	return len(m.sessions)
+}
+
+func (m *SessionManager) evictExpiredLocked() {
+	now := m.now()
+	for sessionID, lastSeen := range m.sessions {
+		if now.Sub(lastSeen) > m.idleTTL {
+			delete(m.sessions, sessionID)
+		}
+	}
+}
+
+// StreamableHTTPConfig Streamable HTTP 传输配置
+type StreamableHTTPConfig struct {
+	EndpointPath      string        // 端点路径，默认 /mcp
+	Stateless         bool          // 无状态模式，不分配会话ID，适合多实例无粘性负载均衡
+	SessionIdleTTL    time.Duration // 会话空闲超时，默认 30 分钟
+	HeartbeatInterval time.Duration // 心跳间隔，默认 25 秒，小于 0 时关闭
+	Authenticator     Authenticator // 认证，为空时不校验
+}
+
+// NewStreamableHTTPHandler 创建 Streamable HTTP 传输的 HTTP 处理器。与 SSE 不同，每个请求都是普通的
+// POST/GET/DELETE，服务端按需以 JSON 或 SSE 响应，可以穿过会缓冲或断开长连接的代理
+func (s *MCPServer) NewStreamableHTTPHandler(cfg StreamableHTTPConfig) (http.Handler, string) {
+	if cfg.EndpointPath == "" {
+		cfg.EndpointPath = "/mcp"
+	}
+	if cfg.HeartbeatInterval == 0 {
+		cfg.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+
+	opts := []server.StreamableHTTPOption{
+		server.WithEndpointPath(cfg.EndpointPath),
+	}
+	if cfg.Stateless {
+		opts = append(opts, server.WithStateLess(true))
+	} else {
+		opts = append(opts, server.WithSessionIdManager(NewSessionManager(cfg.SessionIdleTTL)))
+	}
+	if cfg.HeartbeatInterval > 0 {
+		opts = append(opts, server.WithHeartbeatInterval(cfg.HeartbeatInterval))
+	}
+
+	handler := server.NewStreamableHTTPServer(s.server, opts...)
+	return RequireAuth(handler, cfg.Authenticator, s.logger), cfg.EndpointPath
+}
+
+// ServeSSE 以 SSE 传输启动服务，SSE 连接和消息端点均需通过认证，阻塞直到服务停止
+func (s *MCPServer) ServeSSE(addr string, authenticate Authenticator) error {
+	handler := RequireAuth(server.NewSSEServer(s.server), authenticate, s.logger)
+
+	s.logger.Info("MCP SSE 服务已启动",
+		zap.String("addr", addr),
+		zap.Bool("auth", authenticate != nil),
+	)
+	return (&http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}).ListenAndServe()
+}
+
+// ServeStreamableHTTP 以 Streamable HTTP 传输启动服务，阻塞直到服务停止
+func (s *MCPServer) ServeStreamableHTTP(addr string, cfg StreamableHTTPConfig) error {
+	handler, path := s.NewStreamableHTTPHandler(cfg)
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+
+	s.logger.Info("MCP Streamable HTTP 服务已启动",
+		zap.String("addr", addr),
+		zap.String("path", path),
+		zap.Bool("stateless", cfg.Stateless),
+		zap.Bool("auth", cfg.Authenticator != nil),
+	)
+	return (&http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}).ListenAndServe()
+}
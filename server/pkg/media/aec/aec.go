@@ -0,0 +1,400 @@
+// Package aec implements acoustic echo cancellation on 16-bit little-endian PCM.
+//
+// The canceller keeps the far-end (playback) signal on the same timeline as
+// the near-end (capture) signal, estimates the bulk playback-to-microphone
+// delay by cross-correlating the two, and removes the remaining echo with an
+// NLMS adaptive filter. Adaptation is frozen during double talk (Geigel
+// detector) and residual echo is attenuated while only the far end is active.
+package aec
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultSampleRate   = 16000
+	DefaultFilterLength = 64 * time.Millisecond
+	DefaultMaxDelay     = 320 * time.Millisecond
+	DefaultStepSize     = 0.5
+	DefaultSuppression  = 0.1
+
+	// decimation used for delay estimation, correlating at 4kHz is plenty for speech
+	decimation = 4
+	// delay is re-estimated every estimateInterval of capture
+	estimateInterval = 500 * time.Millisecond
+	// minimum normalized cross-correlation for a delay estimate to be trusted
+	minCorrelation = 0.4
+	// far-end RMS below which the playback is treated as silent
+	farActiveRMS = 50.0
+	// Geigel detector: near-end louder than geigelRatio*max|far| means the user is talking
+	geigelRatio = 0.5
+)
+
+// Config configures an AEC
+type Config struct {
+	SampleRate   int           // PCM sample rate, default 16000
+	FilterLength time.Duration // echo tail modeled by the adaptive filter, default 64ms
+	MaxDelay     time.Duration // largest playback-to-capture delay searched, default 320ms
+	StepSize     float64       // NLMS step size (0-2), default 0.5
+	Suppression  float64       // gain applied to residual echo while only the far end talks, default 0.1; 1 disables
+}
+
+// Stats describes the canceller state, for logging
+type Stats struct {
+	Delay      time.Duration // estimated bulk playback-to-capture delay
+	ERLE       float64       // echo return loss enhancement of the last frame (dB), 0 when the far end is silent
+	DoubleTalk bool          // near-end speech was detected in the last frame
+}
+
+// AEC is an acoustic echo canceller. Playback and Process may be called from
+// different goroutines (typically the playout loop and the capture callback).
+type AEC struct {
+	mu  sync.Mutex
+	cfg Config
+
+	taps     int
+	maxDelay int
+
+	// pending holds playback not yet consumed by the capture timeline. It is
+	// drained at the capture rate, so its head is the sample being played now.
+	pending []float64
+
+	// far-end history aligned with the capture timeline: far[i] is the
+	// playback sample at absolute capture time farBase+i
+	far     []float64
+	farBase int64
+	now     int64 // absolute capture time of the next near-end sample
+
+	// decimated history for delay estimation
+	farDec      []float64
+	nearDec     []float64
+	decAcc      [2]float64
+	decCount    int
+	sinceUpdate int
+
+	delay   int
+	weights []float64
+	power   float64 // energy of the current regression vector
+	stats   Stats
+}
+
+// New creates an AEC, filling in defaults for zero config values
+func New(cfg Config) *AEC {
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = DefaultSampleRate
+	}
+	if cfg.FilterLength <= 0 {
+		cfg.FilterLength = DefaultFilterLength
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = DefaultMaxDelay
+	}
+	if cfg.StepSize <= 0 || cfg.StepSize >= 2 {
+		cfg.StepSize = DefaultStepSize
+	}
+	if cfg.Suppression <= 0 || cfg.Suppression > 1 {
+		cfg.Suppression = DefaultSuppression
+	}
+	taps := samplesFor(cfg.SampleRate, cfg.FilterLength)
+	a := &AEC{
+		cfg:      cfg,
+		taps:     taps,
+		maxDelay: samplesFor(cfg.SampleRate, cfg.MaxDelay),
+		weights:  make([]float64, taps),
+	}
+	a.padFar()
+	return a
+}
+
+// padFar starts the far-end history with silence so the filter window never
+// reaches before its start
+func (a *AEC) padFar() {
+	a.far = make([]float64, a.maxDelay+a.taps)
+	a.farBase = a.now - int64(len(a.far))
+}
+
+func samplesFor(sampleRate int, d time.Duration) int {
+	return int(int64(sampleRate) * int64(d) / int64(time.Second))
+}
+
+// Playback records PCM handed to the speaker. Call it with exactly the data
+// written to the playback device, in order.
+func (a *AEC) Playback(pcm []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := 0; i+1 < len(pcm); i += 2 {
+		a.pending = append(a.pending, float64(int16(uint16(pcm[i])|uint16(pcm[i+1])<<8)))
+	}
+	// A playback queue longer than MaxDelay cannot be cancelled anyway
+	if over := len(a.pending) - a.maxDelay; over > 0 {
+		a.pending = a.pending[over:]
+	}
+}
+
+// DiscardPlayback drops playback that has not been played yet, e.g. after the
+// player buffer is cleared on barge-in
+func (a *AEC) DiscardPlayback() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = nil
+}
+
+// Process removes echo from captured PCM and returns the cleaned PCM
+func (a *AEC) Process(capture []byte) []byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	n := len(capture) / 2
+	out := make([]byte, n*2)
+	if n == 0 {
+		return out
+	}
+	a.advanceFar(n)
+
+	near := make([]float64, n)
+	for i := 0; i < n; i++ {
+		near[i] = float64(int16(uint16(capture[2*i]) | uint16(capture[2*i+1])<<8))
+	}
+
+	// Far-end samples that can echo into this frame; the loudest one is the Geigel reference
+	end := int(a.now-a.farBase) + n - a.delay
+	var farMax, farEnergy float64
+	for _, x := range a.far[end-n-a.taps+1 : end] {
+		farEnergy += x * x
+		farMax = math.Max(farMax, math.Abs(x))
+	}
+	farActive := math.Sqrt(farEnergy/float64(n+a.taps-1)) > farActiveRMS
+
+	doubleTalk := false
+	for _, y := range near {
+		if farActive && math.Abs(y) > geigelRatio*farMax {
+			doubleTalk = true
+			break
+		}
+	}
+
+	var nearEnergy, outEnergy float64
+	residual := make([]float64, n)
+	for i, y := range near {
+		t := a.now + int64(i)
+		residual[i] = a.filterSample(t, y, farActive && !doubleTalk)
+		nearEnergy += y * y
+		outEnergy += residual[i] * residual[i]
+	}
+
+	// Residual echo suppression: with only the far end talking, whatever is
+	// left is echo the linear filter could not model
+	gain := 1.0
+	if farActive && !doubleTalk {
+		gain = a.cfg.Suppression
+	}
+	for i, e := range residual {
+		v := clamp16(e * gain)
+		out[2*i] = byte(v)
+		out[2*i+1] = byte(uint16(v) >> 8)
+	}
+
+	a.stats.DoubleTalk = doubleTalk
+	a.stats.ERLE = 0
+	if farActive && outEnergy > 0 {
+		a.stats.ERLE = 10 * math.Log10(nearEnergy/outEnergy)
+	}
+
+	start := a.now
+	a.now += int64(n)
+	a.recordForEstimate(near, start)
+	a.trimFar()
+	return out
+}
+
+// advanceFar moves n playback samples onto the capture timeline, padding
+// with silence when nothing is playing
+func (a *AEC) advanceFar(n int) {
+	take := n
+	if take > len(a.pending) {
+		take = len(a.pending)
+	}
+	a.far = append(a.far, a.pending[:take]...)
+	a.pending = a.pending[take:]
+	for i := take; i < n; i++ {
+		a.far = append(a.far, 0)
+	}
+}
+
+// trimFar keeps enough far-end history for the longest delay plus the filter
+func (a *AEC) trimFar() {
+	keep := a.maxDelay + a.taps
+	if len(a.far) > 2*keep {
+		drop := len(a.far) - keep
+		a.far = append(a.far[:0], a.far[drop:]...)
+		a.farBase += int64(drop)
+	}
+}
+
+// farAt returns the playback sample at absolute capture time t
+func (a *AEC) farAt(t int64) float64 {
+	i := t - a.farBase
+	if i < 0 || i >= int64(len(a.far)) {
+		return 0
+	}
+	return a.far[i]
+}
+
+// filterSample runs one NLMS step for the near-end sample at time t
+func (a *AEC) filterSample(t int64, y float64, adapt bool) float64 {
+	// x[k] pairs with weights[k]: the far-end sample k samples before the aligned one
+	idx := int(t-a.farBase) - a.delay
+	x := a.far[idx-a.taps+1 : idx+1]
+	newest, oldest := x[len(x)-1], a.far[idx-a.taps]
+	a.power += newest*newest - oldest*oldest
+	if a.power < 0 {
+		a.power = 0
+	}
+
+	last := len(x) - 1
+	var estimate float64
+	for k, w := range a.weights {
+		estimate += w * x[last-k]
+	}
+	e := y - estimate
+	if adapt && a.power > 0 {
+		step := a.cfg.StepSize * e / (a.power + float64(a.taps))
+		for k := range a.weights {
+			a.weights[k] += step * x[last-k]
+		}
+	}
+	return e
+}
+
+// recordForEstimate feeds the decimated delay estimator with the frame that
+// started at capture time start, and periodically re-estimates the bulk delay
+func (a *AEC) recordForEstimate(near []float64, start int64) {
+	for i, y := range near {
+		a.decAcc[0] += y
+		a.decAcc[1] += a.farAt(start + int64(i))
+		a.decCount++
+		if a.decCount == decimation {
+			a.nearDec = append(a.nearDec, a.decAcc[0]/decimation)
+			a.farDec = append(a.farDec, a.decAcc[1]/decimation)
+			a.decAcc = [2]float64{}
+			a.decCount = 0
+		}
+	}
+
+	window := samplesFor(a.cfg.SampleRate, estimateInterval) / decimation
+	maxLag := a.maxDelay / decimation
+	if keep := window + maxLag; len(a.farDec) > keep {
+		drop := len(a.farDec) - keep
+		a.farDec = append(a.farDec[:0], a.farDec[drop:]...)
+		a.nearDec = append(a.nearDec[:0], a.nearDec[drop:]...)
+	}
+
+	a.sinceUpdate += len(near)
+	if a.sinceUpdate < samplesFor(a.cfg.SampleRate, estimateInterval) || len(a.nearDec) < window {
+		return
+	}
+	a.sinceUpdate = 0
+	if lag, ok := estimateDelay(a.farDec, a.nearDec[len(a.nearDec)-window:], maxLag); ok {
+		a.setDelay(lag * decimation)
+	}
+}
+
+// setDelay moves the filter window, keeping a quarter of the filter as
+// margin before the estimated echo onset
+func (a *AEC) setDelay(delay int) {
+	delay -= a.taps / 4
+	if delay < 0 {
+		delay = 0
+	}
+	if abs(delay-a.delay) < a.taps/8 {
+		return
+	}
+	a.delay = delay
+	for i := range a.weights {
+		a.weights[i] = 0
+	}
+	a.power = 0
+	for k := 0; k < a.taps; k++ {
+		x := a.farAt(a.now - 1 - int64(delay) - int64(k))
+		a.power += x * x
+	}
+	a.stats.Delay = time.Duration(delay) * time.Second / time.Duration(a.cfg.SampleRate)
+}
+
+// estimateDelay finds the lag at which far best explains the end of near.
+// far and near end at the same instant; far must hold len(near)+maxLag samples.
+func estimateDelay(far, near []float64, maxLag int) (int, bool) {
+	var nearEnergy float64
+	for _, y := range near {
+		nearEnergy += y * y
+	}
+	if nearEnergy == 0 {
+		return 0, false
+	}
+	end := len(far)
+	best, bestLag := 0.0, 0
+	for lag := 0; lag <= maxLag; lag++ {
+		start := end - lag - len(near)
+		if start < 0 {
+			break
+		}
+		var dot, farEnergy float64
+		for i, y := range near {
+			x := far[start+i]
+			dot += x * y
+			farEnergy += x * x
+		}
+		if farEnergy < farActiveRMS*farActiveRMS*float64(len(near)) {
+			continue
+		}
+		if c := math.Abs(dot) / math.Sqrt(nearEnergy*farEnergy); c > best {
+			best, bestLag = c, lag
+		}
+	}
+	return bestLag, best >= minCorrelation
+}
+
+// Stats returns a snapshot of the canceller state
+func (a *AEC) Stats() Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stats
+}
+
+// Reset forgets the echo path and all buffered playback
+func (a *AEC) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = nil
+	a.padFar()
+	a.farDec = nil
+	a.nearDec = nil
+	a.decAcc = [2]float64{}
+	a.decCount = 0
+	a.sinceUpdate = 0
+	a.delay = 0
+	a.power = 0
+	for i := range a.weights {
+		a.weights[i] = 0
+	}
+	a.stats = Stats{}
+}
+
+func clamp16(v float64) int16 {
+	if v > 32767 {
+		return 32767
+	}
+	if v < -32768 {
+		return -32768
+	}
+	return int16(v)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
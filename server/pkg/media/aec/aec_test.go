@@ -0,0 +1,136 @@
+package aec
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+const testRate = 16000
+
+func toPCM(samples []float64) []byte {
+	pcm := make([]byte, len(samples)*2)
+	for i, v := range samples {
+		s := clamp16(v)
+		pcm[2*i] = byte(s)
+		pcm[2*i+1] = byte(uint16(s) >> 8)
+	}
+	return pcm
+}
+
+func fromPCM(pcm []byte) []float64 {
+	samples := make([]float64, len(pcm)/2)
+	for i := range samples {
+		samples[i] = float64(int16(uint16(pcm[2*i]) | uint16(pcm[2*i+1])<<8))
+	}
+	return samples
+}
+
+// speechLike generates band-limited noise with a syllable-like envelope
+func speechLike(r *rand.Rand, n int, amplitude float64) []float64 {
+	out := make([]float64, n)
+	var lp float64
+	for i := range out {
+		lp = 0.7*lp + 0.3*(r.Float64()*2-1)
+		envelope := 0.6 + 0.4*math.Sin(2*math.Pi*4*float64(i)/testRate)
+		out[i] = amplitude * envelope * lp * 2
+	}
+	return out
+}
+
+// echoPath delays the far end and applies a short decaying room response
+func echoPath(far []float64, delay int) []float64 {
+	taps := []float64{0.5, 0.25, -0.1, 0.05}
+	out := make([]float64, len(far))
+	for i := range out {
+		for k, h := range taps {
+			if j := i - delay - k*7; j >= 0 {
+				out[i] += h * far[j]
+			}
+		}
+	}
+	return out
+}
+
+func energy(samples []float64) float64 {
+	var e float64
+	for _, v := range samples {
+		e += v * v
+	}
+	return e
+}
+
+// run feeds far and near through the canceller in 20ms frames
+func run(a *AEC, far, near []float64) []float64 {
+	frame := testRate / 50
+	var out []float64
+	for off := 0; off+frame <= len(near); off += frame {
+		a.Playback(toPCM(far[off : off+frame]))
+		out = append(out, fromPCM(a.Process(toPCM(near[off:off+frame])))...)
+	}
+	return out
+}
+
+func TestAECCancelsEcho(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	far := speechLike(r, 4*testRate, 8000)
+	delay := 1600 // 100ms playback-to-microphone delay
+	near := echoPath(far, delay)
+
+	// Suppression disabled: the adaptive filter alone must cancel the echo
+	a := New(Config{SampleRate: testRate, Suppression: 1})
+	out := run(a, far, near)
+
+	// After convergence the last second is attenuated by more than 20dB
+	tail := testRate
+	erle := 10 * math.Log10(energy(near[len(near)-tail:])/energy(out[len(out)-tail:]))
+	if erle < 20 {
+		t.Errorf("echo attenuated by %.1f dB, want >= 20", erle)
+	}
+	if got := a.Stats().Delay; got <= 0 || got > 100*time.Millisecond {
+		t.Errorf("unexpected delay estimate %v", got)
+	}
+}
+
+func TestAECKeepsNearEndSpeech(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	far := speechLike(r, 4*testRate, 6000)
+	echo := echoPath(far, 800)
+	talker := speechLike(r, 4*testRate, 8000)
+
+	// The far end talks alone for 3 seconds, then the user barges in
+	near := make([]float64, len(echo))
+	start := 3 * testRate
+	for i := range near {
+		near[i] = echo[i]
+		if i >= start {
+			near[i] += talker[i]
+		}
+	}
+
+	a := New(Config{SampleRate: testRate})
+	out := run(a, far, near)
+
+	// The barge-in must not be suppressed: most of the talker's energy survives
+	kept := energy(out[start:]) / energy(talker[start:])
+	if kept < 0.5 {
+		t.Errorf("near-end speech attenuated to %.2f of its energy", kept)
+	}
+	if !a.Stats().DoubleTalk {
+		t.Error("expected double talk to be detected")
+	}
+}
+
+func TestAECPassThroughWithoutPlayback(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	near := speechLike(r, testRate/2, 5000)
+
+	a := New(Config{SampleRate: testRate})
+	out := run(a, make([]float64, len(near)), near)
+	for i := range out {
+		if math.Abs(out[i]-near[i]) > 1 {
+			t.Fatalf("sample %d changed without playback: %v -> %v", i, near[i], out[i])
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package media
+
+import (
+	"math"
+	"time"
+)
+
+// AGC defaults
+const (
+	DefaultAGCTargetRMS    = 3000.0 // about -21 dBFS, comfortable for ASR
+	DefaultAGCMaxGain      = 10.0   // +20 dB
+	DefaultAGCMinGain      = 0.1    // -20 dB
+	DefaultAGCAttack       = 10 * time.Millisecond
+	DefaultAGCRelease      = 500 * time.Millisecond
+	DefaultAGCNoiseGateRMS = 100.0
+
+	// agcClipLevel is the peak the limiter keeps output below
+	agcClipLevel = 32000.0
+)
+
+// AGCConfig configures an AGC
+type AGCConfig struct {
+	SampleRate int // PCM sample rate, default 16000
+	// TargetRMS is the level (0-32768) the gain steers frames towards
+	TargetRMS float64
+	// MaxGain and MinGain bound the applied gain
+	MaxGain float64
+	MinGain float64
+	// Attack is how fast gain drops when the input gets louder, Release how
+	// fast it recovers when the input gets quieter
+	Attack  time.Duration
+	Release time.Duration
+	// Frames quieter than NoiseGateRMS hold the current gain so background
+	// noise is not pumped up between utterances
+	NoiseGateRMS float64
+}
+
+// AGC is an automatic gain control for 16-bit little-endian PCM with a peak
+// limiter that prevents clipping. It is not safe for concurrent use.
+type AGC struct {
+	cfg     AGCConfig
+	gain    float64
+	attack  float64 // per-sample smoothing coefficients
+	release float64
+}
+
+// NewAGC creates an AGC, filling in defaults for zero config values
+func NewAGC(cfg AGCConfig) *AGC {
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = 16000
+	}
+	if cfg.TargetRMS <= 0 {
+		cfg.TargetRMS = DefaultAGCTargetRMS
+	}
+	if cfg.MaxGain <= 0 {
+		cfg.MaxGain = DefaultAGCMaxGain
+	}
+	if cfg.MinGain <= 0 || cfg.MinGain > cfg.MaxGain {
+		cfg.MinGain = math.Min(DefaultAGCMinGain, cfg.MaxGain)
+	}
+	if cfg.Attack <= 0 {
+		cfg.Attack = DefaultAGCAttack
+	}
+	if cfg.Release <= 0 {
+		cfg.Release = DefaultAGCRelease
+	}
+	if cfg.NoiseGateRMS <= 0 {
+		cfg.NoiseGateRMS = DefaultAGCNoiseGateRMS
+	}
+	return &AGC{
+		cfg:     cfg,
+		gain:    1,
+		attack:  smoothingCoefficient(cfg.Attack, cfg.SampleRate),
+		release: smoothingCoefficient(cfg.Release, cfg.SampleRate),
+	}
+}
+
+// smoothingCoefficient returns the one-pole coefficient for time constant d
+func smoothingCoefficient(d time.Duration, sampleRate int) float64 {
+	return math.Exp(-1 / (d.Seconds() * float64(sampleRate)))
+}
+
+// Process applies the gain to pcm in place
+func (a *AGC) Process(pcm []byte) {
+	n := len(pcm) / 2
+	if n == 0 {
+		return
+	}
+
+	var sum, peak float64
+	for i := 0; i < n; i++ {
+		v := float64(int16(uint16(pcm[2*i]) | uint16(pcm[2*i+1])<<8))
+		sum += v * v
+		peak = math.Max(peak, math.Abs(v))
+	}
+	rms := math.Sqrt(sum / float64(n))
+
+	desired := a.gain
+	if rms >= a.cfg.NoiseGateRMS {
+		desired = math.Max(a.cfg.MinGain, math.Min(a.cfg.MaxGain, a.cfg.TargetRMS/rms))
+	}
+	// Limiter: never let this frame's peak clip, whatever the smoothing says
+	limit := a.cfg.MaxGain
+	if peak > 0 {
+		limit = math.Min(limit, agcClipLevel/peak)
+	}
+	desired = math.Min(desired, limit)
+
+	for i := 0; i < n; i++ {
+		coef := a.release
+		if desired < a.gain {
+			coef = a.attack
+		}
+		a.gain = desired + (a.gain-desired)*coef
+		if a.gain > limit {
+			a.gain = limit
+		}
+		v := float64(int16(uint16(pcm[2*i])|uint16(pcm[2*i+1])<<8)) * a.gain
+		s := int16(math.Max(-32768, math.Min(32767, v)))
+		pcm[2*i] = byte(s)
+		pcm[2*i+1] = byte(uint16(s) >> 8)
+	}
+}
+
+// Gain returns the gain currently applied
+func (a *AGC) Gain() float64 {
+	return a.gain
+}
+
+// Reset returns the gain to unity
+func (a *AGC) Reset() {
+	a.gain = 1
+}
@@ -0,0 +1,65 @@
+package media
+
+import (
+	"math"
+	"testing"
+)
+
+// sineFrame generates 20ms of a 16kHz sine wave
+func sineFrame(amplitude float64, offset int) []byte {
+	frame := make([]byte, 640)
+	for i := 0; i < 320; i++ {
+		v := int16(amplitude * math.Sin(2*math.Pi*440*float64(offset+i)/16000))
+		frame[2*i] = byte(v)
+		frame[2*i+1] = byte(uint16(v) >> 8)
+	}
+	return frame
+}
+
+func frameRMS(frame []byte) float64 {
+	var sum float64
+	for i := 0; i+1 < len(frame); i += 2 {
+		v := float64(int16(uint16(frame[i]) | uint16(frame[i+1])<<8))
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(len(frame)/2))
+}
+
+func TestAGCRaisesQuietInput(t *testing.T) {
+	agc := NewAGC(AGCConfig{})
+	var frame []byte
+	// 2 秒安静语音，增益按 release 时间常数逐渐上升
+	for i := 0; i < 100; i++ {
+		frame = sineFrame(1000, i*320)
+		agc.Process(frame)
+	}
+	if rms := frameRMS(frame); math.Abs(rms-DefaultAGCTargetRMS) > 0.1*DefaultAGCTargetRMS {
+		t.Errorf("expected output near target RMS, got %.0f (gain %.2f)", rms, agc.Gain())
+	}
+}
+
+func TestAGCLimitsLoudInput(t *testing.T) {
+	agc := NewAGC(AGCConfig{MinGain: 0.5})
+	for i := 0; i < 50; i++ {
+		frame := sineFrame(32000, i*320)
+		agc.Process(frame)
+		for j := 0; j+1 < len(frame); j += 2 {
+			if v := int16(uint16(frame[j]) | uint16(frame[j+1])<<8); v == 32767 || v == -32768 {
+				t.Fatalf("frame %d clipped", i)
+			}
+		}
+	}
+	if agc.Gain() > 0.6 {
+		t.Errorf("expected gain reduced towards MinGain, got %.2f", agc.Gain())
+	}
+}
+
+func TestAGCNoiseGateHoldsGain(t *testing.T) {
+	agc := NewAGC(AGCConfig{})
+	for i := 0; i < 100; i++ {
+		agc.Process(sineFrame(50, i*320))
+	}
+	if agc.Gain() != 1 {
+		t.Errorf("background noise should not change the gain, got %.2f", agc.Gain())
+	}
+}
@@ -151,6 +151,31 @@ func Pcm2pcma(pcmData []byte) ([]byte, error) {
 	return alawData, nil
 }
 
+// EncodePCMU encodes 16-bit little-endian PCM data to PCMU (G.711 μ-law) format
+func EncodePCMU(pcmData []byte) ([]byte, error) {
+	return pcm2pcmu(pcmData)
+}
+
+// DecodePCMU decodes PCMU (G.711 μ-law) data to 16-bit little-endian PCM
+func DecodePCMU(ulawData []byte) ([]byte, error) {
+	return pcmu2pcm(ulawData)
+}
+
+// LinearToULaw encodes a single 16-bit PCM sample to μ-law
+func LinearToULaw(sample int16) byte {
+	return linear2ulaw(int(sample))
+}
+
+// ULawToLinear decodes a single μ-law byte to a 16-bit PCM sample
+func ULawToLinear(ulawByte byte) int16 {
+	return int16(ulaw2linear(ulawByte))
+}
+
+// ALawToLinear decodes a single A-law byte to a 16-bit PCM sample
+func ALawToLinear(alawByte byte) int16 {
+	return alaw2linear(alawByte)
+}
+
 // convertULawToPCM converts μ-law encoded data to PCM
 func pcmu2pcm(ulawData []byte) ([]byte, error) {
 	pcmData := make([]byte, len(ulawData)<<1)
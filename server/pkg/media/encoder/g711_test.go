@@ -0,0 +1,51 @@
+package encoder
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestPCMURoundTrip(t *testing.T) {
+	samples := []int16{0, 1, -1, 100, -100, 1000, -1000, 8000, -8000, 32767, -32768}
+	pcm := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(s))
+	}
+
+	ulaw, err := EncodePCMU(pcm)
+	if err != nil || len(ulaw) != len(samples) {
+		t.Fatalf("encode: %v, %d bytes", err, len(ulaw))
+	}
+	decoded, err := DecodePCMU(ulaw)
+	if err != nil || len(decoded) != len(pcm) {
+		t.Fatalf("decode: %v, %d bytes", err, len(decoded))
+	}
+
+	for i, want := range samples {
+		got := int16(binary.LittleEndian.Uint16(decoded[2*i:]))
+		// μ-law 量化步长随幅度增大，约为幅值的 1/16
+		diff, tolerance := int(got)-int(want), int(want)/16
+		if diff < 0 {
+			diff = -diff
+		}
+		if tolerance < 0 {
+			tolerance = -tolerance
+		}
+		if diff > tolerance+16 {
+			t.Errorf("sample %d: got %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestULawReferenceValues(t *testing.T) {
+	// G.711 μ-law：静音为 0xFF，0x00/0x80 为负/正满幅
+	if b := LinearToULaw(0); b != 0xFF {
+		t.Errorf("silence encoded as %#x, want 0xff", b)
+	}
+	if v := ULawToLinear(0x00); v != -32124 {
+		t.Errorf("0x00 decoded as %d, want -32124", v)
+	}
+	if v := ULawToLinear(0x80); v != 32124 {
+		t.Errorf("0x80 decoded as %d, want 32124", v)
+	}
+}
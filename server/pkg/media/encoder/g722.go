@@ -7,60 +7,90 @@ import (
 // G.722 constants
 const (
 	G722_RATE_DEFAULT = 64000
+	G722_RATE_56000   = 56000
+	G722_RATE_48000   = 48000
 	G722_DEFAULT      = 0
+
+	// G722SampleRate G.722 实际音频采样率。SDP 中的 RTP 时钟频率按 RFC 3551 的历史约定写作 8000，不能用来推算采样率
+	G722SampleRate = 16000
+)
+
+// G.722 (ITU-T, SB-ADPCM) 查找表
+var (
+	g722QMFCoeffs = [12]int{3, -11, 12, 32, -210, 951, 3876, -805, 362, -156, 53, -11}
+
+	g722Q6   = [32]int{0, 35, 72, 110, 150, 190, 233, 276, 323, 370, 422, 473, 530, 587, 650, 714, 786, 858, 940, 1023, 1121, 1219, 1339, 1458, 1612, 1765, 1980, 2195, 2557, 2919, 0, 0}
+	g722ILN  = [32]int{0, 63, 62, 31, 30, 29, 28, 27, 26, 25, 24, 23, 22, 21, 20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 0}
+	g722ILP  = [32]int{0, 61, 60, 59, 58, 57, 56, 55, 54, 53, 52, 51, 50, 49, 48, 47, 46, 45, 44, 43, 42, 41, 40, 39, 38, 37, 36, 35, 34, 33, 32, 0}
+	g722WL   = [8]int{-60, -30, 58, 172, 334, 538, 1198, 3042}
+	g722RL42 = [16]int{0, 7, 6, 5, 4, 3, 2, 1, 7, 6, 5, 4, 3, 2, 1, 0}
+	g722ILB  = [32]int{2048, 2093, 2139, 2186, 2233, 2282, 2332, 2383, 2435, 2489, 2543, 2599, 2656, 2714, 2774, 2834, 2896, 2960, 3025, 3091, 3158, 3228, 3298, 3371, 3444, 3520, 3597, 3676, 3756, 3838, 3922, 4008}
+	g722QM4  = [16]int{0, -20456, -12896, -8968, -6288, -4240, -2584, -1200, 20456, 12896, 8968, 6288, 4240, 2584, 1200, 0}
+	g722QM5  = [32]int{-280, -280, -23352, -17560, -14120, -11664, -9752, -8184, -6864, -5712, -4696, -3784, -2960, -2208, -1520, -880, 23352, 17560, 14120, 11664, 9752, 8184, 6864, 5712, 4696, 3784, 2960, 2208, 1520, 880, 280, -280}
+	g722QM6  = [64]int{
+		-136, -136, -136, -136, -24808, -21904, -19008, -16704, -14984, -13512, -12280, -11192, -10232, -9360, -8576, -7856,
+		-7192, -6576, -6000, -5456, -4944, -4464, -4008, -3576, -3168, -2776, -2400, -2032, -1688, -1360, -1040, -728,
+		24808, 21904, 19008, 16704, 14984, 13512, 12280, 11192, 10232, 9360, 8576, 7856, 7192, 6576, 6000, 5456,
+		4944, 4464, 4008, 3576, 3168, 2776, 2400, 2032, 1688, 1360, 1040, 728, 432, 136, -432, -136,
+	}
+	g722QM2 = [4]int{-7408, -1616, 7408, 1616}
+	g722IHN = [3]int{0, 1, 0}
+	g722IHP = [3]int{0, 3, 2}
+	g722WH  = [3]int{0, -214, 798}
+	g722RH2 = [4]int{2, 1, 2, 1}
 )
 
-// G.722 band structures
-type G722Band0 struct {
-	a  [2]int16
-	b  [6]int16
-	d  [7]int16
-	s  [7]int16
-	sz int16
-	sp int16
+// g722Band 子带 ADPCM 的自适应预测器与量化器状态
+type g722Band struct {
+	s   int
+	sp  int
+	sz  int
+	r   [3]int
+	a   [3]int
+	ap  [3]int
+	p   [3]int
+	d   [7]int
+	b   [7]int
+	bp  [7]int
+	sg  [7]int
+	nb  int
+	det int
 }
 
-type G722Band1 struct {
-	a  [2]int16
-	b  [6]int16
-	d  [7]int16
-	s  [7]int16
-	sz int16
-	sp int16
+// g722State 编码器和解码器共用的状态：低/高子带 + QMF 延迟线
+type g722State struct {
+	bitsPerSample int
+	x             [24]int
+	band          [2]g722Band
 }
 
-type G722Band2 struct {
-	a  [2]int16
-	b  [6]int16
-	d  [7]int16
-	s  [7]int16
-	sz int16
-	sp int16
+func newG722State(rate int) g722State {
+	st := g722State{bitsPerSample: 8}
+	switch rate {
+	case G722_RATE_48000:
+		st.bitsPerSample = 6
+	case G722_RATE_56000:
+		st.bitsPerSample = 7
+	}
+	st.band[0].det = 32
+	st.band[1].det = 8
+	return st
 }
 
-// G722Encoder G.722编码器
+// G722Encoder G.722编码器，输入 16kHz 16-bit PCM，64kbit/s 时每 2 个样本输出 1 字节
 type G722Encoder struct {
-	band0 *G722Band0
-	band1 *G722Band1
-	band2 *G722Band2
-	rate  int
+	state   g722State
+	pending []byte // 不足一对的样本留到下一次编码
 }
 
-// G722Decoder G.722解码器
+// G722Decoder G.722解码器，每字节输出 2 个 16kHz 16-bit 样本
 type G722Decoder struct {
-	band0 *G722Band0
-	band1 *G722Band1
-	band2 *G722Band2
-	rate  int
+	state g722State
 }
 
 func createG722Decode(src, pcm media.CodecConfig) media.EncoderFunc {
-	// 使用配置的采样率，如果未设置则使用 G.722 标准采样率 16000Hz
-	sourceSampleRate := src.SampleRate
-	if sourceSampleRate == 0 {
-		sourceSampleRate = 16000 // G.722 标准采样率
-	}
-	res := media.DefaultResampler(sourceSampleRate, pcm.SampleRate)
+	// src.SampleRate 可能是 SDP 中的 RTP 时钟 8000，G.722 解码输出固定为 16000Hz
+	res := media.DefaultResampler(G722SampleRate, pcm.SampleRate)
 	dec := NewG722Decoder(G722_RATE_DEFAULT, G722_DEFAULT)
 
 	return func(packet media.MediaPacket) ([]media.MediaPacket, error) {
@@ -83,12 +113,8 @@ func createG722Decode(src, pcm media.CodecConfig) media.EncoderFunc {
 }
 
 func createG722Encode(src, pcm media.CodecConfig) media.EncoderFunc {
-	// 使用配置的目标采样率，如果未设置则使用 G.722 标准采样率 16000Hz
-	targetSampleRate := src.SampleRate
-	if targetSampleRate == 0 {
-		targetSampleRate = 16000 // G.722 标准采样率
-	}
-	res := media.DefaultResampler(pcm.SampleRate, targetSampleRate)
+	// G.722 编码输入固定为 16000Hz
+	res := media.DefaultResampler(pcm.SampleRate, G722SampleRate)
 	enc := NewG722Encoder(G722_RATE_DEFAULT, G722_DEFAULT)
 	return func(packet media.MediaPacket) ([]media.MediaPacket, error) {
 		audioPacket, ok := packet.(*media.AudioPacket)
@@ -108,156 +134,282 @@ func createG722Encode(src, pcm media.CodecConfig) media.EncoderFunc {
 	}
 }
 
-// NewG722Encoder 创建新的G.722编码器
+// NewG722Encoder 创建新的G.722编码器，rate 为 64000/56000/48000，mode 保留
 func NewG722Encoder(rate, mode int) *G722Encoder {
-	encoder := &G722Encoder{
-		rate:  rate,
-		band0: &G722Band0{},
-		band1: &G722Band1{},
-		band2: &G722Band2{},
+	return &G722Encoder{state: newG722State(rate)}
+}
+
+// Encode 编码 16-bit little-endian PCM
+func (e *G722Encoder) Encode(pcmData []byte) []byte {
+	if len(e.pending) > 0 {
+		pcmData = append(e.pending, pcmData...)
+		e.pending = nil
+	}
+	pairs := len(pcmData) / 4
+	if rest := pcmData[pairs*4:]; len(rest) > 0 {
+		e.pending = append([]byte(nil), rest...)
+	}
+	if pairs == 0 {
+		return nil
+	}
+
+	st := &e.state
+	output := make([]byte, pairs)
+	for i := 0; i < pairs; i++ {
+		// 发送端 QMF：两个输入样本分解为一个低子带和一个高子带样本
+		copy(st.x[:22], st.x[2:])
+		st.x[22] = int(int16(uint16(pcmData[i*4]) | uint16(pcmData[i*4+1])<<8))
+		st.x[23] = int(int16(uint16(pcmData[i*4+2]) | uint16(pcmData[i*4+3])<<8))
+		sumOdd, sumEven := 0, 0
+		for j := 0; j < 12; j++ {
+			sumOdd += st.x[2*j] * g722QMFCoeffs[j]
+			sumEven += st.x[2*j+1] * g722QMFCoeffs[11-j]
+		}
+		xlow := (sumEven + sumOdd) >> 14
+		xhigh := (sumEven - sumOdd) >> 14
+
+		ilow := st.encodeLow(xlow)
+		ihigh := st.encodeHigh(xhigh)
+		output[i] = byte(((ihigh << 6) | ilow) >> (8 - st.bitsPerSample))
 	}
-	encoder.init()
-	return encoder
+	return output
 }
 
-func (e *G722Encoder) init() {
-	for i := range e.band0.a {
-		e.band0.a[i] = 0
-		e.band0.b[i] = 0
-		e.band0.d[i] = 0
-		e.band0.s[i] = 0
+// encodeLow 低子带 6-bit ADPCM 量化（框图 1L-3L）
+func (st *g722State) encodeLow(xlow int) int {
+	band := &st.band[0]
+	el := g722Saturate(xlow - band.s)
+	wd := el
+	if el < 0 {
+		wd = -(el + 1)
 	}
-	for i := range e.band1.a {
-		e.band1.a[i] = 0
-		e.band1.b[i] = 0
-		e.band1.d[i] = 0
-		e.band1.s[i] = 0
+	i := 1
+	for ; i < 30; i++ {
+		if wd < (g722Q6[i]*band.det)>>12 {
+			break
+		}
 	}
-	for i := range e.band2.a {
-		e.band2.a[i] = 0
-		e.band2.b[i] = 0
-		e.band2.d[i] = 0
-		e.band2.s[i] = 0
+	ilow := g722ILP[i]
+	if el < 0 {
+		ilow = g722ILN[i]
 	}
+
+	ril := ilow >> 2
+	dlow := (band.det * g722QM4[ril]) >> 15
+	band.nb = g722ScaleLow(band.nb, ril)
+	band.det = g722Det(band.nb, 8)
+	band.update(dlow)
+	return ilow
 }
 
-func (e *G722Encoder) Encode(pcmData []byte) []byte {
-	if len(pcmData) == 0 {
-		return nil
+// encodeHigh 高子带 2-bit ADPCM 量化
+func (st *g722State) encodeHigh(xhigh int) int {
+	band := &st.band[1]
+	eh := g722Saturate(xhigh - band.s)
+	wd := eh
+	if eh < 0 {
+		wd = -(eh + 1)
 	}
-	if len(pcmData)%2 != 0 {
-		pcmData = pcmData[:len(pcmData)-1]
+	mih := 1
+	if wd >= (564*band.det)>>12 {
+		mih = 2
 	}
-	samples := len(pcmData) / 2
-	output := make([]byte, samples/2)
-	for i := 0; i < samples; i += 2 {
-		sample1 := int16(pcmData[i*2]) | int16(pcmData[i*2+1])<<8
-		sample2 := int16(pcmData[(i+1)*2]) | int16(pcmData[(i+1)*2+1])<<8
-		encoded := e.encodeSamples(sample1, sample2)
-		output[i/2] = encoded
+	ihigh := g722IHP[mih]
+	if eh < 0 {
+		ihigh = g722IHN[mih]
 	}
-	return output
+
+	dhigh := (band.det * g722QM2[ihigh]) >> 15
+	band.nb = g722ScaleHigh(band.nb, ihigh)
+	band.det = g722Det(band.nb, 10)
+	band.update(dhigh)
+	return ihigh
 }
 
-func (e *G722Encoder) encodeSamples(s1, s2 int16) byte {
-	q1 := e.quantize(s1)
-	q2 := e.quantize(s2)
-	return byte((q1 & 0x0F) | ((q2 & 0x0F) << 4))
+// NewG722Decoder 创建新的G.722解码器，rate 为 64000/56000/48000，mode 保留
+func NewG722Decoder(rate, mode int) *G722Decoder {
+	return &G722Decoder{state: newG722State(rate)}
 }
 
-func (e *G722Encoder) quantize(sample int16) int {
-	abs := int(sample)
-	if abs < 0 {
-		abs = -abs
+// Decode 解码为 16-bit little-endian PCM
+func (d *G722Decoder) Decode(g722Data []byte) []byte {
+	if len(g722Data) == 0 {
+		return nil
 	}
-	if abs < 16 {
-		return 0
-	} else if abs < 32 {
-		return 1
-	} else if abs < 64 {
-		return 2
-	} else if abs < 128 {
-		return 3
-	} else if abs < 256 {
-		return 4
-	} else if abs < 512 {
-		return 5
-	} else if abs < 1024 {
-		return 6
-	} else if abs < 2048 {
-		return 7
-	} else if abs < 4096 {
-		return 8
-	} else if abs < 8192 {
-		return 9
-	} else if abs < 16384 {
-		return 10
-	} else {
-		return 11
+	st := &d.state
+	output := make([]byte, len(g722Data)*4)
+	for i, code := range g722Data {
+		var wd1, ihigh, wd2 int
+		switch st.bitsPerSample {
+		case 6:
+			wd1 = int(code) & 0x0F
+			ihigh = (int(code) >> 4) & 0x03
+			wd2 = g722QM4[wd1]
+		case 7:
+			wd1 = int(code) & 0x1F
+			ihigh = (int(code) >> 5) & 0x03
+			wd2 = g722QM5[wd1]
+			wd1 >>= 1
+		default:
+			wd1 = int(code) & 0x3F
+			ihigh = (int(code) >> 6) & 0x03
+			wd2 = g722QM6[wd1]
+			wd1 >>= 2
+		}
+
+		// 低子带重建
+		low := &st.band[0]
+		rlow := g722Clamp(low.s+((low.det*wd2)>>15), -16384, 16383)
+		dlow := (low.det * g722QM4[wd1]) >> 15
+		low.nb = g722ScaleLow(low.nb, wd1)
+		low.det = g722Det(low.nb, 8)
+		low.update(dlow)
+
+		// 高子带重建
+		high := &st.band[1]
+		dhigh := (high.det * g722QM2[ihigh]) >> 15
+		rhigh := g722Clamp(dhigh+high.s, -16384, 16383)
+		high.nb = g722ScaleHigh(high.nb, ihigh)
+		high.det = g722Det(high.nb, 10)
+		high.update(dhigh)
+
+		// 接收端 QMF：两个子带样本合成为两个输出样本
+		copy(st.x[:22], st.x[2:])
+		st.x[22] = rlow + rhigh
+		st.x[23] = rlow - rhigh
+		xout1, xout2 := 0, 0
+		for j := 0; j < 12; j++ {
+			xout2 += st.x[2*j] * g722QMFCoeffs[j]
+			xout1 += st.x[2*j+1] * g722QMFCoeffs[11-j]
+		}
+		s1 := g722Saturate(xout1 >> 11)
+		s2 := g722Saturate(xout2 >> 11)
+		output[i*4] = byte(s1)
+		output[i*4+1] = byte(s1 >> 8)
+		output[i*4+2] = byte(s2)
+		output[i*4+3] = byte(s2 >> 8)
 	}
+	return output
 }
 
-// NewG722Decoder 创建新的G.722解码器
-func NewG722Decoder(rate, mode int) *G722Decoder {
-	decoder := &G722Decoder{
-		rate:  rate,
-		band0: &G722Band0{},
-		band1: &G722Band1{},
-		band2: &G722Band2{},
+// g722ScaleLow 低子带对数量化步长自适应（LOGSCL）
+func g722ScaleLow(nb, ril int) int {
+	nb = (nb*127)>>7 + g722WL[g722RL42[ril]]
+	return g722Clamp(nb, 0, 18432)
+}
+
+// g722ScaleHigh 高子带对数量化步长自适应
+func g722ScaleHigh(nb, ihigh int) int {
+	nb = (nb*127)>>7 + g722WH[g722RH2[ihigh]]
+	return g722Clamp(nb, 0, 22528)
+}
+
+// g722Det 由对数步长计算线性步长（SCALEL/SCALEH）
+func g722Det(nb, shift int) int {
+	wd1 := (nb >> 6) & 31
+	wd2 := shift - (nb >> 11)
+	var wd3 int
+	if wd2 < 0 {
+		wd3 = g722ILB[wd1] << -wd2
+	} else {
+		wd3 = g722ILB[wd1] >> wd2
 	}
-	decoder.init()
-	return decoder
+	return wd3 << 2
 }
 
-func (d *G722Decoder) init() {
-	for i := range d.band0.a {
-		d.band0.a[i] = 0
-		d.band0.b[i] = 0
-		d.band0.d[i] = 0
-		d.band0.s[i] = 0
+// update 更新零极点预测器并计算下一个预测值（框图 4）
+func (b *g722Band) update(d int) {
+	// RECONS / PARREC
+	b.d[0] = d
+	b.r[0] = g722Saturate(b.s + d)
+	b.p[0] = g722Saturate(b.sz + d)
+
+	// UPPOL2
+	for i := 0; i < 3; i++ {
+		b.sg[i] = b.p[i] >> 15
 	}
-	for i := range d.band1.a {
-		d.band1.a[i] = 0
-		d.band1.b[i] = 0
-		d.band1.d[i] = 0
-		d.band1.s[i] = 0
+	wd1 := g722Saturate(b.a[1] << 2)
+	wd2 := wd1
+	if b.sg[0] == b.sg[1] {
+		wd2 = -wd1
 	}
-	for i := range d.band2.a {
-		d.band2.a[i] = 0
-		d.band2.b[i] = 0
-		d.band2.d[i] = 0
-		d.band2.s[i] = 0
+	if wd2 > 32767 {
+		wd2 = 32767
 	}
-}
+	wd3 := wd2 >> 7
+	if b.sg[0] == b.sg[2] {
+		wd3 += 128
+	} else {
+		wd3 -= 128
+	}
+	wd3 += (b.a[2] * 32512) >> 15
+	b.ap[2] = g722Clamp(wd3, -12288, 12288)
 
-func (d *G722Decoder) Decode(g722Data []byte) []byte {
-	if len(g722Data) == 0 {
-		return nil
+	// UPPOL1
+	b.sg[0] = b.p[0] >> 15
+	b.sg[1] = b.p[1] >> 15
+	wd1 = -192
+	if b.sg[0] == b.sg[1] {
+		wd1 = 192
 	}
-	output := make([]byte, len(g722Data)*4)
-	for i, encoded := range g722Data {
-		sample1, sample2 := d.decodeSamples(encoded)
-		output[i*4] = byte(sample1 & 0xFF)
-		output[i*4+1] = byte((sample1 >> 8) & 0xFF)
-		output[i*4+2] = byte(sample2 & 0xFF)
-		output[i*4+3] = byte((sample2 >> 8) & 0xFF)
+	wd2 = (b.a[1] * 32640) >> 15
+	b.ap[1] = g722Saturate(wd1 + wd2)
+	wd3 = g722Saturate(15360 - b.ap[2])
+	b.ap[1] = g722Clamp(b.ap[1], -wd3, wd3)
+
+	// UPZERO
+	wd1 = 0
+	if d != 0 {
+		wd1 = 128
 	}
-	return output
+	b.sg[0] = d >> 15
+	for i := 1; i < 7; i++ {
+		b.sg[i] = b.d[i] >> 15
+		wd2 = -wd1
+		if b.sg[i] == b.sg[0] {
+			wd2 = wd1
+		}
+		wd3 = (b.b[i] * 32640) >> 15
+		b.bp[i] = g722Saturate(wd2 + wd3)
+	}
+
+	// DELAYA
+	for i := 6; i > 0; i-- {
+		b.d[i] = b.d[i-1]
+		b.b[i] = b.bp[i]
+	}
+	for i := 2; i > 0; i-- {
+		b.r[i] = b.r[i-1]
+		b.p[i] = b.p[i-1]
+		b.a[i] = b.ap[i]
+	}
+
+	// FILTEP
+	wd1 = (b.a[1] * g722Saturate(b.r[1]+b.r[1])) >> 15
+	wd2 = (b.a[2] * g722Saturate(b.r[2]+b.r[2])) >> 15
+	b.sp = g722Saturate(wd1 + wd2)
+
+	// FILTEZ
+	b.sz = 0
+	for i := 6; i > 0; i-- {
+		b.sz += (b.b[i] * g722Saturate(b.d[i]+b.d[i])) >> 15
+	}
+	b.sz = g722Saturate(b.sz)
+
+	// PREDIC
+	b.s = g722Saturate(b.sp + b.sz)
 }
 
-func (d *G722Decoder) decodeSamples(encoded byte) (int16, int16) {
-	q1 := int(encoded & 0x0F)
-	q2 := int((encoded >> 4) & 0x0F)
-	sample1 := d.dequantize(q1)
-	sample2 := d.dequantize(q2)
-	return sample1, sample2
+func g722Saturate(v int) int {
+	return g722Clamp(v, -32768, 32767)
 }
 
-func (d *G722Decoder) dequantize(q int) int16 {
-	levels := []int{8, 24, 40, 56, 80, 112, 160, 224, 320, 448, 640, 896, 1280, 1792, 2560, 3584}
-	if q >= len(levels) {
-		q = len(levels) - 1
+func g722Clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
 	}
-	noise := int16((q % 3) - 1)
-	return int16(levels[q]) + noise
+	return v
 }
@@ -0,0 +1,71 @@
+package encoder
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// g722SNR 返回原始信号与解码信号的信噪比（dB），自动对齐 QMF 引入的延迟
+func g722SNR(in, out []int16) float64 {
+	best := math.Inf(-1)
+	for lag := 0; lag < 64; lag++ {
+		var signal, noise float64
+		for i := 200; i+lag < len(out) && i < len(in); i++ {
+			diff := float64(out[i+lag]) - float64(in[i])
+			signal += float64(in[i]) * float64(in[i])
+			noise += diff * diff
+		}
+		if snr := 10 * math.Log10(signal/math.Max(noise, 1)); snr > best {
+			best = snr
+		}
+	}
+	return best
+}
+
+func TestG722RoundTrip(t *testing.T) {
+	// 1 秒 16kHz 音频：1kHz（低子带）叠加 5kHz（高子带，8kHz 编解码器无法传输）
+	in := make([]int16, G722SampleRate)
+	pcm := make([]byte, len(in)*2)
+	for i := range in {
+		ts := float64(i) / G722SampleRate
+		in[i] = int16(6000*math.Sin(2*math.Pi*1000*ts) + 2000*math.Sin(2*math.Pi*5000*ts))
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(in[i]))
+	}
+
+	for _, rate := range []int{G722_RATE_DEFAULT, G722_RATE_56000, G722_RATE_48000} {
+		enc := NewG722Encoder(rate, G722_DEFAULT)
+		dec := NewG722Decoder(rate, G722_DEFAULT)
+
+		// 按 20ms 分帧，验证跨包状态连续
+		var decoded []byte
+		for off := 0; off < len(pcm); off += 640 {
+			payload := enc.Encode(pcm[off : off+640])
+			if len(payload) != 160 {
+				t.Fatalf("rate %d: 20ms frame encoded to %d bytes, want 160", rate, len(payload))
+			}
+			decoded = append(decoded, dec.Decode(payload)...)
+		}
+		if len(decoded) != len(pcm) {
+			t.Fatalf("rate %d: decoded %d bytes, want %d", rate, len(decoded), len(pcm))
+		}
+		out := make([]int16, len(in))
+		for i := range out {
+			out[i] = int16(binary.LittleEndian.Uint16(decoded[2*i:]))
+		}
+		if snr := g722SNR(in, out); snr < 25 {
+			t.Errorf("rate %d: SNR %.1f dB too low", rate, snr)
+		}
+	}
+}
+
+func TestG722EncodeOddLength(t *testing.T) {
+	enc := NewG722Encoder(G722_RATE_DEFAULT, G722_DEFAULT)
+	// 3 个样本：一对立即编码，剩余一个留到下次
+	if out := enc.Encode(make([]byte, 6)); len(out) != 1 {
+		t.Fatalf("expected 1 byte, got %d", len(out))
+	}
+	if out := enc.Encode(make([]byte, 2)); len(out) != 1 {
+		t.Fatalf("expected pending sample to complete a pair, got %d bytes", len(out))
+	}
+}
@@ -7,13 +7,20 @@ import (
 	"github.com/hraban/opus"
 )
 
+const (
+	// OpusSampleRate OPUS 在 WebRTC 中的标准采样率（RTP 时钟频率固定为 48000）
+	OpusSampleRate = 48000
+	// opusMaxFrameMs OPUS 单个包的最大时长
+	opusMaxFrameMs = 120
+)
+
 // createOPUSDecode 创建 OPUS 解码器
 // OPUS 标准采样率为 48000Hz，但也支持 8000, 12000, 16000, 24000, 48000
 func createOPUSDecode(src, pcm media.CodecConfig) media.EncoderFunc {
 	// 使用配置的采样率，如果未设置则使用 OPUS 标准采样率 48000Hz
 	sourceSampleRate := src.SampleRate
 	if sourceSampleRate == 0 {
-		sourceSampleRate = 48000 // OPUS 标准采样率
+		sourceSampleRate = OpusSampleRate
 	}
 
 	// 确定声道数
@@ -42,8 +49,11 @@ func createOPUSDecode(src, pcm media.CodecConfig) media.EncoderFunc {
 		}
 	}
 
-	// 计算每帧的样本数
+	// 计算每帧的样本数，对端可能发送比协商更长的帧，缓冲区按 OPUS 最大帧长 120ms 分配
 	frameSize := sourceSampleRate * frameDurationMs / 1000
+	if maxFrameSize := sourceSampleRate * opusMaxFrameMs / 1000; frameSize < maxFrameSize {
+		frameSize = maxFrameSize
+	}
 
 	return func(packet media.MediaPacket) ([]media.MediaPacket, error) {
 		audioPacket, ok := packet.(*media.AudioPacket)
@@ -86,7 +96,7 @@ func createOPUSEncode(src, pcm media.CodecConfig) media.EncoderFunc {
 	// 使用配置的目标采样率，如果未设置则使用 OPUS 标准采样率 48000Hz
 	targetSampleRate := src.SampleRate
 	if targetSampleRate == 0 {
-		targetSampleRate = 48000 // OPUS 标准采样率
+		targetSampleRate = OpusSampleRate
 	}
 
 	// 验证采样率是否为 OPUS 支持的值
@@ -161,6 +171,11 @@ func createOPUSEncode(src, pcm media.CodecConfig) media.EncoderFunc {
 	// 计算每帧的样本数
 	frameSize := targetSampleRate * frameDurationMs / 1000
 
+	// 未凑满一帧的样本留到下次调用，避免丢弃或插入静音造成断续
+	var pending []int16
+	samplesPerFrame := frameSize * channels
+	opusBuffer := make([]byte, 4000) // 足够大的缓冲区
+
 	return func(packet media.MediaPacket) ([]media.MediaPacket, error) {
 		audioPacket, ok := packet.(*media.AudioPacket)
 		if !ok {
@@ -178,31 +193,25 @@ func createOPUSEncode(src, pcm media.CodecConfig) media.EncoderFunc {
 		}
 
 		// 转换 []byte 为 []int16
-		pcmSamples := make([]int16, len(data)/2)
-		for i := 0; i < len(pcmSamples); i++ {
-			pcmSamples[i] = int16(data[i*2]) | int16(data[i*2+1])<<8
+		for i := 0; i+1 < len(data); i += 2 {
+			pending = append(pending, int16(data[i])|int16(data[i+1])<<8)
 		}
 
-		// 计算每帧需要的样本数
-		samplesPerFrame := frameSize * channels
-		totalSamples := len(pcmSamples)
-
-		// 如果数据不足一帧，填充静音
-		if totalSamples < samplesPerFrame {
-			paddedSamples := make([]int16, samplesPerFrame)
-			copy(paddedSamples, pcmSamples)
-			pcmSamples = paddedSamples
-			totalSamples = samplesPerFrame
+		// 按帧编码，每帧输出一个 OPUS 包
+		var packets []media.MediaPacket
+		for len(pending) >= samplesPerFrame {
+			n, err := encoder.Encode(pending[:samplesPerFrame], opusBuffer)
+			if err != nil {
+				return nil, fmt.Errorf("opus encode error: %w", err)
+			}
+			payload := make([]byte, n)
+			copy(payload, opusBuffer[:n])
+			packets = append(packets, &media.AudioPacket{Payload: payload})
+			pending = pending[samplesPerFrame:]
 		}
+		// 复制剩余样本，避免底层数组无限增长
+		pending = append([]int16(nil), pending...)
 
-		// 只编码第一帧（调用者负责分帧）
-		opusBuffer := make([]byte, 4000) // 足够大的缓冲区
-		n, err := encoder.Encode(pcmSamples[:samplesPerFrame], opusBuffer)
-		if err != nil {
-			return nil, fmt.Errorf("opus encode error: %w", err)
-		}
-
-		audioPacket.Payload = opusBuffer[:n]
-		return []media.MediaPacket{audioPacket}, nil
+		return packets, nil
 	}
 }
@@ -0,0 +1,73 @@
+package encoder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+)
+
+func TestPipelineDecodeResampleEncode(t *testing.T) {
+	pcma := media.CodecConfig{Codec: CodecPCMA, SampleRate: 8000, FrameDuration: "20ms"}
+	g722 := media.CodecConfig{Codec: CodecG722, SampleRate: G722SampleRate, FrameDuration: "20ms"}
+	pipeline, err := media.NewPipeline().Decode(pcma).Resample(16000).Mono().Encode(g722).Build()
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	// 20ms PCMA 帧（160 字节）→ 16kHz 320 样本 → G.722 160 字节
+	frame := make([]byte, 160)
+	for i := range frame {
+		frame[i] = byte(i) // 任意非静音 A-law 数据
+	}
+	packets, err := pipeline(&media.AudioPacket{Payload: frame})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	total := 0
+	for _, p := range packets {
+		total += len(p.(*media.AudioPacket).Payload)
+	}
+	if total != 160 {
+		t.Errorf("expected 160 bytes of G.722, got %d", total)
+	}
+}
+
+func TestPipelineMono(t *testing.T) {
+	pipeline, err := media.NewPipeline().Input(16000, 2).Mono().Build()
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	// 左声道 1000，右声道 -200 → 平均 400
+	stereo := []byte{0xE8, 0x03, 0x38, 0xFF, 0xE8, 0x03, 0x38, 0xFF}
+	packets, err := pipeline(&media.AudioPacket{Payload: stereo})
+	if err != nil || len(packets) != 1 {
+		t.Fatalf("run: %v, %d packets", err, len(packets))
+	}
+	mono := packets[0].(*media.AudioPacket).Payload
+	if len(mono) != 4 || int16(uint16(mono[0])|uint16(mono[1])<<8) != 400 {
+		t.Errorf("unexpected mono output %v", mono)
+	}
+}
+
+func TestPipelineValidation(t *testing.T) {
+	pcma := media.CodecConfig{Codec: CodecPCMA, SampleRate: 8000}
+	cases := map[string]*media.AudioPipeline{
+		"empty":              media.NewPipeline(),
+		"resample first":     media.NewPipeline().Resample(16000),
+		"decode twice":       media.NewPipeline().Decode(pcma).Decode(pcma),
+		"stage after encode": media.NewPipeline().Input(8000, 1).Encode(pcma).Mono(),
+		"stereo encode":      media.NewPipeline().Input(8000, 2).Encode(pcma),
+		"stereo resample":    media.NewPipeline().Input(48000, 2).Resample(16000),
+	}
+	for name, p := range cases {
+		if _, err := p.Build(); !errors.Is(err, media.ErrInvalidPipeline) {
+			t.Errorf("%s: expected ErrInvalidPipeline, got %v", name, err)
+		}
+	}
+
+	_, err := media.NewPipeline().Decode(media.CodecConfig{Codec: "amr", SampleRate: 8000}).Build()
+	if !errors.Is(err, media.ErrCodecNotSupported) {
+		t.Errorf("unknown codec: expected ErrCodecNotSupported, got %v", err)
+	}
+}
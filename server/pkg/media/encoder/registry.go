@@ -21,6 +21,18 @@ func init() {
 	RegisterCodec(CodecPCM, PcmToPcm, PcmToPcm)
 	RegisterCodec(CodecOPUS, createOPUSEncode, createOPUSDecode)
 	RegisterCodec(CodecG722, createG722Encode, createG722Decode)
+	media.SetCodecBuilders(
+		func(codec, pcm media.CodecConfig) (media.EncoderFunc, error) { return transcoderFunc(pcm, codec) },
+		func(codec, pcm media.CodecConfig) (media.EncoderFunc, error) { return transcoderFunc(codec, pcm) },
+	)
+}
+
+func transcoderFunc(from, to media.CodecConfig) (media.EncoderFunc, error) {
+	t, err := NewTranscoder(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return t.Transcode, nil
 }
 
 // CodecFactory defines function type for creating codec encoders/decoders
@@ -42,7 +54,10 @@ func RegisterCodec(name string, encoderFactory, decoderFactory CodecFactory) {
 	}
 }
 
-// BuildEncoder creates an encoder function for the specified codec
+// CreateEncode creates an encoder for src.Codec from pcm
+//
+// Deprecated: src is the encoder's output, which callers regularly get
+// backwards; use NewTranscoder(pcm, codec) instead.
 func CreateEncode(src, pcm media.CodecConfig) (encode media.EncoderFunc, err error) {
 	registry, exists := codecRegistryMap[strings.ToLower(src.Codec)]
 	if !exists {
@@ -53,7 +68,9 @@ func CreateEncode(src, pcm media.CodecConfig) (encode media.EncoderFunc, err err
 	return
 }
 
-// BuildDecoder creates a decoder function for the specified codec
+// CreateDecode creates a decoder from src.Codec to pcm
+//
+// Deprecated: use NewTranscoder(codec, pcm), which validates both sides.
 func CreateDecode(src, pcm media.CodecConfig) (decode media.EncoderFunc, err error) {
 	registry, exists := codecRegistryMap[strings.ToLower(src.Codec)]
 	if !exists {
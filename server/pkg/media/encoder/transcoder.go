@@ -0,0 +1,151 @@
+package encoder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+)
+
+// ErrInvalidCodecConfig is wrapped by NewTranscoder validation errors
+var ErrInvalidCodecConfig = errors.New("invalid codec config")
+
+// codecSpec describes what a codec accepts on the wire
+type codecSpec struct {
+	sampleRates []int // nil means any positive rate
+	bitDepth    int   // bits per sample on the wire
+	maxChannels int
+}
+
+var codecSpecs = map[string]codecSpec{
+	CodecPCM:  {bitDepth: 16, maxChannels: 2},
+	CodecPCMA: {sampleRates: []int{8000}, bitDepth: 8, maxChannels: 1},
+	CodecPCMU: {sampleRates: []int{8000}, bitDepth: 8, maxChannels: 1},
+	CodecG722: {sampleRates: []int{G722SampleRate}, bitDepth: 4, maxChannels: 1},
+	CodecOPUS: {sampleRates: []int{8000, 12000, 16000, 24000, OpusSampleRate}, bitDepth: 16, maxChannels: 2},
+}
+
+// Transcoder converts audio packets from one CodecConfig to another. Unlike
+// CreateEncode/CreateDecode the direction is explicit: packets go in as
+// From and come out as To.
+type Transcoder struct {
+	from, to media.CodecConfig
+	process  media.EncoderFunc
+}
+
+// NewTranscoder creates a transcoder from one format to another. Either
+// side may be PCM or a codec: PCM to codec encodes, codec to PCM decodes,
+// codec to codec decodes and re-encodes, and PCM to PCM resamples. Zero
+// Channels and BitDepth take the codec defaults; a sample rate is required.
+func NewTranscoder(from, to media.CodecConfig) (*Transcoder, error) {
+	from, err := normalizeCodecConfig(from)
+	if err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	to, err = normalizeCodecConfig(to)
+	if err != nil {
+		return nil, fmt.Errorf("to: %w", err)
+	}
+	if from.Channels != to.Channels {
+		return nil, fmt.Errorf("%w: %d to %d channels, downmix the PCM first",
+			ErrInvalidCodecConfig, from.Channels, to.Channels)
+	}
+
+	t := &Transcoder{from: from, to: to}
+	switch {
+	case from.Codec == CodecPCM && to.Codec == CodecPCM:
+		t.process = PcmToPcm(from, to)
+	case from.Codec == CodecPCM:
+		t.process = codecRegistryMap[to.Codec].encoderFactory(to, from)
+	case to.Codec == CodecPCM:
+		t.process = codecRegistryMap[from.Codec].decoderFactory(from, to)
+	default:
+		// Decode at the source rate, the encoder resamples to its own
+		pcm := media.CodecConfig{
+			Codec:         CodecPCM,
+			SampleRate:    from.SampleRate,
+			Channels:      from.Channels,
+			BitDepth:      16,
+			FrameDuration: from.FrameDuration,
+		}
+		decode := codecRegistryMap[from.Codec].decoderFactory(from, pcm)
+		encode := codecRegistryMap[to.Codec].encoderFactory(to, pcm)
+		t.process = func(packet media.MediaPacket) ([]media.MediaPacket, error) {
+			decoded, err := decode(packet)
+			if err != nil {
+				return nil, err
+			}
+			var out []media.MediaPacket
+			for _, p := range decoded {
+				encoded, err := encode(p)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, encoded...)
+			}
+			return out, nil
+		}
+	}
+	return t, nil
+}
+
+// normalizeCodecConfig fills defaults and checks cfg against its codec
+func normalizeCodecConfig(cfg media.CodecConfig) (media.CodecConfig, error) {
+	cfg.Codec = strings.ToLower(cfg.Codec)
+	if !HasCodec(cfg.Codec) {
+		return cfg, fmt.Errorf("%w: %q", media.ErrCodecNotSupported, cfg.Codec)
+	}
+	if cfg.Channels == 0 {
+		cfg.Channels = 1
+	}
+	if cfg.SampleRate <= 0 {
+		return cfg, fmt.Errorf("%w: %s sample rate is required", ErrInvalidCodecConfig, cfg.Codec)
+	}
+	spec, ok := codecSpecs[cfg.Codec]
+	if !ok {
+		// Codecs added with RegisterCodec are trusted to check their own config
+		return cfg, nil
+	}
+	if cfg.BitDepth == 0 {
+		cfg.BitDepth = spec.bitDepth
+	}
+	if spec.sampleRates != nil && !containsInt(spec.sampleRates, cfg.SampleRate) {
+		return cfg, fmt.Errorf("%w: %s does not support %dHz (supported: %v)",
+			ErrInvalidCodecConfig, cfg.Codec, cfg.SampleRate, spec.sampleRates)
+	}
+	if cfg.BitDepth != spec.bitDepth {
+		return cfg, fmt.Errorf("%w: %s is %d-bit, got %d",
+			ErrInvalidCodecConfig, cfg.Codec, spec.bitDepth, cfg.BitDepth)
+	}
+	if cfg.Channels < 0 || cfg.Channels > spec.maxChannels {
+		return cfg, fmt.Errorf("%w: %s supports at most %d channel(s), got %d",
+			ErrInvalidCodecConfig, cfg.Codec, spec.maxChannels, cfg.Channels)
+	}
+	return cfg, nil
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Transcode converts one packet; it has the media.EncoderFunc signature so
+// it can be passed wherever an encoder or decoder is expected
+func (t *Transcoder) Transcode(packet media.MediaPacket) ([]media.MediaPacket, error) {
+	return t.process(packet)
+}
+
+// From returns the normalized input format
+func (t *Transcoder) From() media.CodecConfig {
+	return t.from
+}
+
+// To returns the normalized output format
+func (t *Transcoder) To() media.CodecConfig {
+	return t.to
+}
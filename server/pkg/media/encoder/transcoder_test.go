@@ -0,0 +1,77 @@
+package encoder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+)
+
+func TestTranscoderDirection(t *testing.T) {
+	pcm := media.CodecConfig{Codec: CodecPCM, SampleRate: 16000}
+	pcma := media.CodecConfig{Codec: CodecPCMA, SampleRate: 8000, FrameDuration: "20ms"}
+
+	enc, err := NewTranscoder(pcm, pcma)
+	if err != nil {
+		t.Fatalf("encoder: %v", err)
+	}
+	if enc.From().BitDepth != 16 || enc.To().BitDepth != 8 || enc.To().Channels != 1 {
+		t.Errorf("defaults not applied: %v -> %v", enc.From(), enc.To())
+	}
+	// 20ms 16kHz PCM（640 字节）→ 8kHz PCMA（160 字节）
+	packets, err := enc.Transcode(&media.AudioPacket{Payload: make([]byte, 640)})
+	if err != nil || len(packets) != 1 || len(packets[0].(*media.AudioPacket).Payload) != 160 {
+		t.Fatalf("encode: %v, %d packets", err, len(packets))
+	}
+
+	dec, err := NewTranscoder(pcma, pcm)
+	if err != nil {
+		t.Fatalf("decoder: %v", err)
+	}
+	packets, err = dec.Transcode(packets[0])
+	if err != nil || len(packets) != 1 || len(packets[0].(*media.AudioPacket).Payload) != 640 {
+		t.Fatalf("decode: %v, %d packets", err, len(packets))
+	}
+}
+
+func TestTranscoderCodecToCodec(t *testing.T) {
+	tc, err := NewTranscoder(
+		media.CodecConfig{Codec: CodecPCMU, SampleRate: 8000},
+		media.CodecConfig{Codec: CodecPCMA, SampleRate: 8000},
+	)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	ulaw := make([]byte, 160)
+	for i := range ulaw {
+		ulaw[i] = LinearToULaw(int16(i * 100))
+	}
+	packets, err := tc.Transcode(&media.AudioPacket{Payload: ulaw})
+	if err != nil || len(packets) != 1 || len(packets[0].(*media.AudioPacket).Payload) != 160 {
+		t.Fatalf("transcode: %v, %d packets", err, len(packets))
+	}
+}
+
+func TestTranscoderValidation(t *testing.T) {
+	pcm := media.CodecConfig{Codec: CodecPCM, SampleRate: 16000}
+	cases := map[string]media.CodecConfig{
+		"pcma at 16kHz":  {Codec: CodecPCMA, SampleRate: 16000},
+		"g722 rtp clock": {Codec: CodecG722, SampleRate: 8000},
+		"opus 44.1kHz":   {Codec: CodecOPUS, SampleRate: 44100},
+		"pcma 16-bit":    {Codec: CodecPCMA, SampleRate: 8000, BitDepth: 16},
+		"no sample rate": {Codec: CodecPCMU},
+	}
+	for name, cfg := range cases {
+		if _, err := NewTranscoder(pcm, cfg); !errors.Is(err, ErrInvalidCodecConfig) {
+			t.Errorf("%s: expected ErrInvalidCodecConfig, got %v", name, err)
+		}
+	}
+
+	stereo := media.CodecConfig{Codec: CodecPCM, SampleRate: 8000, Channels: 2}
+	if _, err := NewTranscoder(stereo, media.CodecConfig{Codec: CodecPCMA, SampleRate: 8000}); !errors.Is(err, ErrInvalidCodecConfig) {
+		t.Errorf("channel mismatch: expected ErrInvalidCodecConfig, got %v", err)
+	}
+	if _, err := NewTranscoder(pcm, media.CodecConfig{Codec: "amr", SampleRate: 8000}); !errors.Is(err, media.ErrCodecNotSupported) {
+		t.Errorf("unknown codec: expected ErrCodecNotSupported, got %v", err)
+	}
+}
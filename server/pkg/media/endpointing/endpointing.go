@@ -0,0 +1,247 @@
+// Package endpointing decides when the caller's conversation turn is over.
+//
+// A Detector is fed ASR results as they arrive and reports one Turn per
+// utterance. The turn ends on the first of: a final ASR result, a partial
+// result that already reads as a complete sentence, a pause in the ASR
+// updates longer than the silence threshold, or the utterance running longer
+// than the maximum length. Once a turn has ended early, the ASR final result
+// that closes the same utterance is swallowed so the caller is not answered
+// twice.
+package endpointing
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reason tells what ended a turn
+type Reason string
+
+const (
+	ReasonFinal     Reason = "asr_final"  // the ASR marked the result final
+	ReasonSentence  Reason = "sentence"   // a partial result ended in sentence punctuation
+	ReasonSilence   Reason = "silence"    // no ASR update within the silence threshold
+	ReasonMaxLength Reason = "max_length" // the utterance hit the maximum length
+)
+
+// finalGrace is how long an early-ended turn waits for the ASR final result
+// of the same utterance; partial results after that start a new turn
+const finalGrace = 3 * time.Second
+
+// Policy configures end-of-turn detection. The zero value only ends turns on
+// final ASR results.
+type Policy struct {
+	// SilenceThreshold ends the turn when the ASR has not updated the
+	// transcript for this long, 0 disables it
+	SilenceThreshold time.Duration
+	// MaxUtterance ends the turn this long after its first ASR result even if
+	// the caller is still talking, 0 for no limit
+	MaxUtterance time.Duration
+	// EndOnSentence ends the turn on a partial result that contains sentence
+	// punctuation, without waiting for the final result
+	EndOnSentence bool
+}
+
+// DefaultPolicy ends turns on final results and on complete sentences
+func DefaultPolicy() Policy {
+	return Policy{EndOnSentence: true}
+}
+
+// Turn is one finished caller turn
+type Turn struct {
+	Text   string
+	Start  time.Time // arrival of the first ASR result, or the start reported by the ASR
+	Reason Reason
+}
+
+// Detector tracks the turn in progress. It is safe for concurrent use.
+type Detector struct {
+	policy Policy
+	onTurn func(Turn)
+
+	mu      sync.Mutex
+	text    string
+	start   time.Time
+	endedAt time.Time // set when the turn ended before the ASR final result
+	turnID  int       // bumped on every new turn so stale timers do nothing
+	seq     int       // bumped on every update, stale silence timers do nothing
+	silence *time.Timer
+	maxLen  *time.Timer
+	closed  bool
+}
+
+// NewDetector creates a detector calling onTurn whenever a turn ends. onTurn
+// runs on the caller's goroutine for ASR-driven ends and on a timer goroutine
+// for silence and length ends.
+func NewDetector(policy Policy, onTurn func(Turn)) *Detector {
+	return &Detector{policy: policy, onTurn: onTurn}
+}
+
+// Policy returns the detector's policy
+func (d *Detector) Policy() Policy {
+	return d.policy
+}
+
+// Transcript feeds an ASR result. duration is the utterance length reported
+// by the ASR with final results, 0 when unknown.
+func (d *Detector) Transcript(text string, final bool, duration time.Duration) {
+	if text == "" {
+		return
+	}
+	now := time.Now()
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	if !d.endedAt.IsZero() {
+		// Results of an utterance that has already been answered
+		if final || now.Sub(d.endedAt) < finalGrace {
+			if final {
+				d.reset()
+			}
+			d.mu.Unlock()
+			return
+		}
+		d.reset()
+	}
+
+	d.seq++
+	d.text = text
+	if d.start.IsZero() {
+		d.start = now
+		if d.policy.MaxUtterance > 0 {
+			turnID := d.turnID
+			d.maxLen = time.AfterFunc(d.policy.MaxUtterance, func() { d.expire(turnID, -1, ReasonMaxLength) })
+		}
+	}
+
+	var turn *Turn
+	switch {
+	case final:
+		start := d.start
+		// Providers reporting the utterance length give a better start than the first partial result
+		if duration > 0 && now.Add(-duration).Before(start) {
+			start = now.Add(-duration)
+		}
+		turn = &Turn{Text: text, Start: start, Reason: ReasonFinal}
+		d.reset()
+	case d.policy.EndOnSentence && isCompleteSentence(text):
+		if filtered := filterText(text); filtered != "" && !isMeaninglessText(filtered) {
+			turn = &Turn{Text: filtered, Start: d.start, Reason: ReasonSentence}
+			d.endEarly(now)
+		}
+	}
+	if turn == nil && d.policy.SilenceThreshold > 0 {
+		turnID, seq := d.turnID, d.seq
+		if d.silence != nil {
+			d.silence.Stop()
+		}
+		d.silence = time.AfterFunc(d.policy.SilenceThreshold, func() { d.expire(turnID, seq, ReasonSilence) })
+	}
+	d.mu.Unlock()
+
+	if turn != nil {
+		d.onTurn(*turn)
+	}
+}
+
+// Close stops the timers; no turn is reported afterwards
+func (d *Detector) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	d.stopTimers()
+}
+
+// expire ends the turn from a timer. The silence timer passes the update it
+// was armed after and only fires if nothing arrived since; the maximum length
+// timer passes -1.
+func (d *Detector) expire(turnID, seq int, reason Reason) {
+	d.mu.Lock()
+	if d.closed || turnID != d.turnID || !d.endedAt.IsZero() || (seq >= 0 && seq != d.seq) {
+		d.mu.Unlock()
+		return
+	}
+	text := filterText(d.text)
+	if text == "" || isMeaninglessText(text) {
+		// Nothing worth answering, e.g. a lone filler word; wait for more
+		d.mu.Unlock()
+		return
+	}
+	turn := Turn{Text: text, Start: d.start, Reason: reason}
+	d.endEarly(time.Now())
+	d.mu.Unlock()
+
+	d.onTurn(turn)
+}
+
+// endEarly marks the turn answered before its final result
+func (d *Detector) endEarly(now time.Time) {
+	d.stopTimers()
+	d.endedAt = now
+}
+
+// reset clears the turn after its final result
+func (d *Detector) reset() {
+	d.stopTimers()
+	d.text = ""
+	d.start = time.Time{}
+	d.endedAt = time.Time{}
+	d.turnID++
+}
+
+func (d *Detector) stopTimers() {
+	if d.silence != nil {
+		d.silence.Stop()
+		d.silence = nil
+	}
+	if d.maxLen != nil {
+		d.maxLen.Stop()
+		d.maxLen = nil
+	}
+}
+
+// isCompleteSentence checks if text contains sentence ending markers
+func isCompleteSentence(text string) bool {
+	if text == "" {
+		return false
+	}
+	endMarkers := []string{"。", "？", "！", ".", "?", "!"}
+	for _, marker := range endMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterText removes punctuation and whitespace
+func filterText(text string) string {
+	text = strings.TrimSpace(text)
+	// Remove common punctuation
+	text = strings.Trim(text, "。，、；：？！\"\"''（）【】《》")
+	return text
+}
+
+// isMeaninglessText checks if text is meaningless (should be filtered)
+func isMeaninglessText(text string) bool {
+	if text == "" {
+		return true
+	}
+	// Filter single character meaningless words
+	meaninglessWords := []string{"嗯", "啊", "哦", "额", "呃", "0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+	cleaned := strings.TrimSpace(text)
+	for _, word := range meaninglessWords {
+		if cleaned == word {
+			return true
+		}
+	}
+	// Filter very short text (less than 2 characters after cleaning)
+	if len([]rune(cleaned)) < 2 {
+		return true
+	}
+	return false
+}
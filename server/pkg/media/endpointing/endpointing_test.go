@@ -0,0 +1,129 @@
+package endpointing
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type turnRecorder struct {
+	mu    sync.Mutex
+	turns []Turn
+	ch    chan Turn
+}
+
+func newTurnRecorder() *turnRecorder {
+	return &turnRecorder{ch: make(chan Turn, 8)}
+}
+
+func (r *turnRecorder) onTurn(t Turn) {
+	r.mu.Lock()
+	r.turns = append(r.turns, t)
+	r.mu.Unlock()
+	r.ch <- t
+}
+
+func (r *turnRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.turns)
+}
+
+func (r *turnRecorder) wait(t *testing.T) Turn {
+	t.Helper()
+	select {
+	case turn := <-r.ch:
+		return turn
+	case <-time.After(2 * time.Second):
+		t.Fatal("no turn reported")
+		return Turn{}
+	}
+}
+
+func TestFinalResultEndsTurn(t *testing.T) {
+	rec := newTurnRecorder()
+	d := NewDetector(Policy{}, rec.onTurn)
+	defer d.Close()
+
+	d.Transcript("今天天气", false, 0)
+	d.Transcript("今天天气怎么样？", false, 0)
+	assert.Zero(t, rec.count(), "zero policy waits for the final result")
+
+	d.Transcript("今天天气怎么样？", true, 2*time.Second)
+	turn := rec.wait(t)
+	assert.Equal(t, ReasonFinal, turn.Reason)
+	assert.Equal(t, "今天天气怎么样？", turn.Text)
+	// The start reported by the ASR is earlier than the first partial result
+	assert.WithinDuration(t, time.Now().Add(-2*time.Second), turn.Start, 100*time.Millisecond)
+}
+
+func TestSentenceEndsTurnOnce(t *testing.T) {
+	rec := newTurnRecorder()
+	d := NewDetector(DefaultPolicy(), rec.onTurn)
+	defer d.Close()
+
+	d.Transcript("嗯。", false, 0)
+	assert.Zero(t, rec.count(), "filler words do not end the turn")
+
+	d.Transcript("帮我查一下订单。", false, 0)
+	turn := rec.wait(t)
+	assert.Equal(t, ReasonSentence, turn.Reason)
+	assert.Equal(t, "帮我查一下订单", turn.Text)
+
+	// The rest of the same utterance is not answered again
+	d.Transcript("帮我查一下订单。谢谢", false, 0)
+	d.Transcript("帮我查一下订单。谢谢", true, 0)
+	assert.Equal(t, 1, rec.count())
+
+	// The next utterance starts a new turn
+	d.Transcript("再见", true, 0)
+	assert.Equal(t, "再见", rec.wait(t).Text)
+}
+
+func TestSilenceEndsTurn(t *testing.T) {
+	rec := newTurnRecorder()
+	d := NewDetector(Policy{SilenceThreshold: 50 * time.Millisecond}, rec.onTurn)
+	defer d.Close()
+
+	start := time.Now()
+	d.Transcript("我想", false, 0)
+	time.Sleep(20 * time.Millisecond)
+	d.Transcript("我想预约", false, 0)
+	turn := rec.wait(t)
+	assert.Equal(t, ReasonSilence, turn.Reason)
+	assert.Equal(t, "我想预约", turn.Text)
+	assert.GreaterOrEqual(t, time.Since(start), 70*time.Millisecond, "silence is measured from the last update")
+
+	d.Transcript("我想预约明天", true, 0)
+	time.Sleep(80 * time.Millisecond)
+	assert.Equal(t, 1, rec.count())
+}
+
+func TestMaxUtteranceEndsTurn(t *testing.T) {
+	rec := newTurnRecorder()
+	d := NewDetector(Policy{MaxUtterance: 60 * time.Millisecond}, rec.onTurn)
+	defer d.Close()
+
+	d.Transcript("我要说一段很长的话", false, 0)
+	for i := 0; i < 5; i++ {
+		time.Sleep(20 * time.Millisecond)
+		d.Transcript("我要说一段很长的话还没有说完", false, 0)
+	}
+	turn := rec.wait(t)
+	assert.Equal(t, ReasonMaxLength, turn.Reason)
+	assert.Equal(t, "我要说一段很长的话还没有说完", turn.Text)
+}
+
+func TestCloseStopsTimers(t *testing.T) {
+	rec := newTurnRecorder()
+	d := NewDetector(Policy{SilenceThreshold: 20 * time.Millisecond}, rec.onTurn)
+	d.Transcript("你好你好", false, 0)
+	d.Close()
+	time.Sleep(50 * time.Millisecond)
+	require.Zero(t, rec.count())
+	d.Transcript("你好你好", true, 0)
+	assert.Zero(t, rec.count())
+}
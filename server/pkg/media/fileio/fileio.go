@@ -0,0 +1,213 @@
+// Package fileio reads and writes audio files as streams of 16-bit
+// little-endian PCM.
+//
+// A Source decodes a WAV, Ogg/Opus or MP3 file and hands out fixed 20ms
+// frames, so callers that pace audio onto the wire no longer need to load and
+// slice whole files themselves. A Sink does the reverse. WAV and Ogg/Opus are
+// handled natively; MP3 is decoded and encoded by an ffmpeg subprocess, as the
+// rest of the repo already does for MP3.
+package fileio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+	_ "github.com/code-100-precent/LingEcho/pkg/media/encoder" // registers the codecs used by EncodeFrames
+)
+
+// FrameDuration is the length of one frame returned by Source.ReadFrame
+const FrameDuration = 20 * time.Millisecond
+
+// ErrUnsupportedFormat is returned for files that are not 16-bit PCM WAV,
+// Ogg/Opus or MP3
+var ErrUnsupportedFormat = errors.New("fileio: unsupported audio format")
+
+// Format describes the PCM flowing through a Source or Sink. Samples are
+// always 16-bit little-endian and interleaved.
+type Format struct {
+	SampleRate int
+	Channels   int
+}
+
+// FrameBytes returns the size of d worth of PCM in this format
+func (f Format) FrameBytes(d time.Duration) int {
+	return int(int64(f.SampleRate)*int64(d)/int64(time.Second)) * f.Channels * 2
+}
+
+// Duration returns how long n bytes of PCM in this format play for
+func (f Format) Duration(n int) time.Duration {
+	if f.SampleRate == 0 || f.Channels == 0 {
+		return 0
+	}
+	return time.Duration(n/(f.Channels*2)) * time.Second / time.Duration(f.SampleRate)
+}
+
+// Source is a decoded audio stream. Read returns raw PCM; ReadFrame returns
+// one FrameDuration frame per call, shorter only at the end of the stream,
+// and io.EOF once the stream is exhausted.
+type Source interface {
+	io.ReadCloser
+	Format() Format
+	ReadFrame() ([]byte, error)
+}
+
+// Sink encodes PCM written to it. Close flushes buffered audio, finalises the
+// container and closes the underlying writer.
+type Sink interface {
+	io.WriteCloser
+	Format() Format
+}
+
+// Open opens an audio file, choosing the decoder from its extension
+func Open(path string) (Source, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var src Source
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wav":
+		src, err = NewWAVSource(file)
+	case ".ogg", ".opus":
+		src, err = NewOggOpusSource(file)
+	case ".mp3":
+		src, err = NewMP3Source(file)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return src, nil
+}
+
+// Create creates an audio file, choosing the encoder from its extension
+func Create(path string, format Format) (Sink, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".wav", ".ogg", ".opus", ".mp3":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	var sink Sink
+	switch ext {
+	case ".wav":
+		sink, err = NewWAVSink(file, format)
+	case ".ogg", ".opus":
+		sink, err = NewOggOpusSink(file, format)
+	case ".mp3":
+		sink, err = NewMP3Sink(file, format)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return sink, nil
+}
+
+// EncodeFrames reads src to the end, downmixes it to mono, resamples it to
+// codec.SampleRate and encodes it with codec, returning one packet payload per
+// encoded frame
+func EncodeFrames(src Source, codec media.CodecConfig) ([][]byte, error) {
+	format := src.Format()
+	encode, err := media.NewPipeline().
+		Input(format.SampleRate, format.Channels).
+		Mono().
+		Resample(codec.SampleRate).
+		Encode(codec).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var frames [][]byte
+	for {
+		frame, err := src.ReadFrame()
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		packets, err := encode(&media.AudioPacket{Payload: frame})
+		if err != nil {
+			return nil, err
+		}
+		for _, packet := range packets {
+			if audio, ok := packet.(*media.AudioPacket); ok && len(audio.Payload) > 0 {
+				frames = append(frames, audio.Payload)
+			}
+		}
+	}
+}
+
+// pcmSource turns a PCM reader into a Source
+type pcmSource struct {
+	io.Reader
+	format Format
+	closer io.Closer
+	frame  []byte
+}
+
+func newPCMSource(r io.Reader, format Format, closer io.Closer) *pcmSource {
+	return &pcmSource{
+		Reader: r,
+		format: format,
+		closer: closer,
+		frame:  make([]byte, format.FrameBytes(FrameDuration)),
+	}
+}
+
+func (s *pcmSource) Format() Format { return s.format }
+
+// ReadFrame returns a fresh slice so callers may keep frames around
+func (s *pcmSource) ReadFrame() ([]byte, error) {
+	n, err := io.ReadFull(s.Reader, s.frame)
+	if n == 0 {
+		if err == nil || err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, err
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	frame := make([]byte, n)
+	copy(frame, s.frame[:n])
+	return frame, nil
+}
+
+func (s *pcmSource) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// closerOf returns v as an io.Closer, or nil
+func closerOf(v any) io.Closer {
+	if c, ok := v.(io.Closer); ok {
+		return c
+	}
+	return nil
+}
+
+// validFormat reports an error for formats a Sink cannot write
+func validFormat(format Format) error {
+	if format.SampleRate <= 0 || format.Channels <= 0 {
+		return fmt.Errorf("fileio: invalid format %d Hz, %d channels", format.SampleRate, format.Channels)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package fileio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+	"github.com/hraban/opus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sine returns d of a 440Hz tone in format
+func sine(format Format, d time.Duration) []byte {
+	samples := format.FrameBytes(d) / 2 / format.Channels
+	pcm := make([]byte, 0, samples*format.Channels*2)
+	for i := 0; i < samples; i++ {
+		v := int16(8000 * math.Sin(2*math.Pi*440*float64(i)/float64(format.SampleRate)))
+		for c := 0; c < format.Channels; c++ {
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(v))
+		}
+	}
+	return pcm
+}
+
+func readFrames(t *testing.T, src Source) [][]byte {
+	t.Helper()
+	var frames [][]byte
+	for {
+		frame, err := src.ReadFrame()
+		if err == io.EOF {
+			return frames
+		}
+		require.NoError(t, err)
+		frames = append(frames, frame)
+	}
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	format := Format{SampleRate: 16000, Channels: 1}
+	pcm := sine(format, 110*time.Millisecond)
+	path := filepath.Join(t.TempDir(), "tone.wav")
+
+	sink, err := Create(path, format)
+	require.NoError(t, err)
+	_, err = sink.Write(pcm)
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	src, err := Open(path)
+	require.NoError(t, err)
+	defer src.Close()
+	assert.Equal(t, format, src.Format())
+
+	frames := readFrames(t, src)
+	require.Len(t, frames, 6)
+	assert.Len(t, frames[0], 640)
+	assert.Len(t, frames[5], 320, "last frame is short")
+	assert.Equal(t, pcm, bytes.Join(frames, nil))
+}
+
+func TestDecodeWAVSkipsExtraChunks(t *testing.T) {
+	format := Format{SampleRate: 8000, Channels: 2}
+	pcm := sine(format, 20*time.Millisecond)
+	encoded := EncodeWAV(pcm, format)
+
+	// Insert a LIST chunk with odd size (padded) between fmt and data
+	list := append([]byte("LIST\x03\x00\x00\x00abc"), 0)
+	withList := append(append(append([]byte{}, encoded[:36]...), list...), encoded[36:]...)
+
+	for _, data := range [][]byte{encoded, withList} {
+		got, gotFormat, err := DecodeWAV(data)
+		require.NoError(t, err)
+		assert.Equal(t, format, gotFormat)
+		assert.Equal(t, pcm, got)
+	}
+
+	_, _, err := DecodeWAV(pcm)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestWAVSinkStreaming(t *testing.T) {
+	// A plain writer cannot be rewound, so the header declares a streaming size
+	var buf bytes.Buffer
+	format := Format{SampleRate: 8000, Channels: 1}
+	sink, err := NewWAVSink(&buf, format)
+	require.NoError(t, err)
+	_, err = sink.Write(make([]byte, 320))
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	src, err := NewWAVSource(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Len(t, readFrames(t, src), 1)
+}
+
+func TestOggOpusRoundTrip(t *testing.T) {
+	format := Format{SampleRate: 16000, Channels: 1}
+	if enc, err := opus.NewEncoder(format.SampleRate, format.Channels, opus.AppAudio); err != nil {
+		t.Skipf("libopus unavailable: %v", err)
+	} else if _, err := enc.Encode(make([]int16, 320), make([]byte, opusMaxPacket)); err != nil {
+		t.Skipf("libopus unavailable: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "tone.ogg")
+
+	sink, err := Create(path, format)
+	require.NoError(t, err)
+	_, err = sink.Write(sine(format, 490*time.Millisecond))
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	src, err := Open(path)
+	require.NoError(t, err)
+	defer src.Close()
+	assert.Equal(t, Format{SampleRate: 48000, Channels: 1}, src.Format())
+
+	frames := readFrames(t, src)
+	decoded := bytes.Join(frames, nil)
+	// 25 padded 20ms packets minus the encoder's pre-skip
+	assert.InDelta(t, 500*time.Millisecond, src.Format().Duration(len(decoded)), float64(20*time.Millisecond))
+	assert.Len(t, frames[0], 1920)
+}
+
+func TestOggPacketsAcrossPages(t *testing.T) {
+	page := func(lacing []byte, body []byte) []byte {
+		header := make([]byte, oggPageHeaderSize)
+		copy(header, "OggS")
+		header[26] = byte(len(lacing))
+		return append(append(header, lacing...), body...)
+	}
+	long := bytes.Repeat([]byte{1}, 300)
+	stream := append(page([]byte{255}, long[:255]), page([]byte{45, 2}, append(long[255:], 7, 8))...)
+
+	packets := &oggPackets{r: bytes.NewReader(stream)}
+	first, err := packets.next()
+	require.NoError(t, err)
+	assert.Equal(t, long, first)
+	second, err := packets.next()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{7, 8}, second)
+	_, err = packets.next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestProbeMP3(t *testing.T) {
+	// ID3v2 tag of 5 bytes, then an MPEG-1 Layer III 44.1kHz mono frame header
+	data := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x05"), 0, 0, 0, 0, 0)
+	data = append(data, 0xFF, 0xFB, 0x90, 0xC4)
+	format, err := probeMP3(bufio.NewReader(bytes.NewReader(data)))
+	require.NoError(t, err)
+	assert.Equal(t, Format{SampleRate: 44100, Channels: 1}, format)
+
+	_, err = probeMP3(bufio.NewReader(bytes.NewReader([]byte("not audio"))))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestMP3RoundTrip(t *testing.T) {
+	if _, err := exec.LookPath(FFmpegPath); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	format := Format{SampleRate: 24000, Channels: 1}
+	path := filepath.Join(t.TempDir(), "tone.mp3")
+
+	sink, err := Create(path, format)
+	require.NoError(t, err)
+	_, err = sink.Write(sine(format, time.Second))
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	src, err := Open(path)
+	require.NoError(t, err)
+	defer src.Close()
+	assert.Equal(t, format, src.Format())
+	assert.InDelta(t, time.Second, format.Duration(len(bytes.Join(readFrames(t, src), nil))), float64(100*time.Millisecond))
+}
+
+func TestEncodeFrames(t *testing.T) {
+	format := Format{SampleRate: 16000, Channels: 2}
+	src, err := NewWAVSource(bytes.NewReader(EncodeWAV(sine(format, 100*time.Millisecond), format)))
+	require.NoError(t, err)
+
+	frames, err := EncodeFrames(src, media.CodecConfig{Codec: "pcma", SampleRate: 8000, Channels: 1})
+	require.NoError(t, err)
+	require.Len(t, frames, 5)
+	for _, frame := range frames {
+		assert.Len(t, frame, 160)
+	}
+}
+
+func TestUnsupportedExtension(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "missing.flac"))
+	assert.Error(t, err)
+	_, err = Create(filepath.Join(t.TempDir(), "out.flac"), Format{SampleRate: 8000, Channels: 1})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
@@ -0,0 +1,191 @@
+package fileio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"sync"
+)
+
+// FFmpegPath is the ffmpeg binary used for MP3; override it when ffmpeg is not
+// on PATH
+var FFmpegPath = "ffmpeg"
+
+// mp3SampleRates is indexed by [version][rate index] of an MPEG audio frame
+// header, versions ordered MPEG 2.5, reserved, MPEG 2, MPEG 1
+var mp3SampleRates = [4][3]int{
+	{11025, 12000, 8000},
+	{},
+	{22050, 24000, 16000},
+	{44100, 48000, 32000},
+}
+
+// mp3Probe is how far into the stream the first frame header is looked for
+const mp3Probe = 64 * 1024
+
+// ffmpegProcess is an ffmpeg subprocess; wait reaps it once and closes the
+// file the caller handed over
+type ffmpegProcess struct {
+	cmd      *exec.Cmd
+	stderr   bytes.Buffer
+	closer   io.Closer
+	waitOnce sync.Once
+	waitErr  error
+}
+
+func newFFmpeg(closer io.Closer, args ...string) *ffmpegProcess {
+	p := &ffmpegProcess{closer: closer}
+	p.cmd = exec.Command(FFmpegPath, append([]string{"-v", "error"}, args...)...)
+	p.cmd.Stderr = &p.stderr
+	return p
+}
+
+func (p *ffmpegProcess) wait() error {
+	p.waitOnce.Do(func() {
+		err := p.cmd.Wait()
+		if err != nil && p.stderr.Len() > 0 {
+			err = fmt.Errorf("ffmpeg: %w: %s", err, bytes.TrimSpace(p.stderr.Bytes()))
+		}
+		if p.closer != nil {
+			err = errors.Join(err, p.closer.Close())
+		}
+		p.waitErr = err
+	})
+	return p.waitErr
+}
+
+// mp3Output reads ffmpeg's stdout and reports a failed decode at end of stream
+// instead of a silently truncated one
+type mp3Output struct {
+	stdout  io.ReadCloser
+	process *ffmpegProcess
+	done    bool
+}
+
+func (o *mp3Output) Read(p []byte) (int, error) {
+	n, err := o.stdout.Read(p)
+	if err == io.EOF {
+		o.done = true
+		if waitErr := o.process.wait(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// Close stops ffmpeg if the stream was not read to the end
+func (o *mp3Output) Close() error {
+	if o.done {
+		return o.process.wait()
+	}
+	o.stdout.Close()
+	o.process.cmd.Process.Kill()
+	if err := o.process.wait(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewMP3Source decodes an MP3 stream with ffmpeg. The sample rate and channel
+// count come from the first frame header, so the PCM keeps the file's own
+// format. If r is an io.Closer, closing the Source closes it.
+func NewMP3Source(r io.Reader) (Source, error) {
+	buffered := bufio.NewReaderSize(r, mp3Probe)
+	format, err := probeMP3(buffered)
+	if err != nil {
+		return nil, err
+	}
+
+	process := newFFmpeg(closerOf(r),
+		"-f", "mp3", "-i", "pipe:0",
+		"-f", "s16le", "-acodec", "pcm_s16le",
+		"-ar", strconv.Itoa(format.SampleRate), "-ac", strconv.Itoa(format.Channels),
+		"pipe:1")
+	process.cmd.Stdin = buffered
+	stdout, err := process.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := process.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("fileio: starting ffmpeg: %w", err)
+	}
+	output := &mp3Output{stdout: stdout, process: process}
+	return newPCMSource(output, format, output), nil
+}
+
+// probeMP3 finds the first MPEG audio frame header without consuming input.
+// An ID3v2 tag at the start is skipped using its declared size.
+func probeMP3(r *bufio.Reader) (Format, error) {
+	data, err := r.Peek(mp3Probe)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return Format{}, err
+	}
+
+	offset := 0
+	if len(data) >= 10 && string(data[0:3]) == "ID3" {
+		// Tag size is a 28-bit syncsafe integer excluding the 10-byte header
+		offset = 10 + (int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F))
+	}
+	for i := offset; i+4 <= len(data); i++ {
+		if data[i] != 0xFF || data[i+1]&0xE0 != 0xE0 {
+			continue
+		}
+		version := (data[i+1] >> 3) & 0x03
+		layer := (data[i+1] >> 1) & 0x03
+		rateIndex := (data[i+2] >> 2) & 0x03
+		if version == 1 || layer == 0 || rateIndex == 3 {
+			continue
+		}
+		channels := 2
+		if data[i+3]>>6 == 3 {
+			channels = 1
+		}
+		return Format{SampleRate: mp3SampleRates[version][rateIndex], Channels: channels}, nil
+	}
+	return Format{}, fmt.Errorf("%w: no MPEG audio frame found", ErrUnsupportedFormat)
+}
+
+// mp3Sink pipes PCM into ffmpeg, which writes MP3 to the destination
+type mp3Sink struct {
+	format  Format
+	stdin   io.WriteCloser
+	process *ffmpegProcess
+}
+
+// NewMP3Sink encodes PCM to MP3 with ffmpeg (libmp3lame, 128kbps). If w is an
+// io.Closer, closing the Sink closes it.
+func NewMP3Sink(w io.Writer, format Format) (Sink, error) {
+	if err := validFormat(format); err != nil {
+		return nil, err
+	}
+	process := newFFmpeg(closerOf(w),
+		"-f", "s16le", "-ar", strconv.Itoa(format.SampleRate), "-ac", strconv.Itoa(format.Channels), "-i", "pipe:0",
+		"-acodec", "libmp3lame", "-ab", "128k", "-f", "mp3", "pipe:1")
+	process.cmd.Stdout = w
+	stdin, err := process.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := process.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("fileio: starting ffmpeg: %w", err)
+	}
+	return &mp3Sink{format: format, stdin: stdin, process: process}, nil
+}
+
+func (s *mp3Sink) Format() Format { return s.format }
+
+func (s *mp3Sink) Write(p []byte) (int, error) {
+	return s.stdin.Write(p)
+}
+
+// Close ends the input and waits for ffmpeg to flush the encoded stream
+func (s *mp3Sink) Close() error {
+	return errors.Join(s.stdin.Close(), s.process.wait())
+}
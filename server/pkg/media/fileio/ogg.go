@@ -0,0 +1,233 @@
+package fileio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hraban/opus"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+)
+
+const (
+	// opusRate is the rate Ogg/Opus granule positions count in; sources
+	// always decode at this rate
+	opusRate = 48000
+	// opusMaxFrame is the longest Opus packet (120ms at 48kHz), per channel
+	opusMaxFrame = 5760
+	// opusMaxPacket bounds the size of one encoded packet
+	opusMaxPacket = 4000
+	// oggPageHeaderSize is the fixed part of an Ogg page header
+	oggPageHeaderSize = 27
+)
+
+// oggPackets splits an Ogg stream into packets using the page segment tables,
+// so several packets per page and packets spanning pages are both handled
+type oggPackets struct {
+	r       io.Reader
+	pending [][]byte
+	partial []byte
+}
+
+func (o *oggPackets) next() ([]byte, error) {
+	for len(o.pending) == 0 {
+		if err := o.readPage(); err != nil {
+			return nil, err
+		}
+	}
+	packet := o.pending[0]
+	o.pending = o.pending[1:]
+	return packet, nil
+}
+
+func (o *oggPackets) readPage() error {
+	var header [oggPageHeaderSize]byte
+	if _, err := io.ReadFull(o.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("fileio: truncated Ogg page: %w", err)
+		}
+		return err
+	}
+	if string(header[0:4]) != "OggS" {
+		return fmt.Errorf("%w: bad Ogg capture pattern", ErrUnsupportedFormat)
+	}
+	lacing := make([]byte, header[26])
+	if _, err := io.ReadFull(o.r, lacing); err != nil {
+		return fmt.Errorf("fileio: truncated Ogg page: %w", err)
+	}
+	size := 0
+	for _, l := range lacing {
+		size += int(l)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(o.r, body); err != nil {
+		return fmt.Errorf("fileio: truncated Ogg page: %w", err)
+	}
+
+	// A lacing value below 255 ends a packet; 255 means it continues
+	offset := 0
+	for _, l := range lacing {
+		o.partial = append(o.partial, body[offset:offset+int(l)]...)
+		offset += int(l)
+		if l < 255 {
+			o.pending = append(o.pending, o.partial)
+			o.partial = nil
+		}
+	}
+	return nil
+}
+
+// oggOpusReader decodes Opus packets into PCM on demand
+type oggOpusReader struct {
+	packets  *oggPackets
+	decoder  *opus.Decoder
+	channels int
+	skip     int // samples per channel still to drop (pre-skip)
+	pcm      []int16
+	buf      bytes.Buffer
+}
+
+// NewOggOpusSource decodes an Ogg/Opus stream to 48kHz PCM with the channel
+// count from its OpusHead. If r is an io.Closer, closing the Source closes it.
+func NewOggOpusSource(r io.Reader) (Source, error) {
+	packets := &oggPackets{r: r}
+	head, err := packets.next()
+	if err != nil {
+		return nil, fmt.Errorf("fileio: reading OpusHead: %w", err)
+	}
+	if len(head) < 19 || string(head[0:8]) != "OpusHead" {
+		return nil, fmt.Errorf("%w: Ogg stream is not Opus", ErrUnsupportedFormat)
+	}
+	channels := int(head[9])
+	if channels < 1 || channels > 2 {
+		return nil, fmt.Errorf("%w: Opus stream with %d channels", ErrUnsupportedFormat, channels)
+	}
+	preSkip := int(binary.LittleEndian.Uint16(head[10:12]))
+
+	// The comment header (OpusTags) carries no audio
+	if _, err := packets.next(); err != nil {
+		return nil, fmt.Errorf("fileio: reading OpusTags: %w", err)
+	}
+
+	decoder, err := opus.NewDecoder(opusRate, channels)
+	if err != nil {
+		return nil, err
+	}
+	reader := &oggOpusReader{
+		packets:  packets,
+		decoder:  decoder,
+		channels: channels,
+		skip:     preSkip,
+		pcm:      make([]int16, opusMaxFrame*channels),
+	}
+	return newPCMSource(reader, Format{SampleRate: opusRate, Channels: channels}, closerOf(r)), nil
+}
+
+func (o *oggOpusReader) Read(p []byte) (int, error) {
+	for o.buf.Len() == 0 {
+		packet, err := o.packets.next()
+		if err != nil {
+			return 0, err
+		}
+		if len(packet) == 0 {
+			continue
+		}
+		n, err := o.decoder.Decode(packet, o.pcm)
+		if err != nil {
+			return 0, fmt.Errorf("fileio: opus decode: %w", err)
+		}
+		start := 0
+		if o.skip > 0 {
+			start = min(o.skip, n)
+			o.skip -= start
+		}
+		for _, sample := range o.pcm[start*o.channels : n*o.channels] {
+			o.buf.WriteByte(byte(sample))
+			o.buf.WriteByte(byte(sample >> 8))
+		}
+	}
+	return o.buf.Read(p)
+}
+
+// oggOpusSink encodes 20ms Opus packets into an Ogg container
+type oggOpusSink struct {
+	ogg     *oggwriter.OggWriter
+	encoder *opus.Encoder
+	format  Format
+	frame   int // bytes per 20ms frame
+	buf     []byte
+	pcm     []int16
+	out     []byte
+	packet  *rtp.Packet
+}
+
+// NewOggOpusSink writes an Ogg/Opus stream. Opus only accepts 8, 12, 16, 24
+// and 48kHz input with one or two channels. If w is an io.Closer, closing the
+// Sink closes it.
+func NewOggOpusSink(w io.Writer, format Format) (Sink, error) {
+	if err := validFormat(format); err != nil {
+		return nil, err
+	}
+	encoder, err := opus.NewEncoder(format.SampleRate, format.Channels, opus.AppAudio)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	ogg, err := oggwriter.NewWith(w, uint32(format.SampleRate), uint16(format.Channels))
+	if err != nil {
+		return nil, err
+	}
+	frame := format.FrameBytes(FrameDuration)
+	return &oggOpusSink{
+		ogg:     ogg,
+		encoder: encoder,
+		format:  format,
+		frame:   frame,
+		pcm:     make([]int16, frame/2),
+		out:     make([]byte, opusMaxPacket),
+		packet:  &rtp.Packet{Header: rtp.Header{Timestamp: 1}},
+	}, nil
+}
+
+func (s *oggOpusSink) Format() Format { return s.format }
+
+func (s *oggOpusSink) Write(p []byte) (int, error) {
+	s.buf = append(s.buf, p...)
+	for len(s.buf) >= s.frame {
+		if err := s.encode(s.buf[:s.frame]); err != nil {
+			return 0, err
+		}
+		s.buf = s.buf[s.frame:]
+	}
+	return len(p), nil
+}
+
+// encode writes one 20ms frame; the granule always advances 960 because Ogg
+// Opus counts in 48kHz samples whatever the input rate
+func (s *oggOpusSink) encode(frame []byte) error {
+	for i := range s.pcm {
+		s.pcm[i] = int16(binary.LittleEndian.Uint16(frame[2*i:]))
+	}
+	n, err := s.encoder.Encode(s.pcm, s.out)
+	if err != nil {
+		return fmt.Errorf("fileio: opus encode: %w", err)
+	}
+	s.packet.Payload = s.out[:n]
+	s.packet.Timestamp += opusRate / 50
+	s.packet.SequenceNumber++
+	return s.ogg.WriteRTP(s.packet)
+}
+
+// Close pads the last partial frame with silence, then closes the container
+func (s *oggOpusSink) Close() error {
+	var err error
+	if len(s.buf) > 0 {
+		frame := make([]byte, s.frame)
+		copy(frame, s.buf)
+		s.buf = nil
+		err = s.encode(frame)
+	}
+	return errors.Join(err, s.ogg.Close())
+}
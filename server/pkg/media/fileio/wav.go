@@ -0,0 +1,190 @@
+package fileio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const wavHeaderSize = 44
+
+// wavStreamSize is written as the RIFF and data sizes when the output cannot
+// be rewound to patch them; most readers treat it as "until end of file"
+const wavStreamSize = 0xFFFFFFFF
+
+// NewWAVSource reads a 16-bit PCM WAV stream. Chunks other than fmt and data
+// (LIST, fact, ...) are skipped. If r is an io.Closer, closing the Source
+// closes it.
+func NewWAVSource(r io.Reader) (Source, error) {
+	format, dataSize, err := readWAVHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	var pcm io.Reader = r
+	if dataSize != wavStreamSize && dataSize != 0 {
+		pcm = io.LimitReader(r, int64(dataSize))
+	}
+	return newPCMSource(pcm, format, closerOf(r)), nil
+}
+
+// readWAVHeader consumes everything up to the start of the data chunk
+func readWAVHeader(r io.Reader) (Format, uint32, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return Format{}, 0, fmt.Errorf("fileio: reading WAV header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return Format{}, 0, fmt.Errorf("%w: not a WAV file", ErrUnsupportedFormat)
+	}
+
+	var format Format
+	haveFormat := false
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return Format{}, 0, fmt.Errorf("fileio: reading WAV chunk: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return Format{}, 0, fmt.Errorf("%w: short WAV fmt chunk", ErrUnsupportedFormat)
+			}
+			body := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return Format{}, 0, fmt.Errorf("fileio: reading WAV fmt chunk: %w", err)
+			}
+			audioFormat := binary.LittleEndian.Uint16(body[0:2])
+			bits := binary.LittleEndian.Uint16(body[14:16])
+			// 0xFFFE is WAVE_FORMAT_EXTENSIBLE, which still carries integer PCM here
+			if (audioFormat != 1 && audioFormat != 0xFFFE) || bits != 16 {
+				return Format{}, 0, fmt.Errorf("%w: WAV format %d with %d-bit samples", ErrUnsupportedFormat, audioFormat, bits)
+			}
+			format.Channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			format.SampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return Format{}, 0, fmt.Errorf("%w: WAV data before fmt chunk", ErrUnsupportedFormat)
+			}
+			return format, size, nil
+		default:
+			// Chunks are padded to an even size
+			if _, err := io.CopyN(io.Discard, r, int64(size+size%2)); err != nil {
+				return Format{}, 0, fmt.Errorf("fileio: skipping WAV %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+// wavHeader builds a canonical 44-byte PCM WAV header
+func wavHeader(format Format, dataSize uint32) []byte {
+	riffSize := uint32(wavStreamSize)
+	if dataSize != wavStreamSize {
+		riffSize = 36 + dataSize
+	}
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], riffSize)
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:], uint16(format.Channels))
+	binary.LittleEndian.PutUint32(header[24:], uint32(format.SampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(format.SampleRate*format.Channels*2))
+	binary.LittleEndian.PutUint16(header[32:], uint16(format.Channels*2))
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], dataSize)
+	return header
+}
+
+// wavSink writes PCM behind a WAV header. When the writer is an io.WriteSeeker
+// the sizes are patched on Close; otherwise the header says "streaming".
+type wavSink struct {
+	w       io.Writer
+	format  Format
+	written uint32
+}
+
+// NewWAVSink writes a 16-bit PCM WAV stream. If w is an io.Closer, closing the
+// Sink closes it.
+func NewWAVSink(w io.Writer, format Format) (Sink, error) {
+	if err := validFormat(format); err != nil {
+		return nil, err
+	}
+	size := uint32(wavStreamSize)
+	if _, ok := w.(io.WriteSeeker); ok {
+		size = 0
+	}
+	if _, err := w.Write(wavHeader(format, size)); err != nil {
+		return nil, err
+	}
+	return &wavSink{w: w, format: format}, nil
+}
+
+func (s *wavSink) Format() Format { return s.format }
+
+func (s *wavSink) Write(p []byte) (int, error) {
+	n, err := s.w.Write(p)
+	s.written += uint32(n)
+	return n, err
+}
+
+func (s *wavSink) Close() error {
+	var err error
+	if seeker, ok := s.w.(io.WriteSeeker); ok {
+		err = patchWAVHeader(seeker, s.written)
+	}
+	if closer := closerOf(s.w); closer != nil {
+		err = errors.Join(err, closer.Close())
+	}
+	return err
+}
+
+// patchWAVHeader rewrites the RIFF and data sizes once the length is known
+func patchWAVHeader(w io.WriteSeeker, dataSize uint32) error {
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], 36+dataSize)
+	if _, err := w.Seek(4, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(size[:], dataSize)
+	if _, err := w.Seek(40, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// EncodeWAV wraps in-memory PCM in a WAV header
+func EncodeWAV(pcm []byte, format Format) []byte {
+	out := make([]byte, 0, wavHeaderSize+len(pcm))
+	out = append(out, wavHeader(format, uint32(len(pcm)))...)
+	return append(out, pcm...)
+}
+
+// DecodeWAV extracts the PCM and format from an in-memory WAV file
+func DecodeWAV(data []byte) ([]byte, Format, error) {
+	r := bytes.NewReader(data)
+	format, dataSize, err := readWAVHeader(r)
+	if err != nil {
+		return nil, Format{}, err
+	}
+	pcm := data[len(data)-r.Len():]
+	if dataSize != wavStreamSize && int(dataSize) < len(pcm) {
+		pcm = pcm[:dataSize]
+	}
+	return pcm, format, nil
+}
@@ -0,0 +1,281 @@
+package media
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Jitter buffer defaults
+const (
+	DefaultJitterFrameDuration = 20 * time.Millisecond
+	DefaultJitterTargetDelay   = 60 * time.Millisecond
+	DefaultJitterMaxDelay      = 300 * time.Millisecond
+	DefaultJitterMaxPLCFrames  = 5
+)
+
+// JitterBufferConfig configures a JitterBuffer
+type JitterBufferConfig struct {
+	// FrameDuration is the playout interval of one frame (one RTP packet)
+	FrameDuration time.Duration
+	// TargetDelay is the minimum amount of audio buffered before playout starts
+	TargetDelay time.Duration
+	// MaxDelay caps the adaptive delay; older frames are dropped beyond it
+	MaxDelay time.Duration
+	// ClockRate is the RTP clock rate used to estimate interarrival jitter
+	ClockRate int
+	// MaxPLCFrames is how many consecutive missing frames are concealed before
+	// the buffer treats the stream as stalled and re-buffers
+	MaxPLCFrames int
+}
+
+// JitterBufferStats reports jitter buffer counters
+type JitterBufferStats struct {
+	Received   uint64        // frames pushed
+	Played     uint64        // frames played out as received
+	Concealed  uint64        // missing frames filled by PLC
+	Late       uint64        // frames that arrived after their playout time
+	Duplicates uint64        // frames received more than once
+	Dropped    uint64        // frames discarded to stay under MaxDelay
+	Underruns  uint64        // times playout stalled and re-buffered
+	Jitter     time.Duration // RFC 3550 interarrival jitter estimate
+	Delay      time.Duration // current adaptive target delay
+	Buffered   int           // frames currently buffered
+}
+
+// JitterBuffer reorders decoded PCM frames by RTP sequence number and plays
+// them out at a steady cadence, concealing lost frames by repeating the last
+// frame with decaying gain. The target delay adapts to the measured jitter.
+type JitterBuffer struct {
+	cfg JitterBufferConfig
+
+	mu        sync.Mutex
+	frames    map[uint16][]byte
+	nextSeq   uint16
+	started   bool // nextSeq is valid
+	playing   bool // prebuffering finished
+	lastFrame []byte
+	plcCount  int
+
+	// jitter estimation (RFC 3550 section 6.4.1)
+	lastArrival   time.Time
+	lastTimestamp uint32
+	jitter        float64 // seconds
+
+	stats JitterBufferStats
+}
+
+// NewJitterBuffer creates a jitter buffer, filling in defaults for zero config values
+func NewJitterBuffer(cfg JitterBufferConfig) *JitterBuffer {
+	if cfg.FrameDuration <= 0 {
+		cfg.FrameDuration = DefaultJitterFrameDuration
+	}
+	if cfg.TargetDelay <= 0 {
+		cfg.TargetDelay = DefaultJitterTargetDelay
+	}
+	if cfg.MaxDelay < cfg.TargetDelay {
+		cfg.MaxDelay = DefaultJitterMaxDelay
+		if cfg.MaxDelay < cfg.TargetDelay {
+			cfg.MaxDelay = cfg.TargetDelay
+		}
+	}
+	if cfg.ClockRate <= 0 {
+		cfg.ClockRate = 8000
+	}
+	if cfg.MaxPLCFrames <= 0 {
+		cfg.MaxPLCFrames = DefaultJitterMaxPLCFrames
+	}
+	return &JitterBuffer{
+		cfg:    cfg,
+		frames: make(map[uint16][]byte),
+	}
+}
+
+// seqBefore reports whether a precedes b, accounting for 16-bit wraparound
+func seqBefore(a, b uint16) bool {
+	return a != b && b-a < 0x8000
+}
+
+// Push adds a decoded frame with its RTP sequence number and timestamp
+func (jb *JitterBuffer) Push(seq uint16, timestamp uint32, frame []byte) {
+	jb.PushAt(seq, timestamp, frame, time.Now())
+}
+
+// PushAt is Push with an explicit arrival time
+func (jb *JitterBuffer) PushAt(seq uint16, timestamp uint32, frame []byte, arrival time.Time) {
+	if len(frame) == 0 {
+		return
+	}
+
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	jb.stats.Received++
+	jb.updateJitter(timestamp, arrival)
+
+	if !jb.started {
+		jb.nextSeq = seq
+		jb.started = true
+	}
+	// Before the first playout, earlier sequence numbers are reordered packets;
+	// afterwards they have already been played out or concealed
+	if seqBefore(seq, jb.nextSeq) && jb.lastFrame != nil {
+		jb.stats.Late++
+		return
+	}
+	if _, ok := jb.frames[seq]; ok {
+		jb.stats.Duplicates++
+		return
+	}
+	jb.frames[seq] = frame
+
+	// Keep the buffer under MaxDelay by skipping the oldest frames
+	maxFrames := jb.framesFor(jb.cfg.MaxDelay)
+	if len(jb.frames) > maxFrames && jb.lastFrame == nil {
+		jb.skipToOldest()
+	}
+	for len(jb.frames) > maxFrames {
+		if _, ok := jb.frames[jb.nextSeq]; ok {
+			delete(jb.frames, jb.nextSeq)
+			jb.stats.Dropped++
+		}
+		jb.nextSeq++
+	}
+}
+
+// updateJitter updates the interarrival jitter estimate
+func (jb *JitterBuffer) updateJitter(timestamp uint32, arrival time.Time) {
+	if !jb.lastArrival.IsZero() {
+		transit := arrival.Sub(jb.lastArrival).Seconds()
+		expected := float64(int32(timestamp-jb.lastTimestamp)) / float64(jb.cfg.ClockRate)
+		d := math.Abs(transit - expected)
+		jb.jitter += (d - jb.jitter) / 16
+	}
+	jb.lastArrival = arrival
+	jb.lastTimestamp = timestamp
+}
+
+// framesFor converts a duration into a whole number of frames (at least one)
+func (jb *JitterBuffer) framesFor(d time.Duration) int {
+	n := int((d + jb.cfg.FrameDuration - 1) / jb.cfg.FrameDuration)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// targetDelay is the configured target delay raised to cover the measured jitter
+func (jb *JitterBuffer) targetDelay() time.Duration {
+	delay := jb.cfg.TargetDelay
+	if adaptive := time.Duration(3 * jb.jitter * float64(time.Second)); adaptive > delay {
+		delay = adaptive
+	}
+	if delay > jb.cfg.MaxDelay {
+		delay = jb.cfg.MaxDelay
+	}
+	return delay
+}
+
+// Pop returns the next frame for playout. It returns false while the buffer is
+// prebuffering; once playing, missing frames are concealed until MaxPLCFrames
+// consecutive losses, after which the buffer re-buffers.
+func (jb *JitterBuffer) Pop() ([]byte, bool) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	if !jb.playing {
+		if len(jb.frames) == 0 || len(jb.frames) < jb.framesFor(jb.targetDelay()) {
+			return nil, false
+		}
+		// start from the oldest frame, reordered packets may have arrived before it
+		jb.skipToOldest()
+		jb.playing = true
+		jb.plcCount = 0
+	}
+
+	if frame, ok := jb.frames[jb.nextSeq]; ok {
+		delete(jb.frames, jb.nextSeq)
+		jb.nextSeq++
+		jb.lastFrame = frame
+		jb.plcCount = 0
+		jb.stats.Played++
+		return frame, true
+	}
+
+	if jb.lastFrame == nil || jb.plcCount >= jb.cfg.MaxPLCFrames {
+		// stream stalled (or ended): stop and wait for the buffer to refill
+		jb.playing = false
+		jb.stats.Underruns++
+		return nil, false
+	}
+
+	jb.plcCount++
+	jb.nextSeq++
+	jb.stats.Concealed++
+	return concealFrame(jb.lastFrame, jb.plcCount), true
+}
+
+// skipToOldest moves the playout position to the oldest buffered frame
+func (jb *JitterBuffer) skipToOldest() {
+	oldest, first := uint16(0), true
+	for seq := range jb.frames {
+		if first || seqBefore(seq, oldest) {
+			oldest, first = seq, false
+		}
+	}
+	jb.nextSeq = oldest
+}
+
+// concealFrame repeats a 16-bit PCM frame with gain halving on each consecutive loss
+func concealFrame(last []byte, lossCount int) []byte {
+	gain := math.Pow(0.5, float64(lossCount))
+	out := make([]byte, len(last))
+	for i := 0; i+1 < len(last); i += 2 {
+		sample := int16(uint16(last[i]) | uint16(last[i+1])<<8)
+		scaled := int16(float64(sample) * gain)
+		out[i] = byte(scaled)
+		out[i+1] = byte(uint16(scaled) >> 8)
+	}
+	return out
+}
+
+// Drain pops a frame every FrameDuration and passes it to sink until ctx is done
+func (jb *JitterBuffer) Drain(ctx context.Context, sink func(frame []byte)) {
+	ticker := time.NewTicker(jb.cfg.FrameDuration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if frame, ok := jb.Pop(); ok {
+				sink(frame)
+			}
+		}
+	}
+}
+
+// Reset discards all buffered frames and restarts prebuffering
+func (jb *JitterBuffer) Reset() {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+	jb.frames = make(map[uint16][]byte)
+	jb.started = false
+	jb.playing = false
+	jb.lastFrame = nil
+	jb.plcCount = 0
+	jb.lastArrival = time.Time{}
+	jb.jitter = 0
+}
+
+// Stats returns a snapshot of the buffer counters
+func (jb *JitterBuffer) Stats() JitterBufferStats {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+	stats := jb.stats
+	stats.Jitter = time.Duration(jb.jitter * float64(time.Second))
+	stats.Delay = jb.targetDelay()
+	stats.Buffered = len(jb.frames)
+	return stats
+}
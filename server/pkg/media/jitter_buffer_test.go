@@ -0,0 +1,129 @@
+package media
+
+import (
+	"testing"
+	"time"
+)
+
+func pcmFrame(value int16, samples int) []byte {
+	frame := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		frame[2*i] = byte(value)
+		frame[2*i+1] = byte(uint16(value) >> 8)
+	}
+	return frame
+}
+
+func frameValue(frame []byte) int16 {
+	return int16(uint16(frame[0]) | uint16(frame[1])<<8)
+}
+
+func TestJitterBufferReorder(t *testing.T) {
+	jb := NewJitterBuffer(JitterBufferConfig{TargetDelay: 60 * time.Millisecond})
+
+	// 3 帧乱序到达，满足目标延迟后按序号播放
+	for _, seq := range []uint16{11, 10, 12} {
+		jb.Push(seq, uint32(seq)*160, pcmFrame(int16(seq), 160))
+	}
+	for _, want := range []int16{10, 11, 12} {
+		frame, ok := jb.Pop()
+		if !ok {
+			t.Fatalf("expected frame %d", want)
+		}
+		if got := frameValue(frame); got != want {
+			t.Fatalf("got frame %d, want %d", got, want)
+		}
+	}
+
+	// 已播放的序号再到达视为迟到
+	jb.Push(11, 11*160, pcmFrame(11, 160))
+	if stats := jb.Stats(); stats.Late != 1 || stats.Played != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestJitterBufferPrebuffer(t *testing.T) {
+	jb := NewJitterBuffer(JitterBufferConfig{TargetDelay: 60 * time.Millisecond})
+
+	jb.Push(1, 160, pcmFrame(1, 160))
+	jb.Push(2, 320, pcmFrame(2, 160))
+	if _, ok := jb.Pop(); ok {
+		t.Fatal("expected prebuffering before target delay is reached")
+	}
+	jb.Push(3, 480, pcmFrame(3, 160))
+	if _, ok := jb.Pop(); !ok {
+		t.Fatal("expected playout once target delay is buffered")
+	}
+}
+
+func TestJitterBufferConcealment(t *testing.T) {
+	jb := NewJitterBuffer(JitterBufferConfig{TargetDelay: 20 * time.Millisecond, MaxPLCFrames: 2})
+
+	jb.Push(1, 160, pcmFrame(1000, 160))
+	jb.Push(3, 480, pcmFrame(3000, 160))
+
+	frame, _ := jb.Pop()
+	if frameValue(frame) != 1000 {
+		t.Fatalf("expected first frame, got %d", frameValue(frame))
+	}
+	// 序号 2 丢失，用衰减后的上一帧补偿
+	frame, ok := jb.Pop()
+	if !ok || frameValue(frame) != 500 {
+		t.Fatalf("expected concealed frame at half gain, got %v %d", ok, frameValue(frame))
+	}
+	frame, _ = jb.Pop()
+	if frameValue(frame) != 3000 {
+		t.Fatalf("expected frame 3 after concealment, got %d", frameValue(frame))
+	}
+
+	// 连续丢包超过 MaxPLCFrames 后重新缓冲
+	jb.Pop()
+	jb.Pop()
+	if _, ok := jb.Pop(); ok {
+		t.Fatal("expected underrun after MaxPLCFrames concealed frames")
+	}
+	stats := jb.Stats()
+	if stats.Concealed != 3 || stats.Underruns != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestJitterBufferAdaptiveDelay(t *testing.T) {
+	jb := NewJitterBuffer(JitterBufferConfig{TargetDelay: 40 * time.Millisecond, MaxDelay: 200 * time.Millisecond})
+
+	// 20ms 的包交替提前/延后 30ms 到达
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		offset := 30 * time.Millisecond
+		if i%2 == 0 {
+			offset = 0
+		}
+		arrival := start.Add(time.Duration(i)*20*time.Millisecond + offset)
+		jb.PushAt(uint16(i), uint32(i*160), pcmFrame(1, 160), arrival)
+		jb.Pop()
+	}
+	stats := jb.Stats()
+	if stats.Jitter < 20*time.Millisecond {
+		t.Errorf("expected jitter estimate near 30ms, got %v", stats.Jitter)
+	}
+	if stats.Delay <= 40*time.Millisecond || stats.Delay > 200*time.Millisecond {
+		t.Errorf("expected adaptive delay above target and capped, got %v", stats.Delay)
+	}
+}
+
+func TestJitterBufferMaxDelay(t *testing.T) {
+	jb := NewJitterBuffer(JitterBufferConfig{TargetDelay: 20 * time.Millisecond, MaxDelay: 60 * time.Millisecond})
+
+	for seq := uint16(65534); seq != 4; seq++ {
+		jb.Push(seq, uint32(seq)*160, pcmFrame(int16(seq), 160))
+	}
+	stats := jb.Stats()
+	if stats.Buffered != 3 || stats.Dropped != 3 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	// 跨越 16 位序号回绕后仍从最旧的帧开始
+	frame, _ := jb.Pop()
+	if got := frameValue(frame); got != 1 {
+		t.Errorf("expected frame 1 after trimming, got %d", got)
+	}
+}
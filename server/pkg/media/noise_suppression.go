@@ -0,0 +1,249 @@
+package media
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// NoiseSuppressor removes background noise from 16-bit little-endian PCM.
+// Implementations may delay the signal but must return as many bytes as
+// they are given, so they can sit in front of a fixed-frame encoder.
+type NoiseSuppressor interface {
+	Process(pcm []byte) []byte
+	Reset()
+}
+
+// Noise suppression defaults
+const (
+	DefaultNoiseOverSubtraction = 2.0
+	DefaultNoiseSpectralFloor   = 0.05
+
+	// noiseRiseRate lets the per-bin noise floor climb ~0.8 dB/s so it
+	// follows a fan speeding up without latching onto speech
+	noiseRiseRate = 1.003
+	// noiseBias compensates for minimum tracking underestimating the mean
+	// noise power
+	noiseBias = 1.5
+	// powerSmoothing is the per-frame weight of the previous smoothed power
+	powerSmoothing = 0.9
+	// noiseInitFrames is how many frames are averaged for the initial noise
+	// estimate, the caller is expected to start with a moment of background
+	noiseInitFrames = 16
+	// gainSmoothing averages each bin's gain with the previous frame to
+	// reduce "musical noise"
+	gainSmoothing = 0.5
+)
+
+// NoiseSuppressionConfig configures a SpectralSubtraction suppressor
+type NoiseSuppressionConfig struct {
+	SampleRate int // PCM sample rate, default 16000
+	// OverSubtraction scales the noise estimate before it is subtracted;
+	// higher removes more noise and more speech
+	OverSubtraction float64
+	// SpectralFloor is the smallest gain applied to a bin (0-1), keeping a
+	// little residual noise sounds more natural than silence
+	SpectralFloor float64
+}
+
+// SpectralSubtraction is a NoiseSuppressor that estimates the stationary
+// noise spectrum by minimum tracking and subtracts it in the STFT domain
+// (sqrt-Hann windows, 50% overlap). It adds one hop (16ms at 16kHz) of delay
+// and is not safe for concurrent use.
+type SpectralSubtraction struct {
+	cfg    NoiseSuppressionConfig
+	size   int // FFT size
+	hop    int
+	window []float64
+
+	input  []float64 // analysis buffer, last size samples
+	filled int       // new samples since the last frame
+	output []float64 // overlap-add accumulator
+	ready  []float64 // synthesized samples not yet returned
+
+	smoothed []float64 // smoothed power spectrum
+	noise    []float64 // noise power estimate
+	gains    []float64
+	frames   int // frames processed, saturates at noiseInitFrames
+	spectrum []complex128
+}
+
+// NewSpectralSubtraction creates a spectral subtraction suppressor, filling
+// in defaults for zero config values
+func NewSpectralSubtraction(cfg NoiseSuppressionConfig) *SpectralSubtraction {
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = 16000
+	}
+	if cfg.OverSubtraction <= 0 {
+		cfg.OverSubtraction = DefaultNoiseOverSubtraction
+	}
+	if cfg.SpectralFloor <= 0 || cfg.SpectralFloor > 1 {
+		cfg.SpectralFloor = DefaultNoiseSpectralFloor
+	}
+
+	// ~32ms frames: 256 at 8kHz, 512 at 16kHz, 2048 at 48kHz
+	size := 1
+	for size < cfg.SampleRate/32 {
+		size <<= 1
+	}
+	window := make([]float64, size)
+	for i := range window {
+		window[i] = math.Sqrt(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size)))
+	}
+	ns := &SpectralSubtraction{
+		cfg:    cfg,
+		size:   size,
+		hop:    size / 2,
+		window: window,
+	}
+	ns.Reset()
+	return ns
+}
+
+// Reset clears the buffers and the noise estimate
+func (ns *SpectralSubtraction) Reset() {
+	bins := ns.size/2 + 1
+	ns.input = make([]float64, ns.size)
+	ns.output = make([]float64, ns.size)
+	// Prime with one hop of silence so every call can return a full buffer
+	ns.ready = make([]float64, ns.hop)
+	ns.filled = 0
+	ns.smoothed = make([]float64, bins)
+	ns.noise = make([]float64, bins)
+	ns.gains = make([]float64, bins)
+	ns.spectrum = make([]complex128, ns.size)
+	ns.frames = 0
+}
+
+// Process returns denoised PCM of the same length as pcm
+func (ns *SpectralSubtraction) Process(pcm []byte) []byte {
+	n := len(pcm) / 2
+	for i := 0; i < n; i++ {
+		copy(ns.input, ns.input[1:])
+		ns.input[ns.size-1] = float64(int16(uint16(pcm[2*i]) | uint16(pcm[2*i+1])<<8))
+		ns.filled++
+		if ns.filled == ns.hop {
+			ns.filled = 0
+			ns.processFrame()
+		}
+	}
+
+	out := make([]byte, n*2)
+	for i := 0; i < n && i < len(ns.ready); i++ {
+		v := int16(math.Max(-32768, math.Min(32767, ns.ready[i])))
+		out[2*i] = byte(v)
+		out[2*i+1] = byte(uint16(v) >> 8)
+	}
+	if n < len(ns.ready) {
+		ns.ready = ns.ready[n:]
+	} else {
+		ns.ready = ns.ready[:0]
+	}
+	return out
+}
+
+// processFrame denoises the current analysis window and overlap-adds one hop
+// of output
+func (ns *SpectralSubtraction) processFrame() {
+	for i, x := range ns.input {
+		ns.spectrum[i] = complex(x*ns.window[i], 0)
+	}
+	fft(ns.spectrum, false)
+
+	bins := len(ns.noise)
+	for k := 0; k < bins; k++ {
+		power := real(ns.spectrum[k])*real(ns.spectrum[k]) + imag(ns.spectrum[k])*imag(ns.spectrum[k])
+		if ns.frames < noiseInitFrames {
+			// Average the first frames, then switch to minimum tracking
+			ns.smoothed[k] += (power - ns.smoothed[k]) / float64(ns.frames+1)
+			ns.noise[k] = ns.smoothed[k]
+			if ns.frames == 0 {
+				ns.gains[k] = 1
+			}
+		} else {
+			ns.smoothed[k] = powerSmoothing*ns.smoothed[k] + (1-powerSmoothing)*power
+			// Drop to the smoothed power immediately, rise slowly
+			if ns.smoothed[k] < ns.noise[k] {
+				ns.noise[k] = ns.smoothed[k]
+			} else {
+				ns.noise[k] *= noiseRiseRate
+			}
+		}
+
+		gain := ns.cfg.SpectralFloor
+		if power > 0 {
+			gain = math.Sqrt(math.Max(1-ns.cfg.OverSubtraction*noiseBias*ns.noise[k]/power, ns.cfg.SpectralFloor*ns.cfg.SpectralFloor))
+		}
+		ns.gains[k] = gainSmoothing*ns.gains[k] + (1-gainSmoothing)*gain
+
+		ns.spectrum[k] *= complex(ns.gains[k], 0)
+		if k > 0 && k < ns.size-k {
+			ns.spectrum[ns.size-k] = cmplx.Conj(ns.spectrum[k])
+		}
+	}
+	if ns.frames < noiseInitFrames {
+		ns.frames++
+	}
+	fft(ns.spectrum, true)
+
+	for i := range ns.output {
+		ns.output[i] += real(ns.spectrum[i]) * ns.window[i]
+	}
+	ns.ready = append(ns.ready, ns.output[:ns.hop]...)
+	copy(ns.output, ns.output[ns.hop:])
+	for i := ns.size - ns.hop; i < ns.size; i++ {
+		ns.output[i] = 0
+	}
+}
+
+// fft is an in-place radix-2 FFT; len(x) must be a power of two. The inverse
+// transform is scaled by 1/len(x).
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for length := 2; length <= n; length <<= 1 {
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(length))
+		for start := 0; start < n; start += length {
+			w := complex(1, 0)
+			for k := 0; k < length/2; k++ {
+				u := x[start+k]
+				v := x[start+k+length/2] * w
+				x[start+k] = u + v
+				x[start+k+length/2] = u - v
+				w *= step
+			}
+		}
+	}
+	if inverse {
+		scale := complex(1/float64(n), 0)
+		for i := range x {
+			x[i] *= scale
+		}
+	}
+}
+
+// NoiseSuppressionFilter adapts a NoiseSuppressor to an EncoderFunc so it can
+// be chained in front of an encoder; enabled is checked on every packet, so
+// suppression can be toggled per session while audio is flowing
+func NoiseSuppressionFilter(ns NoiseSuppressor, enabled func() bool) EncoderFunc {
+	return func(packet MediaPacket) ([]MediaPacket, error) {
+		audioPacket, ok := packet.(*AudioPacket)
+		if !ok || (enabled != nil && !enabled()) {
+			return []MediaPacket{packet}, nil
+		}
+		audioPacket.Payload = ns.Process(audioPacket.Payload)
+		return []MediaPacket{audioPacket}, nil
+	}
+}
@@ -0,0 +1,87 @@
+package media
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// noiseFrame generates 20ms of 16kHz white noise
+func noiseFrame(rng *rand.Rand, amplitude float64) []byte {
+	frame := make([]byte, 640)
+	for i := 0; i < 320; i++ {
+		v := int16(rng.NormFloat64() * amplitude)
+		frame[2*i] = byte(v)
+		frame[2*i+1] = byte(uint16(v) >> 8)
+	}
+	return frame
+}
+
+func addFrames(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := 0; i+1 < len(a); i += 2 {
+		v := int32(int16(uint16(a[i])|uint16(a[i+1])<<8)) + int32(int16(uint16(b[i])|uint16(b[i+1])<<8))
+		s := int16(max(-32768, min(32767, v)))
+		out[i] = byte(s)
+		out[i+1] = byte(uint16(s) >> 8)
+	}
+	return out
+}
+
+func TestSpectralSubtractionReducesNoise(t *testing.T) {
+	ns := NewSpectralSubtraction(NoiseSuppressionConfig{})
+	rng := rand.New(rand.NewSource(1))
+	var in, out float64
+	for i := 0; i < 150; i++ {
+		frame := noiseFrame(rng, 1000)
+		processed := ns.Process(frame)
+		if len(processed) != len(frame) {
+			t.Fatalf("output length %d, want %d", len(processed), len(frame))
+		}
+		// 前 1 秒用于噪声估计收敛
+		if i >= 50 {
+			in += frameRMS(frame)
+			out += frameRMS(processed)
+		}
+	}
+	if reduction := 20 * math.Log10(in/out); reduction < 10 {
+		t.Errorf("expected at least 10 dB noise reduction, got %.1f dB", reduction)
+	}
+}
+
+func TestSpectralSubtractionKeepsTone(t *testing.T) {
+	ns := NewSpectralSubtraction(NoiseSuppressionConfig{})
+	rng := rand.New(rand.NewSource(2))
+	// 先喂 1 秒纯噪声，再叠加 440Hz 正弦
+	for i := 0; i < 50; i++ {
+		ns.Process(noiseFrame(rng, 300))
+	}
+	var out float64
+	for i := 0; i < 25; i++ {
+		processed := ns.Process(addFrames(sineFrame(5000, i*320), noiseFrame(rng, 300)))
+		if i >= 5 {
+			out += frameRMS(processed)
+		}
+	}
+	tone := 5000 / math.Sqrt2
+	if avg := out / 20; math.Abs(avg-tone) > 0.1*tone {
+		t.Errorf("expected tone RMS near %.0f, got %.0f", tone, avg)
+	}
+}
+
+func TestNoiseSuppressionFilterToggle(t *testing.T) {
+	ns := NewSpectralSubtraction(NoiseSuppressionConfig{})
+	enabled := false
+	filter := NoiseSuppressionFilter(ns, func() bool { return enabled })
+
+	frame := sineFrame(1000, 0)
+	packets, err := filter(&AudioPacket{Payload: frame})
+	if err != nil || len(packets) != 1 || &packets[0].(*AudioPacket).Payload[0] != &frame[0] {
+		t.Fatalf("disabled filter should pass the packet through")
+	}
+	enabled = true
+	packets, err = filter(&AudioPacket{Payload: frame})
+	if err != nil || len(packets) != 1 || len(packets[0].(*AudioPacket).Payload) != len(frame) {
+		t.Fatalf("enabled filter: %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPipeline is wrapped by every AudioPipeline.Build validation error
+var ErrInvalidPipeline = errors.New("invalid audio pipeline")
+
+// CodecBuilder creates a codec encoder or decoder; codec is the compressed
+// side and pcm the raw side, matching the encoder package factories
+type CodecBuilder func(codec, pcm CodecConfig) (EncoderFunc, error)
+
+var codecEncoderBuilder, codecDecoderBuilder CodecBuilder
+
+// SetCodecBuilders registers the codec factories used by AudioPipeline.
+// The encoder package calls it from init, so importing that package is
+// enough to make its codecs available to pipelines.
+func SetCodecBuilders(encode, decode CodecBuilder) {
+	codecEncoderBuilder = encode
+	codecDecoderBuilder = decode
+}
+
+// pcmFormat is the 16-bit PCM format flowing between pipeline stages
+type pcmFormat struct {
+	sampleRate int
+	channels   int
+}
+
+type pipelineStep struct {
+	name  string
+	build func() (EncoderFunc, error)
+}
+
+// AudioPipeline builds a chain of audio stages as a single EncoderFunc, e.g.
+//
+//	media.NewPipeline().Decode(pcma).Resample(16000).Mono().Encode(opus).Build()
+//
+// Every stage states the format it produces, so unlike the encoder package
+// factories there is no src/pcm pair to get backwards: Decode turns the codec
+// into PCM at the codec's own rate and channels, Resample and Mono change the
+// PCM format, and Encode compresses whatever PCM reaches it. A pipeline that
+// does not start with Decode must declare its PCM input with Input.
+//
+// The builder records the first mistake and Build reports it, so calls can be
+// chained without checking errors in between.
+type AudioPipeline struct {
+	steps   []pipelineStep
+	format  pcmFormat
+	started bool // Input or Decode seen
+	encoded bool // Encode seen, nothing may follow
+	err     error
+}
+
+// NewPipeline creates an empty pipeline
+func NewPipeline() *AudioPipeline {
+	return &AudioPipeline{}
+}
+
+func (p *AudioPipeline) fail(format string, args ...any) *AudioPipeline {
+	if p.err == nil {
+		p.err = fmt.Errorf("%w: %s", ErrInvalidPipeline, fmt.Sprintf(format, args...))
+	}
+	return p
+}
+
+// checkStage validates that a PCM stage can be appended
+func (p *AudioPipeline) checkStage(name string) bool {
+	switch {
+	case p.err != nil:
+		return false
+	case p.encoded:
+		p.fail("%s after Encode", name)
+		return false
+	case !p.started:
+		p.fail("%s before Input or Decode, the PCM format is unknown", name)
+		return false
+	}
+	return true
+}
+
+// Input declares the PCM format entering a pipeline that does not decode
+func (p *AudioPipeline) Input(sampleRate, channels int) *AudioPipeline {
+	switch {
+	case p.err != nil:
+		return p
+	case p.started:
+		return p.fail("Input must be the first stage")
+	case sampleRate <= 0 || channels <= 0:
+		return p.fail("invalid input format %dHz/%d channels", sampleRate, channels)
+	}
+	p.started = true
+	p.format = pcmFormat{sampleRate: sampleRate, channels: channels}
+	return p
+}
+
+// Decode decodes codec packets into PCM at codec.SampleRate with
+// codec.Channels (default 1); it must be the first stage
+func (p *AudioPipeline) Decode(codec CodecConfig) *AudioPipeline {
+	switch {
+	case p.err != nil:
+		return p
+	case p.started:
+		return p.fail("Decode must be the first stage")
+	case codec.SampleRate <= 0:
+		return p.fail("decode %s: sample rate is required", codec.Codec)
+	}
+	if codec.Channels <= 0 {
+		codec.Channels = 1
+	}
+	p.started = true
+	p.format = pcmFormat{sampleRate: codec.SampleRate, channels: codec.Channels}
+	pcm := p.pcmConfig(codec.FrameDuration)
+	p.steps = append(p.steps, pipelineStep{
+		name: "decode " + strings.ToLower(codec.Codec),
+		build: func() (EncoderFunc, error) {
+			if codecDecoderBuilder == nil {
+				return nil, ErrCodecNotSupported
+			}
+			return codecDecoderBuilder(codec, pcm)
+		},
+	})
+	return p
+}
+
+// Resample converts the PCM to sampleRate; it is a no-op if the rate
+// already matches
+func (p *AudioPipeline) Resample(sampleRate int) *AudioPipeline {
+	if !p.checkStage("Resample") {
+		return p
+	}
+	if sampleRate <= 0 {
+		return p.fail("invalid resample rate %d", sampleRate)
+	}
+	if sampleRate == p.format.sampleRate {
+		return p
+	}
+	from, channels := p.format.sampleRate, p.format.channels
+	if channels != 1 {
+		return p.fail("Resample needs mono PCM, add Mono first")
+	}
+	p.format.sampleRate = sampleRate
+	p.steps = append(p.steps, pipelineStep{
+		name: fmt.Sprintf("resample %d->%d", from, sampleRate),
+		build: func() (EncoderFunc, error) {
+			res := DefaultResampler(from, sampleRate)
+			return func(packet MediaPacket) ([]MediaPacket, error) {
+				audioPacket, ok := packet.(*AudioPacket)
+				if !ok {
+					return []MediaPacket{packet}, nil
+				}
+				if _, err := res.Write(audioPacket.Payload); err != nil {
+					return nil, err
+				}
+				data := res.Samples()
+				if len(data) == 0 {
+					return nil, nil
+				}
+				audioPacket.Payload = data
+				return []MediaPacket{audioPacket}, nil
+			}, nil
+		},
+	})
+	return p
+}
+
+// Mono averages interleaved channels down to one; it is a no-op for mono PCM
+func (p *AudioPipeline) Mono() *AudioPipeline {
+	if !p.checkStage("Mono") {
+		return p
+	}
+	channels := p.format.channels
+	if channels == 1 {
+		return p
+	}
+	p.format.channels = 1
+	p.steps = append(p.steps, pipelineStep{
+		name: fmt.Sprintf("mono from %d channels", channels),
+		build: func() (EncoderFunc, error) {
+			return func(packet MediaPacket) ([]MediaPacket, error) {
+				audioPacket, ok := packet.(*AudioPacket)
+				if !ok {
+					return []MediaPacket{packet}, nil
+				}
+				// The mono frame is shorter than its source, so downmix in place
+				data := audioPacket.Payload
+				frames := len(data) / (2 * channels)
+				for i := 0; i < frames; i++ {
+					sum := 0
+					for ch := 0; ch < channels; ch++ {
+						j := 2 * (i*channels + ch)
+						sum += int(int16(uint16(data[j]) | uint16(data[j+1])<<8))
+					}
+					v := int16(sum / channels)
+					data[2*i] = byte(v)
+					data[2*i+1] = byte(uint16(v) >> 8)
+				}
+				audioPacket.Payload = data[:2*frames]
+				return []MediaPacket{audioPacket}, nil
+			}, nil
+		},
+	})
+	return p
+}
+
+// Filter runs fn over the PCM, for processors such as a NoiseSuppressor or
+// AGC; fn may modify pcm in place and must return the data to pass on
+func (p *AudioPipeline) Filter(name string, fn func(pcm []byte) []byte) *AudioPipeline {
+	if !p.checkStage("Filter " + name) {
+		return p
+	}
+	p.steps = append(p.steps, pipelineStep{
+		name: name,
+		build: func() (EncoderFunc, error) {
+			return func(packet MediaPacket) ([]MediaPacket, error) {
+				audioPacket, ok := packet.(*AudioPacket)
+				if !ok {
+					return []MediaPacket{packet}, nil
+				}
+				audioPacket.Payload = fn(audioPacket.Payload)
+				return []MediaPacket{audioPacket}, nil
+			}, nil
+		},
+	})
+	return p
+}
+
+// Encode compresses the PCM with codec, resampling to codec.SampleRate if
+// needed; it must be the last stage
+func (p *AudioPipeline) Encode(codec CodecConfig) *AudioPipeline {
+	if !p.checkStage("Encode") {
+		return p
+	}
+	if codec.Channels <= 0 {
+		codec.Channels = 1
+	}
+	if codec.Channels != p.format.channels {
+		return p.fail("encode %s: %d channel PCM for a %d channel codec, add Mono first",
+			codec.Codec, p.format.channels, codec.Channels)
+	}
+	p.encoded = true
+	pcm := p.pcmConfig(codec.FrameDuration)
+	p.steps = append(p.steps, pipelineStep{
+		name: "encode " + strings.ToLower(codec.Codec),
+		build: func() (EncoderFunc, error) {
+			if codecEncoderBuilder == nil {
+				return nil, ErrCodecNotSupported
+			}
+			return codecEncoderBuilder(codec, pcm)
+		},
+	})
+	return p
+}
+
+// Format returns the PCM sample rate and channel count at the current end of
+// the pipeline, i.e. what the next stage will receive
+func (p *AudioPipeline) Format() (sampleRate, channels int) {
+	return p.format.sampleRate, p.format.channels
+}
+
+func (p *AudioPipeline) pcmConfig(frameDuration string) CodecConfig {
+	return CodecConfig{
+		Codec:         "pcm",
+		SampleRate:    p.format.sampleRate,
+		Channels:      p.format.channels,
+		BitDepth:      16,
+		FrameDuration: frameDuration,
+	}
+}
+
+// Build validates the pipeline and returns it as one EncoderFunc. Each call
+// creates fresh codec and resampler state, so a pipeline description can be
+// built once per stream. The returned function is not safe for concurrent use.
+func (p *AudioPipeline) Build() (EncoderFunc, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if len(p.steps) == 0 {
+		return nil, fmt.Errorf("%w: no stages", ErrInvalidPipeline)
+	}
+
+	stages := make([]EncoderFunc, len(p.steps))
+	names := make([]string, len(p.steps))
+	for i, step := range p.steps {
+		fn, err := step.build()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", step.name, err)
+		}
+		stages[i], names[i] = fn, step.name
+	}
+	if len(stages) == 1 {
+		return stages[0], nil
+	}
+
+	// Intermediate packet lists are reused between calls; only the final
+	// list is handed to the caller
+	current := make([]MediaPacket, 0, 4)
+	next := make([]MediaPacket, 0, 4)
+	return func(packet MediaPacket) ([]MediaPacket, error) {
+		current = append(current[:0], packet)
+		for i, stage := range stages {
+			next = next[:0]
+			for _, pkt := range current {
+				out, err := stage(pkt)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", names[i], err)
+				}
+				next = append(next, out...)
+			}
+			current, next = next, current
+			if len(current) == 0 {
+				return nil, nil
+			}
+		}
+		return append([]MediaPacket(nil), current...), nil
+	}, nil
+}
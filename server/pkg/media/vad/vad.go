@@ -0,0 +1,232 @@
+// Package vad implements voice activity detection on 16-bit little-endian PCM.
+//
+// Each frame is classified by combining an absolute energy floor with the
+// frame's SNR against an adaptive noise estimate and its zero-crossing rate,
+// similar in spirit to WebRTC VAD's aggressiveness modes. A hangover state
+// machine turns per-frame decisions into speech start/end events.
+package vad
+
+import (
+	"math"
+	"time"
+)
+
+// Mode controls how aggressively non-speech is rejected, like WebRTC VAD modes 0-3
+type Mode int
+
+const (
+	ModeQuality        Mode = iota // least aggressive, most frames reported as speech
+	ModeLowBitrate                 // moderate
+	ModeAggressive                 // default
+	ModeVeryAggressive             // most aggressive, only clear speech
+)
+
+// modeSNR is the SNR (dB above the noise floor) a frame needs to count as speech
+var modeSNR = map[Mode]float64{
+	ModeQuality:        3,
+	ModeLowBitrate:     6,
+	ModeAggressive:     9,
+	ModeVeryAggressive: 12,
+}
+
+// Event is a speech state transition reported by Process
+type Event int
+
+const (
+	EventNone        Event = iota
+	EventSpeechStart       // speech began after StartFrames consecutive speech frames
+	EventSpeechEnd         // speech ended after EndFrames consecutive silent frames
+)
+
+func (e Event) String() string {
+	switch e {
+	case EventSpeechStart:
+		return "speech_start"
+	case EventSpeechEnd:
+		return "speech_end"
+	default:
+		return "none"
+	}
+}
+
+const (
+	DefaultSampleRate    = 16000
+	DefaultFrameDuration = 20 * time.Millisecond
+	DefaultStartFrames   = 3
+	DefaultEndFrames     = 15
+
+	// maxZCR rejects frames dominated by broadband noise (hiss, clicks)
+	maxZCR = 0.45
+	// noise floor never drops below this (RMS), avoids infinite SNR on digital silence
+	minNoiseRMS = 10.0
+)
+
+// Config configures a VAD
+type Config struct {
+	SampleRate    int           // PCM sample rate, default 16000
+	FrameDuration time.Duration // analysis frame, default 20ms
+	Mode          Mode          // aggressiveness, default ModeAggressive
+	MinRMS        float64       // absolute energy floor (0-32768), 0 disables it
+	StartFrames   int           // consecutive speech frames before EventSpeechStart
+	EndFrames     int           // consecutive silent frames before EventSpeechEnd
+}
+
+// VAD is a stateful voice activity detector. It is not safe for concurrent use.
+type VAD struct {
+	cfg        Config
+	frameBytes int
+	pending    []byte
+
+	noiseRMS    float64
+	speaking    bool
+	speechRun   int
+	silenceRun  int
+	initialized bool
+}
+
+// New creates a VAD, filling in defaults for zero config values
+func New(cfg Config) *VAD {
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = DefaultSampleRate
+	}
+	if cfg.FrameDuration <= 0 {
+		cfg.FrameDuration = DefaultFrameDuration
+	}
+	if _, ok := modeSNR[cfg.Mode]; !ok {
+		cfg.Mode = ModeAggressive
+	}
+	if cfg.StartFrames <= 0 {
+		cfg.StartFrames = DefaultStartFrames
+	}
+	if cfg.EndFrames <= 0 {
+		cfg.EndFrames = DefaultEndFrames
+	}
+	samples := int(int64(cfg.SampleRate) * int64(cfg.FrameDuration) / int64(time.Second))
+	return &VAD{
+		cfg:        cfg,
+		frameBytes: samples * 2,
+	}
+}
+
+// Process feeds PCM of any length, analysing it in FrameDuration frames. It
+// returns the last state transition seen, or EventNone.
+func (v *VAD) Process(pcm []byte) Event {
+	v.pending = append(v.pending, pcm...)
+	event := EventNone
+	for len(v.pending) >= v.frameBytes {
+		frame := v.pending[:v.frameBytes]
+		if e := v.step(v.IsSpeech(frame)); e != EventNone {
+			event = e
+		}
+		v.pending = v.pending[v.frameBytes:]
+	}
+	if len(v.pending) == 0 {
+		v.pending = nil
+	}
+	return event
+}
+
+// step advances the hangover state machine with one frame decision
+func (v *VAD) step(speech bool) Event {
+	if speech {
+		v.speechRun++
+		v.silenceRun = 0
+		if !v.speaking && v.speechRun >= v.cfg.StartFrames {
+			v.speaking = true
+			return EventSpeechStart
+		}
+		return EventNone
+	}
+	v.silenceRun++
+	v.speechRun = 0
+	if v.speaking && v.silenceRun >= v.cfg.EndFrames {
+		v.speaking = false
+		return EventSpeechEnd
+	}
+	return EventNone
+}
+
+// IsSpeech classifies a single frame and updates the noise estimate
+func (v *VAD) IsSpeech(frame []byte) bool {
+	rms := RMS(frame)
+	if !v.initialized {
+		v.noiseRMS = math.Max(rms, minNoiseRMS)
+		v.initialized = true
+	}
+
+	snr := 20 * math.Log10(math.Max(rms, 1)/v.noiseRMS)
+	speech := snr >= modeSNR[v.cfg.Mode] &&
+		rms >= v.cfg.MinRMS &&
+		ZeroCrossingRate(frame) <= maxZCR
+
+	// The noise floor drops immediately and rises slowly, and only on non-speech frames
+	switch {
+	case rms < v.noiseRMS:
+		v.noiseRMS = math.Max(rms, minNoiseRMS)
+	case !speech:
+		v.noiseRMS = 0.95*v.noiseRMS + 0.05*rms
+	}
+	return speech
+}
+
+// Speaking reports whether the detector is currently in a speech segment
+func (v *VAD) Speaking() bool {
+	return v.speaking
+}
+
+// NoiseRMS returns the current noise floor estimate
+func (v *VAD) NoiseRMS() float64 {
+	return v.noiseRMS
+}
+
+// SetMinRMS changes the absolute energy floor
+func (v *VAD) SetMinRMS(minRMS float64) {
+	v.cfg.MinRMS = minRMS
+}
+
+// SetStartFrames changes how many consecutive speech frames start a segment
+func (v *VAD) SetStartFrames(frames int) {
+	if frames > 0 {
+		v.cfg.StartFrames = frames
+	}
+}
+
+// Reset clears the speech state, keeping the learned noise floor
+func (v *VAD) Reset() {
+	v.pending = nil
+	v.speaking = false
+	v.speechRun = 0
+	v.silenceRun = 0
+}
+
+// RMS returns the root mean square amplitude of 16-bit PCM
+func RMS(pcm []byte) float64 {
+	n := len(pcm) / 2
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i+1 < len(pcm); i += 2 {
+		sample := float64(int16(uint16(pcm[i]) | uint16(pcm[i+1])<<8))
+		sum += sample * sample
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+// ZeroCrossingRate returns the fraction of adjacent 16-bit samples that change sign
+func ZeroCrossingRate(pcm []byte) float64 {
+	n := len(pcm) / 2
+	if n < 2 {
+		return 0
+	}
+	crossings := 0
+	prev := int16(uint16(pcm[0]) | uint16(pcm[1])<<8)
+	for i := 2; i+1 < len(pcm); i += 2 {
+		sample := int16(uint16(pcm[i]) | uint16(pcm[i+1])<<8)
+		if (prev >= 0) != (sample >= 0) {
+			crossings++
+		}
+		prev = sample
+	}
+	return float64(crossings) / float64(n-1)
+}
@@ -0,0 +1,111 @@
+package vad
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const testRate = 16000
+
+// tone generates 20ms of a sine wave at the given amplitude
+func tone(freq, amplitude float64) []byte {
+	samples := testRate / 50
+	pcm := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		v := int16(amplitude * math.Sin(2*math.Pi*freq*float64(i)/testRate))
+		pcm[2*i] = byte(v)
+		pcm[2*i+1] = byte(uint16(v) >> 8)
+	}
+	return pcm
+}
+
+// noise generates 20ms of low-level white noise
+func noise(r *rand.Rand, amplitude float64) []byte {
+	samples := testRate / 50
+	pcm := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		v := int16((r.Float64()*2 - 1) * amplitude)
+		pcm[2*i] = byte(v)
+		pcm[2*i+1] = byte(uint16(v) >> 8)
+	}
+	return pcm
+}
+
+func TestVADSpeechStartAndEnd(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	v := New(Config{SampleRate: testRate, StartFrames: 3, EndFrames: 5})
+
+	// 背景噪声不触发
+	for i := 0; i < 20; i++ {
+		if e := v.Process(noise(r, 100)); e != EventNone {
+			t.Fatalf("noise frame %d produced %v", i, e)
+		}
+	}
+
+	events := []Event{}
+	for i := 0; i < 5; i++ {
+		if e := v.Process(tone(300, 8000)); e != EventNone {
+			events = append(events, e)
+		}
+	}
+	if len(events) != 1 || events[0] != EventSpeechStart || !v.Speaking() {
+		t.Fatalf("expected speech start, got %v", events)
+	}
+
+	for i := 0; i < 5; i++ {
+		if e := v.Process(noise(r, 100)); e != EventNone {
+			events = append(events, e)
+		}
+	}
+	if len(events) != 2 || events[1] != EventSpeechEnd || v.Speaking() {
+		t.Fatalf("expected speech end, got %v", events)
+	}
+}
+
+func TestVADMinRMS(t *testing.T) {
+	v := New(Config{SampleRate: testRate, StartFrames: 1, MinRMS: 5000})
+	v.Process(make([]byte, 640))
+
+	// 低于绝对能量阈值的语音（例如回声）不触发
+	for i := 0; i < 10; i++ {
+		if e := v.Process(tone(300, 2000)); e != EventNone {
+			t.Fatalf("quiet tone produced %v", e)
+		}
+	}
+
+	v = New(Config{SampleRate: testRate, StartFrames: 1, MinRMS: 5000})
+	v.Process(make([]byte, 640))
+	v.SetMinRMS(0)
+	if e := v.Process(tone(300, 2000)); e != EventSpeechStart {
+		t.Fatalf("expected speech start without energy floor, got %v", e)
+	}
+}
+
+func TestVADProcessBuffersPartialFrames(t *testing.T) {
+	v := New(Config{SampleRate: testRate, StartFrames: 2})
+	v.Process(make([]byte, 640))
+
+	frame := tone(300, 8000)
+	speech := append(append([]byte{}, frame...), frame...)
+	// 数据包大小与分析帧不对齐
+	if e := v.Process(speech[:500]); e != EventNone {
+		t.Fatalf("unexpected event %v", e)
+	}
+	if e := v.Process(speech[500:]); e != EventSpeechStart {
+		t.Fatalf("expected speech start once two full frames are seen, got %v", e)
+	}
+}
+
+func TestZeroCrossingRate(t *testing.T) {
+	if zcr := ZeroCrossingRate(tone(300, 8000)); zcr > 0.1 {
+		t.Errorf("low frequency tone zcr too high: %.2f", zcr)
+	}
+	r := rand.New(rand.NewSource(2))
+	if zcr := ZeroCrossingRate(noise(r, 8000)); zcr < maxZCR {
+		t.Errorf("white noise zcr too low: %.2f", zcr)
+	}
+	if rms := RMS(tone(300, 8000)); math.Abs(rms-8000/math.Sqrt2) > 100 {
+		t.Errorf("unexpected tone rms %.1f", rms)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // MonitorAPI 监控API处理器
@@ -45,6 +46,7 @@ func (api *MonitorAPI) RegisterRoutes(r *gin.RouterGroup) {
 	// 指标数据
 	r.GET("/metrics", api.GetMetrics)
 	r.GET("/metrics/prometheus", api.GetPrometheusMetrics)
+	r.GET("/calls", api.GetCallQuality)
 
 	RegisterMonitorUI(r, api)
 }
@@ -261,28 +263,29 @@ func (api *MonitorAPI) GetTraceDetail(c *gin.Context) {
 
 // GetMetrics 获取指标数据
 func (api *MonitorAPI) GetMetrics(c *gin.Context) {
-	if api.monitor.GetMetrics() == nil {
-		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"data":    nil,
-		})
-		return
+	// 通话质量不依赖 Prometheus 指标，始终返回
+	data := map[string]interface{}{
+		"timestamp": time.Now(),
+		"calls":     api.monitor.GetCallQuality(),
 	}
+	if api.monitor.GetMetrics() != nil {
+		data["message"] = "Metrics collection is enabled"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
 
-	// 这里可以返回自定义的指标数据
+// GetCallQuality 获取进行中通话的质量（丢包、抖动、RTT、MOS）
+func (api *MonitorAPI) GetCallQuality(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": map[string]interface{}{
-			"timestamp": time.Now(),
-			"message":   "Metrics collection is enabled",
-		},
+		"data":    api.monitor.GetCallQuality(),
 	})
 }
 
-// GetPrometheusMetrics 获取Prometheus格式的指标
+// GetPrometheusMetrics 获取Prometheus格式的指标，与根路径 /metrics 输出相同
 func (api *MonitorAPI) GetPrometheusMetrics(c *gin.Context) {
-	// 这里应该返回Prometheus格式的指标数据
-	// 由于Prometheus指标是自动注册的，我们只需要返回一个说明
-	c.Header("Content-Type", "text/plain")
-	c.String(http.StatusOK, "# Prometheus metrics are automatically exposed at /metrics endpoint\n# This endpoint is for compatibility only")
+	promhttp.Handler().ServeHTTP(c.Writer, c.Request)
 }
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// CallQuality 单个通话的媒体质量快照（由 WebRTC/SIP 会话周期性上报）
+type CallQuality struct {
+	SessionID       string        `json:"session_id"`
+	Codec           string        `json:"codec"`
+	PacketsReceived uint64        `json:"packets_received"`
+	PacketsLost     int64         `json:"packets_lost"`
+	LossRate        float64       `json:"loss_rate"` // 0-1
+	Jitter          time.Duration `json:"jitter"`
+	RTT             time.Duration `json:"rtt"`
+	RFactor         float64       `json:"r_factor"`
+	MOS             float64       `json:"mos"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// CallQualityTracker 保存进行中通话的最新质量数据
+type CallQualityTracker struct {
+	mu    sync.RWMutex
+	calls map[string]CallQuality
+}
+
+// NewCallQualityTracker 创建通话质量跟踪器
+func NewCallQualityTracker() *CallQualityTracker {
+	return &CallQualityTracker{calls: make(map[string]CallQuality)}
+}
+
+// Update 更新会话的质量快照
+func (t *CallQualityTracker) Update(q CallQuality) {
+	if q.UpdatedAt.IsZero() {
+		q.UpdatedAt = time.Now()
+	}
+	t.mu.Lock()
+	t.calls[q.SessionID] = q
+	t.mu.Unlock()
+}
+
+// Remove 通话结束时移除会话
+func (t *CallQualityTracker) Remove(sessionID string) {
+	t.mu.Lock()
+	delete(t.calls, sessionID)
+	t.mu.Unlock()
+}
+
+// List 按 MOS 升序返回所有会话，质量最差的排在最前
+func (t *CallQualityTracker) List() []CallQuality {
+	t.mu.RLock()
+	calls := make([]CallQuality, 0, len(t.calls))
+	for _, q := range t.calls {
+		calls = append(calls, q)
+	}
+	t.mu.RUnlock()
+	sort.Slice(calls, func(i, j int) bool { return calls[i].MOS < calls[j].MOS })
+	return calls
+}
+
+// Summary 汇总进行中通话的数量与平均质量
+func (t *CallQualityTracker) Summary() map[string]interface{} {
+	calls := t.List()
+	summary := map[string]interface{}{
+		"active_calls": len(calls),
+	}
+	if len(calls) == 0 {
+		return summary
+	}
+	var mos, loss float64
+	var jitter, rtt time.Duration
+	for _, q := range calls {
+		mos += q.MOS
+		loss += q.LossRate
+		jitter += q.Jitter
+		rtt += q.RTT
+	}
+	n := len(calls)
+	summary["avg_mos"] = mos / float64(n)
+	summary["min_mos"] = calls[0].MOS
+	summary["avg_loss_rate"] = loss / float64(n)
+	summary["avg_jitter_ms"] = float64(jitter/time.Duration(n)) / float64(time.Millisecond)
+	summary["avg_rtt_ms"] = float64(rtt/time.Duration(n)) / float64(time.Millisecond)
+	return summary
+}
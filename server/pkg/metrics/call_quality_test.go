@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCallQualityTracker(t *testing.T) {
+	tracker := NewCallQualityTracker()
+
+	summary := tracker.Summary()
+	if summary["active_calls"] != 0 {
+		t.Errorf("Expected 0 active calls, got %v", summary["active_calls"])
+	}
+
+	tracker.Update(CallQuality{SessionID: "a", Codec: "pcma", MOS: 4.4, RTT: 20 * time.Millisecond})
+	tracker.Update(CallQuality{SessionID: "b", Codec: "opus", MOS: 3.2, LossRate: 0.1, RTT: 60 * time.Millisecond})
+
+	calls := tracker.List()
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 calls, got %d", len(calls))
+	}
+	// 质量最差的排在最前
+	if calls[0].SessionID != "b" {
+		t.Errorf("Expected worst call first, got %s", calls[0].SessionID)
+	}
+	if calls[0].UpdatedAt.IsZero() {
+		t.Error("Expected UpdatedAt to be set")
+	}
+
+	summary = tracker.Summary()
+	if summary["min_mos"] != 3.2 {
+		t.Errorf("Expected min_mos 3.2, got %v", summary["min_mos"])
+	}
+	if summary["avg_rtt_ms"] != 40.0 {
+		t.Errorf("Expected avg_rtt_ms 40, got %v", summary["avg_rtt_ms"])
+	}
+
+	tracker.Remove("b")
+	if len(tracker.List()) != 1 {
+		t.Error("Expected call to be removed")
+	}
+}
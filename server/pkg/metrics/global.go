@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"context"
 	"sync"
 )
 
@@ -28,3 +29,20 @@ func IsGlobalMonitorEnabled() bool {
 	monitor := GetGlobalMonitor()
 	return monitor != nil && monitor.IsEnabled()
 }
+
+// StartSpan 使用全局监控器开始跨度，未启用链路追踪时返回原上下文与 nil 跨度。
+// 通过上下文传递的跨度会自动成为父跨度，从而把跨包的调用串成一条链路
+func StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	monitor := GetGlobalMonitor()
+	if monitor == nil {
+		return ctx, nil
+	}
+	return monitor.StartSpan(ctx, name, opts...)
+}
+
+// EndSpan 结束由 StartSpan 返回的跨度，span 为 nil 时忽略
+func EndSpan(span *Span, err error) {
+	if monitor := GetGlobalMonitor(); monitor != nil {
+		monitor.EndSpan(span, err)
+	}
+}
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const gormStartKey = "lingecho:metrics_start"
+
+// GormPlugin 为每条 GORM 语句记录耗时、慢查询与错误，并交给 SQL 分析器
+type GormPlugin struct {
+	monitor *Monitor
+}
+
+// NewGormPlugin 创建 GORM 指标插件，通过 db.Use 注册
+func NewGormPlugin(monitor *Monitor) *GormPlugin {
+	return &GormPlugin{monitor: monitor}
+}
+
+// Name 实现 gorm.Plugin
+func (p *GormPlugin) Name() string {
+	return "lingecho:metrics"
+}
+
+// Initialize 实现 gorm.Plugin，在各类语句前后注册计时回调
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	before := func(tx *gorm.DB) {
+		tx.InstanceSet(gormStartKey, time.Now())
+	}
+
+	callback := db.Callback()
+	if err := callback.Query().Before("*").Register("metrics:before_query", before); err != nil {
+		return err
+	}
+	if err := callback.Query().After("*").Register("metrics:after_query", p.after("query")); err != nil {
+		return err
+	}
+	if err := callback.Create().Before("*").Register("metrics:before_create", before); err != nil {
+		return err
+	}
+	if err := callback.Create().After("*").Register("metrics:after_create", p.after("create")); err != nil {
+		return err
+	}
+	if err := callback.Update().Before("*").Register("metrics:before_update", before); err != nil {
+		return err
+	}
+	if err := callback.Update().After("*").Register("metrics:after_update", p.after("update")); err != nil {
+		return err
+	}
+	if err := callback.Delete().Before("*").Register("metrics:before_delete", before); err != nil {
+		return err
+	}
+	if err := callback.Delete().After("*").Register("metrics:after_delete", p.after("delete")); err != nil {
+		return err
+	}
+	if err := callback.Row().Before("*").Register("metrics:before_row", before); err != nil {
+		return err
+	}
+	if err := callback.Row().After("*").Register("metrics:after_row", p.after("row")); err != nil {
+		return err
+	}
+	if err := callback.Raw().Before("*").Register("metrics:before_raw", before); err != nil {
+		return err
+	}
+	return callback.Raw().After("*").Register("metrics:after_raw", p.after("raw"))
+}
+
+func (p *GormPlugin) after(sqlType string) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		v, ok := tx.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		duration := time.Since(start)
+
+		sql := tx.Statement.SQL.String()
+		if sql == "" {
+			// 语句在生成 SQL 前已被中止（如缺少 WHERE 条件的删除），不计入
+			return
+		}
+		table := tx.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		operation := sqlOperation(sqlType, sql)
+
+		// 未找到记录是正常的查询结果，不算错误
+		err := tx.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+
+		p.monitor.RecordDBQuery(operation, table, sqlType, duration)
+		// 参数可能包含密码哈希等敏感值，不交给 SQL 分析器
+		p.monitor.RecordSQLQuery(tx.Statement.Context, sql, nil, table, operation, duration, tx.RowsAffected, err)
+	}
+}
+
+// sqlOperation 返回语句的操作类型；原生 SQL 取首个关键字，未知关键字归为 OTHER 以限制标签基数
+func sqlOperation(sqlType, sql string) string {
+	switch sqlType {
+	case "query", "row":
+		return "SELECT"
+	case "create":
+		return "INSERT"
+	case "update":
+		return "UPDATE"
+	case "delete":
+		return "DELETE"
+	}
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "OTHER"
+	}
+	switch keyword := strings.ToUpper(fields[0]); keyword {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH", "REPLACE":
+		return keyword
+	default:
+		return "OTHER"
+	}
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// scrape 返回 Prometheus 文本格式的全部指标
+func scrape(t *testing.T, token, authorization string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/metrics", PrometheusHandler(token))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+type gormWidget struct {
+	ID   uint
+	Name string
+}
+
+func TestGormPlugin(t *testing.T) {
+	monitor := NewMonitor(&MonitorConfig{
+		EnableMetrics:     true,
+		EnableSQLAnalysis: true,
+		MaxQueries:        100,
+		SlowThreshold:     0, // 每条语句都算慢查询
+	})
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(NewGormPlugin(monitor)))
+	require.NoError(t, db.AutoMigrate(&gormWidget{}))
+
+	require.NoError(t, db.Create(&gormWidget{Name: "a"}).Error)
+	var widget gormWidget
+	require.NoError(t, db.First(&widget).Error)
+	assert.ErrorIs(t, db.First(&widget, 42).Error, gorm.ErrRecordNotFound)
+	assert.Error(t, db.Exec("SELECT * FROM missing_table").Error)
+
+	queries := monitor.GetQueriesByTable("gorm_widgets", 10)
+	require.Len(t, queries, 3)
+	for _, q := range queries {
+		assert.Nil(t, q.Params, "bound values are not kept")
+		assert.NoError(t, q.Error, "record not found is not an error")
+	}
+
+	body := scrape(t, "", "").Body.String()
+	assert.Contains(t, body, `db_query_duration_seconds_count{operation="INSERT",sql_type="create",table="gorm_widgets"}`)
+	assert.Contains(t, body, `db_slow_queries_total{operation="SELECT",table="gorm_widgets"}`)
+	assert.Contains(t, body, `db_query_errors_total{operation="SELECT",table="unknown"}`)
+}
+
+func TestSQLOperation(t *testing.T) {
+	assert.Equal(t, "SELECT", sqlOperation("row", "SELECT count(*) FROM users"))
+	assert.Equal(t, "INSERT", sqlOperation("create", ""))
+	assert.Equal(t, "UPDATE", sqlOperation("raw", "  update users set name = ?"))
+	assert.Equal(t, "OTHER", sqlOperation("raw", "PRAGMA foreign_keys = ON"))
+	assert.Equal(t, "OTHER", sqlOperation("raw", ""))
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	monitor := NewMonitor(&MonitorConfig{EnableMetrics: true})
+	monitor.SessionStarted("webrtc")
+	monitor.SessionEnded("webrtc", "client", 30*time.Second)
+	monitor.RecordAIRequest("tts", "qcloud", "synthesize", 300*time.Millisecond, nil)
+	monitor.RecordAIRequest("llm", "openai", "query", time.Second, errors.New("timeout"))
+
+	w := scrape(t, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `realtime_sessions_active{transport="webrtc"}`)
+	assert.Contains(t, body, `realtime_sessions_closed_total{reason="client",transport="webrtc"}`)
+	assert.Contains(t, body, `ai_request_duration_seconds_count{kind="tts",operation="synthesize",provider="qcloud",status="success"}`)
+	assert.Contains(t, body, `ai_request_errors_total{kind="llm",operation="query",provider="openai"}`)
+
+	assert.Equal(t, http.StatusUnauthorized, scrape(t, "secret", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, scrape(t, "secret", "Bearer wrong").Code)
+	assert.Equal(t, http.StatusOK, scrape(t, "secret", "Bearer secret").Code)
+}
@@ -25,6 +25,8 @@ type Metrics struct {
 	dbQueryDuration     *prometheus.HistogramVec
 	dbConnectionsActive *prometheus.GaugeVec
 	dbConnectionsTotal  *prometheus.CounterVec
+	dbSlowQueriesTotal  *prometheus.CounterVec
+	dbQueryErrorsTotal  *prometheus.CounterVec
 
 	// 缓存指标
 	cacheHitsTotal   *prometheus.CounterVec
@@ -40,6 +42,21 @@ type Metrics struct {
 	systemMemoryUsage *prometheus.GaugeVec
 	systemCPUUsage    *prometheus.GaugeVec
 	systemGoroutines  *prometheus.GaugeVec
+
+	// 通话质量指标
+	callMOS      *prometheus.HistogramVec
+	callLossRate *prometheus.HistogramVec
+	callJitter   *prometheus.HistogramVec
+	callRTT      *prometheus.HistogramVec
+
+	// 实时会话指标
+	realtimeSessionsActive  *prometheus.GaugeVec
+	realtimeSessionsClosed  *prometheus.CounterVec
+	realtimeSessionDuration *prometheus.HistogramVec
+
+	// AI 服务调用指标（ASR/TTS/LLM）
+	aiRequestDuration *prometheus.HistogramVec
+	aiRequestErrors   *prometheus.CounterVec
 }
 
 // NewMetrics 创建指标管理器
@@ -117,6 +134,22 @@ func newMetrics() *Metrics {
 			[]string{"database", "operation"},
 		),
 
+		dbSlowQueriesTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "db_slow_queries_total",
+				Help: "Total number of database queries slower than the slow query threshold",
+			},
+			[]string{"operation", "table"},
+		),
+
+		dbQueryErrorsTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "db_query_errors_total",
+				Help: "Total number of failed database queries",
+			},
+			[]string{"operation", "table"},
+		),
+
 		// 缓存指标
 		cacheHitsTotal: promauto.NewCounterVec(
 			prometheus.CounterOpts{
@@ -192,6 +225,87 @@ func newMetrics() *Metrics {
 			},
 			[]string{},
 		),
+
+		// 通话质量指标
+		callMOS: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "call_mos",
+				Help:    "Estimated mean opinion score of active calls",
+				Buckets: []float64{1, 2, 2.5, 3, 3.5, 3.8, 4, 4.2, 4.4},
+			},
+			[]string{"codec"},
+		),
+
+		callLossRate: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "call_packet_loss_ratio",
+				Help:    "RTP packet loss ratio of active calls",
+				Buckets: []float64{0, 0.005, 0.01, 0.02, 0.05, 0.1, 0.2},
+			},
+			[]string{"codec"},
+		),
+
+		callJitter: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "call_jitter_seconds",
+				Help:    "RTP interarrival jitter of active calls in seconds",
+				Buckets: []float64{0.005, 0.01, 0.02, 0.03, 0.05, 0.1, 0.2},
+			},
+			[]string{"codec"},
+		),
+
+		callRTT: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "call_rtt_seconds",
+				Help:    "Round trip time of active calls in seconds",
+				Buckets: []float64{0.02, 0.05, 0.1, 0.15, 0.2, 0.3, 0.5, 1},
+			},
+			[]string{"codec"},
+		),
+
+		// 实时会话指标
+		realtimeSessionsActive: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "realtime_sessions_active",
+				Help: "Number of open realtime sessions",
+			},
+			[]string{"transport"},
+		),
+
+		realtimeSessionsClosed: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "realtime_sessions_closed_total",
+				Help: "Total number of closed realtime sessions",
+			},
+			[]string{"transport", "reason"},
+		),
+
+		realtimeSessionDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "realtime_session_duration_seconds",
+				Help:    "Realtime session duration in seconds",
+				Buckets: []float64{5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+			},
+			[]string{"transport"},
+		),
+
+		// AI 服务调用指标
+		aiRequestDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "ai_request_duration_seconds",
+				Help:    "ASR/TTS/LLM request duration in seconds",
+				Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60},
+			},
+			[]string{"kind", "provider", "operation", "status"},
+		),
+
+		aiRequestErrors: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ai_request_errors_total",
+				Help: "Total number of failed ASR/TTS/LLM requests",
+			},
+			[]string{"kind", "provider", "operation"},
+		),
 	}
 
 	return m
@@ -210,6 +324,16 @@ func (m *Metrics) RecordDBQuery(operation, table, sqlType string, duration time.
 	m.dbQueryDuration.WithLabelValues(operation, table, sqlType).Observe(duration.Seconds())
 }
 
+// RecordSlowQuery 记录慢查询
+func (m *Metrics) RecordSlowQuery(operation, table string) {
+	m.dbSlowQueriesTotal.WithLabelValues(operation, table).Inc()
+}
+
+// RecordDBError 记录失败的数据库查询
+func (m *Metrics) RecordDBError(operation, table string) {
+	m.dbQueryErrorsTotal.WithLabelValues(operation, table).Inc()
+}
+
 // RecordDBConnection 记录数据库连接指标
 func (m *Metrics) RecordDBConnection(database, operation string) {
 	m.dbConnectionsTotal.WithLabelValues(database, operation).Inc()
@@ -265,6 +389,41 @@ func (m *Metrics) SetSystemGoroutines(count int) {
 	m.systemGoroutines.WithLabelValues().Set(float64(count))
 }
 
+// RecordCallQuality 记录通话质量指标
+func (m *Metrics) RecordCallQuality(codec string, mos, lossRate float64, jitter, rtt time.Duration) {
+	m.callMOS.WithLabelValues(codec).Observe(mos)
+	m.callLossRate.WithLabelValues(codec).Observe(lossRate)
+	m.callJitter.WithLabelValues(codec).Observe(jitter.Seconds())
+	m.callRTT.WithLabelValues(codec).Observe(rtt.Seconds())
+}
+
+// SessionStarted 记录实时会话建立
+func (m *Metrics) SessionStarted(transport string) {
+	m.realtimeSessionsActive.WithLabelValues(transport).Inc()
+}
+
+// SessionEnded 记录实时会话结束及其时长
+func (m *Metrics) SessionEnded(transport, reason string, duration time.Duration) {
+	m.realtimeSessionsActive.WithLabelValues(transport).Dec()
+	m.realtimeSessionsClosed.WithLabelValues(transport, reason).Inc()
+	m.realtimeSessionDuration.WithLabelValues(transport).Observe(duration.Seconds())
+}
+
+// RecordAIRequest 记录一次 ASR/TTS/LLM 调用的耗时，失败时同时计入错误数
+func (m *Metrics) RecordAIRequest(kind, provider, operation string, duration time.Duration, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+		m.aiRequestErrors.WithLabelValues(kind, provider, operation).Inc()
+	}
+	m.aiRequestDuration.WithLabelValues(kind, provider, operation, status).Observe(duration.Seconds())
+}
+
+// RecordAIError 记录没有对应耗时的 AI 服务错误，如流式识别中途出错
+func (m *Metrics) RecordAIError(kind, provider, operation string) {
+	m.aiRequestErrors.WithLabelValues(kind, provider, operation).Inc()
+}
+
 // GetCacheHitRate 获取缓存命中率
 func (m *Metrics) GetCacheHitRate(cacheType, operation string) float64 {
 	// 由于Prometheus指标是只写的，我们无法直接读取值
@@ -280,6 +439,10 @@ func (m *Metrics) Reset() {
 	m.cacheHitsTotal.Reset()
 	m.cacheMissesTotal.Reset()
 	m.businessCounter.Reset()
+	m.dbSlowQueriesTotal.Reset()
+	m.dbQueryErrorsTotal.Reset()
+	m.realtimeSessionsClosed.Reset()
+	m.aiRequestErrors.Reset()
 
 	// 重置直方图
 	m.httpRequestDuration.Reset()
@@ -287,6 +450,12 @@ func (m *Metrics) Reset() {
 	m.httpResponseSize.Reset()
 	m.dbQueryDuration.Reset()
 	m.businessHistogram.Reset()
+	m.callMOS.Reset()
+	m.callLossRate.Reset()
+	m.callJitter.Reset()
+	m.callRTT.Reset()
+	m.realtimeSessionDuration.Reset()
+	m.aiRequestDuration.Reset()
 
 	// 重置仪表盘
 	m.dbConnectionsActive.Reset()
@@ -295,4 +464,5 @@ func (m *Metrics) Reset() {
 	m.systemMemoryUsage.Reset()
 	m.systemCPUUsage.Reset()
 	m.systemGoroutines.Reset()
+	m.realtimeSessionsActive.Reset()
 }
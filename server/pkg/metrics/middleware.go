@@ -13,13 +13,15 @@ func MonitorMiddleware(monitor *Monitor) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 
-		// 开始链路追踪
+		// 开始链路追踪，接续调用方通过 traceparent 传入的链路
 		ctx, span := monitor.StartSpan(c.Request.Context(), c.HandlerName(),
 			WithTags(map[string]string{
 				"method": c.Request.Method,
 				"path":   c.Request.URL.Path,
 				"ip":     c.ClientIP(),
 			}),
+			WithRemoteParent(c.GetHeader("traceparent")),
+			WithSpanKind(SpanKindServer),
 		)
 
 		// 将span添加到上下文
@@ -47,9 +49,14 @@ func MonitorMiddleware(monitor *Monitor) gin.HandlerFunc {
 		}
 		responseSize := int64(c.Writer.Size())
 
+		// 指标按路由模板聚合，避免路径参数导致标签基数膨胀；未匹配路由统一归入 unmatched
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
 		monitor.RecordHTTPRequest(
 			c.Request.Method,
-			c.Request.URL.Path,
+			route,
 			strconv.Itoa(c.Writer.Status()),
 			c.HandlerName(),
 			duration,
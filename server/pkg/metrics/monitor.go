@@ -2,15 +2,19 @@ package metrics
 
 import (
 	"context"
+	"crypto/subtle"
 	_ "embed"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/code-100-precent/LingEcho"
 	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 )
 
 // Monitor 监控管理器
@@ -19,6 +23,7 @@ type Monitor struct {
 	tracer        *Tracer
 	sqlAnalyzer   *SQLAnalyzer
 	systemMonitor *SystemMonitor
+	callQuality   *CallQualityTracker
 	mu            sync.RWMutex
 	config        *MonitorConfig
 }
@@ -31,6 +36,10 @@ type MonitorConfig struct {
 	// 链路追踪配置
 	EnableTracing bool `json:"enable_tracing" yaml:"enable_tracing" default:"true"`
 	MaxSpans      int  `json:"max_spans" yaml:"max_spans" default:"10000"`
+	// TraceSampleRatio 新链路的采样比例（0~1]，0 表示全部采样
+	TraceSampleRatio float64 `json:"trace_sample_ratio" yaml:"trace_sample_ratio" default:"1"`
+	// OTLP 为空时跨度只保存在内存中，配置 Endpoint 后同时上报到 OpenTelemetry 收集器
+	OTLP OTLPConfig `json:"otlp" yaml:"otlp"`
 
 	// SQL分析配置
 	EnableSQLAnalysis bool          `json:"enable_sql_analysis" yaml:"enable_sql_analysis" default:"true"`
@@ -49,6 +58,7 @@ func DefaultMonitorConfig() *MonitorConfig {
 		EnableMetrics:       true,
 		EnableTracing:       true,
 		MaxSpans:            10000,
+		TraceSampleRatio:    1,
 		EnableSQLAnalysis:   true,
 		MaxQueries:          10000,
 		SlowThreshold:       100 * time.Millisecond,
@@ -65,7 +75,8 @@ func NewMonitor(config *MonitorConfig) *Monitor {
 	}
 
 	monitor := &Monitor{
-		config: config,
+		config:      config,
+		callQuality: NewCallQualityTracker(),
 	}
 
 	// 初始化指标收集
@@ -76,6 +87,10 @@ func NewMonitor(config *MonitorConfig) *Monitor {
 	// 初始化链路追踪
 	if config.EnableTracing {
 		monitor.tracer = NewTracer(config.MaxSpans)
+		monitor.tracer.SetSampleRatio(config.TraceSampleRatio)
+		if config.OTLP.Endpoint != "" {
+			monitor.tracer.SetExporter(NewOTLPExporter(config.OTLP))
+		}
 	}
 
 	// 初始化SQL分析
@@ -131,6 +146,22 @@ func RegisterMonitorUI(grp *gin.RouterGroup, api *MonitorAPI) {
 	})
 }
 
+// PrometheusHandler 以 Prometheus 文本格式输出所有指标。token 非空时要求
+// 请求携带 "Authorization: Bearer <token>"，便于 /metrics 暴露在公网时限制抓取方
+func PrometheusHandler(token string) gin.HandlerFunc {
+	handler := promhttp.Handler()
+	return func(c *gin.Context) {
+		if token != "" {
+			got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
 // Start 启动监控
 func (m *Monitor) Start() {
 	m.mu.Lock()
@@ -149,6 +180,15 @@ func (m *Monitor) Stop() {
 	if m.systemMonitor != nil {
 		m.systemMonitor.Stop()
 	}
+
+	// 退出前把尚未上报的跨度发出去
+	if m.tracer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := m.tracer.Shutdown(ctx); err != nil {
+			logger.Warn("monitor: failed to flush traces", zap.Error(err))
+		}
+	}
 }
 
 // GetMetrics 获取指标管理器
@@ -187,8 +227,16 @@ func (m *Monitor) EndSpan(span *Span, err error) {
 	m.tracer.EndSpan(span, err)
 }
 
-// RecordSQLQuery 记录SQL查询
+// RecordSQLQuery 记录SQL查询，超过慢查询阈值或失败时同时计入 Prometheus 计数
 func (m *Monitor) RecordSQLQuery(ctx context.Context, sql string, params []interface{}, table, operation string, duration time.Duration, rowsAffected int64, err error) {
+	if m.metrics != nil {
+		if duration >= m.config.SlowThreshold {
+			m.metrics.RecordSlowQuery(operation, table)
+		}
+		if err != nil {
+			m.metrics.RecordDBError(operation, table)
+		}
+	}
 	if m.sqlAnalyzer == nil {
 		return
 	}
@@ -235,6 +283,56 @@ func (m *Monitor) SetSystemMetric(metric, category string, value float64) {
 	m.metrics.SetBusinessMetric(metric, category, value)
 }
 
+// RecordCallQuality 更新通话质量快照并记录指标
+func (m *Monitor) RecordCallQuality(q CallQuality) {
+	m.callQuality.Update(q)
+	if m.metrics != nil {
+		m.metrics.RecordCallQuality(q.Codec, q.MOS, q.LossRate, q.Jitter, q.RTT)
+	}
+}
+
+// RemoveCallQuality 通话结束时移除质量快照
+func (m *Monitor) RemoveCallQuality(sessionID string) {
+	m.callQuality.Remove(sessionID)
+}
+
+// SessionStarted 记录实时会话建立，transport 区分 webrtc、sip 等接入方式
+func (m *Monitor) SessionStarted(transport string) {
+	if m.metrics == nil {
+		return
+	}
+	m.metrics.SessionStarted(transport)
+}
+
+// SessionEnded 记录实时会话结束
+func (m *Monitor) SessionEnded(transport, reason string, duration time.Duration) {
+	if m.metrics == nil {
+		return
+	}
+	m.metrics.SessionEnded(transport, reason, duration)
+}
+
+// RecordAIRequest 记录 ASR/TTS/LLM 调用，kind 取 asr、tts 或 llm
+func (m *Monitor) RecordAIRequest(kind, provider, operation string, duration time.Duration, err error) {
+	if m.metrics == nil {
+		return
+	}
+	m.metrics.RecordAIRequest(kind, provider, operation, duration, err)
+}
+
+// RecordAIError 记录没有对应耗时的 AI 服务错误
+func (m *Monitor) RecordAIError(kind, provider, operation string) {
+	if m.metrics == nil {
+		return
+	}
+	m.metrics.RecordAIError(kind, provider, operation)
+}
+
+// GetCallQuality 获取进行中通话的质量，按 MOS 升序
+func (m *Monitor) GetCallQuality() []CallQuality {
+	return m.callQuality.List()
+}
+
 // GetSystemSummary 获取系统摘要
 func (m *Monitor) GetSystemSummary() map[string]interface{} {
 	summary := map[string]interface{}{
@@ -261,6 +359,8 @@ func (m *Monitor) GetSystemSummary() map[string]interface{} {
 		}
 	}
 
+	summary["calls"] = m.callQuality.Summary()
+
 	return summary
 }
 
@@ -0,0 +1,362 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	otlpQueueSize     = 2048
+	otlpBatchSize     = 256
+	otlpFlushInterval = 5 * time.Second
+	otlpScopeName     = "github.com/code-100-precent/LingEcho/pkg/metrics"
+)
+
+// OTLPConfig OTLP/HTTP 导出配置
+type OTLPConfig struct {
+	// Endpoint 收集器地址，如 http://otel-collector:4318，跨度发送到 <Endpoint>/v1/traces；
+	// 已以 /v1/traces 结尾时原样使用
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	// Headers 附加请求头，如鉴权 Token；监控配置会通过 API 输出，因此不序列化
+	Headers map[string]string `json:"-" yaml:"-"`
+	// ServiceName 资源属性 service.name
+	ServiceName string `json:"service_name" yaml:"service_name"`
+	// Timeout 单次上报超时，默认 10s
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// OTLPExporter 以 OTLP/HTTP JSON 协议批量上报跨度，可直接对接 OpenTelemetry Collector、
+// Jaeger、Tempo 等后端。队列满时丢弃新跨度，不阻塞业务
+type OTLPExporter struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+	queue       chan *Span
+	flush       chan chan struct{}
+	done        chan struct{}
+	stopOnce    sync.Once
+	wg          sync.WaitGroup
+}
+
+// NewOTLPExporter 创建导出器并启动后台上报
+func NewOTLPExporter(config OTLPConfig) *OTLPExporter {
+	url := strings.TrimRight(config.Endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "lingecho"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	e := &OTLPExporter{
+		url:         url,
+		headers:     config.Headers,
+		serviceName: config.ServiceName,
+		client:      &http.Client{Timeout: config.Timeout},
+		queue:       make(chan *Span, otlpQueueSize),
+		flush:       make(chan chan struct{}),
+		done:        make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// ParseOTLPHeaders 解析 OTEL_EXPORTER_OTLP_HEADERS 格式的 "k1=v1,k2=v2"
+func ParseOTLPHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return headers
+}
+
+// Export 将跨度加入上报队列
+func (e *OTLPExporter) Export(span *Span) {
+	select {
+	case <-e.done:
+	case e.queue <- span:
+	default:
+		logger.Debug("otlp: queue full, dropping span", zap.String("span", span.Name))
+	}
+}
+
+// Shutdown 上报剩余跨度后停止，ctx 到期时放弃等待
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.done) })
+	finished := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ForceFlush 立即上报队列中的跨度
+func (e *OTLPExporter) ForceFlush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case e.flush <- ack:
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *OTLPExporter) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, otlpBatchSize)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			logger.Warn("otlp: export failed", zap.Int("spans", len(batch)), zap.Error(err))
+		}
+		batch = batch[:0]
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-e.queue:
+				batch = append(batch, span)
+				if len(batch) >= otlpBatchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= otlpBatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-e.flush:
+			drain()
+			close(ack)
+		case <-e.done:
+			drain()
+			return
+		}
+	}
+}
+
+func (e *OTLPExporter) send(spans []*Span) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// OTLP JSON 编码，字段名遵循 opentelemetry-proto 的 JSON 映射：
+// 64 位整数写成字符串，TraceID/SpanID 写成十六进制
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (e *OTLPExporter) encode(spans []*Span) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		out = append(out, encodeSpan(span))
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{otlpAttribute("service.name", e.serviceName)}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: otlpScopeName},
+			Spans: out,
+		}},
+	}}}
+}
+
+func encodeSpan(span *Span) otlpSpan {
+	span.mu.RLock()
+	defer span.mu.RUnlock()
+
+	out := otlpSpan{
+		TraceID:           span.TraceID,
+		SpanID:            span.ID,
+		ParentSpanID:      span.ParentID,
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+	}
+	for k, v := range span.Tags {
+		out.Attributes = append(out.Attributes, otlpAttribute(k, v))
+	}
+	for k, v := range span.Attributes {
+		out.Attributes = append(out.Attributes, otlpAttribute(k, v))
+	}
+	for _, event := range span.Events {
+		encoded := otlpEvent{
+			TimeUnixNano: strconv.FormatInt(event.Time.UnixNano(), 10),
+			Name:         event.Name,
+		}
+		for k, v := range event.Attributes {
+			encoded.Attributes = append(encoded.Attributes, otlpAttribute(k, v))
+		}
+		out.Events = append(out.Events, encoded)
+	}
+	switch span.Status {
+	case SpanStatusOK:
+		out.Status.Code = 1
+	case SpanStatusError:
+		out.Status.Code = 2
+		if span.Error != nil {
+			out.Status.Message = span.Error.Error()
+		}
+	}
+	return out
+}
+
+// otlpAttribute 将属性值映射为 OTLP AnyValue，time.Duration 按毫秒整数输出，其他类型按字符串输出
+func otlpAttribute(key string, value interface{}) otlpKeyValue {
+	var v otlpValue
+	switch val := value.(type) {
+	case string:
+		v.StringValue = &val
+	case bool:
+		v.BoolValue = &val
+	case int:
+		s := strconv.FormatInt(int64(val), 10)
+		v.IntValue = &s
+	case int32:
+		s := strconv.FormatInt(int64(val), 10)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(val, 10)
+		v.IntValue = &s
+	case uint:
+		s := strconv.FormatUint(uint64(val), 10)
+		v.IntValue = &s
+	case uint32:
+		s := strconv.FormatUint(uint64(val), 10)
+		v.IntValue = &s
+	case float32:
+		f := float64(val)
+		v.DoubleValue = &f
+	case float64:
+		v.DoubleValue = &val
+	case time.Duration:
+		s := strconv.FormatInt(val.Milliseconds(), 10)
+		v.IntValue = &s
+	default:
+		s := fmt.Sprint(val)
+		v.StringValue = &s
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
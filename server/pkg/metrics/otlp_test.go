@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []map[string]interface{}
+		headers  []http.Header
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &req))
+		mu.Lock()
+		requests = append(requests, req)
+		headers = append(headers, r.Header.Clone())
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	tracer := NewTracer(100)
+	tracer.SetExporter(NewOTLPExporter(OTLPConfig{
+		Endpoint:    collector.URL + "/",
+		Headers:     ParseOTLPHeaders("authorization=Bearer abc, x-tenant = demo"),
+		ServiceName: "lingecho-test",
+	}))
+
+	ctx, turn := tracer.StartSpan(context.Background(), "voice.turn",
+		WithAttributes(map[string]interface{}{"session.id": "s1", "asr.chars": 12, "latency": 1500 * time.Millisecond}))
+	_, llm := tracer.StartSpan(ctx, "llm.query")
+	llm.AddEvent("first_token", nil)
+	tracer.EndSpan(llm, errors.New("rate limited"))
+	tracer.EndSpan(turn, nil)
+
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "Bearer abc", headers[0].Get("Authorization"))
+	assert.Equal(t, "demo", headers[0].Get("X-Tenant"))
+
+	resource := requests[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	serviceName := resource["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "service.name", serviceName["key"])
+	assert.Equal(t, "lingecho-test", serviceName["value"].(map[string]interface{})["stringValue"])
+
+	spans := resource["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	require.Len(t, spans, 2)
+	byName := map[string]map[string]interface{}{}
+	for _, s := range spans {
+		span := s.(map[string]interface{})
+		byName[span["name"].(string)] = span
+	}
+	llmSpan, turnSpan := byName["llm.query"], byName["voice.turn"]
+	assert.Equal(t, turnSpan["traceId"], llmSpan["traceId"])
+	assert.Equal(t, turnSpan["spanId"], llmSpan["parentSpanId"])
+	assert.Nil(t, turnSpan["parentSpanId"])
+	assert.Equal(t, map[string]interface{}{"code": float64(2), "message": "rate limited"}, llmSpan["status"])
+	assert.Equal(t, "first_token", llmSpan["events"].([]interface{})[0].(map[string]interface{})["name"])
+
+	attrs := map[string]interface{}{}
+	for _, a := range turnSpan["attributes"].([]interface{}) {
+		kv := a.(map[string]interface{})
+		attrs[kv["key"].(string)] = kv["value"]
+	}
+	assert.Equal(t, map[string]interface{}{"stringValue": "s1"}, attrs["session.id"])
+	assert.Equal(t, map[string]interface{}{"intValue": "12"}, attrs["asr.chars"])
+	assert.Equal(t, map[string]interface{}{"intValue": "1500"}, attrs["latency"])
+}
+
+func TestOTLPExporter_SkipsUnsampledSpans(t *testing.T) {
+	calls := 0
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer collector.Close()
+
+	tracer := NewTracer(100)
+	tracer.SetExporter(NewOTLPExporter(OTLPConfig{Endpoint: collector.URL + "/v1/traces"}))
+	_, span := tracer.StartSpan(context.Background(), "server",
+		WithRemoteParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"))
+	tracer.EndSpan(span, nil)
+
+	require.NoError(t, tracer.Shutdown(context.Background()))
+	assert.Equal(t, 0, calls)
+}
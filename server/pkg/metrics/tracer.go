@@ -2,7 +2,10 @@ package metrics
 
 import (
 	"context"
-	"fmt"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
 	"sync"
 	"time"
 )
@@ -22,7 +25,11 @@ type Span struct {
 	Status     SpanStatus             `json:"status"`
 	Error      error                  `json:"error,omitempty"`
 	Children   []*Span                `json:"children,omitempty"`
-	mu         sync.RWMutex
+	Kind       SpanKind               `json:"kind"`
+	// Sampled 为 false 的跨度只用于传递 TraceID，不保存也不导出
+	Sampled bool `json:"sampled"`
+	decided bool // 采样已由父跨度或上游 traceparent 决定
+	mu      sync.RWMutex
 }
 
 // Event 链路事件
@@ -41,19 +48,66 @@ const (
 	SpanStatusError
 )
 
+// SpanKind 跨度类型，取值与 OTLP 一致
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// SpanExporter 接收已结束且被采样的跨度，Export 不能阻塞调用方
+type SpanExporter interface {
+	Export(span *Span)
+	Shutdown(ctx context.Context) error
+}
+
 // Tracer 链路追踪器
 type Tracer struct {
-	spans    map[string]*Span
-	mu       sync.RWMutex
-	maxSpans int
+	spans       map[string]*Span
+	mu          sync.RWMutex
+	maxSpans    int
+	sampleRatio float64
+	exporter    SpanExporter
 }
 
-// NewTracer 创建新的追踪器
+// NewTracer 创建新的追踪器，默认全部采样
 func NewTracer(maxSpans int) *Tracer {
 	return &Tracer{
-		spans:    make(map[string]*Span),
-		maxSpans: maxSpans,
+		spans:       make(map[string]*Span),
+		maxSpans:    maxSpans,
+		sampleRatio: 1,
+	}
+}
+
+// SetSampleRatio 设置新链路的采样比例（0~1]，超出范围视为全部采样。
+// 子跨度与带 traceparent 的请求沿用上游的采样决定，保证同一链路要么完整保留要么整体丢弃
+func (t *Tracer) SetSampleRatio(ratio float64) {
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	t.mu.Lock()
+	t.sampleRatio = ratio
+	t.mu.Unlock()
+}
+
+// SetExporter 设置跨度导出器，如 OTLPExporter
+func (t *Tracer) SetExporter(exporter SpanExporter) {
+	t.mu.Lock()
+	t.exporter = exporter
+	t.mu.Unlock()
+}
+
+// Shutdown 刷新并关闭导出器
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.mu.RLock()
+	exporter := t.exporter
+	t.mu.RUnlock()
+	if exporter == nil {
+		return nil
 	}
+	return exporter.Shutdown(ctx)
 }
 
 // StartSpan 开始一个新的跨度
@@ -68,6 +122,14 @@ func (t *Tracer) StartSpan(ctx context.Context, name string, opts ...SpanOption)
 		Events:     make([]Event, 0),
 		Status:     SpanStatusUnset,
 		Children:   make([]*Span, 0),
+		Kind:       SpanKindInternal,
+	}
+
+	// 上下文中的父跨度决定采样
+	parentSpan := getSpanFromContext(ctx)
+	if parentSpan != nil {
+		span.Sampled = parentSpan.Sampled
+		span.decided = true
 	}
 
 	// 应用选项
@@ -80,22 +142,34 @@ func (t *Tracer) StartSpan(ctx context.Context, name string, opts ...SpanOption)
 		span.TraceID = generateTraceID()
 	}
 
+	// 没有上游决定时按 TraceID 采样
+	t.mu.RLock()
+	ratio := t.sampleRatio
+	t.mu.RUnlock()
+	if !span.decided {
+		span.Sampled = sampleTrace(span.TraceID, ratio)
+	}
+
 	// 获取父跨度ID
-	if parentSpan := getSpanFromContext(ctx); parentSpan != nil {
+	if parentSpan != nil {
 		span.ParentID = parentSpan.ID
-		parentSpan.mu.Lock()
-		parentSpan.Children = append(parentSpan.Children, span)
-		parentSpan.mu.Unlock()
+		if span.Sampled {
+			parentSpan.mu.Lock()
+			parentSpan.Children = append(parentSpan.Children, span)
+			parentSpan.mu.Unlock()
+		}
 	}
 
-	// 存储跨度
-	t.mu.Lock()
-	if len(t.spans) >= t.maxSpans {
-		// 清理最旧的跨度
-		t.cleanupOldSpans()
+	// 存储跨度，未采样的跨度不占用内存
+	if span.Sampled {
+		t.mu.Lock()
+		if len(t.spans) >= t.maxSpans {
+			// 清理最旧的跨度
+			t.cleanupOldSpans()
+		}
+		t.spans[span.ID] = span
+		t.mu.Unlock()
 	}
-	t.spans[span.ID] = span
-	t.mu.Unlock()
 
 	// 将跨度添加到上下文
 	newCtx := context.WithValue(ctx, spanContextKey{}, span)
@@ -109,8 +183,6 @@ func (t *Tracer) EndSpan(span *Span, err error) {
 	}
 
 	span.mu.Lock()
-	defer span.mu.Unlock()
-
 	span.EndTime = time.Now()
 	span.Duration = span.EndTime.Sub(span.StartTime)
 
@@ -120,10 +192,34 @@ func (t *Tracer) EndSpan(span *Span, err error) {
 	} else {
 		span.Status = SpanStatusOK
 	}
+	sampled := span.Sampled
+	span.mu.Unlock()
+
+	t.mu.RLock()
+	exporter := t.exporter
+	t.mu.RUnlock()
+	if sampled && exporter != nil {
+		exporter.Export(span)
+	}
+}
+
+// TraceParent 返回 W3C traceparent 头，用于把链路传给下游服务
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return "00-" + s.TraceID + "-" + s.ID + "-" + flags
 }
 
 // AddEvent 添加事件到跨度
 func (s *Span) AddEvent(name string, attrs map[string]interface{}) {
+	if s == nil {
+		return
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -137,6 +233,9 @@ func (s *Span) AddEvent(name string, attrs map[string]interface{}) {
 
 // SetTag 设置标签
 func (s *Span) SetTag(key, value string) {
+	if s == nil {
+		return
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.Tags[key] = value
@@ -144,6 +243,9 @@ func (s *Span) SetTag(key, value string) {
 
 // SetAttribute 设置属性
 func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.Attributes[key] = value
@@ -209,11 +311,50 @@ func (t *Tracer) cleanupOldSpans() {
 // SpanOption 跨度选项
 type SpanOption func(*Span)
 
-// WithParent 设置父跨度
+// WithParent 设置父跨度，子跨度加入父跨度所在的链路
 func WithParent(parent *Span) SpanOption {
 	return func(s *Span) {
 		if parent != nil {
 			s.ParentID = parent.ID
+			s.TraceID = parent.TraceID
+			s.Sampled = parent.Sampled
+			s.decided = true
+		}
+	}
+}
+
+// WithRemoteParent 从 W3C traceparent 头（00-<trace-id>-<parent-id>-<flags>）
+// 接续上游链路并沿用其采样决定，格式不合法时忽略
+func WithRemoteParent(traceparent string) SpanOption {
+	return func(s *Span) {
+		parts := strings.Split(strings.TrimSpace(traceparent), "-")
+		if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+			!isHexID(parts[1], 32) || !isHexID(parts[2], 16) || len(parts[3]) != 2 {
+			return
+		}
+		flags, err := hex.DecodeString(parts[3])
+		if err != nil {
+			return
+		}
+		s.TraceID = parts[1]
+		s.ParentID = parts[2]
+		s.Sampled = flags[0]&0x01 == 1
+		s.decided = true
+	}
+}
+
+// WithSpanKind 设置跨度类型
+func WithSpanKind(kind SpanKind) SpanOption {
+	return func(s *Span) {
+		s.Kind = kind
+	}
+}
+
+// WithStartTime 指定开始时间，用于补记已经发生的阶段（如语音识别）
+func WithStartTime(start time.Time) SpanOption {
+	return func(s *Span) {
+		if !start.IsZero() {
+			s.StartTime = start
 		}
 	}
 }
@@ -255,12 +396,43 @@ func getTraceIDFromContext(ctx context.Context) string {
 	return ""
 }
 
-// generateSpanID 生成跨度ID
+// generateSpanID 生成 8 字节十六进制跨度ID，与 W3C/OTLP 格式一致
 func generateSpanID() string {
-	return fmt.Sprintf("span_%d", time.Now().UnixNano())
+	return randomHex(8)
 }
 
-// generateTraceID 生成追踪ID
+// generateTraceID 生成 16 字节十六进制追踪ID
 func generateTraceID() string {
-	return fmt.Sprintf("trace_%d", time.Now().UnixNano())
+	return randomHex(16)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// isHexID 判断 id 是否为指定长度且不全为 0 的小写十六进制串
+func isHexID(id string, length int) bool {
+	if len(id) != length || strings.Trim(id, "0") == "" {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// sampleTrace 按 TraceID 低 8 字节决定是否采样，同一链路在各服务得到相同结果
+func sampleTrace(traceID string, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	b, err := hex.DecodeString(traceID)
+	if err != nil || len(b) != 16 {
+		return true
+	}
+	return binary.BigEndian.Uint64(b[8:16])>>1 < uint64(ratio*(1<<63))
 }
@@ -254,3 +254,81 @@ func TestSpanStatus_Constants(t *testing.T) {
 		t.Error("Expected SpanStatusError to be 2")
 	}
 }
+
+func TestTracer_IDsAreW3CCompatible(t *testing.T) {
+	tracer := NewTracer(100)
+	_, span := tracer.StartSpan(context.Background(), "root")
+
+	if !isHexID(span.TraceID, 32) {
+		t.Errorf("Expected 32 hex trace ID, got %q", span.TraceID)
+	}
+	if !isHexID(span.ID, 16) {
+		t.Errorf("Expected 16 hex span ID, got %q", span.ID)
+	}
+	want := "00-" + span.TraceID + "-" + span.ID + "-01"
+	if got := span.TraceParent(); got != want {
+		t.Errorf("Expected traceparent %s, got %s", want, got)
+	}
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	tracer := NewTracer(10000)
+	tracer.SetSampleRatio(0.25)
+
+	sampled := 0
+	for i := 0; i < 2000; i++ {
+		ctx, root := tracer.StartSpan(context.Background(), "root")
+		_, child := tracer.StartSpan(ctx, "child")
+		if child.Sampled != root.Sampled {
+			t.Fatal("Expected child to inherit the sampling decision")
+		}
+		if root.Sampled {
+			sampled++
+		}
+	}
+	if sampled < 400 || sampled > 600 {
+		t.Errorf("Expected about 500 sampled traces, got %d", sampled)
+	}
+	// 未采样的跨度不保存
+	if got := len(tracer.GetSpans()); got != sampled*2 {
+		t.Errorf("Expected %d stored spans, got %d", sampled*2, got)
+	}
+}
+
+func TestTracer_WithRemoteParent(t *testing.T) {
+	tracer := NewTracer(100)
+	tracer.SetSampleRatio(0.0001)
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	_, span := tracer.StartSpan(context.Background(), "server",
+		WithRemoteParent("00-"+traceID+"-00f067aa0ba902b7-01"))
+	if span.TraceID != traceID || span.ParentID != "00f067aa0ba902b7" {
+		t.Errorf("Expected remote trace to be continued, got trace %s parent %s", span.TraceID, span.ParentID)
+	}
+	if !span.Sampled {
+		t.Error("Expected upstream sampled flag to be honoured")
+	}
+
+	_, span = tracer.StartSpan(context.Background(), "server",
+		WithRemoteParent("00-"+traceID+"-00f067aa0ba902b7-00"))
+	if span.Sampled {
+		t.Error("Expected upstream unsampled flag to be honoured")
+	}
+
+	for _, invalid := range []string{"", "garbage", "00-" + traceID + "-0000000000000000-01", "ff-" + traceID + "-00f067aa0ba902b7-01"} {
+		_, span = tracer.StartSpan(context.Background(), "server", WithRemoteParent(invalid))
+		if span.TraceID == traceID || span.ParentID != "" {
+			t.Errorf("Expected invalid traceparent %q to be ignored", invalid)
+		}
+	}
+}
+
+func TestSpan_NilSafe(t *testing.T) {
+	var span *Span
+	span.SetTag("k", "v")
+	span.SetAttribute("k", 1)
+	span.AddEvent("e", nil)
+	if span.TraceParent() != "" {
+		t.Error("Expected empty traceparent for nil span")
+	}
+}
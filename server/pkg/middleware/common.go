@@ -26,8 +26,8 @@ func CorsMiddleware() gin.HandlerFunc {
 
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true") // 允许携带 Cookie
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Origin, X-API-KEY, X-API-SECRET, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Type")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Origin, X-API-KEY, X-API-SECRET, X-Requested-With, "+IdempotencyHeader)
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Type, "+IdempotencyReplayedHeader)
 
 		// 处理预检请求
 		if c.Request.Method == "OPTIONS" {
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/cache"
+	"github.com/code-100-precent/LingEcho/pkg/constants"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyHeader 客户端重试同一请求时携带相同的值
+const IdempotencyHeader = "Idempotency-Key"
+
+// IdempotencyReplayedHeader 响应来自首次请求的记录时设置
+const IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+// DefaultIdempotencyTTL 首次响应的保留时间
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyLockTTL 处理中标记的有效期，处理期间定期续期；进程异常退出后不会长时间阻塞重试
+var idempotencyLockTTL = time.Minute
+
+const (
+	maxIdempotencyKeyLen   = 255
+	maxIdempotencyBodySize = 1 << 20 // 超过该大小的响应不保存，重试会重新执行
+)
+
+// idempotencyRecord 保存在全局缓存中的请求记录，Status 为 0 表示首次请求仍在处理
+type idempotencyRecord struct {
+	Status      int    `json:"status"`
+	Fingerprint string `json:"fingerprint"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Idempotency 幂等中间件，需放在认证之后。携带 Idempotency-Key 的请求按 用户+Key 保存首次成功的响应，
+// ttl 内的重试直接返回该响应；首次请求未完成时返回 409，同一 Key 用于不同请求时返回 422。
+// 失败的响应不保存，客户端可用同一 Key 重试。记录保存在全局缓存中，使用 Redis 时多实例共享
+func Idempotency(ttl time.Duration) gin.HandlerFunc {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(IdempotencyHeader))
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+		value, _ := c.Get(constants.UserField)
+		user, ok := value.(*models.User)
+		if !ok || user == nil {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := idempotencyHash(c.Request.Method, c.Request.URL.Path, string(body))
+		cacheKey := fmt.Sprintf("idempotency:%d:%s", user.ID, idempotencyHash(key))
+
+		pending, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+		acquired, err := cache.SetNX(c, cacheKey, string(pending), idempotencyLockTTL)
+		if err != nil {
+			// 缓存不可用时不阻塞请求
+			c.Next()
+			return
+		}
+		if !acquired {
+			replayIdempotentResponse(c, cacheKey, fingerprint)
+			return
+		}
+
+		stop := keepIdempotencyLock(context.WithoutCancel(c.Request.Context()), cacheKey, string(pending))
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		stop()
+
+		if writer.overflow || !idempotentSuccess(writer.Status(), writer.body.Bytes()) {
+			cache.Delete(c, cacheKey)
+			return
+		}
+		record, _ := json.Marshal(idempotencyRecord{
+			Status:      writer.Status(),
+			Fingerprint: fingerprint,
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		})
+		cache.Set(c, cacheKey, string(record), ttl)
+	}
+}
+
+// keepIdempotencyLock 处理期间定期续期处理中标记，耗时超过 idempotencyLockTTL 的请求不会被重复执行。
+// 返回的函数停止续期并等待续期协程退出，之后才能写入最终记录
+func keepIdempotencyLock(ctx context.Context, cacheKey, pending string) func() {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(idempotencyLockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				cache.Set(ctx, cacheKey, pending, idempotencyLockTTL)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// replayIdempotentResponse 处理重复的 Key：返回首次响应，或说明无法重放的原因
+func replayIdempotentResponse(c *gin.Context, cacheKey, fingerprint string) {
+	value, ok := cache.Get(c, cacheKey)
+	data, isString := value.(string)
+	var record idempotencyRecord
+	if !ok || !isString || json.Unmarshal([]byte(data), &record) != nil {
+		// 首次请求刚好结束并被释放，让客户端稍后重试
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "request with this Idempotency-Key is being processed, retry later"})
+		return
+	}
+	if record.Fingerprint != fingerprint {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+		return
+	}
+	if record.Status == 0 {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "request with this Idempotency-Key is being processed, retry later"})
+		return
+	}
+	c.Header(IdempotencyReplayedHeader, "true")
+	c.Data(record.Status, record.ContentType, record.Body)
+	c.Abort()
+}
+
+// idempotentSuccess 判断响应是否成功；response.Fail 以 200 返回 {"code": 500}，同样视为失败
+func idempotentSuccess(status int, body []byte) bool {
+	if status < 200 || status >= 300 {
+		return false
+	}
+	var envelope struct {
+		Code *int `json:"code"`
+	}
+	if json.Unmarshal(body, &envelope) == nil && envelope.Code != nil && *envelope.Code >= 400 {
+		return false
+	}
+	return true
+}
+
+func idempotencyHash(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotencyWriter 记录响应内容，超过大小限制后停止记录
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *idempotencyWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > maxIdempotencyBodySize {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/cache"
+	"github.com/code-100-precent/LingEcho/pkg/constants"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupIdempotencyRouter(t *testing.T, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cache.SetGlobalCache(cache.NewLocalCache(cache.LocalConfig{MaxSize: 100, DefaultExpiration: time.Minute, CleanupInterval: time.Hour}))
+	t.Cleanup(func() { cache.SetGlobalCache(nil) })
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if uid := c.GetHeader("X-Test-User"); uid != "" {
+			c.Set(constants.UserField, &models.User{ID: uint(len(uid))})
+		}
+	})
+	router.POST("/calls", Idempotency(time.Hour), handler)
+	router.POST("/other", Idempotency(time.Hour), handler)
+	return router
+}
+
+func doIdempotentRequest(router *gin.Engine, path, user, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("X-Test-User", user)
+	if key != "" {
+		req.Header.Set(IdempotencyHeader, key)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysFirstResponse(t *testing.T) {
+	calls := 0
+	router := setupIdempotencyRouter(t, func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"code": 200, "call": calls})
+	})
+
+	first := doIdempotentRequest(router, "/calls", "u", "k1", `{"to":"100"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	retry := doIdempotentRequest(router, "/calls", "u", "k1", `{"to":"100"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	// Key 按用户隔离，未携带 Key 的请求不受影响
+	doIdempotentRequest(router, "/calls", "uu", "k1", `{"to":"100"}`)
+	doIdempotentRequest(router, "/calls", "u", "", `{"to":"100"}`)
+	assert.Equal(t, 3, calls)
+
+	// 同一 Key 用于不同请求
+	assert.Equal(t, http.StatusUnprocessableEntity, doIdempotentRequest(router, "/calls", "u", "k1", `{"to":"200"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, doIdempotentRequest(router, "/other", "u", "k1", `{"to":"100"}`).Code)
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotency_FailedResponseIsNotStored(t *testing.T) {
+	calls := 0
+	router := setupIdempotencyRouter(t, func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "provider unavailable"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 200})
+	})
+
+	doIdempotentRequest(router, "/calls", "u", "k1", "{}")
+	w := doIdempotentRequest(router, "/calls", "u", "k1", "{}")
+	assert.Empty(t, w.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	var router *gin.Engine
+	var nested *httptest.ResponseRecorder
+	router = setupIdempotencyRouter(t, func(c *gin.Context) {
+		if nested == nil {
+			// 首次请求处理中收到重试
+			nested = doIdempotentRequest(router, "/calls", "u", "k1", "{}")
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 200})
+	})
+
+	doIdempotentRequest(router, "/calls", "u", "k1", "{}")
+	assert.Equal(t, http.StatusConflict, nested.Code)
+}
+
+func TestIdempotency_LockOutlivesTTLWhileRunning(t *testing.T) {
+	lockTTL := idempotencyLockTTL
+	idempotencyLockTTL = 60 * time.Millisecond
+	t.Cleanup(func() { idempotencyLockTTL = lockTTL })
+
+	var router *gin.Engine
+	var nested *httptest.ResponseRecorder
+	calls := 0
+	router = setupIdempotencyRouter(t, func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			// 处理耗时超过标记有效期，续期后重试仍被拒绝
+			time.Sleep(3 * idempotencyLockTTL)
+			nested = doIdempotentRequest(router, "/calls", "u", "k1", "{}")
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 200})
+	})
+
+	doIdempotentRequest(router, "/calls", "u", "k1", "{}")
+	assert.Equal(t, http.StatusConflict, nested.Code)
+	assert.Equal(t, 1, calls)
+
+	retry := doIdempotentRequest(router, "/calls", "u", "k1", "{}")
+	assert.Equal(t, "true", retry.Header().Get(IdempotencyReplayedHeader))
+}
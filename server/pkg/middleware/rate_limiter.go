@@ -14,23 +14,27 @@ import (
 	"github.com/ulule/limiter/v3"
 	_ "github.com/ulule/limiter/v3/drivers/middleware/gin"
 	"github.com/ulule/limiter/v3/drivers/store/memory"
+	sredis "github.com/ulule/limiter/v3/drivers/store/redis"
 )
 
 // RateLimiterConfig 企业级限流配置
 //
 // 示例：
-// Rate: "100-M"、Identifier: "ip"/"user"/"header"、HeaderName: "X-Client-ID"
+// Rate: "100-M"、Identifier: "ip"/"user"/"header"/"identity"、HeaderName: "X-Client-ID"
 // PerRouteRates: {"/api/v1/heavy": "10-S", "/api/v1/normal": "100-S"}
+// PlanRates: {"pro": "5000-M"} 按套餐覆盖默认速率，路由速率优先
 // WhitelistCIDRs/BlacklistCIDRs: ["10.0.0.0/8", "127.0.0.1/32"]
 // WhitelistUsers/BlacklistUsers: ["admin", "ops-*"] 支持前缀匹配
 // SkipPaths: ["/health", "/metrics", "/static/"] 前缀匹配
 // AddHeaders: 是否写标准限流响应头；DenyStatus/DenyMessage: 自定义拒绝响应
 //
-// Store 采用内存，可通过 SetRateLimiterStore 注入外部存储（如 Redis）。
+// Identifier 为 identity 时按 IdentityFunc 解析的身份（登录用户或 API 密钥）限流，未识别时按 IP。
+// Store 采用内存，可通过 SetRateLimiterStore 注入外部存储（如 NewRedisStore），多实例共享计数。
 type RateLimiterConfig struct {
 	Rate           string            `json:"rate"`            // e.g. "100-M", "1000-H"
 	PerRouteRates  map[string]string `json:"per_route_rates"` // 路由覆盖速率
-	Identifier     string            `json:"identifier"`      // ip|user|header|ip+route
+	PlanRates      map[string]string `json:"plan_rates"`      // 套餐编码 -> 速率
+	Identifier     string            `json:"identifier"`      // ip|user|header|ip+route|identity
 	HeaderName     string            `json:"header_name"`     // 当 identifier=header 时使用
 	WhitelistCIDRs []string          `json:"whitelist_cidrs"`
 	BlacklistCIDRs []string          `json:"blacklist_cidrs"`
@@ -52,6 +56,23 @@ type PrebuiltStoreFactory struct{ Store limiter.Store }
 
 func (p *PrebuiltStoreFactory) Create() limiter.Store { return p.Store }
 
+// NewRedisStore 创建基于 Redis 的存储，多实例共享同一组计数
+func NewRedisStore(client sredis.Client, prefix string) (limiter.Store, error) {
+	if prefix == "" {
+		prefix = "ratelimit"
+	}
+	return sredis.NewStoreWithOptions(client, limiter.StoreOptions{Prefix: prefix})
+}
+
+// RateLimitIdentity 请求方身份，Key 为空时按 IP 限流
+type RateLimitIdentity struct {
+	Key  string // 如 user:42、key:<hash>
+	Plan string // 套餐编码，对应 PlanRates
+}
+
+// IdentityFunc 解析请求方身份，在认证中间件之前执行，需自行从会话或请求头识别
+type IdentityFunc func(c *gin.Context) RateLimitIdentity
+
 // MetricsObserver 指标上报接口
 // 可接 Prometheus、StatsD 等
 type MetricsObserver interface {
@@ -88,6 +109,7 @@ type RateLimiter struct {
 	store          limiter.Store
 	storeFactory   StoreFactory
 	observer       MetricsObserver
+	identityFunc   IdentityFunc
 	limitersByRate map[string]*limiter.Limiter // rate字符串 -> limiter
 	mu             sync.RWMutex
 	whiteCIDRs     []*net.IPNet
@@ -128,6 +150,14 @@ func (l *RateLimiter) WithObserver(observer MetricsObserver) *RateLimiter {
 	return l
 }
 
+// WithIdentityFunc 配置身份解析，用于 identity 标识与套餐速率
+func (l *RateLimiter) WithIdentityFunc(fn IdentityFunc) *RateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.identityFunc = fn
+	return l
+}
+
 // Middleware 返回 Gin 中间件
 func (l *RateLimiter) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -159,11 +189,13 @@ func (l *RateLimiter) Middleware() gin.HandlerFunc {
 			return
 		}
 
-		key := buildLimitKey(*cfg, c, clientIP, userID)
-		rateStr := l.pickRateForRoute(cfg, c)
+		identity := l.resolveIdentity(c, cfg)
+		key := buildLimitKey(*cfg, c, clientIP, userID, identity)
+		rateStr := l.pickRateForRoute(cfg, c, identity.Plan)
 		lim := l.getLimiter(rateStr)
 
-		context, err := lim.Get(c, key)
+		// 不同速率使用独立计数，避免路由或套餐速率与默认速率共用一个窗口
+		context, err := lim.Get(c, key+"|"+rateStr)
 		if err != nil {
 			c.Next()
 			return
@@ -184,6 +216,20 @@ func (l *RateLimiter) Middleware() gin.HandlerFunc {
 	}
 }
 
+// resolveIdentity 仅在需要时解析身份，避免每个请求都查询会话或缓存
+func (l *RateLimiter) resolveIdentity(c *gin.Context, cfg *RateLimiterConfig) RateLimitIdentity {
+	if cfg.Identifier != "identity" && len(cfg.PlanRates) == 0 {
+		return RateLimitIdentity{}
+	}
+	l.mu.RLock()
+	fn := l.identityFunc
+	l.mu.RUnlock()
+	if fn == nil {
+		return RateLimitIdentity{}
+	}
+	return fn(c)
+}
+
 func (l *RateLimiter) reportAllow(c *gin.Context, key string) {
 	l.mu.RLock()
 	obs := l.observer
@@ -235,7 +281,8 @@ func (l *RateLimiter) getLimiter(rateStr string) *limiter.Limiter {
 	return lim
 }
 
-func (l *RateLimiter) pickRateForRoute(cfg *RateLimiterConfig, c *gin.Context) string {
+// pickRateForRoute 速率优先级：路由速率 > 套餐速率 > 默认速率
+func (l *RateLimiter) pickRateForRoute(cfg *RateLimiterConfig, c *gin.Context, plan string) string {
 	if cfg.PerRouteRates != nil {
 		if full := c.FullPath(); full != "" {
 			if r, ok := cfg.PerRouteRates[full]; ok && r != "" {
@@ -248,6 +295,11 @@ func (l *RateLimiter) pickRateForRoute(cfg *RateLimiterConfig, c *gin.Context) s
 			}
 		}
 	}
+	if plan != "" {
+		if r, ok := cfg.PlanRates[plan]; ok && r != "" {
+			return r
+		}
+	}
 	if cfg.Rate != "" {
 		return cfg.Rate
 	}
@@ -287,6 +339,7 @@ var (
 	rateLimiterMutex  sync.RWMutex
 	rateLimiterConfig = &RateLimiterConfig{Rate: "10-S", Identifier: "ip", AddHeaders: true, DenyStatus: http.StatusTooManyRequests}
 	rlStore           limiter.Store
+	rlIdentityFunc    IdentityFunc
 	globalRL          *RateLimiter
 	compiledWhiteCIDR []*net.IPNet
 	compiledBlackCIDR []*net.IPNet
@@ -300,12 +353,41 @@ func SetRateLimiterStore(store limiter.Store) {
 	globalRL = nil
 }
 
-// SetRateLimiterConfig 动态更新限流配置
+// SetRateLimiterIdentityFunc 注入身份解析；中间件已安装时直接更新其实例
+func SetRateLimiterIdentityFunc(fn IdentityFunc) {
+	rateLimiterMutex.Lock()
+	defer rateLimiterMutex.Unlock()
+	rlIdentityFunc = fn
+	if globalRL != nil {
+		globalRL.WithIdentityFunc(fn)
+	}
+}
+
+// ParsePlanRates 解析套餐速率配置，格式如 "pro=5000-M,enterprise=20000-M"，忽略无效项
+func ParsePlanRates(s string) map[string]string {
+	rates := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		plan, rate, ok := strings.Cut(strings.TrimSpace(item), "=")
+		plan, rate = strings.TrimSpace(plan), strings.TrimSpace(rate)
+		if !ok || plan == "" {
+			continue
+		}
+		if _, err := limiter.NewRateFromFormatted(rate); err != nil {
+			continue
+		}
+		rates[plan] = rate
+	}
+	return rates
+}
+
+// SetRateLimiterConfig 动态更新限流配置；中间件已安装时直接更新其实例，无需重启
 func SetRateLimiterConfig(config RateLimiterConfig) {
 	rateLimiterMutex.Lock()
 	defer rateLimiterMutex.Unlock()
 	rateLimiterConfig = &config
-	globalRL = nil
+	if globalRL != nil {
+		globalRL.UpdateConfig(config)
+	}
 }
 
 // GetRateLimiterConfig 获取当前配置（拷贝）
@@ -346,6 +428,7 @@ func ensureInitialized() {
 	inst := NewRateLimiter(*rateLimiterConfig, rlStore)
 	inst.whiteCIDRs = compiledWhiteCIDR
 	inst.blackCIDRs = compiledBlackCIDR
+	inst.identityFunc = rlIdentityFunc
 	globalRL = inst
 }
 
@@ -426,8 +509,13 @@ func userListed(user string, patterns []string) bool {
 	return false
 }
 
-func buildLimitKey(cfg RateLimiterConfig, c *gin.Context, ip, user string) string {
+func buildLimitKey(cfg RateLimiterConfig, c *gin.Context, ip, user string, identity RateLimitIdentity) string {
 	switch cfg.Identifier {
+	case "identity":
+		if identity.Key != "" {
+			return identity.Key
+		}
+		return "ip:" + ip
 	case "user":
 		if user != "" {
 			return "user:" + user
@@ -228,3 +228,77 @@ func TestRateLimiterMiddleware(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestSetRateLimiterConfig_UpdatesInstalledMiddleware(t *testing.T) {
+	SetRateLimiterStore(memory.NewStore())
+	SetRateLimiterConfig(RateLimiterConfig{Rate: "1-M", Identifier: "ip", DenyStatus: http.StatusTooManyRequests})
+
+	router := setupRateLimiterTestRouter()
+	router.Use(RateLimiterMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	request := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "192.168.1.2:8080"
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request())
+	assert.Equal(t, http.StatusTooManyRequests, request())
+
+	// 运行时放宽限流，已安装的中间件立即生效
+	SetRateLimiterConfig(RateLimiterConfig{Rate: "100-M", Identifier: "ip", DenyStatus: http.StatusTooManyRequests})
+	assert.Equal(t, http.StatusOK, request())
+}
+
+func TestRateLimiter_Middleware_IdentityAndPlanRates(t *testing.T) {
+	cfg := RateLimiterConfig{
+		Rate:       "1-M",
+		Identifier: "identity",
+		PlanRates:  map[string]string{"pro": "3-M"},
+	}
+	rl := NewRateLimiter(cfg, memory.NewStore()).WithIdentityFunc(func(c *gin.Context) RateLimitIdentity {
+		switch c.GetHeader("X-Test-User") {
+		case "free":
+			return RateLimitIdentity{Key: "user:1"}
+		case "pro":
+			return RateLimitIdentity{Key: "user:2", Plan: "pro"}
+		}
+		return RateLimitIdentity{}
+	})
+
+	router := setupRateLimiterTestRouter()
+	router.Use(rl.Middleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	request := func(user string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "10.0.0.1:8080" // 同一出口 IP
+		req.Header.Set("X-Test-User", user)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 同一 IP 下不同用户分别计数
+	assert.Equal(t, http.StatusOK, request("free"))
+	assert.Equal(t, http.StatusTooManyRequests, request("free"))
+	assert.Equal(t, http.StatusOK, request(""))
+	assert.Equal(t, http.StatusTooManyRequests, request(""))
+
+	// 套餐速率覆盖默认速率
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, request("pro"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, request("pro"))
+}
+
+func TestParsePlanRates(t *testing.T) {
+	rates := ParsePlanRates(" pro=5000-M, enterprise = 20000-M,broken,bad=fast,=1-S")
+	assert.Equal(t, map[string]string{"pro": "5000-M", "enterprise": "20000-M"}, rates)
+	assert.Empty(t, ParsePlanRates(""))
+}
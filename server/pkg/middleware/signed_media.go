@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/constants"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// SignedMediaMiddleware 校验 /media、/uploads 下文件地址的签名，需挂在带 *filepath 参数的路由上。
+// 带签名的地址校验签名与有效期，绑定用户的地址只能由该用户的登录会话使用；
+// 未签名的地址仅在 MEDIA_REQUIRE_SIGNATURE 关闭或命中 MEDIA_PUBLIC_PREFIXES 时放行
+func SignedMediaMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("filepath"), "/")
+
+		userID, err := stores.VerifySignedQuery(key, c.Request.URL.Query(), time.Now())
+		switch {
+		case errors.Is(err, stores.ErrSignatureMissing):
+			if !config.GlobalConfig.MediaRequireSignature || isPublicMediaKey(key) {
+				c.Next()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		if userID != 0 {
+			if current, _ := sessions.Default(c).Get(constants.UserField).(uint); current != userID {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "media url is bound to another user"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// isPublicMediaKey 存储键是否以 MEDIA_PUBLIC_PREFIXES 中的前缀开头
+func isPublicMediaKey(key string) bool {
+	for _, prefix := range strings.Split(config.GlobalConfig.MediaPublicPrefixes, ",") {
+		prefix = strings.TrimPrefix(strings.TrimSpace(prefix), "/")
+		if prefix != "" && strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
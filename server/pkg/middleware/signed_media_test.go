@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/constants"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeRouterWithSignedMedia() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(WithMemSession("test-session-secret"))
+	r.GET("/login/:uid", func(c *gin.Context) {
+		session := sessions.Default(c)
+		if c.Param("uid") == "8" {
+			session.Set(constants.UserField, uint(8))
+		} else {
+			session.Set(constants.UserField, uint(7))
+		}
+		_ = session.Save()
+	})
+	r.GET("/media/*filepath", SignedMediaMiddleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return r
+}
+
+func doMediaRequest(r *gin.Engine, target, cookie string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSignedMediaMiddleware(t *testing.T) {
+	old := config.GlobalConfig
+	config.GlobalConfig = &config.Config{MediaSigningSecret: "secret", MediaPublicPrefixes: "avatars/"}
+	defer func() { config.GlobalConfig = old }()
+	r := makeRouterWithSignedMedia()
+
+	// 未强制签名时兼容历史地址
+	assert.Equal(t, http.StatusOK, doMediaRequest(r, "/media/recordings/7/a.wav", "").Code)
+
+	config.GlobalConfig.MediaRequireSignature = true
+	assert.Equal(t, http.StatusForbidden, doMediaRequest(r, "/media/recordings/7/a.wav", "").Code)
+	assert.Equal(t, http.StatusOK, doMediaRequest(r, "/media/avatars/7.png", "").Code)
+
+	// 不绑定用户的签名地址无需登录
+	assert.Equal(t, http.StatusOK, doMediaRequest(r, stores.SignedURL("recordings/7/a.wav", 0, time.Minute), "").Code)
+
+	signed := stores.SignedURL("recordings/7/a.wav", 7, time.Minute)
+	// 绑定用户的地址必须由该用户的登录会话使用
+	assert.Equal(t, http.StatusForbidden, doMediaRequest(r, signed, "").Code)
+	assert.Equal(t, http.StatusForbidden, doMediaRequest(r, strings.Replace(signed, "uid=7", "uid=8", 1), "").Code)
+	assert.Equal(t, http.StatusForbidden, doMediaRequest(r, strings.Replace(signed, "/7/a.wav", "/7/b.wav", 1), "").Code)
+
+	// 绑定用户后，其他已登录用户无法使用
+	other := doMediaRequest(r, "/login/8", "").Header().Get("Set-Cookie")
+	assert.Equal(t, http.StatusForbidden, doMediaRequest(r, signed, other).Code)
+	owner := doMediaRequest(r, "/login/7", "").Header().Get("Set-Cookie")
+	assert.Equal(t, http.StatusOK, doMediaRequest(r, signed, owner).Code)
+}
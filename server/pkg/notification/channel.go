@@ -0,0 +1,134 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+)
+
+// 通知渠道类型
+const (
+	ChannelTypeWebhook  = "webhook"
+	ChannelTypeSlack    = "slack"
+	ChannelTypeDingTalk = "dingtalk"
+	ChannelTypeFeishu   = "feishu"
+)
+
+// 通知事件，用户可以按事件订阅渠道
+const (
+	EventQuotaAlert        = "quota_alert"        // 配额告警
+	EventSystemAlert       = "system_alert"       // 其他告警
+	EventSuspiciousLogin   = "suspicious_login"   // 可疑登录
+	EventTrainingCompleted = "training_completed" // 音色训练完成（成功或失败）
+	EventMissedCall        = "missed_call"        // 未接来电
+	EventWorkflowResult    = "workflow_result"    // 工作流执行结果
+	EventWorkflowApproval  = "workflow_approval"  // 工作流待审批
+	EventTest              = "test"               // 渠道测试消息，总是发送
+)
+
+// 消息级别
+const (
+	LevelInfo    = "info"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// Message 渠道无关的通知消息
+type Message struct {
+	Event   string                 `json:"event"`
+	Level   string                 `json:"level,omitempty"`
+	Title   string                 `json:"title"`
+	Content string                 `json:"content"`
+	Link    string                 `json:"link,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// Channel 通知渠道
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// ChannelConfig 渠道配置
+type ChannelConfig struct {
+	WebhookURL string
+	Secret     string
+}
+
+// NewChannel 根据渠道类型创建通知渠道
+func NewChannel(channelType string, cfg ChannelConfig) (Channel, error) {
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+	switch channelType {
+	case ChannelTypeWebhook:
+		return NewWebhookNotification(WebhookConfig{URL: cfg.WebhookURL, Secret: cfg.Secret}), nil
+	case ChannelTypeSlack:
+		return NewSlackNotification(SlackConfig{WebhookURL: cfg.WebhookURL}), nil
+	case ChannelTypeDingTalk:
+		return NewDingTalkNotification(DingTalkConfig{WebhookURL: cfg.WebhookURL, Secret: cfg.Secret}), nil
+	case ChannelTypeFeishu:
+		return NewFeishuNotification(FeishuConfig{WebhookURL: cfg.WebhookURL, Secret: cfg.Secret}), nil
+	default:
+		return nil, fmt.Errorf("unsupported notification channel: %s", channelType)
+	}
+}
+
+// IsChannelTypeSupported 判断渠道类型是否受支持
+func IsChannelTypeSupported(channelType string) bool {
+	switch channelType {
+	case ChannelTypeWebhook, ChannelTypeSlack, ChannelTypeDingTalk, ChannelTypeFeishu:
+		return true
+	}
+	return false
+}
+
+// channelHTTPClient 渠道地址由用户填写，只允许访问公网地址
+var channelHTTPClient = utils.NewPublicHTTPClient(10 * time.Second)
+
+// postJSON 发送 JSON 请求并返回响应体
+func postJSON(ctx context.Context, url string, payload interface{}, headers map[string]string) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return postBody(ctx, url, body, headers)
+}
+
+func postBody(ctx context.Context, url string, body []byte, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "LingEcho-Notification/1.0")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := channelHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 400 {
+		// 不返回响应内容，错误信息会展示给配置渠道的用户
+		return respBody, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return respBody, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
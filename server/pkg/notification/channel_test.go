@@ -0,0 +1,166 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// allowLoopbackChannels 测试渠道指向本地的 httptest 服务，临时放开公网地址限制
+func allowLoopbackChannels(t *testing.T) {
+	previous := channelHTTPClient
+	channelHTTPClient = &http.Client{Timeout: 10 * time.Second}
+	t.Cleanup(func() { channelHTTPClient = previous })
+}
+
+func TestNewChannel(t *testing.T) {
+	for _, channelType := range []string{ChannelTypeWebhook, ChannelTypeSlack, ChannelTypeDingTalk, ChannelTypeFeishu} {
+		ch, err := NewChannel(channelType, ChannelConfig{WebhookURL: "https://example.com/hook"})
+		assert.NoError(t, err)
+		assert.Equal(t, channelType, ch.Name())
+	}
+
+	_, err := NewChannel("pager", ChannelConfig{WebhookURL: "https://example.com/hook"})
+	assert.Error(t, err)
+
+	_, err = NewChannel(ChannelTypeSlack, ChannelConfig{})
+	assert.Error(t, err)
+}
+
+func TestWebhookNotification_Signature(t *testing.T) {
+	allowLoopbackChannels(t)
+	var (
+		body    []byte
+		headers http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header
+	}))
+	defer server.Close()
+
+	ch := NewWebhookNotification(WebhookConfig{URL: server.URL, Secret: "s3cret"})
+	err := ch.Send(context.Background(), Message{Event: EventQuotaAlert, Title: "Quota", Content: "90% used"})
+	assert.NoError(t, err)
+
+	var payload map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, "Quota", payload["title"])
+	assert.Equal(t, EventQuotaAlert, headers.Get(WebhookEventHeader))
+
+	timestamp := headers.Get(WebhookTimestampHeader)
+	assert.Equal(t, "sha256="+SignWebhookPayload("s3cret", timestamp, body), headers.Get(WebhookSignatureHeader))
+}
+
+func TestWebhookNotification_ErrorStatus(t *testing.T) {
+	allowLoopbackChannels(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ch := NewWebhookNotification(WebhookConfig{URL: server.URL})
+	assert.Error(t, ch.Send(context.Background(), Message{Title: "Test"}))
+}
+
+func TestSlackNotification_Send(t *testing.T) {
+	allowLoopbackChannels(t)
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	ch := NewSlackNotification(SlackConfig{WebhookURL: server.URL})
+	err := ch.Send(context.Background(), Message{Title: "Training done", Content: "Voice is ready", Link: "https://example.com"})
+	assert.NoError(t, err)
+	assert.Contains(t, payload["text"], "*Training done*")
+	assert.Contains(t, payload["text"], "<https://example.com|")
+}
+
+func TestFeishuNotification_Send(t *testing.T) {
+	allowLoopbackChannels(t)
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write([]byte(`{"code":0,"msg":"success"}`))
+	}))
+	defer server.Close()
+
+	ch := NewFeishuNotification(FeishuConfig{WebhookURL: server.URL, Secret: "secret"})
+	assert.NoError(t, ch.Send(context.Background(), Message{Title: "Login", Content: "New device"}))
+	assert.Equal(t, "post", payload["msg_type"])
+	assert.Equal(t, feishuSign("secret", payload["timestamp"].(string)), payload["sign"])
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":19021,"msg":"sign match fail"}`))
+	}))
+	defer failing.Close()
+	ch = NewFeishuNotification(FeishuConfig{WebhookURL: failing.URL})
+	assert.Error(t, ch.SendText("hello"))
+}
+
+func TestChannelService_Notify(t *testing.T) {
+	allowLoopbackChannels(t)
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&UserNotificationChannel{}))
+
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	service := NewChannelService(db)
+	all := &UserNotificationChannel{UserID: 1, Type: ChannelTypeSlack, Name: "all", WebhookURL: server.URL + "/all", Enabled: true}
+	quota := &UserNotificationChannel{UserID: 1, Type: ChannelTypeWebhook, Name: "quota", WebhookURL: server.URL + "/quota", Enabled: true}
+	quota.SetEvents([]string{EventQuotaAlert})
+	other := &UserNotificationChannel{UserID: 2, Type: ChannelTypeSlack, Name: "other", WebhookURL: server.URL + "/other", Enabled: true}
+	for _, ch := range []*UserNotificationChannel{all, quota, other} {
+		assert.NoError(t, service.Save(ch))
+	}
+
+	deliveries := service.Notify(context.Background(), 1, Message{Event: EventSuspiciousLogin, Title: "Login"})
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, "all", deliveries[0].Name)
+
+	deliveries = service.Notify(context.Background(), 1, Message{Event: EventQuotaAlert, Title: "Quota"})
+	assert.Len(t, deliveries, 2)
+	assert.Equal(t, map[string]int{"/all": 2, "/quota": 1}, hits)
+
+	saved, err := service.Get(1, quota.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, saved.LastSentAt)
+
+	_, err = service.Get(2, quota.ID)
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+	assert.ErrorIs(t, service.Delete(2, quota.ID), ErrChannelNotFound)
+}
+
+func TestWebhookNotification_RefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal secret"))
+	}))
+	defer server.Close()
+
+	ch := NewWebhookNotification(WebhookConfig{URL: server.URL})
+	err := ch.Send(context.Background(), Message{Title: "Test"})
+	assert.True(t, errors.Is(err, utils.ErrBlockedAddress), "%v", err)
+
+	// 响应内容不出现在错误信息中
+	allowLoopbackChannels(t)
+	err = ch.Send(context.Background(), Message{Title: "Test"})
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "internal secret")
+}
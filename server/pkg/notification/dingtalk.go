@@ -0,0 +1,100 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// 钉钉推送
+type DingTalkConfig struct {
+	WebhookURL string
+	Secret     string // 加签密钥，机器人安全设置为"加签"时必填
+}
+
+type DingTalkNotification struct {
+	config DingTalkConfig
+}
+
+func NewDingTalkNotification(config DingTalkConfig) *DingTalkNotification {
+	return &DingTalkNotification{config: config}
+}
+
+func (d *DingTalkNotification) Name() string { return ChannelTypeDingTalk }
+
+// Send 以 Markdown 消息发送
+func (d *DingTalkNotification) Send(ctx context.Context, msg Message) error {
+	content := "### " + msg.Title
+	if msg.Content != "" {
+		content += "\n\n" + msg.Content
+	}
+	if msg.Link != "" {
+		content += fmt.Sprintf("\n\n[查看详情](%s)", msg.Link)
+	}
+	return d.send(ctx, map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"title": msg.Title, "text": content},
+	})
+}
+
+func (d *DingTalkNotification) SendText(content string) error {
+	return d.send(context.Background(), map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": content},
+	})
+}
+
+func (d *DingTalkNotification) SendMarkdown(title, content string) error {
+	return d.send(context.Background(), map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"title": title, "text": content},
+	})
+}
+
+func (d *DingTalkNotification) send(ctx context.Context, payload map[string]interface{}) error {
+	webhook, err := d.signedURL(time.Now())
+	if err != nil {
+		return err
+	}
+	body, err := postJSON(ctx, webhook, payload, nil)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("invalid dingtalk response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("dingtalk error %d: %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+// signedURL 加签：sign = urlencode(base64(HMAC-SHA256(secret, timestamp + "\n" + secret)))
+func (d *DingTalkNotification) signedURL(now time.Time) (string, error) {
+	if d.config.Secret == "" {
+		return d.config.WebhookURL, nil
+	}
+	u, err := url.Parse(d.config.WebhookURL)
+	if err != nil {
+		return "", err
+	}
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(d.config.Secret))
+	mac.Write([]byte(timestamp + "\n" + d.config.Secret))
+
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -1,25 +1,5 @@
 package notification
 
-// 钉钉推送
-type DingTalkConfig struct {
-	WebhookURL string
-	Secret     string
-}
-
-type DingTalkNotification struct {
-	config DingTalkConfig
-}
-
-func (d *DingTalkNotification) SendText(content string) error {
-	// TODO: 实现钉钉文本消息发送
-	return nil
-}
-
-func (d *DingTalkNotification) SendMarkdown(title, content string) error {
-	// TODO: 实现钉钉Markdown消息发送
-	return nil
-}
-
 // 企业微信推送
 type WeChatWorkConfig struct {
 	CorpID  string
@@ -31,12 +11,6 @@ type WeChatWorkNotification struct {
 	config WeChatWorkConfig
 }
 
-// 飞书推送
-type FeishuConfig struct {
-	WebhookURL string
-	Secret     string
-}
-
 // 邮件模板引擎
 type EmailTemplate struct {
 	Name     string
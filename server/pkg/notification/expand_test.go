@@ -1,34 +1,55 @@
 package notification
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
 func TestDingTalkNotification_SendText(t *testing.T) {
+	allowLoopbackChannels(t)
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sign") == "" || r.URL.Query().Get("timestamp") == "" {
+			t.Errorf("request is not signed: %s", r.URL.RawQuery)
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
 	config := DingTalkConfig{
-		WebhookURL: "https://oapi.dingtalk.com/robot/send",
+		WebhookURL: server.URL + "/robot/send?access_token=token",
 		Secret:     "test-secret",
 	}
 
 	notif := DingTalkNotification{config: config}
 	err := notif.SendText("Test message")
-	// Currently returns nil (not implemented)
 	if err != nil {
 		t.Errorf("SendText returned error: %v", err)
 	}
+	if received["msgtype"] != "text" {
+		t.Errorf("msgtype = %v, want text", received["msgtype"])
+	}
 }
 
 func TestDingTalkNotification_SendMarkdown(t *testing.T) {
+	allowLoopbackChannels(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+	}))
+	defer server.Close()
+
 	config := DingTalkConfig{
-		WebhookURL: "https://oapi.dingtalk.com/robot/send",
+		WebhookURL: server.URL + "/robot/send",
 		Secret:     "test-secret",
 	}
 
 	notif := DingTalkNotification{config: config}
 	err := notif.SendMarkdown("Title", "# Markdown Content")
-	// Currently returns nil (not implemented)
-	if err != nil {
-		t.Errorf("SendMarkdown returned error: %v", err)
+	if err == nil {
+		t.Errorf("SendMarkdown should return the dingtalk error")
 	}
 }
 
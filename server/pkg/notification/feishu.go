@@ -0,0 +1,84 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// 飞书推送
+type FeishuConfig struct {
+	WebhookURL string
+	Secret     string // 签名校验密钥，可选
+}
+
+type FeishuNotification struct {
+	config FeishuConfig
+}
+
+func NewFeishuNotification(config FeishuConfig) *FeishuNotification {
+	return &FeishuNotification{config: config}
+}
+
+func (f *FeishuNotification) Name() string { return ChannelTypeFeishu }
+
+// Send 以富文本（post）消息发送
+func (f *FeishuNotification) Send(ctx context.Context, msg Message) error {
+	var lines [][]map[string]string
+	if msg.Content != "" {
+		lines = append(lines, []map[string]string{{"tag": "text", "text": msg.Content}})
+	}
+	if msg.Link != "" {
+		lines = append(lines, []map[string]string{{"tag": "a", "text": "查看详情", "href": msg.Link}})
+	}
+	return f.send(ctx, map[string]interface{}{
+		"msg_type": "post",
+		"content": map[string]interface{}{
+			"post": map[string]interface{}{
+				"zh_cn": map[string]interface{}{"title": msg.Title, "content": lines},
+			},
+		},
+	})
+}
+
+// SendText 发送文本消息
+func (f *FeishuNotification) SendText(content string) error {
+	return f.send(context.Background(), map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": content},
+	})
+}
+
+func (f *FeishuNotification) send(ctx context.Context, payload map[string]interface{}) error {
+	if f.config.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		payload["timestamp"] = timestamp
+		payload["sign"] = feishuSign(f.config.Secret, timestamp)
+	}
+	body, err := postJSON(ctx, f.config.WebhookURL, payload, nil)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("invalid feishu response: %w", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("feishu error %d: %s", result.Code, result.Msg)
+	}
+	return nil
+}
+
+// feishuSign 签名：base64(HMAC-SHA256(key = timestamp + "\n" + secret, data = 空))
+func feishuSign(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
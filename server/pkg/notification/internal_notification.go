@@ -8,12 +8,15 @@ import (
 
 // InternalNotification 站内通知
 type InternalNotification struct {
-	ID        uint      `json:"id" gorm:"primaryKey"` // 通知 ID
-	UserID    uint      `json:"user_id"`              // 用户 ID
-	Title     string    `json:"title"`                // 通知标题
-	Content   string    `json:"content"`              // 通知内容
-	Read      bool      `json:"read"`                 // 是否已读
-	CreatedAt time.Time `json:"created_at"`           // 创建时间
+	ID        uint      `json:"id" gorm:"primaryKey"`                 // 通知 ID
+	UserID    uint      `json:"user_id"`                              // 用户 ID
+	Event     string    `json:"event,omitempty" gorm:"size:64;index"` // 通知事件，如 quota_alert
+	Level     string    `json:"level,omitempty" gorm:"size:20"`       // 消息级别
+	Title     string    `json:"title"`                                // 通知标题
+	Content   string    `json:"content"`                              // 通知内容
+	Link      string    `json:"link,omitempty" gorm:"size:500"`       // 跳转链接
+	Read      bool      `json:"read"`                                 // 是否已读
+	CreatedAt time.Time `json:"created_at"`                           // 创建时间
 }
 
 // InternalNotificationService 站内通知服务
@@ -40,6 +43,23 @@ func (s *InternalNotificationService) Send(userID uint, title, content string) e
 	return s.DB.Create(&notification).Error
 }
 
+// SendMessage 将通知消息存入用户的站内信箱，返回创建的通知
+func (s *InternalNotificationService) SendMessage(userID uint, msg Message) (*InternalNotification, error) {
+	notification := InternalNotification{
+		UserID:    userID,
+		Event:     msg.Event,
+		Level:     msg.Level,
+		Title:     msg.Title,
+		Content:   msg.Content,
+		Link:      msg.Link,
+		CreatedAt: time.Now(),
+	}
+	if err := s.DB.Create(&notification).Error; err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
 // GetUnreadNotifications 获取用户的未读通知
 func (s *InternalNotificationService) GetUnreadNotifications(userID uint) ([]InternalNotification, error) {
 	var notifications []InternalNotification
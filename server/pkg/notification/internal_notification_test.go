@@ -47,6 +47,28 @@ func TestInternalNotificationService_Send(t *testing.T) {
 	assert.False(t, notification.Read)
 }
 
+func TestInternalNotificationService_SendMessage(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewInternalNotificationService(db)
+
+	created, err := service.SendMessage(1, Message{
+		Event:   EventQuotaAlert,
+		Level:   LevelWarning,
+		Title:   "Quota alert",
+		Content: "90% of the monthly quota used",
+		Link:    "/billing",
+	})
+	assert.NoError(t, err)
+	assert.NotZero(t, created.ID)
+
+	var notification InternalNotification
+	assert.NoError(t, db.First(&notification, created.ID).Error)
+	assert.Equal(t, EventQuotaAlert, notification.Event)
+	assert.Equal(t, LevelWarning, notification.Level)
+	assert.Equal(t, "/billing", notification.Link)
+	assert.False(t, notification.Read)
+}
+
 func TestInternalNotificationService_GetUnreadNotifications(t *testing.T) {
 	db := setupTestDB(t)
 	service := NewInternalNotificationService(db)
@@ -0,0 +1,200 @@
+package notification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 推送平台
+const (
+	PlatformAndroid = "android" // FCM
+	PlatformIOS     = "ios"     // APNs
+)
+
+// ErrInvalidPushToken 设备 token 已失效（应用被卸载或 token 已轮换），应删除
+var ErrInvalidPushToken = errors.New("push token is invalid or unregistered")
+
+// PushConfig 移动推送配置
+type PushConfig struct {
+	FCM  FCMConfig
+	APNs APNsConfig
+}
+
+// PushSender 单个平台的推送发送器
+type PushSender interface {
+	Send(ctx context.Context, token string, msg Message) error
+}
+
+// PushDeviceToken 移动设备推送 token
+type PushDeviceToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	UserID     uint   `json:"userId" gorm:"index"`                 // 用户 ID
+	Platform   string `json:"platform" gorm:"size:20"`             // 平台：android, ios
+	Token      string `json:"token" gorm:"size:512;uniqueIndex"`   // FCM registration token 或 APNs device token
+	DeviceID   string `json:"deviceId,omitempty" gorm:"size:128"`  // 客户端设备标识
+	AppVersion string `json:"appVersion,omitempty" gorm:"size:32"` // App 版本
+	LastError  string `json:"lastError,omitempty" gorm:"size:500"` // 最近一次推送错误
+
+	LastSentAt *time.Time `json:"lastSentAt,omitempty"` // 最近一次推送时间
+}
+
+func (PushDeviceToken) TableName() string {
+	return "push_device_tokens"
+}
+
+// PushService 移动推送服务
+type PushService struct {
+	DB      *gorm.DB
+	Senders map[string]PushSender // 按平台索引，未配置的平台不推送
+}
+
+// NewPushService 创建推送服务实例
+func NewPushService(db *gorm.DB, senders map[string]PushSender) *PushService {
+	return &PushService{DB: db, Senders: senders}
+}
+
+// NewPushSenders 根据配置创建各平台的发送器，未配置的平台会被跳过
+func NewPushSenders(config PushConfig) (map[string]PushSender, error) {
+	senders := make(map[string]PushSender)
+	if config.FCM.CredentialsFile != "" {
+		fcm, err := NewFCMSender(config.FCM)
+		if err != nil {
+			return nil, fmt.Errorf("fcm: %w", err)
+		}
+		senders[PlatformAndroid] = fcm
+	}
+	if config.APNs.KeyFile != "" {
+		apns, err := NewAPNsSender(config.APNs)
+		if err != nil {
+			return nil, fmt.Errorf("apns: %w", err)
+		}
+		senders[PlatformIOS] = apns
+	}
+	return senders, nil
+}
+
+// RegisterToken 注册或更新设备 token，同一 token 重新登录到其他账号时会转移到新用户
+func (s *PushService) RegisterToken(userID uint, platform, token, deviceID, appVersion string) (*PushDeviceToken, error) {
+	if platform != PlatformAndroid && platform != PlatformIOS {
+		return nil, fmt.Errorf("unsupported push platform: %s", platform)
+	}
+	if token == "" {
+		return nil, errors.New("push token is required")
+	}
+
+	var device PushDeviceToken
+	err := s.DB.Where("token = ?", token).First(&device).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	device.UserID = userID
+	device.Platform = platform
+	device.Token = token
+	device.DeviceID = deviceID
+	device.AppVersion = appVersion
+	device.LastError = ""
+	if err := s.DB.Save(&device).Error; err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// UnregisterToken 注销用户的设备 token（如退出登录）
+func (s *PushService) UnregisterToken(userID uint, token string) error {
+	return s.DB.Where("user_id = ? AND token = ?", userID, token).Delete(&PushDeviceToken{}).Error
+}
+
+// ListTokens 获取用户已注册的设备
+func (s *PushService) ListTokens(userID uint) ([]PushDeviceToken, error) {
+	var devices []PushDeviceToken
+	err := s.DB.Where("user_id = ?", userID).Order("updated_at DESC").Find(&devices).Error
+	return devices, err
+}
+
+// SendToUser 推送到用户的所有设备，返回成功推送的设备数。失效的 token 会被删除
+func (s *PushService) SendToUser(ctx context.Context, userID uint, msg Message) (int, error) {
+	devices, err := s.ListTokens(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var errs []error
+	for _, device := range devices {
+		sender, ok := s.Senders[device.Platform]
+		if !ok {
+			continue
+		}
+		err := sender.Send(ctx, device.Token, msg)
+		switch {
+		case errors.Is(err, ErrInvalidPushToken):
+			s.DB.Delete(&PushDeviceToken{}, device.ID)
+		case err != nil:
+			errs = append(errs, fmt.Errorf("device %d: %w", device.ID, err))
+			s.DB.Model(&PushDeviceToken{}).Where("id = ?", device.ID).Update("last_error", truncate(err.Error(), 490))
+		default:
+			sent++
+			s.DB.Model(&PushDeviceToken{}).Where("id = ?", device.ID).Updates(map[string]interface{}{
+				"last_error":   "",
+				"last_sent_at": time.Now(),
+			})
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// pushData 将消息转换为推送的自定义数据，两个平台的数据值都只能是字符串
+func pushData(msg Message) map[string]string {
+	data := map[string]string{"event": msg.Event}
+	if msg.Link != "" {
+		data["link"] = msg.Link
+	}
+	for k, v := range msg.Data {
+		data[k] = fmt.Sprint(v)
+	}
+	return data
+}
+
+// jwtSigningInput 构造 JWT 的待签名部分 base64url(header).base64url(claims)
+func jwtSigningInput(header, claims map[string]interface{}) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c), nil
+}
+
+// signES256JWT 生成 ES256 签名的 JWT
+func signES256JWT(key *ecdsa.PrivateKey, header, claims map[string]interface{}) (string, error) {
+	input, err := jwtSigningInput(header, claims)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS 的 ES256 签名是定长的 R || S
+	size := (key.Curve.Params().BitSize + 7) / 8
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,164 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	apnsProductionEndpoint  = "https://api.push.apple.com"
+	apnsDevelopmentEndpoint = "https://api.sandbox.push.apple.com"
+	// APNs 要求 provider token 每 20~60 分钟刷新一次
+	apnsTokenLifetime = 50 * time.Minute
+)
+
+// APNsConfig Apple Push Notification service（基于 token 的认证）配置
+type APNsConfig struct {
+	KeyFile    string // .p8 签名密钥
+	KeyID      string // 密钥 ID
+	TeamID     string // 开发者团队 ID
+	Topic      string // App Bundle ID
+	Production bool   // 是否使用生产环境
+}
+
+// APNsSender 通过 APNs 推送 iOS 通知
+type APNsSender struct {
+	config   APNsConfig
+	key      *ecdsa.PrivateKey
+	endpoint string
+	client   *http.Client
+
+	mu       sync.Mutex
+	jwt      string
+	issuedAt time.Time
+}
+
+// NewAPNsSender 创建 APNs 发送器
+func NewAPNsSender(config APNsConfig) (*APNsSender, error) {
+	data, err := os.ReadFile(config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewAPNsSenderFromKey(config, data)
+}
+
+// NewAPNsSenderFromKey 使用 PEM 格式的 .p8 密钥内容创建 APNs 发送器
+func NewAPNsSenderFromKey(config APNsConfig, keyPEM []byte) (*APNsSender, error) {
+	if config.KeyID == "" || config.TeamID == "" || config.Topic == "" {
+		return nil, errors.New("apns key id, team id and topic are required")
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("invalid apns key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apns key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("apns key is not an ECDSA key")
+	}
+
+	endpoint := apnsDevelopmentEndpoint
+	if config.Production {
+		endpoint = apnsProductionEndpoint
+	}
+	return &APNsSender{
+		config:   config,
+		key:      key,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Send 推送一条通知到设备
+func (a *APNsSender) Send(ctx context.Context, token string, msg Message) error {
+	providerToken, err := a.providerToken()
+	if err != nil {
+		return fmt.Errorf("failed to sign apns token: %w", err)
+	}
+
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{"title": msg.Title, "body": msg.Content},
+			"sound": "default",
+		},
+	}
+	for k, v := range pushData(msg) {
+		if k != "aps" {
+			payload[k] = v
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", a.config.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		result.Reason == "BadDeviceToken",
+		result.Reason == "Unregistered",
+		result.Reason == "DeviceTokenNotForTopic":
+		return ErrInvalidPushToken
+	case result.Reason == "ExpiredProviderToken":
+		a.mu.Lock()
+		a.jwt = ""
+		a.mu.Unlock()
+	}
+	return fmt.Errorf("apns returned status %d: %s", resp.StatusCode, result.Reason)
+}
+
+// providerToken 生成 ES256 签名的 provider token，有效期内复用
+func (a *APNsSender) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if a.jwt != "" && now.Sub(a.issuedAt) < apnsTokenLifetime {
+		return a.jwt, nil
+	}
+
+	token, err := signES256JWT(a.key,
+		map[string]interface{}{"alg": "ES256", "kid": a.config.KeyID},
+		map[string]interface{}{"iss": a.config.TeamID, "iat": now.Unix()},
+	)
+	if err != nil {
+		return "", err
+	}
+	a.jwt = token
+	a.issuedAt = now
+	return a.jwt, nil
+}
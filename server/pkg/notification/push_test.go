@@ -0,0 +1,146 @@
+package notification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePushSender struct {
+	sent    []string
+	invalid map[string]bool
+}
+
+func (f *fakePushSender) Send(ctx context.Context, token string, msg Message) error {
+	if f.invalid[token] {
+		return ErrInvalidPushToken
+	}
+	f.sent = append(f.sent, token)
+	return nil
+}
+
+func TestPushService_SendToUser(t *testing.T) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&PushDeviceToken{}))
+
+	android := &fakePushSender{invalid: map[string]bool{"stale": true}}
+	service := NewPushService(db, map[string]PushSender{PlatformAndroid: android})
+
+	_, err := service.RegisterToken(1, "windows", "t", "", "")
+	assert.Error(t, err)
+
+	_, err = service.RegisterToken(1, PlatformAndroid, "fresh", "d1", "1.0.0")
+	assert.NoError(t, err)
+	_, err = service.RegisterToken(1, PlatformAndroid, "stale", "d2", "1.0.0")
+	assert.NoError(t, err)
+	// iOS 未配置发送器，跳过
+	_, err = service.RegisterToken(1, PlatformIOS, "apple", "d3", "1.0.0")
+	assert.NoError(t, err)
+	// 同一 token 被其他用户注册时转移
+	_, err = service.RegisterToken(2, PlatformAndroid, "moved", "", "")
+	assert.NoError(t, err)
+	_, err = service.RegisterToken(1, PlatformAndroid, "moved", "", "")
+	assert.NoError(t, err)
+
+	sent, err := service.SendToUser(context.Background(), 1, Message{Event: EventMissedCall, Title: "Missed call"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	assert.ElementsMatch(t, []string{"fresh", "moved"}, android.sent)
+
+	// 失效的 token 被删除
+	devices, err := service.ListTokens(1)
+	assert.NoError(t, err)
+	assert.Len(t, devices, 3)
+	for _, d := range devices {
+		assert.NotEqual(t, "stale", d.Token)
+	}
+}
+
+func TestFCMSender_Send(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	tokenRequests := 0
+	var message map[string]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			tokenRequests++
+			r.ParseForm()
+			assert.Equal(t, 3, len(strings.Split(r.Form.Get("assertion"), ".")))
+			w.Write([]byte(`{"access_token":"access","expires_in":3600}`))
+		case r.URL.Path == "/v1/projects/demo/messages:send":
+			assert.Equal(t, "Bearer access", r.Header.Get("Authorization"))
+			json.NewDecoder(r.Body).Decode(&message)
+			if message["message"]["token"] == "gone" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	credentials, _ := json.Marshal(map[string]string{
+		"project_id":   "demo",
+		"client_email": "push@demo.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL + "/token",
+	})
+	sender, err := NewFCMSenderFromJSON(credentials, "")
+	assert.NoError(t, err)
+	sender.endpoint = server.URL
+
+	msg := Message{Event: EventTrainingCompleted, Title: "Training", Content: "Done", Data: map[string]interface{}{"taskId": 7}}
+	assert.NoError(t, sender.Send(context.Background(), "device", msg))
+	assert.Equal(t, "7", message["message"]["data"].(map[string]interface{})["taskId"])
+
+	err = sender.Send(context.Background(), "gone", msg)
+	assert.True(t, errors.Is(err, ErrInvalidPushToken))
+	assert.Equal(t, 1, tokenRequests, "access token should be cached")
+}
+
+func TestAPNsSender_Send(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	var headers http.Header
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		if strings.HasSuffix(r.URL.Path, "/gone") {
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"Unregistered"}`))
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	config := APNsConfig{KeyID: "KEY123", TeamID: "TEAM123", Topic: "com.lingecho.app"}
+	sender, err := NewAPNsSenderFromKey(config, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.NoError(t, err)
+	sender.endpoint = server.URL
+	sender.client = server.Client()
+
+	assert.NoError(t, sender.Send(context.Background(), "device", Message{Title: "Missed call"}))
+	assert.Equal(t, "com.lingecho.app", headers.Get("apns-topic"))
+	assert.True(t, strings.HasPrefix(headers.Get("Authorization"), "bearer "))
+
+	err = sender.Send(context.Background(), "gone", Message{Title: "Missed call"})
+	assert.True(t, errors.Is(err, ErrInvalidPushToken))
+}
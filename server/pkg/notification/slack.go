@@ -0,0 +1,47 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Slack Incoming Webhook 推送
+type SlackConfig struct {
+	WebhookURL string
+}
+
+type SlackNotification struct {
+	config SlackConfig
+}
+
+func NewSlackNotification(config SlackConfig) *SlackNotification {
+	return &SlackNotification{config: config}
+}
+
+func (s *SlackNotification) Name() string { return ChannelTypeSlack }
+
+// Send 发送消息，标题加粗，链接放在末尾
+func (s *SlackNotification) Send(ctx context.Context, msg Message) error {
+	text := "*" + msg.Title + "*"
+	if msg.Content != "" {
+		text += "\n" + msg.Content
+	}
+	if msg.Link != "" {
+		text += fmt.Sprintf("\n<%s|查看详情>", msg.Link)
+	}
+	return s.SendText(ctx, text)
+}
+
+// SendText 发送文本消息，支持 Slack mrkdwn 语法
+func (s *SlackNotification) SendText(ctx context.Context, text string) error {
+	body, err := postJSON(ctx, s.config.WebhookURL, map[string]string{"text": text}, nil)
+	if err != nil {
+		return err
+	}
+	// Slack 成功时返回纯文本 ok
+	if resp := strings.TrimSpace(string(body)); resp != "" && resp != "ok" {
+		return fmt.Errorf("slack webhook returned an unexpected response")
+	}
+	return nil
+}
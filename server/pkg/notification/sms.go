@@ -0,0 +1,184 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 短信服务商
+const (
+	SMSProviderAliyun  = "aliyun"
+	SMSProviderTencent = "tencent"
+	SMSProviderTwilio  = "twilio"
+)
+
+// 短信模板
+const (
+	SMSTemplateVerifyCode      = "verify_code"      // 手机验证码，参数：code
+	SMSTemplateSuspiciousLogin = "suspicious_login" // 异地登录提醒，参数：location、time
+)
+
+var (
+	ErrSMSNotConfigured = errors.New("sms provider not configured")
+	ErrSMSRateLimited   = errors.New("too many sms sent to this phone number, please try again later")
+)
+
+// smsTemplateParams 各模板参数的顺序，腾讯云短信模板按位置（{1}、{2}）填充参数
+var smsTemplateParams = map[string][]string{
+	SMSTemplateVerifyCode:      {"code"},
+	SMSTemplateSuspiciousLogin: {"location", "time"},
+}
+
+// smsTemplateBodies 不支持服务端模板的服务商（Twilio）使用的短信正文，{name} 替换为对应参数
+var smsTemplateBodies = map[string]string{
+	SMSTemplateVerifyCode:      "Your LingEcho verification code is {code}. Do not share it with anyone.",
+	SMSTemplateSuspiciousLogin: "LingEcho: your account was signed in from {location} at {time}. If this was not you, change your password immediately.",
+}
+
+// SMSMessage 模板短信
+type SMSMessage struct {
+	Template string            // 模板名，如 SMSTemplateVerifyCode
+	Params   map[string]string // 模板参数
+}
+
+// OrderedParams 按模板参数顺序返回参数值
+func (m SMSMessage) OrderedParams() []string {
+	names := smsTemplateParams[m.Template]
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, m.Params[name])
+	}
+	return values
+}
+
+// Body 渲染短信正文
+func (m SMSMessage) Body() string {
+	body, ok := smsTemplateBodies[m.Template]
+	if !ok {
+		return ""
+	}
+	for name, value := range m.Params {
+		body = strings.ReplaceAll(body, "{"+name+"}", value)
+	}
+	return body
+}
+
+// SMSSender 短信服务商发送接口
+type SMSSender interface {
+	Send(ctx context.Context, phone string, msg SMSMessage) error
+}
+
+// SMSConfig 短信配置
+type SMSConfig struct {
+	Provider    string // aliyun、tencent、twilio，为空表示不发送短信
+	Aliyun      AliyunSMSConfig
+	Tencent     TencentSMSConfig
+	Twilio      TwilioSMSConfig
+	Interval    time.Duration // 同一号码两次发送的最小间隔
+	HourlyLimit int           // 同一号码每小时最多发送条数，0 表示不限制
+}
+
+// NewSMSSender 根据配置创建短信发送器，未配置服务商时返回 nil
+func NewSMSSender(config SMSConfig) (SMSSender, error) {
+	switch config.Provider {
+	case "":
+		return nil, nil
+	case SMSProviderAliyun:
+		if config.Aliyun.AccessKeyId == "" || config.Aliyun.AccessKeySecret == "" {
+			return nil, errors.New("aliyun sms: access key is required")
+		}
+		return NewAliyunSMS(config.Aliyun, NewAliyunSMSClient(config.Aliyun)), nil
+	case SMSProviderTencent:
+		if config.Tencent.SecretID == "" || config.Tencent.SecretKey == "" || config.Tencent.SdkAppID == "" {
+			return nil, errors.New("tencent sms: secret id, secret key and sdk app id are required")
+		}
+		return NewTencentSMS(config.Tencent), nil
+	case SMSProviderTwilio:
+		if config.Twilio.AccountSID == "" || config.Twilio.AuthToken == "" || config.Twilio.From == "" {
+			return nil, errors.New("twilio sms: account sid, auth token and from number are required")
+		}
+		return NewTwilioSMS(config.Twilio), nil
+	}
+	return nil, fmt.Errorf("unsupported sms provider: %s", config.Provider)
+}
+
+// SMSService 带单号码频率限制的短信服务
+type SMSService struct {
+	sender      SMSSender
+	interval    time.Duration
+	hourlyLimit int
+
+	mu   sync.Mutex
+	sent map[string][]time.Time // 号码最近一小时内的发送时间
+	now  func() time.Time
+}
+
+// NewSMSService 创建短信服务，sender 为 nil 时 Send 返回 ErrSMSNotConfigured
+func NewSMSService(sender SMSSender, interval time.Duration, hourlyLimit int) *SMSService {
+	return &SMSService{
+		sender:      sender,
+		interval:    interval,
+		hourlyLimit: hourlyLimit,
+		sent:        make(map[string][]time.Time),
+		now:         time.Now,
+	}
+}
+
+// Configured 是否配置了短信服务商
+func (s *SMSService) Configured() bool {
+	return s != nil && s.sender != nil
+}
+
+// Allowed 号码当前是否还能发送短信
+func (s *SMSService) Allowed(phone string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.allowedLocked(phone, s.now())
+}
+
+func (s *SMSService) allowedLocked(phone string, now time.Time) bool {
+	recent := s.sent[phone][:0]
+	for _, t := range s.sent[phone] {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) == 0 {
+		delete(s.sent, phone)
+	} else {
+		s.sent[phone] = recent
+	}
+
+	if s.hourlyLimit > 0 && len(recent) >= s.hourlyLimit {
+		return false
+	}
+	if n := len(recent); n > 0 && now.Sub(recent[n-1]) < s.interval {
+		return false
+	}
+	return true
+}
+
+// Send 发送模板短信，超过号码的发送频率时返回 ErrSMSRateLimited
+func (s *SMSService) Send(ctx context.Context, phone string, msg SMSMessage) error {
+	if !s.Configured() {
+		return ErrSMSNotConfigured
+	}
+	if phone == "" {
+		return errors.New("phone number is required")
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	if !s.allowedLocked(phone, now) {
+		s.mu.Unlock()
+		return ErrSMSRateLimited
+	}
+	s.sent[phone] = append(s.sent[phone], now)
+	s.mu.Unlock()
+
+	return s.sender.Send(ctx, phone, msg)
+}
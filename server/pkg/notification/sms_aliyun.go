@@ -2,15 +2,30 @@ package notification
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 )
 
+const aliyunSMSEndpoint = "https://dysmsapi.aliyuncs.com/"
+
 type AliyunSMSConfig struct {
 	AccessKeyId     string
 	AccessKeySecret string
 	SignName        string
-	TemplateCode    string
-	Endpoint        string // 默认 cn-hangzhou
+	TemplateCode    string            // 验证码模板 CODE
+	Templates       map[string]string // 其他模板名 → 模板 CODE，如 suspicious_login
+	Endpoint        string            // 默认 cn-hangzhou
 }
 
 type AliyunSMS struct {
@@ -28,9 +43,118 @@ func NewAliyunSMS(cfg AliyunSMSConfig, cli AliyunSMSClient) *AliyunSMS {
 }
 
 func (a *AliyunSMS) SendCode(ctx context.Context, phone, code string) error {
+	return a.Send(ctx, phone, SMSMessage{Template: SMSTemplateVerifyCode, Params: map[string]string{"code": code}})
+}
+
+// Send 使用模板对应的模板 CODE 发送短信
+func (a *AliyunSMS) Send(ctx context.Context, phone string, msg SMSMessage) error {
 	if a.cli == nil {
 		return fmt.Errorf("AliyunSMSClient not configured")
 	}
-	params := map[string]string{"code": code}
-	return a.cli.Send(ctx, phone, a.cfg.SignName, a.cfg.TemplateCode, params)
+	template := a.cfg.Templates[msg.Template]
+	if msg.Template == SMSTemplateVerifyCode && a.cfg.TemplateCode != "" {
+		template = a.cfg.TemplateCode
+	}
+	if template == "" {
+		return fmt.Errorf("aliyun sms: no template code for %s", msg.Template)
+	}
+	return a.cli.Send(ctx, phone, a.cfg.SignName, template, msg.Params)
+}
+
+// aliyunSMSClient 通过短信服务 OpenAPI（RPC 风格签名）发送短信
+type aliyunSMSClient struct {
+	accessKeyID     string
+	accessKeySecret string
+	region          string
+	endpoint        string
+	client          *http.Client
+}
+
+// NewAliyunSMSClient 创建调用阿里云短信服务 SendSms 接口的客户端
+func NewAliyunSMSClient(cfg AliyunSMSConfig) AliyunSMSClient {
+	region := cfg.Endpoint
+	if region == "" {
+		region = "cn-hangzhou"
+	}
+	return &aliyunSMSClient{
+		accessKeyID:     cfg.AccessKeyId,
+		accessKeySecret: cfg.AccessKeySecret,
+		region:          region,
+		endpoint:        aliyunSMSEndpoint,
+		client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (a *aliyunSMSClient) Send(ctx context.Context, phone, sign, template string, params map[string]string) error {
+	templateParam, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	query := url.Values{
+		"AccessKeyId":      {a.accessKeyID},
+		"Action":           {"SendSms"},
+		"Format":           {"JSON"},
+		"PhoneNumbers":     {phone},
+		"RegionId":         {a.region},
+		"SignName":         {sign},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureNonce":   {uuid.NewString()},
+		"SignatureVersion": {"1.0"},
+		"TemplateCode":     {template},
+		"TemplateParam":    {string(templateParam)},
+		"Timestamp":        {time.Now().UTC().Format("2006-01-02T15:04:05Z")},
+		"Version":          {"2017-05-25"},
+	}
+	query.Set("Signature", aliyunSignature(http.MethodPost, query, a.accessKeySecret))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint, strings.NewReader(query.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var result struct {
+		Code    string `json:"Code"`
+		Message string `json:"Message"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("aliyun sms: unexpected response (status %d): %s", resp.StatusCode, truncate(string(body), 200))
+	}
+	if result.Code != "OK" {
+		return fmt.Errorf("aliyun sms: %s: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+// aliyunSignature 计算 RPC 风格接口的签名（HMAC-SHA1）
+func aliyunSignature(method string, query url.Values, secret string) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, aliyunPercentEncode(key)+"="+aliyunPercentEncode(query.Get(key)))
+	}
+	stringToSign := method + "&" + aliyunPercentEncode("/") + "&" + aliyunPercentEncode(strings.Join(pairs, "&"))
+
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliyunPercentEncode 按 RFC 3986 编码，空格编码为 %20，~ 不编码
+func aliyunPercentEncode(s string) string {
+	encoded := url.QueryEscape(s)
+	encoded = strings.ReplaceAll(encoded, "+", "%20")
+	encoded = strings.ReplaceAll(encoded, "*", "%2A")
+	return strings.ReplaceAll(encoded, "%7E", "~")
 }
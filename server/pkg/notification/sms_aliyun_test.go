@@ -3,6 +3,9 @@ package notification
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 )
 
@@ -125,3 +128,70 @@ func TestAliyunSMSConfig_Structure(t *testing.T) {
 		t.Error("SignName should not be empty")
 	}
 }
+
+func TestAliyunSMS_SendTemplate(t *testing.T) {
+	var gotTemplate string
+	mockClient := &mockAliyunSMSClient{
+		sendFunc: func(ctx context.Context, phone, sign, template string, params map[string]string) error {
+			gotTemplate = template
+			return nil
+		},
+	}
+	sms := NewAliyunSMS(AliyunSMSConfig{
+		SignName:  "TestSign",
+		Templates: map[string]string{SMSTemplateSuspiciousLogin: "SMS_654321"},
+	}, mockClient)
+
+	err := sms.Send(context.Background(), "13800138000", SMSMessage{Template: SMSTemplateSuspiciousLogin})
+	if err != nil || gotTemplate != "SMS_654321" {
+		t.Errorf("expected template SMS_654321, got %s (%v)", gotTemplate, err)
+	}
+	if err := sms.Send(context.Background(), "13800138000", SMSMessage{Template: SMSTemplateVerifyCode}); err == nil {
+		t.Error("expected error for template without code")
+	}
+}
+
+func TestAliyunSMSClient_Send(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") != "SendSms" || r.Form.Get("PhoneNumbers") != "13800138000" {
+			t.Errorf("unexpected form: %v", r.Form)
+		}
+		if r.Form.Get("Signature") == "" || r.Form.Get("TemplateParam") != `{"code":"123456"}` {
+			t.Errorf("unexpected signature or params: %v", r.Form)
+		}
+		if r.Form.Get("TemplateCode") == "SMS_FAIL" {
+			w.Write([]byte(`{"Code":"isv.BUSINESS_LIMIT_CONTROL","Message":"limited"}`))
+			return
+		}
+		w.Write([]byte(`{"Code":"OK","Message":"OK"}`))
+	}))
+	defer server.Close()
+
+	cli := NewAliyunSMSClient(AliyunSMSConfig{AccessKeyId: "key", AccessKeySecret: "secret"}).(*aliyunSMSClient)
+	cli.endpoint = server.URL
+	params := map[string]string{"code": "123456"}
+	if err := cli.Send(context.Background(), "13800138000", "Sign", "SMS_123456", params); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.Send(context.Background(), "13800138000", "Sign", "SMS_FAIL", params); err == nil {
+		t.Error("expected error for non-OK code")
+	}
+}
+
+func TestAliyunSignature(t *testing.T) {
+	// 阿里云文档中的签名示例
+	query := url.Values{
+		"AccessKeyId":      {"testid"},
+		"Action":           {"DescribeRegions"},
+		"Format":           {"XML"},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureNonce":   {"3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf"},
+		"SignatureVersion": {"1.0"},
+		"Timestamp":        {"2016-02-23T12:46:24Z"},
+		"Version":          {"2014-05-26"},
+	}
+	if got := aliyunSignature(http.MethodGet, query, "testsecret"); got != "OLeaidS1JvxuMvnyHOwuJ+uX5qY=" {
+		t.Errorf("unexpected signature: %s", got)
+	}
+}
@@ -0,0 +1,70 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTencentSMS_Send(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-TC-Action") != "SendSms" || r.Header.Get("X-TC-Region") != "ap-guangzhou" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "TC3-HMAC-SHA256 Credential=id/2024-05-01/sms/tc3_request") {
+			t.Errorf("unexpected authorization: %s", auth)
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		w.Write([]byte(`{"Response":{"SendStatusSet":[{"Code":"Ok","Message":"send success"}]}}`))
+	}))
+	defer server.Close()
+
+	sms := NewTencentSMS(TencentSMSConfig{
+		SecretID:  "id",
+		SecretKey: "key",
+		SdkAppID:  "1400000000",
+		SignName:  "LingEcho",
+		Templates: map[string]string{SMSTemplateVerifyCode: "100001"},
+	})
+	sms.endpoint = server.URL
+	sms.now = func() time.Time { return time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC) }
+
+	err := sms.Send(context.Background(), "13800138000", SMSMessage{Template: SMSTemplateVerifyCode, Params: map[string]string{"code": "123456"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if phones := payload["PhoneNumberSet"].([]interface{}); phones[0] != "+8613800138000" {
+		t.Errorf("expected +86 prefix, got %v", phones)
+	}
+	if params := payload["TemplateParamSet"].([]interface{}); len(params) != 1 || params[0] != "123456" {
+		t.Errorf("unexpected template params: %v", params)
+	}
+	if payload["TemplateId"] != "100001" {
+		t.Errorf("unexpected template id: %v", payload["TemplateId"])
+	}
+}
+
+func TestTencentSMS_SendErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Response":{"Error":{"Code":"AuthFailure.SignatureFailure","Message":"signature mismatch"}}}`))
+	}))
+	defer server.Close()
+
+	sms := NewTencentSMS(TencentSMSConfig{SecretID: "id", SecretKey: "key", SdkAppID: "1400000000", Templates: map[string]string{SMSTemplateVerifyCode: "100001"}})
+	sms.endpoint = server.URL
+
+	err := sms.Send(context.Background(), "+8613800138000", SMSMessage{Template: SMSTemplateVerifyCode})
+	if err == nil || !strings.Contains(err.Error(), "AuthFailure.SignatureFailure") {
+		t.Errorf("expected api error, got %v", err)
+	}
+	if err := sms.Send(context.Background(), "+8613800138000", SMSMessage{Template: SMSTemplateSuspiciousLogin}); err == nil {
+		t.Error("expected error for template without id")
+	}
+}
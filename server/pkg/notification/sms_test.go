@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type recordingSMSSender struct {
+	sent []SMSMessage
+}
+
+func (r *recordingSMSSender) Send(ctx context.Context, phone string, msg SMSMessage) error {
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func TestSMSMessage_Render(t *testing.T) {
+	msg := SMSMessage{
+		Template: SMSTemplateSuspiciousLogin,
+		Params:   map[string]string{"time": "2024-05-01 10:00:00", "location": "Beijing", "ip": "1.2.3.4"},
+	}
+	params := msg.OrderedParams()
+	if len(params) != 2 || params[0] != "Beijing" || params[1] != "2024-05-01 10:00:00" {
+		t.Fatalf("unexpected ordered params: %v", params)
+	}
+	want := "LingEcho: your account was signed in from Beijing at 2024-05-01 10:00:00. If this was not you, change your password immediately."
+	if body := msg.Body(); body != want {
+		t.Errorf("unexpected body: %s", body)
+	}
+	if body := (SMSMessage{Template: "unknown"}).Body(); body != "" {
+		t.Errorf("expected empty body for unknown template, got %s", body)
+	}
+}
+
+func TestNewSMSSender(t *testing.T) {
+	sender, err := NewSMSSender(SMSConfig{})
+	if err != nil || sender != nil {
+		t.Fatalf("expected no sender without provider, got %v, %v", sender, err)
+	}
+	if _, err := NewSMSSender(SMSConfig{Provider: SMSProviderTwilio}); err == nil {
+		t.Error("expected error for incomplete twilio config")
+	}
+	if _, err := NewSMSSender(SMSConfig{Provider: "carrier-pigeon"}); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+	sender, err = NewSMSSender(SMSConfig{Provider: SMSProviderTencent, Tencent: TencentSMSConfig{SecretID: "id", SecretKey: "key", SdkAppID: "1400000000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sender.(*TencentSMS); !ok {
+		t.Errorf("expected *TencentSMS, got %T", sender)
+	}
+}
+
+func TestSMSService_RateLimit(t *testing.T) {
+	sender := &recordingSMSSender{}
+	service := NewSMSService(sender, time.Minute, 3)
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	msg := SMSMessage{Template: SMSTemplateVerifyCode, Params: map[string]string{"code": "123456"}}
+	ctx := context.Background()
+
+	if err := service.Send(ctx, "13800138000", msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Send(ctx, "13800138000", msg); !errors.Is(err, ErrSMSRateLimited) {
+		t.Fatalf("expected rate limit within interval, got %v", err)
+	}
+	if err := service.Send(ctx, "13900139000", msg); err != nil {
+		t.Fatalf("other numbers are not limited: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		now = now.Add(2 * time.Minute)
+		if err := service.Send(ctx, "13800138000", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(2 * time.Minute)
+	if service.Allowed("13800138000") {
+		t.Error("expected hourly limit to be reached")
+	}
+	now = now.Add(time.Hour)
+	if !service.Allowed("13800138000") {
+		t.Error("expected limit to reset after an hour")
+	}
+	if len(sender.sent) != 4 {
+		t.Errorf("expected 4 messages sent, got %d", len(sender.sent))
+	}
+}
+
+func TestSMSService_NotConfigured(t *testing.T) {
+	service := NewSMSService(nil, time.Minute, 5)
+	if service.Configured() {
+		t.Error("service without sender should not be configured")
+	}
+	if err := service.Send(context.Background(), "13800138000", SMSMessage{}); !errors.Is(err, ErrSMSNotConfigured) {
+		t.Errorf("expected ErrSMSNotConfigured, got %v", err)
+	}
+}
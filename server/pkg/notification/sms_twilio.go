@@ -0,0 +1,78 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const twilioEndpoint = "https://api.twilio.com"
+
+// TwilioSMSConfig Twilio 短信配置
+type TwilioSMSConfig struct {
+	AccountSID string
+	AuthToken  string
+	From       string // 发送号码（E.164 格式）或 Messaging Service SID
+}
+
+// TwilioSMS 通过 Twilio Messages API 发送短信，正文由本地模板渲染
+type TwilioSMS struct {
+	cfg      TwilioSMSConfig
+	endpoint string
+	client   *http.Client
+}
+
+// NewTwilioSMS 创建 Twilio 短信发送器
+func NewTwilioSMS(cfg TwilioSMSConfig) *TwilioSMS {
+	return &TwilioSMS{
+		cfg:      cfg,
+		endpoint: twilioEndpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send 发送短信，号码需为 E.164 格式
+func (t *TwilioSMS) Send(ctx context.Context, phone string, msg SMSMessage) error {
+	body := msg.Body()
+	if body == "" {
+		return fmt.Errorf("twilio sms: unknown template %s", msg.Template)
+	}
+	form := url.Values{"To": {phone}, "Body": {body}}
+	if strings.HasPrefix(t.cfg.From, "MG") {
+		form.Set("MessagingServiceSid", t.cfg.From)
+	} else {
+		form.Set("From", t.cfg.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", t.endpoint, url.PathEscape(t.cfg.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.cfg.AccountSID, t.cfg.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &result); err == nil && result.Message != "" {
+		return fmt.Errorf("twilio sms: %d: %s", result.Code, result.Message)
+	}
+	return fmt.Errorf("twilio sms: status %d: %s", resp.StatusCode, truncate(string(data), 200))
+}
@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTwilioSMS_Send(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "AC123" || pass != "secret" {
+			t.Error("missing basic auth")
+		}
+		r.ParseForm()
+		if r.Form.Get("To") != "+15551234567" || r.Form.Get("From") != "+15557654321" {
+			t.Errorf("unexpected form: %v", r.Form)
+		}
+		if !strings.Contains(r.Form.Get("Body"), "123456") {
+			t.Errorf("body does not contain code: %s", r.Form.Get("Body"))
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid":"SM1"}`))
+	}))
+	defer server.Close()
+
+	sms := NewTwilioSMS(TwilioSMSConfig{AccountSID: "AC123", AuthToken: "secret", From: "+15557654321"})
+	sms.endpoint = server.URL
+	err := sms.Send(context.Background(), "+15551234567", SMSMessage{Template: SMSTemplateVerifyCode, Params: map[string]string{"code": "123456"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTwilioSMS_SendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":21211,"message":"The 'To' number is not a valid phone number."}`))
+	}))
+	defer server.Close()
+
+	sms := NewTwilioSMS(TwilioSMSConfig{AccountSID: "AC123", AuthToken: "secret", From: "+15557654321"})
+	sms.endpoint = server.URL
+	err := sms.Send(context.Background(), "123", SMSMessage{Template: SMSTemplateVerifyCode, Params: map[string]string{"code": "1"}})
+	if err == nil || !strings.Contains(err.Error(), "21211") {
+		t.Errorf("expected twilio error, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserNotificationChannel 用户配置的外部通知渠道（Webhook、Slack、钉钉、飞书）
+type UserNotificationChannel struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	UserID     uint   `json:"userId" gorm:"index"`                 // 用户 ID
+	Type       string `json:"type" gorm:"size:20"`                 // 渠道类型：webhook, slack, dingtalk, feishu
+	Name       string `json:"name" gorm:"size:100"`                // 渠道名称
+	WebhookURL string `json:"webhookUrl" gorm:"size:500"`          // 推送地址
+	Secret     string `json:"-" gorm:"size:200"`                   // 签名密钥，不对外返回
+	Events     string `json:"events" gorm:"type:text"`             // 订阅的事件，JSON数组格式，为空表示全部事件
+	Enabled    bool   `json:"enabled" gorm:"default:true"`         // 是否启用
+	LastError  string `json:"lastError,omitempty" gorm:"size:500"` // 最近一次发送错误
+
+	LastSentAt *time.Time `json:"lastSentAt,omitempty"` // 最近一次发送时间
+}
+
+func (UserNotificationChannel) TableName() string {
+	return "user_notification_channels"
+}
+
+// GetEvents 获取订阅事件列表
+func (c *UserNotificationChannel) GetEvents() []string {
+	if c.Events == "" {
+		return []string{}
+	}
+	var events []string
+	json.Unmarshal([]byte(c.Events), &events)
+	return events
+}
+
+// SetEvents 设置订阅事件列表
+func (c *UserNotificationChannel) SetEvents(events []string) error {
+	if len(events) == 0 {
+		c.Events = ""
+		return nil
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	c.Events = string(data)
+	return nil
+}
+
+// Subscribes 判断渠道是否订阅了事件
+func (c *UserNotificationChannel) Subscribes(event string) bool {
+	if event == EventTest {
+		return true
+	}
+	events := c.GetEvents()
+	if len(events) == 0 {
+		return true
+	}
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Channel 根据配置创建通知渠道
+func (c *UserNotificationChannel) Channel() (Channel, error) {
+	return NewChannel(c.Type, ChannelConfig{WebhookURL: c.WebhookURL, Secret: c.Secret})
+}
+
+// ChannelDelivery 单个渠道的发送结果
+type ChannelDelivery struct {
+	ChannelID uint   `json:"channelId"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ErrChannelNotFound 渠道不存在或不属于该用户
+var ErrChannelNotFound = errors.New("notification channel not found")
+
+// ChannelService 用户外部通知渠道服务
+type ChannelService struct {
+	DB *gorm.DB
+}
+
+// NewChannelService 创建渠道服务实例
+func NewChannelService(db *gorm.DB) *ChannelService {
+	return &ChannelService{DB: db}
+}
+
+// List 获取用户的所有渠道
+func (s *ChannelService) List(userID uint) ([]UserNotificationChannel, error) {
+	var channels []UserNotificationChannel
+	err := s.DB.Where("user_id = ?", userID).Order("id ASC").Find(&channels).Error
+	return channels, err
+}
+
+// Get 获取用户的单个渠道
+func (s *ChannelService) Get(userID, channelID uint) (*UserNotificationChannel, error) {
+	var channel UserNotificationChannel
+	err := s.DB.Where("id = ? AND user_id = ?", channelID, userID).First(&channel).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChannelNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// Save 创建或更新渠道，保存前校验配置
+func (s *ChannelService) Save(channel *UserNotificationChannel) error {
+	if _, err := channel.Channel(); err != nil {
+		return err
+	}
+	return s.DB.Save(channel).Error
+}
+
+// Delete 删除用户的渠道
+func (s *ChannelService) Delete(userID, channelID uint) error {
+	result := s.DB.Where("id = ? AND user_id = ?", channelID, userID).Delete(&UserNotificationChannel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrChannelNotFound
+	}
+	return nil
+}
+
+// Notify 将消息发送到用户所有启用且订阅了该事件的渠道
+func (s *ChannelService) Notify(ctx context.Context, userID uint, msg Message) []ChannelDelivery {
+	var channels []UserNotificationChannel
+	if err := s.DB.Where("user_id = ? AND enabled = ?", userID, true).Find(&channels).Error; err != nil {
+		return []ChannelDelivery{{Error: err.Error()}}
+	}
+
+	var deliveries []ChannelDelivery
+	for i := range channels {
+		if !channels[i].Subscribes(msg.Event) {
+			continue
+		}
+		deliveries = append(deliveries, s.Deliver(ctx, &channels[i], msg))
+	}
+	return deliveries
+}
+
+// Deliver 发送到单个渠道并记录结果
+func (s *ChannelService) Deliver(ctx context.Context, channel *UserNotificationChannel, msg Message) ChannelDelivery {
+	delivery := ChannelDelivery{ChannelID: channel.ID, Type: channel.Type, Name: channel.Name}
+
+	sender, err := channel.Channel()
+	if err == nil {
+		err = sender.Send(ctx, msg)
+	}
+
+	updates := map[string]interface{}{"last_error": ""}
+	if err != nil {
+		delivery.Error = err.Error()
+		updates["last_error"] = truncate(err.Error(), 490)
+	} else {
+		updates["last_sent_at"] = time.Now()
+	}
+	s.DB.Model(&UserNotificationChannel{}).Where("id = ?", channel.ID).Updates(updates)
+	return delivery
+}
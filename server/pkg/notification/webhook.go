@@ -0,0 +1,65 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Webhook 签名请求头
+const (
+	WebhookSignatureHeader = "X-LingEcho-Signature"
+	WebhookTimestampHeader = "X-LingEcho-Timestamp"
+	WebhookEventHeader     = "X-LingEcho-Event"
+	WebhookDeliveryHeader  = "X-LingEcho-Delivery" // 平台事件 Webhook 的投递 ID，重试时不变
+)
+
+// 通用 Webhook 推送
+type WebhookConfig struct {
+	URL    string
+	Secret string // 设置后请求带 HMAC-SHA256 签名
+}
+
+type WebhookNotification struct {
+	config WebhookConfig
+}
+
+func NewWebhookNotification(config WebhookConfig) *WebhookNotification {
+	return &WebhookNotification{config: config}
+}
+
+func (w *WebhookNotification) Name() string { return ChannelTypeWebhook }
+
+// Send 以 JSON 形式推送消息
+func (w *WebhookNotification) Send(ctx context.Context, msg Message) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	body, err := json.Marshal(struct {
+		Message
+		Timestamp string `json:"timestamp"`
+	}{msg, timestamp})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		WebhookEventHeader:     msg.Event,
+		WebhookTimestampHeader: timestamp,
+	}
+	if w.config.Secret != "" {
+		headers[WebhookSignatureHeader] = "sha256=" + SignWebhookPayload(w.config.Secret, timestamp, body)
+	}
+	_, err = postBody(ctx, w.config.URL, body, headers)
+	return err
+}
+
+// SignWebhookPayload 计算 Webhook 签名：hex(HMAC-SHA256(secret, timestamp + "." + body))
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
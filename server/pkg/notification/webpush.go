@@ -0,0 +1,383 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// 单条记录大小，推送服务要求请求体不超过 4096 字节，因此整个消息只用一条记录
+	webPushRecordSize = 4096
+	// aes128gcm 头部：salt(16) + rs(4) + idlen(1) + 服务端公钥(65)
+	webPushHeaderSize = 16 + 4 + 1 + 65
+	// 推送服务在用户离线时保留消息的时长
+	webPushTTL = 24 * time.Hour
+	// VAPID JWT 有效期，RFC 8292 要求不超过 24 小时
+	vapidTokenLifetime = 12 * time.Hour
+)
+
+// ErrWebPushPayloadTooLarge 加密后的消息超过推送服务允许的大小
+var ErrWebPushPayloadTooLarge = errors.New("web push payload too large")
+
+// WebPushConfig 浏览器推送（Web Push + VAPID）配置
+type WebPushConfig struct {
+	PublicKey  string // VAPID 公钥（base64url 编码的未压缩 P-256 点），为空时由私钥推导
+	PrivateKey string // VAPID 私钥（base64url 编码的 32 字节），为空表示不启用浏览器推送
+	Subject    string // 联系方式，mailto: 或 https: 地址
+}
+
+// WebPushSubscription 浏览器推送订阅，对应前端 PushSubscription.toJSON()
+type WebPushSubscription struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	UserID    uint   `json:"userId" gorm:"index"`                  // 用户 ID
+	Endpoint  string `json:"endpoint" gorm:"size:512;uniqueIndex"` // 推送服务地址
+	P256dh    string `json:"p256dh" gorm:"size:128"`               // 浏览器公钥（keys.p256dh）
+	Auth      string `json:"auth" gorm:"size:64"`                  // 认证密钥（keys.auth）
+	UserAgent string `json:"userAgent,omitempty" gorm:"size:255"`  // 订阅时的浏览器
+	LastError string `json:"lastError,omitempty" gorm:"size:500"`  // 最近一次推送错误
+
+	LastSentAt *time.Time `json:"lastSentAt,omitempty"` // 最近一次推送时间
+}
+
+func (WebPushSubscription) TableName() string {
+	return "web_push_subscriptions"
+}
+
+// WebPushSender 通过 Web Push 协议（RFC 8030）推送浏览器通知，消息按 RFC 8291 加密，使用 VAPID（RFC 8292）认证
+type WebPushSender struct {
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+	client    *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]vapidToken // 按推送服务 origin 缓存的 JWT
+}
+
+type vapidToken struct {
+	jwt       string
+	expiresAt time.Time
+}
+
+// NewWebPushSender 创建浏览器推送发送器，未配置私钥时返回 nil
+func NewWebPushSender(config WebPushConfig) (*WebPushSender, error) {
+	if config.PrivateKey == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(config.Subject, "mailto:") && !strings.HasPrefix(config.Subject, "https:") {
+		return nil, errors.New("web push: vapid subject must be a mailto: or https: url")
+	}
+	d, err := decodeBase64URL(config.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("web push: invalid vapid private key: %w", err)
+	}
+	private, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("web push: invalid vapid private key: %w", err)
+	}
+	public := private.PublicKey().Bytes()
+	publicKey := base64.RawURLEncoding.EncodeToString(public)
+	if config.PublicKey != "" && strings.TrimRight(config.PublicKey, "=") != publicKey {
+		return nil, errors.New("web push: vapid public key does not match the private key")
+	}
+
+	// 未压缩点格式为 0x04 || X || Y
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+	return &WebPushSender{
+		key:       key,
+		publicKey: publicKey,
+		subject:   config.Subject,
+		client:    utils.NewPublicHTTPClient(10 * time.Second), // endpoint 来自浏览器订阅，只允许访问公网地址
+		tokens:    make(map[string]vapidToken),
+	}, nil
+}
+
+// PublicKey 返回 VAPID 公钥，前端订阅时作为 applicationServerKey
+func (w *WebPushSender) PublicKey() string {
+	return w.publicKey
+}
+
+// Send 推送一条通知到浏览器订阅，消息以 JSON 形式交给 Service Worker 处理
+func (w *WebPushSender) Send(ctx context.Context, sub WebPushSubscription, msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	body, err := encryptWebPush(payload, sub.P256dh, sub.Auth)
+	if err != nil {
+		return err
+	}
+	token, err := w.vapidToken(sub.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to sign vapid token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(webPushTTL.Seconds())))
+	req.Header.Set("Urgency", webPushUrgency(msg.Level))
+	req.Header.Set("Authorization", fmt.Sprintf("vapid t=%s, k=%s", token, w.publicKey))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusGone:
+		return ErrInvalidPushToken
+	case http.StatusRequestEntityTooLarge:
+		return ErrWebPushPayloadTooLarge
+	}
+	return fmt.Errorf("web push returned status %d", resp.StatusCode)
+}
+
+// vapidToken 生成推送服务 origin 对应的 VAPID JWT，有效期内复用
+func (w *WebPushSender) vapidToken(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	audience := u.Scheme + "://" + u.Host
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	if cached, ok := w.tokens[audience]; ok && now.Add(time.Hour).Before(cached.expiresAt) {
+		return cached.jwt, nil
+	}
+
+	expiresAt := now.Add(vapidTokenLifetime)
+	token, err := signES256JWT(w.key,
+		map[string]interface{}{"typ": "JWT", "alg": "ES256"},
+		map[string]interface{}{"aud": audience, "exp": expiresAt.Unix(), "sub": w.subject},
+	)
+	if err != nil {
+		return "", err
+	}
+	w.tokens[audience] = vapidToken{jwt: token, expiresAt: expiresAt}
+	return token, nil
+}
+
+// webPushUrgency 告警类消息要求推送服务立即投递
+func webPushUrgency(level string) string {
+	switch level {
+	case LevelWarning, LevelError:
+		return "high"
+	}
+	return "normal"
+}
+
+// encryptWebPush 按 RFC 8291 使用 aes128gcm 内容编码加密消息
+func encryptWebPush(plaintext []byte, p256dh, auth string) ([]byte, error) {
+	uaPublicBytes, authSecret, err := parseWebPushKeys(p256dh, auth)
+	if err != nil {
+		return nil, err
+	}
+	if webPushHeaderSize+len(plaintext)+1+16 > webPushRecordSize {
+		return nil, ErrWebPushPayloadTooLarge
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+
+	// 每条消息使用新的临时密钥对和 salt
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+
+	cek, nonce, err := webPushContentKeys(ecdhSecret, authSecret, uaPublicBytes, asPublicBytes, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, webPushHeaderSize)
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(asPublicBytes)))
+	header = append(header, asPublicBytes...)
+
+	// 0x02 表示最后一条记录
+	record := append(append([]byte{}, plaintext...), 0x02)
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+// webPushContentKeys 由 ECDH 共享密钥推导内容加密密钥和 nonce
+func webPushContentKeys(ecdhSecret, authSecret, uaPublic, asPublic, salt []byte) (cek, nonce []byte, err error) {
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	cek, err = hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, err = hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	return cek, nonce, err
+}
+
+// parseWebPushKeys 解码订阅中的浏览器公钥和认证密钥
+func parseWebPushKeys(p256dh, auth string) (public, secret []byte, err error) {
+	public, err = decodeBase64URL(p256dh)
+	if err != nil || len(public) != 65 || public[0] != 0x04 {
+		return nil, nil, errors.New("invalid p256dh key")
+	}
+	secret, err = decodeBase64URL(auth)
+	if err != nil || len(secret) != 16 {
+		return nil, nil, errors.New("invalid auth secret")
+	}
+	return public, secret, nil
+}
+
+// decodeBase64URL 解码 base64url，兼容带填充的写法
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// WebPushService 浏览器推送服务
+type WebPushService struct {
+	DB     *gorm.DB
+	Sender *WebPushSender
+}
+
+// NewWebPushService 创建浏览器推送服务实例
+func NewWebPushService(db *gorm.DB, sender *WebPushSender) *WebPushService {
+	return &WebPushService{DB: db, Sender: sender}
+}
+
+// validateWebPushEndpoint 检查订阅的 endpoint 指向公网地址
+var validateWebPushEndpoint = utils.ValidatePublicURL
+
+// Subscribe 保存浏览器订阅，同一 endpoint 在其他账号登录时会转移到新用户
+func (s *WebPushService) Subscribe(ctx context.Context, userID uint, endpoint, p256dh, auth, userAgent string) (*WebPushSubscription, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, errors.New("web push endpoint must be an https url")
+	}
+	if err := validateWebPushEndpoint(ctx, endpoint); err != nil {
+		return nil, fmt.Errorf("invalid web push endpoint: %w", err)
+	}
+	if _, _, err := parseWebPushKeys(p256dh, auth); err != nil {
+		return nil, err
+	}
+
+	var sub WebPushSubscription
+	err = s.DB.Where("endpoint = ?", endpoint).First(&sub).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	sub.UserID = userID
+	sub.Endpoint = endpoint
+	sub.P256dh = p256dh
+	sub.Auth = auth
+	sub.UserAgent = truncate(userAgent, 250)
+	sub.LastError = ""
+	if err := s.DB.Save(&sub).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// Unsubscribe 删除用户的浏览器订阅
+func (s *WebPushService) Unsubscribe(userID uint, endpoint string) error {
+	return s.DB.Where("user_id = ? AND endpoint = ?", userID, endpoint).Delete(&WebPushSubscription{}).Error
+}
+
+// ListSubscriptions 获取用户的浏览器订阅
+func (s *WebPushService) ListSubscriptions(userID uint) ([]WebPushSubscription, error) {
+	var subs []WebPushSubscription
+	err := s.DB.Where("user_id = ?", userID).Order("updated_at DESC").Find(&subs).Error
+	return subs, err
+}
+
+// SendToUser 推送到用户的所有浏览器订阅，返回成功推送的订阅数。已过期的订阅会被删除
+func (s *WebPushService) SendToUser(ctx context.Context, userID uint, msg Message) (int, error) {
+	if s.Sender == nil {
+		return 0, nil
+	}
+	subs, err := s.ListSubscriptions(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var errs []error
+	for _, sub := range subs {
+		err := s.Sender.Send(ctx, sub, msg)
+		switch {
+		case errors.Is(err, ErrInvalidPushToken):
+			s.DB.Delete(&WebPushSubscription{}, sub.ID)
+		case err != nil:
+			errs = append(errs, fmt.Errorf("subscription %d: %w", sub.ID, err))
+			s.DB.Model(&WebPushSubscription{}).Where("id = ?", sub.ID).Update("last_error", truncate(err.Error(), 490))
+		default:
+			sent++
+			s.DB.Model(&WebPushSubscription{}).Where("id = ?", sub.ID).Updates(map[string]interface{}{
+				"last_error":   "",
+				"last_sent_at": time.Now(),
+			})
+		}
+	}
+	return sent, errors.Join(errs...)
+}
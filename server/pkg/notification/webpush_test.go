@@ -0,0 +1,224 @@
+package notification
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webPushClient 模拟浏览器的推送订阅密钥
+type webPushClient struct {
+	key    *ecdh.PrivateKey
+	p256dh string
+	auth   string
+	secret []byte
+}
+
+func newWebPushClient(t *testing.T) *webPushClient {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	secret := make([]byte, 16)
+	rand.Read(secret)
+	return &webPushClient{
+		key:    key,
+		p256dh: base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		auth:   base64.RawURLEncoding.EncodeToString(secret),
+		secret: secret,
+	}
+}
+
+// decrypt 按浏览器的方式解密 aes128gcm 消息
+func (c *webPushClient) decrypt(t *testing.T, body []byte) []byte {
+	require.Greater(t, len(body), webPushHeaderSize)
+	salt := body[:16]
+	assert.Equal(t, uint32(webPushRecordSize), binary.BigEndian.Uint32(body[16:20]))
+	idLen := int(body[20])
+	asPublicBytes := body[21 : 21+idLen]
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	require.NoError(t, err)
+	ecdhSecret, err := c.key.ECDH(asPublic)
+	require.NoError(t, err)
+	cek, nonce, err := webPushContentKeys(ecdhSecret, c.secret, c.key.PublicKey().Bytes(), asPublicBytes, salt)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(cek)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	record, err := gcm.Open(nil, nonce, body[21+idLen:], nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x02), record[len(record)-1])
+	return record[:len(record)-1]
+}
+
+func newVAPIDConfig(t *testing.T) WebPushConfig {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return WebPushConfig{
+		PublicKey:  base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		PrivateKey: base64.RawURLEncoding.EncodeToString(key.Bytes()),
+		Subject:    "mailto:ops@example.com",
+	}
+}
+
+// verifyVAPID 校验 Authorization 头中的 VAPID JWT，返回其 claims
+func verifyVAPID(t *testing.T, header, publicKey string) map[string]interface{} {
+	require.True(t, strings.HasPrefix(header, "vapid t="))
+	parts := strings.SplitN(strings.TrimPrefix(header, "vapid t="), ", k=", 2)
+	require.Len(t, parts, 2)
+	assert.Equal(t, publicKey, parts[1])
+
+	segments := strings.Split(parts[0], ".")
+	require.Len(t, segments, 3)
+	pub, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(pub[1:33]), Y: new(big.Int).SetBytes(pub[33:])}
+	signature, _ := base64.RawURLEncoding.DecodeString(segments[2])
+	digest := sha256.Sum256([]byte(segments[0] + "." + segments[1]))
+	assert.True(t, ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])))
+
+	var claims map[string]interface{}
+	data, _ := base64.RawURLEncoding.DecodeString(segments[1])
+	require.NoError(t, json.Unmarshal(data, &claims))
+	return claims
+}
+
+func TestNewWebPushSender(t *testing.T) {
+	sender, err := NewWebPushSender(WebPushConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, sender)
+
+	cfg := newVAPIDConfig(t)
+	sender, err = NewWebPushSender(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.PublicKey, sender.PublicKey())
+
+	// 公钥可以省略，由私钥推导
+	sender, err = NewWebPushSender(WebPushConfig{PrivateKey: cfg.PrivateKey, Subject: cfg.Subject})
+	require.NoError(t, err)
+	assert.Equal(t, cfg.PublicKey, sender.PublicKey())
+
+	_, err = NewWebPushSender(WebPushConfig{PublicKey: newVAPIDConfig(t).PublicKey, PrivateKey: cfg.PrivateKey, Subject: cfg.Subject})
+	assert.Error(t, err)
+	_, err = NewWebPushSender(WebPushConfig{PrivateKey: cfg.PrivateKey, Subject: "ops@example.com"})
+	assert.Error(t, err)
+	_, err = NewWebPushSender(WebPushConfig{PrivateKey: "not-a-key", Subject: cfg.Subject})
+	assert.Error(t, err)
+}
+
+func TestWebPushSender_Send(t *testing.T) {
+	cfg := newVAPIDConfig(t)
+	sender, err := NewWebPushSender(cfg)
+	require.NoError(t, err)
+	client := newWebPushClient(t)
+
+	var received Message
+	gone := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gone {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		assert.Equal(t, "aes128gcm", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "86400", r.Header.Get("TTL"))
+		assert.Equal(t, "high", r.Header.Get("Urgency"))
+		claims := verifyVAPID(t, r.Header.Get("Authorization"), cfg.PublicKey)
+		assert.Equal(t, "http://"+r.Host, claims["aud"])
+		assert.Equal(t, cfg.Subject, claims["sub"])
+
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, json.Unmarshal(client.decrypt(t, body), &received))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	sender.client = server.Client()
+
+	sub := WebPushSubscription{Endpoint: server.URL + "/push/abc", P256dh: client.p256dh, Auth: client.auth}
+	msg := Message{Event: EventQuotaAlert, Level: LevelWarning, Title: "Quota", Content: "90% used", Link: "/billing"}
+	assert.NoError(t, sender.Send(context.Background(), sub, msg))
+	assert.Equal(t, msg, received)
+
+	gone = true
+	assert.ErrorIs(t, sender.Send(context.Background(), sub, msg), ErrInvalidPushToken)
+
+	msg.Content = strings.Repeat("x", webPushRecordSize)
+	assert.ErrorIs(t, sender.Send(context.Background(), sub, msg), ErrWebPushPayloadTooLarge)
+}
+
+func TestWebPushService_SendToUser(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&WebPushSubscription{}))
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/stale") {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	sender, err := NewWebPushSender(newVAPIDConfig(t))
+	require.NoError(t, err)
+	sender.client = server.Client()
+	service := NewWebPushService(db, sender)
+	client := newWebPushClient(t)
+
+	// 内网地址不能订阅，测试服务监听在本地，校验通过后临时放开
+	_, err = service.Subscribe(context.Background(), 1, server.URL+"/x", client.p256dh, client.auth, "")
+	assert.ErrorIs(t, err, utils.ErrBlockedAddress)
+	validateWebPushEndpoint = func(context.Context, string) error { return nil }
+	t.Cleanup(func() { validateWebPushEndpoint = utils.ValidatePublicURL })
+
+	_, err = service.Subscribe(context.Background(), 1, "http://push.example.com/x", client.p256dh, client.auth, "")
+	assert.Error(t, err)
+	_, err = service.Subscribe(context.Background(), 1, server.URL+"/x", client.p256dh, "short", "")
+	assert.Error(t, err)
+
+	_, err = service.Subscribe(context.Background(), 1, server.URL+"/fresh", client.p256dh, client.auth, "Firefox")
+	require.NoError(t, err)
+	_, err = service.Subscribe(context.Background(), 1, server.URL+"/stale", client.p256dh, client.auth, "Chrome")
+	require.NoError(t, err)
+	// 同一 endpoint 被其他用户订阅时转移
+	_, err = service.Subscribe(context.Background(), 2, server.URL+"/moved", client.p256dh, client.auth, "")
+	require.NoError(t, err)
+	_, err = service.Subscribe(context.Background(), 1, server.URL+"/moved", client.p256dh, client.auth, "")
+	require.NoError(t, err)
+
+	sent, err := service.SendToUser(context.Background(), 1, Message{Event: EventSuspiciousLogin, Title: "New sign-in"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	// 已过期的订阅被删除
+	subs, err := service.ListSubscriptions(1)
+	assert.NoError(t, err)
+	assert.Len(t, subs, 2)
+	for _, s := range subs {
+		assert.False(t, strings.HasSuffix(s.Endpoint, "/stale"))
+		assert.NotNil(t, s.LastSentAt)
+	}
+	subs, _ = service.ListSubscriptions(2)
+	assert.Empty(t, subs)
+
+	assert.NoError(t, service.Unsubscribe(1, server.URL+"/fresh"))
+	subs, _ = service.ListSubscriptions(1)
+	assert.Len(t, subs, 1)
+}
@@ -3,6 +3,7 @@ package prompt
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
@@ -56,12 +57,90 @@ func InitPromptSystem(db *gorm.DB) error {
 			Arguments:   argMap[model.ID], // 根据 PromptID 映射
 		}
 
-		GlobalPromptManager.registerPrompt(runtimePrompt, nil)
+		var handler promptHandler
+		if model.Template != "" {
+			handler = templatePromptHandler(runtimePrompt, model.Template)
+		}
+		GlobalPromptManager.registerPrompt(runtimePrompt, handler)
 	}
 
 	return nil
 }
 
+// templatePromptHandler 按模板渲染提示：{{参数名}} 替换为参数值，缺少必填参数时返回错误，
+// 未提供的可选参数替换为空字符串
+func templatePromptHandler(prompt *Prompt, template string) promptHandler {
+	return func(ctx context.Context, req *GetPromptRequest) (*GetPromptResult, error) {
+		replacements := make([]string, 0, len(prompt.Arguments)*2)
+		for _, arg := range prompt.Arguments {
+			value, ok := req.Params.Arguments[arg.Name]
+			if !ok && arg.Required {
+				return nil, fmt.Errorf("%w: %s", utils.ErrMissingParams, arg.Name)
+			}
+			replacements = append(replacements, "{{"+arg.Name+"}}", value)
+		}
+		return &GetPromptResult{
+			Description: prompt.Description,
+			Messages: []PromptMessage{
+				{
+					Role:    "user",
+					Content: NewTextContent(strings.NewReplacer(replacements...).Replace(template)),
+				},
+			},
+		}, nil
+	}
+}
+
+// ListPrompts 按注册顺序返回已加载的提示模板，提示系统未初始化时返回 nil
+func ListPrompts() []*Prompt {
+	if GlobalPromptManager == nil {
+		return nil
+	}
+	m := GlobalPromptManager
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prompts := make([]*Prompt, 0, len(m.promptsOrder))
+	for _, name := range m.promptsOrder {
+		if registeredPrompt, exists := m.prompts[name]; exists {
+			prompts = append(prompts, registeredPrompt.Prompt)
+		}
+	}
+	return prompts
+}
+
+// GetPrompt 使用参数渲染指定的提示模板
+func GetPrompt(ctx context.Context, name string, arguments map[string]string) (*GetPromptResult, error) {
+	if GlobalPromptManager == nil {
+		return nil, fmt.Errorf("%v: %s", utils.ErrPromptNotFound, name)
+	}
+	GlobalPromptManager.mu.RLock()
+	registeredPrompt, exists := GlobalPromptManager.prompts[name]
+	GlobalPromptManager.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%v: %s", utils.ErrPromptNotFound, name)
+	}
+
+	if registeredPrompt.Handler != nil {
+		req := &GetPromptRequest{}
+		req.Params.Name = name
+		req.Params.Arguments = arguments
+		if req.Params.Arguments == nil {
+			req.Params.Arguments = make(map[string]string)
+		}
+		return registeredPrompt.Handler(ctx, req)
+	}
+
+	args := make(map[string]interface{}, len(arguments))
+	for k, v := range arguments {
+		args[k] = v
+	}
+	return &GetPromptResult{
+		Description: registeredPrompt.Prompt.Description,
+		Messages:    buildPromptMessages(registeredPrompt.Prompt, args),
+	}, nil
+}
+
 // newPromptManager creates a new prompt manager
 //
 // Note: Simply creating a prompt manager does not enable prompt functionality,
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -653,3 +654,41 @@ func TestInitPromptSystem_MultiplePromptsWithArgs(t *testing.T) {
 	assert.Equal(t, 1, len(p2.Arguments))
 	assert.False(t, p2.Arguments[0].Required)
 }
+
+func TestInitPromptSystem_Template(t *testing.T) {
+	db := setupTestDB(t)
+
+	translate := models.PromptModel{
+		Name:        "translate",
+		Description: "Translate text",
+		Template:    "Translate into {{lang}}{{style}}:\n{{text}}",
+	}
+	db.Create(&translate)
+	db.Create(&models.PromptArgModel{PromptID: translate.ID, Name: "text", Required: true})
+	db.Create(&models.PromptArgModel{PromptID: translate.ID, Name: "lang", Required: true})
+	db.Create(&models.PromptArgModel{PromptID: translate.ID, Name: "style"})
+	db.Create(&models.PromptModel{Name: "plain", Description: "No template"})
+
+	assert.NoError(t, InitPromptSystem(db))
+
+	prompts := ListPrompts()
+	assert.Len(t, prompts, 2)
+	assert.Equal(t, "translate", prompts[0].Name)
+	assert.Equal(t, "plain", prompts[1].Name)
+
+	result, err := GetPrompt(context.Background(), "translate", map[string]string{"text": "你好", "lang": "English"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Translate text", result.Description)
+	assert.Len(t, result.Messages, 1)
+	assert.Equal(t, "Translate into English:\n你好", result.Messages[0].Content.(TextContent).Text)
+
+	_, err = GetPrompt(context.Background(), "translate", map[string]string{"text": "你好"})
+	assert.ErrorIs(t, err, utils.ErrMissingParams)
+
+	result, err = GetPrompt(context.Background(), "plain", nil)
+	assert.NoError(t, err)
+	assert.Contains(t, result.Messages[0].Content.(TextContent).Text, "plain")
+
+	_, err = GetPrompt(context.Background(), "missing", nil)
+	assert.Error(t, err)
+}
@@ -91,6 +91,10 @@ func normalizeProviderName(provider string) string {
 	if normalized == "voiceengine" {
 		return "volcengine"
 	}
+	// 离线部署的本地识别使用 whisper.cpp
+	if normalized == "local" || normalized == "whisper.cpp" {
+		return "whisper_local"
+	}
 	return normalized
 }
 
@@ -134,6 +138,8 @@ func NewTranscriberConfigFromMap(
 		return buildVolcengineLLMConfig(config)
 	case "gladia":
 		return buildGladiaConfig(config)
+	case "whisper_local":
+		return buildLocalWhisperConfig(config, language)
 	default:
 		return nil, fmt.Errorf("unsupported ASR provider: %s", provider)
 	}
@@ -266,3 +272,41 @@ func buildGladiaConfig(config map[string]interface{}) (*GladiaASROption, error)
 	opt := NewGladiaASROption(apiKey, encoding)
 	return &opt, nil
 }
+
+// buildLocalWhisperConfig 构建本地Whisper配置
+func buildLocalWhisperConfig(config map[string]interface{}, language string) (*LocalWhisperOption, error) {
+	cfg := NewConfigReader(config)
+	url := cfg.String("url", utils.GetEnv("WHISPER_LOCAL_URL"))
+	model := cfg.String("model")
+	if url == "" && model == "" {
+		return nil, fmt.Errorf("本地Whisper配置不完整：缺少url（whisper.cpp server）或model（本地模型路径）")
+	}
+	if url == "" {
+		// 命令行模式：命令与模型目录只能由服务器配置，凭证只能选择模型目录内的模型
+		dir := utils.GetEnv(EnvWhisperModelDir)
+		if dir == "" {
+			return nil, fmt.Errorf("本地Whisper命令行模式未启用：服务器未配置%s", EnvWhisperModelDir)
+		}
+		path, err := utils.ResolvePathInDir(dir, model)
+		if err != nil {
+			return nil, fmt.Errorf("本地Whisper模型无效: %w", err)
+		}
+		model = path
+	}
+	opt := NewLocalWhisperOption(url, model)
+	if command := utils.GetEnv(EnvWhisperLocalCommand); command != "" {
+		opt.Command = command
+	}
+	opt.Language = cfg.String("language", language)
+	if opt.Language == "" {
+		opt.Language = "zh"
+	}
+	opt.Prompt = cfg.String("prompt")
+	opt.Threads = cfg.Int("threads", 0)
+	opt.SampleRate = cfg.Int("sampleRate", "sample_rate", 16000)
+	opt.SilenceMs = cfg.Int("silenceMs", "silence_ms", 600)
+	opt.MaxSegmentMs = cfg.Int("maxSegmentMs", "max_segment_ms", 15000)
+	opt.PartialIntervalMs = cfg.Int("partialIntervalMs", "partial_interval_ms", 0)
+	opt.TimeoutSeconds = cfg.Int("timeoutSeconds", "timeout_seconds", 30)
+	return &opt, nil
+}
@@ -42,6 +42,8 @@ const (
 	VendorBaidu Vendor = "baidu"
 	// VendorVoiceAPI VoiceAPI
 	VendorVoiceAPI Vendor = "voiceapi"
+	// VendorWhisperLocal 本地 Whisper（whisper.cpp），用于离线部署
+	VendorWhisperLocal Vendor = "whisper_local"
 )
 
 // TranscriberConfig 统一的配置接口
@@ -96,7 +98,12 @@ func (f *DefaultTranscriberFactory) CreateTranscriber(config TranscriberConfig)
 		return nil, fmt.Errorf("vendor %s not supported", vendor)
 	}
 
-	return creator(config)
+	service, err := creator(config)
+	if err != nil {
+		return nil, err
+	}
+	// 建连耗时与识别错误上报到全局监控
+	return &instrumentedTranscriber{TranscribeService: service}, nil
 }
 
 // GetSupportedVendors 获取支持的供应商列表
@@ -196,6 +203,15 @@ func (f *DefaultTranscriberFactory) registerDefaultCreators() {
 		return &realtime, nil
 	})
 
+	// 注册本地Whisper
+	f.RegisterCreator(VendorWhisperLocal, func(config TranscriberConfig) (TranscribeService, error) {
+		whisperConfig, ok := config.(*LocalWhisperOption)
+		if !ok {
+			return nil, fmt.Errorf("invalid config type for whisper_local")
+		}
+		return NewLocalWhisperASR(*whisperConfig), nil
+	})
+
 	// 注意：以下 transcriber 没有实现 TranscribeService 接口，只有 With 函数
 	// Whisper, Deepgram, AWS, Baidu, VoiceAPI 等需要通过 With*ASR 函数使用
 	// 如果需要使用这些，请直接调用相应的 With*ASR 函数
@@ -0,0 +1,469 @@
+package recognizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/code-100-precent/LingEcho/pkg/media/fileio"
+	"github.com/code-100-precent/LingEcho/pkg/media/vad"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// whisperNoiseRegex Whisper 对静音、音乐输出的标记，如 [BLANK_AUDIO]、[音乐]
+var whisperNoiseRegex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// 命令行模式的 whisper.cpp 可执行文件与模型目录只能由服务器环境变量配置，
+// 避免用户借ASR凭证让服务器执行任意程序或读取任意文件
+const (
+	EnvWhisperLocalCommand = "WHISPER_LOCAL_COMMAND" // whisper.cpp 命令行，默认 whisper-cli
+	EnvWhisperModelDir     = "WHISPER_MODEL_DIR"     // ggml 模型目录，未配置时不启用命令行模式
+)
+
+// LocalWhisperOption 本地 Whisper 识别配置，不依赖云服务：
+// 配置 Url 时请求本地 whisper.cpp server（/inference）或 OpenAI 兼容的 /v1/audio/transcriptions 接口，
+// 否则调用本地 whisper.cpp 命令行
+type LocalWhisperOption struct {
+	Url               string `json:"url" yaml:"url" env:"WHISPER_LOCAL_URL"`
+	Command           string `json:"-" yaml:"-" env:"WHISPER_LOCAL_COMMAND"` // whisper.cpp 命令行，只取自服务器配置
+	Model             string `json:"model" yaml:"model"`                     // 命令行模式为 ggml 模型路径，OpenAI 兼容接口为模型名
+	Language          string `json:"language" yaml:"language" default:"zh"`  // zh、en 等，auto 为自动检测
+	Prompt            string `json:"prompt" yaml:"prompt"`                   // 初始提示，可写入热词
+	Threads           int    `json:"threads" yaml:"threads"`                 // 命令行模式线程数，0 使用默认值
+	SampleRate        int    `json:"sampleRate" yaml:"sample_rate" default:"16000"`
+	SilenceMs         int    `json:"silenceMs" yaml:"silence_ms" default:"600"`          // 静音多久视为一句话结束
+	MaxSegmentMs      int    `json:"maxSegmentMs" yaml:"max_segment_ms" default:"15000"` // 一句话超过该时长时直接识别
+	PartialIntervalMs int    `json:"partialIntervalMs" yaml:"partial_interval_ms"`       // 说话过程中输出中间结果的间隔，0 不输出
+	TimeoutSeconds    int    `json:"timeoutSeconds" yaml:"timeout_seconds" default:"30"` // 单次识别超时
+}
+
+// NewLocalWhisperOption 创建本地 Whisper 配置，url 与 model 至少提供一个
+func NewLocalWhisperOption(url, model string) LocalWhisperOption {
+	return LocalWhisperOption{
+		Url:            url,
+		Command:        "whisper-cli",
+		Model:          model,
+		Language:       "zh",
+		SampleRate:     16000,
+		SilenceMs:      600,
+		MaxSegmentMs:   15000,
+		TimeoutSeconds: 30,
+	}
+}
+
+func (opt *LocalWhisperOption) GetVendor() Vendor {
+	return VendorWhisperLocal
+}
+
+// whisperJob 一段待识别的音频
+type whisperJob struct {
+	pcm     []byte
+	final   bool
+	started time.Time
+}
+
+// whisperSession 一次连接的状态：Whisper 不支持流式识别，用 VAD 切分语句后整句识别
+type whisperSession struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	dialogID string
+	jobs     chan whisperJob
+
+	detector    *vad.VAD
+	buffer      []byte
+	speaking    bool
+	started     time.Time
+	lastPartial time.Time
+	pending     int  // 已提交未完成的识别数
+	ended       bool // 已调用 SendEnd，剩余识别完成后结束
+}
+
+// LocalWhisperASR 本地 Whisper 识别，实现 TranscribeService
+type LocalWhisperASR struct {
+	opt     LocalWhisperOption
+	tr      TranscribeResult
+	er      ProcessError
+	client  *http.Client
+	mu      sync.Mutex
+	session *whisperSession
+}
+
+// NewLocalWhisperASR 创建本地 Whisper 识别服务
+func NewLocalWhisperASR(opt LocalWhisperOption) *LocalWhisperASR {
+	if opt.SampleRate <= 0 {
+		opt.SampleRate = 16000
+	}
+	if opt.SilenceMs <= 0 {
+		opt.SilenceMs = 600
+	}
+	if opt.MaxSegmentMs <= 0 {
+		opt.MaxSegmentMs = 15000
+	}
+	if opt.TimeoutSeconds <= 0 {
+		opt.TimeoutSeconds = 30
+	}
+	if opt.Command == "" {
+		opt.Command = "whisper-cli"
+	}
+	return &LocalWhisperASR{
+		opt:    opt,
+		client: &http.Client{Timeout: time.Duration(opt.TimeoutSeconds) * time.Second},
+	}
+}
+
+func (w *LocalWhisperASR) Init(tr TranscribeResult, er ProcessError) {
+	w.tr = tr
+	w.er = er
+}
+
+func (w *LocalWhisperASR) Vendor() string {
+	return string(VendorWhisperLocal)
+}
+
+func (w *LocalWhisperASR) ConnAndReceive(dialogID string) error {
+	if w.opt.Url == "" {
+		if w.opt.Model == "" {
+			return errors.New("whisper local: url or model is required")
+		}
+		if _, err := exec.LookPath(w.opt.Command); err != nil {
+			return fmt.Errorf("whisper local: command not found: %s", w.opt.Command)
+		}
+		if _, err := os.Stat(w.opt.Model); err != nil {
+			return fmt.Errorf("whisper local: model not found: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &whisperSession{
+		ctx:      ctx,
+		cancel:   cancel,
+		dialogID: dialogID,
+		jobs:     make(chan whisperJob, 8),
+		detector: vad.New(vad.Config{
+			SampleRate: w.opt.SampleRate,
+			EndFrames:  w.opt.SilenceMs / int(vad.DefaultFrameDuration/time.Millisecond),
+		}),
+	}
+	w.mu.Lock()
+	if old := w.session; old != nil && !old.ended {
+		old.cancel()
+	}
+	w.session = session
+	w.mu.Unlock()
+	go w.recvResults(session)
+	return nil
+}
+
+func (w *LocalWhisperASR) Activity() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.session != nil && !w.session.ended && w.session.ctx.Err() == nil
+}
+
+func (w *LocalWhisperASR) RestartClient() {
+	if err := w.StopConn(); err != nil {
+		logrus.WithError(err).Error("whisper local: close client encounter an error")
+	}
+	if err := w.ConnAndReceive(uuid.New().String()); err != nil {
+		w.er(err, true)
+	}
+}
+
+// SendAudioBytes 缓存音频并用 VAD 判断语句边界，一句话结束后提交识别
+func (w *LocalWhisperASR) SendAudioBytes(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.session
+	if s == nil || s.ctx.Err() != nil {
+		return errors.New("whisper local: not connected")
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	bytesPerMs := utils.ComputeSampleByteCount(w.opt.SampleRate, 16, 1)
+	event := s.detector.Process(data)
+	s.buffer = append(s.buffer, data...)
+	now := time.Now()
+
+	switch {
+	case event == vad.EventSpeechStart:
+		s.speaking = true
+		s.started = now
+		s.lastPartial = now
+	case !s.speaking:
+		// 未说话时只保留一小段前导音频，避免切掉句首
+		if preRoll := 300 * bytesPerMs; len(s.buffer) > preRoll {
+			s.buffer = append(s.buffer[:0], s.buffer[len(s.buffer)-preRoll:]...)
+		}
+		return nil
+	}
+
+	if event == vad.EventSpeechEnd || len(s.buffer) >= w.opt.MaxSegmentMs*bytesPerMs {
+		w.flushLocked(s)
+		return nil
+	}
+	if w.opt.PartialIntervalMs > 0 && now.Sub(s.lastPartial) >= time.Duration(w.opt.PartialIntervalMs)*time.Millisecond {
+		s.lastPartial = now
+		// 识别跟不上时跳过中间结果
+		if len(s.jobs) == 0 {
+			select {
+			case s.jobs <- whisperJob{pcm: append([]byte(nil), s.buffer...), started: s.started}:
+				s.pending++
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// flushLocked 提交当前语句，调用方持有 w.mu
+func (w *LocalWhisperASR) flushLocked(s *whisperSession) {
+	if !s.speaking || len(s.buffer) == 0 {
+		return
+	}
+	job := whisperJob{pcm: s.buffer, final: true, started: s.started}
+	s.buffer = nil
+	s.speaking = false
+	s.detector.Reset()
+	select {
+	case s.jobs <- job:
+		s.pending++
+	default:
+		logrus.WithField("dialogID", s.dialogID).Warn("whisper local: transcription queue full, dropping segment")
+	}
+}
+
+func (w *LocalWhisperASR) SendEnd() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.session
+	if s == nil {
+		return nil
+	}
+	w.flushLocked(s)
+	s.ended = true
+	if s.pending == 0 {
+		s.cancel()
+	}
+	return nil
+}
+
+func (w *LocalWhisperASR) StopConn() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.session != nil {
+		w.session.cancel()
+		w.session = nil
+	}
+	return nil
+}
+
+// recvResults 按顺序识别提交的语句并回调结果
+func (w *LocalWhisperASR) recvResults(s *whisperSession) {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case job := <-s.jobs:
+			text, err := w.transcribe(s.ctx, job.pcm)
+			if s.ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				logrus.WithError(err).WithField("dialogID", s.dialogID).Error("whisper local: transcribe failed")
+				if job.final {
+					w.er(err, false)
+					return
+				}
+			} else if text != "" {
+				w.tr(text, job.final, time.Since(job.started), s.dialogID)
+			}
+
+			w.mu.Lock()
+			s.pending--
+			done := s.ended && s.pending == 0
+			w.mu.Unlock()
+			if done {
+				s.cancel()
+				return
+			}
+		}
+	}
+}
+
+// transcribe 识别一段 16bit 单声道 PCM
+func (w *LocalWhisperASR) transcribe(ctx context.Context, pcm []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(w.opt.TimeoutSeconds)*time.Second)
+	defer cancel()
+	wav := fileio.EncodeWAV(pcm, fileio.Format{SampleRate: w.opt.SampleRate, Channels: 1})
+
+	var text string
+	var err error
+	if w.opt.Url != "" {
+		text, err = w.transcribeHTTP(ctx, wav)
+	} else {
+		text, err = w.transcribeCommand(ctx, wav)
+	}
+	if err != nil {
+		return "", err
+	}
+	return cleanWhisperText(text), nil
+}
+
+// transcribeHTTP 请求 whisper.cpp server 或 OpenAI 兼容接口
+func (w *LocalWhisperASR) transcribeHTTP(ctx context.Context, wav []byte) (string, error) {
+	endpoint := w.opt.Url
+	openAICompatible := strings.Contains(endpoint, "/audio/transcriptions")
+	if u, err := url.Parse(endpoint); err == nil && !openAICompatible && strings.Trim(u.Path, "/") == "" {
+		u.Path = "/inference"
+		endpoint = u.String()
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(wav); err != nil {
+		return "", err
+	}
+	fields := map[string]string{"response_format": "json", "temperature": "0"}
+	if lang := whisperLanguage(w.opt.Language); lang != "" {
+		fields["language"] = lang
+	} else if !openAICompatible {
+		fields["language"] = "auto" // whisper.cpp server 默认按英文识别
+	}
+	if w.opt.Prompt != "" {
+		fields["prompt"] = w.opt.Prompt
+	}
+	if openAICompatible && w.opt.Model != "" {
+		fields["model"] = w.opt.Model
+	}
+	for k, v := range fields {
+		if err := form.WriteField(k, v); err != nil {
+			return "", err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("whisper local: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("whisper local: status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	var result struct {
+		Text  string `json:"text"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("whisper local: decode response: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("whisper local: %s", result.Error)
+	}
+	return result.Text, nil
+}
+
+// transcribeCommand 调用 whisper.cpp 命令行识别临时 WAV 文件
+func (w *LocalWhisperASR) transcribeCommand(ctx context.Context, wav []byte) (string, error) {
+	f, err := os.CreateTemp("", "whisper-*.wav")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(wav); err != nil {
+		f.Close()
+		return "", err
+	}
+	f.Close()
+
+	args := []string{"-m", w.opt.Model, "-f", f.Name(), "-nt", "-np"}
+	if lang := whisperLanguage(w.opt.Language); lang != "" {
+		args = append(args, "-l", lang)
+	} else {
+		args = append(args, "-l", "auto")
+	}
+	if w.opt.Threads > 0 {
+		args = append(args, "-t", strconv.Itoa(w.opt.Threads))
+	}
+	if w.opt.Prompt != "" {
+		args = append(args, "--prompt", w.opt.Prompt)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, w.opt.Command, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("whisper local: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// whisperLanguage 将 zh-CN 等语言代码转换为 Whisper 的语言代码，auto 或空返回空
+func whisperLanguage(language string) string {
+	lang := strings.ToLower(strings.TrimSpace(language))
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	if lang == "auto" {
+		return ""
+	}
+	return lang
+}
+
+// cleanWhisperText 合并多行输出并去掉 [BLANK_AUDIO] 等标记，中文之间不加空格
+func cleanWhisperText(text string) string {
+	text = whisperNoiseRegex.ReplaceAllString(text, "")
+	var b strings.Builder
+	for _, field := range strings.Fields(text) {
+		if b.Len() > 0 {
+			last, _ := utf8.DecodeLastRuneInString(b.String())
+			next, _ := utf8.DecodeRuneInString(field)
+			if !isCJK(last) && !isCJK(next) {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(field)
+	}
+	return b.String()
+}
+
+func isCJK(r rune) bool {
+	if unicode.IsPunct(r) && r > unicode.MaxLatin1 {
+		return true
+	}
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
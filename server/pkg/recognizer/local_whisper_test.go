@@ -0,0 +1,191 @@
+package recognizer
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setWhisperEnv(t *testing.T, key, value string) {
+	t.Setenv(key, value)
+	utils.InvalidateEnv(key)
+	t.Cleanup(func() { utils.InvalidateEnv(key) })
+}
+
+// whisperFrames 生成 20ms 一帧的 16kHz PCM，amplitude 为 0 时是静音
+func whisperFrames(n int, amplitude float64) [][]byte {
+	frames := make([][]byte, n)
+	for f := range frames {
+		pcm := make([]byte, 640)
+		for i := 0; i < 320; i++ {
+			v := int16(amplitude * math.Sin(2*math.Pi*300*float64(i)/16000))
+			pcm[2*i] = byte(v)
+			pcm[2*i+1] = byte(uint16(v) >> 8)
+		}
+		frames[f] = pcm
+	}
+	return frames
+}
+
+type whisperResults struct {
+	mu    sync.Mutex
+	texts []string
+	final []bool
+}
+
+func (r *whisperResults) add(text string, isLast bool, duration time.Duration, dialogID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.texts = append(r.texts, text)
+	r.final = append(r.final, isLast)
+}
+
+func (r *whisperResults) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.texts)
+}
+
+func sendWhisperAudio(t *testing.T, asr *LocalWhisperASR, frames ...[][]byte) {
+	for _, group := range frames {
+		for _, frame := range group {
+			require.NoError(t, asr.SendAudioBytes(frame))
+		}
+	}
+}
+
+func TestLocalWhisperASR_HTTP(t *testing.T) {
+	var requests int
+	var language string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/inference", r.URL.Path)
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		file, _, err := r.FormFile("file")
+		require.NoError(t, err)
+		file.Close()
+		requests++
+		language = r.FormValue("language")
+		w.Write([]byte(`{"text":" 你好，\n世界 [BLANK_AUDIO]"}`))
+	}))
+	defer server.Close()
+
+	config, err := NewTranscriberConfigFromMap("whisper_local", map[string]interface{}{"url": server.URL}, "zh-CN")
+	require.NoError(t, err)
+	svc, err := NewTranscriberFactory().CreateTranscriber(config)
+	require.NoError(t, err)
+	assert.Equal(t, "whisper_local", svc.Vendor())
+
+	results := &whisperResults{}
+	svc.Init(results.add, func(err error, isFatal bool) { t.Errorf("unexpected error: %v", err) })
+	require.NoError(t, svc.ConnAndReceive("dialog-1"))
+	defer svc.StopConn()
+	asr := svc.(*instrumentedTranscriber).TranscribeService.(*LocalWhisperASR)
+
+	// 只有静音时不识别
+	sendWhisperAudio(t, asr, whisperFrames(50, 0))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, results.len())
+
+	// 说话后静音超过 silenceMs 识别一句
+	sendWhisperAudio(t, asr, whisperFrames(25, 8000), whisperFrames(40, 0))
+	require.Eventually(t, func() bool { return results.len() == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"你好，世界"}, results.texts)
+	assert.Equal(t, []bool{true}, results.final)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, "zh", language)
+	assert.True(t, svc.Activity())
+
+	// SendEnd 提交未结束的语句，识别完成后不再活跃
+	sendWhisperAudio(t, asr, whisperFrames(25, 8000))
+	require.NoError(t, svc.SendEnd())
+	require.Eventually(t, func() bool { return results.len() == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.False(t, svc.Activity())
+}
+
+func TestLocalWhisperASR_Command(t *testing.T) {
+	dir := t.TempDir()
+	model := filepath.Join(dir, "ggml-base.bin")
+	require.NoError(t, os.WriteFile(model, []byte("model"), 0o644))
+	command := filepath.Join(dir, "whisper-cli")
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, "args") + "\necho ' Hello'\necho ' world.'\n"
+	require.NoError(t, os.WriteFile(command, []byte(script), 0o755))
+
+	setWhisperEnv(t, EnvWhisperModelDir, dir)
+	setWhisperEnv(t, EnvWhisperLocalCommand, command)
+
+	// 凭证中的 command 被忽略，模型按模型目录解析
+	config, err := NewTranscriberConfigFromMap("whisper_local", map[string]interface{}{
+		"model":             "ggml-base.bin",
+		"command":           "/bin/sh",
+		"partialIntervalMs": 200,
+	}, "auto")
+	require.NoError(t, err)
+	opt := config.(*LocalWhisperOption)
+	assert.Equal(t, command, opt.Command)
+	assert.Equal(t, model, opt.Model)
+	asr := NewLocalWhisperASR(*opt)
+	results := &whisperResults{}
+	asr.Init(results.add, func(err error, isFatal bool) { t.Errorf("unexpected error: %v", err) })
+	require.NoError(t, asr.ConnAndReceive("dialog-1"))
+	defer asr.StopConn()
+
+	sendWhisperAudio(t, asr, whisperFrames(10, 0))
+	for _, frame := range whisperFrames(25, 8000) {
+		require.NoError(t, asr.SendAudioBytes(frame))
+		time.Sleep(20 * time.Millisecond)
+	}
+	sendWhisperAudio(t, asr, whisperFrames(40, 0))
+	require.Eventually(t, func() bool {
+		results.mu.Lock()
+		defer results.mu.Unlock()
+		return len(results.final) > 0 && results.final[len(results.final)-1]
+	}, 2*time.Second, 10*time.Millisecond)
+	// 说话过程中有中间结果
+	assert.Greater(t, results.len(), 1)
+	assert.Equal(t, "Hello world.", results.texts[len(results.texts)-1])
+
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	assert.True(t, strings.HasPrefix(string(args), "-m "+model+" -f "))
+	assert.Contains(t, string(args), "-nt -np -l auto")
+}
+
+func TestLocalWhisperConfig(t *testing.T) {
+	_, err := NewTranscriberConfigFromMap("whisper_local", map[string]interface{}{}, "")
+	assert.Error(t, err)
+
+	// 未配置模型目录时不启用命令行模式
+	setWhisperEnv(t, EnvWhisperModelDir, "")
+	_, err = NewTranscriberConfigFromMap("whisper_local", map[string]interface{}{"model": "ggml-base.bin"}, "")
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	setWhisperEnv(t, EnvWhisperModelDir, dir)
+	outside := filepath.Join(t.TempDir(), "ggml-base.bin")
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link.bin")))
+	for _, model := range []string{"../ggml-base.bin", "/etc/passwd", outside, "link.bin"} {
+		_, err = NewTranscriberConfigFromMap("whisper_local", map[string]interface{}{"model": model}, "")
+		assert.Error(t, err, model)
+	}
+	// 服务接口模式的 model 是模型名，不做路径限制
+	config, err := NewTranscriberConfigFromMap("whisper_local", map[string]interface{}{"url": "http://127.0.0.1:8080", "model": "whisper-1"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "whisper-1", config.(*LocalWhisperOption).Model)
+
+	asr := NewLocalWhisperASR(NewLocalWhisperOption("", "/nonexistent/ggml-base.bin"))
+	assert.Error(t, asr.ConnAndReceive(""))
+
+	assert.Equal(t, VendorWhisperLocal, GetVendor("local"))
+	assert.Equal(t, "en", whisperLanguage("en-US"))
+	assert.Equal(t, "", whisperLanguage("auto"))
+	assert.Equal(t, "今天天气不错。Let's go", cleanWhisperText(" 今天天气\n不错。\n Let's go [音乐]"))
+}
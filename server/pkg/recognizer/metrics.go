@@ -0,0 +1,32 @@
+package recognizer
+
+import (
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/metrics"
+)
+
+// instrumentedTranscriber 记录建连耗时与识别过程中的错误，其余方法直接透传
+type instrumentedTranscriber struct {
+	TranscribeService
+}
+
+func (t *instrumentedTranscriber) Init(tr TranscribeResult, er ProcessError) {
+	t.TranscribeService.Init(tr, func(err error, isFatal bool) {
+		if monitor := metrics.GetGlobalMonitor(); monitor != nil {
+			monitor.RecordAIError("asr", t.Vendor(), "stream")
+		}
+		if er != nil {
+			er(err, isFatal)
+		}
+	})
+}
+
+func (t *instrumentedTranscriber) ConnAndReceive(dialogId string) error {
+	start := time.Now()
+	err := t.TranscribeService.ConnAndReceive(dialogId)
+	if monitor := metrics.GetGlobalMonitor(); monitor != nil {
+		monitor.RecordAIRequest("asr", t.Vendor(), "connect", time.Since(start), err)
+	}
+	return err
+}
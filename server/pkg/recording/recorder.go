@@ -0,0 +1,232 @@
+package recording
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Mode 录音声道模式
+type Mode string
+
+const (
+	ModeMixed  Mode = "mixed"  // 单声道，双方混音
+	ModeStereo Mode = "stereo" // 双声道：左声道为对端（rx），右声道为本端（tx），便于分别转写和质检
+)
+
+// Format 录音文件格式
+type Format string
+
+const (
+	FormatWAV  Format = "wav"
+	FormatOpus Format = "opus" // Ogg Opus，约为 WAV 的 1/10 大小
+)
+
+// Channel 录音的音轨
+type Channel int
+
+const (
+	ChannelRx Channel = iota // 收到的音频（用户/主叫）
+	ChannelTx                // 发出的音频（AI/被叫）
+)
+
+// DefaultSampleRate 录音默认采样率，与 ASR/TTS 使用的 16kHz 一致
+const DefaultSampleRate = 16000
+
+// ErrRecorderClosed 录音已结束
+var ErrRecorderClosed = errors.New("recorder closed")
+
+// Options 录音参数
+type Options struct {
+	SampleRate int    // 写入 PCM 的采样率，默认 16000
+	Mode       Mode   // 默认 ModeStereo
+	Format     Format // 默认 FormatWAV
+	TempDir    string // 通话期间暂存 PCM 的目录，默认系统临时目录
+}
+
+// track 一个音轨的 PCM 暂存文件，通话期间边录边写，不在内存中累积
+type track struct {
+	file    *os.File
+	samples int64 // 已写入的样本数（含补齐的静音）
+}
+
+// Recorder 录制通话双方的 16-bit 单声道 PCM
+// 两个音轨按写入时刻对齐：写入的音频落在“当前时间减去自身时长”处，之间的空隙补静音；
+// 比实时更快的写入（如 TTS 突发）顺延在已有音频之后。可并发写入。
+type Recorder struct {
+	opt   Options
+	start time.Time
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tracks [2]*track
+	closed bool
+}
+
+// NewRecorder 创建录音器，开始时间为当前时间
+func NewRecorder(opt Options) (*Recorder, error) {
+	if opt.SampleRate <= 0 {
+		opt.SampleRate = DefaultSampleRate
+	}
+	if opt.Mode == "" {
+		opt.Mode = ModeStereo
+	}
+	if opt.Format == "" {
+		opt.Format = FormatWAV
+	}
+	switch opt.Mode {
+	case ModeMixed, ModeStereo:
+	default:
+		return nil, errors.New("unsupported recording mode: " + string(opt.Mode))
+	}
+	switch opt.Format {
+	case FormatWAV, FormatOpus:
+	default:
+		return nil, errors.New("unsupported recording format: " + string(opt.Format))
+	}
+
+	r := &Recorder{opt: opt, now: time.Now}
+	for i := range r.tracks {
+		f, err := os.CreateTemp(opt.TempDir, "recording-*.pcm")
+		if err != nil {
+			r.Discard()
+			return nil, err
+		}
+		r.tracks[i] = &track{file: f}
+	}
+	r.start = r.now()
+	return r, nil
+}
+
+// Options 返回补全默认值后的参数
+func (r *Recorder) Options() Options {
+	return r.opt
+}
+
+// Write 写入一个音轨的 PCM（16-bit 小端单声道，采样率为 Options.SampleRate）
+func (r *Recorder) Write(ch Channel, pcm []byte) error {
+	if ch != ChannelRx && ch != ChannelTx {
+		return errors.New("invalid recording channel")
+	}
+	n := int64(len(pcm) / 2)
+	if n == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrRecorderClosed
+	}
+	t := r.tracks[ch]
+	pos := int64(r.now().Sub(r.start))*int64(r.opt.SampleRate)/int64(time.Second) - n
+	if gap := pos - t.samples; gap > 0 {
+		if err := writeSilence(t.file, gap); err != nil {
+			return err
+		}
+		t.samples += gap
+	}
+	if _, err := t.file.Write(pcm[:n*2]); err != nil {
+		return err
+	}
+	t.samples += n
+	return nil
+}
+
+// Duration 当前已录制的时长（两个音轨中较长者）
+func (r *Recorder) Duration() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	samples := r.tracks[ChannelRx].samples
+	if tx := r.tracks[ChannelTx].samples; tx > samples {
+		samples = tx
+	}
+	return time.Duration(samples) * time.Second / time.Duration(r.opt.SampleRate)
+}
+
+// Close 停止录音并把录音按 Options 的格式编码写入 w，之后删除暂存文件
+func (r *Recorder) Close(w io.Writer) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return ErrRecorderClosed
+	}
+	r.closed = true
+	r.mu.Unlock()
+	defer r.removeTracks()
+
+	frames := r.tracks[ChannelRx].samples
+	if tx := r.tracks[ChannelTx].samples; tx > frames {
+		frames = tx
+	}
+	readers := [2]io.Reader{}
+	for i, t := range r.tracks {
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		// 较短的音轨在末尾补静音
+		readers[i] = io.MultiReader(t.file, &zeroReader{remaining: (frames - t.samples) * 2})
+	}
+	source := &frameSource{mode: r.opt.Mode, rx: readers[ChannelRx], tx: readers[ChannelTx], remaining: frames}
+
+	switch r.opt.Format {
+	case FormatOpus:
+		return writeOggOpus(w, source, r.opt.SampleRate, source.channels())
+	default:
+		return writeWAV(w, source, r.opt.SampleRate, source.channels(), frames)
+	}
+}
+
+// Discard 停止录音并丢弃数据
+func (r *Recorder) Discard() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	r.removeTracks()
+}
+
+func (r *Recorder) removeTracks() {
+	for _, t := range r.tracks {
+		if t == nil {
+			continue
+		}
+		t.file.Close()
+		os.Remove(t.file.Name())
+	}
+}
+
+var silence = make([]byte, 4096)
+
+func writeSilence(w io.Writer, samples int64) error {
+	for remaining := samples * 2; remaining > 0; {
+		n := int64(len(silence))
+		if remaining < n {
+			n = remaining
+		}
+		if _, err := w.Write(silence[:n]); err != nil {
+			return err
+		}
+		remaining -= n
+	}
+	return nil
+}
+
+type zeroReader struct {
+	remaining int64
+}
+
+func (z *zeroReader) Read(p []byte) (int, error) {
+	if z.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > z.remaining {
+		p = p[:z.remaining]
+	}
+	for i := range p {
+		p[i] = 0
+	}
+	z.remaining -= int64(len(p))
+	return len(p), nil
+}
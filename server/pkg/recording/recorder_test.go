@@ -0,0 +1,190 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestRecorder 创建使用可控时钟的录音器
+func newTestRecorder(t *testing.T, opt Options) (*Recorder, *time.Time) {
+	opt.TempDir = t.TempDir()
+	rec, err := NewRecorder(opt)
+	require.NoError(t, err)
+	clock := rec.start
+	rec.now = func() time.Time { return clock }
+	return rec, &clock
+}
+
+func pcmOf(value int16, samples int) []byte {
+	buf := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		binary.LittleEndian.PutUint16(buf[2*i:], uint16(value))
+	}
+	return buf
+}
+
+func sampleAt(data []byte, index int) int16 {
+	return int16(binary.LittleEndian.Uint16(data[2*index:]))
+}
+
+func TestRecorderStereoAlignment(t *testing.T) {
+	rec, clock := newTestRecorder(t, Options{Mode: ModeStereo, Format: FormatWAV})
+
+	// 20ms 时收到 20ms 用户音频，40ms 时发出 20ms TTS 音频
+	*clock = clock.Add(20 * time.Millisecond)
+	require.NoError(t, rec.Write(ChannelRx, pcmOf(1000, 320)))
+	*clock = clock.Add(20 * time.Millisecond)
+	require.NoError(t, rec.Write(ChannelTx, pcmOf(-2000, 320)))
+	assert.Equal(t, 40*time.Millisecond, rec.Duration())
+
+	var out bytes.Buffer
+	require.NoError(t, rec.Close(&out))
+	wav := out.Bytes()
+	require.Len(t, wav, 44+640*4)
+	assert.Equal(t, "RIFF", string(wav[0:4]))
+	assert.Equal(t, uint16(2), binary.LittleEndian.Uint16(wav[22:]))
+	assert.Equal(t, uint32(DefaultSampleRate), binary.LittleEndian.Uint32(wav[24:]))
+	assert.Equal(t, uint32(640*4), binary.LittleEndian.Uint32(wav[40:]))
+
+	data := wav[44:]
+	// 左声道为 rx，右声道为 tx；tx 前 20ms 补静音，rx 末尾补静音
+	assert.Equal(t, int16(1000), sampleAt(data, 0))
+	assert.Equal(t, int16(0), sampleAt(data, 1))
+	assert.Equal(t, int16(0), sampleAt(data, 2*320))
+	assert.Equal(t, int16(-2000), sampleAt(data, 2*320+1))
+
+	assert.ErrorIs(t, rec.Write(ChannelRx, pcmOf(1, 10)), ErrRecorderClosed)
+	assert.ErrorIs(t, rec.Close(&out), ErrRecorderClosed)
+}
+
+func TestRecorderBurstWrites(t *testing.T) {
+	rec, clock := newTestRecorder(t, Options{Mode: ModeMixed})
+
+	// 比实时更快的写入顺延在已有音频之后，不会互相覆盖
+	*clock = clock.Add(20 * time.Millisecond)
+	require.NoError(t, rec.Write(ChannelTx, pcmOf(100, 320)))
+	require.NoError(t, rec.Write(ChannelTx, pcmOf(200, 320)))
+	assert.Equal(t, 40*time.Millisecond, rec.Duration())
+
+	var out bytes.Buffer
+	require.NoError(t, rec.Close(&out))
+	data := out.Bytes()[44:]
+	require.Len(t, data, 640*2)
+	assert.Equal(t, int16(100), sampleAt(data, 319))
+	assert.Equal(t, int16(200), sampleAt(data, 320))
+}
+
+func TestRecorderMixedClipping(t *testing.T) {
+	rec, clock := newTestRecorder(t, Options{Mode: ModeMixed, Format: FormatWAV, SampleRate: 8000})
+
+	*clock = clock.Add(10 * time.Millisecond)
+	require.NoError(t, rec.Write(ChannelRx, pcmOf(30000, 80)))
+	require.NoError(t, rec.Write(ChannelTx, pcmOf(30000, 40)))
+
+	var out bytes.Buffer
+	require.NoError(t, rec.Close(&out))
+	wav := out.Bytes()
+	assert.Equal(t, uint16(1), binary.LittleEndian.Uint16(wav[22:]))
+	data := wav[44:]
+	require.Len(t, data, 80*2)
+	// tx 较短，对齐到当前时刻，落在后半段
+	assert.Equal(t, int16(30000), sampleAt(data, 0))
+	assert.Equal(t, int16(32767), sampleAt(data, 79))
+}
+
+func TestNewRecorderInvalidOptions(t *testing.T) {
+	_, err := NewRecorder(Options{Mode: "surround", TempDir: t.TempDir()})
+	assert.Error(t, err)
+	_, err = NewRecorder(Options{Format: "mp3", TempDir: t.TempDir()})
+	assert.Error(t, err)
+}
+
+func TestSaveAndPurgeExpired(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.CallRecording{}, &models.RecordingPolicy{}))
+	store := &stores.LocalStore{Root: t.TempDir(), NewDirPerm: 0755}
+
+	rec, clock := newTestRecorder(t, Options{})
+	*clock = clock.Add(time.Second)
+	require.NoError(t, rec.Write(ChannelRx, pcmOf(1, DefaultSampleRate)))
+
+	saved, err := Save(db, store, rec, Meta{UserID: 7, SessionID: "session/1", Source: models.RecordingSourceWebRTC})
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved.Duration)
+	assert.Equal(t, int64(44+DefaultSampleRate*2*2), saved.Size)
+	assert.Contains(t, saved.StorageKey, "recordings/7/")
+	assert.Contains(t, saved.StorageKey, "session_1.wav")
+	exists, err := store.Exists(saved.StorageKey)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// 空录音不保存
+	empty, _ := newTestRecorder(t, Options{})
+	_, err = Save(db, store, empty, Meta{UserID: 7})
+	assert.ErrorIs(t, err, ErrEmptyRecording)
+
+	// 未配置保留天数时不清理
+	deleted, err := PurgeExpired(db, store, time.Now().AddDate(0, 0, 30))
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	require.NoError(t, db.Create(&models.RecordingPolicy{UserID: 7, Enabled: true, RetentionDays: 7}).Error)
+	deleted, err = PurgeExpired(db, store, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	deleted, err = PurgeExpired(db, store, time.Now().AddDate(0, 0, 8))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	exists, err = store.Exists(saved.StorageKey)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	var count int64
+	db.Model(&models.CallRecording{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestPurgeWithoutPolicy(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.CallRecording{}, &models.RecordingPolicy{}))
+	store := &stores.LocalStore{Root: t.TempDir(), NewDirPerm: 0755}
+
+	save := func(userID uint) *models.CallRecording {
+		rec, clock := newTestRecorder(t, Options{})
+		*clock = clock.Add(time.Second)
+		require.NoError(t, rec.Write(ChannelRx, pcmOf(1, DefaultSampleRate)))
+		saved, err := Save(db, store, rec, Meta{UserID: userID, SessionID: fmt.Sprintf("session-%d", userID), Source: models.RecordingSourceWebRTC})
+		require.NoError(t, err)
+		return saved
+	}
+	withoutPolicy := save(7)
+	// 设置了策略（即使永久保留）的用户不受全局保留天数影响
+	require.NoError(t, db.Create(&models.RecordingPolicy{UserID: 8, Enabled: true}).Error)
+	withPolicy := save(8)
+
+	deleted, err := PurgeWithoutPolicy(db, store, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	deleted, err = PurgeWithoutPolicy(db, store, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	exists, err := store.Exists(withoutPolicy.StorageKey)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = store.Exists(withPolicy.StorageKey)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
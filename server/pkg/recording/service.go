@@ -0,0 +1,164 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ErrEmptyRecording 录音中没有任何音频，不保存
+var ErrEmptyRecording = errors.New("empty recording")
+
+// Meta 录音归属信息
+type Meta struct {
+	UserID      uint
+	AssistantID *uint
+	SessionID   string
+	Source      models.RecordingSource
+}
+
+// ForUser 按用户的录音策略创建录音器，用户未开启录音时返回 nil
+func ForUser(db *gorm.DB, userID uint) (*Recorder, error) {
+	policy, err := models.GetRecordingPolicy(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Enabled {
+		return nil, nil
+	}
+	return NewRecorder(Options{Mode: Mode(policy.Mode), Format: Format(policy.Format)})
+}
+
+var unsafeKeyChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// storageKey recordings/{userID}/{yyyymmdd}/{sessionID}.{ext}
+func storageKey(meta Meta, format Format, now time.Time) string {
+	ext := "wav"
+	if format == FormatOpus {
+		ext = "ogg"
+	}
+	return fmt.Sprintf("recordings/%d/%s/%s.%s",
+		meta.UserID, now.Format("20060102"), unsafeKeyChars.ReplaceAllString(meta.SessionID, "_"), ext)
+}
+
+// ContentType 返回录音格式对应的 MIME 类型
+func ContentType(format string) string {
+	if Format(format) == FormatOpus {
+		return "audio/ogg"
+	}
+	return "audio/wav"
+}
+
+// countingWriter 统计写入的字节数；不实现 io.Closer，避免编码器关闭管道
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Save 结束录音，边编码边上传到存储，并写入 CallRecording 记录
+func Save(db *gorm.DB, store stores.Store, rec *Recorder, meta Meta) (*models.CallRecording, error) {
+	duration := rec.Duration()
+	if duration == 0 {
+		rec.Discard()
+		return nil, ErrEmptyRecording
+	}
+
+	opt := rec.Options()
+	key := storageKey(meta, opt.Format, time.Now())
+	pr, pw := io.Pipe()
+	counter := &countingWriter{w: pw}
+	go func() {
+		pw.CloseWithError(rec.Close(counter))
+	}()
+	if err := store.Write(key, pr); err != nil {
+		pr.CloseWithError(err)
+		return nil, fmt.Errorf("upload recording: %w", err)
+	}
+
+	recording := &models.CallRecording{
+		UserID:      meta.UserID,
+		AssistantID: meta.AssistantID,
+		SessionID:   meta.SessionID,
+		Source:      meta.Source,
+		Format:      string(opt.Format),
+		Mode:        string(opt.Mode),
+		SampleRate:  opt.SampleRate,
+		Duration:    int(duration.Round(time.Second) / time.Second),
+		Size:        counter.n,
+		StorageKey:  key,
+	}
+	if err := db.Create(recording).Error; err != nil {
+		if delErr := store.Delete(key); delErr != nil {
+			logrus.WithError(delErr).WithField("key", key).Warn("Failed to delete orphaned recording")
+		}
+		return nil, err
+	}
+	return recording, nil
+}
+
+// Delete 删除录音文件与记录；文件已不存在时仍删除记录
+func Delete(db *gorm.DB, store stores.Store, recording *models.CallRecording) error {
+	if exists, err := store.Exists(recording.StorageKey); err == nil && exists {
+		if err := store.Delete(recording.StorageKey); err != nil {
+			return err
+		}
+	}
+	return db.Delete(recording).Error
+}
+
+// PurgeExpired 按各用户的保留天数删除过期录音，返回删除的数量
+func PurgeExpired(db *gorm.DB, store stores.Store, now time.Time) (int, error) {
+	var policies []models.RecordingPolicy
+	if err := db.Where("retention_days > 0").Find(&policies).Error; err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, policy := range policies {
+		var expired []models.CallRecording
+		cutoff := now.AddDate(0, 0, -policy.RetentionDays)
+		if err := db.Where("user_id = ? AND created_at < ?", policy.UserID, cutoff).Find(&expired).Error; err != nil {
+			return deleted, err
+		}
+		for i := range expired {
+			if err := Delete(db, store, &expired[i]); err != nil {
+				logrus.WithError(err).WithField("recording_id", expired[i].ID).Warn("Failed to delete expired recording")
+				continue
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// PurgeWithoutPolicy 删除未设置录音策略的用户在 cutoff 之前的录音，返回删除的数量
+func PurgeWithoutPolicy(db *gorm.DB, store stores.Store, cutoff time.Time) (int, error) {
+	var expired []models.CallRecording
+	if err := db.Where("created_at < ? AND user_id NOT IN (?)", cutoff,
+		db.Model(&models.RecordingPolicy{}).Select("user_id")).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for i := range expired {
+		if err := Delete(db, store, &expired[i]); err != nil {
+			logrus.WithError(err).WithField("recording_id", expired[i].ID).Warn("Failed to delete expired recording")
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
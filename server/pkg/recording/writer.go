@@ -0,0 +1,152 @@
+package recording
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+)
+
+// chunkFrames 写 WAV 时每次读取的帧数
+const chunkFrames = 960
+
+// frameSource 从两个音轨读取对齐的 PCM，按模式混音或交织为立体声
+type frameSource struct {
+	mode      Mode
+	rx, tx    io.Reader
+	remaining int64 // 剩余帧数
+	rxBuf     []byte
+	txBuf     []byte
+}
+
+func (s *frameSource) channels() int {
+	if s.mode == ModeStereo {
+		return 2
+	}
+	return 1
+}
+
+// next 返回最多 maxFrames 帧输出 PCM，读完时返回 io.EOF
+func (s *frameSource) next(maxFrames int) ([]byte, error) {
+	if s.remaining <= 0 {
+		return nil, io.EOF
+	}
+	frames := int64(maxFrames)
+	if frames > s.remaining {
+		frames = s.remaining
+	}
+	n := int(frames) * 2
+	if cap(s.rxBuf) < n {
+		s.rxBuf, s.txBuf = make([]byte, n), make([]byte, n)
+	}
+	rx, tx := s.rxBuf[:n], s.txBuf[:n]
+	if _, err := io.ReadFull(s.rx, rx); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(s.tx, tx); err != nil {
+		return nil, err
+	}
+	s.remaining -= frames
+
+	out := make([]byte, n*s.channels())
+	for i := 0; i < int(frames); i++ {
+		a := int16(binary.LittleEndian.Uint16(rx[2*i:]))
+		b := int16(binary.LittleEndian.Uint16(tx[2*i:]))
+		if s.mode == ModeStereo {
+			binary.LittleEndian.PutUint16(out[4*i:], uint16(a))
+			binary.LittleEndian.PutUint16(out[4*i+2:], uint16(b))
+			continue
+		}
+		sum := int32(a) + int32(b)
+		if sum > 32767 {
+			sum = 32767
+		} else if sum < -32768 {
+			sum = -32768
+		}
+		binary.LittleEndian.PutUint16(out[2*i:], uint16(int16(sum)))
+	}
+	return out, nil
+}
+
+// writeWAV 写入 16-bit PCM WAV，帧数事先已知，不需要回写文件头（可直接写入对象存储）
+func writeWAV(w io.Writer, src *frameSource, sampleRate, channels int, frames int64) error {
+	dataSize := uint32(frames) * uint32(channels) * 2
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], 36+dataSize)
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(sampleRate*channels*2))
+	binary.LittleEndian.PutUint16(header[32:], uint16(channels*2))
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], dataSize)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	for {
+		pcm, err := src.next(chunkFrames)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(pcm); err != nil {
+			return err
+		}
+	}
+}
+
+// writeOggOpus 以 20ms 帧编码为 Ogg Opus
+func writeOggOpus(w io.Writer, src *frameSource, sampleRate, channels int) error {
+	encode, err := encoder.NewTranscoder(
+		media.CodecConfig{Codec: encoder.CodecPCM, SampleRate: sampleRate, Channels: channels},
+		media.CodecConfig{Codec: encoder.CodecOPUS, SampleRate: sampleRate, Channels: channels, FrameDuration: "20ms"},
+	)
+	if err != nil {
+		return err
+	}
+	ogg, err := oggwriter.NewWith(w, uint32(sampleRate), uint16(channels))
+	if err != nil {
+		return err
+	}
+
+	// Ogg Opus 的 granule 固定以 48kHz 计数，每个 20ms 包前进 960
+	frameSize := sampleRate / 50
+	packet := &rtp.Packet{Header: rtp.Header{Timestamp: 1}}
+	for {
+		pcm, err := src.next(frameSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		encoded, err := encode.Transcode(&media.AudioPacket{Payload: pcm})
+		if err != nil {
+			return err
+		}
+		for _, p := range encoded {
+			audioPacket, ok := p.(*media.AudioPacket)
+			if !ok || len(audioPacket.Payload) == 0 {
+				continue
+			}
+			packet.Payload = audioPacket.Payload
+			packet.Timestamp += 960
+			packet.SequenceNumber++
+			if err := ogg.WriteRTP(packet); err != nil {
+				return err
+			}
+		}
+	}
+	return ogg.Close()
+}
@@ -0,0 +1,248 @@
+package sip
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+)
+
+// dtmfDigits RFC 4733 事件码 0-15 对应的按键
+const dtmfDigits = "0123456789*#ABCD"
+
+// TelephoneEvent RFC 4733 telephone-event 负载
+type TelephoneEvent struct {
+	Event    uint8
+	End      bool
+	Volume   uint8 // 音量，单位 -dBm0
+	Duration uint16
+}
+
+var errShortTelephoneEvent = errors.New("sip: telephone-event payload too short")
+
+// ParseTelephoneEvent 解析 4 字节的 telephone-event 负载
+func ParseTelephoneEvent(payload []byte) (TelephoneEvent, error) {
+	if len(payload) < 4 {
+		return TelephoneEvent{}, errShortTelephoneEvent
+	}
+	return TelephoneEvent{
+		Event:    payload[0],
+		End:      payload[1]&0x80 != 0,
+		Volume:   payload[1] & 0x3f,
+		Duration: uint16(payload[2])<<8 | uint16(payload[3]),
+	}, nil
+}
+
+// Digit 返回事件对应的按键，非 DTMF 事件（如 flash）返回空串
+func (e TelephoneEvent) Digit() string {
+	if int(e.Event) >= len(dtmfDigits) {
+		return ""
+	}
+	return dtmfDigits[e.Event : e.Event+1]
+}
+
+// sdpTelephoneEventPT 返回主叫 offer 中 telephone-event 的负载类型，未提供时返回 0
+func sdpTelephoneEventPT(sdpBody string) uint8 {
+	var session sdp.SessionDescription
+	if err := session.Unmarshal([]byte(sdpBody)); err != nil {
+		return 0
+	}
+	for _, media := range session.MediaDescriptions {
+		if media.MediaName.Media != "audio" {
+			continue
+		}
+		for _, attr := range media.Attributes {
+			if attr.Key != "rtpmap" {
+				continue
+			}
+			pt, name, ok := strings.Cut(attr.Value, " ")
+			if !ok || !strings.HasPrefix(strings.ToLower(name), "telephone-event/8000") {
+				continue
+			}
+			value, err := strconv.ParseUint(pt, 10, 7)
+			if err != nil {
+				return 0
+			}
+			return uint8(value)
+		}
+	}
+	return 0
+}
+
+// DTMFDetector 从一路 RTP 中识别按键。
+// 协商了 telephone-event 时按 RFC 4733 事件识别；对端发送过事件后不再做带内检测，
+// 否则对 G.711 音频做 Goertzel 双音检测
+type DTMFDetector struct {
+	eventPT uint8              // telephone-event 负载类型，0 表示未协商
+	audioPT uint8              // 音频负载类型
+	decode  func(byte) int16   // G.711 解码，nil 时不做带内检测
+	onDigit func(digit string) // 识别到按键时回调
+
+	eventSeen   bool
+	lastEventTS uint32
+	inband      *GoertzelDetector
+}
+
+// NewDTMFDetector 为协商出的音频编码创建检测器
+func NewDTMFDetector(codec bridgeCodec, eventPT uint8, onDigit func(digit string)) *DTMFDetector {
+	d := &DTMFDetector{
+		eventPT: eventPT,
+		audioPT: codec.PayloadType,
+		onDigit: onDigit,
+		inband:  NewGoertzelDetector(),
+	}
+	switch codec.Name {
+	case constants.CodecPCMU:
+		d.decode = encoder.ULawToLinear
+	case constants.CodecPCMA:
+		d.decode = encoder.ALawToLinear
+	}
+	return d
+}
+
+// Process 处理一个收到的 RTP 包
+func (d *DTMFDetector) Process(pkt *rtp.Packet) {
+	switch {
+	case d.eventPT != 0 && pkt.PayloadType == d.eventPT:
+		event, err := ParseTelephoneEvent(pkt.Payload)
+		if err != nil {
+			return
+		}
+		// 同一次按键的所有包（包括重复 3 次的结束包）时间戳相同
+		if d.eventSeen && pkt.Timestamp == d.lastEventTS {
+			return
+		}
+		d.eventSeen = true
+		d.lastEventTS = pkt.Timestamp
+		if digit := event.Digit(); digit != "" {
+			d.onDigit(digit)
+		}
+	case pkt.PayloadType == d.audioPT && d.decode != nil && !d.eventSeen:
+		samples := make([]int16, len(pkt.Payload))
+		for i, b := range pkt.Payload {
+			samples[i] = d.decode(b)
+		}
+		for _, digit := range d.inband.Write(samples) {
+			d.onDigit(digit)
+		}
+	}
+}
+
+// Goertzel 检测参数：8kHz 采样，每块 205 个样本（约 25.6ms）
+const (
+	goertzelSampleRate = 8000
+	goertzelBlockSize  = 205
+	goertzelMinPower   = 400.0 * 400.0 // 每样本最小平均能量，低于此视为静音
+	goertzelToneRatio  = 0.6           // 两个检测频率的能量占总能量的最小比例
+	goertzelPeakRatio  = 4.0           // 峰值频率与同组其它频率的最小能量比
+	goertzelMaxTwist   = 6.3           // 高低频组能量比的上限（约 8dB）
+)
+
+var (
+	dtmfLowFreqs  = [4]float64{697, 770, 852, 941}
+	dtmfHighFreqs = [4]float64{1209, 1336, 1477, 1633}
+	dtmfKeypad    = [4]string{"123A", "456B", "789C", "*0#D"}
+)
+
+// GoertzelDetector 带内 DTMF 检测，按键在连续两块中检出才上报，静音或其它声音后才能再次上报同一按键
+type GoertzelDetector struct {
+	lowCoeff  [4]float64
+	highCoeff [4]float64
+	block     []int16
+
+	candidate string // 上一块检出的按键
+	reported  string // 已上报、尚未松开的按键
+}
+
+// NewGoertzelDetector 创建 8kHz 的带内 DTMF 检测器
+func NewGoertzelDetector() *GoertzelDetector {
+	g := &GoertzelDetector{block: make([]int16, 0, goertzelBlockSize)}
+	for i := range dtmfLowFreqs {
+		g.lowCoeff[i] = 2 * math.Cos(2*math.Pi*dtmfLowFreqs[i]/goertzelSampleRate)
+		g.highCoeff[i] = 2 * math.Cos(2*math.Pi*dtmfHighFreqs[i]/goertzelSampleRate)
+	}
+	return g
+}
+
+// Write 写入 8kHz PCM 样本，返回新识别到的按键
+func (g *GoertzelDetector) Write(samples []int16) []string {
+	var digits []string
+	for len(samples) > 0 {
+		n := goertzelBlockSize - len(g.block)
+		if n > len(samples) {
+			n = len(samples)
+		}
+		g.block = append(g.block, samples[:n]...)
+		samples = samples[n:]
+		if len(g.block) < goertzelBlockSize {
+			break
+		}
+		digit := g.detect(g.block)
+		g.block = g.block[:0]
+
+		if digit == "" {
+			g.candidate, g.reported = "", ""
+			continue
+		}
+		if digit == g.candidate && digit != g.reported {
+			g.reported = digit
+			digits = append(digits, digit)
+		}
+		g.candidate = digit
+	}
+	return digits
+}
+
+// detect 对一块样本做 Goertzel 检测，返回按键或空串
+func (g *GoertzelDetector) detect(block []int16) string {
+	var energy float64
+	for _, s := range block {
+		energy += float64(s) * float64(s)
+	}
+	n := float64(len(block))
+	if energy/n < goertzelMinPower {
+		return ""
+	}
+
+	low, lowPower, lowOK := g.strongest(block, g.lowCoeff)
+	high, highPower, highOK := g.strongest(block, g.highCoeff)
+	if !lowOK || !highOK {
+		return ""
+	}
+	// 纯双音时两个频率的 Goertzel 能量之和约为 energy*n/2
+	if (lowPower+highPower)/(energy*n/2) < goertzelToneRatio {
+		return ""
+	}
+	if lowPower > highPower*goertzelMaxTwist || highPower > lowPower*goertzelMaxTwist {
+		return ""
+	}
+	return dtmfKeypad[low][high : high+1]
+}
+
+// strongest 返回一组频率中能量最大者，其余频率能量不够低时视为无效
+func (g *GoertzelDetector) strongest(block []int16, coeffs [4]float64) (int, float64, bool) {
+	var powers [4]float64
+	best := 0
+	for i, coeff := range coeffs {
+		var s1, s2 float64
+		for _, x := range block {
+			s := float64(x) + coeff*s1 - s2
+			s2, s1 = s1, s
+		}
+		powers[i] = s1*s1 + s2*s2 - coeff*s1*s2
+		if powers[i] > powers[best] {
+			best = i
+		}
+	}
+	for i, p := range powers {
+		if i != best && p*goertzelPeakRatio > powers[best] {
+			return 0, 0, false
+		}
+	}
+	return best, powers[best], true
+}
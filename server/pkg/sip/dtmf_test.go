@@ -0,0 +1,107 @@
+package sip
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dtmfTone 生成 8kHz 的双音样本
+func dtmfTone(low, high float64, ms int) []int16 {
+	samples := make([]int16, 8*ms)
+	for i := range samples {
+		t := float64(i) / 8000
+		samples[i] = int16(6000*math.Sin(2*math.Pi*low*t) + 6000*math.Sin(2*math.Pi*high*t))
+	}
+	return samples
+}
+
+func TestParseTelephoneEvent(t *testing.T) {
+	event, err := ParseTelephoneEvent([]byte{11, 0x8a, 0x03, 0x20})
+	require.NoError(t, err)
+	assert.Equal(t, "#", event.Digit())
+	assert.True(t, event.End)
+	assert.Equal(t, uint8(10), event.Volume)
+	assert.Equal(t, uint16(800), event.Duration)
+
+	_, err = ParseTelephoneEvent([]byte{1, 2})
+	assert.Error(t, err)
+	assert.Empty(t, TelephoneEvent{Event: 16}.Digit())
+
+	assert.Equal(t, uint8(96), sdpTelephoneEventPT(offerSDP("0 96", "0 PCMU/8000", "96 telephone-event/8000")))
+	assert.Zero(t, sdpTelephoneEventPT(offerSDP("0", "0 PCMU/8000")))
+}
+
+func TestDTMFDetectorTelephoneEvent(t *testing.T) {
+	var digits []string
+	d := NewDTMFDetector(bridgeCodecs[0], 101, func(digit string) { digits = append(digits, digit) })
+
+	press := func(ts uint32, event byte) {
+		// 一次按键：开始包、更新包与 3 个结束包共用时间戳
+		for _, flags := range []byte{0x0a, 0x0a, 0x8a, 0x8a, 0x8a} {
+			d.Process(&rtp.Packet{Header: rtp.Header{PayloadType: 101, Timestamp: ts}, Payload: []byte{event, flags, 0, 160}})
+		}
+	}
+	press(1000, 1)
+	press(5000, 1)
+	press(9000, 10)
+	assert.Equal(t, []string{"1", "1", "*"}, digits)
+
+	// 收到过事件后带内音不再重复上报
+	d.Process(&rtp.Packet{Header: rtp.Header{PayloadType: 0}, Payload: make([]byte, 160)})
+	assert.Len(t, digits, 3)
+}
+
+func TestGoertzelDetector(t *testing.T) {
+	g := NewGoertzelDetector()
+	var digits []string
+	silence := make([]int16, 8*60)
+	for _, key := range []struct{ low, high float64 }{{697, 1209}, {941, 1477}, {941, 1477}, {852, 1633}} {
+		digits = append(digits, g.Write(dtmfTone(key.low, key.high, 100))...)
+		digits = append(digits, g.Write(silence)...)
+	}
+	assert.Equal(t, []string{"1", "#", "#", "C"}, digits)
+
+	// 持续按住只上报一次
+	assert.Equal(t, []string{"5"}, g.Write(dtmfTone(770, 1336, 500)))
+
+	// 噪声与单音不被误判
+	g = NewGoertzelDetector()
+	noise := make([]int16, 8000)
+	r := rand.New(rand.NewSource(1))
+	for i := range noise {
+		noise[i] = int16(r.Intn(12000) - 6000)
+	}
+	assert.Empty(t, g.Write(noise))
+	single := make([]int16, 8*200)
+	for i := range single {
+		single[i] = int16(8000 * math.Sin(2*math.Pi*1000*float64(i)/8000))
+	}
+	assert.Empty(t, g.Write(single))
+}
+
+func TestDTMFDetectorInband(t *testing.T) {
+	var digits []string
+	d := NewDTMFDetector(bridgeCodecs[1], 0, func(digit string) { digits = append(digits, digit) })
+	tone := dtmfTone(852, 1336, 120)
+	for i := 0; i+160 <= len(tone); i += 160 {
+		payload, err := encoder.EncodePCMA(pcmBytes(tone[i : i+160]))
+		require.NoError(t, err)
+		d.Process(&rtp.Packet{Header: rtp.Header{PayloadType: 8}, Payload: payload})
+	}
+	assert.Equal(t, []string{"8"}, digits)
+}
+
+func pcmBytes(samples []int16) []byte {
+	out := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		out[2*i] = byte(s)
+		out[2*i+1] = byte(s >> 8)
+	}
+	return out
+}
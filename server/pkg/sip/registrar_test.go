@@ -0,0 +1,178 @@
+package sip
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/emiago/sipgo/sip"
+	"github.com/glebarez/sqlite"
+	"github.com/icholy/digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupRegistrarDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.SipUser{}, &models.SipRegistration{}))
+	return db
+}
+
+// registerRequest 构造 REGISTER 请求，extra 为附加的头（不含结尾换行）
+func registerRequest(t *testing.T, username string, cseq int, contact string, extra ...string) *sip.Request {
+	t.Helper()
+	lines := []string{
+		"REGISTER sip:lingecho SIP/2.0",
+		"Via: SIP/2.0/UDP 192.168.1.20:5060;branch=z9hG4bK" + fmt.Sprint(cseq),
+		"From: <sip:" + username + "@lingecho>;tag=abc",
+		"To: <sip:" + username + "@lingecho>",
+		"Call-ID: phone-1",
+		fmt.Sprintf("CSeq: %d REGISTER", cseq),
+		"Max-Forwards: 70",
+	}
+	if contact != "" {
+		lines = append(lines, "Contact: "+contact)
+	}
+	lines = append(lines, extra...)
+	lines = append(lines, "Content-Length: 0", "", "")
+	msg, err := sip.ParseMessage([]byte(strings.Join(lines, "\r\n")))
+	require.NoError(t, err)
+	req := msg.(*sip.Request)
+	req.SetSource("203.0.113.7:40000")
+	return req
+}
+
+// authorize 按质询计算 Authorization 头
+func authorize(t *testing.T, res *sip.Response, username, password string) string {
+	t.Helper()
+	h := res.GetHeader("WWW-Authenticate")
+	require.NotNil(t, h)
+	chal, err := digest.ParseChallenge(h.Value())
+	require.NoError(t, err)
+	cred, err := digest.Digest(chal, digest.Options{
+		Method:   "REGISTER",
+		URI:      "sip:lingecho",
+		Username: username,
+		Password: password,
+	})
+	require.NoError(t, err)
+	return "Authorization: " + cred.String()
+}
+
+func TestRegistrarDigestAuth(t *testing.T) {
+	db := setupRegistrarDB(t)
+	require.NoError(t, models.CreateSipUser(db, &models.SipUser{Username: "1001", Password: "secret", Enabled: true}))
+	r := NewRegistrar(db)
+
+	res := r.HandleRegister(registerRequest(t, "1001", 1, "<sip:1001@192.168.1.20:5060>"))
+	require.Equal(t, sip.StatusUnauthorized, res.StatusCode)
+
+	bad := authorize(t, res, "1001", "wrong")
+	res = r.HandleRegister(registerRequest(t, "1001", 2, "<sip:1001@192.168.1.20:5060>", bad))
+	require.Equal(t, sip.StatusUnauthorized, res.StatusCode)
+
+	auth := authorize(t, res, "1001", "secret")
+	res = r.HandleRegister(registerRequest(t, "1001", 3, "<sip:1001@192.168.1.20:5060>", auth, "Expires: 600"))
+	require.Equal(t, sip.StatusOK, res.StatusCode)
+
+	binding, ok := r.Lookup("1001")
+	require.True(t, ok)
+	// NAT 后的终端按请求来源地址发送
+	assert.Equal(t, "203.0.113.7:40000", binding.Addr())
+	assert.Equal(t, "sip:1001@192.168.1.20:5060", binding.Contact)
+
+	user, err := models.GetSipUserByUsername(db, "1001")
+	require.NoError(t, err)
+	assert.Equal(t, models.SipUserStatusRegistered, user.Status)
+	assert.Equal(t, 1, user.RegisterCount)
+
+	// 重启后从数据库恢复
+	restored := NewRegistrar(db)
+	require.NoError(t, restored.Restore())
+	_, ok = restored.Lookup("1001")
+	assert.True(t, ok)
+}
+
+func TestRegistrarStaleNonce(t *testing.T) {
+	db := setupRegistrarDB(t)
+	require.NoError(t, models.CreateSipUser(db, &models.SipUser{Username: "1001", Password: "secret", Enabled: true}))
+	r := NewRegistrar(db)
+	r.NonceTTL = -time.Second
+
+	res := r.HandleRegister(registerRequest(t, "1001", 1, "<sip:1001@192.168.1.20:5060>"))
+	auth := authorize(t, res, "1001", "secret")
+	res = r.HandleRegister(registerRequest(t, "1001", 2, "<sip:1001@192.168.1.20:5060>", auth))
+	require.Equal(t, sip.StatusUnauthorized, res.StatusCode)
+	assert.Contains(t, res.GetHeader("WWW-Authenticate").Value(), "stale=true")
+}
+
+func TestRegistrarBindings(t *testing.T) {
+	db := setupRegistrarDB(t)
+	require.NoError(t, models.CreateSipUser(db, &models.SipUser{Username: "1002", Enabled: true}))
+	r := NewRegistrar(db)
+
+	res := r.HandleRegister(registerRequest(t, "1002", 1, "<sip:1002@10.0.0.5>;expires=30"))
+	require.Equal(t, sip.StatusIntervalToBrief, res.StatusCode)
+	assert.Equal(t, "60", res.GetHeader("Min-Expires").Value())
+
+	res = r.HandleRegister(registerRequest(t, "1002", 2, "<sip:1002@10.0.0.5>, <sip:1002@10.0.0.6>;expires=100000"))
+	require.Equal(t, sip.StatusOK, res.StatusCode)
+	assert.Len(t, res.GetHeaders("Contact"), 2)
+	assert.Len(t, r.Bindings("1002"), 2)
+
+	// 同一 Call-ID 的旧 CSeq 被拒绝
+	res = r.HandleRegister(registerRequest(t, "1002", 2, "<sip:1002@10.0.0.5>"))
+	assert.Equal(t, sip.StatusInternalServerError, res.StatusCode)
+
+	res = r.HandleRegister(registerRequest(t, "1002", 3, "<sip:1002@10.0.0.5>;expires=0"))
+	require.Equal(t, sip.StatusOK, res.StatusCode)
+	bindings := r.Bindings("1002")
+	require.Len(t, bindings, 1)
+	assert.Equal(t, "sip:1002@10.0.0.6", bindings[0].Contact)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), bindings[0].ExpiresAt, time.Minute, "expires is capped")
+
+	// 查询不修改绑定
+	res = r.HandleRegister(registerRequest(t, "1002", 4, ""))
+	require.Equal(t, sip.StatusOK, res.StatusCode)
+	assert.Len(t, res.GetHeaders("Contact"), 1)
+
+	res = r.HandleRegister(registerRequest(t, "1002", 5, "*"))
+	assert.Equal(t, sip.StatusBadRequest, res.StatusCode)
+	res = r.HandleRegister(registerRequest(t, "1002", 6, "*", "Expires: 0"))
+	require.Equal(t, sip.StatusOK, res.StatusCode)
+	assert.Empty(t, r.Bindings("1002"))
+
+	regs, err := models.GetSipRegistrations(db, 1, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, regs)
+	user, err := models.GetSipUserByUsername(db, "1002")
+	require.NoError(t, err)
+	assert.Equal(t, models.SipUserStatusUnregistered, user.Status)
+}
+
+func TestRegistrarPurgeExpired(t *testing.T) {
+	db := setupRegistrarDB(t)
+	require.NoError(t, models.CreateSipUser(db, &models.SipUser{Username: "1003", Enabled: true}))
+	r := NewRegistrar(db)
+
+	res := r.HandleRegister(registerRequest(t, "1003", 1, "<sip:1003@10.0.0.7>", "Expires: 60"))
+	require.Equal(t, sip.StatusOK, res.StatusCode)
+
+	r.PurgeExpired(time.Now().Add(2 * time.Minute))
+	_, ok := r.Lookup("1003")
+	assert.False(t, ok)
+	user, err := models.GetSipUserByUsername(db, "1003")
+	require.NoError(t, err)
+	assert.Equal(t, models.SipUserStatusExpired, user.Status)
+}
+
+func TestRegistrarUnknownUser(t *testing.T) {
+	r := NewRegistrar(setupRegistrarDB(t))
+	res := r.HandleRegister(registerRequest(t, "9999", 1, "<sip:9999@10.0.0.8>"))
+	assert.Equal(t, sip.StatusForbidden, res.StatusCode)
+}
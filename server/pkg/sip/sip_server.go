@@ -2,17 +2,23 @@ package sip
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"os"
-	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
+	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	"github.com/code-100-precent/LingEcho/pkg/notification"
+	"github.com/code-100-precent/LingEcho/pkg/recording"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/emiago/sipgo"
 	"github.com/emiago/sipgo/sip"
 	"github.com/pion/rtp"
@@ -28,19 +34,28 @@ const (
 type SipServer struct {
 	SipPort          int
 	RPTPort          int
+	TLSPort          int             // SIPS（SIP over TLS）监听端口，0 表示未启用
+	MediaEncryption  MediaEncryption // 桥接呼出通话默认的媒体加密方式
+	tlsConfig        *tls.Config
 	client           *sipgo.Client
 	ua               *sipgo.UserAgent
 	server           *sipgo.Server
 	rtpConn          *net.UDPConn
 	pendingSessions  map[string]string       // Call-ID -> client RTP address
+	dtmfPayloadTypes map[string]uint8        // Call-ID -> 协商的 telephone-event 负载类型，ACK 时取出
 	sessionsMutex    sync.RWMutex            // Protects concurrent access to pendingSessions
 	activeSessions   map[string]*SessionInfo // Call-ID -> session info
 	activeMutex      sync.RWMutex
 	outgoingSessions map[string]*OutgoingSession // Call-ID -> outgoing session info
 	outgoingMutex    sync.RWMutex
-	registeredUsers  map[string]string // username -> Contact address (从 REGISTER 请求中获取)
-	registerMutex    sync.RWMutex
+	Registrar        *Registrar               // 处理 REGISTER，保存终端的注册绑定
+	BridgeHandler    BridgeHandler            // 设置后呼入通话由 WebRTC 对端（如 AI 语音会话）应答
+	bridges          map[string]*WebRTCBridge // Call-ID -> 已应答的桥接
+	bridgesMutex     sync.Mutex
 	db               *gorm.DB
+	ctx              context.Context // 监听的生命周期，Shutdown/Close 时取消
+	cancel           context.CancelFunc
+	draining         atomic.Bool // 关闭中，拒绝新的呼入
 }
 
 type OutgoingSession struct {
@@ -70,11 +85,35 @@ type SessionInfo struct {
 
 func (as *SipServer) SetDBConfig(db *gorm.DB) {
 	as.db = db
+	as.Registrar.SetDB(db)
 }
 
+// SipServerOptions 创建 SIP 服务的可选配置
+type SipServerOptions struct {
+	// TLSConfig 非 nil 时在 TLSPort 上监听 SIPS，需包含服务端证书
+	TLSConfig *tls.Config
+	TLSPort   int
+	// TLSClientConfig 呼叫 sips: 目标时使用的 TLS 配置，nil 时使用系统根证书校验
+	TLSClientConfig *tls.Config
+	// MediaEncryption 桥接呼出通话默认的媒体加密方式
+	MediaEncryption MediaEncryption
+}
+
+// defaultTLSPort SIPS 默认端口
+const defaultTLSPort = 5061
+
 func NewSipServer(rptPort int) *SipServer {
+	return NewSipServerWithOptions(rptPort, SipServerOptions{})
+}
+
+// NewSipServerWithOptions 按选项创建 SIP 服务，支持 SIPS 信令与加密媒体
+func NewSipServerWithOptions(rptPort int, opts SipServerOptions) *SipServer {
 	// Create SIP server
-	ua, err := sipgo.NewUA()
+	var uaOptions []sipgo.UserAgentOption
+	if opts.TLSClientConfig != nil {
+		uaOptions = append(uaOptions, sipgo.WithUserAgenTLSConfig(opts.TLSClientConfig))
+	}
+	ua, err := sipgo.NewUA(uaOptions...)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to create UA")
 	}
@@ -100,28 +139,102 @@ func NewSipServer(rptPort int) *SipServer {
 		logrus.WithError(err).Fatal("Create SIP Client Failed")
 	}
 
+	if opts.TLSConfig != nil && opts.TLSPort == 0 {
+		opts.TLSPort = defaultTLSPort
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	return &SipServer{
+		ctx:              ctx,
+		cancel:           cancel,
 		RPTPort:          rptPort,
+		TLSPort:          opts.TLSPort,
+		MediaEncryption:  opts.MediaEncryption,
+		tlsConfig:        opts.TLSConfig,
 		server:           server,
 		rtpConn:          rtpConn,
 		client:           client,
 		ua:               ua,
 		pendingSessions:  make(map[string]string),
+		dtmfPayloadTypes: make(map[string]uint8),
 		activeSessions:   make(map[string]*SessionInfo),
+		bridges:          make(map[string]*WebRTCBridge),
 		outgoingSessions: make(map[string]*OutgoingSession),
-		registeredUsers:  make(map[string]string),
+		Registrar:        NewRegistrar(nil),
 	}
 }
 
 func (as *SipServer) Close() {
+	as.cancel()
 	as.server.Close()
 	as.rtpConn.Close()
+	as.bridgesMutex.Lock()
+	for callID, bridge := range as.bridges {
+		bridge.Close()
+		delete(as.bridges, callID)
+	}
+	as.bridgesMutex.Unlock()
+}
+
+// Shutdown 拒绝新的呼入（503），等待进行中的通话结束后停止监听；
+// ctx 到期时挂断剩余通话并返回 ctx 的错误
+func (as *SipServer) Shutdown(ctx context.Context) error {
+	as.draining.Store(true)
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	var err error
+	for err == nil && as.activeCallCount() > 0 {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	if err != nil {
+		logrus.WithField("calls", as.activeCallCount()).Warn("SIP drain deadline exceeded, terminating remaining calls")
+		as.activeMutex.RLock()
+		for _, session := range as.activeSessions {
+			if session.CancelFunc != nil {
+				session.CancelFunc()
+			}
+		}
+		as.activeMutex.RUnlock()
+		as.outgoingMutex.RLock()
+		for _, session := range as.outgoingSessions {
+			if session.CancelFunc != nil {
+				session.CancelFunc()
+			}
+		}
+		as.outgoingMutex.RUnlock()
+	}
+	as.Close()
+	return err
+}
+
+// activeCallCount 返回已接通的呼入通话与未结束的呼出通话数量
+func (as *SipServer) activeCallCount() int {
+	as.activeMutex.RLock()
+	count := len(as.activeSessions)
+	as.activeMutex.RUnlock()
+
+	as.outgoingMutex.RLock()
+	defer as.outgoingMutex.RUnlock()
+	for _, session := range as.outgoingSessions {
+		switch session.Status {
+		case "calling", "ringing", "answered":
+			count++
+		}
+	}
+	return count
 }
 
 func (as *SipServer) Start(sipPort int, targetURI string) {
-	ctx := context.Background()
+	ctx := as.ctx
 	as.SipPort = sipPort
 	as.RegisterFunc()
+	go as.Registrar.Run(ctx)
 
 	// Only make outgoing call if targetURI is provided
 	if targetURI != "" {
@@ -131,7 +244,20 @@ func (as *SipServer) Start(sipPort int, targetURI string) {
 		}()
 	}
 
+	if as.tlsConfig != nil {
+		go func() {
+			if err := as.server.ListenAndServeTLS(ctx, "tls", fmt.Sprintf("0.0.0.0:%d", as.TLSPort), as.tlsConfig); err != nil && ctx.Err() == nil {
+				logrus.WithError(err).Error("Failed to start SIPS (TLS) listener")
+			}
+		}()
+		logrus.WithField("port", as.TLSPort).Info("SIPS (TLS) listener started")
+	}
+
 	if err := as.server.ListenAndServe(ctx, "udp", fmt.Sprintf("0.0.0.0:%d", sipPort)); err != nil {
+		if ctx.Err() != nil {
+			// Shutdown/Close 关闭了监听
+			return
+		}
 		logrus.WithError(err).Fatal("Failed to start server")
 	}
 }
@@ -172,8 +298,8 @@ func (as *SipServer) makeOutgoingCall(targetURI string, sipPort int, rtpPort int
 	// 检查用户是否已注册，如果已注册则使用注册的地址
 	targetUsername := uri.User
 	if targetUsername != "" {
-		as.registerMutex.RLock()
-		if registeredAddr, exists := as.registeredUsers[targetUsername]; exists {
+		if binding, exists := as.Registrar.Lookup(targetUsername); exists {
+			registeredAddr := binding.Addr()
 			log.Printf("用户 %s 已注册，使用注册地址: %s", targetUsername, registeredAddr)
 			// 解析注册地址
 			if addr, err := net.ResolveUDPAddr("udp", registeredAddr); err == nil {
@@ -190,7 +316,6 @@ func (as *SipServer) makeOutgoingCall(targetURI string, sipPort int, rtpPort int
 		} else {
 			log.Printf("用户 %s 未注册，使用原始地址: %s:%d", targetUsername, targetHost, targetPort)
 		}
-		as.registerMutex.RUnlock()
 	}
 
 	// 生成 SDP offer
@@ -356,6 +481,30 @@ func (as *SipServer) makeOutgoingCall(targetURI string, sipPort int, rtpPort int
 
 // MakeOutgoingCall 发起呼出呼叫（公共方法，供API调用）
 func (as *SipServer) MakeOutgoingCall(targetURI string) (string, error) {
+	return as.MakeOutgoingCallWithOptions(targetURI, OutgoingCallOptions{})
+}
+
+// OutgoingCallOptions 呼出选项
+type OutgoingCallOptions struct {
+	// Bridge 为 true 时接通后交给 BridgeHandler（如 AI 语音会话）通话，而不是播放提示音
+	Bridge bool
+	// Metadata 原样放入 BridgeCall.Metadata，如助手ID、开场白
+	Metadata map[string]string
+	// MediaEncryption 媒体加密方式，为空时使用 SipServer.MediaEncryption；仅桥接的通话支持
+	MediaEncryption MediaEncryption
+}
+
+// MakeOutgoingCallWithOptions 按选项发起呼出呼叫
+func (as *SipServer) MakeOutgoingCallWithOptions(targetURI string, opts OutgoingCallOptions) (string, error) {
+	if opts.Bridge && as.BridgeHandler == nil {
+		return "", errors.New("bridge handler not configured")
+	}
+	if opts.MediaEncryption != MediaEncryptionNone && !opts.Bridge {
+		return "", errors.New("media encryption requires a bridged call")
+	}
+	if strings.HasPrefix(strings.ToLower(targetURI), "sips:") && as.TLSPort == 0 {
+		return "", errors.New("sips target requires the TLS listener to be enabled")
+	}
 	callID := generateCallID()
 
 	// 创建呼出会话记录
@@ -373,14 +522,14 @@ func (as *SipServer) MakeOutgoingCall(targetURI string) (string, error) {
 
 	// 异步发起呼叫
 	go func() {
-		as.makeOutgoingCallWithID(targetURI, as.SipPort, as.RPTPort, callID)
+		as.makeOutgoingCallWithID(targetURI, as.SipPort, as.RPTPort, callID, opts)
 	}()
 
 	return callID, nil
 }
 
 // makeOutgoingCallWithID 发起呼出呼叫（带CallID）
-func (as *SipServer) makeOutgoingCallWithID(targetURI string, sipPort int, rtpPort int, callID string) {
+func (as *SipServer) makeOutgoingCallWithID(targetURI string, sipPort int, rtpPort int, callID string, opts OutgoingCallOptions) {
 	logrus.WithField("call_id", callID).Info("=== 开始发起呼叫 ===")
 
 	// 更新会话状态
@@ -409,6 +558,9 @@ func (as *SipServer) makeOutgoingCallWithID(targetURI string, sipPort int, rtpPo
 	targetPort := uri.Port
 	if targetPort == 0 {
 		targetPort = 5060
+		if uri.Encrypted {
+			targetPort = defaultTLSPort
+		}
 	}
 
 	if targetHost == localIP && targetPort == sipPort {
@@ -421,9 +573,8 @@ func (as *SipServer) makeOutgoingCallWithID(targetURI string, sipPort int, rtpPo
 	// 检查用户是否已注册
 	targetUsername := uri.User
 	if targetUsername != "" {
-		as.registerMutex.RLock()
-		if registeredAddr, exists := as.registeredUsers[targetUsername]; exists {
-			if addr, err := net.ResolveUDPAddr("udp", registeredAddr); err == nil {
+		if binding, exists := as.Registrar.Lookup(targetUsername); exists {
+			if addr, err := net.ResolveUDPAddr("udp", binding.Addr()); err == nil {
 				uri.Host = addr.IP.String()
 				if addr.Port > 0 {
 					uri.Port = addr.Port
@@ -434,21 +585,62 @@ func (as *SipServer) makeOutgoingCallWithID(targetURI string, sipPort int, rtpPo
 				targetPort = uri.Port
 			}
 		}
-		as.registerMutex.RUnlock()
 	}
 
-	// 生成 SDP offer
-	sdpOffer := generateSDP(localIP, rtpPort)
+	// 生成 SDP offer；桥接的通话使用独占的 RTP 端口，并提供 telephone-event 以接收按键
+	var bridgeConn *net.UDPConn
+	var sec *mediaSecurity
+	sdpOffer := ""
+	if opts.Bridge {
+		mode := opts.MediaEncryption
+		if mode == MediaEncryptionNone {
+			mode = as.MediaEncryption
+		}
+		var err error
+		if sec, err = newMediaSecurityOffer(mode); err != nil {
+			logrus.WithError(err).Error("生成媒体加密参数失败")
+			as.updateOutgoingSessionStatus(callID, "failed", err.Error())
+			return
+		}
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+		if err != nil {
+			logrus.WithError(err).Error("分配桥接 RTP 端口失败")
+			as.updateOutgoingSessionStatus(callID, "failed", err.Error())
+			return
+		}
+		bridgeConn = conn
+		if sdpOffer, err = generateSecureSDP(localIP, conn.LocalAddr().(*net.UDPAddr).Port, bridgeCodecs[0], outgoingEventPT, sec); err != nil {
+			logrus.WithError(err).Error("生成 SDP offer 失败")
+			as.updateOutgoingSessionStatus(callID, "failed", err.Error())
+			conn.Close()
+			return
+		}
+	} else {
+		sdpOffer = generateSDP(localIP, rtpPort)
+	}
+	answered := false
+	defer func() {
+		// 未接通时释放桥接端口；接通后由桥接持有
+		if bridgeConn != nil && !answered {
+			bridgeConn.Close()
+		}
+	}()
 	sdpBytes := []byte(sdpOffer)
 
 	// 创建 INVITE 请求
 	inviteReq := sip.NewRequest(sip.INVITE, uri)
 
+	// sips: 目标经 TLS 发送，From/Contact 使用本端的 SIPS 地址
+	if uri.Encrypted {
+		sipPort = as.TLSPort
+	}
+
 	// 设置 From 头
 	fromURI := &sip.Uri{
-		User: "server",
-		Host: localIP,
-		Port: sipPort,
+		Encrypted: uri.Encrypted,
+		User:      "server",
+		Host:      localIP,
+		Port:      sipPort,
 	}
 	from := &sip.FromHeader{
 		DisplayName: "SIP Server",
@@ -478,8 +670,9 @@ func (as *SipServer) makeOutgoingCallWithID(targetURI string, sipPort int, rtpPo
 
 	// 设置 Contact 头
 	contactURI := sip.Uri{
-		Host: localIP,
-		Port: sipPort,
+		Encrypted: uri.Encrypted,
+		Host:      localIP,
+		Port:      sipPort,
 	}
 	contact := &sip.ContactHeader{
 		Address: contactURI,
@@ -556,12 +749,15 @@ func (as *SipServer) makeOutgoingCallWithID(targetURI string, sipPort int, rtpPo
 				// 更新会话信息
 				now := time.Now()
 
-				// 创建录音文件路径
-				recordDir := "uploads/audio"
-				if err := os.MkdirAll(recordDir, 0755); err != nil {
-					logrus.WithError(err).Error("Failed to create audio directory")
+				// 创建录音文件路径（桥接的通话不在此录音）
+				recordingFile := ""
+				if bridgeConn == nil {
+					recordDir := "uploads/audio"
+					if err := os.MkdirAll(recordDir, 0755); err != nil {
+						logrus.WithError(err).Error("Failed to create audio directory")
+					}
+					recordingFile = fmt.Sprintf("%s/recorded_%s.wav", recordDir, callID)
 				}
-				recordingFile := fmt.Sprintf("%s/recorded_%s.wav", recordDir, callID)
 
 				as.outgoingMutex.Lock()
 				if session, exists := as.outgoingSessions[callID]; exists {
@@ -583,8 +779,27 @@ func (as *SipServer) makeOutgoingCallWithID(targetURI string, sipPort int, rtpPo
 					return
 				}
 
+				if bridgeConn != nil {
+					if sec != nil {
+						if err := sec.applyAnswer(remoteSDP); err != nil {
+							logrus.WithError(err).WithField("call_id", callID).Error("被叫未接受媒体加密，挂断")
+							as.HangupOutgoingCall(callID)
+							return
+						}
+					}
+					answered = true
+					bridge, err := as.startOutgoingBridge(callID, bridgeConn, uri.User, remoteRTPAddr, sdpTelephoneEventPT(remoteSDP), sec, opts.Metadata)
+					if err != nil {
+						logrus.WithError(err).WithField("call_id", callID).Error("呼出通话桥接失败，挂断")
+						as.HangupOutgoingCall(callID)
+						return
+					}
+					go as.runOutgoingBridge(callID, bridge)
+					return
+				}
+
 				// 启动录音（持续录音直到通话结束）
-				go as.recordAudioContinuous(remoteRTPAddr, callID, recordingFile, ctx)
+				go as.recordAudioContinuous(remoteRTPAddr, callID, recordingFile, sdpTelephoneEventPT(string(res.Body())), ctx)
 
 				// 开始发送音频
 				go as.sendAudioForOutgoing(remoteRTPAddr, callID)
@@ -643,6 +858,7 @@ func (as *SipServer) updateCallStatusInDB(callID string, status string, endTime
 		return
 	}
 
+	wasMissed := sipCall.IsMissed()
 	sipCall.Status = models.SipCallStatus(status)
 	if endTime != nil {
 		sipCall.EndTime = endTime
@@ -661,9 +877,46 @@ func (as *SipServer) updateCallStatusInDB(callID string, status string, endTime
 
 	if err := as.db.Save(&sipCall).Error; err != nil {
 		logrus.WithError(err).WithField("call_id", callID).Error("Failed to update call status in database")
+		return
+	}
+
+	if !wasMissed && sipCall.IsMissed() {
+		as.notifyMissedCall(&sipCall)
+	}
+
+	// 通话结束时生成话单（桥接到助手的通话由 AI 会话补充用量）
+	if endTime != nil && status != string(models.SipCallStatusAnswered) {
+		utils.Sig().Emit(models.SigCallDetailRecord, models.NewSipCallDetailRecord(as.db, &sipCall))
 	}
 }
 
+// notifyMissedCall 通知被叫用户有未接来电
+func (as *SipServer) notifyMissedCall(sipCall *models.SipCall) {
+	userID, ok := sipCall.CalleeUserID(as.db)
+	if !ok {
+		return
+	}
+	var user models.User
+	if err := as.db.First(&user, userID).Error; err != nil {
+		return
+	}
+
+	caller := sipCall.FromUsername
+	if caller == "" {
+		caller = sipCall.FromURI
+	}
+	utils.Sig().Emit(models.SigUserNotify, &user, as.db, notification.Message{
+		Event:   notification.EventMissedCall,
+		Level:   notification.LevelInfo,
+		Title:   "Missed call",
+		Content: fmt.Sprintf("You missed a call from %s at %s.", caller, sipCall.StartTime.Format("2006-01-02 15:04:05")),
+		Data: map[string]interface{}{
+			"callId": sipCall.CallID,
+			"from":   caller,
+		},
+	})
+}
+
 // saveRecordingURL 保存录音URL到数据库
 func (as *SipServer) saveRecordingURL(callID string, recordingFile string) {
 	if as.db == nil {
@@ -794,6 +1047,8 @@ func (as *SipServer) CancelOutgoingCall(callID string) error {
 	session.EndTime = &now
 	as.outgoingMutex.Unlock()
 
+	as.closeBridge(callID)
+
 	// 更新数据库状态
 	as.updateCallStatusInDB(callID, "cancelled", &now)
 
@@ -860,6 +1115,8 @@ func (as *SipServer) HangupOutgoingCall(callID string) error {
 	}
 	as.outgoingMutex.Unlock()
 
+	as.closeBridge(callID)
+
 	// 更新数据库状态
 	as.updateCallStatusInDB(callID, "ended", &now)
 
@@ -1062,6 +1319,12 @@ func (as *SipServer) RegisterFunc() {
 func (as *SipServer) handleInvite(req *sip.Request, tx sip.ServerTransaction) {
 	logrus.WithField("start_line", req.StartLine()).Info("Received INVITE request")
 
+	if as.draining.Load() {
+		res := sip.NewResponseFromRequest(req, sip.StatusServiceUnavailable, "Service Unavailable", nil)
+		tx.Respond(res)
+		return
+	}
+
 	// Parse SDP to get client RTP address
 	sdpBody := string(req.Body())
 	clientRTPAddr, err := parseSDPForRTPAddress(sdpBody)
@@ -1075,9 +1338,66 @@ func (as *SipServer) handleInvite(req *sip.Request, tx sip.ServerTransaction) {
 
 	logrus.WithField("client_rtp_addr", clientRTPAddr).Info("Client RTP address")
 
+	// 主叫提供 telephone-event 时在应答中接受，按键以 RFC 4733 事件发送
+	eventPT := sdpTelephoneEventPT(sdpBody)
+
+	// 主叫要求 SRTP（RTP/SAVP 或 UDP/TLS/RTP/SAVP）时协商密钥，加密媒体只在桥接的通话上支持
+	sec, err := answerMediaSecurity(sdpBody)
+	if err != nil {
+		logrus.WithError(err).Warn("Unsupported media encryption in INVITE, rejecting")
+		res := sip.NewResponseFromRequest(req, sip.StatusNotAcceptableHere, "Not Acceptable Here", nil)
+		tx.Respond(res)
+		return
+	}
+
+	// 桥接到 WebRTC 对端时使用独立的 RTP 端口，编码与主叫一致
+	rtpPort := as.RPTPort
+	codec := bridgeCodecs[0]
+	bridged := false
+	if as.BridgeHandler != nil {
+		if negotiated, ok := negotiateBridgeCodec(sdpBody); ok {
+			bridge, err := as.startBridge(req, clientRTPAddr, negotiated, eventPT, sec)
+			switch {
+			case err == nil:
+				rtpPort, codec, bridged = bridge.LocalPort(), negotiated, true
+				logrus.WithFields(logrus.Fields{
+					"call_id": bridge.CallID,
+					"codec":   negotiated.Name,
+				}).Info("Inbound call bridged to WebRTC")
+			case errors.Is(err, ErrNotBridged):
+			default:
+				logrus.WithError(err).Error("Failed to bridge inbound call")
+				res := sip.NewResponseFromRequest(req, sip.StatusTemporarilyUnavailable, "Temporarily Unavailable", nil)
+				tx.Respond(res)
+				return
+			}
+		}
+	}
+
+	// Only plain G.711 μ-law is spoken on the RTP path
+	if !bridged && sec != nil {
+		logrus.WithField("mode", sec.Mode).Warn("Encrypted media requested for a call that is not bridged, rejecting")
+		res := sip.NewResponseFromRequest(req, sip.StatusNotAcceptableHere, "Not Acceptable Here", nil)
+		tx.Respond(res)
+		return
+	}
+	if !bridged && !sdpOffersPCMU(sdpBody) {
+		logrus.WithField("sdp", sdpBody).Warn("INVITE does not offer PCMU, rejecting")
+		res := sip.NewResponseFromRequest(req, sip.StatusNotAcceptableHere, "Not Acceptable Here", nil)
+		tx.Respond(res)
+		return
+	}
+
 	// Generate SDP response (use request source address to determine server IP)
 	serverIP := getServerIPFromRequest(req)
-	sdp := generateSDP(serverIP, as.RPTPort)
+	sdp, err := generateSecureSDP(serverIP, rtpPort, codec, eventPT, sec)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to generate SDP answer")
+		as.closeBridge(req.CallID().Value())
+		res := sip.NewResponseFromRequest(req, sip.StatusInternalServerError, "Internal Server Error", nil)
+		tx.Respond(res)
+		return
+	}
 	sdpBytes := []byte(sdp)
 
 	// Log SDP content for debugging
@@ -1098,6 +1418,11 @@ func (as *SipServer) handleInvite(req *sip.Request, tx sip.ServerTransaction) {
 		Host: serverIP,
 		Port: as.SipPort,
 	}
+	// 经 TLS 收到的 INVITE 以 SIPS 地址应答，后续请求同样走 TLS
+	if req.Transport() == "TLS" {
+		contactURI.Encrypted = true
+		contactURI.Port = as.TLSPort
+	}
 	contact := &sip.ContactHeader{
 		Address: contactURI,
 	}
@@ -1107,6 +1432,7 @@ func (as *SipServer) handleInvite(req *sip.Request, tx sip.ServerTransaction) {
 	// Send 200 OK response
 	if err := tx.Respond(res); err != nil {
 		logrus.WithError(err).Error("Failed to send response")
+		as.closeBridge(req.CallID().Value())
 		return
 	}
 
@@ -1117,6 +1443,7 @@ func (as *SipServer) handleInvite(req *sip.Request, tx sip.ServerTransaction) {
 	callID := req.CallID().Value()
 	as.sessionsMutex.Lock()
 	as.pendingSessions[callID] = clientRTPAddr
+	as.dtmfPayloadTypes[callID] = eventPT
 	as.sessionsMutex.Unlock()
 	logrus.WithFields(logrus.Fields{
 		"call_id":     callID,
@@ -1147,8 +1474,7 @@ func (as *SipServer) handleInvite(req *sip.Request, tx sip.ServerTransaction) {
 		}
 
 		// 获取服务器IP和RTP端口
-		serverIP := getServerIPFromRequest(req)
-		localRTPAddr := fmt.Sprintf("%s:%d", serverIP, as.RPTPort)
+		localRTPAddr := fmt.Sprintf("%s:%d", serverIP, rtpPort)
 
 		sipCall := &models.SipCall{
 			CallID:        callID,
@@ -1216,7 +1542,7 @@ func (as *SipServer) sendAudio(clientAddr string, sampleRate uint32, samplesPerP
 	packet := &rtp.Packet{
 		Header: rtp.Header{
 			Version:        2,
-			PayloadType:    0, // PCMU (G.711 μ-law)
+			PayloadType:    payloadTypePCMU,
 			SequenceNumber: 0,
 			Timestamp:      0,
 			SSRC:           12345678,
@@ -1242,7 +1568,7 @@ func (as *SipServer) sendAudio(clientAddr string, sampleRate uint32, samplesPerP
 			// Read 16-bit little-endian PCM sample
 			sample := int16(binary.LittleEndian.Uint16(chunk[j*2 : j*2+2]))
 			// Convert to G.711 μ-law
-			payload[j] = linearToMulaw(sample)
+			payload[j] = encoder.LinearToULaw(sample)
 		}
 
 		// If data is insufficient, fill with silence
@@ -1329,7 +1655,7 @@ func (as *SipServer) sendAudioWithContext(clientAddr string, sampleRate uint32,
 	packet := &rtp.Packet{
 		Header: rtp.Header{
 			Version:        2,
-			PayloadType:    0, // PCMU (G.711 μ-law)
+			PayloadType:    payloadTypePCMU,
 			SequenceNumber: 0,
 			Timestamp:      0,
 			SSRC:           12345678,
@@ -1361,7 +1687,7 @@ func (as *SipServer) sendAudioWithContext(clientAddr string, sampleRate uint32,
 		payload := make([]byte, samplesPerPacket)
 		for j := 0; j < samplesPerPacket && j*2+1 < len(chunk); j++ {
 			sample := int16(binary.LittleEndian.Uint16(chunk[j*2 : j*2+2]))
-			payload[j] = linearToMulaw(sample)
+			payload[j] = encoder.LinearToULaw(sample)
 		}
 
 		// If data is insufficient, fill with silence
@@ -1412,172 +1738,13 @@ func (as *SipServer) sendAudioWithContext(clientAddr string, sampleRate uint32,
 func (as *SipServer) handleRegister(req *sip.Request, tx sip.ServerTransaction) {
 	logrus.WithField("start_line", req.StartLine()).Info("Received REGISTER request")
 
-	// Extract username from From header
-	var username string
-	if from := req.From(); from != nil {
-		username = from.Address.User
-	}
-
-	// If db is configured, validate user
-	if as.db != nil {
-		if username == "" {
-			logrus.Warn("REGISTER request missing username in From header")
-			res := sip.NewResponseFromRequest(req, sip.StatusUnauthorized, "Unauthorized", nil)
-			if err := tx.Respond(res); err != nil {
-				logrus.WithError(err).Error("Failed to send 401 response")
-			}
-			return
-		}
-
-		// Query user from database
-		var sipUser models.SipUser
-		err := as.db.Where("username = ?", username).First(&sipUser).Error
-		if err != nil {
-			if err == gorm.ErrRecordNotFound {
-				logrus.WithField("username", username).Warn("SIP user not found in database")
-				res := sip.NewResponseFromRequest(req, sip.StatusUnauthorized, "Unauthorized", nil)
-				if err := tx.Respond(res); err != nil {
-					logrus.WithError(err).Error("Failed to send 401 response")
-				}
-				return
-			}
-			logrus.WithError(err).Error("Database query failed")
-			res := sip.NewResponseFromRequest(req, sip.StatusInternalServerError, "Internal Server Error", nil)
-			if err := tx.Respond(res); err != nil {
-				logrus.WithError(err).Error("Failed to send 500 response")
-			}
-			return
-		}
-
-		// Check if user is enabled
-		if !sipUser.Enabled {
-			logrus.WithField("username", username).Warn("SIP user is disabled")
-			res := sip.NewResponseFromRequest(req, sip.StatusForbidden, "Forbidden", nil)
-			if err := tx.Respond(res); err != nil {
-				logrus.WithError(err).Error("Failed to send 403 response")
-			}
-			return
-		}
-
-		// Extract registration information from request
-		contact := req.Contact()
-		var contactStr string
-		var contactIP string
-		var contactPort int
-
-		if contact != nil {
-			contactStr = contact.Address.String()
-			contactIP = contact.Address.Host
-			contactPort = contact.Address.Port
-			if contactPort == 0 {
-				contactPort = 5060 // Default SIP port
-			}
-		}
-
-		// Extract expires from request or use default
-		expires := 3600 // Default 1 hour
-		if expiresHeader := req.GetHeader("Expires"); expiresHeader != nil {
-			if expiresValue, err := strconv.Atoi(expiresHeader.Value()); err == nil {
-				expires = expiresValue
-			}
-		}
-
-		// Extract User-Agent
-		userAgent := ""
-		if uaHeader := req.GetHeader("User-Agent"); uaHeader != nil {
-			userAgent = uaHeader.Value()
-		}
-
-		// Extract remote IP from Via header or request source
-		remoteIP := ""
-		if via := req.Via(); via != nil {
-			if received, exists := via.Params.Get("received"); exists && received != "" {
-				remoteIP = received
-			} else if via.Host != "" {
-				remoteIP = via.Host
-			}
-		}
-
-		// Update user information
-		now := time.Now()
-		sipUser.Contact = contactStr
-		sipUser.ContactIP = contactIP
-		sipUser.ContactPort = contactPort
-		sipUser.Expires = expires
-		sipUser.Status = models.SipUserStatusRegistered
-		sipUser.LastRegister = &now
-		sipUser.RegisterCount++
-		sipUser.UserAgent = userAgent
-		sipUser.RemoteIP = remoteIP
-		sipUser.UpdateExpiresAt()
-
-		// Save to database
-		if err := as.db.Save(&sipUser).Error; err != nil {
-			logrus.WithError(err).Error("Failed to update SIP user in database")
-			res := sip.NewResponseFromRequest(req, sip.StatusInternalServerError, "Internal Server Error", nil)
-			if err := tx.Respond(res); err != nil {
-				logrus.WithError(err).Error("Failed to send 500 response")
-			}
-			return
-		}
-
-		// Update in-memory registered users map
-		if contactStr != "" {
-			as.registerMutex.Lock()
-			as.registeredUsers[username] = fmt.Sprintf("%s:%d", contactIP, contactPort)
-			as.registerMutex.Unlock()
-		}
-
-		logrus.WithFields(logrus.Fields{
-			"username":       username,
-			"contact":        contactStr,
-			"expires":        expires,
-			"register_count": sipUser.RegisterCount,
-		}).Info("SIP user registered successfully")
-	} else {
-		// If db is nil, allow all registrations (no validation)
-		logrus.Info("Database not configured, allowing registration without validation")
-
-		// Still extract username and update in-memory map if possible
-		if username != "" {
-			contact := req.Contact()
-			if contact != nil {
-				contactIP := contact.Address.Host
-				contactPort := contact.Address.Port
-				if contactPort == 0 {
-					contactPort = 5060
-				}
-				as.registerMutex.Lock()
-				as.registeredUsers[username] = fmt.Sprintf("%s:%d", contactIP, contactPort)
-				as.registerMutex.Unlock()
-			}
-		}
-	}
-
-	// Accept registration, return 200 OK
-	res := sip.NewResponseFromRequest(req, sip.StatusOK, "OK", nil)
-
-	// Add Contact header (optional)
-	if contact := req.Contact(); contact != nil {
-		res.AppendHeader(contact)
-	}
-
-	// Add Expires header
-	expiresValue := 3600 // Default 1 hour
-	if expiresHeader := req.GetHeader("Expires"); expiresHeader != nil {
-		if val, err := strconv.Atoi(expiresHeader.Value()); err == nil {
-			expiresValue = val
-		}
-	}
-	expires := sip.ExpiresHeader(expiresValue)
-	res.AppendHeader(&expires)
-
+	res := as.Registrar.HandleRegister(req)
 	if err := tx.Respond(res); err != nil {
 		logrus.WithError(err).Error("Failed to send REGISTER response")
 		return
 	}
 
-	logrus.Info("REGISTER 200 OK response sent")
+	logrus.WithField("status", res.StatusCode).Info("REGISTER response sent")
 }
 
 func (as *SipServer) handleOptions(req *sip.Request, tx sip.ServerTransaction) {
@@ -1609,9 +1776,11 @@ func (as *SipServer) handleAck(req *sip.Request, tx sip.ServerTransaction) {
 	// Find corresponding session information
 	as.sessionsMutex.Lock()
 	clientRTPAddr, exists := as.pendingSessions[callID]
+	eventPT := as.dtmfPayloadTypes[callID]
 	if exists {
 		// Delete pending session
 		delete(as.pendingSessions, callID)
+		delete(as.dtmfPayloadTypes, callID)
 	}
 	as.sessionsMutex.Unlock()
 
@@ -1640,12 +1809,18 @@ func (as *SipServer) handleAck(req *sip.Request, tx sip.ServerTransaction) {
 	// Create context for session cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// 桥接的通话由 WebRTC 对端收发媒体（AI 会话自行录音）
+	bridge := as.getBridge(callID)
+
 	// 创建录音文件路径
-	recordDir := "uploads/audio"
-	if err := os.MkdirAll(recordDir, 0755); err != nil {
-		logrus.WithError(err).Error("Failed to create audio directory")
+	var recordingFile string
+	if bridge == nil {
+		recordDir := "uploads/audio"
+		if err := os.MkdirAll(recordDir, 0755); err != nil {
+			logrus.WithError(err).Error("Failed to create audio directory")
+		}
+		recordingFile = fmt.Sprintf("%s/recorded_%s.wav", recordDir, callID)
 	}
-	recordingFile := fmt.Sprintf("%s/recorded_%s.wav", recordDir, callID)
 
 	as.activeMutex.Lock()
 	as.activeSessions[callID] = &SessionInfo{
@@ -1664,8 +1839,13 @@ func (as *SipServer) handleAck(req *sip.Request, tx sip.ServerTransaction) {
 		as.updateCallStatusInDB(callID, "answered", &now)
 	}
 
+	if bridge != nil {
+		go as.runBridge(ctx, callID, bridge)
+		return
+	}
+
 	// 启动录音（持续录音直到通话结束）
-	go as.recordAudioContinuous(clientRTPAddr, callID, recordingFile, ctx)
+	go as.recordAudioContinuous(clientRTPAddr, callID, recordingFile, eventPT, ctx)
 
 	// Send audio in goroutine
 	go as.sendAudioWithCallback(clientRTPAddr, callID)
@@ -1755,17 +1935,7 @@ func (as *SipServer) handleInfo(req *sip.Request, tx sip.ServerTransaction) {
 			"call_id": callID,
 		}).Info("Detected DTMF key")
 
-		// Send DTMF to session channel
-		as.activeMutex.RLock()
-		if session, exists := as.activeSessions[callID]; exists {
-			select {
-			case session.DTMFChannel <- dtmfDigit:
-				logrus.WithField("dtmf", dtmfDigit).Debug("DTMF key sent to session channel")
-			default:
-				logrus.WithField("dtmf", dtmfDigit).Warn("DTMF channel full, dropping key")
-			}
-		}
-		as.activeMutex.RUnlock()
+		as.dispatchDTMF(callID, dtmfDigit)
 	}
 
 	// Return 200 OK
@@ -1778,6 +1948,25 @@ func (as *SipServer) handleInfo(req *sip.Request, tx sip.ServerTransaction) {
 	logrus.Info("INFO 200 OK response sent")
 }
 
+// dispatchDTMF 将按键（SIP INFO 或 RTP 中检测到的）投递给通话的 DTMF 通道与桥接的 WebRTC 对端
+func (as *SipServer) dispatchDTMF(callID string, digit string) {
+	if bridge := as.getBridge(callID); bridge != nil {
+		bridge.pushDigit(digit)
+	}
+
+	// Send DTMF to session channel
+	as.activeMutex.RLock()
+	if session, exists := as.activeSessions[callID]; exists {
+		select {
+		case session.DTMFChannel <- digit:
+			logrus.WithField("dtmf", digit).Debug("DTMF key sent to session channel")
+		default:
+			logrus.WithField("dtmf", digit).Warn("DTMF channel full, dropping key")
+		}
+	}
+	as.activeMutex.RUnlock()
+}
+
 // listenDTMF 监听 DTMF 按键（保留原函数以兼容）
 func (as *SipServer) listenDTMF(clientAddr string, callID string) {
 	as.activeMutex.RLock()
@@ -1938,7 +2127,7 @@ func (as *SipServer) recordAudioWithContext(clientAddr string, filename string,
 		}).Debug("RTP packet details")
 
 		// Only process PCMU (payload type 0)
-		if packet.PayloadType != 0 {
+		if packet.PayloadType != payloadTypePCMU {
 			logrus.WithField("payload_type", packet.PayloadType).Debug("Ignoring non-PCMU packet")
 			continue
 		}
@@ -1947,7 +2136,7 @@ func (as *SipServer) recordAudioWithContext(clientAddr string, filename string,
 
 		// 解码 μ-law 为 PCM
 		for _, mulawByte := range packet.Payload {
-			pcm := mulawToLinear(mulawByte)
+			pcm := encoder.ULawToLinear(mulawByte)
 			pcmData = append(pcmData, pcm)
 		}
 	}
@@ -1975,7 +2164,8 @@ func (as *SipServer) recordAudioWithContext(clientAddr string, filename string,
 }
 
 // recordAudioContinuous 持续录音（不限制时长，直到收到停止信号）
-func (as *SipServer) recordAudioContinuous(clientAddr string, callID string, filename string, ctx context.Context) {
+// recordAudioContinuous 持续录制主叫音频，同时检测按键（eventPT 为协商的 telephone-event 负载类型）
+func (as *SipServer) recordAudioContinuous(clientAddr string, callID string, filename string, eventPT uint8, ctx context.Context) {
 	addr, err := net.ResolveUDPAddr("udp", clientAddr)
 	if err != nil {
 		logrus.WithError(err).WithField("call_id", callID).Error("Failed to resolve client address")
@@ -1988,11 +2178,21 @@ func (as *SipServer) recordAudioContinuous(clientAddr string, callID string, fil
 		"address":  addr.String(),
 	}).Info("Starting continuous recording")
 
-	// 创建缓冲区存储 PCM 数据
-	var pcmData []int16
+	// 通话期间 PCM 暂存在临时文件中，按到达时间对齐，静音期间补零
+	recorder, err := recording.NewRecorder(recording.Options{
+		SampleRate: 8000,
+		Mode:       recording.ModeMixed,
+		Format:     recording.FormatWAV,
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("call_id", callID).Error("Failed to create recorder")
+		return
+	}
 	buffer := make([]byte, 1500)
 	packetCount := 0
-	sampleRate := 8000
+	dtmf := NewDTMFDetector(bridgeCodecs[0], eventPT, func(digit string) {
+		as.dispatchDTMF(callID, digit)
+	})
 
 	// 设置读取超时（用于定期检查取消信号）
 	as.rtpConn.SetReadDeadline(time.Now().Add(1 * time.Second))
@@ -2004,17 +2204,20 @@ func (as *SipServer) recordAudioContinuous(clientAddr string, callID string, fil
 			logrus.WithField("call_id", callID).Info("Recording cancelled")
 			as.rtpConn.SetReadDeadline(time.Time{}) // Clear timeout
 			// 保存录音
-			if len(pcmData) > 0 {
-				if err := saveWAV(filename, pcmData, sampleRate); err != nil {
-					logrus.WithError(err).WithField("call_id", callID).Error("Failed to save WAV file")
-				} else {
-					logrus.WithFields(logrus.Fields{
-						"call_id":      callID,
-						"filename":     filename,
-						"samples":      len(pcmData),
-						"packet_count": packetCount,
-					}).Info("Recording saved")
-				}
+			if packetCount == 0 {
+				recorder.Discard()
+				return
+			}
+			duration := recorder.Duration()
+			if err := saveRecording(filename, recorder); err != nil {
+				logrus.WithError(err).WithField("call_id", callID).Error("Failed to save WAV file")
+			} else {
+				logrus.WithFields(logrus.Fields{
+					"call_id":      callID,
+					"filename":     filename,
+					"duration":     duration,
+					"packet_count": packetCount,
+				}).Info("Recording saved")
 			}
 			return
 		default:
@@ -2045,21 +2248,40 @@ func (as *SipServer) recordAudioContinuous(clientAddr string, callID string, fil
 			continue
 		}
 
+		dtmf.Process(packet)
+
 		// 只处理 PCMU (payload type 0)
-		if packet.PayloadType != 0 {
+		if packet.PayloadType != payloadTypePCMU {
 			continue
 		}
 
 		packetCount++
 
 		// 解码 μ-law 为 PCM
-		for _, mulawByte := range packet.Payload {
-			pcm := mulawToLinear(mulawByte)
-			pcmData = append(pcmData, pcm)
+		pcm := make([]byte, 2*len(packet.Payload))
+		for i, mulawByte := range packet.Payload {
+			binary.LittleEndian.PutUint16(pcm[2*i:], uint16(encoder.ULawToLinear(mulawByte)))
+		}
+		if err := recorder.Write(recording.ChannelRx, pcm); err != nil {
+			logrus.WithError(err).WithField("call_id", callID).Warn("Failed to write recording")
 		}
 	}
 }
 
+// saveRecording 结束录音并写入 WAV 文件
+func saveRecording(filename string, recorder *recording.Recorder) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		recorder.Discard()
+		return err
+	}
+	if err := recorder.Close(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
 // sendAudioFromFile 从文件发送音频（保留原函数以兼容）
 func (as *SipServer) sendAudioFromFile(clientAddr string, filename string, samplesPerPacket int) {
 	as.sendAudioFromFileWithContext(clientAddr, filename, samplesPerPacket, context.Background())
@@ -2103,7 +2325,7 @@ func (as *SipServer) sendAudioFromFileWithContext(clientAddr string, filename st
 	packet := &rtp.Packet{
 		Header: rtp.Header{
 			Version:        2,
-			PayloadType:    0,
+			PayloadType:    payloadTypePCMU,
 			SequenceNumber: 0,
 			Timestamp:      0,
 			SSRC:           12345678,
@@ -2135,7 +2357,7 @@ func (as *SipServer) sendAudioFromFileWithContext(clientAddr string, filename st
 		payload := make([]byte, samplesPerPacket)
 		for j := 0; j < samplesPerPacket && j*2+1 < len(chunk); j++ {
 			sample := int16(binary.LittleEndian.Uint16(chunk[j*2 : j*2+2]))
-			payload[j] = linearToMulaw(sample)
+			payload[j] = encoder.LinearToULaw(sample)
 		}
 
 		if len(chunk) < samplesPerPacket*2 {
@@ -2243,6 +2465,8 @@ func (as *SipServer) handleBye(req *sip.Request, tx sip.ServerTransaction) {
 		as.saveRecordingURL(callID, recordingFile)
 	}
 
+	as.closeBridge(callID)
+
 	// Clean up pending session
 	as.sessionsMutex.Lock()
 	if clientRTPAddr, exists := as.pendingSessions[callID]; exists {
@@ -2252,6 +2476,7 @@ func (as *SipServer) handleBye(req *sip.Request, tx sip.ServerTransaction) {
 		}).Warn("Found pending session when receiving BYE, client may have hung up early")
 		delete(as.pendingSessions, callID)
 	}
+	delete(as.dtmfPayloadTypes, callID)
 	as.sessionsMutex.Unlock()
 
 	// Clean up active session and stop all operations
@@ -2307,7 +2532,10 @@ func (as *SipServer) handleCancel(req *sip.Request, tx sip.ServerTransaction) {
 		"call_id":    callID,
 	}).Info("Received CANCEL request")
 
+	as.closeBridge(callID)
+
 	// Clean up pending session (CANCEL is sent before ACK)
+	cancelledBeforeAnswer := false
 	as.sessionsMutex.Lock()
 	if clientRTPAddr, exists := as.pendingSessions[callID]; exists {
 		logrus.WithFields(logrus.Fields{
@@ -2315,8 +2543,14 @@ func (as *SipServer) handleCancel(req *sip.Request, tx sip.ServerTransaction) {
 			"rtp_address": clientRTPAddr,
 		}).Warn("Found pending session when receiving CANCEL, call was cancelled before ACK")
 		delete(as.pendingSessions, callID)
+		cancelledBeforeAnswer = true
 	}
+	delete(as.dtmfPayloadTypes, callID)
 	as.sessionsMutex.Unlock()
+	if cancelledBeforeAnswer {
+		now := time.Now()
+		as.updateCallStatusInDB(callID, "cancelled", &now)
+	}
 
 	// Also check active sessions (in case ACK was already received)
 	as.activeMutex.Lock()
@@ -0,0 +1,143 @@
+package sip
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// securePair 按 offer/answer 协商后在回环地址上建立两端的媒体端口
+func securePair(t *testing.T, mode MediaEncryption) (offerer, answerer *mediaConn) {
+	t.Helper()
+	codec := bridgeCodecs[0]
+
+	offerSec, err := newMediaSecurityOffer(mode)
+	require.NoError(t, err)
+	offer, err := generateSecureSDP("127.0.0.1", 4000, codec, 101, offerSec)
+	require.NoError(t, err)
+
+	answerSec, err := answerMediaSecurity(offer)
+	require.NoError(t, err)
+	require.NotNil(t, answerSec)
+	assert.Equal(t, mode, answerSec.Mode)
+	answer, err := generateSecureSDP("127.0.0.1", 4002, codec, 101, answerSec)
+	require.NoError(t, err)
+	require.NoError(t, offerSec.applyAnswer(answer))
+
+	listen := func(sec *mediaSecurity) *mediaConn {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		require.NoError(t, err)
+		m, err := newMediaConn(conn, sec)
+		require.NoError(t, err)
+		t.Cleanup(func() { m.Close() })
+		return m
+	}
+	return listen(offerSec), listen(answerSec)
+}
+
+// readPackets 持续读取 RTP（同时驱动 DTLS 握手），解密后的包投递到通道
+func readPackets(m *mediaConn) <-chan *rtp.Packet {
+	packets := make(chan *rtp.Packet, 16)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, _, err := m.ReadRTP(buf)
+			if err != nil {
+				return
+			}
+			pkt := &rtp.Packet{}
+			if pkt.Unmarshal(append([]byte(nil), buf[:n]...)) == nil {
+				packets <- pkt
+			}
+		}
+	}()
+	return packets
+}
+
+func assertRTPDelivered(t *testing.T, from, to *mediaConn, received <-chan *rtp.Packet) {
+	t.Helper()
+	pkt := &rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: 0, SequenceNumber: 7, Timestamp: 160, SSRC: 42},
+		Payload: []byte("hello secure media"),
+	}
+	raw, err := pkt.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, from.WriteRTP(raw, to.conn.LocalAddr().(*net.UDPAddr)))
+
+	select {
+	case got := <-received:
+		assert.Equal(t, pkt.Payload, got.Payload)
+		assert.Equal(t, pkt.SequenceNumber, got.SequenceNumber)
+	case <-time.After(2 * time.Second):
+		t.Fatal("packet not delivered")
+	}
+}
+
+func TestMediaSecuritySDES(t *testing.T) {
+	offerer, answerer := securePair(t, MediaEncryptionSDES)
+	received := readPackets(answerer)
+	assertRTPDelivered(t, offerer, answerer, received)
+
+	// 线路上不是明文
+	plain, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer plain.Close()
+	raw, _ := (&rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 1}, Payload: []byte("secret")}).Marshal()
+	require.NoError(t, offerer.WriteRTP(raw, plain.LocalAddr().(*net.UDPAddr)))
+	buf := make([]byte, 1500)
+	plain.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := plain.ReadFromUDP(buf)
+	require.NoError(t, err)
+	assert.NotContains(t, string(buf[:n]), "secret")
+}
+
+func TestMediaSecurityDTLS(t *testing.T) {
+	offerer, answerer := securePair(t, MediaEncryptionDTLS)
+	offererPackets := readPackets(offerer)
+	answererPackets := readPackets(answerer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errs := make(chan error, 2)
+	go func() { errs <- offerer.Handshake(ctx, answerer.conn.LocalAddr().(*net.UDPAddr)) }()
+	go func() { errs <- answerer.Handshake(ctx, offerer.conn.LocalAddr().(*net.UDPAddr)) }()
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+
+	assertRTPDelivered(t, offerer, answerer, answererPackets)
+	assertRTPDelivered(t, answerer, offerer, offererPackets)
+}
+
+func TestAnswerMediaSecurity(t *testing.T) {
+	plain := offerSDP("0 101", "0 PCMU/8000", "101 telephone-event/8000")
+	sec, err := answerMediaSecurity(plain)
+	require.NoError(t, err)
+	assert.Nil(t, sec)
+
+	savp := strings.Replace(plain, "RTP/AVP", "RTP/SAVP", 1)
+	_, err = answerMediaSecurity(savp + "a=crypto:1 F8_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz\r\n")
+	assert.ErrorIs(t, err, ErrMediaEncryptionUnsupported)
+
+	// 带密钥生命周期参数的 a=crypto，优先选择 80 位认证标签
+	sec, err = answerMediaSecurity(savp +
+		"a=crypto:2 AES_CM_128_HMAC_SHA1_32 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz|2^20|1:32\r\n" +
+		"a=crypto:1 AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz|2^20\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "1", sec.cryptoTag)
+	assert.Equal(t, "AES_CM_128_HMAC_SHA1_80", sec.suite)
+	assert.Len(t, sec.remoteKey, sdesKeyLen)
+
+	answer, err := generateSecureSDP("192.0.2.1", 20000, bridgeCodecs[0], 101, sec)
+	require.NoError(t, err)
+	assert.Contains(t, answer, "m=audio 20000 RTP/SAVP 0 101")
+	assert.Contains(t, answer, "a=crypto:1 AES_CM_128_HMAC_SHA1_80 inline:")
+
+	_, err = answerMediaSecurity(strings.Replace(plain, "RTP/AVP", "UDP/TLS/RTP/SAVP", 1))
+	assert.ErrorIs(t, err, ErrMediaEncryptionUnsupported)
+}
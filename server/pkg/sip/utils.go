@@ -3,6 +3,7 @@ package sip
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,67 +12,34 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// linearToMulaw converts 16-bit linear PCM to G.711 μ-law
-func linearToMulaw(pcm int16) byte {
-	// Get sign bit
-	sign := byte(0)
-	if pcm < 0 {
-		sign = 0x80
-		pcm = -pcm
+// payloadTypePCMU is the static RTP payload type of G.711 μ-law (RFC 3551),
+// the codec used on the SIP media path
+const payloadTypePCMU = 0
+
+// sdpOffersPCMU reports whether the audio media in an SDP body lists PCMU.
+// US carriers and most trunks always offer it; an offer without it cannot be answered.
+func sdpOffersPCMU(sdpBody string) bool {
+	var session sdp.SessionDescription
+	if err := session.Unmarshal([]byte(sdpBody)); err != nil {
+		// pion/sdp is stricter than most SIP stacks; don't reject calls over formatting
+		return true
 	}
-
-	// Limit range
-	if pcm > 32635 {
-		pcm = 32635
-	}
-
-	// Add bias
-	pcm += 0x84
-
-	// Find exponent
-	exp := 7
-	for exp > 0 && (pcm&0x4000) == 0 {
-		exp--
-		pcm <<= 1
-	}
-
-	// Extract mantissa (upper 4 bits)
-	mantissa := byte((pcm >> 10) & 0x0F)
-
-	// Combine result
-	result := sign | byte(exp<<4) | mantissa
-
-	// μ-law encoding requires inversion
-	return ^result
-}
-
-// mulawToLinear 将 G.711 μ-law 转换为 16-bit 线性 PCM
-func mulawToLinear(mulaw byte) int16 {
-	// μ-law 解码需要先取反
-	mulaw = ^mulaw
-
-	// 提取符号位
-	sign := int16(0)
-	if (mulaw & 0x80) != 0 {
-		sign = -1
-	}
-
-	// 提取指数（3位）
-	exp := int16((mulaw >> 4) & 0x07)
-
-	// 提取尾数（4位）
-	mantissa := int16(mulaw & 0x0F)
-
-	// 计算线性值
-	linear := 33 + (2 * mantissa)
-	linear <<= exp
-	linear -= 33
-
-	if sign != 0 {
-		linear = -linear
+	for _, media := range session.MediaDescriptions {
+		if media.MediaName.Media != "audio" {
+			continue
+		}
+		for _, format := range media.MediaName.Formats {
+			if format == strconv.Itoa(payloadTypePCMU) {
+				return true
+			}
+		}
+		for _, attr := range media.Attributes {
+			if attr.Key == "rtpmap" && strings.Contains(strings.ToUpper(attr.Value), "PCMU/8000") {
+				return true
+			}
+		}
 	}
-
-	return linear
+	return false
 }
 
 func parseSDPForRTPAddress(sdpBody string) (string, error) {
@@ -153,9 +121,36 @@ func getServerIPFromRequest(req *sip.Request) string {
 }
 
 func generateSDP(serverIP string, rtpPort int) string {
+	return generateSDPWithCodec(serverIP, rtpPort, bridgeCodecs[0], 0)
+}
+
+// generateSDPWithCodec 生成只包含指定编码的应答 SDP，eventPT 非 0 时同时应答 telephone-event（RFC 4733）
+func generateSDPWithCodec(serverIP string, rtpPort int, codec bridgeCodec, eventPT uint8) string {
+	sdpBody, _ := generateSecureSDP(serverIP, rtpPort, codec, eventPT, nil)
+	return sdpBody
+}
+
+// generateSecureSDP 同 generateSDPWithCodec，sec 非 nil 时使用 SAVP 并写入密钥或 DTLS 指纹
+func generateSecureSDP(serverIP string, rtpPort int, codec bridgeCodec, eventPT uint8, sec *mediaSecurity) (string, error) {
+	secAttributes, err := sec.attributes()
+	if err != nil {
+		return "", err
+	}
+
 	// Use pion/sdp library to generate standard SDP response
 	sessionID := time.Now().Unix()
 
+	formats := []string{strconv.Itoa(int(codec.PayloadType))}
+	attributes := []sdp.Attribute{{Key: "rtpmap", Value: codec.rtpmap()}}
+	if eventPT != 0 {
+		formats = append(formats, strconv.Itoa(int(eventPT)))
+		attributes = append(attributes,
+			sdp.Attribute{Key: "rtpmap", Value: fmt.Sprintf("%d telephone-event/8000", eventPT)},
+			sdp.Attribute{Key: "fmtp", Value: fmt.Sprintf("%d 0-15", eventPT)})
+	}
+	attributes = append(attributes, secAttributes...)
+	attributes = append(attributes, sdp.Attribute{Key: "sendrecv"})
+
 	session := sdp.SessionDescription{
 		Version: 0,
 		Origin: sdp.Origin{
@@ -180,19 +175,19 @@ func generateSDP(serverIP string, rtpPort int) string {
 				MediaName: sdp.MediaName{
 					Media:   "audio",
 					Port:    sdp.RangedPort{Value: rtpPort},
-					Protos:  []string{"RTP", "AVP"},
-					Formats: []string{"0"},
-				},
-				Attributes: []sdp.Attribute{
-					{Key: "rtpmap", Value: "0 PCMU/8000/1"},
-					{Key: "sendrecv", Value: ""},
+					Protos:  sec.protos(),
+					Formats: formats,
 				},
+				Attributes: attributes,
 			},
 		},
 	}
 
 	// Serialize to string
 	sdpBytes, err := session.Marshal()
+	if err != nil && sec != nil {
+		return "", err
+	}
 	if err != nil {
 		logrus.WithError(err).Warn("Failed to generate SDP, using fallback method")
 		// If serialization fails, use string concatenation as fallback
@@ -201,13 +196,13 @@ func generateSDP(serverIP string, rtpPort int) string {
 			"s=SIP Call\r\n"+
 			"c=IN IP4 %s\r\n"+
 			"t=0 0\r\n"+
-			"m=audio %d RTP/AVP 0\r\n"+
-			"a=rtpmap:0 PCMU/8000/1\r\n"+
+			"m=audio %d RTP/AVP %d\r\n"+
+			"a=rtpmap:%s\r\n"+
 			"a=sendrecv\r\n",
-			sessionID, sessionID, serverIP, serverIP, rtpPort)
+			sessionID, sessionID, serverIP, serverIP, rtpPort, codec.PayloadType, codec.rtpmap()), nil
 	}
 
-	return string(sdpBytes)
+	return string(sdpBytes), nil
 }
 
 func getLocalIP() string {
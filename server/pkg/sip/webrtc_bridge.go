@@ -0,0 +1,489 @@
+package sip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
+	"github.com/emiago/sipgo/sip"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotBridged BridgeHandler 返回该错误表示不接管此通话，按原有流程应答
+var ErrNotBridged = errors.New("sip: call not bridged")
+
+// BridgeCall 交给 BridgeHandler 的呼入通话信息
+type BridgeCall struct {
+	CallID string
+	From   string // 主叫用户名
+	To     string // 被叫用户名
+	Codec  string // 与主叫协商的编码（pcmu/pcma/g722），取值与 rtcmedia.WebRTCOption.Codec 一致
+	Offer  string // 桥接端的 WebRTC offer，已包含全部 ICE 候选
+	// DTMF 主叫的按键（RFC 4733 事件、带内双音或 SIP INFO），桥接关闭后不再写入
+	DTMF <-chan string
+	// Done 通话结束（桥接关闭）时关闭
+	Done <-chan struct{}
+	// Outbound 为 true 时是服务器发起的呼出通话，From/To 为本端与被叫
+	Outbound bool
+	// Metadata 发起呼出时通过 OutgoingCallOptions 传入的业务参数，原样交给 BridgeHandler
+	Metadata map[string]string
+}
+
+// 呼出通话交给 AI 语音会话时 Metadata 使用的键
+const (
+	MetadataUserID       = "userId"       // 发起呼叫的用户
+	MetadataCredentialID = "credentialId" // 计费使用的API凭证
+	MetadataAssistantID  = "assistantId"  // 通话的助手
+	MetadataGreeting     = "greeting"     // 接通后的开场白
+	MetadataWorkflowCall = "workflowCall" // 由工作流呼叫节点发起，接通后交给等待中的工作流驱动
+)
+
+// BridgeHandler 为呼入通话建立 WebRTC 对端（如 AI 语音会话），返回 answer 与通话结束时的释放函数
+type BridgeHandler func(ctx context.Context, call *BridgeCall) (answer string, release func(), err error)
+
+// bridgeCodec SIP 侧与 WebRTC 侧共同支持的编码，RTP 负载可直接转发
+type bridgeCodec struct {
+	Name        string
+	PayloadType uint8
+	MimeType    string
+	EncodeName  string // SDP rtpmap 中的编码名
+}
+
+// bridgeCodecs 按服务端偏好排列；实际选择以主叫 offer 中的顺序为准
+var bridgeCodecs = []bridgeCodec{
+	{Name: constants.CodecPCMU, PayloadType: 0, MimeType: webrtc.MimeTypePCMU, EncodeName: "PCMU"},
+	{Name: constants.CodecPCMA, PayloadType: 8, MimeType: webrtc.MimeTypePCMA, EncodeName: "PCMA"},
+	{Name: constants.CodecG722, PayloadType: 9, MimeType: webrtc.MimeTypeG722, EncodeName: "G722"},
+}
+
+// rtpmap 返回 SDP 中的 rtpmap 属性值，G.722 的 RTP 时钟按 RFC 3551 记为 8000
+func (c bridgeCodec) rtpmap() string {
+	return fmt.Sprintf("%d %s/8000/1", c.PayloadType, c.EncodeName)
+}
+
+// negotiateBridgeCodec 按主叫 offer 的顺序选出第一个可以直通转发的编码
+func negotiateBridgeCodec(sdpBody string) (bridgeCodec, bool) {
+	var session sdp.SessionDescription
+	if err := session.Unmarshal([]byte(sdpBody)); err != nil {
+		// 与 sdpOffersPCMU 一致，解析失败时按 PCMU 处理
+		return bridgeCodecs[0], true
+	}
+	for _, media := range session.MediaDescriptions {
+		if media.MediaName.Media != "audio" {
+			continue
+		}
+		names := map[string]string{} // payload type -> 编码名
+		for _, attr := range media.Attributes {
+			if attr.Key != "rtpmap" {
+				continue
+			}
+			if pt, name, ok := strings.Cut(attr.Value, " "); ok {
+				names[pt], _, _ = strings.Cut(strings.ToUpper(name), "/")
+			}
+		}
+		for _, format := range media.MediaName.Formats {
+			for _, codec := range bridgeCodecs {
+				name, mapped := names[format]
+				if (mapped && name == codec.EncodeName) || (!mapped && format == strconv.Itoa(int(codec.PayloadType))) {
+					return codec, true
+				}
+			}
+		}
+	}
+	return bridgeCodec{}, false
+}
+
+// WebRTCBridge 将一路 SIP 通话的 RTP 与一个 WebRTC 对端相连。
+// 桥接端只向 WebRTC 对端提供与主叫协商出的编码，两侧编码一致，RTP 负载原样转发，不做转码
+type WebRTCBridge struct {
+	CallID string
+	codec  bridgeCodec
+
+	media    *mediaConn // 本通话独占的 RTP 端口，按协商结果加密
+	remoteMu sync.RWMutex
+	remote   *net.UDPAddr // 主叫 RTP 地址，收到媒体后以实际来源为准（NAT）
+
+	pc    *webrtc.PeerConnection
+	track *webrtc.TrackLocalStaticRTP
+
+	dtmf   *DTMFDetector
+	digits chan string
+
+	release   func()
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewWebRTCBridge 分配 RTP 端口并创建 WebRTC 对端，remoteRTPAddr 为主叫 SDP 中的媒体地址，
+// eventPT 为协商的 telephone-event 负载类型（0 表示未协商，按带内双音检测按键）
+func NewWebRTCBridge(callID string, codec bridgeCodec, eventPT uint8, remoteRTPAddr string) (*WebRTCBridge, error) {
+	return newSecureWebRTCBridge(callID, codec, eventPT, remoteRTPAddr, nil)
+}
+
+// newSecureWebRTCBridge 同 NewWebRTCBridge，sec 非 nil 时 SIP 侧媒体使用 SRTP
+func newSecureWebRTCBridge(callID string, codec bridgeCodec, eventPT uint8, remoteRTPAddr string, sec *mediaSecurity) (*WebRTCBridge, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("listen rtp: %w", err)
+	}
+	return newWebRTCBridge(callID, conn, codec, eventPT, remoteRTPAddr, sec)
+}
+
+// newWebRTCBridge 在已分配的 RTP 端口上创建桥接（呼出时端口需先写入 INVITE 的 SDP），失败时关闭 conn
+func newWebRTCBridge(callID string, conn *net.UDPConn, codec bridgeCodec, eventPT uint8, remoteRTPAddr string, sec *mediaSecurity) (*WebRTCBridge, error) {
+	remote, err := net.ResolveUDPAddr("udp", remoteRTPAddr)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("resolve remote rtp address: %w", err)
+	}
+	media, err := newMediaConn(conn, sec)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	m := &webrtc.MediaEngine{}
+	params := webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: codec.MimeType, ClockRate: 8000},
+		PayloadType:        webrtc.PayloadType(codec.PayloadType),
+	}
+	if err := m.RegisterCodec(params, webrtc.RTPCodecTypeAudio); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	pc, err := webrtc.NewAPI(webrtc.WithMediaEngine(m)).NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	track, err := webrtc.NewTrackLocalStaticRTP(params.RTPCodecCapability, "audio", "sip-"+callID)
+	if err != nil {
+		pc.Close()
+		conn.Close()
+		return nil, err
+	}
+	if _, err := pc.AddTrack(track); err != nil {
+		pc.Close()
+		conn.Close()
+		return nil, err
+	}
+
+	b := &WebRTCBridge{
+		CallID: callID,
+		codec:  codec,
+		media:  media,
+		remote: remote,
+		pc:     pc,
+		track:  track,
+		digits: make(chan string, 16),
+		done:   make(chan struct{}),
+	}
+	b.dtmf = NewDTMFDetector(codec, eventPT, b.pushDigit)
+	pc.OnTrack(func(remoteTrack *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		go b.forwardToSIP(remoteTrack)
+	})
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		logrus.WithFields(logrus.Fields{"call_id": callID, "state": state.String()}).Info("Bridge WebRTC connection state changed")
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			b.Close()
+		}
+	})
+	return b, nil
+}
+
+// LocalPort 返回 SIP 侧的 RTP 端口，写入 200 OK 的 SDP
+func (b *WebRTCBridge) LocalPort() int {
+	return b.media.LocalPort()
+}
+
+// Offer 创建 WebRTC offer 并等待 ICE 候选收集完成
+func (b *WebRTCBridge) Offer(ctx context.Context) (string, error) {
+	offer, err := b.pc.CreateOffer(nil)
+	if err != nil {
+		return "", err
+	}
+	gathered := webrtc.GatheringCompletePromise(b.pc)
+	if err := b.pc.SetLocalDescription(offer); err != nil {
+		return "", err
+	}
+	select {
+	case <-gathered:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	return b.pc.LocalDescription().SDP, nil
+}
+
+// Accept 设置 WebRTC 对端的 answer 并开始转发主叫的 RTP，release 在桥接关闭时调用
+func (b *WebRTCBridge) Accept(answer string, release func()) error {
+	b.release = release
+	if err := b.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer}); err != nil {
+		return err
+	}
+	go b.forwardToWebRTC()
+	if b.media.sec != nil && b.media.sec.Mode == MediaEncryptionDTLS {
+		go b.handshake()
+	}
+	return nil
+}
+
+// handshake 在 SIP 侧媒体上完成 DTLS-SRTP 握手，失败时结束桥接
+func (b *WebRTCBridge) handshake() {
+	b.remoteMu.RLock()
+	remote := b.remote
+	b.remoteMu.RUnlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-b.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if err := b.media.Handshake(ctx, remote); err != nil {
+		logrus.WithError(err).WithField("call_id", b.CallID).Error("Bridge DTLS-SRTP handshake failed")
+		b.Close()
+		return
+	}
+	logrus.WithField("call_id", b.CallID).Info("Bridge DTLS-SRTP established")
+}
+
+// Digits 主叫按键的通道
+func (b *WebRTCBridge) Digits() <-chan string {
+	return b.digits
+}
+
+// pushDigit 投递按键，无人读取时丢弃
+func (b *WebRTCBridge) pushDigit(digit string) {
+	select {
+	case b.digits <- digit:
+	default:
+		logrus.WithFields(logrus.Fields{"call_id": b.CallID, "dtmf": digit}).Warn("Bridge DTMF channel full, dropping key")
+	}
+}
+
+// Done 桥接关闭后返回的通道
+func (b *WebRTCBridge) Done() <-chan struct{} {
+	return b.done
+}
+
+// Close 关闭 RTP 端口与 WebRTC 连接，并释放 WebRTC 对端
+func (b *WebRTCBridge) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		close(b.done)
+		b.media.Close()
+		err = b.pc.Close()
+		if b.release != nil {
+			b.release()
+		}
+		logrus.WithField("call_id", b.CallID).Info("WebRTC bridge closed")
+	})
+	return err
+}
+
+// forwardToWebRTC 主叫 RTP -> WebRTC，同时检测按键；负载类型不符的包（如 telephone-event）不转发
+func (b *WebRTCBridge) forwardToWebRTC() {
+	buf := make([]byte, 1500)
+	latched := false
+	for {
+		n, addr, err := b.media.ReadRTP(buf)
+		if err != nil {
+			return
+		}
+		if !latched {
+			// 对称 RTP：NAT 后的终端以实际来源地址为准
+			latched = true
+			b.remoteMu.Lock()
+			if addr.String() != b.remote.String() {
+				logrus.WithFields(logrus.Fields{
+					"call_id": b.CallID,
+					"sdp":     b.remote.String(),
+					"actual":  addr.String(),
+				}).Info("Bridge RTP source differs from SDP, latching")
+				b.remote = addr
+			}
+			b.remoteMu.Unlock()
+		}
+		var pkt rtp.Packet
+		if err := pkt.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		b.dtmf.Process(&pkt)
+		if pkt.PayloadType != b.codec.PayloadType {
+			continue
+		}
+		if err := b.track.WriteRTP(&pkt); err != nil {
+			logrus.WithError(err).WithField("call_id", b.CallID).Debug("Bridge failed to write RTP to WebRTC")
+		}
+	}
+}
+
+// forwardToSIP WebRTC -> 主叫 RTP，保留序号与时间戳，只改写负载类型
+func (b *WebRTCBridge) forwardToSIP(remoteTrack *webrtc.TrackRemote) {
+	buf := make([]byte, 1500)
+	for {
+		pkt, _, err := remoteTrack.ReadRTP()
+		if err != nil {
+			return
+		}
+		pkt.PayloadType = b.codec.PayloadType
+		n, err := pkt.MarshalTo(buf)
+		if err != nil {
+			continue
+		}
+		b.remoteMu.RLock()
+		remote := b.remote
+		b.remoteMu.RUnlock()
+		if err := b.media.WriteRTP(buf[:n], remote); err != nil {
+			select {
+			case <-b.done:
+				return
+			default:
+			}
+			logrus.WithError(err).WithField("call_id", b.CallID).Debug("Bridge failed to send RTP to SIP")
+		}
+	}
+}
+
+// outgoingEventPT 呼出 offer 中 telephone-event 的负载类型
+const outgoingEventPT = 101
+
+// bridgeSetupTimeout 建立桥接（ICE 收集与 BridgeHandler 应答）的时间上限，超时后回复 480
+const bridgeSetupTimeout = 10 * time.Second
+
+// startBridge 为呼入通话创建桥接并交给 BridgeHandler 应答；返回 ErrNotBridged 时按原有流程处理
+func (as *SipServer) startBridge(req *sip.Request, clientRTPAddr string, codec bridgeCodec, eventPT uint8, sec *mediaSecurity) (*WebRTCBridge, error) {
+	callID := req.CallID().Value()
+	bridge, err := newSecureWebRTCBridge(callID, codec, eventPT, clientRTPAddr, sec)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(as.ctx, bridgeSetupTimeout)
+	defer cancel()
+
+	offer, err := bridge.Offer(ctx)
+	if err != nil {
+		bridge.Close()
+		return nil, err
+	}
+	call := &BridgeCall{CallID: callID, Codec: codec.Name, Offer: offer, DTMF: bridge.Digits(), Done: bridge.Done()}
+	if from := req.From(); from != nil {
+		call.From = from.Address.User
+	}
+	if to := req.To(); to != nil {
+		call.To = to.Address.User
+	}
+	answer, release, err := as.BridgeHandler(ctx, call)
+	if err != nil {
+		bridge.Close()
+		return nil, err
+	}
+	if err := bridge.Accept(answer, release); err != nil {
+		bridge.Close()
+		return nil, err
+	}
+
+	as.bridgesMutex.Lock()
+	as.bridges[callID] = bridge
+	as.bridgesMutex.Unlock()
+	return bridge, nil
+}
+
+// startOutgoingBridge 呼出通话接通后在 INVITE 中提供的 RTP 端口上建立桥接
+func (as *SipServer) startOutgoingBridge(callID string, conn *net.UDPConn, to string, remoteRTPAddr string, eventPT uint8, sec *mediaSecurity, metadata map[string]string) (*WebRTCBridge, error) {
+	codec := bridgeCodecs[0] // 呼出 offer 只提供 PCMU
+	bridge, err := newWebRTCBridge(callID, conn, codec, eventPT, remoteRTPAddr, sec)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(as.ctx, bridgeSetupTimeout)
+	defer cancel()
+
+	offer, err := bridge.Offer(ctx)
+	if err != nil {
+		bridge.Close()
+		return nil, err
+	}
+	call := &BridgeCall{
+		CallID:   callID,
+		From:     "server",
+		To:       to,
+		Codec:    codec.Name,
+		Offer:    offer,
+		DTMF:     bridge.Digits(),
+		Done:     bridge.Done(),
+		Outbound: true,
+		Metadata: metadata,
+	}
+	answer, release, err := as.BridgeHandler(ctx, call)
+	if err != nil {
+		bridge.Close()
+		return nil, err
+	}
+	if err := bridge.Accept(answer, release); err != nil {
+		bridge.Close()
+		return nil, err
+	}
+
+	as.bridgesMutex.Lock()
+	as.bridges[callID] = bridge
+	as.bridgesMutex.Unlock()
+	return bridge, nil
+}
+
+// runOutgoingBridge WebRTC 对端先结束（如助手挂断）时向被叫发送 BYE
+func (as *SipServer) runOutgoingBridge(callID string, bridge *WebRTCBridge) {
+	select {
+	case <-as.ctx.Done():
+	case <-bridge.Done():
+	}
+	as.outgoingMutex.RLock()
+	session, exists := as.outgoingSessions[callID]
+	answered := exists && session.Status == "answered"
+	as.outgoingMutex.RUnlock()
+	if answered {
+		if err := as.HangupOutgoingCall(callID); err != nil {
+			logrus.WithError(err).WithField("call_id", callID).Warn("Failed to hang up bridged outgoing call")
+		}
+	}
+	as.closeBridge(callID)
+}
+
+// getBridge 返回通话的桥接，未桥接时返回 nil
+func (as *SipServer) getBridge(callID string) *WebRTCBridge {
+	as.bridgesMutex.Lock()
+	defer as.bridgesMutex.Unlock()
+	return as.bridges[callID]
+}
+
+// closeBridge 关闭并移除通话的桥接（BYE/CANCEL 或 WebRTC 对端断开时）
+func (as *SipServer) closeBridge(callID string) {
+	as.bridgesMutex.Lock()
+	bridge, exists := as.bridges[callID]
+	delete(as.bridges, callID)
+	as.bridgesMutex.Unlock()
+	if exists {
+		bridge.Close()
+	}
+}
+
+// runBridge 在通话结束或 WebRTC 对端断开前保持桥接
+func (as *SipServer) runBridge(ctx context.Context, callID string, bridge *WebRTCBridge) {
+	select {
+	case <-ctx.Done():
+	case <-bridge.Done():
+		logrus.WithField("call_id", callID).Warn("WebRTC side of the bridge ended before the SIP call")
+	}
+	as.closeBridge(callID)
+}
@@ -0,0 +1,147 @@
+package sip
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func offerSDP(formats string, rtpmaps ...string) string {
+	lines := []string{
+		"v=0",
+		"o=- 1 1 IN IP4 127.0.0.1",
+		"s=-",
+		"c=IN IP4 127.0.0.1",
+		"t=0 0",
+		"m=audio 4000 RTP/AVP " + formats,
+	}
+	for _, m := range rtpmaps {
+		lines = append(lines, "a=rtpmap:"+m)
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func TestNegotiateBridgeCodec(t *testing.T) {
+	codec, ok := negotiateBridgeCodec(offerSDP("8 0 101", "8 PCMA/8000", "0 PCMU/8000", "101 telephone-event/8000"))
+	require.True(t, ok)
+	assert.Equal(t, constants.CodecPCMA, codec.Name)
+
+	// 不带 rtpmap 的静态负载类型
+	codec, ok = negotiateBridgeCodec(offerSDP("18 9"))
+	require.True(t, ok)
+	assert.Equal(t, constants.CodecG722, codec.Name)
+
+	// 动态负载类型映射到非直通编码
+	_, ok = negotiateBridgeCodec(offerSDP("18 96", "18 G729/8000", "96 opus/48000/2"))
+	assert.False(t, ok)
+
+	sdp := generateSDPWithCodec("192.0.2.1", 20000, codec, 101)
+	assert.Contains(t, sdp, "m=audio 20000 RTP/AVP 9 101")
+	assert.Contains(t, sdp, "a=rtpmap:9 G722/8000/1")
+	assert.Contains(t, sdp, "a=fmtp:101 0-15")
+}
+
+func TestWebRTCBridge(t *testing.T) {
+	phone, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer phone.Close()
+
+	codec := bridgeCodecs[1] // PCMA
+	bridge, err := NewWebRTCBridge("call-1", codec, 101, phone.LocalAddr().String())
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	offer, err := bridge.Offer(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, offer, "PCMA/8000")
+	assert.NotContains(t, offer, "opus")
+
+	// WebRTC 对端：与 AI 语音会话相同的传输
+	peer := rtcmedia.NewWebRTCTransport(rtcmedia.WebRTCOption{Codec: constants.CodecPCMA})
+	peer.NewPeerConnection()
+	received := make(chan *rtp.Packet, 1)
+	peer.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		pkt, _, err := track.ReadRTP()
+		if err == nil {
+			received <- pkt
+		}
+	})
+	require.NoError(t, peer.SetRemoteDescription(offer))
+	answer, _, err := peer.CreateAnswer(nil)
+	require.NoError(t, err)
+	released := make(chan struct{})
+	require.NoError(t, bridge.Accept(answer, func() { close(released); peer.Close() }))
+	require.NoError(t, peer.WaitUntilConnected(ctx))
+
+	// 话机 -> WebRTC：负载原样到达
+	bridgeAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: bridge.LocalPort()}
+	payload := []byte{0xd5, 0xd5, 0xd4, 0xd4}
+	go func() {
+		for seq := uint16(1); ; seq++ {
+			pkt := rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: codec.PayloadType, SequenceNumber: seq, Timestamp: uint32(seq) * 160, SSRC: 42}, Payload: payload}
+			data, _ := pkt.Marshal()
+			if _, err := phone.WriteToUDP(data, bridgeAddr); err != nil {
+				return
+			}
+			select {
+			case <-bridge.Done():
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}()
+	select {
+	case pkt := <-received:
+		assert.Equal(t, payload, pkt.Payload)
+	case <-ctx.Done():
+		t.Fatal("no RTP forwarded to WebRTC")
+	}
+
+	// telephone-event 不转发给 WebRTC，按键从 Digits 读出
+	event := rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: 101, SequenceNumber: 1000, Timestamp: 80000, SSRC: 42}, Payload: []byte{9, 0x8a, 0, 160}}
+	data, err := event.Marshal()
+	require.NoError(t, err)
+	_, err = phone.WriteToUDP(data, bridgeAddr)
+	require.NoError(t, err)
+	select {
+	case digit := <-bridge.Digits():
+		assert.Equal(t, "9", digit)
+	case <-ctx.Done():
+		t.Fatal("no DTMF digit from telephone-event")
+	}
+
+	// WebRTC -> 话机：以协商的负载类型发出
+	go func() {
+		for i := 0; i < 50; i++ {
+			if peer.GetTxTrack().WriteSample(media.Sample{Data: []byte{1, 2, 3}, Duration: 20 * time.Millisecond}) != nil {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+	buf := make([]byte, 1500)
+	require.NoError(t, phone.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := phone.Read(buf)
+	require.NoError(t, err)
+	var pkt rtp.Packet
+	require.NoError(t, pkt.Unmarshal(buf[:n]))
+	assert.Equal(t, codec.PayloadType, pkt.PayloadType)
+	assert.Equal(t, []byte{1, 2, 3}, pkt.Payload)
+
+	require.NoError(t, bridge.Close())
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("release not called on close")
+	}
+}
@@ -0,0 +1,52 @@
+package sso
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestGitHubProvider_Exchange(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"token-1","token_type":"bearer"}`))
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"id":1001,"login":"octocat","avatar_url":"https://example.com/a.png"}`))
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"email":"other@example.com","primary":false,"verified":true},{"email":"Octo@Example.com","primary":true,"verified":true}]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	provider := NewGitHubProvider("client", "secret", "http://localhost/callback").(*githubProvider)
+	provider.oauth.Endpoint = oauth2.Endpoint{
+		AuthURL:  server.URL + "/login/oauth/authorize",
+		TokenURL: server.URL + "/login/oauth/access_token",
+	}
+	provider.apiBase = server.URL
+
+	assert.Contains(t, provider.AuthCodeURL("state-1", "nonce-1"), "state=state-1")
+
+	identity, err := provider.Exchange(context.Background(), "code-1", "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "github", identity.Provider)
+	assert.Equal(t, "1001", identity.Subject)
+	assert.Equal(t, "octo@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "octocat", identity.Name)
+}
+
+func TestRegistry_UnconfiguredProvider(t *testing.T) {
+	registry := NewRegistry(func(name string) string { return "http://localhost/" + name })
+	_, err := registry.Get(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrProviderNotConfigured)
+}
@@ -0,0 +1,235 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// 内置的登录提供方名称
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+)
+
+const googleIssuer = "https://accounts.google.com"
+
+var ErrProviderNotConfigured = errors.New("sso provider is not configured")
+
+// Identity 第三方账号的身份信息
+type Identity struct {
+	Provider      string
+	Subject       string // 提供方内唯一且不变的用户标识
+	Email         string
+	EmailVerified bool // 提供方已验证该邮箱，只有已验证的邮箱才能关联到已有账号
+	Name          string
+	Avatar        string
+}
+
+// Provider 一个 OAuth2/OIDC 登录提供方
+type Provider interface {
+	Name() string
+	// AuthCodeURL 返回跳转到提供方授权页的地址
+	AuthCodeURL(state, nonce string) string
+	// Exchange 用授权码换取令牌并解析用户身份，nonce 为发起授权时使用的值
+	Exchange(ctx context.Context, code, nonce string) (*Identity, error)
+}
+
+// providerConfig 从配置读取的提供方参数
+type providerConfig struct {
+	name         string
+	issuer       string // 为空表示 GitHub（非 OIDC）
+	clientID     string
+	clientSecret string
+	scopes       []string
+	trustEmail   bool // 企业 IdP 的邮箱均由管理员分配，未声明 email_verified 时也视为已验证
+}
+
+// Registry 按配置懒加载登录提供方。OIDC 提供方首次使用时才请求发现文档，
+// 发现失败不缓存，下次请求会重试
+type Registry struct {
+	callbackURL func(provider string) string
+
+	mu        sync.Mutex
+	providers map[string]Provider
+}
+
+// NewRegistry 创建提供方注册表，callbackURL 返回各提供方的回调地址
+func NewRegistry(callbackURL func(provider string) string) *Registry {
+	return &Registry{callbackURL: callbackURL, providers: make(map[string]Provider)}
+}
+
+// Names 返回已配置的提供方名称
+func (r *Registry) Names() []string {
+	configs := providerConfigs()
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get 返回名为 name 的提供方
+func (r *Registry) Get(ctx context.Context, name string) (Provider, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if provider, ok := r.providers[name]; ok {
+		return provider, nil
+	}
+
+	cfg, ok := providerConfigs()[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotConfigured, name)
+	}
+	var (
+		provider Provider
+		err      error
+	)
+	if cfg.issuer == "" {
+		provider = NewGitHubProvider(cfg.clientID, cfg.clientSecret, r.callbackURL(name))
+	} else {
+		provider, err = NewOIDCProvider(ctx, name, cfg.issuer, cfg.clientID, cfg.clientSecret, r.callbackURL(name), cfg.scopes, cfg.trustEmail)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r.providers[name] = provider
+	return provider, nil
+}
+
+// providerConfigs 读取 Google、GitHub 与通用 OIDC 提供方的配置，未设置客户端ID的不启用
+func providerConfigs() map[string]providerConfig {
+	configs := make(map[string]providerConfig)
+	cfg := config.GlobalConfig
+	if cfg == nil {
+		return configs
+	}
+	if cfg.OAuthGoogleClientID != "" {
+		configs[ProviderGoogle] = providerConfig{
+			name:         ProviderGoogle,
+			issuer:       googleIssuer,
+			clientID:     cfg.OAuthGoogleClientID,
+			clientSecret: cfg.OAuthGoogleClientSecret,
+		}
+	}
+	if cfg.OAuthGitHubClientID != "" {
+		configs[ProviderGitHub] = providerConfig{
+			name:         ProviderGitHub,
+			clientID:     cfg.OAuthGitHubClientID,
+			clientSecret: cfg.OAuthGitHubClientSecret,
+		}
+	}
+	if cfg.OIDCIssuer != "" && cfg.OIDCClientID != "" {
+		name := cfg.OIDCProviderName
+		if name == "" {
+			name = "oidc"
+		}
+		scopes := strings.FieldsFunc(cfg.OIDCScopes, func(r rune) bool { return r == ',' || r == ' ' })
+		configs[name] = providerConfig{
+			name:         name,
+			issuer:       cfg.OIDCIssuer,
+			clientID:     cfg.OIDCClientID,
+			clientSecret: cfg.OIDCClientSecret,
+			scopes:       scopes,
+			trustEmail:   cfg.OIDCTrustEmail,
+		}
+	}
+	return configs
+}
+
+// oidcProvider 标准 OIDC 提供方，身份来自校验过签名、受众与 nonce 的 ID Token
+type oidcProvider struct {
+	name       string
+	trustEmail bool
+	oauth      oauth2.Config
+	verifier   *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider 通过 issuer 的发现文档创建 OIDC 提供方，scopes 为空时使用 openid email profile；
+// trustEmail 为 true 时未声明 email_verified 的邮箱也视为已验证
+func NewOIDCProvider(ctx context.Context, name, issuer, clientID, clientSecret, redirectURL string, scopes []string, trustEmail bool) (Provider, error) {
+	discovered, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover oidc issuer %s: %w", issuer, err)
+	}
+	if len(scopes) == 0 {
+		scopes = []string{"email", "profile"}
+	}
+	hasOpenID := false
+	for _, scope := range scopes {
+		if scope == oidc.ScopeOpenID {
+			hasOpenID = true
+		}
+	}
+	if !hasOpenID {
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
+	return &oidcProvider{
+		name:       name,
+		trustEmail: trustEmail,
+		oauth: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     discovered.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: discovered.Verifier(&oidc.Config{ClientID: clientID}),
+	}, nil
+}
+
+func (p *oidcProvider) Name() string {
+	return p.name
+}
+
+func (p *oidcProvider) AuthCodeURL(state, nonce string) string {
+	return p.oauth.AuthCodeURL(state, oidc.Nonce(nonce))
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, nonce string) (*Identity, error) {
+	token, err := p.oauth.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	emailVerified := p.trustEmail
+	if claims.EmailVerified != nil {
+		emailVerified = *claims.EmailVerified
+	}
+	return &Identity{
+		Provider:      p.name,
+		Subject:       idToken.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: emailVerified && claims.Email != "",
+		Name:          claims.Name,
+		Avatar:        claims.Picture,
+	}, nil
+}
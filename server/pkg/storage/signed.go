@@ -0,0 +1,173 @@
+package stores
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+)
+
+// 签名 URL 的查询参数
+const (
+	SignedURLExpiresParam = "expires"
+	SignedURLUserParam    = "uid"
+	SignedURLSigParam     = "sig"
+)
+
+var (
+	ErrSignatureMissing = errors.New("media url is not signed")
+	ErrSignatureInvalid = errors.New("media url signature is invalid")
+	ErrSignatureExpired = errors.New("media url has expired")
+)
+
+// signingSecret 签名密钥，未配置 MEDIA_SIGNING_SECRET 时使用会话密钥
+func signingSecret() string {
+	if config.GlobalConfig == nil {
+		return ""
+	}
+	if config.GlobalConfig.MediaSigningSecret != "" {
+		return config.GlobalConfig.MediaSigningSecret
+	}
+	return config.GlobalConfig.SessionSecret
+}
+
+// normalizeKey 去掉开头的斜杠，使 "/a.wav" 与 "a.wav" 的签名一致
+func normalizeKey(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
+
+// SignKey 计算存储键、绑定用户与过期时间（Unix 秒）的 HMAC-SHA256 签名
+func SignKey(key string, userID uint, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(signingSecret()))
+	mac.Write([]byte(normalizeKey(key) + "\n" + strconv.FormatUint(uint64(userID), 10) + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// MediaURLTTL 签名地址的默认有效期，未配置 MEDIA_URL_TTL 时为 1 小时
+func MediaURLTTL() time.Duration {
+	if config.GlobalConfig != nil && config.GlobalConfig.MediaURLTTL > 0 {
+		return config.GlobalConfig.MediaURLTTL
+	}
+	return time.Hour
+}
+
+// MediaRoutePrefix 签名地址使用的媒体路由前缀，取自 MEDIA_PREFIX，默认 /media
+func MediaRoutePrefix() string {
+	prefix := strings.Trim(utils.GetEnv("MEDIA_PREFIX"), "/")
+	if prefix == "" {
+		return "/media"
+	}
+	return "/" + prefix
+}
+
+// SignedURL 生成媒体路由（MEDIA_PREFIX）下带过期时间与用户绑定的签名地址，userID 为 0 表示不绑定用户，
+// 绑定用户的地址只能由该用户的登录会话使用；ttl 不大于 0 时使用 MEDIA_URL_TTL
+func SignedURL(key string, userID uint, ttl time.Duration) string {
+	if ttl <= 0 {
+		ttl = MediaURLTTL()
+	}
+	key = normalizeKey(key)
+	expires := time.Now().Add(ttl).Unix()
+
+	query := url.Values{}
+	query.Set(SignedURLExpiresParam, strconv.FormatInt(expires, 10))
+	query.Set(SignedURLUserParam, strconv.FormatUint(uint64(userID), 10))
+	query.Set(SignedURLSigParam, SignKey(key, userID, expires))
+
+	escaped := (&url.URL{Path: MediaRoutePrefix() + "/" + key}).EscapedPath()
+	if config.GlobalConfig != nil && config.GlobalConfig.ServerUrl != "" {
+		escaped = strings.TrimSuffix(config.GlobalConfig.ServerUrl, "/") + escaped
+	}
+	return escaped + "?" + query.Encode()
+}
+
+// mediaRoutePrefixes 由 SignedMediaMiddleware 保护的路由前缀
+func mediaRoutePrefixes() []string {
+	return []string{MediaRoutePrefix() + "/", "/media/", "/uploads/"}
+}
+
+// SignPublicURL 为 PublicURL 返回的本站媒体地址（含数据库中保存的历史地址）生成签名地址，返回给客户端前调用；
+// 已签名、外部存储或非媒体路由的地址原样返回
+func SignPublicURL(raw string, userID uint) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Query().Get(SignedURLSigParam) != "" {
+		return raw
+	}
+	if u.Host != "" {
+		server, err := url.Parse(serverURL())
+		if err != nil || !strings.EqualFold(server.Host, u.Host) {
+			return raw
+		}
+	}
+	for _, prefix := range mediaRoutePrefixes() {
+		if key, ok := strings.CutPrefix(u.Path, prefix); ok && key != "" {
+			return SignedURL(key, userID, 0)
+		}
+	}
+	return raw
+}
+
+func serverURL() string {
+	if config.GlobalConfig == nil {
+		return ""
+	}
+	return config.GlobalConfig.ServerUrl
+}
+
+// VerifySignedQuery 校验请求 key 的签名参数，返回 URL 绑定的用户 ID
+func VerifySignedQuery(key string, query url.Values, now time.Time) (uint, error) {
+	sig := query.Get(SignedURLSigParam)
+	if sig == "" {
+		return 0, ErrSignatureMissing
+	}
+	expires, err := strconv.ParseInt(query.Get(SignedURLExpiresParam), 10, 64)
+	if err != nil {
+		return 0, ErrSignatureInvalid
+	}
+	userID, err := strconv.ParseUint(query.Get(SignedURLUserParam), 10, 64)
+	if err != nil {
+		return 0, ErrSignatureInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(SignKey(key, uint(userID), expires))) {
+		return 0, ErrSignatureInvalid
+	}
+	if now.Unix() > expires {
+		return 0, ErrSignatureExpired
+	}
+	return uint(userID), nil
+}
+
+// ServeObject 从对象存储读取 key 并写入响应，用于非本地存储时的 /media 访问
+func ServeObject(w http.ResponseWriter, r *http.Request, store Store, key string) {
+	key = normalizeKey(key)
+	reader, size, err := store.Read(key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer reader.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, reader)
+	}
+}
@@ -0,0 +1,102 @@
+package stores
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+)
+
+func TestSignedURL_Verify(t *testing.T) {
+	old := config.GlobalConfig
+	config.GlobalConfig = &config.Config{MediaSigningSecret: "test-secret"}
+	defer func() { config.GlobalConfig = old }()
+	t.Setenv("MEDIA_PREFIX", "")
+	utils.InvalidateEnv("MEDIA_PREFIX")
+	t.Cleanup(func() { utils.InvalidateEnv("MEDIA_PREFIX") })
+
+	raw := SignedURL("/recordings/7/a b.wav", 7, time.Minute)
+	if !strings.HasPrefix(raw, "/media/recordings/7/a%20b.wav?") {
+		t.Fatalf("unexpected signed url %q", raw)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, err := VerifySignedQuery("recordings/7/a b.wav", u.Query(), time.Now())
+	if err != nil || userID != 7 {
+		t.Fatalf("VerifySignedQuery = %d, %v", userID, err)
+	}
+
+	// 签名不能用于其他文件
+	if _, err := VerifySignedQuery("recordings/8/a b.wav", u.Query(), time.Now()); err != ErrSignatureInvalid {
+		t.Fatalf("other key: got %v", err)
+	}
+	// 篡改绑定用户
+	tampered := u.Query()
+	tampered.Set(SignedURLUserParam, "8")
+	if _, err := VerifySignedQuery("recordings/7/a b.wav", tampered, time.Now()); err != ErrSignatureInvalid {
+		t.Fatalf("tampered user: got %v", err)
+	}
+	// 过期
+	if _, err := VerifySignedQuery("recordings/7/a b.wav", u.Query(), time.Now().Add(2*time.Minute)); err != ErrSignatureExpired {
+		t.Fatalf("expired: got %v", err)
+	}
+	// 未签名
+	if _, err := VerifySignedQuery("recordings/7/a b.wav", url.Values{}, time.Now()); err != ErrSignatureMissing {
+		t.Fatalf("missing: got %v", err)
+	}
+}
+
+func TestSignedURL_MediaPrefix(t *testing.T) {
+	old := config.GlobalConfig
+	config.GlobalConfig = &config.Config{MediaSigningSecret: "test-secret"}
+	defer func() { config.GlobalConfig = old }()
+	t.Setenv("MEDIA_PREFIX", "/files/")
+	utils.InvalidateEnv("MEDIA_PREFIX")
+	t.Cleanup(func() { utils.InvalidateEnv("MEDIA_PREFIX") })
+
+	if raw := SignedURL("a.wav", 0, time.Minute); !strings.HasPrefix(raw, "/files/a.wav?") {
+		t.Fatalf("unexpected signed url %q", raw)
+	}
+}
+
+func TestSignPublicURL(t *testing.T) {
+	old := config.GlobalConfig
+	config.GlobalConfig = &config.Config{MediaSigningSecret: "test-secret", ServerUrl: "https://app.example.com"}
+	defer func() { config.GlobalConfig = old }()
+	t.Setenv("MEDIA_PREFIX", "")
+	utils.InvalidateEnv("MEDIA_PREFIX")
+	t.Cleanup(func() { utils.InvalidateEnv("MEDIA_PREFIX") })
+
+	// 本站媒体地址（含历史 /uploads 地址）签名后可校验
+	for _, raw := range []string{
+		"https://app.example.com/uploads/voice_synthesis/1_10.mp3",
+		"/media/voice_synthesis/1_10.mp3",
+	} {
+		signed := SignPublicURL(raw, 3)
+		u, err := url.Parse(signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if userID, err := VerifySignedQuery("voice_synthesis/1_10.mp3", u.Query(), time.Now()); err != nil || userID != 3 {
+			t.Fatalf("%s -> %s: %d, %v", raw, signed, userID, err)
+		}
+	}
+
+	// 外部存储、其他路由与已签名的地址原样返回
+	for _, raw := range []string{
+		"https://bucket.s3.amazonaws.com/uploads/a.mp3",
+		"https://app.example.com/api/users",
+		"",
+		SignedURL("a.mp3", 0, time.Minute),
+	} {
+		if got := SignPublicURL(raw, 3); got != raw {
+			t.Errorf("SignPublicURL(%q) = %q", raw, got)
+		}
+	}
+}
@@ -89,6 +89,9 @@ func (as *AmazonService) Synthesize(ctx context.Context, handler SynthesisHandle
 		Text:         &text,
 		VoiceId:      as.opt.VoiceId, // Replace with your preferred voice ID
 	}
+	if IsSSML(text) {
+		input.TextType = types.TextTypeSsml
+	}
 	resp, err := client.SynthesizeSpeech(ctx, input)
 	if err != nil {
 		return err
@@ -123,12 +123,12 @@ func (as *AzureService) Synthesize(ctx context.Context, handler SynthesisHandler
 		}
 	}
 
-	// 构建 SSML
+	// 构建 SSML：传入 SSML 时取 <speak> 内的内容放入 voice 元素，纯文本需转义
 	ssml := fmt.Sprintf(`<speak version='1.0' xml:lang='%s'>
 	<voice xml:lang='%s' xml:gender='Female' name='%s'>
 		%s
 	</voice>
-</speak>`, lang, lang, opt.Voice, text)
+</speak>`, lang, lang, opt.Voice, SSMLBody(text))
 
 	// 构建 URL
 	url := fmt.Sprintf(azureTTSURLTemplate, opt.Region)
@@ -1,13 +1,17 @@
 package synthesizer
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"fmt"
 	"net/url"
+	"os"
+	"os/exec"
 
 	"github.com/carlmjohnson/requests"
 	"github.com/code-100-precent/LingEcho/pkg/media"
+	"github.com/code-100-precent/LingEcho/pkg/media/fileio"
 	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -20,6 +24,9 @@ type CoquiTTSOption struct {
 	Channels      int    `json:"channels" yaml:"channels" default:"1"`
 	BitDepth      int    `json:"bitDepth" yaml:"bit_depth" default:"16"`
 	FrameDuration string `json:"frameDuration" yaml:"frame_duration" default:"20ms"`
+	// 未配置 Url 时离线调用本地 Coqui 命令行（pip install TTS 提供的 tts 命令）
+	Command string `json:"command" yaml:"command"`
+	Model   string `json:"model" yaml:"model"` // 模型名（如 tts_models/zh-CN/baker/tacotron2-DDC-GST）或本地模型路径
 }
 
 type CoquiResponse struct {
@@ -79,6 +86,9 @@ type coquiSpeechSynthesisListener struct {
 }
 
 func (c *CoquiService) Synthesize(ctx context.Context, handler SynthesisHandler, text string) error {
+	if c.opt.Url == "" {
+		return c.synthesizeWithCommand(ctx, handler, text)
+	}
 	ttsReq := coquiSpeechSynthesisListener{
 		handler: handler,
 	}
@@ -121,3 +131,62 @@ func (c *coquiSpeechSynthesisListener) OnMessage(data []byte) {
 	c.handler.OnMessage(data)
 	c.OnComplete()
 }
+
+// synthesizeWithCommand 通过本地 tts 命令合成到临时 WAV 文件，按配置的采样率输出 PCM
+func (c *CoquiService) synthesizeWithCommand(ctx context.Context, handler SynthesisHandler, text string) error {
+	command := c.opt.Command
+	if command == "" {
+		command = "tts"
+	}
+	cmdPath, err := exec.LookPath(command)
+	if err != nil {
+		return fmt.Errorf("coqui tts: command not found: %s", command)
+	}
+
+	out, err := os.CreateTemp("", "coqui-tts-*.wav")
+	if err != nil {
+		return fmt.Errorf("coqui tts: %w", err)
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+
+	args := []string{"--text", text, "--out_path", out.Name()}
+	if c.opt.Model != "" {
+		if _, err := os.Stat(c.opt.Model); err == nil {
+			args = append(args, "--model_path", c.opt.Model)
+		} else {
+			args = append(args, "--model_name", c.opt.Model)
+		}
+	}
+	if c.opt.Speaker != "" {
+		args = append(args, "--speaker_idx", c.opt.Speaker)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cmdPath, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("coqui tts: %w: %s", err, lastLine(stderr.String()))
+	}
+
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		return fmt.Errorf("coqui tts: %w", err)
+	}
+	pcm, format, err := fileio.DecodeWAV(data)
+	if err != nil {
+		return fmt.Errorf("coqui tts: %w", err)
+	}
+	if format.SampleRate != c.opt.SampleRate && c.opt.SampleRate > 0 {
+		if pcm, err = media.ResamplePCM(pcm, format.SampleRate, c.opt.SampleRate); err != nil {
+			return fmt.Errorf("coqui tts: %w", err)
+		}
+	}
+	if len(pcm) == 0 {
+		return fmt.Errorf("coqui tts: no audio data generated")
+	}
+	handler.OnMessage(pcm)
+	return nil
+}
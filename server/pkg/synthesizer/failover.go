@@ -0,0 +1,360 @@
+package synthesizer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	"github.com/sirupsen/logrus"
+)
+
+// FallbacksKey TTS 凭证配置中按顺序排列的备用提供商列表，每项与主配置格式相同，
+// 例如 {"provider": "volcengine", ..., "fallbacks": [{"provider": "tencent", ...}, {"provider": "local", ...}]}
+const FallbacksKey = "fallbacks"
+
+const (
+	providerFailureThreshold = 3                // 连续失败达到该次数后暂停使用该提供商
+	providerCooldown         = 30 * time.Second // 暂停时长，之后的第一个请求用于试探是否恢复
+	firstAudioTimeout        = 10 * time.Second // 有备用提供商时，超过该时间没有返回音频视为失败
+	probeText                = "你好"
+	probeTimeout             = 15 * time.Second
+)
+
+// fallbackInheritedKeys 备用配置未设置时沿用主配置的字段，保证输出格式一致
+var fallbackInheritedKeys = []string{"sampleRate", "sample_rate", "format", "language"}
+
+// HealthProbe 检查合成服务是否可用
+type HealthProbe func(ctx context.Context, svc SynthesisService) error
+
+var (
+	healthProbesMu sync.RWMutex
+	healthProbes   = map[TTSProvider]HealthProbe{}
+
+	providerHealthMu sync.Mutex
+	providerHealths  = map[string]*providerHealth{}
+)
+
+// RegisterHealthProbe 为提供商注册健康检查，覆盖默认的短句试合成
+func RegisterHealthProbe(provider TTSProvider, probe HealthProbe) {
+	healthProbesMu.Lock()
+	defer healthProbesMu.Unlock()
+	healthProbes[provider] = probe
+}
+
+// defaultHealthProbe 合成一个短句，返回音频即视为可用
+func defaultHealthProbe(ctx context.Context, svc SynthesisService) error {
+	h := &probeHandler{}
+	if err := svc.Synthesize(ctx, h, probeText); err != nil {
+		return err
+	}
+	if h.bytes == 0 {
+		return errors.New("no audio returned")
+	}
+	return nil
+}
+
+type probeHandler struct {
+	bytes int
+}
+
+func (h *probeHandler) OnMessage(data []byte)                   { h.bytes += len(data) }
+func (h *probeHandler) OnTimestamp(timestamp SentenceTimestamp) {}
+
+// ProviderStatus 提供商的健康状态
+type ProviderStatus struct {
+	Provider  TTSProvider `json:"provider"`
+	Healthy   bool        `json:"healthy"`
+	Failures  int         `json:"failures"`            // 连续失败次数
+	DownUntil *time.Time  `json:"downUntil,omitempty"` // 暂停使用截止时间
+	LastError string      `json:"lastError,omitempty"`
+	LatencyMs int64       `json:"latencyMs,omitempty"` // 最近一次探测耗时
+}
+
+// providerHealth 记录一个提供商配置的连续失败情况，同一配置的所有会话共享
+type providerHealth struct {
+	mu        sync.Mutex
+	failures  int
+	downUntil time.Time
+	lastError string
+	latency   time.Duration
+}
+
+// healthFor 按提供商配置（含密钥）的哈希获取共享的健康状态，不同用户的同一提供商互不影响
+func healthFor(config TTSCredentialConfig) *providerHealth {
+	data, _ := json.Marshal(config)
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+	providerHealthMu.Lock()
+	defer providerHealthMu.Unlock()
+	h, ok := providerHealths[key]
+	if !ok {
+		h = &providerHealth{}
+		providerHealths[key] = h
+	}
+	return h
+}
+
+func (h *providerHealth) available(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return !now.Before(h.downUntil)
+}
+
+func (h *providerHealth) recordSuccess() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures = 0
+	h.downUntil = time.Time{}
+	h.lastError = ""
+}
+
+func (h *providerHealth) recordFailure(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures++
+	h.lastError = err.Error()
+	if h.failures >= providerFailureThreshold {
+		h.downUntil = time.Now().Add(providerCooldown)
+	}
+}
+
+func (h *providerHealth) status(provider TTSProvider) ProviderStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := ProviderStatus{
+		Provider:  provider,
+		Healthy:   time.Now().After(h.downUntil) && h.failures == 0,
+		Failures:  h.failures,
+		LastError: h.lastError,
+		LatencyMs: h.latency.Milliseconds(),
+	}
+	if time.Now().Before(h.downUntil) {
+		downUntil := h.downUntil
+		st.DownUntil = &downUntil
+	}
+	return st
+}
+
+type failoverTarget struct {
+	svc    SynthesisService
+	health *providerHealth
+}
+
+// failoverService 按顺序使用主提供商与备用提供商：当前提供商在返回音频前失败或超时即换下一个，
+// 连续失败的提供商暂停使用一段时间。备用提供商的音频重采样为主提供商的采样率
+type failoverService struct {
+	targets []failoverTarget
+}
+
+func newFailoverService(targets []failoverTarget) SynthesisService {
+	if len(targets) == 1 {
+		return targets[0].svc
+	}
+	return &failoverService{targets: targets}
+}
+
+func (s *failoverService) Provider() TTSProvider       { return s.targets[0].svc.Provider() }
+func (s *failoverService) Format() media.StreamFormat  { return s.targets[0].svc.Format() }
+func (s *failoverService) CacheKey(text string) string { return s.targets[0].svc.CacheKey(text) }
+
+func (s *failoverService) synthesisParams() string {
+	return synthesisParamsOf(s.targets[0].svc)
+}
+
+func (s *failoverService) Close() error {
+	var errs []error
+	for _, t := range s.targets {
+		if err := t.svc.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *failoverService) Synthesize(ctx context.Context, handler SynthesisHandler, text string) error {
+	now := time.Now()
+	order := make([]failoverTarget, 0, len(s.targets))
+	var skipped []failoverTarget
+	for _, t := range s.targets {
+		if t.health.available(now) {
+			order = append(order, t)
+		} else {
+			skipped = append(skipped, t)
+		}
+	}
+	// 全部暂停时仍按顺序尝试
+	order = append(order, skipped...)
+
+	var errs []error
+	for i, t := range order {
+		started, err := s.attempt(ctx, t.svc, handler, text)
+		if err == nil {
+			t.health.recordSuccess()
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		t.health.recordFailure(err)
+		errs = append(errs, fmt.Errorf("%s: %w", t.svc.Provider(), err))
+		// 已经输出了部分音频，换提供商会重复播放
+		if started {
+			return err
+		}
+		if i+1 < len(order) {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"provider": t.svc.Provider(),
+				"fallback": order[i+1].svc.Provider(),
+			}).Warn("synthesis: provider failed, falling back")
+		}
+	}
+	return fmt.Errorf("all TTS providers failed: %w", errors.Join(errs...))
+}
+
+// attempt 调用一个提供商，返回是否已输出音频；超过 firstAudioTimeout 没有音频时取消本次调用
+func (s *failoverService) attempt(ctx context.Context, svc SynthesisService, handler SynthesisHandler, text string) (bool, error) {
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fh := &failoverHandler{SynthesisHandler: handler}
+	if rate, target := svc.Format().SampleRate, s.Format().SampleRate; rate > 0 && target > 0 && rate != target {
+		fh.inputRate, fh.outputRate = rate, target
+	}
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(firstAudioTimeout, func() {
+		if !fh.started.Load() {
+			timedOut.Store(true)
+			cancel()
+		}
+	})
+	defer timer.Stop()
+
+	err := svc.Synthesize(attemptCtx, fh, text)
+	if timedOut.Load() && ctx.Err() == nil {
+		return false, fmt.Errorf("no audio within %s", firstAudioTimeout)
+	}
+	return fh.started.Load(), err
+}
+
+// failoverHandler 记录是否已输出音频，并在采样率不同时重采样
+type failoverHandler struct {
+	SynthesisHandler
+	started    atomic.Bool
+	inputRate  int
+	outputRate int
+}
+
+func (h *failoverHandler) OnMessage(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	if h.inputRate > 0 {
+		if !h.started.Load() {
+			data = encoder.StripWavHeader(data)
+		}
+		resampled, err := media.ResamplePCM(data, h.inputRate, h.outputRate)
+		if err != nil {
+			logrus.WithError(err).Warn("synthesis: failed to resample fallback audio")
+			return
+		}
+		data = resampled
+	}
+	h.started.Store(true)
+	h.SynthesisHandler.OnMessage(data)
+}
+
+// CheckHealth 对服务使用的每个提供商执行健康检查并更新其状态。
+// 提供商注册了 HealthProbe 时使用它，否则试合成一个短句（会产生少量用量）
+func CheckHealth(ctx context.Context, svc SynthesisService) []ProviderStatus {
+	targets := []failoverTarget{{svc: svc, health: &providerHealth{}}}
+	if f, ok := unwrapFailover(svc); ok {
+		targets = f.targets
+	}
+	statuses := make([]ProviderStatus, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t failoverTarget) {
+			defer wg.Done()
+			healthProbesMu.RLock()
+			probe, ok := healthProbes[t.svc.Provider()]
+			healthProbesMu.RUnlock()
+			if !ok {
+				probe = defaultHealthProbe
+			}
+			probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+			defer cancel()
+			start := time.Now()
+			err := probe(probeCtx, t.svc)
+			t.health.mu.Lock()
+			t.health.latency = time.Since(start)
+			t.health.mu.Unlock()
+			if err != nil {
+				t.health.recordFailure(err)
+			} else {
+				t.health.recordSuccess()
+			}
+			statuses[i] = t.health.status(t.svc.Provider())
+		}(i, t)
+	}
+	wg.Wait()
+	return statuses
+}
+
+// unwrapFailover 穿过结果缓存、文本归一化等包装取得 failoverService
+func unwrapFailover(svc SynthesisService) (*failoverService, bool) {
+	for {
+		switch s := svc.(type) {
+		case *failoverService:
+			return s, true
+		case *cachedService:
+			svc = s.SynthesisService
+		case *normalizedService:
+			svc = s.SynthesisService
+		default:
+			return nil, false
+		}
+	}
+}
+
+// fallbackConfigs 解析凭证配置中的备用提供商列表，缺少的输出格式字段沿用主配置
+func fallbackConfigs(config TTSCredentialConfig) []TTSCredentialConfig {
+	raw, ok := config[FallbacksKey].([]interface{})
+	if !ok {
+		if typed, ok := config[FallbacksKey].([]map[string]interface{}); ok {
+			for _, m := range typed {
+				raw = append(raw, m)
+			}
+		}
+	}
+	var out []TTSCredentialConfig
+	for _, item := range raw {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fallback := make(TTSCredentialConfig, len(m)+len(fallbackInheritedKeys))
+		for k, v := range m {
+			if k != FallbacksKey {
+				fallback[k] = v
+			}
+		}
+		for _, k := range fallbackInheritedKeys {
+			if _, exists := fallback[k]; !exists {
+				if v, ok := config[k]; ok {
+					fallback[k] = v
+				}
+			}
+		}
+		out = append(out, fallback)
+	}
+	return out
+}
@@ -0,0 +1,139 @@
+package synthesizer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// providerService 指定提供商与采样率的测试服务
+type providerService struct {
+	countingService
+	provider TTSProvider
+	rate     int
+}
+
+func (s *providerService) Provider() TTSProvider { return s.provider }
+func (s *providerService) Format() media.StreamFormat {
+	return media.StreamFormat{SampleRate: s.rate, BitDepth: 16, Channels: 1}
+}
+
+func newTestFailover(services ...*providerService) *failoverService {
+	targets := make([]failoverTarget, len(services))
+	for i, svc := range services {
+		targets[i] = failoverTarget{svc: svc, health: &providerHealth{}}
+	}
+	return newFailoverService(targets).(*failoverService)
+}
+
+func TestFailoverService_FallsBack(t *testing.T) {
+	down := errors.New("503 service unavailable")
+	primary := &providerService{provider: "volcengine", rate: 16000, countingService: countingService{chunkedService: chunkedService{err: down}}}
+	backup := &providerService{provider: "tencent", rate: 16000, countingService: countingService{chunkedService: chunkedService{chunks: [][]byte{{1, 2}}}}}
+	svc := newTestFailover(primary, backup)
+	assert.Equal(t, TTSProvider("volcengine"), svc.Provider())
+
+	for i := 0; i < providerFailureThreshold; i++ {
+		h := &collectHandler{}
+		require.NoError(t, svc.Synthesize(context.Background(), h, "你好"))
+		assert.Equal(t, []byte{1, 2}, h.data)
+	}
+	assert.Equal(t, providerFailureThreshold, primary.calls)
+
+	// 连续失败后暂停使用主提供商
+	require.NoError(t, svc.Synthesize(context.Background(), &collectHandler{}, "你好"))
+	assert.Equal(t, providerFailureThreshold, primary.calls)
+	assert.Equal(t, providerFailureThreshold+1, backup.calls)
+	assert.False(t, svc.targets[0].health.available(time.Now()))
+}
+
+func TestFailoverService_NoSwitchAfterAudio(t *testing.T) {
+	primary := &providerService{provider: "volcengine", rate: 16000, countingService: countingService{chunkedService: chunkedService{chunks: [][]byte{{1, 2}}, err: errors.New("connection reset")}}}
+	backup := &providerService{provider: "tencent", rate: 16000, countingService: countingService{chunkedService: chunkedService{chunks: [][]byte{{3, 4}}}}}
+	svc := newTestFailover(primary, backup)
+
+	h := &collectHandler{}
+	assert.Error(t, svc.Synthesize(context.Background(), h, "你好"))
+	assert.Equal(t, []byte{1, 2}, h.data)
+	assert.Equal(t, 0, backup.calls)
+}
+
+func TestFailoverService_AllFailed(t *testing.T) {
+	primary := &providerService{provider: "volcengine", rate: 16000, countingService: countingService{chunkedService: chunkedService{err: errors.New("a")}}}
+	backup := &providerService{provider: "tencent", rate: 16000, countingService: countingService{chunkedService: chunkedService{err: errors.New("b")}}}
+	err := newTestFailover(primary, backup).Synthesize(context.Background(), &collectHandler{}, "你好")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "volcengine: a")
+	assert.Contains(t, err.Error(), "tencent: b")
+}
+
+func TestFailoverService_Resamples(t *testing.T) {
+	primary := &providerService{provider: "volcengine", rate: 16000, countingService: countingService{chunkedService: chunkedService{err: errors.New("down")}}}
+	backup := &providerService{provider: "local", rate: 8000, countingService: countingService{chunkedService: chunkedService{chunks: [][]byte{make([]byte, 320)}}}}
+	h := &collectHandler{}
+	require.NoError(t, newTestFailover(primary, backup).Synthesize(context.Background(), h, "你好"))
+	assert.Len(t, h.data, 640)
+}
+
+func TestCheckHealth(t *testing.T) {
+	RegisterHealthProbe("tencent", func(ctx context.Context, svc SynthesisService) error { return errors.New("quota exceeded") })
+	defer func() {
+		healthProbesMu.Lock()
+		delete(healthProbes, "tencent")
+		healthProbesMu.Unlock()
+	}()
+	primary := &providerService{provider: "volcengine", rate: 16000, countingService: countingService{chunkedService: chunkedService{chunks: [][]byte{{1}}}}}
+	backup := &providerService{provider: "tencent", rate: 16000}
+	statuses := CheckHealth(context.Background(), WithTextNormalizer(newTestFailover(primary, backup), "zh"))
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Healthy)
+	assert.Equal(t, 1, primary.calls)
+	assert.False(t, statuses[1].Healthy)
+	assert.Equal(t, "quota exceeded", statuses[1].LastError)
+	assert.Equal(t, 0, backup.calls)
+}
+
+func TestFallbackConfigs(t *testing.T) {
+	config := TTSCredentialConfig{
+		"provider":   "volcengine",
+		"sampleRate": 16000,
+		FallbacksKey: []interface{}{
+			map[string]interface{}{"provider": "tencent", "appId": "1"},
+			"invalid",
+			map[string]interface{}{"provider": "local", "sampleRate": 22050},
+		},
+	}
+	fallbacks := fallbackConfigs(config)
+	require.Len(t, fallbacks, 2)
+	assert.Equal(t, 16000, fallbacks[0]["sampleRate"])
+	assert.Equal(t, 22050, fallbacks[1]["sampleRate"])
+
+	// 无效的备用配置被跳过，只有一个可用提供商时不包装
+	svc, err := NewSynthesisServiceFromCredential(TTSCredentialConfig{
+		"provider": "openai",
+		"apiKey":   "sk-test",
+		FallbacksKey: []interface{}{
+			map[string]interface{}{"provider": "unknown"},
+		},
+	})
+	require.NoError(t, err)
+	_, ok := svc.(*failoverService)
+	assert.False(t, ok)
+
+	svc, err = NewSynthesisServiceFromCredential(TTSCredentialConfig{
+		"provider": "openai",
+		"apiKey":   "sk-test",
+		FallbacksKey: []interface{}{
+			map[string]interface{}{"provider": "openai", "apiKey": "sk-backup"},
+		},
+	})
+	require.NoError(t, err)
+	f, ok := svc.(*failoverService)
+	require.True(t, ok)
+	assert.Len(t, f.targets, 2)
+}
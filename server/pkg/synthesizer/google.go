@@ -82,10 +82,14 @@ func (gs *GoogleService) Synthesize(ctx context.Context, handler SynthesisHandle
 		return nil
 	}
 	defer client.Close()
+	input := &texttospeechpb.SynthesisInput{
+		InputSource: &texttospeechpb.SynthesisInput_Text{Text: text},
+	}
+	if IsSSML(text) {
+		input.InputSource = &texttospeechpb.SynthesisInput_Ssml{Ssml: text}
+	}
 	req := texttospeechpb.SynthesizeSpeechRequest{
-		Input: &texttospeechpb.SynthesisInput{
-			InputSource: &texttospeechpb.SynthesisInput_Text{Text: text},
-		},
+		Input: input,
 		Voice: &texttospeechpb.VoiceSelectionParams{
 			LanguageCode: gs.opt.LanguageCode,
 			SsmlGender:   gs.opt.SsmlGender,
@@ -0,0 +1,60 @@
+package synthesizer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/code-100-precent/LingEcho/pkg/metrics"
+)
+
+// instrumentedService 记录每次合成的耗时与错误，并在调用方的链路下创建 tts.synthesize 跨度，
+// 其余方法直接透传
+type instrumentedService struct {
+	SynthesisService
+	params string // 创建服务时的合成参数，用于结果缓存键
+}
+
+func (s *instrumentedService) synthesisParams() string {
+	return s.params
+}
+
+func (s *instrumentedService) Synthesize(ctx context.Context, handler SynthesisHandler, text string) error {
+	start := time.Now()
+	ctx, span := metrics.StartSpan(ctx, "tts.synthesize",
+		metrics.WithAttributes(map[string]interface{}{
+			"tts.provider": string(s.Provider()),
+			"tts.chars":    utf8.RuneCountInString(text),
+		}),
+	)
+	if span != nil {
+		handler = &firstAudioHandler{SynthesisHandler: handler, span: span}
+	}
+
+	err := s.SynthesisService.Synthesize(ctx, handler, text)
+	metrics.EndSpan(span, err)
+	// 被打断取消的合成不是服务故障，也没有完整耗时，不计入
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	if monitor := metrics.GetGlobalMonitor(); monitor != nil {
+		monitor.RecordAIRequest("tts", string(s.Provider()), "synthesize", time.Since(start), err)
+	}
+	return err
+}
+
+// firstAudioHandler 在收到第一段音频时给跨度打上 first_audio 事件，即合成首包延迟
+type firstAudioHandler struct {
+	SynthesisHandler
+	span *metrics.Span
+	once sync.Once
+}
+
+func (h *firstAudioHandler) OnMessage(data []byte) {
+	if len(data) > 0 {
+		h.once.Do(func() { h.span.AddEvent("first_audio", nil) })
+	}
+	h.SynthesisHandler.OnMessage(data)
+}
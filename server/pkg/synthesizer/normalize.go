@@ -0,0 +1,474 @@
+package synthesizer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TextNormalizer 把文本转换为适合朗读的形式
+type TextNormalizer interface {
+	Normalize(text string) string
+}
+
+// TextNormalizeOptions 控制归一化包含的步骤
+type TextNormalizeOptions struct {
+	Markdown bool // 去掉 Markdown 标记
+	Code     bool // 代码块替换为提示语，行内代码只保留内容
+	URLs     bool // 链接只读域名
+	Dates    bool // 日期与时间
+	Currency bool // 货币符号
+	Units    bool // 百分号与常见单位
+	Numbers  bool // 数字、小数、分数读法
+}
+
+// DefaultTextNormalizeOptions 默认开启全部步骤
+var DefaultTextNormalizeOptions = TextNormalizeOptions{
+	Markdown: true, Code: true, URLs: true, Dates: true, Currency: true, Units: true, Numbers: true,
+}
+
+var (
+	normalizersMu sync.RWMutex
+	normalizers   = map[string]TextNormalizer{}
+)
+
+// RegisterTextNormalizer 为语言注册归一化器，覆盖内置规则；language 如 zh、en
+func RegisterTextNormalizer(language string, n TextNormalizer) {
+	normalizersMu.Lock()
+	defer normalizersMu.Unlock()
+	normalizers[normalizeLanguage(language)] = n
+}
+
+// NormalizeForSpeech 按语言归一化待合成的文本；SSML 原样返回。
+// 未注册归一化器的语言使用内置规则：中文与英文展开日期、货币、数字等，其他语言只处理 Markdown、代码与链接
+func NormalizeForSpeech(text, language string) string {
+	if IsSSML(text) {
+		return strings.TrimSpace(text)
+	}
+	lang := normalizeLanguage(language)
+	normalizersMu.RLock()
+	n, ok := normalizers[lang]
+	normalizersMu.RUnlock()
+	if !ok {
+		n = NewTextNormalizer(lang, DefaultTextNormalizeOptions)
+	}
+	return n.Normalize(text)
+}
+
+// NewTextNormalizer 创建使用内置规则的归一化器
+func NewTextNormalizer(language string, opts TextNormalizeOptions) TextNormalizer {
+	rules := genericSpeechRules
+	switch normalizeLanguage(language) {
+	case "zh":
+		rules = zhSpeechRules
+	case "en":
+		rules = enSpeechRules
+	}
+	return &ruleNormalizer{opts: opts, rules: rules}
+}
+
+// WithTextNormalizer 合成前按 language 归一化文本
+func WithTextNormalizer(svc SynthesisService, language string) SynthesisService {
+	if svc == nil {
+		return nil
+	}
+	return &normalizedService{SynthesisService: svc, language: language}
+}
+
+type normalizedService struct {
+	SynthesisService
+	language string
+}
+
+func (s *normalizedService) Synthesize(ctx context.Context, handler SynthesisHandler, text string) error {
+	return s.SynthesisService.Synthesize(ctx, handler, NormalizeForSpeech(text, s.language))
+}
+
+// synthesisParams 归一化语言不同，相同的原文会合成出不同的音频
+func (s *normalizedService) synthesisParams() string {
+	return "normalize=" + normalizeLanguage(s.language) + ";" + synthesisParamsOf(s.SynthesisService)
+}
+
+// normalizeLanguage zh-CN、zh_cn、cmn 等统一为 zh，en-US 统一为 en，空值按中文处理
+func normalizeLanguage(language string) string {
+	lang := strings.ToLower(strings.TrimSpace(language))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	switch lang {
+	case "", "cn", "cmn", "chinese":
+		return "zh"
+	case "english":
+		return "en"
+	}
+	return lang
+}
+
+// speechRules 语言相关的读法，字段为 nil 表示该语言不处理对应内容
+type speechRules struct {
+	codeBlock string                          // 代码块的替代提示语
+	url       func(host string) string        // 链接的读法
+	date      func(y, m, d int) string        // 日期
+	year      func(y string) string           // 中文“2024年”中的年份
+	clock     func(h, m int, s string) string // 时间，s 为空表示没有秒
+	currency  map[string][2]string            // 货币符号 -> 主单位、辅币单位，辅币为空时按小数读
+	percent   func(num string) string
+	units     []speechUnit
+	number    func(num string) string // 整数或小数
+	fraction  func(a, b string) string
+}
+
+var (
+	codeBlockRegex   = regexp.MustCompile("(?s)```.*?```")
+	inlineCodeRegex  = regexp.MustCompile("`([^`]*)`")
+	mdImageRegex     = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLinkRegex      = regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)`)
+	mdBoldRegex      = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	mdItalicRegex    = regexp.MustCompile(`\*([^*\n]+)\*`)
+	mdHeadingRegex   = regexp.MustCompile(`(?m)^\s*#{1,6}\s*`)
+	mdListRegex      = regexp.MustCompile(`(?m)^\s*[-*+]\s+`)
+	mdQuoteRegex     = regexp.MustCompile(`(?m)^\s*>\s*`)
+	blankLinesRegex  = regexp.MustCompile(`\n\s*\n`)
+	urlRegex         = regexp.MustCompile(`https?://[^\s<>"'，。！？、）)]+`)
+	dateRegex        = regexp.MustCompile(`\b(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})\b`)
+	yearRegex        = regexp.MustCompile(`(\d{4})年`)
+	clockRegex       = regexp.MustCompile(`\b(\d{1,2}):(\d{2})(?::(\d{2}))?\b`)
+	currencyRegex    = regexp.MustCompile(`([¥￥$€£])\s?(\d[\d,]*(?:\.\d+)?)`)
+	percentRegex     = regexp.MustCompile(`(-?\d[\d,]*(?:\.\d+)?)\s?%`)
+	fractionRegex    = regexp.MustCompile(`\b(\d+)/(\d+)\b`)
+	numberRegex      = regexp.MustCompile(`(^|[^\d.])(-?)(\d[\d,]*(?:\.\d+)?)`)
+	thousandSepRegex = regexp.MustCompile(`^\d{1,3}(,\d{3})+(\.\d+)?$`)
+)
+
+// speechUnit 数字后的单位及其读法
+type speechUnit struct {
+	re   *regexp.Regexp
+	word string
+}
+
+// newSpeechUnits 按 符号、读法 成对传入，依次替换，较长的符号应写在前面
+func newSpeechUnits(pairs ...string) []speechUnit {
+	units := make([]speechUnit, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		units = append(units, speechUnit{
+			re:   regexp.MustCompile(`(\d)\s?` + regexp.QuoteMeta(pairs[i]) + `([^A-Za-z/]|$)`),
+			word: pairs[i+1],
+		})
+	}
+	return units
+}
+
+type ruleNormalizer struct {
+	opts  TextNormalizeOptions
+	rules *speechRules
+}
+
+func (n *ruleNormalizer) Normalize(text string) string {
+	r := n.rules
+	if n.opts.Code {
+		text = codeBlockRegex.ReplaceAllString(text, r.codeBlock)
+		text = inlineCodeRegex.ReplaceAllString(text, "$1")
+	}
+	if n.opts.Markdown {
+		text = mdImageRegex.ReplaceAllString(text, "$1")
+		text = mdLinkRegex.ReplaceAllString(text, "$1")
+		text = mdBoldRegex.ReplaceAllString(text, "$1")
+		text = mdItalicRegex.ReplaceAllString(text, "$1")
+		text = mdHeadingRegex.ReplaceAllString(text, "")
+		text = mdListRegex.ReplaceAllString(text, "")
+		text = mdQuoteRegex.ReplaceAllString(text, "")
+	}
+	if n.opts.URLs && r.url != nil {
+		text = urlRegex.ReplaceAllStringFunc(text, func(raw string) string {
+			u, err := url.Parse(raw)
+			if err != nil || u.Hostname() == "" {
+				return raw
+			}
+			return r.url(strings.TrimPrefix(u.Hostname(), "www."))
+		})
+	}
+	if n.opts.Dates && r.date != nil {
+		text = replaceSubmatch(dateRegex, text, func(m []string) string {
+			y, _ := strconv.Atoi(m[1])
+			mo, _ := strconv.Atoi(m[2])
+			d, _ := strconv.Atoi(m[3])
+			if mo < 1 || mo > 12 || d < 1 || d > 31 {
+				return m[0]
+			}
+			return r.date(y, mo, d)
+		})
+	}
+	if n.opts.Dates && r.year != nil {
+		text = replaceSubmatch(yearRegex, text, func(m []string) string { return r.year(m[1]) + "年" })
+	}
+	if n.opts.Dates && r.clock != nil {
+		text = replaceSubmatch(clockRegex, text, func(m []string) string {
+			h, _ := strconv.Atoi(m[1])
+			mi, _ := strconv.Atoi(m[2])
+			if h > 24 || mi > 59 {
+				return m[0]
+			}
+			return r.clock(h, mi, m[3])
+		})
+	}
+	if n.opts.Currency && r.currency != nil {
+		text = replaceSubmatch(currencyRegex, text, func(m []string) string {
+			unit := r.currency[m[1]]
+			amount := strings.ReplaceAll(m[2], ",", "")
+			whole, frac, _ := strings.Cut(amount, ".")
+			frac = strings.TrimRight(frac, "0")
+			switch {
+			case frac == "":
+				return joinWords(r.numberOrRaw(whole), unit[0])
+			case unit[1] == "":
+				return joinWords(r.numberOrRaw(whole+"."+frac), unit[0])
+			}
+			if len(frac) == 1 {
+				frac += "0"
+			}
+			cents := joinWords(r.numberOrRaw(strings.TrimLeft(frac[:2], "0")), unit[1])
+			if strings.Trim(whole, "0") == "" {
+				return cents
+			}
+			return joinWords(r.numberOrRaw(whole), unit[0], cents)
+		})
+	}
+	if n.opts.Units {
+		if r.percent != nil {
+			text = replaceSubmatch(percentRegex, text, func(m []string) string {
+				return r.percent(strings.ReplaceAll(m[1], ",", ""))
+			})
+		}
+		for _, unit := range r.units {
+			text = unit.re.ReplaceAllString(text, "${1}"+unit.word+"${2}")
+		}
+	}
+	if n.opts.Numbers && r.number != nil {
+		if r.fraction != nil {
+			text = replaceSubmatch(fractionRegex, text, func(m []string) string {
+				if m[2] == "0" {
+					return m[0]
+				}
+				return r.fraction(m[1], m[2])
+			})
+		}
+		text = replaceSubmatch(numberRegex, text, func(m []string) string {
+			num := m[3]
+			if strings.Contains(num, ",") {
+				if !thousandSepRegex.MatchString(num) {
+					return m[0]
+				}
+				num = strings.ReplaceAll(num, ",", "")
+			}
+			// 负号前是字母时（如 COVID-19）不按负数读
+			if m[2] != "" && !isASCIILetter(m[1]) {
+				return m[1] + r.number("-"+num)
+			}
+			return m[1] + m[2] + r.number(num)
+		})
+	}
+
+	text = blankLinesRegex.ReplaceAllString(text, "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func (r *speechRules) numberOrRaw(num string) string {
+	if num == "" {
+		num = "0"
+	}
+	if r.number == nil {
+		return num
+	}
+	return r.number(num)
+}
+
+// replaceSubmatch 与 ReplaceAllStringFunc 相同，回调中可以取得分组
+func replaceSubmatch(re *regexp.Regexp, text string, fn func(m []string) string) string {
+	return re.ReplaceAllStringFunc(text, func(s string) string {
+		return fn(re.FindStringSubmatch(s))
+	})
+}
+
+// joinWords 中文直接拼接，英文以空格分隔
+func joinWords(parts ...string) string {
+	var b strings.Builder
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		if b.Len() > 0 && isASCIIWord(p) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+func isASCIILetter(s string) bool {
+	return len(s) == 1 && (s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z')
+}
+
+func isASCIIWord(s string) bool {
+	for _, c := range s {
+		if c > 127 {
+			return false
+		}
+	}
+	return true
+}
+
+var genericSpeechRules = &speechRules{
+	codeBlock: " ",
+}
+
+var zhSpeechRules = &speechRules{
+	codeBlock: "（代码略）",
+	url:       func(host string) string { return "链接" + host },
+	date: func(y, m, d int) string {
+		return zhDigits(strconv.Itoa(y)) + "年" + zhNumber(strconv.Itoa(m)) + "月" + zhNumber(strconv.Itoa(d)) + "日"
+	},
+	year: zhDigits,
+	clock: func(h, m int, s string) string {
+		out := zhNumber(strconv.Itoa(h)) + "点"
+		if m > 0 {
+			if m < 10 {
+				out += "零"
+			}
+			out += zhNumber(strconv.Itoa(m)) + "分"
+		}
+		if sec, _ := strconv.Atoi(s); sec > 0 {
+			out += zhNumber(strconv.Itoa(sec)) + "秒"
+		}
+		return out
+	},
+	currency: map[string][2]string{
+		"¥": {"元", ""}, "￥": {"元", ""}, "$": {"美元", ""}, "€": {"欧元", ""}, "£": {"英镑", ""},
+	},
+	percent: func(num string) string { return "百分之" + zhNumber(num) },
+	units: newSpeechUnits("km/h", "公里每小时", "km", "公里", "kg", "千克", "cm", "厘米", "mm", "毫米", "ml", "毫升",
+		"m²", "平方米", "℃", "摄氏度", "°C", "摄氏度"),
+	number:   zhNumber,
+	fraction: func(a, b string) string { return zhNumber(b) + "分之" + zhNumber(a) },
+}
+
+var enMonths = []string{"January", "February", "March", "April", "May", "June", "July",
+	"August", "September", "October", "November", "December"}
+
+var enSpeechRules = &speechRules{
+	codeBlock: "(code omitted)",
+	url:       func(host string) string { return "a link to " + host },
+	date: func(y, m, d int) string {
+		return fmt.Sprintf("%s %d, %d", enMonths[m-1], d, y)
+	},
+	currency: map[string][2]string{
+		"$": {"dollars", "cents"}, "€": {"euros", "cents"}, "£": {"pounds", "pence"}, "¥": {"yuan", ""}, "￥": {"yuan", ""},
+	},
+	percent: func(num string) string { return num + " percent" },
+	units: newSpeechUnits("km/h", " kilometers per hour", "km", " kilometers", "kg", " kilograms", "cm", " centimeters",
+		"mm", " millimeters", "ml", " milliliters", "℃", " degrees Celsius", "°C", " degrees Celsius"),
+	// 英文引擎能正确朗读数字，不展开
+}
+
+const zhDigitChars = "零一二三四五六七八九"
+
+// zhDigits 逐位读出数字，用于年份、号码等
+func zhDigits(num string) string {
+	var b strings.Builder
+	digits := []rune(zhDigitChars)
+	for _, c := range num {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(digits[c-'0'])
+		}
+	}
+	return b.String()
+}
+
+// zhNumber 读出整数或小数；以 0 开头或超过 10 位的整数（编号、电话）逐位读
+func zhNumber(num string) string {
+	neg := strings.HasPrefix(num, "-")
+	num = strings.TrimPrefix(num, "-")
+	whole, frac, hasFrac := strings.Cut(num, ".")
+	var out string
+	if len(whole) > 10 || len(whole) > 1 && whole[0] == '0' && !hasFrac {
+		out = zhDigits(whole)
+	} else {
+		n, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil {
+			return num
+		}
+		out = zhInteger(n)
+	}
+	if hasFrac && frac != "" {
+		out += "点" + zhDigits(frac)
+	}
+	if neg {
+		out = "负" + out
+	}
+	return out
+}
+
+// zhInteger 读出整数，如 10086 -> 一万零八十六，12 -> 十二
+func zhInteger(n int64) string {
+	if n == 0 {
+		return "零"
+	}
+	groupUnits := []string{"", "万", "亿"}
+	var groups []int64
+	for n > 0 {
+		groups = append(groups, n%10000)
+		n /= 10000
+	}
+	var out string
+	needZero := false
+	for i := len(groups) - 1; i >= 0; i-- {
+		g := groups[i]
+		if g == 0 {
+			needZero = out != ""
+			continue
+		}
+		if out != "" && (needZero || g < 1000) {
+			out += "零"
+		}
+		out += zhSection(g) + groupUnits[i]
+		needZero = false
+	}
+	// 10-19 读作“十X”
+	if strings.HasPrefix(out, "一十") {
+		out = strings.TrimPrefix(out, "一")
+	}
+	return out
+}
+
+// zhSection 读出 1-9999
+func zhSection(n int64) string {
+	digits := []rune(zhDigitChars)
+	units := []string{"", "十", "百", "千"}
+	var b strings.Builder
+	zero := false
+	for pos := 3; pos >= 0; pos-- {
+		p := int64(1)
+		for i := 0; i < pos; i++ {
+			p *= 10
+		}
+		d := n / p % 10
+		if d == 0 {
+			zero = b.Len() > 0
+			continue
+		}
+		if zero {
+			b.WriteRune(digits[0])
+			zero = false
+		}
+		b.WriteRune(digits[d])
+		b.WriteString(units[pos])
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package synthesizer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeForSpeech_Chinese(t *testing.T) {
+	cases := map[string]string{
+		"圆周率约等于3.14":                   "圆周率约等于三点一四",
+		"会议定在2024-01-05 14:30":         "会议定在二零二四年一月五日 十四点三十分",
+		"2024年共有12个月":                  "二零二四年共有十二个月",
+		"价格是¥1,299.50":                 "价格是一千二百九十九点五元",
+		"涨了15%":                        "涨了百分之十五",
+		"全程42km，气温-3℃":                 "全程四十二公里，气温负三摄氏度",
+		"完成了3/4":                       "完成了四分之三",
+		"共10086人":                      "共一万零八十六人",
+		"客服电话13800138000":              "客服电话一三八零零一三八零零零",
+		"第110号":                        "第一百一十号",
+		"COVID-19":                     "COVID-十九",
+		"详见 https://www.example.com/a": "详见 链接example.com",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, NormalizeForSpeech(in, "zh-CN"), in)
+	}
+}
+
+func TestNormalizeForSpeech_English(t *testing.T) {
+	assert.Equal(t, "It costs 5 dollars 20 cents", NormalizeForSpeech("It costs $5.20", "en-US"))
+	assert.Equal(t, "Due on January 5, 2024", NormalizeForSpeech("Due on 2024-01-05", "en"))
+	assert.Equal(t, "Only 99 cents, 12 percent off", NormalizeForSpeech("Only $0.99, 12% off", "en"))
+	assert.Equal(t, "Drive 5 kilometers per hour", NormalizeForSpeech("Drive 5 km/h", "en"))
+	assert.Equal(t, "See a link to docs.example.com", NormalizeForSpeech("See https://docs.example.com/x?y=1", "en"))
+	// 英文数字交给引擎朗读
+	assert.Equal(t, "pi is 3.14", NormalizeForSpeech("pi is 3.14", "en"))
+}
+
+func TestNormalizeForSpeech_MarkdownAndCode(t *testing.T) {
+	text := "## 步骤\n\n- **安装**依赖\n- 运行 `make`\n\n```bash\nmake build\n```\n> 参见[文档](https://example.com)"
+	assert.Equal(t, "步骤\n安装依赖\n运行 make\n（代码略）\n参见文档", NormalizeForSpeech(text, "zh"))
+
+	// 其他语言只处理 Markdown 与代码，不改动数字
+	assert.Equal(t, "Il coûte 3.14", NormalizeForSpeech("Il coûte **3.14**", "fr"))
+	// SSML 原样返回
+	assert.Equal(t, "<speak>3.14</speak>", NormalizeForSpeech(" <speak>3.14</speak> ", "zh"))
+}
+
+type upperNormalizer struct{}
+
+func (upperNormalizer) Normalize(text string) string { return strings.ToUpper(text) }
+
+func TestRegisterTextNormalizer(t *testing.T) {
+	RegisterTextNormalizer("de-DE", upperNormalizer{})
+	defer RegisterTextNormalizer("de", NewTextNormalizer("de", DefaultTextNormalizeOptions))
+	assert.Equal(t, "HALLO 3.14", NormalizeForSpeech("hallo 3.14", "de"))
+
+	onlyCode := NewTextNormalizer("zh", TextNormalizeOptions{Code: true})
+	assert.Equal(t, "**3.14**", onlyCode.Normalize("**3.14**"))
+}
+
+func TestWithTextNormalizer(t *testing.T) {
+	inner := &textRecorder{}
+	svc := WithTextNormalizer(inner, "zh")
+	require.NoError(t, svc.Synthesize(context.Background(), &collectHandler{}, "温度25℃"))
+	assert.Equal(t, "温度二十五摄氏度", inner.text)
+}
+
+func TestWithTextNormalizer_ResultCacheKey(t *testing.T) {
+	inner := &instrumentedService{SynthesisService: &chunkedService{}, params: "voice=1;"}
+	zh := ResultCacheKey(WithTextNormalizer(inner, "zh"), "3.14")
+	assert.NotEqual(t, zh, ResultCacheKey(WithTextNormalizer(inner, "en"), "3.14"))
+	assert.NotEqual(t, zh, ResultCacheKey(WithTextNormalizer(&instrumentedService{SynthesisService: &chunkedService{}, params: "voice=2;"}, "zh"), "3.14"))
+}
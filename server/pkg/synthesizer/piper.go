@@ -0,0 +1,213 @@
+package synthesizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const piperDefaultSampleRate = 22050
+
+// 本地命令与模型目录只能由服务器环境变量配置；凭证只能选择模型目录内的模型，
+// 避免用户借TTS凭证让服务器执行任意程序或读取任意文件
+const (
+	EnvPiperCommand  = "PIPER_COMMAND"   // piper 可执行文件，默认 piper
+	EnvPiperModelDir = "PIPER_MODEL_DIR" // Piper 模型目录，未配置时不启用 Piper
+	EnvCoquiCommand  = "COQUI_COMMAND"   // Coqui tts 可执行文件，默认 tts
+	EnvCoquiModelDir = "COQUI_MODEL_DIR" // Coqui 本地模型目录，未配置时不启用本地命令行模式
+)
+
+// coquiModelName Coqui 模型库中的模型名，如 tts_models/zh-CN/baker/tacotron2-DDC-GST
+var coquiModelName = regexp.MustCompile(`^tts_models/[\w.-]+/[\w.-]+/[\w.-]+$`)
+
+// PiperTTSConfig Piper 离线TTS配置，调用本地 piper 可执行文件与 .onnx 模型合成，不依赖网络
+type PiperTTSConfig struct {
+	Command         string  `json:"command" yaml:"command" default:"piper"`  // piper 可执行文件
+	Model           string  `json:"model" yaml:"model"`                      // .onnx 模型路径
+	ModelConfig     string  `json:"modelConfig" yaml:"model_config"`         // 模型配置，默认为 模型路径.json
+	Speaker         int     `json:"speaker" yaml:"speaker"`                  // 多说话人模型的说话人编号
+	LengthScale     float64 `json:"lengthScale" yaml:"length_scale"`         // 语速，大于 1 变慢，0 使用模型默认值
+	NoiseScale      float64 `json:"noiseScale" yaml:"noise_scale"`           // 0 使用模型默认值
+	NoiseW          float64 `json:"noiseW" yaml:"noise_w"`                   // 0 使用模型默认值
+	SentenceSilence float64 `json:"sentenceSilence" yaml:"sentence_silence"` // 句间静音秒数
+	SampleRate      int     `json:"sampleRate" yaml:"sample_rate"`           // 模型输出采样率，0 表示从模型配置读取
+	FrameDuration   string  `json:"frameDuration" yaml:"frame_duration" default:"20ms"`
+}
+
+// NewPiperTTSConfig 创建 Piper 配置
+func NewPiperTTSConfig(model string) PiperTTSConfig {
+	return PiperTTSConfig{
+		Command:       "piper",
+		Model:         model,
+		FrameDuration: "20ms",
+	}
+}
+
+type PiperService struct {
+	opt PiperTTSConfig
+}
+
+// NewPiperService 创建 Piper 服务，未指定采样率时从模型配置读取
+func NewPiperService(opt PiperTTSConfig) *PiperService {
+	if opt.Command == "" {
+		opt.Command = "piper"
+	}
+	if opt.ModelConfig == "" && opt.Model != "" {
+		opt.ModelConfig = opt.Model + ".json"
+	}
+	if opt.SampleRate == 0 {
+		opt.SampleRate = readPiperSampleRate(opt.ModelConfig)
+	}
+	return &PiperService{opt: opt}
+}
+
+// readPiperSampleRate 读取模型配置中的 audio.sample_rate
+func readPiperSampleRate(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return piperDefaultSampleRate
+	}
+	var cfg struct {
+		Audio struct {
+			SampleRate int `json:"sample_rate"`
+		} `json:"audio"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil || cfg.Audio.SampleRate == 0 {
+		return piperDefaultSampleRate
+	}
+	return cfg.Audio.SampleRate
+}
+
+func (s *PiperService) Provider() TTSProvider {
+	return ProviderPiper
+}
+
+func (s *PiperService) Format() media.StreamFormat {
+	return media.StreamFormat{
+		SampleRate:    s.opt.SampleRate,
+		BitDepth:      16,
+		Channels:      1,
+		FrameDuration: utils.NormalizeFramePeriod(s.opt.FrameDuration),
+	}
+}
+
+func (s *PiperService) CacheKey(text string) string {
+	digest := media.MediaCache().BuildKey(text)
+	model := strings.TrimSuffix(filepath.Base(s.opt.Model), ".onnx")
+	return fmt.Sprintf("piper.tts-%s-%d-%d-%s.pcm", model, s.opt.Speaker, s.opt.SampleRate, digest)
+}
+
+// args 构建命令行参数：文本从标准输入读入，原始 16bit PCM 从标准输出逐句输出
+func (s *PiperService) args() []string {
+	args := []string{"--model", s.opt.Model, "--output_raw"}
+	if s.opt.ModelConfig != "" {
+		args = append(args, "--config", s.opt.ModelConfig)
+	}
+	if s.opt.Speaker > 0 {
+		args = append(args, "--speaker", strconv.Itoa(s.opt.Speaker))
+	}
+	for _, f := range []struct {
+		name  string
+		value float64
+	}{
+		{"--length_scale", s.opt.LengthScale},
+		{"--noise_scale", s.opt.NoiseScale},
+		{"--noise_w", s.opt.NoiseW},
+		{"--sentence_silence", s.opt.SentenceSilence},
+	} {
+		if f.value > 0 {
+			args = append(args, f.name, strconv.FormatFloat(f.value, 'f', -1, 64))
+		}
+	}
+	return args
+}
+
+func (s *PiperService) Synthesize(ctx context.Context, handler SynthesisHandler, text string) error {
+	if s.opt.Model == "" {
+		return fmt.Errorf("piper tts: model is not configured")
+	}
+	cmdPath, err := exec.LookPath(s.opt.Command)
+	if err != nil {
+		return fmt.Errorf("piper tts: command not found: %s", s.opt.Command)
+	}
+
+	// piper 按行切分语句，换行统一为空格，整段作为一句输入
+	input := strings.Join(strings.Fields(text), " ") + "\n"
+	cmd := exec.CommandContext(ctx, cmdPath, s.args()...)
+	cmd.Stdin = strings.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("piper tts: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("piper tts: start failed: %w", err)
+	}
+
+	total, readErr := streamPCM(stdout, handler)
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if waitErr != nil {
+		return fmt.Errorf("piper tts: %w: %s", waitErr, lastLine(stderr.String()))
+	}
+	if readErr != nil {
+		return fmt.Errorf("piper tts: reading audio: %w", readErr)
+	}
+	if total == 0 {
+		return fmt.Errorf("piper tts: no audio data generated")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"provider":   ProviderPiper,
+		"model":      filepath.Base(s.opt.Model),
+		"audio_size": total,
+	}).Info("piper tts: synthesis completed")
+	return nil
+}
+
+func (s *PiperService) Close() error {
+	return nil
+}
+
+// streamPCM 边读边回调 16bit PCM，保证每块按采样对齐
+func streamPCM(r io.Reader, handler SynthesisHandler) (int, error) {
+	buf := make([]byte, 8192)
+	pending, total := 0, 0
+	for {
+		n, err := r.Read(buf[pending:])
+		pending += n
+		if aligned := pending &^ 1; aligned > 0 {
+			handler.OnMessage(buf[:aligned])
+			total += aligned
+			copy(buf, buf[aligned:pending])
+			pending -= aligned
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// lastLine 返回命令输出的最后一行非空内容，用于错误信息
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
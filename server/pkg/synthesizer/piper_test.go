@@ -0,0 +1,139 @@
+package synthesizer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/code-100-precent/LingEcho/pkg/media/fileio"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCommand 写入一个记录参数的 shell 脚本，模拟本地TTS命令
+func fakeCommand(t *testing.T, name, script string) (string, string) {
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	path := filepath.Join(dir, name)
+	body := "#!/bin/sh\necho \"$@\" > " + argsFile + "\n" + script
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o755))
+	return path, argsFile
+}
+
+// setLocalTTSEnv 设置本地TTS的服务器环境变量，测试结束后清除缓存
+func setLocalTTSEnv(t *testing.T, key, value string) {
+	t.Setenv(key, value)
+	utils.InvalidateEnv(key)
+	t.Cleanup(func() { utils.InvalidateEnv(key) })
+}
+
+func TestPiperService(t *testing.T) {
+	command, argsFile := fakeCommand(t, "piper", "cat > \"$(dirname \"$0\")/stdin\"\nprintf '\\001\\002\\003\\004\\005'\n")
+	dir := t.TempDir()
+	model := filepath.Join(dir, "zh_CN-huayan-medium.onnx")
+	require.NoError(t, os.WriteFile(model+".json", []byte(`{"audio":{"sample_rate":16000}}`), 0o644))
+	setLocalTTSEnv(t, EnvPiperModelDir, dir)
+	setLocalTTSEnv(t, EnvPiperCommand, command)
+
+	svc, err := NewSynthesisServiceFromCredential(TTSCredentialConfig{
+		"provider":    "piper",
+		"command":     "/bin/sh", // 凭证中的命令被忽略
+		"model":       "zh_CN-huayan-medium.onnx",
+		"speaker":     2,
+		"lengthScale": 1.2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ProviderPiper, svc.Provider())
+	assert.Equal(t, 16000, svc.Format().SampleRate)
+	assert.Contains(t, svc.CacheKey("你好"), "piper.tts-zh_CN-huayan-medium-2-16000")
+
+	h := &collectHandler{}
+	require.NoError(t, svc.Synthesize(context.Background(), h, "你好，\n世界"))
+	// 奇数字节的尾部不是完整采样，丢弃
+	assert.Equal(t, []byte{1, 2, 3, 4}, h.data)
+
+	args, _ := os.ReadFile(argsFile)
+	assert.Equal(t, "--model "+model+" --output_raw --config "+model+".json --speaker 2 --length_scale 1.2", strings.TrimSpace(string(args)))
+	stdin, _ := os.ReadFile(filepath.Join(filepath.Dir(command), "stdin"))
+	assert.Equal(t, "你好， 世界\n", string(stdin))
+}
+
+func TestPiperService_Errors(t *testing.T) {
+	_, err := NewSynthesisServiceFromCredential(TTSCredentialConfig{"provider": "piper"})
+	assert.Error(t, err)
+
+	// 未配置模型目录时不启用
+	setLocalTTSEnv(t, EnvPiperModelDir, "")
+	_, err = NewSynthesisServiceFromCredential(TTSCredentialConfig{"provider": "piper", "model": "a.onnx"})
+	assert.ErrorContains(t, err, EnvPiperModelDir)
+
+	// 模型只能在模型目录内
+	dir := t.TempDir()
+	setLocalTTSEnv(t, EnvPiperModelDir, dir)
+	outside := filepath.Join(t.TempDir(), "b.onnx")
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link.onnx")))
+	for _, model := range []string{"../a.onnx", "/etc/passwd", outside, "link.onnx"} {
+		_, err = NewSynthesisServiceFromCredential(TTSCredentialConfig{"provider": "piper", "model": model})
+		assert.Error(t, err, model)
+	}
+	_, err = NewSynthesisServiceFromCredential(TTSCredentialConfig{"provider": "piper", "model": "a.onnx", "modelConfig": "../a.json"})
+	assert.Error(t, err)
+	svc, err := NewSynthesisServiceFromCredential(TTSCredentialConfig{"provider": "piper", "model": filepath.Join(dir, "voices", "a.onnx")})
+	require.NoError(t, err)
+	assert.Equal(t, ProviderPiper, svc.Provider())
+
+	command, _ := fakeCommand(t, "piper", "echo 'model file not found' >&2\nexit 1\n")
+	piper := NewPiperService(PiperTTSConfig{Command: command, Model: "missing.onnx"})
+	assert.Equal(t, piperDefaultSampleRate, piper.Format().SampleRate)
+	err = piper.Synthesize(context.Background(), &collectHandler{}, "你好")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model file not found")
+
+	piper = NewPiperService(PiperTTSConfig{Command: "piper-not-installed", Model: "a.onnx"})
+	assert.Error(t, piper.Synthesize(context.Background(), &collectHandler{}, "你好"))
+}
+
+func TestCoquiService_Command(t *testing.T) {
+	wav := filepath.Join(t.TempDir(), "out.wav")
+	pcm := make([]byte, 441*2)
+	require.NoError(t, os.WriteFile(wav, fileio.EncodeWAV(pcm, fileio.Format{SampleRate: 22050, Channels: 1}), 0o644))
+	// 把预置的 WAV 复制到 --out_path 指定的位置
+	command, argsFile := fakeCommand(t, "tts", `while [ $# -gt 0 ]; do
+  if [ "$1" = "--out_path" ]; then cp `+wav+` "$2"; fi
+  shift
+done
+`)
+
+	// 未配置模型目录时不启用本地命令行模式
+	setLocalTTSEnv(t, EnvCoquiModelDir, "")
+	_, err := NewSynthesisServiceFromCredential(TTSCredentialConfig{"provider": "coqui", "model": "tts_models/zh-CN/baker/tacotron2-DDC-GST"})
+	assert.ErrorContains(t, err, EnvCoquiModelDir)
+
+	dir := t.TempDir()
+	setLocalTTSEnv(t, EnvCoquiModelDir, dir)
+	setLocalTTSEnv(t, EnvCoquiCommand, command)
+	_, err = NewSynthesisServiceFromCredential(TTSCredentialConfig{"provider": "coqui", "model": "../model.pth"})
+	assert.Error(t, err)
+
+	svc, err := NewSynthesisServiceFromCredential(TTSCredentialConfig{
+		"provider": "coqui",
+		"command":  "/bin/sh", // 凭证中的命令被忽略
+		"model":    "tts_models/zh-CN/baker/tacotron2-DDC-GST",
+	})
+	require.NoError(t, err)
+	h := &collectHandler{}
+	require.NoError(t, svc.Synthesize(context.Background(), h, "你好"))
+	// 22050Hz 重采样为配置的 16000Hz
+	assert.InDelta(t, 320*2, len(h.data), 4)
+
+	args, _ := os.ReadFile(argsFile)
+	assert.Contains(t, string(args), "--text 你好 --out_path ")
+	assert.Contains(t, string(args), "--model_name tts_models/zh-CN/baker/tacotron2-DDC-GST")
+	assert.NotContains(t, string(args), "--speaker_idx")
+
+	_, err = NewSynthesisServiceFromCredential(TTSCredentialConfig{"provider": "coqui"})
+	assert.Error(t, err)
+}
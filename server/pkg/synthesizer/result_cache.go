@@ -0,0 +1,187 @@
+package synthesizer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	"github.com/code-100-precent/LingEcho/pkg/media/fileio"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	resultCachePrefix   = "tts-cache/"
+	maxResultCacheChars = 200     // 只缓存短句：开场白、固定话术等；长回答几乎不会重复
+	maxResultCacheSize  = 4 << 20 // 超过该大小的音频不缓存
+)
+
+var (
+	resultCacheMu    sync.RWMutex
+	resultCacheStore stores.Store
+)
+
+// SetResultCacheStore 设置合成结果缓存使用的存储，nil 表示关闭缓存
+func SetResultCacheStore(store stores.Store) {
+	resultCacheMu.Lock()
+	defer resultCacheMu.Unlock()
+	resultCacheStore = store
+}
+
+func getResultCacheStore() stores.Store {
+	resultCacheMu.RLock()
+	defer resultCacheMu.RUnlock()
+	return resultCacheStore
+}
+
+// WithResultCache 为合成服务加上结果缓存：相同的文本（规范化后）、提供商、音色与合成参数只合成一次，
+// 之后直接从存储返回音频，不再消耗提供商额度。未设置缓存存储时原样返回 svc
+func WithResultCache(svc SynthesisService) SynthesisService {
+	if svc == nil || getResultCacheStore() == nil {
+		return svc
+	}
+	if _, ok := svc.(*cachedService); ok {
+		return svc
+	}
+	return &cachedService{SynthesisService: svc}
+}
+
+// cachedService 先查缓存，未命中时调用提供商并在合成成功后异步写入缓存
+type cachedService struct {
+	SynthesisService
+}
+
+func (s *cachedService) Synthesize(ctx context.Context, handler SynthesisHandler, text string) error {
+	store := getResultCacheStore()
+	key := ResultCacheKey(s.SynthesisService, text)
+	if store == nil || key == "" {
+		return s.SynthesisService.Synthesize(ctx, handler, text)
+	}
+	format := cacheFormat(s.Format())
+	if pcm, ok := readCachedResult(store, key, format); ok {
+		handler.OnMessage(pcm)
+		return nil
+	}
+
+	recorder := &resultRecorder{SynthesisHandler: handler}
+	if err := s.SynthesisService.Synthesize(ctx, recorder, text); err != nil {
+		return err
+	}
+	// 被打断的合成音频不完整，不缓存
+	if ctx.Err() != nil || recorder.overflow || recorder.buf.Len() == 0 {
+		return nil
+	}
+	data := fileio.EncodeWAV(recorder.buf.Bytes(), format)
+	go func() {
+		if err := store.Write(key, bytes.NewReader(data)); err != nil {
+			logrus.WithError(err).WithField("key", key).Warn("synthesis: failed to store cached result")
+		}
+	}()
+	return nil
+}
+
+// ResultCacheKey 返回文本的缓存键，文本过长或为空时返回空表示不缓存。
+// 键由规范化文本、提供商、输出格式、合成参数（音色、语言、语速等，不含密钥）及提供商自身的 CacheKey 哈希得到
+func ResultCacheKey(svc SynthesisService, text string) string {
+	text = NormalizeCacheText(text)
+	if text == "" || utf8.RuneCountInString(text) > maxResultCacheChars {
+		return ""
+	}
+	params := synthesisParamsOf(svc)
+	format := svc.Format()
+	h := sha256.New()
+	for _, part := range []string{
+		svc.Provider().ToString(),
+		fmt.Sprintf("%d/%d/%d", format.SampleRate, format.Channels, format.BitDepth),
+		params,
+		svc.CacheKey(text),
+		text,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return resultCachePrefix + svc.Provider().ToString() + "/" + hex.EncodeToString(h.Sum(nil)) + ".wav"
+}
+
+// NormalizeCacheText 去掉表情与首尾空白并合并连续空白，使仅有空白差异的文本命中同一缓存
+func NormalizeCacheText(text string) string {
+	return strings.Join(strings.Fields(StripEmoji(text)), " ")
+}
+
+// readCachedResult 读取缓存的 WAV 并返回 PCM，格式与当前服务不一致时视为未命中
+func readCachedResult(store stores.Store, key string, format fileio.Format) ([]byte, bool) {
+	r, _, err := store.Read(key)
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, maxResultCacheSize+1))
+	if err != nil || len(data) > maxResultCacheSize {
+		return nil, false
+	}
+	pcm, cached, err := fileio.DecodeWAV(data)
+	if err != nil || cached != format || len(pcm) == 0 {
+		return nil, false
+	}
+	return pcm, true
+}
+
+// synthesisParamsOf 返回服务创建时的合成参数，未知时为空
+func synthesisParamsOf(svc SynthesisService) string {
+	if p, ok := svc.(interface{ synthesisParams() string }); ok {
+		return p.synthesisParams()
+	}
+	return ""
+}
+
+// synthesisParamsDigest 把影响合成结果的配置整理为稳定的字符串，跳过密钥类字段，
+// 这样轮换密钥不会使缓存失效，缓存键中也不会包含密钥
+func synthesisParamsDigest(options map[string]any) string {
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		lower := strings.ToLower(k)
+		if strings.Contains(lower, "key") || strings.Contains(lower, "secret") ||
+			strings.Contains(lower, "token") || strings.Contains(lower, "password") {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%v;", k, options[k])
+	}
+	return b.String()
+}
+
+// resultRecorder 转发合成音频并记录完整结果，超过大小限制后停止记录
+type resultRecorder struct {
+	SynthesisHandler
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (r *resultRecorder) OnMessage(data []byte) {
+	if !r.overflow {
+		pcm := data
+		if r.buf.Len() == 0 {
+			// 部分提供商首包带 WAV 头，缓存中只保存 PCM
+			pcm = encoder.StripWavHeader(data)
+		}
+		if r.buf.Len()+len(pcm) > maxResultCacheSize {
+			r.overflow = true
+			r.buf.Reset()
+		} else {
+			r.buf.Write(pcm)
+		}
+	}
+	r.SynthesisHandler.OnMessage(data)
+}
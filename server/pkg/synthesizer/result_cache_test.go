@@ -0,0 +1,100 @@
+package synthesizer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingService 记录实际调用提供方的次数
+type countingService struct {
+	chunkedService
+	calls int
+}
+
+func (s *countingService) Synthesize(ctx context.Context, handler SynthesisHandler, text string) error {
+	s.calls++
+	return s.chunkedService.Synthesize(ctx, handler, text)
+}
+
+// collectHandler 收集回调的音频
+type collectHandler struct {
+	data []byte
+}
+
+func (h *collectHandler) OnMessage(data []byte)                   { h.data = append(h.data, data...) }
+func (h *collectHandler) OnTimestamp(timestamp SentenceTimestamp) {}
+
+func setupResultCache(t *testing.T) stores.Store {
+	store := stores.NewLocalStore().(*stores.LocalStore)
+	store.Root = t.TempDir()
+	SetResultCacheStore(store)
+	t.Cleanup(func() { SetResultCacheStore(nil) })
+	return store
+}
+
+func TestWithResultCache(t *testing.T) {
+	store := setupResultCache(t)
+	inner := &countingService{chunkedService: chunkedService{chunks: [][]byte{{1, 2}, {3, 4}}}}
+	svc := WithResultCache(inner)
+	assert.Same(t, svc, WithResultCache(svc))
+
+	first := &collectHandler{}
+	require.NoError(t, svc.Synthesize(context.Background(), first, "您好，请问有什么可以帮您？"))
+	assert.Equal(t, []byte{1, 2, 3, 4}, first.data)
+
+	key := ResultCacheKey(inner, "您好，请问有什么可以帮您？")
+	assert.Eventually(t, func() bool {
+		ok, _ := store.Exists(key)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	// 仅空白差异的文本命中同一缓存
+	second := &collectHandler{}
+	require.NoError(t, svc.Synthesize(context.Background(), second, "  您好，请问有什么可以帮您？ "))
+	assert.Equal(t, first.data, second.data)
+	assert.Equal(t, 1, inner.calls)
+
+	// 提供商、参数或输出格式不同时不共用缓存
+	other := &countingService{chunkedService: chunkedService{chunks: [][]byte{{9}}}}
+	assert.NotEqual(t, key, ResultCacheKey(&instrumentedService{SynthesisService: other, params: "voice=1;"}, "您好，请问有什么可以帮您？"))
+	assert.NotEqual(t,
+		ResultCacheKey(&instrumentedService{SynthesisService: other, params: "voice=1;"}, "您好"),
+		ResultCacheKey(&instrumentedService{SynthesisService: other, params: "voice=2;"}, "您好"))
+}
+
+func TestWithResultCache_SkipsFailedAndLongText(t *testing.T) {
+	store := setupResultCache(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	inner := &countingService{chunkedService: chunkedService{chunks: [][]byte{{1}}, delay: time.Second}}
+	svc := WithResultCache(inner)
+
+	// 被取消的合成不缓存
+	assert.Error(t, svc.Synthesize(ctx, &collectHandler{}, "再见"))
+	time.Sleep(50 * time.Millisecond)
+	ok, _ := store.Exists(ResultCacheKey(inner, "再见"))
+	assert.False(t, ok)
+
+	assert.Empty(t, ResultCacheKey(inner, strings.Repeat("长", maxResultCacheChars+1)))
+	assert.Empty(t, ResultCacheKey(inner, " 😀 "))
+}
+
+func TestWithResultCache_Disabled(t *testing.T) {
+	SetResultCacheStore(nil)
+	inner := &countingService{}
+	assert.Same(t, SynthesisService(inner), WithResultCache(inner))
+}
+
+func TestSynthesisParamsDigest(t *testing.T) {
+	a := synthesisParamsDigest(map[string]any{"voiceType": "101016", "speed": 1.2, "secretKey": "s1", "appId": "1"})
+	b := synthesisParamsDigest(map[string]any{"appId": "1", "secretKey": "s2", "speed": 1.2, "voiceType": "101016"})
+	assert.Equal(t, a, b)
+	assert.NotContains(t, a, "s1")
+	assert.NotEqual(t, a, synthesisParamsDigest(map[string]any{"voiceType": "101016", "speed": 1.0, "appId": "1"}))
+}
@@ -0,0 +1,126 @@
+package synthesizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ssmlProviders 可以直接接收 SSML 的提供商；其余提供商收到 SSML 时降级为纯文本
+var ssmlProviders = map[TTSProvider]bool{
+	ProviderAzure:      true,
+	ProviderGoogle:     true,
+	ProviderAWS:        true,
+	ProviderVolcengine: true,
+}
+
+var (
+	ssmlSpeakRegex = regexp.MustCompile(`(?is)^\s*<speak(\s[^>]*)?>(.*)</speak>\s*$`)
+	ssmlBreakRegex = regexp.MustCompile(`(?i)<break(\s[^>]*)?/?>(\s*</break>)?`)
+	ssmlSubRegex   = regexp.MustCompile(`(?is)<sub\s[^>]*alias\s*=\s*["']([^"']*)["'][^>]*>.*?</sub>`)
+	ssmlTagRegex   = regexp.MustCompile(`<[^>]+>`)
+)
+
+// ProviderSupportsSSML 判断提供商是否支持 SSML
+func ProviderSupportsSSML(provider TTSProvider) bool {
+	return ssmlProviders[provider]
+}
+
+// SupportsSSML 判断合成服务是否会按 SSML 处理以 <speak> 开头的文本
+func SupportsSSML(svc SynthesisService) bool {
+	return ProviderSupportsSSML(svc.Provider())
+}
+
+// IsSSML 判断文本是否为 SSML 文档（以 <speak> 包裹）
+func IsSSML(text string) bool {
+	return ssmlSpeakRegex.MatchString(text)
+}
+
+// SSMLBody 返回 <speak> 元素内部的内容，文本不是 SSML 时返回转义后的文本
+func SSMLBody(text string) string {
+	if m := ssmlSpeakRegex.FindStringSubmatch(text); m != nil {
+		return strings.TrimSpace(m[2])
+	}
+	return EscapeSSML(text)
+}
+
+// StripSSML 将 SSML 降级为纯文本：停顿转为逗号，<sub> 使用别名，其余标签只保留文字
+func StripSSML(text string) string {
+	if !IsSSML(text) {
+		return text
+	}
+	body := SSMLBody(text)
+	body = ssmlSubRegex.ReplaceAllString(body, "$1")
+	body = ssmlBreakRegex.ReplaceAllString(body, "\x00")
+	body = html.UnescapeString(ssmlTagRegex.ReplaceAllString(body, ""))
+
+	var b strings.Builder
+	for i, part := range strings.Split(body, "\x00") {
+		part = strings.TrimSpace(part)
+		if i > 0 && b.Len() > 0 && part != "" {
+			last, _ := utf8.DecodeLastRuneInString(b.String())
+			next, _ := utf8.DecodeRuneInString(part)
+			if unicode.IsPunct(last) {
+				b.WriteString(" ")
+			} else if unicode.Is(unicode.Han, last) || unicode.Is(unicode.Han, next) {
+				b.WriteString("，")
+			} else {
+				b.WriteString(", ")
+			}
+		}
+		b.WriteString(part)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// EscapeSSML 转义文本中的 XML 特殊字符，用于拼接 SSML
+func EscapeSSML(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// WrapSSML 用 <speak> 包裹 SSML 片段，lang 为空时不设置 xml:lang
+func WrapSSML(body, lang string) string {
+	if lang == "" {
+		return "<speak>" + body + "</speak>"
+	}
+	return fmt.Sprintf(`<speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xml:lang="%s">%s</speak>`, EscapeSSML(lang), body)
+}
+
+// SSMLBreak 生成指定时长的停顿
+func SSMLBreak(d time.Duration) string {
+	return fmt.Sprintf(`<break time="%dms"/>`, d.Milliseconds())
+}
+
+// SSMLEmphasis 生成重读片段，level 可选 strong、moderate、reduced，为空时使用 moderate
+func SSMLEmphasis(text, level string) string {
+	if level == "" {
+		level = "moderate"
+	}
+	return fmt.Sprintf(`<emphasis level="%s">%s</emphasis>`, EscapeSSML(level), EscapeSSML(text))
+}
+
+// SSMLSayAs 指定文本的读法，interpretAs 如 cardinal、digits、telephone、date，format 用于日期（如 ymd），可为空
+func SSMLSayAs(text, interpretAs, format string) string {
+	if format == "" {
+		return fmt.Sprintf(`<say-as interpret-as="%s">%s</say-as>`, EscapeSSML(interpretAs), EscapeSSML(text))
+	}
+	return fmt.Sprintf(`<say-as interpret-as="%s" format="%s">%s</say-as>`, EscapeSSML(interpretAs), EscapeSSML(format), EscapeSSML(text))
+}
+
+// plainTextService 不支持 SSML 的提供商收到 SSML 时先降级为纯文本，避免把标签读出来
+type plainTextService struct {
+	SynthesisService
+}
+
+func (s *plainTextService) Synthesize(ctx context.Context, handler SynthesisHandler, text string) error {
+	return s.SynthesisService.Synthesize(ctx, handler, StripSSML(text))
+}
@@ -0,0 +1,66 @@
+package synthesizer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSSML(t *testing.T) {
+	assert.True(t, IsSSML("<speak>你好</speak>"))
+	assert.True(t, IsSSML(` <speak version="1.0" xml:lang="zh-CN">你好</speak>`+"\n"))
+	assert.False(t, IsSSML("你好"))
+	assert.False(t, IsSSML("<speaker>你好</speaker>"))
+	assert.False(t, IsSSML("<speak>未闭合"))
+}
+
+func TestStripSSML(t *testing.T) {
+	text := WrapSSML("您的订单号是"+SSMLSayAs("1024", "digits", "")+SSMLBreak(500*time.Millisecond)+
+		"请在"+SSMLSayAs("2026-10-15", "date", "ymd")+"前"+SSMLEmphasis("完成支付", "strong")+"。", "zh-CN")
+	assert.Equal(t, "您的订单号是1024，请在2026-10-15前完成支付。", StripSSML(text))
+
+	assert.Equal(t, "Hello, world", StripSSML(`<speak>Hello<break time="300ms"/>world</speak>`))
+	assert.Equal(t, "Welcome to the World Wide Web Consortium & friends",
+		StripSSML(`<speak>Welcome to the <sub alias="World Wide Web Consortium">W3C</sub> &amp; friends</speak>`))
+	// 非 SSML 文本原样返回
+	assert.Equal(t, "a < b", StripSSML("a < b"))
+}
+
+func TestSSMLBody(t *testing.T) {
+	assert.Equal(t, `你好<break time="200ms"/>`, SSMLBody(`<speak xml:lang="zh-CN">你好<break time="200ms"/></speak>`))
+	assert.Equal(t, "Tom &amp; Jerry &lt;3", SSMLBody("Tom & Jerry <3"))
+}
+
+func TestNewSynthesisService_SSMLFallback(t *testing.T) {
+	assert.True(t, ProviderSupportsSSML(ProviderAzure))
+	assert.False(t, ProviderSupportsSSML(ProviderOpenAI))
+
+	// 不支持 SSML 的提供商收到纯文本
+	inner := &textRecorder{}
+	svc := &plainTextService{SynthesisService: inner}
+	require.NoError(t, svc.Synthesize(context.Background(), &collectHandler{}, `<speak>你好<break time="1s"/>再见</speak>`))
+	assert.Equal(t, "你好，再见", inner.text)
+
+	openai, err := NewSynthesisService(TTS_OPENAI, map[string]any{"apiKey": "sk-test"})
+	require.NoError(t, err)
+	_, ok := openai.(*instrumentedService).SynthesisService.(*plainTextService)
+	assert.True(t, ok)
+	azure, err := NewSynthesisService(TTS_AZURE, map[string]any{"subscriptionKey": "k", "region": "eastus"})
+	require.NoError(t, err)
+	_, ok = azure.(*instrumentedService).SynthesisService.(*plainTextService)
+	assert.False(t, ok)
+}
+
+// textRecorder 记录收到的合成文本
+type textRecorder struct {
+	chunkedService
+	text string
+}
+
+func (r *textRecorder) Synthesize(ctx context.Context, handler SynthesisHandler, text string) error {
+	r.text = text
+	return nil
+}
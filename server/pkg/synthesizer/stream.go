@@ -0,0 +1,80 @@
+package synthesizer
+
+import (
+	"context"
+	"sync"
+)
+
+// streamChunkBuffer 缓冲的音频块数量；消费方按实时节奏播放，
+// 缓冲足够大时提供方的接收循环不会被播放节奏阻塞
+const streamChunkBuffer = 256
+
+// AudioStream 流式合成结果：提供方每产出一段音频就投递一段，
+// 调用方无需等整句合成完即可开始播放
+type AudioStream struct {
+	chunks chan []byte
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+	once   sync.Once
+}
+
+// SynthesizeStream 在后台调用 svc.Synthesize，把回调的音频块按到达顺序写入 Chunks。
+// 合成结束（成功、失败或取消）后 Chunks 关闭，随后可通过 Err 取得合成错误
+func SynthesizeStream(ctx context.Context, svc SynthesisService, text string) *AudioStream {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &AudioStream{
+		chunks: make(chan []byte, streamChunkBuffer),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		defer close(s.chunks)
+		s.err = svc.Synthesize(ctx, &streamHandler{ctx: ctx, chunks: s.chunks}, text)
+	}()
+	return s
+}
+
+// Chunks 返回音频块通道，合成结束后关闭
+func (s *AudioStream) Chunks() <-chan []byte {
+	return s.chunks
+}
+
+// Err 等待合成结束并返回其错误
+func (s *AudioStream) Err() error {
+	<-s.done
+	return s.err
+}
+
+// Close 取消尚未完成的合成并等待后台协程退出，可重复调用
+func (s *AudioStream) Close() {
+	s.once.Do(func() {
+		s.cancel()
+		// 排空通道，避免提供方阻塞在投递上
+		for range s.chunks {
+		}
+	})
+	<-s.done
+}
+
+// streamHandler 把合成回调转成通道投递
+type streamHandler struct {
+	ctx    context.Context
+	chunks chan<- []byte
+}
+
+func (h *streamHandler) OnMessage(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	// 提供方可能复用缓冲区，投递副本
+	chunk := append([]byte(nil), data...)
+	select {
+	case h.chunks <- chunk:
+	case <-h.ctx.Done():
+	}
+}
+
+func (h *streamHandler) OnTimestamp(timestamp SentenceTimestamp) {
+}
@@ -0,0 +1,92 @@
+package synthesizer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chunkedService 按固定间隔逐块回调，模拟流式返回音频的提供方
+type chunkedService struct {
+	chunks [][]byte
+	delay  time.Duration
+	err    error
+}
+
+func (s *chunkedService) Provider() TTSProvider       { return "test" }
+func (s *chunkedService) Format() media.StreamFormat  { return media.StreamFormat{SampleRate: 16000} }
+func (s *chunkedService) CacheKey(text string) string { return text }
+func (s *chunkedService) Close() error                { return nil }
+
+func (s *chunkedService) Synthesize(ctx context.Context, handler SynthesisHandler, text string) error {
+	buf := make([]byte, 4)
+	for _, chunk := range s.chunks {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.delay):
+		}
+		// 复用缓冲区，流式结果必须是副本
+		buf = append(buf[:0], chunk...)
+		handler.OnMessage(buf)
+	}
+	return s.err
+}
+
+func TestSynthesizeStream(t *testing.T) {
+	svc := &chunkedService{chunks: [][]byte{{1, 2}, {}, {3, 4}, {5}}, delay: time.Millisecond}
+	stream := SynthesizeStream(context.Background(), svc, "hello")
+
+	var got [][]byte
+	for chunk := range stream.Chunks() {
+		got = append(got, chunk)
+	}
+	require.NoError(t, stream.Err())
+	// 空块不投递
+	assert.Equal(t, [][]byte{{1, 2}, {3, 4}, {5}}, got)
+
+	failing := &chunkedService{chunks: [][]byte{{1}}, err: errors.New("boom")}
+	stream = SynthesizeStream(context.Background(), failing, "hello")
+	for range stream.Chunks() {
+	}
+	assert.EqualError(t, stream.Err(), "boom")
+}
+
+func TestSynthesizeStreamFirstChunkBeforeCompletion(t *testing.T) {
+	svc := &chunkedService{chunks: [][]byte{{1}, {2}, {3}}, delay: 50 * time.Millisecond}
+	start := time.Now()
+	stream := SynthesizeStream(context.Background(), svc, "hello")
+	defer stream.Close()
+
+	<-stream.Chunks()
+	// 首块在整句合成完成前到达
+	assert.Less(t, time.Since(start), 120*time.Millisecond)
+}
+
+func TestSynthesizeStreamClose(t *testing.T) {
+	chunks := make([][]byte, streamChunkBuffer*2)
+	for i := range chunks {
+		chunks[i] = []byte{byte(i)}
+	}
+	stream := SynthesizeStream(context.Background(), &chunkedService{chunks: chunks}, "hello")
+	<-stream.Chunks()
+
+	// 消费方提前放弃时，Close 取消合成且不会阻塞
+	done := make(chan struct{})
+	go func() {
+		stream.Close()
+		stream.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close blocked")
+	}
+	assert.ErrorIs(t, stream.Err(), context.Canceled)
+}
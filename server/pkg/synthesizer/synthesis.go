@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/code-100-precent/LingEcho/pkg/media"
 	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	"github.com/code-100-precent/LingEcho/pkg/media/fileio"
 	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -195,7 +197,7 @@ func WithSynthesis(svc SynthesisService) media.MediaHandlerFunc {
 			req.Req.OnMessage(make([]byte, 0))
 		} else {
 			cacheKey := req.Req.svc.CacheKey(req.Req.packet.Text)
-			data, err := media.MediaCache().Get(cacheKey)
+			data, err := loadCachedAudio(cacheKey, svc.Format())
 			if err == nil {
 				logrus.WithFields(logrus.Fields{
 					"handler":  h,
@@ -220,7 +222,7 @@ func WithSynthesis(svc SynthesisService) media.MediaHandlerFunc {
 					return err
 				}
 				if len(req.Req.result) > 0 && cacheKey != "" {
-					media.MediaCache().Store(cacheKey, req.Req.result)
+					storeCachedAudio(cacheKey, req.Req.result, svc.Format())
 				}
 			}
 		}
@@ -482,7 +484,20 @@ func (s *SynthesisBuffer) OnTimestamp(timestamp SentenceTimestamp) {
 	s.Timestamp = timestamp
 }
 
+// NewSynthesisService 按名称创建 TTS 服务，Synthesize 的耗时与错误会上报到全局监控；
+// 不支持 SSML 的提供商收到 SSML 时自动降级为纯文本
 func NewSynthesisService(name string, options map[string]any) (SynthesisService, error) {
+	svc, err := newSynthesisService(name, options)
+	if err != nil {
+		return nil, err
+	}
+	if !SupportsSSML(svc) {
+		svc = &plainTextService{SynthesisService: svc}
+	}
+	return &instrumentedService{SynthesisService: svc, params: synthesisParamsDigest(options)}, nil
+}
+
+func newSynthesisService(name string, options map[string]any) (SynthesisService, error) {
 	switch name {
 	case TTS_QCLOUD:
 		opt := media.CastOption[QCloudTTSConfig](options)
@@ -520,6 +535,9 @@ func NewSynthesisService(name string, options map[string]any) (SynthesisService,
 	case TTS_COQUI:
 		opt := media.CastOption[CoquiTTSOption](options)
 		return NewCoquiService(opt), nil
+	case TTS_PIPER:
+		opt := media.CastOption[PiperTTSConfig](options)
+		return NewPiperService(opt), nil
 	case TTS_VOLCENGINE:
 		opt := media.CastOption[VolcengineTTSOption](options)
 		return NewVolcengineService(opt), nil
@@ -565,12 +583,60 @@ func (c TTSCredentialConfig) getInt64(key string) int64 {
 	return 0
 }
 
-// NewSynthesisServiceFromCredential 根据凭证配置创建TTS服务
+// getFloat64 从配置中获取 float64 值
+func (c TTSCredentialConfig) getFloat64(key string) float64 {
+	if val, ok := c[key]; ok {
+		switch v := val.(type) {
+		case float64:
+			return v
+		case int64:
+			return float64(v)
+		case int:
+			return float64(v)
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f
+			}
+		}
+	}
+	return 0
+}
+
+// NewSynthesisServiceFromCredential 根据凭证配置创建TTS服务。
+// 配置了 fallbacks 时，主提供商不可用会按顺序切换到备用提供商
 func NewSynthesisServiceFromCredential(config TTSCredentialConfig) (SynthesisService, error) {
 	if config == nil || len(config) == 0 {
 		return nil, fmt.Errorf("TTS配置为空")
 	}
 
+	fallbacks := fallbackConfigs(config)
+	primary := config
+	if _, ok := config[FallbacksKey]; ok {
+		primary = make(TTSCredentialConfig, len(config))
+		for k, v := range config {
+			if k != FallbacksKey {
+				primary[k] = v
+			}
+		}
+	}
+	svc, err := newSynthesisServiceFromCredential(primary)
+	if err != nil {
+		return nil, err
+	}
+	targets := []failoverTarget{{svc: svc, health: healthFor(primary)}}
+	for _, fallback := range fallbacks {
+		fallbackSvc, err := newSynthesisServiceFromCredential(fallback)
+		if err != nil {
+			logrus.WithError(err).WithField("provider", fallback.getString("provider")).Warn("synthesis: skip invalid fallback provider")
+			continue
+		}
+		targets = append(targets, failoverTarget{svc: fallbackSvc, health: healthFor(fallback)})
+	}
+	return newFailoverService(targets), nil
+}
+
+func newSynthesisServiceFromCredential(config TTSCredentialConfig) (SynthesisService, error) {
+
 	provider := strings.ToLower(strings.TrimSpace(config.getString("provider")))
 	if provider == "" {
 		return nil, fmt.Errorf("TTS provider 未配置")
@@ -1186,8 +1252,25 @@ func NewSynthesisServiceFromCredential(config TTSCredentialConfig) (SynthesisSer
 
 	case "coqui":
 		url := config.getString("url")
+		model := config.getString("model")
+		if url == "" && model == "" {
+			return nil, fmt.Errorf("Coqui TTS配置不完整：缺少url（服务模式）或model（本地命令行模式）")
+		}
+		// 本地命令行模式的命令与模型目录由服务器配置，凭证中的 command 不再使用
+		var command string
 		if url == "" {
-			return nil, fmt.Errorf("Coqui TTS配置不完整：缺少url")
+			dir := utils.GetEnv(EnvCoquiModelDir)
+			if dir == "" {
+				return nil, fmt.Errorf("Coqui TTS本地命令行模式未启用：服务器未配置 %s", EnvCoquiModelDir)
+			}
+			command = utils.GetEnv(EnvCoquiCommand)
+			if !coquiModelName.MatchString(model) {
+				path, err := utils.ResolvePathInDir(dir, model)
+				if err != nil {
+					return nil, fmt.Errorf("Coqui TTS模型无效: %w", err)
+				}
+				model = path
+			}
 		}
 		providerName = TTS_COQUI
 		language := config.getString("language")
@@ -1195,8 +1278,8 @@ func NewSynthesisServiceFromCredential(config TTSCredentialConfig) (SynthesisSer
 			language = "en_US" // 默认值
 		}
 		speaker := config.getString("speaker")
-		if speaker == "" {
-			speaker = "p226" // 默认值
+		if speaker == "" && url != "" {
+			speaker = "p226" // 默认值，本地模型多为单说话人，不设置
 		}
 		sampleRate := config.getInt64("sampleRate")
 		if sampleRate == 0 {
@@ -1222,6 +1305,8 @@ func NewSynthesisServiceFromCredential(config TTSCredentialConfig) (SynthesisSer
 		coquiConfig.SampleRate = int(sampleRate)
 		coquiConfig.Channels = int(channels)
 		coquiConfig.BitDepth = int(bitDepth)
+		coquiConfig.Command = command
+		coquiConfig.Model = model
 		// 将配置对象转换为 map[string]any
 		configBytes, err := json.Marshal(coquiConfig)
 		if err != nil {
@@ -1232,6 +1317,56 @@ func NewSynthesisServiceFromCredential(config TTSCredentialConfig) (SynthesisSer
 			return nil, fmt.Errorf("反序列化Coqui配置失败: %w", err)
 		}
 
+	case "piper":
+		model := config.getString("model")
+		if model == "" {
+			return nil, fmt.Errorf("Piper TTS配置不完整：缺少model")
+		}
+		// 命令与模型目录由服务器配置，凭证只能选择目录内的模型，凭证中的 command 不再使用
+		dir := utils.GetEnv(EnvPiperModelDir)
+		if dir == "" {
+			return nil, fmt.Errorf("Piper TTS未启用：服务器未配置 %s", EnvPiperModelDir)
+		}
+		modelPath, err := utils.ResolvePathInDir(dir, model)
+		if err != nil {
+			return nil, fmt.Errorf("Piper TTS模型无效: %w", err)
+		}
+		providerName = TTS_PIPER
+		piperConfig := NewPiperTTSConfig(modelPath)
+		if command := utils.GetEnv(EnvPiperCommand); command != "" {
+			piperConfig.Command = command
+		}
+		modelConfig := config.getString("modelConfig")
+		if modelConfig == "" {
+			modelConfig = config.getString("model_config") // 兼容下划线格式
+		}
+		if modelConfig != "" {
+			if piperConfig.ModelConfig, err = utils.ResolvePathInDir(dir, modelConfig); err != nil {
+				return nil, fmt.Errorf("Piper TTS模型配置无效: %w", err)
+			}
+		}
+		piperConfig.Speaker = int(config.getInt64("speaker"))
+		piperConfig.LengthScale = config.getFloat64("lengthScale")
+		if piperConfig.LengthScale == 0 {
+			piperConfig.LengthScale = config.getFloat64("length_scale") // 兼容下划线格式
+		}
+		piperConfig.NoiseScale = config.getFloat64("noiseScale")
+		piperConfig.NoiseW = config.getFloat64("noiseW")
+		piperConfig.SentenceSilence = config.getFloat64("sentenceSilence")
+		piperConfig.SampleRate = int(config.getInt64("sampleRate"))
+		if piperConfig.SampleRate == 0 {
+			piperConfig.SampleRate = int(config.getInt64("sample_rate")) // 兼容下划线格式
+		}
+		// 将配置对象转换为 map[string]any
+		configBytes, err := json.Marshal(piperConfig)
+		if err != nil {
+			return nil, fmt.Errorf("序列化Piper配置失败: %w", err)
+		}
+		options = make(map[string]any)
+		if err := json.Unmarshal(configBytes, &options); err != nil {
+			return nil, fmt.Errorf("反序列化Piper配置失败: %w", err)
+		}
+
 	default:
 		return nil, fmt.Errorf("不支持的TTS provider: %s", provider)
 	}
@@ -1239,3 +1374,34 @@ func NewSynthesisServiceFromCredential(config TTSCredentialConfig) (SynthesisSer
 	// 使用工厂方法创建服务
 	return NewSynthesisService(providerName, options)
 }
+
+// cacheFormat 返回合成音频缓存使用的 WAV 格式
+func cacheFormat(format media.StreamFormat) fileio.Format {
+	channels := format.Channels
+	if channels == 0 {
+		channels = 1
+	}
+	return fileio.Format{SampleRate: format.SampleRate, Channels: channels}
+}
+
+// storeCachedAudio 以 WAV 格式缓存合成结果，文件自带采样率和声道数
+func storeCachedAudio(cacheKey string, pcm []byte, format media.StreamFormat) {
+	media.MediaCache().Store(cacheKey, fileio.EncodeWAV(pcm, cacheFormat(format)))
+}
+
+// loadCachedAudio 读取缓存的合成音频并返回 PCM
+// 采样率或声道数与当前服务不一致时视为未命中；兼容旧版直接缓存的裸 PCM
+func loadCachedAudio(cacheKey string, format media.StreamFormat) ([]byte, error) {
+	data, err := media.MediaCache().Get(cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	pcm, cached, err := fileio.DecodeWAV(data)
+	if err != nil {
+		return data, nil
+	}
+	if cached != cacheFormat(format) {
+		return nil, os.ErrNotExist
+	}
+	return pcm, nil
+}
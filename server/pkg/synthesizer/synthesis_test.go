@@ -0,0 +1,38 @@
+package synthesizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedAudio(t *testing.T) {
+	cache := media.MediaCache()
+	if cache.Disabled {
+		t.Skip("media cache disabled")
+	}
+	key := cache.BuildKey(t.Name())
+	defer os.Remove(filepath.Join(cache.CacheRoot, key))
+
+	format := media.StreamFormat{SampleRate: 16000, BitDepth: 16}
+	pcm := []byte{1, 2, 3, 4, 5, 6}
+	storeCachedAudio(key, pcm, format)
+
+	got, err := loadCachedAudio(key, format)
+	require.NoError(t, err)
+	assert.Equal(t, pcm, got)
+
+	// 采样率变化后旧缓存不可用
+	_, err = loadCachedAudio(key, media.StreamFormat{SampleRate: 8000})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// 旧版缓存的裸 PCM 原样返回
+	require.NoError(t, cache.Store(key, pcm))
+	got, err = loadCachedAudio(key, format)
+	require.NoError(t, err)
+	assert.Equal(t, pcm, got)
+}
@@ -13,6 +13,7 @@ const (
 	TTS_LOCAL             = "tts.local"
 	TTS_FISHSPEECH        = "tts.fishspeech"
 	TTS_COQUI             = "tts.coqui"
+	TTS_PIPER             = "tts.piper"
 	TTS_VOLCENGINE        = "tts.volcengine"
 	TTS_VOLCENGINE_CLONE  = "tts.volcengine_clone"
 	TTS_VOLCENGINE_LLM    = "tts.volcengine_llm"
@@ -50,6 +51,8 @@ const (
 	ProviderFishSpeech TTSProvider = "fishspeech"
 	// ProviderCoqui Coqui TTS
 	ProviderCoqui TTSProvider = "coqui"
+	// ProviderPiper Piper 离线TTS
+	ProviderPiper TTSProvider = "piper"
 	// ProviderVolcengine 火山引擎标准TTS
 	ProviderVolcengine TTSProvider = "volcengine"
 	// ProviderVolcengineClone 火山引擎音色克隆TTS
@@ -198,7 +198,7 @@ func (v *volcengineSpeechSynthesisListener) sendRequest(ctx context.Context, opt
 	params["request"] = make(map[string]interface{})
 	params["request"]["reqid"] = reqID
 	params["request"]["text"] = text
-	if strings.HasPrefix(text, SsmlSpeak) {
+	if IsSSML(text) {
 		params["request"]["text_type"] = "ssml"
 	} else {
 		params["request"]["text_type"] = "plain"
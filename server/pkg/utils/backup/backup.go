@@ -1,12 +1,14 @@
 package backup
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/config"
@@ -15,9 +17,13 @@ import (
 	"go.uber.org/zap"
 )
 
+// scheduler is the running backup cron, stopped by StopBackupScheduler
+var scheduler *cron.Cron
+
 // StartBackupScheduler starts the backup scheduler
 func StartBackupScheduler() {
 	c := cron.New()
+	scheduler = c
 
 	// Use Cron expression from configuration
 	schedule := config.GlobalConfig.BackupSchedule
@@ -36,20 +42,128 @@ func StartBackupScheduler() {
 	c.Start()
 }
 
-// ExecuteBackup executes database backup according to configuration
+// StopBackupScheduler stops the backup scheduler and waits for a running
+// backup to finish, or returns ctx's error if it outlives ctx
+func StopBackupScheduler(ctx context.Context) error {
+	if scheduler == nil {
+		return nil
+	}
+	select {
+	case <-scheduler.Stop().Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ExecuteBackup executes database backup according to configuration.
+// The dump is encrypted when BACKUP_ENCRYPTION_KEY is set, copied to S3-compatible
+// storage when BACKUP_S3_ENDPOINT is set, and old backups are pruned by the retention policy.
 func ExecuteBackup() error {
-	switch config.GlobalConfig.DBDriver {
+	cfg := config.GlobalConfig
+	name := backupFilePrefix + time.Now().Format(backupTimeLayout)
+	var dst string
+	var err error
+	switch cfg.DBDriver {
 	case "sqlite":
 		// Execute SQLite backup
-		dst := filepath.Join(config.GlobalConfig.BackupPath, fmt.Sprintf("sys_backup_%s.db", time.Now().Format("20060102_150405")))
-		return BackupSQLiteDatabase(config.GlobalConfig.DSN, dst)
+		dst = filepath.Join(cfg.BackupPath, name+".db")
+		err = BackupSQLiteDatabase(SQLiteFilePath(cfg.DSN), dst)
 	case "mysql":
 		// Execute MySQL backup
-		dst := filepath.Join(config.GlobalConfig.BackupPath, fmt.Sprintf("sys_backup_%s.sql", time.Now().Format("20060102_150405")))
-		return BackupMySQLDatabase(config.GlobalConfig.DSN, dst)
+		dst = filepath.Join(cfg.BackupPath, name+".sql")
+		err = BackupMySQLDatabase(cfg.DSN, dst)
 	default:
-		return fmt.Errorf("unsupported DB_DRIVER: %s", config.GlobalConfig.DBDriver)
+		return fmt.Errorf("unsupported DB_DRIVER: %s", cfg.DBDriver)
+	}
+	if err != nil {
+		return err
+	}
+	return PublishBackup(context.Background(), cfg, dst)
+}
+
+// PublishBackup encrypts the local backup file if configured, uploads it to the
+// remote destination and applies the retention policy to every destination.
+func PublishBackup(ctx context.Context, cfg *config.Config, file string) error {
+	if cfg.BackupEncryptionKey != "" {
+		encrypted, err := EncryptFile(file, cfg.BackupEncryptionKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt backup: %v", err)
+		}
+		file = encrypted
+	}
+
+	destinations := []Destination{LocalDestinationFromConfig(cfg)}
+	if remote := RemoteDestinationFromConfig(cfg); remote != nil {
+		if err := uploadFile(ctx, remote, file); err != nil {
+			return fmt.Errorf("failed to upload backup to %s: %v", remote.Name(), err)
+		}
+		log.Printf("Backup uploaded to %s: %s", remote.Name(), filepath.Base(file))
+		destinations = append(destinations, remote)
+	}
+
+	policy := RetentionFromConfig(cfg)
+	for _, dest := range destinations {
+		removed, err := ApplyRetention(ctx, dest, policy, time.Now())
+		if err != nil {
+			log.Printf("Backup retention failed on %s: %v", dest.Name(), err)
+		}
+		if len(removed) > 0 {
+			log.Printf("Expired backups removed from %s: %s", dest.Name(), strings.Join(removed, ", "))
+		}
+	}
+	return nil
+}
+
+// EncryptFile encrypts src into src+".enc" and removes the plaintext file
+func EncryptFile(src, passphrase string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	dst := src + EncryptedExt
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	if err := EncryptStream(out, in, passphrase); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	in.Close()
+	if err := os.Remove(src); err != nil {
+		log.Printf("Failed to remove plaintext backup %s: %v", src, err)
+	}
+	return dst, nil
+}
+
+func uploadFile(ctx context.Context, dest Destination, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return dest.Upload(ctx, filepath.Base(file), f, info.Size())
+}
+
+// SQLiteFilePath strips the "file:" scheme and query parameters from a SQLite DSN
+func SQLiteFilePath(dsn string) string {
+	p := strings.TrimPrefix(dsn, "file:")
+	if i := strings.Index(p, "?"); i >= 0 {
+		p = p[:i]
 	}
+	return p
 }
 
 // BackupSQLiteDatabase performs backup of SQLite database
@@ -98,12 +212,24 @@ func BackupMySQLDatabase(dsn, dst string) error {
 		}
 	}
 
+	conn, err := ParseMySQLDSN(dsn)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("error creating destination file: %v", err)
+	}
+	defer out.Close()
+
 	// Use mysqldump to perform backup
-	cmd := exec.Command("mysqldump", dsn, ">", dst)
-	cmd.Stdout = os.Stdout
+	cmd := exec.Command("mysqldump", append(conn.Args(), "--single-transaction", "--routines", conn.Database)...)
+	cmd.Env = append(os.Environ(), "MYSQL_PWD="+conn.Password)
+	cmd.Stdout = out
 	cmd.Stderr = os.Stderr
-	err := cmd.Run()
-	if err != nil {
+	if err := cmd.Run(); err != nil {
+		os.Remove(dst)
 		return fmt.Errorf("failed to backup MySQL database: %v", err)
 	}
 
@@ -0,0 +1,180 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EncryptedExt is appended to the name of encrypted backups
+const EncryptedExt = ".enc"
+
+// Encrypted backup layout:
+//
+//	magic(8) | salt(16) | noncePrefix(8) | chunks...
+//
+// Each chunk is length(4) | AES-256-GCM sealed data. The nonce is the prefix plus a
+// chunk counter, and the final chunk is sealed with a different additional data so
+// truncated files fail to decrypt.
+var encryptionMagic = []byte("LEBKENC1")
+
+const (
+	encryptionSaltSize   = 16
+	encryptionPrefixSize = 8
+	encryptionChunkSize  = 64 * 1024
+	encryptionKDFRounds  = 200000
+)
+
+var (
+	adChunk = []byte("chunk")
+	adFinal = []byte("final")
+)
+
+// ErrNotEncrypted is returned when decrypting data without the backup header
+var ErrNotEncrypted = errors.New("backup is not encrypted")
+
+// IsEncryptedName reports whether the backup name denotes an encrypted backup
+func IsEncryptedName(name string) bool {
+	return strings.HasSuffix(name, EncryptedExt)
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, encryptionKDFRounds, 32)
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("backup encryption key is empty")
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encryptionPrefixSize:], counter)
+	return nonce
+}
+
+// EncryptStream encrypts src into dst with a key derived from passphrase
+func EncryptStream(dst io.Writer, src io.Reader, passphrase string) error {
+	salt := make([]byte, encryptionSaltSize)
+	prefix := make([]byte, encryptionPrefixSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	header := append(append(append([]byte{}, encryptionMagic...), salt...), prefix...)
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	buf := make([]byte, encryptionChunkSize)
+	next := make([]byte, encryptionChunkSize)
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	var counter uint32
+	for {
+		// Read ahead so we know whether the current chunk is the last one
+		m, readErr := io.ReadFull(src, next)
+		if readErr != nil && readErr != io.ErrUnexpectedEOF && readErr != io.EOF {
+			return readErr
+		}
+		final := m == 0
+		ad := adChunk
+		if final {
+			ad = adFinal
+		}
+		sealed := gcm.Seal(nil, chunkNonce(prefix, counter), buf[:n], ad)
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(sealed)))
+		if _, err := dst.Write(length[:]); err != nil {
+			return err
+		}
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+		counter++
+		buf, next = next, buf
+		n = m
+	}
+}
+
+// DecryptStream decrypts data produced by EncryptStream
+func DecryptStream(dst io.Writer, src io.Reader, passphrase string) error {
+	magic := make([]byte, len(encryptionMagic))
+	if _, err := io.ReadFull(src, magic); err != nil || !bytes.Equal(magic, encryptionMagic) {
+		return ErrNotEncrypted
+	}
+	header := make([]byte, encryptionSaltSize+encryptionPrefixSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return fmt.Errorf("failed to read backup header: %w", err)
+	}
+	salt, prefix := header[:encryptionSaltSize], header[encryptionSaltSize:]
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	maxSealed := uint32(encryptionChunkSize + gcm.Overhead())
+	var counter uint32
+	for {
+		var length [4]byte
+		if _, err := io.ReadFull(src, length[:]); err != nil {
+			return fmt.Errorf("backup is truncated: %w", err)
+		}
+		size := binary.BigEndian.Uint32(length[:])
+		if size > maxSealed {
+			return fmt.Errorf("backup chunk %d is corrupt", counter)
+		}
+		sealed := make([]byte, size)
+		if _, err := io.ReadFull(src, sealed); err != nil {
+			return fmt.Errorf("backup is truncated: %w", err)
+		}
+
+		nonce := chunkNonce(prefix, counter)
+		plain, err := gcm.Open(nil, nonce, sealed, adChunk)
+		final := false
+		if err != nil {
+			if plain, err = gcm.Open(nil, nonce, sealed, adFinal); err != nil {
+				return fmt.Errorf("failed to decrypt backup (wrong key or corrupt data): %w", err)
+			}
+			final = true
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+		counter++
+	}
+}
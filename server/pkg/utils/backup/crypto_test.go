@@ -0,0 +1,68 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestEncryptDecryptStream(t *testing.T) {
+	sizes := []int{0, 1, encryptionChunkSize - 1, encryptionChunkSize, encryptionChunkSize*3 + 17}
+	for _, size := range sizes {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		var encrypted bytes.Buffer
+		if err := EncryptStream(&encrypted, bytes.NewReader(plain), "secret"); err != nil {
+			t.Fatalf("EncryptStream(%d) error: %v", size, err)
+		}
+		// Short plaintexts can occur in random ciphertext by chance
+		if size >= 16 && bytes.Contains(encrypted.Bytes(), plain) {
+			t.Fatalf("EncryptStream(%d) output contains plaintext", size)
+		}
+
+		var decrypted bytes.Buffer
+		if err := DecryptStream(&decrypted, bytes.NewReader(encrypted.Bytes()), "secret"); err != nil {
+			t.Fatalf("DecryptStream(%d) error: %v", size, err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), plain) {
+			t.Fatalf("DecryptStream(%d) content mismatch", size)
+		}
+	}
+}
+
+func TestDecryptStream_WrongKey(t *testing.T) {
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader([]byte("database")), "secret"); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+	var out bytes.Buffer
+	if err := DecryptStream(&out, bytes.NewReader(encrypted.Bytes()), "other"); err == nil {
+		t.Fatalf("DecryptStream expected error for wrong key")
+	}
+}
+
+func TestDecryptStream_Truncated(t *testing.T) {
+	plain := make([]byte, encryptionChunkSize*2+5)
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader(plain), "secret"); err != nil {
+		t.Fatalf("EncryptStream error: %v", err)
+	}
+
+	// Drop the final chunk: the remaining chunks are intact but the file must be rejected
+	data := encrypted.Bytes()
+	lastChunk := 4 + 5 + 16 // length prefix + 5 bytes of data + GCM tag
+	var out bytes.Buffer
+	if err := DecryptStream(&out, bytes.NewReader(data[:len(data)-lastChunk]), "secret"); err == nil {
+		t.Fatalf("DecryptStream expected error for truncated backup")
+	}
+}
+
+func TestDecryptStream_NotEncrypted(t *testing.T) {
+	var out bytes.Buffer
+	err := DecryptStream(&out, bytes.NewReader([]byte("SQLite format 3\x00 and more data")), "secret")
+	if !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("DecryptStream error = %v, want ErrNotEncrypted", err)
+	}
+}
@@ -0,0 +1,251 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// backupFilePrefix is the common prefix of all backup files
+const backupFilePrefix = "sys_backup_"
+
+// backupTimeLayout is the timestamp layout embedded in backup names
+const backupTimeLayout = "20060102_150405"
+
+// Object describes a stored backup
+type Object struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Destination stores backup files, e.g. a local directory or an S3-compatible bucket
+type Destination interface {
+	Name() string
+	Upload(ctx context.Context, name string, r io.Reader, size int64) error
+	Download(ctx context.Context, name string, w io.Writer) error
+	List(ctx context.Context) ([]Object, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// backupCreatedAt parses the timestamp in a backup name, falling back to fallback
+func backupCreatedAt(name string, fallback time.Time) time.Time {
+	stamp := strings.TrimPrefix(name, backupFilePrefix)
+	if len(stamp) >= len(backupTimeLayout) {
+		if t, err := time.ParseInLocation(backupTimeLayout, stamp[:len(backupTimeLayout)], time.Local); err == nil {
+			return t
+		}
+	}
+	return fallback
+}
+
+// sortObjects orders backups from newest to oldest
+func sortObjects(objects []Object) {
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].CreatedAt.After(objects[j].CreatedAt)
+	})
+}
+
+// LocalDestination keeps backups in a local directory
+type LocalDestination struct {
+	Dir string
+}
+
+func (d *LocalDestination) Name() string {
+	return "local"
+}
+
+func (d *LocalDestination) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) {
+		return "", fmt.Errorf("invalid backup name: %q", name)
+	}
+	return filepath.Join(d.Dir, name), nil
+}
+
+func (d *LocalDestination) Upload(ctx context.Context, name string, r io.Reader, size int64) error {
+	dst, err := d.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.Dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create backup directory: %v", err)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (d *LocalDestination) Download(ctx context.Context, name string, w io.Writer) error {
+	src, err := d.path(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (d *LocalDestination) List(ctx context.Context) ([]Object, error) {
+	entries, err := os.ReadDir(d.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var objects []Object
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), backupFilePrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		objects = append(objects, Object{
+			Name:      entry.Name(),
+			Size:      info.Size(),
+			CreatedAt: backupCreatedAt(entry.Name(), info.ModTime()),
+		})
+	}
+	sortObjects(objects)
+	return objects, nil
+}
+
+func (d *LocalDestination) Delete(ctx context.Context, name string) error {
+	p, err := d.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+// S3Destination stores backups in an S3-compatible bucket (AWS S3, MinIO, OSS, COS ...)
+type S3Destination struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	Prefix    string
+	UseSSL    bool
+}
+
+func (d *S3Destination) Name() string {
+	return "s3"
+}
+
+func (d *S3Destination) client() (*minio.Client, error) {
+	return minio.New(d.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(d.AccessKey, d.SecretKey, ""),
+		Secure: d.UseSSL,
+		Region: d.Region,
+	})
+}
+
+func (d *S3Destination) key(name string) string {
+	return path.Join(d.Prefix, name)
+}
+
+func (d *S3Destination) Upload(ctx context.Context, name string, r io.Reader, size int64) error {
+	cli, err := d.client()
+	if err != nil {
+		return err
+	}
+	exists, err := cli.BucketExists(ctx, d.Bucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := cli.MakeBucket(ctx, d.Bucket, minio.MakeBucketOptions{Region: d.Region}); err != nil {
+			return err
+		}
+	}
+	_, err = cli.PutObject(ctx, d.Bucket, d.key(name), r, size, minio.PutObjectOptions{ContentType: "application/octet-stream"})
+	return err
+}
+
+func (d *S3Destination) Download(ctx context.Context, name string, w io.Writer) error {
+	cli, err := d.client()
+	if err != nil {
+		return err
+	}
+	obj, err := cli.GetObject(ctx, d.Bucket, d.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	_, err = io.Copy(w, obj)
+	return err
+}
+
+func (d *S3Destination) List(ctx context.Context) ([]Object, error) {
+	cli, err := d.client()
+	if err != nil {
+		return nil, err
+	}
+	prefix := d.key(backupFilePrefix)
+	var objects []Object
+	for info := range cli.ListObjects(ctx, d.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		name := path.Base(info.Key)
+		objects = append(objects, Object{
+			Name:      name,
+			Size:      info.Size,
+			CreatedAt: backupCreatedAt(name, info.LastModified),
+		})
+	}
+	sortObjects(objects)
+	return objects, nil
+}
+
+func (d *S3Destination) Delete(ctx context.Context, name string) error {
+	cli, err := d.client()
+	if err != nil {
+		return err
+	}
+	return cli.RemoveObject(ctx, d.Bucket, d.key(name), minio.RemoveObjectOptions{})
+}
+
+// LocalDestinationFromConfig returns the local backup directory destination
+func LocalDestinationFromConfig(cfg *config.Config) *LocalDestination {
+	return &LocalDestination{Dir: cfg.BackupPath}
+}
+
+// RemoteDestinationFromConfig returns the configured S3 destination, or nil when not configured
+func RemoteDestinationFromConfig(cfg *config.Config) Destination {
+	if cfg.BackupS3Endpoint == "" || cfg.BackupS3Bucket == "" {
+		return nil
+	}
+	return &S3Destination{
+		Endpoint:  cfg.BackupS3Endpoint,
+		AccessKey: cfg.BackupS3AccessKey,
+		SecretKey: cfg.BackupS3SecretKey,
+		Bucket:    cfg.BackupS3Bucket,
+		Region:    cfg.BackupS3Region,
+		Prefix:    cfg.BackupS3Prefix,
+		UseSSL:    cfg.BackupS3UseSSL,
+	}
+}
@@ -0,0 +1,362 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+)
+
+// sqliteHeader is the magic string at the start of every SQLite database file
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// ErrInvalidBackup is returned when a backup fails validation
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// RestoreOptions describes a restore operation
+type RestoreOptions struct {
+	Source        Destination // where the backup is read from
+	Name          string      // backup name, "latest" picks the newest one
+	Driver        string      // sqlite or mysql
+	DSN           string      // target database
+	EncryptionKey string      // required for encrypted backups
+	DryRun        bool        // only download and validate
+}
+
+// RestoreResult reports what was restored
+type RestoreResult struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	SafetyCopy string `json:"safetyCopy,omitempty"` // previous SQLite database, kept for manual rollback
+}
+
+// MySQLConn holds the connection settings needed by mysqldump/mysql
+type MySQLConn struct {
+	User     string
+	Password string
+	Host     string
+	Port     string
+	Socket   string
+	Database string
+}
+
+// ParseMySQLDSN parses a go-sql-driver DSN like user:pass@tcp(host:3306)/db?charset=utf8mb4
+func ParseMySQLDSN(dsn string) (*MySQLConn, error) {
+	conn := &MySQLConn{}
+	at := strings.LastIndex(dsn, "@")
+	rest := dsn
+	if at >= 0 {
+		cred := dsn[:at]
+		rest = dsn[at+1:]
+		if i := strings.Index(cred, ":"); i >= 0 {
+			conn.User, conn.Password = cred[:i], cred[i+1:]
+		} else {
+			conn.User = cred
+		}
+	}
+	// The address may contain slashes, e.g. unix(/var/run/mysqld.sock)
+	searchFrom := 0
+	if i := strings.Index(rest, ")"); i >= 0 {
+		searchFrom = i
+	}
+	slash := strings.Index(rest[searchFrom:], "/")
+	if slash >= 0 {
+		slash += searchFrom
+	}
+	if slash < 0 {
+		return nil, fmt.Errorf("invalid MySQL DSN: missing database name")
+	}
+	addr, db := rest[:slash], rest[slash+1:]
+	if i := strings.Index(db, "?"); i >= 0 {
+		db = db[:i]
+	}
+	if db == "" {
+		return nil, fmt.Errorf("invalid MySQL DSN: missing database name")
+	}
+	conn.Database = db
+
+	if open := strings.Index(addr, "("); open >= 0 && strings.HasSuffix(addr, ")") {
+		protocol, target := addr[:open], addr[open+1:len(addr)-1]
+		if protocol == "unix" {
+			conn.Socket = target
+			return conn, nil
+		}
+		addr = target
+	}
+	if addr == "" {
+		addr = "127.0.0.1:3306"
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, "3306"
+	}
+	conn.Host, conn.Port = host, port
+	return conn, nil
+}
+
+// Args returns the connection flags for the mysql command line tools.
+// The password is passed through MYSQL_PWD instead of the command line.
+func (c *MySQLConn) Args() []string {
+	args := []string{}
+	if c.User != "" {
+		args = append(args, "-u", c.User)
+	}
+	if c.Socket != "" {
+		return append(args, "--socket", c.Socket)
+	}
+	return append(args, "-h", c.Host, "-P", c.Port)
+}
+
+// ValidateBackup checks that a decrypted backup file is usable for the given driver
+func ValidateBackup(file, driver string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	head := make([]byte, 4096)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	head = head[:n]
+	if n == 0 {
+		return fmt.Errorf("%w: file is empty", ErrInvalidBackup)
+	}
+
+	switch driver {
+	case "sqlite":
+		if !bytes.HasPrefix(head, sqliteHeader) {
+			return fmt.Errorf("%w: not a SQLite database", ErrInvalidBackup)
+		}
+		return checkSQLiteIntegrity(file)
+	case "mysql":
+		if bytes.HasPrefix(head, sqliteHeader) || bytes.HasPrefix(head, encryptionMagic) {
+			return fmt.Errorf("%w: not a MySQL dump", ErrInvalidBackup)
+		}
+		text := string(head)
+		if !strings.Contains(text, "MySQL dump") && !strings.Contains(text, "CREATE TABLE") && !strings.Contains(text, "INSERT INTO") {
+			return fmt.Errorf("%w: not a MySQL dump", ErrInvalidBackup)
+		}
+		return checkDumpComplete(f)
+	default:
+		return fmt.Errorf("unsupported DB_DRIVER: %s", driver)
+	}
+}
+
+// checkSQLiteIntegrity runs PRAGMA integrity_check on the backup
+func checkSQLiteIntegrity(file string) error {
+	db, err := utils.InitDatabase(io.Discard, "sqlite", file)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	var result string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: integrity check failed: %s", ErrInvalidBackup, result)
+	}
+	return nil
+}
+
+// checkDumpComplete verifies mysqldump wrote its trailer, which is missing when a dump was interrupted
+func checkDumpComplete(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	tailSize := int64(1024)
+	if info.Size() < tailSize {
+		tailSize = info.Size()
+	}
+	if _, err := f.Seek(-tailSize, io.SeekEnd); err != nil {
+		return err
+	}
+	tail, err := io.ReadAll(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(tail, []byte("-- Dump completed")) {
+		return nil
+	}
+	if bytes.Contains(tail, []byte("MySQL dump")) || bytes.Contains(bytes.ToLower(tail), []byte("mysqldump")) {
+		return fmt.Errorf("%w: dump is incomplete", ErrInvalidBackup)
+	}
+	return nil
+}
+
+// ResolveBackupName resolves "latest" or an exact name against the destination
+func ResolveBackupName(ctx context.Context, dest Destination, name string) (*Object, error) {
+	objects, err := dest.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no backups found in %s", dest.Name())
+	}
+	if name == "" || name == "latest" {
+		return &objects[0], nil
+	}
+	for i := range objects {
+		if objects[i].Name == name {
+			return &objects[i], nil
+		}
+	}
+	return nil, fmt.Errorf("backup %s not found in %s", name, dest.Name())
+}
+
+// Restore downloads, decrypts and validates a backup, then replaces the target database.
+// The server must be stopped while restoring.
+func Restore(ctx context.Context, opts RestoreOptions) (*RestoreResult, error) {
+	obj, err := ResolveBackupName(ctx, opts.Source, opts.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir, err := os.MkdirTemp("", "lingecho-restore-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	file, err := fetchBackup(ctx, opts.Source, obj.Name, workDir, opts.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateBackup(file, opts.Driver); err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{Name: obj.Name, Size: obj.Size}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	switch opts.Driver {
+	case "sqlite":
+		result.SafetyCopy, err = restoreSQLite(file, SQLiteFilePath(opts.DSN))
+	case "mysql":
+		err = restoreMySQL(ctx, file, opts.DSN)
+	default:
+		err = fmt.Errorf("unsupported DB_DRIVER: %s", opts.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fetchBackup downloads the backup into dir and decrypts it when needed
+func fetchBackup(ctx context.Context, src Destination, name, dir, passphrase string) (string, error) {
+	downloaded := filepath.Join(dir, name)
+	f, err := os.Create(downloaded)
+	if err != nil {
+		return "", err
+	}
+	if err := src.Download(ctx, name, f); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to download backup: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if !IsEncryptedName(name) {
+		return downloaded, nil
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("backup %s is encrypted, BACKUP_ENCRYPTION_KEY is required", name)
+	}
+
+	in, err := os.Open(downloaded)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	plain := strings.TrimSuffix(downloaded, EncryptedExt)
+	out, err := os.Create(plain)
+	if err != nil {
+		return "", err
+	}
+	if err := DecryptStream(out, in, passphrase); err != nil {
+		out.Close()
+		return "", err
+	}
+	return plain, out.Close()
+}
+
+// restoreSQLite atomically replaces the database file and keeps the old one as a safety copy
+func restoreSQLite(file, target string) (string, error) {
+	if target == "" || strings.Contains(target, ":memory:") {
+		return "", fmt.Errorf("cannot restore into an in-memory SQLite database")
+	}
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	// Copy next to the target first so the final rename is atomic
+	staged := target + ".restore"
+	if err := BackupSQLiteDatabase(file, staged); err != nil {
+		return "", err
+	}
+
+	var safetyCopy string
+	if _, err := os.Stat(target); err == nil {
+		safetyCopy = fmt.Sprintf("%s.pre-restore-%s", target, time.Now().Format(backupTimeLayout))
+		if err := os.Rename(target, safetyCopy); err != nil {
+			os.Remove(staged)
+			return "", fmt.Errorf("failed to keep current database: %v", err)
+		}
+	}
+	// A stale WAL would be replayed on top of the restored database
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err := os.Stat(target + suffix); err == nil {
+			if safetyCopy != "" {
+				os.Rename(target+suffix, safetyCopy+suffix)
+			} else {
+				os.Remove(target + suffix)
+			}
+		}
+	}
+	if err := os.Rename(staged, target); err != nil {
+		return safetyCopy, fmt.Errorf("failed to replace database: %v", err)
+	}
+	return safetyCopy, nil
+}
+
+// restoreMySQL feeds the dump into the mysql client
+func restoreMySQL(ctx context.Context, file, dsn string) error {
+	conn, err := ParseMySQLDSN(dsn)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	cmd := exec.CommandContext(ctx, "mysql", append(conn.Args(), conn.Database)...)
+	cmd.Env = append(os.Environ(), "MYSQL_PWD="+conn.Password)
+	cmd.Stdin = in
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to restore MySQL database: %v", err)
+	}
+	return nil
+}
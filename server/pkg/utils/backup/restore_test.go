@@ -0,0 +1,208 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
+)
+
+// createSQLiteDB creates a real SQLite database holding a single marker row
+func createSQLiteDB(t *testing.T, path, marker string) {
+	t.Helper()
+	db, err := utils.InitDatabase(io.Discard, "sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to create SQLite DB: %v", err)
+	}
+	if err := db.Exec("CREATE TABLE markers (value TEXT)").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if err := db.Exec("INSERT INTO markers (value) VALUES (?)", marker).Error; err != nil {
+		t.Fatalf("Failed to insert marker: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+}
+
+func readMarker(t *testing.T, path string) string {
+	t.Helper()
+	db, err := utils.InitDatabase(io.Discard, "sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open SQLite DB: %v", err)
+	}
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	var marker string
+	if err := db.Raw("SELECT value FROM markers").Scan(&marker).Error; err != nil {
+		t.Fatalf("Failed to read marker: %v", err)
+	}
+	return marker
+}
+
+func TestParseMySQLDSN(t *testing.T) {
+	conn, err := ParseMySQLDSN("root:p@ss:word@tcp(db.local:3307)/lingecho?charset=utf8mb4&parseTime=True")
+	if err != nil {
+		t.Fatalf("ParseMySQLDSN error: %v", err)
+	}
+	if conn.User != "root" || conn.Password != "p@ss:word" || conn.Host != "db.local" || conn.Port != "3307" || conn.Database != "lingecho" {
+		t.Fatalf("ParseMySQLDSN = %+v", conn)
+	}
+
+	conn, err = ParseMySQLDSN("app@unix(/var/run/mysqld.sock)/ling")
+	if err != nil {
+		t.Fatalf("ParseMySQLDSN error: %v", err)
+	}
+	if conn.Socket != "/var/run/mysqld.sock" || conn.Database != "ling" {
+		t.Fatalf("ParseMySQLDSN = %+v", conn)
+	}
+
+	if _, err := ParseMySQLDSN("invalid-dsn"); err == nil {
+		t.Fatalf("ParseMySQLDSN expected error for DSN without database")
+	}
+}
+
+func TestRetentionPolicy_Expired(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	objects := []Object{
+		{Name: "a", CreatedAt: now.Add(-1 * time.Hour)},
+		{Name: "b", CreatedAt: now.Add(-48 * time.Hour)},
+		{Name: "c", CreatedAt: now.Add(-96 * time.Hour)},
+		{Name: "d", CreatedAt: now.Add(-200 * time.Hour)},
+	}
+
+	expired := RetentionPolicy{MaxAge: 72 * time.Hour}.Expired(objects, now)
+	if len(expired) != 2 || expired[0].Name != "c" || expired[1].Name != "d" {
+		t.Fatalf("MaxAge expired = %+v", expired)
+	}
+
+	expired = RetentionPolicy{KeepLast: 2}.Expired(objects, now)
+	if len(expired) != 2 || expired[0].Name != "c" || expired[1].Name != "d" {
+		t.Fatalf("KeepLast expired = %+v", expired)
+	}
+
+	// The newest backup is kept even when it is too old
+	old := []Object{{Name: "only", CreatedAt: now.Add(-1000 * time.Hour)}}
+	if expired := (RetentionPolicy{MaxAge: time.Hour}).Expired(old, now); len(expired) != 0 {
+		t.Fatalf("newest backup should never expire, got %+v", expired)
+	}
+}
+
+func TestExecuteBackup_EncryptedWithRetention(t *testing.T) {
+	originalConfig := config.GlobalConfig
+	defer func() {
+		config.GlobalConfig = originalConfig
+	}()
+
+	tmpDir := t.TempDir()
+	testDB := filepath.Join(tmpDir, "ling.db")
+	backupPath := filepath.Join(tmpDir, "backups")
+	createSQLiteDB(t, testDB, "v1")
+
+	// An old backup that the retention policy should remove
+	if err := os.MkdirAll(backupPath, 0755); err != nil {
+		t.Fatalf("Failed to create backup dir: %v", err)
+	}
+	oldBackup := filepath.Join(backupPath, "sys_backup_20000101_000000.db")
+	if err := os.WriteFile(oldBackup, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to create old backup: %v", err)
+	}
+
+	config.GlobalConfig = &config.Config{
+		DBDriver:            "sqlite",
+		DSN:                 testDB,
+		BackupPath:          backupPath,
+		BackupEncryptionKey: "secret",
+		BackupRetentionDays: 7,
+	}
+	if err := ExecuteBackup(); err != nil {
+		t.Fatalf("ExecuteBackup() error: %v", err)
+	}
+
+	objects, err := LocalDestinationFromConfig(config.GlobalConfig).List(context.Background())
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(objects) != 1 || !IsEncryptedName(objects[0].Name) {
+		t.Fatalf("expected a single encrypted backup, got %+v", objects)
+	}
+}
+
+func TestRestore_SQLite(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "ling.db")
+	createSQLiteDB(t, target, "v1")
+
+	cfg := &config.Config{BackupPath: filepath.Join(tmpDir, "backups"), BackupEncryptionKey: "secret"}
+	dst := filepath.Join(cfg.BackupPath, "sys_backup_20250101_020000.db")
+	if err := BackupSQLiteDatabase(target, dst); err != nil {
+		t.Fatalf("BackupSQLiteDatabase error: %v", err)
+	}
+	if _, err := EncryptFile(dst, cfg.BackupEncryptionKey); err != nil {
+		t.Fatalf("EncryptFile error: %v", err)
+	}
+
+	// Change the live database after the backup was taken
+	os.Remove(target)
+	createSQLiteDB(t, target, "v2")
+
+	opts := RestoreOptions{
+		Source: LocalDestinationFromConfig(cfg),
+		Name:   "latest",
+		Driver: "sqlite",
+		DSN:    target,
+		DryRun: true,
+	}
+	if _, err := Restore(context.Background(), opts); err == nil {
+		t.Fatalf("Restore expected error without encryption key")
+	}
+
+	opts.EncryptionKey = cfg.BackupEncryptionKey
+	if _, err := Restore(context.Background(), opts); err != nil {
+		t.Fatalf("Restore dry run error: %v", err)
+	}
+	if got := readMarker(t, target); got != "v2" {
+		t.Fatalf("dry run changed the database, marker = %s", got)
+	}
+
+	opts.DryRun = false
+	result, err := Restore(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if got := readMarker(t, target); got != "v1" {
+		t.Fatalf("restored marker = %s, want v1", got)
+	}
+	if result.SafetyCopy == "" || readMarker(t, result.SafetyCopy) != "v2" {
+		t.Fatalf("previous database was not kept, safety copy = %q", result.SafetyCopy)
+	}
+}
+
+func TestValidateBackup_Invalid(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	notSQLite := filepath.Join(tmpDir, "broken.db")
+	os.WriteFile(notSQLite, []byte("test database content"), 0644)
+	if err := ValidateBackup(notSQLite, "sqlite"); !errors.Is(err, ErrInvalidBackup) {
+		t.Fatalf("ValidateBackup(sqlite) error = %v, want ErrInvalidBackup", err)
+	}
+
+	incomplete := filepath.Join(tmpDir, "dump.sql")
+	os.WriteFile(incomplete, []byte("-- MySQL dump 10.13\nCREATE TABLE users (id int);\nINSERT INTO users VALUES (1"), 0644)
+	if err := ValidateBackup(incomplete, "mysql"); !errors.Is(err, ErrInvalidBackup) {
+		t.Fatalf("ValidateBackup(mysql) error = %v, want ErrInvalidBackup", err)
+	}
+
+	complete := filepath.Join(tmpDir, "complete.sql")
+	os.WriteFile(complete, []byte("-- MySQL dump 10.13\nCREATE TABLE users (id int);\n-- Dump completed on 2025-01-01\n"), 0644)
+	if err := ValidateBackup(complete, "mysql"); err != nil {
+		t.Fatalf("ValidateBackup(mysql) error: %v", err)
+	}
+}
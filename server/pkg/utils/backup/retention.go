@@ -0,0 +1,60 @@
+package backup
+
+import (
+	"context"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/config"
+)
+
+// RetentionPolicy decides which backups are removed after a new backup is stored.
+// The most recent backup is never removed.
+type RetentionPolicy struct {
+	MaxAge   time.Duration // remove backups older than this, 0 disables
+	KeepLast int           // keep at most this many backups, 0 disables
+}
+
+// RetentionFromConfig builds the retention policy from configuration
+func RetentionFromConfig(cfg *config.Config) RetentionPolicy {
+	return RetentionPolicy{
+		MaxAge:   time.Duration(cfg.BackupRetentionDays) * 24 * time.Hour,
+		KeepLast: cfg.BackupRetentionKeep,
+	}
+}
+
+// Expired returns the backups the policy would remove, objects must be sorted newest first
+func (p RetentionPolicy) Expired(objects []Object, now time.Time) []Object {
+	var expired []Object
+	for i, obj := range objects {
+		if i == 0 {
+			continue
+		}
+		if p.KeepLast > 0 && i >= p.KeepLast {
+			expired = append(expired, obj)
+			continue
+		}
+		if p.MaxAge > 0 && now.Sub(obj.CreatedAt) > p.MaxAge {
+			expired = append(expired, obj)
+		}
+	}
+	return expired
+}
+
+// ApplyRetention removes expired backups from the destination and returns their names
+func ApplyRetention(ctx context.Context, dest Destination, policy RetentionPolicy, now time.Time) ([]string, error) {
+	if policy.MaxAge <= 0 && policy.KeepLast <= 0 {
+		return nil, nil
+	}
+	objects, err := dest.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, obj := range policy.Expired(objects, now) {
+		if err := dest.Delete(ctx, obj.Name); err != nil {
+			return removed, err
+		}
+		removed = append(removed, obj.Name)
+	}
+	return removed, nil
+}
@@ -1,18 +1,13 @@
 package utils
 
 import (
+	"context"
 	"time"
 
+	"github.com/code-100-precent/LingEcho/pkg/cache"
 	lru "github.com/hashicorp/golang-lru/v2"
 )
 
-// GlobalCache is a global cache instance that supports generic key-value pairs
-var GlobalCache *ExpiredLRUCache[string, any]
-
-func InitGlobalCache(maxSize int, expiration time.Duration) {
-	GlobalCache = NewExpiredLRUCache[string, any](maxSize, expiration)
-}
-
 type expiredLRUCacheValue[V any] struct {
 	n   time.Time
 	val V
@@ -58,3 +53,31 @@ func (c *ExpiredLRUCache[K, V]) Contains(key K) bool {
 func (c *ExpiredLRUCache[K, V]) Remove(key K) (present bool) {
 	return c.Cache.Remove(key)
 }
+
+// incrementWithWindow 在全局缓存中计数加一，首次计数时设置窗口过期时间，窗口内不会续期。
+// 先以 SetNX 原子地创建带过期时间的计数再自增，多实例并发时也不会留下没有过期时间的计数
+func incrementWithWindow(key string, window time.Duration) (int64, error) {
+	ctx := context.Background()
+	c := cache.GetGlobalCache()
+	if _, err := c.SetNX(ctx, key, int64(0), window); err != nil {
+		return 0, err
+	}
+	return c.Increment(ctx, key, 1)
+}
+
+// cachedCount 读取全局缓存中的计数，Redis 中的数字读出为 float64
+func cachedCount(key string) int64 {
+	val, ok := cache.Get(context.Background(), key)
+	if !ok {
+		return 0
+	}
+	switch v := val.(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
@@ -1,12 +1,24 @@
 package utils
 
 import (
+	"context"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/code-100-precent/LingEcho/pkg/cache"
 	"github.com/stretchr/testify/assert"
 )
 
+// initTestCache 为测试替换全局缓存，避免用例之间共享计数
+func initTestCache() {
+	cache.SetGlobalCache(cache.NewLocalCache(cache.LocalConfig{
+		MaxSize:           1000,
+		DefaultExpiration: time.Hour,
+		CleanupInterval:   time.Hour,
+	}))
+}
+
 func TestExpiredLRUCache_Basic(t *testing.T) {
 	cache := NewExpiredLRUCache[string, string](2, 100*time.Millisecond)
 
@@ -62,3 +74,39 @@ func TestExpiredLRUCache_Eviction(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, "val2", val)
 }
+
+func TestIncrementWithWindow(t *testing.T) {
+	initTestCache()
+	defer cache.SetGlobalCache(nil)
+
+	for i := int64(1); i <= 3; i++ {
+		count, err := incrementWithWindow("counter", 50*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, i, count)
+	}
+	assert.Equal(t, int64(3), cachedCount("counter"))
+
+	// 并发计数不丢失，且计数始终带有窗口过期时间
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := incrementWithWindow("concurrent", time.Minute)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(20), cachedCount("concurrent"))
+	_, ttl, ok := cache.GetGlobalCache().GetWithTTL(context.Background(), "concurrent")
+	assert.True(t, ok)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, time.Minute)
+
+	// 窗口到期后重新计数
+	time.Sleep(80 * time.Millisecond)
+	assert.Zero(t, cachedCount("counter"))
+	count, err := incrementWithWindow("counter", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
@@ -65,6 +65,11 @@ func GetIntEnv(key string) int64 {
 	return v
 }
 
+// InvalidateEnv drops the cached value of key so LookupEnv reads it again
+func InvalidateEnv(key string) {
+	envCache.Remove(strings.ToUpper(key))
+}
+
 func LookupEnv(key string) (value string, found bool) {
 	key = strings.ToUpper(key)
 	if v, ok := os.LookupEnv(key); ok {
@@ -155,7 +160,7 @@ func SetValue(db *gorm.DB, key, value, format string, autoload, public bool) {
 	}
 	result := db.Model(&Config{}).Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "key"}},
-		DoUpdates: clause.AssignmentColumns([]string{"value", "format", "autoload", "public"}),
+		DoUpdates: clause.AssignmentColumns([]string{"value", "format", "autoload", "public", "updated_at"}),
 	}).Create(newV)
 
 	if result.Error != nil {
@@ -184,6 +189,11 @@ func GetValue(db *gorm.DB, key string) string {
 	return v.Value
 }
 
+// InvalidateValue drops the cached value of key so the next GetValue reads the database
+func InvalidateValue(key string) {
+	configValueCache.Remove(strings.ToUpper(key))
+}
+
 func GetIntValue(db *gorm.DB, key string, defaultVal int) int {
 	v := GetValue(db, key)
 	if v == "" {
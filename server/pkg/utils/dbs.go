@@ -1,17 +1,48 @@
 package utils
 
 import (
+	"context"
 	"io"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/constants"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
+// ReplicaResolver 只读副本解析器名称，通过 ReadDB 显式路由
+const ReplicaResolver = "replica"
+
+// DBOptions 数据库连接池、只读副本与语句超时配置
+type DBOptions struct {
+	MaxIdleConns     int           // 最大空闲连接数
+	MaxOpenConns     int           // 最大打开连接数
+	ConnMaxLifetime  time.Duration // 连接最大存活时间
+	ConnMaxIdleTime  time.Duration // 连接最大空闲时间
+	ReplicaDSNs      []string      // 只读副本 DSN（与主库使用相同驱动）
+	StatementTimeout time.Duration // 单条语句超时，0 表示不限制
+}
+
+// DefaultDBOptions 默认连接池配置
+func DefaultDBOptions() DBOptions {
+	return DBOptions{
+		MaxIdleConns:    10,
+		MaxOpenConns:    100,
+		ConnMaxLifetime: time.Hour,
+		ConnMaxIdleTime: 30 * time.Minute,
+	}
+}
+
 func InitDatabase(logWrite io.Writer, driver, dsn string) (*gorm.DB, error) {
+	return InitDatabaseWithOptions(logWrite, driver, dsn, DefaultDBOptions())
+}
+
+// InitDatabaseWithOptions 创建数据库连接，并按配置注册只读副本、连接池与语句超时
+func InitDatabaseWithOptions(logWrite io.Writer, driver, dsn string, opts DBOptions) (*gorm.DB, error) {
 	if driver == "" {
 		driver = GetEnv(constants.ENV_DB_DRIVER)
 	}
@@ -46,14 +77,85 @@ func InitDatabase(logWrite io.Writer, driver, dsn string) (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// Register read replicas
+	if len(opts.ReplicaDSNs) > 0 {
+		if err := registerReplicas(db, driver, opts); err != nil {
+			return nil, err
+		}
+	}
+
 	// Configure database connection pool
-	ConfigureConnectionPool(db)
+	ConfigureConnectionPoolWithOptions(db, opts)
+
+	// Statement timeout
+	if opts.StatementTimeout > 0 {
+		if err := registerStatementTimeout(db, opts.StatementTimeout); err != nil {
+			return nil, err
+		}
+	}
 
 	return db, nil
 }
 
+// registerReplicas 注册只读副本，只有通过 ReadDB 的查询才会路由到副本，其余读写仍走主库
+func registerReplicas(db *gorm.DB, driver string, opts DBOptions) error {
+	replicas := make([]gorm.Dialector, 0, len(opts.ReplicaDSNs))
+	for _, replicaDSN := range opts.ReplicaDSNs {
+		replicaDSN = strings.TrimSpace(replicaDSN)
+		if replicaDSN == "" {
+			continue
+		}
+		replicas = append(replicas, newDialector(driver, replicaDSN))
+	}
+	if len(replicas) == 0 {
+		return nil
+	}
+
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   dbresolver.RandomPolicy{},
+	}, ReplicaResolver)
+	if opts.MaxIdleConns > 0 {
+		resolver.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.MaxOpenConns > 0 {
+		resolver.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		resolver.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.ConnMaxIdleTime > 0 {
+		resolver.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+	return db.Use(resolver)
+}
+
+// ReadDB 将查询路由到只读副本（列表、统计等重查询使用），未配置副本时等同于主库
+func ReadDB(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Use(ReplicaResolver))
+}
+
 // ConfigureConnectionPool configure database connection pool
 func ConfigureConnectionPool(db *gorm.DB) {
+	ConfigureConnectionPoolWithOptions(db, DefaultDBOptions())
+}
+
+// ConfigureConnectionPoolWithOptions configure database connection pool with options, zero values keep defaults
+func ConfigureConnectionPoolWithOptions(db *gorm.DB, opts DBOptions) {
+	defaults := DefaultDBOptions()
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = defaults.MaxIdleConns
+	}
+	if opts.MaxOpenConns <= 0 {
+		opts.MaxOpenConns = defaults.MaxOpenConns
+	}
+	if opts.ConnMaxLifetime <= 0 {
+		opts.ConnMaxLifetime = defaults.ConnMaxLifetime
+	}
+	if opts.ConnMaxIdleTime <= 0 {
+		opts.ConnMaxIdleTime = defaults.ConnMaxIdleTime
+	}
+
 	// Get the underlying sql.DB
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -62,16 +164,72 @@ func ConfigureConnectionPool(db *gorm.DB) {
 	}
 
 	// Set maximum idle connections
-	sqlDB.SetMaxIdleConns(10)
+	sqlDB.SetMaxIdleConns(opts.MaxIdleConns)
 
 	// Set maximum open connections
-	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetMaxOpenConns(opts.MaxOpenConns)
 
 	// Set connection maximum lifetime
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	sqlDB.SetConnMaxLifetime(opts.ConnMaxLifetime)
 
 	// Set connection maximum idle time
-	sqlDB.SetConnMaxIdleTime(30 * time.Minute)
+	sqlDB.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+}
+
+const statementTimeoutCancelKey = "lingecho:statement_timeout_cancel"
+
+// registerStatementTimeout 为没有截止时间的语句附加超时上下文
+func registerStatementTimeout(db *gorm.DB, timeout time.Duration) error {
+	before := func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if _, ok := ctx.Deadline(); ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(statementTimeoutCancelKey, cancel)
+	}
+	after := func(tx *gorm.DB) {
+		if v, ok := tx.InstanceGet(statementTimeoutCancelKey); ok {
+			if cancel, ok := v.(context.CancelFunc); ok {
+				cancel()
+			}
+		}
+	}
+
+	// Row 回调返回的 *sql.Rows 由调用方迭代，不能在回调结束时取消上下文，因此不处理
+	callback := db.Callback()
+	if err := callback.Query().Before("*").Register("timeout:before_query", before); err != nil {
+		return err
+	}
+	if err := callback.Query().After("*").Register("timeout:after_query", after); err != nil {
+		return err
+	}
+	if err := callback.Create().Before("*").Register("timeout:before_create", before); err != nil {
+		return err
+	}
+	if err := callback.Create().After("*").Register("timeout:after_create", after); err != nil {
+		return err
+	}
+	if err := callback.Update().Before("*").Register("timeout:before_update", before); err != nil {
+		return err
+	}
+	if err := callback.Update().After("*").Register("timeout:after_update", after); err != nil {
+		return err
+	}
+	if err := callback.Delete().Before("*").Register("timeout:before_delete", before); err != nil {
+		return err
+	}
+	if err := callback.Delete().After("*").Register("timeout:after_delete", after); err != nil {
+		return err
+	}
+	if err := callback.Raw().Before("*").Register("timeout:before_raw", before); err != nil {
+		return err
+	}
+	return callback.Raw().After("*").Register("timeout:after_raw", after)
 }
 
 func MakeMigrates(db *gorm.DB, insts []any) error {
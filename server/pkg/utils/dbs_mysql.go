@@ -30,3 +30,8 @@ func createDatabaseInstance(cfg *gorm.Config, driver, dsn string) (*gorm.DB, err
 
 	return db, nil
 }
+
+// newDialector 为只读副本创建方言（副本 DSN 需自行指定 charset=utf8mb4）
+func newDialector(driver, dsn string) gorm.Dialector {
+	return mysql.Open(dsn)
+}
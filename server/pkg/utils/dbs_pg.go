@@ -10,3 +10,8 @@ import (
 func createDatabaseInstance(cfg *gorm.Config, driver, dsn string) (*gorm.DB, error) {
 	return gorm.Open(postgres.Open(dsn), cfg)
 }
+
+// newDialector 为只读副本创建方言
+func newDialector(driver, dsn string) gorm.Dialector {
+	return postgres.Open(dsn)
+}
@@ -41,3 +41,14 @@ func createDatabaseInstance(cfg *gorm.Config, driver, dsn string) (*gorm.DB, err
 	}
 	return gorm.Open(sqlite.Open(dsn), cfg)
 }
+
+// newDialector 为只读副本创建方言
+func newDialector(driver, dsn string) gorm.Dialector {
+	switch driver {
+	case "mysql":
+		return mysql.Open(dsn)
+	case "pg":
+		return postgres.Open(dsn)
+	}
+	return sqlite.Open(dsn)
+}
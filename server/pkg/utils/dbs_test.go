@@ -2,7 +2,9 @@ package utils
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestConfigureConnectionPool(t *testing.T) {
@@ -222,3 +224,80 @@ func TestConfigureConnectionPool_InvalidDB(t *testing.T) {
 	// (it will log an error but not panic)
 	ConfigureConnectionPool(db)
 }
+
+func TestInitDatabaseWithOptions_ReadReplica(t *testing.T) {
+	dir := t.TempDir()
+	primaryDSN := filepath.Join(dir, "primary.db")
+	replicaDSN := filepath.Join(dir, "replica.db")
+
+	type ReplicaModel struct {
+		ID   uint `gorm:"primarykey"`
+		Name string
+	}
+
+	// Prepare replica with a distinguishable row
+	replica, err := InitDatabase(nil, "sqlite", replicaDSN)
+	if err != nil {
+		t.Fatalf("InitDatabase replica error: %v", err)
+	}
+	if err := MakeMigrates(replica, []any{&ReplicaModel{}}); err != nil {
+		t.Fatalf("MakeMigrates replica error: %v", err)
+	}
+	replica.Create(&ReplicaModel{Name: "from-replica"})
+	if sqlDB, _ := replica.DB(); sqlDB != nil {
+		sqlDB.Close()
+	}
+
+	opts := DefaultDBOptions()
+	opts.ReplicaDSNs = []string{replicaDSN}
+	opts.StatementTimeout = 5 * time.Second
+	db, err := InitDatabaseWithOptions(nil, "sqlite", primaryDSN, opts)
+	if err != nil {
+		t.Fatalf("InitDatabaseWithOptions error: %v", err)
+	}
+	defer func() {
+		sqlDB, _ := db.DB()
+		if sqlDB != nil {
+			sqlDB.Close()
+		}
+	}()
+
+	if err := MakeMigrates(db, []any{&ReplicaModel{}}); err != nil {
+		t.Fatalf("MakeMigrates error: %v", err)
+	}
+	if err := db.Create(&ReplicaModel{Name: "from-primary"}).Error; err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	// Default queries stay on primary
+	var fromPrimary ReplicaModel
+	if err := db.First(&fromPrimary).Error; err != nil {
+		t.Fatalf("query primary error: %v", err)
+	}
+	if fromPrimary.Name != "from-primary" {
+		t.Fatalf("expected primary row, got %q", fromPrimary.Name)
+	}
+
+	// ReadDB routes to the replica
+	var fromReplica ReplicaModel
+	if err := ReadDB(db).First(&fromReplica).Error; err != nil {
+		t.Fatalf("query replica error: %v", err)
+	}
+	if fromReplica.Name != "from-replica" {
+		t.Fatalf("expected replica row, got %q", fromReplica.Name)
+	}
+}
+
+func TestReadDB_WithoutReplica(t *testing.T) {
+	db, err := InitDatabase(nil, "sqlite", "file::memory:")
+	if err != nil {
+		t.Fatalf("InitDatabase error: %v", err)
+	}
+	var count int64
+	if err := ReadDB(db).Raw("SELECT 1").Scan(&count).Error; err != nil {
+		t.Fatalf("ReadDB without replica error: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1, got %d", count)
+	}
+}
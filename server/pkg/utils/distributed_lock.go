@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/cache"
 )
 
 // DistributedLock 分布式锁接口
@@ -54,51 +57,61 @@ func (ml *MemoryLock) TryLock(key string, ttl time.Duration) (bool, error) {
 	return ml.Lock(key, ttl)
 }
 
+// CacheLock 基于缓存 SetNX 的锁，使用 Redis 缓存时在多个实例间生效
+type CacheLock struct {
+	cache cache.Cache
+}
+
+// NewCacheLock 创建基于缓存的锁
+func NewCacheLock(c cache.Cache) *CacheLock {
+	return &CacheLock{cache: c}
+}
+
+// Lock 获取锁，ttl 到期后自动释放
+func (cl *CacheLock) Lock(key string, ttl time.Duration) (bool, error) {
+	ok, err := cl.cache.SetNX(context.Background(), key, time.Now().Unix(), ttl)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, errors.New("lock already exists")
+	}
+	return true, nil
+}
+
+// Unlock 释放锁
+func (cl *CacheLock) Unlock(key string) error {
+	return cl.cache.Delete(context.Background(), key)
+}
+
+// TryLock 尝试获取锁（非阻塞）
+func (cl *CacheLock) TryLock(key string, ttl time.Duration) (bool, error) {
+	return cl.Lock(key, ttl)
+}
+
 // GlobalDistributedLock 全局分布式锁实例
 var GlobalDistributedLock DistributedLock
 
-// InitGlobalDistributedLock 初始化全局分布式锁
+// InitGlobalDistributedLock 初始化全局分布式锁，与全局缓存共用存储
 func InitGlobalDistributedLock() {
-	if GlobalCache != nil {
-		// 如果使用Redis等分布式缓存，可以实现基于缓存的分布式锁
-		GlobalDistributedLock = NewMemoryLock()
-	} else {
-		GlobalDistributedLock = NewMemoryLock()
-	}
+	GlobalDistributedLock = NewCacheLock(cache.GetGlobalCache())
 }
 
 // AcquireRegistrationLock 获取注册锁（防止并发注册同一邮箱）
 func AcquireRegistrationLock(email string) (bool, error) {
+	lockKey := fmt.Sprintf("reg:lock:%s", email)
 	if GlobalDistributedLock == nil {
-		// 如果没有分布式锁，使用缓存作为简单锁
-		if GlobalCache != nil {
-			lockKey := fmt.Sprintf("reg:lock:%s", email)
-			if _, exists := GlobalCache.Get(lockKey); exists {
-				return false, errors.New("registration in progress for this email")
-			}
-			// 设置锁，5分钟过期
-			GlobalCache.Add(lockKey, true)
-			return true, nil
-		}
-		// 如果缓存也没有，返回true（允许注册，但无法防止并发）
-		return true, nil
+		// 未初始化时直接使用全局缓存
+		return NewCacheLock(cache.GetGlobalCache()).Lock(lockKey, 5*time.Minute)
 	}
-
-	lockKey := fmt.Sprintf("reg:lock:%s", email)
-	success, err := GlobalDistributedLock.Lock(lockKey, 5*time.Minute)
-	return success, err
+	return GlobalDistributedLock.Lock(lockKey, 5*time.Minute)
 }
 
 // ReleaseRegistrationLock 释放注册锁
 func ReleaseRegistrationLock(email string) error {
+	lockKey := fmt.Sprintf("reg:lock:%s", email)
 	if GlobalDistributedLock == nil {
-		if GlobalCache != nil {
-			lockKey := fmt.Sprintf("reg:lock:%s", email)
-			GlobalCache.Remove(lockKey)
-		}
-		return nil
+		return cache.Delete(context.Background(), lockKey)
 	}
-
-	lockKey := fmt.Sprintf("reg:lock:%s", email)
 	return GlobalDistributedLock.Unlock(lockKey)
 }
@@ -3,6 +3,8 @@ package utils
 import (
 	"testing"
 	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/cache"
 )
 
 func TestNewMemoryLock(t *testing.T) {
@@ -138,9 +140,9 @@ func TestMemoryLock_TryLock(t *testing.T) {
 
 func TestInitGlobalDistributedLock(t *testing.T) {
 	// 初始化缓存
-	InitGlobalCache(1000, 1*time.Hour)
+	initTestCache()
 	defer func() {
-		GlobalCache = nil
+		cache.SetGlobalCache(nil)
 		GlobalDistributedLock = nil
 	}()
 
@@ -152,7 +154,7 @@ func TestInitGlobalDistributedLock(t *testing.T) {
 
 func TestInitGlobalDistributedLock_NoCache(t *testing.T) {
 	// 确保缓存为nil
-	GlobalCache = nil
+	cache.SetGlobalCache(nil)
 	defer func() {
 		GlobalDistributedLock = nil
 	}()
@@ -164,10 +166,10 @@ func TestInitGlobalDistributedLock_NoCache(t *testing.T) {
 }
 
 func TestAcquireRegistrationLock(t *testing.T) {
-	InitGlobalCache(1000, 1*time.Hour)
+	initTestCache()
 	InitGlobalDistributedLock()
 	defer func() {
-		GlobalCache = nil
+		cache.SetGlobalCache(nil)
 		GlobalDistributedLock = nil
 	}()
 
@@ -182,10 +184,10 @@ func TestAcquireRegistrationLock(t *testing.T) {
 }
 
 func TestAcquireRegistrationLock_AlreadyLocked(t *testing.T) {
-	InitGlobalCache(1000, 1*time.Hour)
+	initTestCache()
 	InitGlobalDistributedLock()
 	defer func() {
-		GlobalCache = nil
+		cache.SetGlobalCache(nil)
 		GlobalDistributedLock = nil
 	}()
 
@@ -211,10 +213,10 @@ func TestAcquireRegistrationLock_AlreadyLocked(t *testing.T) {
 }
 
 func TestReleaseRegistrationLock(t *testing.T) {
-	InitGlobalCache(1000, 1*time.Hour)
+	initTestCache()
 	InitGlobalDistributedLock()
 	defer func() {
-		GlobalCache = nil
+		cache.SetGlobalCache(nil)
 		GlobalDistributedLock = nil
 	}()
 
@@ -246,10 +248,10 @@ func TestReleaseRegistrationLock(t *testing.T) {
 }
 
 func TestAcquireRegistrationLock_NoDistributedLock(t *testing.T) {
-	InitGlobalCache(1000, 1*time.Hour)
+	initTestCache()
 	GlobalDistributedLock = nil // 清除分布式锁
 	defer func() {
-		GlobalCache = nil
+		cache.SetGlobalCache(nil)
 		GlobalDistributedLock = nil
 	}()
 
@@ -264,7 +266,7 @@ func TestAcquireRegistrationLock_NoDistributedLock(t *testing.T) {
 }
 
 func TestAcquireRegistrationLock_NoCacheNoLock(t *testing.T) {
-	GlobalCache = nil
+	cache.SetGlobalCache(nil)
 	GlobalDistributedLock = nil
 	defer func() {
 		GlobalDistributedLock = nil
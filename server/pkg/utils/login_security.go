@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/code-100-precent/LingEcho/pkg/cache"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -32,6 +34,9 @@ func NewLoginSecurityManager(logger *zap.Logger) *LoginSecurityManager {
 	}
 }
 
+// failedLoginWindow 失败登录计数的统计窗口
+const failedLoginWindow = 5 * time.Minute
+
 // GlobalLoginSecurityManager 全局登录安全管理器
 var GlobalLoginSecurityManager *LoginSecurityManager
 
@@ -79,21 +84,14 @@ type RecordFailedLoginFunc func(*gorm.DB, string, uint, string, int) error
 
 // RecordFailedLogin 记录失败登录
 func (lsm *LoginSecurityManager) RecordFailedLogin(db *gorm.DB, email string, userID uint, ipAddress string, recordFunc RecordFailedLoginFunc) error {
-	// 获取当前失败次数
+	// 失败计数保存在全局缓存中，多实例共享
 	key := fmt.Sprintf("login:failed:%s", email)
-	var failedCount int
-	if GlobalCache != nil {
-		if val, ok := GlobalCache.Get(key); ok {
-			if c, ok := val.(int); ok {
-				failedCount = c
-			}
-		}
-	}
-
-	failedCount++
-	if GlobalCache != nil {
-		GlobalCache.Add(key, failedCount)
+	count, err := incrementWithWindow(key, failedLoginWindow)
+	if err != nil {
+		lsm.logger.Warn("Failed to count failed login", zap.String("email", email), zap.Error(err))
+		return nil
 	}
+	failedCount := int(count)
 
 	// 如果达到最大失败次数，锁定账号
 	if failedCount >= lsm.maxFailedAttempts {
@@ -116,36 +114,26 @@ func (lsm *LoginSecurityManager) RecordFailedLogin(db *gorm.DB, email string, us
 // ClearFailedLoginCount 清除失败登录计数（登录成功时调用）
 func (lsm *LoginSecurityManager) ClearFailedLoginCount(email string) {
 	key := fmt.Sprintf("login:failed:%s", email)
-	if GlobalCache != nil {
-		GlobalCache.Remove(key)
-	}
+	cache.Delete(context.Background(), key)
 }
 
 // CheckIPRateLimit 检查IP登录限流
 func (lsm *LoginSecurityManager) CheckIPRateLimit(ip string) error {
-	if GlobalCache == nil {
-		return nil
-	}
-
 	key := fmt.Sprintf("login:ip:%s", ip)
-	var count int
-	if val, ok := GlobalCache.Get(key); ok {
-		if c, ok := val.(int); ok {
-			count = c
-		}
+	count, err := incrementWithWindow(key, time.Minute)
+	if err != nil {
+		// 缓存不可用时不阻止登录
+		lsm.logger.Warn("Failed to count login attempt", zap.String("ip", ip), zap.Error(err))
+		return nil
 	}
 
-	if count >= lsm.ipRateLimitPerMinute {
+	if count > int64(lsm.ipRateLimitPerMinute) {
 		lsm.logger.Warn("IP login rate limit exceeded",
 			zap.String("ip", ip),
-			zap.Int("count", count))
+			zap.Int64("count", count))
 		return errors.New("too many login attempts from this IP, please try again later")
 	}
 
-	// 增加计数
-	count++
-	GlobalCache.Add(key, count)
-
 	return nil
 }
 
@@ -1,10 +1,12 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/code-100-precent/LingEcho/pkg/cache"
 	"go.uber.org/zap/zaptest"
 	"gorm.io/gorm"
 )
@@ -14,10 +16,10 @@ func setupTestLoginSecurityManager(t *testing.T) (*LoginSecurityManager, func())
 	lsm := NewLoginSecurityManager(logger)
 
 	// 初始化缓存用于测试
-	InitGlobalCache(1000, 1*time.Hour)
+	initTestCache()
 
 	cleanup := func() {
-		GlobalCache = nil
+		cache.SetGlobalCache(nil)
 	}
 
 	return lsm, cleanup
@@ -94,12 +96,12 @@ func TestLoginSecurityManager_RecordFailedLogin(t *testing.T) {
 
 	// 验证失败计数已增加
 	key := fmt.Sprintf("login:failed:%s", "test@example.com")
-	count, ok := GlobalCache.Get(key)
-	if !ok {
+	count := cachedCount(key)
+	if count == 0 {
 		t.Fatal("Failed login count not recorded")
 	}
-	if count.(int) != 1 {
-		t.Fatalf("Expected failed count 1, got %d", count.(int))
+	if count != 1 {
+		t.Fatalf("Expected failed count 1, got %d", count)
 	}
 }
 
@@ -142,7 +144,7 @@ func TestLoginSecurityManager_ClearFailedLoginCount(t *testing.T) {
 
 	// 验证计数已清除
 	key := fmt.Sprintf("login:failed:%s", email)
-	_, ok := GlobalCache.Get(key)
+	ok := cache.Exists(context.Background(), key)
 	if ok {
 		t.Fatal("Failed login count should be cleared")
 	}
@@ -151,7 +153,7 @@ func TestLoginSecurityManager_ClearFailedLoginCount(t *testing.T) {
 func TestLoginSecurityManager_CheckIPRateLimit_NoCache(t *testing.T) {
 	lsm, cleanup := setupTestLoginSecurityManager(t)
 	defer cleanup()
-	GlobalCache = nil // 清除缓存
+	cache.SetGlobalCache(nil) // 清除缓存
 
 	err := lsm.CheckIPRateLimit("192.168.1.1")
 	if err != nil {
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress 目标地址属于回环、内网、链路本地（含云元数据）等受限网段
+var ErrBlockedAddress = errors.New("destination address is not allowed")
+
+// maxOutboundRedirects 对外请求最多跟随的重定向次数
+const maxOutboundRedirects = 5
+
+// blockedNetworks 标准库判断之外需要额外拦截的保留网段
+var blockedNetworks = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",     // 本网络
+		"100.64.0.0/10", // 运营商级 NAT
+		"192.0.0.0/24",  // IETF 协议分配
+		"198.18.0.0/15", // 基准测试
+		"240.0.0.0/4",   // 保留
+		"64:ff9b::/96",  // NAT64，可映射到任意 IPv4 内网地址
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// IsPublicIP 判断是否为可以安全访问的公网地址
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range blockedNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicDialControl 在建立连接时检查解析后的实际地址，DNS 重绑定也无法绕过
+func publicDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublicIP(net.ParseIP(host)) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	return nil
+}
+
+// NewPublicHTTPClient 创建只能访问公网地址的 HTTP 客户端，用于请求用户填写的 URL（如 Webhook）。
+// 每次拨号（包括重定向后的请求）都会检查目标地址，且不使用环境变量中的代理
+func NewPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicDialControl,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxOutboundRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxOutboundRedirects)
+			}
+			return ValidatePublicURL(req.Context(), req.URL.String())
+		},
+	}
+}
+
+// ValidatePublicURL 检查 URL 为 http(s) 且主机解析到的所有地址都是公网地址，用于保存配置时提前报错；
+// 实际请求时仍以 NewPublicHTTPClient 拨号时的检查为准
+func ValidatePublicURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("invalid url: %s", raw)
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, addr.IP)
+		}
+	}
+	return nil
+}
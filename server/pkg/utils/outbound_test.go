@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "0.0.0.0",
+		"100.64.0.1", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "64:ff9b::a00:1", "224.0.0.1",
+	} {
+		assert.False(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		assert.True(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+	assert.False(t, IsPublicIP(nil))
+}
+
+func TestValidatePublicURL(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, ValidatePublicURL(ctx, "https://8.8.8.8/hook"))
+	assert.Error(t, ValidatePublicURL(ctx, "ftp://8.8.8.8/hook"))
+	assert.Error(t, ValidatePublicURL(ctx, "https:///hook"))
+	assert.True(t, errors.Is(ValidatePublicURL(ctx, "http://169.254.169.254/latest/meta-data"), ErrBlockedAddress))
+	assert.True(t, errors.Is(ValidatePublicURL(ctx, "http://[::1]:8080/"), ErrBlockedAddress))
+	assert.True(t, errors.Is(ValidatePublicURL(ctx, "http://localhost:8080/"), ErrBlockedAddress))
+}
+
+func TestNewPublicHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewPublicHTTPClient(time.Second)
+	_, err := client.Get(server.URL)
+	assert.True(t, errors.Is(err, ErrBlockedAddress), "loopback is refused at dial time: %v", err)
+
+	// 公网地址返回的重定向指向内网时拒绝跟随
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/", nil)
+	err = client.CheckRedirect(req, []*http.Request{{}})
+	assert.True(t, errors.Is(err, ErrBlockedAddress))
+	assert.Error(t, client.CheckRedirect(req, make([]*http.Request, maxOutboundRedirects)))
+}
@@ -109,25 +109,15 @@ func InitGlobalRegistrationGuard(logger *zap.Logger) {
 
 // CheckIPRateLimit 检查IP注册限流
 func (rg *RegistrationGuard) CheckIPRateLimit(ip string) error {
-	if GlobalCache == nil {
-		// 如果缓存未初始化，跳过限流检查
-		return nil
-	}
-
 	// 获取IP的注册次数
 	key := fmt.Sprintf("reg:ip:%s", ip)
-	var count int
-	if val, ok := GlobalCache.Get(key); ok {
-		if c, ok := val.(int); ok {
-			count = c
-		}
-	}
+	count := cachedCount(key)
 
 	// 检查是否超过限制
-	if count >= rg.maxRegistrationsPerIP {
+	if count >= int64(rg.maxRegistrationsPerIP) {
 		rg.logger.Warn("IP registration rate limit exceeded",
 			zap.String("ip", ip),
-			zap.Int("count", count),
+			zap.Int64("count", count),
 			zap.Duration("window", rg.ipRateLimitWindow))
 		return errors.New("registration rate limit exceeded for this IP, please try again later")
 	}
@@ -135,35 +125,14 @@ func (rg *RegistrationGuard) CheckIPRateLimit(ip string) error {
 	return nil
 }
 
-// RecordRegistrationAttempt 记录注册尝试
+// RecordRegistrationAttempt 记录注册尝试，计数保存在全局缓存中，多实例共享
 func (rg *RegistrationGuard) RecordRegistrationAttempt(ip string, email string, success bool, reason string) {
-	if GlobalCache == nil {
-		// 如果缓存未初始化，只记录日志
-		if success {
-			rg.logger.Info("Registration attempt recorded (cache not initialized)",
-				zap.String("ip", ip),
-				zap.String("email", maskEmail(email)),
-				zap.Bool("success", success))
-		} else {
-			rg.logger.Warn("Failed registration attempt (cache not initialized)",
-				zap.String("ip", ip),
-				zap.String("email", maskEmail(email)),
-				zap.String("reason", reason))
-		}
-		return
-	}
-
 	// 记录成功注册
 	if success {
 		key := fmt.Sprintf("reg:ip:%s", ip)
-		var count int
-		if val, ok := GlobalCache.Get(key); ok {
-			if c, ok := val.(int); ok {
-				count = c
-			}
+		if _, err := incrementWithWindow(key, rg.ipRateLimitWindow); err != nil {
+			rg.logger.Warn("Failed to count registration", zap.String("ip", ip), zap.Error(err))
 		}
-		count++
-		GlobalCache.Add(key, count)
 		rg.logger.Info("Registration attempt recorded",
 			zap.String("ip", ip),
 			zap.String("email", maskEmail(email)),
@@ -171,14 +140,9 @@ func (rg *RegistrationGuard) RecordRegistrationAttempt(ip string, email string,
 	} else {
 		// 记录失败尝试
 		failedKey := fmt.Sprintf("reg:failed:ip:%s", ip)
-		var failedCount int
-		if val, ok := GlobalCache.Get(failedKey); ok {
-			if c, ok := val.(int); ok {
-				failedCount = c
-			}
+		if _, err := incrementWithWindow(failedKey, rg.failedAttemptWindow); err != nil {
+			rg.logger.Warn("Failed to count failed registration", zap.String("ip", ip), zap.Error(err))
 		}
-		failedCount++
-		GlobalCache.Add(failedKey, failedCount)
 
 		rg.logger.Warn("Failed registration attempt",
 			zap.String("ip", ip),
@@ -189,23 +153,13 @@ func (rg *RegistrationGuard) RecordRegistrationAttempt(ip string, email string,
 
 // CheckFailedAttempts 检查失败尝试次数
 func (rg *RegistrationGuard) CheckFailedAttempts(ip string) error {
-	if GlobalCache == nil {
-		// 如果缓存未初始化，跳过检查
-		return nil
-	}
-
 	failedKey := fmt.Sprintf("reg:failed:ip:%s", ip)
-	var failedCount int
-	if val, ok := GlobalCache.Get(failedKey); ok {
-		if c, ok := val.(int); ok {
-			failedCount = c
-		}
-	}
+	failedCount := cachedCount(failedKey)
 
-	if failedCount >= rg.maxFailedAttemptsPerIP {
+	if failedCount >= int64(rg.maxFailedAttemptsPerIP) {
 		rg.logger.Warn("Too many failed registration attempts",
 			zap.String("ip", ip),
-			zap.Int("failed_count", failedCount))
+			zap.Int64("failed_count", failedCount))
 		return errors.New("too many failed registration attempts, please try again later")
 	}
 
@@ -3,8 +3,8 @@ package utils
 import (
 	"fmt"
 	"testing"
-	"time"
 
+	"github.com/code-100-precent/LingEcho/pkg/cache"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -13,10 +13,10 @@ func setupTestRegistrationGuard(t *testing.T) (*RegistrationGuard, func()) {
 	rg := NewRegistrationGuard(logger)
 
 	// 初始化缓存用于测试
-	InitGlobalCache(1000, 1*time.Hour)
+	initTestCache()
 
 	cleanup := func() {
-		GlobalCache = nil
+		cache.SetGlobalCache(nil)
 	}
 
 	return rg, cleanup
@@ -70,7 +70,7 @@ func TestRegistrationGuard_AddIPToBlacklist_InvalidCIDR(t *testing.T) {
 func TestRegistrationGuard_CheckIPRateLimit_NoCache(t *testing.T) {
 	rg, cleanup := setupTestRegistrationGuard(t)
 	defer cleanup()
-	GlobalCache = nil // 清除缓存
+	cache.SetGlobalCache(nil) // 清除缓存
 
 	err := rg.CheckIPRateLimit("192.168.1.1")
 	if err != nil {
@@ -115,12 +115,12 @@ func TestRegistrationGuard_RecordRegistrationAttempt_Success(t *testing.T) {
 
 	// 验证记录已增加
 	key := fmt.Sprintf("reg:ip:%s", ip)
-	count, ok := GlobalCache.Get(key)
-	if !ok {
+	count := cachedCount(key)
+	if count == 0 {
 		t.Fatal("Registration attempt not recorded")
 	}
-	if count.(int) != 1 {
-		t.Fatalf("Expected count 1, got %d", count.(int))
+	if count != 1 {
+		t.Fatalf("Expected count 1, got %d", count)
 	}
 }
 
@@ -135,19 +135,19 @@ func TestRegistrationGuard_RecordRegistrationAttempt_Failure(t *testing.T) {
 
 	// 验证失败记录已增加
 	failedKey := fmt.Sprintf("reg:failed:ip:%s", ip)
-	failedCount, ok := GlobalCache.Get(failedKey)
-	if !ok {
+	failedCount := cachedCount(failedKey)
+	if failedCount == 0 {
 		t.Fatal("Failed registration attempt not recorded")
 	}
-	if failedCount.(int) != 1 {
-		t.Fatalf("Expected failed count 1, got %d", failedCount.(int))
+	if failedCount != 1 {
+		t.Fatalf("Expected failed count 1, got %d", failedCount)
 	}
 }
 
 func TestRegistrationGuard_CheckFailedAttempts_NoCache(t *testing.T) {
 	rg, cleanup := setupTestRegistrationGuard(t)
 	defer cleanup()
-	GlobalCache = nil // 清除缓存
+	cache.SetGlobalCache(nil) // 清除缓存
 
 	err := rg.CheckFailedAttempts("192.168.1.1")
 	if err != nil {
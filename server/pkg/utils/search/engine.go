@@ -3,6 +3,7 @@ package search
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"sync"
 	"time"
@@ -21,19 +22,34 @@ type Engine interface {
 	Search(ctx context.Context, req SearchRequest) (SearchResult, error)
 	GetAutoCompleteSuggestions(ctx context.Context, keyword string) ([]string, error)
 	GetSearchSuggestions(ctx context.Context, keyword string) ([]string, error)
+	// Rebuild 清空索引（Bleve 会按当前映射重新创建），调用方随后重新写入全部文档
+	Rebuild(ctx context.Context) error
 	Close() error
 }
 
+// Open 按 cfg.Backend 创建搜索引擎，Bleve 使用 cfg.DefaultAnalyzer 构建映射
+func Open(cfg Config) (Engine, error) {
+	switch cfg.Backend {
+	case "", BackendBleve:
+		return New(cfg, BuildIndexMapping(cfg.DefaultAnalyzer))
+	case BackendMeilisearch:
+		return NewMeilisearch(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported search backend: %s", cfg.Backend)
+	}
+}
+
 type bleveEngine struct {
 	cfg           Config
 	index         bleve.Index
+	mapping       mapping.IndexMapping
 	defaultFields []string
 	mu            sync.RWMutex
 	closed        bool
 }
 
 func New(cfg Config, m mapping.IndexMapping) (Engine, error) { // mapping 引自 bleve
-	be := &bleveEngine{cfg: cfg, defaultFields: cfg.DefaultSearchFields}
+	be := &bleveEngine{cfg: cfg, mapping: m, defaultFields: cfg.DefaultSearchFields}
 
 	var idx bleve.Index
 	if _, err := os.Stat(cfg.IndexPath); err == nil {
@@ -55,13 +71,14 @@ func New(cfg Config, m mapping.IndexMapping) (Engine, error) { // mapping 引自
 	return be, nil
 }
 
-func (e *bleveEngine) guard() error {
+// current 返回当前索引，引擎已关闭时返回 ErrClosed；Rebuild 会替换索引，因此不直接读取 e.index
+func (e *bleveEngine) current() (bleve.Index, error) {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 	if e.closed {
-		return ErrClosed
+		return nil, ErrClosed
 	}
-	return nil
+	return e.index, nil
 }
 
 func (e *bleveEngine) withDeadline(ctx context.Context, d time.Duration, fn func(context.Context) error) error {
@@ -86,7 +103,8 @@ func (e *bleveEngine) withDeadline(ctx context.Context, d time.Duration, fn func
 }
 
 func (e *bleveEngine) Index(ctx context.Context, doc Doc) error {
-	if err := e.guard(); err != nil {
+	idx, err := e.current()
+	if err != nil {
 		return err
 	}
 	return e.withDeadline(ctx, e.cfg.QueryTimeout, func(ctx context.Context) error {
@@ -97,12 +115,13 @@ func (e *bleveEngine) Index(ctx context.Context, doc Doc) error {
 		if doc.Type != "" {
 			data["type"] = doc.Type
 		}
-		return e.index.Index(doc.ID, data)
+		return idx.Index(doc.ID, data)
 	})
 }
 
 func (e *bleveEngine) IndexBatch(ctx context.Context, docs []Doc) error {
-	if err := e.guard(); err != nil {
+	idx, err := e.current()
+	if err != nil {
 		return err
 	}
 	bs := e.cfg.BatchSize
@@ -115,7 +134,7 @@ func (e *bleveEngine) IndexBatch(ctx context.Context, docs []Doc) error {
 			if end > len(docs) {
 				end = len(docs)
 			}
-			b := e.index.NewBatch()
+			b := idx.NewBatch()
 			for _, d := range docs[i:end] {
 				data := make(map[string]any, len(d.Fields)+1)
 				for k, v := range d.Fields {
@@ -128,7 +147,7 @@ func (e *bleveEngine) IndexBatch(ctx context.Context, docs []Doc) error {
 					return err
 				}
 			}
-			if err := e.index.Batch(b); err != nil {
+			if err := idx.Batch(b); err != nil {
 				return err
 			}
 		}
@@ -137,19 +156,24 @@ func (e *bleveEngine) IndexBatch(ctx context.Context, docs []Doc) error {
 }
 
 func (e *bleveEngine) Delete(ctx context.Context, id string) error {
-	if err := e.guard(); err != nil {
+	idx, err := e.current()
+	if err != nil {
 		return err
 	}
 	return e.withDeadline(ctx, e.cfg.QueryTimeout, func(ctx context.Context) error {
-		return e.index.Delete(id)
+		return idx.Delete(id)
 	})
 }
 
 func (e *bleveEngine) Search(ctx context.Context, req SearchRequest) (SearchResult, error) {
-	if err := e.guard(); err != nil {
+	idx, err := e.current()
+	if err != nil {
 		return SearchResult{}, err
 	}
 
+	if req.Fuzziness <= 0 {
+		req.Fuzziness = e.cfg.Fuzziness
+	}
 	q := buildQuery(req, e.defaultFields)
 	sr := bleve.NewSearchRequest(q)
 
@@ -203,8 +227,8 @@ func (e *bleveEngine) Search(ctx context.Context, req SearchRequest) (SearchResu
 	}
 
 	var res *bleve.SearchResult
-	err := e.withDeadline(ctx, e.cfg.QueryTimeout, func(ctx context.Context) error {
-		r, e2 := e.index.Search(sr)
+	err = e.withDeadline(ctx, e.cfg.QueryTimeout, func(ctx context.Context) error {
+		r, e2 := idx.Search(sr)
 		if e2 != nil {
 			return e2
 		}
@@ -244,6 +268,28 @@ func (e *bleveEngine) Search(ctx context.Context, req SearchRequest) (SearchResu
 	return out, nil
 }
 
+func (e *bleveEngine) Rebuild(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return ErrClosed
+	}
+	if err := e.index.Close(); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(e.cfg.IndexPath); err != nil {
+		return err
+	}
+	idx, err := bleve.New(e.cfg.IndexPath, e.mapping)
+	if err != nil {
+		// 索引无法重建时标记为关闭，避免继续访问已关闭的索引
+		e.closed = true
+		return err
+	}
+	e.index = idx
+	return nil
+}
+
 func (e *bleveEngine) Close() error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -255,7 +301,8 @@ func (e *bleveEngine) Close() error {
 }
 
 func (e *bleveEngine) GetAutoCompleteSuggestions(ctx context.Context, keyword string) ([]string, error) {
-	if err := e.guard(); err != nil {
+	idx, err := e.current()
+	if err != nil {
 		return nil, err
 	}
 	if keyword == "" {
@@ -263,13 +310,13 @@ func (e *bleveEngine) GetAutoCompleteSuggestions(ctx context.Context, keyword st
 	}
 
 	var suggestions []string
-	err := e.withDeadline(ctx, e.cfg.QueryTimeout, func(ctx context.Context) error {
+	err = e.withDeadline(ctx, e.cfg.QueryTimeout, func(ctx context.Context) error {
 		// 这里假设你用前缀查询实现自动补全
 		query := bleve.NewPrefixQuery(keyword)
 		sr := bleve.NewSearchRequest(query)
 		sr.Size = 5 // 限制返回最多5个建议
 
-		searchResult, err := e.index.Search(sr)
+		searchResult, err := idx.Search(sr)
 		if err != nil {
 			return err
 		}
@@ -285,7 +332,8 @@ func (e *bleveEngine) GetAutoCompleteSuggestions(ctx context.Context, keyword st
 }
 
 func (e *bleveEngine) GetSearchSuggestions(ctx context.Context, keyword string) ([]string, error) {
-	if err := e.guard(); err != nil {
+	idx, err := e.current()
+	if err != nil {
 		return nil, err
 	}
 	if keyword == "" {
@@ -293,14 +341,14 @@ func (e *bleveEngine) GetSearchSuggestions(ctx context.Context, keyword string)
 	}
 
 	var suggestions []string
-	err := e.withDeadline(ctx, e.cfg.QueryTimeout, func(ctx context.Context) error {
+	err = e.withDeadline(ctx, e.cfg.QueryTimeout, func(ctx context.Context) error {
 		// 这里可以通过索引中的某些字段获取搜索建议
 		// 例如，你可以查询所有标题或者文章内容来生成相关建议
 		query := bleve.NewMatchQuery(keyword)
 		sr := bleve.NewSearchRequest(query)
 		sr.Size = 5 // 限制返回最多5个建议
 
-		searchResult, err := e.index.Search(sr)
+		searchResult, err := idx.Search(sr)
 		if err != nil {
 			return err
 		}
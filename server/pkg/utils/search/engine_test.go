@@ -489,3 +489,85 @@ func TestBleveEngine_WithDeadline_Timeout(t *testing.T) {
 		t.Fatalf("Expected timeout error, got nil")
 	}
 }
+
+func setupCJKTestEngine(t *testing.T, fuzziness int) (Engine, string) {
+	indexPath := filepath.Join(t.TempDir(), "index")
+	engine, err := Open(Config{
+		IndexPath:           indexPath,
+		DefaultSearchFields: []string{"title", "content"},
+		QueryTimeout:        5 * time.Second,
+		Fuzziness:           fuzziness,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open engine: %v", err)
+	}
+	return engine, indexPath
+}
+
+func TestBleveEngine_SearchChinese(t *testing.T) {
+	engine, indexPath := setupCJKTestEngine(t, 0)
+	defer cleanupTestEngine(t, engine, indexPath)
+
+	docs := []Doc{
+		{ID: "a1", Fields: map[string]interface{}{"title": "语音助手配置指南", "content": "如何配置语音识别和语音合成"}},
+		{ID: "a2", Fields: map[string]interface{}{"title": "知识库使用说明", "content": "上传文档并建立索引"}},
+	}
+	if err := engine.IndexBatch(context.Background(), docs); err != nil {
+		t.Fatalf("IndexBatch failed: %v", err)
+	}
+
+	result, err := engine.Search(context.Background(), SearchRequest{Keyword: "语音合成", Size: 10})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Hits) != 1 || result.Hits[0].ID != "a1" {
+		t.Fatalf("Expected only a1 to match, got %+v", result.Hits)
+	}
+}
+
+func TestBleveEngine_SearchFuzzy(t *testing.T) {
+	engine, indexPath := setupCJKTestEngine(t, 1)
+	defer cleanupTestEngine(t, engine, indexPath)
+
+	if err := engine.Index(context.Background(), Doc{ID: "d1", Fields: map[string]interface{}{"title": "Assistant settings"}}); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	result, err := engine.Search(context.Background(), SearchRequest{Keyword: "asistant", Size: 10})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Total != 1 {
+		t.Fatalf("Expected typo to match with fuzziness, got %d", result.Total)
+	}
+}
+
+func TestBleveEngine_Rebuild(t *testing.T) {
+	engine, indexPath := setupCJKTestEngine(t, 0)
+	defer cleanupTestEngine(t, engine, indexPath)
+
+	if err := engine.Index(context.Background(), Doc{ID: "d1", Fields: map[string]interface{}{"title": "hello"}}); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if err := engine.Rebuild(context.Background()); err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+
+	result, err := engine.Search(context.Background(), SearchRequest{Keyword: "hello", Size: 10})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Total != 0 {
+		t.Fatalf("Expected empty index after rebuild, got %d", result.Total)
+	}
+
+	if err := engine.Index(context.Background(), Doc{ID: "d2", Fields: map[string]interface{}{"title": "hello again"}}); err != nil {
+		t.Fatalf("Index after rebuild failed: %v", err)
+	}
+}
+
+func TestOpen_UnsupportedBackend(t *testing.T) {
+	if _, err := Open(Config{Backend: "solr"}); err == nil {
+		t.Fatalf("Expected error for unsupported backend")
+	}
+}
@@ -3,12 +3,21 @@ package search
 import (
 	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
 	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
 	"github.com/blevesearch/bleve/v2/mapping"
 )
 
+// 可选的文本分析器
+const (
+	AnalyzerStandard = standard.Name    // 英文分词，中文会被拆成单字
+	AnalyzerCJK      = cjk.AnalyzerName // 中日韩二元分词，英文按词切分并转小写
+)
+
+// BuildIndexMapping 构建索引映射，defaultAnalyzer 为空时使用 CJK 分析器以支持中文内容
+// 已存在的索引沿用创建时的映射，切换分析器后需要重建索引
 func BuildIndexMapping(defaultAnalyzer string) *mapping.IndexMappingImpl {
 	if defaultAnalyzer == "" {
-		defaultAnalyzer = standard.Name
+		defaultAnalyzer = AnalyzerCJK
 	}
 	idx := mapping.NewIndexMapping()
 	if idx == nil {
@@ -60,6 +69,7 @@ func BuildIndexMapping(defaultAnalyzer string) *mapping.IndexMappingImpl {
 	def.AddFieldMappingsAt("url", kw)           // URL
 	def.AddFieldMappingsAt("icon", kw)          // 图标
 	def.AddFieldMappingsAt("category", kw)      // 分类
+	def.AddFieldMappingsAt("knowledgeKey", kw)  // 知识库标识，用于检索知识库分片
 	idx.DefaultMapping = def
 	return idx
 }
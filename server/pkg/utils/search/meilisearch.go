@@ -0,0 +1,527 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnsupportedQuery Meilisearch 不支持的查询子句（通配符、正则）
+var ErrUnsupportedQuery = errors.New("query clause not supported by meilisearch backend")
+
+// DefaultFilterableFields Meilisearch 默认的可过滤字段，与 BuildIndexMapping 中的关键词字段一致
+var DefaultFilterableFields = []string{"userId", "type", "category", "knowledgeKey", "tags", "author", "createdAt", "views"}
+
+const (
+	meiliPrimaryKey   = "pk"
+	meiliDefaultIndex = "lingecho"
+	highlightPreTag   = "<mark>" // 与 Bleve html 高亮保持一致
+	highlightPostTag  = "</mark>"
+)
+
+// Meilisearch 主键只允许字母、数字、- 和 _
+var meiliKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,511}$`)
+
+// meiliEngine 外部 Meilisearch 实现，分词（含中文）与拼写容错由 Meilisearch 负责
+// 写入操作在 Meilisearch 中是异步任务，返回成功仅表示任务已入队
+type meiliEngine struct {
+	cfg        Config
+	baseURL    string
+	apiKey     string
+	index      string
+	httpClient *http.Client
+	mu         sync.RWMutex
+	closed     bool
+}
+
+// NewMeilisearch 连接 Meilisearch，创建索引（已存在时忽略）并同步可过滤字段与语言设置
+func NewMeilisearch(cfg Config) (Engine, error) {
+	if cfg.MeiliHost == "" {
+		return nil, fmt.Errorf("meilisearch host is required")
+	}
+	if cfg.MeiliIndex == "" {
+		cfg.MeiliIndex = meiliDefaultIndex
+	}
+	if len(cfg.Filterable) == 0 {
+		cfg.Filterable = DefaultFilterableFields
+	}
+
+	e := &meiliEngine{
+		cfg:        cfg,
+		baseURL:    strings.TrimRight(cfg.MeiliHost, "/"),
+		apiKey:     cfg.MeiliAPIKey,
+		index:      cfg.MeiliIndex,
+		httpClient: &http.Client{},
+	}
+
+	ctx := context.Background()
+	if cfg.OpenTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.OpenTimeout)
+		defer cancel()
+	}
+	if err := e.do(ctx, http.MethodGet, "/health", nil, nil); err != nil {
+		return nil, fmt.Errorf("meilisearch unavailable: %w", err)
+	}
+	if err := e.do(ctx, http.MethodPost, "/indexes", map[string]any{"uid": e.index, "primaryKey": meiliPrimaryKey}, nil); err != nil {
+		return nil, err
+	}
+
+	settings := map[string]any{
+		"filterableAttributes": cfg.Filterable,
+		"sortableAttributes":   cfg.Filterable,
+	}
+	if len(cfg.Locales) > 0 {
+		settings["localizedAttributes"] = []map[string]any{
+			{"attributePatterns": []string{"*"}, "locales": cfg.Locales},
+		}
+	}
+	if err := e.do(ctx, http.MethodPatch, e.indexPath("/settings"), settings, nil); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *meiliEngine) guard() error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+func (e *meiliEngine) indexPath(suffix string) string {
+	return "/indexes/" + e.index + suffix
+}
+
+// do 发送请求并在 out 非空时解析响应
+func (e *meiliEngine) do(ctx context.Context, method, path string, body any, out any) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if e.cfg.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.cfg.QueryTimeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, e.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("meilisearch request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("meilisearch %s: %s", apiErr.Code, apiErr.Message)
+		}
+		return fmt.Errorf("meilisearch request failed with status: %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// meiliKey 将文档 ID 转换为合法的 Meilisearch 主键，不合法的 ID 使用其 SHA1
+func meiliKey(id string) string {
+	if meiliKeyPattern.MatchString(id) {
+		return id
+	}
+	sum := sha1.Sum([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// toMeiliDocument 展开文档字段；时间转换为 Unix 秒以便进行范围过滤
+func toMeiliDocument(doc Doc) map[string]any {
+	data := make(map[string]any, len(doc.Fields)+3)
+	for k, v := range doc.Fields {
+		switch t := v.(type) {
+		case time.Time:
+			data[k] = t.Unix()
+		case *time.Time:
+			if t != nil {
+				data[k] = t.Unix()
+			}
+		default:
+			data[k] = v
+		}
+	}
+	if doc.Type != "" {
+		data["type"] = doc.Type
+	}
+	data["id"] = doc.ID
+	data[meiliPrimaryKey] = meiliKey(doc.ID)
+	return data
+}
+
+func (e *meiliEngine) Index(ctx context.Context, doc Doc) error {
+	return e.IndexBatch(ctx, []Doc{doc})
+}
+
+func (e *meiliEngine) IndexBatch(ctx context.Context, docs []Doc) error {
+	if err := e.guard(); err != nil {
+		return err
+	}
+	bs := e.cfg.BatchSize
+	if bs <= 0 {
+		bs = 200
+	}
+	for i := 0; i < len(docs); i += bs {
+		end := i + bs
+		if end > len(docs) {
+			end = len(docs)
+		}
+		batch := make([]map[string]any, 0, end-i)
+		for _, d := range docs[i:end] {
+			batch = append(batch, toMeiliDocument(d))
+		}
+		if err := e.do(ctx, http.MethodPost, e.indexPath("/documents"), batch, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *meiliEngine) Delete(ctx context.Context, id string) error {
+	if err := e.guard(); err != nil {
+		return err
+	}
+	return e.do(ctx, http.MethodDelete, e.indexPath("/documents/"+meiliKey(id)), nil, nil)
+}
+
+// Rebuild 删除索引中的全部文档，设置保持不变
+func (e *meiliEngine) Rebuild(ctx context.Context) error {
+	if err := e.guard(); err != nil {
+		return err
+	}
+	return e.do(ctx, http.MethodDelete, e.indexPath("/documents"), nil, nil)
+}
+
+type meiliSearchResponse struct {
+	Hits               []map[string]any          `json:"hits"`
+	EstimatedTotalHits uint64                    `json:"estimatedTotalHits"`
+	TotalHits          uint64                    `json:"totalHits"`
+	ProcessingTimeMs   int64                     `json:"processingTimeMs"`
+	FacetDistribution  map[string]map[string]int `json:"facetDistribution"`
+}
+
+func (e *meiliEngine) Search(ctx context.Context, req SearchRequest) (SearchResult, error) {
+	if err := e.guard(); err != nil {
+		return SearchResult{}, err
+	}
+	body, err := buildMeiliSearch(req, e.cfg.DefaultSearchFields)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	var resp meiliSearchResponse
+	if err := e.do(ctx, http.MethodPost, e.indexPath("/search"), body, &resp); err != nil {
+		return SearchResult{}, err
+	}
+
+	total := resp.EstimatedTotalHits
+	if resp.TotalHits > 0 {
+		total = resp.TotalHits
+	}
+	out := SearchResult{
+		Total:  total,
+		Took:   time.Duration(resp.ProcessingTimeMs) * time.Millisecond,
+		Hits:   make([]Hit, 0, len(resp.Hits)),
+		Facets: map[string]FacetResult{},
+	}
+	for _, h := range resp.Hits {
+		out.Hits = append(out.Hits, toHit(h))
+	}
+	for _, f := range req.Facets {
+		if dist, ok := resp.FacetDistribution[f.Field]; ok {
+			out.Facets[f.Name] = toFacetResult(dist, f.Size)
+		}
+	}
+	return out, nil
+}
+
+// buildMeiliSearch 将 SearchRequest 转换为 Meilisearch 搜索请求
+// 关键字、Match、Phrase、Prefix、Fuzzy 子句合并为 q，Term 与范围条件转换为 filter
+func buildMeiliSearch(req SearchRequest, defaultFields []string) (map[string]any, error) {
+	if len(req.Wildcards) > 0 || len(req.Regexps) > 0 {
+		return nil, ErrUnsupportedQuery
+	}
+
+	terms := make([]string, 0, 4)
+	if kw := strings.TrimSpace(req.Keyword); kw != "" {
+		terms = append(terms, kw)
+	}
+	if req.QueryString != nil && req.QueryString.Query != "" {
+		terms = append(terms, req.QueryString.Query)
+	}
+	for _, m := range req.Matches {
+		terms = append(terms, m.Query)
+	}
+	for _, p := range req.Phrases {
+		terms = append(terms, strconv.Quote(p.Phrase))
+	}
+	for _, f := range req.Fuzzies {
+		terms = append(terms, f.Term)
+	}
+	for _, p := range req.Prefixes {
+		terms = append(terms, p.Prefix)
+	}
+
+	size := req.Size
+	if size <= 0 {
+		size = 10
+	}
+	from := req.From
+	if from < 0 {
+		from = 0
+	}
+	body := map[string]any{
+		"q":                strings.Join(terms, " "),
+		"offset":           from,
+		"limit":            size,
+		"showRankingScore": true,
+	}
+
+	fields := req.SearchFields
+	if len(fields) == 0 {
+		fields = defaultFields
+	}
+	if len(fields) > 0 {
+		body["attributesToSearchOn"] = fields
+	}
+	if filter := buildMeiliFilter(req); len(filter) > 0 {
+		body["filter"] = filter
+	}
+	if len(req.IncludeFields) > 0 {
+		body["attributesToRetrieve"] = append([]string{"id"}, req.IncludeFields...)
+	}
+	if sortBy := toMeiliSort(req.SortBy); len(sortBy) > 0 {
+		body["sort"] = sortBy
+	}
+	if req.Highlight {
+		attrs := req.HighlightFields
+		if len(attrs) == 0 {
+			attrs = []string{"*"}
+		}
+		body["attributesToHighlight"] = attrs
+		body["highlightPreTag"] = highlightPreTag
+		body["highlightPostTag"] = highlightPostTag
+		if req.FragmentSize > 0 {
+			body["attributesToCrop"] = attrs
+			body["cropLength"] = req.FragmentSize
+		}
+	}
+	if len(req.Facets) > 0 {
+		facets := make([]string, 0, len(req.Facets))
+		for _, f := range req.Facets {
+			facets = append(facets, f.Field)
+		}
+		body["facets"] = facets
+	}
+	return body, nil
+}
+
+// buildMeiliFilter 生成 filter 数组，数组元素之间为 AND 关系
+func buildMeiliFilter(req SearchRequest) []string {
+	var filter []string
+	for f, vs := range req.MustTerms {
+		if len(vs) == 1 {
+			filter = append(filter, fmt.Sprintf("%s = %s", f, strconv.Quote(vs[0])))
+		} else if len(vs) > 1 {
+			filter = append(filter, fmt.Sprintf("%s IN [%s]", f, quoteAll(vs)))
+		}
+	}
+	for f, vs := range req.MustNotTerms {
+		if len(vs) > 0 {
+			filter = append(filter, fmt.Sprintf("%s NOT IN [%s]", f, quoteAll(vs)))
+		}
+	}
+	var should []string
+	for f, vs := range req.ShouldTerms {
+		for _, v := range vs {
+			should = append(should, fmt.Sprintf("%s = %s", f, strconv.Quote(v)))
+		}
+	}
+	if len(should) > 0 {
+		sort.Strings(should)
+		filter = append(filter, "("+strings.Join(should, " OR ")+")")
+	}
+	for _, r := range req.NumericRanges {
+		filter = append(filter, rangeConditions(r.Field, r.GTE, r.GT, r.LTE, r.LT)...)
+	}
+	for _, r := range req.TimeRanges {
+		if r.From != nil {
+			op := ">"
+			if r.IncFrom {
+				op = ">="
+			}
+			filter = append(filter, fmt.Sprintf("%s %s %d", r.Field, op, r.From.Unix()))
+		}
+		if r.To != nil {
+			op := "<"
+			if r.IncTo {
+				op = "<="
+			}
+			filter = append(filter, fmt.Sprintf("%s %s %d", r.Field, op, r.To.Unix()))
+		}
+	}
+	return filter
+}
+
+func rangeConditions(field string, gte, gt, lte, lt *float64) []string {
+	var conds []string
+	add := func(op string, v *float64) {
+		if v != nil {
+			conds = append(conds, fmt.Sprintf("%s %s %s", field, op, strconv.FormatFloat(*v, 'f', -1, 64)))
+		}
+	}
+	add(">=", gte)
+	add(">", gt)
+	add("<=", lte)
+	add("<", lt)
+	return conds
+}
+
+func quoteAll(vs []string) string {
+	quoted := make([]string, 0, len(vs))
+	for _, v := range vs {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// toMeiliSort 将 Bleve 排序语法（"-field" 降序，"_score" 相关度）转换为 "field:desc"
+func toMeiliSort(sortBy []string) []string {
+	out := make([]string, 0, len(sortBy))
+	for _, s := range sortBy {
+		if s == "" || s == "_score" || s == "-_score" {
+			continue // Meilisearch 默认按相关度排序
+		}
+		if strings.HasPrefix(s, "-") {
+			out = append(out, s[1:]+":desc")
+		} else {
+			out = append(out, s+":asc")
+		}
+	}
+	return out
+}
+
+func toHit(h map[string]any) Hit {
+	hit := Hit{Fields: make(map[string]any, len(h))}
+	for k, v := range h {
+		switch k {
+		case meiliPrimaryKey:
+		case "_rankingScore":
+			hit.Score, _ = v.(float64)
+		case "_formatted":
+			formatted, _ := v.(map[string]any)
+			for field, fv := range formatted {
+				if s, ok := fv.(string); ok && strings.Contains(s, highlightPreTag) {
+					if hit.Fragments == nil {
+						hit.Fragments = make(map[string][]string)
+					}
+					hit.Fragments[field] = []string{s}
+				}
+			}
+		default:
+			hit.Fields[k] = v
+		}
+	}
+	hit.ID, _ = h["id"].(string)
+	return hit
+}
+
+func toFacetResult(dist map[string]int, size int) FacetResult {
+	if size <= 0 {
+		size = 10
+	}
+	fr := FacetResult{}
+	for term, count := range dist {
+		fr.Total += count
+		fr.Terms = append(fr.Terms, FacetTerm{Term: term, Count: count})
+	}
+	sort.Slice(fr.Terms, func(i, j int) bool {
+		if fr.Terms[i].Count != fr.Terms[j].Count {
+			return fr.Terms[i].Count > fr.Terms[j].Count
+		}
+		return fr.Terms[i].Term < fr.Terms[j].Term
+	})
+	if len(fr.Terms) > size {
+		fr.Terms = fr.Terms[:size]
+	}
+	return fr
+}
+
+func (e *meiliEngine) GetAutoCompleteSuggestions(ctx context.Context, keyword string) ([]string, error) {
+	return e.suggest(ctx, keyword)
+}
+
+func (e *meiliEngine) GetSearchSuggestions(ctx context.Context, keyword string) ([]string, error) {
+	return e.suggest(ctx, keyword)
+}
+
+// suggest Meilisearch 默认对最后一个词做前缀匹配，自动补全与搜索建议共用同一查询
+func (e *meiliEngine) suggest(ctx context.Context, keyword string) ([]string, error) {
+	if err := e.guard(); err != nil {
+		return nil, err
+	}
+	if keyword == "" {
+		return []string{}, nil
+	}
+	var resp meiliSearchResponse
+	body := map[string]any{"q": keyword, "limit": 5, "attributesToRetrieve": []string{"id"}}
+	if err := e.do(ctx, http.MethodPost, e.indexPath("/search"), body, &resp); err != nil {
+		return nil, err
+	}
+	suggestions := make([]string, 0, len(resp.Hits))
+	for _, h := range resp.Hits {
+		if id, ok := h["id"].(string); ok {
+			suggestions = append(suggestions, id)
+		}
+	}
+	return suggestions, nil
+}
+
+func (e *meiliEngine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+	e.httpClient.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,97 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMeilisearch 记录收到的请求并返回固定的搜索结果
+func fakeMeilisearch(t *testing.T, requests map[string]map[string]any) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests[r.Method+" "+r.URL.Path] = body
+
+		switch r.URL.Path {
+		case "/health":
+			_, _ = w.Write([]byte(`{"status":"available"}`))
+		case "/indexes/docs/search":
+			_, _ = w.Write([]byte(`{
+				"hits": [{"pk": "chat_1", "id": "chat_1", "title": "语音合成", "_rankingScore": 0.9,
+					"_formatted": {"title": "<mark>语音</mark>合成"}}],
+				"estimatedTotalHits": 1,
+				"processingTimeMs": 3,
+				"facetDistribution": {"type": {"chat": 3, "assistant": 5}}
+			}`))
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"taskUid": 1}`))
+		}
+	}))
+}
+
+func TestMeilisearchEngine(t *testing.T) {
+	requests := map[string]map[string]any{}
+	server := fakeMeilisearch(t, requests)
+	defer server.Close()
+
+	engine, err := Open(Config{Backend: BackendMeilisearch, MeiliHost: server.URL, MeiliAPIKey: "secret", MeiliIndex: "docs", Locales: []string{"cmn"}})
+	require.NoError(t, err)
+	defer engine.Close()
+
+	settings := requests["PATCH /indexes/docs/settings"]
+	require.NotNil(t, settings)
+	assert.Contains(t, settings["filterableAttributes"], "userId")
+	assert.NotNil(t, settings["localizedAttributes"])
+
+	createdAt := time.Unix(1700000000, 0)
+	require.NoError(t, engine.Index(context.Background(), Doc{ID: "knowledge_a/b", Type: "knowledge", Fields: map[string]any{"createdAt": createdAt}}))
+	require.NoError(t, engine.Delete(context.Background(), "knowledge_a/b"))
+	assert.Contains(t, requests, "DELETE /indexes/docs/documents/"+meiliKey("knowledge_a/b"))
+
+	from := time.Unix(1600000000, 0)
+	result, err := engine.Search(context.Background(), SearchRequest{
+		Keyword:    "语音",
+		MustTerms:  map[string][]string{"userId": {"1"}},
+		TimeRanges: []TimeRangeFilter{{Field: "createdAt", From: &from, IncFrom: true}},
+		SortBy:     []string{"-createdAt", "_score"},
+		Facets:     []FacetRequest{{Name: "types", Field: "type", Size: 1}},
+		Highlight:  true,
+	})
+	require.NoError(t, err)
+
+	search := requests["POST /indexes/docs/search"]
+	assert.Equal(t, "语音", search["q"])
+	assert.Equal(t, []any{`userId = "1"`, "createdAt >= 1600000000"}, search["filter"])
+	assert.Equal(t, []any{"createdAt:desc"}, search["sort"])
+
+	require.Len(t, result.Hits, 1)
+	hit := result.Hits[0]
+	assert.Equal(t, "chat_1", hit.ID)
+	assert.Equal(t, 0.9, hit.Score)
+	assert.NotContains(t, hit.Fields, "pk")
+	assert.Equal(t, []string{"<mark>语音</mark>合成"}, hit.Fragments["title"])
+	assert.Equal(t, 8, result.Facets["types"].Total)
+	assert.Equal(t, []FacetTerm{{Term: "assistant", Count: 5}}, result.Facets["types"].Terms)
+
+	_, err = engine.Search(context.Background(), SearchRequest{Wildcards: []ClauseWildcard{{Field: "title", Pattern: "语*"}}})
+	assert.ErrorIs(t, err, ErrUnsupportedQuery)
+
+	require.NoError(t, engine.Rebuild(context.Background()))
+	assert.Contains(t, requests, "DELETE /indexes/docs/documents")
+}
+
+func TestMeiliKey(t *testing.T) {
+	assert.Equal(t, "assistant_12", meiliKey("assistant_12"))
+	key := meiliKey("knowledge_中文")
+	assert.Len(t, key, 40)
+	assert.Equal(t, key, meiliKey("knowledge_中文"))
+}
@@ -36,7 +36,12 @@ func buildQuery(req SearchRequest, defaultFields []string) q.Query {
 			}
 		}
 		if qs != "" {
-			must = append(must, bleve.NewQueryStringQuery(qs))
+			if req.Fuzziness > 0 {
+				// 拼写容错：精确查询与各字段的模糊匹配取并集，精确命中得分更高
+				must = append(must, bleve.NewDisjunctionQuery(append([]q.Query{bleve.NewQueryStringQuery(qs)}, fuzzyKeywordQueries(keyword, fields, req.Fuzziness)...)...))
+			} else {
+				must = append(must, bleve.NewQueryStringQuery(qs))
+			}
 		}
 	}
 
@@ -224,3 +229,23 @@ func rMax(n NumericRangeFilter) *float64 {
 	return nil
 }
 func boolPtr(b bool) *bool { return &b }
+
+// fuzzyKeywordQueries 为关键字在每个字段上构建带编辑距离的 Match 查询，fields 为空时查询 _all
+func fuzzyKeywordQueries(keyword string, fields []string, fuzziness int) []q.Query {
+	if fuzziness > 2 {
+		fuzziness = 2 // Bleve 支持的最大编辑距离
+	}
+	if len(fields) == 0 {
+		fields = []string{""}
+	}
+	queries := make([]q.Query, 0, len(fields))
+	for _, f := range fields {
+		mq := bleve.NewMatchQuery(keyword)
+		if f != "" {
+			mq.SetField(f)
+		}
+		mq.SetFuzziness(fuzziness)
+		queries = append(queries, mq)
+	}
+	return queries
+}
@@ -1,20 +1,30 @@
 package search
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"sync/atomic"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
 	"github.com/code-100-precent/LingEcho/pkg/constants"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"github.com/code-100-precent/LingEcho/pkg/response"
 	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+// ReindexFunc 将全部数据重新写入搜索引擎
+type ReindexFunc func(ctx context.Context, engine Engine) error
+
 // SearchHandlers 封装搜索相关的API处理
 type SearchHandlers struct {
-	engine Engine
-	db     *gorm.DB
+	engine     Engine
+	db         *gorm.DB
+	reindex    ReindexFunc
+	rebuilding atomic.Bool
 }
 
 // SetDB 设置数据库连接（用于检查配置）
@@ -22,6 +32,11 @@ func (h *SearchHandlers) SetDB(db *gorm.DB) {
 	h.db = db
 }
 
+// SetReindexer 设置重建索引后用于回填数据的函数
+func (h *SearchHandlers) SetReindexer(fn ReindexFunc) {
+	h.reindex = fn
+}
+
 // GetEngine 获取搜索引擎实例
 func (h *SearchHandlers) GetEngine() Engine {
 	return h.engine
@@ -59,6 +74,8 @@ func (h *SearchHandlers) RegisterSearchRoutes(r *gin.RouterGroup) {
 	r.POST("/search/auto-complete", h.handleAutoComplete)
 	// 搜索建议接口
 	r.POST("/search/suggest", h.handleSuggest)
+	// 重建索引接口（仅管理员）
+	r.POST("/search/rebuild", h.handleRebuild)
 }
 
 // handleSearch 处理搜索请求
@@ -242,3 +259,44 @@ func (h *SearchHandlers) handleSuggest(c *gin.Context) {
 
 	response.Success(c, "Get Suggestion successfully", suggestions)
 }
+
+// handleRebuild 清空索引并在后台重新写入全部数据，用于切换分析器或修复索引
+func (h *SearchHandlers) handleRebuild(c *gin.Context) {
+	user := models.CurrentUser(c)
+	if user == nil || !user.IsAdmin() {
+		response.AbortWithStatusJSON(c, http.StatusForbidden, fmt.Errorf("admin permission required"))
+		return
+	}
+
+	if h.engine == nil {
+		response.Fail(c, "Search engine not initialized", gin.H{"error": "搜索引擎未初始化"})
+		return
+	}
+
+	if !h.rebuilding.CompareAndSwap(false, true) {
+		response.Fail(c, "Index rebuild already in progress", nil)
+		return
+	}
+
+	if err := h.engine.Rebuild(c.Request.Context()); err != nil {
+		h.rebuilding.Store(false)
+		response.Fail(c, "Failed to rebuild index", gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.reindex == nil {
+		h.rebuilding.Store(false)
+		response.Success(c, "Index cleared", nil)
+		return
+	}
+
+	go func() {
+		defer h.rebuilding.Store(false)
+		if err := h.reindex(context.Background(), h.engine); err != nil {
+			logger.Error("Search reindex failed", zap.Error(err))
+			return
+		}
+		logger.Info("Search reindex completed")
+	}()
+	response.Success(c, "Index rebuild started", nil)
+}
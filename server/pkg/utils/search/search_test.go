@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"github.com/code-100-precent/LingEcho/pkg/config"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,6 +23,7 @@ type mockEngine struct {
 	searchFunc               func(ctx context.Context, req SearchRequest) (SearchResult, error)
 	getAutoCompleteFunc      func(ctx context.Context, keyword string) ([]string, error)
 	getSearchSuggestionsFunc func(ctx context.Context, keyword string) ([]string, error)
+	rebuildFunc              func(ctx context.Context) error
 	closeFunc                func() error
 }
 
@@ -66,6 +69,13 @@ func (m *mockEngine) GetSearchSuggestions(ctx context.Context, keyword string) (
 	return []string{}, nil
 }
 
+func (m *mockEngine) Rebuild(ctx context.Context) error {
+	if m.rebuildFunc != nil {
+		return m.rebuildFunc(ctx)
+	}
+	return nil
+}
+
 func (m *mockEngine) Close() error {
 	if m.closeFunc != nil {
 		return m.closeFunc()
@@ -265,3 +275,24 @@ func TestSearchHandlers_HandleSuggest_Success(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestSearchHandlers_HandleRebuild_RequiresAdmin(t *testing.T) {
+	router := setupTestRouter()
+	router.Use(sessions.Sessions("test-session", cookie.NewStore([]byte("secret"))))
+	rebuilt := false
+	mock := &mockEngine{
+		rebuildFunc: func(ctx context.Context) error {
+			rebuilt = true
+			return nil
+		},
+	}
+	handlers := NewSearchHandlers(mock)
+	handlers.RegisterSearchRoutes(router.Group("/api"))
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("POST", "/api/search/rebuild", nil)
+	router.ServeHTTP(w, httpReq)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, rebuilt)
+}
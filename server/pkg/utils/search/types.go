@@ -2,13 +2,29 @@ package search
 
 import "time"
 
+// 搜索后端
+const (
+	BackendBleve       = "bleve"       // 内嵌 Bleve 索引（默认）
+	BackendMeilisearch = "meilisearch" // 外部 Meilisearch 服务
+)
+
 type Config struct {
+	Backend             string // BackendBleve / BackendMeilisearch，默认 bleve
 	IndexPath           string
 	DefaultAnalyzer     string
 	DefaultSearchFields []string
 	OpenTimeout         time.Duration
 	QueryTimeout        time.Duration
 	BatchSize           int
+	// Fuzziness 关键字搜索的默认容错编辑距离（仅 Bleve，Meilisearch 自带拼写容错），0 表示精确匹配
+	Fuzziness int
+
+	// Meilisearch 连接配置
+	MeiliHost   string
+	MeiliAPIKey string
+	MeiliIndex  string   // 索引 UID，默认 lingecho
+	Filterable  []string // 可过滤/聚合字段，默认 DefaultFilterableFields
+	Locales     []string // 文本语言提示（如 cmn、eng），为空时由 Meilisearch 自动检测
 }
 
 type Doc struct {
@@ -91,6 +107,7 @@ type SearchRequest struct {
 	// 关键字（保留老接口）
 	Keyword      string
 	SearchFields []string
+	Fuzziness    int // 关键字容错编辑距离，0 时使用引擎默认值
 
 	// 结构化 Term
 	MustTerms    map[string][]string
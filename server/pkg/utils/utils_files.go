@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -100,3 +102,53 @@ func RemoveFile(path string) error {
 func RemoveDirectory(path string) error {
 	return os.RemoveAll(path)
 }
+
+// ResolvePathInDir 将用户提供的相对或绝对路径解析为 dir 内的路径，
+// 拒绝跳出目录（包括经由符号链接）的路径，用于只允许选择服务器指定目录下的模型等文件
+func ResolvePathInDir(dir, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+	if !withinDir(root, path) {
+		return "", fmt.Errorf("%q is outside %s", name, dir)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("directory %s: %w", dir, err)
+	}
+	if real, ok := realPath(path); !ok || !withinDir(realRoot, real) {
+		return "", fmt.Errorf("%q is outside %s", name, dir)
+	}
+	return path, nil
+}
+
+// realPath 解析路径中已存在部分的符号链接，尚不存在的部分原样拼接；悬空的符号链接无法判断指向，返回 false
+func realPath(path string) (string, bool) {
+	rest := ""
+	for p := path; ; p = filepath.Dir(p) {
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(real, rest), true
+		}
+		if _, err := os.Lstat(p); err == nil {
+			return "", false
+		}
+		if filepath.Dir(p) == p {
+			return path, true
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+	}
+}
+
+func withinDir(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -69,6 +69,7 @@ func (h *Handler) HandleWebSocket(
 	enableVAD := true
 	vadThreshold := 500.0
 	vadConsecutiveFrames := 2
+	ttsCache := true
 	if assistantID > 0 && db != nil {
 		var assistant models.Assistant
 		if err := db.First(&assistant, assistantID).Error; err == nil {
@@ -89,6 +90,7 @@ func (h *Handler) HandleWebSocket(
 			if assistant.VADConsecutiveFrames > 0 {
 				vadConsecutiveFrames = assistant.VADConsecutiveFrames
 			}
+			ttsCache = assistant.TTSCacheEnabled
 		}
 	}
 
@@ -117,6 +119,7 @@ func (h *Handler) HandleWebSocket(
 		EnableVAD:            enableVAD,
 		VADThreshold:         vadThreshold,
 		VADConsecutiveFrames: vadConsecutiveFrames,
+		TTSCache:             ttsCache,
 	}
 
 	// 创建会话
@@ -7,6 +7,7 @@ import (
 
 	"github.com/code-100-precent/LingEcho/internal/models"
 	"github.com/code-100-precent/LingEcho/pkg/recognizer"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/code-100-precent/LingEcho/pkg/voice/asr"
 	"github.com/code-100-precent/LingEcho/pkg/voice/errhandler"
 	"github.com/code-100-precent/LingEcho/pkg/voice/factory"
@@ -17,14 +18,11 @@ import (
 	"github.com/code-100-precent/LingEcho/pkg/voice/tts"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
 const (
 	// AudioSampleRate 音频采样率，用于计算音频大小
 	AudioSampleRate = 32000
-	// ASRUsageRecordTimeout ASR使用量记录超时时间
-	ASRUsageRecordTimeout = 5 * time.Second
 	// ASRConnectionWaitDelay ASR连接建立后的等待时间
 	ASRConnectionWaitDelay = 500 * time.Millisecond
 )
@@ -44,6 +42,7 @@ type Session struct {
 	vadDetector   *VADDetector // VAD 检测器用于 barge-in
 	mu            sync.RWMutex
 	active        bool
+	startedAt     time.Time
 }
 
 // NewSession 创建新的语音会话
@@ -96,6 +95,8 @@ func NewSession(config *SessionConfig) (*Session, error) {
 	// 创建服务工厂
 	transcriberFactory := recognizer.GetGlobalFactory()
 	serviceFactory := factory.NewServiceFactory(transcriberFactory, config.Logger)
+	serviceFactory.SetTTSCache(config.TTSCache)
+	serviceFactory.SetTTSLanguage(config.Language)
 
 	// 创建消息写入器
 	messageWriter := message.NewWriter(config.Conn, config.Logger)
@@ -179,8 +180,8 @@ func NewSession(config *SessionConfig) (*Session, error) {
 	asrService.SetCallbacks(
 		func(text string, isLast bool, duration time.Duration, uuid string) {
 			// 记录ASR使用量
-			if isLast && config.DB != nil && config.Credential != nil && duration > 0 {
-				go recordASRUsage(ctx, config, duration, uuid, config.Logger)
+			if isLast && duration > 0 {
+				meterUsage(config, models.UsageTypeASR, uuid, int(duration.Seconds()), int64(duration.Seconds()*AudioSampleRate))
 			}
 
 			// 处理ASR结果
@@ -239,6 +240,7 @@ func (s *Session) Start() error {
 	}
 
 	s.active = true
+	s.startedAt = time.Now()
 
 	// 启动消息处理循环
 	go s.messageLoop()
@@ -284,6 +286,9 @@ func (s *Session) Stop() error {
 
 	s.active = false
 
+	// 按会话时长记录通话使用量
+	meterUsage(s.config, models.UsageTypeCall, "", int(time.Since(s.startedAt).Seconds()), 0)
+
 	return nil
 }
 
@@ -391,44 +396,25 @@ func (s *Session) messageLoop() {
 	}
 }
 
-// recordASRUsage 记录ASR使用量
-func recordASRUsage(ctx context.Context, config *SessionConfig, duration time.Duration, uuid string, logger *zap.Logger) {
-	// 创建带超时的上下文
-	recordCtx, cancel := context.WithTimeout(ctx, ASRUsageRecordTimeout)
-	defer cancel()
-
-	// 计算音频大小
-	audioSize := int64(duration.Seconds() * AudioSampleRate)
+// meterUsage 发出计量事件，由计费监听器按凭证记录使用量
+func meterUsage(config *SessionConfig, usageType models.UsageType, sessionID string, duration int, audioSize int64) {
+	if config.DB == nil || config.Credential == nil {
+		return
+	}
 
-	// 准备助手ID
 	var assistantID *uint
 	if config.AssistantID > 0 {
 		aid := uint(config.AssistantID)
 		assistantID = &aid
 	}
 
-	// 获取组织ID（如果助手属于组织）
-	var groupID *uint
-	if assistantID != nil && config.DB != nil {
-		var assistant models.Assistant
-		if err := config.DB.WithContext(recordCtx).Where("id = ?", *assistantID).First(&assistant).Error; err == nil {
-			groupID = assistant.GroupID
-		} else if err != gorm.ErrRecordNotFound {
-			logger.Warn("查询助手信息失败", zap.Error(err))
-		}
-	}
-
-	// 记录使用量
-	if err := models.RecordASRUsage(
-		config.DB,
-		config.Credential.UserID,
-		config.Credential.ID,
-		assistantID,
-		groupID,
-		uuid,
-		int(duration.Seconds()),
-		audioSize,
-	); err != nil {
-		logger.Warn("记录ASR使用量失败", zap.Error(err))
-	}
+	utils.Sig().Emit(models.SigUsageMetered, &models.UsageEvent{
+		UsageType:    usageType,
+		UserID:       config.Credential.UserID,
+		CredentialID: config.Credential.ID,
+		AssistantID:  assistantID,
+		SessionID:    sessionID,
+		Duration:     duration,
+		AudioSize:    audioSize,
+	})
 }
@@ -3,9 +3,11 @@ package tts
 import (
 	"context"
 	"sync"
+	"unicode/utf8"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
 	"github.com/code-100-precent/LingEcho/pkg/synthesizer"
+	"github.com/code-100-precent/LingEcho/pkg/utils"
 	"github.com/code-100-precent/LingEcho/pkg/voice/errhandler"
 	"go.uber.org/zap"
 )
@@ -78,16 +80,36 @@ func (s *Service) Synthesize(ctx context.Context, text string) (<-chan []byte, e
 			case <-ctx.Done():
 			case audioChan <- nil: // nil表示错误
 			}
+			return
 		}
+		s.meterUsage(text, handler.audioSize)
 	}()
 
 	return audioChan, nil
 }
 
+// meterUsage 按凭证记录TTS合成字符数与音频时长
+func (s *Service) meterUsage(text string, audioSize int64) {
+	if s.credential == nil || audioSize == 0 {
+		return
+	}
+	// 按 16kHz、16bit、单声道估算时长
+	duration := int(audioSize / 32000)
+	utils.Sig().Emit(models.SigUsageMetered, &models.UsageEvent{
+		UsageType:    models.UsageTypeTTS,
+		UserID:       s.credential.UserID,
+		CredentialID: s.credential.ID,
+		Duration:     duration,
+		AudioSize:    audioSize,
+		Characters:   utf8.RuneCountInString(text),
+	})
+}
+
 // synthesisHandler 实现 SynthesisHandler 接口
 type synthesisHandler struct {
 	audioChan chan []byte
 	ctx       context.Context
+	audioSize int64
 }
 
 func (h *synthesisHandler) OnMessage(data []byte) {
@@ -95,6 +117,7 @@ func (h *synthesisHandler) OnMessage(data []byte) {
 	case <-h.ctx.Done():
 		return
 	case h.audioChan <- data:
+		h.audioSize += int64(len(data))
 	}
 }
 
@@ -33,6 +33,7 @@ type SessionConfig struct {
 	EnableVAD            bool    // 是否启用VAD
 	VADThreshold         float64 // VAD阈值
 	VADConsecutiveFrames int     // 需要连续超过阈值的帧数
+	TTSCache             bool    // 是否复用已合成的短句音频
 }
 
 // SessionInterface 语音会话接口（避免与实现类冲突）
@@ -0,0 +1,152 @@
+package voiceclone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrQuotaUnsupported 提供商不支持查询剩余训练次数
+var ErrQuotaUnsupported = errors.New("voiceclone: provider does not support training quota queries")
+
+// Capabilities 提供商的音色克隆能力，前端据此展示可选语言、录音要求和训练流程
+type Capabilities struct {
+	Provider             Provider `json:"provider"`
+	Name                 string   `json:"name"`
+	Languages            []string `json:"languages"`            // 支持的语言代码
+	AudioFormats         []string `json:"audioFormats"`         // 训练音频格式
+	TrainingSampleRates  []int    `json:"trainingSampleRates"`  // 推荐的训练音频采样率
+	SynthesisSampleRates []int    `json:"synthesisSampleRates"` // 合成输出采样率
+	MinAudioSeconds      float64  `json:"minAudioSeconds"`      // 单段训练音频最短时长
+	MaxAudioSeconds      float64  `json:"maxAudioSeconds"`      // 单段训练音频最长时长
+	MaxAudioBytes        int64    `json:"maxAudioBytes"`        // 单段训练音频最大字节数
+	MaxUploads           int      `json:"maxUploads,omitempty"` // 同一音色最多上传次数，0 表示不限
+	TrainingText         bool     `json:"trainingText"`         // 录音需朗读平台提供的训练文本
+	CreateTask           bool     `json:"createTask"`           // 需先创建训练任务，否则直接用控制台分配的 speaker_id 上传
+	Streaming            bool     `json:"streaming"`            // 支持流式合成
+	TrainingQuota        bool     `json:"trainingQuota"`        // 支持查询剩余训练次数
+}
+
+// SupportsLanguage 判断是否支持语言，zh-CN 等地区代码按语言部分匹配
+func (c Capabilities) SupportsLanguage(language string) bool {
+	language = strings.ToLower(language)
+	if i := strings.IndexAny(language, "-_"); i > 0 {
+		language = language[:i]
+	}
+	for _, l := range c.Languages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
+
+// ProviderSpec 注册的提供商：能力描述、按配置创建服务、从环境变量读取配置
+type ProviderSpec struct {
+	Capabilities Capabilities
+	New          func(options map[string]interface{}) (VoiceCloneService, error)
+	EnvOptions   func() map[string]interface{}
+}
+
+var (
+	registryMu    sync.RWMutex
+	registry      = map[Provider]ProviderSpec{}
+	registryOrder []Provider
+)
+
+// RegisterProvider 注册提供商，重复注册覆盖之前的定义
+func RegisterProvider(spec ProviderSpec) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	p := spec.Capabilities.Provider
+	if _, exists := registry[p]; !exists {
+		registryOrder = append(registryOrder, p)
+	}
+	registry[p] = spec
+}
+
+func lookupProvider(p Provider) (ProviderSpec, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	spec, ok := registry[p]
+	return spec, ok
+}
+
+// GetCapabilities 获取提供商的能力描述
+func GetCapabilities(p Provider) (Capabilities, bool) {
+	spec, ok := lookupProvider(p)
+	return spec.Capabilities, ok
+}
+
+// ListCapabilities 按注册顺序列出所有提供商的能力描述
+func ListCapabilities() []Capabilities {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	list := make([]Capabilities, 0, len(registryOrder))
+	for _, p := range registryOrder {
+		list = append(list, registry[p].Capabilities)
+	}
+	return list
+}
+
+// TrainingQuota 剩余训练次数
+type TrainingQuota struct {
+	Provider  Provider `json:"provider"`
+	TaskID    string   `json:"taskId,omitempty"`
+	Remaining int      `json:"remaining"`
+}
+
+// QuotaQuerier 可以查询剩余训练次数的服务
+type QuotaQuerier interface {
+	QueryTrainingQuota(ctx context.Context, taskID string) (*TrainingQuota, error)
+}
+
+// QueryTrainingQuota 查询剩余训练次数，服务不支持时返回 ErrQuotaUnsupported
+func QueryTrainingQuota(ctx context.Context, svc VoiceCloneService, taskID string) (*TrainingQuota, error) {
+	q, ok := svc.(QuotaQuerier)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrQuotaUnsupported, svc.Provider())
+	}
+	return q.QueryTrainingQuota(ctx, taskID)
+}
+
+func init() {
+	RegisterProvider(ProviderSpec{
+		Capabilities: Capabilities{
+			Provider:             ProviderXunfei,
+			Name:                 "讯飞星火",
+			Languages:            []string{"zh", "en", "ja", "ko", "ru"},
+			AudioFormats:         []string{"wav", "mp3"},
+			TrainingSampleRates:  []int{16000, 24000},
+			SynthesisSampleRates: []int{xunfeiStreamSampleRate},
+			MinAudioSeconds:      3,
+			MaxAudioSeconds:      40,
+			MaxAudioBytes:        10 << 20,
+			TrainingText:         true,
+			CreateTask:           true,
+			Streaming:            true,
+		},
+		New:        newXunfeiService,
+		EnvOptions: xunfeiEnvOptions,
+	})
+	RegisterProvider(ProviderSpec{
+		Capabilities: Capabilities{
+			Provider:             ProviderVolcengine,
+			Name:                 "火山引擎",
+			Languages:            []string{"zh", "en", "ja", "es", "id", "pt"},
+			AudioFormats:         []string{"wav", "mp3", "ogg", "m4a", "aac", "pcm"},
+			TrainingSampleRates:  []int{16000, 24000, 48000},
+			SynthesisSampleRates: []int{8000, 16000, 24000},
+			MinAudioSeconds:      5,
+			MaxAudioSeconds:      60,
+			MaxAudioBytes:        10 << 20,
+			MaxUploads:           volcengineMaxUploads,
+			Streaming:            true,
+			TrainingQuota:        true,
+		},
+		New:        newVolcengineService,
+		EnvOptions: volcengineEnvOptions,
+	})
+}
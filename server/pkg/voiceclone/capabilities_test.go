@@ -0,0 +1,87 @@
+package voiceclone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilitiesRegistry(t *testing.T) {
+	caps := ListCapabilities()
+	require.GreaterOrEqual(t, len(caps), 2)
+	assert.Equal(t, ProviderXunfei, caps[0].Provider)
+	assert.Equal(t, ProviderVolcengine, caps[1].Provider)
+	assert.Equal(t, []Provider{ProviderXunfei, ProviderVolcengine}, NewFactory().GetSupportedProviders()[:2])
+
+	xunfei, ok := GetCapabilities(ProviderXunfei)
+	require.True(t, ok)
+	assert.True(t, xunfei.TrainingText)
+	assert.False(t, xunfei.TrainingQuota)
+	assert.True(t, xunfei.SupportsLanguage("zh-CN"))
+	assert.True(t, xunfei.SupportsLanguage("EN"))
+	assert.False(t, xunfei.SupportsLanguage("es"))
+
+	volcengine, ok := GetCapabilities(ProviderVolcengine)
+	require.True(t, ok)
+	assert.Equal(t, volcengineMaxUploads, volcengine.MaxUploads)
+	assert.True(t, volcengine.TrainingQuota)
+
+	_, ok = GetCapabilities("unknown")
+	assert.False(t, ok)
+	_, err := NewFactory().CreateServiceFromEnv("unknown")
+	assert.Error(t, err)
+}
+
+func TestFactoryCreateService(t *testing.T) {
+	factory := NewFactory()
+	_, err := factory.CreateService(&Config{Provider: ProviderXunfei, Options: map[string]interface{}{}})
+	assert.Error(t, err)
+
+	svc, err := factory.CreateService(&Config{
+		Provider: ProviderVolcengine,
+		Options:  map[string]interface{}{"app_id": "app", "token": "token"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ProviderVolcengine, svc.Provider())
+
+	// 讯飞不支持查询训练次数
+	svc, err = factory.CreateService(&Config{
+		Provider: ProviderXunfei,
+		Options:  map[string]interface{}{"app_id": "app", "api_key": "key"},
+	})
+	require.NoError(t, err)
+	_, err = QueryTrainingQuota(context.Background(), svc, "task")
+	assert.True(t, errors.Is(err, ErrQuotaUnsupported))
+}
+
+func TestVolcengineQueryTrainingQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer;token", r.Header.Get("Authorization"))
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "app", body["appid"])
+		assert.Equal(t, "S_abc", body["speaker_id"])
+		w.Write([]byte(`{"BaseResp":{"StatusCode":0},"speaker_id":"S_abc","status":2,"available_training_times":7}`))
+	}))
+	defer server.Close()
+	defer func(url string) { volcengineStatusURL = url }(volcengineStatusURL)
+	volcengineStatusURL = server.URL
+
+	svc := NewVolcengineService(VolcengineConfig{AppID: "app", Token: "token"})
+	quota, err := QueryTrainingQuota(context.Background(), svc, "speaker_id:S_abc")
+	require.NoError(t, err)
+	assert.Equal(t, &TrainingQuota{Provider: ProviderVolcengine, TaskID: "S_abc", Remaining: 7}, quota)
+
+	status, err := svc.QueryTaskStatus(context.Background(), "S_abc")
+	require.NoError(t, err)
+	assert.Equal(t, TrainingStatusSuccess, status.Status)
+
+	_, err = svc.QueryTrainingQuota(context.Background(), "")
+	assert.Error(t, err)
+}
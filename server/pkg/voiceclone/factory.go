@@ -21,76 +21,79 @@ func (f *Factory) CreateService(config *Config) (VoiceCloneService, error) {
 		return nil, fmt.Errorf("config is required")
 	}
 
-	switch config.Provider {
-	case ProviderXunfei:
-		return f.createXunfeiService(config.Options)
-	case ProviderVolcengine:
-		return f.createVolcengineService(config.Options)
-	default:
+	spec, ok := lookupProvider(config.Provider)
+	if !ok {
 		return nil, fmt.Errorf("unsupported provider: %s", config.Provider)
 	}
+	return spec.New(config.Options)
 }
 
 // CreateServiceFromEnv 从环境变量创建服务
 func (f *Factory) CreateServiceFromEnv(provider Provider) (VoiceCloneService, error) {
-	config := &Config{
-		Provider: provider,
-		Options:  make(map[string]interface{}),
+	spec, ok := lookupProvider(provider)
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
 
-	switch provider {
-	case ProviderXunfei:
-		config.Options["app_id"] = utils.GetEnv("XUNFEI_APP_ID")
-		config.Options["api_key"] = utils.GetEnv("XUNFEI_API_KEY")
-		config.Options["base_url"] = utils.GetEnv("XUNFEI_BASE_URL")
-		if config.Options["base_url"] == "" {
-			// 使用讯飞默认值
-			config.Options["base_url"] = "http://opentrain.xfyousheng.com"
-		}
-		config.Options["timeout"] = utils.GetIntEnv("XUNFEI_TIMEOUT")
-		if config.Options["timeout"] == 0 {
-			config.Options["timeout"] = 30
-		}
-		// WebSocket配置
-		config.Options["ws_app_id"] = utils.GetEnv("XUNFEI_WS_APP_ID")
-		config.Options["ws_api_key"] = utils.GetEnv("XUNFEI_WS_API_KEY")
-		config.Options["ws_api_secret"] = utils.GetEnv("XUNFEI_WS_API_SECRET")
-
-	case ProviderVolcengine:
-		config.Options["app_id"] = utils.GetEnv("VOLCENGINE_CLONE_APP_ID")
-		config.Options["token"] = utils.GetEnv("VOLCENGINE_CLONE_TOKEN")
-		config.Options["cluster"] = utils.GetEnv("VOLCENGINE_CLONE_CLUSTER")
-		config.Options["voice_type"] = utils.GetEnv("VOLCENGINE_CLONE_VOICE_TYPE")
-		config.Options["encoding"] = utils.GetEnv("VOLCENGINE_CLONE_ENCODING")
-		if sampleRate := utils.GetIntEnv("VOLCENGINE_CLONE_SAMPLE_RATE"); sampleRate > 0 {
-			config.Options["sample_rate"] = sampleRate
-		}
-		if bitDepth := utils.GetIntEnv("VOLCENGINE_CLONE_BIT_DEPTH"); bitDepth > 0 {
-			config.Options["bit_depth"] = bitDepth
-		}
-		if channels := utils.GetIntEnv("VOLCENGINE_CLONE_CHANNELS"); channels > 0 {
-			config.Options["channels"] = channels
-		}
-		config.Options["frame_duration"] = utils.GetEnv("VOLCENGINE_CLONE_FRAME_DURATION")
-		if speedRatio := utils.GetFloatEnv("VOLCENGINE_CLONE_SPEED_RATIO"); speedRatio > 0 {
-			config.Options["speed_ratio"] = speedRatio
-		}
-		if trainingTimes := utils.GetIntEnv("VOLCENGINE_CLONE_TRAINING_TIMES"); trainingTimes > 0 {
-			config.Options["training_times"] = trainingTimes
-		}
-		if config.Options["cluster"] == "" {
-			config.Options["cluster"] = "volcano_icl"
-		}
+	return f.CreateService(&Config{
+		Provider: provider,
+		Options:  spec.EnvOptions(),
+	})
+}
 
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", provider)
+// xunfeiEnvOptions 从环境变量读取讯飞配置
+func xunfeiEnvOptions() map[string]interface{} {
+	options := make(map[string]interface{})
+	options["app_id"] = utils.GetEnv("XUNFEI_APP_ID")
+	options["api_key"] = utils.GetEnv("XUNFEI_API_KEY")
+	options["base_url"] = utils.GetEnv("XUNFEI_BASE_URL")
+	if options["base_url"] == "" {
+		// 使用讯飞默认值
+		options["base_url"] = "http://opentrain.xfyousheng.com"
+	}
+	options["timeout"] = utils.GetIntEnv("XUNFEI_TIMEOUT")
+	if options["timeout"] == 0 {
+		options["timeout"] = 30
 	}
+	// WebSocket配置
+	options["ws_app_id"] = utils.GetEnv("XUNFEI_WS_APP_ID")
+	options["ws_api_key"] = utils.GetEnv("XUNFEI_WS_API_KEY")
+	options["ws_api_secret"] = utils.GetEnv("XUNFEI_WS_API_SECRET")
+	return options
+}
 
-	return f.CreateService(config)
+// volcengineEnvOptions 从环境变量读取火山引擎配置
+func volcengineEnvOptions() map[string]interface{} {
+	options := make(map[string]interface{})
+	options["app_id"] = utils.GetEnv("VOLCENGINE_CLONE_APP_ID")
+	options["token"] = utils.GetEnv("VOLCENGINE_CLONE_TOKEN")
+	options["cluster"] = utils.GetEnv("VOLCENGINE_CLONE_CLUSTER")
+	options["voice_type"] = utils.GetEnv("VOLCENGINE_CLONE_VOICE_TYPE")
+	options["encoding"] = utils.GetEnv("VOLCENGINE_CLONE_ENCODING")
+	if sampleRate := utils.GetIntEnv("VOLCENGINE_CLONE_SAMPLE_RATE"); sampleRate > 0 {
+		options["sample_rate"] = sampleRate
+	}
+	if bitDepth := utils.GetIntEnv("VOLCENGINE_CLONE_BIT_DEPTH"); bitDepth > 0 {
+		options["bit_depth"] = bitDepth
+	}
+	if channels := utils.GetIntEnv("VOLCENGINE_CLONE_CHANNELS"); channels > 0 {
+		options["channels"] = channels
+	}
+	options["frame_duration"] = utils.GetEnv("VOLCENGINE_CLONE_FRAME_DURATION")
+	if speedRatio := utils.GetFloatEnv("VOLCENGINE_CLONE_SPEED_RATIO"); speedRatio > 0 {
+		options["speed_ratio"] = speedRatio
+	}
+	if trainingTimes := utils.GetIntEnv("VOLCENGINE_CLONE_TRAINING_TIMES"); trainingTimes > 0 {
+		options["training_times"] = trainingTimes
+	}
+	if options["cluster"] == "" {
+		options["cluster"] = "volcano_icl"
+	}
+	return options
 }
 
-// createXunfeiService 创建讯飞服务
-func (f *Factory) createXunfeiService(options map[string]interface{}) (VoiceCloneService, error) {
+// newXunfeiService 创建讯飞服务
+func newXunfeiService(options map[string]interface{}) (VoiceCloneService, error) {
 	appID, _ := options["app_id"].(string)
 	apiKey, _ := options["api_key"].(string)
 	baseURL, _ := options["base_url"].(string)
@@ -114,9 +117,9 @@ func (f *Factory) createXunfeiService(options map[string]interface{}) (VoiceClon
 	}), nil
 }
 
-// createVolcengineService 创建火山引擎服务
+// newVolcengineService 创建火山引擎服务
 // 完全效仿 voiceserver-main，只支持 WebSocket，需要 token
-func (f *Factory) createVolcengineService(options map[string]interface{}) (VoiceCloneService, error) {
+func newVolcengineService(options map[string]interface{}) (VoiceCloneService, error) {
 	appID, _ := options["app_id"].(string)
 	token, _ := options["token"].(string)
 	cluster, _ := options["cluster"].(string)
@@ -193,8 +196,10 @@ func (f *Factory) ValidateConfig(config *Config) error {
 
 // GetSupportedProviders 获取支持的提供商列表
 func (f *Factory) GetSupportedProviders() []Provider {
-	return []Provider{
-		ProviderXunfei,
-		ProviderVolcengine,
+	caps := ListCapabilities()
+	providers := make([]Provider, 0, len(caps))
+	for _, c := range caps {
+		providers = append(providers, c.Provider)
 	}
+	return providers
 }
@@ -0,0 +1,334 @@
+package voiceclone
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+	"github.com/code-100-precent/LingEcho/pkg/media/fileio"
+)
+
+// ErrInvalidTrainingAudio 训练音频不符合要求，提交给提供商前即被拒绝
+var ErrInvalidTrainingAudio = errors.New("invalid training audio")
+
+const (
+	// maxTrainingInputBytes 上传的原始录音大小上限，超过时不再解码
+	maxTrainingInputBytes = 50 << 20
+
+	preprocessFrameMs   = 20
+	defaultTargetDBFS   = -20.0
+	defaultSilenceDBFS  = -45.0
+	defaultMaxClipRatio = 0.001
+	defaultMaxGainDB    = 20.0
+	peakLimitDBFS       = -1.0
+	silencePaddingMs    = 150
+	clipLevel           = 32700
+	clipRunSamples      = 3 // 连续满幅采样数达到该值才计为削波
+)
+
+// PreprocessOptions 训练音频预处理参数
+type PreprocessOptions struct {
+	SampleRates  []int   // 提供商接受的采样率，源采样率不在其中时重采样
+	MinSeconds   float64 // 去除首尾静音后的最短时长
+	MaxSeconds   float64 // 去除首尾静音后的最长时长
+	MaxBytes     int64   // 处理后 WAV 的最大字节数
+	TargetDBFS   float64 // 响度归一化目标（语音段 RMS），默认 -20 dBFS
+	SilenceDBFS  float64 // 低于该电平的帧视为静音，默认 -45 dBFS
+	MaxClipRatio float64 // 允许的削波采样比例，默认 0.1%
+	MaxGainDB    float64 // 最大增益，避免把底噪放大成语音，默认 20 dB
+}
+
+// PreprocessOptions 按提供商要求生成预处理参数
+func (c Capabilities) PreprocessOptions() PreprocessOptions {
+	return PreprocessOptions{
+		SampleRates: c.TrainingSampleRates,
+		MinSeconds:  c.MinAudioSeconds,
+		MaxSeconds:  c.MaxAudioSeconds,
+		MaxBytes:    c.MaxAudioBytes,
+	}
+}
+
+// PreparedAudio 预处理后的训练音频及分析结果
+type PreparedAudio struct {
+	WAV            []byte  `json:"-"`              // 16bit 单声道 WAV
+	SampleRate     int     `json:"sampleRate"`     // 输出采样率
+	SourceRate     int     `json:"sourceRate"`     // 原始采样率
+	Duration       float64 `json:"duration"`       // 输出时长（秒）
+	TrimmedSeconds float64 `json:"trimmedSeconds"` // 去除的首尾静音时长
+	LoudnessDBFS   float64 `json:"loudnessDbfs"`   // 归一化前语音段 RMS
+	PeakDBFS       float64 `json:"peakDbfs"`       // 归一化前峰值
+	GainDB         float64 `json:"gainDb"`         // 施加的增益
+	ClipRatio      float64 `json:"clipRatio"`      // 削波采样比例
+}
+
+// PrepareTrainingAudio 校验并预处理训练录音：解码任意格式，转单声道，
+// 重采样到提供商支持的采样率，检测削波，去除首尾静音，校验时长，响度归一化后输出 WAV
+func PrepareTrainingAudio(data []byte, filename string, opts PreprocessOptions) (*PreparedAudio, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: 音频文件为空", ErrInvalidTrainingAudio)
+	}
+	if len(data) > maxTrainingInputBytes {
+		return nil, fmt.Errorf("%w: 音频文件过大（%d MB），上限 %d MB", ErrInvalidTrainingAudio, len(data)>>20, maxTrainingInputBytes>>20)
+	}
+	if opts.TargetDBFS == 0 {
+		opts.TargetDBFS = defaultTargetDBFS
+	}
+	if opts.SilenceDBFS == 0 {
+		opts.SilenceDBFS = defaultSilenceDBFS
+	}
+	if opts.MaxClipRatio == 0 {
+		opts.MaxClipRatio = defaultMaxClipRatio
+	}
+	if opts.MaxGainDB == 0 {
+		opts.MaxGainDB = defaultMaxGainDB
+	}
+
+	pcm, format, err := decodeTrainingAudio(data, filename)
+	if err != nil {
+		return nil, err
+	}
+	if format.SampleRate <= 0 || format.Channels <= 0 {
+		return nil, fmt.Errorf("%w: 无法识别音频格式", ErrInvalidTrainingAudio)
+	}
+	samples := downmix(pcm, format.Channels)
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("%w: 音频没有内容", ErrInvalidTrainingAudio)
+	}
+
+	result := &PreparedAudio{SourceRate: format.SampleRate}
+	result.ClipRatio = clipRatio(samples)
+	if result.ClipRatio > opts.MaxClipRatio {
+		return nil, fmt.Errorf("%w: 录音削波失真（%.2f%% 的采样达到满幅），请降低录音音量后重录", ErrInvalidTrainingAudio, result.ClipRatio*100)
+	}
+
+	rate := chooseSampleRate(format.SampleRate, opts.SampleRates)
+	if rate != format.SampleRate {
+		resampled, err := media.ResamplePCM(samplesToPCM(samples), format.SampleRate, rate)
+		if err != nil {
+			return nil, fmt.Errorf("resample training audio: %w", err)
+		}
+		samples = pcmToSamples(resampled)
+	}
+	result.SampleRate = rate
+
+	total := len(samples)
+	frame := rate * preprocessFrameMs / 1000
+	start, end, loudness := voicedRange(samples, frame, opts.SilenceDBFS)
+	if start >= end {
+		return nil, fmt.Errorf("%w: 未检测到有效语音，请检查麦克风后重录", ErrInvalidTrainingAudio)
+	}
+	padding := rate * silencePaddingMs / 1000
+	start = max(0, start-padding)
+	end = min(total, end+padding)
+	samples = samples[start:end]
+	result.TrimmedSeconds = float64(total-len(samples)) / float64(rate)
+	result.Duration = float64(len(samples)) / float64(rate)
+
+	if opts.MinSeconds > 0 && result.Duration < opts.MinSeconds {
+		return nil, fmt.Errorf("%w: 有效语音 %.1f 秒，至少需要 %.0f 秒", ErrInvalidTrainingAudio, result.Duration, opts.MinSeconds)
+	}
+	if opts.MaxSeconds > 0 && result.Duration > opts.MaxSeconds {
+		return nil, fmt.Errorf("%w: 有效语音 %.1f 秒，超过上限 %.0f 秒", ErrInvalidTrainingAudio, result.Duration, opts.MaxSeconds)
+	}
+
+	// 按语音段 RMS 归一化，增益受峰值和最大增益限制
+	peak := peakDBFS(samples)
+	result.LoudnessDBFS = round2(loudness)
+	result.PeakDBFS = round2(peak)
+	gain := math.Min(opts.TargetDBFS-loudness, peakLimitDBFS-peak)
+	gain = math.Min(gain, opts.MaxGainDB)
+	applyGain(samples, gain)
+	result.GainDB = round2(gain)
+
+	result.WAV = fileio.EncodeWAV(samplesToPCM(samples), fileio.Format{SampleRate: rate, Channels: 1})
+	if opts.MaxBytes > 0 && int64(len(result.WAV)) > opts.MaxBytes {
+		return nil, fmt.Errorf("%w: 处理后音频 %d 字节，超过上限 %d 字节", ErrInvalidTrainingAudio, len(result.WAV), opts.MaxBytes)
+	}
+	result.Duration = round2(result.Duration)
+	result.TrimmedSeconds = round2(result.TrimmedSeconds)
+	return result, nil
+}
+
+// decodeTrainingAudio 16bit WAV 直接解析，其他格式（mp3/m4a/aac/ogg/webm 等）交给 ffmpeg 转成 WAV
+func decodeTrainingAudio(data []byte, filename string) ([]byte, fileio.Format, error) {
+	pcm, format, err := fileio.DecodeWAV(data)
+	if err == nil {
+		return pcm, format, nil
+	}
+	if bytes.HasPrefix(data, []byte("RIFF")) && !errors.Is(err, fileio.ErrUnsupportedFormat) {
+		return nil, fileio.Format{}, fmt.Errorf("%w: WAV 文件损坏: %v", ErrInvalidTrainingAudio, err)
+	}
+
+	wav, err := transcodeToWAV(data, filepath.Ext(filename))
+	if err != nil {
+		return nil, fileio.Format{}, err
+	}
+	pcm, format, err = fileio.DecodeWAV(wav)
+	if err != nil {
+		return nil, fileio.Format{}, fmt.Errorf("%w: %v", ErrInvalidTrainingAudio, err)
+	}
+	return pcm, format, nil
+}
+
+// transcodeToWAV 用 ffmpeg 转成 16bit WAV，保留原采样率；写临时文件是因为 m4a 的索引可能在文件末尾，无法从管道读取
+func transcodeToWAV(data []byte, ext string) ([]byte, error) {
+	if _, err := exec.LookPath(fileio.FFmpegPath); err != nil {
+		return nil, fmt.Errorf("%w: 仅支持 16bit WAV，其他格式需要安装 ffmpeg", ErrInvalidTrainingAudio)
+	}
+	tmp, err := os.CreateTemp("", "voice_training_*"+strings.ToLower(ext))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp audio file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write temp audio file: %w", err)
+	}
+	tmp.Close()
+
+	cmd := exec.Command(fileio.FFmpegPath,
+		"-v", "error",
+		"-i", tmp.Name(),
+		"-vn",
+		"-acodec", "pcm_s16le",
+		"-f", "wav",
+		"pipe:1",
+	)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: 无法解码音频: %s", ErrInvalidTrainingAudio, strings.TrimSpace(stderr.String()))
+	}
+	return out.Bytes(), nil
+}
+
+// chooseSampleRate 源采样率受支持时保持不变，否则选不高于源采样率的最大支持值，避免无意义的升采样
+func chooseSampleRate(source int, supported []int) int {
+	if len(supported) == 0 {
+		return source
+	}
+	rates := append([]int(nil), supported...)
+	sort.Ints(rates)
+	chosen := rates[0]
+	for _, r := range rates {
+		if r == source {
+			return source
+		}
+		if r < source {
+			chosen = r
+		}
+	}
+	return chosen
+}
+
+// voicedRange 返回首个和最后一个非静音帧覆盖的采样区间，以及这些帧的整体 RMS（dBFS）
+func voicedRange(samples []int16, frame int, silenceDBFS float64) (int, int, float64) {
+	start, end := -1, -1
+	var energy float64
+	var count int
+	for i := 0; i < len(samples); i += frame {
+		j := min(i+frame, len(samples))
+		var sum float64
+		for _, s := range samples[i:j] {
+			sum += float64(s) * float64(s)
+		}
+		if toDBFS(math.Sqrt(sum/float64(j-i))) < silenceDBFS {
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		end = j
+		energy += sum
+		count += j - i
+	}
+	if start < 0 {
+		return 0, 0, math.Inf(-1)
+	}
+	return start, end, toDBFS(math.Sqrt(energy / float64(count)))
+}
+
+// clipRatio 统计处于连续满幅区段中的采样比例，单个满幅采样不算削波
+func clipRatio(samples []int16) float64 {
+	clipped, run := 0, 0
+	for _, s := range samples {
+		if s >= clipLevel || s <= -clipLevel {
+			run++
+			continue
+		}
+		if run >= clipRunSamples {
+			clipped += run
+		}
+		run = 0
+	}
+	if run >= clipRunSamples {
+		clipped += run
+	}
+	return float64(clipped) / float64(len(samples))
+}
+
+func peakDBFS(samples []int16) float64 {
+	var peak float64
+	for _, s := range samples {
+		peak = math.Max(peak, math.Abs(float64(s)))
+	}
+	return toDBFS(peak)
+}
+
+func applyGain(samples []int16, gainDB float64) {
+	if gainDB == 0 {
+		return
+	}
+	factor := math.Pow(10, gainDB/20)
+	for i, s := range samples {
+		v := math.Round(float64(s) * factor)
+		samples[i] = int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, v)))
+	}
+}
+
+func toDBFS(amplitude float64) float64 {
+	if amplitude <= 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(amplitude/32768)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// downmix 交错的多声道 16bit PCM 取平均转单声道
+func downmix(pcm []byte, channels int) []int16 {
+	n := len(pcm) / (2 * channels)
+	samples := make([]int16, n)
+	for i := 0; i < n; i++ {
+		var sum int
+		for ch := 0; ch < channels; ch++ {
+			off := (i*channels + ch) * 2
+			sum += int(int16(uint16(pcm[off]) | uint16(pcm[off+1])<<8))
+		}
+		samples[i] = int16(sum / channels)
+	}
+	return samples
+}
+
+func pcmToSamples(pcm []byte) []int16 {
+	return downmix(pcm, 1)
+}
+
+func samplesToPCM(samples []int16) []byte {
+	pcm := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		pcm[2*i] = byte(s)
+		pcm[2*i+1] = byte(uint16(s) >> 8)
+	}
+	return pcm
+}
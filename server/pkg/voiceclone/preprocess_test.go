@@ -0,0 +1,114 @@
+package voiceclone
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/code-100-precent/LingEcho/pkg/media/fileio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trainingWAV 生成前后各带静音的正弦波录音
+func trainingWAV(rate, channels int, silence, speech float64, amplitude float64) []byte {
+	var samples []int16
+	for i := 0; i < int(silence*float64(rate)); i++ {
+		samples = append(samples, 0)
+	}
+	for i := 0; i < int(speech*float64(rate)); i++ {
+		v := amplitude * math.Sin(2*math.Pi*220*float64(i)/float64(rate))
+		samples = append(samples, int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, v))))
+	}
+	for i := 0; i < int(silence*float64(rate)); i++ {
+		samples = append(samples, 0)
+	}
+	pcm := make([]byte, 0, len(samples)*2*channels)
+	for _, s := range samples {
+		for ch := 0; ch < channels; ch++ {
+			pcm = append(pcm, byte(s), byte(uint16(s)>>8))
+		}
+	}
+	return fileio.EncodeWAV(pcm, fileio.Format{SampleRate: rate, Channels: channels})
+}
+
+func TestPrepareTrainingAudio(t *testing.T) {
+	caps, _ := GetCapabilities(ProviderXunfei)
+	// 48kHz 立体声、音量偏小的录音
+	data := trainingWAV(48000, 2, 1, 4, 1000)
+
+	prepared, err := PrepareTrainingAudio(data, "record.wav", caps.PreprocessOptions())
+	require.NoError(t, err)
+	assert.Equal(t, 48000, prepared.SourceRate)
+	assert.Equal(t, 24000, prepared.SampleRate)
+	assert.InDelta(t, 4.3, prepared.Duration, 0.05)
+	assert.InDelta(t, 1.7, prepared.TrimmedSeconds, 0.05)
+	assert.InDelta(t, -33.3, prepared.LoudnessDBFS, 0.2)
+	assert.InDelta(t, 13.3, prepared.GainDB, 0.2)
+
+	pcm, format, err := fileio.DecodeWAV(prepared.WAV)
+	require.NoError(t, err)
+	assert.Equal(t, fileio.Format{SampleRate: 24000, Channels: 1}, format)
+	assert.InDelta(t, defaultTargetDBFS, peakDBFS(pcmToSamples(pcm))-3, 0.3)
+}
+
+func TestPrepareTrainingAudio_PeakLimit(t *testing.T) {
+	// 支持的采样率保持不变；峰值限制优先于响度目标
+	data := trainingWAV(16000, 1, 0.2, 3, 12000)
+	samples := pcmToSamples(data[44:])
+	// 插入一个尖峰
+	samples[len(samples)/2] = 32000
+	data = fileio.EncodeWAV(samplesToPCM(samples), fileio.Format{SampleRate: 16000, Channels: 1})
+
+	prepared, err := PrepareTrainingAudio(data, "a.wav", PreprocessOptions{SampleRates: []int{16000, 24000}})
+	require.NoError(t, err)
+	assert.Equal(t, 16000, prepared.SampleRate)
+	pcm, _, err := fileio.DecodeWAV(prepared.WAV)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, peakDBFS(pcmToSamples(pcm)), peakLimitDBFS+0.01)
+	assert.Less(t, prepared.GainDB, 0.0)
+}
+
+func TestPrepareTrainingAudio_Rejects(t *testing.T) {
+	opts := PreprocessOptions{SampleRates: []int{24000}, MinSeconds: 3, MaxSeconds: 10, MaxBytes: 10 << 20}
+	cases := map[string][]byte{
+		"empty":     nil,
+		"silence":   trainingWAV(24000, 1, 2, 0, 0),
+		"too short": trainingWAV(24000, 1, 1, 1, 8000),
+		"too long":  trainingWAV(24000, 1, 0, 12, 8000),
+		"clipped":   trainingWAV(24000, 1, 0.5, 4, 60000),
+		"corrupt":   []byte("RIFF\x10\x00\x00\x00WAVEjunk"),
+	}
+	for name, data := range cases {
+		_, err := PrepareTrainingAudio(data, "a.wav", opts)
+		assert.True(t, errors.Is(err, ErrInvalidTrainingAudio), "%s: %v", name, err)
+	}
+
+	// 处理后超过大小上限
+	opts.MaxBytes = 1000
+	_, err := PrepareTrainingAudio(trainingWAV(24000, 1, 0, 4, 8000), "a.wav", opts)
+	assert.True(t, errors.Is(err, ErrInvalidTrainingAudio))
+
+	// 非 WAV 需要 ffmpeg 解码
+	defer func(path string) { fileio.FFmpegPath = path }(fileio.FFmpegPath)
+	fileio.FFmpegPath = "ffmpeg-not-installed"
+	_, err = PrepareTrainingAudio([]byte("ID3\x03\x00\x00\x00"), "a.mp3", opts)
+	assert.True(t, errors.Is(err, ErrInvalidTrainingAudio))
+}
+
+func TestChooseSampleRate(t *testing.T) {
+	assert.Equal(t, 16000, chooseSampleRate(16000, []int{24000, 16000}))
+	assert.Equal(t, 24000, chooseSampleRate(44100, []int{16000, 24000}))
+	assert.Equal(t, 16000, chooseSampleRate(8000, []int{24000, 16000}))
+	assert.Equal(t, 44100, chooseSampleRate(44100, nil))
+}
+
+func TestClipRatio(t *testing.T) {
+	samples := make([]int16, 1000)
+	samples[10] = 32767
+	assert.Zero(t, clipRatio(samples))
+	samples[500], samples[502] = 32767, 32767
+	assert.Zero(t, clipRatio(samples))
+	samples[501] = -32768
+	assert.InDelta(t, 0.003, clipRatio(samples), 1e-9)
+}
@@ -0,0 +1,72 @@
+package voiceclone
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/code-100-precent/LingEcho/pkg/media"
+	"github.com/code-100-precent/LingEcho/pkg/synthesizer"
+)
+
+// xunfeiStreamSampleRate 讯飞流式合成固定输出 24kHz PCM
+const xunfeiStreamSampleRate = 24000
+
+// StreamSampleRate 返回服务流式合成输出的 PCM 采样率
+func StreamSampleRate(svc VoiceCloneService) int {
+	if s, ok := svc.(*VolcengineService); ok {
+		return s.config.SampleRate
+	}
+	return xunfeiStreamSampleRate
+}
+
+// cloneSynthesis 把训练好的音色适配为 synthesizer.SynthesisService，供实时通话直接使用
+type cloneSynthesis struct {
+	svc        VoiceCloneService
+	assetID    string
+	language   string
+	sampleRate int
+}
+
+// NewSynthesisService 创建使用训练音色 assetID 合成的 TTS 服务
+func NewSynthesisService(svc VoiceCloneService, assetID, language string) synthesizer.SynthesisService {
+	return &cloneSynthesis{
+		svc:        svc,
+		assetID:    assetID,
+		language:   language,
+		sampleRate: StreamSampleRate(svc),
+	}
+}
+
+func (c *cloneSynthesis) Provider() synthesizer.TTSProvider {
+	return synthesizer.TTSProvider("voiceclone_" + string(c.svc.Provider()))
+}
+
+func (c *cloneSynthesis) Format() media.StreamFormat {
+	return media.StreamFormat{SampleRate: c.sampleRate, BitDepth: 16, Channels: 1}
+}
+
+func (c *cloneSynthesis) CacheKey(text string) string {
+	digest := media.MediaCache().BuildKey(text)
+	return fmt.Sprintf("voiceclone.%s-%s-%d-%s.pcm", c.svc.Provider(), c.assetID, c.sampleRate, digest)
+}
+
+func (c *cloneSynthesis) Synthesize(ctx context.Context, handler synthesizer.SynthesisHandler, text string) error {
+	req := &SynthesizeRequest{AssetID: c.assetID, Text: text, Language: c.language}
+	return c.svc.SynthesizeStream(ctx, req, &cloneSynthesisHandler{handler: handler})
+}
+
+func (c *cloneSynthesis) Close() error {
+	return nil
+}
+
+// cloneSynthesisHandler 把音色克隆的回调转给 synthesizer 的处理器，时间戳格式不同暂不转发
+type cloneSynthesisHandler struct {
+	handler synthesizer.SynthesisHandler
+}
+
+func (h *cloneSynthesisHandler) OnMessage(data []byte) {
+	h.handler.OnMessage(data)
+}
+
+func (h *cloneSynthesisHandler) OnTimestamp(timestamp SentenceTimestamp) {
+}
@@ -23,8 +23,13 @@ import (
 const (
 	optSubmit              = "submit"
 	VolcengineCloneCluster = "volcano_icl"
+
+	// volcengineMaxUploads 同一音色最多上传训练音频的次数
+	volcengineMaxUploads = 10
 )
 
+var volcengineStatusURL = "https://openspeech.bytedance.com/api/v1/mega_tts/status"
+
 var defaultHeader = []byte{0x11, 0x10, 0x11, 0x00}
 
 // VolcengineConfig 火山引擎配置
@@ -255,7 +260,7 @@ func (s *VolcengineService) SubmitAudio(ctx context.Context, req *SubmitAudioReq
 	if apiResp.BaseResp.StatusCode != 0 {
 		// 特殊处理：已达上传次数限制（错误码 1123）
 		if apiResp.BaseResp.StatusCode == 1123 {
-			return fmt.Errorf("training failed: 已达上传次数限制（同一音色最多支持10次上传），错误信息: %s", apiResp.BaseResp.StatusMessage)
+			return fmt.Errorf("training failed: 已达上传次数限制（同一音色最多支持%d次上传），错误信息: %s", volcengineMaxUploads, apiResp.BaseResp.StatusMessage)
 		}
 		return fmt.Errorf("training failed: %s", apiResp.BaseResp.StatusMessage)
 	}
@@ -276,8 +281,55 @@ func (s *VolcengineService) QueryTaskStatus(ctx context.Context, taskID string)
 		speakerID = strings.TrimPrefix(speakerID, "speaker_id:")
 	}
 
-	url := "https://openspeech.bytedance.com/api/v1/mega_tts/status"
+	apiResp, err := s.queryStatus(ctx, speakerID)
+	if err != nil {
+		return nil, err
+	}
 
+	// 转换状态
+	var trainingStatus TrainingStatus
+	switch apiResp.Status {
+	case 0: // NotFound
+		trainingStatus = TrainingStatusFailed
+	case 1: // Training
+		trainingStatus = TrainingStatusInProgress
+	case 2, 4: // Success, Active (都可以使用)
+		trainingStatus = TrainingStatusSuccess
+	case 3: // Failed
+		trainingStatus = TrainingStatusFailed
+	default:
+		trainingStatus = TrainingStatusInProgress
+	}
+
+	return &TaskStatus{
+		TaskID:     speakerID,
+		TaskName:   speakerID,
+		Status:     trainingStatus,
+		AssetID:    speakerID, // 火山引擎使用 speaker_id 作为 asset_id
+		TrainVID:   apiResp.Version,
+		FailedDesc: apiResp.BaseResp.StatusMessage,
+		Progress:   0, // 火山引擎不返回进度
+		CreatedAt:  time.Unix(apiResp.CreateTime/1000, 0),
+		UpdatedAt:  time.Now(),
+	}, nil
+}
+
+// volcengineStatusResponse mega_tts/status 接口响应
+type volcengineStatusResponse struct {
+	BaseResp struct {
+		StatusCode    int    `json:"StatusCode"`
+		StatusMessage string `json:"StatusMessage"`
+	} `json:"BaseResp"`
+	SpeakerID              string `json:"speaker_id"`
+	Status                 int    `json:"status"` // 0=NotFound, 1=Training, 2=Success, 3=Failed, 4=Active
+	CreateTime             int64  `json:"create_time"`
+	Version                string `json:"version"`
+	DemoAudio              string `json:"demo_audio"`
+	AvailableTrainingTimes int    `json:"available_training_times"` // 剩余训练次数
+}
+
+// queryStatus 调用状态查询接口
+func (s *VolcengineService) queryStatus(ctx context.Context, speakerID string) (*volcengineStatusResponse, error) {
 	requestBody := map[string]interface{}{
 		"appid":      s.config.AppID,
 		"speaker_id": speakerID,
@@ -288,7 +340,7 @@ func (s *VolcengineService) QueryTaskStatus(ctx context.Context, taskID string)
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", volcengineStatusURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -309,18 +361,7 @@ func (s *VolcengineService) QueryTaskStatus(ctx context.Context, taskID string)
 		return nil, fmt.Errorf("query status failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	var apiResp struct {
-		BaseResp struct {
-			StatusCode    int    `json:"StatusCode"`
-			StatusMessage string `json:"StatusMessage"`
-		} `json:"BaseResp"`
-		SpeakerID  string `json:"speaker_id"`
-		Status     int    `json:"status"` // 0=NotFound, 1=Training, 2=Success, 3=Failed, 4=Active
-		CreateTime int64  `json:"create_time"`
-		Version    string `json:"version"`
-		DemoAudio  string `json:"demo_audio"`
-	}
-
+	var apiResp volcengineStatusResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
@@ -328,32 +369,27 @@ func (s *VolcengineService) QueryTaskStatus(ctx context.Context, taskID string)
 	if apiResp.BaseResp.StatusCode != 0 {
 		return nil, fmt.Errorf("query status failed: %s", apiResp.BaseResp.StatusMessage)
 	}
+	return &apiResp, nil
+}
 
-	// 转换状态
-	var trainingStatus TrainingStatus
-	switch apiResp.Status {
-	case 0: // NotFound
-		trainingStatus = TrainingStatusFailed
-	case 1: // Training
-		trainingStatus = TrainingStatusInProgress
-	case 2, 4: // Success, Active (都可以使用)
-		trainingStatus = TrainingStatusSuccess
-	case 3: // Failed
-		trainingStatus = TrainingStatusFailed
-	default:
-		trainingStatus = TrainingStatusInProgress
+// QueryTrainingQuota 查询音色剩余训练次数，taskID 为 speaker_id
+func (s *VolcengineService) QueryTrainingQuota(ctx context.Context, taskID string) (*TrainingQuota, error) {
+	if s.config.Token == "" {
+		return nil, fmt.Errorf("token is required for querying status")
+	}
+	speakerID := strings.TrimPrefix(taskID, "speaker_id:")
+	if speakerID == "" {
+		return nil, fmt.Errorf("speaker_id is required for querying training quota")
 	}
 
-	return &TaskStatus{
-		TaskID:     speakerID,
-		TaskName:   speakerID,
-		Status:     trainingStatus,
-		AssetID:    speakerID, // 火山引擎使用 speaker_id 作为 asset_id
-		TrainVID:   apiResp.Version,
-		FailedDesc: apiResp.BaseResp.StatusMessage,
-		Progress:   0, // 火山引擎不返回进度
-		CreatedAt:  time.Unix(apiResp.CreateTime/1000, 0),
-		UpdatedAt:  time.Now(),
+	apiResp, err := s.queryStatus(ctx, speakerID)
+	if err != nil {
+		return nil, err
+	}
+	return &TrainingQuota{
+		Provider:  ProviderVolcengine,
+		TaskID:    speakerID,
+		Remaining: apiResp.AvailableTrainingTimes,
 	}, nil
 }
 
@@ -0,0 +1,196 @@
+package bridge
+
+import (
+	"fmt"
+	"sync"
+
+	media2 "github.com/code-100-precent/LingEcho/pkg/media"
+	_ "github.com/code-100-precent/LingEcho/pkg/media/encoder" // 注册编解码器
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/sirupsen/logrus"
+)
+
+// Role 成员在房间中的角色
+type Role string
+
+const (
+	RoleSpeaker  Role = "speaker"  // 发言并收听其他成员
+	RoleListener Role = "listener" // 只收听全部成员，其他人听不到（如主管旁听）
+)
+
+// Participant 房间成员
+type Participant struct {
+	ID   string
+	Role Role
+
+	room *Room
+	sink func(pcm []byte) error
+
+	mu     sync.Mutex
+	buffer []byte // 已解码、待混音的 PCM
+	muted  bool
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Join 把传输层接入房间：解码收到的音频参与混音，并把混音编码后写入发送轨道。
+// 加入后发送轨道由房间独占，不应再由其它组件（如 AIClient 的 TTS）写入。
+func (r *Room) Join(id string, role Role, transport *rtcmedia.WebRTCTransport) (*Participant, error) {
+	txTrack := transport.GetTxTrack()
+	if txTrack == nil {
+		return nil, ErrNoTxTrack
+	}
+	sink, err := r.trackSink(txTrack)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := r.add(id, role, sink)
+	if err != nil {
+		return nil, err
+	}
+	if role == RoleSpeaker {
+		if track := transport.GetRxTrack(); track != nil {
+			go p.readTrack(track)
+		} else {
+			transport.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+				go p.readTrack(track)
+			})
+		}
+	}
+	return p, nil
+}
+
+// trackSink 创建把房间 PCM 编码为发送轨道编解码的写入函数
+func (r *Room) trackSink(txTrack *webrtc.TrackLocalStaticSample) (func(pcm []byte) error, error) {
+	codec, err := rtcmedia.CodecForMimeType(txTrack.Codec().MimeType)
+	if err != nil {
+		return nil, err
+	}
+	encode, err := media2.NewPipeline().
+		Input(r.sampleRate, 1).
+		Encode(rtcmedia.CodecConfigFor(codec)).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("bridge: create %s encoder: %w", codec, err)
+	}
+
+	return func(pcm []byte) error {
+		packets, err := encode(&media2.AudioPacket{Payload: pcm})
+		if err != nil {
+			return err
+		}
+		for _, packet := range packets {
+			audioPacket, ok := packet.(*media2.AudioPacket)
+			if !ok || len(audioPacket.Payload) == 0 {
+				continue
+			}
+			if err := txTrack.WriteSample(media.Sample{Data: audioPacket.Payload, Duration: FrameDuration}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// readTrack 读取并解码成员的音频，直到成员离开或轨道结束
+func (p *Participant) readTrack(track *webrtc.TrackRemote) {
+	mimeType := track.Codec().MimeType
+	codec, err := rtcmedia.CodecForMimeType(mimeType)
+	if err != nil {
+		logrus.WithError(err).WithField("participant", p.ID).Warn("bridge: unsupported track codec")
+		return
+	}
+	decode, err := media2.NewPipeline().
+		Decode(rtcmedia.CodecConfigFor(codec)).
+		Mono().
+		Resample(p.room.sampleRate).
+		Build()
+	if err != nil {
+		logrus.WithError(err).WithField("participant", p.ID).Warn("bridge: failed to create decoder")
+		return
+	}
+	var red *rtcmedia.REDDepacketizer
+	if rtcmedia.IsRED(mimeType) {
+		red = rtcmedia.NewREDDepacketizer()
+	}
+
+	for {
+		packet, _, err := track.ReadRTP()
+		if err != nil {
+			return
+		}
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+		p.decodePacket(packet, red, decode)
+	}
+}
+
+func (p *Participant) decodePacket(packet *rtp.Packet, red *rtcmedia.REDDepacketizer, decode media2.EncoderFunc) {
+	payloads := [][]byte{packet.Payload}
+	if red != nil {
+		var err error
+		if payloads, err = red.Depacketize(packet); err != nil {
+			return
+		}
+	}
+	for _, payload := range payloads {
+		frames, err := decode(&media2.AudioPacket{Payload: payload})
+		if err != nil {
+			continue
+		}
+		for _, frame := range frames {
+			if audioPacket, ok := frame.(*media2.AudioPacket); ok {
+				p.push(audioPacket.Payload)
+			}
+		}
+	}
+}
+
+// push 缓存解码后的 PCM，超出上限时丢弃最旧的部分
+func (p *Participant) push(pcm []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buffer = append(p.buffer, pcm...)
+	if limit := maxBufferedFrames * p.room.frameBytes; len(p.buffer) > limit {
+		p.buffer = append(p.buffer[:0], p.buffer[len(p.buffer)-limit:]...)
+	}
+}
+
+// pop 取出一帧 PCM，不足一帧时返回 nil（该成员本周期视为静音）
+func (p *Participant) pop(frameBytes int) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.buffer) < frameBytes {
+		return nil
+	}
+	frame := make([]byte, frameBytes)
+	copy(frame, p.buffer)
+	p.buffer = append(p.buffer[:0], p.buffer[frameBytes:]...)
+	return frame
+}
+
+// SetMuted 静音后该成员的声音不再混入，仍可收听
+func (p *Participant) SetMuted(muted bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.muted = muted
+}
+
+// Muted 是否已静音
+func (p *Participant) Muted() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.muted
+}
+
+func (p *Participant) stop() {
+	p.stopOnce.Do(func() { close(p.done) })
+}
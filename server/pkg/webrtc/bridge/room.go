@@ -0,0 +1,205 @@
+// Package bridge 多方通话混音桥：把多个 WebRTCTransport 接入同一个房间，
+// 每 20ms 混合所有发言者的 PCM，再分别编码发回给每个成员（N-1 混音，不回放自己的声音）。
+// 适用于小型群组通话和坐席主管旁听。
+package bridge
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultSampleRate 房间内混音的采样率，与 ASR/TTS 使用的 16kHz 一致
+	DefaultSampleRate = 16000
+	// FrameDuration 混音周期
+	FrameDuration = 20 * time.Millisecond
+	// maxBufferedFrames 每个成员最多缓存的帧数，超出时丢弃最旧的音频以限制延迟
+	maxBufferedFrames = 10
+)
+
+var (
+	// ErrRoomClosed 房间已关闭
+	ErrRoomClosed = errors.New("bridge: room closed")
+	// ErrParticipantExists 同一 ID 的成员已在房间中
+	ErrParticipantExists = errors.New("bridge: participant already in room")
+	// ErrNoTxTrack 传输层尚未创建发送轨道，无法发回混音
+	ErrNoTxTrack = errors.New("bridge: transport has no tx track")
+)
+
+// Room 混音房间，成员可并发加入和离开
+type Room struct {
+	ID string
+
+	sampleRate int
+	frameBytes int // 一帧 16-bit 单声道 PCM 的字节数
+
+	mu           sync.Mutex
+	participants map[string]*Participant
+	closed       bool
+	done         chan struct{}
+}
+
+// NewRoom 创建房间并开始混音
+func NewRoom(id string) *Room {
+	r := newRoom(id, DefaultSampleRate)
+	go r.run()
+	return r
+}
+
+func newRoom(id string, sampleRate int) *Room {
+	return &Room{
+		ID:           id,
+		sampleRate:   sampleRate,
+		frameBytes:   int(int64(sampleRate)*int64(FrameDuration)/int64(time.Second)) * 2,
+		participants: make(map[string]*Participant),
+		done:         make(chan struct{}),
+	}
+}
+
+// SampleRate 房间混音的采样率
+func (r *Room) SampleRate() int {
+	return r.sampleRate
+}
+
+// add 加入一个成员，sink 接收发给该成员的混音 PCM
+func (r *Room) add(id string, role Role, sink func(pcm []byte) error) (*Participant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, ErrRoomClosed
+	}
+	if _, exists := r.participants[id]; exists {
+		return nil, ErrParticipantExists
+	}
+	p := &Participant{
+		ID:   id,
+		Role: role,
+		room: r,
+		sink: sink,
+		done: make(chan struct{}),
+	}
+	r.participants[id] = p
+	return p, nil
+}
+
+// Leave 移除成员；成员的传输层由调用方关闭
+func (r *Room) Leave(id string) {
+	r.mu.Lock()
+	p, ok := r.participants[id]
+	delete(r.participants, id)
+	r.mu.Unlock()
+	if ok {
+		p.stop()
+	}
+}
+
+// Participant 按 ID 查找成员
+func (r *Room) Participant(id string) (*Participant, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.participants[id]
+	return p, ok
+}
+
+// Participants 返回当前成员的快照
+func (r *Room) Participants() []*Participant {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]*Participant, 0, len(r.participants))
+	for _, p := range r.participants {
+		list = append(list, p)
+	}
+	return list
+}
+
+// Close 停止混音并移除所有成员
+func (r *Room) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	participants := r.participants
+	r.participants = make(map[string]*Participant)
+	close(r.done)
+	r.mu.Unlock()
+
+	for _, p := range participants {
+		p.stop()
+	}
+}
+
+// Done 房间关闭时关闭
+func (r *Room) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *Room) run() {
+	ticker := time.NewTicker(FrameDuration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			r.tick()
+		}
+	}
+}
+
+// tick 混合一帧：每个发言者取一帧累加，再为每个成员减去自己的声音后发出
+func (r *Room) tick() {
+	participants := r.Participants()
+	if len(participants) == 0 {
+		return
+	}
+
+	total := make([]int32, r.frameBytes/2)
+	inputs := make(map[*Participant][]byte, len(participants))
+	for _, p := range participants {
+		if p.Role != RoleSpeaker {
+			continue
+		}
+		// 静音的成员同样消费缓冲，取消静音后不会播放积压的音频
+		frame := p.pop(r.frameBytes)
+		if frame == nil || p.Muted() {
+			continue
+		}
+		inputs[p] = frame
+		for i := range total {
+			total[i] += int32(int16(uint16(frame[2*i]) | uint16(frame[2*i+1])<<8))
+		}
+	}
+
+	for _, p := range participants {
+		if err := p.sink(mixExcluding(total, inputs[p])); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"room":        r.ID,
+				"participant": p.ID,
+			}).Debug("bridge: failed to send mix")
+		}
+	}
+}
+
+// mixExcluding 返回总混音减去 own 后的 PCM，超出 16-bit 范围时削波
+func mixExcluding(total []int32, own []byte) []byte {
+	out := make([]byte, len(total)*2)
+	for i, sum := range total {
+		if own != nil {
+			sum -= int32(int16(uint16(own[2*i]) | uint16(own[2*i+1])<<8))
+		}
+		if sum > 32767 {
+			sum = 32767
+		} else if sum < -32768 {
+			sum = -32768
+		}
+		v := uint16(int16(sum))
+		out[2*i] = byte(v)
+		out[2*i+1] = byte(v >> 8)
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package bridge
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pcmFrame(r *Room, value int16) []byte {
+	frame := make([]byte, r.frameBytes)
+	for i := 0; i < len(frame); i += 2 {
+		binary.LittleEndian.PutUint16(frame[i:], uint16(value))
+	}
+	return frame
+}
+
+// joinTest 加入一个把收到的混音记录下来的成员
+func joinTest(t *testing.T, r *Room, id string, role Role) (*Participant, *[][]byte) {
+	var received [][]byte
+	p, err := r.add(id, role, func(pcm []byte) error {
+		received = append(received, pcm)
+		return nil
+	})
+	require.NoError(t, err)
+	return p, &received
+}
+
+func firstSample(pcm []byte) int16 {
+	return int16(binary.LittleEndian.Uint16(pcm))
+}
+
+func TestRoomMixExcludesOwnAudio(t *testing.T) {
+	r := newRoom("test", DefaultSampleRate)
+	assert.Equal(t, 640, r.frameBytes)
+
+	alice, aliceGot := joinTest(t, r, "alice", RoleSpeaker)
+	bob, bobGot := joinTest(t, r, "bob", RoleSpeaker)
+	_, supervisorGot := joinTest(t, r, "supervisor", RoleListener)
+
+	alice.push(pcmFrame(r, 1000))
+	bob.push(pcmFrame(r, 2000))
+	r.tick()
+
+	require.Len(t, *aliceGot, 1)
+	require.Len(t, *bobGot, 1)
+	require.Len(t, *supervisorGot, 1)
+	assert.Equal(t, int16(2000), firstSample((*aliceGot)[0]))
+	assert.Equal(t, int16(1000), firstSample((*bobGot)[0]))
+	assert.Equal(t, int16(3000), firstSample((*supervisorGot)[0]))
+	assert.Len(t, (*supervisorGot)[0], r.frameBytes)
+
+	// 缓冲不足一帧的成员本周期静音，其余成员仍收到帧
+	bob.push(pcmFrame(r, 2000)[:100])
+	r.tick()
+	assert.Equal(t, int16(0), firstSample((*aliceGot)[1]))
+	assert.Equal(t, int16(0), firstSample((*supervisorGot)[1]))
+}
+
+func TestRoomMuteAndClipping(t *testing.T) {
+	r := newRoom("test", DefaultSampleRate)
+	alice, _ := joinTest(t, r, "alice", RoleSpeaker)
+	bob, _ := joinTest(t, r, "bob", RoleSpeaker)
+	carol, _ := joinTest(t, r, "carol", RoleSpeaker)
+	_, listenerGot := joinTest(t, r, "listener", RoleListener)
+
+	alice.push(pcmFrame(r, 20000))
+	bob.push(pcmFrame(r, 20000))
+	carol.push(pcmFrame(r, -30000))
+	carol.SetMuted(true)
+	r.tick()
+	assert.Equal(t, int16(32767), firstSample((*listenerGot)[0]))
+
+	// 静音期间的音频已被消费，取消静音后不会播放积压的音频
+	carol.SetMuted(false)
+	r.tick()
+	assert.Equal(t, int16(0), firstSample((*listenerGot)[1]))
+}
+
+func TestRoomBufferLimit(t *testing.T) {
+	r := newRoom("test", DefaultSampleRate)
+	alice, _ := joinTest(t, r, "alice", RoleSpeaker)
+	_, listenerGot := joinTest(t, r, "listener", RoleListener)
+
+	alice.push(pcmFrame(r, 1))
+	for i := 0; i < maxBufferedFrames; i++ {
+		alice.push(pcmFrame(r, 2))
+	}
+	// 最旧的一帧被丢弃
+	r.tick()
+	assert.Equal(t, int16(2), firstSample((*listenerGot)[0]))
+}
+
+func TestRoomMembership(t *testing.T) {
+	r := newRoom("test", DefaultSampleRate)
+	joinTest(t, r, "alice", RoleSpeaker)
+
+	_, err := r.add("alice", RoleSpeaker, func([]byte) error { return nil })
+	assert.ErrorIs(t, err, ErrParticipantExists)
+
+	p, ok := r.Participant("alice")
+	require.True(t, ok)
+	r.Leave("alice")
+	_, ok = r.Participant("alice")
+	assert.False(t, ok)
+	select {
+	case <-p.done:
+	default:
+		t.Fatal("participant not stopped after leaving")
+	}
+
+	joinTest(t, r, "bob", RoleListener)
+	r.Close()
+	assert.Empty(t, r.Participants())
+	_, err = r.add("carol", RoleSpeaker, func([]byte) error { return nil })
+	assert.ErrorIs(t, err, ErrRoomClosed)
+	r.Close()
+}
+
+func TestTrackSink(t *testing.T) {
+	r := newRoom("test", DefaultSampleRate)
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypePCMA, ClockRate: 8000}, "audio", "bridge")
+	require.NoError(t, err)
+	sink, err := r.trackSink(track)
+	require.NoError(t, err)
+	assert.NoError(t, sink(pcmFrame(r, 1000)))
+
+	video, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "bridge")
+	require.NoError(t, err)
+	_, err = r.trackSink(video)
+	assert.Error(t, err)
+}
@@ -11,6 +11,17 @@ const (
 	WebRTCOffer       = "offer"
 	WebRTCAnswer      = "answer"
 	WebRTCCandidate   = "candidate"
+	// DataChannelLabel 传输转写文本与控制消息的 DataChannel 名称
+	DataChannelLabel = "lingecho"
+
+	// ICE 重启：断开后等待 DefaultDisconnectGrace 仍未恢复则重启，失败后指数退避重试
+	DefaultReconnectAttempts   = 5
+	DefaultReconnectBackoff    = time.Second
+	DefaultReconnectMaxBackoff = 10 * time.Second
+	DefaultDisconnectGrace     = 2 * time.Second
+
+	// DefaultStatsInterval 通话质量（丢包、抖动、MOS）上报到 metrics 的周期
+	DefaultStatsInterval = 5 * time.Second
 )
 
 const (
@@ -19,4 +30,7 @@ const (
 	CodecG722 = "g722"
 	CodecOPUS = "opus"
 	CodecG711 = "g711"
+
+	// CodecOpus 与 CodecOPUS 相同，48kHz 宽带语音
+	CodecOpus = CodecOPUS
 )
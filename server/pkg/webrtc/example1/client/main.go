@@ -1,23 +1,29 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/url"
 	"os"
 	"os/signal"
+	"sync"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/devices"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"github.com/code-100-precent/LingEcho/pkg/media"
-	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	_ "github.com/code-100-precent/LingEcho/pkg/media/encoder" // registers the codecs used by media pipelines
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
 	"github.com/gen2brain/malgo"
 	"github.com/gorilla/websocket"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
+	"go.uber.org/zap"
 )
 
 // Constants
@@ -28,20 +34,26 @@ const (
 	wsPath   = "/websocket"
 
 	// Connection retry configuration
-	maxConnectionRetries       = 100
-	connectionRetryDelay       = 100 * time.Millisecond
-	connectionStateLogInterval = 10
+	maxConnectionRetries = 100
+	connectionRetryDelay = 100 * time.Millisecond
+	connectionTimeout    = 10 * time.Second
 
-	// Audio configuration
-	targetSampleRate = 8000 // PCMA standard sample rate
-	audioChannels    = 1
-	audioBitDepth    = 16
+	// Audio configuration (playback runs at the negotiated codec's sample rate)
+	audioChannels = 1
 
 	// Logging intervals
 	packetLogInterval = 100
 	warningLogLimit   = 3
 )
 
+// Command-line flags
+var (
+	listDevices  = flag.Bool("list-devices", false, "list audio devices and exit")
+	outputDevice = flag.String("output-device", "", "playback device: ID, #index or name (default: system default)")
+	codecName    = flag.String("codec", constants.CodecPCMA, "audio codec to negotiate: pcma, pcmu, g722 or opus")
+	jitterDelay  = flag.Duration("jitter-delay", media.DefaultJitterTargetDelay, "jitter buffer target delay")
+)
+
 // SignalMessage represents a WebSocket signaling message
 type SignalMessage struct {
 	Type      string      `json:"type"`
@@ -56,6 +68,9 @@ type Client struct {
 	sessionID string
 	interrupt chan os.Signal
 	done      chan struct{}
+
+	// writeMu serializes WebSocket writes (trickled candidates are sent from ICE callbacks)
+	writeMu sync.Mutex
 }
 
 // NewClient creates a new WebRTC client
@@ -74,7 +89,7 @@ func NewClient() (*Client, error) {
 
 	// Create WebRTC transport
 	transport := rtcmedia.NewWebRTCTransport(rtcmedia.WebRTCOption{
-		Codec:      constants.CodecPCMA,
+		Codec:      *codecName,
 		ICETimeout: constants.DefaultICETimeout,
 		ICEServers: []webrtc.ICEServer{
 			{URLs: []string{"stun:stun.l.google.com:19302"}},
@@ -122,60 +137,95 @@ func (c *Client) InitializeSession() (string, error) {
 	}
 
 	c.sessionID = initSignal.SessionID
-	fmt.Printf("[Client] Connected with session ID: %s\n", c.sessionID)
+	logger.Info("[Client] Connected", zap.String("session", c.sessionID))
 	return c.sessionID, nil
 }
 
-// CreateAndSendOffer creates a WebRTC offer and sends it to the server
+// sendSignal writes a signaling message to the WebSocket
+func (c *Client) sendSignal(msg SignalMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.wsConn.WriteJSON(msg)
+}
+
+// CreateAndSendOffer creates a WebRTC offer and sends it to the server.
+// ICE candidates are trickled to the server as they are gathered instead of
+// waiting for gathering to complete.
 func (c *Client) CreateAndSendOffer() error {
 	c.transport.NewPeerConnection()
 
-	offer, candidates, err := c.transport.CreateOffer()
+	// Candidates gathered after the offer is sent arrive at the server as "candidate" messages
+	c.transport.OnICECandidate(func(candidate string) {
+		candidateMsg := SignalMessage{
+			Type:      constants.WebRTCCandidate,
+			SessionID: c.sessionID,
+			Data:      map[string]interface{}{"candidate": candidate},
+		}
+		if err := c.sendSignal(candidateMsg); err != nil {
+			logger.Warn("[Client] Error sending ICE candidate", zap.Error(err))
+		}
+	})
+
+	// ICE restart: when the network blips the transport re-offers over the same WebSocket
+	c.transport.OnRestartOffer(func(offer string, _ []string) error {
+		return c.sendSignal(SignalMessage{
+			Type:      "offer",
+			SessionID: c.sessionID,
+			Data: map[string]interface{}{
+				"sdp":         offer,
+				"trickle":     true,
+				"ice_restart": true,
+			},
+		})
+	})
+	c.transport.OnReconnected(func() {
+		logger.Info("[Client] WebRTC connection recovered")
+	})
+
+	offer, _, err := c.transport.CreateOffer()
 	if err != nil {
 		return fmt.Errorf("failed to create offer: %w", err)
 	}
 
-	fmt.Printf("[Client] Created offer with %d candidates\n", len(candidates))
-
 	offerMsg := SignalMessage{
 		Type:      "offer",
 		SessionID: c.sessionID,
 		Data: map[string]interface{}{
-			"sdp":        offer,
-			"candidates": candidates,
+			"sdp":     offer,
+			"trickle": true,
 		},
 	}
-
-	offerBytes, err := json.Marshal(offerMsg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal offer: %w", err)
-	}
-
-	if err := c.wsConn.WriteMessage(websocket.TextMessage, offerBytes); err != nil {
+	if err := c.sendSignal(offerMsg); err != nil {
 		return fmt.Errorf("failed to send offer: %w", err)
 	}
 
-	fmt.Println("[Client] Offer sent to server")
+	logger.Info("[Client] Offer sent to server, trickling ICE candidates")
 	return nil
 }
 
+// HandleCandidate adds an ICE candidate trickled by the server
+func (c *Client) HandleCandidate(msg SignalMessage) error {
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid candidate data")
+	}
+	candidate, ok := data["candidate"].(string)
+	if !ok || candidate == "" {
+		return fmt.Errorf("invalid candidate")
+	}
+	return c.transport.AddICECandidate(candidate)
+}
+
 // WaitForConnection waits for the WebRTC connection to be established
 func (c *Client) WaitForConnection() error {
-	for i := 0; i < maxConnectionRetries; i++ {
-		state := c.transport.GetConnectionState()
-		if state == webrtc.PeerConnectionStateConnected {
-			fmt.Println("[Client] WebRTC connection established")
-			return nil
-		}
-
-		if i%connectionStateLogInterval == 0 {
-			fmt.Printf("[Client] Waiting for connection... (state: %s)\n", state.String())
-		}
-
-		time.Sleep(connectionRetryDelay)
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	logger.Info("[Client] Waiting for connection", zap.String("state", c.transport.GetConnectionState().String()))
+	if err := c.transport.WaitUntilConnected(ctx); err != nil {
+		return fmt.Errorf("connection not established (state: %s): %w", c.transport.GetConnectionState().String(), err)
 	}
-
-	return fmt.Errorf("connection timeout after %d retries", maxConnectionRetries)
+	logger.Info("[Client] WebRTC connection established")
+	return nil
 }
 
 // WaitForTrack waits for the remote audio track to be available
@@ -193,42 +243,48 @@ func (c *Client) WaitForTrack() (*webrtc.TrackRemote, error) {
 
 // SetupAudioPlayback sets up audio playback components
 func (c *Client) SetupAudioPlayback() (*devices.StreamAudioPlayer, media.EncoderFunc, error) {
+	// PCMA/PCMU play at 8kHz, Opus at 48kHz
+	wireConfig := rtcmedia.CodecConfigFor(*codecName)
+	playbackSampleRate := wireConfig.SampleRate
+
 	// Create stream player
 	streamPlayer, err := devices.NewStreamAudioPlayer(
 		audioChannels,
-		targetSampleRate,
+		uint32(playbackSampleRate),
 		malgo.FormatS16,
 	)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create stream player: %w", err)
 	}
 
+	if *outputDevice != "" {
+		device, err := streamPlayer.SelectDevice(*outputDevice)
+		if err != nil {
+			streamPlayer.Close()
+			return nil, nil, fmt.Errorf("failed to select output device: %w", err)
+		}
+		logger.Info("[Client] Using output device", zap.String("device", device.Name))
+	}
+
+	// Live audio: if the device falls behind, skip stale audio rather than rejecting new frames
+	streamPlayer.SetOverflowPolicy(devices.OverflowDropOldest)
+
 	// Start playback
 	if err := streamPlayer.Play(); err != nil {
 		streamPlayer.Close()
 		return nil, nil, fmt.Errorf("failed to start playback: %w", err)
 	}
 
-	fmt.Printf("[Client] Audio playback started: %dHz, %d channel(s)\n",
-		targetSampleRate, audioChannels)
+	logger.Info("[Client] Audio playback started",
+		zap.Int("sampleRate", playbackSampleRate),
+		zap.Int("channels", audioChannels))
 
-	// Create PCMA decoder
-	decodeFunc, err := encoder.CreateDecode(
-		media.CodecConfig{
-			Codec:         "pcma",
-			SampleRate:    targetSampleRate,
-			Channels:      audioChannels,
-			BitDepth:      8,
-			FrameDuration: "20ms",
-		},
-		media.CodecConfig{
-			Codec:         "pcm",
-			SampleRate:    targetSampleRate,
-			Channels:      audioChannels,
-			BitDepth:      audioBitDepth,
-			FrameDuration: "20ms",
-		},
-	)
+	// Create decoder for the negotiated codec
+	decodeFunc, err := media.NewPipeline().
+		Decode(wireConfig).
+		Mono().
+		Resample(playbackSampleRate).
+		Build()
 	if err != nil {
 		streamPlayer.Close()
 		return nil, nil, fmt.Errorf("failed to create decoder: %w", err)
@@ -241,7 +297,7 @@ func (c *Client) SetupAudioPlayback() (*devices.StreamAudioPlayer, media.Encoder
 func (c *Client) ProcessAudioPacket(
 	packet *rtp.Packet,
 	decodeFunc media.EncoderFunc,
-	streamPlayer *devices.StreamAudioPlayer,
+	jitterBuffer *media.JitterBuffer,
 	packetCount int,
 ) error {
 	payload := packet.Payload
@@ -249,25 +305,21 @@ func (c *Client) ProcessAudioPacket(
 		return nil
 	}
 
-	// Decode PCMA to PCM
+	// Decode the negotiated codec to PCM
 	audioPacket := &media.AudioPacket{Payload: payload}
 	decodedPackets, err := decodeFunc(audioPacket)
 	if err != nil {
 		if packetCount%packetLogInterval == 0 {
-			fmt.Printf("[Client] Error decoding frame %d: %v\n", packetCount, err)
+			logger.Warn("[Client] Error decoding frame", zap.Int("packet", packetCount), zap.Error(err))
 		}
 		return err
 	}
 
-	// Collect all decoded PCM data and write at once to reduce discontinuity
+	// Collect all decoded PCM data as one frame; the jitter buffer reorders
+	// frames by sequence number and conceals the missing ones
 	allPCMData := c.collectPCMData(decodedPackets, packetCount)
 	if len(allPCMData) > 0 {
-		if err := streamPlayer.Write(allPCMData); err != nil {
-			// Buffer full is not critical, only log other errors
-			if packetCount%packetLogInterval == 0 && err.Error() != "音频缓冲区已满" {
-				fmt.Printf("[Client] Error writing to player: %v\n", err)
-			}
-		}
+		jitterBuffer.Push(packet.SequenceNumber, packet.Timestamp, allPCMData)
 	}
 
 	return nil
@@ -291,8 +343,7 @@ func (c *Client) collectPCMData(decodedPackets []media.MediaPacket, packetCount
 		// Validate PCM data (should be 16-bit, so length must be even)
 		if len(af.Payload)%2 != 0 {
 			if packetCount <= warningLogLimit {
-				fmt.Printf("[Client] Warning: Odd PCM length at packet %d: %d bytes\n",
-					packetCount, len(af.Payload))
+				logger.Warn("[Client] Odd PCM length", zap.Int("packet", packetCount), zap.Int("bytes", len(af.Payload)))
 			}
 			continue
 		}
@@ -312,7 +363,21 @@ func (c *Client) StartAudioReceiver(rxTrack *webrtc.TrackRemote) error {
 	defer streamPlayer.Close()
 
 	codec := rxTrack.Codec()
-	fmt.Printf("[Client] Received track: %s, %dHz\n", codec.MimeType, codec.ClockRate)
+	logger.Info("[Client] Received track", zap.String("codec", codec.MimeType), zap.Uint32("clockRate", codec.ClockRate))
+
+	// Play out at a steady 20ms cadence regardless of packet arrival timing
+	jitterBuffer := media.NewJitterBuffer(media.JitterBufferConfig{
+		TargetDelay: *jitterDelay,
+		ClockRate:   int(codec.ClockRate),
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go jitterBuffer.Drain(ctx, func(frame []byte) {
+		// Buffer full is not critical, only log other errors
+		if err := streamPlayer.Write(frame); err != nil && !errors.Is(err, devices.ErrBufferFull) {
+			logger.Warn("[Client] Error writing to player", zap.Error(err))
+		}
+	})
 
 	packetCount := 0
 	for {
@@ -321,14 +386,19 @@ func (c *Client) StartAudioReceiver(rxTrack *webrtc.TrackRemote) error {
 			return fmt.Errorf("error reading RTP packet: %w", err)
 		}
 
-		if err := c.ProcessAudioPacket(packet, decodeFunc, streamPlayer, packetCount); err != nil {
+		if err := c.ProcessAudioPacket(packet, decodeFunc, jitterBuffer, packetCount); err != nil {
 			// Continue processing even if one packet fails
 			continue
 		}
 
 		packetCount++
 		if packetCount%packetLogInterval == 0 {
-			fmt.Printf("[Client] Received and played %d RTP packets\n", packetCount)
+			stats := jitterBuffer.Stats()
+			logger.Debug("[Client] Received RTP packets",
+				zap.Int("packets", packetCount),
+				zap.Duration("jitterDelay", stats.Delay),
+				zap.Uint64("concealed", stats.Concealed),
+				zap.Uint64("late", stats.Late))
 		}
 	}
 }
@@ -351,16 +421,12 @@ func (c *Client) HandleAnswer(msg SignalMessage) error {
 		return fmt.Errorf("failed to set remote description: %w", err)
 	}
 
-	// Extract and add ICE candidates
-	candidates, ok := answerData["candidates"].([]interface{})
-	if !ok {
-		return fmt.Errorf("invalid candidates data")
-	}
-
+	// Add ICE candidates bundled with the answer (servers without trickle support)
+	candidates, _ := answerData["candidates"].([]interface{})
 	candidateStrs := c.extractCandidates(candidates)
 	for _, candidate := range candidateStrs {
 		if err := c.transport.AddICECandidate(candidate); err != nil {
-			log.Printf("[Client] Error adding ICE candidate: %v", err)
+			logger.Warn("[Client] Error adding ICE candidate", zap.Error(err))
 		}
 	}
 
@@ -370,11 +436,11 @@ func (c *Client) HandleAnswer(msg SignalMessage) error {
 		SessionID: c.sessionID,
 		Data:      map[string]interface{}{},
 	}
-	if err := c.wsConn.WriteJSON(connectedMsg); err != nil {
+	if err := c.sendSignal(connectedMsg); err != nil {
 		return fmt.Errorf("failed to send connected message: %w", err)
 	}
 
-	fmt.Println("[Client] WebRTC connection should now be establishing...")
+	logger.Info("[Client] WebRTC connection establishing")
 
 	// Wait for connection
 	if err := c.WaitForConnection(); err != nil {
@@ -391,6 +457,36 @@ func (c *Client) HandleAnswer(msg SignalMessage) error {
 	return c.StartAudioReceiver(rxTrack)
 }
 
+// HandleRestartAnswer applies the answer to an ICE restart offer; media and playback keep running
+func (c *Client) HandleRestartAnswer(msg SignalMessage) error {
+	answerData, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid answer data")
+	}
+	answerStr, ok := answerData["sdp"].(string)
+	if !ok {
+		return fmt.Errorf("invalid answer SDP")
+	}
+	answerJSON, err := json.Marshal(map[string]string{
+		"type": "answer",
+		"sdp":  answerStr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal answer SDP: %w", err)
+	}
+	if err := c.transport.SetRemoteDescription(string(answerJSON)); err != nil {
+		return fmt.Errorf("failed to set remote description: %w", err)
+	}
+	candidates, _ := answerData["candidates"].([]interface{})
+	for _, candidate := range c.extractCandidates(candidates) {
+		if err := c.transport.AddICECandidate(candidate); err != nil {
+			logger.Warn("[Client] Error adding ICE candidate", zap.Error(err))
+		}
+	}
+	logger.Info("[Client] ICE restart answer applied")
+	return nil
+}
+
 // extractCandidates extracts candidate strings from the interface slice
 func (c *Client) extractCandidates(candidates []interface{}) []string {
 	var candidateStrs []string
@@ -409,23 +505,36 @@ func (c *Client) StartMessageListener() {
 		for {
 			_, message, err := c.wsConn.ReadMessage()
 			if err != nil {
-				log.Printf("[Client] Error reading message: %v", err)
+				logger.Warn("[Client] Error reading message", zap.Error(err))
 				return
 			}
 
 			var signal SignalMessage
 			if err := json.Unmarshal(message, &signal); err != nil {
-				log.Printf("[Client] Error unmarshaling message: %v", err)
+				logger.Warn("[Client] Error unmarshaling message", zap.Error(err))
 				continue
 			}
 
 			switch signal.Type {
 			case "answer":
-				if err := c.HandleAnswer(signal); err != nil {
-					log.Printf("[Client] Error handling answer: %v", err)
+				if restart, _ := signal.Data.(map[string]interface{})["ice_restart"].(bool); restart {
+					if err := c.HandleRestartAnswer(signal); err != nil {
+						logger.Error("[Client] Error handling ICE restart answer", zap.Error(err))
+					}
+					continue
+				}
+				// HandleAnswer blocks until audio ends; keep reading so trickled candidates are applied
+				go func(msg SignalMessage) {
+					if err := c.HandleAnswer(msg); err != nil {
+						logger.Error("[Client] Error handling answer", zap.Error(err))
+					}
+				}(signal)
+			case constants.WebRTCCandidate:
+				if err := c.HandleCandidate(signal); err != nil {
+					logger.Warn("[Client] Error adding ICE candidate", zap.Error(err))
 				}
 			default:
-				log.Printf("[Client] Unknown message type: %s", signal.Type)
+				logger.Debug("[Client] Unknown message type", zap.String("type", signal.Type))
 			}
 		}
 	}()
@@ -449,24 +558,51 @@ func (c *Client) Run() error {
 	c.StartMessageListener()
 
 	// Wait for interrupt or done signal
-	fmt.Println("[Client] Waiting for connection to establish...")
+	logger.Info("[Client] Waiting for connection to establish")
 	select {
 	case <-c.interrupt:
-		fmt.Println("\n[Client] Interrupted, closing connection...")
+		logger.Info("[Client] Interrupted, closing connection")
 		return nil
 	case <-c.done:
-		fmt.Println("[Client] Connection closed")
+		logger.Info("[Client] Connection closed")
 		return nil
 	}
 }
 
 func main() {
+	flag.Parse()
+
+	// Initialize logger
+	logCfg := &logger.LogConfig{
+		Level:      "info",
+		Filename:   "logs/example1-client.log",
+		MaxSize:    100,
+		MaxAge:     7,
+		MaxBackups: 3,
+	}
+	if err := logger.Init(logCfg, "dev"); err != nil {
+		log.Fatalf("[Client] Failed to initialize logger: %v", err)
+	}
+	defer logger.Sync()
+
+	if *listDevices {
+		streamCtx, err := devices.NewStreamContext(nil)
+		if err != nil {
+			logger.Fatal("[Client] Failed to initialize audio context", zap.Error(err))
+		}
+		defer streamCtx.Close()
+		if err := devices.PrintAllDevices(streamCtx.GetContext()); err != nil {
+			logger.Fatal("[Client] Failed to list devices", zap.Error(err))
+		}
+		return
+	}
+
 	client, err := NewClient()
 	if err != nil {
-		log.Fatalf("[Client] Failed to create client: %v", err)
+		logger.Fatal("[Client] Failed to create client", zap.Error(err))
 	}
 
 	if err := client.Run(); err != nil {
-		log.Fatalf("[Client] Error: %v", err)
+		logger.Fatal("[Client] Error", zap.Error(err))
 	}
 }
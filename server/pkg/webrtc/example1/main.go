@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -76,24 +77,17 @@ func main() {
 
 	// Wait for connection to establish
 	fmt.Println("Waiting for connection...")
-	timeout := time.After(10 * time.Second)
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
-
-	connected := false
-	for !connected {
-		select {
-		case <-timeout:
-			fmt.Println("Connection timeout!")
-			return
-		case <-ticker.C:
-			if client.GetConnectionState() == webrtc.PeerConnectionStateConnected &&
-				server.GetConnectionState() == webrtc.PeerConnectionStateConnected {
-				connected = true
-				fmt.Println("✓ Connection established!")
-			}
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.WaitUntilConnected(ctx); err != nil {
+		fmt.Printf("Connection timeout! %v\n", err)
+		return
+	}
+	if err := server.WaitUntilConnected(ctx); err != nil {
+		fmt.Printf("Connection timeout! %v\n", err)
+		return
 	}
+	fmt.Println("✓ Connection established!")
 
 	// Server: Read and send audio file
 	go func() {
@@ -283,13 +277,11 @@ func main() {
 
 		fmt.Printf("[Client] Audio playback started: %dHz, %d channel(s)\n", sampleRate, channels)
 
-		// Create PCMA decoder
-		// Input: PCMA at 8kHz, Output: PCM at 8kHz (16-bit)
-		// No resampling needed since both are 8kHz
-		decodeFunc, err := encoder.CreateDecode(
-			media2.CodecConfig{Codec: "pcma", SampleRate: 8000, Channels: 1, BitDepth: 8, FrameDuration: "20ms"},
-			media2.CodecConfig{Codec: "pcm", SampleRate: targetSampleRate, Channels: 1, BitDepth: 16, FrameDuration: "20ms"},
-		)
+		// Decode PCMA (8kHz) to 16-bit PCM at the playback rate
+		decodeFunc, err := media2.NewPipeline().
+			Decode(media2.CodecConfig{Codec: "pcma", SampleRate: 8000, Channels: 1, FrameDuration: "20ms"}).
+			Resample(targetSampleRate).
+			Build()
 		if err != nil {
 			fmt.Printf("[Client] Error creating decoder: %v\n", err)
 			return
@@ -17,7 +17,7 @@ const (
 	// WebRTC信令
 	TypeOffer        MessageType = "offer"
 	TypeAnswer       MessageType = "answer"
-	TypeICECandidate MessageType = "ice_candidate"
+	TypeICECandidate MessageType = "candidate"
 
 	// 文本消息
 	TypeTextMessage  MessageType = "text_message"
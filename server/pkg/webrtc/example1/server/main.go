@@ -1,23 +1,22 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
-	"sync"
 	"time"
 
-	media2 "github.com/code-100-precent/LingEcho/pkg/media"
-	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/media/fileio"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/signaling"
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
-	"github.com/youpy/go-wav"
+	"go.uber.org/zap"
 )
 
 // Constants
@@ -26,24 +25,14 @@ const (
 	serverPort = ":8080"
 	wsPath     = "/websocket"
 
-	// Connection retry configuration
-	maxConnectionRetries       = 50
-	connectionRetryDelay       = 100 * time.Millisecond
-	connectionStateLogInterval = 10
-	connectionReadyDelay       = 200 * time.Millisecond
-
-	// Audio configuration
-	targetSampleRate = 8000 // PCMA standard sample rate
-	audioChannels    = 1
-	audioBitDepth    = 16
-	bytesPerSample   = 2 // 16-bit = 2 bytes
+	// Connection configuration
+	connectionTimeout    = 5 * time.Second
+	connectionReadyDelay = 200 * time.Millisecond
 
 	// Frame configuration
 	frameDurationMs = 20
-	bytesPerFrame   = 160 // 20ms * 8000Hz = 160 samples = 160 bytes (PCMA is 1 byte per sample)
 
 	// File configuration
-	readBufferSize    = 8192
 	audioFilePrimary  = "ringring.wav"
 	audioFileFallback = "ringing.wav"
 
@@ -51,389 +40,136 @@ const (
 	frameLogInterval = 50
 )
 
-// ClientManager manages WebRTC client connections
-type ClientManager struct {
-	clients map[string]*Client
-	mutex   sync.RWMutex
-}
-
-// NewClientManager creates a new client manager
-func NewClientManager() *ClientManager {
-	return &ClientManager{
-		clients: make(map[string]*Client),
-	}
-}
-
-// AddClient adds a client to the manager
-func (m *ClientManager) AddClient(sessionID string, client *Client) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	m.clients[sessionID] = client
-}
-
-// RemoveClient removes a client from the manager
-func (m *ClientManager) RemoveClient(sessionID string) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	delete(m.clients, sessionID)
-}
-
-// GetClient retrieves a client by session ID
-func (m *ClientManager) GetClient(sessionID string) (*Client, bool) {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-	client, exists := m.clients[sessionID]
-	return client, exists
-}
-
-// Client represents a WebRTC client connection
-type Client struct {
-	conn      *websocket.Conn
-	transport *rtcmedia.WebRTCTransport
-	sessionID string
-}
-
-// SignalMessage represents a WebSocket signaling message
-type SignalMessage struct {
-	Type      string      `json:"type"`
-	SessionID string      `json:"session_id,omitempty"`
-	Data      interface{} `json:"data,omitempty"`
-}
-
+// Command-line flags
 var (
-	manager  = NewClientManager()
-	upgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true // Allow all origins in development
-		},
-	}
+	// codecName selects the codec advertised to clients (pcma, pcmu, g722 or opus)
+	codecName = flag.String("codec", constants.CodecPCMA, "audio codec to negotiate: pcma, pcmu, g722 or opus")
+	audioFile = flag.String("audio", "", "audio file to play (wav, mp3 or ogg); defaults to "+audioFilePrimary)
 )
 
-// websocketHandler handles WebSocket connections
-func websocketHandler(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		log.Printf("[Server] Failed to upgrade connection: %v", err)
-		return
-	}
-	defer conn.Close()
-
-	// Create session ID
-	sessionID := fmt.Sprintf("session_%d", time.Now().UnixNano())
-
-	// Create WebRTC transport
-	transport := rtcmedia.NewWebRTCTransport(rtcmedia.WebRTCOption{
-		Codec: constants.CodecPCMA,
-		ICEServers: []webrtc.ICEServer{
-			{URLs: []string{"stun:stun.l.google.com:19302"}},
-		},
-		StreamID:   "lingecho_server",
-		ICETimeout: constants.DefaultICETimeout,
-	})
-	transport.NewPeerConnection()
-
-	client := &Client{
-		conn:      conn,
-		transport: transport,
-		sessionID: sessionID,
-	}
-
-	manager.AddClient(sessionID, client)
-	defer manager.RemoveClient(sessionID)
-
-	// Send session ID to client
-	initMsg := SignalMessage{
-		Type:      "init",
-		SessionID: sessionID,
-	}
-	if err := conn.WriteJSON(initMsg); err != nil {
-		log.Printf("[Server] Failed to send init message: %v", err)
-		return
-	}
-
-	// Handle incoming messages
-	for {
-		var msg SignalMessage
-		if err := conn.ReadJSON(&msg); err != nil {
-			log.Printf("[Server] Error reading message: %v", err)
-			break
-		}
-
-		handleSignalMessage(client, msg)
-	}
-}
-
-// handleSignalMessage routes signaling messages to appropriate handlers
-func handleSignalMessage(client *Client, msg SignalMessage) {
-	switch msg.Type {
-	case "offer":
-		handleOffer(client, msg)
-	case "connected":
-		handleConnection(client, msg)
-	default:
-		log.Printf("[Server] Unknown message type: %s", msg.Type)
-	}
-}
-
-// handleOffer handles the WebRTC offer from the client
-func handleOffer(client *Client, msg SignalMessage) {
-	offerData, ok := msg.Data.(map[string]interface{})
-	if !ok {
-		log.Println("[Server] Invalid offer data")
-		return
-	}
-
-	offerStr, ok := offerData["sdp"].(string)
-	if !ok {
-		log.Println("[Server] Invalid offer SDP")
-		return
-	}
-
-	// Set remote description
-	if err := client.transport.SetRemoteDescription(offerStr); err != nil {
-		log.Printf("[Server] Error setting remote description: %v", err)
-		return
-	}
-
-	// Extract candidates
-	candidates, ok := offerData["candidates"].([]interface{})
-	if !ok {
-		log.Println("[Server] Invalid candidates data")
-		return
-	}
-
-	candidateStrs := extractCandidates(candidates)
-	answer, serverCandidates, err := client.transport.CreateAnswer(candidateStrs)
-	if err != nil {
-		log.Printf("[Server] Error creating answer: %v", err)
-		return
-	}
-
-	// Send answer back to client
-	answerMsg := SignalMessage{
-		Type:      "answer",
-		SessionID: client.sessionID,
-		Data: map[string]interface{}{
-			"sdp":        answer,
-			"candidates": serverCandidates,
-		},
-	}
-
-	if err := client.conn.WriteJSON(answerMsg); err != nil {
-		log.Printf("[Server] Error sending answer: %v", err)
-		return
-	}
-
-	fmt.Printf("[Server] Sent answer to client %s\n", client.sessionID)
-}
-
-// extractCandidates extracts candidate strings from the interface slice
-func extractCandidates(candidates []interface{}) []string {
-	var candidateStrs []string
-	for _, c := range candidates {
-		if candidateStr, ok := c.(string); ok {
-			candidateStrs = append(candidateStrs, candidateStr)
-		}
-	}
-	return candidateStrs
+// newSignalingServer creates the signaling server; each session gets its own WebRTC transport
+func newSignalingServer() *signaling.Server {
+	srv := signaling.NewServer()
+	srv.Upgrader.CheckOrigin = func(r *http.Request) bool {
+		return true // Allow all origins in development
+	}
+	srv.OnSession = func(s *signaling.Session) error {
+		transport := rtcmedia.NewWebRTCTransport(rtcmedia.WebRTCOption{
+			Codec: *codecName,
+			ICEServers: []webrtc.ICEServer{
+				{URLs: []string{"stun:stun.l.google.com:19302"}},
+			},
+			StreamID:   "lingecho_server",
+			ICETimeout: constants.DefaultICETimeout,
+		})
+		transport.NewPeerConnection()
+		s.Transport = transport
+		return nil
+	}
+	srv.Handle(signaling.TypeConnected, handleConnection)
+	return srv
 }
 
 // handleConnection handles the connection established message and starts sending audio
-func handleConnection(client *Client, msg SignalMessage) {
+func handleConnection(s *signaling.Session, msg signaling.SignalMessage) error {
 	// Run in goroutine to avoid blocking
 	go func() {
-		if err := sendAudioToClient(client); err != nil {
-			log.Printf("[Server] Error sending audio: %v", err)
+		if err := sendAudioToClient(s.Transport); err != nil {
+			logger.Error("[Server] Error sending audio", zap.Error(err))
 		}
 	}()
+	return nil
 }
 
 // sendAudioToClient sends audio data to the client via WebRTC
-func sendAudioToClient(client *Client) error {
+func sendAudioToClient(transport *rtcmedia.WebRTCTransport) error {
 	// Wait for connection to be fully established
-	if err := waitForConnection(client.transport); err != nil {
+	if err := waitForConnection(transport); err != nil {
 		return fmt.Errorf("connection not established: %w", err)
 	}
 
 	// Additional delay to ensure everything is ready
 	time.Sleep(connectionReadyDelay)
 
-	fmt.Println("[Server] Starting to send signal...")
+	logger.Info("[Server] Starting to send signal")
 
 	// Get transmit track
-	txTrack := client.transport.GetTxTrack()
+	txTrack := transport.GetTxTrack()
 	if txTrack == nil {
 		return fmt.Errorf("txTrack is nil")
 	}
 
 	// Load and process audio file
-	pcmaData, err := loadAndProcessAudioFile()
+	frames, err := loadAndProcessAudioFile()
 	if err != nil {
 		return fmt.Errorf("failed to load audio: %w", err)
 	}
 
 	// Send audio frames
-	return sendAudioFrames(txTrack, pcmaData)
+	return sendAudioFrames(txTrack, frames)
 }
 
 // waitForConnection waits for the WebRTC connection to be established
 func waitForConnection(transport *rtcmedia.WebRTCTransport) error {
-	for i := 0; i < maxConnectionRetries; i++ {
-		state := transport.GetConnectionState()
-		if state == webrtc.PeerConnectionStateConnected {
-			return nil
-		}
-
-		if i%connectionStateLogInterval == 0 {
-			fmt.Printf("[Server] Waiting for connection... (state: %s)\n", state.String())
-		}
-
-		time.Sleep(connectionRetryDelay)
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	logger.Info("[Server] Waiting for connection", zap.String("state", transport.GetConnectionState().String()))
+	if err := transport.WaitUntilConnected(ctx); err != nil {
+		return fmt.Errorf("connection not established (state: %s): %w", transport.GetConnectionState().String(), err)
 	}
-
-	return fmt.Errorf("connection timeout after %d retries", maxConnectionRetries)
+	return nil
 }
 
-// loadAndProcessAudioFile loads and processes the audio file
-func loadAndProcessAudioFile() ([]byte, error) {
-	// Open audio file
-	file, err := openAudioFile()
+// loadAndProcessAudioFile loads the audio file and encodes it into 20ms frames of the negotiated codec
+func loadAndProcessAudioFile() ([][]byte, error) {
+	src, err := openAudioFile()
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	// Read WAV format
-	w := wav.NewReader(file)
-	format, err := w.Format()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get WAV format: %w", err)
-	}
-
-	fmt.Printf("[Server] WAV format: %dHz, %d channels, %d bits\n",
-		format.SampleRate, format.NumChannels, format.BitsPerSample)
-
-	// Read entire file
-	allPCMData, err := readWAVFile(w)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read WAV file: %w", err)
-	}
+	defer src.Close()
 
-	fmt.Printf("[Server] Read %d bytes from WAV file\n", len(allPCMData))
-
-	// Convert to mono if needed
-	channels := int(format.NumChannels)
-	if channels > 1 {
-		allPCMData = convertToMono(allPCMData, channels, int(format.BitsPerSample))
-		channels = 1
-	}
-
-	// Resample if needed
-	if int(format.SampleRate) != targetSampleRate {
-		allPCMData, err = resampleAudio(allPCMData, int(format.SampleRate))
-		if err != nil {
-			return nil, err
-		}
-	}
+	format := src.Format()
+	logger.Info("[Server] Audio file format",
+		zap.Int("sampleRate", format.SampleRate),
+		zap.Int("channels", format.Channels))
 
-	// Encode to PCMA
-	pcmaData, err := encoder.EncodePCMA(allPCMData)
+	// Downmix, resample and encode to the negotiated codec, one packet per 20ms frame
+	wireConfig := rtcmedia.CodecConfigFor(*codecName)
+	frames, err := fileio.EncodeFrames(src, wireConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode to PCMA: %w", err)
+		return nil, fmt.Errorf("failed to encode to %s: %w", wireConfig.Codec, err)
 	}
 
-	fmt.Printf("[Server] Encoded %d bytes PCM to %d bytes PCMA\n",
-		len(allPCMData), len(pcmaData))
+	logger.Info("[Server] Encoded audio file",
+		zap.Int("frames", len(frames)),
+		zap.String("codec", wireConfig.Codec))
 
-	return pcmaData, nil
+	return frames, nil
 }
 
-// openAudioFile opens the audio file with fallback
-func openAudioFile() (*os.File, error) {
-	file, err := os.Open(audioFilePrimary)
-	if err != nil {
-		file, err = os.Open(audioFileFallback)
+// openAudioFile opens the -audio file, or the bundled ringtone with fallback
+func openAudioFile() (fileio.Source, error) {
+	if *audioFile != "" {
+		return fileio.Open(*audioFile)
 	}
+	src, err := fileio.Open(audioFilePrimary)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open audio file: %w", err)
+		src, err = fileio.Open(audioFileFallback)
 	}
-	return file, nil
-}
-
-// readWAVFile reads the entire WAV file
-func readWAVFile(w *wav.Reader) ([]byte, error) {
-	var allPCMData []byte
-	tempBuffer := make([]byte, readBufferSize)
-
-	for {
-		n, err := w.Read(tempBuffer)
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-		allPCMData = append(allPCMData, tempBuffer[:n]...)
-	}
-
-	return allPCMData, nil
-}
-
-// convertToMono converts stereo audio to mono by averaging channels
-func convertToMono(data []byte, channels, bytesPerSample int) []byte {
-	if bytesPerSample != 2 {
-		return data // Only support 16-bit
-	}
-
-	sampleCount := len(data) / (bytesPerSample * channels)
-	monoData := make([]byte, sampleCount*bytesPerSample)
-
-	for i := 0; i < sampleCount; i++ {
-		leftIdx := i * bytesPerSample * channels
-		rightIdx := leftIdx + bytesPerSample
-
-		if rightIdx+bytesPerSample <= len(data) {
-			leftSample := int16(data[leftIdx]) | int16(data[leftIdx+1])<<8
-			rightSample := int16(data[rightIdx]) | int16(data[rightIdx+1])<<8
-
-			// Average the two channels
-			avg := int16((int32(leftSample) + int32(rightSample)) / 2)
-
-			monoIdx := i * bytesPerSample
-			monoData[monoIdx] = byte(avg)
-			monoData[monoIdx+1] = byte(avg >> 8)
-		}
-	}
-
-	fmt.Printf("[Server] Converted stereo to mono (%d samples)\n", sampleCount)
-	return monoData
-}
-
-// resampleAudio resamples audio to target sample rate
-func resampleAudio(data []byte, sourceRate int) ([]byte, error) {
-	fmt.Printf("[Server] Resampling from %dHz to %dHz...\n", sourceRate, targetSampleRate)
-
-	resampled, err := media2.ResamplePCM(data, sourceRate, targetSampleRate)
 	if err != nil {
-		return nil, fmt.Errorf("resampling failed: %w", err)
+		return nil, fmt.Errorf("failed to open audio file: %w", err)
 	}
-
-	fmt.Printf("[Server] Resampled to %dHz (%d bytes)\n", targetSampleRate, len(resampled))
-	return resampled, nil
+	return src, nil
 }
 
 // sendAudioFrames sends audio frames with precise timing
-func sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, pcmaData []byte) error {
+func sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, frames [][]byte) error {
 	frameDuration := time.Duration(frameDurationMs) * time.Millisecond
 	startTime := time.Now()
 	frameCount := 0
+	totalBytes := 0
 
-	for i := 0; i < len(pcmaData); i += bytesPerFrame {
-		end := i + bytesPerFrame
-		if end > len(pcmaData) {
-			end = len(pcmaData)
-		}
-
+	for _, frame := range frames {
 		// Calculate exact send time to maintain consistent frame rate
 		expectedTime := startTime.Add(time.Duration(frameCount) * frameDuration)
 		if now := time.Now(); expectedTime.After(now) {
@@ -441,7 +177,7 @@ func sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, pcmaData []byte) er
 		}
 
 		sample := media.Sample{
-			Data:     pcmaData[i:end],
+			Data:     frame,
 			Duration: frameDuration,
 		}
 
@@ -450,29 +186,43 @@ func sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, pcmaData []byte) er
 		}
 
 		frameCount++
+		totalBytes += len(frame)
 		if frameCount%frameLogInterval == 0 {
-			fmt.Printf("[Server] Sent %d frames (PCMA: %d bytes)...\n",
-				frameCount, len(pcmaData[i:end]))
+			logger.Debug("[Server] Sent frames", zap.Int("frames", frameCount), zap.Int("frameBytes", len(frame)))
 		}
 	}
 
-	fmt.Printf("[Server] Finished sending audio (%d frames, %d bytes PCMA)\n",
-		frameCount, len(pcmaData))
+	logger.Info("[Server] Finished sending audio", zap.Int("frames", frameCount), zap.Int("bytes", totalBytes))
 
 	return nil
 }
 
 func main() {
+	flag.Parse()
+
+	// Initialize logger
+	logCfg := &logger.LogConfig{
+		Level:      "info",
+		Filename:   "logs/example1-server.log",
+		MaxSize:    100,
+		MaxAge:     7,
+		MaxBackups: 3,
+	}
+	if err := logger.Init(logCfg, "dev"); err != nil {
+		log.Fatalf("[Server] Failed to initialize logger: %v", err)
+	}
+	defer logger.Sync()
+
 	// Set Gin to release mode
 	gin.SetMode(gin.ReleaseMode)
 
 	// Create router
 	router := gin.Default()
-	router.GET(wsPath, websocketHandler)
+	router.GET(wsPath, gin.WrapH(newSignalingServer()))
 
 	// Start server
-	fmt.Printf("[Server] Starting server on %s\n", serverPort)
+	logger.Info("[Server] Starting server", zap.String("addr", serverPort))
 	if err := router.Run(serverPort); err != nil {
-		log.Fatalf("[Server] Failed to start server: %v", err)
+		logger.Fatal("[Server] Failed to start server", zap.Error(err))
 	}
 }
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"math"
@@ -12,8 +15,10 @@ import (
 	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/devices"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
 	media2 "github.com/code-100-precent/LingEcho/pkg/media"
-	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	"github.com/code-100-precent/LingEcho/pkg/media/aec"
+	_ "github.com/code-100-precent/LingEcho/pkg/media/encoder" // registers the codecs used by media pipelines
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
 	"github.com/gen2brain/malgo"
@@ -21,6 +26,7 @@ import (
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
+	"go.uber.org/zap"
 )
 
 // Constants
@@ -31,27 +37,35 @@ const (
 	wsPath   = "/websocket"
 
 	// Connection retry configuration
-	maxConnectionRetries       = 100
-	connectionRetryDelay       = 100 * time.Millisecond
-	connectionStateLogInterval = 10
+	maxConnectionRetries = 100
+	connectionRetryDelay = 100 * time.Millisecond
+	connectionTimeout    = 10 * time.Second
 
 	// Audio configuration
 	// Use 16kHz for microphone capture and ASR processing.
 	// Most microphones support 16kHz well (better than 8kHz).
-	// The PCMA codec will handle the encoding internally.
+	// The negotiated codec (PCMA 8kHz or Opus 48kHz) resamples internally.
 	// QCloud ASR uses 16k_zh model which expects 16kHz signal.
 	targetSampleRate = 16000
 	audioChannels    = 1
-	audioBitDepth    = 16
-
-	// Audio gain (amplification factor, 1.0 = no gain, 2.0 = double volume)
-	// Increase this if microphone volume is too low for ASR
-	audioGain = 2.0 // 增加增益以提高 ASR 识别率
 
 	// Logging intervals
 	packetLogInterval = 100
 )
 
+// Command-line flags
+var (
+	listDevices  = flag.Bool("list-devices", false, "list audio devices and exit")
+	outputDevice = flag.String("output-device", "", "playback device: ID, #index or name (default: system default)")
+	inputDevice  = flag.String("input-device", "", "capture device: ID, #index or name (default: system default)")
+	codecName    = flag.String("codec", constants.CodecPCMA, "audio codec to negotiate: pcma, pcmu, g722 or opus")
+	jitterDelay  = flag.Duration("jitter-delay", media2.DefaultJitterTargetDelay, "jitter buffer target delay")
+	enableAEC    = flag.Bool("aec", true, "cancel speaker echo in microphone audio (disable when using a headset)")
+	enableNS     = flag.Bool("ns", false, "suppress stationary background noise (fans, hum) in microphone audio")
+	agcTarget    = flag.Float64("agc-target", media2.DefaultAGCTargetRMS, "microphone level (RMS, 0-32768) the automatic gain control aims for")
+	agcMaxGain   = flag.Float64("agc-max-gain", media2.DefaultAGCMaxGain, "largest gain applied to quiet microphones")
+)
+
 // SignalMessage represents a WebSocket signaling message
 type SignalMessage struct {
 	Type      string      `json:"type"`
@@ -69,15 +83,25 @@ type Client struct {
 
 	// Add mutex for thread safety
 	mu sync.RWMutex
+	// writeMu serializes WebSocket writes (trickled candidates are sent from ICE callbacks)
+	writeMu sync.Mutex
 	// Add done channel for audio callback
 	doneChan chan struct{}
 
 	// Audio components
 	streamPlayer *devices.StreamAudioPlayer
-	pcmaDecoder  media2.EncoderFunc
-	pcmaEncoder  media2.EncoderFunc
+	stopFollow   context.CancelFunc
+	audioDecoder media2.EncoderFunc
+	audioEncoder media2.EncoderFunc
 	txTrack      *webrtc.TrackLocalStaticSample
 
+	// Jitter buffer between the RTP receive loop and the player
+	jitterBuffer *media2.JitterBuffer
+	stopPlayout  context.CancelFunc
+
+	// Echo canceller: TTS played through the speakers is removed from the microphone signal
+	echoCanceller *aec.AEC
+
 	// Microphone capture
 	malgoCtx      *malgo.AllocatedContext
 	captureDevice *malgo.Device
@@ -102,7 +126,7 @@ func NewClient() (*Client, error) {
 
 	// Create WebRTC transport
 	transport := rtcmedia.NewWebRTCTransport(rtcmedia.WebRTCOption{
-		Codec:      constants.CodecPCMA,
+		Codec:      *codecName,
 		ICETimeout: constants.DefaultICETimeout,
 		ICEServers: []webrtc.ICEServer{
 			{URLs: []string{"stun:stun.l.google.com:19302"}},
@@ -132,6 +156,9 @@ func (c *Client) Close() error {
 	}
 	c.mu.Unlock()
 
+	if c.stopPlayout != nil {
+		c.stopPlayout()
+	}
 	if c.captureDevice != nil {
 		c.captureDevice.Stop()
 		c.captureDevice.Uninit()
@@ -141,6 +168,9 @@ func (c *Client) Close() error {
 		c.malgoCtx.Uninit()
 		c.malgoCtx = nil
 	}
+	if c.stopFollow != nil {
+		c.stopFollow()
+	}
 	if c.streamPlayer != nil {
 		c.streamPlayer.Close()
 	}
@@ -170,7 +200,7 @@ func (c *Client) InitializeSession() (string, error) {
 	}
 
 	c.sessionID = initSignal.SessionID
-	fmt.Printf("[Client] Connected with session ID: %s\n", c.sessionID)
+	logger.Info("[Client] Connected", zap.String("session", c.sessionID))
 	return c.sessionID, nil
 }
 
@@ -183,54 +213,122 @@ func (c *Client) CreateAndSendOffer() error {
 	if c.txTrack == nil {
 		return fmt.Errorf("txTrack is nil after NewPeerConnection")
 	}
-	fmt.Printf("[Client] txTrack created: ID=%s\n", c.txTrack.ID())
+	logger.Debug("[Client] txTrack created", zap.String("track", c.txTrack.ID()))
+
+	// Trickle ICE: candidates are sent as "candidate" messages as soon as they are gathered
+	c.transport.OnICECandidate(func(candidate string) {
+		candidateMsg := SignalMessage{
+			Type:      constants.WebRTCCandidate,
+			SessionID: c.sessionID,
+			Data:      map[string]interface{}{"candidate": candidate},
+		}
+		if err := c.sendSignal(candidateMsg); err != nil {
+			logger.Warn("[Client] Error sending ICE candidate", zap.Error(err))
+		}
+	})
+
+	// ICE restart: when the network blips the transport re-offers over the same WebSocket
+	c.transport.OnRestartOffer(func(offer string, _ []string) error {
+		return c.sendSignal(SignalMessage{
+			Type:      "offer",
+			SessionID: c.sessionID,
+			Data: map[string]interface{}{
+				"sdp":         offer,
+				"trickle":     true,
+				"ice_restart": true,
+			},
+		})
+	})
+	c.transport.OnReconnected(func() {
+		logger.Info("[Client] WebRTC connection recovered")
+	})
 
-	offer, candidates, err := c.transport.CreateOffer()
+	// DataChannel for transcripts and control messages; must exist before the offer
+	if err := c.transport.CreateDataChannel(); err != nil {
+		return fmt.Errorf("failed to create data channel: %w", err)
+	}
+	c.transport.OnDataMessage(c.handleDataMessage)
+
+	offer, _, err := c.transport.CreateOffer()
 	if err != nil {
 		return fmt.Errorf("failed to create offer: %w", err)
 	}
 
-	fmt.Printf("[Client] Created offer with %d candidates\n", len(candidates))
-
 	offerMsg := SignalMessage{
 		Type:      "offer",
 		SessionID: c.sessionID,
 		Data: map[string]interface{}{
-			"sdp":        offer,
-			"candidates": candidates,
+			"sdp":     offer,
+			"trickle": true,
 		},
 	}
-
-	offerBytes, err := json.Marshal(offerMsg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal offer: %w", err)
-	}
-
-	if err := c.wsConn.WriteMessage(websocket.TextMessage, offerBytes); err != nil {
+	if err := c.sendSignal(offerMsg); err != nil {
 		return fmt.Errorf("failed to send offer: %w", err)
 	}
 
-	fmt.Println("[Client] Offer sent to server")
+	logger.Info("[Client] Offer sent to server, trickling ICE candidates")
 	return nil
 }
 
-// WaitForConnection waits for the WebRTC connection to be established
-func (c *Client) WaitForConnection() error {
-	for i := 0; i < maxConnectionRetries; i++ {
-		state := c.transport.GetConnectionState()
-		if state == webrtc.PeerConnectionStateConnected {
-			fmt.Println("[Client] WebRTC connection established")
-			return nil
+// handleDataMessage prints transcripts and LLM output, and flushes playback on interrupt
+func (c *Client) handleDataMessage(msg rtcmedia.DataMessage) {
+	switch msg.Type {
+	case rtcmedia.DataMessageTranscript:
+		if msg.Final {
+			fmt.Printf("[Client] You: %s\n", msg.Text)
 		}
-
-		if i%connectionStateLogInterval == 0 {
-			fmt.Printf("[Client] Waiting for connection... (state: %s)\n", state.String())
+	case rtcmedia.DataMessageLLMDelta:
+		fmt.Print(msg.Text)
+		if msg.Final {
+			fmt.Println()
+		}
+	case rtcmedia.DataMessageTTSStart:
+		fmt.Printf("[Client] AI speaking: %s\n", msg.Text)
+	case rtcmedia.DataMessageInterrupt:
+		// Drop audio already queued so playback stops immediately
+		if c.jitterBuffer != nil {
+			c.jitterBuffer.Reset()
 		}
+		if c.streamPlayer != nil {
+			c.streamPlayer.ClearBuffer()
+		}
+		if c.echoCanceller != nil {
+			c.echoCanceller.DiscardPlayback()
+		}
+		fmt.Println("[Client] AI interrupted")
+	}
+}
 
-		time.Sleep(connectionRetryDelay)
+// sendSignal writes a signaling message to the WebSocket
+func (c *Client) sendSignal(msg SignalMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.wsConn.WriteJSON(msg)
+}
+
+// HandleCandidate adds an ICE candidate trickled by the server
+func (c *Client) HandleCandidate(msg SignalMessage) error {
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid candidate data")
 	}
+	candidate, ok := data["candidate"].(string)
+	if !ok || candidate == "" {
+		return fmt.Errorf("invalid candidate")
+	}
+	return c.transport.AddICECandidate(candidate)
+}
 
-	return fmt.Errorf("connection timeout after %d retries", maxConnectionRetries)
+// WaitForConnection waits for the WebRTC connection to be established
+func (c *Client) WaitForConnection() error {
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	logger.Info("[Client] Waiting for connection", zap.String("state", c.transport.GetConnectionState().String()))
+	if err := c.transport.WaitUntilConnected(ctx); err != nil {
+		return fmt.Errorf("connection not established (state: %s): %w", c.transport.GetConnectionState().String(), err)
+	}
+	logger.Info("[Client] WebRTC connection established")
+	return nil
 }
 
 // WaitForTrack waits for the remote audio track to be available
@@ -258,6 +356,18 @@ func (c *Client) SetupAudioPlayback() error {
 		return fmt.Errorf("failed to create stream player: %w", err)
 	}
 
+	if *outputDevice != "" {
+		device, err := streamPlayer.SelectDevice(*outputDevice)
+		if err != nil {
+			streamPlayer.Close()
+			return fmt.Errorf("failed to select output device: %w", err)
+		}
+		logger.Info("[Client] Using output device", zap.String("device", device.Name))
+	}
+
+	// Live audio: if the device falls behind, skip stale audio rather than rejecting new frames
+	streamPlayer.SetOverflowPolicy(devices.OverflowDropOldest)
+
 	// Start playback
 	if err := streamPlayer.Play(); err != nil {
 		streamPlayer.Close()
@@ -266,66 +376,71 @@ func (c *Client) SetupAudioPlayback() error {
 
 	c.streamPlayer = streamPlayer
 
-	fmt.Printf("[Client] Audio playback started: %dHz, %d channel(s)\n",
-		targetSampleRate, audioChannels)
-
-	// PCMA codec standard sample rate (RFC 3551)
-	const pcmaSampleRate = 8000
-
-	// Create PCMA decoder (for receiving audio from server)
-	// Server sends PCMA at 8kHz, we decode to PCM at 16kHz for playback
-	// Flow: PCMA (8kHz, 8-bit) -> PCM (8kHz) -> resample -> PCM (16kHz, 16-bit)
-	// NOTE: CreateDecode uses src.Codec to find the decoder, so src must be "pcma"
-	decodeFunc, err := encoder.CreateDecode(
-		media2.CodecConfig{
-			Codec:         "pcma",         // Source codec - used to find decoder
-			SampleRate:    pcmaSampleRate, // 8kHz - PCMA standard (source)
-			Channels:      audioChannels,
-			BitDepth:      8, // PCMA uses 8-bit samples
-			FrameDuration: "20ms",
-		},
-		media2.CodecConfig{
-			Codec:         "pcm",            // Target codec
-			SampleRate:    targetSampleRate, // 16kHz - target for playback
-			Channels:      audioChannels,
-			BitDepth:      audioBitDepth, // 16-bit PCM for playback
-			FrameDuration: "20ms",
-		},
-	)
+	// Keep playing when the output device goes away (e.g. headset unplugged) by falling back to the default device
+	followCtx, stopFollow := context.WithCancel(context.Background())
+	c.stopFollow = stopFollow
+	go streamPlayer.FollowDevices(followCtx, devices.DefaultWatchInterval)
+
+	logger.Info("[Client] Audio playback started",
+		zap.Int("sampleRate", targetSampleRate),
+		zap.Int("channels", audioChannels))
+
+	// Wire format of the negotiated codec (PCMA/PCMU 8kHz, Opus 48kHz)
+	wireConfig := rtcmedia.CodecConfigFor(*codecName)
+
+	// Create decoder (for receiving audio from server)
+	// Flow: codec (wire rate) -> PCM -> resample -> PCM (16kHz, 16-bit)
+	decodeFunc, err := media2.NewPipeline().
+		Decode(wireConfig).
+		Mono().
+		Resample(targetSampleRate). // 16kHz - microphone capture and playback rate
+		Build()
 	if err != nil {
 		streamPlayer.Close()
 		return fmt.Errorf("failed to create decoder: %w", err)
 	}
 
-	c.pcmaDecoder = decodeFunc
-
-	// Create PCMA encoder (for sending audio to server)
-	// Microphone captures at 16kHz, we encode to PCMA at 8kHz for WebRTC
-	// Flow: PCM (16kHz, 16-bit) -> resample -> PCM (8kHz) -> PCMA (8kHz, 8-bit)
-	// NOTE: CreateEncode uses src.Codec to find the encoder, so src must be "pcma"
-	//       The src/dst semantics are confusing - src is the codec type, not the input format
-	encodeFunc, err := encoder.CreateEncode(
-		media2.CodecConfig{
-			Codec:         "pcma",           // Codec type - used to find encoder
-			SampleRate:    targetSampleRate, // 16kHz - microphone capture rate (input)
-			Channels:      audioChannels,
-			BitDepth:      audioBitDepth, // 16-bit PCM from microphone
-			FrameDuration: "20ms",
-		},
-		media2.CodecConfig{
-			Codec:         "pcma",         // Target codec
-			SampleRate:    pcmaSampleRate, // 8kHz - PCMA standard (output)
-			Channels:      audioChannels,
-			BitDepth:      8, // PCMA uses 8-bit samples
-			FrameDuration: "20ms",
-		},
-	)
+	c.audioDecoder = decodeFunc
+
+	// Received frames are reordered and concealed by the jitter buffer, then
+	// played out at a steady 20ms cadence instead of as packets arrive
+	c.jitterBuffer = media2.NewJitterBuffer(media2.JitterBufferConfig{
+		TargetDelay: *jitterDelay,
+		ClockRate:   wireConfig.SampleRate,
+	})
+	if *enableAEC {
+		c.mu.Lock()
+		c.echoCanceller = aec.New(aec.Config{SampleRate: targetSampleRate})
+		c.mu.Unlock()
+	}
+	echoCanceller := c.echoCanceller
+	playoutCtx, stopPlayout := context.WithCancel(context.Background())
+	c.stopPlayout = stopPlayout
+	go c.jitterBuffer.Drain(playoutCtx, func(frame []byte) {
+		if err := streamPlayer.Write(frame); err != nil {
+			if !errors.Is(err, devices.ErrBufferFull) {
+				logger.Warn("[Client] Error writing to player", zap.Error(err))
+			}
+			return
+		}
+		// The echo canceller's reference is exactly what reaches the speaker
+		if echoCanceller != nil {
+			echoCanceller.Playback(frame)
+		}
+	})
+
+	// Create encoder (for sending audio to server)
+	// Flow: PCM (16kHz, 16-bit) -> codec (wire rate), one packet per 20ms frame
+	encodeFunc, err := media2.NewPipeline().
+		Input(targetSampleRate, audioChannels).
+		Encode(wireConfig).
+		Build()
 	if err != nil {
 		streamPlayer.Close()
 		return fmt.Errorf("failed to create encoder: %w", err)
 	}
 
-	c.pcmaEncoder = encodeFunc
+	c.audioEncoder = encodeFunc
 	return nil
 }
 
@@ -339,12 +454,12 @@ func (c *Client) ProcessAudioPacket(
 		return nil
 	}
 
-	// Decode PCMA to PCM
+	// Decode the negotiated codec to PCM
 	audioPacket := &media2.AudioPacket{Payload: payload}
-	decodedPackets, err := c.pcmaDecoder(audioPacket)
+	decodedPackets, err := c.audioDecoder(audioPacket)
 	if err != nil {
 		if packetCount%packetLogInterval == 0 {
-			fmt.Printf("[Client] Error decoding frame %d: %v\n", packetCount, err)
+			logger.Warn("[Client] Error decoding frame", zap.Int("packet", packetCount), zap.Error(err))
 		}
 		return err
 	}
@@ -360,8 +475,7 @@ func (c *Client) ProcessAudioPacket(
 			// Validate PCM data (should be 16-bit, so length must be even)
 			if len(af.Payload)%2 != 0 {
 				if packetCount <= 3 {
-					fmt.Printf("[Client] Warning: Odd PCM length at packet %d: %d bytes\n",
-						packetCount, len(af.Payload))
+					logger.Warn("[Client] Odd PCM length", zap.Int("packet", packetCount), zap.Int("bytes", len(af.Payload)))
 				}
 				continue
 			}
@@ -370,13 +484,19 @@ func (c *Client) ProcessAudioPacket(
 		}
 	}
 
-	// Write to player
+	// Queue for playout; the jitter buffer drains to the player every 20ms
 	if len(allPCMData) > 0 {
-		if err := c.streamPlayer.Write(allPCMData); err != nil {
-			if packetCount%packetLogInterval == 0 && err.Error() != "音频缓冲区已满" {
-				fmt.Printf("[Client] Error writing to player: %v\n", err)
-			}
-		}
+		c.jitterBuffer.Push(packet.SequenceNumber, packet.Timestamp, allPCMData)
+	}
+
+	if packetCount > 0 && packetCount%(packetLogInterval*10) == 0 {
+		stats := c.jitterBuffer.Stats()
+		logger.Debug("[Client] Jitter buffer",
+			zap.Duration("delay", stats.Delay),
+			zap.Duration("jitter", stats.Jitter),
+			zap.Uint64("concealed", stats.Concealed),
+			zap.Uint64("late", stats.Late),
+			zap.Uint64("underruns", stats.Underruns))
 	}
 
 	return nil
@@ -389,12 +509,12 @@ func (c *Client) startAudioReceiverFromTrack(rxTrack *webrtc.TrackRemote) error
 	}
 
 	// SetupAudioPlayback should already be called before this
-	if c.streamPlayer == nil || c.pcmaDecoder == nil {
+	if c.streamPlayer == nil || c.audioDecoder == nil {
 		return fmt.Errorf("audio playback not initialized")
 	}
 
 	codec := rxTrack.Codec()
-	fmt.Printf("[Client] Received track: %s, %dHz\n", codec.MimeType, codec.ClockRate)
+	logger.Info("[Client] Received track", zap.String("codec", codec.MimeType), zap.Uint32("clockRate", codec.ClockRate))
 
 	packetCount := 0
 	for {
@@ -410,7 +530,7 @@ func (c *Client) startAudioReceiverFromTrack(rxTrack *webrtc.TrackRemote) error
 
 		packetCount++
 		if packetCount%packetLogInterval == 0 {
-			fmt.Printf("[Client] Received and played %d RTP packets\n", packetCount)
+			logger.Debug("[Client] Received and played RTP packets", zap.Int("packets", packetCount))
 		}
 	}
 }
@@ -428,7 +548,7 @@ func (c *Client) StartAudioSender() error {
 
 	// Initialize malgo context
 	malgoCtx, err := malgo.InitContext(nil, malgo.ContextConfig{}, func(message string) {
-		log.Printf("[Client] Malgo: %s", message)
+		logger.Debug("[Client] Malgo", zap.String("message", message))
 	})
 	if err != nil {
 		return fmt.Errorf("failed to initialize malgo context: %w", err)
@@ -440,34 +560,42 @@ func (c *Client) StartAudioSender() error {
 	deviceConfig.Capture.Format = malgo.FormatS16
 	deviceConfig.Capture.Channels = uint32(audioChannels)
 	deviceConfig.SampleRate = uint32(targetSampleRate)
+	if *inputDevice != "" {
+		device, err := devices.FindCaptureDevice(malgoCtx, *inputDevice)
+		if err != nil {
+			return fmt.Errorf("failed to select input device: %w", err)
+		}
+		deviceConfig.Capture.DeviceID = device.ID.Pointer()
+		logger.Info("[Client] Using input device", zap.String("device", device.Name))
+	}
 
 	// Audio capture callback
 	frameDuration := 20 * time.Millisecond
 	startTime := time.Now()
 	frameCount := 0
 
-	// Wait a bit to ensure pcmaEncoder is initialized
+	// Wait a bit to ensure audioEncoder is initialized
 	// SetupAudioPlayback should have been called before this, but let's verify
 	c.mu.RLock()
-	encoderReady := c.pcmaEncoder != nil
+	encoderReady := c.audioEncoder != nil
 	txTrackReady := c.txTrack != nil
 	c.mu.RUnlock()
 
 	if !encoderReady {
 		// Wait a bit for encoder to be ready
-		fmt.Printf("[Client] Waiting for pcmaEncoder to be initialized...\n")
+		logger.Debug("[Client] Waiting for audioEncoder to be initialized")
 		for i := 0; i < 50; i++ {
 			time.Sleep(50 * time.Millisecond)
 			c.mu.RLock()
-			encoderReady = c.pcmaEncoder != nil
+			encoderReady = c.audioEncoder != nil
 			c.mu.RUnlock()
 			if encoderReady {
-				fmt.Printf("[Client] pcmaEncoder is now ready\n")
+				logger.Debug("[Client] audioEncoder is now ready")
 				break
 			}
 		}
 		if !encoderReady {
-			return fmt.Errorf("pcmaEncoder is still nil after waiting")
+			return fmt.Errorf("audioEncoder is still nil after waiting")
 		}
 	}
 
@@ -478,18 +606,33 @@ func (c *Client) StartAudioSender() error {
 	// Create local references to avoid potential race conditions
 	c.mu.RLock()
 	localTxTrack := c.txTrack
-	localPcmaEncoder := c.pcmaEncoder
+	localEncoder := c.audioEncoder
+	echoCanceller := c.echoCanceller
 	c.mu.RUnlock()
 
-	if localPcmaEncoder == nil {
-		return fmt.Errorf("pcmaEncoder is nil after lock")
+	if localEncoder == nil {
+		return fmt.Errorf("audioEncoder is nil after lock")
 	}
 	if localTxTrack == nil {
 		return fmt.Errorf("txTrack is nil after lock")
 	}
 
-	fmt.Printf("[Client] Audio components ready: txTrack=%v, encoder=%v\n",
-		localTxTrack != nil, localPcmaEncoder != nil)
+	logger.Info("[Client] Audio components ready",
+		zap.Bool("txTrack", localTxTrack != nil),
+		zap.Bool("encoder", localEncoder != nil))
+
+	// Noise suppression runs after echo cancellation, before the AGC can amplify the noise
+	var noiseSuppressor media2.NoiseSuppressor
+	if *enableNS {
+		noiseSuppressor = media2.NewSpectralSubtraction(media2.NoiseSuppressionConfig{SampleRate: targetSampleRate})
+	}
+
+	// Automatic gain control, runs after echo cancellation so echo does not drive the gain
+	agc := media2.NewAGC(media2.AGCConfig{
+		SampleRate: targetSampleRate,
+		TargetRMS:  *agcTarget,
+		MaxGain:    *agcMaxGain,
+	})
 
 	// Create a channel to signal when the client is closing
 	doneChan := make(chan struct{})
@@ -502,9 +645,9 @@ func (c *Client) StartAudioSender() error {
 	onSamples := func(pOutputSample, pInputSamples []byte, framecount uint32) {
 		// Log first call to confirm callback is working
 		if frameCount == 0 {
-			fmt.Printf("[Client] ===== onSamples callback FIRED! =====\n")
-			fmt.Printf("[Client] First frame: pInputSamples len=%d, framecount=%d\n",
-				len(pInputSamples), framecount)
+			logger.Debug("[Client] First captured frame",
+				zap.Int("bytes", len(pInputSamples)),
+				zap.Uint32("framecount", framecount))
 		}
 
 		// Check if we should stop processing
@@ -517,14 +660,14 @@ func (c *Client) StartAudioSender() error {
 		// Use local references to avoid potential race conditions
 		if localTxTrack == nil {
 			if frameCount < 3 {
-				fmt.Printf("[Client] WARNING: localTxTrack is nil at frame %d!\n", frameCount)
+				logger.Warn("[Client] localTxTrack is nil", zap.Int("frame", frameCount))
 			}
 			frameCount++
 			return
 		}
-		if localPcmaEncoder == nil {
+		if localEncoder == nil {
 			if frameCount < 3 {
-				fmt.Printf("[Client] WARNING: localPcmaEncoder is nil at frame %d!\n", frameCount)
+				logger.Warn("[Client] localEncoder is nil", zap.Int("frame", frameCount))
 			}
 			frameCount++
 			return
@@ -533,7 +676,7 @@ func (c *Client) StartAudioSender() error {
 		// pInputSamples contains the captured PCM audio (16-bit, mono, 8kHz)
 		if len(pInputSamples) == 0 {
 			if frameCount < 3 {
-				fmt.Printf("[Client] WARNING: pInputSamples is empty at frame %d!\n", frameCount)
+				logger.Warn("[Client] pInputSamples is empty", zap.Int("frame", frameCount))
 			}
 			frameCount++
 			return
@@ -541,8 +684,10 @@ func (c *Client) StartAudioSender() error {
 
 		// Debug: Log input samples (log first few frames and then every 100th)
 		if frameCount < 5 || frameCount%100 == 0 {
-			fmt.Printf("[Client] Captured audio frame #%d, size: %d bytes, framecount: %d\n",
-				frameCount, len(pInputSamples), framecount)
+			logger.Debug("[Client] Captured audio frame",
+				zap.Int("frame", frameCount),
+				zap.Int("bytes", len(pInputSamples)),
+				zap.Uint32("framecount", framecount))
 
 			// Calculate audio level (RMS) for debugging
 			if len(pInputSamples) >= 2 {
@@ -554,95 +699,84 @@ func (c *Client) StartAudioSender() error {
 				rms := float64(sumSquares) / float64(len(pInputSamples)/2)
 				if rms > 0 {
 					level := 20 * math.Log10(math.Sqrt(rms))
-					fmt.Printf("[Client] Audio level: %.2f dB (RMS: %.0f)\n", level, rms)
+					logger.Debug("[Client] Audio level", zap.Float64("db", level), zap.Float64("rms", rms))
 					if level < -60 {
-						fmt.Printf("[Client] WARNING: Audio level is very low! Consider increasing audioGain or microphone volume.\n")
+						logger.Warn("[Client] Audio level is very low, consider raising -agc-max-gain or microphone volume")
 					}
 				} else {
-					fmt.Printf("[Client] Audio level: SILENT (RMS: 0)\n")
+					logger.Debug("[Client] Audio level: silent")
 				}
 			}
 		}
 
-		// Apply audio gain if needed
-		if audioGain != 1.0 && len(pInputSamples) >= 2 {
-			for i := 0; i < len(pInputSamples)-1; i += 2 {
-				sample := int16(pInputSamples[i]) | int16(pInputSamples[i+1])<<8
-				// Apply gain
-				amplified := float64(sample) * audioGain
-				// Clamp to int16 range
-				if amplified > 32767 {
-					amplified = 32767
-				} else if amplified < -32768 {
-					amplified = -32768
-				}
-				sample = int16(amplified)
-				pInputSamples[i] = byte(sample)
-				pInputSamples[i+1] = byte(sample >> 8)
+		// Remove the assistant's own voice picked up from the speakers before ASR hears it
+		if echoCanceller != nil {
+			pInputSamples = echoCanceller.Process(pInputSamples)
+			if frameCount%packetLogInterval == 0 {
+				stats := echoCanceller.Stats()
+				logger.Debug("[Client] AEC", zap.Duration("delay", stats.Delay), zap.Float64("erle", stats.ERLE), zap.Bool("doubleTalk", stats.DoubleTalk))
 			}
 		}
 
-		// Encode PCM to PCMA
+		if noiseSuppressor != nil {
+			pInputSamples = noiseSuppressor.Process(pInputSamples)
+		}
+
+		// Level the microphone for ASR: quiet voices are raised, loud ones limited before clipping
+		agc.Process(pInputSamples)
+		if frameCount%packetLogInterval == 0 {
+			logger.Debug("[Client] AGC gain", zap.Float64("gain", agc.Gain()))
+		}
+
+		// Encode PCM to the negotiated codec
 		audioPacket := &media2.AudioPacket{Payload: pInputSamples}
-		encodedPackets, err := localPcmaEncoder(audioPacket)
+		encodedPackets, err := localEncoder(audioPacket)
 		if err != nil {
 			if frameCount%packetLogInterval == 0 {
-				log.Printf("[Client] Encode error: %v", err)
+				logger.Warn("[Client] Encode error", zap.Error(err))
 			}
 			frameCount++
 			return
 		}
 
-		// Collect all encoded PCMA data
-		var pcmaData []byte
+		// Each encoded packet is one 20ms frame; Opus packets cannot be concatenated
+		var frames [][]byte
 		for _, packet := range encodedPackets {
-			if af, ok := packet.(*media2.AudioPacket); ok {
-				if len(af.Payload) > 0 {
-					pcmaData = append(pcmaData, af.Payload...)
-				}
+			if af, ok := packet.(*media2.AudioPacket); ok && len(af.Payload) > 0 {
+				frames = append(frames, af.Payload)
 			}
 		}
 
 		// Debug: Log encoded data
-		if frameCount%100 == 0 && len(pcmaData) > 0 {
-			fmt.Printf("[Client] Encoded PCMA data size: %d bytes\n", len(pcmaData))
+		if frameCount%100 == 0 && len(frames) > 0 {
+			logger.Debug("[Client] Encoded frames", zap.Int("frames", len(frames)), zap.Int("firstFrameBytes", len(frames[0])))
 		}
 
-		// Warn if no encoded data
-		if len(pcmaData) == 0 && frameCount < 10 {
-			fmt.Printf("[Client] WARNING: No encoded data for frame #%d (input: %d bytes)\n",
-				frameCount, len(pInputSamples))
+		// Encoders buffer partial frames, so an empty result is expected now and then
+		if len(frames) == 0 {
+			frameCount++
+			return
 		}
 
 		// Send via WebRTC with precise timing
-		if len(pcmaData) > 0 {
-			expectedTime := startTime.Add(time.Duration(frameCount) * frameDuration)
-			if now := time.Now(); expectedTime.After(now) {
-				time.Sleep(expectedTime.Sub(now))
-			}
-
-			sample := media.Sample{
-				Data:     pcmaData,
-				Duration: frameDuration,
-			}
+		expectedTime := startTime.Add(time.Duration(frameCount) * frameDuration)
+		if now := time.Now(); expectedTime.After(now) {
+			time.Sleep(expectedTime.Sub(now))
+		}
 
+		for _, frame := range frames {
 			// Use local reference to txTrack
-			if err := localTxTrack.WriteSample(sample); err != nil {
+			if err := localTxTrack.WriteSample(media.Sample{Data: frame, Duration: frameDuration}); err != nil {
 				if frameCount%packetLogInterval == 0 {
-					log.Printf("[Client] Error writing sample: %v", err)
+					logger.Warn("[Client] Error writing sample", zap.Error(err))
 				}
-				frameCount++
-				return
-			} else if frameCount%100 == 0 {
-				fmt.Printf("[Client] Sent %d bytes via WebRTC\n", len(pcmaData))
+				break
 			}
+		}
 
-			frameCount++
-			if frameCount%packetLogInterval == 0 {
-				fmt.Printf("[Client] Sent %d audio frames\n", frameCount)
-			}
-		} else {
-			frameCount++
+		frameCount++
+		if frameCount%packetLogInterval == 0 {
+			logger.Debug("[Client] Sent audio frames", zap.Int("frames", frameCount))
 		}
 	}
 
@@ -666,10 +800,10 @@ func (c *Client) StartAudioSender() error {
 		return fmt.Errorf("failed to start capture device: %w", err)
 	}
 
-	fmt.Println("[Client] Microphone capture started, sending audio to server...")
-	fmt.Printf("[Client] Device config: SampleRate=%d, Channels=%d, Format=%d\n",
-		deviceConfig.SampleRate, deviceConfig.Capture.Channels, deviceConfig.Capture.Format)
-	fmt.Printf("[Client] Waiting for audio samples from microphone...\n")
+	logger.Info("[Client] Microphone capture started, sending audio to server",
+		zap.Uint32("sampleRate", deviceConfig.SampleRate),
+		zap.Uint32("channels", deviceConfig.Capture.Channels),
+		zap.Int("format", int(deviceConfig.Capture.Format)))
 
 	// Keep the function running
 	select {
@@ -718,19 +852,20 @@ func (c *Client) HandleAnswer(msg SignalMessage) error {
 		if !c.audioReceived {
 			c.audioReceived = true
 			c.mu.Unlock()
-			fmt.Printf("[Client] ===== OnTrack callback FIRED! =====\n")
-			fmt.Printf("[Client] Track codec: %s, SSRC: %d, ID: %s\n",
-				track.Codec().MimeType, track.SSRC(), track.ID())
+			logger.Info("[Client] Received server track",
+				zap.String("codec", track.Codec().MimeType),
+				zap.Uint32("ssrc", uint32(track.SSRC())),
+				zap.String("track", track.ID()))
 
 			// Start receiving audio in a separate goroutine
 			go func() {
 				defer func() {
 					if r := recover(); r != nil {
-						log.Printf("[Client] Recovered from panic in audio receiver: %v", r)
+						logger.Error("[Client] Recovered from panic in audio receiver", zap.Any("panic", r))
 					}
 				}()
 				if err := c.startAudioReceiverFromTrack(track); err != nil {
-					log.Printf("[Client] Error in audio receiver: %v", err)
+					logger.Error("[Client] Error in audio receiver", zap.Error(err))
 				}
 			}()
 		} else {
@@ -751,16 +886,13 @@ func (c *Client) HandleAnswer(msg SignalMessage) error {
 		return fmt.Errorf("failed to set remote description: %w", err)
 	}
 
-	// Extract and add ICE candidates
-	candidates, ok := answerData["candidates"].([]interface{})
-	if !ok {
-		return fmt.Errorf("invalid candidates data")
-	}
+	// Add ICE candidates bundled with the answer (servers without trickle support)
+	candidates, _ := answerData["candidates"].([]interface{})
 
 	candidateStrs := c.extractCandidates(candidates)
 	for _, candidate := range candidateStrs {
 		if err := c.transport.AddICECandidate(candidate); err != nil {
-			log.Printf("[Client] Error adding ICE candidate: %v", err)
+			logger.Warn("[Client] Error adding ICE candidate", zap.Error(err))
 		}
 	}
 
@@ -770,18 +902,18 @@ func (c *Client) HandleAnswer(msg SignalMessage) error {
 		SessionID: c.sessionID,
 		Data:      map[string]interface{}{},
 	}
-	if err := c.wsConn.WriteJSON(connectedMsg); err != nil {
+	if err := c.sendSignal(connectedMsg); err != nil {
 		return fmt.Errorf("failed to send connected message: %w", err)
 	}
 
-	fmt.Println("[Client] WebRTC connection should now be establishing...")
+	logger.Info("[Client] WebRTC connection establishing")
 
 	// Wait for connection
 	if err := c.WaitForConnection(); err != nil {
 		return err
 	}
 
-	// Setup audio playback first (this initializes pcmaEncoder which is needed for sending)
+	// Setup audio playback first (this initializes audioEncoder which is needed for sending)
 	// We need this even if we're not receiving audio yet, because we need the encoder
 	if err := c.SetupAudioPlayback(); err != nil {
 		return fmt.Errorf("failed to setup audio playback: %w", err)
@@ -790,18 +922,48 @@ func (c *Client) HandleAnswer(msg SignalMessage) error {
 	// Note: Audio receiving is now handled by the OnTrack callback
 	// which is set up before SetRemoteDescription
 	// No need to wait here - OnTrack will fire automatically when the track arrives
-	fmt.Println("[Client] Audio playback setup complete, waiting for OnTrack callback to fire when server sends signal...")
+	logger.Info("[Client] Audio playback ready, waiting for server track")
 
-	// Start sending audio from microphone (now pcmaEncoder should be ready)
+	// Start sending audio from microphone (now audioEncoder should be ready)
 	go func() {
 		if err := c.StartAudioSender(); err != nil {
-			log.Printf("[Client] Audio sender error: %v", err)
+			logger.Error("[Client] Audio sender error", zap.Error(err))
 		}
 	}()
 
 	return nil
 }
 
+// HandleRestartAnswer applies the answer to an ICE restart offer; media and playback keep running
+func (c *Client) HandleRestartAnswer(msg SignalMessage) error {
+	answerData, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid answer data")
+	}
+	answerStr, ok := answerData["sdp"].(string)
+	if !ok {
+		return fmt.Errorf("invalid answer SDP")
+	}
+	answerJSON, err := json.Marshal(map[string]string{
+		"type": "answer",
+		"sdp":  answerStr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal answer SDP: %w", err)
+	}
+	if err := c.transport.SetRemoteDescription(string(answerJSON)); err != nil {
+		return fmt.Errorf("failed to set remote description: %w", err)
+	}
+	candidates, _ := answerData["candidates"].([]interface{})
+	for _, candidate := range c.extractCandidates(candidates) {
+		if err := c.transport.AddICECandidate(candidate); err != nil {
+			logger.Warn("[Client] Error adding ICE candidate", zap.Error(err))
+		}
+	}
+	logger.Info("[Client] ICE restart answer applied")
+	return nil
+}
+
 // extractCandidates extracts candidate strings from the interface slice
 func (c *Client) extractCandidates(candidates []interface{}) []string {
 	var candidateStrs []string
@@ -820,23 +982,36 @@ func (c *Client) StartMessageListener() {
 		for {
 			_, message, err := c.wsConn.ReadMessage()
 			if err != nil {
-				log.Printf("[Client] Error reading message: %v", err)
+				logger.Warn("[Client] Error reading message", zap.Error(err))
 				return
 			}
 
 			var signal SignalMessage
 			if err := json.Unmarshal(message, &signal); err != nil {
-				log.Printf("[Client] Error unmarshaling message: %v", err)
+				logger.Warn("[Client] Error unmarshaling message", zap.Error(err))
 				continue
 			}
 
 			switch signal.Type {
 			case "answer":
-				if err := c.HandleAnswer(signal); err != nil {
-					log.Printf("[Client] Error handling answer: %v", err)
+				if restart, _ := signal.Data.(map[string]interface{})["ice_restart"].(bool); restart {
+					if err := c.HandleRestartAnswer(signal); err != nil {
+						logger.Error("[Client] Error handling ICE restart answer", zap.Error(err))
+					}
+					continue
+				}
+				// HandleAnswer waits for the connection; keep reading so trickled candidates are applied
+				go func(msg SignalMessage) {
+					if err := c.HandleAnswer(msg); err != nil {
+						logger.Error("[Client] Error handling answer", zap.Error(err))
+					}
+				}(signal)
+			case constants.WebRTCCandidate:
+				if err := c.HandleCandidate(signal); err != nil {
+					logger.Warn("[Client] Error adding ICE candidate", zap.Error(err))
 				}
 			default:
-				log.Printf("[Client] Unknown message type: %s", signal.Type)
+				logger.Debug("[Client] Unknown message type", zap.String("type", signal.Type))
 			}
 		}
 	}()
@@ -860,25 +1035,52 @@ func (c *Client) Run() error {
 	c.StartMessageListener()
 
 	// Wait for interrupt or done signal
-	fmt.Println("[Client] Waiting for connection to establish...")
+	logger.Info("[Client] Waiting for connection to establish")
 	fmt.Println("[Client] Press Ctrl+C to exit")
 	select {
 	case <-c.interrupt:
-		fmt.Println("\n[Client] Interrupted, closing connection...")
+		logger.Info("[Client] Interrupted, closing connection")
 		return nil
 	case <-c.done:
-		fmt.Println("[Client] Connection closed")
+		logger.Info("[Client] Connection closed")
 		return nil
 	}
 }
 
 func main() {
+	flag.Parse()
+
+	// Initialize logger
+	logCfg := &logger.LogConfig{
+		Level:      "info",
+		Filename:   "logs/example2-client.log",
+		MaxSize:    100,
+		MaxAge:     7,
+		MaxBackups: 3,
+	}
+	if err := logger.Init(logCfg, "dev"); err != nil {
+		log.Fatalf("[Client] Failed to initialize logger: %v", err)
+	}
+	defer logger.Sync()
+
+	if *listDevices {
+		streamCtx, err := devices.NewStreamContext(nil)
+		if err != nil {
+			logger.Fatal("[Client] Failed to initialize audio context", zap.Error(err))
+		}
+		defer streamCtx.Close()
+		if err := devices.PrintAllDevices(streamCtx.GetContext()); err != nil {
+			logger.Fatal("[Client] Failed to list devices", zap.Error(err))
+		}
+		return
+	}
+
 	client, err := NewClient()
 	if err != nil {
-		log.Fatalf("[Client] Failed to create client: %v", err)
+		logger.Fatal("[Client] Failed to create client", zap.Error(err))
 	}
 
 	if err := client.Run(); err != nil {
-		log.Fatalf("[Client] Error: %v", err)
+		logger.Fatal("[Client] Error", zap.Error(err))
 	}
 }
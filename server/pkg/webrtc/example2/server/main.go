@@ -1,20 +1,22 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
-	"sync"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/logger"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/signaling"
 	transports "github.com/code-100-precent/LingEcho/pkg/webrtc/transport"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/pion/webrtc/v3"
+	"go.uber.org/zap"
 )
 
 // Constants
@@ -22,73 +24,36 @@ const (
 	serverPort = ":8080"
 	wsPath     = "/websocket"
 	// Connection configuration
-	maxConnectionRetries       = 50
-	connectionRetryDelay       = 100 * time.Millisecond
-	connectionStateLogInterval = 10
-	connectionReadyDelay       = 200 * time.Millisecond
+	connectionTimeout    = 5 * time.Second
+	connectionReadyDelay = 200 * time.Millisecond
 )
 
-// ClientManager manages WebRTC client connections
-type ClientManager struct {
-	clients map[string]*transports.AIClient
-	mutex   sync.RWMutex
-}
-
-func NewClientManager() *ClientManager {
-	return &ClientManager{
-		clients: make(map[string]*transports.AIClient),
-	}
-}
-
-func (m *ClientManager) AddClient(sessionID string, client *transports.AIClient) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	m.clients[sessionID] = client
-}
-
-func (m *ClientManager) RemoveClient(sessionID string) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	delete(m.clients, sessionID)
-}
+// codecName selects the codec advertised to clients (pcma, pcmu, g722 or opus)
+var codecName = flag.String("codec", constants.CodecPCMA, "audio codec to negotiate: pcma, pcmu, g722 or opus")
 
-func (m *ClientManager) GetClient(sessionID string) (*transports.AIClient, bool) {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-	client, exists := m.clients[sessionID]
-	return client, exists
-}
-
-// SignalMessage represents a WebSocket signaling message
-type SignalMessage struct {
-	Type      string      `json:"type"`
-	SessionID string      `json:"session_id,omitempty"`
-	Data      interface{} `json:"data,omitempty"`
-}
+// aiClientKey is the session value holding the session's AI client
+const aiClientKey = "aiClient"
 
-var (
-	manager  = NewClientManager()
-	upgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
+// newSignalingServer creates the signaling server; each session gets its own transport and AI client
+func newSignalingServer() *signaling.Server {
+	srv := signaling.NewServer()
+	srv.Upgrader.CheckOrigin = func(r *http.Request) bool {
+		return true
 	}
-)
-
-// websocketHandler handles WebSocket connections
-func websocketHandler(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		log.Printf("[Server] Failed to upgrade connection: %v", err)
-		return
+	srv.OnSession = setupSession
+	srv.OnClose = func(s *signaling.Session) {
+		if client, ok := sessionClient(s); ok {
+			client.Close()
+		}
 	}
-	defer conn.Close()
-
-	sessionID := fmt.Sprintf("session_%d", time.Now().UnixNano())
+	srv.Handle(signaling.TypeConnected, handleConnection)
+	return srv
+}
 
-	// Create WebRTC transport
+// setupSession creates the WebRTC transport and AI client for a new session
+func setupSession(s *signaling.Session) error {
 	transport := rtcmedia.NewWebRTCTransport(rtcmedia.WebRTCOption{
-		Codec: constants.CodecPCMA,
+		Codec: *codecName,
 		ICEServers: []webrtc.ICEServer{
 			{URLs: []string{"stun:stun.l.google.com:19302"}},
 		},
@@ -96,13 +61,17 @@ func websocketHandler(c *gin.Context) {
 		ICETimeout: constants.DefaultICETimeout,
 	})
 	transport.NewPeerConnection()
+	s.Transport = transport
 
 	// Initialize AI components
-	aiClient, err := transports.NewAIClient(conn, transport, sessionID, "", nil, 0, 0, nil)
+	conn, _ := s.Conn().(*websocket.Conn)
+	aiClient, err := transports.NewAIClient(conn, transport, s.ID, "", nil, 0, 0, nil)
 	if err != nil {
-		log.Printf("[Server] Failed to create AI client: %v", err)
-		return
+		return fmt.Errorf("failed to create AI client: %w", err)
 	}
+	// Share the AI client's write lock so signaling replies never interleave with its messages
+	s.SetWriter(aiClient.WriteJSON)
+	s.Set(aiClientKey, aiClient)
 
 	// Set up OnTrack callback BEFORE handling any signaling messages
 	// This is critical - OnTrack must be set up early to catch the track when it arrives
@@ -111,158 +80,50 @@ func websocketHandler(c *gin.Context) {
 		if !aiClient.AudioReceived {
 			aiClient.AudioReceived = true
 			aiClient.Mu.Unlock()
-			fmt.Printf("[Server] ===== OnTrack callback FIRED! =====\n")
-			fmt.Printf("[Server] Track codec: %s, SSRC: %d, ID: %s\n",
-				track.Codec().MimeType, track.SSRC(), track.ID())
+			logger.Info("[Server] Received client track",
+				zap.String("session", s.ID),
+				zap.String("codec", track.Codec().MimeType),
+				zap.Uint32("ssrc", uint32(track.SSRC())),
+				zap.String("track", track.ID()))
 
 			// Start audio receiver in a separate goroutine
 			go func() {
 				defer func() {
 					if r := recover(); r != nil {
-						log.Printf("[Server] Recovered from panic in audio receiver: %v", r)
+						logger.Error("[Server] Recovered from panic in audio receiver", zap.Any("panic", r))
 					}
 				}()
 				if err := aiClient.StartAudioReceiverFromTrack(track); err != nil {
-					log.Printf("[Server] Error in audio receiver: %v", err)
+					logger.Error("[Server] Error in audio receiver", zap.String("session", s.ID), zap.Error(err))
 				}
 			}()
 		} else {
 			aiClient.Mu.Unlock()
 		}
 	})
-	fmt.Printf("[Server] OnTrack callback registered for client %s\n", sessionID)
-
-	manager.AddClient(sessionID, aiClient)
-	defer manager.RemoveClient(sessionID)
-	defer aiClient.Close()
-
-	// Send session ID to client
-	initMsg := SignalMessage{
-		Type:      "init",
-		SessionID: sessionID,
-	}
-	if err := conn.WriteJSON(initMsg); err != nil {
-		log.Printf("[Server] Failed to send init message: %v", err)
-		return
-	}
-
-	// Handle incoming messages
-	for {
-		var msg SignalMessage
-		if err := conn.ReadJSON(&msg); err != nil {
-			log.Printf("[Server] Error reading message: %v", err)
-			break
-		}
-
-		handleSignalMessage(aiClient, msg)
-	}
+	logger.Debug("[Server] OnTrack callback registered", zap.String("session", s.ID))
+	return nil
 }
 
-// handleSignalMessage routes signaling messages
-func handleSignalMessage(client *transports.AIClient, msg SignalMessage) {
-	switch msg.Type {
-	case "offer":
-		handleOffer(client, msg)
-	case "connected":
-		handleConnection(client, msg)
-	default:
-		log.Printf("[Server] Unknown message type: %s", msg.Type)
-	}
+// sessionClient returns the AI client attached to a session
+func sessionClient(s *signaling.Session) (*transports.AIClient, bool) {
+	value, _ := s.Get(aiClientKey)
+	client, ok := value.(*transports.AIClient)
+	return client, ok
 }
 
-// handleOffer handles the WebRTC offer
-func handleOffer(client *transports.AIClient, msg SignalMessage) {
-	offerData, ok := msg.Data.(map[string]interface{})
-	if !ok {
-		log.Println("[Server] Invalid offer data")
-		return
-	}
-
-	offerStr, ok := offerData["sdp"].(string)
-	if !ok {
-		log.Println("[Server] Invalid offer SDP")
-		return
-	}
-
-	// Debug: Check if offer SDP contains audio media
-	if strings.Contains(offerStr, "m=audio") {
-		fmt.Printf("[Server] Offer SDP contains audio media description\n")
-	} else {
-		fmt.Printf("[Server] WARNING: Offer SDP does NOT contain audio media description!\n")
-		previewLen := 200
-		if len(offerStr) < previewLen {
-			previewLen = len(offerStr)
-		}
-		fmt.Printf("[Server] Offer SDP preview: %s...\n", offerStr[:previewLen])
-	}
-
-	if err := client.Transport.SetRemoteDescription(offerStr); err != nil {
-		log.Printf("[Server] Error setting remote description: %v", err)
-		return
-	}
-	fmt.Printf("[Server] Remote description set successfully\n")
-
-	candidates, ok := offerData["candidates"].([]interface{})
+// handleConnection handles connection established message (client confirmation)
+func handleConnection(s *signaling.Session, msg signaling.SignalMessage) error {
+	client, ok := sessionClient(s)
 	if !ok {
-		log.Println("[Server] Invalid candidates data")
-		return
-	}
-
-	candidateStrs := extractCandidates(candidates)
-	answer, serverCandidates, err := client.Transport.CreateAnswer(candidateStrs)
-	if err != nil {
-		log.Printf("[Server] Error creating answer: %v", err)
-		return
-	}
-
-	// Debug: Check if answer SDP contains audio media
-	if strings.Contains(answer, "m=audio") {
-		fmt.Printf("[Server] Answer SDP contains audio media description\n")
-	} else {
-		fmt.Printf("[Server] WARNING: Answer SDP does NOT contain audio media description!\n")
-	}
-
-	answerMsg := SignalMessage{
-		Type:      "answer",
-		SessionID: client.SessionID,
-		Data: map[string]interface{}{
-			"sdp":        answer,
-			"candidates": serverCandidates,
-		},
-	}
-
-	if err := client.Conn.WriteJSON(answerMsg); err != nil {
-		log.Printf("[Server] Error sending answer: %v", err)
-		return
-	}
-
-	fmt.Printf("[Server] Sent answer to client %s\n", client.SessionID)
-
-	// Note: Audio receiving is now handled by the OnTrack callback
-	// which is set up in websocketHandler before any signaling messages are processed
-	// No need to wait here - OnTrack will fire automatically when the track arrives
-	fmt.Println("[Server] Answer sent, waiting for OnTrack callback to fire when client sends signal...")
-}
-
-// extractCandidates extracts candidate strings
-func extractCandidates(candidates []interface{}) []string {
-	var candidateStrs []string
-	for _, c := range candidates {
-		if candidateStr, ok := c.(string); ok {
-			candidateStrs = append(candidateStrs, candidateStr)
-		}
+		return signaling.ErrNoTransport
 	}
-	return candidateStrs
-}
-
-// handleConnection handles connection established message (client confirmation)
-func handleConnection(client *transports.AIClient, msg SignalMessage) {
-	fmt.Printf("[Server] Client confirmed connection for session %s\n", client.SessionID)
+	logger.Info("[Server] Client confirmed connection", zap.String("session", client.SessionID))
 
 	// Wait for connection to be established, then send greeting
 	go func() {
 		if err := waitForConnection(client.Transport); err != nil {
-			log.Printf("[Server] Connection not established: %v", err)
+			logger.Warn("[Server] Connection not established", zap.String("session", client.SessionID), zap.Error(err))
 			return
 		}
 
@@ -271,11 +132,11 @@ func handleConnection(client *transports.AIClient, msg SignalMessage) {
 		for i := 0; i < maxWait; i++ {
 			txTrack := client.Transport.GetTxTrack()
 			if txTrack != nil {
-				fmt.Printf("[Server] txTrack is ready after %d attempts\n", i+1)
+				logger.Debug("[Server] txTrack is ready", zap.Int("attempts", i+1))
 				break
 			}
 			if i == 0 {
-				fmt.Printf("[Server] Waiting for txTrack to be ready...\n")
+				logger.Debug("[Server] Waiting for txTrack to be ready")
 			}
 			time.Sleep(50 * time.Millisecond)
 		}
@@ -285,30 +146,26 @@ func handleConnection(client *transports.AIClient, msg SignalMessage) {
 
 		// Send greeting to start the conversation
 		greeting := "你好，我是AI助手，很高兴和你对话。"
-		fmt.Printf("[Server] Sending greeting: %s\n", greeting)
+		logger.Info("[Server] Sending greeting", zap.String("text", greeting))
 		client.GenerateTTS(greeting)
 	}()
+	return nil
 }
 
 // waitForConnection waits for WebRTC connection
 func waitForConnection(transport *rtcmedia.WebRTCTransport) error {
-	for i := 0; i < maxConnectionRetries; i++ {
-		state := transport.GetConnectionState()
-		if state == webrtc.PeerConnectionStateConnected {
-			return nil
-		}
-
-		if i%connectionStateLogInterval == 0 {
-			fmt.Printf("[Server] Waiting for connection... (state: %s)\n", state.String())
-		}
-
-		time.Sleep(connectionRetryDelay)
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	logger.Info("[Server] Waiting for connection", zap.String("state", transport.GetConnectionState().String()))
+	if err := transport.WaitUntilConnected(ctx); err != nil {
+		return fmt.Errorf("connection not established (state: %s): %w", transport.GetConnectionState().String(), err)
 	}
-
-	return fmt.Errorf("connection timeout")
+	return nil
 }
 
 func main() {
+	flag.Parse()
+
 	// Initialize logger
 	logCfg := &logger.LogConfig{
 		Level:      "info",
@@ -326,10 +183,10 @@ func main() {
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.Default()
-	router.GET(wsPath, websocketHandler)
+	router.GET(wsPath, gin.WrapH(newSignalingServer()))
 
-	fmt.Printf("[Server] Starting AI voice server on %s\n", serverPort)
+	logger.Info("[Server] Starting AI voice server", zap.String("addr", serverPort))
 	if err := router.Run(serverPort); err != nil {
-		log.Fatalf("[Server] Failed to start server: %v", err)
+		logger.Fatal("[Server] Failed to start server", zap.Error(err))
 	}
 }
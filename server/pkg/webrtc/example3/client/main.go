@@ -1,18 +1,23 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net/url"
 	"os"
 	"os/signal"
+	"sync"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/devices"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
 	media2 "github.com/code-100-precent/LingEcho/pkg/media"
-	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	_ "github.com/code-100-precent/LingEcho/pkg/media/encoder" // registers the codecs used by media pipelines
+	"github.com/code-100-precent/LingEcho/pkg/media/fileio"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
 	"github.com/gen2brain/malgo"
@@ -20,7 +25,7 @@ import (
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
-	"github.com/youpy/go-wav"
+	"go.uber.org/zap"
 )
 
 // Constants
@@ -31,19 +36,16 @@ const (
 	wsPath   = "/websocket"
 
 	// Connection retry configuration
-	maxConnectionRetries       = 100
-	connectionRetryDelay       = 100 * time.Millisecond
-	connectionStateLogInterval = 10
+	maxConnectionRetries = 100
+	connectionRetryDelay = 100 * time.Millisecond
+	connectionTimeout    = 10 * time.Second
 
 	// Audio configuration
 	targetSampleRate = 8000 // PCMA standard sample rate
 	audioChannels    = 1
-	audioBitDepth    = 16
-	bytesPerSample   = 2 // 16-bit = 2 bytes
 
 	// Frame configuration
 	frameDurationMs = 20
-	bytesPerFrame   = 160 // 20ms * 8000Hz = 160 samples = 160 bytes (PCMA is 1 byte per sample)
 
 	// Logging intervals
 	packetLogInterval = 100
@@ -53,6 +55,12 @@ const (
 	clientAudioFile = "ringing.wav"
 )
 
+// Command-line flags
+var (
+	listDevices  = flag.Bool("list-devices", false, "list audio devices and exit")
+	outputDevice = flag.String("output-device", "", "playback device: ID, #index or name (default: system default)")
+)
+
 // SignalMessage represents a WebSocket signaling message
 type SignalMessage struct {
 	Type      string      `json:"type"`
@@ -67,6 +75,9 @@ type Client struct {
 	sessionID string
 	interrupt chan os.Signal
 	done      chan struct{}
+
+	// writeMu serializes WebSocket writes (trickled candidates are sent from ICE callbacks)
+	writeMu sync.Mutex
 }
 
 // NewClient creates a new WebRTC client
@@ -133,60 +144,95 @@ func (c *Client) InitializeSession() (string, error) {
 	}
 
 	c.sessionID = initSignal.SessionID
-	fmt.Printf("[Client] Connected with session ID: %s\n", c.sessionID)
+	logger.Info("[Client] Connected", zap.String("session", c.sessionID))
 	return c.sessionID, nil
 }
 
-// CreateAndSendOffer creates a WebRTC offer and sends it to the server
+// sendSignal writes a signaling message to the WebSocket
+func (c *Client) sendSignal(msg SignalMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.wsConn.WriteJSON(msg)
+}
+
+// CreateAndSendOffer creates a WebRTC offer and sends it to the server.
+// ICE candidates are trickled to the server as they are gathered instead of
+// waiting for gathering to complete.
 func (c *Client) CreateAndSendOffer() error {
 	c.transport.NewPeerConnection()
 
-	offer, candidates, err := c.transport.CreateOffer()
+	// Candidates gathered after the offer is sent arrive at the server as "candidate" messages
+	c.transport.OnICECandidate(func(candidate string) {
+		candidateMsg := SignalMessage{
+			Type:      constants.WebRTCCandidate,
+			SessionID: c.sessionID,
+			Data:      map[string]interface{}{"candidate": candidate},
+		}
+		if err := c.sendSignal(candidateMsg); err != nil {
+			logger.Warn("[Client] Error sending ICE candidate", zap.Error(err))
+		}
+	})
+
+	// ICE restart: when the network blips the transport re-offers over the same WebSocket
+	c.transport.OnRestartOffer(func(offer string, _ []string) error {
+		return c.sendSignal(SignalMessage{
+			Type:      "offer",
+			SessionID: c.sessionID,
+			Data: map[string]interface{}{
+				"sdp":         offer,
+				"trickle":     true,
+				"ice_restart": true,
+			},
+		})
+	})
+	c.transport.OnReconnected(func() {
+		logger.Info("[Client] WebRTC connection recovered")
+	})
+
+	offer, _, err := c.transport.CreateOffer()
 	if err != nil {
 		return fmt.Errorf("failed to create offer: %w", err)
 	}
 
-	fmt.Printf("[Client] Created offer with %d candidates\n", len(candidates))
-
 	offerMsg := SignalMessage{
 		Type:      "offer",
 		SessionID: c.sessionID,
 		Data: map[string]interface{}{
-			"sdp":        offer,
-			"candidates": candidates,
+			"sdp":     offer,
+			"trickle": true,
 		},
 	}
-
-	offerBytes, err := json.Marshal(offerMsg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal offer: %w", err)
-	}
-
-	if err := c.wsConn.WriteMessage(websocket.TextMessage, offerBytes); err != nil {
+	if err := c.sendSignal(offerMsg); err != nil {
 		return fmt.Errorf("failed to send offer: %w", err)
 	}
 
-	fmt.Println("[Client] Offer sent to server")
+	logger.Info("[Client] Offer sent to server, trickling ICE candidates")
 	return nil
 }
 
+// HandleCandidate adds an ICE candidate trickled by the server
+func (c *Client) HandleCandidate(msg SignalMessage) error {
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid candidate data")
+	}
+	candidate, ok := data["candidate"].(string)
+	if !ok || candidate == "" {
+		return fmt.Errorf("invalid candidate")
+	}
+	return c.transport.AddICECandidate(candidate)
+}
+
 // WaitForConnection waits for the WebRTC connection to be established
 func (c *Client) WaitForConnection() error {
-	for i := 0; i < maxConnectionRetries; i++ {
-		state := c.transport.GetConnectionState()
-		if state == webrtc.PeerConnectionStateConnected {
-			fmt.Println("[Client] WebRTC connection established")
-			return nil
-		}
-
-		if i%connectionStateLogInterval == 0 {
-			fmt.Printf("[Client] Waiting for connection... (state: %s)\n", state.String())
-		}
-
-		time.Sleep(connectionRetryDelay)
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	logger.Info("[Client] Waiting for connection", zap.String("state", c.transport.GetConnectionState().String()))
+	if err := c.transport.WaitUntilConnected(ctx); err != nil {
+		return fmt.Errorf("connection not established (state: %s): %w", c.transport.GetConnectionState().String(), err)
 	}
-
-	return fmt.Errorf("connection timeout after %d retries", maxConnectionRetries)
+	logger.Info("[Client] WebRTC connection established")
+	return nil
 }
 
 // WaitForTrack waits for the remote audio track to be available
@@ -214,32 +260,29 @@ func (c *Client) SetupAudioPlayback() (*devices.StreamAudioPlayer, media2.Encode
 		return nil, nil, fmt.Errorf("failed to create stream player: %w", err)
 	}
 
+	if *outputDevice != "" {
+		device, err := streamPlayer.SelectDevice(*outputDevice)
+		if err != nil {
+			streamPlayer.Close()
+			return nil, nil, fmt.Errorf("failed to select output device: %w", err)
+		}
+		logger.Info("[Client] Using output device", zap.String("device", device.Name))
+	}
+
 	// Start playback
 	if err := streamPlayer.Play(); err != nil {
 		streamPlayer.Close()
 		return nil, nil, fmt.Errorf("failed to start playback: %w", err)
 	}
 
-	fmt.Printf("[Client] Audio playback started: %dHz, %d channel(s)\n",
-		targetSampleRate, audioChannels)
+	logger.Info("[Client] Audio playback started",
+		zap.Int("sampleRate", targetSampleRate),
+		zap.Int("channels", audioChannels))
 
 	// Create PCMA decoder
-	decodeFunc, err := encoder.CreateDecode(
-		media2.CodecConfig{
-			Codec:         "pcma",
-			SampleRate:    targetSampleRate,
-			Channels:      audioChannels,
-			BitDepth:      8,
-			FrameDuration: "20ms",
-		},
-		media2.CodecConfig{
-			Codec:         "pcm",
-			SampleRate:    targetSampleRate,
-			Channels:      audioChannels,
-			BitDepth:      audioBitDepth,
-			FrameDuration: "20ms",
-		},
-	)
+	decodeFunc, err := media2.NewPipeline().
+		Decode(media2.CodecConfig{Codec: "pcma", SampleRate: targetSampleRate, Channels: audioChannels, FrameDuration: "20ms"}).
+		Build()
 	if err != nil {
 		streamPlayer.Close()
 		return nil, nil, fmt.Errorf("failed to create decoder: %w", err)
@@ -265,7 +308,7 @@ func (c *Client) ProcessAudioPacket(
 	decodedPackets, err := decodeFunc(audioPacket)
 	if err != nil {
 		if packetCount%packetLogInterval == 0 {
-			fmt.Printf("[Client] Error decoding frame %d: %v\n", packetCount, err)
+			logger.Warn("[Client] Error decoding frame", zap.Int("packet", packetCount), zap.Error(err))
 		}
 		return err
 	}
@@ -275,8 +318,8 @@ func (c *Client) ProcessAudioPacket(
 	if len(allPCMData) > 0 {
 		if err := streamPlayer.Write(allPCMData); err != nil {
 			// Buffer full is not critical, only log other errors
-			if packetCount%packetLogInterval == 0 && err.Error() != "音频缓冲区已满" {
-				fmt.Printf("[Client] Error writing to player: %v\n", err)
+			if packetCount%packetLogInterval == 0 && !errors.Is(err, devices.ErrBufferFull) {
+				logger.Warn("[Client] Error writing to player", zap.Error(err))
 			}
 		}
 	}
@@ -302,8 +345,7 @@ func (c *Client) collectPCMData(decodedPackets []media2.MediaPacket, packetCount
 		// Validate PCM data (should be 16-bit, so length must be even)
 		if len(af.Payload)%2 != 0 {
 			if packetCount <= warningLogLimit {
-				fmt.Printf("[Client] Warning: Odd PCM length at packet %d: %d bytes\n",
-					packetCount, len(af.Payload))
+				logger.Warn("[Client] Odd PCM length", zap.Int("packet", packetCount), zap.Int("bytes", len(af.Payload)))
 			}
 			continue
 		}
@@ -316,7 +358,7 @@ func (c *Client) collectPCMData(decodedPackets []media2.MediaPacket, packetCount
 
 // SendAudioToServer sends audio data to the server via WebRTC
 func (c *Client) SendAudioToServer() error {
-	fmt.Println("[Client] Starting to send audio to server...")
+	logger.Info("[Client] Starting to send audio to server")
 
 	// Get transmit track
 	txTrack := c.transport.GetTxTrack()
@@ -325,143 +367,47 @@ func (c *Client) SendAudioToServer() error {
 	}
 
 	// Load and process audio file
-	pcmaData, err := c.loadAndProcessAudioFile()
+	frames, err := c.loadAndProcessAudioFile()
 	if err != nil {
 		return fmt.Errorf("failed to load audio: %w", err)
 	}
 
 	// Send audio frames
-	return c.sendAudioFrames(txTrack, pcmaData)
+	return c.sendAudioFrames(txTrack, frames)
 }
 
-// loadAndProcessAudioFile loads and processes the audio file
-func (c *Client) loadAndProcessAudioFile() ([]byte, error) {
-	// Open audio file
-	file, err := os.Open(clientAudioFile)
+// loadAndProcessAudioFile loads the audio file and encodes it into 20ms PCMA frames
+func (c *Client) loadAndProcessAudioFile() ([][]byte, error) {
+	src, err := fileio.Open(clientAudioFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open audio file: %w", err)
 	}
-	defer file.Close()
+	defer src.Close()
 
-	// Read WAV format
-	w := wav.NewReader(file)
-	format, err := w.Format()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get WAV format: %w", err)
-	}
+	format := src.Format()
+	logger.Info("[Client] Audio file format",
+		zap.Int("sampleRate", format.SampleRate),
+		zap.Int("channels", format.Channels))
 
-	fmt.Printf("[Client] WAV format: %dHz, %d channels, %d bits\n",
-		format.SampleRate, format.NumChannels, format.BitsPerSample)
-
-	// Read entire file
-	allPCMData, err := c.readWAVFile(w)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read WAV file: %w", err)
-	}
-
-	fmt.Printf("[Client] Read %d bytes from WAV file\n", len(allPCMData))
-
-	// Convert to mono if needed
-	channels := int(format.NumChannels)
-	if channels > 1 {
-		allPCMData = c.convertToMono(allPCMData, channels, int(format.BitsPerSample))
-		channels = 1
-	}
-
-	// Resample if needed
-	if int(format.SampleRate) != targetSampleRate {
-		allPCMData, err = c.resampleAudio(allPCMData, int(format.SampleRate))
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	// Encode to PCMA
-	pcmaData, err := encoder.EncodePCMA(allPCMData)
+	// Downmix, resample to 8kHz and encode to PCMA
+	frames, err := fileio.EncodeFrames(src, media2.CodecConfig{Codec: "pcma", SampleRate: targetSampleRate, Channels: audioChannels})
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode to PCMA: %w", err)
 	}
 
-	fmt.Printf("[Client] Encoded %d bytes PCM to %d bytes PCMA\n",
-		len(allPCMData), len(pcmaData))
-
-	return pcmaData, nil
-}
-
-// readWAVFile reads the entire WAV file
-func (c *Client) readWAVFile(w *wav.Reader) ([]byte, error) {
-	var allPCMData []byte
-	tempBuffer := make([]byte, 8192)
-
-	for {
-		n, err := w.Read(tempBuffer)
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-		allPCMData = append(allPCMData, tempBuffer[:n]...)
-	}
-
-	return allPCMData, nil
-}
-
-// convertToMono converts stereo audio to mono by averaging channels
-func (c *Client) convertToMono(data []byte, channels, bytesPerSample int) []byte {
-	if bytesPerSample != 2 {
-		return data // Only support 16-bit
-	}
-
-	sampleCount := len(data) / (bytesPerSample * channels)
-	monoData := make([]byte, sampleCount*bytesPerSample)
-
-	for i := 0; i < sampleCount; i++ {
-		leftIdx := i * bytesPerSample * channels
-		rightIdx := leftIdx + bytesPerSample
-
-		if rightIdx+bytesPerSample <= len(data) {
-			leftSample := int16(data[leftIdx]) | int16(data[leftIdx+1])<<8
-			rightSample := int16(data[rightIdx]) | int16(data[rightIdx+1])<<8
-
-			// Average the two channels
-			avg := int16((int32(leftSample) + int32(rightSample)) / 2)
-
-			monoIdx := i * bytesPerSample
-			monoData[monoIdx] = byte(avg)
-			monoData[monoIdx+1] = byte(avg >> 8)
-		}
-	}
-
-	fmt.Printf("[Client] Converted stereo to mono (%d samples)\n", sampleCount)
-	return monoData
-}
-
-// resampleAudio resamples audio to target sample rate
-func (c *Client) resampleAudio(data []byte, sourceRate int) ([]byte, error) {
-	fmt.Printf("[Client] Resampling from %dHz to %dHz...\n", sourceRate, targetSampleRate)
-
-	resampled, err := media2.ResamplePCM(data, sourceRate, targetSampleRate)
-	if err != nil {
-		return nil, fmt.Errorf("resampling failed: %w", err)
-	}
+	logger.Info("[Client] Encoded audio file to PCMA", zap.Int("frames", len(frames)))
 
-	fmt.Printf("[Client] Resampled to %dHz (%d bytes)\n", targetSampleRate, len(resampled))
-	return resampled, nil
+	return frames, nil
 }
 
 // sendAudioFrames sends audio frames with precise timing
-func (c *Client) sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, pcmaData []byte) error {
+func (c *Client) sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, frames [][]byte) error {
 	frameDuration := time.Duration(frameDurationMs) * time.Millisecond
 	startTime := time.Now()
 	frameCount := 0
+	totalBytes := 0
 
-	for i := 0; i < len(pcmaData); i += bytesPerFrame {
-		end := i + bytesPerFrame
-		if end > len(pcmaData) {
-			end = len(pcmaData)
-		}
-
+	for _, frame := range frames {
 		// Calculate exact send time to maintain consistent frame rate
 		expectedTime := startTime.Add(time.Duration(frameCount) * frameDuration)
 		if now := time.Now(); expectedTime.After(now) {
@@ -469,7 +415,7 @@ func (c *Client) sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, pcmaDat
 		}
 
 		sample := media.Sample{
-			Data:     pcmaData[i:end],
+			Data:     frame,
 			Duration: frameDuration,
 		}
 
@@ -478,14 +424,13 @@ func (c *Client) sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, pcmaDat
 		}
 
 		frameCount++
+		totalBytes += len(frame)
 		if frameCount%50 == 0 {
-			fmt.Printf("[Client] Sent %d frames (PCMA: %d bytes)...\n",
-				frameCount, len(pcmaData[i:end]))
+			logger.Debug("[Client] Sent frames", zap.Int("frames", frameCount), zap.Int("frameBytes", len(frame)))
 		}
 	}
 
-	fmt.Printf("[Client] Finished sending audio (%d frames, %d bytes PCMA)\n",
-		frameCount, len(pcmaData))
+	logger.Info("[Client] Finished sending audio", zap.Int("frames", frameCount), zap.Int("bytes", totalBytes))
 
 	return nil
 }
@@ -499,7 +444,7 @@ func (c *Client) StartAudioReceiver(rxTrack *webrtc.TrackRemote) error {
 	defer streamPlayer.Close()
 
 	codec := rxTrack.Codec()
-	fmt.Printf("[Client] Received track: %s, %dHz\n", codec.MimeType, codec.ClockRate)
+	logger.Info("[Client] Received track", zap.String("codec", codec.MimeType), zap.Uint32("clockRate", codec.ClockRate))
 
 	packetCount := 0
 	lastPacketTime := time.Now()
@@ -535,18 +480,18 @@ func (c *Client) StartAudioReceiver(rxTrack *webrtc.TrackRemote) error {
 			}
 			packetCount++
 			if packetCount%packetLogInterval == 0 {
-				fmt.Printf("[Client] Received and played %d RTP packets\n", packetCount)
+				logger.Debug("[Client] Received and played RTP packets", zap.Int("packets", packetCount))
 			}
 		case err := <-errChan:
 			close(done)
 			// If we get an error, wait a bit for playback to finish, then send audio
-			fmt.Printf("[Client] Error reading RTP packet (may be end of stream): %v\n", err)
+			logger.Info("[Client] Error reading RTP packet, may be end of stream", zap.Error(err))
 			time.Sleep(500 * time.Millisecond)
 			return c.SendAudioToServer()
 		case <-time.After(noPacketTimeout):
 			// No packet received for timeout duration, check if we should consider playback finished
 			if time.Since(lastPacketTime) >= noPacketTimeout {
-				fmt.Printf("[Client] No packets received for %v, assuming playback finished\n", noPacketTimeout)
+				logger.Info("[Client] No packets received, assuming playback finished", zap.Duration("timeout", noPacketTimeout))
 				close(done)
 				// Wait a bit more to ensure all audio is played
 				time.Sleep(500 * time.Millisecond)
@@ -575,16 +520,13 @@ func (c *Client) HandleAnswer(msg SignalMessage) error {
 		return fmt.Errorf("failed to set remote description: %w", err)
 	}
 
-	// Extract and add ICE candidates
-	candidates, ok := answerData["candidates"].([]interface{})
-	if !ok {
-		return fmt.Errorf("invalid candidates data")
-	}
+	// Add ICE candidates bundled with the answer (servers without trickle support)
+	candidates, _ := answerData["candidates"].([]interface{})
 
 	candidateStrs := c.extractCandidates(candidates)
 	for _, candidate := range candidateStrs {
 		if err := c.transport.AddICECandidate(candidate); err != nil {
-			log.Printf("[Client] Error adding ICE candidate: %v", err)
+			logger.Warn("[Client] Error adding ICE candidate", zap.Error(err))
 		}
 	}
 
@@ -594,11 +536,11 @@ func (c *Client) HandleAnswer(msg SignalMessage) error {
 		SessionID: c.sessionID,
 		Data:      map[string]interface{}{},
 	}
-	if err := c.wsConn.WriteJSON(connectedMsg); err != nil {
+	if err := c.sendSignal(connectedMsg); err != nil {
 		return fmt.Errorf("failed to send connected message: %w", err)
 	}
 
-	fmt.Println("[Client] WebRTC connection should now be establishing...")
+	logger.Info("[Client] WebRTC connection establishing")
 
 	// Wait for connection
 	if err := c.WaitForConnection(); err != nil {
@@ -615,6 +557,36 @@ func (c *Client) HandleAnswer(msg SignalMessage) error {
 	return c.StartAudioReceiver(rxTrack)
 }
 
+// HandleRestartAnswer applies the answer to an ICE restart offer; media and playback keep running
+func (c *Client) HandleRestartAnswer(msg SignalMessage) error {
+	answerData, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid answer data")
+	}
+	answerStr, ok := answerData["sdp"].(string)
+	if !ok {
+		return fmt.Errorf("invalid answer SDP")
+	}
+	answerJSON, err := json.Marshal(map[string]string{
+		"type": "answer",
+		"sdp":  answerStr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal answer SDP: %w", err)
+	}
+	if err := c.transport.SetRemoteDescription(string(answerJSON)); err != nil {
+		return fmt.Errorf("failed to set remote description: %w", err)
+	}
+	candidates, _ := answerData["candidates"].([]interface{})
+	for _, candidate := range c.extractCandidates(candidates) {
+		if err := c.transport.AddICECandidate(candidate); err != nil {
+			logger.Warn("[Client] Error adding ICE candidate", zap.Error(err))
+		}
+	}
+	logger.Info("[Client] ICE restart answer applied")
+	return nil
+}
+
 // extractCandidates extracts candidate strings from the interface slice
 func (c *Client) extractCandidates(candidates []interface{}) []string {
 	var candidateStrs []string
@@ -633,23 +605,36 @@ func (c *Client) StartMessageListener() {
 		for {
 			_, message, err := c.wsConn.ReadMessage()
 			if err != nil {
-				log.Printf("[Client] Error reading message: %v", err)
+				logger.Warn("[Client] Error reading message", zap.Error(err))
 				return
 			}
 
 			var signal SignalMessage
 			if err := json.Unmarshal(message, &signal); err != nil {
-				log.Printf("[Client] Error unmarshaling message: %v", err)
+				logger.Warn("[Client] Error unmarshaling message", zap.Error(err))
 				continue
 			}
 
 			switch signal.Type {
 			case "answer":
-				if err := c.HandleAnswer(signal); err != nil {
-					log.Printf("[Client] Error handling answer: %v", err)
+				if restart, _ := signal.Data.(map[string]interface{})["ice_restart"].(bool); restart {
+					if err := c.HandleRestartAnswer(signal); err != nil {
+						logger.Error("[Client] Error handling ICE restart answer", zap.Error(err))
+					}
+					continue
+				}
+				// HandleAnswer blocks until audio ends; keep reading so trickled candidates are applied
+				go func(msg SignalMessage) {
+					if err := c.HandleAnswer(msg); err != nil {
+						logger.Error("[Client] Error handling answer", zap.Error(err))
+					}
+				}(signal)
+			case constants.WebRTCCandidate:
+				if err := c.HandleCandidate(signal); err != nil {
+					logger.Warn("[Client] Error adding ICE candidate", zap.Error(err))
 				}
 			default:
-				log.Printf("[Client] Unknown message type: %s", signal.Type)
+				logger.Debug("[Client] Unknown message type", zap.String("type", signal.Type))
 			}
 		}
 	}()
@@ -673,24 +658,51 @@ func (c *Client) Run() error {
 	c.StartMessageListener()
 
 	// Wait for interrupt or done signal
-	fmt.Println("[Client] Waiting for connection to establish...")
+	logger.Info("[Client] Waiting for connection to establish")
 	select {
 	case <-c.interrupt:
-		fmt.Println("\n[Client] Interrupted, closing connection...")
+		logger.Info("[Client] Interrupted, closing connection")
 		return nil
 	case <-c.done:
-		fmt.Println("[Client] Connection closed")
+		logger.Info("[Client] Connection closed")
 		return nil
 	}
 }
 
 func main() {
+	flag.Parse()
+
+	// Initialize logger
+	logCfg := &logger.LogConfig{
+		Level:      "info",
+		Filename:   "logs/example3-client.log",
+		MaxSize:    100,
+		MaxAge:     7,
+		MaxBackups: 3,
+	}
+	if err := logger.Init(logCfg, "dev"); err != nil {
+		log.Fatalf("[Client] Failed to initialize logger: %v", err)
+	}
+	defer logger.Sync()
+
+	if *listDevices {
+		streamCtx, err := devices.NewStreamContext(nil)
+		if err != nil {
+			logger.Fatal("[Client] Failed to initialize audio context", zap.Error(err))
+		}
+		defer streamCtx.Close()
+		if err := devices.PrintAllDevices(streamCtx.GetContext()); err != nil {
+			logger.Fatal("[Client] Failed to list devices", zap.Error(err))
+		}
+		return
+	}
+
 	client, err := NewClient()
 	if err != nil {
-		log.Fatalf("[Client] Failed to create client: %v", err)
+		logger.Fatal("[Client] Failed to create client", zap.Error(err))
 	}
 
 	if err := client.Run(); err != nil {
-		log.Fatalf("[Client] Error: %v", err)
+		logger.Fatal("[Client] Error", zap.Error(err))
 	}
 }
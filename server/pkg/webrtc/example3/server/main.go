@@ -1,26 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
 	"sync"
 	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/devices"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
 	media2 "github.com/code-100-precent/LingEcho/pkg/media"
-	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	_ "github.com/code-100-precent/LingEcho/pkg/media/encoder" // registers the codecs used by media pipelines
+	"github.com/code-100-precent/LingEcho/pkg/media/fileio"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/signaling"
 	"github.com/gen2brain/malgo"
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
-	"github.com/youpy/go-wav"
+	"go.uber.org/zap"
 )
 
 // Constants
@@ -29,17 +31,13 @@ const (
 	serverPort = ":8080"
 	wsPath     = "/websocket"
 
-	// Connection retry configuration
-	maxConnectionRetries       = 50
-	connectionRetryDelay       = 100 * time.Millisecond
-	connectionStateLogInterval = 10
-	connectionReadyDelay       = 200 * time.Millisecond
+	// Connection configuration
+	connectionTimeout    = 5 * time.Second
+	connectionReadyDelay = 200 * time.Millisecond
 
 	// Audio configuration
 	targetSampleRate = 8000 // PCMA standard sample rate
 	audioChannels    = 1
-	audioBitDepth    = 16
-	bytesPerSample   = 2 // 16-bit = 2 bytes
 
 	// Logging intervals
 	packetLogInterval = 100
@@ -47,10 +45,8 @@ const (
 
 	// Frame configuration
 	frameDurationMs = 20
-	bytesPerFrame   = 160 // 20ms * 8000Hz = 160 samples = 160 bytes (PCMA is 1 byte per sample)
 
 	// File configuration
-	readBufferSize    = 8192
 	audioFilePrimary  = "ringring.wav"
 	audioFileFallback = "ringing.wav"
 
@@ -58,35 +54,19 @@ const (
 	frameLogInterval = 50
 )
 
-// SignalMessage represents a WebSocket signaling message
-type SignalMessage struct {
-	Type      string      `json:"type"`
-	SessionID string      `json:"session_id,omitempty"`
-	Data      interface{} `json:"data,omitempty"`
-}
-
-var (
-	manager  = NewClientManager()
-	upgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true // Allow all origins in development
-		},
-	}
-)
-
-// websocketHandler handles WebSocket connections
-func websocketHandler(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		log.Printf("[Server] Failed to upgrade connection: %v", err)
-		return
+// newSignalingServer creates the signaling server; each session gets its own WebRTC transport
+func newSignalingServer() *signaling.Server {
+	srv := signaling.NewServer()
+	srv.Upgrader.CheckOrigin = func(r *http.Request) bool {
+		return true // Allow all origins in development
 	}
-	defer conn.Close()
-
-	// Create session ID
-	sessionID := fmt.Sprintf("session_%d", time.Now().UnixNano())
+	srv.OnSession = setupSession
+	srv.Handle(signaling.TypeConnected, handleConnection)
+	return srv
+}
 
-	// Create WebRTC transport
+// setupSession creates the WebRTC transport and starts receiving once the client's track arrives
+func setupSession(s *signaling.Session) error {
 	transport := rtcmedia.NewWebRTCTransport(rtcmedia.WebRTCOption{
 		Codec: constants.CodecPCMA,
 		ICEServers: []webrtc.ICEServer{
@@ -96,282 +76,97 @@ func websocketHandler(c *gin.Context) {
 		ICETimeout: constants.DefaultICETimeout,
 	})
 	transport.NewPeerConnection()
-
-	client := &Client{
-		conn:          conn,
-		transport:     transport,
-		sessionID:     sessionID,
-		audioReceived: false,
-	}
+	s.Transport = transport
 
 	// Set up OnTrack callback to start receiving audio when client sends it
+	var receiveOnce sync.Once
 	transport.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
-		client.mu.Lock()
-		if !client.audioReceived {
-			client.audioReceived = true
-			client.mu.Unlock()
+		receiveOnce.Do(func() {
 			// Start receiving audio in a goroutine
 			go func() {
-				if err := receiveAudioFromClient(client); err != nil {
-					log.Printf("[Server] Error receiving audio: %v", err)
+				if err := receiveAudioFromClient(transport); err != nil {
+					logger.Error("[Server] Error receiving audio", zap.Error(err))
 				}
 			}()
-		} else {
-			client.mu.Unlock()
-		}
+		})
 	})
-
-	manager.AddClient(sessionID, client)
-	defer manager.RemoveClient(sessionID)
-
-	// Send session ID to client
-	initMsg := SignalMessage{
-		Type:      "init",
-		SessionID: sessionID,
-	}
-	if err := conn.WriteJSON(initMsg); err != nil {
-		log.Printf("[Server] Failed to send init message: %v", err)
-		return
-	}
-
-	// Handle incoming messages
-	for {
-		var msg SignalMessage
-		if err := conn.ReadJSON(&msg); err != nil {
-			log.Printf("[Server] Error reading message: %v", err)
-			break
-		}
-
-		handleSignalMessage(client, msg)
-	}
-}
-
-// handleSignalMessage routes signaling messages to appropriate handlers
-func handleSignalMessage(client *Client, msg SignalMessage) {
-	switch msg.Type {
-	case "offer":
-		HandleOffer(client, msg)
-	case "connected":
-		handleConnection(client, msg)
-	default:
-		log.Printf("[Server] Unknown message type: %s", msg.Type)
-	}
+	return nil
 }
 
 // handleConnection handles the connection established message and starts sending audio
-func handleConnection(client *Client, msg SignalMessage) {
+func handleConnection(s *signaling.Session, msg signaling.SignalMessage) error {
 	// Run in goroutine to avoid blocking
 	go func() {
-		if err := sendAudioToClient(client); err != nil {
-			log.Printf("[Server] Error sending audio: %v", err)
+		if err := sendAudioToClient(s.Transport); err != nil {
+			logger.Error("[Server] Error sending audio", zap.Error(err))
 		}
 	}()
 
 	// Note: Audio receiving is started in OnTrack callback when client sends audio
 	// No need to start it here, as it will be triggered when OnTrack fires
+	return nil
 }
 
 // sendAudioToClient sends audio data to the client via WebRTC
-func sendAudioToClient(client *Client) error {
+func sendAudioToClient(transport *rtcmedia.WebRTCTransport) error {
 	// Wait for connection to be fully established
-	if err := waitForConnection(client.transport); err != nil {
+	if err := waitForConnection(transport); err != nil {
 		return fmt.Errorf("connection not established: %w", err)
 	}
 
 	// Additional delay to ensure everything is ready
 	time.Sleep(connectionReadyDelay)
 
-	fmt.Println("[Server] Starting to send signal...")
+	logger.Info("[Server] Starting to send signal")
 
 	// Get transmit track
-	txTrack := client.transport.GetTxTrack()
+	txTrack := transport.GetTxTrack()
 	if txTrack == nil {
 		return fmt.Errorf("txTrack is nil")
 	}
 
 	// Load and process audio file
-	pcmaData, err := loadAndProcessAudioFile()
+	frames, err := loadAndProcessAudioFile()
 	if err != nil {
 		return fmt.Errorf("failed to load audio: %w", err)
 	}
 
 	// Send audio frames
-	return sendAudioFrames(txTrack, pcmaData)
+	return sendAudioFrames(txTrack, frames)
 }
 
-// loadAndProcessAudioFile loads and processes the audio file
-func loadAndProcessAudioFile() ([]byte, error) {
-	// Open audio file
-	file, err := openAudioFile()
+// loadAndProcessAudioFile loads the audio file and encodes it into 20ms PCMA frames
+func loadAndProcessAudioFile() ([][]byte, error) {
+	src, err := openAudioFile()
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	// Read WAV format
-	w := wav.NewReader(file)
-	format, err := w.Format()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get WAV format: %w", err)
-	}
+	defer src.Close()
 
-	fmt.Printf("[Server] WAV format: %dHz, %d channels, %d bits\n",
-		format.SampleRate, format.NumChannels, format.BitsPerSample)
+	format := src.Format()
+	logger.Info("[Server] Audio file format",
+		zap.Int("sampleRate", format.SampleRate),
+		zap.Int("channels", format.Channels))
 
-	// Read entire file
-	allPCMData, err := readWAVFile(w)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read WAV file: %w", err)
-	}
-
-	fmt.Printf("[Server] Read %d bytes from WAV file\n", len(allPCMData))
-
-	// Convert to mono if needed
-	channels := int(format.NumChannels)
-	if channels > 1 {
-		allPCMData = convertToMono(allPCMData, channels, int(format.BitsPerSample))
-		channels = 1
-	}
-
-	// Resample if needed
-	if int(format.SampleRate) != targetSampleRate {
-		allPCMData, err = resampleAudio(allPCMData, int(format.SampleRate))
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	// Encode to PCMA
-	pcmaData, err := encoder.EncodePCMA(allPCMData)
+	// Downmix, resample to 8kHz and encode to PCMA
+	frames, err := fileio.EncodeFrames(src, media2.CodecConfig{Codec: "pcma", SampleRate: targetSampleRate, Channels: audioChannels})
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode to PCMA: %w", err)
 	}
 
-	fmt.Printf("[Server] Encoded %d bytes PCM to %d bytes PCMA\n",
-		len(allPCMData), len(pcmaData))
-
-	return pcmaData, nil
-}
+	logger.Info("[Server] Encoded audio file to PCMA", zap.Int("frames", len(frames)))
 
-// ClientManager manages WebRTC client connections
-type ClientManager struct {
-	clients map[string]*Client
-	mutex   sync.RWMutex
-}
-
-// NewClientManager creates a new client manager
-func NewClientManager() *ClientManager {
-	return &ClientManager{
-		clients: make(map[string]*Client),
-	}
-}
-
-// AddClient adds a client to the manager
-func (m *ClientManager) AddClient(sessionID string, client *Client) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	m.clients[sessionID] = client
-}
-
-// RemoveClient removes a client from the manager
-func (m *ClientManager) RemoveClient(sessionID string) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	delete(m.clients, sessionID)
-}
-
-// GetClient retrieves a client by session ID
-func (m *ClientManager) GetClient(sessionID string) (*Client, bool) {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-	client, exists := m.clients[sessionID]
-	return client, exists
-}
-
-// Client represents a WebRTC client connection
-type Client struct {
-	conn          *websocket.Conn
-	transport     *rtcmedia.WebRTCTransport
-	sessionID     string
-	audioReceived bool // Track if we've started receiving audio
-	mu            sync.Mutex
-}
-
-// handleOffer handles the WebRTC offer from the client
-func HandleOffer(client *Client, msg SignalMessage) {
-	offerData, ok := msg.Data.(map[string]interface{})
-	if !ok {
-		log.Println("[Server] Invalid offer data")
-		return
-	}
-
-	offerStr, ok := offerData["sdp"].(string)
-	if !ok {
-		log.Println("[Server] Invalid offer SDP")
-		return
-	}
-
-	// Set remote description
-	if err := client.transport.SetRemoteDescription(offerStr); err != nil {
-		log.Printf("[Server] Error setting remote description: %v", err)
-		return
-	}
-
-	// Extract candidates
-	candidates, ok := offerData["candidates"].([]interface{})
-	if !ok {
-		log.Println("[Server] Invalid candidates data")
-		return
-	}
-
-	candidateStrs := extractCandidates(candidates)
-	answer, serverCandidates, err := client.transport.CreateAnswer(candidateStrs)
-	if err != nil {
-		log.Printf("[Server] Error creating answer: %v", err)
-		return
-	}
-
-	// Send answer back to client
-	answerMsg := SignalMessage{
-		Type:      "answer",
-		SessionID: client.sessionID,
-		Data: map[string]interface{}{
-			"sdp":        answer,
-			"candidates": serverCandidates,
-		},
-	}
-
-	if err := client.conn.WriteJSON(answerMsg); err != nil {
-		log.Printf("[Server] Error sending answer: %v", err)
-		return
-	}
-
-	fmt.Printf("[Server] Sent answer to client %s\n", client.sessionID)
-}
-
-// extractCandidates extracts candidate strings from the interface slice
-func extractCandidates(candidates []interface{}) []string {
-	var candidateStrs []string
-	for _, c := range candidates {
-		if candidateStr, ok := c.(string); ok {
-			candidateStrs = append(candidateStrs, candidateStr)
-		}
-	}
-	return candidateStrs
+	return frames, nil
 }
 
 // sendAudioFrames sends audio frames with precise timing
-func sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, pcmaData []byte) error {
+func sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, frames [][]byte) error {
 	frameDuration := time.Duration(frameDurationMs) * time.Millisecond
 	startTime := time.Now()
 	frameCount := 0
+	totalBytes := 0
 
-	for i := 0; i < len(pcmaData); i += bytesPerFrame {
-		end := i + bytesPerFrame
-		if end > len(pcmaData) {
-			end = len(pcmaData)
-		}
-
+	for _, frame := range frames {
 		// Calculate exact send time to maintain consistent frame rate
 		expectedTime := startTime.Add(time.Duration(frameCount) * frameDuration)
 		if now := time.Now(); expectedTime.After(now) {
@@ -379,7 +174,7 @@ func sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, pcmaData []byte) er
 		}
 
 		sample := media.Sample{
-			Data:     pcmaData[i:end],
+			Data:     frame,
 			Duration: frameDuration,
 		}
 
@@ -388,123 +183,53 @@ func sendAudioFrames(txTrack *webrtc.TrackLocalStaticSample, pcmaData []byte) er
 		}
 
 		frameCount++
+		totalBytes += len(frame)
 		if frameCount%frameLogInterval == 0 {
-			fmt.Printf("[Server] Sent %d frames (PCMA: %d bytes)...\n",
-				frameCount, len(pcmaData[i:end]))
+			logger.Debug("[Server] Sent frames", zap.Int("frames", frameCount), zap.Int("frameBytes", len(frame)))
 		}
 	}
 
-	fmt.Printf("[Server] Finished sending audio (%d frames, %d bytes PCMA)\n",
-		frameCount, len(pcmaData))
+	logger.Info("[Server] Finished sending audio", zap.Int("frames", frameCount), zap.Int("bytes", totalBytes))
 
 	return nil
 }
 
 // openAudioFile opens the audio file with fallback
-func openAudioFile() (*os.File, error) {
-	file, err := os.Open(audioFilePrimary)
+func openAudioFile() (fileio.Source, error) {
+	src, err := fileio.Open(audioFilePrimary)
 	if err != nil {
-		file, err = os.Open(audioFileFallback)
+		src, err = fileio.Open(audioFileFallback)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to open audio file: %w", err)
 	}
-	return file, nil
-}
-
-// readWAVFile reads the entire WAV file
-func readWAVFile(w *wav.Reader) ([]byte, error) {
-	var allPCMData []byte
-	tempBuffer := make([]byte, readBufferSize)
-
-	for {
-		n, err := w.Read(tempBuffer)
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-		allPCMData = append(allPCMData, tempBuffer[:n]...)
-	}
-
-	return allPCMData, nil
-}
-
-// convertToMono converts stereo audio to mono by averaging channels
-func convertToMono(data []byte, channels, bytesPerSample int) []byte {
-	if bytesPerSample != 2 {
-		return data // Only support 16-bit
-	}
-
-	sampleCount := len(data) / (bytesPerSample * channels)
-	monoData := make([]byte, sampleCount*bytesPerSample)
-
-	for i := 0; i < sampleCount; i++ {
-		leftIdx := i * bytesPerSample * channels
-		rightIdx := leftIdx + bytesPerSample
-
-		if rightIdx+bytesPerSample <= len(data) {
-			leftSample := int16(data[leftIdx]) | int16(data[leftIdx+1])<<8
-			rightSample := int16(data[rightIdx]) | int16(data[rightIdx+1])<<8
-
-			// Average the two channels
-			avg := int16((int32(leftSample) + int32(rightSample)) / 2)
-
-			monoIdx := i * bytesPerSample
-			monoData[monoIdx] = byte(avg)
-			monoData[monoIdx+1] = byte(avg >> 8)
-		}
-	}
-
-	fmt.Printf("[Server] Converted stereo to mono (%d samples)\n", sampleCount)
-	return monoData
-}
-
-// resampleAudio resamples audio to target sample rate
-func resampleAudio(data []byte, sourceRate int) ([]byte, error) {
-	fmt.Printf("[Server] Resampling from %dHz to %dHz...\n", sourceRate, targetSampleRate)
-
-	resampled, err := media2.ResamplePCM(data, sourceRate, targetSampleRate)
-	if err != nil {
-		return nil, fmt.Errorf("resampling failed: %w", err)
-	}
-
-	fmt.Printf("[Server] Resampled to %dHz (%d bytes)\n", targetSampleRate, len(resampled))
-	return resampled, nil
+	return src, nil
 }
 
 // waitForConnection waits for the WebRTC connection to be established
 func waitForConnection(transport *rtcmedia.WebRTCTransport) error {
-	for i := 0; i < maxConnectionRetries; i++ {
-		state := transport.GetConnectionState()
-		if state == webrtc.PeerConnectionStateConnected {
-			return nil
-		}
-
-		if i%connectionStateLogInterval == 0 {
-			fmt.Printf("[Server] Waiting for connection... (state: %s)\n", state.String())
-		}
-
-		time.Sleep(connectionRetryDelay)
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	logger.Info("[Server] Waiting for connection", zap.String("state", transport.GetConnectionState().String()))
+	if err := transport.WaitUntilConnected(ctx); err != nil {
+		return fmt.Errorf("connection not established (state: %s): %w", transport.GetConnectionState().String(), err)
 	}
-
-	return fmt.Errorf("connection timeout after %d retries", maxConnectionRetries)
+	return nil
 }
 
 // receiveAudioFromClient receives and plays audio from the client
-func receiveAudioFromClient(client *Client) error {
+func receiveAudioFromClient(transport *rtcmedia.WebRTCTransport) error {
 	// Wait for connection to be fully established
-	if err := waitForConnection(client.transport); err != nil {
+	if err := waitForConnection(transport); err != nil {
 		return fmt.Errorf("connection not established: %w", err)
 	}
 
 	// Get remote track (should be available since OnTrack just fired)
-	rxTrack := client.transport.GetRxTrack()
+	rxTrack := transport.GetRxTrack()
 	if rxTrack == nil {
 		// Wait a bit for track to be set
 		for i := 0; i < 50; i++ {
-			rxTrack = client.transport.GetRxTrack()
+			rxTrack = transport.GetRxTrack()
 			if rxTrack != nil {
 				break
 			}
@@ -516,7 +241,7 @@ func receiveAudioFromClient(client *Client) error {
 		return fmt.Errorf("rxTrack not available")
 	}
 
-	fmt.Println("[Server] Starting to receive audio from client...")
+	logger.Info("[Server] Starting to receive audio from client")
 
 	// Setup audio playback
 	streamPlayer, decodeFunc, err := setupAudioPlayback()
@@ -526,7 +251,7 @@ func receiveAudioFromClient(client *Client) error {
 	defer streamPlayer.Close()
 
 	codec := rxTrack.Codec()
-	fmt.Printf("[Server] Received track: %s, %dHz\n", codec.MimeType, codec.ClockRate)
+	logger.Info("[Server] Received track", zap.String("codec", codec.MimeType), zap.Uint32("clockRate", codec.ClockRate))
 
 	packetCount := 0
 	for {
@@ -542,7 +267,7 @@ func receiveAudioFromClient(client *Client) error {
 
 		packetCount++
 		if packetCount%packetLogInterval == 0 {
-			fmt.Printf("[Server] Received and played %d RTP packets from client\n", packetCount)
+			logger.Debug("[Server] Received and played RTP packets", zap.Int("packets", packetCount))
 		}
 	}
 }
@@ -565,26 +290,14 @@ func setupAudioPlayback() (*devices.StreamAudioPlayer, media2.EncoderFunc, error
 		return nil, nil, fmt.Errorf("failed to start playback: %w", err)
 	}
 
-	fmt.Printf("[Server] Audio playback started: %dHz, %d channel(s)\n",
-		targetSampleRate, audioChannels)
+	logger.Info("[Server] Audio playback started",
+		zap.Int("sampleRate", targetSampleRate),
+		zap.Int("channels", audioChannels))
 
 	// Create PCMA decoder
-	decodeFunc, err := encoder.CreateDecode(
-		media2.CodecConfig{
-			Codec:         "pcma",
-			SampleRate:    targetSampleRate,
-			Channels:      audioChannels,
-			BitDepth:      8,
-			FrameDuration: "20ms",
-		},
-		media2.CodecConfig{
-			Codec:         "pcm",
-			SampleRate:    targetSampleRate,
-			Channels:      audioChannels,
-			BitDepth:      audioBitDepth,
-			FrameDuration: "20ms",
-		},
-	)
+	decodeFunc, err := media2.NewPipeline().
+		Decode(media2.CodecConfig{Codec: "pcma", SampleRate: targetSampleRate, Channels: audioChannels, FrameDuration: "20ms"}).
+		Build()
 	if err != nil {
 		streamPlayer.Close()
 		return nil, nil, fmt.Errorf("failed to create decoder: %w", err)
@@ -610,7 +323,7 @@ func processAudioPacket(
 	decodedPackets, err := decodeFunc(audioPacket)
 	if err != nil {
 		if packetCount%packetLogInterval == 0 {
-			fmt.Printf("[Server] Error decoding frame %d: %v\n", packetCount, err)
+			logger.Warn("[Server] Error decoding frame", zap.Int("packet", packetCount), zap.Error(err))
 		}
 		return err
 	}
@@ -620,8 +333,8 @@ func processAudioPacket(
 	if len(allPCMData) > 0 {
 		if err := streamPlayer.Write(allPCMData); err != nil {
 			// Buffer full is not critical, only log other errors
-			if packetCount%packetLogInterval == 0 && err.Error() != "音频缓冲区已满" {
-				fmt.Printf("[Server] Error writing to player: %v\n", err)
+			if packetCount%packetLogInterval == 0 && !errors.Is(err, devices.ErrBufferFull) {
+				logger.Warn("[Server] Error writing to player", zap.Error(err))
 			}
 		}
 	}
@@ -647,8 +360,7 @@ func collectPCMData(decodedPackets []media2.MediaPacket, packetCount int) []byte
 		// Validate PCM data (should be 16-bit, so length must be even)
 		if len(af.Payload)%2 != 0 {
 			if packetCount <= warningLogLimit {
-				fmt.Printf("[Server] Warning: Odd PCM length at packet %d: %d bytes\n",
-					packetCount, len(af.Payload))
+				logger.Warn("[Server] Odd PCM length", zap.Int("packet", packetCount), zap.Int("bytes", len(af.Payload)))
 			}
 			continue
 		}
@@ -660,16 +372,29 @@ func collectPCMData(decodedPackets []media2.MediaPacket, packetCount int) []byte
 }
 
 func main() {
+	// Initialize logger
+	logCfg := &logger.LogConfig{
+		Level:      "info",
+		Filename:   "logs/example3-server.log",
+		MaxSize:    100,
+		MaxAge:     7,
+		MaxBackups: 3,
+	}
+	if err := logger.Init(logCfg, "dev"); err != nil {
+		log.Fatalf("[Server] Failed to initialize logger: %v", err)
+	}
+	defer logger.Sync()
+
 	// Set Gin to release mode
 	gin.SetMode(gin.ReleaseMode)
 
 	// Create router
 	router := gin.Default()
-	router.GET(wsPath, websocketHandler)
+	router.GET(wsPath, gin.WrapH(newSignalingServer()))
 
 	// Start server
-	fmt.Printf("[Server] Starting server on %s\n", serverPort)
+	logger.Info("[Server] Starting server", zap.String("addr", serverPort))
 	if err := router.Run(serverPort); err != nil {
-		log.Fatalf("[Server] Failed to start server: %v", err)
+		logger.Fatal("[Server] Failed to start server", zap.Error(err))
 	}
 }
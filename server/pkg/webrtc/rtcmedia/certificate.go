@@ -0,0 +1,183 @@
+package rtcmedia
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// DefaultCertificateValidity LoadOrCreateCertificate 生成的自签名证书有效期
+const DefaultCertificateValidity = 365 * 24 * time.Hour
+
+// ErrFingerprintMismatch 远端 SDP 中的 DTLS 指纹不在 RemoteFingerprints 白名单中
+var ErrFingerprintMismatch = errors.New("remote DTLS fingerprint not pinned")
+
+// certificateMu 防止多个会话同时生成并写入同一对证书文件
+var certificateMu sync.Mutex
+
+// LoadCertificate 从 PEM 文件加载 DTLS 证书与私钥（ECDSA 或 RSA）
+func LoadCertificate(certFile, keyFile string) (*webrtc.Certificate, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load DTLS certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse DTLS certificate: %w", err)
+	}
+	cert := webrtc.CertificateFromX509(pair.PrivateKey, leaf)
+	return &cert, nil
+}
+
+// LoadOrCreateCertificate 加载证书，文件不存在时生成 ECDSA P-256 自签名证书并写入，
+// 之后每次启动使用同一证书，指纹保持不变
+func LoadOrCreateCertificate(certFile, keyFile string) (*webrtc.Certificate, error) {
+	certificateMu.Lock()
+	defer certificateMu.Unlock()
+
+	if _, err := os.Stat(certFile); err == nil {
+		return LoadCertificate(certFile, keyFile)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "lingecho-webrtc"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(DefaultCertificateValidity),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range []string{filepath.Dir(certFile), filepath.Dir(keyFile)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return nil, err
+	}
+	return LoadCertificate(certFile, keyFile)
+}
+
+// FormatFingerprint 按 SDP a=fingerprint 的格式输出，如 "sha-256 AB:CD:..."
+func FormatFingerprint(fp webrtc.DTLSFingerprint) string {
+	return fp.Algorithm + " " + strings.ToUpper(fp.Value)
+}
+
+// CertificateFingerprints 返回证书的 DTLS 指纹，与 SDP 中 a=fingerprint 的值一致
+func CertificateFingerprints(cert *webrtc.Certificate) ([]string, error) {
+	fps, err := cert.GetFingerprints()
+	if err != nil {
+		return nil, err
+	}
+	fingerprints := make([]string, 0, len(fps))
+	for _, fp := range fps {
+		fingerprints = append(fingerprints, FormatFingerprint(fp))
+	}
+	return fingerprints, nil
+}
+
+// certificate 返回 WebRTCOption 中配置的证书，未配置时返回 nil（由 pion 生成临时证书）
+func (wts *WebRTCOption) certificate() (*webrtc.Certificate, error) {
+	if wts.Certificate != nil {
+		return wts.Certificate, nil
+	}
+	if wts.CertFile == "" {
+		return nil, nil
+	}
+	return LoadOrCreateCertificate(wts.CertFile, wts.KeyFile)
+}
+
+// LocalFingerprints 返回本端 DTLS 证书指纹（配置的证书或 pion 生成的临时证书），
+// 客户端可通过带外渠道比对 SDP 中的指纹
+func (wts *WebRTCTransport) LocalFingerprints() ([]string, error) {
+	wts.mu.RLock()
+	pc := wts.peerConnection
+	wts.mu.RUnlock()
+	if pc == nil {
+		return nil, errors.New("peer connection not created")
+	}
+
+	params, err := pc.SCTP().Transport().GetLocalParameters()
+	if err != nil {
+		return nil, err
+	}
+	fingerprints := make([]string, 0, len(params.Fingerprints))
+	for _, fp := range params.Fingerprints {
+		fingerprints = append(fingerprints, FormatFingerprint(fp))
+	}
+	return fingerprints, nil
+}
+
+// verifyRemoteFingerprint 检查远端 SDP 的 DTLS 指纹是否都在白名单中，白名单为空时不校验
+// 握手时 pion 接受与任一 SDP 指纹匹配的证书，所以每个指纹都必须是固定的，不能只匹配其中一个
+func (wts *WebRTCTransport) verifyRemoteFingerprint(sdp string) error {
+	if len(wts.opt.RemoteFingerprints) == 0 {
+		return nil
+	}
+	remote := sdpFingerprints(sdp)
+	if len(remote) == 0 {
+		return fmt.Errorf("%w: no fingerprint in SDP", ErrFingerprintMismatch)
+	}
+	for _, fp := range remote {
+		if !fingerprintPinned(wts.opt.RemoteFingerprints, fp) {
+			return fmt.Errorf("%w: %s", ErrFingerprintMismatch, fp)
+		}
+	}
+	return nil
+}
+
+func fingerprintPinned(pinned []string, fp string) bool {
+	for _, p := range pinned {
+		if strings.EqualFold(strings.TrimSpace(p), fp) {
+			return true
+		}
+	}
+	return false
+}
+
+// sdpFingerprints 提取 SDP 中所有 a=fingerprint 的值（会话级与媒体级）
+func sdpFingerprints(sdp string) []string {
+	var fingerprints []string
+	for _, line := range strings.Split(sdp, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "a=fingerprint:") {
+			fingerprints = append(fingerprints, strings.TrimPrefix(line, "a=fingerprint:"))
+		}
+	}
+	return fingerprints
+}
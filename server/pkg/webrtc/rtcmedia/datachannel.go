@@ -0,0 +1,106 @@
+package rtcmedia
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/zap"
+)
+
+// DataMessageType DataChannel 消息类型
+type DataMessageType string
+
+const (
+	DataMessageTranscript DataMessageType = "transcript" // ASR 识别结果（服务器 → 客户端），Final 区分中间/最终结果
+	DataMessageLLMDelta   DataMessageType = "llm_delta"  // LLM 流式输出片段（服务器 → 客户端），Final 表示回答结束
+	DataMessageTTSStart   DataMessageType = "tts_start"  // 开始播放 TTS（服务器 → 客户端），Text 为要合成的文本
+	DataMessageTTSEnd     DataMessageType = "tts_end"    // TTS 播放结束或被打断（服务器 → 客户端）
+	DataMessageInterrupt  DataMessageType = "interrupt"  // 打断：服务器检测到插话时通知客户端清空播放缓冲，客户端发送则要求停止说话
+)
+
+// DataMessage DataChannel 上传输的 JSON 消息
+type DataMessage struct {
+	Type      DataMessageType `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	Final     bool            `json:"final,omitempty"`
+	Timestamp int64           `json:"timestamp"` // 毫秒
+}
+
+// ErrDataChannelNotOpen DataChannel 尚未建立或已关闭
+var ErrDataChannelNotOpen = errors.New("data channel not open")
+
+// CreateDataChannel 创建控制消息 DataChannel，需由 offer 方在 CreateOffer 之前调用（SDP 中才会包含 m=application）
+// answer 方无需调用，远端创建的 DataChannel 会在 NewPeerConnection 注册的 OnDataChannel 中自动接管
+func (wts *WebRTCTransport) CreateDataChannel() error {
+	wts.mu.Lock()
+	defer wts.mu.Unlock()
+	if wts.peerConnection == nil {
+		return errors.New("peer connection is nil")
+	}
+	dc, err := wts.peerConnection.CreateDataChannel(constants.DataChannelLabel, nil)
+	if err != nil {
+		return err
+	}
+	wts.attachDataChannel(dc)
+	return nil
+}
+
+// attachDataChannel 保存 DataChannel 并转发收到的消息
+func (wts *WebRTCTransport) attachDataChannel(dc *webrtc.DataChannel) {
+	wts.dataMu.Lock()
+	wts.dataChannel = dc
+	wts.dataMu.Unlock()
+
+	dc.OnOpen(func() {
+		logger.Info("webrtc: DataChannel opened", zap.String("label", dc.Label()))
+	})
+	dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		var message DataMessage
+		if err := json.Unmarshal(msg.Data, &message); err != nil {
+			logger.Warn("webrtc: invalid DataChannel message", zap.Error(err))
+			return
+		}
+		wts.dataMu.Lock()
+		handler := wts.onDataMessage
+		wts.dataMu.Unlock()
+		if handler != nil {
+			handler(message)
+		}
+	})
+}
+
+// OnDataMessage 设置 DataChannel 消息回调
+func (wts *WebRTCTransport) OnDataMessage(f func(msg DataMessage)) {
+	wts.dataMu.Lock()
+	defer wts.dataMu.Unlock()
+	wts.onDataMessage = f
+}
+
+// DataChannelOpen DataChannel 是否可以发送消息
+func (wts *WebRTCTransport) DataChannelOpen() bool {
+	wts.dataMu.Lock()
+	defer wts.dataMu.Unlock()
+	return wts.dataChannel != nil && wts.dataChannel.ReadyState() == webrtc.DataChannelStateOpen
+}
+
+// SendDataMessage 通过 DataChannel 发送消息，Timestamp 为空时自动填充
+func (wts *WebRTCTransport) SendDataMessage(msg DataMessage) error {
+	wts.dataMu.Lock()
+	dc := wts.dataChannel
+	wts.dataMu.Unlock()
+	if dc == nil || dc.ReadyState() != webrtc.DataChannelStateOpen {
+		return ErrDataChannelNotOpen
+	}
+	if msg.Timestamp == 0 {
+		msg.Timestamp = time.Now().UnixMilli()
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return dc.SendText(string(data))
+}
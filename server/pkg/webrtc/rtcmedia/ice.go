@@ -0,0 +1,132 @@
+package rtcmedia
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// DefaultTURNCredentialTTL TURN 临时凭证默认有效期
+const DefaultTURNCredentialTTL = 24 * time.Hour
+
+// TURNServer TURN 中继服务器配置
+// 双方都在对称 NAT 后面时只有中继候选者能连通，公网部署需要至少配置一个
+type TURNServer struct {
+	URLs       []string `json:"urls"`                 // 如 turn:turn.example.com:3478?transport=udp、turns:turn.example.com:5349
+	Username   string   `json:"username,omitempty"`   // 长期凭证用户名
+	Credential string   `json:"credential,omitempty"` // 长期凭证密码
+	// Secret 设置后按 TURN REST API（coturn use-auth-secret / static-auth-secret）生成临时凭证，忽略 Username/Credential
+	Secret string        `json:"secret,omitempty"`
+	TTL    time.Duration `json:"-"` // 临时凭证有效期，默认 24h
+}
+
+// ICEServer 转换为 pion 的 ICEServer，user 作为临时凭证的用户标识（可为空）
+func (s TURNServer) ICEServer(user string, now time.Time) webrtc.ICEServer {
+	server := webrtc.ICEServer{
+		URLs:           s.URLs,
+		Username:       s.Username,
+		Credential:     s.Credential,
+		CredentialType: webrtc.ICECredentialTypePassword,
+	}
+	if s.Secret != "" {
+		server.Username, server.Credential = TURNRESTCredentials(s.Secret, user, s.TTL, now)
+	}
+	return server
+}
+
+// TURNRESTCredentials 生成 TURN REST API 临时凭证
+// 用户名为 "过期时间戳:user"，密码为 base64(HMAC-SHA1(secret, 用户名))
+func TURNRESTCredentials(secret, user string, ttl time.Duration, now time.Time) (username, password string) {
+	if ttl <= 0 {
+		ttl = DefaultTURNCredentialTTL
+	}
+	username = strconv.FormatInt(now.Add(ttl).Unix(), 10)
+	if user != "" {
+		username += ":" + user
+	}
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(username))
+	return username, base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// GetICEServers 合并 STUN 与 TURN 服务器，TURN 临时凭证在调用时生成
+func (wts *WebRTCOption) GetICEServers() []webrtc.ICEServer {
+	servers := make([]webrtc.ICEServer, 0, len(wts.ICEServers)+len(wts.TURNServers))
+	servers = append(servers, wts.ICEServers...)
+	now := time.Now()
+	for _, turn := range wts.TURNServers {
+		if len(turn.URLs) == 0 {
+			continue
+		}
+		servers = append(servers, turn.ICEServer(wts.TURNUser, now))
+	}
+	return servers
+}
+
+// CandidatePairInfo 当前选中的候选者对，用于排查连接走的是直连还是中继
+type CandidatePairInfo struct {
+	LocalType     string        `json:"localType"` // host / srflx / prflx / relay
+	LocalAddress  string        `json:"localAddress"`
+	RemoteType    string        `json:"remoteType"`
+	RemoteAddress string        `json:"remoteAddress"`
+	Protocol      string        `json:"protocol"` // udp / tcp
+	Relayed       bool          `json:"relayed"`  // 任意一端是中继候选者
+	RTT           time.Duration `json:"rtt"`      // STUN 连通性检查测得的往返时延，未知时为 0
+}
+
+func (p CandidatePairInfo) String() string {
+	return fmt.Sprintf("%s %s <-> %s %s (%s, relayed=%v, rtt=%v)",
+		p.LocalType, p.LocalAddress, p.RemoteType, p.RemoteAddress, p.Protocol, p.Relayed, p.RTT)
+}
+
+// ErrNoSelectedCandidatePair ICE 尚未选出候选者对（未连接或连接已关闭）
+var ErrNoSelectedCandidatePair = errors.New("no selected ICE candidate pair")
+
+// SelectedCandidatePair 返回当前选中的候选者对
+func (wts *WebRTCTransport) SelectedCandidatePair() (*CandidatePairInfo, error) {
+	wts.mu.RLock()
+	pc := wts.peerConnection
+	wts.mu.RUnlock()
+	if pc == nil {
+		return nil, errors.New("peer connection not initialized")
+	}
+
+	var pair *webrtc.ICECandidatePair
+	for _, sender := range pc.GetSenders() {
+		dtls := sender.Transport()
+		if dtls == nil {
+			continue
+		}
+		p, err := dtls.ICETransport().GetSelectedCandidatePair()
+		if err == nil && p != nil {
+			pair = p
+			break
+		}
+	}
+	if pair == nil || pair.Local == nil || pair.Remote == nil {
+		return nil, ErrNoSelectedCandidatePair
+	}
+
+	info := &CandidatePairInfo{
+		LocalType:     pair.Local.Typ.String(),
+		LocalAddress:  fmt.Sprintf("%s:%d", pair.Local.Address, pair.Local.Port),
+		RemoteType:    pair.Remote.Typ.String(),
+		RemoteAddress: fmt.Sprintf("%s:%d", pair.Remote.Address, pair.Remote.Port),
+		Protocol:      pair.Local.Protocol.String(),
+		Relayed:       pair.Local.Typ == webrtc.ICECandidateTypeRelay || pair.Remote.Typ == webrtc.ICECandidateTypeRelay,
+	}
+	for _, s := range pc.GetStats() {
+		if stats, ok := s.(webrtc.ICECandidatePairStats); ok && stats.Nominated &&
+			stats.State == webrtc.StatsICECandidatePairStateSucceeded {
+			info.RTT = time.Duration(stats.CurrentRoundTripTime * float64(time.Second))
+			break
+		}
+	}
+	return info, nil
+}
@@ -10,19 +10,38 @@ import (
 	"sync"
 	"time"
 
+	"github.com/code-100-precent/LingEcho/pkg/logger"
 	media2 "github.com/code-100-precent/LingEcho/pkg/media"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
+	"github.com/pion/interceptor/pkg/stats"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
-	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
 )
 
 // WebRTCOption WebRTC 配置选项
 type WebRTCOption struct {
-	ICEServers []webrtc.ICEServer `json:"iceServers"` // ICE 服务器
-	StreamID   string             `json:"streamId"`   // 流 ID
-	ICETimeout time.Duration      `json:"iceTimeout"` // ICE 超时时间
-	Codec      string             `json:"codec"`      // 编解码器名称
+	ICEServers  []webrtc.ICEServer `json:"iceServers"`  // ICE 服务器（STUN，或已带凭证的 TURN）
+	TURNServers []TURNServer       `json:"turnServers"` // TURN 中继服务器，支持临时凭证
+	TURNUser    string             `json:"turnUser"`    // 生成 TURN 临时凭证时使用的用户标识
+	// ICETransportPolicy 为 relay 时只使用中继候选者（用于验证 TURN 或隐藏客户端 IP）
+	ICETransportPolicy webrtc.ICETransportPolicy `json:"iceTransportPolicy"`
+	StreamID           string                    `json:"streamId"`   // 流 ID
+	ICETimeout         time.Duration             `json:"iceTimeout"` // ICE 超时时间
+	Codec              string                    `json:"codec"`      // 编解码器名称
+	// ICE 重启重试策略（仅在设置 OnRestartOffer 后生效），ReconnectAttempts < 0 关闭自动重启
+	ReconnectAttempts   int           `json:"reconnectAttempts"`
+	ReconnectBackoff    time.Duration `json:"reconnectBackoff"`
+	ReconnectMaxBackoff time.Duration `json:"reconnectMaxBackoff"`
+	// EnableRedundancy 协商音频 NACK 重传与 RFC 2198 RED 冗余，改善移动网络丢包时的断续
+	// Opus 带内 FEC 始终通过 useinbandfec=1 声明，不受此开关影响
+	EnableRedundancy bool `json:"enableRedundancy"`
+	// DTLS 证书：Certificate 优先，其次从 CertFile/KeyFile 加载（不存在时生成并保存），都未设置时每个连接使用临时证书
+	Certificate *webrtc.Certificate `json:"-"`
+	CertFile    string              `json:"certFile"`
+	KeyFile     string              `json:"keyFile"`
+	// RemoteFingerprints 固定对端 DTLS 指纹（如 "sha-256 AB:CD:..."），远端描述中的指纹不在其中时拒绝
+	RemoteFingerprints []string `json:"remoteFingerprints"`
 }
 
 func (wts *WebRTCOption) GetICETimeout() time.Duration {
@@ -33,8 +52,8 @@ func (wts *WebRTCOption) GetICETimeout() time.Duration {
 }
 
 func (wts WebRTCOption) String() string {
-	return fmt.Sprintf("WebRTCOption{ICEServers: %d, StreamID: %s,ICETimeout: %v}",
-		len(wts.ICEServers), wts.StreamID, wts.ICETimeout)
+	return fmt.Sprintf("WebRTCOption{ICEServers: %d, TURNServers: %d, StreamID: %s,ICETimeout: %v}",
+		len(wts.ICEServers), len(wts.TURNServers), wts.StreamID, wts.ICETimeout)
 }
 
 type WebRTCTransport struct {
@@ -50,6 +69,33 @@ type WebRTCTransport struct {
 	AnswerSDP       string                    `json:"answer,omitempty"` // Answer SDP
 	mu              sync.RWMutex              // 读写锁
 	playAudioStop   chan struct{}             // 用于停止播放音频
+
+	// Trickle ICE：设置 onICECandidate 后候选者逐个推送，不再等待收集完成
+	// candidateMu 独立于 mu，因为 CreateOffer/CreateAnswer 持有 mu 等待收集时回调仍需写入
+	candidateMu       sync.Mutex
+	onICECandidate    func(candidate string)
+	pendingCandidates []string // 远端描述设置前收到的候选者
+
+	// ICE 重启：网络抖动导致断开/失败时由 offer 方重启 ICE，新 offer 通过 onRestartOffer 交给信令层发送
+	reconnectMu    sync.Mutex
+	onRestartOffer func(offer string, candidates []string) error
+	onReconnected  func()
+	interrupted    bool // 连接曾断开/失败，恢复时回调 onReconnected
+	restarting     bool // ICE 重启循环正在运行
+	closed         bool
+
+	// 连接状态事件：stateChanged 在每次状态变化（及 Close）时关闭并替换，用于唤醒所有等待者
+	stateMu       sync.Mutex
+	stateHandlers []func(webrtc.PeerConnectionState)
+	stateChanged  chan struct{}
+
+	// DataChannel：转写文本、LLM 增量输出与打断等控制消息
+	dataMu        sync.Mutex
+	dataChannel   *webrtc.DataChannel
+	onDataMessage func(msg DataMessage)
+
+	// RTP 统计（丢包、抖动、RTT），由 stats 拦截器按 SSRC 记录
+	statsGetter stats.Getter
 }
 
 // NewWebRTCTransport 创建新的 WebRTC 传输
@@ -63,23 +109,68 @@ func NewWebRTCTransport(opt WebRTCOption) *WebRTCTransport {
 	if opt.Codec == "" {
 		opt.Codec = constants.CodecOPUS
 	}
+	if opt.ReconnectAttempts == 0 {
+		opt.ReconnectAttempts = constants.DefaultReconnectAttempts
+	}
+	if opt.ReconnectBackoff <= 0 {
+		opt.ReconnectBackoff = constants.DefaultReconnectBackoff
+	}
+	if opt.ReconnectMaxBackoff < opt.ReconnectBackoff {
+		opt.ReconnectMaxBackoff = constants.DefaultReconnectMaxBackoff
+		if opt.ReconnectMaxBackoff < opt.ReconnectBackoff {
+			opt.ReconnectMaxBackoff = opt.ReconnectBackoff
+		}
+	}
 
 	return &WebRTCTransport{
 		opt: opt,
 		config: webrtc.Configuration{
-			ICEServers: opt.ICEServers,
+			ICEServers:         opt.GetICEServers(),
+			ICETransportPolicy: opt.ICETransportPolicy,
 		},
 		connectionState: webrtc.PeerConnectionStateNew,
-		codec: media2.CodecConfig{
-			Codec:         strings.ToLower(opt.Codec),
-			SampleRate:    8000,
-			Channels:      1,
-			BitDepth:      8,
-			FrameDuration: "20ms",
-		},
+		codec:           CodecConfigFor(opt.Codec),
+		stateChanged:    make(chan struct{}),
 	}
 }
 
+// CodecConfigFor 返回编解码器在 RTP 上传输时的音频参数，用作 media.AudioPipeline 的 Decode/Encode 配置
+func CodecConfigFor(codec string) media2.CodecConfig {
+	config := media2.CodecConfig{
+		Codec:         strings.ToLower(codec),
+		SampleRate:    8000,
+		Channels:      1,
+		BitDepth:      8,
+		FrameDuration: "20ms",
+	}
+	switch config.Codec {
+	case constants.CodecOPUS:
+		// OPUS 以 48kHz 宽带编码，解码输出 16-bit PCM
+		config.SampleRate = 48000
+		config.BitDepth = 16
+	case constants.CodecG722:
+		// G.722 以 16kHz 采样、每样本 4 bit（64kbit/s），RTP 时钟仍为 8000
+		config.SampleRate = 16000
+		config.BitDepth = 4
+	}
+	return config
+}
+
+// CodecForMimeType 将轨道的 MIME 类型映射为编解码器名称，RED 返回其主编码 Opus
+func CodecForMimeType(mimeType string) (string, error) {
+	switch {
+	case strings.EqualFold(mimeType, webrtc.MimeTypePCMA):
+		return constants.CodecPCMA, nil
+	case strings.EqualFold(mimeType, webrtc.MimeTypePCMU):
+		return constants.CodecPCMU, nil
+	case strings.EqualFold(mimeType, webrtc.MimeTypeOpus), IsRED(mimeType):
+		return constants.CodecOPUS, nil
+	case strings.EqualFold(mimeType, webrtc.MimeTypeG722):
+		return constants.CodecG722, nil
+	}
+	return "", fmt.Errorf("unsupported codec: %s", mimeType)
+}
+
 // getCodecParameters 根据编解码器名称获取参数
 func (wts *WebRTCTransport) getCodecParameters() webrtc.RTPCodecParameters {
 	switch wts.opt.Codec {
@@ -94,10 +185,7 @@ func (wts *WebRTCTransport) getCodecParameters() webrtc.RTPCodecParameters {
 			PayloadType:        9,
 		}
 	case constants.CodecOPUS:
-		return webrtc.RTPCodecParameters{
-			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000},
-			PayloadType:        111,
-		}
+		return opusCodecParameters
 	default: // pcmu
 		return webrtc.RTPCodecParameters{
 			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypePCMU, ClockRate: 8000},
@@ -106,6 +194,17 @@ func (wts *WebRTCTransport) getCodecParameters() webrtc.RTPCodecParameters {
 	}
 }
 
+// opusCodecParameters OPUS 在 SDP 中必须声明为 opus/48000/2（RFC 7587），浏览器才能协商成功
+var opusCodecParameters = webrtc.RTPCodecParameters{
+	RTPCodecCapability: webrtc.RTPCodecCapability{
+		MimeType:    webrtc.MimeTypeOpus,
+		ClockRate:   48000,
+		Channels:    2,
+		SDPFmtpLine: "minptime=10;useinbandfec=1",
+	},
+	PayloadType: 111,
+}
+
 // GetMediaEngine 获取媒体引擎配置
 func GetMediaEngine() *webrtc.MediaEngine {
 	m := &webrtc.MediaEngine{}
@@ -117,10 +216,7 @@ func GetMediaEngine() *webrtc.MediaEngine {
 	}, webrtc.RTPCodecTypeAudio)
 
 	// 注册 Opus
-	m.RegisterCodec(webrtc.RTPCodecParameters{
-		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000},
-		PayloadType:        111,
-	}, webrtc.RTPCodecTypeAudio)
+	m.RegisterCodec(opusCodecParameters, webrtc.RTPCodecTypeAudio)
 
 	// 注册 PCMU (G.711 μ-law)
 	m.RegisterCodec(webrtc.RTPCodecParameters{
@@ -145,27 +241,59 @@ func GetMediaEngine() *webrtc.MediaEngine {
 func (wts *WebRTCTransport) NewPeerConnection() {
 	wts.mu.Lock()
 	defer wts.mu.Unlock()
-	api := webrtc.NewAPI(webrtc.WithMediaEngine(GetMediaEngine()))
+	cert, err := wts.opt.certificate()
+	if err != nil {
+		logger.Error("webrtc: DTLS certificate", zap.Error(err))
+		return
+	}
+	if cert != nil {
+		wts.config.Certificates = []webrtc.Certificate{*cert}
+	}
+	mediaEngine := GetMediaEngine()
+	var statsGetter stats.Getter
+	registry, err := newInterceptorRegistry(func(getter stats.Getter) { statsGetter = getter })
+	if err != nil {
+		logger.Error("webrtc: interceptor registry", zap.Error(err))
+		return
+	}
+	if wts.opt.EnableRedundancy {
+		if err := configureRedundancy(mediaEngine, registry); err != nil {
+			logger.Error("webrtc: configure redundancy", zap.Error(err))
+			return
+		}
+	}
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(mediaEngine), webrtc.WithInterceptorRegistry(registry))
 	connection, err := api.NewPeerConnection(wts.config)
 	if err != nil {
-		logrus.WithField("transport", wts).WithError(err).Error("webrtc: NewPeerConnection")
+		logger.Error("webrtc: NewPeerConnection", zap.Error(err))
 		return
 	}
 	wts.peerConnection = connection
+	wts.statsGetter = statsGetter
 
-	// 设置 ICE candidate 回调 收集 ICE 候选者并存储到 wts.Candidates
+	// 设置 ICE candidate 回调 收集 ICE 候选者并存储到 wts.Candidates，Trickle 模式下同时推送给信令层
 	wts.peerConnection.OnICECandidate(func(i *webrtc.ICECandidate) {
-		if i != nil {
-			wts.Candidates = append(wts.Candidates, i.ToJSON())
-			logrus.WithField("candidate", i.ToJSON().Candidate).Debug("ICE candidate generated")
+		if i == nil {
+			return
+		}
+		candidate := i.ToJSON()
+		wts.candidateMu.Lock()
+		wts.Candidates = append(wts.Candidates, candidate)
+		handler := wts.onICECandidate
+		wts.candidateMu.Unlock()
+		logger.Debug("webrtc: ICE candidate generated", zap.String("candidate", candidate.Candidate))
+		if handler != nil {
+			handler(candidate.Candidate)
 		}
 	})
 
 	// 连接状态变化 监控连接状态变化，处理连接建立和断开
 	wts.peerConnection.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
-		logrus.WithField("state", state.String()).Info("Connection state changed")
+		logger.Info("webrtc: connection state changed", zap.String("state", state.String()))
 		if state == webrtc.PeerConnectionStateConnected {
-			fmt.Println("Connected")
+			if pair, err := wts.SelectedCandidatePair(); err == nil {
+				logger.Info("webrtc: ICE selected candidate pair", zap.String("pair", pair.String()))
+			}
 		} else if state == webrtc.PeerConnectionStateDisconnected ||
 			state == webrtc.PeerConnectionStateFailed ||
 			state == webrtc.PeerConnectionStateClosed {
@@ -175,26 +303,31 @@ func (wts *WebRTCTransport) NewPeerConnection() {
 				wts.playAudioStop = nil
 			}
 		}
+		wts.handleStateForReconnect(state)
+		wts.notifyStateChange(state)
+	})
+
+	// 远端创建的 DataChannel（answer 方）
+	wts.peerConnection.OnDataChannel(func(dc *webrtc.DataChannel) {
+		if dc.Label() != constants.DataChannelLabel {
+			logger.Warn("webrtc: ignoring unknown DataChannel", zap.String("label", dc.Label()))
+			return
+		}
+		wts.attachDataChannel(dc)
 	})
 
 	// 接收远程音频轨道 处理接收到的远程音轨，保存到 wts.rxTrack
 	wts.peerConnection.OnTrack(func(remoteTrack *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
 		// 先打印日志，确保能看到触发
-		fmt.Printf("[WebRTC] ===== OnTrack callback FIRED! =====\n")
-		fmt.Printf("[WebRTC] OnTrack: codec=%s, ssrc=%d, streamID=%s, kind=%s\n",
-			remoteTrack.Codec().MimeType, remoteTrack.SSRC(), remoteTrack.StreamID(), remoteTrack.Kind().String())
-
 		wts.mu.Lock()
 		wts.rxTrack = remoteTrack
 		wts.mu.Unlock()
 
-		logrus.WithFields(logrus.Fields{
-			"codec":    remoteTrack.Codec().MimeType,
-			"ssrc":     remoteTrack.SSRC(),
-			"streamID": remoteTrack.StreamID(),
-			"kind":     remoteTrack.Kind().String(),
-		}).Info("Received remote track")
-		fmt.Printf("[WebRTC] OnTrack callback completed: rxTrack saved\n")
+		logger.Info("webrtc: received remote track",
+			zap.String("codec", remoteTrack.Codec().MimeType),
+			zap.Uint32("ssrc", uint32(remoteTrack.SSRC())),
+			zap.String("streamID", remoteTrack.StreamID()),
+			zap.String("kind", remoteTrack.Kind().String()))
 	})
 
 	// 创建发送轨道 创建发送轨道
@@ -204,14 +337,14 @@ func (wts *WebRTCTransport) NewPeerConnection() {
 		wts.opt.StreamID,
 	)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to create track")
+		logger.Error("webrtc: failed to create track", zap.Error(err))
 		return
 	}
 
 	// 添加发送轨道
 	_, err = wts.peerConnection.AddTrack(wts.txTrack)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to add track")
+		logger.Error("webrtc: failed to add track", zap.Error(err))
 		return
 	}
 }
@@ -220,13 +353,64 @@ func (wts *WebRTCTransport) Codec() media2.CodecConfig {
 	return wts.codec
 }
 
+// OnICECandidate 启用 Trickle ICE：本地候选者生成后立即回调，CreateOffer/CreateAnswer 不再等待收集完成
+// 需在 CreateOffer/CreateAnswer 之前调用，回调中通过信令发送 "candidate" 消息
+func (wts *WebRTCTransport) OnICECandidate(f func(candidate string)) {
+	wts.candidateMu.Lock()
+	defer wts.candidateMu.Unlock()
+	wts.onICECandidate = f
+}
+
+// trickle 是否启用了 Trickle ICE
+func (wts *WebRTCTransport) trickle() bool {
+	wts.candidateMu.Lock()
+	defer wts.candidateMu.Unlock()
+	return wts.onICECandidate != nil
+}
+
+// localCandidates 返回已收集到的本地候选者
+func (wts *WebRTCTransport) localCandidates() []string {
+	wts.candidateMu.Lock()
+	defer wts.candidateMu.Unlock()
+	candidates := make([]string, 0, len(wts.Candidates))
+	for _, c := range wts.Candidates {
+		candidates = append(candidates, c.Candidate)
+	}
+	return candidates
+}
+
+// resetLocalCandidates 清空已收集的本地候选者，在 SetLocalDescription 触发新一轮收集前调用
+func (wts *WebRTCTransport) resetLocalCandidates() {
+	wts.candidateMu.Lock()
+	defer wts.candidateMu.Unlock()
+	wts.Candidates = nil
+}
+
+// waitForCandidates 非 Trickle 模式下等待 ICE 收集完成并返回全部候选者
+func (wts *WebRTCTransport) waitForCandidates() ([]string, error) {
+	if wts.trickle() {
+		return nil, nil
+	}
+
+	gatherComplete := webrtc.GatheringCompletePromise(wts.peerConnection)
+	select {
+	case <-time.After(wts.opt.ICETimeout):
+		return nil, fmt.Errorf("ICE gathering timeout")
+	case <-gatherComplete:
+	}
+
+	candidates := wts.localCandidates()
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no ICE candidates generated")
+	}
+	return candidates, nil
+}
+
 // SetRemoteDescription 设置远程描述
 // 支持两种格式：
 // 1. JSON 格式的 SessionDescription: {"type":"offer","sdp":"v=0\r\n..."}
 // 2. 纯 SDP 字符串: "v=0\r\n..."
 func (wts *WebRTCTransport) SetRemoteDescription(sdp string) error {
-	fmt.Printf("[WebRTC] SetRemoteDescription called, OnTrack should fire if SDP contains media tracks\n")
-
 	var sessionDescription webrtc.SessionDescription
 
 	// 尝试解析为 JSON 格式
@@ -234,7 +418,7 @@ func (wts *WebRTCTransport) SetRemoteDescription(sdp string) error {
 	if err != nil {
 		// 如果 JSON 解析失败，假设是纯 SDP 字符串
 		// 创建一个 SessionDescription，类型默认为 "offer"
-		fmt.Printf("[WebRTC] SDP is not JSON format, treating as plain SDP string\n")
+		logger.Debug("webrtc: SDP is not JSON, treating as plain SDP string")
 		sessionDescription = webrtc.SessionDescription{
 			Type: webrtc.SDPTypeOffer,
 			SDP:  sdp,
@@ -245,16 +429,25 @@ func (wts *WebRTCTransport) SetRemoteDescription(sdp string) error {
 	if sessionDescription.SDP != "" {
 		// 检查 SDP 中是否包含 "m=audio"（音频媒体行）
 		if strings.Contains(sessionDescription.SDP, "m=audio") {
-			fmt.Printf("[WebRTC] ✓ SDP contains audio media line (m=audio), OnTrack should fire\n")
+			logger.Debug("webrtc: remote SDP contains audio media line")
 		} else {
-			fmt.Printf("[WebRTC] ✗ WARNING: SDP does NOT contain audio media line, OnTrack will NOT fire\n")
+			logger.Warn("webrtc: remote SDP has no audio media line, OnTrack will not fire")
 		}
 		// 打印 SDP 的前 300 个字符用于调试
 		sdpPreview := sessionDescription.SDP
 		if len(sdpPreview) > 300 {
 			sdpPreview = sdpPreview[:300] + "..."
 		}
-		fmt.Printf("[WebRTC] SDP preview: %s\n", sdpPreview)
+		logger.Debug("webrtc: remote SDP", zap.String("preview", sdpPreview))
+	}
+
+	if err := wts.verifyRemoteFingerprint(sessionDescription.SDP); err != nil {
+		return err
+	}
+
+	// 远端 ICE 重启的 offer 会让本端在 SetRemoteDescription 中重新收集候选者，之前的候选者作废
+	if sessionDescription.Type == webrtc.SDPTypeOffer {
+		wts.resetLocalCandidates()
 	}
 
 	// 注意：SetRemoteDescription 可能会同步触发 OnTrack 回调
@@ -264,51 +457,53 @@ func (wts *WebRTCTransport) SetRemoteDescription(sdp string) error {
 		return err
 	}
 
-	fmt.Printf("[WebRTC] SetRemoteDescription completed\n")
+	// 添加远端描述到达前缓存的 trickle 候选者
+	wts.candidateMu.Lock()
+	pending := wts.pendingCandidates
+	wts.pendingCandidates = nil
+	wts.candidateMu.Unlock()
+	for _, c := range pending {
+		if err := wts.peerConnection.AddICECandidate(webrtc.ICECandidateInit{Candidate: c}); err != nil {
+			logger.Warn("webrtc: failed to add pending ICE candidate", zap.String("candidate", c), zap.Error(err))
+		}
+	}
 	return nil
 }
 
 func (wts *WebRTCTransport) CreateOffer() (offer string, candidates []string, err error) {
+	return wts.createOffer(nil)
+}
+
+// createOffer 创建 offer，options 为 nil 时使用默认选项
+func (wts *WebRTCTransport) createOffer(options *webrtc.OfferOptions) (offer string, candidates []string, err error) {
 	if wts.peerConnection == nil {
-		logrus.WithError(err).Error("peer connection is nil")
+		logger.Error("webrtc: peer connection is nil")
 		return "", nil, errors.New("peer connection is nil")
 	}
 	wts.mu.Lock()
 	defer wts.mu.Unlock()
 
-	// 创建 offer
-	offerSDP, err := wts.peerConnection.CreateOffer(nil)
+	// 创建 offer（ICE 重启时 pion 在这里就开始重新收集候选者，所以先清空旧的）
+	wts.resetLocalCandidates()
+	offerSDP, err := wts.peerConnection.CreateOffer(options)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to create offer")
+		logger.Error("webrtc: failed to create offer", zap.Error(err))
 		return
 	}
 
 	// 设置本地描述
 	err = wts.peerConnection.SetLocalDescription(offerSDP)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to set local description")
+		logger.Error("webrtc: failed to set local description", zap.Error(err))
 		return
 	}
 
-	// 等待 ICE gathering 完成 等待所有 ICE 候选者收集完毕
-	gatherComplete := webrtc.GatheringCompletePromise(wts.peerConnection)
-	select {
-	case <-time.After(wts.opt.ICETimeout):
-		err = fmt.Errorf("ICE gathering timeout")
-		return
-	case <-gatherComplete:
-	}
-
-	if len(wts.Candidates) == 0 {
-		err = fmt.Errorf("no ICE candidates generated")
+	// 等待所有 ICE 候选者收集完毕（Trickle 模式下候选者通过 OnICECandidate 推送，直接返回）
+	candidates, err = wts.waitForCandidates()
+	if err != nil {
 		return
 	}
 
-	// 提取 candidate 字符串
-	for _, c := range wts.Candidates {
-		candidates = append(candidates, c.Candidate)
-	}
-
 	// 获取 offer SDP 字符串（不需要 JSON 序列化，直接返回 SDP 字符串）
 	localOfferSDP := wts.peerConnection.LocalDescription()
 	offer = localOfferSDP.SDP
@@ -319,17 +514,16 @@ func (wts *WebRTCTransport) CreateOffer() (offer string, candidates []string, er
 	if len(offer) > 50 {
 		offerPreview = offer[:50] + "..."
 	}
-	logrus.WithFields(logrus.Fields{
-		constants.WebRTCOffer:     offerPreview,
-		constants.WebRTCCandidate: len(candidates),
-	}).Info("Offer generated")
+	logger.Info("webrtc: offer generated",
+		zap.String(constants.WebRTCOffer, offerPreview),
+		zap.Int(constants.WebRTCCandidate, len(candidates)))
 
 	return offer, candidates, nil
 }
 
 func (wts *WebRTCTransport) CreateAnswer(clientCandidates []string) (serverAnswer string, serverCandidates []string, err error) {
 	if wts.peerConnection == nil {
-		logrus.WithError(err).Error("peer connection is nil")
+		logger.Error("webrtc: peer connection is nil")
 		return "", nil, errors.New("peer connection is nil")
 	}
 
@@ -339,44 +533,30 @@ func (wts *WebRTCTransport) CreateAnswer(clientCandidates []string) (serverAnswe
 	// 创建 answer
 	answerSDP, err := wts.peerConnection.CreateAnswer(nil)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to create answer")
+		logger.Error("webrtc: failed to create answer", zap.Error(err))
 		return
 	}
 
 	// 设置本地描述
 	err = wts.peerConnection.SetLocalDescription(answerSDP)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to set local description")
+		logger.Error("webrtc: failed to set local description", zap.Error(err))
 		return
 	}
 
-	// 等待 ICE gathering
-	gatherComplete := webrtc.GatheringCompletePromise(wts.peerConnection)
-	select {
-	case <-time.After(wts.opt.ICETimeout):
-		err = fmt.Errorf("ICE gathering timeout")
-		return
-	case <-gatherComplete:
-	}
-
-	if len(wts.Candidates) == 0 {
-		err = fmt.Errorf("no ICE candidates generated")
+	// 等待 ICE gathering（Trickle 模式下直接返回）
+	serverCandidates, err = wts.waitForCandidates()
+	if err != nil {
 		return
 	}
 
 	// 添加客户端的 ICE candidates
 	for _, c := range clientCandidates {
-		err = wts.peerConnection.AddICECandidate(webrtc.ICECandidateInit{Candidate: c})
-		if err != nil {
-			logrus.WithError(err).WithField("candidate", c).Warn("Failed to add ICE candidate")
+		if err := wts.peerConnection.AddICECandidate(webrtc.ICECandidateInit{Candidate: c}); err != nil {
+			logger.Warn("webrtc: failed to add ICE candidate", zap.String("candidate", c), zap.Error(err))
 		}
 	}
 
-	// 提取服务器的 candidates
-	for _, c := range wts.Candidates {
-		serverCandidates = append(serverCandidates, c.Candidate)
-	}
-
 	// 获取 answer SDP 字符串（不需要 JSON 序列化，直接返回 SDP 字符串）
 	localSDP := wts.peerConnection.LocalDescription()
 	serverAnswer = localSDP.SDP
@@ -387,10 +567,9 @@ func (wts *WebRTCTransport) CreateAnswer(clientCandidates []string) (serverAnswe
 	if len(serverAnswer) > 50 {
 		answerPreview = serverAnswer[:50] + "..."
 	}
-	logrus.WithFields(logrus.Fields{
-		constants.WebRTCAnswer:    answerPreview,
-		constants.WebRTCCandidate: len(serverCandidates),
-	}).Info("Answer generated")
+	logger.Info("webrtc: answer generated",
+		zap.String(constants.WebRTCAnswer, answerPreview),
+		zap.Int(constants.WebRTCCandidate, len(serverCandidates)))
 
 	return serverAnswer, serverCandidates, nil
 }
@@ -398,7 +577,7 @@ func (wts *WebRTCTransport) CreateAnswer(clientCandidates []string) (serverAnswe
 func (wts *WebRTCTransport) SelectPreferredCodec() (*media2.CodecConfig, error) {
 	sdp, err := wts.peerConnection.LocalDescription().Unmarshal()
 	if err != nil {
-		logrus.WithField("transport", wts).WithError(err).Error("webrtc: Failed to unmarshal Local description")
+		logger.Error("webrtc: failed to unmarshal local description", zap.Error(err))
 		return nil, err
 	}
 
@@ -409,11 +588,10 @@ func (wts *WebRTCTransport) SelectPreferredCodec() (*media2.CodecConfig, error)
 				if attr.Key == "rtpmap" {
 					if strings.HasPrefix(attr.Value, m.MediaName.Formats[0]) {
 						vals := strings.Split(attr.Value, " ")[1]
-						codec.Codec = strings.ToLower(strings.Split(vals, "/")[0])
-						codec.SampleRate, _ = strconv.Atoi(strings.Split(vals, "/")[1])
-						codec.Channels = 1
-						codec.BitDepth = 8
-						codec.FrameDuration = "20ms"
+						codec = CodecConfigFor(strings.Split(vals, "/")[0])
+						if codec.Codec != constants.CodecG722 {
+							codec.SampleRate, _ = strconv.Atoi(strings.Split(vals, "/")[1])
+						}
 						return &codec, nil
 					}
 				}
@@ -423,11 +601,21 @@ func (wts *WebRTCTransport) SelectPreferredCodec() (*media2.CodecConfig, error)
 	return nil, fmt.Errorf("webrtc: did not find codec in SDP")
 }
 
-// AddICECandidate 添加 ICE 候选者
+// AddICECandidate 添加 ICE 候选者，远端描述尚未设置时先缓存（trickle 候选者可能先于 offer/answer 到达）
 func (wts *WebRTCTransport) AddICECandidate(candidate string) error {
 	wts.mu.Lock()
 	defer wts.mu.Unlock()
 
+	if wts.peerConnection == nil {
+		return errors.New("peer connection is nil")
+	}
+	wts.candidateMu.Lock()
+	if wts.peerConnection.RemoteDescription() == nil {
+		wts.pendingCandidates = append(wts.pendingCandidates, candidate)
+		wts.candidateMu.Unlock()
+		return nil
+	}
+	wts.candidateMu.Unlock()
 	return wts.peerConnection.AddICECandidate(webrtc.ICECandidateInit{Candidate: candidate})
 }
 
@@ -443,6 +631,62 @@ func (wts *WebRTCTransport) GetConnectionState() webrtc.PeerConnectionState {
 	return wts.peerConnection.ConnectionState()
 }
 
+// ErrConnectionFailed 连接进入 failed/closed 状态，WaitUntilConnected 不会再等到 connected
+var ErrConnectionFailed = errors.New("webrtc connection failed")
+
+// OnConnectionStateChange 注册连接状态回调，可注册多个，回调在独立 goroutine 中按注册顺序执行
+func (wts *WebRTCTransport) OnConnectionStateChange(f func(state webrtc.PeerConnectionState)) {
+	wts.stateMu.Lock()
+	defer wts.stateMu.Unlock()
+	wts.stateHandlers = append(wts.stateHandlers, f)
+}
+
+// notifyStateChange 唤醒 WaitUntilConnected 的等待者并执行已注册的回调
+func (wts *WebRTCTransport) notifyStateChange(state webrtc.PeerConnectionState) {
+	wts.stateMu.Lock()
+	close(wts.stateChanged)
+	wts.stateChanged = make(chan struct{})
+	handlers := wts.stateHandlers
+	wts.stateMu.Unlock()
+
+	for _, f := range handlers {
+		f(state)
+	}
+}
+
+// stateNotify 返回下一次状态变化时关闭的 channel，须在读取状态之前获取，避免错过唤醒
+func (wts *WebRTCTransport) stateNotify() <-chan struct{} {
+	wts.stateMu.Lock()
+	defer wts.stateMu.Unlock()
+	return wts.stateChanged
+}
+
+// WaitUntilConnected 阻塞直到连接建立；连接失败/关闭时返回 ErrConnectionFailed，ctx 结束时返回 ctx.Err()
+func (wts *WebRTCTransport) WaitUntilConnected(ctx context.Context) error {
+	for {
+		changed := wts.stateNotify()
+		wts.reconnectMu.Lock()
+		closed := wts.closed
+		wts.reconnectMu.Unlock()
+		if closed {
+			return fmt.Errorf("%w: transport closed", ErrConnectionFailed)
+		}
+
+		switch state := wts.GetConnectionState(); state {
+		case webrtc.PeerConnectionStateConnected:
+			return nil
+		case webrtc.PeerConnectionStateFailed, webrtc.PeerConnectionStateClosed:
+			return fmt.Errorf("%w: %s", ErrConnectionFailed, state)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
 // GetRxTrack 获取接收轨道 (线程安全)
 func (wts *WebRTCTransport) GetRxTrack() *webrtc.TrackRemote {
 	wts.mu.RLock()
@@ -471,16 +715,14 @@ func (wts *WebRTCTransport) Next(ctx context.Context) (media2.MediaPacket, error
 		case <-time.After(10 * time.Millisecond):
 			//wait for connection established
 		}
-		logrus.WithFields(logrus.Fields{
-			"transport":       wts,
-			"connectionState": wts.connectionState,
-		}).Info("webrtc: connection state is not connected")
+		logger.Info("webrtc: connection state is not connected",
+			zap.String("connectionState", wts.connectionState.String()))
 		return nil, nil
 	}
 
 	rtpPacket, _, err := wts.rxTrack.ReadRTP()
 	if err != nil {
-		logrus.WithField("transport", wts).WithError(err).Error("webrtc: Error reading RTP packet")
+		logger.Error("webrtc: error reading RTP packet", zap.Error(err))
 		return nil, err
 	}
 	return &media2.AudioPacket{
@@ -499,25 +741,37 @@ func (wts *WebRTCTransport) Send(frame media2.MediaPacket) (int, error) {
 	}
 
 	audioFrame := frame.(*media2.AudioPacket)
-	duration := len(audioFrame.Body()) / GetSampleSize(wts.codec.SampleRate, wts.codec.BitDepth, wts.codec.Channels)
+	var duration time.Duration
+	if wts.codec.Codec == constants.CodecOPUS {
+		// OPUS 是压缩编码，无法从字节数推算时长，每个包对应一帧
+		duration, _ = time.ParseDuration(wts.codec.FrameDuration)
+	} else {
+		duration = time.Duration(len(audioFrame.Body())/GetSampleSize(wts.codec.SampleRate, wts.codec.BitDepth, wts.codec.Channels)) * time.Millisecond
+	}
 	sample := media.Sample{
 		Data:     audioFrame.Body(),
-		Duration: time.Duration(duration) * time.Millisecond,
+		Duration: duration,
 	}
 	wts.txTrack.WriteSample(sample)
 	return len(frame.Body()), nil
 }
 
 func (wts *WebRTCTransport) Close() error {
-	if wts.txTrack != nil {
-		wts.txTrack = nil
-	}
-	if wts.rxTrack != nil {
-		wts.rxTrack = nil
-	}
-	if wts.peerConnection != nil {
-		wts.peerConnection.Close()
-		wts.peerConnection = nil
+	wts.reconnectMu.Lock()
+	wts.closed = true
+	wts.reconnectMu.Unlock()
+	defer wts.notifyStateChange(webrtc.PeerConnectionStateClosed)
+
+	wts.mu.Lock()
+	wts.txTrack = nil
+	wts.rxTrack = nil
+	pc := wts.peerConnection
+	wts.peerConnection = nil
+	wts.mu.Unlock()
+
+	// 在锁外关闭，关闭过程中触发的回调可能需要获取 mu
+	if pc != nil {
+		pc.Close()
 	}
 	return nil
 }
@@ -540,12 +794,9 @@ func (wts *WebRTCTransport) OnTrack(f func(*webrtc.TrackRemote, *webrtc.RTPRecei
 			wts.mu.Unlock()
 
 			// Log the received track
-			logrus.WithFields(logrus.Fields{
-				"codec": remoteTrack.Codec().MimeType,
-				"ssrc":  remoteTrack.SSRC(),
-			}).Info("Received remote track")
-			fmt.Printf("[WebRTC] OnTrack callback fired: codec=%s, ssrc=%d\n",
-				remoteTrack.Codec().MimeType, remoteTrack.SSRC())
+			logger.Info("webrtc: received remote track",
+				zap.String("codec", remoteTrack.Codec().MimeType),
+				zap.Uint32("ssrc", uint32(remoteTrack.SSRC())))
 
 			// Call the user-provided callback
 			if f != nil {
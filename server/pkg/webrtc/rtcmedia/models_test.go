@@ -1,9 +1,15 @@
 package rtcmedia
 
 import (
+	"context"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,6 +24,296 @@ func TestNewWebRTCTransport(t *testing.T) {
 	assert.Equal(t, transport.opt.StreamID, constants.DefaultStreamID)
 }
 
+func TestCodecConfigFor(t *testing.T) {
+	opus := CodecConfigFor(constants.CodecOpus)
+	assert.Equal(t, 48000, opus.SampleRate)
+	assert.Equal(t, "20ms", opus.FrameDuration)
+
+	pcma := CodecConfigFor(constants.CodecPCMA)
+	assert.Equal(t, 8000, pcma.SampleRate)
+	assert.Equal(t, 8, pcma.BitDepth)
+
+	// G.722: 16kHz 音频，20ms 为 160 字节
+	g722 := CodecConfigFor(constants.CodecG722)
+	assert.Equal(t, 16000, g722.SampleRate)
+	assert.Equal(t, 8, GetSampleSize(g722.SampleRate, g722.BitDepth, g722.Channels))
+}
+
+func TestOpusOffer(t *testing.T) {
+	transport := NewWebRTCTransport(WebRTCOption{
+		Codec:      constants.CodecOpus,
+		ICETimeout: constants.DefaultICETimeout,
+		StreamID:   constants.DefaultStreamID,
+	})
+	assert.Equal(t, 48000, transport.Codec().SampleRate)
+
+	params := transport.getCodecParameters()
+	assert.Equal(t, uint32(48000), params.ClockRate)
+	assert.Equal(t, uint16(2), params.Channels)
+
+	transport.NewPeerConnection()
+	defer transport.Close()
+	offer, _, err := transport.CreateOffer()
+	assert.NoError(t, err)
+	assert.Contains(t, offer, "opus/48000/2")
+}
+
+func TestRedundancyOffer(t *testing.T) {
+	plain := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecOpus})
+	plain.NewPeerConnection()
+	defer plain.Close()
+	offer, _, err := plain.CreateOffer()
+	assert.NoError(t, err)
+	assert.NotContains(t, offer, "red/48000/2")
+
+	transport := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecOpus, EnableRedundancy: true})
+	transport.NewPeerConnection()
+	defer transport.Close()
+	offer, _, err = transport.CreateOffer()
+	assert.NoError(t, err)
+	assert.Contains(t, offer, "a=rtpmap:63 red/48000/2")
+	assert.Contains(t, offer, "a=fmtp:63 111/111")
+	assert.Contains(t, offer, "a=rtcp-fb:111 nack")
+}
+
+// redPacket 按 RFC 2198 组装 RED 包：redundant 为较早的帧（由旧到新），primary 为当前帧
+func redPacket(seq uint16, ts uint32, frame uint32, redundant [][]byte, primary []byte) *rtp.Packet {
+	var payload []byte
+	for i, block := range redundant {
+		offset := frame * uint32(len(redundant)-i)
+		header := 1<<31 | uint32(111)<<24 | offset<<10 | uint32(len(block))
+		payload = append(payload, byte(header>>24), byte(header>>16), byte(header>>8), byte(header))
+	}
+	payload = append(payload, 111)
+	for _, block := range redundant {
+		payload = append(payload, block...)
+	}
+	payload = append(payload, primary...)
+	return &rtp.Packet{Header: rtp.Header{SequenceNumber: seq, Timestamp: ts, PayloadType: 63}, Payload: payload}
+}
+
+func TestParseRED(t *testing.T) {
+	blocks, err := ParseRED(redPacket(1, 1920, 960, [][]byte{{1, 2}}, []byte{3, 4, 5}).Payload)
+	assert.NoError(t, err)
+	assert.Len(t, blocks, 2)
+	assert.Equal(t, uint32(960), blocks[0].TimestampOffset)
+	assert.Equal(t, []byte{1, 2}, blocks[0].Payload)
+	assert.Equal(t, uint8(111), blocks[1].PayloadType)
+	assert.Equal(t, []byte{3, 4, 5}, blocks[1].Payload)
+
+	// 块长度超出负载
+	_, err = ParseRED([]byte{0xef, 0x00, 0x0f, 0xff, 111})
+	assert.ErrorIs(t, err, ErrInvalidRED)
+	_, err = ParseRED(nil)
+	assert.ErrorIs(t, err, ErrInvalidRED)
+}
+
+func TestREDDepacketizer(t *testing.T) {
+	d := NewREDDepacketizer()
+	const frame = 960
+
+	// 首包只输出主帧
+	frames, err := d.Depacketize(redPacket(1, frame, frame, [][]byte{{0}}, []byte{1}))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{{1}}, frames)
+
+	// 连续包：冗余帧已输出过，只取主帧
+	frames, _ = d.Depacketize(redPacket(2, 2*frame, frame, [][]byte{{1}}, []byte{2}))
+	assert.Equal(t, [][]byte{{2}}, frames)
+
+	// 丢失 seq 3、4，seq 5 携带两份冗余，全部补回
+	frames, _ = d.Depacketize(redPacket(5, 5*frame, frame, [][]byte{{3}, {4}}, []byte{5}))
+	assert.Equal(t, [][]byte{{3}, {4}, {5}}, frames)
+
+	// 迟到的重传包被丢弃
+	frames, _ = d.Depacketize(redPacket(4, 4*frame, frame, [][]byte{{3}}, []byte{4}))
+	assert.Empty(t, frames)
+}
+
+func TestTrickleICE(t *testing.T) {
+	client := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA})
+	server := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA})
+	client.NewPeerConnection()
+	server.NewPeerConnection()
+	defer client.Close()
+	defer server.Close()
+
+	// 候选者在 answer 之前到达服务端时先缓存
+	client.OnICECandidate(func(candidate string) {
+		assert.NoError(t, server.AddICECandidate(candidate))
+	})
+	server.OnICECandidate(func(candidate string) {
+		assert.NoError(t, client.AddICECandidate(candidate))
+	})
+
+	offer, candidates, err := client.CreateOffer()
+	assert.NoError(t, err)
+	assert.Empty(t, candidates)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, server.SetRemoteDescription(offer))
+	answer, serverCandidates, err := server.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, serverCandidates)
+	assert.NoError(t, client.SetRemoteDescription(`{"type":"answer","sdp":`+strconv.Quote(answer)+`}`))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, client.WaitUntilConnected(ctx))
+
+	pair, err := client.SelectedCandidatePair()
+	assert.NoError(t, err)
+	assert.Equal(t, "host", pair.LocalType)
+	assert.False(t, pair.Relayed)
+}
+
+func TestRestartICE(t *testing.T) {
+	client := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA})
+	server := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA})
+	client.NewPeerConnection()
+	server.NewPeerConnection()
+	defer client.Close()
+	defer server.Close()
+
+	// 与信令层一致：offer 交给对端生成 answer，answer 再设置回来
+	negotiate := func(offer string, candidates []string) error {
+		if err := server.SetRemoteDescription(offer); err != nil {
+			return err
+		}
+		answer, _, err := server.CreateAnswer(candidates)
+		if err != nil {
+			return err
+		}
+		return client.SetRemoteDescription(`{"type":"answer","sdp":` + strconv.Quote(answer) + `}`)
+	}
+	waitConnected := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, client.WaitUntilConnected(ctx))
+	}
+
+	offer, candidates, err := client.CreateOffer()
+	assert.NoError(t, err)
+	assert.NoError(t, negotiate(offer, candidates))
+	waitConnected()
+
+	restartOffer, restartCandidates, err := client.RestartICE()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, restartCandidates)
+	assert.NotEqual(t, iceUfrag(offer), iceUfrag(restartOffer))
+	assert.NoError(t, negotiate(restartOffer, restartCandidates))
+	waitConnected()
+}
+
+// iceUfrag 提取 SDP 中的 ice-ufrag
+func iceUfrag(sdp string) string {
+	for _, line := range strings.Split(sdp, "\r\n") {
+		if strings.HasPrefix(line, "a=ice-ufrag:") {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestWaitUntilConnected(t *testing.T) {
+	transport := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA})
+	transport.NewPeerConnection()
+
+	states := make(chan webrtc.PeerConnectionState, 1)
+	transport.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		states <- state
+	})
+
+	// 未协商时一直等待，直到 ctx 超时
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, transport.WaitUntilConnected(ctx), context.DeadlineExceeded)
+
+	// Close 唤醒等待者并通知回调
+	done := make(chan error, 1)
+	go func() {
+		done <- transport.WaitUntilConnected(context.Background())
+	}()
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, transport.Close())
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrConnectionFailed)
+	case <-time.After(time.Second):
+		t.Fatal("WaitUntilConnected not woken by Close")
+	}
+	assert.Equal(t, webrtc.PeerConnectionStateClosed, <-states)
+}
+
+func TestDataChannel(t *testing.T) {
+	client := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA})
+	server := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA})
+	client.NewPeerConnection()
+	server.NewPeerConnection()
+	defer client.Close()
+	defer server.Close()
+
+	received := make(chan DataMessage, 1)
+	server.OnDataMessage(func(msg DataMessage) {
+		received <- msg
+	})
+	assert.ErrorIs(t, client.SendDataMessage(DataMessage{Type: DataMessageInterrupt}), ErrDataChannelNotOpen)
+
+	// DataChannel 由 offer 方在 CreateOffer 之前创建
+	assert.NoError(t, client.CreateDataChannel())
+	offer, candidates, err := client.CreateOffer()
+	assert.NoError(t, err)
+	assert.NoError(t, server.SetRemoteDescription(offer))
+	answer, _, err := server.CreateAnswer(candidates)
+	assert.NoError(t, err)
+	assert.NoError(t, client.SetRemoteDescription(`{"type":"answer","sdp":`+strconv.Quote(answer)+`}`))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, client.WaitUntilConnected(ctx))
+	assert.Eventually(t, client.DataChannelOpen, 5*time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, client.SendDataMessage(DataMessage{Type: DataMessageTranscript, Text: "你好", Final: true}))
+	select {
+	case msg := <-received:
+		assert.Equal(t, DataMessageTranscript, msg.Type)
+		assert.Equal(t, "你好", msg.Text)
+		assert.True(t, msg.Final)
+		assert.NotZero(t, msg.Timestamp)
+	case <-time.After(5 * time.Second):
+		t.Fatal("data message not received")
+	}
+}
+
+func TestTURNRESTCredentials(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	username, password := TURNRESTCredentials("secret", "42", time.Hour, now)
+	assert.Equal(t, "1700003600:42", username)
+	// coturn: base64(hmac-sha1(static-auth-secret, username))
+	assert.Equal(t, "BiRzwDjrED4KnqX7kNBEFJ1fDaQ=", password)
+
+	username, _ = TURNRESTCredentials("secret", "", 0, now)
+	assert.Equal(t, strconv.FormatInt(now.Add(DefaultTURNCredentialTTL).Unix(), 10), username)
+}
+
+func TestGetICEServers(t *testing.T) {
+	opt := WebRTCOption{
+		ICEServers: []webrtc.ICEServer{{URLs: []string{"stun:stun.example.com:3478"}}},
+		TURNServers: []TURNServer{
+			{URLs: []string{"turn:turn.example.com:3478"}, Username: "user", Credential: "pass"},
+			{URLs: []string{"turns:turn.example.com:5349"}, Secret: "secret"},
+			{}, // 未配置地址的条目被忽略
+		},
+		TURNUser: "42",
+	}
+	servers := opt.GetICEServers()
+	assert.Len(t, servers, 3)
+	assert.Equal(t, "user", servers[1].Username)
+	assert.Equal(t, "pass", servers[1].Credential)
+	assert.Contains(t, servers[2].Username, ":42")
+	assert.NotEmpty(t, servers[2].Credential)
+}
+
 //
 //func TestFullConnection(t *testing.T) {
 //	client := NewWebRTCTransport(WebRTCOption{
@@ -263,3 +559,75 @@ func TestNewWebRTCTransport(t *testing.T) {
 //	// Let the test run
 //	time.Sleep(15 * time.Second)
 //}
+
+func TestEstimateMOS(t *testing.T) {
+	// 理想网络接近 G.711 上限
+	r, mos := EstimateMOS(constants.CodecPCMA, 0, 0, 20*time.Millisecond)
+	assert.Greater(t, r, 90.0)
+	assert.Greater(t, mos, 4.3)
+
+	// 10% 丢包加 400ms RTT 明显劣化
+	_, badMOS := EstimateMOS(constants.CodecPCMA, 0.1, 30*time.Millisecond, 400*time.Millisecond)
+	assert.Less(t, badMOS, 3.0)
+	assert.GreaterOrEqual(t, badMOS, 1.0)
+
+	// Opus 的 Bpl 取值较小，同等丢包下得分不高于 G.711
+	_, opusMOS := EstimateMOS(constants.CodecOpus, 0.05, 0, 20*time.Millisecond)
+	_, pcmaMOS := EstimateMOS(constants.CodecPCMA, 0.05, 0, 20*time.Millisecond)
+	assert.LessOrEqual(t, opusMOS, pcmaMOS)
+}
+
+func TestGetStatsUnavailable(t *testing.T) {
+	transport := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA})
+	_, err := transport.GetStats()
+	assert.ErrorIs(t, err, ErrStatsUnavailable)
+
+	transport.NewPeerConnection()
+	defer transport.Close()
+	stats, err := transport.GetStats()
+	assert.NoError(t, err)
+	assert.Equal(t, constants.CodecPCMA, stats.Codec)
+	assert.Zero(t, stats.PacketsReceived)
+}
+
+func TestDTLSCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := dir+"/dtls/cert.pem", dir+"/dtls/key.pem"
+
+	// 首次生成，之后加载同一证书
+	cert, err := LoadOrCreateCertificate(certFile, keyFile)
+	assert.NoError(t, err)
+	fingerprints, err := CertificateFingerprints(cert)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, fingerprints)
+	reloaded, err := LoadOrCreateCertificate(certFile, keyFile)
+	assert.NoError(t, err)
+	reloadedFingerprints, _ := CertificateFingerprints(reloaded)
+	assert.Equal(t, fingerprints, reloadedFingerprints)
+
+	transport := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA, CertFile: certFile, KeyFile: keyFile})
+	transport.NewPeerConnection()
+	defer transport.Close()
+	local, err := transport.LocalFingerprints()
+	assert.NoError(t, err)
+	assert.Equal(t, fingerprints, local)
+	offer, _, err := transport.CreateOffer()
+	assert.NoError(t, err)
+	assert.Contains(t, strings.ToUpper(offer), strings.ToUpper("a=fingerprint:"+fingerprints[0]))
+
+	// 固定指纹：匹配时接受，不匹配时拒绝
+	pinned := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA, RemoteFingerprints: fingerprints})
+	pinned.NewPeerConnection()
+	defer pinned.Close()
+	assert.NoError(t, pinned.SetRemoteDescription(offer))
+
+	other := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA})
+	other.NewPeerConnection()
+	defer other.Close()
+	otherOffer, _, err := other.CreateOffer()
+	assert.NoError(t, err)
+	rejecting := NewWebRTCTransport(WebRTCOption{Codec: constants.CodecPCMA, RemoteFingerprints: fingerprints})
+	rejecting.NewPeerConnection()
+	defer rejecting.Close()
+	assert.ErrorIs(t, rejecting.SetRemoteDescription(otherOffer), ErrFingerprintMismatch)
+}
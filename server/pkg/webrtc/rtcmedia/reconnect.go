@@ -0,0 +1,135 @@
+package rtcmedia
+
+import (
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/logger"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/zap"
+)
+
+// OnRestartOffer 启用自动 ICE 重启：连接断开或失败后生成 ICE 重启 offer 并回调，
+// 回调中通过现有信令（WebSocket）把 offer 发给远端，远端的 answer 照常调用 SetRemoteDescription
+// 只应在最初发起 offer 的一端设置，避免双方同时重启造成 offer 冲突
+func (wts *WebRTCTransport) OnRestartOffer(f func(offer string, candidates []string) error) {
+	wts.reconnectMu.Lock()
+	defer wts.reconnectMu.Unlock()
+	wts.onRestartOffer = f
+}
+
+// OnReconnected 设置连接断开后恢复时的回调（无论是自行恢复还是由 ICE 重启恢复）
+func (wts *WebRTCTransport) OnReconnected(f func()) {
+	wts.reconnectMu.Lock()
+	defer wts.reconnectMu.Unlock()
+	wts.onReconnected = f
+}
+
+// RestartICE 生成 ICE 重启 offer（新的 ufrag/pwd），媒体轨道和 DTLS 会话保持不变
+func (wts *WebRTCTransport) RestartICE() (offer string, candidates []string, err error) {
+	return wts.createOffer(&webrtc.OfferOptions{ICERestart: true})
+}
+
+// handleStateForReconnect 根据连接状态启动或结束 ICE 重启
+func (wts *WebRTCTransport) handleStateForReconnect(state webrtc.PeerConnectionState) {
+	wts.reconnectMu.Lock()
+	defer wts.reconnectMu.Unlock()
+
+	switch state {
+	case webrtc.PeerConnectionStateConnected:
+		if !wts.interrupted {
+			return
+		}
+		wts.interrupted = false
+		logger.Info("webrtc: connection recovered")
+		if wts.onReconnected != nil {
+			go wts.onReconnected()
+		}
+	case webrtc.PeerConnectionStateDisconnected, webrtc.PeerConnectionStateFailed:
+		if wts.closed {
+			return
+		}
+		wts.interrupted = true
+		if wts.restarting || wts.onRestartOffer == nil || wts.opt.ReconnectAttempts < 0 {
+			return
+		}
+		wts.restarting = true
+		// disconnected 经常在几秒内自行恢复，先观察一段时间；failed 立即重启
+		grace := time.Duration(0)
+		if state == webrtc.PeerConnectionStateDisconnected {
+			grace = constants.DefaultDisconnectGrace
+		}
+		go wts.reconnectLoop(grace)
+	}
+}
+
+// reconnectLoop 按指数退避重试 ICE 重启，直到连接恢复、传输关闭或重试次数用尽
+func (wts *WebRTCTransport) reconnectLoop(grace time.Duration) {
+	defer func() {
+		wts.reconnectMu.Lock()
+		wts.restarting = false
+		wts.reconnectMu.Unlock()
+	}()
+	time.Sleep(grace)
+
+	backoff := wts.opt.ReconnectBackoff
+	for attempt := 1; attempt <= wts.opt.ReconnectAttempts; attempt++ {
+		if wts.reconnectDone() {
+			return
+		}
+
+		logger.Info("webrtc: restarting ICE", zap.Int("attempt", attempt))
+		offer, candidates, err := wts.RestartICE()
+		if err == nil {
+			wts.reconnectMu.Lock()
+			send := wts.onRestartOffer
+			wts.reconnectMu.Unlock()
+			if send == nil {
+				break
+			}
+			err = send(offer, candidates)
+		}
+		if err != nil {
+			logger.Warn("webrtc: ICE restart failed", zap.Int("attempt", attempt), zap.Error(err))
+		} else if wts.waitConnected(wts.opt.GetICETimeout()) {
+			return
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > wts.opt.ReconnectMaxBackoff {
+			backoff = wts.opt.ReconnectMaxBackoff
+		}
+	}
+
+	if wts.reconnectDone() {
+		return
+	}
+	logger.Error("webrtc: ICE restart gave up", zap.Int("attempts", wts.opt.ReconnectAttempts))
+}
+
+// reconnectDone 连接已恢复或传输已关闭
+func (wts *WebRTCTransport) reconnectDone() bool {
+	wts.reconnectMu.Lock()
+	closed := wts.closed
+	wts.reconnectMu.Unlock()
+	return closed || wts.GetConnectionState() == webrtc.PeerConnectionStateConnected
+}
+
+// waitConnected 等待连接恢复，超时返回 false
+// 不使用 WaitUntilConnected：ICE 重启期间连接可能仍处于 failed，需要继续等待
+func (wts *WebRTCTransport) waitConnected(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		changed := wts.stateNotify()
+		if wts.reconnectDone() {
+			return true
+		}
+		select {
+		case <-timer.C:
+			return false
+		case <-changed:
+		}
+	}
+}
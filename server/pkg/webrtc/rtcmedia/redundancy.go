@@ -0,0 +1,149 @@
+package rtcmedia
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/nack"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+// MimeTypeRED RFC 2198 冗余音频，每个包携带前几帧的副本，单个丢包可直接从下一个包恢复
+const MimeTypeRED = "audio/red"
+
+// redCodecParameters 以 Opus 为主编码的 RED，fmtp 与 Chrome 一致（主帧与一份冗余均为 PT 111）
+var redCodecParameters = webrtc.RTPCodecParameters{
+	RTPCodecCapability: webrtc.RTPCodecCapability{
+		MimeType:    MimeTypeRED,
+		ClockRate:   48000,
+		Channels:    2,
+		SDPFmtpLine: "111/111",
+	},
+	PayloadType: 63,
+}
+
+// REDPrimaryMimeType RED 负载中主编码的类型，解码器按此创建
+const REDPrimaryMimeType = webrtc.MimeTypeOpus
+
+// configureRedundancy 注册 RED 编解码与音频 NACK 反馈，并加入 NACK 生成/重传拦截器
+// 必须在其它音频编解码注册之后调用，RegisterFeedback 只作用于已注册的编解码
+func configureRedundancy(m *webrtc.MediaEngine, registry *interceptor.Registry) error {
+	if err := m.RegisterCodec(redCodecParameters, webrtc.RTPCodecTypeAudio); err != nil {
+		return err
+	}
+	m.RegisterFeedback(webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBNACK}, webrtc.RTPCodecTypeAudio)
+
+	generator, err := nack.NewGeneratorInterceptor()
+	if err != nil {
+		return err
+	}
+	responder, err := nack.NewResponderInterceptor()
+	if err != nil {
+		return err
+	}
+	registry.Add(responder)
+	registry.Add(generator)
+	return nil
+}
+
+// IsRED 判断轨道编解码是否为 RFC 2198 冗余音频
+func IsRED(mimeType string) bool {
+	return strings.EqualFold(mimeType, MimeTypeRED)
+}
+
+// ErrInvalidRED RED 负载头部不完整或块长度越界
+var ErrInvalidRED = errors.New("invalid RED payload")
+
+// REDBlock RED 负载中的一个编码块
+type REDBlock struct {
+	PayloadType     uint8
+	TimestampOffset uint32 // 相对 RTP 包时间戳的偏移，主块为 0
+	Payload         []byte
+}
+
+// ParseRED 按 RFC 2198 拆分 RED 负载，返回的块按时间从旧到新排列，最后一块为主块
+func ParseRED(payload []byte) ([]REDBlock, error) {
+	var blocks []REDBlock
+	var lengths []int
+	offset := 0
+	for {
+		if offset >= len(payload) {
+			return nil, ErrInvalidRED
+		}
+		if payload[offset]&0x80 == 0 {
+			// 最后一个头部只有 1 字节：F=0 与主块 PT
+			blocks = append(blocks, REDBlock{PayloadType: payload[offset] & 0x7f})
+			offset++
+			break
+		}
+		if offset+4 > len(payload) {
+			return nil, ErrInvalidRED
+		}
+		header := uint32(payload[offset])<<24 | uint32(payload[offset+1])<<16 | uint32(payload[offset+2])<<8 | uint32(payload[offset+3])
+		blocks = append(blocks, REDBlock{
+			PayloadType:     payload[offset] & 0x7f,
+			TimestampOffset: header >> 10 & 0x3fff,
+		})
+		lengths = append(lengths, int(header&0x3ff))
+		offset += 4
+	}
+
+	for i, length := range lengths {
+		if offset+length > len(payload) {
+			return nil, ErrInvalidRED
+		}
+		blocks[i].Payload = payload[offset : offset+length]
+		offset += length
+	}
+	blocks[len(blocks)-1].Payload = payload[offset:]
+	return blocks, nil
+}
+
+// REDDepacketizer 从 RED 包中取出主编码帧，并用冗余块补回此前丢失的帧
+// 不是并发安全的，每条接收轨道一个
+type REDDepacketizer struct {
+	lastTimestamp uint32
+	started       bool
+}
+
+// NewREDDepacketizer 创建 RED 解包器
+func NewREDDepacketizer() *REDDepacketizer {
+	return &REDDepacketizer{}
+}
+
+// Depacketize 返回该包中需要解码的主编码负载（按时间顺序）；
+// 已输出过的帧（重复包、NACK 重传的迟到包）会被跳过
+func (d *REDDepacketizer) Depacketize(packet *rtp.Packet) ([][]byte, error) {
+	blocks, err := ParseRED(packet.Payload)
+	if err != nil {
+		return nil, err
+	}
+	primary := blocks[len(blocks)-1]
+	if d.started && !timestampAfter(packet.Timestamp, d.lastTimestamp) {
+		return nil, nil
+	}
+
+	var frames [][]byte
+	if d.started {
+		for _, block := range blocks[:len(blocks)-1] {
+			ts := packet.Timestamp - block.TimestampOffset
+			if block.PayloadType != primary.PayloadType || len(block.Payload) == 0 || !timestampAfter(ts, d.lastTimestamp) {
+				continue
+			}
+			frames = append(frames, block.Payload)
+		}
+	}
+	if len(primary.Payload) > 0 {
+		frames = append(frames, primary.Payload)
+	}
+	d.lastTimestamp = packet.Timestamp
+	d.started = true
+	return frames, nil
+}
+
+// timestampAfter 按 RTP 时间戳回绕规则判断 a 是否晚于 b
+func timestampAfter(a, b uint32) bool {
+	return a != b && a-b < 1<<31
+}
@@ -0,0 +1,155 @@
+package rtcmedia
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/stats"
+	"github.com/pion/webrtc/v3"
+)
+
+// CallStats 单个会话的 RTP 统计与通话质量估算
+// Inbound 为本端收到的音频（对端麦克风），Outbound 为本端发送的音频，Remote* 来自对端 RTCP 接收报告
+type CallStats struct {
+	Timestamp time.Time `json:"timestamp"`
+	Codec     string    `json:"codec"`
+
+	PacketsReceived uint64        `json:"packetsReceived"`
+	PacketsLost     int64         `json:"packetsLost"`
+	BytesReceived   uint64        `json:"bytesReceived"`
+	Jitter          time.Duration `json:"jitter"`
+	LossRate        float64       `json:"lossRate"` // 0-1，累计丢包率
+
+	PacketsSent      uint64        `json:"packetsSent"`
+	BytesSent        uint64        `json:"bytesSent"`
+	RemoteJitter     time.Duration `json:"remoteJitter"`
+	RemoteLossRate   float64       `json:"remoteLossRate"` // 0-1，对端最近一个报告周期的丢包率
+	RoundTripTime    time.Duration `json:"roundTripTime"`
+	ICERoundTripTime time.Duration `json:"iceRoundTripTime"` // STUN 连通性检查测得，RTCP 报告尚未到达时可作参考
+
+	// E-model（ITU-T G.107）估算，RFactor 0-100，MOS 1-4.5；分别对应本端听到的和对端听到的音质
+	RFactor       float64 `json:"rFactor"`
+	MOS           float64 `json:"mos"`
+	RemoteRFactor float64 `json:"remoteRFactor"`
+	RemoteMOS     float64 `json:"remoteMos"`
+}
+
+// ErrStatsUnavailable 连接未建立或统计拦截器未启用
+var ErrStatsUnavailable = errors.New("rtp stats unavailable")
+
+// newInterceptorRegistry 注册 RTCP 报告（用于 RTT 与对端丢包）和统计拦截器，onStats 在 PeerConnection 创建时同步回调
+func newInterceptorRegistry(onStats func(stats.Getter)) (*interceptor.Registry, error) {
+	registry := &interceptor.Registry{}
+	if err := webrtc.ConfigureRTCPReports(registry); err != nil {
+		return nil, err
+	}
+	statsFactory, err := stats.NewInterceptor()
+	if err != nil {
+		return nil, err
+	}
+	statsFactory.OnNewPeerConnection(func(_ string, getter stats.Getter) {
+		onStats(getter)
+	})
+	registry.Add(statsFactory)
+	return registry, nil
+}
+
+// GetStats 返回当前的 RTP 统计（丢包、抖动、RTT）及 MOS 估算
+func (wts *WebRTCTransport) GetStats() (*CallStats, error) {
+	wts.mu.RLock()
+	pc := wts.peerConnection
+	getter := wts.statsGetter
+	rxTrack := wts.rxTrack
+	codec := wts.codec.Codec
+	wts.mu.RUnlock()
+
+	if pc == nil || getter == nil {
+		return nil, ErrStatsUnavailable
+	}
+
+	result := &CallStats{Timestamp: time.Now(), Codec: codec}
+
+	if rxTrack != nil {
+		if s := getter.Get(uint32(rxTrack.SSRC())); s != nil {
+			in := s.InboundRTPStreamStats
+			result.PacketsReceived = in.PacketsReceived
+			result.PacketsLost = in.PacketsLost
+			result.BytesReceived = in.BytesReceived
+			// 入站抖动以 RTP 时间戳为单位
+			if clockRate := rxTrack.Codec().ClockRate; clockRate > 0 {
+				result.Jitter = time.Duration(in.Jitter / float64(clockRate) * float64(time.Second))
+			}
+			if expected := float64(in.PacketsReceived) + float64(in.PacketsLost); expected > 0 && in.PacketsLost > 0 {
+				result.LossRate = float64(in.PacketsLost) / expected
+			}
+		}
+	}
+
+	for _, sender := range pc.GetSenders() {
+		if sender.Track() == nil {
+			continue
+		}
+		for _, encoding := range sender.GetParameters().Encodings {
+			s := getter.Get(uint32(encoding.SSRC))
+			if s == nil {
+				continue
+			}
+			result.PacketsSent += s.OutboundRTPStreamStats.PacketsSent
+			result.BytesSent += s.OutboundRTPStreamStats.BytesSent
+			remote := s.RemoteInboundRTPStreamStats
+			result.RemoteJitter = time.Duration(remote.Jitter * float64(time.Second))
+			result.RemoteLossRate = remote.FractionLost
+			if remote.RoundTripTimeMeasurements > 0 {
+				result.RoundTripTime = remote.RoundTripTime
+			}
+		}
+	}
+
+	for _, report := range pc.GetStats() {
+		if pair, ok := report.(webrtc.ICECandidatePairStats); ok && pair.Nominated {
+			result.ICERoundTripTime = time.Duration(pair.CurrentRoundTripTime * float64(time.Second))
+		}
+	}
+
+	rtt := result.RoundTripTime
+	if rtt == 0 {
+		rtt = result.ICERoundTripTime
+	}
+	result.RFactor, result.MOS = EstimateMOS(codec, result.LossRate, result.Jitter, rtt)
+	result.RemoteRFactor, result.RemoteMOS = EstimateMOS(codec, result.RemoteLossRate, result.RemoteJitter, rtt)
+	return result, nil
+}
+
+// codecImpairment G.113 附录 I 中的设备损伤因子 Ie 与丢包鲁棒性因子 Bpl（随机丢包、带丢包隐藏）
+var codecImpairment = map[string]struct{ ie, bpl float64 }{
+	constants.CodecPCMA: {0, 25.1},
+	constants.CodecPCMU: {0, 25.1},
+	constants.CodecG722: {0, 25.1},
+	constants.CodecOPUS: {0, 20},
+}
+
+// EstimateMOS 按简化 E-model（ITU-T G.107）由丢包率（0-1）、抖动和 RTT 估算 R 因子与 MOS
+// 单向时延取 RTT/2，另计两倍抖动作为抖动缓冲时延及 10ms 编解码时延
+func EstimateMOS(codec string, lossRate float64, jitter, rtt time.Duration) (rFactor, mos float64) {
+	impairment, ok := codecImpairment[codec]
+	if !ok {
+		impairment = codecImpairment[constants.CodecPCMA]
+	}
+
+	delayMs := float64(rtt/2+2*jitter)/float64(time.Millisecond) + 10
+	// 时延损伤 Id：177.3ms 以内影响很小，之后急剧上升
+	id := 0.024 * delayMs
+	if delayMs > 177.3 {
+		id += 0.11 * (delayMs - 177.3)
+	}
+
+	ppl := math.Max(0, math.Min(1, lossRate)) * 100
+	ieEff := impairment.ie + (95-impairment.ie)*ppl/(ppl+impairment.bpl)
+
+	rFactor = math.Max(0, math.Min(100, 93.2-id-ieEff))
+	mos = 1 + 0.035*rFactor + 7e-6*rFactor*(rFactor-60)*(100-rFactor)
+	return rFactor, math.Max(1, math.Min(4.5, mos))
+}
@@ -0,0 +1,322 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/signaling/signalingpb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// typeHello gRPC 客户端的首条消息，只在流建立时使用，之后收到的忽略
+const typeHello MessageType = "hello"
+
+// GRPCHandler 以 gRPC 双向流提供与 WebSocket 相同的信令，供嵌入式与原生客户端使用。
+// 会话交给同一个 Server，共用 SessionManager、消息处理器、并发限制与跨节点登记
+type GRPCHandler struct {
+	signalingpb.UnimplementedSignalingServer
+
+	Server *Server
+	// OnStream 为新流创建会话。r 由 Hello 的参数（URL 查询参数）与 authorization metadata
+	// （Authorization 头）构成，可与 WebSocket 路径共用 Authenticator 与参数解析；
+	// release 在会话结束后调用，可为 nil。返回 *AuthError 时按其状态码映射为 gRPC 状态。
+	// 为 nil 时按 ServeHTTP 的流程认证并调用 Server.OnSession
+	OnStream func(r *http.Request, conn *StreamConn) (s *Session, release func(), err error)
+}
+
+// NewGRPCHandler 创建 gRPC 信令服务，通过 signalingpb.RegisterSignalingServer 注册
+func NewGRPCHandler(srv *Server) *GRPCHandler {
+	return &GRPCHandler{Server: srv}
+}
+
+// Connect 实现 signalingpb.SignalingServer
+func (h *GRPCHandler) Connect(stream signalingpb.Signaling_ConnectServer) error {
+	if h.Server.Closed() {
+		return grpcError(ErrServerClosed)
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	hello := first.GetHello()
+	if hello == nil {
+		return status.Error(codes.InvalidArgument, "signaling: first message must be hello")
+	}
+	r := streamRequest(stream.Context(), hello)
+
+	conn := NewStreamConn(stream)
+	defer conn.Close()
+
+	var s *Session
+	var release func()
+	if h.OnStream != nil {
+		s, release, err = h.OnStream(r, conn)
+	} else {
+		s, err = h.accept(r, conn)
+	}
+	if err != nil {
+		logrus.WithError(err).Info("signaling: rejected grpc stream")
+		return grpcError(err)
+	}
+	if release != nil {
+		defer release()
+	}
+
+	err = h.Server.Serve(s)
+	if err == nil {
+		return nil
+	}
+	logrus.WithError(err).WithField("session", s.ID).Info("signaling: grpc stream closed")
+	switch s.CloseReason() {
+	case CloseReasonShutdown:
+		return grpcError(ErrServerClosed)
+	case CloseReasonLimit:
+		return grpcError(ErrTooManySessions)
+	case CloseReasonIdle:
+		return status.Error(codes.DeadlineExceeded, "signaling: session idle timeout")
+	}
+	if stream.Context().Err() != nil {
+		// 客户端已断开
+		return nil
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
+
+// accept 与 ServeHTTP 相同：认证后创建会话并调用 Server.OnSession
+func (h *GRPCHandler) accept(r *http.Request, conn *StreamConn) (*Session, error) {
+	identity, err := h.Server.Authenticate(r)
+	if err != nil {
+		return nil, err
+	}
+	s := NewSession("", conn, nil)
+	s.Identity = identity
+	if h.Server.OnSession != nil {
+		if err := h.Server.OnSession(s); err != nil {
+			s.Close(CloseReasonError)
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// streamRequest 把 Hello 参数与 authorization metadata 转换为 HTTP 请求
+func streamRequest(ctx context.Context, hello *signalingpb.Hello) *http.Request {
+	query := url.Values{}
+	for key, value := range hello.GetParams() {
+		query.Set(key, value)
+	}
+	r := (&http.Request{
+		Method: http.MethodGet,
+		URL:    &url.URL{Path: signalingpb.Signaling_Connect_FullMethodName, RawQuery: query.Encode()},
+		Header: make(http.Header),
+	}).WithContext(ctx)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			r.Header.Add("Authorization", value)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+	}
+	return r
+}
+
+// grpcError 把认证与会话错误映射为 gRPC 状态
+func grpcError(err error) error {
+	var authErr *AuthError
+	switch {
+	case errors.Is(err, ErrServerClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, ErrTooManySessions):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.As(err, &authErr):
+		return status.Error(httpStatusCode(AuthStatus(err)), err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func httpStatusCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusPaymentRequired, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
+
+// StreamConn 把 gRPC 信令流适配为 Conn：读取时把 ClientMessage 转换为 SignalMessage，
+// 写入时把 SignalMessage（或同结构的 JSON 值）转换为 ServerMessage。可并发写入；
+// Close 使阻塞的读取立即返回，流在 Connect 返回时结束
+type StreamConn struct {
+	stream signalingpb.Signaling_ConnectServer
+	sendMu sync.Mutex
+
+	recv      chan streamRecv
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type streamRecv struct {
+	msg *signalingpb.ClientMessage
+	err error
+}
+
+// NewStreamConn 包装信令流并开始读取
+func NewStreamConn(stream signalingpb.Signaling_ConnectServer) *StreamConn {
+	c := &StreamConn{
+		stream: stream,
+		recv:   make(chan streamRecv),
+		done:   make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// readLoop 流的 Recv 不能被打断，在单独的协程中读取，Close 后丢弃结果
+func (c *StreamConn) readLoop() {
+	for {
+		msg, err := c.stream.Recv()
+		select {
+		case c.recv <- streamRecv{msg: msg, err: err}:
+		case <-c.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// ReadJSON 实现 Conn
+func (c *StreamConn) ReadJSON(v interface{}) error {
+	var got streamRecv
+	select {
+	case got = <-c.recv:
+	case <-c.done:
+		return net.ErrClosed
+	}
+	if got.err != nil {
+		return got.err
+	}
+	msg, err := fromClientMessage(got.msg)
+	if err != nil {
+		return err
+	}
+	if out, ok := v.(*SignalMessage); ok {
+		*out = msg
+		return nil
+	}
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// WriteJSON 实现 Conn
+func (c *StreamConn) WriteJSON(v interface{}) error {
+	var msg SignalMessage
+	switch m := v.(type) {
+	case SignalMessage:
+		msg = m
+	case *SignalMessage:
+		msg = *m
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return err
+		}
+	}
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	select {
+	case <-c.done:
+		return net.ErrClosed
+	default:
+	}
+	return c.stream.Send(toServerMessage(msg))
+}
+
+// Close 结束读取，之后的读写返回 net.ErrClosed
+func (c *StreamConn) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return nil
+}
+
+func fromClientMessage(m *signalingpb.ClientMessage) (SignalMessage, error) {
+	switch body := m.GetMessage().(type) {
+	case *signalingpb.ClientMessage_Hello:
+		return SignalMessage{Type: typeHello}, nil
+	case *signalingpb.ClientMessage_Offer:
+		return NewMessage(TypeOffer, "", OfferData{
+			SDP:        body.Offer.GetSdp(),
+			Candidates: body.Offer.GetCandidates(),
+			Trickle:    body.Offer.GetTrickle(),
+			ICERestart: body.Offer.GetIceRestart(),
+		})
+	case *signalingpb.ClientMessage_Candidate:
+		return NewMessage(TypeCandidate, "", CandidateData{Candidate: body.Candidate.GetCandidate()})
+	case *signalingpb.ClientMessage_Connected:
+		return SignalMessage{Type: TypeConnected}, nil
+	case *signalingpb.ClientMessage_Close:
+		return SignalMessage{Type: TypeClose}, nil
+	}
+	// 未设置 oneof 的空消息按未知类型忽略
+	return SignalMessage{}, nil
+}
+
+func toServerMessage(msg SignalMessage) *signalingpb.ServerMessage {
+	out := &signalingpb.ServerMessage{SessionId: msg.SessionID}
+	switch msg.Type {
+	case TypeInit:
+		out.Message = &signalingpb.ServerMessage_Init{Init: &signalingpb.Init{}}
+		return out
+	case TypeAnswer:
+		var data AnswerData
+		if msg.Decode(&data) == nil {
+			out.Message = &signalingpb.ServerMessage_Answer{Answer: &signalingpb.Answer{
+				Sdp:        data.SDP,
+				Candidates: data.Candidates,
+				IceRestart: data.ICERestart,
+			}}
+			return out
+		}
+	case TypeCandidate:
+		var data CandidateData
+		if msg.Decode(&data) == nil {
+			out.Message = &signalingpb.ServerMessage_Candidate{Candidate: &signalingpb.Candidate{Candidate: data.Candidate}}
+			return out
+		}
+	case TypeError:
+		var data ErrorData
+		if msg.Decode(&data) == nil {
+			out.Message = &signalingpb.ServerMessage_Error{Error: &signalingpb.Error{Code: data.Code, Message: data.Message}}
+			return out
+		}
+	}
+	// 其它消息（以及负载无法解析的消息）原样作为事件转发
+	out.Message = &signalingpb.ServerMessage_Event{Event: &signalingpb.Event{Type: string(msg.Type), Data: msg.Data}}
+	return out
+}
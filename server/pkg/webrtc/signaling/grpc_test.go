@@ -0,0 +1,124 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/signaling/signalingpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC 在内存连接上启动 gRPC 信令服务并返回客户端
+func dialGRPC(t *testing.T, handler *GRPCHandler) signalingpb.SignalingClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	signalingpb.RegisterSignalingServer(server, handler)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return signalingpb.NewSignalingClient(conn)
+}
+
+func TestGRPCSignaling(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	var identity interface{}
+	srv.Authenticator = func(r *http.Request) (interface{}, error) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.URL.Query().Get("assistantId") != "7" {
+			return nil, &AuthError{Status: http.StatusUnauthorized, Message: "bad token"}
+		}
+		return "alice", nil
+	}
+	connected := make(chan string, 1)
+	srv.Handle(TypeConnected, func(s *Session, msg SignalMessage) error {
+		identity = s.Identity
+		connected <- s.ID
+		return nil
+	})
+	client := dialGRPC(t, NewGRPCHandler(srv))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	hello := &signalingpb.ClientMessage{Message: &signalingpb.ClientMessage_Hello{
+		Hello: &signalingpb.Hello{Params: map[string]string{"assistantId": "7"}},
+	}}
+
+	// 认证失败映射为 Unauthenticated
+	stream, err := client.Connect(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(hello))
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	stream, err = client.Connect(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret"))
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(hello))
+	init, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, init.GetInit())
+	sessionID := init.GetSessionId()
+	assert.NotEmpty(t, sessionID)
+	assert.Equal(t, 1, srv.Sessions.Len(), "grpc sessions share the websocket session manager")
+
+	require.NoError(t, stream.Send(&signalingpb.ClientMessage{Message: &signalingpb.ClientMessage_Connected{Connected: &signalingpb.Connected{}}}))
+	assert.Equal(t, sessionID, <-connected)
+	assert.Equal(t, "alice", identity)
+
+	// 未关联 Transport 时 offer 返回错误消息
+	require.NoError(t, stream.Send(&signalingpb.ClientMessage{Message: &signalingpb.ClientMessage_Offer{Offer: &signalingpb.Offer{Sdp: "v=0"}}}))
+	reply, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, ErrCodeWebRTCFailed, reply.GetError().GetCode())
+
+	require.NoError(t, stream.Send(&signalingpb.ClientMessage{Message: &signalingpb.ClientMessage_Close{Close: &signalingpb.Close{}}}))
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+	require.Eventually(t, func() bool { return srv.Sessions.Len() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestGRPCRequiresHello(t *testing.T) {
+	client := dialGRPC(t, NewGRPCHandler(NewServer()))
+	stream, err := client.Connect(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&signalingpb.ClientMessage{Message: &signalingpb.ClientMessage_Close{Close: &signalingpb.Close{}}}))
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestToServerMessage(t *testing.T) {
+	// AIClient 以 map 写出的消息与 SignalMessage 同结构
+	var msg SignalMessage
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"candidate","session_id":"s1","data":{"candidate":"candidate:1"}}`), &msg))
+	out := toServerMessage(msg)
+	assert.Equal(t, "s1", out.GetSessionId())
+	assert.Equal(t, "candidate:1", out.GetCandidate().GetCandidate())
+
+	event := toServerMessage(SignalMessage{Type: "asr_result", Data: []byte(`{"text":"hi"}`)})
+	assert.Equal(t, "asr_result", event.GetEvent().GetType())
+	assert.JSONEq(t, `{"text":"hi"}`, string(event.GetEvent().GetData()))
+
+	answer, err := NewMessage(TypeAnswer, "s1", AnswerData{SDP: "v=0", Candidates: []string{"c"}, ICERestart: true})
+	require.NoError(t, err)
+	got := toServerMessage(answer).GetAnswer()
+	assert.Equal(t, "v=0", got.GetSdp())
+	assert.Equal(t, []string{"c"}, got.GetCandidates())
+	assert.True(t, got.GetIceRestart())
+}
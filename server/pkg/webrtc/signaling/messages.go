@@ -0,0 +1,90 @@
+// Package signaling WebSocket 信令：offer/answer/candidate 交换、会话管理与消息路由，
+// 供示例服务和生产语音接口共用。消息格式与现有浏览器/Go 客户端保持兼容：
+//
+//	{"type":"offer","session_id":"session_1","data":{"sdp":"...","candidates":[],"trickle":true}}
+package signaling
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
+)
+
+// MessageType 信令消息类型
+type MessageType string
+
+const (
+	TypeInit       MessageType = "init" // 服务端 → 客户端：分配的会话 ID
+	TypeOffer      MessageType = constants.WebRTCOffer
+	TypeAnswer     MessageType = constants.WebRTCAnswer
+	TypeCandidate  MessageType = constants.WebRTCCandidate
+	TypeConnected  MessageType = "connected"  // 客户端 → 服务端：WebRTC 已连通
+	TypeClose      MessageType = "close"      // 客户端 → 服务端：结束会话
+	TypeDisconnect MessageType = "disconnect" // 同 TypeClose
+	TypeError      MessageType = "error"      // 服务端 → 客户端：处理消息失败
+)
+
+// ErrNoData 消息没有 data 字段
+var ErrNoData = errors.New("signaling: message has no data")
+
+// SignalMessage 信令消息，Data 按 Type 解码为对应的负载结构
+type SignalMessage struct {
+	Type      MessageType     `json:"type"`
+	SessionID string          `json:"session_id,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// NewMessage 创建消息，data 为 nil 时不带 data 字段
+func NewMessage(msgType MessageType, sessionID string, data interface{}) (SignalMessage, error) {
+	msg := SignalMessage{Type: msgType, SessionID: sessionID}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return msg, err
+		}
+		msg.Data = raw
+	}
+	return msg, nil
+}
+
+// Decode 把 data 字段解码到 v
+func (m SignalMessage) Decode(v interface{}) error {
+	if len(m.Data) == 0 || string(m.Data) == "null" {
+		return ErrNoData
+	}
+	return json.Unmarshal(m.Data, v)
+}
+
+// OfferData offer 负载；Candidates 为非 trickle 客户端随 offer 一起发送的候选者
+type OfferData struct {
+	SDP        string   `json:"sdp"`
+	Candidates []string `json:"candidates,omitempty"`
+	Trickle    bool     `json:"trickle,omitempty"`     // 候选者以单独的 candidate 消息发送
+	ICERestart bool     `json:"ice_restart,omitempty"` // 网络切换后的重新协商
+}
+
+// AnswerData answer 负载
+type AnswerData struct {
+	SDP        string   `json:"sdp"`
+	Candidates []string `json:"candidates"`
+	ICERestart bool     `json:"ice_restart,omitempty"` // 回显 offer 的标记，客户端据此只更新远端描述
+}
+
+// CandidateData trickle ICE 候选者
+type CandidateData struct {
+	Candidate string `json:"candidate"`
+}
+
+// ErrorData 错误负载
+type ErrorData struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// 错误代码
+const (
+	ErrCodeInvalidMessage  = "invalid_message"
+	ErrCodeWebRTCFailed    = "webrtc_failed"
+	ErrCodeTooManySessions = "too_many_sessions"
+)
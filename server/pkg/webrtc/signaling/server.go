@@ -0,0 +1,629 @@
+package signaling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/events"
+	"github.com/code-100-precent/LingEcho/pkg/metrics"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrUnknownMessage 没有为该消息类型注册处理器
+	ErrUnknownMessage = errors.New("signaling: unknown message type")
+	// ErrInvalidMessage 消息负载缺失或格式错误
+	ErrInvalidMessage = errors.New("signaling: invalid message")
+	// ErrNoTransport 会话尚未关联 WebRTC 传输
+	ErrNoTransport = errors.New("signaling: session has no transport")
+	// ErrServerClosed 服务已开始关闭，不再接受新会话
+	ErrServerClosed = errors.New("signaling: server closed")
+	// ErrTooManySessions 同一身份的并发会话数已达 MaxSessionsPerIdentity
+	ErrTooManySessions = errors.New("signaling: too many concurrent sessions")
+)
+
+const (
+	// DefaultPingInterval 默认的 WebSocket ping 间隔
+	DefaultPingInterval = 15 * time.Second
+	// DefaultIdleTimeout 默认的空闲超时，超过该时长未收到消息或 pong 的会话被回收
+	DefaultIdleTimeout = 45 * time.Second
+
+	// EventSessionClosed 会话结束时发布到全局事件总线的事件类型，
+	// Data 包含 session_id、reason 与 duration_ms
+	EventSessionClosed = "signaling.session_closed"
+
+	pingWriteTimeout = 5 * time.Second
+	// storeTimeout 单次访问 SessionStore 的超时
+	storeTimeout = 3 * time.Second
+
+	// metricsTransport 会话指标中 transport 标签的取值
+	metricsTransport = "webrtc"
+)
+
+// reapInterval 回收检查的间隔
+var reapInterval = 5 * time.Second
+
+// drainPollInterval Shutdown 检查剩余会话的间隔
+var drainPollInterval = 200 * time.Millisecond
+
+// pinger 支持 ping/pong 保活的连接，*websocket.Conn 即满足
+type pinger interface {
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetPongHandler(h func(appData string) error)
+}
+
+// HandlerFunc 处理一种类型的信令消息
+type HandlerFunc func(s *Session, msg SignalMessage) error
+
+// Authenticator 在 WebSocket 升级前校验请求，返回的身份保存到 Session.Identity。
+// 返回 *AuthError 时按其状态码拒绝，其它错误按 401 拒绝
+type Authenticator func(r *http.Request) (identity interface{}, err error)
+
+// AuthError 认证失败及返回给客户端的 HTTP 状态码
+type AuthError struct {
+	Status  int
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+// BearerToken 返回请求携带的令牌：优先取 Authorization: Bearer 头，
+// 浏览器无法为 WebSocket 设置请求头，因此也接受 token 查询参数
+func BearerToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return r.URL.Query().Get("token")
+}
+
+// AllowOrigins 返回只接受给定来源的 Upgrader.CheckOrigin。来源以 * 结尾时按前缀匹配，
+// 需显式配置单独的 * 才允许所有来源；列表为空时只允许与请求 Host 相同的来源。没有 Origin 头的请求来自非浏览器客户端，始终允许
+func AllowOrigins(origins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if len(origins) == 0 {
+			u, err := url.Parse(origin)
+			return err == nil && strings.EqualFold(u.Host, r.Host)
+		}
+		for _, allowed := range origins {
+			if allowed == origin || (strings.HasSuffix(allowed, "*") && strings.HasPrefix(origin, strings.TrimSuffix(allowed, "*"))) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Server 信令服务：认证、升级 WebSocket、发送 init 并把收到的消息路由到处理器。
+// 默认处理 offer 与 candidate，其它类型（如 connected）由调用方通过 Handle 注册。
+type Server struct {
+	Upgrader      websocket.Upgrader
+	Authenticator Authenticator // nil 表示不认证
+	Sessions      *SessionManager
+
+	// PingInterval 向连接发送 ping 的间隔，<= 0 时不发送
+	PingInterval time.Duration
+	// IdleTimeout 会话的空闲超时，<= 0 时不回收
+	IdleTimeout time.Duration
+
+	// OnSession 在 ServeHTTP 建立会话后、发送 init 之前调用，用于创建 Transport、注册 OnTrack 等；
+	// 返回错误时关闭连接
+	OnSession func(s *Session) error
+	// OnClose 在会话结束后调用
+	OnClose func(s *Session)
+
+	// Store 会话登记与跨节点转发，默认为进程内存储；多副本部署时设为 RedisStore。
+	// 须在第一个会话开始前设置
+	Store SessionStore
+	// NodeID 本节点在 Store 中的标识
+	NodeID string
+	// SessionTTL 会话记录的有效期，回收检查时续期
+	SessionTTL time.Duration
+
+	// IdentityKey 把 Session.Identity 映射为并发限制的键（如用户 ID），返回空串时不限制
+	IdentityKey func(identity interface{}) string
+	// MaxSessionsPerIdentity 同一身份在本节点的并发会话上限，<= 0 时不限制
+	MaxSessionsPerIdentity int
+
+	mu          sync.RWMutex
+	handlers    map[MessageType]HandlerFunc
+	active      map[string]int // 各身份进行中的会话数
+	reaperStop  chan struct{}
+	subscribed  bool
+	unsubscribe func()
+	closed      bool
+}
+
+// NewServer 创建信令服务，已注册默认的 offer/candidate 处理器
+func NewServer() *Server {
+	srv := &Server{
+		Sessions:     NewSessionManager(),
+		PingInterval: DefaultPingInterval,
+		IdleTimeout:  DefaultIdleTimeout,
+		Store:        NewMemoryStore(),
+		NodeID:       DefaultNodeID(),
+		SessionTTL:   DefaultSessionTTL,
+		handlers:     make(map[MessageType]HandlerFunc),
+		active:       make(map[string]int),
+	}
+	srv.Handle(TypeOffer, HandleOffer)
+	srv.Handle(TypeCandidate, HandleCandidate)
+	return srv
+}
+
+// Handle 注册或替换一种消息类型的处理器
+func (srv *Server) Handle(msgType MessageType, handler HandlerFunc) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.handlers[msgType] = handler
+}
+
+// Dispatch 把消息交给对应的处理器
+func (srv *Server) Dispatch(s *Session, msg SignalMessage) error {
+	srv.mu.RLock()
+	handler, ok := srv.handlers[msg.Type]
+	srv.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownMessage, msg.Type)
+	}
+	return handler(s, msg)
+}
+
+// Authenticate 执行配置的 Authenticator
+func (srv *Server) Authenticate(r *http.Request) (interface{}, error) {
+	if srv.Authenticator == nil {
+		return nil, nil
+	}
+	return srv.Authenticator(r)
+}
+
+// AuthStatus 返回认证错误对应的 HTTP 状态码
+func AuthStatus(err error) int {
+	var authErr *AuthError
+	if errors.As(err, &authErr) && authErr.Status != 0 {
+		return authErr.Status
+	}
+	return http.StatusUnauthorized
+}
+
+// Upgrade 把 HTTP 请求升级为 WebSocket
+func (srv *Server) Upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	return srv.Upgrader.Upgrade(w, r, nil)
+}
+
+// ServeHTTP 认证并升级请求，通过 OnSession 初始化会话后处理信令直到连接关闭
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if srv.Closed() {
+		http.Error(w, ErrServerClosed.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	identity, err := srv.Authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), AuthStatus(err))
+		return
+	}
+	conn, err := srv.Upgrade(w, r)
+	if err != nil {
+		logrus.WithError(err).Warn("signaling: failed to upgrade connection")
+		return
+	}
+	defer conn.Close()
+
+	session := NewSession("", conn, nil)
+	session.Identity = identity
+	if srv.OnSession != nil {
+		if err := srv.OnSession(session); err != nil {
+			logrus.WithError(err).WithField("session", session.ID).Warn("signaling: failed to set up session")
+			session.SendError(ErrCodeWebRTCFailed, err.Error())
+			session.Close(CloseReasonError)
+			return
+		}
+	}
+	if err := srv.Serve(session); err != nil {
+		logrus.WithError(err).WithField("session", session.ID).Info("signaling: connection closed")
+	}
+}
+
+// Serve 发送 init 并处理会话的信令消息，直到客户端发送 close/disconnect（返回 nil）、
+// 读取失败或会话因空闲被回收。返回前关闭会话并发布 EventSessionClosed。
+// 服务关闭后调用时直接关闭会话并返回 ErrServerClosed；身份的并发会话数已达上限时
+// 发送 too_many_sessions 错误、关闭会话并返回 ErrTooManySessions
+func (srv *Server) Serve(s *Session) error {
+	srv.mu.Lock()
+	if srv.closed {
+		srv.mu.Unlock()
+		s.Close(CloseReasonShutdown)
+		return ErrServerClosed
+	}
+	key, ok := srv.admit(s)
+	if !ok {
+		srv.mu.Unlock()
+		s.SendError(ErrCodeTooManySessions, ErrTooManySessions.Error())
+		s.Close(CloseReasonLimit)
+		return ErrTooManySessions
+	}
+	// 持有锁加入，保证 Close 遍历会话时不会遗漏
+	srv.Sessions.Add(s)
+	srv.mu.Unlock()
+	srv.register(s)
+	srv.startReaper()
+	srv.subscribe()
+	stopKeepalive := srv.keepalive(s)
+	if monitor := metrics.GetGlobalMonitor(); monitor != nil {
+		monitor.SessionStarted(metricsTransport)
+	}
+
+	reason := CloseReasonError
+	defer func() {
+		stopKeepalive()
+		srv.Sessions.Remove(s.ID)
+		srv.release(key)
+		srv.unregister(s.ID)
+		s.Close(reason)
+		if srv.OnClose != nil {
+			srv.OnClose(s)
+		}
+		duration := time.Since(s.CreatedAt)
+		if monitor := metrics.GetGlobalMonitor(); monitor != nil {
+			monitor.SessionEnded(metricsTransport, s.CloseReason(), duration)
+		}
+		events.PublishEvent(EventSessionClosed, map[string]interface{}{
+			"session_id":  s.ID,
+			"reason":      s.CloseReason(),
+			"duration_ms": duration.Milliseconds(),
+		}, "signaling")
+	}()
+
+	if err := s.Send(TypeInit, nil); err != nil {
+		return err
+	}
+
+	for {
+		var msg SignalMessage
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+		s.Touch()
+		if msg.Type == TypeClose || msg.Type == TypeDisconnect {
+			reason = CloseReasonClient
+			return nil
+		}
+
+		if err := srv.Dispatch(s, msg); err != nil {
+			entry := logrus.WithError(err).WithFields(logrus.Fields{"session": s.ID, "type": msg.Type})
+			if errors.Is(err, ErrUnknownMessage) {
+				entry.Debug("signaling: ignored message")
+				continue
+			}
+			entry.Warn("signaling: failed to handle message")
+			code := ErrCodeWebRTCFailed
+			if errors.Is(err, ErrInvalidMessage) {
+				code = ErrCodeInvalidMessage
+			}
+			s.SendError(code, err.Error())
+		}
+	}
+}
+
+// admit 检查并占用身份的并发名额，调用方须持有 srv.mu
+func (srv *Server) admit(s *Session) (string, bool) {
+	if srv.IdentityKey == nil || srv.MaxSessionsPerIdentity <= 0 {
+		return "", true
+	}
+	key := srv.IdentityKey(s.Identity)
+	if key == "" {
+		return "", true
+	}
+	if srv.active[key] >= srv.MaxSessionsPerIdentity {
+		return key, false
+	}
+	srv.active[key]++
+	return key, true
+}
+
+// release 归还 admit 占用的名额
+func (srv *Server) release(key string) {
+	if key == "" {
+		return
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.active[key]--; srv.active[key] <= 0 {
+		delete(srv.active, key)
+	}
+}
+
+// ActiveSessions 返回身份在本节点进行中的会话数，未配置 IdentityKey 时为 0
+func (srv *Server) ActiveSessions(identity interface{}) int {
+	if srv.IdentityKey == nil {
+		return 0
+	}
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+	return srv.active[srv.IdentityKey(identity)]
+}
+
+// keepalive 定期向支持 ping 的连接发送 ping，收到 pong 时标记会话活跃。返回停止函数
+func (srv *Server) keepalive(s *Session) func() {
+	p, ok := s.conn.(pinger)
+	if !ok || srv.PingInterval <= 0 {
+		return func() {}
+	}
+	p.SetPongHandler(func(string) error {
+		s.Touch()
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(srv.PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := p.WriteControl(websocket.PingMessage, nil, time.Now().Add(pingWriteTimeout)); err != nil {
+					logrus.WithError(err).WithField("session", s.ID).Debug("signaling: failed to send ping")
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// startReaper 第一个会话开始时启动后台回收
+func (srv *Server) startReaper() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.reaperStop != nil || srv.closed {
+		return
+	}
+	stop := make(chan struct{})
+	srv.reaperStop = stop
+
+	go func() {
+		ticker := time.NewTicker(reapInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				srv.reap(now)
+				srv.refresh()
+			}
+		}
+	}()
+}
+
+// reap 关闭超过空闲超时的会话，返回关闭的数量。半开连接上的读取不会出错，
+// 只能靠关闭连接让 Serve 返回并释放传输与音频设备
+func (srv *Server) reap(now time.Time) int {
+	reaped := 0
+	for _, s := range srv.Sessions.All() {
+		timeout := s.IdleTimeout
+		if timeout == 0 {
+			timeout = srv.IdleTimeout
+		}
+		idle := now.Sub(s.LastSeen())
+		if timeout <= 0 || idle < timeout {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{"session": s.ID, "idle": idle}).Warn("signaling: reaping idle session")
+		s.Close(CloseReasonIdle)
+		reaped++
+	}
+	return reaped
+}
+
+// register 在 Store 中登记会话，失败时会话仍可在本节点使用，只是其它节点找不到它
+func (srv *Server) register(s *Session) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	info := SessionInfo{ID: s.ID, NodeID: srv.NodeID, CreatedAt: s.CreatedAt}
+	if err := srv.Store.Register(ctx, info, srv.SessionTTL); err != nil {
+		logrus.WithError(err).WithField("session", s.ID).Warn("signaling: failed to register session")
+	}
+}
+
+func (srv *Server) unregister(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := srv.Store.Unregister(ctx, id); err != nil {
+		logrus.WithError(err).WithField("session", id).Warn("signaling: failed to unregister session")
+	}
+}
+
+// refresh 续期本节点所有会话的记录
+func (srv *Server) refresh() {
+	for _, s := range srv.Sessions.All() {
+		srv.register(s)
+	}
+}
+
+// subscribe 第一个会话开始时订阅转发给本节点的指令，失败时由下一个会话重试
+func (srv *Server) subscribe() {
+	srv.mu.Lock()
+	if srv.subscribed || srv.closed {
+		srv.mu.Unlock()
+		return
+	}
+	srv.subscribed = true
+	srv.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	unsubscribe, err := srv.Store.Subscribe(ctx, srv.NodeID, srv.handleForward)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if err != nil {
+		srv.subscribed = false
+		logrus.WithError(err).WithField("node", srv.NodeID).Warn("signaling: failed to subscribe to forwarded messages")
+		return
+	}
+	if srv.closed {
+		// 订阅期间服务已关闭
+		go unsubscribe()
+		return
+	}
+	srv.unsubscribe = unsubscribe
+}
+
+// handleForward 执行其它节点转发来的指令
+func (srv *Server) handleForward(fwd Forward) {
+	s, ok := srv.Sessions.Get(fwd.SessionID)
+	if !ok {
+		logrus.WithField("session", fwd.SessionID).Debug("signaling: forwarded message for unknown session")
+		return
+	}
+	if err := apply(s, fwd); err != nil {
+		logrus.WithError(err).WithField("session", s.ID).Warn("signaling: failed to deliver forwarded message")
+	}
+}
+
+func apply(s *Session, fwd Forward) error {
+	if fwd.Message != nil {
+		if err := s.WriteJSON(*fwd.Message); err != nil {
+			return err
+		}
+	}
+	if fwd.Close != "" {
+		s.Close(fwd.Close)
+	}
+	return nil
+}
+
+// SendTo 向集群中任一节点上的会话发送消息：会话在本节点时直接写入，
+// 否则转发给会话所在节点。会话不存在时返回 ErrSessionNotFound
+func (srv *Server) SendTo(ctx context.Context, sessionID string, msg SignalMessage) error {
+	if msg.SessionID == "" {
+		msg.SessionID = sessionID
+	}
+	return srv.forward(ctx, Forward{SessionID: sessionID, Message: &msg})
+}
+
+// CloseSession 以给定原因关闭集群中任一节点上的会话
+func (srv *Server) CloseSession(ctx context.Context, sessionID, reason string) error {
+	return srv.forward(ctx, Forward{SessionID: sessionID, Close: reason})
+}
+
+func (srv *Server) forward(ctx context.Context, fwd Forward) error {
+	if s, ok := srv.Sessions.Get(fwd.SessionID); ok {
+		return apply(s, fwd)
+	}
+	info, err := srv.Store.Lookup(ctx, fwd.SessionID)
+	if err != nil {
+		return err
+	}
+	if info.NodeID == srv.NodeID {
+		// 本节点已没有该会话，记录是尚未过期的残留
+		return ErrSessionNotFound
+	}
+	return srv.Store.Publish(ctx, info.NodeID, fwd)
+}
+
+// Closed 报告服务是否已开始关闭，调用方可据此在建立传输前拒绝请求
+func (srv *Server) Closed() bool {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+	return srv.closed
+}
+
+// Shutdown 停止接受新会话，等待现有会话自然结束；ctx 到期时以 CloseReasonShutdown
+// 关闭剩余会话并返回 ctx 的错误
+func (srv *Server) Shutdown(ctx context.Context) error {
+	srv.mu.Lock()
+	srv.closed = true
+	srv.mu.Unlock()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	var err error
+	for err == nil && srv.Sessions.Len() > 0 {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	srv.Close()
+	return err
+}
+
+// Close 停止后台回收与转发订阅并关闭所有会话
+func (srv *Server) Close() {
+	srv.mu.Lock()
+	srv.closed = true
+	if srv.reaperStop != nil {
+		close(srv.reaperStop)
+		srv.reaperStop = nil
+	}
+	unsubscribe := srv.unsubscribe
+	srv.unsubscribe = nil
+	srv.mu.Unlock()
+
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+
+	for _, s := range srv.Sessions.All() {
+		s.Close(CloseReasonShutdown)
+	}
+}
+
+// HandleOffer 设置远端描述并回复 answer；trickle 客户端的本地候选者随生成逐个发送
+func HandleOffer(s *Session, msg SignalMessage) error {
+	if s.Transport == nil {
+		return ErrNoTransport
+	}
+	var offer OfferData
+	if err := msg.Decode(&offer); err != nil || offer.SDP == "" {
+		return fmt.Errorf("%w: offer without sdp", ErrInvalidMessage)
+	}
+
+	if err := s.Transport.SetRemoteDescription(offer.SDP); err != nil {
+		return fmt.Errorf("set remote description: %w", err)
+	}
+
+	// Trickle 客户端单独发送候选者，answer 无需等待本地候选者收集完成
+	if offer.Trickle {
+		s.Transport.OnICECandidate(func(candidate string) {
+			if err := s.Send(TypeCandidate, CandidateData{Candidate: candidate}); err != nil {
+				logrus.WithError(err).WithField("session", s.ID).Warn("signaling: failed to send ICE candidate")
+			}
+		})
+	}
+
+	answer, candidates, err := s.Transport.CreateAnswer(offer.Candidates)
+	if err != nil {
+		return fmt.Errorf("create answer: %w", err)
+	}
+	return s.Send(TypeAnswer, AnswerData{SDP: answer, Candidates: candidates, ICERestart: offer.ICERestart})
+}
+
+// HandleCandidate 添加客户端 trickle 的 ICE 候选者
+func HandleCandidate(s *Session, msg SignalMessage) error {
+	if s.Transport == nil {
+		return ErrNoTransport
+	}
+	var data CandidateData
+	if err := msg.Decode(&data); err != nil || data.Candidate == "" {
+		return fmt.Errorf("%w: empty candidate", ErrInvalidMessage)
+	}
+	return s.Transport.AddICECandidate(data.Candidate)
+}
@@ -0,0 +1,357 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConn 用通道模拟客户端：in 为客户端发送的消息，out 记录服务端写出的消息
+type fakeConn struct {
+	in        chan SignalMessage
+	out       chan SignalMessage
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		in:   make(chan SignalMessage, 8),
+		out:  make(chan SignalMessage, 8),
+		done: make(chan struct{}),
+	}
+}
+
+func (c *fakeConn) ReadJSON(v interface{}) error {
+	select {
+	case msg, ok := <-c.in:
+		if !ok {
+			return io.EOF
+		}
+		*v.(*SignalMessage) = msg
+		return nil
+	case <-c.done:
+		return errors.New("use of closed connection")
+	}
+}
+
+func (c *fakeConn) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return nil
+}
+
+// pingConn 额外支持 ping/pong，每次 ping 立即回 pong
+type pingConn struct {
+	*fakeConn
+	pings chan struct{}
+	pong  func(string) error
+}
+
+func (c *pingConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	c.pings <- struct{}{}
+	return c.pong("")
+}
+
+func (c *pingConn) SetPongHandler(h func(appData string) error) {
+	c.pong = h
+}
+
+func (c *fakeConn) WriteJSON(v interface{}) error {
+	// 经过一次 JSON 编解码，与实际写到连接上的内容一致
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var msg SignalMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return err
+	}
+	c.out <- msg
+	return nil
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	msg, err := NewMessage(TypeOffer, "s1", OfferData{SDP: "v=0", Trickle: true})
+	require.NoError(t, err)
+
+	raw, err := json.Marshal(msg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"offer","session_id":"s1","data":{"sdp":"v=0","trickle":true}}`, string(raw))
+
+	var decoded SignalMessage
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	var offer OfferData
+	require.NoError(t, decoded.Decode(&offer))
+	assert.Equal(t, "v=0", offer.SDP)
+	assert.True(t, offer.Trickle)
+
+	initMsg, err := NewMessage(TypeInit, "s1", nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, initMsg.Decode(&offer), ErrNoData)
+}
+
+func TestServeDispatchesMessages(t *testing.T) {
+	srv := NewServer()
+	var connected []string
+	srv.Handle(TypeConnected, func(s *Session, msg SignalMessage) error {
+		connected = append(connected, s.ID)
+		return nil
+	})
+	closed := false
+	srv.OnClose = func(s *Session) { closed = true }
+
+	conn := newFakeConn()
+	session := NewSession("s1", conn, nil)
+	conn.in <- SignalMessage{Type: "unknown"}
+	conn.in <- SignalMessage{Type: TypeConnected}
+	conn.in <- SignalMessage{Type: TypeOffer, Data: json.RawMessage(`{"sdp":"v=0"}`)}
+	conn.in <- SignalMessage{Type: TypeClose}
+
+	require.NoError(t, srv.Serve(session))
+
+	initMsg := <-conn.out
+	assert.Equal(t, TypeInit, initMsg.Type)
+	assert.Equal(t, "s1", initMsg.SessionID)
+
+	// 未关联 Transport 时 offer 返回错误消息，未知类型被忽略
+	errMsg := <-conn.out
+	assert.Equal(t, TypeError, errMsg.Type)
+	var data ErrorData
+	require.NoError(t, errMsg.Decode(&data))
+	assert.Equal(t, ErrCodeWebRTCFailed, data.Code)
+	assert.Empty(t, conn.out)
+
+	assert.Equal(t, []string{"s1"}, connected)
+	assert.True(t, closed)
+	assert.Equal(t, 0, srv.Sessions.Len())
+	assert.Equal(t, CloseReasonClient, session.CloseReason())
+}
+
+func TestReapIdleSession(t *testing.T) {
+	closedEvents := make(chan events.Event, 4)
+	events.GetEventBus().Subscribe(EventSessionClosed, func(event events.Event) error {
+		closedEvents <- event
+		return nil
+	})
+	defer events.GetEventBus().Unsubscribe(EventSessionClosed)
+
+	srv := NewServer()
+	defer srv.Close()
+	conn := newFakeConn()
+	session := NewSession("idle", conn, nil)
+	session.IdleTimeout = time.Minute
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(session) }()
+	<-conn.out // init
+
+	// 未超过会话自己的超时（比服务默认值长）时不回收
+	assert.Equal(t, 0, srv.reap(time.Now().Add(DefaultIdleTimeout+time.Second)))
+	assert.Equal(t, 1, srv.reap(time.Now().Add(2*time.Minute)))
+
+	select {
+	case err := <-served:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after the session was reaped")
+	}
+	assert.Equal(t, CloseReasonIdle, session.CloseReason())
+	assert.Equal(t, 0, srv.Sessions.Len())
+
+	select {
+	case event := <-closedEvents:
+		assert.Equal(t, "idle", event.Data["session_id"])
+		assert.Equal(t, CloseReasonIdle, event.Data["reason"])
+	case <-time.After(time.Second):
+		t.Fatal("no session_closed event")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	drainPollInterval = time.Millisecond
+	srv := NewServer()
+
+	serve := func(id string) (*fakeConn, *Session, chan error) {
+		conn := newFakeConn()
+		session := NewSession(id, conn, nil)
+		served := make(chan error, 1)
+		go func() { served <- srv.Serve(session) }()
+		<-conn.out // init
+		return conn, session, served
+	}
+	leaving, _, leavingDone := serve("leaving")
+	_, staying, stayingDone := serve("staying")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- srv.Shutdown(ctx) }()
+
+	// 关闭期间拒绝新会话，已有会话可以正常结束
+	require.Eventually(t, srv.Closed, time.Second, time.Millisecond)
+	rejected := NewSession("late", newFakeConn(), nil)
+	assert.ErrorIs(t, srv.Serve(rejected), ErrServerClosed)
+	assert.Equal(t, CloseReasonShutdown, rejected.CloseReason())
+
+	leaving.in <- SignalMessage{Type: TypeClose}
+	assert.NoError(t, <-leavingDone)
+
+	// 超过期限后强制关闭剩余会话
+	assert.ErrorIs(t, <-shutdown, context.DeadlineExceeded)
+	assert.Error(t, <-stayingDone)
+	assert.Equal(t, CloseReasonShutdown, staying.CloseReason())
+	assert.Equal(t, 0, srv.Sessions.Len())
+
+	// 没有会话时立即返回
+	assert.NoError(t, NewServer().Shutdown(context.Background()))
+}
+
+func TestKeepalive(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.PingInterval = 10 * time.Millisecond
+
+	conn := &pingConn{fakeConn: newFakeConn(), pings: make(chan struct{}, 8)}
+	session := NewSession("", conn, nil)
+	session.lastSeen.Store(0)
+
+	go srv.Serve(session)
+	select {
+	case <-conn.pings:
+	case <-time.After(time.Second):
+		t.Fatal("no ping sent")
+	}
+	// pong 刷新了活跃时间
+	assert.WithinDuration(t, time.Now(), session.LastSeen(), time.Second)
+	conn.in <- SignalMessage{Type: TypeClose}
+}
+
+func TestServeReturnsReadError(t *testing.T) {
+	srv := NewServer()
+	conn := newFakeConn()
+	close(conn.in)
+	assert.ErrorIs(t, srv.Serve(NewSession("", conn, nil)), io.EOF)
+}
+
+func TestDispatchErrors(t *testing.T) {
+	err := HandleCandidate(&Session{}, SignalMessage{Type: TypeCandidate})
+	assert.ErrorIs(t, err, ErrNoTransport)
+
+	err = NewServer().Dispatch(&Session{}, SignalMessage{Type: "unknown"})
+	assert.ErrorIs(t, err, ErrUnknownMessage)
+}
+
+func TestSessionWriterAndValues(t *testing.T) {
+	conn := newFakeConn()
+	session := NewSession("", conn, nil)
+	assert.NotEmpty(t, session.ID)
+
+	var written []interface{}
+	session.SetWriter(func(v interface{}) error {
+		written = append(written, v)
+		return nil
+	})
+	require.NoError(t, session.SendError(ErrCodeInvalidMessage, "bad"))
+	assert.Len(t, written, 1)
+	assert.Empty(t, conn.out)
+
+	session.Set("key", 42)
+	value, ok := session.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, 42, value)
+}
+
+func TestAuthStatus(t *testing.T) {
+	assert.Equal(t, http.StatusForbidden, AuthStatus(&AuthError{Status: http.StatusForbidden, Message: "denied"}))
+	assert.Equal(t, http.StatusUnauthorized, AuthStatus(errors.New("bad credentials")))
+
+	srv := NewServer()
+	identity, err := srv.Authenticate(&http.Request{})
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+}
+
+func TestSessionLimit(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.IdentityKey = func(identity interface{}) string {
+		user, _ := identity.(string)
+		return user
+	}
+	srv.MaxSessionsPerIdentity = 1
+
+	serve := func(id, user string) (*fakeConn, *Session, chan error) {
+		conn := newFakeConn()
+		session := NewSession(id, conn, nil)
+		session.Identity = user
+		served := make(chan error, 1)
+		go func() { served <- srv.Serve(session) }()
+		return conn, session, served
+	}
+
+	first, _, firstDone := serve("s1", "alice")
+	<-first.out // init
+	assert.Equal(t, 1, srv.ActiveSessions("alice"))
+
+	// 同一用户的第二个会话被拒绝，其他用户不受影响
+	second, rejected, secondDone := serve("s2", "alice")
+	assert.ErrorIs(t, <-secondDone, ErrTooManySessions)
+	errMsg := <-second.out
+	var data ErrorData
+	require.NoError(t, errMsg.Decode(&data))
+	assert.Equal(t, ErrCodeTooManySessions, data.Code)
+	assert.Equal(t, CloseReasonLimit, rejected.CloseReason())
+
+	other, _, otherDone := serve("s3", "bob")
+	<-other.out
+	other.in <- SignalMessage{Type: TypeClose}
+	assert.NoError(t, <-otherDone)
+
+	// 会话结束后归还名额
+	first.in <- SignalMessage{Type: TypeClose}
+	assert.NoError(t, <-firstDone)
+	assert.Equal(t, 0, srv.ActiveSessions("alice"))
+	again, _, againDone := serve("s4", "alice")
+	<-again.out
+	again.in <- SignalMessage{Type: TypeClose}
+	assert.NoError(t, <-againDone)
+}
+
+func TestAllowOrigins(t *testing.T) {
+	request := func(origin string) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, "/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		return r
+	}
+	check := AllowOrigins([]string{"https://app.example.com", "http://localhost*"})
+	assert.True(t, check(request("https://app.example.com")))
+	assert.True(t, check(request("http://localhost:5173")))
+	assert.True(t, check(request("")), "non-browser clients send no Origin")
+	assert.False(t, check(request("https://evil.example.com")))
+
+	// 未配置时只允许同源
+	sameOrigin := request("https://app.example.com")
+	sameOrigin.Host = "app.example.com"
+	assert.True(t, AllowOrigins(nil)(sameOrigin))
+	assert.False(t, AllowOrigins(nil)(request("https://any.example.com")))
+	assert.True(t, AllowOrigins(nil)(request("")))
+	assert.True(t, AllowOrigins([]string{"*"})(request("https://any.example.com")))
+}
+
+func TestBearerToken(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "/ws?token=from-query", nil)
+	assert.Equal(t, "from-query", BearerToken(r))
+	r.Header.Set("Authorization", "Bearer from-header")
+	assert.Equal(t, "from-header", BearerToken(r))
+}
@@ -0,0 +1,207 @@
+package signaling
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
+)
+
+// Conn 信令连接，*websocket.Conn 即满足
+type Conn interface {
+	ReadJSON(v interface{}) error
+	WriteJSON(v interface{}) error
+}
+
+// Session 一个信令连接及其 WebRTC 传输
+type Session struct {
+	ID        string
+	Transport *rtcmedia.WebRTCTransport
+	Identity  interface{} // Authenticator 返回的身份，未配置认证时为 nil
+	CreatedAt time.Time
+	// IdleTimeout 覆盖 Server.IdleTimeout，0 表示使用服务的默认值
+	IdleTimeout time.Duration
+
+	conn     Conn
+	writeMu  sync.Mutex
+	writer   func(v interface{}) error
+	lastSeen atomic.Int64 // 最近一次收到消息或 pong 的时间（UnixNano）
+
+	closeOnce   sync.Once
+	closeReason string
+
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+// 会话关闭原因，随 session_closed 事件发布
+const (
+	CloseReasonClient   = "client_closed"    // 客户端发送 close/disconnect
+	CloseReasonIdle     = "idle_timeout"     // 超过空闲超时未收到消息或 pong，被回收
+	CloseReasonError    = "connection_error" // 读取信令连接失败
+	CloseReasonShutdown = "server_shutdown"  // 服务关闭
+	CloseReasonLimit    = "session_limit"    // 同一身份的并发会话数已达上限
+)
+
+// NewSessionID 生成会话 ID
+func NewSessionID() string {
+	return fmt.Sprintf("session_%d", time.Now().UnixNano())
+}
+
+// NewSession 创建会话，id 为空时自动生成
+func NewSession(id string, conn Conn, transport *rtcmedia.WebRTCTransport) *Session {
+	if id == "" {
+		id = NewSessionID()
+	}
+	s := &Session{
+		ID:        id,
+		Transport: transport,
+		CreatedAt: time.Now(),
+		conn:      conn,
+		values:    make(map[string]interface{}),
+	}
+	s.Touch()
+	return s
+}
+
+// Conn 返回底层信令连接
+func (s *Session) Conn() Conn {
+	return s.conn
+}
+
+// Touch 标记会话活跃，推迟空闲回收
+func (s *Session) Touch() {
+	s.lastSeen.Store(time.Now().UnixNano())
+}
+
+// LastSeen 最近一次活跃的时间
+func (s *Session) LastSeen() time.Time {
+	return time.Unix(0, s.lastSeen.Load())
+}
+
+// Close 以给定原因关闭会话：关闭 WebRTC 传输，连接实现 io.Closer 时一并关闭，
+// 阻塞在读取上的 Serve 随即返回。只有第一次调用生效
+func (s *Session) Close(reason string) {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.closeReason = reason
+		s.mu.Unlock()
+
+		if s.Transport != nil {
+			s.Transport.Close()
+		}
+		if closer, ok := s.conn.(io.Closer); ok {
+			closer.Close()
+		}
+	})
+}
+
+// CloseReason 返回关闭原因，未关闭时为空
+func (s *Session) CloseReason() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closeReason
+}
+
+// SetWriter 替换消息写入函数。连接同时由其它组件写入（如 AIClient）时，
+// 传入该组件的写入方法，使所有写入共用同一把锁
+func (s *Session) SetWriter(writer func(v interface{}) error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.writer = writer
+}
+
+// WriteJSON 写入一条消息，可并发调用（trickle 候选者在 ICE 回调中发送）
+func (s *Session) WriteJSON(v interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.writer != nil {
+		return s.writer(v)
+	}
+	return s.conn.WriteJSON(v)
+}
+
+// Send 发送一条带本会话 ID 的消息
+func (s *Session) Send(msgType MessageType, data interface{}) error {
+	msg, err := NewMessage(msgType, s.ID, data)
+	if err != nil {
+		return err
+	}
+	return s.WriteJSON(msg)
+}
+
+// SendError 发送错误消息
+func (s *Session) SendError(code, message string) error {
+	return s.Send(TypeError, ErrorData{Code: code, Message: message})
+}
+
+// Set 保存与会话关联的值（如处理该会话的 AIClient）
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+}
+
+// Get 读取与会话关联的值
+func (s *Session) Get(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// SessionManager 管理进行中的会话
+type SessionManager struct {
+	sessions map[string]*Session
+	mu       sync.RWMutex
+}
+
+// NewSessionManager 创建会话管理器
+func NewSessionManager() *SessionManager {
+	return &SessionManager{
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Add 添加会话
+func (m *SessionManager) Add(session *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[session.ID] = session
+}
+
+// Get 按 ID 获取会话
+func (m *SessionManager) Get(id string) (*Session, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, ok := m.sessions[id]
+	return session, ok
+}
+
+// Remove 移除会话
+func (m *SessionManager) Remove(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+}
+
+// Len 会话数
+func (m *SessionManager) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.sessions)
+}
+
+// All 返回所有会话的快照
+func (m *SessionManager) All() []*Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sessions := make([]*Session, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
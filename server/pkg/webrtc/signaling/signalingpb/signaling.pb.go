@@ -0,0 +1,871 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: signaling.proto
+
+package signalingpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ClientMessage 客户端 → 服务端
+type ClientMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*ClientMessage_Hello
+	//	*ClientMessage_Offer
+	//	*ClientMessage_Candidate
+	//	*ClientMessage_Connected
+	//	*ClientMessage_Close
+	Message       isClientMessage_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClientMessage) Reset() {
+	*x = ClientMessage{}
+	mi := &file_signaling_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientMessage) ProtoMessage() {}
+
+func (x *ClientMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_signaling_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientMessage.ProtoReflect.Descriptor instead.
+func (*ClientMessage) Descriptor() ([]byte, []int) {
+	return file_signaling_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ClientMessage) GetMessage() isClientMessage_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetHello() *Hello {
+	if x != nil {
+		if x, ok := x.Message.(*ClientMessage_Hello); ok {
+			return x.Hello
+		}
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetOffer() *Offer {
+	if x != nil {
+		if x, ok := x.Message.(*ClientMessage_Offer); ok {
+			return x.Offer
+		}
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetCandidate() *Candidate {
+	if x != nil {
+		if x, ok := x.Message.(*ClientMessage_Candidate); ok {
+			return x.Candidate
+		}
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetConnected() *Connected {
+	if x != nil {
+		if x, ok := x.Message.(*ClientMessage_Connected); ok {
+			return x.Connected
+		}
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetClose() *Close {
+	if x != nil {
+		if x, ok := x.Message.(*ClientMessage_Close); ok {
+			return x.Close
+		}
+	}
+	return nil
+}
+
+type isClientMessage_Message interface {
+	isClientMessage_Message()
+}
+
+type ClientMessage_Hello struct {
+	Hello *Hello `protobuf:"bytes,1,opt,name=hello,proto3,oneof"`
+}
+
+type ClientMessage_Offer struct {
+	Offer *Offer `protobuf:"bytes,2,opt,name=offer,proto3,oneof"`
+}
+
+type ClientMessage_Candidate struct {
+	Candidate *Candidate `protobuf:"bytes,3,opt,name=candidate,proto3,oneof"`
+}
+
+type ClientMessage_Connected struct {
+	Connected *Connected `protobuf:"bytes,4,opt,name=connected,proto3,oneof"`
+}
+
+type ClientMessage_Close struct {
+	Close *Close `protobuf:"bytes,5,opt,name=close,proto3,oneof"`
+}
+
+func (*ClientMessage_Hello) isClientMessage_Message() {}
+
+func (*ClientMessage_Offer) isClientMessage_Message() {}
+
+func (*ClientMessage_Candidate) isClientMessage_Message() {}
+
+func (*ClientMessage_Connected) isClientMessage_Message() {}
+
+func (*ClientMessage_Close) isClientMessage_Message() {}
+
+// ServerMessage 服务端 → 客户端
+type ServerMessage struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SessionId string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*ServerMessage_Init
+	//	*ServerMessage_Answer
+	//	*ServerMessage_Candidate
+	//	*ServerMessage_Error
+	//	*ServerMessage_Event
+	Message       isServerMessage_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServerMessage) Reset() {
+	*x = ServerMessage{}
+	mi := &file_signaling_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerMessage) ProtoMessage() {}
+
+func (x *ServerMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_signaling_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerMessage.ProtoReflect.Descriptor instead.
+func (*ServerMessage) Descriptor() ([]byte, []int) {
+	return file_signaling_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ServerMessage) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ServerMessage) GetMessage() isServerMessage_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetInit() *Init {
+	if x != nil {
+		if x, ok := x.Message.(*ServerMessage_Init); ok {
+			return x.Init
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetAnswer() *Answer {
+	if x != nil {
+		if x, ok := x.Message.(*ServerMessage_Answer); ok {
+			return x.Answer
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetCandidate() *Candidate {
+	if x != nil {
+		if x, ok := x.Message.(*ServerMessage_Candidate); ok {
+			return x.Candidate
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetError() *Error {
+	if x != nil {
+		if x, ok := x.Message.(*ServerMessage_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetEvent() *Event {
+	if x != nil {
+		if x, ok := x.Message.(*ServerMessage_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+type isServerMessage_Message interface {
+	isServerMessage_Message()
+}
+
+type ServerMessage_Init struct {
+	Init *Init `protobuf:"bytes,2,opt,name=init,proto3,oneof"`
+}
+
+type ServerMessage_Answer struct {
+	Answer *Answer `protobuf:"bytes,3,opt,name=answer,proto3,oneof"`
+}
+
+type ServerMessage_Candidate struct {
+	Candidate *Candidate `protobuf:"bytes,4,opt,name=candidate,proto3,oneof"`
+}
+
+type ServerMessage_Error struct {
+	Error *Error `protobuf:"bytes,5,opt,name=error,proto3,oneof"`
+}
+
+type ServerMessage_Event struct {
+	Event *Event `protobuf:"bytes,6,opt,name=event,proto3,oneof"`
+}
+
+func (*ServerMessage_Init) isServerMessage_Message() {}
+
+func (*ServerMessage_Answer) isServerMessage_Message() {}
+
+func (*ServerMessage_Candidate) isServerMessage_Message() {}
+
+func (*ServerMessage_Error) isServerMessage_Message() {}
+
+func (*ServerMessage_Event) isServerMessage_Message() {}
+
+// Hello 流的第一条消息，params 与 WebSocket 接口的查询参数同名（apiKey、apiSecret、assistantId、codec 等）。
+// 登录令牌也可以通过 authorization metadata 以 "Bearer <token>" 传递
+type Hello struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Params        map[string]string      `protobuf:"bytes,1,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Hello) Reset() {
+	*x = Hello{}
+	mi := &file_signaling_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Hello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hello) ProtoMessage() {}
+
+func (x *Hello) ProtoReflect() protoreflect.Message {
+	mi := &file_signaling_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hello.ProtoReflect.Descriptor instead.
+func (*Hello) Descriptor() ([]byte, []int) {
+	return file_signaling_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Hello) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+// Init 会话已建立，ServerMessage.session_id 为分配的会话 ID
+type Init struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Init) Reset() {
+	*x = Init{}
+	mi := &file_signaling_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Init) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Init) ProtoMessage() {}
+
+func (x *Init) ProtoReflect() protoreflect.Message {
+	mi := &file_signaling_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Init.ProtoReflect.Descriptor instead.
+func (*Init) Descriptor() ([]byte, []int) {
+	return file_signaling_proto_rawDescGZIP(), []int{3}
+}
+
+type Offer struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Sdp   string                 `protobuf:"bytes,1,opt,name=sdp,proto3" json:"sdp,omitempty"`
+	// 非 trickle 客户端随 offer 一起发送的候选者
+	Candidates []string `protobuf:"bytes,2,rep,name=candidates,proto3" json:"candidates,omitempty"`
+	// 候选者以单独的 Candidate 消息发送
+	Trickle bool `protobuf:"varint,3,opt,name=trickle,proto3" json:"trickle,omitempty"`
+	// 网络切换后的重新协商
+	IceRestart    bool `protobuf:"varint,4,opt,name=ice_restart,json=iceRestart,proto3" json:"ice_restart,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Offer) Reset() {
+	*x = Offer{}
+	mi := &file_signaling_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Offer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Offer) ProtoMessage() {}
+
+func (x *Offer) ProtoReflect() protoreflect.Message {
+	mi := &file_signaling_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Offer.ProtoReflect.Descriptor instead.
+func (*Offer) Descriptor() ([]byte, []int) {
+	return file_signaling_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Offer) GetSdp() string {
+	if x != nil {
+		return x.Sdp
+	}
+	return ""
+}
+
+func (x *Offer) GetCandidates() []string {
+	if x != nil {
+		return x.Candidates
+	}
+	return nil
+}
+
+func (x *Offer) GetTrickle() bool {
+	if x != nil {
+		return x.Trickle
+	}
+	return false
+}
+
+func (x *Offer) GetIceRestart() bool {
+	if x != nil {
+		return x.IceRestart
+	}
+	return false
+}
+
+type Answer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sdp           string                 `protobuf:"bytes,1,opt,name=sdp,proto3" json:"sdp,omitempty"`
+	Candidates    []string               `protobuf:"bytes,2,rep,name=candidates,proto3" json:"candidates,omitempty"`
+	IceRestart    bool                   `protobuf:"varint,3,opt,name=ice_restart,json=iceRestart,proto3" json:"ice_restart,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Answer) Reset() {
+	*x = Answer{}
+	mi := &file_signaling_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Answer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Answer) ProtoMessage() {}
+
+func (x *Answer) ProtoReflect() protoreflect.Message {
+	mi := &file_signaling_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Answer.ProtoReflect.Descriptor instead.
+func (*Answer) Descriptor() ([]byte, []int) {
+	return file_signaling_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Answer) GetSdp() string {
+	if x != nil {
+		return x.Sdp
+	}
+	return ""
+}
+
+func (x *Answer) GetCandidates() []string {
+	if x != nil {
+		return x.Candidates
+	}
+	return nil
+}
+
+func (x *Answer) GetIceRestart() bool {
+	if x != nil {
+		return x.IceRestart
+	}
+	return false
+}
+
+// Candidate trickle ICE 候选者
+type Candidate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Candidate     string                 `protobuf:"bytes,1,opt,name=candidate,proto3" json:"candidate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Candidate) Reset() {
+	*x = Candidate{}
+	mi := &file_signaling_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Candidate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Candidate) ProtoMessage() {}
+
+func (x *Candidate) ProtoReflect() protoreflect.Message {
+	mi := &file_signaling_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Candidate.ProtoReflect.Descriptor instead.
+func (*Candidate) Descriptor() ([]byte, []int) {
+	return file_signaling_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Candidate) GetCandidate() string {
+	if x != nil {
+		return x.Candidate
+	}
+	return ""
+}
+
+// Connected WebRTC 已连通
+type Connected struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Connected) Reset() {
+	*x = Connected{}
+	mi := &file_signaling_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Connected) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Connected) ProtoMessage() {}
+
+func (x *Connected) ProtoReflect() protoreflect.Message {
+	mi := &file_signaling_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Connected.ProtoReflect.Descriptor instead.
+func (*Connected) Descriptor() ([]byte, []int) {
+	return file_signaling_proto_rawDescGZIP(), []int{7}
+}
+
+// Close 结束会话
+type Close struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Close) Reset() {
+	*x = Close{}
+	mi := &file_signaling_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Close) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Close) ProtoMessage() {}
+
+func (x *Close) ProtoReflect() protoreflect.Message {
+	mi := &file_signaling_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Close.ProtoReflect.Descriptor instead.
+func (*Close) Descriptor() ([]byte, []int) {
+	return file_signaling_proto_rawDescGZIP(), []int{8}
+}
+
+type Error struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_signaling_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_signaling_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_signaling_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Error) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Event 其它服务端消息（如识别与回复文本），data 为 WebSocket 消息中 data 字段的 JSON
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_signaling_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_signaling_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_signaling_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+var File_signaling_proto protoreflect.FileDescriptor
+
+const file_signaling_proto_rawDesc = "" +
+	"\n" +
+	"\x0fsignaling.proto\x12\x15lingecho.signaling.v1\"\xc0\x02\n" +
+	"\rClientMessage\x124\n" +
+	"\x05hello\x18\x01 \x01(\v2\x1c.lingecho.signaling.v1.HelloH\x00R\x05hello\x124\n" +
+	"\x05offer\x18\x02 \x01(\v2\x1c.lingecho.signaling.v1.OfferH\x00R\x05offer\x12@\n" +
+	"\tcandidate\x18\x03 \x01(\v2 .lingecho.signaling.v1.CandidateH\x00R\tcandidate\x12@\n" +
+	"\tconnected\x18\x04 \x01(\v2 .lingecho.signaling.v1.ConnectedH\x00R\tconnected\x124\n" +
+	"\x05close\x18\x05 \x01(\v2\x1c.lingecho.signaling.v1.CloseH\x00R\x05closeB\t\n" +
+	"\amessage\"\xd3\x02\n" +
+	"\rServerMessage\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x121\n" +
+	"\x04init\x18\x02 \x01(\v2\x1b.lingecho.signaling.v1.InitH\x00R\x04init\x127\n" +
+	"\x06answer\x18\x03 \x01(\v2\x1d.lingecho.signaling.v1.AnswerH\x00R\x06answer\x12@\n" +
+	"\tcandidate\x18\x04 \x01(\v2 .lingecho.signaling.v1.CandidateH\x00R\tcandidate\x124\n" +
+	"\x05error\x18\x05 \x01(\v2\x1c.lingecho.signaling.v1.ErrorH\x00R\x05error\x124\n" +
+	"\x05event\x18\x06 \x01(\v2\x1c.lingecho.signaling.v1.EventH\x00R\x05eventB\t\n" +
+	"\amessage\"\x84\x01\n" +
+	"\x05Hello\x12@\n" +
+	"\x06params\x18\x01 \x03(\v2(.lingecho.signaling.v1.Hello.ParamsEntryR\x06params\x1a9\n" +
+	"\vParamsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x06\n" +
+	"\x04Init\"t\n" +
+	"\x05Offer\x12\x10\n" +
+	"\x03sdp\x18\x01 \x01(\tR\x03sdp\x12\x1e\n" +
+	"\n" +
+	"candidates\x18\x02 \x03(\tR\n" +
+	"candidates\x12\x18\n" +
+	"\atrickle\x18\x03 \x01(\bR\atrickle\x12\x1f\n" +
+	"\vice_restart\x18\x04 \x01(\bR\n" +
+	"iceRestart\"[\n" +
+	"\x06Answer\x12\x10\n" +
+	"\x03sdp\x18\x01 \x01(\tR\x03sdp\x12\x1e\n" +
+	"\n" +
+	"candidates\x18\x02 \x03(\tR\n" +
+	"candidates\x12\x1f\n" +
+	"\vice_restart\x18\x03 \x01(\bR\n" +
+	"iceRestart\")\n" +
+	"\tCandidate\x12\x1c\n" +
+	"\tcandidate\x18\x01 \x01(\tR\tcandidate\"\v\n" +
+	"\tConnected\"\a\n" +
+	"\x05Close\"5\n" +
+	"\x05Error\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"/\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data2f\n" +
+	"\tSignaling\x12Y\n" +
+	"\aConnect\x12$.lingecho.signaling.v1.ClientMessage\x1a$.lingecho.signaling.v1.ServerMessage(\x010\x01BGZEgithub.com/code-100-precent/LingEcho/pkg/webrtc/signaling/signalingpbb\x06proto3"
+
+var (
+	file_signaling_proto_rawDescOnce sync.Once
+	file_signaling_proto_rawDescData []byte
+)
+
+func file_signaling_proto_rawDescGZIP() []byte {
+	file_signaling_proto_rawDescOnce.Do(func() {
+		file_signaling_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_signaling_proto_rawDesc), len(file_signaling_proto_rawDesc)))
+	})
+	return file_signaling_proto_rawDescData
+}
+
+var file_signaling_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_signaling_proto_goTypes = []any{
+	(*ClientMessage)(nil), // 0: lingecho.signaling.v1.ClientMessage
+	(*ServerMessage)(nil), // 1: lingecho.signaling.v1.ServerMessage
+	(*Hello)(nil),         // 2: lingecho.signaling.v1.Hello
+	(*Init)(nil),          // 3: lingecho.signaling.v1.Init
+	(*Offer)(nil),         // 4: lingecho.signaling.v1.Offer
+	(*Answer)(nil),        // 5: lingecho.signaling.v1.Answer
+	(*Candidate)(nil),     // 6: lingecho.signaling.v1.Candidate
+	(*Connected)(nil),     // 7: lingecho.signaling.v1.Connected
+	(*Close)(nil),         // 8: lingecho.signaling.v1.Close
+	(*Error)(nil),         // 9: lingecho.signaling.v1.Error
+	(*Event)(nil),         // 10: lingecho.signaling.v1.Event
+	nil,                   // 11: lingecho.signaling.v1.Hello.ParamsEntry
+}
+var file_signaling_proto_depIdxs = []int32{
+	2,  // 0: lingecho.signaling.v1.ClientMessage.hello:type_name -> lingecho.signaling.v1.Hello
+	4,  // 1: lingecho.signaling.v1.ClientMessage.offer:type_name -> lingecho.signaling.v1.Offer
+	6,  // 2: lingecho.signaling.v1.ClientMessage.candidate:type_name -> lingecho.signaling.v1.Candidate
+	7,  // 3: lingecho.signaling.v1.ClientMessage.connected:type_name -> lingecho.signaling.v1.Connected
+	8,  // 4: lingecho.signaling.v1.ClientMessage.close:type_name -> lingecho.signaling.v1.Close
+	3,  // 5: lingecho.signaling.v1.ServerMessage.init:type_name -> lingecho.signaling.v1.Init
+	5,  // 6: lingecho.signaling.v1.ServerMessage.answer:type_name -> lingecho.signaling.v1.Answer
+	6,  // 7: lingecho.signaling.v1.ServerMessage.candidate:type_name -> lingecho.signaling.v1.Candidate
+	9,  // 8: lingecho.signaling.v1.ServerMessage.error:type_name -> lingecho.signaling.v1.Error
+	10, // 9: lingecho.signaling.v1.ServerMessage.event:type_name -> lingecho.signaling.v1.Event
+	11, // 10: lingecho.signaling.v1.Hello.params:type_name -> lingecho.signaling.v1.Hello.ParamsEntry
+	0,  // 11: lingecho.signaling.v1.Signaling.Connect:input_type -> lingecho.signaling.v1.ClientMessage
+	1,  // 12: lingecho.signaling.v1.Signaling.Connect:output_type -> lingecho.signaling.v1.ServerMessage
+	12, // [12:13] is the sub-list for method output_type
+	11, // [11:12] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_signaling_proto_init() }
+func file_signaling_proto_init() {
+	if File_signaling_proto != nil {
+		return
+	}
+	file_signaling_proto_msgTypes[0].OneofWrappers = []any{
+		(*ClientMessage_Hello)(nil),
+		(*ClientMessage_Offer)(nil),
+		(*ClientMessage_Candidate)(nil),
+		(*ClientMessage_Connected)(nil),
+		(*ClientMessage_Close)(nil),
+	}
+	file_signaling_proto_msgTypes[1].OneofWrappers = []any{
+		(*ServerMessage_Init)(nil),
+		(*ServerMessage_Answer)(nil),
+		(*ServerMessage_Candidate)(nil),
+		(*ServerMessage_Error)(nil),
+		(*ServerMessage_Event)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_signaling_proto_rawDesc), len(file_signaling_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_signaling_proto_goTypes,
+		DependencyIndexes: file_signaling_proto_depIdxs,
+		MessageInfos:      file_signaling_proto_msgTypes,
+	}.Build()
+	File_signaling_proto = out.File
+	file_signaling_proto_goTypes = nil
+	file_signaling_proto_depIdxs = nil
+}
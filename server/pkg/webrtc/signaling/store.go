@@ -0,0 +1,229 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrSessionNotFound 集群中没有该会话
+var ErrSessionNotFound = errors.New("signaling: session not found")
+
+// DefaultSessionTTL 会话记录的默认有效期，节点异常退出后记录在该时长后过期
+const DefaultSessionTTL = 60 * time.Second
+
+// SessionInfo 会话在集群中的登记信息
+type SessionInfo struct {
+	ID        string    `json:"id"`
+	NodeID    string    `json:"node_id"` // 持有 WebSocket 与 WebRTC 传输的节点
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Forward 转发给会话所在节点的指令
+type Forward struct {
+	SessionID string         `json:"session_id"`
+	Message   *SignalMessage `json:"message,omitempty"` // 发给客户端的消息
+	Close     string         `json:"close,omitempty"`   // 非空时以该原因关闭会话
+}
+
+// SessionStore 会话在集群中的登记表与节点间的转发通道。
+// WebSocket 与 WebRTC 传输只能留在建立连接的节点上，其它节点通过登记表找到
+// 会话所在节点，再把消息发布到该节点的通道
+type SessionStore interface {
+	// Register 登记或续期会话，记录在 ttl 后过期
+	Register(ctx context.Context, info SessionInfo, ttl time.Duration) error
+	// Unregister 删除会话记录
+	Unregister(ctx context.Context, id string) error
+	// Lookup 查询会话，不存在时返回 ErrSessionNotFound
+	Lookup(ctx context.Context, id string) (SessionInfo, error)
+	// Publish 把指令发给指定节点
+	Publish(ctx context.Context, nodeID string, fwd Forward) error
+	// Subscribe 接收发给本节点的指令，返回取消订阅的函数
+	Subscribe(ctx context.Context, nodeID string, handler func(Forward)) (func(), error)
+}
+
+// DefaultNodeID 返回默认的节点标识：主机名与进程号
+func DefaultNodeID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "node"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// MemoryStore 进程内的 SessionStore，单节点部署使用
+type MemoryStore struct {
+	mu          sync.RWMutex
+	sessions    map[string]memoryEntry
+	subscribers map[string]map[int]func(Forward)
+	nextSub     int
+}
+
+type memoryEntry struct {
+	info    SessionInfo
+	expires time.Time
+}
+
+// NewMemoryStore 创建进程内存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions:    make(map[string]memoryEntry),
+		subscribers: make(map[string]map[int]func(Forward)),
+	}
+}
+
+// Register 实现 SessionStore
+func (m *MemoryStore) Register(ctx context.Context, info SessionInfo, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[info.ID] = memoryEntry{info: info, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Unregister 实现 SessionStore
+func (m *MemoryStore) Unregister(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+// Lookup 实现 SessionStore
+func (m *MemoryStore) Lookup(ctx context.Context, id string) (SessionInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.sessions[id]
+	if !ok || time.Now().After(entry.expires) {
+		return SessionInfo{}, ErrSessionNotFound
+	}
+	return entry.info, nil
+}
+
+// Publish 实现 SessionStore，同步调用本进程内该节点的订阅者
+func (m *MemoryStore) Publish(ctx context.Context, nodeID string, fwd Forward) error {
+	m.mu.RLock()
+	handlers := make([]func(Forward), 0, len(m.subscribers[nodeID]))
+	for _, handler := range m.subscribers[nodeID] {
+		handlers = append(handlers, handler)
+	}
+	m.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(fwd)
+	}
+	return nil
+}
+
+// Subscribe 实现 SessionStore
+func (m *MemoryStore) Subscribe(ctx context.Context, nodeID string, handler func(Forward)) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.subscribers[nodeID] == nil {
+		m.subscribers[nodeID] = make(map[int]func(Forward))
+	}
+	id := m.nextSub
+	m.nextSub++
+	m.subscribers[nodeID][id] = handler
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.subscribers[nodeID], id)
+	}, nil
+}
+
+// DefaultRedisPrefix RedisStore 默认的键前缀
+const DefaultRedisPrefix = "lingecho:signaling:"
+
+// RedisStore 基于 Redis 的 SessionStore，多副本部署时共享会话登记并通过 pub/sub 转发信令。
+// 会话记录保存在 <prefix>session:<id>，节点通道为 <prefix>node:<nodeID>
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore 创建 Redis 存储，prefix 为空时使用 DefaultRedisPrefix
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (r *RedisStore) sessionKey(id string) string {
+	return r.prefix + "session:" + id
+}
+
+func (r *RedisStore) nodeChannel(nodeID string) string {
+	return r.prefix + "node:" + nodeID
+}
+
+// Register 实现 SessionStore
+func (r *RedisStore) Register(ctx context.Context, info SessionInfo, ttl time.Duration) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.sessionKey(info.ID), data, ttl).Err()
+}
+
+// Unregister 实现 SessionStore
+func (r *RedisStore) Unregister(ctx context.Context, id string) error {
+	return r.client.Del(ctx, r.sessionKey(id)).Err()
+}
+
+// Lookup 实现 SessionStore
+func (r *RedisStore) Lookup(ctx context.Context, id string) (SessionInfo, error) {
+	data, err := r.client.Get(ctx, r.sessionKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return SessionInfo{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	var info SessionInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return SessionInfo{}, fmt.Errorf("decode session %s: %w", id, err)
+	}
+	return info, nil
+}
+
+// Publish 实现 SessionStore。Redis pub/sub 不保证送达，目标节点不在线时指令被丢弃
+func (r *RedisStore) Publish(ctx context.Context, nodeID string, fwd Forward) error {
+	data, err := json.Marshal(fwd)
+	if err != nil {
+		return err
+	}
+	return r.client.Publish(ctx, r.nodeChannel(nodeID), data).Err()
+}
+
+// Subscribe 实现 SessionStore，订阅确认后才返回，保证此后发布的指令都能收到
+func (r *RedisStore) Subscribe(ctx context.Context, nodeID string, handler func(Forward)) (func(), error) {
+	pubsub := r.client.Subscribe(ctx, r.nodeChannel(nodeID))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range pubsub.Channel() {
+			var fwd Forward
+			if err := json.Unmarshal([]byte(msg.Payload), &fwd); err != nil {
+				logrus.WithError(err).Warn("signaling: invalid forwarded message")
+				continue
+			}
+			handler(fwd)
+		}
+	}()
+	return func() {
+		pubsub.Close()
+		<-done
+	}, nil
+}
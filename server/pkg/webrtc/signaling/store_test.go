@@ -0,0 +1,91 @@
+package signaling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardAcrossNodes(t *testing.T) {
+	// 两个节点共享同一登记表，会话建立在 a 上，由 b 发送信令
+	store := NewMemoryStore()
+	a, b := NewServer(), NewServer()
+	a.Store, a.NodeID = store, "a"
+	b.Store, b.NodeID = store, "b"
+	defer a.Close()
+	defer b.Close()
+
+	conn := newFakeConn()
+	session := NewSession("s1", conn, nil)
+	served := make(chan error, 1)
+	go func() { served <- a.Serve(session) }()
+	<-conn.out // init
+
+	ctx := context.Background()
+	info, err := store.Lookup(ctx, "s1")
+	require.NoError(t, err)
+	assert.Equal(t, "a", info.NodeID)
+
+	msg, err := NewMessage(TypeError, "", ErrorData{Code: "remote", Message: "from b"})
+	require.NoError(t, err)
+	require.NoError(t, b.SendTo(ctx, "s1", msg))
+	forwarded := <-conn.out
+	assert.Equal(t, TypeError, forwarded.Type)
+	assert.Equal(t, "s1", forwarded.SessionID)
+
+	require.NoError(t, b.CloseSession(ctx, "s1", CloseReasonShutdown))
+	assert.Error(t, <-served)
+	assert.Equal(t, CloseReasonShutdown, session.CloseReason())
+
+	_, err = store.Lookup(ctx, "s1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.ErrorIs(t, b.SendTo(ctx, "s1", msg), ErrSessionNotFound)
+}
+
+func TestMemoryStoreExpires(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	require.NoError(t, store.Register(ctx, SessionInfo{ID: "s1", NodeID: "a"}, time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, err := store.Lookup(ctx, "s1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestRedisStore(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379", DialTimeout: 500 * time.Millisecond})
+	defer client.Close()
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skip("Redis not available, skipping test")
+	}
+
+	store := NewRedisStore(client, "lingecho:test:signaling:")
+	info := SessionInfo{ID: "s1", NodeID: "a", CreatedAt: time.Now().UTC().Truncate(time.Second)}
+	require.NoError(t, store.Register(ctx, info, time.Minute))
+	defer store.Unregister(ctx, "s1")
+
+	got, err := store.Lookup(ctx, "s1")
+	require.NoError(t, err)
+	assert.Equal(t, info, got)
+
+	received := make(chan Forward, 1)
+	unsubscribe, err := store.Subscribe(ctx, "a", func(fwd Forward) { received <- fwd })
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	require.NoError(t, store.Publish(ctx, "a", Forward{SessionID: "s1", Close: CloseReasonShutdown}))
+	select {
+	case fwd := <-received:
+		assert.Equal(t, Forward{SessionID: "s1", Close: CloseReasonShutdown}, fwd)
+	case <-time.After(2 * time.Second):
+		t.Fatal("forwarded message not received")
+	}
+
+	require.NoError(t, store.Unregister(ctx, "s1"))
+	_, err = store.Lookup(ctx, "s1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
@@ -2,24 +2,31 @@ package transport
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"math"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/code-100-precent/LingEcho/internal/models"
 	"github.com/code-100-precent/LingEcho/pkg/llm"
+	"github.com/code-100-precent/LingEcho/pkg/llm/memory"
+	"github.com/code-100-precent/LingEcho/pkg/logger"
 	media2 "github.com/code-100-precent/LingEcho/pkg/media"
 	"github.com/code-100-precent/LingEcho/pkg/media/encoder"
+	"github.com/code-100-precent/LingEcho/pkg/media/endpointing"
+	"github.com/code-100-precent/LingEcho/pkg/media/vad"
+	"github.com/code-100-precent/LingEcho/pkg/metrics"
 	"github.com/code-100-precent/LingEcho/pkg/recognizer"
+	"github.com/code-100-precent/LingEcho/pkg/recording"
+	stores "github.com/code-100-precent/LingEcho/pkg/storage"
 	"github.com/code-100-precent/LingEcho/pkg/synthesizer"
 	"github.com/code-100-precent/LingEcho/pkg/utils"
+	"github.com/code-100-precent/LingEcho/pkg/webrtc/constants"
 	"github.com/code-100-precent/LingEcho/pkg/webrtc/rtcmedia"
-	"github.com/gorilla/websocket"
 	"github.com/pion/webrtc/v3"
-	"github.com/pion/webrtc/v3/pkg/media"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -31,7 +38,6 @@ var (
 	// and the codec resamples as needed.
 	targetSampleRate = 16000
 	audioChannels    = 1
-	audioBitDepth    = 16
 	// Frame size calculation for 16kHz:
 	// - 20ms frame duration at 16000Hz = 320 samples
 	// - For PCMA (8-bit), after resampling to 8kHz: 160 samples = 160 bytes
@@ -47,11 +53,18 @@ var (
 	packetLogInterval = 100
 )
 
+// SignalConn 向客户端发送信令的连接，*websocket.Conn 与 gRPC 信令流均满足
+type SignalConn interface {
+	WriteJSON(v interface{}) error
+	Close() error
+}
+
 // AIClient represents an AI-powered WebRTC client connection
 type AIClient struct {
-	Conn      *websocket.Conn
+	Conn      SignalConn
 	Transport *rtcmedia.WebRTCTransport
 	SessionID string
+	connMu    sync.Mutex // 串行化信令写入（trickle 候选者在 ICE 回调中发送）
 
 	// AI components
 	asrService  recognizer.TranscribeService
@@ -82,11 +95,13 @@ type AIClient struct {
 	userID       uint  // User ID for usage tracking
 	credentialID uint  // Credential ID for usage tracking
 	assistantID  *uint // Assistant ID for usage tracking
+	createdAt    time.Time
 
 	// Assistant configuration
 	llmModel    string  // LLM model from assistant
 	maxTokens   int     // Max tokens from assistant
 	temperature float32 // Temperature from assistant
+	language    string  // Assistant language, selects the speech normalization rules
 
 	// Echo cancellation: Half-duplex mode
 	// When TTS is playing, we pause ASR to prevent AI from hearing itself
@@ -94,17 +109,69 @@ type AIClient struct {
 	ttsEndTime    time.Time // When TTS finished playing (for cooldown)
 	ttsCooldownMs int       // Cooldown period after TTS ends (default 500ms)
 
+	// While paused (e.g. an IVR menu is driving the call) caller audio is not sent to ASR
+	conversationPaused bool
+	// Receives the caller's ASR results instead of the turn detector while a
+	// workflow listens, nil otherwise. Audio reaches ASR even when paused.
+	listener chan Transcript
+
 	// Barge-in (interrupt) support with VAD
 	enableVAD            bool          // Whether to enable VAD for barge-in detection
 	vadThreshold         float64       // RMS threshold for voice activity detection (0-32768)
 	ttsStopChan          chan struct{} // Channel to signal TTS to stop
 	bargeInCooldown      int           // Cooldown after barge-in before processing (ms)
 	vadConsecutiveFrames int           // Number of consecutive frames needed to trigger barge-in
-	vadFrameCounter      int           // Current count of consecutive frames above threshold
+	vad                  *vad.VAD      // Speech detector, rebuilt when the VAD settings change
+
+	// Decides when the caller's turn has ended from the ASR results
+	turnDetector *endpointing.Detector
+
+	// Cancels the voice turn in progress (LLM query and its TTS), nil between turns
+	turnCancel context.CancelFunc
+	// An LLM stream is in flight; providers only honour Interrupt while streaming
+	llmStreaming bool
+
+	// Noise suppression on decoded microphone audio, nil when disabled
+	noiseSuppressor media2.NoiseSuppressor
+
+	// Call recording of the decoded microphone audio and the sent TTS audio,
+	// nil when the user has not enabled recording
+	recorder *recording.Recorder
+
+	// Arrival of the first ASR result of the utterance in progress,
+	// used as the start offset of its transcript segment
+	utteranceStart time.Time
+
+	// Call detail record accumulated over the session and emitted on Close.
+	// Guarded by its own mutex since usage is metered while Mu is held
+	cdrMu sync.Mutex
+	cdr   models.CallDetailRecord
+
+	// Transcript writes still in flight, awaited before the transcript.ready
+	// webhook is queued. Segments finishing after Close are not tracked
+	transcriptMu     sync.Mutex
+	transcriptWG     sync.WaitGroup
+	transcriptClosed bool
+}
+
+// Transcript is an ASR result delivered to a listener
+type Transcript struct {
+	Text  string
+	Final bool
+}
+
+// CallInfo describes the call a session carries, for its call detail record.
+// Sessions default to an inbound WebRTC call from the user to the assistant
+type CallInfo struct {
+	CallID    string // SIP Call-ID for bridged calls; defaults to the session ID
+	Channel   models.CallChannel
+	Direction models.SipCallDirection
+	Caller    string
+	Callee    string
 }
 
 // NewAIClient creates a new AI-powered client (legacy, uses environment variables)
-func NewAIClient(conn *websocket.Conn, transport *rtcmedia.WebRTCTransport, sessionID string, knowledgeKey string, db *gorm.DB, userID uint, credentialID uint, assistantID *uint) (*AIClient, error) {
+func NewAIClient(conn SignalConn, transport *rtcmedia.WebRTCTransport, sessionID string, knowledgeKey string, db *gorm.DB, userID uint, credentialID uint, assistantID *uint) (*AIClient, error) {
 	// Initialize ASR (using QCloud as example, you can change to other providers)
 	asrOpt := recognizer.NewQcloudASROption(
 		utils.GetEnv("QCLOUD_APP_ID"),
@@ -126,6 +193,8 @@ func NewAIClient(conn *websocket.Conn, transport *rtcmedia.WebRTCTransport, sess
 	if err != nil {
 		return nil, fmt.Errorf("failed to create LLM provider: %w", err)
 	}
+	// Calls can run long, summarize older turns instead of resending them all
+	llm.EnableMemory(llmProvider, memory.Default())
 
 	// Initialize TTS (using QCloud as example)
 	// Use 16kHz sample rate for better audio quality
@@ -148,6 +217,7 @@ func NewAIClient(conn *websocket.Conn, transport *rtcmedia.WebRTCTransport, sess
 		Conn:           conn,
 		Transport:      transport,
 		SessionID:      sessionID,
+		createdAt:      time.Now(),
 		asrService:     asrService,
 		llmProvider:    llmProvider,
 		ttsService:     ttsService,
@@ -172,54 +242,29 @@ func NewAIClient(conn *websocket.Conn, transport *rtcmedia.WebRTCTransport, sess
 		ttsStopChan:          make(chan struct{}),
 		bargeInCooldown:      100,
 		vadConsecutiveFrames: 5,
-		vadFrameCounter:      0,
 	}
+	client.turnDetector = endpointing.NewDetector(endpointing.DefaultPolicy(), client.handleTurnEnd)
 
 	// Note: OnTrack callback is now set up in websocketHandler after NewAIClient
 	// This ensures it's set up before any signaling messages are processed
 
+	// Control messages (e.g. "interrupt") from the client's DataChannel
+	transport.OnDataMessage(client.handleDataMessage)
+
 	// Initialize ASR service
 	client.asrService.Init(
 		func(text string, isLast bool, duration time.Duration, uuid string) {
 			// 记录ASR使用量（当识别完成时）
-			if isLast && client.db != nil && client.userID > 0 && client.credentialID > 0 && duration > 0 {
-				go func() {
-					// 估算音频大小（假设16kHz, 16bit, 单声道，约32KB/秒）
-					audioSize := int64(duration.Seconds() * 32000)
-
-					sessionID := uuid
-					if sessionID == "" {
-						sessionID = fmt.Sprintf("webrtc_%d_%d", client.userID, time.Now().Unix())
-					}
-
-					// 获取组织ID（如果助手属于组织）
-					var groupID *uint
-					if client.assistantID != nil {
-						var assistant models.Assistant
-						if err := client.db.Where("id = ?", *client.assistantID).First(&assistant).Error; err == nil {
-							groupID = assistant.GroupID
-						}
-					}
-
-					if err := models.RecordASRUsage(
-						client.db,
-						client.userID,
-						client.credentialID,
-						client.assistantID,
-						groupID,
-						sessionID,
-						int(duration.Seconds()),
-						audioSize,
-					); err != nil {
-						log.Printf("[Server] 记录ASR使用量失败: %v", err)
-					}
-				}()
+			if isLast && duration > 0 {
+				// 估算音频大小（假设16kHz, 16bit, 单声道，约32KB/秒）
+				audioSize := int64(duration.Seconds() * 32000)
+				client.meterUsage(models.UsageTypeASR, uuid, int(duration.Seconds()), audioSize, 0)
 			}
 
 			client.handleASRResult(text, isLast, duration)
 		},
 		func(err error, isFatal bool) {
-			log.Printf("[Server] ASR error: %v (fatal: %v)", err, isFatal)
+			logger.Error("transport: ASR error", zap.String("session", client.SessionID), zap.Bool("fatal", isFatal), zap.Error(err))
 			if isFatal {
 				// Handle fatal error
 			} else {
@@ -238,7 +283,7 @@ func NewAIClient(conn *websocket.Conn, transport *rtcmedia.WebRTCTransport, sess
 
 // NewAIClientWithCredential creates a new AI-powered client using credential and assistant configuration
 func NewAIClientWithCredential(
-	conn *websocket.Conn,
+	conn SignalConn,
 	transport *rtcmedia.WebRTCTransport,
 	sessionID string,
 	knowledgeKey string,
@@ -305,10 +350,12 @@ func NewAIClientWithCredential(
 	if err != nil {
 		return nil, fmt.Errorf("failed to create LLM provider: %w", err)
 	}
+	// Calls can run long, summarize older turns instead of resending them all
+	llm.EnableMemory(llmProvider, memory.Default())
 
 	// Set LLM model if provided
 	if llmModel != "" {
-		log.Printf("[Server] LLM Model from assistant: %s", llmModel)
+		logger.Info("transport: LLM model from assistant", zap.String("model", llmModel))
 	}
 
 	// Initialize TTS from credential with assistant configuration
@@ -363,6 +410,7 @@ func NewAIClientWithCredential(
 		Conn:           conn,
 		Transport:      transport,
 		SessionID:      sessionID,
+		createdAt:      time.Now(),
 		asrService:     asrService,
 		llmProvider:    llmProvider,
 		ttsService:     ttsService,
@@ -380,6 +428,7 @@ func NewAIClientWithCredential(
 		llmModel:    llmModel,
 		maxTokens:   maxTokens,
 		temperature: temperature,
+		language:    language,
 		// Half-duplex mode: 500ms cooldown after TTS ends
 		isTTSPlaying:  false,
 		ttsCooldownMs: 500,
@@ -389,51 +438,26 @@ func NewAIClientWithCredential(
 		ttsStopChan:          make(chan struct{}),
 		bargeInCooldown:      100,
 		vadConsecutiveFrames: 5,
-		vadFrameCounter:      0,
 	}
+	client.turnDetector = endpointing.NewDetector(endpointing.DefaultPolicy(), client.handleTurnEnd)
+
+	// Control messages (e.g. "interrupt") from the client's DataChannel
+	transport.OnDataMessage(client.handleDataMessage)
 
 	// Initialize ASR service
 	client.asrService.Init(
 		func(text string, isLast bool, duration time.Duration, uuid string) {
 			// 记录ASR使用量（当识别完成时）
-			if isLast && client.db != nil && client.userID > 0 && client.credentialID > 0 && duration > 0 {
-				go func() {
-					// 估算音频大小（假设16kHz, 16bit, 单声道，约32KB/秒）
-					audioSize := int64(duration.Seconds() * 32000)
-
-					sessionID := uuid
-					if sessionID == "" {
-						sessionID = fmt.Sprintf("webrtc_%d_%d", client.userID, time.Now().Unix())
-					}
-
-					// 获取组织ID（如果助手属于组织）
-					var groupID *uint
-					if client.assistantID != nil {
-						var assistant models.Assistant
-						if err := client.db.Where("id = ?", *client.assistantID).First(&assistant).Error; err == nil {
-							groupID = assistant.GroupID
-						}
-					}
-
-					if err := models.RecordASRUsage(
-						client.db,
-						client.userID,
-						client.credentialID,
-						client.assistantID,
-						groupID,
-						sessionID,
-						int(duration.Seconds()),
-						audioSize,
-					); err != nil {
-						log.Printf("[Server] 记录ASR使用量失败: %v", err)
-					}
-				}()
+			if isLast && duration > 0 {
+				// 估算音频大小（假设16kHz, 16bit, 单声道，约32KB/秒）
+				audioSize := int64(duration.Seconds() * 32000)
+				client.meterUsage(models.UsageTypeASR, uuid, int(duration.Seconds()), audioSize, 0)
 			}
 
 			client.handleASRResult(text, isLast, duration)
 		},
 		func(err error, isFatal bool) {
-			log.Printf("[Server] ASR error: %v (fatal: %v)", err, isFatal)
+			logger.Error("transport: ASR error", zap.String("session", client.SessionID), zap.Bool("fatal", isFatal), zap.Error(err))
 			if isFatal {
 				// Handle fatal error
 			} else {
@@ -473,16 +497,31 @@ func normalizeProviderName(provider string) string {
 
 // Close closes the AI client
 func (c *AIClient) Close() error {
-	log.Printf("[Server] Closing AI client for session: %s", c.SessionID)
+	logger.Info("transport: closing AI client", zap.String("session", c.SessionID))
 
 	// Stop TTS immediately
 	c.stopTTS()
+	c.Mu.RLock()
+	detector := c.turnDetector
+	c.Mu.RUnlock()
+	detector.Close()
 
 	// Close the done channel to signal audio processing to stop
 	c.Mu.Lock()
 	if c.doneChan != nil {
 		close(c.doneChan)
 		c.doneChan = nil
+		// 首次关闭时按会话时长记录通话使用量
+		c.meterUsage(models.UsageTypeCall, c.SessionID, int(time.Since(c.createdAt).Seconds()), 0, 0)
+		c.emitCallDetailRecord()
+		go c.emitTranscriptReady()
+		if monitor := metrics.GetGlobalMonitor(); monitor != nil {
+			monitor.RemoveCallQuality(c.SessionID)
+		}
+		if c.recorder != nil {
+			go c.saveRecording(c.recorder)
+			c.recorder = nil
+		}
 	}
 	// Mark as closed to prevent further TTS generation
 	c.isTTSPlaying = false
@@ -500,22 +539,151 @@ func (c *AIClient) Close() error {
 	if c.Conn != nil {
 		c.Conn.Close()
 	}
-	log.Printf("[Server] AI client closed for session: %s", c.SessionID)
+	logger.Info("transport: AI client closed", zap.String("session", c.SessionID))
 	return nil
 }
 
+// WriteJSON 线程安全地向客户端发送信令消息
+func (c *AIClient) WriteJSON(v interface{}) error {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c.Conn.WriteJSON(v)
+}
+
+// EnableTrickleICE 在创建 answer 前调用，本地候选者生成后立即以 "candidate" 消息发送给客户端
+func (c *AIClient) EnableTrickleICE() {
+	c.Transport.OnICECandidate(func(candidate string) {
+		msg := map[string]interface{}{
+			"type":       constants.WebRTCCandidate,
+			"session_id": c.SessionID,
+			"data":       map[string]interface{}{"candidate": candidate},
+		}
+		if err := c.WriteJSON(msg); err != nil {
+			logger.Warn("transport: failed to send ICE candidate", zap.String("session", c.SessionID), zap.Error(err))
+		}
+	})
+}
+
+// meterUsage 发出计量事件，由计费监听器按凭证记录使用量
+func (c *AIClient) meterUsage(usageType models.UsageType, sessionID string, duration int, audioSize int64, characters int) {
+	c.cdrMu.Lock()
+	switch usageType {
+	case models.UsageTypeASR:
+		c.cdr.ASRSeconds += duration
+		c.cdr.ASRAudioBytes += audioSize
+	case models.UsageTypeTTS:
+		c.cdr.TTSSeconds += duration
+		c.cdr.TTSCharacters += characters
+	}
+	c.cdrMu.Unlock()
+
+	if c.db == nil || c.userID == 0 || c.credentialID == 0 {
+		return
+	}
+	if sessionID == "" {
+		sessionID = fmt.Sprintf("webrtc_%d_%d", c.userID, time.Now().Unix())
+	}
+	utils.Sig().Emit(models.SigUsageMetered, &models.UsageEvent{
+		UsageType:    usageType,
+		UserID:       c.userID,
+		CredentialID: c.credentialID,
+		AssistantID:  c.assistantID,
+		SessionID:    sessionID,
+		Duration:     duration,
+		AudioSize:    audioSize,
+		Characters:   characters,
+	})
+}
+
+// SetCallInfo sets who and what the session's call detail record describes
+func (c *AIClient) SetCallInfo(info CallInfo) {
+	c.cdrMu.Lock()
+	defer c.cdrMu.Unlock()
+	c.cdr.CallID = info.CallID
+	c.cdr.Channel = info.Channel
+	c.cdr.Direction = info.Direction
+	c.cdr.Caller = info.Caller
+	c.cdr.Callee = info.Callee
+}
+
+// recordLLMTurn adds a completed LLM turn and its token usage to the call detail record
+func (c *AIClient) recordLLMTurn() {
+	usage, ok := c.llmProvider.GetLastUsage()
+	c.cdrMu.Lock()
+	defer c.cdrMu.Unlock()
+	c.cdr.LLMTurns++
+	if ok {
+		c.cdr.LLMPromptTokens += usage.PromptTokens
+		c.cdr.LLMCompletionTokens += usage.CompletionTokens
+		c.cdr.LLMTotalTokens += usage.TotalTokens
+	}
+}
+
+// emitCallDetailRecord reports the session's call detail record on teardown.
+// For SIP calls the signaling side reports times and disposition, so only
+// attribution and usage are filled in here
+func (c *AIClient) emitCallDetailRecord() {
+	if c.userID == 0 {
+		return
+	}
+	c.cdrMu.Lock()
+	cdr := c.cdr
+	c.cdrMu.Unlock()
+
+	cdr.UserID = c.userID
+	cdr.CredentialID = c.credentialID
+	cdr.AssistantID = c.assistantID
+	if cdr.CallID == "" {
+		cdr.CallID = c.SessionID
+	}
+	if cdr.Channel == "" {
+		cdr.Channel = models.CallChannelWebRTC
+	}
+	if cdr.Channel == models.CallChannelWebRTC {
+		if cdr.Direction == "" {
+			cdr.Direction = models.SipCallDirectionInbound
+		}
+		if cdr.Caller == "" {
+			cdr.Caller = fmt.Sprintf("user:%d", c.userID)
+		}
+		if cdr.Callee == "" && c.assistantID != nil {
+			cdr.Callee = fmt.Sprintf("assistant:%d", *c.assistantID)
+		}
+		now := time.Now()
+		cdr.StartTime = c.createdAt
+		cdr.EndTime = &now
+		if cdr.AnswerTime != nil {
+			cdr.Duration = int(now.Sub(*cdr.AnswerTime).Seconds())
+			cdr.Disposition = models.CallDispositionAnswered
+		} else {
+			cdr.Disposition = models.CallDispositionFailed
+			cdr.HangupCause = "no media received"
+		}
+	} else {
+		// Owned by the signaling side
+		cdr.AnswerTime = nil
+	}
+	utils.Sig().Emit(models.SigCallDetailRecord, &cdr)
+}
+
 // setTTSPlaying sets the TTS playing state (for half-duplex echo cancellation)
 func (c *AIClient) setTTSPlaying(playing bool) {
 	c.Mu.Lock()
 	defer c.Mu.Unlock()
+	wasPlaying := c.isTTSPlaying
 	c.isTTSPlaying = playing
 	if playing {
 		// Create new stop channel for this TTS session
 		c.ttsStopChan = make(chan struct{})
-	} else {
+		// Speech already in progress counts as a new barge-in once TTS starts
+		if c.vad != nil {
+			c.vad.Reset()
+		}
+	} else if wasPlaying {
+		// Already stopped by barge-in: no cooldown, the user is talking
 		c.ttsEndTime = time.Now()
 	}
-	log.Printf("[Server] TTS playing state: %v", playing)
+	logger.Debug("transport: TTS playing state", zap.String("session", c.SessionID), zap.Bool("playing", playing))
 }
 
 // stopTTS signals TTS to stop immediately (for barge-in)
@@ -530,8 +698,11 @@ func (c *AIClient) stopTTS() {
 			close(c.ttsStopChan)
 		}
 		c.isTTSPlaying = false
-		c.ttsEndTime = time.Now()
-		log.Printf("[Server] TTS stopped by barge-in")
+		// The user is already talking: skip the post-TTS cooldown so ASR hears the whole utterance
+		c.ttsEndTime = time.Time{}
+		logger.Info("transport: TTS stopped by barge-in", zap.String("session", c.SessionID))
+		// Audio already queued on the client would keep playing; tell it to flush
+		go c.sendDataMessage(rtcmedia.DataMessageInterrupt, "", true)
 	}
 }
 
@@ -555,7 +726,7 @@ func (c *AIClient) SetEnableVAD(enable bool) {
 	c.Mu.Lock()
 	defer c.Mu.Unlock()
 	c.enableVAD = enable
-	log.Printf("[Server] VAD enabled: %v", enable)
+	logger.Info("transport: VAD enabled", zap.String("session", c.SessionID), zap.Bool("enabled", enable))
 }
 
 // SetVADThreshold sets the VAD threshold (0-32768, typical speech ~500-5000)
@@ -563,7 +734,10 @@ func (c *AIClient) SetVADThreshold(threshold float64) {
 	c.Mu.Lock()
 	defer c.Mu.Unlock()
 	c.vadThreshold = threshold
-	log.Printf("[Server] VAD threshold set to: %.2f", threshold)
+	if c.vad != nil {
+		c.vad.SetMinRMS(threshold)
+	}
+	logger.Info("transport: VAD threshold set", zap.String("session", c.SessionID), zap.Float64("threshold", threshold))
 }
 
 // SetVADConsecutiveFrames sets how many consecutive frames above threshold needed for barge-in
@@ -572,76 +746,170 @@ func (c *AIClient) SetVADConsecutiveFrames(frames int) {
 	c.Mu.Lock()
 	defer c.Mu.Unlock()
 	c.vadConsecutiveFrames = frames
-	log.Printf("[Server] VAD consecutive frames set to: %d (~%dms)", frames, frames*20)
+	if c.vad != nil {
+		c.vad.SetStartFrames(frames)
+	}
+	logger.Info("transport: VAD consecutive frames set", zap.String("session", c.SessionID), zap.Int("frames", frames), zap.Int("ms", frames*20))
 }
 
-// calculateRMS calculates the Root Mean Square of 16-bit PCM audio data
-func calculateRMS(pcmData []byte) float64 {
-	if len(pcmData) < 2 {
-		return 0
+// SetNoiseSuppression enables or disables noise suppression of the caller's
+// audio before barge-in detection and ASR
+func (c *AIClient) SetNoiseSuppression(enable bool) {
+	c.Mu.Lock()
+	defer c.Mu.Unlock()
+	if !enable {
+		c.noiseSuppressor = nil
+	} else if c.noiseSuppressor == nil {
+		c.noiseSuppressor = media2.NewSpectralSubtraction(media2.NoiseSuppressionConfig{SampleRate: targetSampleRate})
 	}
+	logger.Info("transport: noise suppression enabled", zap.String("session", c.SessionID), zap.Bool("enabled", enable))
+}
 
-	var sumSquares float64
-	sampleCount := len(pcmData) / 2
+// SetRecorder records the call into rec; it is saved to storage when the
+// client closes. Call it before audio starts flowing.
+func (c *AIClient) SetRecorder(rec *recording.Recorder) {
+	c.Mu.Lock()
+	defer c.Mu.Unlock()
+	c.recorder = rec
+}
 
-	for i := 0; i < len(pcmData)-1; i += 2 {
-		// Convert little-endian bytes to int16
-		sample := int16(pcmData[i]) | int16(pcmData[i+1])<<8
-		sumSquares += float64(sample) * float64(sample)
+// record writes PCM to the call recording if one is running
+func (c *AIClient) record(ch recording.Channel, pcm []byte) {
+	c.Mu.RLock()
+	rec := c.recorder
+	c.Mu.RUnlock()
+	if rec == nil || len(pcm) == 0 {
+		return
 	}
+	if err := rec.Write(ch, pcm); err != nil && !errors.Is(err, recording.ErrRecorderClosed) {
+		logger.Warn("transport: recording write error", zap.String("session", c.SessionID), zap.Error(err))
+	}
+}
 
-	if sampleCount == 0 {
-		return 0
+// saveRecording uploads the call recording and stores its metadata
+func (c *AIClient) saveRecording(rec *recording.Recorder) {
+	if c.db == nil {
+		rec.Discard()
+		return
+	}
+	saved, err := recording.Save(c.db, stores.Default(), rec, recording.Meta{
+		UserID:      c.userID,
+		AssistantID: c.assistantID,
+		SessionID:   c.SessionID,
+		Source:      models.RecordingSourceWebRTC,
+	})
+	if err != nil {
+		if !errors.Is(err, recording.ErrEmptyRecording) {
+			logger.Error("transport: failed to save recording", zap.String("session", c.SessionID), zap.Error(err))
+		}
+		return
 	}
+	logger.Info("transport: saved recording", zap.String("session", c.SessionID), zap.Uint("recording", saved.ID), zap.Int("duration", saved.Duration))
+}
+
+// saveTranscriptAsync stores a transcript segment in the background
+func (c *AIClient) saveTranscriptAsync(speaker models.TranscriptSpeaker, start, end time.Time, text string, interrupted bool) {
+	c.transcriptMu.Lock()
+	tracked := !c.transcriptClosed
+	if tracked {
+		c.transcriptWG.Add(1)
+	}
+	c.transcriptMu.Unlock()
 
-	return math.Sqrt(sumSquares / float64(sampleCount))
+	go func() {
+		if tracked {
+			defer c.transcriptWG.Done()
+		}
+		c.saveTranscript(speaker, start, end, text, interrupted)
+	}()
+}
+
+// emitTranscriptReady queues the transcript.ready webhook once the pending
+// transcript segments of the session are stored
+func (c *AIClient) emitTranscriptReady() {
+	c.transcriptMu.Lock()
+	c.transcriptClosed = true
+	c.transcriptMu.Unlock()
+	c.transcriptWG.Wait()
+
+	if c.db == nil || c.userID == 0 {
+		return
+	}
+	segments, err := models.GetCallTranscript(c.db, c.SessionID, c.userID)
+	if err != nil || len(segments) == 0 {
+		return
+	}
+	c.cdrMu.Lock()
+	callID := c.cdr.CallID
+	c.cdrMu.Unlock()
+	if callID == "" {
+		callID = c.SessionID
+	}
+	data := map[string]interface{}{
+		"sessionId":   c.SessionID,
+		"callId":      callID,
+		"assistantId": c.assistantID,
+		"segments":    segments,
+	}
+	if _, err := models.EnqueueWebhookEvent(c.db, c.userID, models.WebhookEventTranscriptReady, "transcript.ready:"+c.SessionID, data, 0); err != nil {
+		logger.Warn("transport: failed to queue transcript webhook", zap.String("session", c.SessionID), zap.Error(err))
+	}
+}
+
+// saveTranscript stores one transcript segment with offsets relative to the call start
+func (c *AIClient) saveTranscript(speaker models.TranscriptSpeaker, start, end time.Time, text string, interrupted bool) {
+	if c.db == nil || strings.TrimSpace(text) == "" {
+		return
+	}
+	segment := &models.CallTranscript{
+		SessionID:   c.SessionID,
+		UserID:      c.userID,
+		AssistantID: c.assistantID,
+		Speaker:     speaker,
+		StartMs:     start.Sub(c.createdAt).Milliseconds(),
+		EndMs:       end.Sub(c.createdAt).Milliseconds(),
+		Text:        text,
+		Interrupted: interrupted,
+	}
+	if segment.StartMs < 0 {
+		segment.StartMs = 0
+	}
+	if err := c.db.Create(segment).Error; err != nil {
+		logger.Error("transport: failed to save transcript", zap.String("session", c.SessionID), zap.Error(err))
+	}
 }
 
 // checkBargeIn checks if user is speaking and should interrupt TTS
 // Returns true if barge-in detected (TTS should stop)
-// Uses consecutive frame detection to avoid false triggers from TTS echo
+// Audio is analysed even while TTS is silent so the VAD keeps tracking the
+// noise floor; only a speech start during playback triggers barge-in.
+// vadThreshold acts as an absolute energy floor to filter out TTS echo.
 func (c *AIClient) checkBargeIn(pcmData []byte) bool {
 	c.Mu.Lock()
-	enableVAD := c.enableVAD
-	vadThreshold := c.vadThreshold
-	isTTSPlaying := c.isTTSPlaying
-	consecutiveFramesNeeded := c.vadConsecutiveFrames
-
-	// Only check for barge-in when VAD is enabled and TTS is playing
-	if !enableVAD || !isTTSPlaying {
-		c.vadFrameCounter = 0 // Reset counter when not in barge-in detection mode
+	if !c.enableVAD {
 		c.Mu.Unlock()
 		return false
 	}
-
-	// Calculate audio energy (RMS)
-	rms := calculateRMS(pcmData)
-
-	// Debug: Log RMS values periodically to help tune threshold
-	if c.vadFrameCounter%50 == 0 || rms > vadThreshold*0.5 {
-		log.Printf("[Server] VAD: RMS=%.2f, Threshold=%.2f, Counter=%d, TTS=%v",
-			rms, vadThreshold, c.vadFrameCounter, isTTSPlaying)
+	if c.vad == nil {
+		c.vad = vad.New(vad.Config{
+			SampleRate:  targetSampleRate,
+			Mode:        vad.ModeAggressive,
+			MinRMS:      c.vadThreshold,
+			StartFrames: c.vadConsecutiveFrames,
+		})
 	}
+	event := c.vad.Process(pcmData)
+	isTTSPlaying := c.isTTSPlaying
+	noiseRMS := c.vad.NoiseRMS()
+	c.Mu.Unlock()
 
-	// Check if energy exceeds threshold
-	if rms > vadThreshold {
-		c.vadFrameCounter++
-		// Only trigger barge-in after consecutive frames exceed threshold
-		// This filters out short bursts from TTS echo
-		if c.vadFrameCounter >= consecutiveFramesNeeded {
-			log.Printf("[Server] Barge-in detected! RMS: %.2f, Threshold: %.2f, Consecutive frames: %d",
-				rms, vadThreshold, c.vadFrameCounter)
-			c.Mu.Unlock()
-			c.stopTTS()
-			return true
-		}
-	} else {
-		// Reset counter if energy drops below threshold
-		c.vadFrameCounter = 0
+	if event != vad.EventSpeechStart || !isTTSPlaying {
+		return false
 	}
 
-	c.Mu.Unlock()
-	return false
+	logger.Info("transport: barge-in detected", zap.String("session", c.SessionID), zap.Float64("threshold", c.vadThreshold), zap.Float64("noiseFloor", noiseRMS))
+	c.stopTTS()
+	return true
 }
 
 // shouldProcessAudio checks if we should process incoming audio
@@ -651,6 +919,10 @@ func (c *AIClient) shouldProcessAudio() bool {
 	c.Mu.RLock()
 	defer c.Mu.RUnlock()
 
+	if c.conversationPaused && c.listener == nil {
+		return false
+	}
+
 	// Don't process audio while TTS is playing (unless barge-in detected)
 	if c.isTTSPlaying {
 		return false
@@ -667,85 +939,132 @@ func (c *AIClient) shouldProcessAudio() bool {
 	return true
 }
 
+// sendDataMessage sends a transcript/control message over the DataChannel
+// Clients without a DataChannel only get audio, so a closed channel is not an error
+func (c *AIClient) sendDataMessage(msgType rtcmedia.DataMessageType, text string, final bool) {
+	err := c.Transport.SendDataMessage(rtcmedia.DataMessage{Type: msgType, Text: text, Final: final})
+	if err != nil && !errors.Is(err, rtcmedia.ErrDataChannelNotOpen) {
+		logger.Warn("transport: DataChannel send error", zap.String("session", c.SessionID), zap.Error(err))
+	}
+}
+
+// handleDataMessage handles messages received on the DataChannel
+func (c *AIClient) handleDataMessage(msg rtcmedia.DataMessage) {
+	switch msg.Type {
+	case rtcmedia.DataMessageInterrupt:
+		// The user asked the assistant to stop speaking (e.g. tapped "stop")
+		logger.Info("transport: interrupt requested by client", zap.String("session", c.SessionID))
+		c.Interrupt()
+	default:
+		logger.Warn("transport: unknown DataChannel message type", zap.String("session", c.SessionID), zap.String("type", string(msg.Type)))
+	}
+}
+
 // handleASRResult handles ASR recognition results
 func (c *AIClient) handleASRResult(text string, isLast bool, duration time.Duration) {
 	if text == "" {
 		return
 	}
 
+	// Live transcript for the client UI, partial results included
+	c.sendDataMessage(rtcmedia.DataMessageTranscript, text, isLast)
+
+	now := time.Now()
 	c.Mu.Lock()
 	c.lastText = text
+	start := c.utteranceStart
+	if start.IsZero() {
+		start = now
+		c.utteranceStart = now
+	}
+	if isLast {
+		c.utteranceStart = time.Time{}
+	}
 	c.Mu.Unlock()
 
-	log.Printf("[Server] ASR Result: %s (isLast: %v, duration: %v)", text, isLast, duration)
-
-	// Check if it's a complete sentence (even if isLast=false)
-	isComplete := isCompleteSentence(text)
+	logger.Info("transport: ASR result", zap.String("session", c.SessionID), zap.String("text", text), zap.Bool("isLast", isLast), zap.Duration("duration", duration))
 
 	if isLast {
-		// Final result - process with LLM
-		go c.processWithLLM(text)
-	} else if isComplete {
-		// Sentence end - also process if it's a meaningful sentence
-		// Filter meaningless text
-		filteredText := filterText(text)
-		if filteredText != "" && !isMeaninglessText(filteredText) {
-			log.Printf("[Server] Processing sentence end (isLast=false but complete): %s", filteredText)
-			go c.processWithLLM(filteredText)
+		// Providers reporting the utterance length give a better start than the first partial result
+		if duration > 0 && now.Add(-duration).Before(start) {
+			start = now.Add(-duration)
 		}
+		c.saveTranscriptAsync(models.TranscriptSpeakerUser, start, now, text, false)
 	}
-}
 
-// isCompleteSentence checks if text contains sentence ending markers
-func isCompleteSentence(text string) bool {
-	if text == "" {
-		return false
-	}
-	endMarkers := []string{"。", "？", "！", ".", "?", "!"}
-	for _, marker := range endMarkers {
-		if strings.Contains(text, marker) {
-			return true
+	// The turn policy decides when the caller has finished and the LLM should answer
+	c.Mu.RLock()
+	detector := c.turnDetector
+	listener := c.listener
+	c.Mu.RUnlock()
+	if listener != nil {
+		select {
+		case listener <- Transcript{Text: text, Final: isLast}:
+		default:
+			logger.Warn("transport: listener is not keeping up, dropping ASR result", zap.String("session", c.SessionID))
 		}
+		return
 	}
-	return false
+	detector.Transcript(text, isLast, duration)
 }
 
-// filterText removes punctuation and whitespace
-func filterText(text string) string {
-	text = strings.TrimSpace(text)
-	// Remove common punctuation
-	text = strings.Trim(text, "。，、；：？！\"\"''（）【】《》")
-	return text
+// handleTurnEnd answers a finished caller turn
+func (c *AIClient) handleTurnEnd(turn endpointing.Turn) {
+	if turn.Reason != endpointing.ReasonFinal {
+		logger.Info("transport: processing turn before final result", zap.String("session", c.SessionID), zap.String("text", turn.Text), zap.String("reason", string(turn.Reason)))
+	}
+	go c.processWithLLM(turn.Text, turn.Start)
 }
 
-// isMeaninglessText checks if text is meaningless (should be filtered)
-func isMeaninglessText(text string) bool {
-	if text == "" {
-		return true
-	}
-	// Filter single character meaningless words
-	meaninglessWords := []string{"嗯", "啊", "哦", "额", "呃", "0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
-	cleaned := strings.TrimSpace(text)
-	for _, word := range meaninglessWords {
-		if cleaned == word {
-			return true
-		}
+// LLMProvider returns the provider answering the caller, e.g. to register the
+// function tools the model may call during the conversation
+func (c *AIClient) LLMProvider() llm.LLMProvider {
+	return c.llmProvider
+}
+
+// SetTTSService replaces the synthesizer the client speaks with, e.g. with
+// the assistant's trained voice. Call it before the call starts speaking.
+func (c *AIClient) SetTTSService(svc synthesizer.SynthesisService) {
+	c.Mu.Lock()
+	old := c.ttsService
+	c.ttsService = svc
+	c.Mu.Unlock()
+	if old != nil {
+		old.Close()
 	}
-	// Filter very short text (less than 2 characters after cleaning)
-	if len([]rune(cleaned)) < 2 {
-		return true
+}
+
+// EnableTTSCache serves short phrases such as the greeting from the synthesis
+// result cache instead of the provider. Call it after SetTTSService.
+func (c *AIClient) EnableTTSCache() {
+	c.Mu.Lock()
+	c.ttsService = synthesizer.WithResultCache(c.ttsService)
+	c.Mu.Unlock()
+}
+
+// SetTurnPolicy sets when the caller's turn is considered finished
+func (c *AIClient) SetTurnPolicy(policy endpointing.Policy) {
+	detector := endpointing.NewDetector(policy, c.handleTurnEnd)
+	c.Mu.Lock()
+	old := c.turnDetector
+	c.turnDetector = detector
+	c.Mu.Unlock()
+	if old != nil {
+		old.Close()
 	}
-	return false
 }
 
-// processWithLLM processes text with LLM and generates TTS
-func (c *AIClient) processWithLLM(userText string) {
+// processWithLLM processes text with LLM and generates TTS. Each call is one
+// voice turn and produces one trace: ASR → knowledge search → LLM → TTS → WebRTC send.
+// utteranceStart is when the first ASR result of the utterance arrived.
+func (c *AIClient) processWithLLM(userText string, utteranceStart time.Time) {
 	c.Mu.Lock()
 	if c.isProcessing {
 		c.Mu.Unlock()
 		return
 	}
 	c.isProcessing = true
+	conversationID := c.conversationID
 	c.Mu.Unlock()
 
 	defer func() {
@@ -754,15 +1073,51 @@ func (c *AIClient) processWithLLM(userText string) {
 		c.Mu.Unlock()
 	}()
 
-	log.Printf("[Server] Processing with LLM: %s", userText)
+	ctx, turn := metrics.StartSpan(context.Background(), "voice.turn",
+		metrics.WithStartTime(utteranceStart),
+		metrics.WithAttributes(map[string]interface{}{
+			"session.id":      c.SessionID,
+			"conversation.id": conversationID,
+		}),
+	)
+	var turnErr error
+	defer func() { metrics.EndSpan(turn, turnErr) }()
+
+	// Interrupt cancels the turn through this context
+	ctx, cancel := context.WithCancel(ctx)
+	c.Mu.Lock()
+	c.turnCancel = cancel
+	c.Mu.Unlock()
+	defer func() {
+		c.Mu.Lock()
+		c.turnCancel = nil
+		c.Mu.Unlock()
+		cancel()
+	}()
+
+	// Recognition already happened; record it from the first partial result to now
+	_, asrSpan := metrics.StartSpan(ctx, "asr.recognize",
+		metrics.WithStartTime(utteranceStart),
+		metrics.WithAttributes(map[string]interface{}{
+			"asr.vendor":     c.asrService.Vendor(),
+			"asr.text_chars": utf8.RuneCountInString(userText),
+		}),
+	)
+	metrics.EndSpan(asrSpan, nil)
+
+	logger.Info("transport: processing with LLM", zap.String("session", c.SessionID), zap.String("text", userText))
 
 	// Build query text (if knowledge base is provided, search knowledge base first)
 	queryText := userText
 	if c.knowledgeKey != "" && c.db != nil {
 		// Search knowledge base
+		_, searchSpan := metrics.StartSpan(ctx, "knowledge.search",
+			metrics.WithAttributes(map[string]interface{}{"knowledge.key": c.knowledgeKey}))
 		knowledgeResults, err := models.SearchKnowledgeBase(c.db, c.knowledgeKey, userText, 5)
+		searchSpan.SetAttribute("knowledge.results", len(knowledgeResults))
+		metrics.EndSpan(searchSpan, err)
 		if err != nil {
-			log.Printf("[Server] Failed to search knowledge base: %v", err)
+			logger.Warn("transport: failed to search knowledge base", zap.String("session", c.SessionID), zap.Error(err))
 			// Use original query when search fails
 			queryText = userText
 		} else if len(knowledgeResults) > 0 {
@@ -778,7 +1133,7 @@ func (c *AIClient) processWithLLM(userText string) {
 			}
 			contextBuilder.WriteString("\n\n请基于以上信息回答用户问题，回答要自然流畅，不要提及信息来源。")
 			queryText = contextBuilder.String()
-			log.Printf("[Server] Retrieved %d relevant documents from knowledge base (key: %s)", len(knowledgeResults), c.knowledgeKey)
+			logger.Info("transport: retrieved knowledge base documents", zap.String("session", c.SessionID), zap.Int("count", len(knowledgeResults)), zap.String("knowledgeKey", c.knowledgeKey))
 		} else {
 			// No relevant content found, use original query
 			queryText = userText
@@ -819,27 +1174,133 @@ func (c *AIClient) processWithLLM(userText string) {
 		options.Temperature = &defaultTemp
 	}
 
-	// Query LLM with options
-	response, err := c.llmProvider.QueryWithOptions(queryText, options)
+	// Query LLM with options; stream the answer when the client can display partial text
+	streaming := c.Transport.DataChannelOpen()
+	_, llmSpan := metrics.StartSpan(ctx, "llm.query",
+		metrics.WithAttributes(map[string]interface{}{
+			"llm.model":     model,
+			"llm.streaming": streaming,
+		}),
+	)
+	var response string
+	var err error
+	if streaming {
+		c.Mu.Lock()
+		c.llmStreaming = true
+		c.Mu.Unlock()
+		firstSegment := true
+		response, err = c.llmProvider.QueryStream(queryText, options, func(segment string, isComplete bool) error {
+			if ctx.Err() != nil {
+				// Interrupted: the stream is being torn down, show nothing more
+				return ctx.Err()
+			}
+			if firstSegment && segment != "" {
+				firstSegment = false
+				llmSpan.AddEvent("first_token", nil)
+			}
+			c.sendDataMessage(rtcmedia.DataMessageLLMDelta, segment, isComplete)
+			return nil
+		})
+		c.Mu.Lock()
+		c.llmStreaming = false
+		c.Mu.Unlock()
+	} else {
+		response, err = c.llmProvider.QueryWithOptions(queryText, options)
+	}
+	if ctx.Err() != nil {
+		// Non-streaming queries cannot be stopped; their answer is dropped instead
+		llmSpan.SetAttribute("llm.interrupted", true)
+		metrics.EndSpan(llmSpan, nil)
+		logger.Info("transport: voice turn interrupted", zap.String("session", c.SessionID))
+		return
+	}
+	metrics.EndSpan(llmSpan, err)
 	if err != nil {
-		log.Printf("[Server] LLM error: %v", err)
+		logger.Error("transport: LLM error", zap.String("session", c.SessionID), zap.Error(err))
+		turnErr = err
 		return
 	}
+	c.recordLLMTurn()
 
-	log.Printf("[Server] LLM Response: %s", response)
+	logger.Info("transport: LLM response", zap.String("session", c.SessionID), zap.String("text", response))
 
 	// Generate TTS
-	c.GenerateTTS(response)
+	turnErr = c.generateTTS(ctx, response)
+}
+
+// Interrupt cuts the assistant off mid-sentence: the LLM answer being
+// generated is abandoned, TTS frames not yet sent are dropped and the client
+// is told over the DataChannel to flush its playback buffer. It does nothing
+// while the assistant is idle.
+func (c *AIClient) Interrupt() {
+	c.Mu.RLock()
+	cancel := c.turnCancel
+	streaming := c.llmStreaming
+	playing := c.isTTSPlaying
+	c.Mu.RUnlock()
+
+	if cancel == nil && !playing {
+		return
+	}
+	logger.Info("transport: interrupting assistant", zap.String("session", c.SessionID), zap.Bool("llmStreaming", streaming), zap.Bool("ttsPlaying", playing))
+	if cancel != nil {
+		cancel()
+	}
+	if streaming {
+		c.llmProvider.Interrupt()
+	}
+	if playing {
+		// Also sends the interrupt event
+		c.stopTTS()
+		return
+	}
+	c.sendDataMessage(rtcmedia.DataMessageInterrupt, "", true)
 }
 
 // GenerateTTS generates TTS audio and sends it via WebRTC
 func (c *AIClient) GenerateTTS(text string) {
-	log.Printf("[Server] Generating TTS for: %s", text)
+	c.generateTTS(context.Background(), text)
+}
+
+// Speak plays text to the peer and blocks until playback has finished
+func (c *AIClient) Speak(text string) error {
+	return c.generateTTS(context.Background(), text)
+}
+
+// SetConversationPaused stops (or resumes) feeding caller audio to ASR,
+// so something else can drive the call without the assistant replying
+func (c *AIClient) SetConversationPaused(paused bool) {
+	c.Mu.Lock()
+	defer c.Mu.Unlock()
+	c.conversationPaused = paused
+}
+
+// StartListening routes the caller's ASR results to the returned channel
+// instead of the assistant until stop is called, also while the conversation
+// is paused. Only one listener is active at a time, a new one replaces it.
+func (c *AIClient) StartListening() (<-chan Transcript, func()) {
+	listener := make(chan Transcript, 32)
+	c.Mu.Lock()
+	c.listener = listener
+	c.Mu.Unlock()
+	stop := func() {
+		c.Mu.Lock()
+		if c.listener == listener {
+			c.listener = nil
+		}
+		c.Mu.Unlock()
+	}
+	return listener, stop
+}
+
+// generateTTS synthesizes text and paces it onto the send track. Spans for
+// synthesis and sending become children of the span carried by ctx.
+func (c *AIClient) generateTTS(ctx context.Context, text string) error {
+	logger.Info("transport: generating TTS", zap.String("session", c.SessionID), zap.String("text", text))
 
-	ctx := context.Background()
 	txTrack := c.Transport.GetTxTrack()
 	if txTrack == nil {
-		log.Printf("[Server] txTrack is nil, waiting...")
+		logger.Debug("transport: txTrack is nil, waiting", zap.String("session", c.SessionID))
 		// Wait for track
 		for i := 0; i < maxConnectionRetries; i++ {
 			txTrack = c.Transport.GetTxTrack()
@@ -849,75 +1310,108 @@ func (c *AIClient) GenerateTTS(text string) {
 			time.Sleep(connectionRetryDelay)
 		}
 		if txTrack == nil {
-			log.Printf("[Server] Failed to get txTrack")
-			return
+			logger.Error("transport: failed to get txTrack", zap.String("session", c.SessionID))
+			return errors.New("transport: no send track")
 		}
 	}
 
-	// Create TTS handler
+	// Create encoder for the negotiated send codec (PCMA at 8kHz or Opus at 48kHz)
+	encode, frameDuration, err := c.createEncoderForCodec(txTrack.Codec().MimeType)
+	if err != nil {
+		logger.Error("transport: failed to create TTS encoder", zap.String("session", c.SessionID), zap.Error(err))
+		return err
+	}
+
+	// Create TTS handler; PCM is buffered into whole frames of the TTS
+	// sample rate so every sample written to the track is a full frame
 	ttsHandler := &TTSSender{
-		txTrack:   txTrack,
-		client:    c,
-		audioSize: 0,
-		startTime: time.Now(),
+		ctx:        ctx,
+		txTrack:    txTrack,
+		writer:     newTrackWriter(txTrack, frameDuration),
+		client:     c,
+		encode:     encode,
+		frameBytes: int(time.Duration(c.ttsService.Format().SampleRate*2*audioChannels) * frameDuration / time.Second),
+		audioSize:  0,
+		startTime:  time.Now(),
+	}
+
+	// Decode the sent frames back to PCM for the call recording, so only
+	// audio that actually went out (not what barge-in cut off) is recorded
+	c.Mu.RLock()
+	recordingEnabled := c.recorder != nil
+	c.Mu.RUnlock()
+	if recordingEnabled {
+		codec := txTrack.Codec()
+		if decode, err := c.createDecoderForCodec(codec.MimeType, int(codec.ClockRate)); err != nil {
+			logger.Warn("transport: failed to create recording decoder", zap.String("session", c.SessionID), zap.Error(err))
+		} else {
+			ttsHandler.recordDecode = decode
+		}
 	}
 
 	// Half-duplex mode: Set TTS playing state to pause ASR
 	c.setTTSPlaying(true)
-
-	// Synthesize
-	if err := c.ttsService.Synthesize(ctx, ttsHandler, text); err != nil {
-		log.Printf("[Server] TTS synthesis error: %v", err)
+	c.sendDataMessage(rtcmedia.DataMessageTTSStart, text, false)
+	defer c.sendDataMessage(rtcmedia.DataMessageTTSEnd, "", true)
+
+	// Stream the synthesis so playback starts with the first chunk instead of
+	// once the whole utterance is ready. Numbers, dates and markdown in the
+	// reply are expanded into speakable text first
+	stream := synthesizer.SynthesizeStream(ctx, c.ttsService, synthesizer.NormalizeForSpeech(text, c.language))
+	interrupted := false
+	for chunk := range stream.Chunks() {
+		if c.shouldStopTTS() {
+			interrupted = true
+			break
+		}
+		ttsHandler.OnMessage(chunk)
+	}
+	stream.Close()
+	if interrupted || ctx.Err() != nil {
+		// Barge-in or Interrupt cancelled the rest of the synthesis, which is not an error
+		err = nil
+	} else if err = stream.Err(); err == nil {
+		ttsHandler.flush()
+	}
+	ttsHandler.endSendSpan(err)
+	if err != nil {
+		logger.Error("transport: TTS synthesis error", zap.String("session", c.SessionID), zap.Error(err))
 		c.setTTSPlaying(false) // Reset state on error
-		return
+		return err
 	}
 
+	// Frames are paced in real time, so the stream ending marks the end of playback
+	c.saveTranscriptAsync(models.TranscriptSpeakerAssistant, ttsHandler.startTime, time.Now(), text, c.shouldStopTTS())
+
 	// TTS finished, start cooldown period
 	c.setTTSPlaying(false)
 
 	// 记录TTS使用量
-	if c.db != nil && c.userID > 0 && c.credentialID > 0 && ttsHandler.audioSize > 0 {
-		go func() {
-			ttsDuration := int(time.Since(ttsHandler.startTime).Seconds())
-			if ttsDuration == 0 {
-				// 如果时长为0，根据音频大小估算（假设16kHz, 16bit, 单声道）
-				ttsDuration = int(float64(ttsHandler.audioSize) / 32000)
-			}
-
-			sessionID := fmt.Sprintf("webrtc_%d_%d", c.userID, time.Now().Unix())
-
-			// 获取组织ID（如果助手属于组织）
-			var groupID *uint
-			if c.assistantID != nil {
-				var assistant models.Assistant
-				if err := c.db.Where("id = ?", *c.assistantID).First(&assistant).Error; err == nil {
-					groupID = assistant.GroupID
-				}
-			}
-
-			if err := models.RecordTTSUsage(
-				c.db,
-				c.userID,
-				c.credentialID,
-				c.assistantID,
-				groupID,
-				sessionID,
-				ttsDuration,
-				ttsHandler.audioSize,
-			); err != nil {
-				log.Printf("[Server] 记录TTS使用量失败: %v", err)
-			}
-		}()
+	if ttsHandler.audioSize > 0 {
+		ttsDuration := int(time.Since(ttsHandler.startTime).Seconds())
+		if ttsDuration == 0 {
+			// 如果时长为0，根据音频大小估算（假设16kHz, 16bit, 单声道）
+			ttsDuration = int(float64(ttsHandler.audioSize) / 32000)
+		}
+		c.meterUsage(models.UsageTypeTTS, "", ttsDuration, ttsHandler.audioSize, utf8.RuneCountInString(text))
 	}
+	return nil
 }
 
 // TTSSender handles TTS audio data and sends it via WebRTC
 type TTSSender struct {
-	txTrack   *webrtc.TrackLocalStaticSample
-	client    *AIClient
-	buffer    []byte
-	audioSize int64     // Track total audio size
-	startTime time.Time // Track TTS start time
+	ctx          context.Context // Carries the voice turn span
+	sendSpan     *metrics.Span   // Started with the first frame, nil until then or when tracing is off
+	framesSent   int
+	txTrack      *webrtc.TrackLocalStaticSample
+	writer       *trackWriter // Paces frames onto txTrack across chunks
+	client       *AIClient
+	encode       media2.EncoderFunc // Encodes TTS PCM into frames of the send codec
+	frameBytes   int                // PCM bytes per frame at the TTS sample rate, 0 to encode chunks as they come
+	buffer       []byte             // PCM left over from the last chunk, less than one frame
+	audioSize    int64              // Track total audio size
+	startTime    time.Time          // Track TTS start time
+	recordDecode media2.EncoderFunc // Decodes sent frames for the call recording, nil when not recording
 }
 
 func (t *TTSSender) OnMessage(data []byte) {
@@ -934,7 +1428,7 @@ func (t *TTSSender) OnMessage(data []byte) {
 	t.client.Mu.RUnlock()
 
 	if connClosed || transportClosed {
-		log.Printf("[Server] TTS stopped: connection closed")
+		logger.Info("transport: TTS stopped, connection closed", zap.String("session", t.client.SessionID))
 		return
 	}
 
@@ -947,50 +1441,64 @@ func (t *TTSSender) OnMessage(data []byte) {
 	// Note: QCloud TTS returns PCM directly, but other providers might return WAV
 	// data = encoder.StripWavHeader(data) // Uncomment if needed
 
-	// PCMA standard sample rate (RFC 3551)
-	const pcmaSampleRate = 8000
-
-	// Get TTS format
-	ttsFormat := t.client.ttsService.Format()
-
-	// Resample from TTS sample rate to PCMA sample rate (8kHz)
-	// TTS typically returns 16kHz, but PCMA needs 8kHz
-	if ttsFormat.SampleRate != pcmaSampleRate {
-		resampled, err := media2.ResamplePCM(data, ttsFormat.SampleRate, pcmaSampleRate)
-		if err != nil {
-			log.Printf("[Server] Resample error: %v", err)
+	// Streaming providers send chunks of any length; encode whole frames only
+	// and keep the rest for the next chunk
+	if t.frameBytes > 0 {
+		t.buffer = append(t.buffer, data...)
+		n := len(t.buffer) / t.frameBytes * t.frameBytes
+		if n == 0 {
 			return
 		}
-		data = resampled
+		data = append([]byte(nil), t.buffer[:n]...)
+		t.buffer = append(t.buffer[:0], t.buffer[n:]...)
 	}
+	t.encodeAndSend(data)
+}
+
+// flush pads the PCM left over after the last chunk with silence and sends it
+func (t *TTSSender) flush() {
+	if len(t.buffer) == 0 || t.client.shouldStopTTS() {
+		return
+	}
+	data := make([]byte, t.frameBytes)
+	copy(data, t.buffer)
+	t.buffer = t.buffer[:0]
+	t.encodeAndSend(data)
+}
 
-	// Encode to PCMA (now at 8kHz)
-	pcmaData, err := encoder.Pcm2pcma(data)
+// encodeAndSend encodes PCM to the send codec and writes the frames
+func (t *TTSSender) encodeAndSend(data []byte) {
+	// Encode to the send codec; the encoder resamples from the TTS sample rate
+	// and splits the audio into frames (PCMA: 160 bytes, Opus: one packet per 20ms)
+	packets, err := t.encode(&media2.AudioPacket{Payload: data})
 	if err != nil {
-		log.Printf("[Server] Encode PCMA error: %v", err)
+		logger.Error("transport: encode TTS audio error", zap.String("session", t.client.SessionID), zap.Error(err))
 		return
 	}
 
+	frames := make([][]byte, 0, len(packets))
+	for _, packet := range packets {
+		if audioPacket, ok := packet.(*media2.AudioPacket); ok && len(audioPacket.Payload) > 0 {
+			frames = append(frames, audioPacket.Payload)
+		}
+	}
+
 	// Send in frames
-	t.sendPCMAFrames(pcmaData)
+	t.sendFrames(frames)
 }
 
 func (t *TTSSender) OnTimestamp(timestamp synthesizer.SentenceTimestamp) {
 	// Not used for now
 }
 
-func (t *TTSSender) sendPCMAFrames(pcmaData []byte) {
-	frameDuration := 20 * time.Millisecond
-	startTime := time.Now()
+func (t *TTSSender) sendFrames(frames [][]byte) {
 	frameCount := 0
+	totalBytes := 0
 
-	// PCMA frame size at 8kHz: 20ms * 8000Hz = 160 samples = 160 bytes (8-bit)
-	const pcmaFrameSize = 160
-
-	for i := 0; i < len(pcmaData); i += pcmaFrameSize {
+	for _, frame := range frames {
 		// Check for barge-in: stop sending if user started speaking
 		if t.client.shouldStopTTS() {
-			log.Printf("[Server] TTS interrupted by barge-in after %d frames", frameCount)
+			logger.Info("transport: TTS interrupted by barge-in", zap.String("session", t.client.SessionID), zap.Int("frames", frameCount))
 			return
 		}
 
@@ -1001,41 +1509,81 @@ func (t *TTSSender) sendPCMAFrames(pcmaData []byte) {
 		t.client.Mu.RUnlock()
 
 		if connClosed || transportClosed {
-			log.Printf("[Server] TTS stopped: connection closed after %d frames", frameCount)
+			logger.Info("transport: TTS stopped, connection closed", zap.String("session", t.client.SessionID), zap.Int("frames", frameCount))
 			return
 		}
 
 		// Check if txTrack is still valid
 		if t.txTrack == nil {
-			log.Printf("[Server] TTS stopped: txTrack is nil after %d frames", frameCount)
+			logger.Warn("transport: TTS stopped, txTrack is nil", zap.String("session", t.client.SessionID), zap.Int("frames", frameCount))
 			return
 		}
 
-		end := i + pcmaFrameSize
-		if end > len(pcmaData) {
-			end = len(pcmaData)
+		if t.sendSpan == nil && t.ctx != nil {
+			_, t.sendSpan = metrics.StartSpan(t.ctx, "webrtc.send",
+				metrics.WithAttributes(map[string]interface{}{"webrtc.codec": t.txTrack.Codec().MimeType}))
 		}
-
-		// Calculate exact send time
-		expectedTime := startTime.Add(time.Duration(frameCount) * frameDuration)
-		if now := time.Now(); expectedTime.After(now) {
-			time.Sleep(expectedTime.Sub(now))
+		// The writer sleeps until the frame is due
+		if err := t.writer.WriteFrame(frame); err != nil {
+			logger.Error("transport: error writing sample", zap.String("session", t.client.SessionID), zap.Error(err))
+			return
 		}
+		t.recordFrame(frame)
 
-		sample := media.Sample{
-			Data:     pcmaData[i:end],
-			Duration: frameDuration,
-		}
+		frameCount++
+		t.framesSent++
+		totalBytes += len(frame)
+	}
 
-		if err := t.txTrack.WriteSample(sample); err != nil {
-			log.Printf("[Server] Error writing sample: %v", err)
-			return
+	logger.Debug("transport: sent TTS frames", zap.String("session", t.client.SessionID), zap.Int("frames", frameCount), zap.Int("bytes", totalBytes))
+}
+
+// endSendSpan closes the webrtc.send span once synthesis has returned
+func (t *TTSSender) endSendSpan(err error) {
+	if t.sendSpan == nil {
+		return
+	}
+	t.sendSpan.SetAttribute("webrtc.frames", t.framesSent)
+	t.sendSpan.SetAttribute("webrtc.interrupted", t.client.shouldStopTTS())
+	metrics.EndSpan(t.sendSpan, err)
+}
+
+// recordFrame decodes a sent frame into the call recording
+func (t *TTSSender) recordFrame(frame []byte) {
+	if t.recordDecode == nil {
+		return
+	}
+	// The decoder may keep the payload, so give it a copy
+	packets, err := t.recordDecode(&media2.AudioPacket{Payload: append([]byte(nil), frame...)})
+	if err != nil {
+		return
+	}
+	for _, packet := range packets {
+		if audioPacket, ok := packet.(*media2.AudioPacket); ok {
+			t.client.record(recording.ChannelTx, audioPacket.Payload)
 		}
+	}
+}
 
-		frameCount++
+// createEncoderForCodec creates the TTS encoder for the send track's codec
+func (c *AIClient) createEncoderForCodec(mimeType string) (media2.EncoderFunc, time.Duration, error) {
+	codecName, err := rtcmedia.CodecForMimeType(mimeType)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	log.Printf("[Server] Sent %d TTS frames (%d bytes)", frameCount, len(pcmaData))
+	src := rtcmedia.CodecConfigFor(codecName)
+	frameDuration, _ := time.ParseDuration(src.FrameDuration)
+
+	ttsFormat := c.ttsService.Format()
+	encode, err := media2.NewPipeline().
+		Input(ttsFormat.SampleRate, audioChannels).
+		Encode(src).
+		Build()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create %s encoder: %w", codecName, err)
+	}
+	return encode, frameDuration, nil
 }
 
 // createDecoderForCodec creates the appropriate decoder based on codec type
@@ -1053,39 +1601,28 @@ func (c *AIClient) createDecoderForCodec(mimeType string, clockRate int) (media2
 		sourceSampleRate = 8000
 	case "audio/opus":
 		codecName = "opus"
-		sourceSampleRate = 48000
+		sourceSampleRate = encoder.OpusSampleRate
 	case "audio/G722":
 		codecName = "g722"
-		sourceSampleRate = 8000 // G.722 uses 16kHz but clock rate is 8000
+		sourceSampleRate = encoder.G722SampleRate // RTP clock rate is 8000, audio is 16kHz
 	default:
 		return nil, fmt.Errorf("unsupported codec: %s", mimeType)
 	}
 
-	fmt.Printf("[Server] Creating decoder: %s (%dHz) -> PCM (%dHz)\n",
-		codecName, sourceSampleRate, targetSampleRate)
+	logger.Debug("transport: creating decoder",
+		zap.String("codec", codecName), zap.Int("sourceRate", sourceSampleRate), zap.Int("targetRate", targetSampleRate))
 
-	// Determine bit depth based on codec
-	bitDepth := 8
-	if codecName == "opus" || codecName == "g722" {
-		bitDepth = 16 // These codecs decode to 16-bit PCM
-	}
-
-	decoder, err := encoder.CreateDecode(
-		media2.CodecConfig{
+	// 16kHz mono PCM for ASR
+	decoder, err := media2.NewPipeline().
+		Decode(media2.CodecConfig{
 			Codec:         codecName,
 			SampleRate:    sourceSampleRate,
 			Channels:      audioChannels,
-			BitDepth:      bitDepth,
-			FrameDuration: "20ms",
-		},
-		media2.CodecConfig{
-			Codec:         "pcm",
-			SampleRate:    targetSampleRate, // 16kHz for ASR
-			Channels:      audioChannels,
-			BitDepth:      audioBitDepth, // 16-bit PCM for ASR
 			FrameDuration: "20ms",
-		},
-	)
+		}).
+		Mono().
+		Resample(targetSampleRate).
+		Build()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create %s decoder: %w", codecName, err)
 	}
@@ -1104,12 +1641,13 @@ func (c *AIClient) startAudioReceiver() error {
 	for i := 0; i < maxConnectionRetries; i++ {
 		rxTrack = c.Transport.GetRxTrack()
 		if rxTrack != nil {
-			fmt.Printf("[Server] rxTrack received after %d attempts\n", i+1)
+			logger.Debug("transport: rxTrack received", zap.String("session", c.SessionID), zap.Int("attempts", i+1))
 			break
 		}
 		if i%connectionStateLogInterval == 0 {
-			fmt.Printf("[Server] Waiting for rxTrack... (attempt %d/%d, connection state: %s)\n",
-				i+1, maxConnectionRetries, c.Transport.GetConnectionState().String())
+			logger.Debug("transport: waiting for rxTrack", zap.String("session", c.SessionID),
+				zap.Int("attempt", i+1), zap.Int("maxAttempts", maxConnectionRetries),
+				zap.String("connectionState", c.Transport.GetConnectionState().String()))
 		}
 		time.Sleep(connectionRetryDelay)
 	}
@@ -1122,6 +1660,45 @@ func (c *AIClient) startAudioReceiver() error {
 	return c.StartAudioReceiverFromTrack(rxTrack)
 }
 
+// reportCallStats periodically pushes the transport's RTP stats and MOS
+// estimate to the global metrics monitor until done is closed
+func (c *AIClient) reportCallStats(done <-chan struct{}) {
+	ticker := time.NewTicker(constants.DefaultStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		monitor := metrics.GetGlobalMonitor()
+		if monitor == nil || c.Transport == nil {
+			continue
+		}
+		stats, err := c.Transport.GetStats()
+		if err != nil {
+			continue
+		}
+		rtt := stats.RoundTripTime
+		if rtt == 0 {
+			rtt = stats.ICERoundTripTime
+		}
+		monitor.RecordCallQuality(metrics.CallQuality{
+			SessionID:       c.SessionID,
+			Codec:           stats.Codec,
+			PacketsReceived: stats.PacketsReceived,
+			PacketsLost:     stats.PacketsLost,
+			LossRate:        stats.LossRate,
+			Jitter:          stats.Jitter,
+			RTT:             rtt,
+			RFactor:         stats.RFactor,
+			MOS:             stats.MOS,
+			UpdatedAt:       stats.Timestamp,
+		})
+	}
+}
+
 // StartAudioReceiverFromTrack starts receiving and processing audio from a specific track
 func (c *AIClient) StartAudioReceiverFromTrack(rxTrack *webrtc.TrackRemote) error {
 	if rxTrack == nil {
@@ -1129,19 +1706,44 @@ func (c *AIClient) StartAudioReceiverFromTrack(rxTrack *webrtc.TrackRemote) erro
 	}
 
 	codecParams := rxTrack.Codec()
-	fmt.Printf("[Server] Received track: %s, %dHz\n", codecParams.MimeType, codecParams.ClockRate)
+	logger.Info("transport: received track", zap.String("session", c.SessionID), zap.String("codec", codecParams.MimeType), zap.Uint32("clockRate", codecParams.ClockRate))
+
+	// RED (RFC 2198) carries the primary codec plus redundant copies of
+	// earlier frames; unwrap it and decode the primary codec
+	mimeType := codecParams.MimeType
+	var red *rtcmedia.REDDepacketizer
+	if rtcmedia.IsRED(mimeType) {
+		red = rtcmedia.NewREDDepacketizer()
+		mimeType = rtcmedia.REDPrimaryMimeType
+	}
 
 	// Create decoder based on actual codec type
-	decoder, err := c.createDecoderForCodec(codecParams.MimeType, int(codecParams.ClockRate))
+	decoder, err := c.createDecoderForCodec(mimeType, int(codecParams.ClockRate))
 	if err != nil {
-		return fmt.Errorf("failed to create decoder for %s: %w", codecParams.MimeType, err)
+		return fmt.Errorf("failed to create decoder for %s: %w", mimeType, err)
 	}
 
 	c.Mu.Lock()
 	c.audioDecoder = decoder
 	c.Mu.Unlock()
 
-	fmt.Printf("[Server] Created decoder for codec: %s\n", codecParams.MimeType)
+	// Media flowing from the peer is when the call counts as answered
+	c.cdrMu.Lock()
+	if c.cdr.AnswerTime == nil {
+		now := time.Now()
+		c.cdr.AnswerTime = &now
+	}
+	c.cdr.Codec = strings.TrimPrefix(strings.ToLower(mimeType), "audio/")
+	c.cdrMu.Unlock()
+
+	logger.Debug("transport: created decoder", zap.String("session", c.SessionID), zap.String("codec", codecParams.MimeType))
+
+	c.Mu.RLock()
+	done := c.doneChan
+	c.Mu.RUnlock()
+	if done != nil {
+		go c.reportCallStats(done)
+	}
 
 	packetCount := 0
 	for {
@@ -1156,6 +1758,7 @@ func (c *AIClient) StartAudioReceiverFromTrack(rxTrack *webrtc.TrackRemote) erro
 		c.Mu.RLock()
 		currentDecoder := c.audioDecoder
 		asrService := c.asrService
+		noiseSuppressor := c.noiseSuppressor
 		c.Mu.RUnlock()
 
 		if currentDecoder == nil {
@@ -1169,32 +1772,48 @@ func (c *AIClient) StartAudioReceiverFromTrack(rxTrack *webrtc.TrackRemote) erro
 
 		// Debug: Log packet information
 		if packetCount%100 == 0 {
-			fmt.Printf("[Server] Received RTP packet #%d, payload size: %d, payload type: %d\n",
-				packetCount, len(packet.Payload), packet.PayloadType)
+			logger.Debug("transport: received RTP packet", zap.String("session", c.SessionID),
+				zap.Int("packet", packetCount), zap.Int("payloadSize", len(packet.Payload)), zap.Uint8("payloadType", packet.PayloadType))
 		}
 
-		// Decode audio to PCM (supports PCMA, PCMU, Opus, G722)
-		audioFrame := &media2.AudioPacket{Payload: packet.Payload}
-		decodedFrames, err := currentDecoder(audioFrame)
-		if err != nil {
-			if packetCount%packetLogInterval == 0 {
-				log.Printf("[Server] Decode error: %v", err)
+		payloads := [][]byte{packet.Payload}
+		if red != nil {
+			if payloads, err = red.Depacketize(packet); err != nil {
+				if packetCount%packetLogInterval == 0 {
+					logger.Warn("transport: RED depacketize error", zap.String("session", c.SessionID), zap.Error(err))
+				}
+				packetCount++
+				continue
 			}
-			packetCount++
-			continue
 		}
 
-		// Collect PCM data
+		// Decode audio to PCM (supports PCMA, PCMU, Opus, G722)
 		var pcmData []byte
-		for _, frame := range decodedFrames {
-			if af, ok := frame.(*media2.AudioPacket); ok && len(af.Payload) > 0 {
-				pcmData = append(pcmData, af.Payload...)
+		for _, payload := range payloads {
+			decodedFrames, err := currentDecoder(&media2.AudioPacket{Payload: payload})
+			if err != nil {
+				if packetCount%packetLogInterval == 0 {
+					logger.Warn("transport: decode error", zap.String("session", c.SessionID), zap.Error(err))
+				}
+				continue
 			}
+			for _, frame := range decodedFrames {
+				if af, ok := frame.(*media2.AudioPacket); ok && len(af.Payload) > 0 {
+					pcmData = append(pcmData, af.Payload...)
+				}
+			}
+		}
+
+		// Record what the caller actually sent, before noise suppression
+		c.record(recording.ChannelRx, pcmData)
+
+		if noiseSuppressor != nil && len(pcmData) > 0 {
+			pcmData = noiseSuppressor.Process(pcmData)
 		}
 
 		// Debug: Log decoded data
 		if packetCount%100 == 0 && len(pcmData) > 0 {
-			fmt.Printf("[Server] Decoded PCM data size: %d bytes\n", len(pcmData))
+			logger.Debug("transport: decoded PCM", zap.String("session", c.SessionID), zap.Int("bytes", len(pcmData)))
 		}
 
 		// Barge-in detection: Check if user is speaking while TTS is playing
@@ -1208,7 +1827,7 @@ func (c *AIClient) StartAudioReceiverFromTrack(rxTrack *webrtc.TrackRemote) erro
 		if !c.shouldProcessAudio() {
 			packetCount++
 			if packetCount%packetLogInterval == 0 {
-				fmt.Printf("[Server] Skipped %d RTP packets (TTS playing or cooldown)\n", packetCount)
+				logger.Debug("transport: skipped RTP packets while TTS playing or cooling down", zap.String("session", c.SessionID), zap.Int("packets", packetCount))
 			}
 			continue
 		}
@@ -1216,16 +1835,16 @@ func (c *AIClient) StartAudioReceiverFromTrack(rxTrack *webrtc.TrackRemote) erro
 		// Send to ASR (check if ASR service is still available)
 		if len(pcmData) > 0 && asrService != nil {
 			if err := asrService.SendAudioBytes(pcmData); err != nil {
-				log.Printf("[Server] ASR send error: %v", err)
+				logger.Warn("transport: ASR send error", zap.String("session", c.SessionID), zap.Error(err))
 				asrService.RestartClient()
 			} else if packetCount%100 == 0 {
-				fmt.Printf("[Server] Sent %d bytes to ASR\n", len(pcmData))
+				logger.Debug("transport: sent audio to ASR", zap.String("session", c.SessionID), zap.Int("bytes", len(pcmData)))
 			}
 		}
 
 		packetCount++
 		if packetCount%packetLogInterval == 0 {
-			fmt.Printf("[Server] Processed %d RTP packets\n", packetCount)
+			logger.Debug("transport: processed RTP packets", zap.String("session", c.SessionID), zap.Int("packets", packetCount))
 		}
 	}
 }
@@ -0,0 +1,35 @@
+package transport
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+// trackWriter paces encoded frames onto a send track in real time. Its clock
+// keeps running across writes, so audio streamed in small chunks plays back
+// to back instead of each chunk restarting the pacing. When the producer falls
+// behind by more than a frame the clock restarts, rather than bursting the
+// late frames out to catch up.
+type trackWriter struct {
+	track         *webrtc.TrackLocalStaticSample
+	frameDuration time.Duration
+	next          time.Time // When the next frame is due, zero before the first
+}
+
+func newTrackWriter(track *webrtc.TrackLocalStaticSample, frameDuration time.Duration) *trackWriter {
+	return &trackWriter{track: track, frameDuration: frameDuration}
+}
+
+// WriteFrame waits until the frame is due and writes it as one sample
+func (w *trackWriter) WriteFrame(frame []byte) error {
+	now := time.Now()
+	if w.next.IsZero() || now.Sub(w.next) > w.frameDuration {
+		w.next = now
+	} else if w.next.After(now) {
+		time.Sleep(w.next.Sub(now))
+	}
+	w.next = w.next.Add(w.frameDuration)
+	return w.track.WriteSample(media.Sample{Data: frame, Duration: w.frameDuration})
+}
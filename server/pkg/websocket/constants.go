@@ -15,6 +15,7 @@ const (
 	// 业务消息类型
 	MessageTypeChat         = "chat"
 	MessageTypeNotification = "notification"
+	MessageTypeUnreadCount  = "notification_unread"
 	MessageTypeSystem       = "system"
 	MessageTypeError        = "error"
 	MessageTypeSuccess      = "success"
@@ -457,6 +457,23 @@ func (h *Hub) GetBroadcastChannel() chan<- *Message {
 	return h.broadcast
 }
 
+// SendToUser 向用户的所有在线连接发送消息，不阻塞调用方，广播通道已满时丢弃并返回 false
+func (h *Hub) SendToUser(userID, msgType string, data interface{}) bool {
+	message := &Message{
+		Type:      msgType,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+		To:        userID,
+	}
+	select {
+	case h.broadcast <- message:
+		return true
+	default:
+		logrus.Warnf("广播通道已满，发送给用户 %s 的消息被丢弃", userID)
+		return false
+	}
+}
+
 // Close 关闭Hub
 func (h *Hub) Close() {
 	h.cancel()
@@ -15,6 +15,19 @@ const (
 	NodeTypeWait      NodeType = "wait"
 	NodeTypeTimer     NodeType = "timer"
 	NodeTypeScript    NodeType = "script"
+	NodeTypeHTTP      NodeType = "http"
+	NodeTypeLLM       NodeType = "llm"
+	NodeTypeTTS       NodeType = "tts"
+	NodeTypeDelay     NodeType = "delay"
+	NodeTypeApproval  NodeType = "approval"
+	NodeTypeIVR       NodeType = "ivr"
+
+	// Voice nodes drive the live call of the workflow context
+	NodeTypeCall        NodeType = "call"
+	NodeTypeSay         NodeType = "say"
+	NodeTypeCollectDTMF NodeType = "collect_dtmf"
+	NodeTypeListen      NodeType = "listen"
+	NodeTypeIntent      NodeType = "intent"
 )
 
 func (nt NodeType) String() string {
@@ -29,4 +42,5 @@ const (
 	NodeStatusCompleted NodeStatus = "completed"
 	NodeStatusFailed    NodeStatus = "failed"
 	NodeStatusSkipped   NodeStatus = "skipped"
+	NodeStatusWaiting   NodeStatus = "waiting"
 )
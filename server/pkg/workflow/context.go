@@ -1,7 +1,12 @@
 package workflow
 
 import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,8 +23,16 @@ type WorkflowContext struct {
 	NodeData    map[string]interface{} // storage node data
 	NodeStatus  map[string]NodeStatus  // all node status
 	History     []NodeExecutionRecord  // execution history
+	Steps       []StepRecord           // per-node inputs, outputs and timing of the current execution
 	Logs        []ExecutionLog         // execution logs for frontend display
 	LogSender   LogSender              // optional log sender for real-time streaming
+	Call        CallSession            // optional live call driving IVR and voice nodes
+	UserID      uint                   // workflow owner, dials and is billed for calls placed by call nodes
+
+	placedMu     sync.Mutex
+	placedCalls  []PlacedCall     // calls placed by call nodes during the current execution
+	placedClosed bool             // execution stopped, calls placed from now on are hung up at once
+	root         *WorkflowContext // set on attempt copies, placed calls are tracked by the workflow context
 }
 
 // ExecutionLog represents a log entry for frontend terminal display
@@ -39,6 +52,20 @@ type NodeExecutionRecord struct {
 	Error     string
 }
 
+// StepRecord captures one node execution for step-level debugging
+type StepRecord struct {
+	NodeID    string
+	NodeName  string
+	NodeType  NodeType
+	Status    NodeStatus
+	Inputs    map[string]interface{} // resolved input params, nil when the node declares none
+	Outputs   map[string]interface{} // context keys the node added or changed
+	NextNodes []string
+	Error     string
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
 // NewWorkflowContext helper to build context with initialized maps
 func NewWorkflowContext(workflowID string) *WorkflowContext {
 	return &WorkflowContext{
@@ -203,3 +230,119 @@ func (ctx *WorkflowContext) SetData(key string, value interface{}) {
 	}
 	ctx.NodeData[key] = value
 }
+
+// trackPlacedCall remembers a call placed by a call node so it is hung up when execution stops
+func (ctx *WorkflowContext) trackPlacedCall(call PlacedCall) {
+	if ctx.root != nil {
+		ctx.root.trackPlacedCall(call)
+		return
+	}
+	ctx.placedMu.Lock()
+	closed := ctx.placedClosed
+	if !closed {
+		ctx.placedCalls = append(ctx.placedCalls, call)
+	}
+	ctx.placedMu.Unlock()
+	if closed {
+		// A call node that timed out finished dialing after the workflow stopped
+		_ = call.Hangup()
+	}
+}
+
+// hangupPlacedCalls hangs up the calls placed during execution. A stopped
+// workflow (finished, failed or suspended) cannot drive them any further.
+func (ctx *WorkflowContext) hangupPlacedCalls() {
+	ctx.placedMu.Lock()
+	calls := ctx.placedCalls
+	ctx.placedCalls = nil
+	ctx.placedClosed = true
+	ctx.placedMu.Unlock()
+	for _, call := range calls {
+		if err := call.Hangup(); err != nil {
+			ctx.AddLog("warning", fmt.Sprintf("Hang up call %s failed: %v", call.CallID(), err), "", "")
+		}
+	}
+	if len(calls) > 0 {
+		ctx.Call = nil
+	}
+}
+
+// fork returns a copy of the context for a single node attempt. Maps and slices
+// are copied shallowly, so a node that timed out and keeps running in background
+// only modifies its abandoned copy.
+func (ctx *WorkflowContext) fork() *WorkflowContext {
+	root := ctx
+	if ctx.root != nil {
+		root = ctx.root
+	}
+	return &WorkflowContext{
+		WorkflowID:  ctx.WorkflowID,
+		CurrentNode: ctx.CurrentNode,
+		Status:      ctx.Status,
+		Parameters:  maps.Clone(ctx.Parameters),
+		NodeData:    maps.Clone(ctx.NodeData),
+		NodeStatus:  maps.Clone(ctx.NodeStatus),
+		History:     slices.Clone(ctx.History),
+		Steps:       slices.Clone(ctx.Steps),
+		Logs:        slices.Clone(ctx.Logs),
+		LogSender:   ctx.LogSender,
+		Call:        ctx.Call,
+		UserID:      ctx.UserID,
+		root:        root,
+	}
+}
+
+// adopt takes over the state of a finished attempt created by fork
+func (ctx *WorkflowContext) adopt(attempt *WorkflowContext) {
+	ctx.CurrentNode = attempt.CurrentNode
+	ctx.Status = attempt.Status
+	ctx.Parameters = attempt.Parameters
+	ctx.NodeData = attempt.NodeData
+	ctx.NodeStatus = attempt.NodeStatus
+	ctx.History = attempt.History
+	ctx.Steps = attempt.Steps
+	ctx.Logs = attempt.Logs
+	ctx.Call = attempt.Call
+	ctx.UserID = attempt.UserID
+}
+
+// recordStep appends the step record of a node execution. before is the
+// NodeData snapshot taken right before the node ran.
+func (ctx *WorkflowContext) recordStep(node ExecutableNode, before map[string]interface{}, startedAt time.Time, status NodeStatus, nextNodes []string, nodeErr error) {
+	base := node.Base()
+	step := StepRecord{
+		NodeID:    base.ID,
+		NodeName:  base.Name,
+		NodeType:  base.Type,
+		Status:    status,
+		NextNodes: nextNodes,
+		StartedAt: startedAt,
+		Duration:  time.Since(startedAt),
+	}
+	if nodeErr != nil {
+		step.Error = nodeErr.Error()
+	}
+	if len(base.InputParams) > 0 {
+		if inputs, err := base.PrepareInputs(ctx); err == nil {
+			step.Inputs = inputs
+		}
+	}
+	for key, value := range ctx.NodeData {
+		if old, ok := before[key]; !ok || !reflect.DeepEqual(old, value) {
+			if step.Outputs == nil {
+				step.Outputs = make(map[string]interface{})
+			}
+			step.Outputs[key] = value
+		}
+	}
+	ctx.Steps = append(ctx.Steps, step)
+}
+
+// snapshotData returns a shallow copy of the node data used to diff step outputs
+func (ctx *WorkflowContext) snapshotData() map[string]interface{} {
+	snapshot := make(map[string]interface{}, len(ctx.NodeData))
+	for key, value := range ctx.NodeData {
+		snapshot[key] = value
+	}
+	return snapshot
+}
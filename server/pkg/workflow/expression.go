@@ -0,0 +1,205 @@
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EvaluateExpression evaluates a boolean expression against the workflow context.
+// Supported syntax:
+//   - comparisons: parameters.age >= 18, context.status == "ok", {{task1.statusCode}} != 200
+//   - logical operators: &&, ||, ! and parentheses
+//   - literals: numbers, quoted strings, true/false/null
+//
+// Bare operands are resolved from the context (parameters.x, context.x, nodeId.x);
+// when they cannot be resolved they evaluate to null, which is false. {{x}} is the
+// same as the bare operand x, so string values are compared as values and never
+// parsed as part of the expression.
+func EvaluateExpression(ctx *WorkflowContext, expression string) (bool, error) {
+	expr := strings.TrimSpace(exprTemplatePattern.ReplaceAllString(expression, " $1 "))
+	if expr == "" {
+		return false, fmt.Errorf("expression is empty")
+	}
+	p := &exprParser{ctx: ctx, tokens: tokenizeExpression(expr)}
+	result, err := p.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("unexpected token %q in expression: %s", p.tokens[p.pos], expression)
+	}
+	return result, nil
+}
+
+// exprTemplatePattern matches {{x}} placeholders in an expression
+var exprTemplatePattern = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+var exprOperators = []string{"&&", "||", ">=", "<=", "==", "!=", ">", "<", "!", "(", ")"}
+
+// tokenizeExpression splits an expression into operands, operators and quoted strings
+func tokenizeExpression(expr string) []string {
+	var tokens []string
+	var current strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			tokens = append(tokens, s)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(expr); {
+		ch := expr[i]
+		if ch == '"' || ch == '\'' {
+			flush()
+			end := strings.IndexByte(expr[i+1:], ch)
+			if end == -1 {
+				tokens = append(tokens, expr[i:])
+				return tokens
+			}
+			tokens = append(tokens, expr[i:i+end+2])
+			i += end + 2
+			continue
+		}
+		if ch == ' ' || ch == '\t' || ch == '\n' {
+			flush()
+			i++
+			continue
+		}
+		matched := ""
+		for _, op := range exprOperators {
+			if strings.HasPrefix(expr[i:], op) {
+				matched = op
+				break
+			}
+		}
+		if matched != "" {
+			flush()
+			tokens = append(tokens, matched)
+			i += len(matched)
+			continue
+		}
+		current.WriteByte(ch)
+		i++
+	}
+	flush()
+	return tokens
+}
+
+type exprParser struct {
+	ctx    *WorkflowContext
+	tokens []string
+	pos    int
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *exprParser) parseOr() (bool, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return false, err
+	}
+	for p.peek() == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return false, err
+		}
+		left = left || right
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (bool, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return false, err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return false, err
+		}
+		left = left && right
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (bool, error) {
+	if p.peek() == "!" {
+		p.next()
+		val, err := p.parseUnary()
+		return !val, err
+	}
+	if p.peek() == "(" {
+		p.next()
+		val, err := p.parseOr()
+		if err != nil {
+			return false, err
+		}
+		if p.next() != ")" {
+			return false, fmt.Errorf("missing closing parenthesis")
+		}
+		return val, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (bool, error) {
+	tok := p.next()
+	if tok == "" {
+		return false, fmt.Errorf("unexpected end of expression")
+	}
+	left := p.operand(tok)
+	switch op := p.peek(); op {
+	case ">", "<", ">=", "<=", "==", "!=":
+		p.next()
+		rightTok := p.next()
+		if rightTok == "" {
+			return false, fmt.Errorf("missing right operand for %s", op)
+		}
+		return compareValues(left, p.operand(rightTok), op)
+	default:
+		return truthy(left), nil
+	}
+}
+
+// operand converts a token into a literal or a value resolved from context
+func (p *exprParser) operand(tok string) interface{} {
+	if len(tok) >= 2 && (tok[0] == '"' || tok[0] == '\'') && tok[len(tok)-1] == tok[0] {
+		return tok[1 : len(tok)-1]
+	}
+	switch tok {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null", "nil":
+		return nil
+	}
+	if num, err := parseNumber(tok); err == nil {
+		return num
+	}
+	if val, ok := p.ctx.ResolveValue(tok); ok {
+		if s, ok := val.(string); ok {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f
+			}
+		}
+		return val
+	}
+	return nil
+}
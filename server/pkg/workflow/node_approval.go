@@ -0,0 +1,86 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrWorkflowSuspended returned (wrapped in SuspendedError) when a node pauses the workflow
+var ErrWorkflowSuspended = errors.New("workflow suspended")
+
+// SuspendedError signals that execution paused at NodeID and can be resumed later
+type SuspendedError struct {
+	NodeID string
+	Reason string
+}
+
+func (e *SuspendedError) Error() string {
+	return fmt.Sprintf("workflow suspended at node %s: %s", e.NodeID, e.Reason)
+}
+
+func (e *SuspendedError) Unwrap() error {
+	return ErrWorkflowSuspended
+}
+
+// Approval decisions
+const (
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// ApprovalDecisionKey is the context key holding the decision for an approval node
+func ApprovalDecisionKey(nodeID string) string {
+	return nodeID + "_decision"
+}
+
+// ApprovalNode pauses the workflow until a human approves or rejects it
+type ApprovalNode struct {
+	Node
+	Title              string   // title shown to approvers
+	Message            string   // message template, supports {{var}}
+	Approvers          []string // user ids or email addresses
+	ApprovedNextNodeID string
+	RejectedNextNodeID string
+}
+
+func (a *ApprovalNode) Base() *Node {
+	return &a.Node
+}
+
+// RenderMessage resolves the message template against the context
+func (a *ApprovalNode) RenderMessage(ctx *WorkflowContext) string {
+	return resolveTemplate(a.Message, nil, ctx)
+}
+
+func (a *ApprovalNode) Run(ctx *WorkflowContext) ([]string, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("approval node %s requires a workflow context", a.Name)
+	}
+	raw, ok := ctx.NodeData[ApprovalDecisionKey(a.ID)]
+	if !ok {
+		ctx.AddLog("info", fmt.Sprintf("Waiting for approval: %s", a.Title), a.ID, a.Name)
+		return nil, &SuspendedError{NodeID: a.ID, Reason: "waiting for approval"}
+	}
+
+	decision, _ := raw.(string)
+	approved := decision == ApprovalApproved
+	ctx.NodeData[a.ID+"_result"] = approved
+	ctx.AddLog("info", fmt.Sprintf("Approval decision: %s", decision), a.ID, a.Name)
+
+	if approved {
+		if a.ApprovedNextNodeID != "" {
+			return []string{a.ApprovedNextNodeID}, nil
+		}
+		if len(a.NextNodes) > 0 {
+			return a.NextNodes[:1], nil
+		}
+		return nil, nil
+	}
+	if a.RejectedNextNodeID != "" {
+		return []string{a.RejectedNextNodeID}, nil
+	}
+	if len(a.NextNodes) > 1 {
+		return a.NextNodes[1:2], nil
+	}
+	return nil, fmt.Errorf("approval node %s was %s", a.Name, decision)
+}
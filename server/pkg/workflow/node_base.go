@@ -1,6 +1,9 @@
 package workflow
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // ExecutableNode unify all concrete nodes
 type ExecutableNode interface {
@@ -18,6 +21,8 @@ type Node struct {
 	PrevNodes    []string                         // previous node ids
 	Properties   map[string]string                // node properties
 	Execute      func(ctx *WorkflowContext) error // node execute function
+	Retry        *RetryPolicy                     // optional retry policy, overrides workflow default
+	Timeout      time.Duration                    // optional per-attempt timeout, overrides workflow default
 }
 
 func (n *Node) Base() *Node {
@@ -0,0 +1,118 @@
+package workflow
+
+import (
+	"fmt"
+	"time"
+)
+
+// CallSession is the live phone call IVR and voice nodes talk to
+type CallSession interface {
+	// Say plays text to the caller and returns once playback has finished
+	Say(text string) error
+	// ReadDigits collects up to max digits, stopping early on '#' or when
+	// no key is pressed within timeout. An empty string means no input.
+	ReadDigits(max int, timeout time.Duration) (string, error)
+	// Listen transcribes what the caller says until silence passes without
+	// new speech once the caller has started talking, or timeout passes.
+	// An empty string means the caller said nothing.
+	Listen(timeout, silence time.Duration) (string, error)
+}
+
+// IVR defaults
+const (
+	DefaultIVRTimeout = 5 * time.Second
+	DefaultIVRRetries = 2
+)
+
+// IVRDigitsKey is the context key holding the digits collected by an IVR node
+func IVRDigitsKey(nodeID string) string {
+	return nodeID + "_digits"
+}
+
+// IVRNode plays a menu prompt and routes on the digits the caller presses
+type IVRNode struct {
+	Node
+	Prompt            string            // menu prompt, supports {{var}}
+	InvalidPrompt     string            // played after invalid input or timeout before retrying
+	Options           map[string]string // digits -> next node id
+	MaxDigits         int               // digits to collect, defaults to 1
+	Timeout           time.Duration     // wait for input after the prompt
+	Retries           int               // extra attempts after invalid input
+	DefaultNextNodeID string            // taken when no valid input was received
+}
+
+func (n *IVRNode) Base() *Node {
+	return &n.Node
+}
+
+// RenderPrompt resolves the prompt template against the context
+func (n *IVRNode) RenderPrompt(ctx *WorkflowContext) string {
+	return resolveTemplate(n.Prompt, nil, ctx)
+}
+
+func (n *IVRNode) Run(ctx *WorkflowContext) ([]string, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("ivr node %s requires a workflow context", n.Name)
+	}
+	if ctx.NodeData == nil {
+		ctx.NodeData = make(map[string]interface{})
+	}
+
+	// Digits supplied up front (e.g. replayed executions) skip the call entirely
+	if raw, ok := ctx.NodeData[IVRDigitsKey(n.ID)]; ok {
+		digits, _ := raw.(string)
+		if next, ok := n.Options[digits]; ok {
+			ctx.AddLog("info", fmt.Sprintf("IVR input: %s", digits), n.ID, n.Name)
+			return []string{next}, nil
+		}
+		return n.fallback(ctx, digits)
+	}
+
+	if ctx.Call == nil {
+		return nil, fmt.Errorf("ivr node %s requires an active call", n.Name)
+	}
+
+	maxDigits := n.MaxDigits
+	if maxDigits <= 0 {
+		maxDigits = 1
+	}
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = DefaultIVRTimeout
+	}
+
+	var digits string
+	for attempt := 0; attempt <= n.Retries; attempt++ {
+		if prompt := n.RenderPrompt(ctx); prompt != "" {
+			if err := ctx.Call.Say(prompt); err != nil {
+				return nil, fmt.Errorf("ivr node %s prompt failed: %w", n.Name, err)
+			}
+		}
+		var err error
+		digits, err = ctx.Call.ReadDigits(maxDigits, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("ivr node %s read digits failed: %w", n.Name, err)
+		}
+		if next, ok := n.Options[digits]; ok {
+			ctx.NodeData[IVRDigitsKey(n.ID)] = digits
+			ctx.AddLog("info", fmt.Sprintf("IVR input: %s", digits), n.ID, n.Name)
+			return []string{next}, nil
+		}
+		ctx.AddLog("warning", fmt.Sprintf("IVR invalid input %q (attempt %d)", digits, attempt+1), n.ID, n.Name)
+		if attempt < n.Retries && n.InvalidPrompt != "" {
+			if err := ctx.Call.Say(n.InvalidPrompt); err != nil {
+				return nil, fmt.Errorf("ivr node %s prompt failed: %w", n.Name, err)
+			}
+		}
+	}
+	ctx.NodeData[IVRDigitsKey(n.ID)] = digits
+	return n.fallback(ctx, digits)
+}
+
+func (n *IVRNode) fallback(ctx *WorkflowContext, digits string) ([]string, error) {
+	if n.DefaultNextNodeID != "" {
+		ctx.AddLog("info", "IVR no valid input, taking default route", n.ID, n.Name)
+		return []string{n.DefaultNextNodeID}, nil
+	}
+	return nil, fmt.Errorf("ivr node %s received no valid input (got %q)", n.Name, digits)
+}
@@ -0,0 +1,453 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Voice node defaults
+const (
+	DefaultRingTimeout   = 30 * time.Second
+	DefaultListenTimeout = 15 * time.Second
+	DefaultListenSilence = 1200 * time.Millisecond
+	DefaultDTMFMaxDigits = 1
+	DefaultDTMFMinDigits = 1
+)
+
+// LastTranscriptKey is the context key holding the transcript of the latest listen node
+const LastTranscriptKey = "_last_transcript"
+
+// CallIDKey is the context key holding the SIP call id placed by a call node
+func CallIDKey(nodeID string) string {
+	return nodeID + "_call_id"
+}
+
+// AnsweredKey is the context key telling whether the call placed by a call node was answered
+func AnsweredKey(nodeID string) string {
+	return nodeID + "_answered"
+}
+
+// TranscriptKey is the context key holding what the caller said during a listen node
+func TranscriptKey(nodeID string) string {
+	return nodeID + "_transcript"
+}
+
+// IntentKey is the context key holding the intent chosen by an intent node
+func IntentKey(nodeID string) string {
+	return nodeID + "_intent"
+}
+
+// DialRequest describes the outbound call placed by a call node
+type DialRequest struct {
+	Target       string        // SIP URI or number to call
+	UserID       uint          // workflow owner placing the call
+	AssistantID  uint          // assistant whose ASR/TTS configuration the call uses
+	CredentialID uint          // owner's API credential billed for the call
+	RingTimeout  time.Duration // give up when the call is not answered in time
+}
+
+// PlacedCall is an answered outbound call
+type PlacedCall interface {
+	CallSession
+	CallID() string
+	Hangup() error
+}
+
+// CallDialer places an outbound call and returns once it has been answered
+type CallDialer func(req DialRequest) (PlacedCall, error)
+
+var (
+	callDialerMu sync.RWMutex
+	callDialer   CallDialer
+)
+
+// SetCallDialer installs the backend used by call nodes
+func SetCallDialer(fn CallDialer) {
+	callDialerMu.Lock()
+	defer callDialerMu.Unlock()
+	callDialer = fn
+}
+
+func getCallDialer() CallDialer {
+	callDialerMu.RLock()
+	defer callDialerMu.RUnlock()
+	return callDialer
+}
+
+// CallNode places an outbound call; the voice nodes after it talk to the callee.
+// The call is hung up when the workflow stops.
+type CallNode struct {
+	Node
+	Target             string // number or SIP URI, supports {{var}}
+	AssistantID        uint
+	CredentialID       uint
+	RingTimeout        time.Duration
+	AnsweredNextNodeID string
+	NoAnswerNextNodeID string // taken when the call fails or is not answered
+}
+
+func (n *CallNode) Base() *Node {
+	return &n.Node
+}
+
+func (n *CallNode) Run(ctx *WorkflowContext) ([]string, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("call node %s requires a workflow context", n.Name)
+	}
+	dial := getCallDialer()
+	if dial == nil {
+		return nil, fmt.Errorf("call node %s: outbound calling is not configured", n.Name)
+	}
+	target := strings.TrimSpace(resolveTemplate(n.Target, nil, ctx))
+	if target == "" {
+		return nil, fmt.Errorf("call node %s: target resolved to empty string", n.Name)
+	}
+	if ctx.UserID == 0 {
+		return nil, fmt.Errorf("call node %s: workflow has no owner to place the call", n.Name)
+	}
+	ringTimeout := n.RingTimeout
+	if ringTimeout <= 0 {
+		ringTimeout = DefaultRingTimeout
+	}
+
+	ctx.AddLog("info", fmt.Sprintf("Calling %s", target), n.ID, n.Name)
+	call, err := dial(DialRequest{
+		Target:       target,
+		UserID:       ctx.UserID,
+		AssistantID:  n.AssistantID,
+		CredentialID: n.CredentialID,
+		RingTimeout:  ringTimeout,
+	})
+	if err != nil {
+		ctx.SetData(AnsweredKey(n.ID), false)
+		n.PersistOutputs(ctx, map[string]interface{}{"answered": false, "error": err.Error()})
+		ctx.AddLog("warning", fmt.Sprintf("Call to %s not answered: %v", target, err), n.ID, n.Name)
+		if n.NoAnswerNextNodeID != "" {
+			return []string{n.NoAnswerNextNodeID}, nil
+		}
+		return nil, fmt.Errorf("call node %s: %w", n.Name, err)
+	}
+
+	ctx.trackPlacedCall(call)
+	ctx.Call = call
+	ctx.SetData(CallIDKey(n.ID), call.CallID())
+	ctx.SetData(AnsweredKey(n.ID), true)
+	n.PersistOutputs(ctx, map[string]interface{}{"answered": true, "callId": call.CallID()})
+	ctx.AddLog("success", fmt.Sprintf("Call %s answered", call.CallID()), n.ID, n.Name)
+
+	if n.AnsweredNextNodeID != "" {
+		return []string{n.AnsweredNextNodeID}, nil
+	}
+	return excludeNode(n.NextNodes, n.NoAnswerNextNodeID), nil
+}
+
+// SayNode speaks text on the call
+type SayNode struct {
+	Node
+	Text string // supports {{var}}
+}
+
+func (n *SayNode) Base() *Node {
+	return &n.Node
+}
+
+func (n *SayNode) Run(ctx *WorkflowContext) ([]string, error) {
+	if ctx == nil || ctx.Call == nil {
+		return nil, fmt.Errorf("say node %s requires an active call", n.Name)
+	}
+	text := resolveTemplate(n.Text, nil, ctx)
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("say node %s: text resolved to empty string", n.Name)
+	}
+	if err := ctx.Call.Say(text); err != nil {
+		return nil, fmt.Errorf("say node %s failed: %w", n.Name, err)
+	}
+	ctx.AddLog("info", fmt.Sprintf("Said: %s", text), n.ID, n.Name)
+	return n.NextNodes, nil
+}
+
+// CollectDTMFNode optionally plays a prompt and stores the digits the caller
+// presses, e.g. an account number. Unlike the IVR node it does not route.
+type CollectDTMFNode struct {
+	Node
+	Prompt        string // supports {{var}}
+	InvalidPrompt string // played before retrying when fewer than MinDigits were pressed
+	MaxDigits     int
+	MinDigits     int
+	Timeout       time.Duration // wait for each key
+	Retries       int
+}
+
+func (n *CollectDTMFNode) Base() *Node {
+	return &n.Node
+}
+
+func (n *CollectDTMFNode) Run(ctx *WorkflowContext) ([]string, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("collect_dtmf node %s requires a workflow context", n.Name)
+	}
+	// Digits supplied up front (e.g. replayed executions) skip the call entirely
+	if raw, ok := ctx.NodeData[IVRDigitsKey(n.ID)]; ok {
+		digits, _ := raw.(string)
+		n.PersistOutputs(ctx, map[string]interface{}{"digits": digits})
+		return n.NextNodes, nil
+	}
+	if ctx.Call == nil {
+		return nil, fmt.Errorf("collect_dtmf node %s requires an active call", n.Name)
+	}
+
+	maxDigits := n.MaxDigits
+	if maxDigits <= 0 {
+		maxDigits = DefaultDTMFMaxDigits
+	}
+	minDigits := n.MinDigits
+	if minDigits <= 0 {
+		minDigits = DefaultDTMFMinDigits
+	}
+	if minDigits > maxDigits {
+		minDigits = maxDigits
+	}
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = DefaultIVRTimeout
+	}
+
+	var digits string
+	for attempt := 0; attempt <= n.Retries; attempt++ {
+		if prompt := resolveTemplate(n.Prompt, nil, ctx); prompt != "" {
+			if err := ctx.Call.Say(prompt); err != nil {
+				return nil, fmt.Errorf("collect_dtmf node %s prompt failed: %w", n.Name, err)
+			}
+		}
+		var err error
+		digits, err = ctx.Call.ReadDigits(maxDigits, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("collect_dtmf node %s read digits failed: %w", n.Name, err)
+		}
+		if len(digits) >= minDigits {
+			break
+		}
+		ctx.AddLog("warning", fmt.Sprintf("Collected %d of %d digits (attempt %d)", len(digits), minDigits, attempt+1), n.ID, n.Name)
+		if attempt < n.Retries && n.InvalidPrompt != "" {
+			if err := ctx.Call.Say(n.InvalidPrompt); err != nil {
+				return nil, fmt.Errorf("collect_dtmf node %s prompt failed: %w", n.Name, err)
+			}
+		}
+	}
+
+	// Too few digits is not an error, a gateway after the node can check them
+	ctx.SetData(IVRDigitsKey(n.ID), digits)
+	n.PersistOutputs(ctx, map[string]interface{}{"digits": digits})
+	ctx.AddLog("info", fmt.Sprintf("Collected digits: %q", digits), n.ID, n.Name)
+	return n.NextNodes, nil
+}
+
+// ListenNode optionally plays a prompt, then transcribes the caller with ASR
+// until they fall silent. The assistant does not reply while a workflow listens.
+type ListenNode struct {
+	Node
+	Prompt  string        // supports {{var}}
+	Timeout time.Duration // overall listening limit
+	Silence time.Duration // pause that ends the caller's answer
+}
+
+func (n *ListenNode) Base() *Node {
+	return &n.Node
+}
+
+func (n *ListenNode) Run(ctx *WorkflowContext) ([]string, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("listen node %s requires a workflow context", n.Name)
+	}
+	if raw, ok := ctx.NodeData[TranscriptKey(n.ID)]; ok {
+		transcript, _ := raw.(string)
+		ctx.SetData(LastTranscriptKey, transcript)
+		n.PersistOutputs(ctx, map[string]interface{}{"transcript": transcript})
+		return n.NextNodes, nil
+	}
+	if ctx.Call == nil {
+		return nil, fmt.Errorf("listen node %s requires an active call", n.Name)
+	}
+
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = DefaultListenTimeout
+	}
+	silence := n.Silence
+	if silence <= 0 {
+		silence = DefaultListenSilence
+	}
+
+	if prompt := resolveTemplate(n.Prompt, nil, ctx); prompt != "" {
+		if err := ctx.Call.Say(prompt); err != nil {
+			return nil, fmt.Errorf("listen node %s prompt failed: %w", n.Name, err)
+		}
+	}
+	transcript, err := ctx.Call.Listen(timeout, silence)
+	if err != nil {
+		return nil, fmt.Errorf("listen node %s failed: %w", n.Name, err)
+	}
+	transcript = strings.TrimSpace(transcript)
+
+	ctx.SetData(TranscriptKey(n.ID), transcript)
+	ctx.SetData(LastTranscriptKey, transcript)
+	n.PersistOutputs(ctx, map[string]interface{}{"transcript": transcript})
+	if transcript == "" {
+		ctx.AddLog("warning", "Caller said nothing", n.ID, n.Name)
+	} else {
+		ctx.AddLog("info", fmt.Sprintf("Caller said: %s", transcript), n.ID, n.Name)
+	}
+	return n.NextNodes, nil
+}
+
+// IntentNode classifies a transcript into one of the configured intents and
+// routes on it. Keywords are matched first, the longest matching keyword
+// wins (so "not interested" beats "interested"); when none matches and UseLLM
+// is set the LLM backend picks the intent.
+type IntentNode struct {
+	Node
+	Input             string              // transcript template, defaults to the latest listen node's transcript
+	Intents           map[string][]string // intent -> keywords
+	UseLLM            bool
+	Provider          string
+	APIKey            string
+	BaseURL           string
+	Model             string
+	Routes            map[string]string // intent -> next node id
+	DefaultNextNodeID string            // taken when no intent matched or the intent has no route
+}
+
+func (n *IntentNode) Base() *Node {
+	return &n.Node
+}
+
+func (n *IntentNode) Run(ctx *WorkflowContext) ([]string, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("intent node %s requires a workflow context", n.Name)
+	}
+	var transcript string
+	if n.Input != "" {
+		transcript = resolveTemplate(n.Input, nil, ctx)
+	} else if raw, ok := ctx.NodeData[LastTranscriptKey]; ok {
+		transcript, _ = raw.(string)
+	}
+	transcript = strings.TrimSpace(transcript)
+
+	intent := n.matchKeywords(transcript)
+	if intent == "" && n.UseLLM && transcript != "" {
+		var err error
+		if intent, err = n.classifyWithLLM(transcript); err != nil {
+			ctx.AddLog("warning", fmt.Sprintf("LLM intent classification failed: %v", err), n.ID, n.Name)
+		}
+	}
+
+	ctx.SetData(IntentKey(n.ID), intent)
+	n.PersistOutputs(ctx, map[string]interface{}{"intent": intent, "transcript": transcript})
+	if intent == "" {
+		ctx.AddLog("info", fmt.Sprintf("No intent matched %q", transcript), n.ID, n.Name)
+	} else {
+		ctx.AddLog("info", fmt.Sprintf("Intent: %s", intent), n.ID, n.Name)
+	}
+
+	if next, ok := n.Routes[intent]; ok && intent != "" {
+		return []string{next}, nil
+	}
+	if n.DefaultNextNodeID != "" {
+		return []string{n.DefaultNextNodeID}, nil
+	}
+	if len(n.Routes) == 0 {
+		// Without branch edges the intent is only recorded for later nodes
+		return n.NextNodes, nil
+	}
+	return nil, fmt.Errorf("intent node %s has no route for intent %q", n.Name, intent)
+}
+
+// intentNames returns the configured intents in a stable order
+func (n *IntentNode) intentNames() []string {
+	names := make(map[string]bool, len(n.Intents)+len(n.Routes))
+	for name := range n.Intents {
+		names[name] = true
+	}
+	for name := range n.Routes {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func (n *IntentNode) matchKeywords(transcript string) string {
+	text := strings.ToLower(transcript)
+	best, bestLen := "", 0
+	for _, intent := range n.intentNames() {
+		for _, keyword := range n.Intents[intent] {
+			keyword = strings.ToLower(strings.TrimSpace(keyword))
+			if keyword != "" && len(keyword) > bestLen && strings.Contains(text, keyword) {
+				best, bestLen = intent, len(keyword)
+			}
+		}
+	}
+	return best
+}
+
+func (n *IntentNode) classifyWithLLM(transcript string) (string, error) {
+	completer := getLLMCompleter()
+	if completer == nil {
+		return "", fmt.Errorf("LLM backend is not configured")
+	}
+	names := n.intentNames()
+	if len(names) == 0 {
+		return "", fmt.Errorf("no intents configured")
+	}
+	var prompt strings.Builder
+	prompt.WriteString("Classify what the caller said into exactly one of these intents:\n")
+	for _, name := range names {
+		prompt.WriteString("- " + name)
+		if keywords := n.Intents[name]; len(keywords) > 0 {
+			prompt.WriteString(" (e.g. " + strings.Join(keywords, ", ") + ")")
+		}
+		prompt.WriteString("\n")
+	}
+	prompt.WriteString("Reply with the intent name only, or \"none\" if no intent applies.\n\nCaller: ")
+	prompt.WriteString(transcript)
+
+	temperature := float32(0)
+	reply, err := completer(LLMRequest{
+		Provider:    n.Provider,
+		APIKey:      n.APIKey,
+		BaseURL:     n.BaseURL,
+		Model:       n.Model,
+		Prompt:      prompt.String(),
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return "", err
+	}
+	reply = strings.ToLower(strings.Trim(strings.TrimSpace(reply), "\"'`.。"))
+	for _, name := range names {
+		if strings.ToLower(name) == reply {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// excludeNode returns nodes without id
+func excludeNode(nodes []string, id string) []string {
+	if id == "" {
+		return nodes
+	}
+	result := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if node != id {
+			result = append(result, node)
+		}
+	}
+	return result
+}
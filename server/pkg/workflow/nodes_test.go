@@ -1,7 +1,9 @@
 package workflow
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -125,3 +127,519 @@ func TestWorkflowMissingInputFails(t *testing.T) {
 	require.Contains(t, err.Error(), "missing input")
 	require.Equal(t, NodeStatusFailed, ctx.GetNodeStatus("task"))
 }
+
+func TestWorkflowStepRecords(t *testing.T) {
+	ctx := NewWorkflowContext("wf-steps")
+	ctx.Parameters["request_message"] = "ping"
+
+	start := &StartNode{
+		Node: Node{ID: "start", Name: "Start", Type: NodeTypeStart, NextNodes: []string{"task"}},
+	}
+	task := &TaskNode{
+		Node: Node{
+			ID:           "task",
+			Name:         "Task",
+			Type:         NodeTypeTask,
+			NextNodes:    []string{"check"},
+			InputParams:  map[string]string{"message": "request_message"},
+			OutputParams: map[string]string{"echo": "task.echo"},
+		},
+		Handler: func(ctx *WorkflowContext, inputs map[string]interface{}) (map[string]interface{}, error) {
+			return map[string]interface{}{"echo": inputs["message"]}, nil
+		},
+	}
+	check := &TaskNode{
+		Node: Node{ID: "check", Name: "Check", Type: NodeTypeTask, NextNodes: []string{"end"}},
+		Handler: func(ctx *WorkflowContext, inputs map[string]interface{}) (map[string]interface{}, error) {
+			return nil, errors.New("boom")
+		},
+	}
+	end := &EndNode{Node: Node{ID: "end", Name: "End", Type: NodeTypeEnd}}
+
+	wf := NewWorkflow("wf-steps")
+	wf.Context = ctx
+	wf.SetStartNode("start")
+	wf.SetEndNode("end")
+	for _, node := range []ExecutableNode{start, task, check, end} {
+		wf.RegisterNode(node)
+	}
+
+	require.Error(t, wf.Execute())
+	require.Len(t, ctx.Steps, 3)
+
+	step := ctx.Steps[1]
+	require.Equal(t, "task", step.NodeID)
+	require.Equal(t, NodeTypeTask, step.NodeType)
+	require.Equal(t, NodeStatusCompleted, step.Status)
+	require.Equal(t, []string{"check"}, step.NextNodes)
+	require.Equal(t, map[string]interface{}{"message": "ping"}, step.Inputs)
+	require.Equal(t, "ping", step.Outputs["task.echo"])
+
+	failed := ctx.Steps[2]
+	require.Equal(t, "check", failed.NodeID)
+	require.Equal(t, NodeStatusFailed, failed.Status)
+	require.Contains(t, failed.Error, "boom")
+	require.Nil(t, failed.Inputs)
+	require.Nil(t, failed.Outputs)
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	ctx := NewWorkflowContext("wf-expr")
+	ctx.Parameters["age"] = 20
+	ctx.Parameters["status"] = "ok"
+	ctx.NodeData["http1"] = map[string]interface{}{"statusCode": 200}
+
+	cases := map[string]bool{
+		"parameters.age >= 18": true,
+		"parameters.age < 18":  false,
+		`parameters.status == "ok" && http1.statusCode == 200`: true,
+		`parameters.status != 'ok' || parameters.age > 30`:     false,
+		"!(parameters.age > 30)":                               true,
+		"{{parameters.age}} == 20":                             true,
+		"parameters.missing":                                   false, // unresolved operand is null
+		"parameters.missing == null":                           true,
+		`{{parameters.status}} == "ok"`:                        true,
+		"{{parameters.missing}}":                               false,
+		"false || parameters.age":                              true,
+	}
+	for expr, want := range cases {
+		got, err := EvaluateExpression(ctx, expr)
+		require.NoError(t, err, expr)
+		require.Equal(t, want, got, expr)
+	}
+
+	_, err := EvaluateExpression(ctx, "(parameters.age > 1")
+	require.Error(t, err)
+}
+
+func TestLLMAndTTSTaskExecutors(t *testing.T) {
+	SetLLMCompleter(func(req LLMRequest) (string, error) {
+		require.Equal(t, "gpt-test", req.Model)
+		return "echo: " + req.Prompt, nil
+	})
+	SetTTSSynthesizer(func(req TTSRequest) (*TTSResult, error) {
+		require.Equal(t, "mock", req.Config["provider"])
+		return &TTSResult{AudioURL: "/media/" + req.Text + ".wav", Format: "wav"}, nil
+	})
+	defer SetLLMCompleter(nil)
+	defer SetTTSSynthesizer(nil)
+
+	ctx := NewWorkflowContext("wf-ai")
+	ctx.Parameters["name"] = "alice"
+
+	llmNode := &TaskNode{
+		Node:     Node{ID: "llm1", Name: "LLM", Type: NodeTypeLLM},
+		TaskType: "llm",
+		Config:   map[string]interface{}{"prompt": "hi {{parameters.name}}", "model": "gpt-test"},
+	}
+	_, err := llmNode.Run(ctx)
+	require.NoError(t, err)
+	val, ok := ctx.ResolveValue("llm1.text")
+	require.True(t, ok)
+	require.Equal(t, "echo: hi alice", val)
+
+	ttsNode := &TaskNode{
+		Node:     Node{ID: "tts1", Name: "TTS", Type: NodeTypeTTS},
+		TaskType: "tts",
+		Config:   map[string]interface{}{"text": "{{llm1.text}}", "provider": "mock"},
+	}
+	_, err = ttsNode.Run(ctx)
+	require.NoError(t, err)
+	val, ok = ctx.ResolveValue("tts1.audioUrl")
+	require.True(t, ok)
+	require.Equal(t, "/media/echo: hi alice.wav", val)
+}
+
+func TestLLMTaskExecutorWithoutBackend(t *testing.T) {
+	SetLLMCompleter(nil)
+	_, err := (&LLMTaskExecutor{}).Execute(nil, map[string]interface{}{"prompt": "x"}, nil)
+	require.Error(t, err)
+}
+
+func buildSingleTaskWorkflow(task *TaskNode) *Workflow {
+	start := &StartNode{Node: Node{ID: "start", Name: "Start", Type: NodeTypeStart, NextNodes: []string{task.ID}}}
+	task.NextNodes = []string{"end"}
+	end := &EndNode{Node: Node{ID: "end", Name: "End", Type: NodeTypeEnd}}
+
+	wf := NewWorkflow("wf-retry")
+	wf.Context = NewWorkflowContext("wf-retry")
+	wf.SetStartNode("start")
+	wf.SetEndNode("end")
+	for _, node := range []ExecutableNode{start, task, end} {
+		wf.RegisterNode(node)
+	}
+	return wf
+}
+
+func TestWorkflowNodeRetry(t *testing.T) {
+	calls := 0
+	task := &TaskNode{
+		Node: Node{ID: "flaky", Name: "Flaky", Type: NodeTypeTask,
+			Retry: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}},
+		Handler: func(ctx *WorkflowContext, inputs map[string]interface{}) (map[string]interface{}, error) {
+			calls++
+			if calls < 3 {
+				return nil, errors.New("temporary failure")
+			}
+			return map[string]interface{}{"ok": true}, nil
+		},
+	}
+	wf := buildSingleTaskWorkflow(task)
+	require.NoError(t, wf.Execute())
+	require.Equal(t, 3, calls)
+}
+
+func TestWorkflowNodeRetryExhausted(t *testing.T) {
+	calls := 0
+	task := &TaskNode{
+		Node: Node{ID: "broken", Name: "Broken", Type: NodeTypeTask},
+		Handler: func(ctx *WorkflowContext, inputs map[string]interface{}) (map[string]interface{}, error) {
+			calls++
+			return nil, errors.New("permanent failure")
+		},
+	}
+	wf := buildSingleTaskWorkflow(task)
+	wf.DefaultRetry = &RetryPolicy{MaxAttempts: 2}
+
+	err := wf.Execute()
+	require.Error(t, err)
+	require.Equal(t, 2, calls)
+	require.Equal(t, "broken", wf.FailedNodeID)
+	var nodeErr *NodeExecutionError
+	require.True(t, errors.As(err, &nodeErr))
+	require.Equal(t, 2, nodeErr.Attempts)
+	require.True(t, nodeErr.DeadLetter())
+
+	// 没有重试策略的节点失败一次是普通失败，不进入死信
+	wf = buildSingleTaskWorkflow(task)
+	err = wf.Execute()
+	require.True(t, errors.As(err, &nodeErr))
+	require.False(t, nodeErr.DeadLetter())
+}
+
+func TestWorkflowNodeTimeout(t *testing.T) {
+	task := &TaskNode{
+		Node: Node{ID: "slow", Name: "Slow", Type: NodeTypeTask, Timeout: 10 * time.Millisecond,
+			Retry: &RetryPolicy{MaxAttempts: 3}},
+		Handler: func(ctx *WorkflowContext, inputs map[string]interface{}) (map[string]interface{}, error) {
+			time.Sleep(200 * time.Millisecond)
+			return nil, nil
+		},
+	}
+	wf := buildSingleTaskWorkflow(task)
+	err := wf.Execute()
+	require.ErrorIs(t, err, ErrNodeTimeout)
+	var nodeErr *NodeExecutionError
+	require.True(t, errors.As(err, &nodeErr))
+	require.True(t, nodeErr.DeadLetter())
+
+	wf = buildSingleTaskWorkflow(&TaskNode{
+		Node:    Node{ID: "slow", Name: "Slow", Type: NodeTypeTask},
+		Handler: task.Handler,
+	})
+	wf.Timeout = 10 * time.Millisecond
+	require.ErrorIs(t, wf.Execute(), ErrWorkflowTimeout)
+}
+
+func TestWorkflowNodeTimeoutIsolatesContext(t *testing.T) {
+	finished := make(chan struct{})
+	task := &TaskNode{
+		Node: Node{ID: "slow", Name: "Slow", Type: NodeTypeTask, Timeout: 10 * time.Millisecond},
+		Handler: func(ctx *WorkflowContext, inputs map[string]interface{}) (map[string]interface{}, error) {
+			ctx.SetData("early", true)
+			time.Sleep(50 * time.Millisecond)
+			ctx.SetData("late", true)
+			ctx.AddLog("info", "still running", "slow", "Slow")
+			close(finished)
+			return nil, nil
+		},
+	}
+	wf := buildSingleTaskWorkflow(task)
+	require.ErrorIs(t, wf.Execute(), ErrNodeTimeout)
+	<-finished
+
+	// 超时后仍在运行的节点只修改自己的副本
+	_, early := wf.Context.NodeData["early"]
+	_, late := wf.Context.NodeData["late"]
+	require.False(t, early)
+	require.False(t, late)
+	for _, log := range wf.Context.Logs {
+		require.NotEqual(t, "still running", log.Message)
+	}
+
+	// 按时完成的节点写入的数据保留
+	wf = buildSingleTaskWorkflow(&TaskNode{
+		Node: Node{ID: "fast", Name: "Fast", Type: NodeTypeTask, Timeout: time.Second},
+		Handler: func(ctx *WorkflowContext, inputs map[string]interface{}) (map[string]interface{}, error) {
+			ctx.SetData("done", true)
+			return nil, nil
+		},
+	})
+	require.NoError(t, wf.Execute())
+	require.Equal(t, true, wf.Context.NodeData["done"])
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	require.Equal(t, time.Duration(0), p.Backoff(1))
+	require.Equal(t, 100*time.Millisecond, p.Backoff(2))
+	require.Equal(t, 200*time.Millisecond, p.Backoff(3))
+	require.Equal(t, 300*time.Millisecond, p.Backoff(4))
+	require.Equal(t, 1, (*RetryPolicy)(nil).Attempts())
+}
+
+func TestApprovalNodeSuspendAndResume(t *testing.T) {
+	newWorkflow := func() *Workflow {
+		start := &StartNode{Node: Node{ID: "start", Name: "Start", Type: NodeTypeStart, NextNodes: []string{"approval"}}}
+		approval := &ApprovalNode{
+			Node:               Node{ID: "approval", Name: "Approval", Type: NodeTypeApproval, NextNodes: []string{"end", "rejected"}},
+			Title:              "Need approval",
+			Message:            "amount {{parameters.amount}}",
+			ApprovedNextNodeID: "end",
+			RejectedNextNodeID: "rejected",
+		}
+		rejected := &EndNode{Node: Node{ID: "rejected", Name: "Rejected", Type: NodeTypeEnd}}
+		end := &EndNode{Node: Node{ID: "end", Name: "End", Type: NodeTypeEnd}}
+
+		wf := NewWorkflow("wf-approval")
+		wf.Context = NewWorkflowContext("wf-approval")
+		wf.Context.Parameters["amount"] = 42
+		wf.SetStartNode("start")
+		wf.SetEndNode("end")
+		for _, node := range []ExecutableNode{start, approval, rejected, end} {
+			wf.RegisterNode(node)
+		}
+		return wf
+	}
+
+	wf := newWorkflow()
+	err := wf.Execute()
+	var suspended *SuspendedError
+	require.True(t, errors.As(err, &suspended))
+	require.Equal(t, "approval", suspended.NodeID)
+	require.Equal(t, NodeStatusWaiting, wf.Context.GetNodeStatus("approval"))
+	require.Equal(t, "amount 42", wf.Nodes["approval"].(*ApprovalNode).RenderMessage(wf.Context))
+
+	// approved resumes into the approve branch
+	wf = newWorkflow()
+	wf.Context.NodeData[ApprovalDecisionKey("approval")] = ApprovalApproved
+	require.NoError(t, wf.ResumeFrom("approval"))
+	require.Equal(t, NodeStatusCompleted, wf.Context.GetNodeStatus("end"))
+
+	// rejected follows the reject branch, which never reaches the end node
+	wf = newWorkflow()
+	wf.Context.NodeData[ApprovalDecisionKey("approval")] = ApprovalRejected
+	require.Error(t, wf.ResumeFrom("approval"))
+	require.Equal(t, NodeStatusCompleted, wf.Context.GetNodeStatus("rejected"))
+}
+
+type fakeCall struct {
+	inputs  []string
+	speech  []string
+	said    []string
+	hungUp  bool
+	callID  string
+	listens int
+}
+
+func (c *fakeCall) Say(text string) error {
+	c.said = append(c.said, text)
+	return nil
+}
+
+func (c *fakeCall) ReadDigits(max int, timeout time.Duration) (string, error) {
+	if len(c.inputs) == 0 {
+		return "", nil
+	}
+	digits := c.inputs[0]
+	c.inputs = c.inputs[1:]
+	return digits, nil
+}
+
+func (c *fakeCall) Listen(timeout, silence time.Duration) (string, error) {
+	c.listens++
+	if len(c.speech) == 0 {
+		return "", nil
+	}
+	text := c.speech[0]
+	c.speech = c.speech[1:]
+	return text, nil
+}
+
+func (c *fakeCall) CallID() string {
+	return c.callID
+}
+
+func (c *fakeCall) Hangup() error {
+	c.hungUp = true
+	return nil
+}
+
+func TestIVRNodeRouting(t *testing.T) {
+	newNode := func() *IVRNode {
+		return &IVRNode{
+			Node:              Node{ID: "menu", Name: "Menu", Type: NodeTypeIVR},
+			Prompt:            "Hi {{parameters.caller}}, press 1 for sales",
+			InvalidPrompt:     "Sorry, try again",
+			Options:           map[string]string{"1": "sales", "2": "support"},
+			Retries:           1,
+			DefaultNextNodeID: "operator",
+		}
+	}
+
+	// invalid input is retried once, then the valid key routes
+	call := &fakeCall{inputs: []string{"9", "2"}}
+	ctx := NewWorkflowContext("wf-ivr")
+	ctx.Parameters["caller"] = "alice"
+	ctx.Call = call
+	next, err := newNode().Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"support"}, next)
+	require.Equal(t, "2", ctx.NodeData[IVRDigitsKey("menu")])
+	require.Equal(t, []string{"Hi alice, press 1 for sales", "Sorry, try again", "Hi alice, press 1 for sales"}, call.said)
+
+	// retries exhausted without input takes the default route
+	ctx = NewWorkflowContext("wf-ivr")
+	ctx.Call = &fakeCall{}
+	next, err = newNode().Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"operator"}, next)
+
+	// preset digits route without a call
+	ctx = NewWorkflowContext("wf-ivr")
+	ctx.NodeData[IVRDigitsKey("menu")] = "1"
+	next, err = newNode().Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"sales"}, next)
+
+	// no call and no digits is an error
+	_, err = newNode().Run(NewWorkflowContext("wf-ivr"))
+	require.Error(t, err)
+}
+
+func TestVoiceNodesOutboundFlow(t *testing.T) {
+	call := &fakeCall{callID: "c1", inputs: []string{"12", "1234"}, speech: []string{"I am not interested, thanks"}}
+	var dialed DialRequest
+	SetCallDialer(func(req DialRequest) (PlacedCall, error) {
+		dialed = req
+		return call, nil
+	})
+	defer SetCallDialer(nil)
+
+	wf := NewWorkflow("wf-voice")
+	wf.Context = NewWorkflowContext("wf-voice")
+	wf.Context.UserID = 7
+	wf.Context.Parameters["phone"] = "1001"
+	nodes := []ExecutableNode{
+		&StartNode{Node: Node{ID: "start", Name: "Start", Type: NodeTypeStart, NextNodes: []string{"dial"}}},
+		&CallNode{
+			Node:               Node{ID: "dial", Name: "Dial", Type: NodeTypeCall, NextNodes: []string{"greet", "missed"}},
+			Target:             "sip:{{parameters.phone}}@example.com",
+			AssistantID:        3,
+			CredentialID:       5,
+			NoAnswerNextNodeID: "missed",
+		},
+		&SayNode{Node: Node{ID: "greet", Name: "Greet", Type: NodeTypeSay, NextNodes: []string{"pin"}}, Text: "Hello from LingEcho"},
+		&CollectDTMFNode{
+			Node:          Node{ID: "pin", Name: "PIN", Type: NodeTypeCollectDTMF, NextNodes: []string{"ask"}, OutputParams: map[string]string{"digits": "pin_code"}},
+			Prompt:        "Enter your PIN",
+			InvalidPrompt: "PIN too short",
+			MaxDigits:     4,
+			MinDigits:     4,
+			Retries:       1,
+		},
+		&ListenNode{Node: Node{ID: "ask", Name: "Ask", Type: NodeTypeListen, NextNodes: []string{"route"}}, Prompt: "Interested in our offer?"},
+		&IntentNode{
+			Node:              Node{ID: "route", Name: "Route", Type: NodeTypeIntent},
+			Intents:           map[string][]string{"yes": {"interested", "sure"}, "no": {"not interested"}},
+			Routes:            map[string]string{"yes": "end", "no": "bye"},
+			DefaultNextNodeID: "end",
+		},
+		&SayNode{Node: Node{ID: "bye", Name: "Bye", Type: NodeTypeSay, NextNodes: []string{"end"}}, Text: "Goodbye"},
+		&SayNode{Node: Node{ID: "missed", Name: "Missed", Type: NodeTypeSay, NextNodes: []string{"end"}}, Text: "unreachable"},
+		&EndNode{Node: Node{ID: "end", Name: "End", Type: NodeTypeEnd}},
+	}
+	for _, node := range nodes {
+		wf.RegisterNode(node)
+	}
+	wf.SetStartNode("start")
+	wf.SetEndNode("end")
+
+	require.NoError(t, wf.Execute())
+	require.Equal(t, DialRequest{Target: "sip:1001@example.com", UserID: 7, AssistantID: 3, CredentialID: 5, RingTimeout: DefaultRingTimeout}, dialed)
+	require.Equal(t, []string{"Hello from LingEcho", "Enter your PIN", "PIN too short", "Enter your PIN", "Interested in our offer?", "Goodbye"}, call.said)
+	require.Equal(t, "1234", wf.Context.NodeData["pin_code"])
+	require.Equal(t, "no", wf.Context.NodeData[IntentKey("route")])
+	require.Equal(t, NodeStatus(""), wf.Context.GetNodeStatus("missed"))
+	require.Equal(t, "c1", wf.Context.NodeData[CallIDKey("dial")])
+	require.True(t, call.hungUp)
+	require.Nil(t, wf.Context.Call)
+}
+
+func TestCallNodeNoAnswer(t *testing.T) {
+	SetCallDialer(func(req DialRequest) (PlacedCall, error) {
+		return nil, errors.New("busy")
+	})
+	defer SetCallDialer(nil)
+
+	ctx := NewWorkflowContext("wf-call")
+	ctx.UserID = 1
+	node := &CallNode{Node: Node{ID: "dial", Name: "Dial", NextNodes: []string{"talk", "retry"}}, Target: "1001", NoAnswerNextNodeID: "retry"}
+	next, err := node.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"retry"}, next)
+	require.Equal(t, false, ctx.NodeData[AnsweredKey("dial")])
+
+	node.NoAnswerNextNodeID = ""
+	_, err = node.Run(ctx)
+	require.ErrorContains(t, err, "busy")
+
+	// workflows without an owner cannot place calls
+	_, err = node.Run(NewWorkflowContext("wf-call"))
+	require.Error(t, err)
+}
+
+func TestIntentNodeClassification(t *testing.T) {
+	newNode := func() *IntentNode {
+		return &IntentNode{
+			Node:    Node{ID: "intent", Name: "Intent", NextNodes: []string{"sales", "support", "other"}},
+			Intents: map[string][]string{"sales": {"buy", "price"}, "support": {"broken", "help"}},
+			Routes:  map[string]string{"sales": "sales", "support": "support"},
+			UseLLM:  true,
+		}
+	}
+
+	// keywords match the latest transcript case-insensitively
+	ctx := NewWorkflowContext("wf-intent")
+	ctx.SetData(LastTranscriptKey, "What is the PRICE?")
+	next, err := newNode().Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"sales"}, next)
+
+	// the LLM decides when no keyword matches
+	SetLLMCompleter(func(req LLMRequest) (string, error) {
+		require.Contains(t, req.Prompt, "- support (e.g. broken, help)")
+		return "Support.", nil
+	})
+	defer SetLLMCompleter(nil)
+	ctx = NewWorkflowContext("wf-intent")
+	ctx.Parameters["said"] = "my router keeps rebooting"
+	node := newNode()
+	node.Input = "{{parameters.said}}"
+	next, err = node.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"support"}, next)
+
+	// no intent and no default route is an error, a default route takes over
+	SetLLMCompleter(func(req LLMRequest) (string, error) { return "none", nil })
+	ctx = NewWorkflowContext("wf-intent")
+	ctx.SetData(LastTranscriptKey, "hello")
+	_, err = newNode().Run(ctx)
+	require.Error(t, err)
+	node = newNode()
+	node.DefaultNextNodeID = "other"
+	next, err = node.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"other"}, next)
+	require.Equal(t, "", ctx.NodeData[IntentKey("intent")])
+}
@@ -0,0 +1,162 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNodeTimeout returned when a node exceeds its timeout
+	ErrNodeTimeout = errors.New("node execution timed out")
+	// ErrWorkflowTimeout returned when the whole workflow exceeds its timeout
+	ErrWorkflowTimeout = errors.New("workflow execution timed out")
+)
+
+// RetryPolicy controls how a failed node is retried
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts including the first one, <=1 disables retry
+	InitialBackoff time.Duration // wait before the second attempt
+	MaxBackoff     time.Duration // upper bound of backoff, 0 means unlimited
+	Multiplier     float64       // backoff multiplier, defaults to 2
+}
+
+// Attempts returns the effective number of attempts
+func (p *RetryPolicy) Attempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// Backoff returns the wait duration before the given attempt (attempt starts at 1)
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	if p == nil || attempt <= 1 || p.InitialBackoff <= 0 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	backoff := float64(p.InitialBackoff)
+	for i := 2; i < attempt; i++ {
+		backoff *= multiplier
+		if p.MaxBackoff > 0 && time.Duration(backoff) >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && time.Duration(backoff) > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return time.Duration(backoff)
+}
+
+// NodeExecutionError wraps the final error of a node after all attempts
+type NodeExecutionError struct {
+	NodeID   string
+	Attempts int
+	Err      error
+}
+
+func (e *NodeExecutionError) Error() string {
+	return fmt.Sprintf("node %s execution failed after %d attempt(s): %v", e.NodeID, e.Attempts, e.Err)
+}
+
+func (e *NodeExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// DeadLetter reports whether the failure needs manual handling: the node timed
+// out or failed on every attempt of a retry policy. A node without retries that
+// fails once is an ordinary failure.
+func (e *NodeExecutionError) DeadLetter() bool {
+	return e.Attempts > 1 || errors.Is(e.Err, ErrNodeTimeout) || errors.Is(e.Err, ErrWorkflowTimeout)
+}
+
+// runNode executes a node honoring its retry policy and timeout.
+// deadline is the workflow deadline (zero means none).
+func (wf *Workflow) runNode(node ExecutableNode, deadline time.Time) ([]string, error) {
+	base := node.Base()
+	policy := base.Retry
+	if policy == nil {
+		policy = wf.DefaultRetry
+	}
+	timeout := base.Timeout
+	if timeout <= 0 {
+		timeout = wf.DefaultNodeTimeout
+	}
+
+	attempts := policy.Attempts()
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if wait := policy.Backoff(attempt); wait > 0 {
+			if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+				return nil, &NodeExecutionError{NodeID: base.ID, Attempts: attempt - 1, Err: ErrWorkflowTimeout}
+			}
+			wf.Context.AddLog("warning", fmt.Sprintf("Retrying node in %v (attempt %d/%d)", wait, attempt, attempts), base.ID, base.Name)
+			time.Sleep(wait)
+		}
+
+		nodeTimeout := timeout
+		timeoutErr := ErrNodeTimeout
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return nil, &NodeExecutionError{NodeID: base.ID, Attempts: attempt - 1, Err: ErrWorkflowTimeout}
+			}
+			if nodeTimeout <= 0 || remaining < nodeTimeout {
+				nodeTimeout = remaining
+				timeoutErr = ErrWorkflowTimeout
+			}
+		}
+
+		next, err := runWithTimeout(node, wf.Context, nodeTimeout, timeoutErr)
+		if err == nil || errors.Is(err, ErrWorkflowSuspended) {
+			return next, err
+		}
+		lastErr = err
+		wf.Context.AddLog("error", fmt.Sprintf("Node attempt %d/%d failed: %v", attempt, attempts, err), base.ID, base.Name)
+		// A timed out node may still be running in background with side effects
+		// (requests sent, calls placed), so timeouts are never retried.
+		if errors.Is(err, ErrWorkflowTimeout) || errors.Is(err, ErrNodeTimeout) {
+			return nil, &NodeExecutionError{NodeID: base.ID, Attempts: attempt, Err: err}
+		}
+	}
+	return nil, &NodeExecutionError{NodeID: base.ID, Attempts: attempts, Err: lastErr}
+}
+
+// runWithTimeout runs node.Run and gives up after timeout.
+// Nodes have no cancellation hook, so a timed out node keeps running in background
+// and its result is discarded. The attempt runs on a copy of the context that is
+// adopted only when the node returns in time, so the abandoned node cannot modify
+// the context while the workflow goes on.
+func runWithTimeout(node ExecutableNode, ctx *WorkflowContext, timeout time.Duration, timeoutErr error) ([]string, error) {
+	if timeout <= 0 {
+		return node.Run(ctx)
+	}
+	type result struct {
+		next []string
+		err  error
+	}
+	attempt := ctx.fork()
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("node panic: %v", r)}
+			}
+		}()
+		next, err := node.Run(attempt)
+		done <- result{next: next, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		ctx.adopt(attempt)
+		return r.next, r.err
+	case <-timer.C:
+		return nil, fmt.Errorf("%w after %v", timeoutErr, timeout)
+	}
+}
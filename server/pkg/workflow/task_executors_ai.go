@@ -0,0 +1,233 @@
+package workflow
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// LLMRequest describes a completion request issued by an llm node
+type LLMRequest struct {
+	Provider     string            // openai, coze, ollama ...
+	APIKey       string            // required except for ollama, the server key is never used
+	BaseURL      string            // optional, falls back to server defaults
+	Model        string            // chat model
+	SystemPrompt string            // system prompt
+	Prompt       string            // resolved user prompt
+	Temperature  *float32          // optional temperature
+	MaxTokens    *int              // optional max tokens
+	Extra        map[string]string // provider specific options
+}
+
+// TTSRequest describes a synthesis request issued by a tts node
+type TTSRequest struct {
+	Text   string                 // resolved text to synthesize
+	Config map[string]interface{} // provider config (provider, apiKey, voiceType ...)
+}
+
+// TTSResult is returned by a TTSSynthesizer
+type TTSResult struct {
+	AudioURL   string
+	Format     string
+	Size       int
+	SampleRate int
+}
+
+// LLMCompleter performs a completion for llm nodes
+type LLMCompleter func(req LLMRequest) (string, error)
+
+// TTSSynthesizer synthesizes speech for tts nodes
+type TTSSynthesizer func(req TTSRequest) (*TTSResult, error)
+
+var (
+	aiBackendMu    sync.RWMutex
+	llmCompleter   LLMCompleter
+	ttsSynthesizer TTSSynthesizer
+)
+
+// SetLLMCompleter installs the backend used by llm nodes
+func SetLLMCompleter(fn LLMCompleter) {
+	aiBackendMu.Lock()
+	defer aiBackendMu.Unlock()
+	llmCompleter = fn
+}
+
+// SetTTSSynthesizer installs the backend used by tts nodes
+func SetTTSSynthesizer(fn TTSSynthesizer) {
+	aiBackendMu.Lock()
+	defer aiBackendMu.Unlock()
+	ttsSynthesizer = fn
+}
+
+func getLLMCompleter() LLMCompleter {
+	aiBackendMu.RLock()
+	defer aiBackendMu.RUnlock()
+	return llmCompleter
+}
+
+func getTTSSynthesizer() TTSSynthesizer {
+	aiBackendMu.RLock()
+	defer aiBackendMu.RUnlock()
+	return ttsSynthesizer
+}
+
+// LLMTaskExecutor handles LLM completion tasks
+type LLMTaskExecutor struct{}
+
+func (e *LLMTaskExecutor) GetTaskType() string {
+	return "llm"
+}
+
+func (e *LLMTaskExecutor) Execute(ctx *WorkflowContext, config map[string]interface{}, inputs map[string]interface{}) (map[string]interface{}, error) {
+	completer := getLLMCompleter()
+	if completer == nil {
+		return nil, fmt.Errorf("LLM backend is not configured")
+	}
+
+	prompt := configString(config, "prompt")
+	if prompt == "" {
+		return nil, fmt.Errorf("LLM task requires 'prompt' configuration")
+	}
+
+	req := LLMRequest{
+		Provider:     configString(config, "provider"),
+		APIKey:       configString(config, "api_key", "apiKey"),
+		BaseURL:      configString(config, "base_url", "baseUrl"),
+		Model:        configString(config, "model"),
+		SystemPrompt: resolveTemplate(configString(config, "system_prompt", "systemPrompt"), inputs, ctx),
+		Prompt:       resolveTemplate(prompt, inputs, ctx),
+	}
+	if t, ok := configFloat(config, "temperature"); ok {
+		temperature := float32(t)
+		req.Temperature = &temperature
+	}
+	if m, ok := configFloat(config, "max_tokens", "maxTokens"); ok && m > 0 {
+		maxTokens := int(m)
+		req.MaxTokens = &maxTokens
+	}
+	if extra, ok := config["extra"].(map[string]interface{}); ok {
+		req.Extra = make(map[string]string, len(extra))
+		for k, v := range extra {
+			req.Extra[k] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	if ctx != nil {
+		ctx.AddLog("info", fmt.Sprintf("Executing LLM task (provider: %s, model: %s)", req.Provider, req.Model), "", "")
+	}
+
+	text, err := completer(req)
+	if err != nil {
+		if ctx != nil {
+			ctx.AddLog("error", fmt.Sprintf("LLM completion failed: %v", err), "", "")
+		}
+		return nil, fmt.Errorf("LLM completion failed: %w", err)
+	}
+
+	if ctx != nil {
+		ctx.AddLog("success", fmt.Sprintf("LLM completion returned %d characters", len(text)), "", "")
+	}
+
+	return map[string]interface{}{
+		"text":   text,
+		"prompt": req.Prompt,
+		"model":  req.Model,
+	}, nil
+}
+
+// TTSTaskExecutor handles text-to-speech tasks
+type TTSTaskExecutor struct{}
+
+func (e *TTSTaskExecutor) GetTaskType() string {
+	return "tts"
+}
+
+func (e *TTSTaskExecutor) Execute(ctx *WorkflowContext, config map[string]interface{}, inputs map[string]interface{}) (map[string]interface{}, error) {
+	synthesize := getTTSSynthesizer()
+	if synthesize == nil {
+		return nil, fmt.Errorf("TTS backend is not configured")
+	}
+
+	text := configString(config, "text")
+	if text == "" {
+		return nil, fmt.Errorf("TTS task requires 'text' configuration")
+	}
+	text = resolveTemplate(text, inputs, ctx)
+	if text == "" {
+		return nil, fmt.Errorf("TTS text resolved to empty string")
+	}
+
+	ttsConfig := make(map[string]interface{})
+	for k, v := range config {
+		if k == "text" || k == "task_type" {
+			continue
+		}
+		ttsConfig[k] = resolveTemplateInValue(v, inputs, ctx)
+	}
+
+	if ctx != nil {
+		ctx.AddLog("info", fmt.Sprintf("Executing TTS task (%d characters)", len([]rune(text))), "", "")
+	}
+
+	result, err := synthesize(TTSRequest{Text: text, Config: ttsConfig})
+	if err != nil {
+		if ctx != nil {
+			ctx.AddLog("error", fmt.Sprintf("TTS synthesis failed: %v", err), "", "")
+		}
+		return nil, fmt.Errorf("TTS synthesis failed: %w", err)
+	}
+	if result == nil {
+		return nil, fmt.Errorf("TTS synthesis returned no result")
+	}
+
+	if ctx != nil {
+		ctx.AddLog("success", fmt.Sprintf("TTS synthesis completed: %s", result.AudioURL), "", "")
+	}
+
+	return map[string]interface{}{
+		"audioUrl":   result.AudioURL,
+		"format":     result.Format,
+		"size":       result.Size,
+		"sampleRate": result.SampleRate,
+		"text":       text,
+	}, nil
+}
+
+// configString returns the first non-empty string value among keys
+func configString(config map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if v, ok := config[key]; ok && v != nil {
+			switch val := v.(type) {
+			case string:
+				if val != "" {
+					return val
+				}
+			default:
+				return fmt.Sprintf("%v", val)
+			}
+		}
+	}
+	return ""
+}
+
+// configFloat returns the first numeric value among keys
+func configFloat(config map[string]interface{}, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		switch v := config[key].(type) {
+		case float64:
+			return v, true
+		case int:
+			return float64(v), true
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func init() {
+	RegisterTaskExecutor(&LLMTaskExecutor{})
+	RegisterTaskExecutor(&TTSTaskExecutor{})
+}
@@ -3,6 +3,7 @@ package workflow
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 var (
@@ -18,6 +19,11 @@ type Workflow struct {
 	StartNodeID string
 	EndNodeID   string
 	MaxSteps    int
+
+	Timeout            time.Duration // whole workflow timeout, 0 means unlimited
+	DefaultNodeTimeout time.Duration // per-attempt timeout for nodes without their own
+	DefaultRetry       *RetryPolicy  // retry policy for nodes without their own
+	FailedNodeID       string        // node that caused the last failure
 }
 
 // NewWorkflow creates workflow with sane defaults
@@ -54,14 +60,38 @@ func (wf *Workflow) SetEndNode(nodeID string) {
 // It processes nodes in a breadth-first manner, executing each node and following its next nodes.
 // Returns an error if the workflow exceeds max steps, a node fails, or the end node is not reached.
 func (wf *Workflow) Execute() error {
+	return wf.executeFrom(wf.StartNodeID)
+}
+
+// ResumeFrom continues a suspended workflow starting at the given node,
+// typically the node that returned a SuspendedError.
+func (wf *Workflow) ResumeFrom(nodeID string) error {
+	if _, ok := wf.Nodes[nodeID]; !ok {
+		return fmt.Errorf("node %s not registered", nodeID)
+	}
+	return wf.executeFrom(nodeID)
+}
+
+func (wf *Workflow) executeFrom(startNodeID string) error {
 	if err := wf.ensureReady(); err != nil {
 		return err
 	}
 	if wf.Context == nil {
 		return fmt.Errorf("workflow context is nil")
 	}
+	wf.Context.placedMu.Lock()
+	wf.Context.placedClosed = false
+	wf.Context.placedMu.Unlock()
+	defer wf.Context.hangupPlacedCalls()
+
+	wf.FailedNodeID = ""
+	wf.Context.Steps = nil
+	var deadline time.Time
+	if wf.Timeout > 0 {
+		deadline = time.Now().Add(wf.Timeout)
+	}
 
-	queue := []string{wf.StartNodeID}
+	queue := []string{startNodeID}
 	steps := 0
 	visited := make(map[string]bool) // Track visited nodes to detect cycles
 
@@ -89,13 +119,24 @@ func (wf *Workflow) Execute() error {
 		wf.Context.SetNodeStatus(currentNodeID, NodeStatusRunning, nil)
 		wf.Context.AddLog("info", fmt.Sprintf("Executing node: %s", node.Base().Name), currentNodeID, node.Base().Name)
 
-		nextNodes, err := node.Run(wf.Context)
+		before := wf.Context.snapshotData()
+		startedAt := time.Now()
+		nextNodes, err := wf.runNode(node, deadline)
+		if errors.Is(err, ErrWorkflowSuspended) {
+			wf.Context.recordStep(node, before, startedAt, NodeStatusWaiting, nil, nil)
+			wf.Context.SetNodeStatus(currentNodeID, NodeStatusWaiting, nil)
+			wf.Context.AddLog("info", fmt.Sprintf("Workflow suspended at node: %s", node.Base().Name), currentNodeID, node.Base().Name)
+			return err
+		}
 		if err != nil {
+			wf.Context.recordStep(node, before, startedAt, NodeStatusFailed, nil, err)
+			wf.FailedNodeID = currentNodeID
 			wf.Context.SetNodeStatus(currentNodeID, NodeStatusFailed, err)
 			wf.Context.AddLog("error", fmt.Sprintf("Node execution failed: %s", err.Error()), currentNodeID, node.Base().Name)
 			return fmt.Errorf("node %s execution failed: %w", currentNodeID, err)
 		}
 
+		wf.Context.recordStep(node, before, startedAt, NodeStatusCompleted, nextNodes, nil)
 		wf.Context.SetNodeStatus(currentNodeID, NodeStatusCompleted, nil)
 		wf.Context.AddLog("success", fmt.Sprintf("Node completed: %s", node.Base().Name), currentNodeID, node.Base().Name)
 